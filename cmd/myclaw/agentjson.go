@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+)
+
+const agentJSONSchemaVersion = 1
+
+// agentJSONFlag prints the result of agent -m as JSON.
+var agentJSONFlag bool
+
+// errAgentReported is returned when a failed run was already reported as
+// JSON, so the error is not printed again.
+var errAgentReported = errors.New("agent run failed")
+
+type agentToolCallJSON struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Result    string         `json:"result,omitempty"`
+}
+
+// writeAgentJSON reports a single-message run: its answer, the tools it
+// called and the tokens it used, or why it failed. A failure keeps the exit
+// code of runErr.
+func writeAgentJSON(w io.Writer, sessionID string, resp *api.Response, runErr error, elapsed time.Duration) error {
+	out := map[string]any{
+		"schemaVersion": agentJSONSchemaVersion,
+		"command":       "agent",
+		"ok":            runErr == nil,
+		"sessionId":     sessionID,
+		"durationMs":    elapsed.Milliseconds(),
+	}
+	if runErr != nil {
+		out["error"] = runErr.Error()
+	}
+	if resp != nil && resp.Result != nil {
+		r := resp.Result
+		calls := make([]agentToolCallJSON, 0, len(r.ToolCalls))
+		for _, c := range r.ToolCalls {
+			calls = append(calls, agentToolCallJSON{ID: c.ID, Name: c.Name, Arguments: c.Arguments, Result: c.Result})
+		}
+		out["output"] = r.Output
+		out["stopReason"] = r.StopReason
+		out["toolCalls"] = calls
+		out["usage"] = map[string]int{
+			"inputTokens":      r.Usage.InputTokens,
+			"outputTokens":     r.Usage.OutputTokens,
+			"cacheReadTokens":  r.Usage.CacheReadTokens,
+			"cacheWriteTokens": r.Usage.CacheCreationTokens,
+		}
+	}
+	if err := writeJSON(w, out); err != nil {
+		return err
+	}
+	if runErr != nil {
+		return withExitCode(exitCode(runErr), errAgentReported)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func TestRunAgentWithOptions_JSON(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	oldMsg, oldJSON := messageFlag, agentJSONFlag
+	messageFlag, agentJSONFlag = "list files", true
+	t.Cleanup(func() { messageFlag, agentJSONFlag = oldMsg, oldJSON })
+
+	rt := &mockRuntime{response: &api.Response{Result: &api.Result{
+		Output:     "Two files.",
+		StopReason: "end_turn",
+		Usage:      model.Usage{InputTokens: 120, OutputTokens: 8},
+		ToolCalls:  []model.ToolCall{{ID: "t1", Name: "Bash", Arguments: map[string]any{"command": "ls"}, Result: "a\nb"}},
+	}}}
+	var stdout bytes.Buffer
+	if err := runAgentWithOptions(AgentOptions{RuntimeFactory: mockRuntimeFactory(rt), Stdout: &stdout}); err != nil {
+		t.Fatalf("runAgentWithOptions error: %v", err)
+	}
+
+	var got struct {
+		SchemaVersion int    `json:"schemaVersion"`
+		Command       string `json:"command"`
+		OK            bool   `json:"ok"`
+		SessionID     string `json:"sessionId"`
+		Output        string `json:"output"`
+		ToolCalls     []struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+			Result    string         `json:"result"`
+		} `json:"toolCalls"`
+		Usage      map[string]int `json:"usage"`
+		DurationMs *int64         `json:"durationMs"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, stdout.String())
+	}
+	if got.SchemaVersion != agentJSONSchemaVersion || got.Command != "agent" || !got.OK || got.SessionID != "cli" || got.Output != "Two files." {
+		t.Errorf("result = %+v", got)
+	}
+	if len(got.ToolCalls) != 1 || got.ToolCalls[0].Name != "Bash" || got.ToolCalls[0].Arguments["command"] != "ls" || got.ToolCalls[0].Result != "a\nb" {
+		t.Errorf("toolCalls = %+v", got.ToolCalls)
+	}
+	if got.Usage["inputTokens"] != 120 || got.Usage["outputTokens"] != 8 || got.DurationMs == nil {
+		t.Errorf("usage = %v, durationMs = %v", got.Usage, got.DurationMs)
+	}
+}
+
+func TestRunAgentWithOptions_JSONError(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	oldMsg, oldJSON := messageFlag, agentJSONFlag
+	messageFlag, agentJSONFlag = "hi", true
+	t.Cleanup(func() { messageFlag, agentJSONFlag = oldMsg, oldJSON })
+
+	var stdout bytes.Buffer
+	err := runAgentWithOptions(AgentOptions{RuntimeFactory: mockRuntimeFactory(&mockRuntime{err: context.DeadlineExceeded}), Stdout: &stdout})
+	if !errors.Is(err, errAgentReported) {
+		t.Fatalf("err = %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, stdout.String())
+	}
+	if got["ok"] != false || got["error"] != context.DeadlineExceeded.Error() {
+		t.Errorf("result = %v", got)
+	}
+
+	messageFlag = ""
+	if err := runAgentWithOptions(AgentOptions{}); err == nil {
+		t.Error("expected --json without -m to fail")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/audit"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const auditJSONSchemaVersion = 1
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Review what the assistant did",
+	Long: `Review the audit log: every message the assistant got, each tool it ran
+with its arguments, the files it changed and the replies it sent.`,
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Show the latest audit entries",
+	Args:  cobra.NoArgs,
+	RunE:  runAuditTail,
+}
+
+var auditSearchCmd = &cobra.Command{
+	Use:   "search <text>",
+	Short: "Find audit entries that mention text",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runAuditSearch,
+}
+
+func init() {
+	auditTailCmd.Flags().IntP("lines", "n", 20, "Number of entries to show")
+	auditTailCmd.Flags().String("kind", "", "Only show inbound, tool, file or outbound entries")
+	auditTailCmd.Flags().Bool("json", false, "Output as JSON")
+	auditSearchCmd.Flags().String("since", "30d", "How far back to look: 30m, 24h, 7d or a date (YYYY-MM-DD)")
+	auditSearchCmd.Flags().String("kind", "", "Only search inbound, tool, file or outbound entries")
+	auditSearchCmd.Flags().Int("limit", 50, "Show at most this many of the latest matches")
+	auditSearchCmd.Flags().Bool("json", false, "Output as JSON")
+	auditCmd.AddCommand(auditTailCmd, auditSearchCmd)
+	rootCmd.AddCommand(auditCmd)
+}
+
+func runAuditTail(cmd *cobra.Command, args []string) error {
+	lines, _ := cmd.Flags().GetInt("lines")
+	return showAudit(cmd, "audit.tail", time.Time{}, "", lines)
+}
+
+func runAuditSearch(cmd *cobra.Command, args []string) error {
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	since, err := parseSince(sinceFlag, time.Now())
+	if err != nil {
+		return err
+	}
+	limit, _ := cmd.Flags().GetInt("limit")
+	return showAudit(cmd, "audit.search", since, strings.Join(args, " "), limit)
+}
+
+// showAudit prints the last limit entries since since that are of --kind
+// and match query.
+func showAudit(cmd *cobra.Command, command string, since time.Time, query string, limit int) error {
+	kind, _ := cmd.Flags().GetString("kind")
+	switch kind {
+	case "", audit.Inbound, audit.Tool, audit.File, audit.Outbound:
+	default:
+		return fmt.Errorf("invalid --kind %q: want inbound, tool, file or outbound", kind)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	entries, err := audit.Read(audit.Path(cfg.Agent.Workspace), since)
+	if err != nil {
+		return err
+	}
+	matched := entries[:0]
+	for _, e := range entries {
+		if (kind == "" || e.Kind == kind) && (query == "" || e.Matches(query)) {
+			matched = append(matched, e)
+		}
+	}
+	if limit > 0 && len(matched) > limit {
+		matched = matched[len(matched)-limit:]
+	}
+
+	if readJSONFlag(cmd) {
+		if matched == nil {
+			matched = []audit.Entry{}
+		}
+		return printJSON(map[string]any{
+			"schemaVersion": auditJSONSchemaVersion,
+			"command":       command,
+			"ok":            true,
+			"count":         len(matched),
+			"entries":       matched,
+		})
+	}
+
+	if len(matched) == 0 {
+		if !cfg.Audit.Enabled {
+			fmt.Println("No audit entries. The audit log is off; set audit.enabled to turn it on.")
+		} else {
+			fmt.Println("No audit entries.")
+		}
+		return nil
+	}
+	for _, e := range matched {
+		fmt.Println(formatAuditEntry(e))
+	}
+	return nil
+}
+
+func formatAuditEntry(e audit.Entry) string {
+	where := e.Channel
+	if e.ChatID != "" {
+		where += "/" + e.ChatID
+	}
+	if e.Sender != "" && e.Sender != e.ChatID {
+		where += " (" + e.Sender + ")"
+	}
+	if where == "" {
+		where = "-"
+	}
+	var what string
+	switch e.Kind {
+	case audit.Tool:
+		args, _ := json.Marshal(e.Args)
+		what = e.Tool + " " + clipLine(string(args), 120)
+	case audit.File:
+		what = e.Tool + " " + e.Path
+	default:
+		what = clipLine(e.Content, 120)
+	}
+	if e.Error != "" {
+		what += " (failed: " + clipLine(e.Error, 80) + ")"
+	}
+	return fmt.Sprintf("%s  %-8s  %s  %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.Kind, where, what)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/audit"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func seedAudit(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "")
+	log := audit.NewLog(audit.Path(config.DefaultConfig().Agent.Workspace))
+	now := time.Now()
+	for _, e := range []audit.Entry{
+		{Time: now.Add(-3 * time.Minute), Kind: audit.Inbound, Source: audit.Source{Channel: "telegram", ChatID: "42", Sender: "7"}, Content: "clean up the build dir"},
+		{Time: now.Add(-2 * time.Minute), Kind: audit.Tool, Source: audit.Source{Channel: "telegram", ChatID: "42"}, Tool: "Bash", Args: map[string]any{"command": "rm -rf build"}},
+		{Time: now.Add(-1 * time.Minute), Kind: audit.File, Source: audit.Source{Channel: "telegram", ChatID: "42"}, Tool: "Write", Path: "/tmp/notes.md"},
+		{Time: now, Kind: audit.Outbound, Source: audit.Source{Channel: "telegram", ChatID: "42"}, Content: "Done."},
+	} {
+		if err := log.Append(e); err != nil {
+			t.Fatalf("seed audit: %v", err)
+		}
+	}
+}
+
+func auditCommand(flags map[string]string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().IntP("lines", "n", 20, "")
+	cmd.Flags().String("kind", "", "")
+	cmd.Flags().String("since", "30d", "")
+	cmd.Flags().Int("limit", 50, "")
+	cmd.Flags().Bool("json", false, "")
+	for name, value := range flags {
+		_ = cmd.Flags().Set(name, value)
+	}
+	return cmd
+}
+
+func TestRunAuditTail(t *testing.T) {
+	seedAudit(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runAuditTail(auditCommand(map[string]string{"lines": "2"}), nil)
+	})
+	if err != nil {
+		t.Fatalf("runAuditTail error: %v", err)
+	}
+	if strings.Contains(output, "rm -rf") || !strings.Contains(output, "Write /tmp/notes.md") || !strings.Contains(output, "Done.") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	output, _ = captureRunOutput(t, func() error {
+		return runAuditTail(auditCommand(map[string]string{"kind": "inbound"}), nil)
+	})
+	if !strings.Contains(output, "telegram/42 (7)  clean up the build dir") || strings.Contains(output, "Done.") {
+		t.Errorf("unexpected --kind output: %s", output)
+	}
+
+	if err := runAuditTail(auditCommand(map[string]string{"kind": "everything"}), nil); err == nil {
+		t.Error("expected error for bad --kind")
+	}
+}
+
+func TestRunAuditSearch(t *testing.T) {
+	seedAudit(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runAuditSearch(auditCommand(map[string]string{"json": "true"}), []string{"RM", "-rf"})
+	})
+	if err != nil {
+		t.Fatalf("runAuditSearch error: %v", err)
+	}
+	var result struct {
+		Count   int           `json:"count"`
+		Entries []audit.Entry `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, output)
+	}
+	if result.Count != 1 || result.Entries[0].Tool != "Bash" {
+		t.Errorf("search = %+v", result)
+	}
+
+	output, _ = captureRunOutput(t, func() error {
+		return runAuditSearch(auditCommand(nil), []string{"nothing like this"})
+	})
+	if !strings.Contains(output, "No audit entries.") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/calendar"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/googleauth"
+	"github.com/stellarlinkco/myclaw/internal/mailbox"
+	"golang.org/x/oauth2"
+)
+
+// authTimeout is how long `myclaw auth google` waits for the browser.
+const authTimeout = 5 * time.Minute
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Sign in to services the agent uses",
+}
+
+var authGoogleCmd = &cobra.Command{
+	Use:   "google",
+	Short: "Sign in to Google Calendar",
+	Long: `Sign in to Google so the agent can read and add events in your calendar.
+
+Create an OAuth client of the "Desktop app" type in the Google Cloud console,
+enable the Google Calendar API, and set calendar.google.clientId and
+clientSecret. This command prints a URL to open; after you allow access, the
+sign-in is saved under ~/.myclaw/data/calendar and refreshed as needed.`,
+	Args: cobra.NoArgs,
+	RunE: runAuthGoogle,
+}
+
+var authGmailCmd = &cobra.Command{
+	Use:   "gmail",
+	Short: "Sign in to Gmail",
+	Long: `Sign in to Gmail so the agent can search and read your mail, save drafts
+and, as mail.send allows, send.
+
+Create an OAuth client of the "Desktop app" type in the Google Cloud console,
+enable the Gmail API, and set mail.gmail.clientId and clientSecret. This
+command prints a URL to open; after you allow access, the sign-in is saved
+under ~/.myclaw/data/mail and refreshed as needed.`,
+	Args: cobra.NoArgs,
+	RunE: runAuthGmail,
+}
+
+func init() {
+	authGoogleCmd.Flags().Bool("logout", false, "Forget the saved sign-in")
+	authGmailCmd.Flags().Bool("logout", false, "Forget the saved sign-in")
+	authCmd.AddCommand(authGoogleCmd, authGmailCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+func runAuthGoogle(cmd *cobra.Command, args []string) error {
+	path := calendar.GoogleTokenPath()
+	if logout, _ := cmd.Flags().GetBool("logout"); logout {
+		return signOut(path, "Google")
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	g := cfg.Calendar.Google
+	if g.ClientID == "" || g.ClientSecret == "" {
+		return errors.New("set calendar.google.clientId and calendar.google.clientSecret first; see `myclaw auth google --help`")
+	}
+	if err := signIn(cmd, calendar.GoogleOAuth(g), path); err != nil {
+		return err
+	}
+	fmt.Println("Signed in to Google. Set calendar.provider to \"google\" if you have not yet.")
+	return nil
+}
+
+func runAuthGmail(cmd *cobra.Command, args []string) error {
+	path := mailbox.GmailTokenPath()
+	if logout, _ := cmd.Flags().GetBool("logout"); logout {
+		return signOut(path, "Gmail")
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	g := cfg.Mail.Gmail
+	if g.ClientID == "" || g.ClientSecret == "" {
+		return errors.New("set mail.gmail.clientId and mail.gmail.clientSecret first; see `myclaw auth gmail --help`")
+	}
+	if err := signIn(cmd, mailbox.GmailOAuth(g), path); err != nil {
+		return err
+	}
+	fmt.Println("Signed in to Gmail. Set mail.provider to \"gmail\" if you have not yet.")
+	return nil
+}
+
+func signOut(path, service string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	fmt.Printf("Signed out of %s.\n", service)
+	return nil
+}
+
+// signIn runs the browser sign-in with oauth and saves the token at path.
+func signIn(cmd *cobra.Command, oauth *oauth2.Config, path string) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	oauth.RedirectURL = fmt.Sprintf("http://%s/callback", ln.Addr())
+	state := randomState()
+	verifier := oauth2.GenerateVerifier()
+
+	fmt.Printf("Open this URL in a browser and allow access:\n\n  %s\n\nWaiting for Google...\n",
+		oauth.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier)))
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), authTimeout)
+	defer cancel()
+	code, err := waitForCode(ctx, ln, state)
+	if err != nil {
+		return err
+	}
+	tok, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return fmt.Errorf("finish sign-in: %w", err)
+	}
+	if err := googleauth.SaveToken(path, tok); err != nil {
+		return fmt.Errorf("save sign-in: %w", err)
+	}
+	return nil
+}
+
+// waitForCode serves the OAuth redirect on ln until it brings a code for
+// state, or ctx ends.
+func waitForCode(ctx context.Context, ln net.Listener, state string) (string, error) {
+	type result struct {
+		code string
+		err  error
+	}
+	done := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		var res result
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "This sign-in link is out of date; run the sign-in again.", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("google refused the sign-in: %s", q.Get("error"))
+			fmt.Fprintln(w, "Sign-in cancelled. You can close this tab.")
+		default:
+			res.code = q.Get("code")
+			fmt.Fprintln(w, "Signed in to myclaw. You can close this tab.")
+		}
+		select {
+		case done <- res:
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	select {
+	case res := <-done:
+		return res.code, res.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("no sign-in within %s", authTimeout)
+	}
+}
+
+func randomState() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
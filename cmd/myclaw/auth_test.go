@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/googleauth"
+	"github.com/stellarlinkco/myclaw/internal/mailbox"
+	"golang.org/x/oauth2"
+)
+
+func TestWaitForCode(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "http://" + ln.Addr().String() + "/callback"
+	done := make(chan string, 1)
+	go func() {
+		code, err := waitForCode(context.Background(), ln, "s1")
+		if err != nil {
+			done <- "error: " + err.Error()
+			return
+		}
+		done <- code
+	}()
+
+	resp, err := http.Get(base + "?state=wrong&code=evil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("wrong state status = %d", resp.StatusCode)
+	}
+	resp, err = http.Get(base + "?state=s1&code=good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := <-done; got != "good" {
+		t.Errorf("code = %q", got)
+	}
+}
+
+func TestRunAuthGmail(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logout := &cobra.Command{}
+	logout.Flags().Bool("logout", false, "")
+
+	if err := runAuthGmail(logout, nil); err == nil || !strings.Contains(err.Error(), "mail.gmail.clientId") {
+		t.Errorf("sign-in without a client error = %v", err)
+	}
+
+	path := mailbox.GmailTokenPath()
+	if err := googleauth.SaveToken(path, &oauth2.Token{AccessToken: "access"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = logout.Flags().Set("logout", "true")
+	output, err := captureRunOutput(t, func() error { return runAuthGmail(logout, nil) })
+	if err != nil || !strings.Contains(output, "Signed out of Gmail") {
+		t.Fatalf("logout = %q, %v", output, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("token still there: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/backup"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const backupJSONSchemaVersion = 1
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up and restore the config, workspace, skills and cron jobs",
+	Long: `Back up the config file, the workspace (memory, sessions, tasks), skills
+and cron jobs to a tarball in a directory, an S3 bucket or a WebDAV folder,
+as set in the "backup" config. The gateway takes backups on a schedule
+when backup.enabled is set.`,
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Take a backup now",
+	Args:  cobra.NoArgs,
+	RunE:  runBackupCreate,
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List backups, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runBackupList,
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore [name]",
+	Short: "Restore a backup, the newest by default",
+	Long: `Restore a backup, the newest by default, over the current files. Files
+made since the backup are left in place. Stop the gateway first.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runBackupRestore,
+}
+
+func init() {
+	backupCreateCmd.Flags().Bool("json", false, "Output as JSON")
+	backupListCmd.Flags().Bool("json", false, "Output as JSON")
+	backupRestoreCmd.Flags().Bool("json", false, "Output as JSON")
+	backupRestoreCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+	backupCmd.AddCommand(backupCreateCmd, backupListCmd, backupRestoreCmd)
+	rootCmd.AddCommand(backupCmd)
+}
+
+func openBackupTarget() (*config.Config, backup.Target, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("load config: %w", err)
+	}
+	target, err := backup.NewTarget(cfg.Backup)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, target, nil
+}
+
+func runBackupCreate(cmd *cobra.Command, args []string) error {
+	cfg, target, err := openBackupTarget()
+	if err != nil {
+		return err
+	}
+	info, dropped, err := backup.Create(context.Background(), cfg, target, cfg.Backup.Keep, time.Now())
+	if err != nil {
+		return err
+	}
+	if dropped == nil {
+		dropped = []string{}
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": backupJSONSchemaVersion,
+			"command":       "backup.create",
+			"ok":            true,
+			"target":        target.String(),
+			"backup":        info,
+			"dropped":       dropped,
+		})
+	}
+	fmt.Printf("Backed up to %s: %s (%s)\n", target, info.Name, formatBackupSize(info.Size))
+	for _, name := range dropped {
+		fmt.Printf("Dropped old backup %s\n", name)
+	}
+	return nil
+}
+
+func runBackupList(cmd *cobra.Command, args []string) error {
+	_, target, err := openBackupTarget()
+	if err != nil {
+		return err
+	}
+	list, err := backup.List(context.Background(), target)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = []backup.Info{}
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": backupJSONSchemaVersion,
+			"command":       "backup.list",
+			"ok":            true,
+			"target":        target.String(),
+			"backups":       list,
+		})
+	}
+	if len(list) == 0 {
+		fmt.Printf("No backups in %s.\n", target)
+		return nil
+	}
+	for _, info := range list {
+		fmt.Printf("%s  %s  %s\n", info.Name, info.Time.Local().Format("2006-01-02 15:04"), formatBackupSize(info.Size))
+	}
+	return nil
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) error {
+	cfg, target, err := openBackupTarget()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	} else {
+		list, err := backup.List(ctx, target)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return fmt.Errorf("no backups in %s", target)
+		}
+		name = list[0].Name
+	}
+
+	yes, _ := cmd.Flags().GetBool("yes")
+	jsonOutput := readJSONFlag(cmd)
+	if !yes {
+		if jsonOutput {
+			return fmt.Errorf("refusing to restore a backup without --yes")
+		}
+		fmt.Printf("Restore %s from %s over the current files? [y/N] ", name, target)
+		if !confirm(cmd.InOrStdin()) {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	r, err := target.Get(ctx, name)
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	defer r.Close()
+	n, err := backup.Restore(r, backup.Sources(cfg))
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printJSON(map[string]any{
+			"schemaVersion": backupJSONSchemaVersion,
+			"command":       "backup.restore",
+			"ok":            true,
+			"backup":        name,
+			"files":         n,
+		})
+	}
+	fmt.Printf("Restored %d files from %s.\n", n, name)
+	return nil
+}
+
+func formatBackupSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestRunBackup(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	memory := filepath.Join(cfg.Agent.Workspace, "memory", "MEMORY.md")
+	os.MkdirAll(filepath.Dir(memory), 0755)
+	os.WriteFile(memory, []byte("Likes tea."), 0644)
+
+	output, err := captureRunOutput(t, func() error { return runBackupList(&cobra.Command{}, nil) })
+	if err != nil || !strings.HasPrefix(output, "No backups in ") {
+		t.Errorf("empty list = %q, %v", output, err)
+	}
+	if err := runBackupRestore(backupCommand(nil), nil); err == nil {
+		t.Error("restore with no backups")
+	}
+
+	output, err = captureRunOutput(t, func() error { return runBackupCreate(buildJSONCommand(), nil) })
+	if err != nil || !strings.Contains(output, `"command": "backup.create"`) || !strings.Contains(output, `"name": "myclaw-`) {
+		t.Fatalf("create = %q, %v", output, err)
+	}
+	output, _ = captureRunOutput(t, func() error { return runBackupList(&cobra.Command{}, nil) })
+	if lines := strings.Split(strings.TrimSpace(output), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], "myclaw-") {
+		t.Errorf("list = %q", output)
+	}
+
+	os.WriteFile(memory, []byte("Forgot everything."), 0644)
+	cmd := backupCommand(nil)
+	cmd.SetIn(strings.NewReader("n\n"))
+	output, _ = captureRunOutput(t, func() error { return runBackupRestore(cmd, nil) })
+	if !strings.Contains(output, "Aborted.") {
+		t.Errorf("output = %q", output)
+	}
+	if err := runBackupRestore(backupCommand(map[string]string{"json": "true"}), nil); err == nil {
+		t.Error("--json without --yes should fail instead of prompting")
+	}
+	output, err = captureRunOutput(t, func() error { return runBackupRestore(backupCommand(map[string]string{"yes": "true"}), nil) })
+	if err != nil || !strings.HasPrefix(output, "Restored ") {
+		t.Fatalf("restore = %q, %v", output, err)
+	}
+	if data, _ := os.ReadFile(memory); string(data) != "Likes tea." {
+		t.Errorf("MEMORY.md = %q", data)
+	}
+	if err := runBackupRestore(backupCommand(map[string]string{"yes": "true"}), []string{"myclaw-20000101-000000.tar.gz"}); err == nil {
+		t.Error("restore of a missing backup")
+	}
+}
+
+func backupCommand(flags map[string]string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", false, "")
+	cmd.Flags().Bool("yes", false, "")
+	for k, v := range flags {
+		_ = cmd.Flags().Set(k, v)
+	}
+	return cmd
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/calendar"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const calendarJSONSchemaVersion = 1
+
+// agendaPrompt is what the morning agenda job asks the agent.
+const agendaPrompt = "Give me my agenda for today. Use list_events for today, and for tomorrow morning " +
+	"if something there needs preparing. Reply with a short briefing: the events in order with times " +
+	"and places, gaps worth knowing about, and anything that clashes."
+
+var calendarCmd = &cobra.Command{
+	Use:   "calendar",
+	Short: "Read the calendar and schedule agenda summaries",
+}
+
+var calendarEventsCmd = &cobra.Command{
+	Use:   "events [from] [to]",
+	Short: "List events, today's by default",
+	Long: `List the events of the configured calendar. from and to are "today",
+"tomorrow", a date (2026-10-16) or a date and time (2026-10-16T09:00); to is
+exclusive, and a date means the end of that day.`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: runCalendarEvents,
+}
+
+var calendarAgendaCmd = &cobra.Command{
+	Use:   "agenda",
+	Short: "Send a summary of the day's events every morning",
+	Long: `Add a cron job that has the agent summarize the day's events every
+morning and sends the summary to a chat, for example:
+
+  myclaw calendar agenda --at 7:30am --deliver telegram:123456789
+
+The job runs in the gateway like any other; see myclaw cron list.`,
+	Args: cobra.NoArgs,
+	RunE: runCalendarAgenda,
+}
+
+func init() {
+	calendarEventsCmd.Flags().Bool("json", false, "Output as JSON")
+	dailyJobFlags(calendarAgendaCmd, "8am", "agenda")
+	calendarCmd.AddCommand(calendarEventsCmd, calendarAgendaCmd)
+	rootCmd.AddCommand(calendarCmd)
+}
+
+func runCalendarEvents(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	cal, err := calendar.New(cfg.Calendar)
+	if err != nil {
+		return err
+	}
+	if cal == nil {
+		return errors.New("no calendar configured; set calendar.provider to caldav or google")
+	}
+	loc, _ := calendar.Location(cfg.Calendar)
+	args = append(args, "", "")
+	from, to, err := calendar.ParseRange(args[0], args[1], loc, time.Now())
+	if err != nil {
+		return err
+	}
+	events, err := cal.Events(cmd.Context(), from, to)
+	if err != nil {
+		return err
+	}
+	if events == nil {
+		events = []calendar.Event{}
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": calendarJSONSchemaVersion,
+			"command":       "calendar.events",
+			"ok":            true,
+			"calendar":      cal.Name(),
+			"from":          from,
+			"to":            to,
+			"events":        events,
+		})
+	}
+	fmt.Println(strings.TrimRight(calendar.Format(events, loc), "\n"))
+	return nil
+}
+
+func runCalendarAgenda(cmd *cobra.Command, args []string) error {
+	return addDailyJob(cmd, "Morning agenda", "agenda", agendaPrompt, "calendar.agenda")
+}
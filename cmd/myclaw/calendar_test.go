@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunCalendarAgenda(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := runCalendarAgenda(dailyJobCommand(nil), nil); err == nil || !strings.Contains(err.Error(), "--deliver") {
+		t.Errorf("agenda without --deliver error = %v", err)
+	}
+	if err := runCalendarAgenda(dailyJobCommand(map[string]string{"at": "teatime"}, "telegram:42"), nil); err == nil {
+		t.Error("agenda accepted --at teatime")
+	}
+
+	output, err := captureRunOutput(t, func() error {
+		return runCalendarAgenda(dailyJobCommand(map[string]string{"at": "7:30am", "weekdays": "true"}, "telegram:42"), nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "weekdays at 07:30") || !strings.Contains(output, "Deliver: telegram:42") {
+		t.Errorf("output = %q", output)
+	}
+	svc, _ := openCronService()
+	jobs := svc.ListJobs()
+	if len(jobs) != 1 || jobs[0].Schedule.Expr != "0 30 7 * * 1-5" || jobs[0].Payload.Message != agendaPrompt || len(jobs[0].Targets()) != 1 {
+		t.Errorf("jobs = %+v", jobs)
+	}
+}
+
+func TestRunCalendarEvents_NotConfigured(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := runCalendarEvents(buildJSONCommand(), nil); err == nil || !strings.Contains(err.Error(), "calendar.provider") {
+		t.Errorf("error = %v", err)
+	}
+}
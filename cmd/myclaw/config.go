@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/prompt"
+)
+
+const configJSONSchemaVersion = 1
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read, change and check the config file",
+	Long: `Read, change and check the config file without editing JSON by hand.
+
+Keys are dotted paths of the names in config.json, such as agent.model,
+channels.telegram.allowFrom or models.aliases.fast.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a config value, after environment overrides",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a value in the config file",
+	Long: `Change a value in the config file.
+
+Strings are stored as given. Lists of strings take comma-separated items,
+and other values are JSON:
+
+  myclaw config set agent.model claude-opus-4-1
+  myclaw config set agent.maxTokens 4096
+  myclaw config set channels.telegram.allowFrom 123,456`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in $EDITOR and check it afterwards",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigEdit,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for errors and unknown keys",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigValidate,
+}
+
+func init() {
+	configValidateCmd.Flags().Bool("json", false, "Output as JSON")
+	configCmd.AddCommand(configGetCmd, configSetCmd, configEditCmd, configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	value, err := config.Get(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	if s, ok := value.(string); ok {
+		fmt.Println(s)
+		return nil
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal value: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	if err := config.UpdateConfig(func(cfg *config.Config) error {
+		return config.Set(cfg, args[0], args[1])
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("Set %s in %s\n", args[0], config.ConfigPath())
+	return nil
+}
+
+func runConfigEdit(cmd *cobra.Command, args []string) error {
+	path := config.ConfigPath()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := config.SaveConfig(config.DefaultConfig()); err != nil {
+			return fmt.Errorf("create config: %w", err)
+		}
+	}
+	editor := editorCommand()
+	if len(editor) == 0 {
+		return errors.New("no editor: set $EDITOR")
+	}
+	c := exec.Command(editor[0], append(editor[1:], path)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("run %s: %w", filepath.Base(editor[0]), err)
+	}
+	return runConfigValidate(cmd, nil)
+}
+
+// editorCommand returns the editor to open files with, split into the
+// program and its arguments, such as "code --wait".
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	path := config.ConfigPath()
+	var warnings, problems []string
+	exists := true
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		exists = false
+	} else if warnings, err = config.CheckFile(path); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) == 0 {
+		cfg, err := config.LoadConfig()
+		if err == nil {
+			err = cfg.Validate()
+			// A broken template is sent as written, so it only warns.
+			if tmplErr := prompt.Check(cfg); tmplErr != nil {
+				warnings = append(warnings, tmplErr.Error())
+			}
+		}
+		problems = append(problems, errorLines(err)...)
+	}
+
+	if readJSONFlag(cmd) {
+		if err := printJSON(map[string]any{
+			"schemaVersion": configJSONSchemaVersion,
+			"command":       "config.validate",
+			"ok":            len(problems) == 0,
+			"path":          path,
+			"exists":        exists,
+			"warnings":      nonNil(warnings),
+			"errors":        nonNil(problems),
+		}); err != nil {
+			return err
+		}
+	} else {
+		if !exists {
+			fmt.Printf("No config file at %s; checking the defaults.\n", path)
+		}
+		for _, w := range warnings {
+			fmt.Printf("warning: %s\n", w)
+		}
+		for _, p := range problems {
+			fmt.Printf("error: %s\n", p)
+		}
+		if len(problems) == 0 {
+			fmt.Printf("Config OK: %s\n", path)
+		}
+	}
+	if len(problems) > 0 {
+		cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		return fmt.Errorf("config has %d error(s)", len(problems))
+	}
+	return nil
+}
+
+// errorLines flattens err, which may join several errors, into one line
+// per error.
+func errorLines(err error) []string {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var lines []string
+		for _, e := range joined.Unwrap() {
+			lines = append(lines, errorLines(e)...)
+		}
+		return lines
+	}
+	return []string{err.Error()}
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
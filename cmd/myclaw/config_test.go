@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestRunConfigSetGet(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "")
+
+	if _, err := captureRunOutput(t, func() error {
+		return runConfigSet(&cobra.Command{}, []string{"agent.maxTokens", "2048"})
+	}); err != nil {
+		t.Fatalf("runConfigSet: %v", err)
+	}
+	output, err := captureRunOutput(t, func() error {
+		return runConfigGet(&cobra.Command{}, []string{"agent.maxTokens"})
+	})
+	if err != nil {
+		t.Fatalf("runConfigGet: %v", err)
+	}
+	if strings.TrimSpace(output) != "2048" {
+		t.Errorf("get agent.maxTokens = %q, want 2048", output)
+	}
+
+	if err := runConfigSet(&cobra.Command{}, []string{"agent.maxTokens", "x"}); err == nil {
+		t.Error("setting an integer to x succeeded")
+	}
+}
+
+func TestRunConfigValidate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MYCLAW_API_KEY", "sk-test")
+
+	path := filepath.Join(home, ".myclaw", "config.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"agent": {"modle": "x"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", false, "")
+	output, err := captureRunOutput(t, func() error { return runConfigValidate(cmd, nil) })
+	if err != nil {
+		t.Fatalf("runConfigValidate: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "warning: unknown key agent.modle (did you mean agent.model?)") || !strings.Contains(output, "Config OK") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"provider": {"type": "acme"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = cmd.Flags().Set("json", "true")
+	output, err = captureRunOutput(t, func() error { return runConfigValidate(cmd, nil) })
+	if err == nil {
+		t.Fatal("runConfigValidate accepted provider.type acme")
+	}
+	if !strings.Contains(output, `"ok": false`) || !strings.Contains(output, `provider.type \"acme\"`) {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
+
+func TestRunConfigEdit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "sk-test")
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "true")
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", false, "")
+	if _, err := captureRunOutput(t, func() error { return runConfigEdit(cmd, nil) }); err != nil {
+		t.Fatalf("runConfigEdit: %v", err)
+	}
+	if _, err := os.Stat(config.ConfigPath()); err != nil {
+		t.Errorf("config file not created: %v", err)
+	}
+}
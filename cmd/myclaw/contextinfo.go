@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	sdkconfig "github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/prompt"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+const contextJSONSchemaVersion = 1
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Show what the model will be sent on the next turn",
+	Long: `Show the context the next agent turn starts from: each part of the system
+prompt (AGENTS.md, SOUL.md, memory, CLAUDE.md), the skills offered to the
+model, and the session's message history, with an estimate of the tokens
+each takes. --full prints the text of every part.`,
+	Args: cobra.NoArgs,
+	RunE: runContext,
+}
+
+func init() {
+	contextCmd.Flags().String("session", replSessionID, "Session whose history to include")
+	contextCmd.Flags().Bool("full", false, "Print the text of each part, not just its size")
+	contextCmd.Flags().Bool("json", false, "Output as JSON")
+	rootCmd.AddCommand(contextCmd)
+}
+
+// contextSection is one part of the context with its estimated size.
+type contextSection struct {
+	Name   string `json:"name"`
+	Source string `json:"source,omitempty"`
+	Tokens int    `json:"tokens"`
+	Text   string `json:"text,omitempty"`
+}
+
+// contextReport is what the next turn of a session starts from.
+type contextReport struct {
+	SessionID string
+	Model     string
+	// Sections are the system prompt and the skill list, in order.
+	Sections []contextSection
+	// History is the saved conversation; nil for a new session.
+	History       *session.Session
+	HistoryTokens int
+}
+
+func (r *contextReport) total() int {
+	total := r.HistoryTokens
+	for _, s := range r.Sections {
+		total += s.Tokens
+	}
+	return total
+}
+
+// estimateTokens estimates text the way the runtime's history does, at
+// about four bytes a token.
+func estimateTokens(text string) int {
+	return len(text) / 4
+}
+
+// inspectContext gathers the context for the next turn of sessionID.
+// carryOver is what /compact or /model left to go out with the next prompt.
+func inspectContext(cfg *config.Config, sessionID, carryOver string) (*contextReport, error) {
+	report := &contextReport{SessionID: sessionID, Model: modelLabel(cfg)}
+	add := func(name, source, text string) {
+		report.Sections = append(report.Sections, contextSection{Name: name, Source: source, Tokens: estimateTokens(text), Text: text})
+	}
+
+	ctx := prompt.WithVars(context.Background(), cliPromptVars())
+	for _, section := range systemPromptSections(cfg, memory.NewMemoryStore(cfg.MemoryWorkspace())) {
+		// Templates are shown as a CLI run would render them now.
+		if slices.Contains(prompt.Paths(cfg), section.source) {
+			section.text, section.source = renderSection(ctx, cfg, section)
+		}
+		add(section.name, section.source, section.text)
+	}
+	if cfg.Memory.Semantic {
+		add("Memory", fmt.Sprintf("semantic recall, top %d chunks per message", cfg.Memory.TopK), "")
+	}
+	// The runtime appends CLAUDE.md from the workspace on its own.
+	if claudeMD, err := sdkconfig.LoadClaudeMD(cfg.Agent.Workspace, sdkconfig.NewFS(cfg.Agent.Workspace, nil)); err == nil && strings.TrimSpace(claudeMD) != "" {
+		add("CLAUDE.md", filepath.Join(cfg.Agent.Workspace, "CLAUDE.md"), "\n\n## Memory\n\n"+strings.TrimSpace(claudeMD))
+	}
+
+	if regs := loadRuntimeSkills(cfg); len(regs) > 0 {
+		var sb strings.Builder
+		for _, reg := range regs {
+			fmt.Fprintf(&sb, "- %s: %s\n", reg.Definition.Name, strings.TrimSpace(reg.Definition.Description))
+		}
+		add(fmt.Sprintf("Skills (%d)", len(regs)), "Skill tool description", sb.String())
+	}
+	if carryOver != "" {
+		add("Carried over", "/compact or /model, sent with the next prompt", carryOver)
+	}
+
+	sess, err := session.NewStore(cfg.Agent.Workspace).Get(sessionID)
+	if err != nil && !errors.Is(err, session.ErrNotFound) {
+		return nil, err
+	}
+	if sess != nil {
+		report.History = sess
+		var counter message.NaiveCounter
+		for _, msg := range sess.Messages {
+			report.HistoryTokens += counter.Count(msg)
+		}
+	}
+	return report, nil
+}
+
+// renderSection renders an instruction file that uses template actions,
+// noting a template error in its source.
+func renderSection(ctx context.Context, cfg *config.Config, section promptSection) (text, source string) {
+	tmpl, err := prompt.Parse(section.text, prompt.BaseVars(cfg))
+	if err == nil && tmpl != nil {
+		var rendered string
+		if rendered, err = tmpl.Render(ctx, time.Now()); err == nil {
+			return rendered + "\n\n", section.source + " (template)"
+		}
+	}
+	if err != nil {
+		return section.text, fmt.Sprintf("%s (%v)", section.source, err)
+	}
+	return section.text, section.source
+}
+
+// printContext writes the report as a table of sizes and, with full, the
+// text of each part after it.
+func printContext(w io.Writer, r *contextReport, full bool) error {
+	fmt.Fprintf(w, "Context for session %s (model %s)\n\n", r.SessionID, r.Model)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PART\tTOKENS\tSOURCE")
+	for _, s := range r.Sections {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", s.Name, s.Tokens, s.Source)
+	}
+	messages := 0
+	if r.History != nil {
+		messages = len(r.History.Messages)
+	}
+	fmt.Fprintf(tw, "History (%d messages)\t%d\tsession %s\n", messages, r.HistoryTokens, r.SessionID)
+	fmt.Fprintf(tw, "Total\t%d\t\n", r.total())
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "\nToken counts are estimates; tool definitions are not included.")
+
+	if !full {
+		return nil
+	}
+	for _, s := range r.Sections {
+		if strings.TrimSpace(s.Text) == "" {
+			continue
+		}
+		fmt.Fprintf(w, "\n--- %s ---\n%s\n", s.Name, strings.TrimSpace(s.Text))
+	}
+	if r.History != nil && len(r.History.Messages) > 0 {
+		fmt.Fprintf(w, "\n--- History ---\n")
+		for _, turn := range r.History.Turns() {
+			if turn.Content != "" {
+				fmt.Fprintf(w, "[%s] %s\n", turn.Role, turn.Content)
+			}
+			for _, call := range turn.Calls {
+				fmt.Fprintf(w, "[%s] tool %s (%d chars of result)\n", turn.Role, call.Name, len(call.Result))
+			}
+		}
+	}
+	return nil
+}
+
+func runContext(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	sessionID, _ := cmd.Flags().GetString("session")
+	full, _ := cmd.Flags().GetBool("full")
+	report, err := inspectContext(cfg, sessionID, "")
+	if err != nil {
+		return err
+	}
+
+	if !readJSONFlag(cmd) {
+		return printContext(os.Stdout, report, full)
+	}
+	sections := report.Sections
+	if !full {
+		sections = make([]contextSection, len(report.Sections))
+		for i, s := range report.Sections {
+			s.Text = ""
+			sections[i] = s
+		}
+	}
+	history := map[string]any{"messages": 0, "tokens": report.HistoryTokens}
+	if report.History != nil {
+		history["messages"] = len(report.History.Messages)
+		if full {
+			history["turns"] = report.History.Turns()
+		}
+	}
+	return printJSON(map[string]any{
+		"schemaVersion": contextJSONSchemaVersion,
+		"command":       "context",
+		"ok":            true,
+		"sessionId":     report.SessionID,
+		"model":         report.Model,
+		"sections":      sections,
+		"history":       history,
+		"totalTokens":   report.total(),
+	})
+}
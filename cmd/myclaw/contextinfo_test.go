@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+func TestInspectContext(t *testing.T) {
+	s, stdout, _ := newTestREPLSession(t, &mockRuntime{})
+	ws := s.cfg.Agent.Workspace
+	if err := os.WriteFile(filepath.Join(ws, "AGENTS.md"), []byte("# Agent\n\nBe brief."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := session.HistoryDir(ws)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	body := `{"version":1,"session_id":"cli-repl","messages":[{"Role":"user","Content":"what is in the notes folder?"},{"Role":"assistant","Content":"Three files."}]}`
+	if err := os.WriteFile(filepath.Join(dir, session.FileName(replSessionID)), []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := inspectContext(s.cfg, replSessionID, "")
+	if err != nil {
+		t.Fatalf("inspectContext error: %v", err)
+	}
+	if len(report.Sections) == 0 || report.Sections[0].Name != "AGENTS.md" || report.Sections[0].Tokens == 0 {
+		t.Errorf("sections = %+v", report.Sections)
+	}
+	if report.History == nil || len(report.History.Messages) != 2 || report.HistoryTokens == 0 {
+		t.Errorf("history = %+v, %d tokens", report.History, report.HistoryTokens)
+	}
+	var joined strings.Builder
+	for _, sec := range report.Sections {
+		joined.WriteString(sec.Text)
+	}
+	if !strings.HasPrefix(joined.String(), "# Agent\n\nBe brief.") {
+		t.Errorf("sections should carry the prompt text as sent: %q", joined.String())
+	}
+
+	s.carryOver = "We talked about notes."
+	if !s.handleSlash(context.Background(), "/context full") {
+		t.Fatal("/context should be handled")
+	}
+	for _, want := range []string{"AGENTS.md", "Carried over", "History (2 messages)", "Total", "--- AGENTS.md ---", "[user] what is in the notes folder?"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("/context output missing %q:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestInspectContext_Project(t *testing.T) {
+	s, _, _ := newTestREPLSession(t, &mockRuntime{})
+	ws := s.cfg.Agent.Workspace
+	project := filepath.Join(t.TempDir(), config.ProjectDirName)
+	if err := os.MkdirAll(filepath.Join(project, "memory"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(ws, "AGENTS.md"):                "# Agent",
+		filepath.Join(ws, "memory", "MEMORY.md"):      "global note",
+		filepath.Join(project, "AGENTS.md"):           "Use tabs in this repo.",
+		filepath.Join(project, "memory", "MEMORY.md"): "project note",
+	}
+	for path, body := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.cfg.Project = project
+
+	report, err := inspectContext(s.cfg, "never-used", "")
+	if err != nil {
+		t.Fatalf("inspectContext error: %v", err)
+	}
+	var names []string
+	var joined strings.Builder
+	for _, sec := range report.Sections {
+		names = append(names, sec.Name)
+		joined.WriteString(sec.Text)
+	}
+	if len(names) < 3 || names[0] != "AGENTS.md" || names[1] != "project AGENTS.md" || names[2] != "Memory" {
+		t.Errorf("sections = %v, want the project's AGENTS.md after the workspace's", names)
+	}
+	if text := joined.String(); !strings.Contains(text, "project note") || strings.Contains(text, "global note") {
+		t.Errorf("project memory should replace the workspace's: %q", text)
+	}
+}
+
+func TestInspectContext_NewSession(t *testing.T) {
+	s, _, _ := newTestREPLSession(t, &mockRuntime{})
+	report, err := inspectContext(s.cfg, "never-used", "")
+	if err != nil {
+		t.Fatalf("inspectContext error: %v", err)
+	}
+	if report.History != nil || report.HistoryTokens != 0 {
+		t.Errorf("a new session should have no history: %+v", report)
+	}
+}
+
+func TestRunContext_JSON(t *testing.T) {
+	seedSession(t)
+	cmd := &cobra.Command{}
+	cmd.Flags().String("session", "telegram:42", "")
+	cmd.Flags().Bool("full", false, "")
+	cmd.Flags().Bool("json", true, "")
+
+	output, err := captureRunOutput(t, func() error { return runContext(cmd, nil) })
+	if err != nil {
+		t.Fatalf("runContext error: %v", err)
+	}
+	var payload struct {
+		Command     string         `json:"command"`
+		SessionID   string         `json:"sessionId"`
+		History     map[string]any `json:"history"`
+		TotalTokens int            `json:"totalTokens"`
+	}
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, output)
+	}
+	if payload.Command != "context" || payload.SessionID != "telegram:42" || payload.History["messages"] != float64(2) || payload.TotalTokens == 0 {
+		t.Errorf("payload = %+v", payload)
+	}
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/cron"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+const cronJSONSchemaVersion = 1
+
+var cronCmd = &cobra.Command{
+	Use:   "cron",
+	Short: "Manage scheduled jobs",
+}
+
+var cronListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled jobs",
+	Args:  cobra.NoArgs,
+	RunE:  runCronList,
+}
+
+var cronAddCmd = &cobra.Command{
+	Use:   "add <request>",
+	Short: "Schedule a prompt described in plain language",
+	Long: `Schedule a prompt described in plain language, for example:
+
+  myclaw cron add "every weekday at 9am summarize my inbox"
+  myclaw cron add "remind me to stretch every 2 hours"
+  myclaw cron add "tomorrow at 8:30 check the train times"
+
+Common schedules are understood directly. Anything else is handed to the
+model, which turns it into a cron expression. The parsed schedule is shown
+for confirmation before the job is saved. A running gateway picks the job up
+as soon as it is saved.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runCronAdd,
+}
+
+var cronRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Remove a scheduled job",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runCronRemove,
+}
+
+var cronHistoryCmd = &cobra.Command{
+	Use:   "history [id|name]",
+	Short: "Show past runs of a job, or of all jobs",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runCronHistory,
+}
+
+func init() {
+	cronListCmd.Flags().Bool("json", false, "Output as JSON")
+	cronHistoryCmd.Flags().IntP("limit", "n", 20, "Maximum runs to show")
+	cronHistoryCmd.Flags().Bool("json", false, "Output as JSON")
+	cronAddCmd.Flags().String("name", "", "Job name (default: start of the prompt)")
+	cronAddCmd.Flags().StringArray("deliver", nil, "Send the output to channel:to, webhook:URL, file:path or notify:name (repeatable)")
+	cronAddCmd.Flags().String("template", "", "Format of delivered output; {{title}}, {{timestamp}}, {{result}} and {{job}} are replaced")
+	cronAddCmd.Flags().Bool("no-cache", false, "Always run the prompt, even with responseCache enabled")
+	cronAddCmd.Flags().BoolP("yes", "y", false, "Save without asking for confirmation")
+	cronAddCmd.Flags().Bool("json", false, "Output as JSON")
+	cronRemoveCmd.Flags().Bool("json", false, "Output as JSON")
+	cronCmd.AddCommand(cronListCmd, cronAddCmd, cronRemoveCmd, cronHistoryCmd)
+	rootCmd.AddCommand(cronCmd)
+}
+
+// cronStorePath is where the gateway keeps its jobs.
+func cronStorePath() string {
+	return filepath.Join(config.DataDir(), "data", "cron", "jobs.json")
+}
+
+func openCronService() (*cron.Service, error) {
+	svc := cron.NewService(cronStorePath())
+	if err := svc.Load(); err != nil {
+		return nil, fmt.Errorf("load cron jobs: %w", err)
+	}
+	return svc, nil
+}
+
+// dailyJobFlags adds the flags addDailyJob reads to cmd, which schedules a
+// daily what at the time at by default.
+func dailyJobFlags(cmd *cobra.Command, at, what string) {
+	cmd.Flags().String("at", at, "Time of day to send the "+what)
+	cmd.Flags().Bool("weekdays", false, "Only on weekdays")
+	cmd.Flags().StringArray("deliver", nil, "Send the "+what+" to channel:to, webhook:URL, file:path or notify:name (repeatable)")
+	cmd.Flags().Bool("json", false, "Output as JSON")
+}
+
+// addDailyJob adds a cron job that runs prompt every day, or every weekday,
+// at the time of --at and sends the output to the --deliver targets, then
+// reports it as command.
+func addDailyJob(cmd *cobra.Command, name, what, prompt, command string) error {
+	specs, _ := cmd.Flags().GetStringArray("deliver")
+	if len(specs) == 0 {
+		return fmt.Errorf("give --deliver, e.g. --deliver telegram:123456789, so the %s goes somewhere", what)
+	}
+	var targets []cron.Target
+	for _, spec := range specs {
+		target, err := cron.ParseTarget(spec)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+	}
+	at, _ := cmd.Flags().GetString("at")
+	days := "every day"
+	if weekdays, _ := cmd.Flags().GetBool("weekdays"); weekdays {
+		days = "every weekday"
+	}
+	sched, rest, err := cron.ParseNatural(days+" at "+at+" job", time.Now())
+	if err == nil && (sched.Kind != "cron" || rest != "job") {
+		err = errors.New("want a time of day such as 7am or 07:30")
+	}
+	if err != nil {
+		return fmt.Errorf("--at %q: %w", at, err)
+	}
+
+	svc, err := openCronService()
+	if err != nil {
+		return err
+	}
+	// The prompt is the same every day but the data its tools fetch is
+	// not, so a cached answer would be stale.
+	job, err := svc.AddJob(name, sched, cron.Payload{Message: prompt, Targets: targets, NoCache: true})
+	if err != nil {
+		return err
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": cronJSONSchemaVersion,
+			"command":       command,
+			"ok":            true,
+			"description":   sched.Describe(),
+			"job":           job,
+		})
+	}
+	fmt.Printf("Added job %s: %s %s.\n", job.ID, what, sched.Describe())
+	for _, target := range targets {
+		fmt.Printf("Deliver: %s\n", target)
+	}
+	return nil
+}
+
+func runCronList(cmd *cobra.Command, args []string) error {
+	svc, err := openCronService()
+	if err != nil {
+		return err
+	}
+	jobs := svc.ListJobs()
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": cronJSONSchemaVersion,
+			"command":       "cron.list",
+			"ok":            true,
+			"jobs":          jobs,
+		})
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No scheduled jobs.")
+		return nil
+	}
+	for _, job := range jobs {
+		status := ""
+		if !job.Enabled {
+			status = " (disabled)"
+		}
+		if job.Payload.NoCache {
+			status += " (no cache)"
+		}
+		fmt.Printf("%s  %s%s\n    %s: %s\n", job.ID, job.Name, status, job.Schedule.Describe(), job.Payload.Message)
+		for _, target := range job.Targets() {
+			fmt.Printf("    deliver to %s\n", target)
+		}
+		if job.State.LastRunAtMs > 0 {
+			last := fmt.Sprintf("    last run %s: %s", time.UnixMilli(job.State.LastRunAtMs).Format("2006-01-02 15:04"), job.State.LastStatus)
+			if job.State.LastError != "" {
+				last += " (" + job.State.LastError + ")"
+			}
+			fmt.Println(last)
+		}
+	}
+	return nil
+}
+
+func runCronHistory(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	id := ""
+	if len(args) == 1 {
+		id = args[0]
+		// Accept a job name as well; runs of deleted jobs are found by ID.
+		if svc, err := openCronService(); err == nil {
+			for _, job := range svc.ListJobs() {
+				if job.Name == id {
+					id = job.ID
+					break
+				}
+			}
+		}
+	}
+	limit, _ := cmd.Flags().GetInt("limit")
+	runs, err := cron.NewHistory(cron.HistoryPath(cfg.Agent.Workspace)).Runs(id, limit)
+	if err != nil {
+		return fmt.Errorf("read cron history: %w", err)
+	}
+	if runs == nil {
+		runs = []cron.Run{}
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": cronJSONSchemaVersion,
+			"command":       "cron.history",
+			"ok":            true,
+			"id":            id,
+			"runs":          runs,
+		})
+	}
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded.")
+		return nil
+	}
+	for _, run := range runs {
+		line := fmt.Sprintf("%s  %-5s  %6s", run.StartedAt.Local().Format("2006-01-02 15:04:05"), run.Status,
+			(time.Duration(run.DurationMs) * time.Millisecond).Round(100*time.Millisecond))
+		if run.Cached {
+			line += "  cached"
+		} else if run.InputTokens+run.OutputTokens > 0 {
+			line += fmt.Sprintf("  %d in/%d out tokens", run.InputTokens, run.OutputTokens)
+		}
+		if id == "" {
+			line += "  " + run.JobName
+		}
+		fmt.Println(line)
+		detail := run.Output
+		if run.Error != "" {
+			detail = "error: " + run.Error
+		}
+		if detail = strings.Join(strings.Fields(detail), " "); detail != "" {
+			fmt.Printf("    %s\n", clipLine(detail, 100))
+		}
+	}
+	return nil
+}
+
+func runCronAdd(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	return runCronAddWithOptions(cmd, args, cfg, AgentOptions{RuntimeFactory: DefaultRuntimeFactory})
+}
+
+func runCronAddWithOptions(cmd *cobra.Command, args []string, cfg *config.Config, opts AgentOptions) error {
+	request := strings.Join(args, " ")
+	now := time.Now()
+	jsonOutput := readJSONFlag(cmd)
+	yes, _ := cmd.Flags().GetBool("yes")
+	if jsonOutput && !yes {
+		return fmt.Errorf("refusing to add a job without --yes")
+	}
+
+	specs, _ := cmd.Flags().GetStringArray("deliver")
+	tmpl, _ := cmd.Flags().GetString("template")
+	var targets []cron.Target
+	for _, spec := range specs {
+		target, err := cron.ParseTarget(spec)
+		if err != nil {
+			return err
+		}
+		target.Template = tmpl
+		targets = append(targets, target)
+	}
+
+	sched, prompt, err := cron.ParseNatural(request, now)
+	parsedBy := "rules"
+	if errors.Is(err, cron.ErrUnrecognized) {
+		sched, prompt, err = parseScheduleWithModel(cfg, opts, request, now)
+		parsedBy = "model"
+	}
+	if err != nil {
+		return fmt.Errorf("parse schedule: %w", err)
+	}
+	if err := sched.Validate(now); err != nil {
+		return fmt.Errorf("parse schedule: %w", err)
+	}
+	if prompt == "" {
+		return fmt.Errorf("parse schedule: no prompt left after the schedule in %q", request)
+	}
+	name, _ := cmd.Flags().GetString("name")
+	if name == "" {
+		name = clipLine(prompt, 40)
+	}
+
+	if !jsonOutput {
+		fmt.Printf("Schedule: %s%s\n", sched.Describe(), scheduleDetail(sched))
+		fmt.Printf("Prompt:   %s\n", prompt)
+		for _, target := range targets {
+			fmt.Printf("Deliver:  %s\n", target)
+		}
+	}
+	if !yes {
+		fmt.Print("Save this job? [y/N] ")
+		if !confirm(cmd.InOrStdin()) {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	svc, err := openCronService()
+	if err != nil {
+		return err
+	}
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	job, err := svc.AddJob(name, sched, cron.Payload{Message: prompt, Targets: targets, NoCache: noCache})
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON(map[string]any{
+			"schemaVersion": cronJSONSchemaVersion,
+			"command":       "cron.add",
+			"ok":            true,
+			"parsedBy":      parsedBy,
+			"description":   sched.Describe(),
+			"job":           job,
+		})
+	}
+	fmt.Printf("Added job %s (%s).\n", job.ID, job.Name)
+	return nil
+}
+
+func runCronRemove(cmd *cobra.Command, args []string) error {
+	svc, err := openCronService()
+	if err != nil {
+		return err
+	}
+	if !svc.RemoveJob(args[0]) {
+		return fmt.Errorf("job %s not found", args[0])
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": cronJSONSchemaVersion,
+			"command":       "cron.remove",
+			"ok":            true,
+			"id":            args[0],
+		})
+	}
+	fmt.Printf("Removed job %s.\n", args[0])
+	return nil
+}
+
+// scheduleDetail shows the underlying cron expression, when there is one,
+// so it can be checked against the description.
+func scheduleDetail(s cron.Schedule) string {
+	if s.Kind == "cron" && !strings.HasPrefix(s.Describe(), "cron ") {
+		return " (cron " + s.Expr + ")"
+	}
+	return ""
+}
+
+const cronParseSessionID = "cron-parse"
+
+const cronParsePrompt = `Turn this scheduling request into a job. The current time is %s.
+
+Reply with only a JSON object, no prose:
+{"kind": "cron", "expr": "...", "everyMinutes": 0, "at": "", "prompt": "..."}
+
+- kind "cron": expr is a cron expression with six fields, seconds first,
+  for example "0 0 9 * * 1-5" for weekdays at 09:00.
+- kind "every": everyMinutes is the interval in minutes.
+- kind "at": a single run; at is an RFC 3339 time in the same time zone.
+- prompt is the task to perform when the job runs, without the schedule.
+If the request has no schedule, reply {"error": "why"}.
+
+Request: %s`
+
+// parseScheduleWithModel asks the model to turn a request the rules do not
+// understand into a schedule.
+func parseScheduleWithModel(cfg *config.Config, opts AgentOptions, request string, now time.Time) (cron.Schedule, string, error) {
+	runCfg := *cfg
+	runCfg.Memory.Semantic = false
+	runCfg.Memory.AutoExtract = false
+	rt, err := opts.RuntimeFactory(&runCfg)
+	if err != nil {
+		return cron.Schedule{}, "", err
+	}
+	defer rt.Close()
+	defer session.NewStore(cfg.Agent.Workspace).Delete(cronParseSessionID)
+
+	resp, err := rt.Run(context.Background(), api.Request{
+		Prompt:    fmt.Sprintf(cronParsePrompt, now.Format(time.RFC3339+" Monday"), request),
+		SessionID: cronParseSessionID,
+	})
+	if err != nil {
+		return cron.Schedule{}, "", err
+	}
+	output := ""
+	if resp != nil && resp.Result != nil {
+		output = stripCodeFence(resp.Result.Output)
+	}
+	var reply struct {
+		Kind         string  `json:"kind"`
+		Expr         string  `json:"expr"`
+		EveryMinutes float64 `json:"everyMinutes"`
+		At           string  `json:"at"`
+		Prompt       string  `json:"prompt"`
+		Error        string  `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(output), &reply); err != nil {
+		return cron.Schedule{}, "", fmt.Errorf("could not understand %q", request)
+	}
+	if reply.Error != "" {
+		return cron.Schedule{}, "", errors.New(reply.Error)
+	}
+	sched := cron.Schedule{Kind: reply.Kind, Expr: strings.TrimSpace(reply.Expr)}
+	switch reply.Kind {
+	case "every":
+		sched.EveryMs = int64(reply.EveryMinutes * float64(time.Minute/time.Millisecond))
+	case "at":
+		at, err := time.Parse(time.RFC3339, reply.At)
+		if err != nil {
+			return cron.Schedule{}, "", fmt.Errorf("invalid time %q", reply.At)
+		}
+		sched.AtMs = at.UnixMilli()
+	}
+	return sched, strings.TrimSpace(reply.Prompt), nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/cron"
+)
+
+func cronCommand(flags map[string]string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", false, "")
+	cmd.Flags().Bool("yes", false, "")
+	cmd.Flags().String("name", "", "")
+	cmd.Flags().Int("limit", 20, "")
+	cmd.Flags().StringArray("deliver", nil, "")
+	cmd.Flags().String("template", "", "")
+	cmd.Flags().Bool("no-cache", false, "")
+	for k, v := range flags {
+		_ = cmd.Flags().Set(k, v)
+	}
+	return cmd
+}
+
+// dailyJobCommand returns a command with the flags of dailyJobFlags set.
+func dailyJobCommand(flags map[string]string, deliver ...string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", false, "")
+	cmd.Flags().String("at", "8am", "")
+	cmd.Flags().Bool("weekdays", false, "")
+	cmd.Flags().StringArray("deliver", nil, "")
+	for k, v := range flags {
+		_ = cmd.Flags().Set(k, v)
+	}
+	for _, d := range deliver {
+		_ = cmd.Flags().Set("deliver", d)
+	}
+	return cmd
+}
+
+func TestRunCronAdd(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	noModel := AgentOptions{RuntimeFactory: func(*config.Config) (Runtime, error) {
+		t.Fatal("the model should not be asked")
+		return nil, nil
+	}}
+
+	cmd := cronCommand(nil)
+	cmd.SetIn(strings.NewReader("n\n"))
+	output, err := captureRunOutput(t, func() error {
+		return runCronAddWithOptions(cmd, []string{"every weekday at 9am summarize my inbox"}, cfg, noModel)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "Schedule: weekdays at 09:00 (cron 0 0 9 * * 1-5)") || !strings.Contains(output, "Aborted.") {
+		t.Errorf("output = %q", output)
+	}
+	svc, _ := openCronService()
+	if len(svc.ListJobs()) != 0 {
+		t.Fatal("job saved without confirmation")
+	}
+
+	cmd = cronCommand(nil)
+	cmd.SetIn(strings.NewReader("y\n"))
+	if _, err := captureRunOutput(t, func() error {
+		return runCronAddWithOptions(cmd, []string{"every weekday at 9am summarize my inbox"}, cfg, noModel)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	svc, _ = openCronService()
+	jobs := svc.ListJobs()
+	if len(jobs) != 1 || jobs[0].Schedule.Expr != "0 0 9 * * 1-5" || jobs[0].Payload.Message != "summarize my inbox" || jobs[0].Name != "summarize my inbox" {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+
+	output, _ = captureRunOutput(t, func() error { return runCronList(cronCommand(nil), nil) })
+	if !strings.Contains(output, jobs[0].ID) || !strings.Contains(output, "weekdays at 09:00: summarize my inbox") {
+		t.Errorf("list output = %q", output)
+	}
+	if _, err := captureRunOutput(t, func() error { return runCronRemove(cronCommand(nil), []string{jobs[0].ID}) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCronRemove(cronCommand(nil), []string{"missing"}); err == nil {
+		t.Error("expected error removing a missing job")
+	}
+}
+
+func TestRunCronAdd_Model(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = t.TempDir()
+	rt := &mockRuntime{response: &api.Response{Result: &api.Result{
+		Output: "```json\n{\"kind\": \"cron\", \"expr\": \"0 0 17 * * 5\", \"prompt\": \"write the weekly report\"}\n```",
+	}}}
+
+	output, err := captureRunOutput(t, func() error {
+		return runCronAddWithOptions(cronCommand(map[string]string{"json": "true", "yes": "true", "name": "report"}),
+			[]string{"when the work week wraps up, write the weekly report"}, cfg, AgentOptions{RuntimeFactory: mockRuntimeFactory(rt)})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, `"parsedBy": "model"`) || !strings.Contains(output, `"description": "Fridays at 17:00"`) || !rt.closed {
+		t.Errorf("output = %s", output)
+	}
+	svc, _ := openCronService()
+	if jobs := svc.ListJobs(); len(jobs) != 1 || jobs[0].Name != "report" || jobs[0].Payload.Message != "write the weekly report" {
+		t.Errorf("jobs = %+v", jobs)
+	}
+
+	// An invalid expression from the model is rejected.
+	rt.response = &api.Response{Result: &api.Result{Output: `{"kind": "cron", "expr": "0 17 * * 5", "prompt": "x"}`}}
+	if err := runCronAddWithOptions(cronCommand(map[string]string{"json": "true", "yes": "true"}),
+		[]string{"sometime soon"}, cfg, AgentOptions{RuntimeFactory: mockRuntimeFactory(rt)}); err == nil {
+		t.Error("expected error for an invalid expression")
+	}
+	if err := runCronAddWithOptions(cronCommand(map[string]string{"json": "true"}),
+		[]string{"every day at 9am x"}, cfg, AgentOptions{}); err == nil {
+		t.Error("--json without --yes should fail instead of prompting")
+	}
+}
+
+func TestRunCronHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, _ := openCronService()
+	job, _ := svc.AddJob("nightly", cron.Schedule{Kind: "cron", Expr: "0 0 2 * * *"}, cron.Payload{Message: "back up"})
+	history := cron.NewHistory(cron.HistoryPath(cfg.Agent.Workspace))
+	history.Append(cron.Run{JobID: job.ID, JobName: "nightly", StartedAt: time.Now().Add(-time.Hour), DurationMs: 2300, Status: "ok", InputTokens: 900, OutputTokens: 40, Output: "Backed up 3 files."})
+	history.Append(cron.Run{JobID: job.ID, JobName: "nightly", StartedAt: time.Now().Add(-time.Minute), Status: "ok", Output: "Backed up 3 files.", Cached: true})
+	history.Append(cron.Run{JobID: job.ID, JobName: "nightly", StartedAt: time.Now(), DurationMs: 100, Status: "error", Error: "disk full"})
+	history.Append(cron.Run{JobID: "other", JobName: "other", StartedAt: time.Now(), Status: "ok"})
+
+	output, err := captureRunOutput(t, func() error { return runCronHistory(cronCommand(nil), []string{"nightly"}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "error: disk full") || !strings.Contains(output, "900 in/40 out tokens") || !strings.Contains(output, "cached") ||
+		!strings.Contains(output, "Backed up 3 files.") || strings.Index(output, "disk full") > strings.Index(output, "Backed up") {
+		t.Errorf("output = %q", output)
+	}
+
+	output, _ = captureRunOutput(t, func() error {
+		return runCronHistory(cronCommand(map[string]string{"json": "true", "limit": "1"}), []string{job.ID})
+	})
+	var got struct {
+		Runs []cron.Run `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(output), &got); err != nil || len(got.Runs) != 1 || got.Runs[0].Status != "error" {
+		t.Errorf("json output = %s (%v)", output, err)
+	}
+}
+
+func TestRunCronAdd_Deliver(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+
+	cmd := cronCommand(map[string]string{"yes": "true", "template": "{{title}}: {{result}}", "no-cache": "true"})
+	cmd.Flags().Set("deliver", "telegram:12345")
+	cmd.Flags().Set("deliver", "file:reports/inbox.md")
+	output, err := captureRunOutput(t, func() error {
+		return runCronAddWithOptions(cmd, []string{"every day at 7am summarize my inbox"}, cfg, AgentOptions{})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "Deliver:  telegram:12345") || !strings.Contains(output, "Deliver:  file:reports/inbox.md") {
+		t.Errorf("output = %q", output)
+	}
+	svc, _ := openCronService()
+	if !svc.ListJobs()[0].Payload.NoCache {
+		t.Error("--no-cache not saved")
+	}
+	targets := svc.ListJobs()[0].Payload.Targets
+	if len(targets) != 2 || targets[0].Channel != "telegram" || targets[0].To != "12345" || targets[1].File != "reports/inbox.md" || targets[1].Template != "{{title}}: {{result}}" {
+		t.Errorf("targets = %+v", targets)
+	}
+
+	cmd = cronCommand(map[string]string{"yes": "true", "deliver": "file:../outside.md"})
+	if err := runCronAddWithOptions(cmd, []string{"every day at 7am x"}, cfg, AgentOptions{}); err == nil {
+		t.Error("expected error for a file outside the workspace")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/deadletter"
+)
+
+const deadLetterJSONSchemaVersion = 1
+
+var deadLetterCmd = &cobra.Command{
+	Use:   "deadletter",
+	Short: "Inspect and redeliver messages that failed to send",
+}
+
+var deadLetterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List dead-lettered messages",
+	RunE:  runDeadLetterList,
+}
+
+var deadLetterRetryCmd = &cobra.Command{
+	Use:   "retry [id...]",
+	Short: "Queue dead-lettered messages for redelivery by the gateway",
+	RunE:  runDeadLetterRetry,
+}
+
+var deadLetterPurgeCmd = &cobra.Command{
+	Use:   "purge [id...]",
+	Short: "Delete dead-lettered messages",
+	RunE:  runDeadLetterPurge,
+}
+
+func init() {
+	deadLetterListCmd.Flags().Bool("json", false, "Output as JSON")
+	deadLetterRetryCmd.Flags().Bool("all", false, "Retry every dead letter")
+	deadLetterPurgeCmd.Flags().Bool("all", false, "Purge every dead letter")
+	deadLetterCmd.AddCommand(deadLetterListCmd, deadLetterRetryCmd, deadLetterPurgeCmd)
+	rootCmd.AddCommand(deadLetterCmd)
+}
+
+func runDeadLetterList(cmd *cobra.Command, args []string) error {
+	store := deadletter.NewStore(deadletter.DefaultStorePath())
+	entries, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	if readJSONFlag(cmd) {
+		if entries == nil {
+			entries = []deadletter.Entry{}
+		}
+		return printJSON(map[string]any{
+			"schemaVersion": deadLetterJSONSchemaVersion,
+			"command":       "deadletter.list",
+			"ok":            true,
+			"count":         len(entries),
+			"entries":       entries,
+		})
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No dead letters.")
+		return nil
+	}
+	for _, entry := range entries {
+		failedAt := time.UnixMilli(entry.FailedAtMs).Format(time.RFC3339)
+		status := ""
+		if entry.RetryRequested {
+			status = " [retry pending]"
+		}
+		fmt.Printf("- %s %s/%s at %s (%d attempts)%s\n", entry.ID, entry.Channel, entry.ChatID, failedAt, entry.Attempts, status)
+		fmt.Printf("  reason: %s\n", entry.Reason)
+		fmt.Printf("  content: %s\n", clipLine(entry.Content, 80))
+	}
+	return nil
+}
+
+func runDeadLetterRetry(cmd *cobra.Command, args []string) error {
+	if err := requireIDsOrAll(cmd, args); err != nil {
+		return err
+	}
+	n, err := deadletter.NewStore(deadletter.DefaultStorePath()).MarkRetry(args...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Queued %d message(s) for redelivery; the running gateway will resend them.\n", n)
+	return nil
+}
+
+func runDeadLetterPurge(cmd *cobra.Command, args []string) error {
+	if err := requireIDsOrAll(cmd, args); err != nil {
+		return err
+	}
+	n, err := deadletter.NewStore(deadletter.DefaultStorePath()).Purge(args...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Purged %d message(s).\n", n)
+	return nil
+}
+
+func requireIDsOrAll(cmd *cobra.Command, args []string) error {
+	all, _ := cmd.Flags().GetBool("all")
+	if len(args) == 0 && !all {
+		return fmt.Errorf("specify dead letter ids or --all")
+	}
+	if len(args) > 0 && all {
+		return fmt.Errorf("--all cannot be combined with ids")
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/deadletter"
+)
+
+func seedDeadLetters(t *testing.T) (*deadletter.Store, deadletter.Entry) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	store := deadletter.NewStore(deadletter.DefaultStorePath())
+	entry, err := store.Add(bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "lost reply"}, "bot was blocked", 3)
+	if err != nil {
+		t.Fatalf("seed dead letter: %v", err)
+	}
+	return store, entry
+}
+
+func buildAllCommand() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("all", false, "")
+	return cmd
+}
+
+func TestRunDeadLetterList(t *testing.T) {
+	_, entry := seedDeadLetters(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runDeadLetterList(&cobra.Command{}, nil)
+	})
+	if err != nil {
+		t.Fatalf("runDeadLetterList error: %v", err)
+	}
+	if !strings.Contains(output, entry.ID) || !strings.Contains(output, "bot was blocked") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
+
+func TestRunDeadLetterList_Empty(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	output, err := captureRunOutput(t, func() error {
+		return runDeadLetterList(&cobra.Command{}, nil)
+	})
+	if err != nil {
+		t.Fatalf("runDeadLetterList error: %v", err)
+	}
+	if !strings.Contains(output, "No dead letters.") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
+
+func TestRunDeadLetterList_JSON(t *testing.T) {
+	seedDeadLetters(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runDeadLetterList(buildJSONCommand(), nil)
+	})
+	if err != nil {
+		t.Fatalf("runDeadLetterList error: %v", err)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, output)
+	}
+	if payload["command"] != "deadletter.list" {
+		t.Errorf("command = %v, want deadletter.list", payload["command"])
+	}
+	if payload["schemaVersion"] != float64(deadLetterJSONSchemaVersion) {
+		t.Errorf("schemaVersion = %v", payload["schemaVersion"])
+	}
+	if payload["count"] != float64(1) {
+		t.Errorf("count = %v, want 1", payload["count"])
+	}
+}
+
+func TestRunDeadLetterRetry(t *testing.T) {
+	store, entry := seedDeadLetters(t)
+
+	_, err := captureRunOutput(t, func() error {
+		return runDeadLetterRetry(buildAllCommand(), []string{entry.ID})
+	})
+	if err != nil {
+		t.Fatalf("runDeadLetterRetry error: %v", err)
+	}
+	entries, _ := store.List()
+	if len(entries) != 1 || !entries[0].RetryRequested {
+		t.Errorf("entry should be flagged for retry: %+v", entries)
+	}
+}
+
+func TestRunDeadLetterRetry_RequiresTarget(t *testing.T) {
+	seedDeadLetters(t)
+
+	if err := runDeadLetterRetry(buildAllCommand(), nil); err == nil {
+		t.Error("expected error without ids or --all")
+	}
+}
+
+func TestRunDeadLetterPurge_All(t *testing.T) {
+	store, _ := seedDeadLetters(t)
+
+	cmd := buildAllCommand()
+	_ = cmd.Flags().Set("all", "true")
+	output, err := captureRunOutput(t, func() error {
+		return runDeadLetterPurge(cmd, nil)
+	})
+	if err != nil {
+		t.Fatalf("runDeadLetterPurge error: %v", err)
+	}
+	if !strings.Contains(output, "Purged 1") {
+		t.Errorf("unexpected output: %s", output)
+	}
+	entries, _ := store.List()
+	if len(entries) != 0 {
+		t.Errorf("len(entries) = %d, want 0", len(entries))
+	}
+
+	if err := runDeadLetterPurge(cmd, []string{"abc"}); err == nil {
+		t.Error("expected error combining --all with ids")
+	}
+}
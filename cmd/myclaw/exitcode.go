@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// Exit codes, so scripts can tell failures apart. A second Ctrl-C exits
+// with 130, as the shell would.
+const (
+	exitFailure  = 1 // anything else
+	exitProvider = 2 // the model provider failed the request
+	exitConfig   = 3 // the config is missing, invalid or incomplete
+	exitCanceled = 4 // the run was stopped with Ctrl-C
+)
+
+// codedError is an error that ends myclaw with a given exit code.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// withExitCode makes err end myclaw with code. It keeps nil as nil.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// exitCode returns the code myclaw exits with after err.
+func exitCode(err error) int {
+	var coded *codedError
+	switch {
+	case errors.As(err, &coded):
+		return coded.code
+	case errors.Is(err, errInterrupted), errors.Is(err, context.Canceled):
+		return exitCanceled
+	}
+	return exitFailure
+}
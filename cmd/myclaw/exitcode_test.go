@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("boom"), exitFailure},
+		{withExitCode(exitConfig, errors.New("no key")), exitConfig},
+		{fmt.Errorf("agent error: %w", withExitCode(exitProvider, errors.New("429"))), exitProvider},
+		{errInterrupted, exitCanceled},
+		{fmt.Errorf("run: %w", context.Canceled), exitCanceled},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+	if withExitCode(exitConfig, nil) != nil {
+		t.Error("withExitCode(nil) should stay nil")
+	}
+}
+
+func TestRunAgentWithOptions_ExitCodes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	oldMsg, oldJSON := messageFlag, agentJSONFlag
+	messageFlag, agentJSONFlag = "hi", false
+	t.Cleanup(func() { messageFlag, agentJSONFlag = oldMsg, oldJSON })
+
+	failing := func(cfg *config.Config) (Runtime, error) { return nil, errors.New("no API key") }
+	err := runAgentWithOptions(AgentOptions{RuntimeFactory: failing, Stdout: &bytes.Buffer{}})
+	if got := exitCode(err); got != exitConfig {
+		t.Errorf("runtime setup failure: exit %d (%v), want %d", got, err, exitConfig)
+	}
+
+	rt := &mockRuntime{err: errors.New("rate limited")}
+	err = runAgentWithOptions(AgentOptions{RuntimeFactory: mockRuntimeFactory(rt), Stdout: &bytes.Buffer{}})
+	if got := exitCode(err); got != exitProvider {
+		t.Errorf("provider failure: exit %d (%v), want %d", got, err, exitProvider)
+	}
+
+	agentJSONFlag = true
+	err = runAgentWithOptions(AgentOptions{RuntimeFactory: mockRuntimeFactory(rt), Stdout: &bytes.Buffer{}})
+	if !errors.Is(err, errAgentReported) || exitCode(err) != exitProvider {
+		t.Errorf("provider failure with --json: exit %d (%v), want %d", exitCode(err), err, exitProvider)
+	}
+}
+
+func TestRunAgentWithOptions_Quiet(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	oldMsg, oldQuiet := messageFlag, quietFlag
+	messageFlag, quietFlag = "", true
+	t.Cleanup(func() { messageFlag, quietFlag = oldMsg, oldQuiet })
+
+	rt := &mockRuntime{response: &api.Response{Result: &api.Result{Output: "the answer"}}}
+	var stdout, stderr bytes.Buffer
+	err := runAgentWithOptions(AgentOptions{
+		RuntimeFactory: mockRuntimeFactory(rt),
+		Stdin:          strings.NewReader("question\n/help\nexit\n"),
+		Stdout:         &stdout,
+		Stderr:         &stderr,
+	})
+	if err != nil {
+		t.Fatalf("runAgentWithOptions error: %v", err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "the answer" {
+		t.Errorf("stdout = %q, want only the answer", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "myclaw agent") || !strings.Contains(stderr.String(), "> ") {
+		t.Errorf("stderr should get the banner and prompts, got %q", stderr.String())
+	}
+}
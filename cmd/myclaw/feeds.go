@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/feeds"
+)
+
+const feedsJSONSchemaVersion = 1
+
+var feedsCmd = &cobra.Command{
+	Use:   "feeds",
+	Short: "Check the RSS and Atom feeds the gateway digests",
+}
+
+var feedsCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Show the new items the next digest would cover",
+	Long: `Fetch the configured feeds and list the items that are new and pass the
+filters, as the gateway's next digest would cover them. Nothing is marked
+seen unless --mark-seen is given, which skips these items in the digest, for
+example after adding a feed with a long history.`,
+	Args: cobra.NoArgs,
+	RunE: runFeedsCheck,
+}
+
+func init() {
+	feedsCheckCmd.Flags().Bool("mark-seen", false, "Mark the items seen so the digest skips them")
+	feedsCheckCmd.Flags().Bool("json", false, "Output as JSON")
+	feedsCmd.AddCommand(feedsCheckCmd)
+	rootCmd.AddCommand(feedsCmd)
+}
+
+func runFeedsCheck(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if len(cfg.Feeds.Feeds) == 0 {
+		return errors.New("no feeds configured; add them under feeds.feeds")
+	}
+	w := feeds.NewWatcher(cfg.Feeds, feeds.NewStore(feeds.Path(cfg.Agent.Workspace)))
+	updates, checkErr := w.Check(cmd.Context())
+	if checkErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", checkErr)
+	}
+	markSeen, _ := cmd.Flags().GetBool("mark-seen")
+	if markSeen {
+		if err := w.Commit(updates, time.Now()); err != nil {
+			return err
+		}
+	}
+	if updates == nil {
+		updates = []feeds.Update{}
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": feedsJSONSchemaVersion,
+			"command":       "feeds.check",
+			"ok":            checkErr == nil,
+			"markedSeen":    markSeen,
+			"feeds":         updates,
+		})
+	}
+	for _, u := range updates {
+		fmt.Printf("%s: %d new\n", u.Name, len(u.Items))
+		for _, it := range u.Items {
+			fmt.Printf("  - %s", it.Title)
+			if !it.Published.IsZero() {
+				fmt.Printf(" (%s)", it.Published.Local().Format("Mon 2 Jan 15:04"))
+			}
+			fmt.Println()
+			if it.Link != "" {
+				fmt.Printf("    %s\n", it.Link)
+			}
+		}
+	}
+	if markSeen {
+		fmt.Println("Marked seen; the next digest skips these items.")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRunFeedsCheck(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := runFeedsCheck(buildJSONCommand(), nil); err == nil || !strings.Contains(err.Error(), "feeds.feeds") {
+		t.Errorf("error without feeds = %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><title>Go Blog</title>
+<item><title>Go 1.30 is released</title><link>https://go.dev/blog/go1.30</link></item>
+</channel></rss>`))
+	}))
+	defer srv.Close()
+	cfg := fmt.Sprintf(`{"agent": {"workspace": %q}, "feeds": {"deliver": ["telegram:42"], "feeds": [{"url": %q}]}}`, filepath.Join(home, "ws"), srv.URL)
+	os.MkdirAll(filepath.Join(home, ".myclaw"), 0755)
+	if err := os.WriteFile(filepath.Join(home, ".myclaw", "config.json"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(markSeen bool) string {
+		cmd := &cobra.Command{}
+		cmd.SetContext(context.Background())
+		cmd.Flags().Bool("json", false, "")
+		cmd.Flags().Bool("mark-seen", markSeen, "")
+		output, err := captureRunOutput(t, func() error { return runFeedsCheck(cmd, nil) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output
+	}
+	if output := check(false); !strings.Contains(output, "Go Blog: 1 new") || !strings.Contains(output, "- Go 1.30 is released") {
+		t.Errorf("check = %q", output)
+	}
+	if output := check(true); !strings.Contains(output, "Marked seen") {
+		t.Errorf("check --mark-seen = %q", output)
+	}
+	if output := check(false); !strings.Contains(output, "Go Blog: 0 new") {
+		t.Errorf("check after --mark-seen = %q", output)
+	}
+}
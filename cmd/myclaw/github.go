@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// reviewsPrompt is what the review digest job asks the agent.
+const reviewsPrompt = "List the pull requests waiting for my review. Use github_search with " +
+	"\"is:open is:pr review-requested:@me\", and github_notifications with participating set for " +
+	"anything else that asks for me. Reply with a short list: repository and number, title, author, " +
+	"how long it has waited and the link, oldest first. If nothing waits, say so in one line."
+
+var githubCmd = &cobra.Command{
+	Use:   "github",
+	Short: "Schedule GitHub summaries",
+}
+
+var githubDigestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Send the pull requests waiting for your review every day",
+	Long: `Add a cron job that has the agent list the pull requests waiting for your
+review and sends the list to a chat, for example:
+
+  myclaw github digest --at 9am --weekdays --deliver telegram:123456789
+
+The GitHub tools must be enabled (github.enabled). The job runs in the
+gateway like any other; see myclaw cron list.`,
+	Args: cobra.NoArgs,
+	RunE: runGitHubDigest,
+}
+
+func init() {
+	dailyJobFlags(githubDigestCmd, "9am", "review digest")
+	githubCmd.AddCommand(githubDigestCmd)
+	rootCmd.AddCommand(githubCmd)
+}
+
+func runGitHubDigest(cmd *cobra.Command, args []string) error {
+	return addDailyJob(cmd, "Review digest", "review digest", reviewsPrompt, "github.digest")
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunGitHubDigest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := runGitHubDigest(dailyJobCommand(nil), nil); err == nil || !strings.Contains(err.Error(), "review digest goes somewhere") {
+		t.Errorf("digest without --deliver error = %v", err)
+	}
+	output, err := captureRunOutput(t, func() error {
+		return runGitHubDigest(dailyJobCommand(map[string]string{"at": "9am"}, "slack:C123"), nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "review digest every day at 09:00") || !strings.Contains(output, "Deliver: slack:C123") {
+		t.Errorf("output = %q", output)
+	}
+	svc, _ := openCronService()
+	jobs := svc.ListJobs()
+	if len(jobs) != 1 || jobs[0].Name != "Review digest" || jobs[0].Schedule.Expr != "0 0 9 * * *" || jobs[0].Payload.Message != reviewsPrompt {
+		t.Errorf("jobs = %+v", jobs)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/cron"
+	"github.com/stellarlinkco/myclaw/internal/templates"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init [template|git-url]",
+	Short: "Initialize config and workspace from a template",
+	Long: `Initialize config and workspace from a template.
+
+Built-in templates: personal-assistant, dev-helper, family-bot. A git URL
+(optionally suffixed with #subdir) fetches a template from a repository.
+Example automations are added to the cron store disabled; set "enabled" to
+true in ~/.myclaw/data/cron/jobs.json once you have reviewed them.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInit,
+}
+
+func init() {
+	initCmd.Flags().Bool("list", false, "List built-in templates")
+	initCmd.Flags().Bool("force", false, "Overwrite existing workspace files")
+	onboardCmd.Flags().String("template", "", "Workspace template name or git URL")
+	onboardCmd.Flags().Bool("force", false, "Overwrite existing workspace files with the template's")
+	rootCmd.AddCommand(initCmd)
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	if list, _ := cmd.Flags().GetBool("list"); list || len(args) == 0 {
+		fmt.Println("Templates:")
+		for _, info := range templates.Builtin() {
+			fmt.Printf("- %s: %s\n", info.Name, info.Description)
+		}
+		if !list {
+			fmt.Println("\nUsage: myclaw init <template|git-url>")
+		}
+		return nil
+	}
+	return runOnboard(cmd, args)
+}
+
+// readTemplateFlag returns the template requested on the command line: the
+// init argument or onboard's --template flag.
+func readTemplateFlag(cmd *cobra.Command, args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	if cmd == nil {
+		return ""
+	}
+	ref, _ := cmd.Flags().GetString("template")
+	return ref
+}
+
+func loadTemplate(ref string) (*templates.Template, func(), error) {
+	if !templates.IsGitURL(ref) {
+		tmpl, err := templates.Load(ref)
+		return tmpl, func() {}, err
+	}
+	dir, err := os.MkdirTemp("", "myclaw-template-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	tmpl, err := templates.Clone(ref, filepath.Join(dir, "repo"))
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return tmpl, cleanup, nil
+}
+
+// applyTemplate copies a template into the configured workspace and adds its
+// example automations to the cron store, disabled.
+func applyTemplate(cfg *config.Config, ref string, force bool) error {
+	tmpl, cleanup, err := loadTemplate(ref)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	fmt.Printf("Applying template: %s\n", tmpl.Name)
+	written, err := tmpl.Apply(cfg.Agent.Workspace, resolveSkillsDir(cfg), force)
+	for _, path := range written {
+		fmt.Printf("  Created: %s\n", path)
+	}
+	if err != nil {
+		return fmt.Errorf("apply template: %w", err)
+	}
+
+	automations, err := tmpl.Automations()
+	if err != nil {
+		return err
+	}
+	if len(automations) == 0 {
+		return nil
+	}
+
+	svc, err := openCronService()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, job := range svc.ListJobs() {
+		existing[job.Name] = true
+	}
+	for _, auto := range automations {
+		if existing[auto.Name] {
+			continue
+		}
+		job, err := svc.AddJob(auto.Name, auto.Schedule, cron.Payload{Message: auto.Message})
+		if err != nil {
+			return fmt.Errorf("add automation %s: %w", auto.Name, err)
+		}
+		if _, err := svc.EnableJob(job.ID, false); err != nil {
+			return fmt.Errorf("add automation %s: %w", auto.Name, err)
+		}
+		fmt.Printf("  Automation (disabled): %s [%s]\n", auto.Name, auto.Schedule.Expr)
+	}
+	return nil
+}
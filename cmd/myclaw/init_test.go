@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/cron"
+)
+
+func TestRunInit_List(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	initCmd.Flags().Set("list", "true")
+	defer initCmd.Flags().Set("list", "false")
+
+	output, err := captureRunOutput(t, func() error { return runInit(initCmd, nil) })
+	if err != nil {
+		t.Fatalf("runInit error: %v", err)
+	}
+	for _, name := range []string{"personal-assistant", "dev-helper", "family-bot"} {
+		if !strings.Contains(output, name) {
+			t.Errorf("list output missing %s: %s", name, output)
+		}
+	}
+}
+
+func TestRunInit_Template(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("MYCLAW_API_KEY", "")
+
+	output, err := captureRunOutput(t, func() error { return runInit(initCmd, []string{"dev-helper"}) })
+	if err != nil {
+		t.Fatalf("runInit error: %v", err)
+	}
+	if !strings.Contains(output, "Applying template: dev-helper") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	ws := filepath.Join(tmpDir, ".myclaw", "workspace")
+	agents, err := os.ReadFile(filepath.Join(ws, "AGENTS.md"))
+	if err != nil {
+		t.Fatalf("read AGENTS.md: %v", err)
+	}
+	if string(agents) == defaultAgentsMD {
+		t.Error("AGENTS.md should come from the template")
+	}
+	if _, err := os.Stat(filepath.Join(ws, "skills", "code-review", "SKILL.md")); err != nil {
+		t.Errorf("starter skill missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "memory", "MEMORY.md")); err != nil {
+		t.Errorf("onboard defaults missing: %v", err)
+	}
+
+	storePath := filepath.Join(tmpDir, ".myclaw", "data", "cron", "jobs.json")
+	data, err := os.ReadFile(storePath)
+	if err != nil {
+		t.Fatalf("read cron store: %v", err)
+	}
+	var jobs []cron.CronJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		t.Fatalf("parse cron store: %v", err)
+	}
+	if len(jobs) == 0 {
+		t.Fatal("expected example automations")
+	}
+	for _, job := range jobs {
+		if job.Enabled {
+			t.Errorf("automation %s should start disabled", job.Name)
+		}
+	}
+
+	// Re-running does not duplicate automations.
+	if _, err := captureRunOutput(t, func() error { return runInit(initCmd, []string{"dev-helper"}) }); err != nil {
+		t.Fatalf("second runInit error: %v", err)
+	}
+	data, _ = os.ReadFile(storePath)
+	var again []cron.CronJob
+	json.Unmarshal(data, &again)
+	if len(again) != len(jobs) {
+		t.Errorf("jobs after re-init = %d, want %d", len(again), len(jobs))
+	}
+}
+
+func TestRunOnboard_TemplateFlag(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("MYCLAW_API_KEY", "")
+	onboardCmd.Flags().Set("template", "family-bot")
+	defer onboardCmd.Flags().Set("template", "")
+
+	if _, err := captureRunOutput(t, func() error { return runOnboard(onboardCmd, nil) }); err != nil {
+		t.Fatalf("runOnboard error: %v", err)
+	}
+	skill := filepath.Join(tmpDir, ".myclaw", "workspace", "skills", "shopping-list", "SKILL.md")
+	if _, err := os.Stat(skill); err != nil {
+		t.Errorf("template skill missing: %v", err)
+	}
+}
+
+func TestRunInit_UnknownTemplate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, err := captureRunOutput(t, func() error { return runInit(initCmd, []string{"nope"}) })
+	if err == nil || !strings.Contains(err.Error(), "unknown template") {
+		t.Errorf("expected unknown template error, got %v", err)
+	}
+}
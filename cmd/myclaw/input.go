@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/media"
+)
+
+// maxInputBytes caps what is read from one file or stdin. Text past the
+// character limit is cut anyway; this keeps a stray log file or disk
+// image from being read whole.
+const maxInputBytes = 10 << 20
+
+// maxImageInputBytes caps an image file; photos straight off a camera are
+// large, and get scaled down before they are sent.
+const maxImageInputBytes = 50 << 20
+
+var (
+	// fileFlags are the files attached to a single message with --file.
+	fileFlags []string
+	// imageFlags are the images attached to it with --image.
+	imageFlags []string
+)
+
+// attachInputs adds the files named with --file, and whatever was piped to
+// stdin, to prompt as documents. Text over limit characters is cut, with a
+// warning on stderr; binary input is refused.
+func attachInputs(prompt string, stdin io.Reader, limit int, stderr io.Writer) (string, error) {
+	if limit <= 0 {
+		limit = media.DefaultMaxDocumentChars
+	}
+	var docs []string
+	add := func(name string, data []byte) error {
+		if media.LooksBinary(data) {
+			return fmt.Errorf("%s looks like binary data; only text can be attached", name)
+		}
+		text := strings.TrimSpace(string(data))
+		if text == "" {
+			return nil
+		}
+		text, cut := media.Clip(text, limit)
+		if cut {
+			fmt.Fprintf(stderr, "Warning: %s cut to %d characters (media.maxDocumentChars)\n", name, limit)
+		}
+		docs = append(docs, media.Document(name, text))
+		return nil
+	}
+
+	if piped(stdin) {
+		data, err := io.ReadAll(io.LimitReader(stdin, maxInputBytes+1))
+		if err != nil {
+			return "", fmt.Errorf("read stdin: %w", err)
+		}
+		if len(data) > maxInputBytes {
+			return "", fmt.Errorf("stdin is over %d MB", maxInputBytes>>20)
+		}
+		if err := add("stdin", data); err != nil {
+			return "", err
+		}
+	}
+	for _, path := range fileFlags {
+		data, err := readInputFile(path, maxInputBytes)
+		if err != nil {
+			return "", err
+		}
+		if err := add(filepath.Base(path), data); err != nil {
+			return "", err
+		}
+	}
+
+	if len(docs) == 0 {
+		return prompt, nil
+	}
+	// Documents first, so the request comes after what it is about.
+	return strings.Join(docs, "\n\n") + "\n\n" + prompt, nil
+}
+
+// attachImages reads the images named with --image into blocks for the
+// model, noting on stderr the ones scaled down to fit provider limits.
+func attachImages(stderr io.Writer) ([]model.ContentBlock, error) {
+	var blocks []model.ContentBlock
+	for _, path := range imageFlags {
+		data, err := readInputFile(path, maxImageInputBytes)
+		if err != nil {
+			return nil, err
+		}
+		block, scaled, err := media.ImageBlock(data)
+		if err != nil {
+			return nil, fmt.Errorf("attach image %s: %w", filepath.Base(path), err)
+		}
+		if scaled {
+			fmt.Fprintf(stderr, "Note: %s scaled down to fit the model's image limits\n", filepath.Base(path))
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func readInputFile(path string, limit int64) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("attach file: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("attach file: %s is a directory", path)
+	}
+	if info.Size() > limit {
+		return nil, fmt.Errorf("attach file: %s is over %d MB", path, limit>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("attach file: %w", err)
+	}
+	return data, nil
+}
+
+// piped reports whether stdin carries input for the message: a pipe or a
+// redirected file, not a terminal or /dev/null. Readers that are not files,
+// as in tests, always count.
+func piped(stdin io.Reader) bool {
+	f, ok := stdin.(*os.File)
+	if !ok {
+		return stdin != nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func TestAttachInputs(t *testing.T) {
+	dir := t.TempDir()
+	mainGo := filepath.Join(dir, "main.go")
+	os.WriteFile(mainGo, []byte("package main\n"), 0644)
+	orig := fileFlags
+	fileFlags = []string{mainGo}
+	t.Cleanup(func() { fileFlags = orig })
+
+	var stderr bytes.Buffer
+	got, err := attachInputs("review", strings.NewReader("quarterly numbers"), 0, &stderr)
+	if err != nil {
+		t.Fatalf("attachInputs error: %v", err)
+	}
+	want := "Document stdin:\n```\nquarterly numbers\n```\n\nDocument main.go:\n```\npackage main\n```\n\nreview"
+	if got != want {
+		t.Errorf("prompt =\n%s\nwant\n%s", got, want)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("unexpected warning: %s", stderr.String())
+	}
+}
+
+func TestAttachInputs_Limits(t *testing.T) {
+	orig := fileFlags
+	t.Cleanup(func() { fileFlags = orig })
+	fileFlags = nil
+
+	var stderr bytes.Buffer
+	got, err := attachInputs("sum up", strings.NewReader("abcdefghij"), 4, &stderr)
+	if err != nil || !strings.Contains(got, "abcd\n[... cut at 4 characters]") {
+		t.Errorf("clipped prompt = %q, %v", got, err)
+	}
+	if !strings.Contains(stderr.String(), "stdin cut to 4 characters") {
+		t.Errorf("warning = %q", stderr.String())
+	}
+
+	if _, err := attachInputs("x", strings.NewReader("PK\x03\x04\x00\x00"), 0, &stderr); err == nil || !strings.Contains(err.Error(), "binary") {
+		t.Errorf("binary stdin: %v", err)
+	}
+
+	fileFlags = []string{t.TempDir()}
+	if _, err := attachInputs("x", nil, 0, &stderr); err == nil || !strings.Contains(err.Error(), "directory") {
+		t.Errorf("directory: %v", err)
+	}
+}
+
+func TestRunAgentWithOptions_FileNeedsMessage(t *testing.T) {
+	origFiles, origMsg := fileFlags, messageFlag
+	fileFlags, messageFlag = []string{"main.go"}, ""
+	t.Cleanup(func() { fileFlags, messageFlag = origFiles, origMsg })
+
+	if err := runAgentWithOptions(AgentOptions{}); err == nil || !strings.Contains(err.Error(), "-m") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRunAgentWithOptions_Image(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("GOOGLE_API_KEY", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	shot := filepath.Join(t.TempDir(), "shot.png")
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2000, 100)))
+	os.WriteFile(shot, buf.Bytes(), 0644)
+
+	origImages, origMsg := imageFlags, messageFlag
+	t.Cleanup(func() { imageFlags, messageFlag = origImages, origMsg })
+	imageFlags, messageFlag = []string{shot}, ""
+	if err := runAgentWithOptions(AgentOptions{}); err == nil || !strings.Contains(err.Error(), "-m") {
+		t.Errorf("without -m err = %v", err)
+	}
+
+	messageFlag = "what's wrong with this screenshot"
+	rt := &recordingRuntime{mockRuntime: mockRuntime{response: &api.Response{Result: &api.Result{Output: "ok"}}}}
+	var stdout, stderr bytes.Buffer
+	if err := runAgentWithOptions(AgentOptions{RuntimeFactory: mockRuntimeFactory(rt), Stdout: &stdout, Stderr: &stderr}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.requests) != 1 {
+		t.Fatalf("requests = %+v", rt.requests)
+	}
+	blocks := rt.requests[0].ContentBlocks
+	if len(blocks) != 2 || blocks[0].Text != messageFlag || blocks[1].Type != model.ContentBlockImage || blocks[1].MediaType != "image/png" {
+		t.Errorf("blocks = %+v", blocks)
+	}
+	if !strings.Contains(stderr.String(), "shot.png scaled down") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+
+	imageFlags = []string{filepath.Join(filepath.Dir(shot), "missing.png")}
+	if err := runAgentWithOptions(AgentOptions{RuntimeFactory: mockRuntimeFactory(rt), Stdout: &stdout, Stderr: &stderr}); err == nil {
+		t.Error("expected error for a missing image")
+	}
+}
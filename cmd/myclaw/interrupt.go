@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// errInterrupted ends a single-message run stopped with Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// interrupter turns Ctrl-C into stopping the run in flight. A second
+// Ctrl-C while the run is stopping, or one with no run in flight, exits
+// as Ctrl-C always did.
+type interrupter struct {
+	mu       sync.Mutex
+	cancel   context.CancelFunc // of the run in flight, or nil
+	stopping bool
+	stderr   io.Writer
+	exit     func(code int)
+}
+
+// watchInterrupts routes SIGINT to an interrupter until stop is called.
+func watchInterrupts(stderr io.Writer) (in *interrupter, stop func()) {
+	in = &interrupter{stderr: stderr, exit: os.Exit}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sigCh:
+				in.interrupt()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return in, func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
+
+// start begins a run under ctx. The returned context is canceled by
+// Ctrl-C; end must be called when the run returns, and reports whether
+// it was interrupted.
+func (in *interrupter) start(ctx context.Context) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	in.mu.Lock()
+	in.cancel, in.stopping = cancel, false
+	in.mu.Unlock()
+	return ctx, func() bool {
+		in.mu.Lock()
+		defer in.mu.Unlock()
+		interrupted := in.stopping
+		in.cancel, in.stopping = nil, false
+		cancel()
+		return interrupted
+	}
+}
+
+func (in *interrupter) interrupt() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.cancel == nil || in.stopping {
+		fmt.Fprintln(in.stderr)
+		in.exit(130)
+		return
+	}
+	in.stopping = true
+	in.cancel()
+	fmt.Fprintln(in.stderr, "\nStopping... (Ctrl-C again to quit)")
+}
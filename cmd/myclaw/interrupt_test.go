@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+)
+
+func TestInterrupter(t *testing.T) {
+	var stderr bytes.Buffer
+	exited := -1
+	in := &interrupter{stderr: &stderr, exit: func(code int) { exited = code }}
+
+	ctx, end := in.start(context.Background())
+	in.interrupt()
+	if ctx.Err() == nil || exited != -1 {
+		t.Fatalf("first Ctrl-C: ctx err %v, exited %d", ctx.Err(), exited)
+	}
+	if !strings.Contains(stderr.String(), "Ctrl-C again to quit") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+	in.interrupt()
+	if exited != 130 {
+		t.Errorf("second Ctrl-C exited %d", exited)
+	}
+	if !end() {
+		t.Error("run not reported interrupted")
+	}
+
+	// Once the run is over, the next run starts afresh.
+	exited = -1
+	_, end = in.start(context.Background())
+	if end() {
+		t.Error("quiet run reported interrupted")
+	}
+	in.interrupt()
+	if exited != 130 {
+		t.Errorf("Ctrl-C with no run exited %d", exited)
+	}
+}
+
+// interruptedRuntime sends the process Ctrl-C once a run starts, and waits
+// for the run to be canceled.
+type interruptedRuntime struct{ mockRuntime }
+
+func (r *interruptedRuntime) Run(ctx context.Context, req api.Request) (*api.Response, error) {
+	p, _ := os.FindProcess(os.Getpid())
+	p.Signal(os.Interrupt)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRunAgentWithOptions_Interrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGINT on Windows")
+	}
+	t.Setenv("HOME", t.TempDir())
+	oldFlag := messageFlag
+	t.Cleanup(func() { messageFlag = oldFlag })
+
+	messageFlag = "write a novel"
+	var stderr bytes.Buffer
+	err := runAgentWithOptions(AgentOptions{
+		RuntimeFactory: mockRuntimeFactory(&interruptedRuntime{}),
+		Stdout:         &bytes.Buffer{},
+		Stderr:         &stderr,
+	})
+	if err == nil || err.Error() != "interrupted" {
+		t.Errorf("single message: err = %v", err)
+	}
+
+	messageFlag = ""
+	stderr.Reset()
+	err = runAgentWithOptions(AgentOptions{
+		RuntimeFactory: mockRuntimeFactory(&interruptedRuntime{}),
+		Stdin:          strings.NewReader("write a novel\nexit\n"),
+		Stdout:         &bytes.Buffer{},
+		Stderr:         &stderr,
+	})
+	if err != nil || !strings.Contains(stderr.String(), "Interrupted.") {
+		t.Errorf("REPL: err = %v, stderr = %q", err, stderr.String())
+	}
+}
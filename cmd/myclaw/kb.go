@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/kb"
+)
+
+const kbJSONSchemaVersion = 1
+
+var kbCmd = &cobra.Command{
+	Use:   "kb",
+	Short: "Manage the knowledge base of documents the agent draws on",
+	Long: `Manage the knowledge base: PDFs, markdown, HTML and text files and web
+pages whose passages are recalled into each message they are relevant to,
+in the CLI and through the gateway.
+
+Documents are split into passages and embedded with memory.embedding into
+workspace/kb/index.db. Reading PDFs needs pdftotext (poppler-utils).`,
+}
+
+var kbAddCmd = &cobra.Command{
+	Use:   "add <path|url>...",
+	Short: "Add files, directories or web pages to the knowledge base",
+	Long: `Add files, directories or web pages to the knowledge base.
+
+A directory adds every PDF, markdown, HTML and text file under it. Adding a
+source that is already in the knowledge base re-reads it and re-embeds it
+if it changed.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runKBAdd,
+}
+
+var kbListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the documents in the knowledge base",
+	Args:  cobra.NoArgs,
+	RunE:  runKBList,
+}
+
+var kbRemoveCmd = &cobra.Command{
+	Use:   "remove <id|path|url>...",
+	Short: "Remove documents from the knowledge base",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runKBRemove,
+}
+
+var kbReindexCmd = &cobra.Command{
+	Use:   "reindex [id|path|url]...",
+	Short: "Read documents again and re-embed them (all when none are given)",
+	RunE:  runKBReindex,
+}
+
+var kbSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the knowledge base by meaning",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runKBSearch,
+}
+
+func init() {
+	kbAddCmd.Flags().Bool("json", false, "Output as JSON")
+	kbListCmd.Flags().Bool("json", false, "Output as JSON")
+	kbRemoveCmd.Flags().Bool("json", false, "Output as JSON")
+	kbReindexCmd.Flags().Bool("json", false, "Output as JSON")
+	kbSearchCmd.Flags().IntP("limit", "n", 0, "Maximum results (default kb.topK)")
+	kbSearchCmd.Flags().Bool("json", false, "Output as JSON")
+	kbCmd.AddCommand(kbAddCmd, kbListCmd, kbRemoveCmd, kbReindexCmd, kbSearchCmd)
+	rootCmd.AddCommand(kbCmd)
+}
+
+// openKB opens the knowledge base. Unless create is set, a missing one is
+// an error rather than created empty.
+func openKB(create bool) (*config.Config, *kb.Store, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("load config: %w", err)
+	}
+	if !create && !kb.Exists(cfg.Agent.Workspace) {
+		return nil, nil, errors.New(`the knowledge base is empty; add documents with "myclaw kb add"`)
+	}
+	store, err := kb.OpenConfigured(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, store, nil
+}
+
+func runKBAdd(cmd *cobra.Command, args []string) error {
+	_, store, err := openKB(true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var indexed []kb.Indexed
+	var errs []error
+	for _, source := range args {
+		docs, err := store.Add(cmd.Context(), source)
+		indexed = append(indexed, docs...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return reportIndexed(cmd, "kb.add", indexed, errors.Join(errs...))
+}
+
+func runKBReindex(cmd *cobra.Command, args []string) error {
+	_, store, err := openKB(false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	indexed, err := store.Reindex(cmd.Context(), args...)
+	return reportIndexed(cmd, "kb.reindex", indexed, err)
+}
+
+// reportIndexed prints what add or reindex went over, then fails with
+// indexErr if some sources could not be read.
+func reportIndexed(cmd *cobra.Command, command string, indexed []kb.Indexed, indexErr error) error {
+	if readJSONFlag(cmd) {
+		if indexed == nil {
+			indexed = []kb.Indexed{}
+		}
+		payload := map[string]any{
+			"schemaVersion": kbJSONSchemaVersion,
+			"command":       command,
+			"ok":            indexErr == nil,
+			"documents":     indexed,
+		}
+		if indexErr != nil {
+			payload["error"] = indexErr.Error()
+		}
+		if err := printJSON(payload); err != nil {
+			return err
+		}
+		return indexErr
+	}
+	for _, d := range indexed {
+		if d.Unchanged {
+			fmt.Printf("Unchanged: %s\n", d.Source)
+			continue
+		}
+		fmt.Printf("Indexed [%d] %s: %d passages\n", d.ID, d.Source, d.Chunks)
+	}
+	return indexErr
+}
+
+func runKBList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	var docs []kb.Document
+	if kb.Exists(cfg.Agent.Workspace) {
+		store, err := kb.OpenConfigured(cfg)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if docs, err = store.List(cmd.Context()); err != nil {
+			return err
+		}
+	}
+
+	if readJSONFlag(cmd) {
+		if docs == nil {
+			docs = []kb.Document{}
+		}
+		return printJSON(map[string]any{
+			"schemaVersion": kbJSONSchemaVersion,
+			"command":       "kb.list",
+			"ok":            true,
+			"count":         len(docs),
+			"documents":     docs,
+		})
+	}
+	if len(docs) == 0 {
+		fmt.Println(`The knowledge base is empty; add documents with "myclaw kb add".`)
+		return nil
+	}
+	for _, d := range docs {
+		fmt.Printf("[%d] %s (%s, %d passages, indexed %s)\n", d.ID, d.Title, d.Kind, d.Chunks, d.Indexed.Local().Format("2006-01-02 15:04"))
+		fmt.Printf("    %s\n", d.Source)
+	}
+	return nil
+}
+
+func runKBRemove(cmd *cobra.Command, args []string) error {
+	_, store, err := openKB(false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	var removed []kb.Document
+	for _, ref := range args {
+		d, err := store.Remove(cmd.Context(), ref)
+		if err != nil {
+			return err
+		}
+		removed = append(removed, *d)
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": kbJSONSchemaVersion,
+			"command":       "kb.remove",
+			"ok":            true,
+			"documents":     removed,
+		})
+	}
+	for _, d := range removed {
+		fmt.Printf("Removed [%d] %s\n", d.ID, d.Source)
+	}
+	return nil
+}
+
+func runKBSearch(cmd *cobra.Command, args []string) error {
+	cfg, store, err := openKB(false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	limit, _ := cmd.Flags().GetInt("limit")
+	if limit <= 0 {
+		limit = cfg.KB.TopK
+	}
+	if limit <= 0 {
+		limit = config.DefaultKBTopK
+	}
+	query := strings.Join(args, " ")
+	results, err := store.Search(cmd.Context(), query, limit, cfg.KB.MinScore)
+	if err != nil {
+		return fmt.Errorf("search knowledge base: %w", err)
+	}
+
+	if readJSONFlag(cmd) {
+		if results == nil {
+			results = []kb.Result{}
+		}
+		return printJSON(map[string]any{
+			"schemaVersion": kbJSONSchemaVersion,
+			"command":       "kb.search",
+			"ok":            true,
+			"query":         query,
+			"count":         len(results),
+			"results":       results,
+		})
+	}
+	if len(results) == 0 {
+		fmt.Println("Nothing in the knowledge base matches.")
+		return nil
+	}
+	for _, r := range results {
+		label := r.Title
+		if r.Heading != "" && r.Heading != r.Title {
+			label += " > " + r.Heading
+		}
+		fmt.Printf("[%.2f] %s (%s)\n", r.Score, label, r.Source)
+		for _, line := range strings.Split(r.Content, "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+		fmt.Println()
+	}
+	return nil
+}
@@ -1,25 +1,52 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/user"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
 	"github.com/cexll/agentsdk-go/pkg/model"
 	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
 	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/audit"
+	"github.com/stellarlinkco/myclaw/internal/calendar"
 	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/delegate"
+	"github.com/stellarlinkco/myclaw/internal/fetch"
 	"github.com/stellarlinkco/myclaw/internal/gateway"
+	"github.com/stellarlinkco/myclaw/internal/github"
+	"github.com/stellarlinkco/myclaw/internal/health"
+	"github.com/stellarlinkco/myclaw/internal/homeassistant"
+	"github.com/stellarlinkco/myclaw/internal/imagegen"
+	"github.com/stellarlinkco/myclaw/internal/kb"
+	"github.com/stellarlinkco/myclaw/internal/mailbox"
 	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+	"github.com/stellarlinkco/myclaw/internal/prompt"
+	"github.com/stellarlinkco/myclaw/internal/provider"
+	"github.com/stellarlinkco/myclaw/internal/readline"
+	"github.com/stellarlinkco/myclaw/internal/redact"
+	"github.com/stellarlinkco/myclaw/internal/search"
+	"github.com/stellarlinkco/myclaw/internal/session"
 	"github.com/stellarlinkco/myclaw/internal/skills"
+	"github.com/stellarlinkco/myclaw/internal/todo"
+	"github.com/stellarlinkco/myclaw/internal/tracing"
+	"github.com/stellarlinkco/myclaw/internal/usage"
 )
 
 // Runtime interface for agent runtime (allows mocking in tests)
@@ -30,15 +57,216 @@ type Runtime interface {
 
 // runtimeWrapper wraps api.Runtime to implement Runtime interface
 type runtimeWrapper struct {
-	rt *api.Runtime
+	rt     *api.Runtime
+	vector *memory.VectorStore // nil unless memory.semantic is on
+	topK   int
+	// knowledge is recalled from like memory; nil without a knowledge
+	// base or with kb.topK -1.
+	knowledge *kb.Store
+	kb        config.KBConfig
+	extract   *memory.MemoryStore // nil unless memory.autoExtract is on
+	history   *session.Store
+	wg        sync.WaitGroup // pending memory extractions
+	ledger    *usage.Ledger
+	audit     *audit.Log // nil when audit.enabled is off
+	model     string
+	// stopTracing flushes traces; nil when the runtime was built by hand.
+	stopTracing func(context.Context) error
 }
 
+const extractSessionID = "memory-extract"
+
 func (r *runtimeWrapper) Run(ctx context.Context, req api.Request) (*api.Response, error) {
-	return r.rt.Run(ctx, req)
+	user := requestText(req)
+	ctx = r.auditInbound(ctx, req, user)
+	ctx = prompt.WithVars(ctx, cliPromptVars())
+	ctx, span := tracing.StartRun(ctx, "cli", req)
+	resp, err := r.rt.Run(ctx, r.withMemory(ctx, req))
+	tracing.EndRun(span, resp, err)
+	if err == nil && resp != nil && resp.Result != nil {
+		r.recordUsage(req, resp.Result.Usage)
+		r.auditOutbound(req, resp.Result.Output)
+		r.extractMemory(user, resp.Result.Output)
+	}
+	return resp, err
+}
+
+// cliPromptVars are the template variables of a CLI run: the channel is
+// cli and the user is whoever runs myclaw.
+func cliPromptVars() prompt.Vars {
+	v := prompt.Vars{Channel: "cli"}
+	if u, err := user.Current(); err == nil {
+		v.UserName, v.UserID = u.Username, u.Uid
+	}
+	return v
+}
+
+// auditInbound logs the prompt of a CLI run and returns ctx with the run's
+// source for its tool calls.
+func (r *runtimeWrapper) auditInbound(ctx context.Context, req api.Request, prompt string) context.Context {
+	if r.audit == nil {
+		return ctx
+	}
+	src := audit.Source{Channel: "cli", Session: req.SessionID}
+	if err := r.audit.Append(audit.Entry{Kind: audit.Inbound, Source: src, Content: prompt}); err != nil {
+		log.Printf("[audit] record failed: %v", err)
+	}
+	return audit.WithSource(ctx, src)
+}
+
+func (r *runtimeWrapper) auditOutbound(req api.Request, reply string) {
+	if r.audit == nil || strings.TrimSpace(reply) == "" {
+		return
+	}
+	err := r.audit.Append(audit.Entry{Kind: audit.Outbound, Source: audit.Source{Channel: "cli", Session: req.SessionID}, Content: reply})
+	if err != nil {
+		log.Printf("[audit] record failed: %v", err)
+	}
+}
+
+// recordUsage writes a CLI run to the usage ledger.
+func (r *runtimeWrapper) recordUsage(req api.Request, u model.Usage) {
+	if r.ledger == nil {
+		return
+	}
+	_, _, err := r.ledger.Record(usage.Entry{
+		Time:             time.Now(),
+		Session:          req.SessionID,
+		Channel:          "cli",
+		Model:            r.model,
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheReadTokens:  u.CacheReadTokens,
+		CacheWriteTokens: u.CacheCreationTokens,
+	})
+	if err != nil {
+		log.Printf("[usage] record failed: %v", err)
+	}
+}
+
+func (r *runtimeWrapper) RunStream(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error) {
+	user := requestText(req)
+	ctx = r.auditInbound(ctx, req, user)
+	ctx = prompt.WithVars(ctx, cliPromptVars())
+	ctx, span := tracing.StartRun(ctx, "cli", req)
+	events, err := r.rt.RunStream(ctx, r.withMemory(ctx, req))
+	if err != nil {
+		tracing.EndRun(span, nil, err)
+		return events, err
+	}
+
+	// Pass events through, collecting the answer for extraction and ending
+	// the run's span when the stream does.
+	out := make(chan api.StreamEvent)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer close(out)
+		var reply strings.Builder
+		var runErr error
+		for ev := range events {
+			switch {
+			case ev.Type == api.EventContentBlockDelta && ev.Delta != nil && ev.Delta.Type == "text_delta":
+				reply.WriteString(ev.Delta.Text)
+			case ev.Type == api.EventError:
+				runErr = fmt.Errorf("%v", ev.Output)
+			}
+			out <- ev
+		}
+		tracing.EndRun(span, nil, runErr)
+		if runErr == nil {
+			r.auditOutbound(req, reply.String())
+			r.extractMemory(user, reply.String())
+		}
+	}()
+	return out, nil
+}
+
+// extractMemory notes durable facts from one exchange in the background;
+// Close waits for it.
+func (r *runtimeWrapper) extractMemory(user, reply string) {
+	if r.extract == nil || strings.TrimSpace(reply) == "" {
+		return
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		summarize := func(ctx context.Context, prompt string) (string, error) {
+			defer r.history.Delete(extractSessionID)
+			resp, err := r.rt.Run(ctx, api.Request{Prompt: prompt, SessionID: extractSessionID})
+			if err != nil || resp == nil || resp.Result == nil {
+				return "", err
+			}
+			return resp.Result.Output, nil
+		}
+		if _, err := r.extract.Extract(context.Background(), user, reply, summarize); err != nil {
+			log.Printf("[memory] extraction error: %v", err)
+		}
+	}()
+}
+
+// requestText returns the user's text in req.
+func requestText(req api.Request) string {
+	if req.Prompt != "" {
+		return req.Prompt
+	}
+	for _, b := range req.ContentBlocks {
+		if b.Type == model.ContentBlockText && b.Text != "" {
+			return b.Text
+		}
+	}
+	return ""
+}
+
+// withMemory adds the memory chunks and knowledge base passages relevant
+// to the request's text.
+func (r *runtimeWrapper) withMemory(ctx context.Context, req api.Request) api.Request {
+	if r.vector == nil && r.knowledge == nil {
+		return req
+	}
+	if req.Prompt != "" {
+		req.Prompt = r.recall(ctx, req.Prompt)
+		return req
+	}
+	for i, b := range req.ContentBlocks {
+		if b.Type == model.ContentBlockText && b.Text != "" {
+			blocks := append([]model.ContentBlock(nil), req.ContentBlocks...)
+			blocks[i].Text = r.recall(ctx, b.Text)
+			req.ContentBlocks = blocks
+			break
+		}
+	}
+	return req
+}
+
+// recall prefixes text with what memory and the knowledge base have on it.
+func (r *runtimeWrapper) recall(ctx context.Context, text string) string {
+	var knowledge string
+	if r.knowledge != nil {
+		knowledge = r.knowledge.Prefix(ctx, text, r.kb.TopK, r.kb.MinScore)
+	}
+	if r.vector != nil {
+		text = r.vector.WithRecall(ctx, text, r.topK)
+	}
+	return knowledge + text
 }
 
 func (r *runtimeWrapper) Close() {
+	r.wg.Wait()
 	r.rt.Close()
+	if r.vector != nil {
+		_ = r.vector.Close()
+	}
+	if r.knowledge != nil {
+		_ = r.knowledge.Close()
+	}
+	if r.stopTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := r.stopTracing(ctx); err != nil {
+			log.Printf("[tracing] flush failed: %v", err)
+		}
+	}
 }
 
 // RuntimeFactory creates a Runtime instance
@@ -47,49 +275,150 @@ type RuntimeFactory func(cfg *config.Config) (Runtime, error)
 // DefaultRuntimeFactory creates the default agentsdk-go runtime
 func DefaultRuntimeFactory(cfg *config.Config) (Runtime, error) {
 	if cfg.Provider.APIKey == "" {
-		return nil, fmt.Errorf("API key not set. Run 'myclaw onboard' or set MYCLAW_API_KEY / ANTHROPIC_API_KEY")
+		return nil, fmt.Errorf("API key not set. Run 'myclaw onboard' or set MYCLAW_API_KEY / ANTHROPIC_API_KEY / OPENAI_API_KEY / GOOGLE_API_KEY")
+	}
+
+	var vector *memory.VectorStore
+	if cfg.Memory.Semantic {
+		var err error
+		if vector, err = memory.OpenConfigured(cfg); err != nil {
+			log.Printf("[memory] semantic memory unavailable, using MEMORY.md: %v", err)
+			cfg.Memory.Semantic = false
+		}
 	}
 
-	mem := memory.NewMemoryStore(cfg.Agent.Workspace)
+	mem := memory.NewMemoryStore(cfg.MemoryWorkspace())
 	sysPrompt := buildSystemPrompt(cfg, mem)
 	skillRegs := loadRuntimeSkills(cfg)
 
-	var provider api.ModelFactory
-	switch cfg.Provider.Type {
-	case "openai":
-		provider = &model.OpenAIProvider{
-			APIKey:    cfg.Provider.APIKey,
-			BaseURL:   cfg.Provider.BaseURL,
-			ModelName: cfg.Agent.Model,
-			MaxTokens: cfg.Agent.MaxTokens,
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		if vector != nil {
+			_ = vector.Close()
 		}
-	default:
-		provider = &model.AnthropicProvider{
-			APIKey:    cfg.Provider.APIKey,
-			BaseURL:   cfg.Provider.BaseURL,
-			ModelName: cfg.Agent.Model,
-			MaxTokens: cfg.Agent.MaxTokens,
+		return nil, err
+	}
+	tmpl, err := prompt.Load(cfg)
+	if err != nil {
+		log.Printf("[agent] %v; using AGENTS.md and SOUL.md as written", err)
+	}
+	modelFactory := tracing.ModelFactory(redact.ModelFactory(provider.New(cfg), redactor), cfg.Models.Resolve(cfg.Agent.Model))
+	modelFactory = prompt.ModelFactory(modelFactory, tmpl)
+	skillRegs = tracing.Skills(skillRegs)
+	policy, err := permission.NewPolicy(cfg.Permissions)
+	if err != nil {
+		if vector != nil {
+			_ = vector.Close()
 		}
+		return nil, err
+	}
+	searchTools, disallowed, err := search.Tools(cfg.Tools.Search)
+	if err != nil {
+		if vector != nil {
+			_ = vector.Close()
+		}
+		return nil, err
+	}
+	fetchTools, fetchDisallowed := fetch.Tools(cfg.Tools.Fetch)
+	tools := append(append(skills.Tools(skillRegs, skills.CommandOptionsFromConfig(cfg.Skills)), searchTools...), fetchTools...)
+	if calendarTools, err := calendar.Tools(cfg.Calendar); err != nil {
+		log.Printf("[calendar] calendar tools unavailable: %v", err)
+	} else {
+		tools = append(tools, calendarTools...)
+	}
+	if mailTools, err := mailbox.Tools(cfg.Mail); err != nil {
+		log.Printf("[mail] email tools unavailable: %v", err)
+	} else {
+		tools = append(tools, mailTools...)
+	}
+	if githubTools, err := github.Tools(cfg.GitHub); err != nil {
+		log.Printf("[github] GitHub tools unavailable: %v", err)
+	} else {
+		tools = append(tools, githubTools...)
+	}
+	if homeTools, err := homeassistant.Tools(cfg.HomeAssistant); err != nil {
+		log.Printf("[homeassistant] home tools unavailable: %v", err)
+	} else {
+		tools = append(tools, homeTools...)
+	}
+	if imageTools, err := imagegen.Tools(cfg.Media.Images, cfg.Agent.Workspace); err != nil {
+		log.Printf("[imagegen] image generation unavailable: %v", err)
+	} else {
+		tools = append(tools, imageTools...)
+	}
+	tools = append(tools, todo.Tools(todo.NewStore(todo.Path(cfg.Agent.Workspace)))...)
+	disallowed = append(disallowed, fetchDisallowed...)
+	tools = append(tools, delegate.Tools(cfg, tools, disallowed)...)
+
+	auditLog := audit.Open(cfg)
+	middlewares := []middleware.Middleware{tracing.Middleware()}
+	if auditLog != nil {
+		middlewares = append(middlewares, audit.Middleware(auditLog))
 	}
 
 	rt, err := api.New(context.Background(), api.Options{
-		ProjectRoot:   cfg.Agent.Workspace,
-		ModelFactory:  provider,
-		SystemPrompt:  sysPrompt,
-		MaxIterations: cfg.Agent.MaxToolIterations,
-		MCPServers:    cfg.MCP.Servers,
-		TokenTracking: cfg.TokenTracking.Enabled,
+		ProjectRoot:        cfg.Agent.Workspace,
+		ModelFactory:       modelFactory,
+		Middleware:         middlewares,
+		HookMiddleware:     permission.HookMiddleware(policy),
+		SystemPrompt:       sysPrompt,
+		MaxIterations:      cfg.Agent.MaxToolIterations,
+		MCPServers:         cfg.MCP.Specs(),
+		TokenTracking:      cfg.TokenTracking.Enabled,
+		DefaultEnableCache: cfg.Provider.PromptCache,
 		AutoCompact: api.CompactConfig{
 			Enabled:       cfg.AutoCompact.Enabled,
 			Threshold:     cfg.AutoCompact.Threshold,
 			PreserveCount: cfg.AutoCompact.PreserveCount,
 		},
-		Skills: skillRegs,
+		Skills:          skillRegs,
+		CustomTools:     tools,
+		DisallowedTools: disallowed,
 	})
 	if err != nil {
+		if vector != nil {
+			_ = vector.Close()
+		}
 		return nil, fmt.Errorf("create runtime: %w", err)
 	}
-	return &runtimeWrapper{rt: rt}, nil
+	stopTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		rt.Close()
+		if vector != nil {
+			_ = vector.Close()
+		}
+		return nil, err
+	}
+	wrapper := &runtimeWrapper{
+		rt:          rt,
+		vector:      vector,
+		topK:        cfg.Memory.TopK,
+		knowledge:   openKnowledge(cfg),
+		kb:          cfg.KB,
+		ledger:      usage.NewLedger(usage.Path(cfg.Agent.Workspace), cfg.TokenTracking.Prices),
+		audit:       auditLog,
+		model:       cfg.Models.Resolve(cfg.Agent.Model),
+		stopTracing: stopTracing,
+	}
+	if cfg.Memory.AutoExtract {
+		wrapper.extract = mem
+		wrapper.history = session.NewStore(cfg.Agent.Workspace)
+	}
+	return wrapper, nil
+}
+
+// openKnowledge opens the knowledge base to recall from, or returns nil
+// when there is none or recall is off.
+func openKnowledge(cfg *config.Config) *kb.Store {
+	if cfg.KB.TopK < 0 || !kb.Exists(cfg.Agent.Workspace) {
+		return nil
+	}
+	store, err := kb.OpenConfigured(cfg)
+	if err != nil {
+		log.Printf("[kb] knowledge base unavailable: %v", err)
+		return nil
+	}
+	return store
 }
 
 // AgentOptions for running agent with custom dependencies
@@ -103,12 +432,33 @@ type AgentOptions struct {
 var rootCmd = &cobra.Command{
 	Use:   "myclaw",
 	Short: "myclaw - personal AI assistant",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("profile")
+		if err := config.SetProfile(name); err != nil {
+			return err
+		}
+		ws, _ := cmd.Flags().GetString("workspace")
+		return config.SetWorkspace(ws)
+	},
 }
 
 var agentCmd = &cobra.Command{
 	Use:   "agent",
 	Short: "Run agent in single message or REPL mode",
-	RunE:  runAgent,
+	Long: `Run the agent on a single message with -m, or interactively.
+
+With -m, files given with --file and text piped to stdin are sent along
+with the message:
+
+  cat report.txt | myclaw agent -m "summarize this"
+  myclaw agent -m "review" --file main.go --file design.md
+
+Only text is attached, each input cut to media.maxDocumentChars.
+
+With --voice, press Enter and speak instead of typing; the question is
+transcribed with media.stt and the reply is read aloud with media.tts when
+it is set.`,
+	RunE: runAgent,
 }
 
 var gatewayCmd = &cobra.Command{
@@ -126,7 +476,13 @@ var onboardCmd = &cobra.Command{
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show myclaw status",
-	RunE:  runStatus,
+	Long: `Show myclaw status.
+
+With --probe, check instead that the config is valid, the provider answers,
+the MCP servers complete a handshake and, when the gateway runs, its
+channels are connected. The command exits non-zero when a check fails, for
+systemd or container health checks.`,
+	RunE: runStatus,
 }
 
 var skillsCmd = &cobra.Command{
@@ -153,35 +509,77 @@ var skillsCheckCmd = &cobra.Command{
 	RunE:  runSkillsCheck,
 }
 
-var messageFlag string
+var (
+	messageFlag string
+	quietFlag   bool
+)
 
-const skillsJSONSchemaVersion = 1
+const (
+	skillsJSONSchemaVersion = 1
+	statusJSONSchemaVersion = 1
+)
 
 func init() {
+	rootCmd.PersistentFlags().String("profile", "", "Use the named profile (~/.myclaw/profiles/<name>.json); defaults to MYCLAW_PROFILE")
+	rootCmd.PersistentFlags().String("workspace", "", "Use the named workspace, or a workspace directory; defaults to MYCLAW_WORKSPACE, then 'myclaw workspace switch'")
 	agentCmd.Flags().StringVarP(&messageFlag, "message", "m", "", "Single message to send")
+	agentCmd.Flags().BoolVar(&plainFlag, "plain", false, "Print replies as written, without rendering markdown")
+	agentCmd.Flags().StringArrayVarP(&fileFlags, "file", "f", nil, "Attach a text file to the message (repeatable)")
+	agentCmd.Flags().StringArrayVar(&imageFlags, "image", nil, "Attach an image to the message for the model to look at (repeatable)")
+	agentCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "Write only answers to stdout; the banner, prompts and notices go to stderr")
+	agentCmd.Flags().BoolVar(&agentJSONFlag, "json", false, "Print the result of -m as JSON: output, tool calls, usage and timing")
+	agentCmd.Flags().BoolVar(&voiceFlag, "voice", false, "Talk instead of typing: record from the microphone and speak replies (see media.stt and media.tts)")
 	skillsListCmd.Flags().Bool("json", false, "Output as JSON")
 	skillsInfoCmd.Flags().Bool("json", false, "Output as JSON")
 	skillsCheckCmd.Flags().Bool("json", false, "Output as JSON")
+	statusCmd.Flags().Bool("probe", false, "Check config, provider and channels; exit non-zero on failure")
+	statusCmd.Flags().Bool("json", false, "Output probe results as JSON")
 	skillsCmd.AddCommand(skillsListCmd, skillsInfoCmd, skillsCheckCmd)
 	rootCmd.AddCommand(agentCmd, gatewayCmd, onboardCmd, statusCmd, skillsCmd)
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
 // runAgent is the command handler that uses default options
 func runAgent(cmd *cobra.Command, args []string) error {
-	return runAgentWithOptions(AgentOptions{})
+	err := runAgentWithOptions(AgentOptions{})
+	if errors.Is(err, errAgentReported) {
+		cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	}
+	return err
 }
 
 // runAgentWithOptions runs the agent with injectable dependencies for testing
 func runAgentWithOptions(opts AgentOptions) error {
+	if len(fileFlags) > 0 && messageFlag == "" {
+		return errors.New("--file needs a message to go with it (-m)")
+	}
+	if len(imageFlags) > 0 && messageFlag == "" {
+		return errors.New("--image needs a message to go with it (-m)")
+	}
+	if agentJSONFlag && messageFlag == "" {
+		return errors.New("--json needs a message (-m)")
+	}
+	if voiceFlag && messageFlag != "" {
+		return errors.New("--voice is interactive and cannot be used with -m")
+	}
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		return fmt.Errorf("load config: %w", err)
+		return withExitCode(exitConfig, fmt.Errorf("load config: %w", err))
+	}
+	// Only the Anthropic client sends images on to the model.
+	if len(imageFlags) > 0 && (cfg.Provider.Type == provider.TypeOpenAI || cfg.Provider.Type == provider.TypeGemini) {
+		return withExitCode(exitConfig, fmt.Errorf("--image: provider.type %s does not pass images to the model; use an Anthropic model", cfg.Provider.Type))
+	}
+	var voiceMode *voiceREPL
+	if voiceFlag {
+		if voiceMode, err = newVoiceREPL(cfg); err != nil {
+			return withExitCode(exitConfig, err)
+		}
 	}
 
 	// Use injected factory or default
@@ -192,9 +590,8 @@ func runAgentWithOptions(opts AgentOptions) error {
 
 	rt, err := factory(cfg)
 	if err != nil {
-		return err
+		return withExitCode(exitConfig, err)
 	}
-	defer rt.Close()
 
 	// Use injected IO or defaults
 	stdin := opts.Stdin
@@ -210,49 +607,134 @@ func runAgentWithOptions(opts AgentOptions) error {
 		stderr = os.Stderr
 	}
 
+	// info gets all but the answers: the banner, prompts and command
+	// output. --quiet sends it to stderr, leaving stdout to the answers.
+	info := stdout
+	if quietFlag {
+		info = stderr
+	}
+
+	session := &replSession{
+		cfg:       cfg,
+		factory:   factory,
+		rt:        rt,
+		sessionID: replSessionID,
+		stdout:    info,
+		stderr:    stderr,
+	}
+	defer session.close()
+	render := replyRenderer(stdout)
+
 	ctx := context.Background()
+	// Images the agent draws are saved in the workspace; say where.
+	imageNotes := info
+	if agentJSONFlag {
+		imageNotes = stderr
+	}
+	ctx = imagegen.WithSink(ctx, func(path string) { fmt.Fprintf(imageNotes, "Image saved to %s\n", path) })
+	// Ctrl-C stops the answer being written rather than myclaw.
+	interrupts, stopInterrupts := watchInterrupts(stderr)
+	defer stopInterrupts()
 
 	// Single message mode
 	if messageFlag != "" {
-		resp, err := rt.Run(ctx, api.Request{
-			Prompt:    messageFlag,
-			SessionID: "cli",
-		})
+		prompt, err := attachInputs(messageFlag, stdin, cfg.Media.MaxDocumentChars, stderr)
+		if err != nil && agentJSONFlag {
+			return writeAgentJSON(stdout, "cli", nil, err, 0)
+		}
+		var images []model.ContentBlock
+		if err == nil {
+			images, err = attachImages(stderr)
+		}
+		if err != nil && agentJSONFlag {
+			return writeAgentJSON(stdout, "cli", nil, err, 0)
+		}
+		if err != nil {
+			return err
+		}
+		req := api.Request{Prompt: prompt, SessionID: "cli"}
+		if len(images) > 0 {
+			// The SDK drops Prompt when there are blocks; the text goes
+			// first, as the gateway sends it.
+			req.ContentBlocks = append([]model.ContentBlock{{Type: model.ContentBlockText, Text: prompt}}, images...)
+			req.Prompt = ""
+		}
+		runCtx, end := interrupts.start(ctx)
+		started := time.Now()
+		resp, err := rt.Run(runCtx, req)
+		if end() {
+			err = errInterrupted
+		} else {
+			err = withExitCode(exitProvider, err)
+		}
+		if agentJSONFlag {
+			return writeAgentJSON(stdout, "cli", resp, err, time.Since(started))
+		}
+		if errors.Is(err, errInterrupted) {
+			return err
+		}
 		if err != nil {
 			return fmt.Errorf("agent error: %w", err)
 		}
 		if resp != nil && resp.Result != nil {
-			fmt.Fprintln(stdout, resp.Result.Output)
+			fmt.Fprintln(stdout, render(resp.Result.Output))
 		}
 		return nil
 	}
 
+	if voiceMode != nil {
+		voiceMode.session, voiceMode.interrupts, voiceMode.render = session, interrupts, render
+		voiceMode.stdout, voiceMode.info, voiceMode.stderr = stdout, info, stderr
+		return voiceMode.run(ctx, stdin)
+	}
+
 	// REPL mode
-	fmt.Fprintln(stdout, "myclaw agent (type 'exit' to quit)")
-	scanner := bufio.NewScanner(stdin)
+	fmt.Fprintln(info, "myclaw agent (type 'exit' to quit, '/help' for commands)")
+	rl := readline.New(stdin, info)
+	if rl.Interactive() {
+		if err := rl.LoadHistory(replHistoryPath(cfg)); err != nil {
+			fmt.Fprintf(stderr, "Warning: load history: %v\n", err)
+		}
+	}
+	// Tool calls that need approval are asked about here; in single
+	// message mode nobody is asked and they are denied.
+	ctx = permission.WithAsker(ctx, terminalAsker(rl, info))
 	for {
-		fmt.Fprint(stdout, "\n> ")
-		if !scanner.Scan() {
+		fmt.Fprintln(info)
+		input, err := rl.ReadInput("> ", "... ")
+		if errors.Is(err, readline.ErrInterrupt) {
+			continue
+		}
+		if err != nil {
 			break
 		}
-		input := strings.TrimSpace(scanner.Text())
+		input = strings.TrimSpace(input)
 		if input == "" {
 			continue
 		}
+		if err := rl.AddHistory(input); err != nil {
+			fmt.Fprintf(stderr, "Warning: save history: %v\n", err)
+		}
 		if input == "exit" || input == "quit" {
 			break
 		}
+		runCtx, end := interrupts.start(ctx)
+		if session.handleSlash(runCtx, input) {
+			end()
+			continue
+		}
 
-		resp, err := rt.Run(ctx, api.Request{
-			Prompt:    input,
-			SessionID: "cli-repl",
-		})
+		resp, err := session.prompt(runCtx, input)
+		if end() {
+			fmt.Fprintln(stderr, "Interrupted.")
+			continue
+		}
 		if err != nil {
 			fmt.Fprintf(stderr, "Error: %v\n", err)
 			continue
 		}
 		if resp != nil && resp.Result != nil {
-			fmt.Fprintln(stdout, resp.Result.Output)
+			fmt.Fprintln(stdout, render(resp.Result.Output))
 		}
 	}
 	return nil
@@ -265,7 +747,13 @@ func runGateway(cmd *cobra.Command, args []string) error {
 	}
 
 	if cfg.Provider.APIKey == "" {
-		return fmt.Errorf("API key not set. Run 'myclaw onboard' or set MYCLAW_API_KEY / ANTHROPIC_API_KEY")
+		return fmt.Errorf("API key not set. Run 'myclaw onboard' or set MYCLAW_API_KEY / ANTHROPIC_API_KEY / OPENAI_API_KEY / GOOGLE_API_KEY")
+	}
+	// Typos in key names would otherwise be ignored without a word.
+	if warnings, err := config.CheckFile(config.ConfigPath()); err == nil {
+		for _, w := range warnings {
+			log.Printf("[config] %s", w)
+		}
 	}
 
 	gw, err := gateway.New(cfg)
@@ -277,10 +765,9 @@ func runGateway(cmd *cobra.Command, args []string) error {
 }
 
 func runOnboard(cmd *cobra.Command, args []string) error {
-	cfgDir := config.ConfigDir()
 	cfgPath := config.ConfigPath()
 
-	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0755); err != nil {
 		return fmt.Errorf("create config dir: %w", err)
 	}
 
@@ -295,7 +782,10 @@ func runOnboard(cmd *cobra.Command, args []string) error {
 		fmt.Printf("Config already exists: %s\n", cfgPath)
 	}
 
-	cfg, _ := config.LoadConfig()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
 	ws := cfg.Agent.Workspace
 	if err := os.MkdirAll(filepath.Join(ws, "memory"), 0755); err != nil {
 		return fmt.Errorf("create workspace: %w", err)
@@ -304,6 +794,13 @@ func runOnboard(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("create skills dir: %w", err)
 	}
 
+	if ref := readTemplateFlag(cmd, args); ref != "" {
+		force, _ := cmd.Flags().GetBool("force")
+		if err := applyTemplate(cfg, ref, force); err != nil {
+			return err
+		}
+	}
+
 	writeIfNotExists(filepath.Join(ws, "AGENTS.md"), defaultAgentsMD)
 	writeIfNotExists(filepath.Join(ws, "SOUL.md"), defaultSoulMD)
 	writeIfNotExists(filepath.Join(ws, "memory", "MEMORY.md"), "")
@@ -321,16 +818,25 @@ func runOnboard(cmd *cobra.Command, args []string) error {
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
+	if probe, _ := cmd.Flags().GetBool("probe"); probe {
+		return runStatusProbe(cmd)
+	}
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		fmt.Printf("Config: error (%v)\n", err)
 		return nil
 	}
 
+	if name := config.Profile(); name != "" {
+		fmt.Printf("Profile: %s\n", name)
+	}
 	fmt.Printf("Config: %s\n", config.ConfigPath())
 	fmt.Printf("Workspace: %s\n", cfg.Agent.Workspace)
-	fmt.Printf("Model: %s\n", cfg.Agent.Model)
-	fmt.Printf("Provider: %s\n", providerDisplay(cfg.Provider.Type))
+	if cfg.Project != "" {
+		fmt.Printf("Project: %s\n", cfg.Project)
+	}
+	fmt.Printf("Model: %s\n", modelLabel(cfg))
+	fmt.Printf("Provider: %s\n", provider.Display(cfg.Provider.Type))
 	if cfg.Provider.APIKey != "" && len(cfg.Provider.APIKey) > 8 {
 		masked := cfg.Provider.APIKey[:4] + "..." + cfg.Provider.APIKey[len(cfg.Provider.APIKey)-4:]
 		fmt.Printf("API Key: %s\n", masked)
@@ -342,12 +848,26 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Telegram: enabled=%v\n", cfg.Channels.Telegram.Enabled)
 	fmt.Printf("Feishu: enabled=%v\n", cfg.Channels.Feishu.Enabled)
 	fmt.Printf("WeCom: enabled=%v\n", cfg.Channels.WeCom.Enabled)
+	fmt.Printf("Slack: enabled=%v\n", cfg.Channels.Slack.Enabled)
+	fmt.Printf("Email: enabled=%v\n", cfg.Channels.Email.Enabled)
+	fmt.Printf("WhatsApp: enabled=%v mode=%s\n", cfg.Channels.WhatsApp.Enabled, whatsappModeDisplay(cfg.Channels.WhatsApp.Mode))
+	fmt.Printf("iMessage: enabled=%v\n", cfg.Channels.IMessage.Enabled)
+	fmt.Printf("XMPP: enabled=%v\n", cfg.Channels.XMPP.Enabled)
+	fmt.Printf("Mattermost: enabled=%v\n", cfg.Channels.Mattermost.Enabled)
+	fmt.Printf("Rocket.Chat: enabled=%v\n", cfg.Channels.RocketChat.Enabled)
 	fmt.Printf("Skills: enabled=%v dir=%s\n", cfg.Skills.Enabled, resolveSkillsDir(cfg))
+	if len(cfg.MCP.Servers) > 0 {
+		labels := make([]string, len(cfg.MCP.Servers))
+		for i, server := range cfg.MCP.Servers {
+			labels[i] = server.Label()
+		}
+		fmt.Printf("MCP: %s (connect with 'myclaw status --probe')\n", strings.Join(labels, ", "))
+	}
 
 	if _, err := os.Stat(cfg.Agent.Workspace); err != nil {
 		fmt.Println("Workspace: not found (run 'myclaw onboard')")
 	} else {
-		mem := memory.NewMemoryStore(cfg.Agent.Workspace)
+		mem := memory.NewMemoryStore(cfg.MemoryWorkspace())
 		lt, _ := mem.ReadLongTerm()
 		if lt != "" {
 			fmt.Printf("Memory: %d bytes\n", len(lt))
@@ -359,6 +879,95 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runStatusProbe checks the config and provider here, and asks a running
+// gateway about its channels.
+func runStatusProbe(cmd *cobra.Command) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	probes := []health.Probe{health.Config()}
+	cfg, err := config.LoadConfig()
+	if err == nil {
+		probes = append(probes, health.Provider(cfg, 0))
+		for _, server := range cfg.MCP.Servers {
+			probes = append(probes, health.MCP(server))
+		}
+	}
+	report := health.Run(ctx, probes...)
+
+	gatewayNote := "Gateway: not running, channels not checked"
+	if cfg != nil {
+		if remote, err := probeGateway(ctx, cfg.Gateway); err == nil {
+			gatewayNote = ""
+			for _, c := range remote.Checks {
+				if strings.HasPrefix(c.Name, "channel:") {
+					report.Checks = append(report.Checks, c)
+					report.OK = report.OK && c.OK
+				}
+			}
+		}
+	}
+
+	if readJSONFlag(cmd) {
+		if err := printJSON(map[string]any{
+			"schemaVersion": statusJSONSchemaVersion,
+			"command":       "status.probe",
+			"ok":            report.OK,
+			"gateway":       gatewayNote == "",
+			"checks":        report.Checks,
+		}); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range report.Checks {
+			if c.OK {
+				fmt.Printf("ok    %s\n", c.Name)
+			} else {
+				fmt.Printf("FAIL  %s: %s\n", c.Name, c.Error)
+			}
+		}
+		if gatewayNote != "" {
+			fmt.Println(gatewayNote)
+		}
+	}
+	if !report.OK {
+		cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		return errProbeFailed
+	}
+	return nil
+}
+
+var errProbeFailed = errors.New("health check failed")
+
+// probeGateway fetches the readiness report of a gateway running here.
+func probeGateway(ctx context.Context, gw config.GatewayConfig) (health.Report, error) {
+	host := gw.Host
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	port := gw.Port
+	if port == 0 {
+		port = config.DefaultPort
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+net.JoinHostPort(host, strconv.Itoa(port))+"/readyz", nil)
+	if err != nil {
+		return health.Report{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return health.Report{}, err
+	}
+	defer resp.Body.Close()
+	var report health.Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return health.Report{}, fmt.Errorf("gateway readiness: %w", err)
+	}
+	return report, nil
+}
+
 func runSkillsList(cmd *cobra.Command, args []string) error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -386,12 +995,15 @@ func runSkillsList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	registrations, err := skills.LoadSkills(skillDir)
+	registrations, err := loadSkills(cfg)
 	if err != nil {
 		return fmt.Errorf("load skills: %w", err)
 	}
 
 	if !jsonOutput {
+		if cfg.Project != "" {
+			fmt.Printf("Project skills dir: %s\n", cfg.ProjectSkillsDir())
+		}
 		fmt.Printf("Loaded skills: %d\n", len(registrations))
 	}
 	if len(registrations) == 0 {
@@ -421,6 +1033,7 @@ func runSkillsList(cmd *cobra.Command, args []string) error {
 				"name":        registration.Definition.Name,
 				"description": desc,
 				"keywords":    extractSkillKeywords(registration),
+				"disabled":    skills.IsDisabled(registration.Definition.Name, cfg.Skills.Disabled),
 			})
 		}
 		return printJSON(map[string]any{
@@ -439,6 +1052,9 @@ func runSkillsList(cmd *cobra.Command, args []string) error {
 		if desc == "" {
 			desc = "(no description)"
 		}
+		if skills.IsDisabled(registration.Definition.Name, cfg.Skills.Disabled) {
+			desc += " (disabled)"
+		}
 		fmt.Printf("- %s: %s\n", registration.Definition.Name, desc)
 	}
 
@@ -461,7 +1077,7 @@ func runSkillsInfo(cmd *cobra.Command, args []string) error {
 	}
 
 	skillDir := resolveSkillsDir(cfg)
-	registrations, err := skills.LoadSkills(skillDir)
+	registrations, err := loadSkills(cfg)
 	if err != nil {
 		return fmt.Errorf("load skills: %w", err)
 	}
@@ -486,6 +1102,7 @@ func runSkillsInfo(cmd *cobra.Command, args []string) error {
 		}
 	}
 	keywords := extractSkillKeywords(*registration)
+	params := skills.Parameters(registration.Definition)
 	if jsonOutput {
 		payload := map[string]any{
 			"schemaVersion": skillsJSONSchemaVersion,
@@ -497,6 +1114,15 @@ func runSkillsInfo(cmd *cobra.Command, args []string) error {
 			"keywords":      keywords,
 			"source":        sourcePath,
 			"preview":       preview,
+			"disabled":      skills.IsDisabled(registration.Definition.Name, cfg.Skills.Disabled),
+			"parameters":    []skills.Parameter{},
+		}
+		if command := skills.CommandOf(registration.Definition); command != nil {
+			payload["run"] = command.Run
+		}
+		if len(params) > 0 {
+			payload["parameters"] = params
+			payload["parameterSchema"] = skills.ParameterSchema(params)
 		}
 		if handlerError != "" {
 			payload["handlerError"] = handlerError
@@ -513,6 +1139,9 @@ func runSkillsInfo(cmd *cobra.Command, args []string) error {
 		desc = "(no description)"
 	}
 	fmt.Printf("Description: %s\n", desc)
+	if skills.IsDisabled(registration.Definition.Name, cfg.Skills.Disabled) {
+		fmt.Println("Status: disabled")
+	}
 	fmt.Printf("Skills dir: %s\n", skillDir)
 
 	if len(keywords) == 0 {
@@ -521,6 +1150,30 @@ func runSkillsInfo(cmd *cobra.Command, args []string) error {
 		fmt.Printf("Keywords: %s\n", strings.Join(keywords, ", "))
 	}
 
+	if command := skills.CommandOf(registration.Definition); command != nil {
+		fmt.Printf("Type: command (tool %s%s)\n", skills.ParameterToolPrefix, registration.Definition.Name)
+		fmt.Printf("Run: %s\n", command.Run)
+		if command.Timeout > 0 {
+			fmt.Printf("Timeout: %s\n", command.Timeout)
+		}
+	}
+	if len(params) > 0 {
+		fmt.Printf("Parameters (tool %s%s):\n", skills.ParameterToolPrefix, registration.Definition.Name)
+		for _, p := range params {
+			line := fmt.Sprintf("  - %s (%s", p.Name, p.Type)
+			if p.Required() {
+				line += ", required"
+			} else {
+				line += fmt.Sprintf(", default %v", p.Default)
+			}
+			line += ")"
+			if p.Description != "" {
+				line += ": " + p.Description
+			}
+			fmt.Println(line)
+		}
+	}
+
 	if sourcePath != "" {
 		fmt.Printf("Source: %s\n", sourcePath)
 	}
@@ -634,11 +1287,11 @@ func runSkillsCheck(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func providerDisplay(t string) string {
-	if t == "" {
-		return "anthropic (default)"
+func whatsappModeDisplay(mode string) string {
+	if mode == "" {
+		return config.WhatsAppModeWeb
 	}
-	return t
+	return mode
 }
 
 func resolveSkillsDir(cfg *config.Config) string {
@@ -648,17 +1301,23 @@ func resolveSkillsDir(cfg *config.Config) string {
 	return filepath.Join(cfg.Agent.Workspace, "skills")
 }
 
+// loadSkills loads the workspace's skills and then the project's, which
+// win on a name clash.
+func loadSkills(cfg *config.Config) ([]api.SkillRegistration, error) {
+	return skills.LoadSkillDirs(resolveSkillsDir(cfg), cfg.ProjectSkillsDir())
+}
+
 func loadRuntimeSkills(cfg *config.Config) []api.SkillRegistration {
 	if !cfg.Skills.Enabled {
 		return nil
 	}
 
-	skillRegs, err := skills.LoadSkills(resolveSkillsDir(cfg))
+	skillRegs, err := loadSkills(cfg)
 	if err != nil {
 		log.Printf("[agent] skills load warning: %v", err)
 		return nil
 	}
-	return skillRegs
+	return skills.FilterDisabled(skillRegs, cfg.Skills.Disabled)
 }
 
 func findSkillRegistration(
@@ -738,32 +1397,58 @@ func readJSONFlag(cmd *cobra.Command) bool {
 }
 
 func printJSON(v any) error {
+	return writeJSON(os.Stdout, v)
+}
+
+func writeJSON(w io.Writer, v any) error {
 	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal json: %w", err)
 	}
-	fmt.Println(string(data))
+	fmt.Fprintln(w, string(data))
 	return nil
 }
 
 func buildSystemPrompt(cfg *config.Config, mem *memory.MemoryStore) string {
 	var sb strings.Builder
-
-	if data, err := os.ReadFile(filepath.Join(cfg.Agent.Workspace, "AGENTS.md")); err == nil {
-		sb.Write(data)
-		sb.WriteString("\n\n")
+	for _, section := range systemPromptSections(cfg, mem) {
+		sb.WriteString(section.text)
 	}
+	return sb.String()
+}
+
+// promptSection is one part of the system prompt, as it appears there.
+type promptSection struct {
+	name   string
+	source string
+	text   string
+}
 
-	if data, err := os.ReadFile(filepath.Join(cfg.Agent.Workspace, "SOUL.md")); err == nil {
-		sb.Write(data)
-		sb.WriteString("\n\n")
+// systemPromptSections returns the parts buildSystemPrompt joins, so that
+// the context inspector shows exactly what the runtime is given.
+func systemPromptSections(cfg *config.Config, mem *memory.MemoryStore) []promptSection {
+	var sections []promptSection
+	for _, path := range prompt.Paths(cfg) {
+		if data, err := os.ReadFile(path); err == nil {
+			sections = append(sections, promptSection{name: promptSectionName(cfg, path), source: path, text: string(data) + "\n\n"})
+		}
 	}
 
-	if memCtx := mem.GetMemoryContext(); memCtx != "" {
-		sb.WriteString(memCtx)
+	// With semantic memory, relevant chunks are added per request instead.
+	if !cfg.Memory.Semantic {
+		if memCtx := mem.GetMemoryContext(); memCtx != "" {
+			sections = append(sections, promptSection{name: "Memory", source: filepath.Join(cfg.MemoryWorkspace(), "memory"), text: memCtx})
+		}
 	}
+	return sections
+}
 
-	return sb.String()
+// promptSectionName names an instruction file, marking the project's.
+func promptSectionName(cfg *config.Config, path string) string {
+	if cfg.Project != "" && filepath.Dir(path) == cfg.Project {
+		return "project " + filepath.Base(path)
+	}
+	return filepath.Base(path)
 }
 
 func writeIfNotExists(path, content string) {
@@ -783,7 +1468,9 @@ Use them to help the user accomplish tasks.
 ## Guidelines
 - Be concise and helpful
 - Use tools proactively when needed
-- Remember information the user tells you by writing to memory
+- Remember lasting facts the user tells you by writing to memory/MEMORY.md
+- Note what happened today in memory/YYYY-MM-DD.md (today's date); older days
+  are summarized into MEMORY.md over time
 - Check your memory context for previously stored information
 `
 
@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,8 +17,10 @@ import (
 
 	"github.com/cexll/agentsdk-go/pkg/api"
 	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
+	"github.com/cexll/agentsdk-go/pkg/tool"
 	"github.com/spf13/cobra"
 	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/imagegen"
 	"github.com/stellarlinkco/myclaw/internal/memory"
 )
 
@@ -1027,6 +1033,50 @@ func TestRunAgentWithOptions_SingleMessage(t *testing.T) {
 	}
 }
 
+// drawingRuntime draws an image with image_generate on each run.
+type drawingRuntime struct {
+	mockRuntime
+	draw tool.Tool
+}
+
+func (d *drawingRuntime) Run(ctx context.Context, req api.Request) (*api.Response, error) {
+	if _, err := d.draw.Execute(ctx, map[string]any{"prompt": "a fox"}); err != nil {
+		return nil, err
+	}
+	return d.mockRuntime.Run(ctx, req)
+}
+
+type fakeImageGenerator struct{}
+
+func (fakeImageGenerator) Name() string { return "fake" }
+
+func (fakeImageGenerator) Generate(context.Context, string, string) (*imagegen.Image, error) {
+	return &imagegen.Image{Data: []byte("\x89PNG"), MediaType: "image/png"}, nil
+}
+
+func TestRunAgentWithOptions_SingleMessageImage(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("MYCLAW_API_KEY", "")
+
+	dir := filepath.Join(tmpDir, "images")
+	rt := &drawingRuntime{
+		mockRuntime: mockRuntime{response: &api.Response{Result: &api.Result{Output: "Drawn."}}},
+		draw:        imagegen.NewTool(fakeImageGenerator{}, dir),
+	}
+	oldFlag := messageFlag
+	messageFlag = "draw a fox"
+	defer func() { messageFlag = oldFlag }()
+
+	var stdout bytes.Buffer
+	if err := runAgentWithOptions(AgentOptions{RuntimeFactory: mockRuntimeFactory(rt), Stdout: &stdout}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "Image saved to "+dir) || !strings.Contains(stdout.String(), "Drawn.") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+}
+
 func TestRunAgentWithOptions_REPLMode(t *testing.T) {
 	tmpDir := t.TempDir()
 	origHome := os.Getenv("HOME")
@@ -1226,3 +1276,60 @@ func TestDefaultRuntimeFactory_NoAPIKey(t *testing.T) {
 		t.Errorf("error should mention API key: %v", err)
 	}
 }
+
+func TestRunStatusProbe(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	providerAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": []}`)
+	}))
+	defer providerAPI.Close()
+	gatewaySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"ok": false, "checks": [{"name": "provider", "ok": true}, {"name": "channel:telegram", "ok": false, "error": "send failed: 401"}]}`)
+	}))
+	defer gatewaySrv.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(gatewaySrv.URL, "http://"))
+
+	writeConfig := func(cfg string) {
+		t.Helper()
+		dir := filepath.Join(home, ".myclaw")
+		os.MkdirAll(dir, 0755)
+		if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(cfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	probeCmd := func(jsonOutput bool) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().Bool("probe", true, "")
+		cmd.Flags().Bool("json", jsonOutput, "")
+		return cmd
+	}
+
+	// No gateway is listening on port 1, so only the local checks run.
+	writeConfig(fmt.Sprintf(`{"provider": {"apiKey": "sk-test", "baseUrl": %q}, "gateway": {"host": "127.0.0.1", "port": 1}}`, providerAPI.URL))
+	output, err := captureRunOutput(t, func() error { return runStatus(probeCmd(false), nil) })
+	if err != nil || !strings.Contains(output, "ok    config") || !strings.Contains(output, "ok    provider") || !strings.Contains(output, "Gateway: not running") {
+		t.Errorf("local probe: %v\n%s", err, output)
+	}
+
+	writeConfig(fmt.Sprintf(`{"provider": {"apiKey": "sk-test", "baseUrl": %q}, "gateway": {"host": %q, "port": %s}}`, providerAPI.URL, host, port))
+	output, err = captureRunOutput(t, func() error { return runStatus(probeCmd(true), nil) })
+	if !errors.Is(err, errProbeFailed) {
+		t.Fatalf("err = %v, want errProbeFailed", err)
+	}
+	var payload struct {
+		OK      bool `json:"ok"`
+		Gateway bool `json:"gateway"`
+		Checks  []struct {
+			Name  string `json:"name"`
+			Error string `json:"error"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if payload.OK || !payload.Gateway || len(payload.Checks) != 3 || payload.Checks[2].Name != "channel:telegram" {
+		t.Errorf("payload = %+v", payload)
+	}
+}
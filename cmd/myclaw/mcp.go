@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/mcp"
+)
+
+const mcpJSONSchemaVersion = 1
+
+// mcpConnectTimeout bounds a handshake; a stdio server fetched by npx or
+// uvx on first use can take a while to start.
+const mcpConnectTimeout = 60 * time.Second
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Manage the MCP servers whose tools the agent gets",
+	Long: `Manage the MCP servers in mcp.servers. The agent gets their tools in the
+CLI and through the gateway, which picks up changes on its next reload.`,
+}
+
+var mcpListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the configured MCP servers",
+	Args:  cobra.NoArgs,
+	RunE:  runMCPList,
+}
+
+var mcpAddCmd = &cobra.Command{
+	Use:   "add <name> <command> [args...] | <name> <url>",
+	Short: "Add an MCP server, after checking that it connects",
+	Long: `Add an MCP server: a command run over stdio, or the URL of a remote server.
+
+  myclaw mcp add files -- npx -y @modelcontextprotocol/server-filesystem ~/notes
+  myclaw mcp add docs https://mcp.example.com/mcp
+  myclaw mcp add legacy https://mcp.example.com/sse --transport sse
+
+The server is started or connected to and its tools listed before it is
+saved; --no-test saves it without connecting. Put -- before a command so
+that its flags are not read as myclaw's.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runMCPAdd,
+}
+
+var mcpRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove an MCP server",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMCPRemove,
+}
+
+var mcpTestCmd = &cobra.Command{
+	Use:   "test <name>",
+	Short: "Connect to an MCP server and list its tools",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMCPTest,
+}
+
+func init() {
+	mcpListCmd.Flags().Bool("json", false, "Output as JSON")
+	mcpAddCmd.Flags().String("transport", "", "For a URL: http (streamable HTTP, default) or sse")
+	mcpAddCmd.Flags().Bool("no-test", false, "Save without connecting to the server")
+	mcpAddCmd.Flags().Bool("json", false, "Output as JSON")
+	mcpRemoveCmd.Flags().Bool("json", false, "Output as JSON")
+	mcpTestCmd.Flags().Bool("json", false, "Output as JSON")
+	mcpCmd.AddCommand(mcpListCmd, mcpAddCmd, mcpRemoveCmd, mcpTestCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
+
+// inspectMCP is mcp.Inspect, replaced in tests.
+var inspectMCP = mcp.Inspect
+
+func runMCPList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	servers := cfg.MCP.Servers
+	if readJSONFlag(cmd) {
+		if servers == nil {
+			servers = []config.MCPServer{}
+		}
+		return printJSON(map[string]any{
+			"schemaVersion": mcpJSONSchemaVersion,
+			"command":       "mcp.list",
+			"ok":            true,
+			"servers":       servers,
+		})
+	}
+	if len(servers) == 0 {
+		fmt.Println(`No MCP servers; add one with "myclaw mcp add".`)
+		return nil
+	}
+	for _, s := range servers {
+		name := s.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+		fmt.Printf("%-16s %s\n", name, s.Spec)
+	}
+	return nil
+}
+
+func runMCPAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	transport, _ := cmd.Flags().GetString("transport")
+	spec, err := mcp.Spec(args[1:], transport)
+	if err != nil {
+		return err
+	}
+	server := config.MCPServer{Name: name, Spec: spec}
+	if err := server.Validate(); err != nil {
+		return err
+	}
+	if err := mcp.Validate(spec); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	var info *mcp.Info
+	if noTest, _ := cmd.Flags().GetBool("no-test"); !noTest {
+		if info, err = connectMCP(cmd, spec); err != nil {
+			return fmt.Errorf("%s: %w (use --no-test to add it anyway)", name, err)
+		}
+	}
+	err = config.UpdateConfig(func(cfg *config.Config) error {
+		if _, ok := cfg.MCP.Server(name); ok {
+			return fmt.Errorf("an MCP server named %q exists; remove it first", name)
+		}
+		cfg.MCP.Servers = append(cfg.MCP.Servers, server)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": mcpJSONSchemaVersion,
+			"command":       "mcp.add",
+			"ok":            true,
+			"server":        server,
+			"info":          info,
+		})
+	}
+	if info != nil {
+		printMCPInfo(info)
+	}
+	fmt.Printf("Added MCP server %s (%s).\n", name, spec)
+	return nil
+}
+
+func runMCPRemove(cmd *cobra.Command, args []string) error {
+	var removed config.MCPServer
+	err := config.UpdateConfig(func(cfg *config.Config) error {
+		server, ok := cfg.MCP.Server(args[0])
+		if !ok {
+			return fmt.Errorf("no MCP server named %q", args[0])
+		}
+		removed = server
+		kept := cfg.MCP.Servers[:0]
+		for _, s := range cfg.MCP.Servers {
+			if s != server {
+				kept = append(kept, s)
+			}
+		}
+		cfg.MCP.Servers = kept
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": mcpJSONSchemaVersion,
+			"command":       "mcp.remove",
+			"ok":            true,
+			"server":        removed,
+		})
+	}
+	fmt.Printf("Removed MCP server %s.\n", removed.Label())
+	return nil
+}
+
+func runMCPTest(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	server, ok := cfg.MCP.Server(args[0])
+	if !ok {
+		return fmt.Errorf("no MCP server named %q", args[0])
+	}
+	info, err := connectMCP(cmd, server.Spec)
+	if err != nil {
+		return fmt.Errorf("%s: %w", server.Label(), err)
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": mcpJSONSchemaVersion,
+			"command":       "mcp.test",
+			"ok":            true,
+			"server":        server,
+			"info":          info,
+		})
+	}
+	printMCPInfo(info)
+	return nil
+}
+
+func connectMCP(cmd *cobra.Command, spec string) (*mcp.Info, error) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, mcpConnectTimeout)
+	defer cancel()
+	return inspectMCP(ctx, spec)
+}
+
+func printMCPInfo(info *mcp.Info) {
+	server := info.Server
+	if server == "" {
+		server = "server"
+	}
+	if info.Version != "" {
+		server += " " + info.Version
+	}
+	fmt.Printf("Connected to %s (protocol %s), %d tools:\n", server, info.Protocol, len(info.Tools))
+	for _, t := range info.Tools {
+		desc := firstLine(t.Description)
+		if desc == "" {
+			fmt.Printf("  %s\n", t.Name)
+			continue
+		}
+		fmt.Printf("  %-24s %s\n", t.Name, desc)
+	}
+}
+
+// firstLine returns the first line of s, trimmed.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
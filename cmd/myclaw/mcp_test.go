@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/mcp"
+)
+
+func TestRunMCP(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	orig := inspectMCP
+	defer func() { inspectMCP = orig }()
+	var inspected []string
+	inspectMCP = func(_ context.Context, spec string) (*mcp.Info, error) {
+		inspected = append(inspected, spec)
+		if strings.Contains(spec, "broken") {
+			return nil, errors.New("connect: EOF")
+		}
+		return &mcp.Info{Server: "docs", Version: "2.0", Protocol: "2025-06-18", Tools: []mcp.Tool{{Name: "search_docs", Description: "Search the docs.\nFull text."}}}, nil
+	}
+	newCmd := func(noTest bool) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.SetContext(context.Background())
+		cmd.Flags().Bool("json", false, "")
+		cmd.Flags().String("transport", "", "")
+		cmd.Flags().Bool("no-test", noTest, "")
+		return cmd
+	}
+
+	output, err := captureRunOutput(t, func() error {
+		return runMCPAdd(newCmd(false), []string{"docs", "https://docs.example.com/mcp"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "Connected to docs 2.0") || !strings.Contains(output, "search_docs") || strings.Contains(output, "Full text") {
+		t.Errorf("add = %q", output)
+	}
+	if len(inspected) != 1 || inspected[0] != "https+stream://docs.example.com/mcp" {
+		t.Errorf("inspected %q", inspected)
+	}
+
+	if err := runMCPAdd(newCmd(false), []string{"docs", "https://other.example.com/mcp"}); err == nil || !strings.Contains(err.Error(), "exists") {
+		t.Errorf("duplicate add = %v", err)
+	}
+	if err := runMCPAdd(newCmd(false), []string{"my docs", "https://other.example.com/mcp"}); err == nil || !strings.Contains(err.Error(), "name") {
+		t.Errorf("bad name = %v", err)
+	}
+	if err := runMCPAdd(newCmd(false), []string{"broken", "https://broken.example.com/mcp"}); err == nil || !strings.Contains(err.Error(), "--no-test") {
+		t.Errorf("failed handshake = %v", err)
+	}
+	if _, err := captureRunOutput(t, func() error {
+		return runMCPAdd(newCmd(true), []string{"broken", "https://broken.example.com/mcp"})
+	}); err != nil {
+		t.Fatalf("add --no-test = %v", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.MCP.Specs(); len(got) != 2 || got[1] != "https+stream://broken.example.com/mcp" {
+		t.Errorf("saved specs = %q", got)
+	}
+
+	if err := runMCPTest(newCmd(false), []string{"broken"}); err == nil || !strings.Contains(err.Error(), "broken: connect") {
+		t.Errorf("test broken = %v", err)
+	}
+	if _, err := captureRunOutput(t, func() error { return runMCPRemove(newCmd(false), []string{"broken"}) }); err != nil {
+		t.Fatal(err)
+	}
+	output, err = captureRunOutput(t, func() error { return runMCPList(newCmd(false), nil) })
+	if err != nil || strings.Contains(output, "broken") || !strings.Contains(output, "docs") {
+		t.Errorf("list = %q, %v", output, err)
+	}
+	if err := runMCPRemove(newCmd(false), []string{"broken"}); err == nil {
+		t.Error("expected an error removing a missing server")
+	}
+}
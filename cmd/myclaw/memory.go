@@ -0,0 +1,555 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+const memoryJSONSchemaVersion = 1
+
+var memoryCmd = &cobra.Command{
+	Use:   "memory",
+	Short: "Inspect and edit long-term memory",
+}
+
+var memoryShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show long-term memory (MEMORY.md)",
+	Args:  cobra.NoArgs,
+	RunE:  runMemoryShow,
+}
+
+var memoryAddCmd = &cobra.Command{
+	Use:   "add <text>",
+	Short: "Add a line to long-term memory",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runMemoryAdd,
+}
+
+var memoryEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open MEMORY.md in $VISUAL or $EDITOR",
+	Args:  cobra.NoArgs,
+	RunE:  runMemoryEdit,
+}
+
+var memoryClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Erase long-term memory",
+	Args:  cobra.NoArgs,
+	RunE:  runMemoryClear,
+}
+
+var memorySearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search memory by meaning",
+	Long: `Search MEMORY.md and the daily journal by meaning.
+
+The vector index (workspace/memory/vectors.db) is updated first, embedding
+only new or changed sections with the provider from memory.embedding.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMemorySearch,
+}
+
+var memoryCompactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "Have the agent rewrite MEMORY.md more concisely",
+	Long: `Have the agent rewrite MEMORY.md more concisely, merging duplicates and
+dropping outdated entries. The previous version is kept as MEMORY.md.bak.`,
+	Args: cobra.NoArgs,
+	RunE: runMemoryCompact,
+}
+
+var memoryExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export MEMORY.md and the journal",
+	Long: `Export MEMORY.md and every journal file, archived ones included, as one
+markdown document or as JSON. Both formats can be read by "memory import".`,
+	Args: cobra.NoArgs,
+	RunE: runMemoryExport,
+}
+
+var memoryImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import memory written by memory export",
+	Long: `Import memory written by "memory export" ("-" reads standard input).
+
+By default the import is merged: lines missing from MEMORY.md or a journal
+file are appended, so importing the same file twice changes nothing. With
+--replace, MEMORY.md and the imported journal files are overwritten.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMemoryImport,
+}
+
+var memorySyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Commit memory to git and sync it with memory.sync.remote",
+	Args:  cobra.NoArgs,
+	RunE:  runMemorySync,
+}
+
+func init() {
+	memoryShowCmd.Flags().Int("days", 0, "Also show this many days of journal")
+	memoryShowCmd.Flags().Bool("json", false, "Output as JSON")
+	memoryAddCmd.Flags().Bool("journal", false, "Add to today's journal instead")
+	memoryAddCmd.Flags().Bool("json", false, "Output as JSON")
+	memoryClearCmd.Flags().Bool("all", false, "Also delete the journal and search index")
+	memoryClearCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+	memoryClearCmd.Flags().Bool("json", false, "Output as JSON")
+	memorySearchCmd.Flags().IntP("limit", "n", 0, "Maximum results (default memory.topK)")
+	memorySearchCmd.Flags().Bool("json", false, "Output as JSON")
+	memoryCompactCmd.Flags().Bool("json", false, "Output as JSON")
+	memoryExportCmd.Flags().String("format", "md", "Output format: md or json")
+	memoryExportCmd.Flags().StringP("output", "o", "", "Write to this file instead of standard output")
+	memoryImportCmd.Flags().Bool("replace", false, "Overwrite instead of merging")
+	memoryImportCmd.Flags().Bool("json", false, "Output as JSON")
+	memorySyncCmd.Flags().Bool("json", false, "Output as JSON")
+	memoryCmd.AddCommand(memoryShowCmd, memoryAddCmd, memoryEditCmd, memoryClearCmd, memorySearchCmd, memoryCompactCmd,
+		memoryExportCmd, memoryImportCmd, memorySyncCmd)
+	rootCmd.AddCommand(memoryCmd)
+}
+
+func openMemoryStore() (*config.Config, *memory.MemoryStore, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("load config: %w", err)
+	}
+	return cfg, memory.NewMemoryStore(cfg.MemoryWorkspace()), nil
+}
+
+func runMemoryShow(cmd *cobra.Command, args []string) error {
+	_, mem, err := openMemoryStore()
+	if err != nil {
+		return err
+	}
+	content, err := mem.ReadLongTerm()
+	if err != nil {
+		return fmt.Errorf("read memory: %w", err)
+	}
+	days, _ := cmd.Flags().GetInt("days")
+	var journal string
+	if days > 0 {
+		if journal, err = mem.GetRecentMemories(days); err != nil {
+			return fmt.Errorf("read journal: %w", err)
+		}
+	}
+
+	if readJSONFlag(cmd) {
+		payload := map[string]any{
+			"schemaVersion": memoryJSONSchemaVersion,
+			"command":       "memory.show",
+			"ok":            true,
+			"path":          mem.LongTermPath(),
+			"bytes":         len(content),
+			"content":       content,
+		}
+		if days > 0 {
+			payload["journal"] = journal
+		}
+		return printJSON(payload)
+	}
+
+	if strings.TrimSpace(content) == "" {
+		fmt.Println("Memory is empty.")
+	} else {
+		fmt.Println(strings.TrimSpace(content))
+	}
+	if strings.TrimSpace(journal) != "" {
+		fmt.Printf("\n# Recent Journal\n%s", journal)
+	}
+	return nil
+}
+
+func runMemoryAdd(cmd *cobra.Command, args []string) error {
+	_, mem, err := openMemoryStore()
+	if err != nil {
+		return err
+	}
+	text := strings.TrimSpace(strings.Join(args, " "))
+	if text == "" {
+		return fmt.Errorf("nothing to add")
+	}
+	toJournal, _ := cmd.Flags().GetBool("journal")
+
+	target := "memory"
+	if toJournal {
+		target = "journal"
+		err = mem.AppendToday(text)
+	} else {
+		if !strings.HasPrefix(text, "-") && !strings.HasPrefix(text, "#") {
+			text = "- " + text
+		}
+		err = mem.AppendLongTerm(text)
+	}
+	if err != nil {
+		return fmt.Errorf("write %s: %w", target, err)
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": memoryJSONSchemaVersion,
+			"command":       "memory.add",
+			"ok":            true,
+			"target":        target,
+			"text":          text,
+		})
+	}
+	fmt.Printf("Added to %s.\n", target)
+	return nil
+}
+
+func runMemoryEdit(cmd *cobra.Command, args []string) error {
+	_, mem, err := openMemoryStore()
+	if err != nil {
+		return err
+	}
+	path := mem.LongTermPath()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := mem.WriteLongTerm(""); err != nil {
+			return fmt.Errorf("create memory: %w", err)
+		}
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	fields := strings.Fields(editor)
+	c := exec.Command(fields[0], append(fields[1:], path)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("run editor %q: %w", editor, err)
+	}
+	return nil
+}
+
+func runMemoryClear(cmd *cobra.Command, args []string) error {
+	_, mem, err := openMemoryStore()
+	if err != nil {
+		return err
+	}
+	all, _ := cmd.Flags().GetBool("all")
+	yes, _ := cmd.Flags().GetBool("yes")
+	jsonOutput := readJSONFlag(cmd)
+
+	if !yes {
+		if jsonOutput {
+			return fmt.Errorf("refusing to clear memory without --yes")
+		}
+		what := "MEMORY.md"
+		if all {
+			what = "MEMORY.md, the journal and the search index"
+		}
+		fmt.Printf("Erase %s? [y/N] ", what)
+		if !confirm(cmd.InOrStdin()) {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+	if err := mem.Clear(all); err != nil {
+		return fmt.Errorf("clear memory: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string]any{
+			"schemaVersion": memoryJSONSchemaVersion,
+			"command":       "memory.clear",
+			"ok":            true,
+			"all":           all,
+		})
+	}
+	fmt.Println("Memory cleared.")
+	return nil
+}
+
+func confirm(r io.Reader) bool {
+	line, _ := bufio.NewReader(r).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
+
+func runMemorySearch(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	limit, _ := cmd.Flags().GetInt("limit")
+	if limit <= 0 {
+		limit = cfg.Memory.TopK
+	}
+	query := strings.Join(args, " ")
+
+	vs, err := memory.OpenConfigured(cfg)
+	if err != nil {
+		return err
+	}
+	defer vs.Close()
+	results, err := vs.Search(context.Background(), query, limit)
+	if err != nil {
+		return fmt.Errorf("search memory: %w", err)
+	}
+
+	if readJSONFlag(cmd) {
+		if results == nil {
+			results = []memory.SearchResult{}
+		}
+		return printJSON(map[string]any{
+			"schemaVersion": memoryJSONSchemaVersion,
+			"command":       "memory.search",
+			"ok":            true,
+			"query":         query,
+			"count":         len(results),
+			"results":       results,
+		})
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No matching memory.")
+		return nil
+	}
+	for _, r := range results {
+		label := r.Source
+		if r.Heading != "" {
+			label += " > " + r.Heading
+		}
+		fmt.Printf("[%.2f] %s\n", r.Score, label)
+		for _, line := range strings.Split(r.Content, "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func runMemoryExport(cmd *cobra.Command, args []string) error {
+	_, mem, err := openMemoryStore()
+	if err != nil {
+		return err
+	}
+	snap, err := mem.Export()
+	if err != nil {
+		return fmt.Errorf("export memory: %w", err)
+	}
+
+	var data []byte
+	switch format, _ := cmd.Flags().GetString("format"); format {
+	case "md", "markdown":
+		data = snap.MarshalMarkdown()
+	case "json":
+		if data, err = json.MarshalIndent(snap, "", "  "); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("unknown format %q (want md or json)", format)
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(output, data, 0600); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported memory (%d journal files) to %s\n", len(snap.Journal), output)
+	return nil
+}
+
+func runMemoryImport(cmd *cobra.Command, args []string) error {
+	_, mem, err := openMemoryStore()
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if args[0] == "-" {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("read import: %w", err)
+	}
+	snap, err := memory.ParseSnapshot(data)
+	if err != nil {
+		return err
+	}
+	replace, _ := cmd.Flags().GetBool("replace")
+	result, err := mem.Import(snap, replace)
+	if err != nil {
+		return fmt.Errorf("import memory: %w", err)
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": memoryJSONSchemaVersion,
+			"command":       "memory.import",
+			"ok":            true,
+			"replace":       replace,
+			"longTerm":      result.LongTerm,
+			"journal":       result.Journal,
+		})
+	}
+	if !result.LongTerm && result.Journal == 0 {
+		fmt.Println("Memory already up to date.")
+		return nil
+	}
+	longTerm := "unchanged"
+	if result.LongTerm {
+		longTerm = "updated"
+	}
+	fmt.Printf("Imported memory: MEMORY.md %s, %d journal files written.\n", longTerm, result.Journal)
+	return nil
+}
+
+func runMemorySync(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	result, err := memory.NewGitSync(cfg.Agent.Workspace, cfg.Memory.Sync).Sync(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": memoryJSONSchemaVersion,
+			"command":       "memory.sync",
+			"ok":            true,
+			"remote":        cfg.Memory.Sync.Remote,
+			"committed":     result.Committed,
+			"pulled":        result.Pulled,
+			"pushed":        result.Pushed,
+		})
+	}
+	switch {
+	case cfg.Memory.Sync.Remote == "" && result.Committed:
+		fmt.Println("Committed memory changes (no memory.sync.remote configured).")
+	case cfg.Memory.Sync.Remote == "":
+		fmt.Println("No memory changes (no memory.sync.remote configured).")
+	case result == (memory.SyncResult{}):
+		fmt.Println("Memory already in sync.")
+	default:
+		fmt.Printf("Memory synced with %s (committed: %v, pulled: %v, pushed: %v).\n", cfg.Memory.Sync.Remote, result.Committed, result.Pulled, result.Pushed)
+	}
+	return nil
+}
+
+const memoryCompactSessionID = "memory-compact"
+
+const memoryCompactPrompt = `Rewrite the long-term memory file below so it is shorter but loses no
+useful fact. Merge duplicates, drop entries that later entries contradict or
+make obsolete, and group related facts under markdown headings. Reply with
+the new file content only, without code fences or commentary.
+
+---
+%s`
+
+func runMemoryCompact(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	return runMemoryCompactWithOptions(cmd, cfg, AgentOptions{RuntimeFactory: DefaultRuntimeFactory})
+}
+
+func runMemoryCompactWithOptions(cmd *cobra.Command, cfg *config.Config, opts AgentOptions) error {
+	mem := memory.NewMemoryStore(cfg.MemoryWorkspace())
+	before, err := mem.ReadLongTerm()
+	if err != nil {
+		return fmt.Errorf("read memory: %w", err)
+	}
+	jsonOutput := readJSONFlag(cmd)
+	if strings.TrimSpace(before) == "" {
+		if jsonOutput {
+			return printJSON(map[string]any{
+				"schemaVersion": memoryJSONSchemaVersion,
+				"command":       "memory.compact",
+				"ok":            true,
+				"changed":       false,
+				"bytesBefore":   0,
+				"bytesAfter":    0,
+			})
+		}
+		fmt.Println("Memory is empty; nothing to compact.")
+		return nil
+	}
+
+	// The memory is in the prompt already; skip recall and extraction.
+	runCfg := *cfg
+	runCfg.Memory.Semantic = false
+	runCfg.Memory.AutoExtract = false
+	rt, err := opts.RuntimeFactory(&runCfg)
+	if err != nil {
+		return err
+	}
+	defer rt.Close()
+	defer session.NewStore(cfg.Agent.Workspace).Delete(memoryCompactSessionID)
+
+	resp, err := rt.Run(context.Background(), api.Request{
+		Prompt:    fmt.Sprintf(memoryCompactPrompt, before),
+		SessionID: memoryCompactSessionID,
+	})
+	if err != nil {
+		return fmt.Errorf("compact memory: %w", err)
+	}
+	after := ""
+	if resp != nil && resp.Result != nil {
+		after = stripCodeFence(resp.Result.Output)
+	}
+	if after == "" {
+		return fmt.Errorf("compact memory: agent returned nothing; memory left unchanged")
+	}
+
+	if err := os.WriteFile(mem.LongTermPath()+".bak", []byte(before), 0644); err != nil {
+		return fmt.Errorf("back up memory: %w", err)
+	}
+	if err := mem.WriteLongTerm(after + "\n"); err != nil {
+		return fmt.Errorf("write memory: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string]any{
+			"schemaVersion": memoryJSONSchemaVersion,
+			"command":       "memory.compact",
+			"ok":            true,
+			"changed":       true,
+			"bytesBefore":   len(before),
+			"bytesAfter":    len(after) + 1,
+			"backup":        mem.LongTermPath() + ".bak",
+		})
+	}
+	fmt.Printf("Compacted memory: %d -> %d bytes (previous version in %s.bak)\n", len(before), len(after)+1, mem.LongTermPath())
+	return nil
+}
+
+// stripCodeFence removes a markdown code fence wrapped around the whole reply.
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") {
+		return s
+	}
+	s = strings.TrimSuffix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	} else {
+		s = ""
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+)
+
+func seedMemory(t *testing.T) *memory.MemoryStore {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	mem := memory.NewMemoryStore(filepath.Join(home, ".myclaw", "workspace"))
+	if err := mem.WriteLongTerm("# Pets\nThe dog is called Biscuit.\n\n# Work\nWrites Go for a living."); err != nil {
+		t.Fatal(err)
+	}
+	return mem
+}
+
+func TestRunMemorySearch(t *testing.T) {
+	seedMemory(t)
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Int("limit", 1, "")
+	output, err := captureRunOutput(t, func() error {
+		return runMemorySearch(cmd, []string{"what", "is", "the", "dog", "called"})
+	})
+	if err != nil {
+		t.Fatalf("runMemorySearch error: %v", err)
+	}
+	if !strings.Contains(output, "MEMORY.md > Pets") || !strings.Contains(output, "Biscuit") || strings.Contains(output, "Writes Go") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
+
+func TestRunMemorySearch_JSON(t *testing.T) {
+	seedMemory(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runMemorySearch(buildJSONCommand(), []string{"golang work"})
+	})
+	if err != nil {
+		t.Fatalf("runMemorySearch error: %v", err)
+	}
+	var payload struct {
+		SchemaVersion int                   `json:"schemaVersion"`
+		Command       string                `json:"command"`
+		OK            bool                  `json:"ok"`
+		Query         string                `json:"query"`
+		Results       []memory.SearchResult `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if payload.Command != "memory.search" || !payload.OK || payload.Query != "golang work" || len(payload.Results) == 0 || payload.Results[0].Heading != "Work" {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestBuildSystemPrompt_SemanticMemory(t *testing.T) {
+	mem := seedMemory(t)
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: t.TempDir()}}
+	cfg.Memory.Semantic = true
+
+	if prompt := buildSystemPrompt(cfg, mem); strings.Contains(prompt, "Biscuit") {
+		t.Errorf("semantic memory should keep MEMORY.md out of the system prompt: %q", prompt)
+	}
+}
+
+func memoryCommand(flags map[string]string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", false, "")
+	cmd.Flags().Bool("journal", false, "")
+	cmd.Flags().Bool("all", false, "")
+	cmd.Flags().Bool("yes", false, "")
+	cmd.Flags().Int("days", 0, "")
+	cmd.Flags().String("format", "md", "")
+	cmd.Flags().String("output", "", "")
+	cmd.Flags().Bool("replace", false, "")
+	for k, v := range flags {
+		_ = cmd.Flags().Set(k, v)
+	}
+	return cmd
+}
+
+func TestRunMemoryShow(t *testing.T) {
+	mem := seedMemory(t)
+	mem.AppendToday("Went climbing.")
+
+	output, err := captureRunOutput(t, func() error {
+		return runMemoryShow(memoryCommand(map[string]string{"days": "1"}), nil)
+	})
+	if err != nil {
+		t.Fatalf("runMemoryShow error: %v", err)
+	}
+	if !strings.Contains(output, "Biscuit") || !strings.Contains(output, "Went climbing.") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	output, _ = captureRunOutput(t, func() error {
+		return runMemoryShow(memoryCommand(map[string]string{"json": "true"}), nil)
+	})
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if payload["command"] != "memory.show" || payload["ok"] != true || !strings.Contains(payload["content"].(string), "Biscuit") {
+		t.Errorf("payload = %v", payload)
+	}
+	if _, ok := payload["journal"]; ok {
+		t.Error("journal should only be included with --days")
+	}
+}
+
+func TestRunMemoryAdd(t *testing.T) {
+	mem := seedMemory(t)
+
+	if _, err := captureRunOutput(t, func() error {
+		return runMemoryAdd(memoryCommand(nil), []string{"prefers", "tea"})
+	}); err != nil {
+		t.Fatalf("runMemoryAdd error: %v", err)
+	}
+	content, _ := mem.ReadLongTerm()
+	if !strings.HasSuffix(content, "Writes Go for a living.\n- prefers tea\n") {
+		t.Errorf("memory = %q", content)
+	}
+
+	output, err := captureRunOutput(t, func() error {
+		return runMemoryAdd(memoryCommand(map[string]string{"journal": "true", "json": "true"}), []string{"called mom"})
+	})
+	if err != nil || !strings.Contains(output, `"target": "journal"`) {
+		t.Fatalf("journal add = %q, %v", output, err)
+	}
+	if today, _ := mem.ReadToday(); today != "called mom\n" {
+		t.Errorf("journal = %q", today)
+	}
+}
+
+func TestRunMemoryClear(t *testing.T) {
+	mem := seedMemory(t)
+	mem.AppendToday("entry")
+
+	cmd := memoryCommand(nil)
+	cmd.SetIn(strings.NewReader("n\n"))
+	output, _ := captureRunOutput(t, func() error { return runMemoryClear(cmd, nil) })
+	if !strings.Contains(output, "Aborted.") {
+		t.Errorf("output = %q", output)
+	}
+	if content, _ := mem.ReadLongTerm(); content == "" {
+		t.Error("memory cleared without confirmation")
+	}
+
+	if _, err := captureRunOutput(t, func() error {
+		return runMemoryClear(memoryCommand(map[string]string{"json": "true"}), nil)
+	}); err == nil {
+		t.Error("--json without --yes should fail instead of prompting")
+	}
+
+	cmd = memoryCommand(map[string]string{"all": "true"})
+	cmd.SetIn(strings.NewReader("y\n"))
+	if _, err := captureRunOutput(t, func() error { return runMemoryClear(cmd, nil) }); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := mem.ReadLongTerm(); content != "" {
+		t.Errorf("memory = %q", content)
+	}
+	if today, _ := mem.ReadToday(); today != "" {
+		t.Errorf("journal = %q", today)
+	}
+}
+
+func TestRunMemoryEdit(t *testing.T) {
+	mem := seedMemory(t)
+	script := filepath.Join(t.TempDir(), "editor.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\necho '- edited' >> \"$1\"\n"), 0755)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", script)
+
+	if err := runMemoryEdit(memoryCommand(nil), nil); err != nil {
+		t.Fatalf("runMemoryEdit error: %v", err)
+	}
+	if content, _ := mem.ReadLongTerm(); !strings.HasSuffix(content, "- edited\n") {
+		t.Errorf("memory = %q", content)
+	}
+}
+
+func TestRunMemoryCompact(t *testing.T) {
+	mem := seedMemory(t)
+	cfg := config.DefaultConfig()
+	rt := &mockRuntime{response: &api.Response{Result: &api.Result{Output: "```markdown\n# Facts\n- Dog: Biscuit\n```"}}}
+
+	output, err := captureRunOutput(t, func() error {
+		return runMemoryCompactWithOptions(memoryCommand(map[string]string{"json": "true"}), cfg, AgentOptions{RuntimeFactory: mockRuntimeFactory(rt)})
+	})
+	if err != nil {
+		t.Fatalf("runMemoryCompact error: %v", err)
+	}
+	if content, _ := mem.ReadLongTerm(); content != "# Facts\n- Dog: Biscuit\n" {
+		t.Errorf("memory = %q", content)
+	}
+	backup, _ := os.ReadFile(mem.LongTermPath() + ".bak")
+	if !strings.Contains(string(backup), "Writes Go") {
+		t.Errorf("backup = %q", backup)
+	}
+	if !strings.Contains(output, `"changed": true`) || !rt.closed {
+		t.Errorf("output = %s, closed = %v", output, rt.closed)
+	}
+
+	// An empty answer must not wipe memory.
+	rt = &mockRuntime{response: &api.Response{Result: &api.Result{Output: "  "}}}
+	if _, err := captureRunOutput(t, func() error {
+		return runMemoryCompactWithOptions(memoryCommand(nil), cfg, AgentOptions{RuntimeFactory: mockRuntimeFactory(rt)})
+	}); err == nil {
+		t.Error("expected error for empty answer")
+	}
+	if content, _ := mem.ReadLongTerm(); content == "" {
+		t.Error("memory was wiped")
+	}
+}
+
+func TestRunMemoryExportImport(t *testing.T) {
+	mem := seedMemory(t)
+	mem.AppendToday("Went climbing.")
+	dir := t.TempDir()
+
+	for _, format := range []string{"md", "json"} {
+		file := filepath.Join(dir, "memory."+format)
+		if _, err := captureRunOutput(t, func() error {
+			return runMemoryExport(memoryCommand(map[string]string{"format": format, "output": file}), nil)
+		}); err != nil {
+			t.Fatalf("%s export error: %v", format, err)
+		}
+		if err := mem.Clear(true); err != nil {
+			t.Fatal(err)
+		}
+
+		output, err := captureRunOutput(t, func() error {
+			return runMemoryImport(memoryCommand(map[string]string{"json": "true"}), []string{file})
+		})
+		if err != nil {
+			t.Fatalf("%s import error: %v", format, err)
+		}
+		if !strings.Contains(output, `"longTerm": true`) || !strings.Contains(output, `"journal": 1`) {
+			t.Errorf("%s import output = %s", format, output)
+		}
+		if lt, _ := mem.ReadLongTerm(); !strings.Contains(lt, "Biscuit") {
+			t.Errorf("%s long-term = %q", format, lt)
+		}
+		if today, _ := mem.ReadToday(); today != "Went climbing.\n" {
+			t.Errorf("%s journal = %q", format, today)
+		}
+	}
+
+	output, _ := captureRunOutput(t, func() error {
+		return runMemoryImport(memoryCommand(nil), []string{filepath.Join(dir, "memory.md")})
+	})
+	if !strings.Contains(output, "already up to date") {
+		t.Errorf("repeat import output = %q", output)
+	}
+	if err := runMemoryExport(memoryCommand(map[string]string{"format": "yaml"}), nil); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/provider"
+)
+
+const modelsJSONSchemaVersion = 1
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "Inspect the models of the configured provider",
+}
+
+var modelsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the models the configured provider offers",
+	RunE:  runModelsList,
+}
+
+func init() {
+	modelsListCmd.Flags().Bool("json", false, "Output as JSON")
+	modelsCmd.AddCommand(modelsListCmd)
+	rootCmd.AddCommand(modelsCmd)
+}
+
+func runModelsList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if cfg.Provider.APIKey == "" {
+		return fmt.Errorf("API key not set. Run 'myclaw onboard' or set MYCLAW_API_KEY")
+	}
+	models, err := provider.ListModels(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	if models == nil {
+		models = []provider.ModelInfo{}
+	}
+	aliases := cfg.Models.Aliases
+	if aliases == nil {
+		aliases = map[string]string{}
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": modelsJSONSchemaVersion,
+			"command":       "models.list",
+			"ok":            true,
+			"provider":      cfg.Provider.Type,
+			"models":        models,
+		})
+	}
+	fmt.Printf("Provider: %s\n", provider.Display(cfg.Provider.Type))
+	for _, m := range models {
+		line := "  " + m.ID
+		if m.Name != "" && m.Name != m.ID {
+			line += " (" + m.Name + ")"
+		}
+		if m.OutputTokens > 0 {
+			line += fmt.Sprintf(" max output %d", m.OutputTokens)
+		}
+		fmt.Println(line)
+	}
+	if len(aliases) > 0 {
+		fmt.Println("Aliases:")
+		for _, name := range slices.Sorted(maps.Keys(aliases)) {
+			fmt.Printf("  %s -> %s\n", name, aliases[name])
+		}
+	}
+	return nil
+}
+
+// modelLabel names cfg.Agent.Model, with the model it stands for when it
+// is an alias.
+func modelLabel(cfg *config.Config) string {
+	if resolved := cfg.Models.Resolve(cfg.Agent.Model); resolved != cfg.Agent.Model {
+		return cfg.Agent.Model + " (" + resolved + ")"
+	}
+	return cfg.Agent.Model
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/stellarlinkco/myclaw/internal/markdown"
+	"golang.org/x/term"
+)
+
+// plainFlag turns off markdown rendering of replies.
+var plainFlag bool
+
+// replyRenderer returns how replies written to w are rendered: as markdown
+// on a terminal, and as written with --plain or when w is a pipe or file.
+// NO_COLOR keeps the layout but drops the styling.
+func replyRenderer(w io.Writer) func(string) string {
+	f, ok := w.(*os.File)
+	if plainFlag || !ok || !term.IsTerminal(int(f.Fd())) {
+		return func(s string) string { return s }
+	}
+	opts := markdown.Options{Color: os.Getenv("NO_COLOR") == ""}
+	if width, _, err := term.GetSize(int(f.Fd())); err == nil {
+		opts.Width = width
+	}
+	return func(s string) string { return markdown.Render(s, opts) }
+}
+
+// clipLine returns the first line of s, cut to n runes, for one-line
+// listings.
+func clipLine(s string, n int) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(s), "\n", 2)[0])
+	if r := []rune(line); len(r) > n {
+		return strings.TrimSpace(string(r[:n])) + "..."
+	}
+	return line
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestReplyRenderer_NotTerminal(t *testing.T) {
+	const reply = "**bold** and `code`"
+	if got := replyRenderer(&bytes.Buffer{})(reply); got != reply {
+		t.Errorf("buffer: %q", got)
+	}
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got := replyRenderer(f)(reply); got != reply {
+		t.Errorf("file: %q", got)
+	}
+}
+
+func TestClipLine(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"  short  \nsecond line", 80, "short"},
+		{"héllo wörld", 6, "héllo..."},
+		{"日本語のテキスト", 3, "日本語..."},
+	} {
+		if got := clipLine(tc.in, tc.n); got != tc.want {
+			t.Errorf("clipLine(%q, %d) = %q, want %q", tc.in, tc.n, got, tc.want)
+		}
+	}
+}
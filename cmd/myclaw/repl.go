@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+	"github.com/stellarlinkco/myclaw/internal/readline"
+	"github.com/stellarlinkco/myclaw/internal/session"
+	"github.com/stellarlinkco/myclaw/internal/skills"
+)
+
+const (
+	replSessionID = "cli-repl"
+
+	compactPrompt = "Summarize our conversation so far in a few short paragraphs. " +
+		"Keep facts, decisions and open tasks; drop small talk. Reply with the summary only."
+)
+
+// replSession holds the state of an interactive agent session that slash
+// commands are allowed to change.
+type replSession struct {
+	cfg       *config.Config
+	factory   RuntimeFactory
+	rt        Runtime
+	sessionID string
+	// carryOver is prepended to the next prompt after /compact or /model so
+	// the new session or runtime starts with what came before.
+	carryOver string
+	// turns records the exchanges of this session so /model can hand them
+	// to the new runtime when the old one kept history only in memory.
+	turns  []string
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type slashCommand struct {
+	name  string
+	usage string
+	help  string
+	run   func(s *replSession, ctx context.Context, args []string) error
+}
+
+var slashCommands []slashCommand
+
+func init() {
+	slashCommands = []slashCommand{
+		{name: "help", usage: "/help", help: "Show available commands", run: (*replSession).cmdHelp},
+		{name: "clear", usage: "/clear", help: "Forget the conversation and start a fresh session", run: (*replSession).cmdClear},
+		{name: "memory", usage: "/memory", help: "Show long-term memory", run: (*replSession).cmdMemory},
+		{name: "skills", usage: "/skills", help: "List loaded skills", run: (*replSession).cmdSkills},
+		{name: "model", usage: "/model [name]", help: "Show or switch the model", run: (*replSession).cmdModel},
+		{name: "compact", usage: "/compact [preview]", help: "Summarize the conversation into a fresh session", run: (*replSession).cmdCompact},
+		{name: "session", usage: "/session [new]", help: "Show the session id or start a new session", run: (*replSession).cmdSession},
+		{name: "context", usage: "/context [full]", help: "Show what the model is sent next turn", run: (*replSession).cmdContext},
+	}
+}
+
+// terminalAsker asks on the terminal whether a tool call may run, reading
+// the answer from the REPL's input. Anything but y or yes is a no.
+func terminalAsker(rl *readline.Reader, stdout io.Writer) permission.AskFunc {
+	return func(_ context.Context, req permission.Request) (bool, error) {
+		fmt.Fprintln(stdout)
+		line, err := rl.ReadLine(fmt.Sprintf("Allow %s? [y/N] ", req))
+		if errors.Is(err, readline.ErrInterrupt) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.New("input closed")
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		return answer == "y" || answer == "yes", nil
+	}
+}
+
+// replHistoryPath is where the REPL keeps its input history.
+func replHistoryPath(cfg *config.Config) string {
+	return filepath.Join(cfg.Agent.Workspace, ".repl_history")
+}
+
+func (s *replSession) close() {
+	if s.rt != nil {
+		s.rt.Close()
+	}
+}
+
+// prompt sends user input to the runtime, prepending any compacted context.
+func (s *replSession) prompt(ctx context.Context, input string) (*api.Response, error) {
+	prompt := input
+	if s.carryOver != "" {
+		prompt = "Context from our earlier conversation:\n" + s.carryOver + "\n\n" + input
+	}
+	resp, err := s.rt.Run(ctx, api.Request{
+		Prompt:    prompt,
+		SessionID: s.sessionID,
+	})
+	if err == nil {
+		s.carryOver = ""
+		turn := "User: " + prompt
+		if resp != nil && resp.Result != nil {
+			turn += "\nAssistant: " + strings.TrimSpace(resp.Result.Output)
+		}
+		s.turns = append(s.turns, turn)
+	}
+	return resp, err
+}
+
+// handleSlash runs input as a slash command. It reports false when input
+// does not name a known command, such as a pasted path, and should go to
+// the model.
+func (s *replSession) handleSlash(ctx context.Context, input string) bool {
+	if !strings.HasPrefix(input, "/") {
+		return false
+	}
+	fields := strings.Fields(strings.TrimPrefix(input, "/"))
+	if len(fields) == 0 {
+		return false
+	}
+	name := strings.ToLower(fields[0])
+	for _, cmd := range slashCommands {
+		if cmd.name == name {
+			if err := cmd.run(s, ctx, fields[1:]); err != nil {
+				fmt.Fprintf(s.stderr, "Error: %v\n", err)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (s *replSession) newSession() {
+	s.sessionID = fmt.Sprintf("%s-%d", replSessionID, time.Now().UnixNano())
+	s.turns = nil
+}
+
+func (s *replSession) cmdHelp(_ context.Context, _ []string) error {
+	fmt.Fprintln(s.stdout, "Commands:")
+	for _, cmd := range slashCommands {
+		fmt.Fprintf(s.stdout, "  %-16s %s\n", cmd.usage, cmd.help)
+	}
+	fmt.Fprintf(s.stdout, "  %-16s %s\n", "exit", "Leave the REPL")
+	return nil
+}
+
+func (s *replSession) cmdClear(_ context.Context, _ []string) error {
+	s.newSession()
+	s.carryOver = ""
+	fmt.Fprintln(s.stdout, "Conversation cleared.")
+	return nil
+}
+
+func (s *replSession) cmdSession(_ context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(s.stdout, "Session: %s\n", s.sessionID)
+		return nil
+	}
+	if args[0] != "new" {
+		return fmt.Errorf("usage: /session [new]")
+	}
+	s.newSession()
+	s.carryOver = ""
+	fmt.Fprintf(s.stdout, "Started session %s\n", s.sessionID)
+	return nil
+}
+
+func (s *replSession) cmdMemory(_ context.Context, _ []string) error {
+	content, err := memory.NewMemoryStore(s.cfg.MemoryWorkspace()).ReadLongTerm()
+	if err != nil {
+		return fmt.Errorf("read memory: %w", err)
+	}
+	if strings.TrimSpace(content) == "" {
+		fmt.Fprintln(s.stdout, "Memory is empty.")
+		return nil
+	}
+	fmt.Fprintln(s.stdout, strings.TrimSpace(content))
+	return nil
+}
+
+func (s *replSession) cmdSkills(_ context.Context, _ []string) error {
+	if !s.cfg.Skills.Enabled {
+		fmt.Fprintln(s.stdout, "Skills are disabled in config.")
+		return nil
+	}
+	registrations, err := loadSkills(s.cfg)
+	if err != nil {
+		return fmt.Errorf("load skills: %w", err)
+	}
+	if len(registrations) == 0 {
+		fmt.Fprintln(s.stdout, "No skills found.")
+		return nil
+	}
+	for _, registration := range registrations {
+		desc := strings.TrimSpace(registration.Definition.Description)
+		if desc == "" {
+			desc = "(no description)"
+		}
+		if skills.IsDisabled(registration.Definition.Name, s.cfg.Skills.Disabled) {
+			desc += " (disabled)"
+		}
+		fmt.Fprintf(s.stdout, "- %s: %s\n", registration.Definition.Name, desc)
+	}
+	return nil
+}
+
+func (s *replSession) cmdModel(_ context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(s.stdout, "Model: %s\n", modelLabel(s.cfg))
+		if len(s.cfg.Models.Aliases) > 0 {
+			fmt.Fprintf(s.stdout, "Aliases: %s\n", strings.Join(slices.Sorted(maps.Keys(s.cfg.Models.Aliases)), ", "))
+		}
+		return nil
+	}
+
+	previous := s.cfg.Agent.Model
+	s.cfg.Agent.Model = args[0]
+	rt, err := s.factory(s.cfg)
+	if err != nil {
+		s.cfg.Agent.Model = previous
+		return fmt.Errorf("switch model: %w", err)
+	}
+	s.rt.Close()
+	s.rt = rt
+	s.handOver()
+	fmt.Fprintf(s.stdout, "Model switched to %s\n", modelLabel(s.cfg))
+	return nil
+}
+
+// handOver carries the conversation over to a freshly built runtime. The
+// session ID is kept, so a runtime that saves history reloads it on its
+// own; otherwise the exchanges so far are sent along with the next prompt.
+func (s *replSession) handOver() {
+	if _, err := session.NewStore(s.cfg.Agent.Workspace).Get(s.sessionID); err == nil {
+		return
+	}
+	if len(s.turns) == 0 {
+		return
+	}
+	s.carryOver = strings.Join(s.turns, "\n\n")
+	s.turns = nil
+}
+
+func (s *replSession) cmdCompact(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		if args[0] != "preview" {
+			return fmt.Errorf("usage: /compact [preview]")
+		}
+		return s.previewCompact()
+	}
+	resp, err := s.rt.Run(ctx, api.Request{
+		Prompt:    compactionPrompt(s.cfg),
+		SessionID: s.sessionID,
+	})
+	if err != nil {
+		return fmt.Errorf("compact: %w", err)
+	}
+	if resp == nil || resp.Result == nil || strings.TrimSpace(resp.Result.Output) == "" {
+		return fmt.Errorf("compact: model returned no summary")
+	}
+
+	s.newSession()
+	s.carryOver = strings.TrimSpace(resp.Result.Output)
+	fmt.Fprintf(s.stdout, "Compacted into session %s (%d chars of summary).\n", s.sessionID, len(s.carryOver))
+	return nil
+}
+
+func (s *replSession) cmdContext(_ context.Context, args []string) error {
+	if len(args) > 0 && args[0] != "full" {
+		return fmt.Errorf("usage: /context [full]")
+	}
+	report, err := inspectContext(s.cfg, s.sessionID, s.carryOver)
+	if err != nil {
+		return err
+	}
+	return printContext(s.stdout, report, len(args) > 0)
+}
+
+// previewCompact shows what /compact would replace: the whole saved
+// conversation, and any summary still waiting to be sent.
+func (s *replSession) previewCompact() error {
+	sess, err := session.NewStore(s.cfg.Agent.Workspace).Get(s.sessionID)
+	if errors.Is(err, session.ErrNotFound) {
+		fmt.Fprintln(s.stdout, "Nothing to compact yet.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(s.stdout, sess.PlanCompaction(0).Preview())
+	return nil
+}
+
+// compactionPrompt is what manual compaction asks the model for.
+func compactionPrompt(cfg *config.Config) string {
+	if p := strings.TrimSpace(cfg.AutoCompact.Prompt); p != "" {
+		return p
+	}
+	return compactPrompt
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+	"github.com/stellarlinkco/myclaw/internal/readline"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+// recordingRuntime remembers every request it receives.
+type recordingRuntime struct {
+	mockRuntime
+	requests []api.Request
+}
+
+func (r *recordingRuntime) Run(ctx context.Context, req api.Request) (*api.Response, error) {
+	r.requests = append(r.requests, req)
+	return r.mockRuntime.Run(ctx, req)
+}
+
+func newTestREPLSession(t *testing.T, rt Runtime) (*replSession, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = t.TempDir()
+	var stdout, stderr bytes.Buffer
+	return &replSession{
+		cfg:       cfg,
+		factory:   mockRuntimeFactory(rt),
+		rt:        rt,
+		sessionID: replSessionID,
+		stdout:    &stdout,
+		stderr:    &stderr,
+	}, &stdout, &stderr
+}
+
+func TestREPLSession_NotSlash(t *testing.T) {
+	s, _, _ := newTestREPLSession(t, &mockRuntime{})
+	if s.handleSlash(context.Background(), "hello /help") {
+		t.Error("plain input should not be handled as a slash command")
+	}
+}
+
+func TestREPLSession_Help(t *testing.T) {
+	s, stdout, _ := newTestREPLSession(t, &mockRuntime{})
+	if !s.handleSlash(context.Background(), "/help") {
+		t.Fatal("/help should be handled")
+	}
+	for _, want := range []string{"/clear", "/memory", "/skills", "/model", "/compact", "/session"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("help output missing %s: %s", want, stdout.String())
+		}
+	}
+}
+
+func TestREPLSession_Unknown(t *testing.T) {
+	s, _, stderr := newTestREPLSession(t, &mockRuntime{})
+	for _, input := range []string{"/nope", "/etc/hosts has a bad line", "/Users/me/notes.md"} {
+		if s.handleSlash(context.Background(), input) {
+			t.Errorf("%q should go to the model", input)
+		}
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("unexpected stderr: %s", stderr.String())
+	}
+}
+
+func TestREPLSession_ClearAndSession(t *testing.T) {
+	s, stdout, stderr := newTestREPLSession(t, &mockRuntime{})
+	ctx := context.Background()
+
+	s.handleSlash(ctx, "/session")
+	if !strings.Contains(stdout.String(), replSessionID) {
+		t.Errorf("expected session id in output: %s", stdout.String())
+	}
+
+	s.handleSlash(ctx, "/clear")
+	if s.sessionID == replSessionID {
+		t.Error("/clear should start a new session")
+	}
+
+	cleared := s.sessionID
+	s.handleSlash(ctx, "/session new")
+	if s.sessionID == cleared {
+		t.Error("/session new should start a new session")
+	}
+
+	s.handleSlash(ctx, "/session bogus")
+	if !strings.Contains(stderr.String(), "usage: /session") {
+		t.Errorf("expected usage error, got: %s", stderr.String())
+	}
+}
+
+func TestREPLSession_Memory(t *testing.T) {
+	s, stdout, _ := newTestREPLSession(t, &mockRuntime{})
+	ctx := context.Background()
+
+	s.handleSlash(ctx, "/memory")
+	if !strings.Contains(stdout.String(), "Memory is empty.") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+
+	memDir := filepath.Join(s.cfg.Agent.Workspace, "memory")
+	os.MkdirAll(memDir, 0755)
+	os.WriteFile(filepath.Join(memDir, "MEMORY.md"), []byte("likes tea"), 0644)
+	stdout.Reset()
+	s.handleSlash(ctx, "/memory")
+	if !strings.Contains(stdout.String(), "likes tea") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestREPLSession_Skills(t *testing.T) {
+	s, stdout, _ := newTestREPLSession(t, &mockRuntime{})
+	s.cfg.Skills.Enabled = true
+	writeSkillFile(t, s.cfg.Agent.Workspace, "weather", "Check the weather")
+
+	s.handleSlash(context.Background(), "/skills")
+	if !strings.Contains(stdout.String(), "weather: Check the weather") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestREPLSession_Model(t *testing.T) {
+	oldRt := &mockRuntime{}
+	s, stdout, _ := newTestREPLSession(t, oldRt)
+	newRt := &mockRuntime{}
+	var gotModel string
+	s.factory = func(cfg *config.Config) (Runtime, error) {
+		gotModel = cfg.Agent.Model
+		return newRt, nil
+	}
+
+	s.handleSlash(context.Background(), "/model claude-haiku")
+	if gotModel != "claude-haiku" {
+		t.Errorf("factory saw model %q", gotModel)
+	}
+	if !oldRt.closed {
+		t.Error("old runtime should be closed")
+	}
+	if s.rt != newRt {
+		t.Error("session should use the new runtime")
+	}
+	if !strings.Contains(stdout.String(), "Model switched to claude-haiku") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestREPLSession_ModelKeepsConversation(t *testing.T) {
+	oldRt := &recordingRuntime{mockRuntime: mockRuntime{
+		response: &api.Response{Result: &api.Result{Output: "Paris"}},
+	}}
+	s, _, _ := newTestREPLSession(t, oldRt)
+	newRt := &recordingRuntime{}
+	s.factory = func(cfg *config.Config) (Runtime, error) { return newRt, nil }
+	ctx := context.Background()
+
+	if _, err := s.prompt(ctx, "capital of France?"); err != nil {
+		t.Fatal(err)
+	}
+	s.handleSlash(ctx, "/model claude-haiku")
+	if s.sessionID != replSessionID {
+		t.Errorf("session = %q, want it kept", s.sessionID)
+	}
+	if _, err := s.prompt(ctx, "and its population?"); err != nil {
+		t.Fatal(err)
+	}
+	got := newRt.requests[0]
+	if got.SessionID != replSessionID || !strings.Contains(got.Prompt, "User: capital of France?\nAssistant: Paris") || !strings.HasSuffix(got.Prompt, "and its population?") {
+		t.Errorf("request = %+v, want the earlier exchange carried over", got)
+	}
+
+	// A session the runtime saved is reloaded by the new runtime itself.
+	store := session.NewStore(s.cfg.Agent.Workspace)
+	if err := store.Save(&session.Session{ID: s.sessionID, Messages: []message.Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatal(err)
+	}
+	s.handleSlash(ctx, "/model claude-opus")
+	if s.carryOver != "" {
+		t.Errorf("carryOver = %q, want none for a saved session", s.carryOver)
+	}
+}
+
+func TestREPLSession_ModelAlias(t *testing.T) {
+	s, stdout, _ := newTestREPLSession(t, &mockRuntime{})
+	s.cfg.Models.Aliases = map[string]string{"fast": "claude-haiku-4-5", "smart": "claude-opus-4-1"}
+	s.factory = func(cfg *config.Config) (Runtime, error) { return &mockRuntime{}, nil }
+
+	s.handleSlash(context.Background(), "/model fast")
+	if !strings.Contains(stdout.String(), "Model switched to fast (claude-haiku-4-5)") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+	stdout.Reset()
+	s.handleSlash(context.Background(), "/model")
+	if !strings.Contains(stdout.String(), "Model: fast (claude-haiku-4-5)") || !strings.Contains(stdout.String(), "Aliases: fast, smart") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestREPLSession_ModelFactoryError(t *testing.T) {
+	oldRt := &mockRuntime{}
+	s, _, stderr := newTestREPLSession(t, oldRt)
+	previous := s.cfg.Agent.Model
+	s.factory = func(cfg *config.Config) (Runtime, error) {
+		return nil, fmt.Errorf("unknown model")
+	}
+
+	s.handleSlash(context.Background(), "/model bogus")
+	if s.cfg.Agent.Model != previous {
+		t.Errorf("model = %q, want %q restored", s.cfg.Agent.Model, previous)
+	}
+	if oldRt.closed || s.rt != oldRt {
+		t.Error("old runtime should be kept on error")
+	}
+	if !strings.Contains(stderr.String(), "switch model") {
+		t.Errorf("unexpected stderr: %s", stderr.String())
+	}
+}
+
+func TestREPLSession_Compact(t *testing.T) {
+	rt := &recordingRuntime{mockRuntime: mockRuntime{
+		response: &api.Response{Result: &api.Result{Output: "we talked about tea"}},
+	}}
+	s, _, _ := newTestREPLSession(t, rt)
+	ctx := context.Background()
+
+	s.handleSlash(ctx, "/compact")
+	if s.sessionID == replSessionID {
+		t.Error("/compact should move to a new session")
+	}
+	if s.carryOver != "we talked about tea" {
+		t.Errorf("carryOver = %q", s.carryOver)
+	}
+
+	if _, err := s.prompt(ctx, "and coffee?"); err != nil {
+		t.Fatalf("prompt error: %v", err)
+	}
+	last := rt.requests[len(rt.requests)-1]
+	if last.SessionID != s.sessionID {
+		t.Errorf("SessionID = %q, want %q", last.SessionID, s.sessionID)
+	}
+	if !strings.Contains(last.Prompt, "we talked about tea") || !strings.Contains(last.Prompt, "and coffee?") {
+		t.Errorf("prompt should carry the summary: %q", last.Prompt)
+	}
+	if s.carryOver != "" {
+		t.Error("carryOver should be consumed after a successful prompt")
+	}
+}
+
+func TestRunAgentWithOptions_REPLMode_SlashCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("ANTHROPIC_AUTH_TOKEN", "")
+	t.Setenv("OPENAI_API_KEY", "")
+
+	rt := &recordingRuntime{}
+	var stdout, stderr bytes.Buffer
+
+	oldFlag := messageFlag
+	messageFlag = ""
+	defer func() { messageFlag = oldFlag }()
+
+	err := runAgentWithOptions(AgentOptions{
+		RuntimeFactory: mockRuntimeFactory(rt),
+		Stdin:          strings.NewReader("/help\nexit\n"),
+		Stdout:         &stdout,
+		Stderr:         &stderr,
+	})
+	if err != nil {
+		t.Fatalf("runAgentWithOptions error: %v", err)
+	}
+	if len(rt.requests) != 0 {
+		t.Errorf("slash commands should not reach the model, got %d requests", len(rt.requests))
+	}
+	if !strings.Contains(stdout.String(), "Commands:") {
+		t.Errorf("expected help output, got: %s", stdout.String())
+	}
+	if !rt.closed {
+		t.Error("runtime should be closed")
+	}
+}
+
+func TestTerminalAsker(t *testing.T) {
+	var out bytes.Buffer
+	rl := readline.New(strings.NewReader("y\nno\n"), &out)
+	ask := terminalAsker(rl, &out)
+	req := permission.Request{Tool: "Bash", Target: "rm:-rf build"}
+
+	if ok, err := ask(context.Background(), req); !ok || err != nil {
+		t.Errorf("first answer = %v, %v; want yes", ok, err)
+	}
+	if !strings.Contains(out.String(), "Allow Bash: rm -rf build? [y/N]") {
+		t.Errorf("prompt = %q", out.String())
+	}
+	if ok, err := ask(context.Background(), req); ok || err != nil {
+		t.Errorf("second answer = %v, %v; want no", ok, err)
+	}
+	if _, err := ask(context.Background(), req); err == nil {
+		t.Error("expected error once input is closed")
+	}
+}
+
+func TestREPLSession_CompactPreviewAndPrompt(t *testing.T) {
+	rt := &recordingRuntime{mockRuntime: mockRuntime{
+		response: &api.Response{Result: &api.Result{Output: "summary"}},
+	}}
+	s, stdout, _ := newTestREPLSession(t, rt)
+	ctx := context.Background()
+
+	s.handleSlash(ctx, "/compact preview")
+	if !strings.Contains(stdout.String(), "Nothing to compact") {
+		t.Errorf("preview of an unsaved session: %s", stdout.String())
+	}
+	store := session.NewStore(s.cfg.Agent.Workspace)
+	if err := store.Save(&session.Session{ID: s.sessionID, Messages: []message.Message{{Role: "user", Content: "hello there"}}}); err != nil {
+		t.Fatal(err)
+	}
+	s.handleSlash(ctx, "/compact preview")
+	if !strings.Contains(stdout.String(), "- [user] hello there") {
+		t.Errorf("preview should list the saved messages: %s", stdout.String())
+	}
+	if len(rt.requests) != 0 {
+		t.Error("preview should not call the model")
+	}
+
+	s.cfg.AutoCompact.Prompt = "Bullet points only."
+	s.handleSlash(ctx, "/compact")
+	if len(rt.requests) != 1 || rt.requests[0].Prompt != "Bullet points only." {
+		t.Errorf("requests = %+v, want the configured prompt", rt.requests)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/secrets"
+	"golang.org/x/term"
+)
+
+// secretStore is the keyring the secret commands use. Tests replace it.
+var secretStore = secrets.Keyring
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Keep API keys and tokens in the system keyring",
+	Long: `Keep API keys and tokens in the system keyring instead of config.json.
+
+Store a secret, then refer to it in config.json as "keyring:<name>":
+
+  myclaw secret set provider.apiKey
+  "provider": {"apiKey": "keyring:provider.apiKey"}`,
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Store a secret, read from the terminal or stdin",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSecretSet,
+}
+
+var secretDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Remove a secret from the keyring",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSecretDelete,
+}
+
+func init() {
+	secretCmd.AddCommand(secretSetCmd, secretDeleteCmd)
+	rootCmd.AddCommand(secretCmd)
+}
+
+func runSecretSet(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := secrets.CheckName(name); err != nil {
+		return err
+	}
+	value, err := readSecret(cmd.InOrStdin(), name)
+	if err != nil {
+		return err
+	}
+	if err := secretStore().Set(name, value); err != nil {
+		return fmt.Errorf("store secret: %w", err)
+	}
+	fmt.Printf("Stored %s in the system keyring.\n", name)
+	fmt.Printf("Refer to it in config.json as %q.\n", secrets.Ref(name))
+	return nil
+}
+
+func runSecretDelete(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := secretStore().Delete(name); err != nil {
+		if errors.Is(err, secrets.ErrNotFound) {
+			return fmt.Errorf("no secret named %s", name)
+		}
+		return fmt.Errorf("delete secret: %w", err)
+	}
+	fmt.Printf("Deleted %s.\n", name)
+	return nil
+}
+
+// readSecret prompts for the value without echo on a terminal, and
+// otherwise reads all of r, so values can be piped in.
+func readSecret(r io.Reader, name string) (string, error) {
+	var value string
+	if f, ok := r.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+		data, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("read secret: %w", err)
+		}
+		value = string(data)
+	} else {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return "", fmt.Errorf("read secret: %w", err)
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+	}
+	if value == "" {
+		return "", errors.New("no value given")
+	}
+	return value, nil
+}
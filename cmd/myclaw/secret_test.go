@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/secrets"
+)
+
+// memoryKeyring is a keyring held in memory.
+type memoryKeyring map[string]string
+
+func (m memoryKeyring) Get(name string) (string, error) {
+	v, ok := m[name]
+	if !ok {
+		return "", secrets.ErrNotFound
+	}
+	return v, nil
+}
+
+func (m memoryKeyring) Set(name, value string) error {
+	m[name] = value
+	return nil
+}
+
+func (m memoryKeyring) Delete(name string) error {
+	if _, ok := m[name]; !ok {
+		return secrets.ErrNotFound
+	}
+	delete(m, name)
+	return nil
+}
+
+func useSecretStore(t *testing.T) memoryKeyring {
+	t.Helper()
+	store := memoryKeyring{}
+	orig := secretStore
+	secretStore = func() secrets.Store { return store }
+	t.Cleanup(func() { secretStore = orig })
+	return store
+}
+
+func TestRunSecretSet(t *testing.T) {
+	store := useSecretStore(t)
+	cmd := &cobra.Command{}
+	cmd.SetIn(strings.NewReader("sk-piped\n"))
+
+	output, err := captureRunOutput(t, func() error {
+		return runSecretSet(cmd, []string{"provider.apiKey"})
+	})
+	if err != nil {
+		t.Fatalf("runSecretSet error: %v", err)
+	}
+	if store["provider.apiKey"] != "sk-piped" {
+		t.Errorf("stored %q", store["provider.apiKey"])
+	}
+	if !strings.Contains(output, `"keyring:provider.apiKey"`) {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	cmd.SetIn(strings.NewReader("\n"))
+	if err := runSecretSet(cmd, []string{"provider.apiKey"}); err == nil {
+		t.Error("expected error for empty value")
+	}
+	if err := runSecretSet(cmd, []string{"bad name"}); err == nil {
+		t.Error("expected error for bad name")
+	}
+}
+
+func TestRunSecretDelete(t *testing.T) {
+	store := useSecretStore(t)
+	store["provider.apiKey"] = "sk-test"
+
+	if _, err := captureRunOutput(t, func() error {
+		return runSecretDelete(&cobra.Command{}, []string{"provider.apiKey"})
+	}); err != nil {
+		t.Fatalf("runSecretDelete error: %v", err)
+	}
+	if _, ok := store["provider.apiKey"]; ok {
+		t.Error("secret still stored")
+	}
+	if err := runSecretDelete(&cobra.Command{}, []string{"provider.apiKey"}); err == nil || !strings.Contains(err.Error(), "no secret named") {
+		t.Errorf("delete missing = %v", err)
+	}
+}
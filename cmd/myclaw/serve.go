@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/server"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the agent over a local HTTP API",
+	Long: `Serve the agent over a local HTTP API.
+
+Endpoints: POST /v1/messages, GET /v1/sessions, GET /v1/status. Requests need
+"Authorization: Bearer <token>"; the token comes from --token, server.token
+or MYCLAW_SERVER_TOKEN, and a random one is generated if none is set.
+
+With --grpc, the gRPC service from internal/server/agentpb/agent.proto is
+served on that address as well (cleartext HTTP/2, token sent as
+"authorization" metadata).
+
+With --ui, a chat page for browsers is served at /. It asks for the token
+once, keeps its conversations apart from other sessions, and takes photos
+and documents. Use --host 0.0.0.0 to open it from other devices on the LAN.`,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().String("host", "", "Listen host (default from config, 127.0.0.1)")
+	serveCmd.Flags().Int("port", 0, "Listen port (default from config, 18791)")
+	serveCmd.Flags().String("token", "", "API bearer token")
+	serveCmd.Flags().String("grpc", "", "Also serve gRPC on this address (e.g. :9090)")
+	serveCmd.Flags().Bool("ui", false, "Also serve a chat page for browsers at /")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// ServeOptions for running the API server with custom dependencies
+type ServeOptions struct {
+	RuntimeFactory RuntimeFactory
+	Listener       net.Listener // when nil, listen on the configured address
+	GRPCAddr       string       // when set, also serve gRPC here
+	GRPCListener   net.Listener // overrides GRPCAddr
+	UI             bool         // serve the chat page
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if host, _ := cmd.Flags().GetString("host"); host != "" {
+		cfg.Server.Host = host
+	}
+	if port, _ := cmd.Flags().GetInt("port"); port != 0 {
+		cfg.Server.Port = port
+	}
+	if token, _ := cmd.Flags().GetString("token"); token != "" {
+		cfg.Server.Token = token
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	grpcAddr, _ := cmd.Flags().GetString("grpc")
+	ui, _ := cmd.Flags().GetBool("ui")
+	return runServeWithOptions(ctx, cfg, ServeOptions{RuntimeFactory: DefaultRuntimeFactory, GRPCAddr: grpcAddr, UI: ui})
+}
+
+func runServeWithOptions(ctx context.Context, cfg *config.Config, opts ServeOptions) error {
+	if cfg.Server.Token == "" {
+		token, err := generateServerToken()
+		if err != nil {
+			return err
+		}
+		cfg.Server.Token = token
+		fmt.Printf("Generated API token: %s\n", token)
+		fmt.Println("Set server.token or MYCLAW_SERVER_TOKEN to keep it across restarts.")
+	}
+
+	rt, err := opts.RuntimeFactory(cfg)
+	if err != nil {
+		return err
+	}
+	defer rt.Close()
+
+	srv, err := server.New(cfg, rt, cfg.Server.Token)
+	if err != nil {
+		return err
+	}
+	if opts.UI {
+		if err := srv.EnableUI(); err != nil {
+			return err
+		}
+	}
+
+	ln := opts.Listener
+	if ln == nil {
+		addr := net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port))
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+	}
+	grpcLn := opts.GRPCListener
+	if grpcLn == nil && opts.GRPCAddr != "" {
+		if grpcLn, err = net.Listen("tcp", opts.GRPCAddr); err != nil {
+			ln.Close()
+			return fmt.Errorf("listen grpc: %w", err)
+		}
+	}
+
+	fmt.Printf("myclaw API listening on http://%s\n", ln.Addr())
+	if opts.UI {
+		fmt.Printf("Chat page at http://%s/\n", ln.Addr())
+	}
+	if grpcLn == nil {
+		return srv.Serve(ctx, ln)
+	}
+	fmt.Printf("myclaw gRPC listening on %s\n", grpcLn.Addr())
+
+	// Stop both servers when either one fails.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	grpcErr := make(chan error, 1)
+	go func() {
+		err := srv.ServeGRPC(ctx, grpcLn)
+		cancel()
+		grpcErr <- err
+	}()
+	err = srv.Serve(ctx, ln)
+	cancel()
+	if gerr := <-grpcErr; err == nil && gerr != nil {
+		err = fmt.Errorf("grpc: %w", gerr)
+	}
+	return err
+}
+
+func generateServerToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestRunServeWithOptions(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = t.TempDir()
+	cfg.Server.Token = "tok"
+	rt := &mockRuntime{response: &api.Response{Result: &api.Result{Output: "pong"}}}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := captureRunOutput(t, func() error {
+			return runServeWithOptions(ctx, cfg, ServeOptions{RuntimeFactory: mockRuntimeFactory(rt), Listener: ln})
+		})
+		done <- err
+	}()
+
+	req, _ := http.NewRequest(http.MethodPost, "http://"+ln.Addr().String()+"/v1/messages", strings.NewReader(`{"message":"ping"}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d", resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serve error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+	if !rt.closed {
+		t.Error("runtime should be closed on shutdown")
+	}
+}
+
+func TestRunServeWithOptions_GeneratesToken(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = t.TempDir()
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	output, err := captureRunOutput(t, func() error {
+		return runServeWithOptions(ctx, cfg, ServeOptions{RuntimeFactory: mockRuntimeFactory(&mockRuntime{}), Listener: ln})
+	})
+	if err != nil {
+		t.Fatalf("serve error: %v", err)
+	}
+	if cfg.Server.Token == "" || !strings.Contains(output, cfg.Server.Token) {
+		t.Errorf("token not generated or not shown: %q", output)
+	}
+}
+
+func TestRunServeWithOptions_GRPC(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = t.TempDir()
+	cfg.Server.Token = "tok"
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	grpcLn, _ := net.Listen("tcp", "127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := captureRunOutput(t, func() error {
+			return runServeWithOptions(ctx, cfg, ServeOptions{
+				RuntimeFactory: mockRuntimeFactory(&mockRuntime{response: &api.Response{Result: &api.Result{Output: "pong"}}}),
+				Listener:       ln,
+				GRPCListener:   grpcLn,
+			})
+		})
+		done <- err
+	}()
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+	// RunRequest{prompt: "ping"}, framed.
+	body := []byte{0, 0, 0, 0, 6, 0x0a, 4, 'p', 'i', 'n', 'g'}
+	req, _ := http.NewRequest(http.MethodPost, "http://"+grpcLn.Addr().String()+"/myclaw.v1.Agent/Run", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Authorization", "Bearer tok")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("grpc request error: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Trailer.Get("Grpc-Status") != "0" || !bytes.Contains(data, []byte("pong")) {
+		t.Errorf("grpc status = %q, body = %q", resp.Trailer.Get("Grpc-Status"), data)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serve error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
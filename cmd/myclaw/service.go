@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/service"
+)
+
+const serviceJSONSchemaVersion = 1
+
+// serviceManager is the service manager the service commands use. Tests
+// replace it.
+var serviceManager = service.System
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Run the gateway in the background, started at login",
+	Long: `Run the gateway in the background as a systemd user unit (Linux) or a
+launchd agent (macOS). It starts at login and restarts if it fails.
+
+Each profile gets its own service, named myclaw-<profile>. The service
+doesn't see this shell's environment, so keep the API key in the config
+file or the keyring (myclaw secret set provider.apiKey).`,
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the gateway service and start it",
+	Args:  cobra.NoArgs,
+	RunE:  runServiceInstall,
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop the gateway service and remove it",
+	Args:  cobra.NoArgs,
+	RunE:  runServiceUninstall,
+}
+
+var serviceStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the gateway service is installed and running",
+	Args:  cobra.NoArgs,
+	RunE:  runServiceStatus,
+}
+
+func init() {
+	serviceStatusCmd.Flags().Bool("json", false, "Output as JSON")
+	serviceCmd.AddCommand(serviceInstallCmd, serviceUninstallCmd, serviceStatusCmd)
+	rootCmd.AddCommand(serviceCmd)
+}
+
+// gatewayUnit describes the gateway of the active profile, run by this
+// binary.
+func gatewayUnit() (service.Unit, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return service.Unit{}, fmt.Errorf("find myclaw binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	args := []string{"gateway"}
+	if profile := config.Profile(); profile != "" {
+		args = []string{"--profile", profile, "gateway"}
+	}
+	u := service.Unit{
+		Name:       service.Name(config.Profile()),
+		Executable: exe,
+		Args:       args,
+		LogDir:     filepath.Join(config.DataDir(), "logs"),
+	}
+	// Tools the agent runs, such as git, are found on the same PATH.
+	if path := os.Getenv("PATH"); path != "" {
+		u.Env = append(u.Env, "PATH="+path)
+	}
+	return u, nil
+}
+
+func runServiceInstall(cmd *cobra.Command, args []string) error {
+	// A service that can't start would only restart in a loop.
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	m, err := serviceManager()
+	if err != nil {
+		return err
+	}
+	u, err := gatewayUnit()
+	if err != nil {
+		return err
+	}
+	if err := m.Install(u); err != nil {
+		return fmt.Errorf("install %s: %w", u.Name, err)
+	}
+	stdout, stderr := u.LogFiles()
+	fmt.Printf("Installed and started %s: %s\n", u.Name, m.Path(u.Name))
+	fmt.Printf("Logs: %s\n      %s\n", stdout, stderr)
+	if runtime.GOOS == "linux" {
+		fmt.Println("To keep it running after you log out: loginctl enable-linger $USER")
+	}
+	return nil
+}
+
+func runServiceUninstall(cmd *cobra.Command, args []string) error {
+	m, err := serviceManager()
+	if err != nil {
+		return err
+	}
+	name := service.Name(config.Profile())
+	if err := m.Uninstall(name); err != nil {
+		return fmt.Errorf("uninstall %s: %w", name, err)
+	}
+	fmt.Printf("Stopped and removed %s.\n", name)
+	return nil
+}
+
+func runServiceStatus(cmd *cobra.Command, args []string) error {
+	m, err := serviceManager()
+	if err != nil {
+		return err
+	}
+	u, err := gatewayUnit()
+	if err != nil {
+		return err
+	}
+	st, err := m.Status(u.Name)
+	if err != nil {
+		return fmt.Errorf("service status: %w", err)
+	}
+	stdout, stderr := u.LogFiles()
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": serviceJSONSchemaVersion,
+			"command":       "service.status",
+			"ok":            true,
+			"name":          u.Name,
+			"installed":     st.Installed,
+			"running":       st.Running,
+			"path":          st.Path,
+			"detail":        st.Detail,
+			"logs":          map[string]string{"stdout": stdout, "stderr": stderr},
+		})
+	}
+
+	if !st.Installed {
+		fmt.Printf("%s is not installed. Run 'myclaw service install'.\n", u.Name)
+		return nil
+	}
+	state := "stopped"
+	if st.Running {
+		state = "running"
+	}
+	if st.Detail != "" && st.Detail != state {
+		state += " (" + st.Detail + ")"
+	}
+	fmt.Printf("Service: %s\n", u.Name)
+	fmt.Printf("State:   %s\n", state)
+	fmt.Printf("File:    %s\n", st.Path)
+	fmt.Printf("Logs:    %s\n         %s\n", stdout, stderr)
+	return nil
+}
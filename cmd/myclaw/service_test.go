@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/service"
+)
+
+// fakeServices is a service manager that keeps units in memory.
+type fakeServices map[string]service.Unit
+
+func (f fakeServices) Path(name string) string { return "/units/" + name }
+
+func (f fakeServices) Install(u service.Unit) error {
+	f[u.Name] = u
+	return nil
+}
+
+func (f fakeServices) Uninstall(name string) error {
+	if _, ok := f[name]; !ok {
+		return errors.New(name + " is not installed")
+	}
+	delete(f, name)
+	return nil
+}
+
+func (f fakeServices) Status(name string) (service.Status, error) {
+	_, ok := f[name]
+	return service.Status{Installed: ok, Running: ok, Path: f.Path(name)}, nil
+}
+
+func useServiceManager(t *testing.T) fakeServices {
+	t.Helper()
+	services := fakeServices{}
+	orig := serviceManager
+	serviceManager = func() (service.Manager, error) { return services, nil }
+	t.Cleanup(func() { serviceManager = orig })
+	return services
+}
+
+func TestRunServiceInstall(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "sk-test")
+	services := useServiceManager(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runServiceInstall(&cobra.Command{}, nil)
+	})
+	if err != nil {
+		t.Fatalf("runServiceInstall error: %v", err)
+	}
+	u, ok := services["myclaw"]
+	if !ok {
+		t.Fatalf("nothing installed: %v", services)
+	}
+	if strings.Join(u.Args, " ") != "gateway" || u.Executable == "" {
+		t.Errorf("unit = %+v", u)
+	}
+	if !strings.Contains(output, "/units/myclaw") || !strings.Contains(output, "gateway.log") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	output, err = captureRunOutput(t, func() error {
+		return runServiceUninstall(&cobra.Command{}, nil)
+	})
+	if err != nil || len(services) != 0 {
+		t.Fatalf("runServiceUninstall error: %v, left %v", err, services)
+	}
+	if !strings.Contains(output, "removed myclaw") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
+
+func TestRunServiceStatus_JSON(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	useServiceManager(t)
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", true, "")
+
+	output, err := captureRunOutput(t, func() error {
+		return runServiceStatus(cmd, nil)
+	})
+	if err != nil {
+		t.Fatalf("runServiceStatus error: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, output)
+	}
+	if got["command"] != "service.status" || got["installed"] != false || got["name"] != "myclaw" {
+		t.Errorf("status = %v", got)
+	}
+}
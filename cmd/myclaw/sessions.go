@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+const sessionsJSONSchemaVersion = 1
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List, inspect and export saved conversations",
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved sessions",
+	RunE:  runSessionsList,
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a session transcript",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSessionsShow,
+}
+
+var sessionsDeleteCmd = &cobra.Command{
+	Use:   "delete <id>...",
+	Short: "Delete saved sessions",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runSessionsDelete,
+}
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export <id>",
+	Short: "Export a session transcript as markdown, HTML or JSON",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSessionsExport,
+}
+
+var sessionsCompactCmd = &cobra.Command{
+	Use:   "compact <id>",
+	Short: "Summarize the older part of a session to free context",
+	Long: `Have the agent summarize all but the latest messages of a saved session,
+and replace them with the summary. A preview of what is dropped is shown
+first; --dry-run stops there. The previous history is kept with a .bak
+suffix. The summary is asked for with autoCompact.prompt when set.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionsCompact,
+}
+
+func init() {
+	sessionsListCmd.Flags().Bool("json", false, "Output as JSON")
+	sessionsShowCmd.Flags().Bool("json", false, "Output as JSON")
+	sessionsExportCmd.Flags().StringP("format", "f", "markdown", "Export format: markdown (md), html or json")
+	sessionsExportCmd.Flags().StringP("output", "o", "", "Write to file instead of stdout")
+	sessionsCompactCmd.Flags().Int("keep", 0, "Messages to keep as they are (default autoCompact.preserveCount)")
+	sessionsCompactCmd.Flags().Bool("dry-run", false, "Show what would be dropped without changing anything")
+	sessionsCompactCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+	sessionsCmd.AddCommand(sessionsListCmd, sessionsShowCmd, sessionsDeleteCmd, sessionsExportCmd, sessionsCompactCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+func openSessionStore() (*session.Store, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	return session.NewStore(cfg.Agent.Workspace), nil
+}
+
+func runSessionsList(cmd *cobra.Command, args []string) error {
+	store, err := openSessionStore()
+	if err != nil {
+		return err
+	}
+	summaries, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	if readJSONFlag(cmd) {
+		if summaries == nil {
+			summaries = []session.Summary{}
+		}
+		return printJSON(map[string]any{
+			"schemaVersion": sessionsJSONSchemaVersion,
+			"command":       "sessions.list",
+			"ok":            true,
+			"count":         len(summaries),
+			"sessions":      summaries,
+		})
+	}
+
+	if len(summaries) == 0 {
+		fmt.Println("No saved sessions.")
+		return nil
+	}
+	for _, sum := range summaries {
+		fmt.Printf("- %s (%d messages, updated %s)\n", sum.ID, sum.Messages, sum.UpdatedAt.Local().Format(time.RFC3339))
+		if sum.Preview != "" {
+			fmt.Printf("  %s\n", sum.Preview)
+		}
+	}
+	return nil
+}
+
+func runSessionsShow(cmd *cobra.Command, args []string) error {
+	store, err := openSessionStore()
+	if err != nil {
+		return err
+	}
+	sess, err := store.Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": sessionsJSONSchemaVersion,
+			"command":       "sessions.show",
+			"ok":            true,
+			"session":       sess,
+		})
+	}
+	fmt.Print(sess.Markdown())
+	return nil
+}
+
+func runSessionsDelete(cmd *cobra.Command, args []string) error {
+	store, err := openSessionStore()
+	if err != nil {
+		return err
+	}
+	for _, id := range args {
+		if err := store.Delete(id); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted session %s\n", id)
+	}
+	return nil
+}
+
+func runSessionsExport(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	store, err := openSessionStore()
+	if err != nil {
+		return err
+	}
+	sess, err := store.Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch strings.ToLower(format) {
+	case "markdown", "md":
+		data = []byte(sess.Transcript(time.Now()))
+	case "html":
+		page, err := sess.HTML(time.Now())
+		if err != nil {
+			return err
+		}
+		data = []byte(page)
+	case "json":
+		data, err = json.MarshalIndent(sess, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode session: %w", err)
+		}
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("unknown format %q (use markdown, html or json)", format)
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	fmt.Printf("Exported session %s to %s\n", sess.ID, output)
+	return nil
+}
+
+const sessionsCompactSessionID = "session-compact"
+
+func runSessionsCompact(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	return runSessionsCompactWithOptions(cmd, args, cfg, AgentOptions{RuntimeFactory: DefaultRuntimeFactory})
+}
+
+func runSessionsCompactWithOptions(cmd *cobra.Command, args []string, cfg *config.Config, opts AgentOptions) error {
+	keep, _ := cmd.Flags().GetInt("keep")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+	if !cmd.Flags().Changed("keep") {
+		keep = cfg.AutoCompact.PreserveCount
+	}
+	stdout := cmd.OutOrStdout()
+
+	store := session.NewStore(cfg.Agent.Workspace)
+	sess, err := store.Get(args[0])
+	if err != nil {
+		return err
+	}
+	plan := sess.PlanCompaction(keep)
+	if len(plan.Dropped) == 0 {
+		fmt.Fprintf(stdout, "Session %s has %d messages; nothing to compact.\n", sess.ID, len(sess.Messages))
+		return nil
+	}
+	fmt.Fprint(stdout, plan.Preview())
+	if dryRun {
+		return nil
+	}
+	if !yes {
+		fmt.Fprintf(stdout, "Replace %d messages with a summary? [y/N] ", len(plan.Dropped))
+		if !confirm(cmd.InOrStdin()) {
+			fmt.Fprintln(stdout, "Aborted.")
+			return nil
+		}
+	}
+
+	// The transcript is in the prompt; skip recall and extraction.
+	runCfg := *cfg
+	runCfg.Memory.Semantic = false
+	runCfg.Memory.AutoExtract = false
+	rt, err := opts.RuntimeFactory(&runCfg)
+	if err != nil {
+		return err
+	}
+	defer rt.Close()
+	defer store.Delete(sessionsCompactSessionID)
+
+	resp, err := rt.Run(context.Background(), api.Request{
+		Prompt:    compactionPrompt(cfg) + "\n\n---\n" + plan.Transcript(),
+		SessionID: sessionsCompactSessionID,
+	})
+	if err != nil {
+		return fmt.Errorf("compact session: %w", err)
+	}
+	if resp == nil || resp.Result == nil || strings.TrimSpace(resp.Result.Output) == "" {
+		return fmt.Errorf("compact session: model returned no summary; session left unchanged")
+	}
+
+	compacted := &session.Session{ID: sess.ID, Messages: plan.Apply(resp.Result.Output)}
+	if err := store.Save(compacted); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Compacted session %s: %d messages summarized, %d kept (previous history in %s.bak).\n",
+		sess.ID, len(plan.Dropped), len(plan.Kept), filepath.Join(store.Dir(), session.FileName(sess.ID)))
+	return nil
+}
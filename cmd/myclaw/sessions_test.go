@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+func seedSession(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := session.HistoryDir(filepath.Join(home, ".myclaw", "workspace"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	body := `{"version":1,"session_id":"telegram:42","updated_at":"2026-01-02T03:04:05Z","messages":[` +
+		`{"Role":"user","Content":"remind me to call mom"},{"Role":"assistant","Content":"Sure, when?"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "telegram-42.json"), []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func buildExportCommand(format, output string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("format", format, "")
+	cmd.Flags().String("output", output, "")
+	return cmd
+}
+
+func TestRunSessionsList(t *testing.T) {
+	seedSession(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runSessionsList(&cobra.Command{}, nil)
+	})
+	if err != nil {
+		t.Fatalf("runSessionsList error: %v", err)
+	}
+	if !strings.Contains(output, "telegram:42 (2 messages") || !strings.Contains(output, "remind me to call mom") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
+
+func TestRunSessionsList_JSON(t *testing.T) {
+	seedSession(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runSessionsList(buildJSONCommand(), nil)
+	})
+	if err != nil {
+		t.Fatalf("runSessionsList error: %v", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, output)
+	}
+	if payload["command"] != "sessions.list" || payload["count"] != float64(1) {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestRunSessionsShow(t *testing.T) {
+	seedSession(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runSessionsShow(&cobra.Command{}, []string{"telegram:42"})
+	})
+	if err != nil {
+		t.Fatalf("runSessionsShow error: %v", err)
+	}
+	if !strings.Contains(output, "## User") || !strings.Contains(output, "Sure, when?") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	if err := runSessionsShow(&cobra.Command{}, []string{"nope"}); err == nil {
+		t.Error("expected error for unknown session")
+	}
+}
+
+func TestRunSessionsDelete(t *testing.T) {
+	dir := seedSession(t)
+
+	_, err := captureRunOutput(t, func() error {
+		return runSessionsDelete(&cobra.Command{}, []string{"telegram:42"})
+	})
+	if err != nil {
+		t.Fatalf("runSessionsDelete error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "telegram-42.json")); !os.IsNotExist(err) {
+		t.Error("session file should be deleted")
+	}
+}
+
+func TestRunSessionsExport(t *testing.T) {
+	seedSession(t)
+	out := filepath.Join(t.TempDir(), "transcript.json")
+
+	_, err := captureRunOutput(t, func() error {
+		return runSessionsExport(buildExportCommand("json", out), []string{"telegram:42"})
+	})
+	if err != nil {
+		t.Fatalf("runSessionsExport error: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	var sess session.Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		t.Fatalf("invalid export: %v", err)
+	}
+	if sess.ID != "telegram:42" || len(sess.Messages) != 2 {
+		t.Errorf("exported = %+v", sess)
+	}
+
+	output, err := captureRunOutput(t, func() error {
+		return runSessionsExport(buildExportCommand("markdown", ""), []string{"telegram:42"})
+	})
+	if err != nil {
+		t.Fatalf("runSessionsExport error: %v", err)
+	}
+	if !strings.Contains(output, "# Session telegram:42") {
+		t.Errorf("unexpected markdown: %s", output)
+	}
+
+	if !strings.Contains(output, "- Exported: ") {
+		t.Errorf("markdown export should be timestamped: %s", output)
+	}
+
+	output, err = captureRunOutput(t, func() error {
+		return runSessionsExport(buildExportCommand("html", ""), []string{"telegram:42"})
+	})
+	if err != nil {
+		t.Fatalf("runSessionsExport html error: %v", err)
+	}
+	if !strings.HasPrefix(output, "<!DOCTYPE html>") || !strings.Contains(output, "remind me to call mom") {
+		t.Errorf("unexpected html: %s", output)
+	}
+
+	if err := runSessionsExport(buildExportCommand("pdf", ""), []string{"telegram:42"}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestRunSessionsCompact(t *testing.T) {
+	seedSession(t)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := &cobra.Command{}
+	cmd.Flags().Int("keep", 0, "")
+	cmd.Flags().Bool("dry-run", true, "")
+	cmd.Flags().Bool("yes", true, "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	_ = cmd.Flags().Set("keep", "1")
+
+	rt := &recordingRuntime{mockRuntime: mockRuntime{response: &api.Response{Result: &api.Result{Output: "They asked for a reminder."}}}}
+	opts := AgentOptions{RuntimeFactory: mockRuntimeFactory(rt)}
+	if err := runSessionsCompactWithOptions(cmd, []string{"telegram:42"}, cfg, opts); err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+	if !strings.Contains(out.String(), "- [user] remind me to call mom") || !strings.Contains(out.String(), "  [assistant] Sure, when?") {
+		t.Errorf("preview = %s", out.String())
+	}
+	if len(rt.requests) != 0 {
+		t.Fatal("a dry run should not call the model")
+	}
+
+	_ = cmd.Flags().Set("dry-run", "false")
+	if err := runSessionsCompactWithOptions(cmd, []string{"telegram:42"}, cfg, opts); err != nil {
+		t.Fatalf("compact error: %v", err)
+	}
+	if len(rt.requests) != 1 || !strings.Contains(rt.requests[0].Prompt, "User: remind me to call mom") {
+		t.Errorf("requests = %+v", rt.requests)
+	}
+	sess, err := session.NewStore(cfg.Agent.Workspace).Get("telegram:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sess.Messages) != 2 || !strings.Contains(sess.Messages[0].Content, "They asked for a reminder.") || sess.Messages[1].Content != "Sure, when?" {
+		t.Errorf("compacted = %+v", sess.Messages)
+	}
+}
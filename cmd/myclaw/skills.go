@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/skills"
+)
+
+var skillsInstallCmd = &cobra.Command{
+	Use:   "install <registry-name[@version]|git-url|archive|path>",
+	Short: "Install a skill from the registry, git, a tarball or a local directory",
+	Long: `Install a skill into the skills directory.
+
+The source can be the name of a skill in the registry set in skills.registry
+(append @<version> to pin a version; the archive's checksum is verified), a
+git repository (append #<branch-or-tag> to pick a ref), a .tar.gz/.tgz/.tar
+archive given as a path or http(s) URL, or a local directory. SKILL.md must
+be at the root of the source or inside its only top-level directory, and its
+frontmatter must parse and include a name.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSkillsInstall,
+}
+
+var skillsBrowseCmd = &cobra.Command{
+	Use:   "browse [query]",
+	Short: "List the skills in the registry",
+	Long: `List the skills in the registry set in skills.registry, optionally only
+those whose name or description contains query.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSkillsBrowse,
+}
+
+var skillsUpdateCmd = &cobra.Command{
+	Use:   "update [name...]",
+	Short: "Reinstall skills from the source they were installed from",
+	Long: `Reinstall skills from the source they were installed from. Without names,
+every skill installed with "skills install" is updated.`,
+	RunE: runSkillsUpdate,
+}
+
+var skillsRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Delete an installed skill",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSkillsRemove,
+}
+
+var skillsEnableCmd = &cobra.Command{
+	Use:   "enable <name>",
+	Short: "Load a disabled skill again",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSkillsEnable,
+}
+
+var skillsDisableCmd = &cobra.Command{
+	Use:   "disable <name>",
+	Short: "Stop loading a skill without deleting it",
+	Long: `Stop loading a skill without deleting it. The name is added to
+skills.disabled in the config file, which a running gateway reloads.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSkillsDisable,
+}
+
+var skillsTestCmd = &cobra.Command{
+	Use:   "test <name>",
+	Short: "Dry-run a skill against a prompt",
+	Long: `Dry-run a skill against a prompt without calling the model: show which of
+its matchers fire, whether the runtime would activate it (and which other
+skills the prompt activates), what its handler returns, and the prompt the
+model would receive.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSkillsTest,
+}
+
+func init() {
+	skillsInstallCmd.Flags().String("name", "", "Install under this directory name")
+	skillsInstallCmd.Flags().Bool("force", false, "Replace an installed skill with the same name")
+	skillsInstallCmd.Flags().Bool("json", false, "Output as JSON")
+	skillsBrowseCmd.Flags().Bool("json", false, "Output as JSON")
+	skillsUpdateCmd.Flags().Bool("json", false, "Output as JSON")
+	skillsRemoveCmd.Flags().Bool("json", false, "Output as JSON")
+	skillsEnableCmd.Flags().Bool("json", false, "Output as JSON")
+	skillsDisableCmd.Flags().Bool("json", false, "Output as JSON")
+	skillsTestCmd.Flags().StringP("prompt", "p", "", "Prompt to test against (required)")
+	skillsTestCmd.Flags().Bool("json", false, "Output as JSON")
+	skillsCmd.AddCommand(skillsInstallCmd, skillsBrowseCmd, skillsUpdateCmd, skillsRemoveCmd, skillsEnableCmd, skillsDisableCmd, skillsTestCmd)
+}
+
+func runSkillsInstall(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	name, _ := cmd.Flags().GetString("name")
+	force, _ := cmd.Flags().GetBool("force")
+
+	inst, err := skills.Install(resolveSkillsDir(cfg), args[0], skills.InstallOptions{Name: name, Force: force, Registry: cfg.Skills.Registry})
+	if err != nil {
+		return err
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": skillsJSONSchemaVersion,
+			"command":       "skills.install",
+			"ok":            true,
+			"skill":         inst,
+		})
+	}
+	if inst.Version != "" {
+		fmt.Printf("Installed skill %s %s (%s) in %s\n", inst.Name, inst.Version, inst.Kind, inst.Dir)
+	} else {
+		fmt.Printf("Installed skill %s (%s) in %s\n", inst.Name, inst.Kind, inst.Dir)
+	}
+	if !cfg.Skills.Enabled {
+		fmt.Println("Note: skills are disabled in config.")
+	}
+	return nil
+}
+
+func runSkillsBrowse(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	idx, err := skills.FetchIndex(cfg.Skills.Registry)
+	if err != nil {
+		return err
+	}
+	query := ""
+	if len(args) > 0 {
+		query = args[0]
+	}
+
+	// Note which registry skills are installed, and at what version.
+	installed := make(map[string]string)
+	skillDir := resolveSkillsDir(cfg)
+	entries, _ := os.ReadDir(skillDir)
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if inst, err := skills.ReadInstalled(filepath.Join(skillDir, entry.Name())); err == nil && inst.Kind == skills.SourceRegistry {
+			installed[inst.Name] = inst.Version
+		}
+	}
+
+	type browseItem struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Latest      string   `json:"latest"`
+		Versions    []string `json:"versions"`
+		Installed   string   `json:"installed,omitempty"`
+	}
+	items := []browseItem{}
+	for _, entry := range idx.Search(query) {
+		item := browseItem{Name: entry.Name, Description: entry.Description, Versions: []string{}, Installed: installed[entry.Name]}
+		if latest := entry.Latest(); latest != nil {
+			item.Latest = latest.Version
+		}
+		for _, v := range entry.Versions {
+			item.Versions = append(item.Versions, v.Version)
+		}
+		items = append(items, item)
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": skillsJSONSchemaVersion,
+			"command":       "skills.browse",
+			"ok":            true,
+			"registry":      cfg.Skills.Registry,
+			"skills":        items,
+		})
+	}
+	if len(items) == 0 {
+		fmt.Println("No skills found.")
+		return nil
+	}
+	for _, item := range items {
+		desc := item.Description
+		if desc == "" {
+			desc = "(no description)"
+		}
+		line := fmt.Sprintf("- %s %s: %s", item.Name, item.Latest, desc)
+		if item.Installed != "" {
+			line += fmt.Sprintf(" [installed %s]", item.Installed)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func runSkillsUpdate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	skillDir := resolveSkillsDir(cfg)
+
+	names := args
+	if len(names) == 0 {
+		entries, err := os.ReadDir(skillDir)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("read skills dir: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if _, err := skills.ReadInstalled(filepath.Join(skillDir, entry.Name())); err == nil {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+
+	updated := make([]*skills.Installed, 0, len(names))
+	failed := make(map[string]string)
+	for _, name := range names {
+		inst, err := skills.Update(skillDir, name)
+		if err != nil {
+			failed[name] = err.Error()
+			continue
+		}
+		updated = append(updated, inst)
+	}
+
+	if readJSONFlag(cmd) {
+		if err := printJSON(map[string]any{
+			"schemaVersion": skillsJSONSchemaVersion,
+			"command":       "skills.update",
+			"ok":            len(failed) == 0,
+			"updated":       updated,
+			"failed":        failed,
+		}); err != nil {
+			return err
+		}
+	} else {
+		if len(names) == 0 {
+			fmt.Println("No installed skills to update.")
+		}
+		for _, inst := range updated {
+			fmt.Printf("Updated %s from %s\n", inst.Name, inst.Source)
+		}
+		for name, msg := range failed {
+			fmt.Printf("Failed to update %s: %s\n", name, msg)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d skills failed to update", len(failed), len(names))
+	}
+	return nil
+}
+
+func runSkillsRemove(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	dir, err := skills.Remove(resolveSkillsDir(cfg), args[0])
+	if err != nil {
+		return err
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": skillsJSONSchemaVersion,
+			"command":       "skills.remove",
+			"ok":            true,
+			"name":          args[0],
+			"dir":           dir,
+		})
+	}
+	fmt.Printf("Removed skill %s (%s)\n", args[0], dir)
+	return nil
+}
+
+func runSkillsEnable(cmd *cobra.Command, args []string) error {
+	return setSkillDisabled(cmd, args[0], false)
+}
+
+func runSkillsDisable(cmd *cobra.Command, args []string) error {
+	return setSkillDisabled(cmd, args[0], true)
+}
+
+// setSkillDisabled adds the skill to or removes it from skills.disabled.
+func setSkillDisabled(cmd *cobra.Command, target string, disable bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	name := strings.TrimSpace(target)
+	registrations, err := loadSkills(cfg)
+	if err != nil {
+		return fmt.Errorf("load skills: %w", err)
+	}
+	if registration := findSkillRegistration(registrations, name); registration != nil {
+		name = registration.Definition.Name
+	} else if disable {
+		return fmt.Errorf("skill not found: %s", target)
+	}
+
+	changed := skills.IsDisabled(name, cfg.Skills.Disabled) != disable
+	if changed {
+		err = config.UpdateConfig(func(c *config.Config) error {
+			kept := make([]string, 0, len(c.Skills.Disabled)+1)
+			for _, d := range c.Skills.Disabled {
+				if !strings.EqualFold(strings.TrimSpace(d), name) {
+					kept = append(kept, d)
+				}
+			}
+			if disable {
+				kept = append(kept, name)
+			}
+			c.Skills.Disabled = kept
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+	}
+
+	command, state := "skills.enable", "enabled"
+	if disable {
+		command, state = "skills.disable", "disabled"
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": skillsJSONSchemaVersion,
+			"command":       command,
+			"ok":            true,
+			"name":          name,
+			"disabled":      disable,
+			"changed":       changed,
+		})
+	}
+	if !changed {
+		fmt.Printf("Skill %s is already %s.\n", name, state)
+		return nil
+	}
+	fmt.Printf("Skill %s %s. Restart the gateway to apply.\n", name, state)
+	return nil
+}
+
+func runSkillsTest(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	prompt, _ := cmd.Flags().GetString("prompt")
+	if strings.TrimSpace(prompt) == "" {
+		return fmt.Errorf("--prompt is required")
+	}
+	registrations, err := loadSkills(cfg)
+	if err != nil {
+		return fmt.Errorf("load skills: %w", err)
+	}
+	registration := findSkillRegistration(registrations, args[0])
+	if registration == nil {
+		return fmt.Errorf("skill not found: %s", args[0])
+	}
+
+	// Test against the skills the runtime would load, plus the target even
+	// if it is disabled.
+	disabled := skills.IsDisabled(registration.Definition.Name, cfg.Skills.Disabled)
+	loaded := skills.FilterDisabled(registrations, cfg.Skills.Disabled)
+	if disabled {
+		loaded = append(loaded, *registration)
+	}
+	result, err := skills.DryRun(context.Background(), loaded, registration.Definition.Name, prompt)
+	if err != nil {
+		return err
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": skillsJSONSchemaVersion,
+			"command":       "skills.test",
+			"ok":            true,
+			"disabled":      disabled,
+			"skillsEnabled": cfg.Skills.Enabled,
+			"result":        result,
+		})
+	}
+
+	fmt.Printf("Skill: %s\n", result.Skill)
+	if !cfg.Skills.Enabled {
+		fmt.Println("Note: skills are disabled in config; the runtime loads none.")
+	}
+	if disabled {
+		fmt.Println("Note: this skill is disabled; the runtime would not load it.")
+	}
+	if result.InvalidName != "" {
+		fmt.Printf("Warning: %s\n", result.InvalidName)
+	}
+	if len(result.Matchers) == 0 {
+		fmt.Println("Matchers: none (activates on every prompt)")
+	} else {
+		fmt.Println("Matchers:")
+		for _, m := range result.Matchers {
+			status := "no match"
+			if m.Matched {
+				status = fmt.Sprintf("match (score %.2f, %s)", m.Score, m.Reason)
+			}
+			fmt.Printf("  - %s: %s\n", m.Matcher, status)
+		}
+	}
+	if result.Activated {
+		fmt.Printf("Activated: yes (score %.2f, %s)\n", result.Score, result.Reason)
+	} else {
+		fmt.Println("Activated: no")
+	}
+	if len(result.AlsoActivated) > 0 {
+		fmt.Printf("Also activated: %s\n", strings.Join(result.AlsoActivated, ", "))
+	}
+	if result.HandlerError != "" {
+		fmt.Printf("Handler error: %s\n", result.HandlerError)
+	} else {
+		fmt.Printf("\nHandler output:\n%s\n", result.Output)
+	}
+	fmt.Printf("\nPrompt sent to the model:\n%s\n", result.Prompt)
+	return nil
+}
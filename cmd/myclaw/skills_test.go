@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func skillsCommand(flags map[string]string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", false, "")
+	cmd.Flags().String("name", "", "")
+	cmd.Flags().Bool("force", false, "")
+	for k, v := range flags {
+		_ = cmd.Flags().Set(k, v)
+	}
+	return cmd
+}
+
+func TestRunSkillsInstallUpdateRemove(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := t.TempDir()
+	srcSkill := writeSkillFile(t, src, "writer", "v1")
+
+	output, err := captureRunOutput(t, func() error {
+		return runSkillsInstall(skillsCommand(nil), []string{filepath.Dir(srcSkill)})
+	})
+	if err != nil {
+		t.Fatalf("runSkillsInstall error: %v", err)
+	}
+	if !strings.Contains(output, "Installed skill writer (path)") {
+		t.Errorf("install output = %s", output)
+	}
+	cfg, _ := config.LoadConfig()
+	if _, err := os.Stat(filepath.Join(resolveSkillsDir(cfg), "writer", "SKILL.md")); err != nil {
+		t.Fatalf("skill not installed: %v", err)
+	}
+
+	if _, err := captureRunOutput(t, func() error {
+		return runSkillsInstall(skillsCommand(nil), []string{filepath.Dir(srcSkill)})
+	}); err == nil {
+		t.Error("expected collision error")
+	}
+
+	writeSkillFile(t, src, "writer", "v2")
+	output, err = captureRunOutput(t, func() error {
+		return runSkillsUpdate(skillsCommand(map[string]string{"json": "true"}), nil)
+	})
+	if err != nil {
+		t.Fatalf("runSkillsUpdate error: %v", err)
+	}
+	var payload struct {
+		Command string `json:"command"`
+		OK      bool   `json:"ok"`
+		Updated []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"updated"`
+	}
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if payload.Command != "skills.update" || !payload.OK || len(payload.Updated) != 1 || payload.Updated[0].Description != "v2" {
+		t.Errorf("update payload = %+v", payload)
+	}
+
+	output, err = captureRunOutput(t, func() error {
+		return runSkillsRemove(skillsCommand(nil), []string{"writer"})
+	})
+	if err != nil || !strings.Contains(output, "Removed skill writer") {
+		t.Fatalf("runSkillsRemove = %q, %v", output, err)
+	}
+	if _, err := os.Stat(filepath.Join(resolveSkillsDir(cfg), "writer")); !os.IsNotExist(err) {
+		t.Errorf("skill still present: %v", err)
+	}
+}
+
+func TestRunSkillsEnableDisable(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "env-key")
+	cfg, _ := config.LoadConfig()
+	writeSkillFile(t, cfg.Agent.Workspace, "writer", "writing helper")
+
+	output, err := captureRunOutput(t, func() error {
+		return runSkillsDisable(skillsCommand(nil), []string{"WRITER"})
+	})
+	if err != nil || !strings.Contains(output, "Skill writer disabled") {
+		t.Fatalf("disable = %q, %v", output, err)
+	}
+	cfg, _ = config.LoadConfig()
+	if len(cfg.Skills.Disabled) != 1 || cfg.Skills.Disabled[0] != "writer" {
+		t.Errorf("skills.disabled = %v", cfg.Skills.Disabled)
+	}
+	if regs := loadRuntimeSkills(cfg); len(regs) != 0 {
+		t.Errorf("disabled skill still loaded: %d", len(regs))
+	}
+	data, _ := os.ReadFile(config.ConfigPath())
+	if strings.Contains(string(data), "env-key") {
+		t.Error("environment API key written to config")
+	}
+
+	output, _ = captureRunOutput(t, func() error {
+		return runSkillsList(&cobra.Command{}, nil)
+	})
+	if !strings.Contains(output, "- writer: writing helper (disabled)") {
+		t.Errorf("list output = %s", output)
+	}
+	output, _ = captureRunOutput(t, func() error {
+		return runSkillsDisable(skillsCommand(map[string]string{"json": "true"}), []string{"writer"})
+	})
+	if !strings.Contains(output, `"changed": false`) {
+		t.Errorf("repeat disable = %s", output)
+	}
+
+	if _, err := captureRunOutput(t, func() error {
+		return runSkillsEnable(skillsCommand(nil), []string{"writer"})
+	}); err != nil {
+		t.Fatalf("enable error: %v", err)
+	}
+	cfg, _ = config.LoadConfig()
+	if len(cfg.Skills.Disabled) != 0 || len(loadRuntimeSkills(cfg)) != 1 {
+		t.Errorf("after enable: disabled = %v", cfg.Skills.Disabled)
+	}
+
+	if _, err := captureRunOutput(t, func() error {
+		return runSkillsDisable(skillsCommand(nil), []string{"missing"})
+	}); err == nil {
+		t.Error("expected error disabling unknown skill")
+	}
+}
+
+func TestRunSkillsTest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg, _ := config.LoadConfig()
+	writeSkillFile(t, cfg.Agent.Workspace, "writer", "writing helper")
+
+	cmd := skillsCommand(nil)
+	cmd.Flags().String("prompt", "", "")
+	cmd.Flags().Set("prompt", "draft an email")
+	output, err := captureRunOutput(t, func() error {
+		return runSkillsTest(cmd, []string{"writer"})
+	})
+	if err != nil {
+		t.Fatalf("runSkillsTest error: %v", err)
+	}
+	for _, want := range []string{"Activated: yes", "keywords: any of [draft, write]: match", "Use this skill for writing tasks.", "Prompt sent to the model:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+
+	cmd.Flags().Set("prompt", "what's the weather")
+	cmd.Flags().Set("json", "true")
+	output, _ = captureRunOutput(t, func() error {
+		return runSkillsTest(cmd, []string{"writer"})
+	})
+	var payload struct {
+		Command string `json:"command"`
+		Result  struct {
+			Activated bool   `json:"activated"`
+			Prompt    string `json:"prompt"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if payload.Command != "skills.test" || payload.Result.Activated || payload.Result.Prompt != "what's the weather" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	cmd.Flags().Set("prompt", "")
+	if err := runSkillsTest(cmd, []string{"writer"}); err == nil {
+		t.Error("expected error without --prompt")
+	}
+}
+
+func TestRunSkillsInfo_Parameters(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg, _ := config.LoadConfig()
+	skillPath := filepath.Join(cfg.Agent.Workspace, "skills", "translator", "SKILL.md")
+	content := "---\nname: translator\nparameters:\n  - name: language\n    description: Target language\n  - name: formal\n    type: boolean\n    default: true\n---\nTranslate into {{language}}.\n"
+	if err := os.MkdirAll(filepath.Dir(skillPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(skillPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := captureRunOutput(t, func() error {
+		return runSkillsInfo(skillsCommand(nil), []string{"translator"})
+	})
+	if err != nil {
+		t.Fatalf("runSkillsInfo error: %v", err)
+	}
+	for _, want := range []string{"Parameters (tool skill_translator):", "- language (string, required): Target language", "- formal (boolean, default true)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+
+	output, _ = captureRunOutput(t, func() error {
+		return runSkillsInfo(skillsCommand(map[string]string{"json": "true"}), []string{"translator"})
+	})
+	var payload struct {
+		Parameters []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"parameters"`
+		ParameterSchema struct {
+			Required []string `json:"required"`
+		} `json:"parameterSchema"`
+	}
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if len(payload.Parameters) != 2 || payload.Parameters[0].Type != "string" || len(payload.ParameterSchema.Required) != 1 {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestRunSkillsBrowse_NoRegistry(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	err := runSkillsBrowse(skillsCommand(nil), nil)
+	if err == nil || !strings.Contains(err.Error(), "skills.registry") {
+		t.Fatalf("err = %v, want a hint to set skills.registry", err)
+	}
+}
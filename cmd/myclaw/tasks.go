@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/tasks"
+)
+
+const tasksJSONSchemaVersion = 1
+
+var tasksCmd = &cobra.Command{
+	Use:   "tasks",
+	Short: "Manage the agent's background tasks",
+	Long: `Manage the background tasks the agent started from chats. The gateway
+runs them one at a time and sends the result to the chat that asked.`,
+}
+
+var tasksListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List background tasks, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runTasksList,
+}
+
+var tasksStatusCmd = &cobra.Command{
+	Use:   "status <id>",
+	Short: "Show a task's status",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTasksStatus,
+}
+
+var tasksCancelCmd = &cobra.Command{
+	Use:   "cancel <id>",
+	Short: "Cancel a queued or running task",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTasksCancel,
+}
+
+var tasksResultCmd = &cobra.Command{
+	Use:   "result <id>",
+	Short: "Print a finished task's result",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTasksResult,
+}
+
+func init() {
+	tasksListCmd.Flags().Bool("json", false, "Output as JSON")
+	tasksStatusCmd.Flags().Bool("json", false, "Output as JSON")
+	tasksCmd.AddCommand(tasksListCmd, tasksStatusCmd, tasksCancelCmd, tasksResultCmd)
+	rootCmd.AddCommand(tasksCmd)
+}
+
+func openTaskStore() (*tasks.Store, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	return tasks.NewStore(tasks.Path(cfg.Agent.Workspace)), nil
+}
+
+func runTasksList(cmd *cobra.Command, args []string) error {
+	store, err := openTaskStore()
+	if err != nil {
+		return err
+	}
+	list, err := store.List("", "")
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = []tasks.Task{}
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": tasksJSONSchemaVersion,
+			"command":       "tasks.list",
+			"ok":            true,
+			"tasks":         list,
+		})
+	}
+	if len(list) == 0 {
+		fmt.Println("No background tasks.")
+		return nil
+	}
+	for _, t := range list {
+		fmt.Println(t)
+	}
+	return nil
+}
+
+// getTask returns task id, or an error when there is none.
+func getTask(store *tasks.Store, id string) (tasks.Task, error) {
+	t, ok, err := store.Get(id)
+	if err != nil {
+		return tasks.Task{}, err
+	}
+	if !ok {
+		return tasks.Task{}, fmt.Errorf("no background task %q", id)
+	}
+	return t, nil
+}
+
+func runTasksStatus(cmd *cobra.Command, args []string) error {
+	store, err := openTaskStore()
+	if err != nil {
+		return err
+	}
+	t, err := getTask(store, args[0])
+	if err != nil {
+		return err
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": tasksJSONSchemaVersion,
+			"command":       "tasks.status",
+			"ok":            true,
+			"task":          t,
+		})
+	}
+	fmt.Printf("Task:    %s\n", t.ID)
+	fmt.Printf("Title:   %s\n", t.Title)
+	fmt.Printf("Status:  %s\n", t.Status)
+	if t.Channel != "" {
+		fmt.Printf("Chat:    %s/%s\n", t.Channel, t.ChatID)
+	}
+	fmt.Printf("Created: %s\n", time.UnixMilli(t.CreatedAtMs).Format(time.RFC3339))
+	if t.StartedAtMs > 0 {
+		fmt.Printf("Started: %s\n", time.UnixMilli(t.StartedAtMs).Format(time.RFC3339))
+	}
+	if t.FinishedAtMs > 0 {
+		fmt.Printf("Ended:   %s\n", time.UnixMilli(t.FinishedAtMs).Format(time.RFC3339))
+	}
+	if t.Error != "" {
+		fmt.Printf("Error:   %s\n", t.Error)
+	}
+	fmt.Printf("\n%s\n", t.Prompt)
+	return nil
+}
+
+func runTasksCancel(cmd *cobra.Command, args []string) error {
+	store, err := openTaskStore()
+	if err != nil {
+		return err
+	}
+	t, ok, err := store.Cancel(args[0], "", "", time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no background task %q", args[0])
+	}
+	if t.Status != tasks.Canceled {
+		return fmt.Errorf("background task %s is already %s", t.ID, t.Status)
+	}
+	fmt.Printf("Canceled task %s. A running task stops within a few seconds.\n", t.ID)
+	return nil
+}
+
+func runTasksResult(cmd *cobra.Command, args []string) error {
+	store, err := openTaskStore()
+	if err != nil {
+		return err
+	}
+	t, err := getTask(store, args[0])
+	if err != nil {
+		return err
+	}
+	switch t.Status {
+	case tasks.Done:
+		fmt.Println(strings.TrimRight(t.Result, "\n"))
+		return nil
+	case tasks.Failed:
+		return fmt.Errorf("background task %s failed: %s", t.ID, t.Error)
+	default:
+		return fmt.Errorf("background task %s is %s and has no result", t.ID, t.Status)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/tasks"
+)
+
+func TestRunTasks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	output, err := captureRunOutput(t, func() error { return runTasksList(&cobra.Command{}, nil) })
+	if err != nil || output != "No background tasks.\n" {
+		t.Errorf("empty list = %q, %v", output, err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := tasks.NewStore(tasks.Path(cfg.Agent.Workspace))
+	now := time.Now()
+	done, _ := store.Add("Solar research", "Research solar panels", "telegram", "42", now)
+	_, _, _ = store.Next(now)
+	_, _, _ = store.Finish(done.ID, "Panels pay for themselves.", nil, now)
+	queued, _ := store.Add("Trip plan", "Plan a trip", "telegram", "42", now.Add(time.Second))
+
+	output, _ = captureRunOutput(t, func() error { return runTasksList(&cobra.Command{}, nil) })
+	if lines := strings.Split(strings.TrimSpace(output), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[0], queued.ID+" queued") {
+		t.Errorf("list = %q", output)
+	}
+	output, err = captureRunOutput(t, func() error { return runTasksStatus(buildJSONCommand(), []string{done.ID}) })
+	if err != nil || !strings.Contains(output, `"command": "tasks.status"`) || !strings.Contains(output, `"status": "done"`) {
+		t.Errorf("status = %q, %v", output, err)
+	}
+	output, err = captureRunOutput(t, func() error { return runTasksResult(&cobra.Command{}, []string{done.ID}) })
+	if err != nil || output != "Panels pay for themselves.\n" {
+		t.Errorf("result = %q, %v", output, err)
+	}
+
+	if err := runTasksResult(&cobra.Command{}, []string{queued.ID}); err == nil {
+		t.Error("result of a queued task")
+	}
+	if _, err := captureRunOutput(t, func() error { return runTasksCancel(&cobra.Command{}, []string{queued.ID}) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := runTasksCancel(&cobra.Command{}, []string{done.ID}); err == nil || !strings.Contains(err.Error(), "already done") {
+		t.Errorf("cancel of a finished task: %v", err)
+	}
+	if err := runTasksStatus(&cobra.Command{}, []string{"nope"}); err == nil {
+		t.Error("status of a missing task")
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/todo"
+)
+
+const todoJSONSchemaVersion = 1
+
+var todoCmd = &cobra.Command{
+	Use:   "todo",
+	Short: "Manage the todo list the agent keeps",
+	Long: `Manage the todo list in the workspace. The agent reads and updates the
+same list with its todo tools, and the heartbeat reminds you of overdue items
+once a day.`,
+}
+
+var todoAddCmd = &cobra.Command{
+	Use:   "add <text>",
+	Short: "Add an item",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runTodoAdd,
+}
+
+var todoListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List open items, soonest due first",
+	Args:  cobra.NoArgs,
+	RunE:  runTodoList,
+}
+
+var todoDoneCmd = &cobra.Command{
+	Use:   "done <id>",
+	Short: "Check off an item",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTodoDone,
+}
+
+var todoRmCmd = &cobra.Command{
+	Use:   "rm <id>",
+	Short: "Delete an item",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTodoRm,
+}
+
+func init() {
+	todoAddCmd.Flags().String("due", "", `When it is due: "tomorrow", "friday", "2026-10-20", "in 2 hours", "tomorrow at 9am"`)
+	todoAddCmd.Flags().Bool("json", false, "Output as JSON")
+	todoListCmd.Flags().Bool("all", false, "Include items already done")
+	todoListCmd.Flags().Bool("json", false, "Output as JSON")
+	todoDoneCmd.Flags().Bool("json", false, "Output as JSON")
+	todoCmd.AddCommand(todoAddCmd, todoListCmd, todoDoneCmd, todoRmCmd)
+	rootCmd.AddCommand(todoCmd)
+}
+
+func openTodoStore() (*todo.Store, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	return todo.NewStore(todo.Path(cfg.Agent.Workspace)), nil
+}
+
+func runTodoAdd(cmd *cobra.Command, args []string) error {
+	store, err := openTodoStore()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var due time.Time
+	var day bool
+	if s, _ := cmd.Flags().GetString("due"); s != "" {
+		if due, day, err = todo.ParseDue(s, now); err != nil {
+			return err
+		}
+	}
+	it, err := store.Add(strings.Join(args, " "), due, day, now)
+	if err != nil {
+		return err
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": todoJSONSchemaVersion,
+			"command":       "todo.add",
+			"ok":            true,
+			"item":          it,
+		})
+	}
+	fmt.Printf("Added %s\n", it)
+	return nil
+}
+
+func runTodoList(cmd *cobra.Command, args []string) error {
+	store, err := openTodoStore()
+	if err != nil {
+		return err
+	}
+	all, _ := cmd.Flags().GetBool("all")
+	items, err := store.List(all)
+	if err != nil {
+		return err
+	}
+	if items == nil {
+		items = []todo.Item{}
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": todoJSONSchemaVersion,
+			"command":       "todo.list",
+			"ok":            true,
+			"items":         items,
+		})
+	}
+	fmt.Print(todo.Format(items))
+	return nil
+}
+
+func runTodoDone(cmd *cobra.Command, args []string) error {
+	id, err := todo.ParseID(args[0])
+	if err != nil {
+		return err
+	}
+	store, err := openTodoStore()
+	if err != nil {
+		return err
+	}
+	it, ok, err := store.Complete(id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no todo %d", id)
+	}
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": todoJSONSchemaVersion,
+			"command":       "todo.done",
+			"ok":            true,
+			"item":          it,
+		})
+	}
+	fmt.Printf("Checked off %d. %s\n", it.ID, it.Text)
+	return nil
+}
+
+func runTodoRm(cmd *cobra.Command, args []string) error {
+	id, err := todo.ParseID(args[0])
+	if err != nil {
+		return err
+	}
+	store, err := openTodoStore()
+	if err != nil {
+		return err
+	}
+	removed, err := store.Remove(id)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("no todo %d", id)
+	}
+	fmt.Printf("Deleted todo %d.\n", id)
+	return nil
+}
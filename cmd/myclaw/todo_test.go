@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func todoAddCommand(due string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("due", "", "")
+	cmd.Flags().Bool("json", false, "")
+	_ = cmd.Flags().Set("due", due)
+	return cmd
+}
+
+func TestRunTodo(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := runTodoAdd(todoAddCommand("someday"), []string{"Pay rent"}); err == nil {
+		t.Error("add accepted --due someday")
+	}
+	output, err := captureRunOutput(t, func() error {
+		return runTodoAdd(todoAddCommand("2026-10-20"), []string{"Pay", "rent"})
+	})
+	if err != nil || output != "Added 1. Pay rent (due Tue 20 Oct)\n" {
+		t.Errorf("add = %q, %v", output, err)
+	}
+	if _, err := captureRunOutput(t, func() error { return runTodoAdd(todoAddCommand(""), []string{"Buy milk"}) }); err != nil {
+		t.Fatal(err)
+	}
+
+	listCmd := &cobra.Command{}
+	listCmd.Flags().Bool("all", false, "")
+	listCmd.Flags().Bool("json", false, "")
+	output, _ = captureRunOutput(t, func() error { return runTodoList(listCmd, nil) })
+	if output != "1. Pay rent (due Tue 20 Oct)\n2. Buy milk\n" {
+		t.Errorf("list = %q", output)
+	}
+
+	if err := runTodoDone(buildJSONCommand(), []string{"7"}); err == nil {
+		t.Error("done accepted a missing item")
+	}
+	output, err = captureRunOutput(t, func() error { return runTodoDone(buildJSONCommand(), []string{"#1"}) })
+	if err != nil || !strings.Contains(output, `"command": "todo.done"`) || !strings.Contains(output, `"doneAtMs"`) {
+		t.Errorf("done = %q, %v", output, err)
+	}
+	if _, err := captureRunOutput(t, func() error { return runTodoRm(&cobra.Command{}, []string{"2"}) }); err != nil {
+		t.Fatal(err)
+	}
+
+	output, _ = captureRunOutput(t, func() error { return runTodoList(buildJSONCommand(), nil) })
+	if !strings.Contains(output, `"items": []`) {
+		t.Errorf("list after done and rm = %q", output)
+	}
+}
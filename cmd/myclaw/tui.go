@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/session"
+	"github.com/stellarlinkco/myclaw/internal/tui"
+)
+
+// runTUI shows the TUI. Tests replace it.
+var runTUI = tui.Run
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Chat with the agent in a full-screen terminal UI",
+	Long: `Chat with the agent in a full-screen terminal UI: answers stream in as
+they are written, tool calls show as they run, and past sessions and memory
+are a key away.
+
+Keys: Enter sends, PgUp/PgDn scroll, Ctrl-S switches session, Ctrl-O shows
+memory, Ctrl-N starts a new session and Ctrl-C stops an answer or quits.
+Set NO_COLOR to turn colors off.`,
+	Args: cobra.NoArgs,
+	RunE: runTUICmd,
+}
+
+func init() {
+	tuiCmd.Flags().String("session", "", "Continue the session with this id")
+	rootCmd.AddCommand(tuiCmd)
+}
+
+func runTUICmd(cmd *cobra.Command, _ []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	return runTUIWithOptions(cmd, cfg, AgentOptions{RuntimeFactory: DefaultRuntimeFactory})
+}
+
+func runTUIWithOptions(cmd *cobra.Command, cfg *config.Config, opts AgentOptions) error {
+	rt, err := opts.RuntimeFactory(cfg)
+	if err != nil {
+		return err
+	}
+	defer rt.Close()
+
+	sessionID, _ := cmd.Flags().GetString("session")
+	err = runTUI(context.Background(), tui.Options{
+		Stream:    streamFunc(rt),
+		Sessions:  session.NewStore(cfg.Agent.Workspace),
+		Memory:    memory.NewMemoryStore(cfg.MemoryWorkspace()),
+		Model:     modelLabel(cfg),
+		SessionID: sessionID,
+		Color:     os.Getenv("NO_COLOR") == "",
+	})
+	if errors.Is(err, tui.ErrNotTerminal) {
+		return fmt.Errorf("%w; use 'myclaw agent' for line-based input", err)
+	}
+	return err
+}
+
+// streamFunc streams from rt, or fakes a one-event stream when rt cannot
+// stream.
+func streamFunc(rt Runtime) func(context.Context, api.Request) (<-chan api.StreamEvent, error) {
+	return func(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error) {
+		if st, ok := rt.(interface {
+			RunStream(context.Context, api.Request) (<-chan api.StreamEvent, error)
+		}); ok {
+			return st.RunStream(ctx, req)
+		}
+		resp, err := rt.Run(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		ch := make(chan api.StreamEvent, 1)
+		if resp != nil && resp.Result != nil {
+			ch <- api.StreamEvent{Type: api.EventContentBlockDelta, Delta: &api.Delta{Type: "text_delta", Text: resp.Result.Output}}
+		}
+		close(ch)
+		return ch, nil
+	}
+}
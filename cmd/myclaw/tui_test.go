@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/tui"
+)
+
+func TestRunTUIWithOptions(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = t.TempDir()
+	rt := &mockRuntime{response: &api.Response{Result: &api.Result{Output: "hello"}}}
+	cmd := &cobra.Command{}
+	cmd.Flags().String("session", "s1", "")
+
+	var got tui.Options
+	orig := runTUI
+	runTUI = func(ctx context.Context, opts tui.Options) error {
+		got = opts
+		return nil
+	}
+	t.Cleanup(func() { runTUI = orig })
+
+	if err := runTUIWithOptions(cmd, cfg, AgentOptions{RuntimeFactory: mockRuntimeFactory(rt)}); err != nil {
+		t.Fatalf("runTUIWithOptions error: %v", err)
+	}
+	if got.SessionID != "s1" || got.Sessions == nil || got.Memory == nil || got.Model == "" {
+		t.Errorf("options = %+v", got)
+	}
+	if !rt.closed {
+		t.Error("runtime not closed")
+	}
+
+	// A runtime that can't stream answers in one event.
+	events, err := got.Stream(context.Background(), api.Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	var text strings.Builder
+	for ev := range events {
+		if ev.Delta != nil {
+			text.WriteString(ev.Delta.Text)
+		}
+	}
+	if text.String() != "hello" {
+		t.Errorf("streamed %q", text.String())
+	}
+}
+
+func TestRunTUIWithOptions_NotTerminal(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = t.TempDir()
+	cmd := &cobra.Command{}
+	cmd.Flags().String("session", "", "")
+
+	err := runTUIWithOptions(cmd, cfg, AgentOptions{RuntimeFactory: mockRuntimeFactory(&mockRuntime{})})
+	if !errors.Is(err, tui.ErrNotTerminal) || !strings.Contains(err.Error(), "myclaw agent") {
+		t.Errorf("err = %v", err)
+	}
+}
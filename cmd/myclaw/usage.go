@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/ratelimit"
+	"github.com/stellarlinkco/myclaw/internal/usage"
+)
+
+const usageJSONSchemaVersion = 1
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show token usage and estimated cost",
+	Long: `Show the tokens agent runs used and what they cost, from the usage ledger,
+grouped by day, channel, model or session. Costs are estimates from list
+prices; set tokenTracking.prices to correct them.`,
+	Args: cobra.NoArgs,
+	RunE: runUsage,
+}
+
+var usageUsersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Show who is using the agent's message and token budget",
+	Long: `Show the messages and tokens each chat user consumed in a day, heaviest
+first, against the limits in the rateLimit config.`,
+	Args: cobra.NoArgs,
+	RunE: runUsageUsers,
+}
+
+func init() {
+	usageCmd.Flags().String("since", "7d", "How far back to look: 30m, 24h, 7d or a date (YYYY-MM-DD)")
+	usageCmd.Flags().String("by", "day", "Group by day, channel, model or session")
+	usageCmd.Flags().Bool("json", false, "Output as JSON")
+	usageUsersCmd.Flags().String("day", "", "Day to show, as YYYY-MM-DD (default today)")
+	usageUsersCmd.Flags().Bool("json", false, "Output as JSON")
+	usageCmd.AddCommand(usageUsersCmd)
+	rootCmd.AddCommand(usageCmd)
+}
+
+// parseSince reads --since: a duration in minutes, hours or days back from
+// now, or a date.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && strings.HasSuffix(value, "d") && n > 0 {
+		return now.AddDate(0, 0, -n), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: want 30m, 24h, 7d or YYYY-MM-DD", value)
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	by, _ := cmd.Flags().GetString("by")
+	since, err := parseSince(sinceFlag, time.Now())
+	if err != nil {
+		return err
+	}
+	if usage.Groupings[by] == nil {
+		return fmt.Errorf("invalid --by %q: want day, channel, model or session", by)
+	}
+
+	entries, err := usage.Read(usage.Path(cfg.Agent.Workspace), since)
+	if err != nil {
+		return err
+	}
+	groups, total := usage.Summarize(entries, by)
+
+	if readJSONFlag(cmd) {
+		if groups == nil {
+			groups = []usage.Totals{}
+		}
+		return printJSON(map[string]any{
+			"schemaVersion": usageJSONSchemaVersion,
+			"command":       "usage",
+			"ok":            true,
+			"since":         since.Format(time.RFC3339),
+			"by":            by,
+			"groups":        groups,
+			"total":         total,
+		})
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No usage since %s.\n", since.Format("2006-01-02 15:04"))
+		return nil
+	}
+	fmt.Printf("Usage since %s, by %s:\n", since.Format("2006-01-02 15:04"), by)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tRUNS\tINPUT\tOUTPUT\tCACHED\tCOST\n", strings.ToUpper(by))
+	for _, t := range append(groups, total) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n", t.Key, t.Runs, t.InputTokens, t.OutputTokens, t.CacheReadTokens, formatCost(t))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if total.UnpricedRuns > 0 {
+		fmt.Printf("%d runs used models without a known price; add them to tokenTracking.prices.\n", total.UnpricedRuns)
+	}
+	if total.CacheReadTokens > 0 || total.CacheWriteTokens > 0 {
+		fmt.Printf("Prompt caching: %d tokens read from the cache, %d written; saved about $%.2f.\n", total.CacheReadTokens, total.CacheWriteTokens, total.SavedUSD)
+	}
+	if budget := cfg.TokenTracking.DailyBudgetUSD; budget > 0 {
+		today, _ := usage.Summarize(entriesSince(entries, startOfDay(time.Now())), "day")
+		spent := 0.0
+		if len(today) > 0 {
+			spent = today[0].CostUSD
+		}
+		fmt.Printf("Today: $%.2f of the $%.2f daily budget.\n", spent, budget)
+	}
+	return nil
+}
+
+func formatCost(t usage.Totals) string {
+	cost := fmt.Sprintf("$%.2f", t.CostUSD)
+	if t.UnpricedRuns > 0 {
+		cost += "+"
+	}
+	return cost
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func entriesSince(entries []usage.Entry, since time.Time) []usage.Entry {
+	i := slices.IndexFunc(entries, func(e usage.Entry) bool { return !e.Time.Before(since) })
+	if i < 0 {
+		return nil
+	}
+	return entries[i:]
+}
+
+func runUsageUsers(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	day, _ := cmd.Flags().GetString("day")
+	if day == "" {
+		day = time.Now().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", day); err != nil {
+		return fmt.Errorf("invalid --day %q: want YYYY-MM-DD", day)
+	}
+
+	perUser, err := ratelimit.Load(ratelimit.Path(cfg.Agent.Workspace))
+	if err != nil {
+		return err
+	}
+	users := perUser.Day(day)
+
+	if readJSONFlag(cmd) {
+		type userJSON struct {
+			ratelimit.UserUsage
+			Tokens int              `json:"tokens"`
+			Limit  config.RateLimit `json:"limit"`
+		}
+		out := make([]userJSON, 0, len(users))
+		for _, u := range users {
+			out = append(out, userJSON{UserUsage: u, Tokens: u.Tokens(), Limit: cfg.RateLimit.For(usageChannel(u.User))})
+		}
+		return printJSON(map[string]any{
+			"schemaVersion": usageJSONSchemaVersion,
+			"command":       "usage.users",
+			"ok":            true,
+			"day":           day,
+			"count":         len(out),
+			"users":         out,
+		})
+	}
+
+	if len(users) == 0 {
+		fmt.Printf("No usage on %s.\n", day)
+		return nil
+	}
+	fmt.Printf("Usage on %s:\n", day)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USER\tMESSAGES\tINPUT\tOUTPUT\tTOKENS\tLIMITED")
+	for _, u := range users {
+		tokens := fmt.Sprint(u.Tokens())
+		if limit := cfg.RateLimit.For(usageChannel(u.User)).TokensPerDay; limit > 0 {
+			tokens = fmt.Sprintf("%d/%d", u.Tokens(), limit)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%d\n", u.User, u.Messages, u.InputTokens, u.OutputTokens, tokens, u.Limited)
+	}
+	return w.Flush()
+}
+
+// usageChannel returns the channel of a "channel:sender" usage key.
+func usageChannel(user string) string {
+	channel, _, _ := strings.Cut(user, ":")
+	return channel
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/ratelimit"
+	"github.com/stellarlinkco/myclaw/internal/usage"
+)
+
+func seedUsage(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := ratelimit.Path(filepath.Join(home, ".myclaw", "workspace"))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	body := `{"2026-10-16": {
+		"telegram:7": {"user": "telegram:7", "messages": 3, "inputTokens": 100, "outputTokens": 20},
+		"telegram:42": {"user": "telegram:42", "messages": 9, "inputTokens": 900, "outputTokens": 300, "limited": 2}
+	}}`
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func buildUsageUsersCommand(day string, jsonOutput bool) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("day", day, "")
+	cmd.Flags().Bool("json", jsonOutput, "")
+	return cmd
+}
+
+func TestRunUsageUsers(t *testing.T) {
+	seedUsage(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runUsageUsers(buildUsageUsersCommand("2026-10-16", false), nil)
+	})
+	if err != nil {
+		t.Fatalf("runUsageUsers error: %v", err)
+	}
+	if strings.Index(output, "telegram:42") > strings.Index(output, "telegram:7") || !strings.Contains(output, "1200") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	output, _ = captureRunOutput(t, func() error {
+		return runUsageUsers(buildUsageUsersCommand("2026-10-15", false), nil)
+	})
+	if !strings.Contains(output, "No usage on 2026-10-15.") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	if err := runUsageUsers(buildUsageUsersCommand("yesterday", false), nil); err == nil {
+		t.Error("expected error for a bad --day")
+	}
+}
+
+func TestRunUsageUsers_JSON(t *testing.T) {
+	seedUsage(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runUsageUsers(buildUsageUsersCommand("2026-10-16", true), nil)
+	})
+	if err != nil {
+		t.Fatalf("runUsageUsers error: %v", err)
+	}
+	var payload struct {
+		Count int `json:"count"`
+		Users []struct {
+			User    string `json:"user"`
+			Tokens  int    `json:"tokens"`
+			Limited int    `json:"limited"`
+		} `json:"users"`
+	}
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if payload.Count != 2 || payload.Users[0].User != "telegram:42" || payload.Users[0].Tokens != 1200 || payload.Users[0].Limited != 2 {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func seedLedger(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	ledger := usage.NewLedger(usage.Path(filepath.Join(home, ".myclaw", "workspace")), nil)
+	now := time.Now()
+	for _, e := range []usage.Entry{
+		{Time: now.AddDate(0, 0, -10), Channel: "telegram", Model: "claude-sonnet-4-5-20250929", InputTokens: 1_000_000},
+		{Time: now.Add(-time.Hour), Session: "telegram:42", Channel: "telegram", Model: "claude-sonnet-4-5-20250929", InputTokens: 100_000, OutputTokens: 10_000, CacheReadTokens: 200_000},
+		{Time: now, Session: "cli", Channel: "cli", Model: "local-llama", InputTokens: 500, OutputTokens: 50},
+	} {
+		if _, _, err := ledger.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func buildUsageCommand(since, by string, jsonOutput bool) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("since", since, "")
+	cmd.Flags().String("by", by, "")
+	cmd.Flags().Bool("json", jsonOutput, "")
+	return cmd
+}
+
+func TestRunUsage(t *testing.T) {
+	seedLedger(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runUsage(buildUsageCommand("7d", "channel", false), nil)
+	})
+	if err != nil {
+		t.Fatalf("runUsage error: %v", err)
+	}
+	// 100k input at $3/M, 10k output at $15/M and 200k cache reads at $0.3/M.
+	if !strings.Contains(output, "$0.51") || !strings.Contains(output, "$0.00+") || !strings.Contains(output, "1 runs used models without a known price") {
+		t.Errorf("unexpected output: %s", output)
+	}
+	if !strings.Contains(output, "200000 tokens read from the cache, 0 written; saved about $0.54") {
+		t.Errorf("unexpected output: %s", output)
+	}
+	if strings.Index(output, "telegram") > strings.Index(output, "cli") {
+		t.Errorf("costliest channel should come first: %s", output)
+	}
+
+	for _, c := range []*cobra.Command{buildUsageCommand("last week", "day", false), buildUsageCommand("7d", "user", false)} {
+		if err := runUsage(c, nil); err == nil {
+			t.Errorf("expected error for %v", c.Flags())
+		}
+	}
+}
+
+func TestRunUsage_JSON(t *testing.T) {
+	seedLedger(t)
+
+	output, err := captureRunOutput(t, func() error {
+		return runUsage(buildUsageCommand("30d", "model", true), nil)
+	})
+	if err != nil {
+		t.Fatalf("runUsage error: %v", err)
+	}
+	var payload struct {
+		Groups []usage.Totals `json:"groups"`
+		Total  usage.Totals   `json:"total"`
+	}
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if len(payload.Groups) != 2 || payload.Groups[0].Runs != 2 || payload.Total.Runs != 3 || payload.Total.UnpricedRuns != 1 {
+		t.Errorf("payload = %+v", payload)
+	}
+	if got := payload.Total.CostUSD; got < 3.509 || got > 3.511 {
+		t.Errorf("total cost = %v, want 3.51", got)
+	}
+	if got := payload.Total.SavedUSD; got < 0.539 || got > 0.541 {
+		t.Errorf("total saved = %v, want 0.54", got)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	tests := map[string]time.Time{
+		"7d":         now.AddDate(0, 0, -7),
+		"24h":        now.Add(-24 * time.Hour),
+		"2026-10-01": time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local),
+	}
+	for in, want := range tests {
+		if got, err := parseSince(in, now); err != nil || !got.Equal(want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0d", "-1h", "week"} {
+		if _, err := parseSince(in, now); err == nil {
+			t.Errorf("parseSince(%q): expected error", in)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/media"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+	"github.com/stellarlinkco/myclaw/internal/voice"
+)
+
+var voiceFlag bool
+
+// recorder records one utterance; see voice.Recorder.
+type recorder interface {
+	Record(ctx context.Context, stop <-chan struct{}) ([]byte, error)
+}
+
+// voiceREPL is myclaw agent --voice: Enter records a question, which is
+// transcribed and sent, and each reply is printed and, with a speaker,
+// read aloud. Typed lines are sent as they are.
+type voiceREPL struct {
+	rec recorder
+	stt media.Transcriber
+	tts voice.Speaker // nil when replies are only printed
+
+	session    *replSession
+	interrupts *interrupter
+	stdout     io.Writer // answers
+	info       io.Writer // everything else
+	stderr     io.Writer
+	render     func(string) string
+}
+
+// newVoiceREPL checks that cfg can listen, and speak if it asks to, before
+// anything else starts.
+func newVoiceREPL(cfg *config.Config) (*voiceREPL, error) {
+	stt, err := media.NewTranscriber(cfg.Media.STT)
+	if err != nil {
+		return nil, err
+	}
+	if stt == nil {
+		return nil, errors.New("--voice needs speech to text; set media.stt.provider to openai or whispercpp")
+	}
+	rec, err := voice.NewRecorder(cfg.Media.Record)
+	if err != nil {
+		return nil, err
+	}
+	tts, err := voice.NewSpeaker(cfg.Media.TTS)
+	if err != nil {
+		return nil, err
+	}
+	return &voiceREPL{rec: rec, stt: stt, tts: tts}, nil
+}
+
+func (v *voiceREPL) run(ctx context.Context, stdin io.Reader) error {
+	lines := readLines(stdin)
+	ctx = permission.WithAsker(ctx, v.asker(lines))
+	fmt.Fprintln(v.info, "myclaw agent, voice mode: press Enter and speak, then Enter again or pause to send.")
+	fmt.Fprintln(v.info, "Type a message to send it as text, or 'exit' to quit.")
+	for {
+		fmt.Fprint(v.info, "\n[Enter to talk] ")
+		line, ok := <-lines
+		if !ok {
+			return nil
+		}
+		input := strings.TrimSpace(line)
+		if input == "exit" || input == "quit" {
+			return nil
+		}
+		if input == "" {
+			var closed bool
+			input, closed = v.listen(ctx, lines)
+			if input == "" {
+				if closed {
+					return nil
+				}
+				continue
+			}
+		}
+
+		runCtx, end := v.interrupts.start(ctx)
+		if v.session.handleSlash(runCtx, input) {
+			end()
+			continue
+		}
+		resp, err := v.session.prompt(runCtx, input)
+		if err == nil && resp != nil && resp.Result != nil {
+			fmt.Fprintln(v.stdout, v.render(resp.Result.Output))
+			if v.tts != nil {
+				if text := voice.Speakable(resp.Result.Output); text != "" {
+					err = v.tts.Speak(runCtx, text)
+				}
+			}
+		}
+		if end() {
+			fmt.Fprintln(v.stderr, "Interrupted.")
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(v.stderr, "Error: %v\n", err)
+		}
+	}
+}
+
+// listen records until Enter or a pause and returns the transcript, or ""
+// when nothing was understood. closed reports that input ended meanwhile.
+func (v *voiceREPL) listen(ctx context.Context, lines <-chan string) (text string, closed bool) {
+	fmt.Fprintln(v.info, "Listening...")
+	stop := make(chan struct{})
+	type result struct {
+		audio []byte
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		audio, err := v.rec.Record(ctx, stop)
+		done <- result{audio, err}
+	}()
+	var res result
+	select {
+	case res = <-done:
+	case _, ok := <-lines:
+		closed = !ok
+		close(stop)
+		res = <-done
+	}
+	if res.err != nil {
+		fmt.Fprintf(v.stderr, "Error: %v\n", res.err)
+		return "", closed
+	}
+
+	runCtx, end := v.interrupts.start(ctx)
+	text, err := v.stt.Transcribe(runCtx, bus.Attachment{Name: "speech.wav", MediaType: "audio/wav", Data: res.audio})
+	if end() {
+		fmt.Fprintln(v.stderr, "Interrupted.")
+		return "", closed
+	}
+	if err != nil {
+		fmt.Fprintf(v.stderr, "Error: %v\n", err)
+		return "", closed
+	}
+	if text == "" {
+		fmt.Fprintln(v.info, "Didn't catch that.")
+		return "", closed
+	}
+	fmt.Fprintf(v.info, "You: %s\n", text)
+	return text, closed
+}
+
+// asker asks about tool calls that need approval on the next typed line.
+func (v *voiceREPL) asker(lines <-chan string) permission.AskFunc {
+	return func(ctx context.Context, req permission.Request) (bool, error) {
+		fmt.Fprintf(v.info, "\nAllow %s? [y/N] ", req)
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return false, errors.New("input closed")
+			}
+			answer := strings.ToLower(strings.TrimSpace(line))
+			return answer == "y" || answer == "yes", nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// readLines sends the lines of r, closing the channel at the end. Reading
+// goes on in the background so a line can stop a recording.
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
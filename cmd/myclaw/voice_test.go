@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// stopRecorder records until it is stopped.
+type stopRecorder struct{}
+
+func (stopRecorder) Record(ctx context.Context, stop <-chan struct{}) ([]byte, error) {
+	<-stop
+	return []byte("RIFF"), nil
+}
+
+type fakeSTT struct{ text string }
+
+func (f fakeSTT) Transcribe(_ context.Context, audio bus.Attachment) (string, error) {
+	if string(audio.Data) != "RIFF" {
+		return "", nil
+	}
+	return f.text, nil
+}
+
+type fakeSpeaker struct{ said []string }
+
+func (f *fakeSpeaker) Speak(_ context.Context, text string) error {
+	f.said = append(f.said, text)
+	return nil
+}
+
+func TestVoiceREPL(t *testing.T) {
+	rt := &recordingRuntime{mockRuntime: mockRuntime{response: &api.Response{Result: &api.Result{Output: "**Sunny**, 21°C."}}}}
+	s, _, stderr := newTestREPLSession(t, rt)
+	var stdout, info bytes.Buffer
+	speaker := &fakeSpeaker{}
+	v := &voiceREPL{
+		rec: stopRecorder{}, stt: fakeSTT{text: "What's the weather?"}, tts: speaker,
+		session: s, interrupts: &interrupter{stderr: stderr}, render: func(s string) string { return s },
+		stdout: &stdout, info: &info, stderr: stderr,
+	}
+
+	// Enter starts recording and Enter stops it; then a typed question.
+	if err := v.run(context.Background(), strings.NewReader("\n\nand tomorrow?\nexit\n")); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.requests) != 2 || rt.requests[0].Prompt != "What's the weather?" || rt.requests[1].Prompt != "and tomorrow?" {
+		t.Errorf("requests = %+v", rt.requests)
+	}
+	if !strings.Contains(info.String(), "You: What's the weather?") {
+		t.Errorf("info = %q", info.String())
+	}
+	if strings.Count(stdout.String(), "**Sunny**, 21°C.") != 2 {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	if len(speaker.said) != 2 || speaker.said[0] != "Sunny, 21°C." {
+		t.Errorf("said = %q", speaker.said)
+	}
+
+	// Nothing understood: nothing is sent.
+	v.stt = fakeSTT{}
+	rt.requests = nil
+	if err := v.run(context.Background(), strings.NewReader("\n\n")); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.requests) != 0 || !strings.Contains(info.String(), "Didn't catch that.") {
+		t.Errorf("requests = %+v, info = %q", rt.requests, info.String())
+	}
+}
+
+func TestNewVoiceREPL(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if _, err := newVoiceREPL(cfg); err == nil || !strings.Contains(err.Error(), "media.stt") {
+		t.Errorf("without stt error = %v", err)
+	}
+	cfg.Media.STT = config.STTConfig{Provider: "openai", APIKey: "k"}
+	cfg.Media.Record = []string{"rec"}
+	v, err := newVoiceREPL(cfg)
+	if err != nil || v.tts != nil {
+		t.Errorf("newVoiceREPL = %+v, %v", v, err)
+	}
+}
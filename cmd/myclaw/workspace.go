@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/session"
+	"github.com/stellarlinkco/myclaw/internal/skills"
+)
+
+const workspaceJSONSchemaVersion = 1
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage separate workspaces for memory, skills and sessions",
+	Long: `Keep several isolated workspaces, each with its own AGENTS.md, SOUL.md,
+memory, skills and sessions, for example one per project.
+
+The default workspace is agent.workspace. Others live under
+~/.myclaw/workspaces/<name> (per profile). Commands use the workspace
+given with --workspace, then MYCLAW_WORKSPACE, then the one chosen with
+'myclaw workspace switch'. An explicit skills.dir is shared by all
+workspaces.`,
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List workspaces",
+	Args:  cobra.NoArgs,
+	RunE:  runWorkspaceList,
+}
+
+var workspaceCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a workspace",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runWorkspaceCreate,
+}
+
+var workspaceSwitchCmd = &cobra.Command{
+	Use:   "switch <name>",
+	Short: "Make a workspace the one later commands use",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runWorkspaceSwitch,
+}
+
+var workspaceInfoCmd = &cobra.Command{
+	Use:   "info [name]",
+	Short: "Show where a workspace is and what it holds",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runWorkspaceInfo,
+}
+
+func init() {
+	workspaceListCmd.Flags().Bool("json", false, "Output as JSON")
+	workspaceCreateCmd.Flags().String("template", "", "Seed the workspace from a template (name, directory, or git URL)")
+	workspaceCreateCmd.Flags().Bool("switch", false, "Switch to the new workspace")
+	workspaceInfoCmd.Flags().Bool("json", false, "Output as JSON")
+	workspaceCmd.AddCommand(workspaceListCmd, workspaceCreateCmd, workspaceSwitchCmd, workspaceInfoCmd)
+	rootCmd.AddCommand(workspaceCmd)
+}
+
+// workspaceInfo describes one workspace for list and info.
+type workspaceInfo struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Active   bool   `json:"active"`
+	Source   string `json:"source,omitempty"`
+	Exists   bool   `json:"exists"`
+	Sessions int    `json:"sessions"`
+	Skills   int    `json:"skills"`
+	Journal  int    `json:"journalDays"`
+	Memory   int    `json:"memoryBytes"`
+}
+
+func runWorkspaceList(cmd *cobra.Command, args []string) error {
+	names, err := config.ListWorkspaces()
+	if err != nil {
+		return err
+	}
+	names = append([]string{config.DefaultWorkspace}, names...)
+	active, _ := config.Workspace()
+
+	infos := make([]workspaceInfo, 0, len(names))
+	for _, name := range names {
+		cfg, err := config.LoadWorkspaceConfig(name)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		info, err := inspectWorkspace(cfg)
+		if err != nil {
+			return err
+		}
+		info.Name = name
+		info.Active = name == active
+		infos = append(infos, info)
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": workspaceJSONSchemaVersion,
+			"command":       "workspace.list",
+			"ok":            true,
+			"active":        active,
+			"workspaces":    infos,
+		})
+	}
+	for _, info := range infos {
+		mark := " "
+		if info.Active {
+			mark = "*"
+		}
+		fmt.Printf("%s %s  %s (%d sessions)\n", mark, info.Name, info.Path, info.Sessions)
+	}
+	if !containsWorkspace(names, active) {
+		fmt.Printf("* %s\n", config.WorkspacePath(active))
+	}
+	return nil
+}
+
+func runWorkspaceCreate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if name == config.DefaultWorkspace {
+		return fmt.Errorf("the %s workspace is agent.workspace; run 'myclaw onboard' to set it up", name)
+	}
+	if err := config.ValidateWorkspaceName(name); err != nil {
+		return err
+	}
+	ws := config.WorkspacePath(name)
+	if dirExists(ws) {
+		return fmt.Errorf("workspace %q already exists: %s", name, ws)
+	}
+	if err := os.MkdirAll(filepath.Join(ws, "memory"), 0755); err != nil {
+		return fmt.Errorf("create workspace: %w", err)
+	}
+	cfg, err := config.LoadWorkspaceConfig(name)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if err := os.MkdirAll(resolveSkillsDir(cfg), 0755); err != nil {
+		return fmt.Errorf("create skills dir: %w", err)
+	}
+	if ref, _ := cmd.Flags().GetString("template"); ref != "" {
+		if err := applyTemplate(cfg, ref, false); err != nil {
+			return err
+		}
+	}
+	writeIfNotExists(filepath.Join(ws, "AGENTS.md"), defaultAgentsMD)
+	writeIfNotExists(filepath.Join(ws, "SOUL.md"), defaultSoulMD)
+	writeIfNotExists(filepath.Join(ws, "memory", "MEMORY.md"), "")
+	writeIfNotExists(filepath.Join(ws, "HEARTBEAT.md"), "")
+	fmt.Printf("Workspace %s ready: %s\n", name, ws)
+
+	if sw, _ := cmd.Flags().GetBool("switch"); sw {
+		return switchWorkspace(name)
+	}
+	fmt.Printf("Use it with 'myclaw workspace switch %s' or --workspace %s\n", name, name)
+	return nil
+}
+
+func runWorkspaceSwitch(cmd *cobra.Command, args []string) error {
+	return switchWorkspace(args[0])
+}
+
+func switchWorkspace(name string) error {
+	if err := config.SwitchWorkspace(name); err != nil {
+		return err
+	}
+	fmt.Printf("Switched to workspace %s\n", name)
+	if env := os.Getenv("MYCLAW_WORKSPACE"); env != "" && env != name {
+		fmt.Printf("Note: MYCLAW_WORKSPACE=%s still wins while it is set\n", env)
+	}
+	return nil
+}
+
+func runWorkspaceInfo(cmd *cobra.Command, args []string) error {
+	active, source := config.Workspace()
+	name := active
+	if len(args) > 0 {
+		name = args[0]
+	}
+	cfg, err := config.LoadWorkspaceConfig(name)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	info, err := inspectWorkspace(cfg)
+	if err != nil {
+		return err
+	}
+	info.Name = name
+	info.Active = name == active
+	if info.Active {
+		info.Source = source
+	}
+
+	if readJSONFlag(cmd) {
+		return printJSON(map[string]any{
+			"schemaVersion": workspaceJSONSchemaVersion,
+			"command":       "workspace.info",
+			"ok":            true,
+			"workspace":     info,
+		})
+	}
+	fmt.Printf("Workspace: %s\n", info.Name)
+	fmt.Printf("Path: %s\n", info.Path)
+	if info.Active {
+		fmt.Printf("Active: yes (%s)\n", workspaceSourceLabel(info.Source))
+	} else {
+		fmt.Println("Active: no")
+	}
+	if !info.Exists {
+		fmt.Println("Not set up yet; run 'myclaw onboard'")
+		return nil
+	}
+	fmt.Printf("Sessions: %d\n", info.Sessions)
+	fmt.Printf("Skills: %d (%s)\n", info.Skills, resolveSkillsDir(cfg))
+	fmt.Printf("Memory: %d bytes in MEMORY.md, %d journal days\n", info.Memory, info.Journal)
+	return nil
+}
+
+// inspectWorkspace counts what the workspace cfg points at holds.
+func inspectWorkspace(cfg *config.Config) (workspaceInfo, error) {
+	ws := cfg.Agent.Workspace
+	info := workspaceInfo{Path: ws, Exists: dirExists(ws)}
+	if !info.Exists {
+		return info, nil
+	}
+	summaries, err := session.NewStore(ws).List()
+	if err != nil {
+		return info, err
+	}
+	info.Sessions = len(summaries)
+	if regs, err := skills.LoadSkills(resolveSkillsDir(cfg)); err == nil {
+		info.Skills = len(regs)
+	}
+	snap, err := memory.NewMemoryStore(ws).Export()
+	if err != nil {
+		return info, err
+	}
+	info.Memory = len(snap.LongTerm)
+	info.Journal = len(snap.Journal)
+	return info, nil
+}
+
+func workspaceSourceLabel(source string) string {
+	switch source {
+	case "flag":
+		return "--workspace"
+	case "env":
+		return "MYCLAW_WORKSPACE"
+	case "switch":
+		return "myclaw workspace switch"
+	default:
+		return "agent.workspace"
+	}
+}
+
+func containsWorkspace(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
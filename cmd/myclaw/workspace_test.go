@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func buildWorkspaceCreateCommand(sw bool) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("template", "", "")
+	cmd.Flags().Bool("switch", sw, "")
+	return cmd
+}
+
+func TestRunWorkspaceCreateAndSwitch(t *testing.T) {
+	seedSession(t)
+	t.Setenv("MYCLAW_WORKSPACE", "")
+
+	output, err := captureRunOutput(t, func() error {
+		return runWorkspaceCreate(buildWorkspaceCreateCommand(true), []string{"blog"})
+	})
+	if err != nil {
+		t.Fatalf("runWorkspaceCreate error: %v", err)
+	}
+	ws := config.WorkspacePath("blog")
+	for _, name := range []string{"AGENTS.md", "SOUL.md", "HEARTBEAT.md", filepath.Join("memory", "MEMORY.md")} {
+		if _, err := os.Stat(filepath.Join(ws, name)); err != nil {
+			t.Errorf("%s not created: %v", name, err)
+		}
+	}
+	if !strings.Contains(output, "Switched to workspace blog") {
+		t.Errorf("unexpected output: %s", output)
+	}
+	t.Cleanup(func() { config.SwitchWorkspace(config.DefaultWorkspace) })
+
+	if _, err := captureRunOutput(t, func() error {
+		return runWorkspaceCreate(buildWorkspaceCreateCommand(false), []string{"blog"})
+	}); err == nil {
+		t.Error("creating blog twice should fail")
+	}
+
+	// The seeded session lives in the default workspace, not in blog.
+	output, err = captureRunOutput(t, func() error {
+		return runWorkspaceList(buildJSONCommand(), nil)
+	})
+	if err != nil {
+		t.Fatalf("runWorkspaceList error: %v", err)
+	}
+	var payload struct {
+		Active     string          `json:"active"`
+		Workspaces []workspaceInfo `json:"workspaces"`
+	}
+	if err := json.Unmarshal([]byte(output), &payload); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, output)
+	}
+	if payload.Active != "blog" || len(payload.Workspaces) != 2 {
+		t.Fatalf("payload = %+v", payload)
+	}
+	if def, blog := payload.Workspaces[0], payload.Workspaces[1]; def.Sessions != 1 || blog.Sessions != 0 || !blog.Active {
+		t.Errorf("workspaces = %+v", payload.Workspaces)
+	}
+
+	output, err = captureRunOutput(t, func() error {
+		return runWorkspaceInfo(&cobra.Command{}, nil)
+	})
+	if err != nil {
+		t.Fatalf("runWorkspaceInfo error: %v", err)
+	}
+	if !strings.Contains(output, "Workspace: blog") || !strings.Contains(output, "myclaw workspace switch") || !strings.Contains(output, ws) {
+		t.Errorf("unexpected info output: %s", output)
+	}
+
+	if _, err := captureRunOutput(t, func() error {
+		return runWorkspaceSwitch(&cobra.Command{}, []string{"default"})
+	}); err != nil {
+		t.Fatalf("runWorkspaceSwitch error: %v", err)
+	}
+	if name, _ := config.Workspace(); name != config.DefaultWorkspace {
+		t.Errorf("active workspace = %q after switching to default", name)
+	}
+}
+
+func TestRunWorkspaceCreate_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, name := range []string{"default", "../x"} {
+		if _, err := captureRunOutput(t, func() error {
+			return runWorkspaceCreate(buildWorkspaceCreateCommand(false), []string{name})
+		}); err == nil {
+			t.Errorf("runWorkspaceCreate(%q) = nil, want error", name)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Package audit keeps an append-only log of what the assistant did: the
+// messages it got, the tools it ran and with what arguments, the files it
+// changed and the replies it sent.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// Kinds of entries.
+const (
+	Inbound  = "inbound"
+	Tool     = "tool"
+	File     = "file"
+	Outbound = "outbound"
+)
+
+// maxArgLen caps each string argument of a tool call, so writing a large
+// file doesn't copy it into the log.
+const maxArgLen = 2000
+
+// Path returns where the audit log is kept for workspace.
+func Path(workspace string) string {
+	return filepath.Join(workspace, ".claude", "audit.jsonl")
+}
+
+// Source is who a run or message is for.
+type Source struct {
+	Channel string `json:"channel,omitempty"`
+	ChatID  string `json:"chatId,omitempty"`
+	Sender  string `json:"sender,omitempty"`
+	Session string `json:"session,omitempty"`
+}
+
+type sourceKey struct{}
+
+// WithSource makes the tool calls of runs with ctx log src as their
+// source.
+func WithSource(ctx context.Context, src Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, src)
+}
+
+// SourceOf returns the source WithSource set on ctx.
+func SourceOf(ctx context.Context) Source {
+	src, _ := ctx.Value(sourceKey{}).(Source)
+	return src
+}
+
+// Entry is one event in the log.
+type Entry struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	Source
+	Tool    string         `json:"tool,omitempty"`
+	Args    map[string]any `json:"args,omitempty"`
+	Path    string         `json:"path,omitempty"` // the file a File entry changed
+	Content string         `json:"content,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// Log appends entries to a JSON Lines file. A nil *Log records nothing.
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// Open returns the log of cfg's workspace, or nil when audit.enabled is
+// off.
+func Open(cfg *config.Config) *Log {
+	if !cfg.Audit.Enabled {
+		return nil
+	}
+	return NewLog(Path(cfg.Agent.Workspace))
+}
+
+// Append writes e, stamping it with the current time if it has none.
+func (l *Log) Append(e Entry) error {
+	if l == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Read returns the entries at path from since on, oldest first. A missing
+// log has no entries; unreadable lines are skipped.
+func Read(path string, since time.Time) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Matches reports whether query appears, ignoring case, anywhere in e: its
+// text, tool, arguments, path, error, channel, chat or session.
+func (e Entry) Matches(query string) bool {
+	query = strings.ToLower(query)
+	args, _ := json.Marshal(e.Args)
+	for _, field := range []string{e.Content, e.Tool, string(args), e.Path, e.Error, e.Channel, e.ChatID, e.Sender, e.Session} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileTools are the tools that change the file named by one of their
+// arguments. Commands run through Bash can change files too; those show
+// only as the Bash call.
+var fileTools = map[string][]string{
+	"Write":        {"file_path", "path"},
+	"Edit":         {"file_path", "path"},
+	"MultiEdit":    {"file_path", "path"},
+	"NotebookEdit": {"notebook_path", "file_path"},
+}
+
+// Middleware logs each tool call to l, with a File entry when the call
+// changed a file.
+func Middleware(l *Log) middleware.Middleware {
+	return middleware.Funcs{
+		Identifier: "audit",
+		OnAfterTool: func(ctx context.Context, st *middleware.State) error {
+			call, ok := st.ToolCall.(agent.ToolCall)
+			if !ok {
+				return nil
+			}
+			entry := Entry{Kind: Tool, Source: SourceOf(ctx), Tool: call.Name, Args: clip(call.Input)}
+			if res, ok := st.ToolResult.(agent.ToolResult); ok {
+				if failed, _ := res.Metadata["is_error"].(bool); failed {
+					entry.Error, _ = res.Metadata["error"].(string)
+				}
+			}
+			// A failed write is logged rather than returned, which would
+			// end the run.
+			if err := l.Append(entry); err != nil {
+				log.Printf("[audit] record failed: %v", err)
+			}
+			if entry.Error != "" {
+				return nil
+			}
+			for _, key := range fileTools[call.Name] {
+				if path, _ := call.Input[key].(string); path != "" {
+					if err := l.Append(Entry{Kind: File, Source: entry.Source, Tool: call.Name, Path: path}); err != nil {
+						log.Printf("[audit] record failed: %v", err)
+					}
+					break
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// clip returns args with long strings cut short.
+func clip(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if s, ok := v.(string); ok && len(s) > maxArgLen {
+			n := maxArgLen
+			for n > 0 && !utf8.RuneStart(s[n]) {
+				n--
+			}
+			v = fmt.Sprintf("%s… (%d more bytes)", s[:n], len(s)-n)
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".claude", "audit.jsonl")
+	log := NewLog(path)
+	earlier := time.Date(2026, 10, 15, 9, 0, 0, 0, time.Local)
+	if err := log.Append(Entry{Time: earlier, Kind: Inbound, Source: Source{Channel: "telegram", ChatID: "42"}, Content: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Append(Entry{Kind: Outbound, Source: Source{Channel: "telegram", ChatID: "42"}, Content: "Hi there"}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("not json\n")
+	f.Close()
+
+	all, err := Read(path, time.Time{})
+	if err != nil || len(all) != 2 {
+		t.Fatalf("Read = %d entries, %v", len(all), err)
+	}
+	if all[1].Time.IsZero() || all[1].Channel != "telegram" || all[1].Content != "Hi there" {
+		t.Errorf("entry = %+v", all[1])
+	}
+	if since, _ := Read(path, earlier.Add(time.Hour)); len(since) != 1 {
+		t.Errorf("Read since = %d entries", len(since))
+	}
+	if missing, err := Read(filepath.Join(t.TempDir(), "none.jsonl"), time.Time{}); missing != nil || err != nil {
+		t.Errorf("missing log = %v, %v", missing, err)
+	}
+
+	var nilLog *Log
+	if err := nilLog.Append(Entry{Kind: Inbound}); err != nil {
+		t.Errorf("nil log Append = %v", err)
+	}
+	if Open(&config.Config{}) != nil {
+		t.Error("Open with audit off should be nil")
+	}
+}
+
+func TestEntry_Matches(t *testing.T) {
+	t.Parallel()
+
+	e := Entry{Kind: Tool, Tool: "Bash", Args: map[string]any{"command": "git push origin main"}, Source: Source{Session: "telegram:42"}}
+	for _, query := range []string{"bash", "PUSH ORIGIN", "telegram:42"} {
+		if !e.Matches(query) {
+			t.Errorf("Matches(%q) = false", query)
+		}
+	}
+	if e.Matches("rm -rf") {
+		t.Error("Matches(rm -rf) = true")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	mw := Middleware(NewLog(path))
+	ctx := WithSource(context.Background(), Source{Channel: "cli", Session: "s1"})
+
+	run := func(call agent.ToolCall, res agent.ToolResult) {
+		t.Helper()
+		st := &middleware.State{ToolCall: call, ToolResult: res, Values: map[string]any{}}
+		if err := mw.AfterTool(ctx, st); err != nil {
+			t.Fatalf("AfterTool error: %v", err)
+		}
+	}
+	big := strings.Repeat("x", maxArgLen+500)
+	run(agent.ToolCall{Name: "Write", Input: map[string]any{"file_path": "/tmp/a.txt", "content": big}}, agent.ToolResult{Name: "Write"})
+	run(agent.ToolCall{Name: "Edit", Input: map[string]any{"file_path": "/tmp/b.txt"}}, agent.ToolResult{Name: "Edit", Metadata: map[string]any{"is_error": true, "error": "permission denied"}})
+
+	entries, err := Read(path, time.Time{})
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Read = %d entries, %v", len(entries), err)
+	}
+	write, file, failed := entries[0], entries[1], entries[2]
+	if write.Kind != Tool || write.Session != "s1" || write.Channel != "cli" {
+		t.Errorf("tool entry = %+v", write)
+	}
+	if content, _ := write.Args["content"].(string); !strings.HasSuffix(content, "(500 more bytes)") {
+		t.Errorf("long argument not clipped: %d bytes", len(content))
+	}
+	if file.Kind != File || file.Path != "/tmp/a.txt" || file.Tool != "Write" {
+		t.Errorf("file entry = %+v", file)
+	}
+	// A failed edit changed nothing, so it has no file entry.
+	if failed.Kind != Tool || failed.Error != "permission denied" {
+		t.Errorf("failed entry = %+v", failed)
+	}
+}
@@ -0,0 +1,316 @@
+// Package backup snapshots what makes up an assistant (its config file,
+// workspace, skills and cron jobs) to a gzipped tarball, keeps the newest
+// few on a Target, and restores them.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	namePrefix = "myclaw-"
+	nameSuffix = ".tar.gz"
+	nameLayout = "20060102-150405"
+)
+
+// Name returns the file name of a backup taken at t.
+func Name(t time.Time) string {
+	return namePrefix + t.UTC().Format(nameLayout) + nameSuffix
+}
+
+// parseName returns when the backup called name was taken, or false when
+// name is not a backup's.
+func parseName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, namePrefix) || !strings.HasSuffix(name, nameSuffix) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(nameLayout, strings.TrimSuffix(strings.TrimPrefix(name, namePrefix), nameSuffix))
+	return t, err == nil
+}
+
+// Source is a file or directory that goes into a backup under Name.
+type Source struct {
+	Name string
+	Path string
+}
+
+// Sources returns what a backup of cfg holds: the config file, the
+// workspace, the skills directory when it is outside the workspace, and
+// the cron jobs.
+func Sources(cfg *config.Config) []Source {
+	sources := []Source{
+		{Name: "config", Path: config.ConfigPath()},
+		{Name: "workspace", Path: cfg.Agent.Workspace},
+	}
+	if dir := cfg.Skills.Dir; dir != "" && !within(dir, cfg.Agent.Workspace) {
+		sources = append(sources, Source{Name: "skills", Path: dir})
+	}
+	return append(sources, Source{Name: "cron", Path: filepath.Join(config.DataDir(), "data", "cron")})
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Write writes a backup of sources to w. Sources that do not exist are
+// left out, as are temporary files and anything under skip, such as the
+// directory backups are kept in.
+func Write(w io.Writer, sources []Source, skip string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, src := range sources {
+		if err := addSource(tw, src, skip); err != nil {
+			return fmt.Errorf("back up %s: %w", src.Path, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addSource(tw *tar.Writer, src Source, skip string) error {
+	info, err := os.Stat(src.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return addFile(tw, src.Path, path.Join(src.Name, filepath.Base(src.Path)), info)
+	}
+	return filepath.WalkDir(src.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if skip != "" && within(p, skip) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(p, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(src.Path, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return addFile(tw, p, path.Join(src.Name, filepath.ToSlash(rel)), info)
+	})
+}
+
+func addFile(tw *tar.Writer, file, name string, info fs.FileInfo) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Restore writes the files of the backup read from r back to where sources
+// say they belong, replacing the files there, and returns how many it
+// wrote. Files that are not in the backup are left alone.
+func Restore(r io.Reader, sources []Source) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("read backup: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	var n int
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("read backup: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		target, err := restorePath(hdr.Name, sources)
+		if err != nil {
+			return n, err
+		}
+		if target == "" {
+			continue
+		}
+		if err := restoreFile(tr, target, hdr.FileInfo().Mode().Perm()); err != nil {
+			return n, fmt.Errorf("restore %s: %w", target, err)
+		}
+		n++
+	}
+}
+
+// restorePath returns where the backup entry called name goes, or "" when
+// no source takes it.
+func restorePath(name string, sources []Source) (string, error) {
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("backup entry %q is outside the backup", name)
+	}
+	head, rest, ok := strings.Cut(clean, "/")
+	if !ok {
+		return "", nil
+	}
+	for _, src := range sources {
+		if src.Name != head {
+			continue
+		}
+		if head == "config" {
+			// The config file keeps its name, in this profile's directory.
+			return filepath.Join(filepath.Dir(src.Path), path.Base(rest)), nil
+		}
+		return filepath.Join(src.Path, filepath.FromSlash(rest)), nil
+	}
+	return "", nil
+}
+
+func restoreFile(r io.Reader, target string, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if perm == 0 {
+		perm = 0644
+	}
+	tmp := target + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+// Info describes a backup on a target.
+type Info struct {
+	Name string    `json:"name"`
+	Size int64     `json:"size"`
+	Time time.Time `json:"time"`
+}
+
+// ErrNotFound is returned for a backup a target does not have.
+var ErrNotFound = errors.New("backup not found")
+
+// Target is where backups are kept.
+type Target interface {
+	// Put stores the backup called name, of size bytes, read from r.
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	// Get opens the backup called name.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the backups kept, in any order.
+	List(ctx context.Context) ([]Info, error)
+	Delete(ctx context.Context, name string) error
+	// String says where the backups are, for messages.
+	String() string
+}
+
+// Create backs cfg up to target and drops all but the newest keep backups
+// there, or none with keep 0. It returns the backup taken and the names of
+// those dropped.
+func Create(ctx context.Context, cfg *config.Config, target Target, keep int, now time.Time) (Info, []string, error) {
+	tmp, err := os.CreateTemp("", "myclaw-backup-*"+nameSuffix)
+	if err != nil {
+		return Info{}, nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var skip string
+	if dir, ok := target.(*dirTarget); ok {
+		skip = dir.dir
+	}
+	if err := Write(tmp, Sources(cfg), skip); err != nil {
+		return Info{}, nil, err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return Info{}, nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return Info{}, nil, err
+	}
+	info := Info{Name: Name(now), Size: size, Time: now.UTC().Truncate(time.Second)}
+	if err := target.Put(ctx, info.Name, tmp, size); err != nil {
+		return Info{}, nil, fmt.Errorf("store backup on %s: %w", target, err)
+	}
+	if keep <= 0 {
+		return info, nil, nil
+	}
+	dropped, err := Prune(ctx, target, keep)
+	return info, dropped, err
+}
+
+// List returns the backups on target, newest first.
+func List(ctx context.Context, target Target) ([]Info, error) {
+	list, err := target.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list backups on %s: %w", target, err)
+	}
+	var out []Info
+	for _, info := range list {
+		if t, ok := parseName(info.Name); ok {
+			info.Time = t
+			out = append(out, info)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.After(out[j].Time) })
+	return out, nil
+}
+
+// Prune deletes all but the newest keep backups on target and returns the
+// names of those it deleted.
+func Prune(ctx context.Context, target Target, keep int) ([]string, error) {
+	list, err := List(ctx, target)
+	if err != nil || len(list) <= keep {
+		return nil, err
+	}
+	var dropped []string
+	for _, info := range list[keep:] {
+		if err := target.Delete(ctx, info.Name); err != nil {
+			return dropped, fmt.Errorf("delete backup %s: %w", info.Name, err)
+		}
+		dropped = append(dropped, info.Name)
+	}
+	return dropped, nil
+}
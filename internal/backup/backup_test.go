@@ -0,0 +1,258 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"golang.org/x/net/webdav"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWriteRestore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	cfgFile := filepath.Join(dir, "config.json")
+	backups := filepath.Join(workspace, "backups")
+	writeFile(t, cfgFile, `{"agent":{}}`)
+	writeFile(t, filepath.Join(workspace, "memory", "MEMORY.md"), "Likes tea.")
+	writeFile(t, filepath.Join(workspace, ".claude", "sessions.json.tmp"), "half written")
+	writeFile(t, filepath.Join(backups, "old.tar.gz"), "a backup")
+	sources := []Source{
+		{Name: "config", Path: cfgFile},
+		{Name: "workspace", Path: workspace},
+		{Name: "cron", Path: filepath.Join(dir, "missing")},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, sources, backups); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore onto another machine's paths.
+	other := t.TempDir()
+	restored := []Source{
+		{Name: "config", Path: filepath.Join(other, "config.json")},
+		{Name: "workspace", Path: filepath.Join(other, "ws")},
+	}
+	writeFile(t, filepath.Join(other, "ws", "memory", "MEMORY.md"), "Forgot everything.")
+	n, err := Restore(bytes.NewReader(buf.Bytes()), restored)
+	if err != nil || n != 2 {
+		t.Fatalf("Restore = %d, %v; want 2 files", n, err)
+	}
+	if got := readFile(t, filepath.Join(other, "ws", "memory", "MEMORY.md")); got != "Likes tea." {
+		t.Errorf("MEMORY.md = %q", got)
+	}
+	if got := readFile(t, filepath.Join(other, "config.json")); got != `{"agent":{}}` {
+		t.Errorf("config.json = %q", got)
+	}
+	for _, skipped := range []string{
+		filepath.Join(other, "ws", ".claude", "sessions.json.tmp"),
+		filepath.Join(other, "ws", "backups", "old.tar.gz"),
+	} {
+		if _, err := os.Stat(skipped); !os.IsNotExist(err) {
+			t.Errorf("%s was backed up", skipped)
+		}
+	}
+}
+
+func TestRestorePath(t *testing.T) {
+	t.Parallel()
+
+	sources := []Source{{Name: "workspace", Path: "/data/ws"}}
+	if _, err := restorePath("../etc/passwd", sources); err == nil {
+		t.Error("accepted an entry outside the backup")
+	}
+	if p, err := restorePath("workspace/../../etc/passwd", sources); err == nil {
+		t.Errorf("accepted an entry climbing out: %q", p)
+	}
+	if p, _ := restorePath("other/file", sources); p != "" {
+		t.Errorf("entry of no source restored to %q", p)
+	}
+	if p, _ := restorePath("workspace/memory/MEMORY.md", sources); p != filepath.Join("/data/ws", "memory", "MEMORY.md") {
+		t.Errorf("restorePath = %q", p)
+	}
+}
+
+// testTarget backs up a small workspace to target three times and checks
+// that only the newest two are kept and that one can be read back.
+func testTarget(t *testing.T, target Target) {
+	t.Helper()
+	ctx := context.Background()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cfg := config.DefaultConfig()
+	writeFile(t, filepath.Join(cfg.Agent.Workspace, "memory", "MEMORY.md"), "Likes tea.")
+
+	start := time.Date(2026, 10, 16, 4, 0, 0, 0, time.UTC)
+	var dropped []string
+	for i := range 3 {
+		info, d, err := Create(ctx, cfg, target, 2, start.AddDate(0, 0, i))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if info.Size == 0 {
+			t.Errorf("backup %s is empty", info.Name)
+		}
+		dropped = append(dropped, d...)
+	}
+	if len(dropped) != 1 || dropped[0] != Name(start) {
+		t.Errorf("dropped = %v, want the first", dropped)
+	}
+	list, err := List(ctx, target)
+	if err != nil || len(list) != 2 || list[0].Name != Name(start.AddDate(0, 0, 2)) || list[0].Size == 0 {
+		t.Fatalf("List = %+v, %v", list, err)
+	}
+
+	rc, err := target.Get(ctx, list[1].Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	restored := filepath.Join(t.TempDir(), "ws")
+	if n, err := Restore(rc, []Source{{Name: "workspace", Path: restored}}); err != nil || n != 1 {
+		t.Fatalf("Restore = %d, %v", n, err)
+	}
+	if got := readFile(t, filepath.Join(restored, "memory", "MEMORY.md")); got != "Likes tea." {
+		t.Errorf("MEMORY.md = %q", got)
+	}
+	if _, err := target.Get(ctx, Name(start)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a dropped backup: %v", err)
+	}
+}
+
+func TestDirTarget(t *testing.T) {
+	target, err := NewTarget(config.BackupConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTarget(t, target)
+}
+
+// fakeS3 is a bucket in memory that wants signed requests.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") ||
+		!strings.Contains(auth, "x-amz-content-sha256") || r.Header.Get("X-Amz-Content-Sha256") != "UNSIGNED-PAYLOAD" || r.Header.Get("X-Amz-Date") == "" {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/backups/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		prefix := r.URL.Query().Get("prefix")
+		var keys []string
+		for k := range s.objects {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		io.WriteString(w, "<ListBucketResult>")
+		for _, k := range keys {
+			io.WriteString(w, "<Contents><Key>"+k+"</Key><Size>"+strconv.Itoa(len(s.objects[k]))+"</Size></Contents>")
+		}
+		io.WriteString(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		s.objects[key] = data
+	case r.Method == http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Target(t *testing.T) {
+	s3 := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(s3)
+	defer srv.Close()
+
+	if _, err := NewTarget(config.BackupConfig{S3: config.S3BackupConfig{Bucket: "backups"}}); err == nil {
+		t.Error("S3 target without keys")
+	}
+	target, err := NewTarget(config.BackupConfig{S3: config.S3BackupConfig{
+		Bucket:          "backups",
+		Region:          "eu-west-1",
+		Endpoint:        srv.URL,
+		Prefix:          "/home/",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTarget(t, target)
+	for key := range s3.objects {
+		if !strings.HasPrefix(key, "home/myclaw-") {
+			t.Errorf("object key %q", key)
+		}
+	}
+}
+
+func TestWebDAVTarget(t *testing.T) {
+	dav := &webdav.Handler{FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "me" || pass != "pw" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		dav.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	target, err := NewTarget(config.BackupConfig{WebDAV: config.WebDAVBackupConfig{URL: srv.URL + "/myclaw", Username: "me", Password: "pw"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTarget(t, target)
+}
@@ -0,0 +1,182 @@
+package backup
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/smithy-go/encoding/httpbinding"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// s3Target keeps backups in an S3 bucket, or one on an S3-compatible store,
+// signing requests with AWS Signature Version 4.
+type s3Target struct {
+	base   *url.URL // of the bucket, ending in '/'
+	prefix string   // of the object keys, empty or ending in '/'
+	region string
+	creds  aws.Credentials
+	signer *v4.Signer
+	now    func() time.Time
+}
+
+func newS3Target(cfg config.S3BackupConfig) (*s3Target, error) {
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("backup.s3: accessKeyId and secretAccessKey are not set")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	// AWS buckets are addressed by host; other stores take the bucket as
+	// the first part of the path.
+	raw := "https://" + cfg.Bucket + ".s3." + region + ".amazonaws.com/"
+	if cfg.Endpoint != "" {
+		raw = strings.TrimRight(cfg.Endpoint, "/") + "/" + cfg.Bucket + "/"
+	}
+	base, err := url.Parse(raw)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("backup.s3: bad endpoint %q", cfg.Endpoint)
+	}
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Target{
+		base:   base,
+		prefix: prefix,
+		region: region,
+		creds:  aws.Credentials{AccessKeyID: cfg.AccessKeyID, SecretAccessKey: cfg.SecretAccessKey},
+		// S3 signs the path as sent, without escaping it a second time.
+		signer: v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true }),
+		now:    time.Now,
+	}, nil
+}
+
+func (t *s3Target) String() string {
+	return "s3 " + t.base.String() + t.prefix
+}
+
+// object is the URL of the object name, its path escaped the way S3 signs
+// it.
+func (t *s3Target) object(name string) *url.URL {
+	u := *t.base
+	u.Path += t.prefix + name
+	u.RawPath = httpbinding.EscapePath(u.Path, false)
+	return &u
+}
+
+func (t *s3Target) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, t.object(name).String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	resp, err := t.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *s3Target) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.object(name).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (t *s3Target) Delete(ctx context.Context, name string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.object(name).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listBucketResult is the part of a ListObjectsV2 response myclaw reads.
+type listBucketResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (t *s3Target) List(ctx context.Context) ([]Info, error) {
+	var list []Info
+	var token string
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {t.prefix + namePrefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u := *t.base
+		u.RawQuery = q.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.do(req)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3: decode list: %w", err)
+		}
+		for _, obj := range result.Contents {
+			name := strings.TrimPrefix(obj.Key, t.prefix)
+			if !strings.Contains(name, "/") {
+				list = append(list, Info{Name: name, Size: obj.Size})
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return list, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// do signs and sends req, returning an error for a status of 300 or more.
+// The body is not hashed; S3 accepts UNSIGNED-PAYLOAD over HTTPS.
+func (t *s3Target) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	if err := t.signer.SignHTTP(req.Context(), t.creds, req, unsignedPayload, "s3", t.region, t.now()); err != nil {
+		return nil, fmt.Errorf("s3: sign: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, errorStatus("s3: "+req.Method+" "+req.URL.Path, resp)
+	}
+	return resp, nil
+}
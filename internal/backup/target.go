@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// httpClient is used by the S3 and WebDAV targets. Backups can be large,
+// so it has no overall timeout; requests end with their context.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: time.Minute,
+	},
+}
+
+// NewTarget returns the target cfg names: S3 with a bucket set, WebDAV
+// with a URL set, and otherwise a local directory.
+func NewTarget(cfg config.BackupConfig) (Target, error) {
+	switch {
+	case cfg.S3.Bucket != "":
+		return newS3Target(cfg.S3)
+	case cfg.WebDAV.URL != "":
+		return newWebDAVTarget(cfg.WebDAV)
+	}
+	dir := cfg.Dir
+	if dir == "" {
+		dir = filepath.Join(config.DataDir(), "backups")
+	}
+	if strings.HasPrefix(dir, "~/") {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, dir[2:])
+	}
+	return &dirTarget{dir: dir}, nil
+}
+
+// dirTarget keeps backups in a local directory.
+type dirTarget struct{ dir string }
+
+func (t *dirTarget) String() string { return t.dir }
+
+func (t *dirTarget) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(t.dir, name)
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (t *dirTarget) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(t.dir, filepath.Base(name)))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	return f, err
+}
+
+func (t *dirTarget) List(ctx context.Context) ([]Info, error) {
+	entries, err := os.ReadDir(t.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Info
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		list = append(list, Info{Name: e.Name(), Size: info.Size()})
+	}
+	return list, nil
+}
+
+func (t *dirTarget) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(t.dir, filepath.Base(name)))
+}
+
+// errorStatus turns a response with a status of 300 or more into an error
+// naming what failed, with the start of its body.
+func errorStatus(what string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", what, ErrNotFound)
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+	return fmt.Errorf("%s: %s %s", what, resp.Status, strings.TrimSpace(string(data)))
+}
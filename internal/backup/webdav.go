@@ -0,0 +1,163 @@
+package backup
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// webdavTarget keeps backups in a WebDAV folder.
+type webdavTarget struct {
+	url                string // of the folder, ending in '/'
+	username, password string
+}
+
+func newWebDAVTarget(cfg config.WebDAVBackupConfig) (*webdavTarget, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("backup.webdav: bad url %q", cfg.URL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return &webdavTarget{url: u.String(), username: cfg.Username, password: cfg.Password}, nil
+}
+
+func (t *webdavTarget) String() string { return "webdav " + t.url }
+
+func (t *webdavTarget) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	// The folder is made on first use; servers answer 405 when it exists.
+	resp, err := t.do(ctx, "MKCOL", t.url, nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMethodNotAllowed {
+		defer resp.Body.Close()
+		return errorStatus("webdav: MKCOL "+t.url, resp)
+	}
+	resp.Body.Close()
+	req, err := t.request(ctx, http.MethodPut, t.url+name, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webdav: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errorStatus("webdav: PUT "+name, resp)
+	}
+	return nil
+}
+
+func (t *webdavTarget) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := t.do(ctx, http.MethodGet, t.url+name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, errorStatus("webdav: GET "+name, resp)
+	}
+	return resp.Body, nil
+}
+
+func (t *webdavTarget) Delete(ctx context.Context, name string) error {
+	resp, err := t.do(ctx, http.MethodDelete, t.url+name, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errorStatus("webdav: DELETE "+name, resp)
+	}
+	return nil
+}
+
+// davListing is the part of a PROPFIND response myclaw reads.
+type davListing struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ContentLength string `xml:"getcontentlength"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func (t *webdavTarget) List(ctx context.Context) ([]Info, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:getcontentlength/></D:prop></D:propfind>`
+	resp, err := t.do(ctx, "PROPFIND", t.url, strings.NewReader(body), http.Header{
+		"Depth":        {"1"},
+		"Content-Type": {"application/xml; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode >= 300 {
+		return nil, errorStatus("webdav: PROPFIND "+t.url, resp)
+	}
+	var ms davListing
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("webdav: decode listing: %w", err)
+	}
+	var list []Info
+	for _, r := range ms.Responses {
+		href, err := url.PathUnescape(r.Href)
+		if err != nil || strings.HasSuffix(href, "/") {
+			continue
+		}
+		info := Info{Name: path.Base(href)}
+		for _, ps := range r.Propstat {
+			if n, err := strconv.ParseInt(ps.Prop.ContentLength, 10, 64); err == nil {
+				info.Size = n
+			}
+		}
+		list = append(list, info)
+	}
+	return list, nil
+}
+
+func (t *webdavTarget) request(ctx context.Context, method, target string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if t.username != "" || t.password != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+	return req, nil
+}
+
+// do sends a request; the caller checks the status and closes the body.
+func (t *webdavTarget) do(ctx context.Context, method, target string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := t.request(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("webdav: %w", err)
+	}
+	return resp, nil
+}
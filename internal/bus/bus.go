@@ -12,6 +12,7 @@ type MessageBus struct {
 
 	mu   sync.RWMutex
 	subs map[string][]func(OutboundMessage)
+	taps []func(OutboundMessage)
 }
 
 func NewMessageBus(bufSize int) *MessageBus {
@@ -31,13 +32,25 @@ func (b *MessageBus) SubscribeOutbound(channel string, fn func(OutboundMessage))
 	b.subs[channel] = append(b.subs[channel], fn)
 }
 
+// TapOutbound has fn see every outbound message, whatever its channel,
+// before the channel's subscribers get it.
+func (b *MessageBus) TapOutbound(fn func(OutboundMessage)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.taps = append(b.taps, fn)
+}
+
 func (b *MessageBus) DispatchOutbound(ctx context.Context) {
 	for {
 		select {
 		case msg := <-b.Outbound:
 			b.mu.RLock()
 			cbs := b.subs[msg.Channel]
+			taps := b.taps
 			b.mu.RUnlock()
+			for _, tap := range taps {
+				tap(msg)
+			}
 			for _, cb := range cbs {
 				cb(msg)
 			}
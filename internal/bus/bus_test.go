@@ -111,3 +111,35 @@ func TestDispatch_ContextCancel(t *testing.T) {
 		t.Fatal("DispatchOutbound did not exit after context cancel")
 	}
 }
+
+func TestTapOutbound(t *testing.T) {
+	b := NewMessageBus(10)
+
+	var mu sync.Mutex
+	var tapped []string
+	b.TapOutbound(func(msg OutboundMessage) {
+		mu.Lock()
+		tapped = append(tapped, msg.Channel)
+		mu.Unlock()
+	})
+	delivered := make(chan struct{}, 1)
+	b.SubscribeOutbound("telegram", func(OutboundMessage) { delivered <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.DispatchOutbound(ctx)
+
+	b.Outbound <- OutboundMessage{Channel: "nowhere", Content: "dropped"}
+	b.Outbound <- OutboundMessage{Channel: "telegram", Content: "hi"}
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(tapped) != 2 || tapped[0] != "nowhere" || tapped[1] != "telegram" {
+		t.Errorf("tapped = %v", tapped)
+	}
+}
@@ -15,6 +15,16 @@ type InboundMessage struct {
 	Media         []string
 	Metadata      map[string]any
 	ContentBlocks []model.ContentBlock // 多模态内容（图片、文档等）
+	// Attachments are files the gateway reads before the agent runs: voice
+	// notes are transcribed and documents turned into text or blocks.
+	Attachments []Attachment
+}
+
+// Attachment is a file received with a message.
+type Attachment struct {
+	Name      string // file name, if the sender gave one
+	MediaType string
+	Data      []byte
 }
 
 func (m *InboundMessage) SessionKey() string {
@@ -22,11 +32,28 @@ func (m *InboundMessage) SessionKey() string {
 }
 
 type OutboundMessage struct {
-	Channel       string
-	ChatID        string
-	Content       string
-	ReplyTo       string
+	Channel string
+	ChatID  string
+	Content string
+	ReplyTo string
+	// Media are paths of files sent after the text: images as photos on
+	// Telegram and WhatsApp, anything as attachments by email.
 	Media         []string
 	Metadata      map[string]any
 	ContentBlocks []model.ContentBlock // 多模态内容
+	// Buttons are answers the user can pick with one tap, on channels that
+	// show them. Others leave them out, so Content must say how to answer.
+	Buttons []Button
+	// Voice is Content spoken, as OGG/Opus, for channels that send voice
+	// notes. They send it after the text, or instead of it with VoiceOnly.
+	// Other channels send the text alone.
+	Voice     []byte
+	VoiceOnly bool
+}
+
+// Button is a reply choice shown under a message. Pressing it comes back as
+// an inbound message from the presser with Data as its content.
+type Button struct {
+	Text string
+	Data string
 }
@@ -0,0 +1,221 @@
+package calendar
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const maxResponseBytes = 4 << 20
+
+// calDAV is a calendar collection on a CalDAV server (RFC 4791). Event IDs
+// are the hrefs of the calendar objects.
+type calDAV struct {
+	url                string // of the collection, ending in '/'
+	username, password string
+	loc                *time.Location
+	now                func() time.Time
+}
+
+func (c *calDAV) Name() string { return "caldav" }
+
+// multistatus is the part of a WebDAV REPORT response myclaw reads.
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				CalendarData string `xml:"calendar-data"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func (c *calDAV) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	start, end := from.UTC().Format(utcLayout), to.UTC().Format(utcLayout)
+	// expand has the server turn recurring events into their instances.
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <C:calendar-data><C:expand start="` + start + `" end="` + end + `"/></C:calendar-data>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range start="` + start + `" end="` + end + `"/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+	resp, err := c.do(ctx, "REPORT", c.url, strings.NewReader(body), http.Header{
+		"Depth":        {"1"},
+		"Content-Type": {"application/xml; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ms multistatus
+	if err := xml.Unmarshal(resp.body, &ms); err != nil {
+		return nil, fmt.Errorf("caldav: decode response: %w", err)
+	}
+	var events []Event
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			if ps.Prop.CalendarData == "" || (ps.Status != "" && !strings.Contains(ps.Status, " 200 ")) {
+				continue
+			}
+			cal, err := parseICS(ps.Prop.CalendarData)
+			if err != nil {
+				return nil, fmt.Errorf("caldav: %s: %w", r.Href, err)
+			}
+			for _, vevent := range cal.events() {
+				ev, err := toEvent(vevent, r.Href, c.loc)
+				if err != nil {
+					return nil, fmt.Errorf("caldav: %s: %w", r.Href, err)
+				}
+				// A server that ignores expand returns the first instance of
+				// a series, which may be outside the range.
+				if overlaps(ev, from, to) {
+					events = append(events, ev)
+				}
+			}
+		}
+	}
+	sortEvents(events)
+	return events, nil
+}
+
+func (c *calDAV) Get(ctx context.Context, id string) (Event, error) {
+	cal, _, err := c.get(ctx, id)
+	if err != nil {
+		return Event{}, err
+	}
+	return toEvent(cal.master(), id, c.loc)
+}
+
+// get fetches the calendar object at href, with its ETag.
+func (c *calDAV) get(ctx context.Context, href string) (*component, string, error) {
+	target, err := c.resolve(href)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := c.do(ctx, http.MethodGet, target, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	cal, err := parseICS(string(resp.body))
+	if err != nil {
+		return nil, "", fmt.Errorf("caldav: %s: %w", href, err)
+	}
+	if cal.master() == nil {
+		return nil, "", fmt.Errorf("caldav: %s: %w", href, ErrNotFound)
+	}
+	return cal, resp.header.Get("ETag"), nil
+}
+
+func (c *calDAV) Create(ctx context.Context, ev Event) (Event, error) {
+	cal, uid := newCalendar(ev, c.clock())
+	name, _, _ := strings.Cut(uid, "@")
+	target := c.url + name + ".ics"
+	if _, err := c.do(ctx, http.MethodPut, target, strings.NewReader(cal.encode()), http.Header{
+		"Content-Type":  {"text/calendar; charset=utf-8"},
+		"If-None-Match": {"*"},
+	}); err != nil {
+		return Event{}, err
+	}
+	u, _ := url.Parse(target)
+	ev.ID = u.Path
+	return ev, nil
+}
+
+func (c *calDAV) Update(ctx context.Context, ev Event) (Event, error) {
+	cal, etag, err := c.get(ctx, ev.ID)
+	if err != nil {
+		return Event{}, err
+	}
+	master := cal.master()
+	if has(master, "RRULE") {
+		return Event{}, errors.New("caldav: changing a recurring event is not supported; change it in your calendar app")
+	}
+	setEvent(master, ev, c.clock())
+	target, _ := c.resolve(ev.ID)
+	header := http.Header{"Content-Type": {"text/calendar; charset=utf-8"}}
+	if etag != "" {
+		header.Set("If-Match", etag)
+	}
+	if _, err := c.do(ctx, http.MethodPut, target, strings.NewReader(cal.encode()), header); err != nil {
+		return Event{}, err
+	}
+	return ev, nil
+}
+
+func (c *calDAV) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// resolve turns an href into a URL on the server of the collection.
+func (c *calDAV) resolve(href string) (string, error) {
+	base, err := url.Parse(c.url)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(href)
+	if err != nil || href == "" {
+		return "", fmt.Errorf("caldav: %q: %w", href, ErrNotFound)
+	}
+	u := base.ResolveReference(ref)
+	if u.Host != base.Host {
+		return "", fmt.Errorf("caldav: %s is not on the calendar's server", href)
+	}
+	return u.String(), nil
+}
+
+type davResponse struct {
+	header http.Header
+	body   []byte
+}
+
+func (c *calDAV) do(ctx context.Context, method, target string, body io.Reader, header http.Header) (*davResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("caldav: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("caldav: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("caldav: %s: %w", target, ErrNotFound)
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return nil, errors.New("caldav: the event was changed elsewhere; read it again")
+	case resp.StatusCode >= 300:
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 200 {
+			msg = msg[:200]
+		}
+		return nil, fmt.Errorf("caldav: %s %s: %s %s", method, target, resp.Status, msg)
+	}
+	return &davResponse{header: resp.Header, body: data}, nil
+}
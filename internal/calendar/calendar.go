@@ -0,0 +1,139 @@
+// Package calendar reads and writes events in the user's calendar, on a
+// CalDAV server or in Google Calendar, and gives the agent tools for it.
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// httpClient sends CalDAV requests; Google requests go through an OAuth
+// client built on it.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// ErrNotFound is returned for an event ID the calendar does not have.
+var ErrNotFound = errors.New("event not found")
+
+// Event is one calendar event. All-day events start at midnight of their
+// first day and end at midnight after their last.
+type Event struct {
+	ID          string    `json:"id"`
+	Summary     string    `json:"summary"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	AllDay      bool      `json:"allDay,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Recurring   bool      `json:"recurring,omitempty"`
+}
+
+// Calendar is a calendar the agent can read and write.
+type Calendar interface {
+	Name() string
+	// Events returns the events overlapping [from, to), recurring events
+	// expanded, in order of start.
+	Events(ctx context.Context, from, to time.Time) ([]Event, error)
+	Get(ctx context.Context, id string) (Event, error)
+	Create(ctx context.Context, ev Event) (Event, error)
+	// Update saves the summary, times, location and description of ev.
+	Update(ctx context.Context, ev Event) (Event, error)
+}
+
+// New returns the calendar cfg selects, or nil when no provider is set.
+func New(cfg config.CalendarConfig) (Calendar, error) {
+	loc, err := Location(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "caldav":
+		if cfg.CalDAV.URL == "" {
+			return nil, errors.New("calendar: caldav needs url")
+		}
+		return &calDAV{
+			url:      strings.TrimRight(cfg.CalDAV.URL, "/") + "/",
+			username: cfg.CalDAV.Username,
+			password: cfg.CalDAV.Password,
+			loc:      loc,
+		}, nil
+	case "google":
+		return newGoogle(cfg.Google, GoogleTokenPath(), loc)
+	default:
+		return nil, fmt.Errorf("calendar: unknown provider %q", cfg.Provider)
+	}
+}
+
+// Location returns the time zone of cfg.
+func Location(cfg config.CalendarConfig) (*time.Location, error) {
+	if cfg.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("calendar.timezone: %w", err)
+	}
+	return loc, nil
+}
+
+// Day returns the start of the day t falls on in loc.
+func Day(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// overlaps reports whether ev takes place in [from, to). An event without
+// a duration counts when it starts in the range.
+func overlaps(ev Event, from, to time.Time) bool {
+	return ev.Start.Before(to) && (ev.End.After(from) || !ev.Start.Before(from))
+}
+
+// sortEvents orders events by start, then by end.
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Start.Equal(events[j].Start) {
+			return events[i].Start.Before(events[j].Start)
+		}
+		return events[i].End.Before(events[j].End)
+	})
+}
+
+// Format lists events by day, in loc, for the agent or a terminal.
+func Format(events []Event, loc *time.Location) string {
+	if len(events) == 0 {
+		return "No events."
+	}
+	var b strings.Builder
+	var day time.Time
+	for _, ev := range events {
+		d := Day(ev.Start, loc)
+		if !d.Equal(day) {
+			if !day.IsZero() {
+				b.WriteString("\n")
+			}
+			day = d
+			fmt.Fprintf(&b, "%s\n", d.Format("Monday, 2 January 2006"))
+		}
+		when := "all day"
+		if !ev.AllDay {
+			when = ev.Start.In(loc).Format("15:04") + "–" + ev.End.In(loc).Format("15:04")
+			if !Day(ev.End.Add(-time.Nanosecond), loc).Equal(d) {
+				when = ev.Start.In(loc).Format("15:04") + "–" + ev.End.In(loc).Format("Mon 15:04")
+			}
+		}
+		fmt.Fprintf(&b, "- %s %s", when, ev.Summary)
+		if ev.Location != "" {
+			fmt.Fprintf(&b, " @ %s", ev.Location)
+		}
+		fmt.Fprintf(&b, " [id: %s]\n", ev.ID)
+	}
+	return b.String()
+}
@@ -0,0 +1,407 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/googleauth"
+	"golang.org/x/oauth2"
+)
+
+var berlin = mustLoad("Europe/Berlin")
+
+func mustLoad(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+const standup = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\n" +
+	"BEGIN:VEVENT\r\nUID:standup@test\r\nDTSTART;TZID=Europe/Berlin:20261016T093000\r\nDURATION:PT15M\r\n" +
+	"SUMMARY:Standup\\, team\r\nLOCATION:Room 1\r\nX-CUSTOM:keep me\r\n" +
+	"DESCRIPTION:Agenda:\\n- demos\r\nBEGIN:VALARM\r\nTRIGGER:-PT5M\r\nACTION:DISPLAY\r\nEND:VALARM\r\nEND:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	cal, err := parseICS(strings.ReplaceAll(standup, "SUMMARY:Standup\\, team\r\n", "SUMMARY:Standup\\,\r\n  team\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := toEvent(cal.master(), "/cal/standup.ics", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Event{
+		ID:          "/cal/standup.ics",
+		Summary:     "Standup, team",
+		Start:       time.Date(2026, 10, 16, 9, 30, 0, 0, berlin),
+		End:         time.Date(2026, 10, 16, 9, 45, 0, 0, berlin),
+		Location:    "Room 1",
+		Description: "Agenda:\n- demos",
+	}
+	if !ev.Start.Equal(want.Start) || !ev.End.Equal(want.End) || ev.Summary != want.Summary || ev.Description != want.Description || ev.Location != want.Location {
+		t.Errorf("event = %+v, want %+v", ev, want)
+	}
+
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	ev.Summary = "Standup; moved"
+	ev.Start = ev.Start.Add(time.Hour)
+	ev.End = ev.Start.Add(30 * time.Minute)
+	ev.Location = ""
+	setEvent(cal.master(), ev, now)
+	out := cal.encode()
+	for _, s := range []string{"SUMMARY:Standup\\; moved\r\n", "DTSTART:20261016T083000Z\r\n", "DTEND:20261016T090000Z\r\n",
+		"X-CUSTOM:keep me\r\n", "BEGIN:VALARM\r\n", "SEQUENCE:0\r\n"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("encoded event lacks %q:\n%s", s, out)
+		}
+	}
+	for _, s := range []string{"DURATION", "LOCATION"} {
+		if strings.Contains(out, s) {
+			t.Errorf("encoded event still has %s:\n%s", s, out)
+		}
+	}
+
+	long := Event{Summary: strings.Repeat("ü", 60), Start: now, End: now.Add(time.Hour)}
+	cal, _ = newCalendar(long, now)
+	for _, line := range strings.Split(cal.encode(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+	again, err := parseICS(cal.encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := toEvent(again.master(), "", time.UTC); ev.Summary != long.Summary {
+		t.Errorf("folded summary = %q", ev.Summary)
+	}
+
+	allDay, _ := parseICS("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;VALUE=DATE:20261017\nSUMMARY:Trip\nRRULE:FREQ=YEARLY\nEND:VEVENT\nEND:VCALENDAR\n")
+	ev, _ = toEvent(allDay.master(), "", berlin)
+	if !ev.AllDay || !ev.Recurring || !ev.Start.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, berlin)) || !ev.End.Equal(ev.Start.AddDate(0, 0, 1)) {
+		t.Errorf("all-day event = %+v", ev)
+	}
+	if _, err := parseICS("BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VCALENDAR\n"); err == nil {
+		t.Error("mismatched END parsed")
+	}
+}
+
+// fakeCalDAV serves one calendar collection at /cal/.
+type fakeCalDAV struct {
+	mu      sync.Mutex
+	objects map[string]string // path -> ics
+	report  string            // last REPORT body
+}
+
+func (f *fakeCalDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if user, pass, _ := r.BasicAuth(); user != "me" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	switch r.Method {
+	case "REPORT":
+		f.report = string(body)
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">`)
+		for path, ics := range f.objects {
+			fmt.Fprintf(&b, `<d:response><d:href>%s</d:href><d:propstat><d:prop><cal:calendar-data>%s</cal:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+				path, strings.NewReplacer("&", "&amp;", "<", "&lt;").Replace(ics))
+		}
+		b.WriteString(`</d:multistatus>`)
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, b.String())
+	case http.MethodGet:
+		ics, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, len(ics)))
+		fmt.Fprint(w, ics)
+	case http.MethodPut:
+		ics, exists := f.objects[r.URL.Path]
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if m := r.Header.Get("If-Match"); m != "" && m != fmt.Sprintf(`"%d"`, len(ics)) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		f.objects[r.URL.Path] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestCalDAV(t *testing.T) {
+	fake := &fakeCalDAV{objects: map[string]string{"/cal/standup.ics": standup}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	ctx := context.Background()
+
+	cal, err := New(config.CalendarConfig{
+		Provider: "caldav",
+		Timezone: "Europe/Berlin",
+		CalDAV:   config.CalDAVConfig{URL: srv.URL + "/cal", Username: "me", Password: "secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, berlin)
+	events, err := cal.Events(ctx, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Summary != "Standup, team" || events[0].ID != "/cal/standup.ics" {
+		t.Fatalf("events = %+v", events)
+	}
+	if !strings.Contains(fake.report, `<C:time-range start="20261015T220000Z" end="20261016T220000Z"/>`) {
+		t.Errorf("report = %s", fake.report)
+	}
+	if events, _ := cal.Events(ctx, day.AddDate(0, 0, 1), day.AddDate(0, 0, 2)); len(events) != 0 {
+		t.Errorf("events outside the range = %+v", events)
+	}
+
+	created, err := cal.Create(ctx, Event{Summary: "Dentist", Start: day.Add(15 * time.Hour), End: day.Add(16 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.ID, "/cal/") || !strings.HasSuffix(created.ID, ".ics") {
+		t.Errorf("created id = %q", created.ID)
+	}
+	got, err := cal.Get(ctx, created.ID)
+	if err != nil || got.Summary != "Dentist" || !got.Start.Equal(day.Add(15*time.Hour)) {
+		t.Errorf("Get(created) = %+v, %v", got, err)
+	}
+
+	got.Summary = "Dentist (moved)"
+	if _, err := cal.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := cal.Get(ctx, created.ID); got.Summary != "Dentist (moved)" {
+		t.Errorf("after update = %+v", got)
+	}
+	if _, err := cal.Get(ctx, "/cal/missing.ics"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) error = %v", err)
+	}
+	if _, err := cal.Get(ctx, "https://elsewhere.example.com/cal/x.ics"); err == nil {
+		t.Error("Get followed an href to another server")
+	}
+}
+
+func TestGoogle(t *testing.T) {
+	var mu sync.Mutex
+	var patched map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/calendars/primary/events":
+			if r.URL.Query().Get("singleEvents") != "true" {
+				http.Error(w, "want singleEvents", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"items":[
+				{"id":"b","summary":"Lunch","start":{"dateTime":"2026-10-16T12:00:00+02:00"},"end":{"dateTime":"2026-10-16T13:00:00+02:00"}},
+				{"id":"gone","status":"cancelled","start":{"dateTime":"2026-10-16T08:00:00+02:00"},"end":{"dateTime":"2026-10-16T09:00:00+02:00"}},
+				{"id":"a","summary":"Holiday","start":{"date":"2026-10-16"},"end":{"date":"2026-10-17"}}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/calendars/primary/events/b":
+			fmt.Fprint(w, `{"id":"b","summary":"Lunch","start":{"dateTime":"2026-10-16T12:00:00+02:00"},"end":{"dateTime":"2026-10-16T13:00:00+02:00"}}`)
+		case r.Method == http.MethodPatch && r.URL.Path == "/calendars/primary/events/b":
+			json.NewDecoder(r.Body).Decode(&patched)
+			fmt.Fprint(w, `{"id":"b","summary":"Late lunch","start":{"dateTime":"2026-10-16T13:00:00+02:00"},"end":{"dateTime":"2026-10-16T14:00:00+02:00"}}`)
+		case r.Method == http.MethodPost:
+			var in googleEvent
+			json.NewDecoder(r.Body).Decode(&in)
+			in.ID = "new"
+			json.NewEncoder(w).Encode(in)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"message":"Not Found"}}`)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	tokenPath := filepath.Join(t.TempDir(), "token.json")
+	gcfg := config.GoogleCalendarConfig{ClientID: "id", ClientSecret: "secret"}
+	if _, err := newGoogle(gcfg, tokenPath, berlin); !errors.Is(err, ErrNotAuthorized) {
+		t.Fatalf("newGoogle without a token error = %v", err)
+	}
+	if err := googleauth.SaveToken(tokenPath, &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	g, err := newGoogle(gcfg, tokenPath, berlin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.baseURL = srv.URL
+
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, berlin)
+	events, err := g.Events(ctx, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].ID != "a" || !events[0].AllDay || events[1].Summary != "Lunch" {
+		t.Fatalf("events = %+v", events)
+	}
+
+	ev, err := g.Get(ctx, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev.Summary = "Late lunch"
+	ev.Start, ev.End = ev.Start.Add(time.Hour), ev.End.Add(time.Hour)
+	if ev, err = g.Update(ctx, ev); err != nil || ev.Summary != "Late lunch" {
+		t.Fatalf("Update = %+v, %v", ev, err)
+	}
+	if start := patched["start"].(map[string]any); start["dateTime"] != "2026-10-16T13:00:00+02:00" {
+		t.Errorf("patched start = %v", start)
+	}
+
+	created, err := g.Create(ctx, Event{Summary: "Trip", Start: day.AddDate(0, 0, 1), End: day.AddDate(0, 0, 3), AllDay: true})
+	if err != nil || created.ID != "new" || !created.AllDay || !created.End.Equal(day.AddDate(0, 0, 3)) {
+		t.Errorf("Create = %+v, %v", created, err)
+	}
+	if _, err := g.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) error = %v", err)
+	}
+}
+
+// memCalendar is a Calendar in memory.
+type memCalendar struct {
+	events []Event
+}
+
+func (m *memCalendar) Name() string { return "mem" }
+
+func (m *memCalendar) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	var out []Event
+	for _, ev := range m.events {
+		if overlaps(ev, from, to) {
+			out = append(out, ev)
+		}
+	}
+	sortEvents(out)
+	return out, nil
+}
+
+func (m *memCalendar) Get(ctx context.Context, id string) (Event, error) {
+	for _, ev := range m.events {
+		if ev.ID == id {
+			return ev, nil
+		}
+	}
+	return Event{}, ErrNotFound
+}
+
+func (m *memCalendar) Create(ctx context.Context, ev Event) (Event, error) {
+	ev.ID = fmt.Sprintf("e%d", len(m.events)+1)
+	m.events = append(m.events, ev)
+	return ev, nil
+}
+
+func (m *memCalendar) Update(ctx context.Context, ev Event) (Event, error) {
+	for i := range m.events {
+		if m.events[i].ID == ev.ID {
+			m.events[i] = ev
+			return ev, nil
+		}
+	}
+	return Event{}, ErrNotFound
+}
+
+func TestTools(t *testing.T) {
+	if tools, err := Tools(config.CalendarConfig{}); tools != nil || err != nil {
+		t.Errorf("Tools(none) = %v, %v", tools, err)
+	}
+	if _, err := Tools(config.CalendarConfig{Provider: "outlook"}); err == nil {
+		t.Error("unknown provider accepted")
+	}
+
+	cal := &memCalendar{}
+	now := time.Date(2026, 10, 16, 7, 0, 0, 0, berlin)
+	tools := newTools(cal, berlin, func() time.Time { return now })
+	run := func(name string, params map[string]any) (string, bool) {
+		t.Helper()
+		for _, tl := range tools {
+			if tl.Name() != name {
+				continue
+			}
+			res, err := tl.Execute(context.Background(), params)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			return res.Output, res.Success
+		}
+		t.Fatalf("no tool %s", name)
+		return "", false
+	}
+
+	if out, ok := run("create_event", map[string]any{"summary": "Dentist", "start": "tomorrow"}); !ok || !strings.Contains(out, "all day Dentist") {
+		t.Errorf("create all-day = %q", out)
+	}
+	if out, ok := run("create_event", map[string]any{"summary": "Call", "start": "2026-10-16T15:00", "duration_minutes": float64(30), "location": "Zoom"}); !ok ||
+		!strings.Contains(out, "15:00–15:30 Call @ Zoom [id: e2]") {
+		t.Errorf("create = %q", out)
+	}
+	if out, ok := run("create_event", map[string]any{"summary": "Bad", "start": "2026-10-16T15:00", "end": "2026-10-16T14:00"}); ok {
+		t.Errorf("create with end before start = %q", out)
+	}
+
+	out, _ := run("list_events", map[string]any{})
+	if !strings.HasPrefix(out, "Friday, 16 October 2026\n- 15:00–15:30 Call") || strings.Contains(out, "Dentist") {
+		t.Errorf("list today = %q", out)
+	}
+	if out, _ := run("list_events", map[string]any{"from": "today", "to": "tomorrow"}); !strings.Contains(out, "Saturday, 17 October 2026\n- all day Dentist") {
+		t.Errorf("list through tomorrow = %q", out)
+	}
+
+	if out, ok := run("update_event", map[string]any{"id": "e2", "start": "2026-10-16T16:00", "location": ""}); !ok || !strings.Contains(out, "16:00–16:30 Call [id: e2]") {
+		t.Errorf("update = %q", out)
+	}
+	if out, ok := run("update_event", map[string]any{"id": "nope", "summary": "x"}); ok || !strings.Contains(out, "not found") {
+		t.Errorf("update missing = %q", out)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	now := time.Date(2026, 10, 16, 23, 30, 0, 0, time.UTC) // already the 17th in Berlin
+	from, to, err := ParseRange("", "", berlin, now)
+	if err != nil || !from.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, berlin)) || !to.Equal(from.AddDate(0, 0, 1)) {
+		t.Errorf("default range = %v, %v, %v", from, to, err)
+	}
+	from, to, err = ParseRange("2026-10-20T09:00", "2026-10-21", berlin, now)
+	if err != nil || !from.Equal(time.Date(2026, 10, 20, 9, 0, 0, 0, berlin)) || !to.Equal(time.Date(2026, 10, 22, 0, 0, 0, 0, berlin)) {
+		t.Errorf("range = %v, %v, %v", from, to, err)
+	}
+	if _, _, err := ParseRange("tomorrow", "today", berlin, now); err == nil {
+		t.Error("backwards range accepted")
+	}
+	if _, _, err := ParseRange("next friday", "", berlin, now); err == nil {
+		t.Error("unreadable time accepted")
+	}
+}
@@ -0,0 +1,243 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/googleauth"
+	"golang.org/x/oauth2"
+)
+
+const (
+	googleAPIURL = "https://www.googleapis.com/calendar/v3"
+	// GoogleScope lets myclaw read and write events, not calendar settings.
+	GoogleScope = "https://www.googleapis.com/auth/calendar.events"
+)
+
+// ErrNotAuthorized is returned when Google is configured but nobody has
+// signed in with `myclaw auth google` yet.
+var ErrNotAuthorized = errors.New("not signed in to Google; run `myclaw auth google`")
+
+// GoogleTokenPath is where the Google sign-in is kept.
+func GoogleTokenPath() string {
+	return filepath.Join(config.DataDir(), "data", "calendar", "google-token.json")
+}
+
+// GoogleOAuth returns the OAuth client for cfg.
+func GoogleOAuth(cfg config.GoogleCalendarConfig) *oauth2.Config {
+	return googleauth.Config(cfg.ClientID, cfg.ClientSecret, GoogleScope)
+}
+
+// google is a calendar in Google Calendar.
+type google struct {
+	client     *http.Client
+	baseURL    string
+	calendarID string
+	loc        *time.Location
+}
+
+func newGoogle(cfg config.GoogleCalendarConfig, tokenPath string, loc *time.Location) (*google, error) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil, errors.New("calendar: google needs clientId and clientSecret")
+	}
+	client, err := googleauth.Client(GoogleOAuth(cfg), tokenPath, httpClient)
+	if errors.Is(err, googleauth.ErrNoToken) {
+		err = ErrNotAuthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("calendar: %w", err)
+	}
+	id := cfg.CalendarID
+	if id == "" {
+		id = "primary"
+	}
+	return &google{
+		client:     client,
+		baseURL:    googleAPIURL,
+		calendarID: id,
+		loc:        loc,
+	}, nil
+}
+
+func (g *google) Name() string { return "google" }
+
+// googleEvent is an event as the Calendar API has it.
+type googleEvent struct {
+	ID          string     `json:"id,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	Summary     string     `json:"summary"`
+	Location    string     `json:"location"`
+	Description string     `json:"description"`
+	Start       googleTime `json:"start"`
+	End         googleTime `json:"end"`
+	Recurrence  []string   `json:"recurrence,omitempty"`
+	RecurringID string     `json:"recurringEventId,omitempty"`
+}
+
+type googleTime struct {
+	Date     string `json:"date,omitempty"`
+	DateTime string `json:"dateTime,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+func (g *google) toEvent(ge googleEvent) (Event, error) {
+	ev := Event{
+		ID:          ge.ID,
+		Summary:     ge.Summary,
+		Location:    ge.Location,
+		Description: ge.Description,
+		Recurring:   len(ge.Recurrence) > 0 || ge.RecurringID != "",
+	}
+	var err error
+	if ev.Start, ev.AllDay, err = g.parseTime(ge.Start); err != nil {
+		return ev, err
+	}
+	if ev.End, _, err = g.parseTime(ge.End); err != nil {
+		return ev, err
+	}
+	return ev, nil
+}
+
+func (g *google) parseTime(t googleTime) (time.Time, bool, error) {
+	if t.Date != "" {
+		d, err := time.ParseInLocation("2006-01-02", t.Date, g.loc)
+		return d, true, err
+	}
+	d, err := time.Parse(time.RFC3339, t.DateTime)
+	return d, false, err
+}
+
+func (g *google) fromEvent(ev Event) googleEvent {
+	ge := googleEvent{Summary: ev.Summary, Location: ev.Location, Description: ev.Description}
+	if ev.AllDay {
+		ge.Start.Date = ev.Start.In(g.loc).Format("2006-01-02")
+		ge.End.Date = ev.End.In(g.loc).Format("2006-01-02")
+	} else {
+		ge.Start.DateTime = ev.Start.Format(time.RFC3339)
+		ge.End.DateTime = ev.End.Format(time.RFC3339)
+	}
+	return ge
+}
+
+func (g *google) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	q := url.Values{
+		"timeMin":      {from.Format(time.RFC3339)},
+		"timeMax":      {to.Format(time.RFC3339)},
+		"singleEvents": {"true"},
+		"orderBy":      {"startTime"},
+		"maxResults":   {"250"},
+	}
+	var out struct {
+		Items []googleEvent `json:"items"`
+	}
+	if err := g.call(ctx, http.MethodGet, g.eventsURL("")+"?"+q.Encode(), nil, &out); err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(out.Items))
+	for _, item := range out.Items {
+		if item.Status == "cancelled" {
+			continue
+		}
+		ev, err := g.toEvent(item)
+		if err != nil {
+			return nil, fmt.Errorf("google: event %s: %w", item.ID, err)
+		}
+		events = append(events, ev)
+	}
+	sortEvents(events)
+	return events, nil
+}
+
+func (g *google) Get(ctx context.Context, id string) (Event, error) {
+	var out googleEvent
+	if err := g.call(ctx, http.MethodGet, g.eventsURL(id), nil, &out); err != nil {
+		return Event{}, err
+	}
+	return g.toEvent(out)
+}
+
+func (g *google) Create(ctx context.Context, ev Event) (Event, error) {
+	var out googleEvent
+	if err := g.call(ctx, http.MethodPost, g.eventsURL(""), g.fromEvent(ev), &out); err != nil {
+		return Event{}, err
+	}
+	return g.toEvent(out)
+}
+
+func (g *google) Update(ctx context.Context, ev Event) (Event, error) {
+	if ev.ID == "" {
+		return Event{}, fmt.Errorf("google: %w", ErrNotFound)
+	}
+	var out googleEvent
+	if err := g.call(ctx, http.MethodPatch, g.eventsURL(ev.ID), g.fromEvent(ev), &out); err != nil {
+		return Event{}, err
+	}
+	return g.toEvent(out)
+}
+
+func (g *google) eventsURL(id string) string {
+	u := g.baseURL + "/calendars/" + url.PathEscape(g.calendarID) + "/events"
+	if id != "" {
+		u += "/" + url.PathEscape(id)
+	}
+	return u
+}
+
+func (g *google) call(ctx context.Context, method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		if reason, ok := googleauth.Revoked(err); ok {
+			return fmt.Errorf("google: sign-in expired or revoked (%s); run `myclaw auth google`", reason)
+		}
+		return fmt.Errorf("google: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("google: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return fmt.Errorf("google: %w", ErrNotFound)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			msg = apiErr.Error.Message
+		}
+		return fmt.Errorf("google: %s: %s", resp.Status, msg)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("google: decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// component is an iCalendar (RFC 5545) component, such as a VCALENDAR or
+// a VEVENT, with its properties in their original order so that an event
+// can be changed without losing what myclaw does not understand.
+type component struct {
+	name  string
+	props []prop
+	subs  []*component
+}
+
+// prop is a content line: NAME;PARAMS:VALUE.
+type prop struct {
+	name   string
+	params string // as written, with the leading ';'
+	value  string
+}
+
+func (p prop) param(key string) string {
+	for _, kv := range strings.Split(strings.TrimPrefix(p.params, ";"), ";") {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.EqualFold(k, key) {
+			return strings.Trim(v, `"`)
+		}
+	}
+	return ""
+}
+
+// parseICS parses an iCalendar object.
+func parseICS(data string) (*component, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	var root *component
+	var stack []*component
+	for _, line := range lines {
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		switch p.name {
+		case "BEGIN":
+			c := &component{name: strings.ToUpper(p.value)}
+			if len(stack) > 0 {
+				top := stack[len(stack)-1]
+				top.subs = append(top.subs, c)
+			} else if root == nil {
+				root = c
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].name != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("ics: unexpected END:%s", p.value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("ics: %s outside a component", p.name)
+			}
+			top := stack[len(stack)-1]
+			top.props = append(top.props, p)
+		}
+	}
+	if root == nil || len(stack) > 0 {
+		return nil, errors.New("ics: not a complete calendar object")
+	}
+	return root, nil
+}
+
+// parseLine splits a content line. The value starts at the first ':' that
+// is not inside a quoted parameter value.
+func parseLine(line string) (prop, error) {
+	quoted := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ':' && !quoted:
+			name, params, _ := strings.Cut(line[:i], ";")
+			if params != "" {
+				params = ";" + params
+			}
+			return prop{name: strings.ToUpper(name), params: params, value: line[i+1:]}, nil
+		}
+	}
+	return prop{}, fmt.Errorf("ics: malformed line %q", line)
+}
+
+// encode writes c with CRLF line endings and lines folded at 75 octets.
+func (c *component) encode() string {
+	var b strings.Builder
+	c.write(&b)
+	return b.String()
+}
+
+func (c *component) write(b *strings.Builder) {
+	writeLine(b, "BEGIN:"+c.name)
+	for _, p := range c.props {
+		writeLine(b, p.name+p.params+":"+p.value)
+	}
+	for _, sub := range c.subs {
+		sub.write(b)
+	}
+	writeLine(b, "END:"+c.name)
+}
+
+func writeLine(b *strings.Builder, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n")
+		line = " " + line[cut:]
+	}
+	b.WriteString(line + "\r\n")
+}
+
+func (c *component) get(name string) (prop, bool) {
+	for _, p := range c.props {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return prop{}, false
+}
+
+// set replaces the first name property, or adds one, and drops any others.
+func (c *component) set(p prop) {
+	out := c.props[:0]
+	done := false
+	for _, old := range c.props {
+		if old.name != p.name {
+			out = append(out, old)
+		} else if !done {
+			out = append(out, p)
+			done = true
+		}
+	}
+	if !done {
+		out = append(out, p)
+	}
+	c.props = out
+}
+
+func (c *component) del(name string) {
+	out := c.props[:0]
+	for _, p := range c.props {
+		if p.name != name {
+			out = append(out, p)
+		}
+	}
+	c.props = out
+}
+
+// events returns the VEVENTs of a calendar object.
+func (c *component) events() []*component {
+	var out []*component
+	for _, sub := range c.subs {
+		if sub.name == "VEVENT" {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+// master returns the VEVENT that is not an override of one instance of a
+// recurring event.
+func (c *component) master() *component {
+	events := c.events()
+	for _, ev := range events {
+		if _, ok := ev.get("RECURRENCE-ID"); !ok {
+			return ev
+		}
+	}
+	if len(events) > 0 {
+		return events[0]
+	}
+	return nil
+}
+
+// toEvent reads a VEVENT; times without a zone are in loc.
+func toEvent(vevent *component, id string, loc *time.Location) (Event, error) {
+	ev := Event{ID: id}
+	start, ok := vevent.get("DTSTART")
+	if !ok {
+		return ev, errors.New("ics: event without DTSTART")
+	}
+	var err error
+	if ev.Start, ev.AllDay, err = parseTime(start, loc); err != nil {
+		return ev, err
+	}
+	switch {
+	case has(vevent, "DTEND"):
+		end, _ := vevent.get("DTEND")
+		if ev.End, _, err = parseTime(end, loc); err != nil {
+			return ev, err
+		}
+	case has(vevent, "DURATION"):
+		dur, _ := vevent.get("DURATION")
+		d, err := parseDuration(dur.value)
+		if err != nil {
+			return ev, err
+		}
+		ev.End = ev.Start.Add(d)
+	case ev.AllDay:
+		ev.End = ev.Start.AddDate(0, 0, 1)
+	default:
+		ev.End = ev.Start
+	}
+	for name, field := range map[string]*string{"SUMMARY": &ev.Summary, "LOCATION": &ev.Location, "DESCRIPTION": &ev.Description} {
+		if p, ok := vevent.get(name); ok {
+			*field = unescapeText(p.value)
+		}
+	}
+	ev.Recurring = has(vevent, "RRULE") || has(vevent, "RECURRENCE-ID")
+	return ev, nil
+}
+
+// setEvent writes the summary, times, location and description of ev into
+// a VEVENT.
+func setEvent(vevent *component, ev Event, now time.Time) {
+	vevent.set(prop{name: "SUMMARY", value: escapeText(ev.Summary)})
+	vevent.set(formatTime("DTSTART", ev.Start, ev.AllDay))
+	vevent.set(formatTime("DTEND", ev.End, ev.AllDay))
+	vevent.del("DURATION")
+	for _, p := range []prop{{name: "LOCATION", value: ev.Location}, {name: "DESCRIPTION", value: ev.Description}} {
+		if p.value == "" {
+			vevent.del(p.name)
+		} else {
+			vevent.set(prop{name: p.name, value: escapeText(p.value)})
+		}
+	}
+	seq := 0
+	if p, ok := vevent.get("SEQUENCE"); ok {
+		seq, _ = strconv.Atoi(p.value)
+		seq++
+	}
+	vevent.set(prop{name: "SEQUENCE", value: strconv.Itoa(seq)})
+	vevent.set(prop{name: "DTSTAMP", value: now.UTC().Format(utcLayout)})
+	vevent.set(prop{name: "LAST-MODIFIED", value: now.UTC().Format(utcLayout)})
+}
+
+// newCalendar returns a calendar object holding a new event with a fresh
+// UID.
+func newCalendar(ev Event, now time.Time) (*component, string) {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	uid := fmt.Sprintf("%x@myclaw", b)
+	vevent := &component{name: "VEVENT", props: []prop{{name: "UID", value: uid}}}
+	setEvent(vevent, ev, now)
+	vevent.set(prop{name: "CREATED", value: now.UTC().Format(utcLayout)})
+	return &component{
+		name: "VCALENDAR",
+		props: []prop{
+			{name: "VERSION", value: "2.0"},
+			{name: "PRODID", value: "-//myclaw//calendar//EN"},
+		},
+		subs: []*component{vevent},
+	}, uid
+}
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	dateLayout  = "20060102"
+)
+
+func parseTime(p prop, loc *time.Location) (time.Time, bool, error) {
+	v := strings.TrimSpace(p.value)
+	if strings.EqualFold(p.param("VALUE"), "DATE") || len(v) == len(dateLayout) {
+		t, err := time.ParseInLocation(dateLayout, v, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse(utcLayout, v)
+		return t, false, err
+	}
+	if tz := p.param("TZID"); tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(localLayout, v, loc)
+	return t, false, err
+}
+
+func formatTime(name string, t time.Time, allDay bool) prop {
+	if allDay {
+		return prop{name: name, params: ";VALUE=DATE", value: t.Format(dateLayout)}
+	}
+	return prop{name: name, value: t.UTC().Format(utcLayout)}
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses an iCalendar DURATION such as PT1H30M or P1D.
+func parseDuration(s string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("ics: bad duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if n, err := strconv.Atoi(m[i+2]); err == nil {
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(strings.ReplaceAll(s, "\r\n", "\n"))
+}
+
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func has(c *component, name string) bool {
+	_, ok := c.get(name)
+	return ok
+}
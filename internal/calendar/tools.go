@@ -0,0 +1,286 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const defaultDuration = time.Hour
+
+// Tools returns the calendar tools for cfg, or nil when no calendar is
+// configured.
+func Tools(cfg config.CalendarConfig) ([]tool.Tool, error) {
+	cal, err := New(cfg)
+	if err != nil || cal == nil {
+		return nil, err
+	}
+	loc, _ := Location(cfg)
+	return NewTools(cal, loc), nil
+}
+
+// NewTools returns the tools the agent reads and writes cal with.
+func NewTools(cal Calendar, loc *time.Location) []tool.Tool {
+	return newTools(cal, loc, time.Now)
+}
+
+func newTools(cal Calendar, loc *time.Location, now func() time.Time) []tool.Tool {
+	base := toolBase{cal: cal, loc: loc, now: now}
+	return []tool.Tool{&listTool{base}, &createTool{base}, &updateTool{base}}
+}
+
+type toolBase struct {
+	cal Calendar
+	loc *time.Location
+	now func() time.Time
+}
+
+// ParseTime reads a time as the tools and the CLI take it: "today",
+// "tomorrow", a date, or a date and time with or without a zone, in loc.
+// dateOnly reports a bare date.
+func ParseTime(s string, loc *time.Location, now time.Time) (t time.Time, dateOnly bool, err error) {
+	s = strings.TrimSpace(s)
+	today := Day(now, loc)
+	switch strings.ToLower(s) {
+	case "today":
+		return today, true, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, false, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("cannot read time %q; use 2006-01-02T15:04 or 2006-01-02", s)
+}
+
+// ParseRange reads a from and to as ParseTime does. from defaults to
+// today and to to the end of the day from is on; a date as to means the
+// end of that day.
+func ParseRange(fromParam, toParam string, loc *time.Location, now time.Time) (from, to time.Time, err error) {
+	from = Day(now, loc)
+	if fromParam != "" {
+		if from, _, err = ParseTime(fromParam, loc, now); err != nil {
+			return from, from, err
+		}
+	}
+	to = Day(from, loc).AddDate(0, 0, 1)
+	if toParam != "" {
+		end, dateOnly, err := ParseTime(toParam, loc, now)
+		if err != nil {
+			return from, from, err
+		}
+		if to = end; dateOnly {
+			to = end.AddDate(0, 0, 1)
+		}
+	}
+	if !to.After(from) {
+		return from, to, errors.New("to must be after from")
+	}
+	return from, to, nil
+}
+
+func (b toolBase) parseTime(s string) (time.Time, bool, error) {
+	return ParseTime(s, b.loc, b.now())
+}
+
+type listTool struct{ toolBase }
+
+func (t *listTool) Name() string { return "list_events" }
+
+func (t *listTool) Description() string {
+	return "List the events in the user's calendar between two times, e.g. for \"what's on my schedule tomorrow\". " +
+		"Without from and to, lists today's events."
+}
+
+func (t *listTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"from": map[string]any{
+				"type":        "string",
+				"description": "Start: \"today\", \"tomorrow\", a date (2026-10-16) or a date and time (2026-10-16T09:00); default today",
+			},
+			"to": map[string]any{
+				"type":        "string",
+				"description": "End, exclusive, in the same forms; a date means the end of that day. Default: the end of the day from is on",
+			},
+		},
+		Required: []string{},
+	}
+}
+
+func (t *listTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	from, to, err := ParseRange(stringParam(params, "from"), stringParam(params, "to"), t.loc, t.now())
+	if err != nil {
+		return failed(err), nil
+	}
+	events, err := t.cal.Events(ctx, from, to)
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: Format(events, t.loc), Data: events}, nil
+}
+
+type createTool struct{ toolBase }
+
+func (t *createTool) Name() string { return "create_event" }
+
+func (t *createTool) Description() string {
+	return "Add an event to the user's calendar. Give start as a date for an all-day event."
+}
+
+func (t *createTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"summary":          map[string]any{"type": "string", "description": "The title of the event"},
+			"start":            map[string]any{"type": "string", "description": "When it starts: 2026-10-16T09:00 (in the user's time zone), RFC 3339, or a date for all day"},
+			"end":              map[string]any{"type": "string", "description": "When it ends, in the same forms; for all-day events, the last day"},
+			"duration_minutes": map[string]any{"type": "integer", "description": "Length instead of end; default 60"},
+			"location":         map[string]any{"type": "string"},
+			"description":      map[string]any{"type": "string"},
+		},
+		Required: []string{"summary", "start"},
+	}
+}
+
+func (t *createTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	ev := Event{
+		Summary:     stringParam(params, "summary"),
+		Location:    stringParam(params, "location"),
+		Description: stringParam(params, "description"),
+	}
+	if ev.Summary == "" {
+		return failed(errors.New("summary is required")), nil
+	}
+	start, dateOnly, err := t.parseTime(stringParam(params, "start"))
+	if err != nil {
+		return failed(err), nil
+	}
+	ev.Start, ev.AllDay = start, dateOnly
+	if ev.End, err = t.end(params, ev); err != nil {
+		return failed(err), nil
+	}
+	created, err := t.cal.Create(ctx, ev)
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: "Created:\n" + Format([]Event{created}, t.loc), Data: created}, nil
+}
+
+// end works out the end of ev from the end or duration_minutes parameter.
+// Without them it keeps the end ev has, or for a new event makes it an
+// hour long (a day when all day).
+func (b toolBase) end(params map[string]any, ev Event) (time.Time, error) {
+	end := ev.End
+	if end.IsZero() {
+		end = ev.Start.Add(defaultDuration)
+		if ev.AllDay {
+			end = ev.Start.AddDate(0, 0, 1)
+		}
+	}
+	if n, ok := params["duration_minutes"].(float64); ok && n > 0 {
+		end = ev.Start.Add(time.Duration(n) * time.Minute)
+	}
+	if s := stringParam(params, "end"); s != "" {
+		t, dateOnly, err := b.parseTime(s)
+		if err != nil {
+			return end, err
+		}
+		if end = t; dateOnly && ev.AllDay {
+			end = t.AddDate(0, 0, 1)
+		}
+	}
+	if !end.After(ev.Start) {
+		return end, errors.New("end must be after start")
+	}
+	return end, nil
+}
+
+type updateTool struct{ toolBase }
+
+func (t *updateTool) Name() string { return "update_event" }
+
+func (t *updateTool) Description() string {
+	return "Change an event in the user's calendar by the id list_events shows. " +
+		"Only the fields given change; moving the start keeps the length unless end is given."
+}
+
+func (t *updateTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"id":               map[string]any{"type": "string", "description": "The event id"},
+			"summary":          map[string]any{"type": "string"},
+			"start":            map[string]any{"type": "string", "description": "New start, in the forms create_event takes"},
+			"end":              map[string]any{"type": "string", "description": "New end"},
+			"duration_minutes": map[string]any{"type": "integer", "description": "New length instead of end"},
+			"location":         map[string]any{"type": "string", "description": "New location; an empty string removes it"},
+			"description":      map[string]any{"type": "string", "description": "New description; an empty string removes it"},
+		},
+		Required: []string{"id"},
+	}
+}
+
+func (t *updateTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	id := stringParam(params, "id")
+	if id == "" {
+		return failed(errors.New("id is required")), nil
+	}
+	ev, err := t.cal.Get(ctx, id)
+	if err != nil {
+		return failed(err), nil
+	}
+	if s := stringParam(params, "summary"); s != "" {
+		ev.Summary = s
+	}
+	for name, field := range map[string]*string{"location": &ev.Location, "description": &ev.Description} {
+		if v, ok := params[name].(string); ok {
+			*field = strings.TrimSpace(v)
+		}
+	}
+	if s := stringParam(params, "start"); s != "" {
+		start, dateOnly, err := t.parseTime(s)
+		if err != nil {
+			return failed(err), nil
+		}
+		if dateOnly == ev.AllDay {
+			ev.End = start.Add(ev.End.Sub(ev.Start))
+		} else {
+			ev.End = time.Time{}
+		}
+		ev.Start, ev.AllDay = start, dateOnly
+	}
+	if ev.End, err = t.end(params, ev); err != nil {
+		return failed(err), nil
+	}
+	updated, err := t.cal.Update(ctx, ev)
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: "Updated:\n" + Format([]Event{updated}, t.loc), Data: updated}, nil
+}
+
+func stringParam(params map[string]any, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+func failed(err error) *tool.ToolResult {
+	return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}
+}
@@ -2,6 +2,9 @@ package channel
 
 import (
 	"context"
+	"log"
+	"path/filepath"
+	"strings"
 
 	"github.com/stellarlinkco/myclaw/internal/bus"
 )
@@ -14,26 +17,82 @@ type Channel interface {
 }
 
 type BaseChannel struct {
-	name      string
-	bus       *bus.MessageBus
-	allowFrom map[string]bool
+	name        string
+	bus         *bus.MessageBus
+	allowFrom   map[string]bool
+	denyFrom    map[string]bool
+	deniedReply string
+}
+
+// Access says who may message a channel. Entries are user IDs, usernames
+// (with or without a leading @) or chat IDs. DenyFrom wins over AllowFrom;
+// an empty AllowFrom lets in everyone not denied. DeniedReply, when set,
+// is sent to rejected senders.
+type Access struct {
+	AllowFrom   []string
+	DenyFrom    []string
+	DeniedReply string
 }
 
 func NewBaseChannel(name string, b *bus.MessageBus, allowFrom []string) BaseChannel {
-	af := make(map[string]bool, len(allowFrom))
-	for _, id := range allowFrom {
-		af[id] = true
+	return NewBaseChannelWithAccess(name, b, Access{AllowFrom: allowFrom})
+}
+
+func NewBaseChannelWithAccess(name string, b *bus.MessageBus, access Access) BaseChannel {
+	return BaseChannel{
+		name:        name,
+		bus:         b,
+		allowFrom:   idSet(access.AllowFrom),
+		denyFrom:    idSet(access.DenyFrom),
+		deniedReply: access.DeniedReply,
+	}
+}
+
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[strings.TrimPrefix(id, "@")] = true
 	}
-	return BaseChannel{name: name, bus: b, allowFrom: af}
+	return set
 }
 
 func (c *BaseChannel) Name() string {
 	return c.name
 }
 
-func (c *BaseChannel) IsAllowed(senderID string) bool {
-	if len(c.allowFrom) == 0 {
+// IsAllowed reports whether a message may come in from a sender known by
+// ids: its user ID, username, chat ID and so on. Empty ids are ignored.
+func (c *BaseChannel) IsAllowed(ids ...string) bool {
+	allowed := len(c.allowFrom) == 0
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if c.denyFrom[id] {
+			return false
+		}
+		if c.allowFrom[id] {
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// Reject logs a message turned away by IsAllowed and, when the channel has
+// a denied reply, sends it to chatID.
+func (c *BaseChannel) Reject(chatID, sender string) {
+	log.Printf("[%s] rejected message from %s", c.name, sender)
+	if c.deniedReply == "" || chatID == "" || c.bus == nil {
+		return
+	}
+	c.bus.Outbound <- bus.OutboundMessage{Channel: c.name, ChatID: chatID, Content: c.deniedReply}
+}
+
+// isImageFile reports whether path names a picture chat apps show inline.
+func isImageFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp":
 		return true
 	}
-	return c.allowFrom[senderID]
+	return false
 }
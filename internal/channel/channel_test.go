@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -50,6 +53,47 @@ func TestBaseChannel_IsAllowed_WithFilter(t *testing.T) {
 	}
 }
 
+func TestBaseChannel_Access(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	ch := NewBaseChannelWithAccess("test", b, Access{
+		AllowFrom:   []string{"@alice", "-100"},
+		DenyFrom:    []string{"mallory"},
+		DeniedReply: "This is a private assistant.",
+	})
+
+	if !ch.IsAllowed("1", "alice") {
+		t.Error("should allow by username")
+	}
+	if !ch.IsAllowed("2", "", "-100") {
+		t.Error("should allow anyone in an allowed chat")
+	}
+	if ch.IsAllowed("3", "mallory", "-100") {
+		t.Error("denyFrom should win over an allowed chat")
+	}
+	if ch.IsAllowed("4", "bob") {
+		t.Error("should reject senders not allowed")
+	}
+
+	ch.Reject("chat4", "4")
+	select {
+	case msg := <-b.Outbound:
+		if msg.Channel != "test" || msg.ChatID != "chat4" || msg.Content != "This is a private assistant." {
+			t.Errorf("denied reply = %+v", msg)
+		}
+	default:
+		t.Error("expected a denied reply")
+	}
+
+	open := NewBaseChannelWithAccess("test", b, Access{DenyFrom: []string{"spammer"}})
+	if !open.IsAllowed("anyone") || open.IsAllowed("spammer") {
+		t.Error("denyFrom alone should reject only the listed senders")
+	}
+	open.Reject("chat", "spammer")
+	if len(b.Outbound) != 0 {
+		t.Error("no reply should be sent without deniedReply")
+	}
+}
+
 func TestNewTelegramChannel_NoToken(t *testing.T) {
 	b := bus.NewMessageBus(10)
 	_, err := NewTelegramChannel(config.TelegramConfig{}, b)
@@ -69,26 +113,6 @@ func TestNewTelegramChannel_Valid(t *testing.T) {
 	}
 }
 
-func TestToTelegramHTML(t *testing.T) {
-	tests := []struct {
-		input string
-		want  string
-	}{
-		{"hello", "hello"},
-		{"**bold**", "<b>bold</b>"},
-		{"`code`", "<code>code</code>"},
-		{"a & b", "a &amp; b"},
-		{"<tag>", "&lt;tag&gt;"},
-	}
-
-	for _, tt := range tests {
-		got := toTelegramHTML(tt.input)
-		if got != tt.want {
-			t.Errorf("toTelegramHTML(%q) = %q, want %q", tt.input, got, tt.want)
-		}
-	}
-}
-
 func TestChannelManager_Empty(t *testing.T) {
 	b := bus.NewMessageBus(10)
 	m, err := NewChannelManager(config.ChannelsConfig{}, b)
@@ -100,64 +124,6 @@ func TestChannelManager_Empty(t *testing.T) {
 	}
 }
 
-func TestToTelegramHTML_CodeBlocks(t *testing.T) {
-	tests := []struct {
-		name  string
-		input string
-		want  string
-	}{
-		{
-			"code block with language",
-			"```go\nfunc main() {}\n```",
-			"<pre>func main() {}\n</pre>",
-		},
-		{
-			"code block without language",
-			"```\ncode here\n```",
-			"<pre>\ncode here\n</pre>",
-		},
-		{
-			"italic text",
-			"*italic*",
-			"<i>italic</i>",
-		},
-		{
-			"mixed bold and italic",
-			"**bold** and *italic*",
-			"<b>bold</b> and <i>italic</i>",
-		},
-		{
-			"unclosed code block",
-			"```code",
-			"<code></code>`code", // best-effort: processes inline code
-		},
-		{
-			"unclosed inline code",
-			"`code",
-			"`code", // no closing backtick, unchanged
-		},
-		{
-			"unclosed bold",
-			"**bold",
-			"<i></i>bold", // best-effort: processes single * as italic
-		},
-		{
-			"unclosed italic",
-			"*italic",
-			"*italic", // no closing *, unchanged
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := toTelegramHTML(tt.input)
-			if got != tt.want {
-				t.Errorf("toTelegramHTML(%q) = %q, want %q", tt.input, got, tt.want)
-			}
-		})
-	}
-}
-
 func TestTelegramChannel_Stop_NotStarted(t *testing.T) {
 	b := bus.NewMessageBus(10)
 	ch, _ := NewTelegramChannel(config.TelegramConfig{Token: "fake-token"}, b)
@@ -329,7 +295,7 @@ func TestTelegramChannel_HandleMessage_Allowed(t *testing.T) {
 		Date: 1234567890,
 	}
 
-	ch.handleMessage(msg)
+	ch.handleMessage(msg, 0)
 
 	select {
 	case inbound := <-b.Inbound:
@@ -360,7 +326,7 @@ func TestTelegramChannel_HandleMessage_Rejected(t *testing.T) {
 		Text: "hello",
 	}
 
-	ch.handleMessage(msg)
+	ch.handleMessage(msg, 0)
 
 	// Should not receive any message
 	select {
@@ -381,7 +347,7 @@ func TestTelegramChannel_HandleMessage_EmptyText(t *testing.T) {
 		Text: "", // Empty text
 	}
 
-	ch.handleMessage(msg)
+	ch.handleMessage(msg, 0)
 
 	// Should not receive any message
 	select {
@@ -403,7 +369,7 @@ func TestTelegramChannel_HandleMessage_Caption(t *testing.T) {
 		Caption: "image caption", // Caption instead of text
 	}
 
-	ch.handleMessage(msg)
+	ch.handleMessage(msg, 0)
 
 	select {
 	case inbound := <-b.Inbound:
@@ -441,7 +407,7 @@ func TestTelegramChannel_HandleMessage_Photo(t *testing.T) {
 		},
 	}
 
-	ch.handleMessage(msg)
+	ch.handleMessage(msg, 0)
 
 	select {
 	case inbound := <-b.Inbound:
@@ -466,6 +432,29 @@ func TestTelegramChannel_HandleMessage_Photo(t *testing.T) {
 	}
 }
 
+func TestTelegramChannel_DownloadLimits(t *testing.T) {
+	ch, _ := NewTelegramChannel(config.TelegramConfig{Token: "fake-token"}, bus.NewMessageBus(10))
+	mockBot := newMockBot()
+	mockBot.files["big"] = tgbotapi.File{FileID: "big", FilePath: "documents/big.bin"}
+	ch.SetBot(mockBot)
+
+	var deadline time.Time
+	ch.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		deadline, _ = req.Context().Deadline()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader(make([]byte, telegramFileMaxBytes+1))),
+			Header:     make(http.Header),
+		}, nil
+	})}
+	if _, err := ch.downloadFileData("big"); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("oversized download error = %v", err)
+	}
+	if deadline.IsZero() || time.Until(deadline) > telegramFileTimeout {
+		t.Errorf("download deadline = %v", deadline)
+	}
+}
+
 func TestTelegramChannel_HandleMessage_PhotoWithCaption(t *testing.T) {
 	b := bus.NewMessageBus(10)
 	ch, _ := NewTelegramChannel(config.TelegramConfig{Token: "fake-token"}, b)
@@ -506,7 +495,7 @@ func TestTelegramChannel_HandleMessage_PhotoWithCaption(t *testing.T) {
 		},
 	}
 
-	ch.handleMessage(msg)
+	ch.handleMessage(msg, 0)
 
 	select {
 	case inbound := <-b.Inbound:
@@ -622,25 +611,45 @@ func TestTelegramChannel_HandleMessage_Document(t *testing.T) {
 		},
 	}
 
-	ch.handleMessage(msg)
+	ch.handleMessage(msg, 0)
 
 	select {
 	case inbound := <-b.Inbound:
 		if inbound.Content != "" {
 			t.Errorf("content = %q, want empty", inbound.Content)
 		}
-		if len(inbound.ContentBlocks) != 1 {
-			t.Fatalf("content blocks len = %d, want 1", len(inbound.ContentBlocks))
-		}
-		block := inbound.ContentBlocks[0]
-		if block.Type != model.ContentBlockDocument {
-			t.Errorf("content block type = %q, want %q", block.Type, model.ContentBlockDocument)
+		if len(inbound.ContentBlocks) != 0 || len(inbound.Attachments) != 1 {
+			t.Fatalf("blocks = %d, attachments = %d, want the document as an attachment", len(inbound.ContentBlocks), len(inbound.Attachments))
 		}
-		if block.MediaType != "application/pdf" {
-			t.Errorf("content block media type = %q, want application/pdf", block.MediaType)
+		att := inbound.Attachments[0]
+		if att.MediaType != "application/pdf" || !bytes.Equal(att.Data, pdfData) {
+			t.Errorf("attachment = %s %q", att.MediaType, att.Data)
 		}
-		if block.Data != base64.StdEncoding.EncodeToString(pdfData) {
-			t.Errorf("content block data mismatch")
+	default:
+		t.Error("expected inbound message")
+	}
+}
+
+func TestTelegramChannel_HandleMessage_Voice(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	ch, _ := NewTelegramChannel(config.TelegramConfig{Token: "fake-token"}, b)
+	mockBot := newMockBot()
+	mockBot.files["voice-1"] = tgbotapi.File{FileID: "voice-1", FilePath: "voice/file.oga"}
+	ch.SetBot(mockBot)
+	ch.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("OggS")), Header: make(http.Header)}, nil
+	})}
+
+	ch.handleMessage(&tgbotapi.Message{
+		From:  &tgbotapi.User{ID: 123},
+		Chat:  &tgbotapi.Chat{ID: 456},
+		Voice: &tgbotapi.Voice{FileID: "voice-1"},
+	}, 0)
+
+	select {
+	case inbound := <-b.Inbound:
+		if len(inbound.Attachments) != 1 || inbound.Attachments[0].MediaType != "audio/ogg" || string(inbound.Attachments[0].Data) != "OggS" {
+			t.Errorf("attachments = %+v", inbound.Attachments)
 		}
 	default:
 		t.Error("expected inbound message")
@@ -655,7 +664,7 @@ func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 
 // mockTelegramBot implements TelegramBot interface for testing
 type mockTelegramBot struct {
-	updatesChan chan tgbotapi.Update
+	updatesChan chan TelegramUpdate
 	stopped     bool
 	sentMsgs    []tgbotapi.Chattable
 	sendErr     error
@@ -666,13 +675,13 @@ type mockTelegramBot struct {
 
 func newMockBot() *mockTelegramBot {
 	return &mockTelegramBot{
-		updatesChan: make(chan tgbotapi.Update, 10),
+		updatesChan: make(chan TelegramUpdate, 10),
 		files:       make(map[string]tgbotapi.File),
 		self:        tgbotapi.User{UserName: "testbot"},
 	}
 }
 
-func (m *mockTelegramBot) GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+func (m *mockTelegramBot) GetUpdatesChan(config tgbotapi.UpdateConfig) <-chan TelegramUpdate {
 	return m.updatesChan
 }
 
@@ -770,13 +779,13 @@ func TestTelegramChannel_Start_Success(t *testing.T) {
 	}
 
 	// Send a test update
-	mockBot.updatesChan <- tgbotapi.Update{
+	mockBot.updatesChan <- TelegramUpdate{Update: tgbotapi.Update{
 		Message: &tgbotapi.Message{
 			From: &tgbotapi.User{ID: 123},
 			Chat: &tgbotapi.Chat{ID: 456},
 			Text: "test message",
 		},
-	}
+	}}
 
 	// Wait for message to be processed
 	time.Sleep(100 * time.Millisecond)
@@ -828,7 +837,7 @@ func TestTelegramChannel_Start_NilMessage(t *testing.T) {
 	ch.Start(ctx)
 
 	// Send update with nil message (should be ignored)
-	mockBot.updatesChan <- tgbotapi.Update{Message: nil}
+	mockBot.updatesChan <- TelegramUpdate{}
 
 	time.Sleep(50 * time.Millisecond)
 
@@ -934,7 +943,7 @@ type sendCountingBot struct {
 	callCount int
 }
 
-func (s *sendCountingBot) GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+func (s *sendCountingBot) GetUpdatesChan(config tgbotapi.UpdateConfig) <-chan TelegramUpdate {
 	return s.mockBot.updatesChan
 }
 
@@ -971,3 +980,465 @@ func TestTelegramChannel_Send_BothFail(t *testing.T) {
 		t.Error("expected error when both sends fail")
 	}
 }
+
+// flakyChannel fails its first N sends before succeeding
+type flakyChannel struct {
+	mockChannel
+	failures int
+	calls    int
+}
+
+func (f *flakyChannel) Send(msg bus.OutboundMessage) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return fmt.Errorf("send failed %d", f.calls)
+	}
+	return f.mockChannel.Send(msg)
+}
+
+func TestChannelManager_Deliver_RetriesThenSucceeds(t *testing.T) {
+	ch := &flakyChannel{mockChannel: mockChannel{name: "flaky"}, failures: 2}
+	m := &ChannelManager{
+		channels:     map[string]Channel{},
+		bus:          bus.NewMessageBus(10),
+		retryBackoff: func(int) time.Duration { return 0 },
+	}
+	var deadLettered bool
+	m.SetDeadLetterHandler(3, func(bus.OutboundMessage, error, int) { deadLettered = true })
+
+	m.deliver(ch, bus.OutboundMessage{Channel: "flaky", Content: "hi"})
+
+	if ch.calls != 3 {
+		t.Errorf("calls = %d, want 3", ch.calls)
+	}
+	if len(ch.sentMsgs) != 1 {
+		t.Errorf("sent = %d, want 1", len(ch.sentMsgs))
+	}
+	if deadLettered {
+		t.Error("message should not be dead-lettered")
+	}
+}
+
+func TestChannelManager_Deliver_DeadLetter(t *testing.T) {
+	ch := &flakyChannel{mockChannel: mockChannel{name: "flaky"}, failures: 10}
+	m := &ChannelManager{
+		channels:     map[string]Channel{},
+		bus:          bus.NewMessageBus(10),
+		retryBackoff: func(int) time.Duration { return 0 },
+	}
+
+	var gotMsg bus.OutboundMessage
+	var gotErr error
+	var gotAttempts int
+	m.SetDeadLetterHandler(2, func(msg bus.OutboundMessage, err error, attempts int) {
+		gotMsg, gotErr, gotAttempts = msg, err, attempts
+	})
+
+	m.deliver(ch, bus.OutboundMessage{Channel: "flaky", ChatID: "1", Content: "lost"})
+
+	if ch.calls != 2 {
+		t.Errorf("calls = %d, want 2", ch.calls)
+	}
+	if gotMsg.Content != "lost" {
+		t.Errorf("dead letter content = %q, want lost", gotMsg.Content)
+	}
+	if gotErr == nil || gotAttempts != 2 {
+		t.Errorf("dead letter err=%v attempts=%d", gotErr, gotAttempts)
+	}
+}
+
+func TestChannelManager_Deliver_NoHandler(t *testing.T) {
+	ch := &flakyChannel{mockChannel: mockChannel{name: "flaky"}, failures: 10}
+	m := &ChannelManager{channels: map[string]Channel{}, bus: bus.NewMessageBus(10)}
+
+	// Without a handler a single attempt is made and the failure is only logged
+	m.deliver(ch, bus.OutboundMessage{Channel: "flaky"})
+	if ch.calls != 1 {
+		t.Errorf("calls = %d, want 1", ch.calls)
+	}
+}
+
+// signalChannel reports each message it is sent on a channel.
+type signalChannel struct {
+	mockChannel
+	fail bool
+	sent chan bus.OutboundMessage
+}
+
+func (s *signalChannel) Send(msg bus.OutboundMessage) error {
+	s.sent <- msg
+	if s.fail {
+		return fmt.Errorf("down")
+	}
+	return nil
+}
+
+func TestChannelManager_RetriesDoNotBlockOtherChannels(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	down := &signalChannel{mockChannel: mockChannel{name: "down"}, fail: true, sent: make(chan bus.OutboundMessage, 10)}
+	up := &signalChannel{mockChannel: mockChannel{name: "up"}, sent: make(chan bus.OutboundMessage, 10)}
+	m := &ChannelManager{channels: map[string]Channel{}, bus: b, retryBackoff: func(int) time.Duration { return time.Hour }}
+	m.SetDeadLetterHandler(2, func(bus.OutboundMessage, error, int) {})
+	m.register(down)
+	m.register(up)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.DispatchOutbound(ctx)
+
+	b.Outbound <- bus.OutboundMessage{Channel: "down", Content: "first"}
+	<-down.sent // the first attempt failed; the retry waits an hour
+	b.Outbound <- bus.OutboundMessage{Channel: "down", Content: "second"}
+	b.Outbound <- bus.OutboundMessage{Channel: "up", Content: "hi"}
+	select {
+	case msg := <-up.sent:
+		if msg.Content != "hi" {
+			t.Errorf("up got %q", msg.Content)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a retrying channel held up the others")
+	}
+	select {
+	case msg := <-down.sent:
+		t.Errorf("down was sent %q before its first message was done", msg.Content)
+	default:
+	}
+}
+
+func TestChannelManager_OutboxFullAndFlush(t *testing.T) {
+	ch := &signalChannel{mockChannel: mockChannel{name: "slow"}, sent: make(chan bus.OutboundMessage)}
+	m := &ChannelManager{channels: map[string]Channel{}, bus: bus.NewMessageBus(10)}
+	var dead []error
+	m.SetDeadLetterHandler(1, func(_ bus.OutboundMessage, err error, _ int) { dead = append(dead, err) })
+	m.register(ch)
+
+	// An outbox as full as it gets, as if a drain were under way.
+	m.outboxes = map[string][]bus.OutboundMessage{"slow": make([]bus.OutboundMessage, maxOutbox)}
+	m.enqueue("slow", bus.OutboundMessage{Channel: "slow"})
+	if len(dead) != 1 || !errors.Is(dead[0], errOutboxFull) {
+		t.Fatalf("dead letters = %v, want one for the full outbox", dead)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.Flush(ctx); err == nil {
+		t.Error("Flush returned with messages queued")
+	}
+	go func() {
+		for range ch.sent {
+		}
+	}()
+	go m.drain("slow")
+	if err := m.Flush(context.Background()); err != nil {
+		t.Errorf("Flush: %v", err)
+	}
+	close(ch.sent)
+}
+
+func TestChannelManager_Status(t *testing.T) {
+	ch := &flakyChannel{mockChannel: mockChannel{name: "flaky"}, failures: 1}
+	broken := &mockChannel{name: "broken", startErr: fmt.Errorf("bad token")}
+	m := &ChannelManager{channels: map[string]Channel{}, bus: bus.NewMessageBus(10)}
+	m.register(ch)
+	m.register(broken)
+
+	if err := m.StartAll(context.Background()); err == nil {
+		t.Fatal("expected start error")
+	}
+	if s := m.Status()["broken"]; s.Running || s.Healthy() == nil {
+		t.Errorf("broken = %+v", s)
+	}
+	if s := m.Status()["flaky"]; !s.Running || s.Healthy() != nil {
+		t.Errorf("flaky after start = %+v", s)
+	}
+
+	m.deliver(ch, bus.OutboundMessage{Channel: "flaky"})
+	if err := m.Status()["flaky"].Healthy(); err == nil || !strings.Contains(err.Error(), "send failed 1") {
+		t.Errorf("after failed send: %v", err)
+	}
+	m.deliver(ch, bus.OutboundMessage{Channel: "flaky"})
+	if s := m.Status()["flaky"]; s.Healthy() != nil || s.LastSendAt.IsZero() {
+		t.Errorf("after successful send = %+v", s)
+	}
+
+	_ = m.Stop("flaky")
+	if s := m.Status()["flaky"]; s.Running || s.Healthy() != nil {
+		t.Errorf("a stopped channel is not running but not failing: %+v", s)
+	}
+}
+
+func TestChannelManager_Apply(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	slack := &mockChannel{name: slackChannelName}
+	m := &ChannelManager{bus: b}
+	m.register(slack)
+	m.settings = map[string]any{slackChannelName: config.SlackConfig{Enabled: true}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Slack is switched off and email on.
+	cfg := config.ChannelsConfig{Email: config.EmailConfig{
+		Enabled: true, IMAPHost: "127.0.0.1:1", SMTPHost: "127.0.0.1:1",
+		Username: "bot@example.com", Password: "secret", PollIntervalSeconds: 3600,
+		AllowFrom: []string{"me@example.com"},
+	}}
+	changed, err := m.Apply(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	slices.Sort(changed)
+	if !slices.Equal(changed, []string{emailChannelName, slackChannelName}) {
+		t.Errorf("changed = %v", changed)
+	}
+	if !slack.stopped {
+		t.Error("disabled channel should be stopped")
+	}
+	if _, ok := m.Channel(slackChannelName); ok {
+		t.Error("disabled channel still registered")
+	}
+	email, ok := m.Channel(emailChannelName)
+	if !ok || !m.Status()[emailChannelName].Running {
+		t.Fatalf("enabled channel not running: %+v", m.Status())
+	}
+
+	if changed, _ := m.Apply(ctx, cfg); len(changed) != 0 {
+		t.Errorf("unchanged config restarted %v", changed)
+	}
+
+	// Settings that fail leave the running channel alone.
+	cfg.Email.Password = ""
+	if _, err := m.Apply(ctx, cfg); err == nil {
+		t.Error("expected error for an email channel without a password")
+	}
+	if ch, _ := m.Channel(emailChannelName); ch != email {
+		t.Error("channel replaced by one that failed to build")
+	}
+	_ = m.StopAll()
+}
+
+func TestTelegramChannel_HandleCallback(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	mockBot := newMockBot()
+	ch, _ := NewTelegramChannel(config.TelegramConfig{Token: "fake-token"}, b)
+	ch.SetBot(mockBot)
+
+	ch.handleCallback(&tgbotapi.CallbackQuery{
+		ID:      "q1",
+		From:    &tgbotapi.User{ID: 123, UserName: "testuser"},
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 456}},
+		Data:    "/approve abcd1234",
+	}, 0)
+
+	if len(mockBot.sentMsgs) != 1 {
+		t.Errorf("expected the callback to be answered, got %d sends", len(mockBot.sentMsgs))
+	}
+	select {
+	case inbound := <-b.Inbound:
+		if inbound.Content != "/approve abcd1234" || inbound.SenderID != "123" || inbound.ChatID != "456" {
+			t.Errorf("inbound = %+v", inbound)
+		}
+	default:
+		t.Error("expected inbound message")
+	}
+
+	// A choice under a message is taken off once picked.
+	mockBot.sentMsgs = nil
+	keyboard := telegramKeyboard([]bus.Button{{Text: "BA 117 at 08:25 from LHR", Data: "BA 117"}, {Text: "VS 3 at 11:40 from LHR", Data: "VS 3"}})
+	if len(keyboard.InlineKeyboard) != 2 {
+		t.Errorf("long labels share a row: %#v", keyboard)
+	}
+	ch.handleCallback(&tgbotapi.CallbackQuery{
+		ID:      "q2",
+		From:    &tgbotapi.User{ID: 123, UserName: "testuser"},
+		Message: &tgbotapi.Message{MessageID: 9, Chat: &tgbotapi.Chat{ID: 456}, ReplyMarkup: &keyboard},
+		Data:    "VS 3",
+	}, 0)
+	if len(mockBot.sentMsgs) != 2 {
+		t.Fatalf("sent %d, want the answer and the edit", len(mockBot.sentMsgs))
+	}
+	if answer, ok := mockBot.sentMsgs[0].(tgbotapi.CallbackConfig); !ok || answer.Text != "✓ VS 3 at 11:40 from LHR" {
+		t.Errorf("answer = %#v", mockBot.sentMsgs[0])
+	}
+	if edit, ok := mockBot.sentMsgs[1].(tgbotapi.EditMessageReplyMarkupConfig); !ok || edit.MessageID != 9 || len(edit.ReplyMarkup.InlineKeyboard) != 0 {
+		t.Errorf("edit = %#v", mockBot.sentMsgs[1])
+	}
+	if inbound := <-b.Inbound; inbound.Content != "VS 3" {
+		t.Errorf("choice inbound = %+v", inbound)
+	}
+}
+
+func TestTelegramChannel_Groups(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	mockBot := newMockBot()
+	mockBot.self = tgbotapi.User{ID: 999, UserName: "testbot"}
+	ch, _ := NewTelegramChannel(config.TelegramConfig{Token: "fake-token"}, b)
+	ch.SetBot(mockBot)
+
+	group := &tgbotapi.Chat{ID: -100, Type: "supergroup"}
+	from := &tgbotapi.User{ID: 123}
+	for _, tc := range []struct {
+		name   string
+		msg    *tgbotapi.Message
+		thread int
+		chat   string
+		want   string // "" when the message is ignored
+	}{
+		{"chatter", &tgbotapi.Message{From: from, Chat: group, Text: "lunch?"}, 0, "", ""},
+		{"mention", &tgbotapi.Message{From: from, Chat: group, Text: "@TestBot what is 2+2?", Entities: []tgbotapi.MessageEntity{{Type: "mention", Offset: 0, Length: 8}}}, 0, "-100", "what is 2+2?"},
+		{"mention after emoji", &tgbotapi.Message{From: from, Chat: group, Text: "👋 @testbot hi", Entities: []tgbotapi.MessageEntity{{Type: "mention", Offset: 3, Length: 8}}}, 0, "-100", "👋 hi"},
+		{"other bot", &tgbotapi.Message{From: from, Chat: group, Text: "@otherbot hi", Entities: []tgbotapi.MessageEntity{{Type: "mention", Offset: 0, Length: 9}}}, 0, "", ""},
+		{"reply", &tgbotapi.Message{From: from, Chat: group, Text: "and 3+3?", ReplyToMessage: &tgbotapi.Message{MessageID: 5, From: &mockBot.self}}, 0, "-100", "and 3+3?"},
+		{"command", &tgbotapi.Message{From: from, Chat: group, Text: "/status@testbot", Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: 15}}}, 0, "-100", "/status"},
+		{"command for another bot", &tgbotapi.Message{From: from, Chat: group, Text: "/status@otherbot", Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: 16}}}, 0, "", ""},
+		{"topic started by the bot", &tgbotapi.Message{From: from, Chat: group, Text: "hello", ReplyToMessage: &tgbotapi.Message{MessageID: 42, From: &mockBot.self}}, 42, "", ""},
+		{"topic mention", &tgbotapi.Message{From: from, Chat: group, Text: "@testbot plan the trip", Entities: []tgbotapi.MessageEntity{{Type: "mention", Offset: 0, Length: 8}}}, 42, "-100:42", "plan the trip"},
+		{"private", &tgbotapi.Message{From: from, Chat: &tgbotapi.Chat{ID: 123, Type: "private"}, Text: "hi"}, 0, "123", "hi"},
+	} {
+		ch.handleMessage(tc.msg, tc.thread)
+		select {
+		case inbound := <-b.Inbound:
+			if inbound.Content != tc.want || inbound.ChatID != tc.chat {
+				t.Errorf("%s: got %q in %s, want %q in %s", tc.name, inbound.Content, inbound.ChatID, tc.want, tc.chat)
+			}
+		default:
+			if tc.want != "" {
+				t.Errorf("%s: ignored", tc.name)
+			}
+		}
+	}
+
+	ch.groups = config.TelegramGroupsAll
+	ch.handleMessage(&tgbotapi.Message{From: from, Chat: group, Text: "lunch?"}, 0)
+	if inbound := <-b.Inbound; inbound.Content != "lunch?" {
+		t.Errorf("groups all: %q", inbound.Content)
+	}
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: "-100:42", Content: "Done."}); err != nil {
+		t.Fatal(err)
+	}
+	sent := mockBot.sentMsgs[len(mockBot.sentMsgs)-1].(tgbotapi.MessageConfig)
+	if sent.ChatID != -100 || sent.ReplyToMessageID != 42 || !sent.AllowSendingWithoutReply {
+		t.Errorf("topic reply = %+v", sent.BaseChat)
+	}
+	if err := ch.Send(bus.OutboundMessage{ChatID: "-100:x", Content: "Done."}); err == nil {
+		t.Error("bad topic accepted")
+	}
+}
+
+func TestDecodeTelegramUpdates(t *testing.T) {
+	raw := `[
+		{"update_id": 1, "message": {"message_id": 7, "message_thread_id": 42, "is_topic_message": true, "chat": {"id": -100, "type": "supergroup"}, "text": "hi"}},
+		{"update_id": 2, "message": {"message_id": 8, "message_thread_id": 5, "chat": {"id": -100, "type": "supergroup"}, "text": "a reply thread"}},
+		{"update_id": 3, "callback_query": {"id": "q", "data": "Yes", "message": {"message_id": 9, "message_thread_id": 42, "is_topic_message": true, "chat": {"id": -100}}}}
+	]`
+	updates, err := decodeTelegramUpdates(json.RawMessage(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 3 || updates[0].ThreadID != 42 || updates[0].Message.Text != "hi" || updates[1].ThreadID != 0 || updates[2].ThreadID != 42 || updates[2].CallbackQuery.Data != "Yes" {
+		t.Errorf("updates = %+v", updates)
+	}
+}
+
+func TestTelegramChannel_Send_Buttons(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	mockBot := newMockBot()
+	ch, _ := NewTelegramChannel(config.TelegramConfig{Token: "fake-token"}, b)
+	ch.SetBot(mockBot)
+
+	err := ch.Send(bus.OutboundMessage{ChatID: "123", Content: "May I?", Buttons: []bus.Button{
+		{Text: "Approve", Data: "/approve x"},
+		{Text: "Deny", Data: "/deny x"},
+	}})
+	if err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(mockBot.sentMsgs) != 1 {
+		t.Fatalf("expected 1 sent message, got %d", len(mockBot.sentMsgs))
+	}
+	sent, ok := mockBot.sentMsgs[0].(tgbotapi.MessageConfig)
+	if !ok {
+		t.Fatalf("sent %T", mockBot.sentMsgs[0])
+	}
+	keyboard, ok := sent.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok || len(keyboard.InlineKeyboard) != 1 || len(keyboard.InlineKeyboard[0]) != 2 {
+		t.Fatalf("reply markup = %#v", sent.ReplyMarkup)
+	}
+	if data := keyboard.InlineKeyboard[0][1].CallbackData; data == nil || *data != "/deny x" {
+		t.Errorf("deny button data = %v", data)
+	}
+}
+
+// voiceFailBot fails voice notes and sends everything else.
+type voiceFailBot struct{ *mockTelegramBot }
+
+func (m voiceFailBot) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if _, ok := c.(tgbotapi.VoiceConfig); ok {
+		return tgbotapi.Message{}, fmt.Errorf("voice rejected")
+	}
+	return m.mockTelegramBot.Send(c)
+}
+
+func TestTelegramChannel_Send_Voice(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	mockBot := newMockBot()
+	ch, _ := NewTelegramChannel(config.TelegramConfig{Token: "fake-token"}, b)
+	ch.SetBot(mockBot)
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: "123", Content: "Sunny", Voice: []byte("OggS"), VoiceOnly: true}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(mockBot.sentMsgs) != 1 {
+		t.Fatalf("voice only sent %d messages", len(mockBot.sentMsgs))
+	}
+	if note, ok := mockBot.sentMsgs[0].(tgbotapi.VoiceConfig); !ok || note.ChatID != 123 {
+		t.Fatalf("sent %#v", mockBot.sentMsgs[0])
+	}
+
+	mockBot.sentMsgs = nil
+	if err := ch.Send(bus.OutboundMessage{ChatID: "123", Content: "Sunny", Voice: []byte("OggS")}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(mockBot.sentMsgs) != 2 {
+		t.Fatalf("text and voice sent %d messages", len(mockBot.sentMsgs))
+	}
+	if _, ok := mockBot.sentMsgs[0].(tgbotapi.MessageConfig); !ok {
+		t.Errorf("first sent %T, want the text", mockBot.sentMsgs[0])
+	}
+	if _, ok := mockBot.sentMsgs[1].(tgbotapi.VoiceConfig); !ok {
+		t.Errorf("second sent %T, want the voice note", mockBot.sentMsgs[1])
+	}
+
+	// A rejected voice note falls back to text.
+	mockBot.sentMsgs = nil
+	ch.SetBot(voiceFailBot{mockBot})
+	if err := ch.Send(bus.OutboundMessage{ChatID: "123", Content: "Sunny", Voice: []byte("OggS"), VoiceOnly: true}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(mockBot.sentMsgs) != 1 {
+		t.Fatalf("fallback sent %d messages", len(mockBot.sentMsgs))
+	}
+	if sent, ok := mockBot.sentMsgs[0].(tgbotapi.MessageConfig); !ok || sent.Text != "Sunny" {
+		t.Errorf("fallback sent %#v", mockBot.sentMsgs[0])
+	}
+}
+
+func TestTelegramChannel_Send_Media(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	mockBot := newMockBot()
+	ch, _ := NewTelegramChannel(config.TelegramConfig{Token: "fake-token"}, b)
+	ch.SetBot(mockBot)
+
+	err := ch.Send(bus.OutboundMessage{ChatID: "123", Content: "Here you go", Media: []string{"/ws/images/fox.png", "/ws/notes.txt"}})
+	if err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(mockBot.sentMsgs) != 3 {
+		t.Fatalf("sent %d messages, want 3", len(mockBot.sentMsgs))
+	}
+	if photo, ok := mockBot.sentMsgs[1].(tgbotapi.PhotoConfig); !ok || photo.File != tgbotapi.FilePath("/ws/images/fox.png") {
+		t.Errorf("second sent %#v, want the photo", mockBot.sentMsgs[1])
+	}
+	if _, ok := mockBot.sentMsgs[2].(tgbotapi.DocumentConfig); !ok {
+		t.Errorf("third sent %T, want a document", mockBot.sentMsgs[2])
+	}
+}
@@ -0,0 +1,595 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/mailbox"
+)
+
+const emailChannelName = "email"
+
+const (
+	emailDefaultMailbox      = "INBOX"
+	emailDefaultPollInterval = 60 * time.Second
+	emailMaxAttachmentSize   = 10 << 20
+	emailDefaultSubject      = "Message from myclaw"
+	// A thread not heard from for emailThreadTTL is forgotten; replies to it
+	// then thread on the root named in the chat ID.
+	emailThreadTTL = 7 * 24 * time.Hour
+)
+
+var (
+	emailQuoteHeaderRe = regexp.MustCompile(`(?i)^on .+ wrote:$`)
+	emailHTMLTagRe     = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// EmailClient interface for mailbox access (allows mocking)
+type EmailClient interface {
+	// FetchUnseen returns the raw RFC 5322 messages not yet seen and marks
+	// them seen.
+	FetchUnseen(ctx context.Context) ([][]byte, error)
+	Send(ctx context.Context, from string, to []string, msg []byte) error
+}
+
+type defaultEmailClient struct {
+	cfg config.EmailConfig
+}
+
+func (c *defaultEmailClient) FetchUnseen(ctx context.Context) ([][]byte, error) {
+	imap, err := mailbox.DialIMAP(ctx, c.cfg.IMAPHost, c.cfg.IMAPPort)
+	if err != nil {
+		return nil, err
+	}
+	defer imap.Logout(ctx)
+
+	box := c.cfg.Mailbox
+	if box == "" {
+		box = emailDefaultMailbox
+	}
+	if err := imap.Login(ctx, c.cfg.Username, c.cfg.Password); err != nil {
+		return nil, err
+	}
+	if err := imap.Select(ctx, box); err != nil {
+		return nil, err
+	}
+	uids, err := imap.Search(ctx, "UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+
+	var messages [][]byte
+	for _, uid := range uids {
+		raw, err := imap.Fetch(ctx, uid)
+		if err != nil {
+			return messages, err
+		}
+		if err := imap.MarkSeen(ctx, uid); err != nil {
+			return messages, err
+		}
+		messages = append(messages, raw)
+	}
+	return messages, nil
+}
+
+func (c *defaultEmailClient) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	smtp := mailbox.SMTPServer{Host: c.cfg.SMTPHost, Port: c.cfg.SMTPPort, Username: c.cfg.Username, Password: c.cfg.Password}
+	return smtp.Send(ctx, from, to, msg)
+}
+
+// EmailClientFactory creates EmailClient instances
+type EmailClientFactory func(cfg config.EmailConfig) EmailClient
+
+var defaultEmailClientFactory EmailClientFactory = func(cfg config.EmailConfig) EmailClient {
+	return &defaultEmailClient{cfg: cfg}
+}
+
+// emailThread remembers how to reply into a conversation.
+type emailThread struct {
+	to         string
+	subject    string
+	messageID  string // last message received in the thread
+	references string
+	expires    time.Time
+}
+
+// EmailChannel polls an IMAP mailbox and replies over SMTP. Each email
+// thread is one session per sender, with the chat ID
+// "alice@example.com <root-id@host>": who to answer and the Message-ID that
+// started the thread, so a reply can be addressed and threaded even when the
+// thread is no longer remembered.
+type EmailChannel struct {
+	BaseChannel
+	cfg           config.EmailConfig
+	client        EmailClient
+	clientFactory EmailClientFactory
+	cancel        context.CancelFunc
+
+	mu      sync.Mutex
+	threads map[string]emailThread // chat id -> reply info
+	lastGC  time.Time
+}
+
+func NewEmailChannel(cfg config.EmailConfig, b *bus.MessageBus) (*EmailChannel, error) {
+	return NewEmailChannelWithFactory(cfg, b, defaultEmailClientFactory)
+}
+
+func NewEmailChannelWithFactory(cfg config.EmailConfig, b *bus.MessageBus, factory EmailClientFactory) (*EmailChannel, error) {
+	if cfg.IMAPHost == "" || cfg.SMTPHost == "" {
+		return nil, fmt.Errorf("email imapHost and smtpHost are required")
+	}
+	if cfg.Username == "" || cfg.Password == "" {
+		return nil, fmt.Errorf("email username and password are required")
+	}
+	if len(cfg.AllowFrom) == 0 {
+		return nil, fmt.Errorf("email allowFrom is required: anyone can forge a From header")
+	}
+	if cfg.Address == "" {
+		cfg.Address = cfg.Username
+	}
+
+	// Addresses are case-insensitive; normalize so allowFrom and denyFrom match.
+	normalize := func(addrs []string) []string {
+		out := make([]string, len(addrs))
+		for i, addr := range addrs {
+			out[i] = strings.ToLower(strings.TrimSpace(addr))
+		}
+		return out
+	}
+
+	return &EmailChannel{
+		BaseChannel:   NewBaseChannelWithAccess(emailChannelName, b, Access{AllowFrom: normalize(cfg.AllowFrom), DenyFrom: normalize(cfg.DenyFrom)}),
+		cfg:           cfg,
+		clientFactory: factory,
+		threads:       make(map[string]emailThread),
+	}, nil
+}
+
+func (e *EmailChannel) Start(ctx context.Context) error {
+	e.client = e.clientFactory(e.cfg)
+	ctx, e.cancel = context.WithCancel(ctx)
+
+	interval := emailDefaultPollInterval
+	if e.cfg.PollIntervalSeconds > 0 {
+		interval = time.Duration(e.cfg.PollIntervalSeconds) * time.Second
+	}
+	go e.pollLoop(ctx, interval)
+
+	log.Printf("[email] polling %s every %s", e.cfg.IMAPHost, interval)
+	return nil
+}
+
+func (e *EmailChannel) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	log.Printf("[email] stopped")
+	return nil
+}
+
+func (e *EmailChannel) pollLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		e.poll(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *EmailChannel) poll(ctx context.Context) {
+	messages, err := e.client.FetchUnseen(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("[email] fetch error: %v", err)
+	}
+	for _, raw := range messages {
+		e.handleRaw(raw)
+	}
+}
+
+func (e *EmailChannel) handleRaw(raw []byte) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		log.Printf("[email] parse message error: %v", err)
+		return
+	}
+	h := msg.Header
+
+	from, err := mail.ParseAddress(h.Get("From"))
+	if err != nil {
+		log.Printf("[email] bad From header %q: %v", h.Get("From"), err)
+		return
+	}
+	sender := strings.ToLower(from.Address)
+	if strings.EqualFold(sender, e.cfg.Address) || isAutomatedEmail(h) {
+		return
+	}
+	if !e.IsAllowed(sender) {
+		// No canned reply: answering unknown senders confirms the address to spammers.
+		e.Reject("", sender)
+		return
+	}
+
+	text, blocks := parseEmailBody(h, msg.Body)
+	content := stripQuotedReply(text)
+	if content == "" && len(blocks) == 0 {
+		return
+	}
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(h.Get("Subject"))
+	if err != nil {
+		subject = h.Get("Subject")
+	}
+	messageID := strings.TrimSpace(h.Get("Message-Id"))
+	references := strings.TrimSpace(h.Get("References"))
+	chatID := sender
+	if root := emailThreadID(messageID, references, h.Get("In-Reply-To")); root != "" {
+		chatID = emailChatID(sender, root)
+	}
+
+	now := time.Now()
+	e.mu.Lock()
+	e.threads[chatID] = emailThread{
+		to:         from.Address,
+		subject:    subject,
+		messageID:  messageID,
+		references: strings.TrimSpace(references + " " + messageID),
+		expires:    now.Add(emailThreadTTL),
+	}
+	if now.Sub(e.lastGC) >= time.Hour {
+		for id, thread := range e.threads {
+			if now.After(thread.expires) {
+				delete(e.threads, id)
+			}
+		}
+		e.lastGC = now
+	}
+	e.mu.Unlock()
+
+	if subject != "" && !isReplySubject(subject) {
+		content = "Subject: " + subject + "\n\n" + content
+	}
+
+	e.bus.Inbound <- bus.InboundMessage{
+		Channel:       emailChannelName,
+		SenderID:      sender,
+		ChatID:        chatID,
+		Content:       content,
+		Timestamp:     time.Now(),
+		ContentBlocks: blocks,
+		Metadata: map[string]any{
+			"subject":    subject,
+			"message_id": messageID,
+			"from_name":  from.Name,
+		},
+	}
+}
+
+// Send replies within the thread the chat id names. A bare address starts a
+// new thread, but only to an address allowed to write in, so nothing is
+// mailed to a Message-ID or a stranger. Media paths are attached.
+func (e *EmailChannel) Send(msg bus.OutboundMessage) error {
+	if e.client == nil {
+		return fmt.Errorf("email client not initialized")
+	}
+
+	to, root := splitEmailChatID(msg.ChatID)
+	addr, err := mail.ParseAddress(to)
+	if err != nil || addr.Name != "" || !strings.EqualFold(addr.Address, to) {
+		return fmt.Errorf("email: chat id %q does not name a recipient", msg.ChatID)
+	}
+
+	if root != "" && !validEmailRoot(root) {
+		return fmt.Errorf("email: chat id %q does not name a Message-ID", msg.ChatID)
+	}
+
+	e.mu.Lock()
+	thread, ok := e.threads[msg.ChatID]
+	e.mu.Unlock()
+
+	switch {
+	case ok && time.Now().Before(thread.expires):
+	case root != "":
+		// A thread from before a restart: answer the message that started it.
+		thread = emailThread{to: to, subject: emailDefaultSubject, messageID: "<" + root + ">", references: "<" + root + ">"}
+	case e.IsAllowed(strings.ToLower(to)):
+		thread = emailThread{to: to, subject: emailDefaultSubject}
+	default:
+		return fmt.Errorf("email: %s is not in allowFrom", to)
+	}
+	if subject, ok := msg.Metadata["subject"].(string); ok && subject != "" && thread.messageID == "" {
+		thread.subject = subject
+	}
+	if thread.messageID != "" && !isReplySubject(thread.subject) {
+		thread.subject = "Re: " + thread.subject
+	}
+
+	raw, err := buildEmail(e.cfg.Address, thread, msg.Content, msg.Media)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return e.client.Send(ctx, e.cfg.Address, []string{thread.to}, raw)
+}
+
+// emailChatID names the session for sender in the thread started by root.
+func emailChatID(sender, root string) string {
+	return sender + " <" + root + ">"
+}
+
+// splitEmailChatID returns the address and thread root of a chat ID made by
+// emailChatID. A chat ID without a root is a bare address.
+func splitEmailChatID(chatID string) (to, root string) {
+	if i := strings.Index(chatID, " <"); i > 0 && strings.HasSuffix(chatID, ">") {
+		return chatID[:i], chatID[i+2 : len(chatID)-1]
+	}
+	return chatID, ""
+}
+
+// validEmailRoot reports whether root can go into In-Reply-To and
+// References as <root>: it comes from a chat ID, which the agent can name.
+func validEmailRoot(root string) bool {
+	return !strings.ContainsAny(root, "<>\r\n\x00") && strings.IndexFunc(root, unicode.IsSpace) < 0
+}
+
+// emailThreadID picks the first Message-ID of the conversation, so every
+// reply in a thread maps to the same session.
+func emailThreadID(messageID, references, inReplyTo string) string {
+	id := messageID
+	if refs := strings.Fields(references); len(refs) > 0 {
+		id = refs[0]
+	} else if inReplyTo = strings.TrimSpace(inReplyTo); inReplyTo != "" {
+		id = strings.Fields(inReplyTo)[0]
+	}
+	return strings.Trim(id, "<>")
+}
+
+func isReplySubject(subject string) bool {
+	lower := strings.ToLower(subject)
+	return strings.HasPrefix(lower, "re:") || strings.HasPrefix(lower, "aw:")
+}
+
+// isAutomatedEmail reports bounces, vacation replies and list mail, which
+// must never get an answer.
+func isAutomatedEmail(h mail.Header) bool {
+	if auto := strings.ToLower(h.Get("Auto-Submitted")); auto != "" && auto != "no" {
+		return true
+	}
+	switch strings.ToLower(h.Get("Precedence")) {
+	case "bulk", "list", "junk", "auto_reply":
+		return true
+	}
+	return h.Get("List-Id") != ""
+}
+
+// parseEmailBody returns the plain-text body and attachments as content
+// blocks. Text attachments are inlined into the returned text.
+func parseEmailBody(h mail.Header, body io.Reader) (string, []model.ContentBlock) {
+	var plain, html strings.Builder
+	var blocks []model.ContentBlock
+	var inline []string
+
+	var walk func(h mail.Header, body io.Reader)
+	walk = func(h mail.Header, body io.Reader) {
+		mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+		if err != nil {
+			mediaType = "text/plain"
+		}
+		if strings.HasPrefix(mediaType, "multipart/") {
+			mr := multipart.NewReader(body, params["boundary"])
+			for {
+				part, err := mr.NextRawPart()
+				if err != nil {
+					return
+				}
+				walk(mail.Header(part.Header), part)
+			}
+		}
+
+		data, err := io.ReadAll(io.LimitReader(decodeTransfer(h.Get("Content-Transfer-Encoding"), body), emailMaxAttachmentSize+1))
+		if err != nil || len(data) > emailMaxAttachmentSize {
+			return
+		}
+
+		disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+		filename := dparams["filename"]
+		if filename == "" {
+			filename = params["name"]
+		}
+		if decoded, err := new(mime.WordDecoder).DecodeHeader(filename); err == nil {
+			filename = decoded
+		}
+		isAttachment := disposition == "attachment" || filename != ""
+
+		switch {
+		case mediaType == "text/plain" && !isAttachment:
+			plain.Write(data)
+		case mediaType == "text/html" && !isAttachment:
+			html.Write(data)
+		case strings.HasPrefix(mediaType, "image/"):
+			blocks = append(blocks, model.ContentBlock{
+				Type:      model.ContentBlockImage,
+				MediaType: mediaType,
+				Data:      base64.StdEncoding.EncodeToString(data),
+			})
+		case mediaType == "application/pdf":
+			blocks = append(blocks, model.ContentBlock{
+				Type:      model.ContentBlockDocument,
+				MediaType: mediaType,
+				Data:      base64.StdEncoding.EncodeToString(data),
+			})
+		case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json":
+			inline = append(inline, fmt.Sprintf("Attachment %s:\n```\n%s\n```", filename, strings.TrimSpace(string(data))))
+		default:
+			inline = append(inline, fmt.Sprintf("[Attachment %s (%s) not readable]", filename, mediaType))
+		}
+	}
+	walk(h, body)
+
+	text := plain.String()
+	if strings.TrimSpace(text) == "" && html.Len() > 0 {
+		text = htmlToText(html.String())
+	}
+	if len(inline) > 0 {
+		text = strings.TrimSpace(text) + "\n\n" + strings.Join(inline, "\n\n")
+	}
+	return text, blocks
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineSkipper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// newlineSkipper drops CR/LF so base64 bodies wrapped at 76 columns decode.
+type newlineSkipper struct{ r io.Reader }
+
+func (n *newlineSkipper) Read(p []byte) (int, error) {
+	for {
+		c, err := n.r.Read(p)
+		j := 0
+		for _, b := range p[:c] {
+			if b != '\r' && b != '\n' {
+				p[j] = b
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
+
+func htmlToText(s string) string {
+	s = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n\n", "</div>", "\n").Replace(s)
+	s = emailHTMLTagRe.ReplaceAllString(s, "")
+	return strings.NewReplacer("&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#39;", "'").Replace(s)
+}
+
+// stripQuotedReply drops the quoted history mail clients append to replies;
+// the session already has it.
+func stripQuotedReply(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var kept []string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		if emailQuoteHeaderRe.MatchString(trimmed) && i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), ">") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+func buildEmail(from string, thread emailThread, body string, attachments []string) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+
+	header("From", from)
+	header("To", thread.to)
+	header("Subject", mime.QEncoding.Encode("utf-8", thread.subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", mailbox.NewMessageID(from))
+	if thread.messageID != "" {
+		header("In-Reply-To", thread.messageID)
+	}
+	if thread.references != "" {
+		header("References", thread.references)
+	}
+	header("MIME-Version", "1.0")
+	header("Auto-Submitted", "auto-replied")
+
+	if len(attachments) == 0 {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	buf.WriteString("\r\n")
+
+	part, err := mw.CreatePart(map[string][]string{
+		"Content-Type":              {`text/plain; charset="utf-8"`},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintable(part, body); err != nil {
+		return nil, err
+	}
+
+	for _, path := range attachments {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read attachment: %w", err)
+		}
+		name := filepath.Base(path)
+		ctype := mime.TypeByExtension(filepath.Ext(name))
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		part, err := mw.CreatePart(map[string][]string{
+			"Content-Type":              {ctype},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(data)
+		for len(encoded) > 76 {
+			io.WriteString(part, encoded[:76]+"\r\n")
+			encoded = encoded[76:]
+		}
+		io.WriteString(part, encoded+"\r\n")
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, strings.ReplaceAll(body, "\n", "\r\n")); err != nil {
+		return err
+	}
+	return qp.Close()
+}
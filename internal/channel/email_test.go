@@ -0,0 +1,264 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+type emailSend struct {
+	from string
+	to   []string
+	msg  []byte
+}
+
+type mockEmailClient struct {
+	inbox [][]byte
+	sent  []emailSend
+}
+
+func (m *mockEmailClient) FetchUnseen(ctx context.Context) ([][]byte, error) {
+	msgs := m.inbox
+	m.inbox = nil
+	return msgs, nil
+}
+
+func (m *mockEmailClient) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	m.sent = append(m.sent, emailSend{from, to, msg})
+	return nil
+}
+
+func newTestEmailChannel(t *testing.T, cfg config.EmailConfig, client *mockEmailClient) (*EmailChannel, *bus.MessageBus) {
+	t.Helper()
+	cfg.IMAPHost = "imap.example.com"
+	cfg.SMTPHost = "smtp.example.com"
+	cfg.Username = "bot@example.com"
+	cfg.Password = "pw"
+	if len(cfg.AllowFrom) == 0 {
+		cfg.AllowFrom = []string{"ann@example.com"}
+	}
+	b := bus.NewMessageBus(10)
+	ch, err := NewEmailChannelWithFactory(cfg, b, func(config.EmailConfig) EmailClient { return client })
+	if err != nil {
+		t.Fatalf("NewEmailChannelWithFactory error: %v", err)
+	}
+	ch.client = client
+	return ch, b
+}
+
+func rawEmail(headers, body string) []byte {
+	return []byte(strings.ReplaceAll(headers, "\n", "\r\n") + "\r\n" + body)
+}
+
+func TestNewEmailChannel_Validation(t *testing.T) {
+	b := bus.NewMessageBus(1)
+	if _, err := NewEmailChannel(config.EmailConfig{Username: "a", Password: "b"}, b); err == nil {
+		t.Error("expected error without hosts")
+	}
+	if _, err := NewEmailChannel(config.EmailConfig{IMAPHost: "i", SMTPHost: "s"}, b); err == nil {
+		t.Error("expected error without credentials")
+	}
+	if _, err := NewEmailChannel(config.EmailConfig{IMAPHost: "i", SMTPHost: "s", Username: "bot@x", Password: "p"}, b); err == nil {
+		t.Error("expected error without allowFrom")
+	}
+	ch, err := NewEmailChannel(config.EmailConfig{IMAPHost: "i", SMTPHost: "s", Username: "bot@x", Password: "p", AllowFrom: []string{"me@x"}}, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ch.Name() != "email" || ch.cfg.Address != "bot@x" {
+		t.Errorf("name = %q, address = %q", ch.Name(), ch.cfg.Address)
+	}
+}
+
+func TestEmailChannel_ThreadIsSession(t *testing.T) {
+	client := &mockEmailClient{inbox: [][]byte{
+		rawEmail("From: Ann <Ann@Example.com>\nTo: bot@example.com\nSubject: Trip ideas\nMessage-ID: <m1@example.com>\n", "Where should we go?\r\n"),
+		rawEmail("From: ann@example.com\nSubject: Re: Trip ideas\nMessage-ID: <m3@example.com>\nIn-Reply-To: <m2@example.com>\nReferences: <m1@example.com> <m2@example.com>\n",
+			"Somewhere warm.\r\n\r\nOn Mon, Jan 1, 2024 bot wrote:\r\n> Lisbon?\r\n"),
+	}}
+	ch, b := newTestEmailChannel(t, config.EmailConfig{AllowFrom: []string{"ANN@example.com"}}, client)
+	ch.poll(context.Background())
+
+	first, second := <-b.Inbound, <-b.Inbound
+	if first.ChatID != "ann@example.com <m1@example.com>" || second.ChatID != first.ChatID {
+		t.Errorf("chat ids = %q, %q; want the sender and thread root", first.ChatID, second.ChatID)
+	}
+	if first.SenderID != "ann@example.com" {
+		t.Errorf("sender = %q", first.SenderID)
+	}
+	if first.Content != "Subject: Trip ideas\n\nWhere should we go?" {
+		t.Errorf("first content = %q", first.Content)
+	}
+	if second.Content != "Somewhere warm." {
+		t.Errorf("quoted reply not stripped: %q", second.Content)
+	}
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: first.ChatID, Content: "Try Seville."}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	sent, err := mail.ReadMessage(bytes.NewReader(client.sent[0].msg))
+	if err != nil {
+		t.Fatalf("parse sent message: %v", err)
+	}
+	if client.sent[0].to[0] != "ann@example.com" {
+		t.Errorf("to = %v", client.sent[0].to)
+	}
+	if got := sent.Header.Get("In-Reply-To"); got != "<m3@example.com>" {
+		t.Errorf("In-Reply-To = %q", got)
+	}
+	if got := sent.Header.Get("References"); got != "<m1@example.com> <m2@example.com> <m3@example.com>" {
+		t.Errorf("References = %q", got)
+	}
+	if got := sent.Header.Get("Subject"); got != "Re: Trip ideas" {
+		t.Errorf("Subject = %q", got)
+	}
+	body, _ := io.ReadAll(sent.Body)
+	if !strings.Contains(string(body), "Try Seville.") {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestEmailChannel_SkipsUnwanted(t *testing.T) {
+	client := &mockEmailClient{inbox: [][]byte{
+		rawEmail("From: stranger@example.com\nSubject: hi\nMessage-ID: <a@x>\n", "hello"),
+		rawEmail("From: ann@example.com\nSubject: Out of office\nAuto-Submitted: auto-replied\nMessage-ID: <b@x>\n", "away"),
+		rawEmail("From: bot@example.com\nSubject: loop\nMessage-ID: <c@x>\n", "me"),
+		rawEmail("From: ann@example.com\nSubject: news\nList-Id: <news.example.com>\nMessage-ID: <d@x>\n", "digest"),
+	}}
+	ch, b := newTestEmailChannel(t, config.EmailConfig{AllowFrom: []string{"ann@example.com"}}, client)
+	ch.poll(context.Background())
+
+	select {
+	case msg := <-b.Inbound:
+		t.Errorf("unexpected inbound: %+v", msg)
+	default:
+	}
+}
+
+func TestEmailChannel_Attachments(t *testing.T) {
+	body := "--XX\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n" +
+		"Caf=C3=A9 receipts attached\r\n" +
+		"--XX\r\n" +
+		"Content-Type: image/png\r\nContent-Disposition: attachment; filename=\"scan.png\"\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+		"iVBORw0K\r\nGgo=\r\n" +
+		"--XX\r\n" +
+		"Content-Type: text/csv; name=\"totals.csv\"\r\nContent-Disposition: attachment; filename=\"totals.csv\"\r\n\r\n" +
+		"item,amount\r\ncoffee,3\r\n" +
+		"--XX\r\n" +
+		"Content-Type: application/zip\r\nContent-Disposition: attachment; filename=\"x.zip\"\r\n\r\n" +
+		"PK\r\n" +
+		"--XX--\r\n"
+	client := &mockEmailClient{inbox: [][]byte{
+		rawEmail("From: ann@example.com\nSubject: Re: expenses\nMessage-ID: <e@x>\nContent-Type: multipart/mixed; boundary=XX\n", body),
+	}}
+	ch, b := newTestEmailChannel(t, config.EmailConfig{}, client)
+	ch.poll(context.Background())
+
+	msg := <-b.Inbound
+	if !strings.HasPrefix(msg.Content, "Café receipts attached") {
+		t.Errorf("content = %q", msg.Content)
+	}
+	if !strings.Contains(msg.Content, "Attachment totals.csv:") || !strings.Contains(msg.Content, "coffee,3") {
+		t.Errorf("text attachment not inlined: %q", msg.Content)
+	}
+	if !strings.Contains(msg.Content, "x.zip (application/zip) not readable") {
+		t.Errorf("unreadable attachment not noted: %q", msg.Content)
+	}
+	if len(msg.ContentBlocks) != 1 || msg.ContentBlocks[0].Type != model.ContentBlockImage || msg.ContentBlocks[0].Data != "iVBORw0KGgo=" {
+		t.Errorf("blocks = %+v", msg.ContentBlocks)
+	}
+}
+
+func TestEmailChannel_HTMLOnly(t *testing.T) {
+	client := &mockEmailClient{inbox: [][]byte{
+		rawEmail("From: ann@example.com\nSubject: Re: x\nMessage-ID: <h@x>\nContent-Type: text/html\n", "<p>Hello&nbsp;<b>there</b></p>"),
+	}}
+	ch, b := newTestEmailChannel(t, config.EmailConfig{}, client)
+	ch.poll(context.Background())
+	if msg := <-b.Inbound; msg.Content != "Hello there" {
+		t.Errorf("content = %q", msg.Content)
+	}
+}
+
+func TestEmailChannel_SendAfterRestart(t *testing.T) {
+	client := &mockEmailClient{}
+	ch, _ := newTestEmailChannel(t, config.EmailConfig{AllowFrom: []string{"ann@example.com"}}, client)
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: "ann@example.com <m1@example.com>", Content: "Still there?"}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	sent, err := mail.ReadMessage(bytes.NewReader(client.sent[0].msg))
+	if err != nil {
+		t.Fatalf("parse sent message: %v", err)
+	}
+	if client.sent[0].to[0] != "ann@example.com" || sent.Header.Get("In-Reply-To") != "<m1@example.com>" || sent.Header.Get("References") != "<m1@example.com>" {
+		t.Errorf("to = %v, headers = %v", client.sent[0].to, sent.Header)
+	}
+
+	// A bare Message-ID looks like an address but is nobody to write to,
+	// and a root must not carry more headers.
+	for _, chatID := range []string{
+		"m1@example.com", "Ann <m1@example.com>", "<m1@example.com>",
+		"ann@example.com <m1@example.com\r\nBcc: eve@example.com>",
+		"ann@example.com <m1@example.com> <m2@example.com>",
+	} {
+		if err := ch.Send(bus.OutboundMessage{ChatID: chatID, Content: "x"}); err == nil {
+			t.Errorf("Send to %q should fail", chatID)
+		}
+	}
+	if len(client.sent) != 1 {
+		t.Errorf("sent %d messages, want 1", len(client.sent))
+	}
+
+	// A remembered thread that has expired threads on its root.
+	ch.threads["ann@example.com <m1@example.com>"] = emailThread{to: "ann@example.com", subject: "Old", messageID: "<m9@example.com>", expires: time.Now().Add(-time.Minute)}
+	if err := ch.Send(bus.OutboundMessage{ChatID: "ann@example.com <m1@example.com>", Content: "Again"}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if sent, _ := mail.ReadMessage(bytes.NewReader(client.sent[1].msg)); sent.Header.Get("In-Reply-To") != "<m1@example.com>" {
+		t.Errorf("headers = %v", sent.Header)
+	}
+}
+
+func TestEmailChannel_SendNewThreadWithAttachment(t *testing.T) {
+	client := &mockEmailClient{}
+	ch, _ := newTestEmailChannel(t, config.EmailConfig{}, client)
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: "no-such-thread", Content: "x"}); err == nil {
+		t.Error("expected error for unknown thread")
+	}
+
+	path := filepath.Join(t.TempDir(), "report.txt")
+	os.WriteFile(path, []byte("report body"), 0644)
+	err := ch.Send(bus.OutboundMessage{
+		ChatID:   "ann@example.com",
+		Content:  "Weekly report",
+		Media:    []string{path},
+		Metadata: map[string]any{"subject": "Your report"},
+	})
+	if err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	sent, err := mail.ReadMessage(bytes.NewReader(client.sent[0].msg))
+	if err != nil {
+		t.Fatalf("parse sent message: %v", err)
+	}
+	if sent.Header.Get("Subject") != "Your report" || sent.Header.Get("In-Reply-To") != "" {
+		t.Errorf("headers = %v", sent.Header)
+	}
+	text, blocks := parseEmailBody(sent.Header, sent.Body)
+	if !strings.Contains(text, "Weekly report") || !strings.Contains(text, "report body") || len(blocks) != 0 {
+		t.Errorf("round trip text = %q blocks = %d", text, len(blocks))
+	}
+}
@@ -23,13 +23,24 @@ const feishuChannelName = "feishu"
 const (
 	feishuInboundImageMaxBytes = 10 << 20 // 10MB
 	feishuInboundImageTimeout  = 10 * time.Second
+	feishuInboundFileMaxBytes  = 20 << 20
+	feishuInboundFileTimeout   = 30 * time.Second
+	// feishuCardChunkLimit keeps each card well under Feishu's 30 KB card
+	// size limit, even for CJK text.
+	feishuCardChunkLimit = 8000
 )
 
 type FeishuImageDownloader func(ctx context.Context, tenantAccessToken, imageKey string) (string, string, error)
 
+// FeishuFileDownloader fetches the audio or file of a received message and
+// returns its bytes and media type.
+type FeishuFileDownloader func(ctx context.Context, tenantAccessToken, messageID, fileKey string) ([]byte, string, error)
+
 // FeishuClient interface for sending messages (allows mocking)
 type FeishuClient interface {
 	SendMessage(ctx context.Context, chatID, content string) error
+	// SendCard sends an interactive card, as built by feishuCard.
+	SendCard(ctx context.Context, chatID string, card map[string]any) error
 	GetTenantAccessToken(ctx context.Context) (string, error)
 }
 
@@ -93,21 +104,32 @@ func (c *defaultFeishuClient) GetTenantAccessToken(ctx context.Context) (string,
 }
 
 func (c *defaultFeishuClient) SendMessage(ctx context.Context, chatID, content string) error {
-	token, err := c.GetTenantAccessToken(ctx)
-	if err != nil {
-		return err
-	}
-
 	// Use json.Marshal for proper escaping of content
 	textJSON, err := json.Marshal(map[string]string{"text": content})
 	if err != nil {
 		return fmt.Errorf("marshal text content: %w", err)
 	}
+	return c.send(ctx, chatID, "text", string(textJSON))
+}
+
+func (c *defaultFeishuClient) SendCard(ctx context.Context, chatID string, card map[string]any) error {
+	cardJSON, err := json.Marshal(card)
+	if err != nil {
+		return fmt.Errorf("marshal card content: %w", err)
+	}
+	return c.send(ctx, chatID, "interactive", string(cardJSON))
+}
+
+func (c *defaultFeishuClient) send(ctx context.Context, chatID, msgType, content string) error {
+	token, err := c.GetTenantAccessToken(ctx)
+	if err != nil {
+		return err
+	}
 
 	payload := map[string]interface{}{
 		"receive_id": chatID,
-		"msg_type":   "text",
-		"content":    string(textJSON),
+		"msg_type":   msgType,
+		"content":    content,
 	}
 	data, err := json.Marshal(payload)
 	if err != nil {
@@ -157,6 +179,11 @@ type FeishuChannel struct {
 	cancel          context.CancelFunc
 	clientFactory   FeishuClientFactory
 	imageDownloader FeishuImageDownloader
+	fileDownloader  FeishuFileDownloader
+
+	cardsMu   sync.Mutex
+	cards     map[string]map[string]any
+	cardOrder []string
 }
 
 func NewFeishuChannel(cfg config.FeishuConfig, b *bus.MessageBus) (*FeishuChannel, error) {
@@ -169,10 +196,11 @@ func NewFeishuChannelWithFactory(cfg config.FeishuConfig, b *bus.MessageBus, fac
 	}
 
 	ch := &FeishuChannel{
-		BaseChannel:     NewBaseChannel(feishuChannelName, b, cfg.AllowFrom),
+		BaseChannel:     NewBaseChannelWithAccess(feishuChannelName, b, Access{AllowFrom: cfg.AllowFrom, DenyFrom: cfg.DenyFrom, DeniedReply: cfg.DeniedReply}),
 		cfg:             cfg,
 		clientFactory:   factory,
 		imageDownloader: downloadFeishuImageAsBase64,
+		fileDownloader:  downloadFeishuMessageFile,
 	}
 	return ch, nil
 }
@@ -221,11 +249,32 @@ func (f *FeishuChannel) Stop() error {
 	return nil
 }
 
+// Send posts replies as interactive cards, split to fit the card size
+// limit, with any buttons under the last. A card Feishu refuses is resent
+// as text.
 func (f *FeishuChannel) Send(msg bus.OutboundMessage) error {
 	if f.client == nil {
 		return fmt.Errorf("feishu client not initialized")
 	}
-	return f.client.SendMessage(context.Background(), msg.ChatID, msg.Content)
+	ctx := context.Background()
+	chunks := splitMessage(msg.Content, feishuCardChunkLimit, runeCount)
+	for i, chunk := range chunks {
+		var card map[string]any
+		if i == len(chunks)-1 && len(msg.Buttons) > 0 {
+			id := newFeishuCardID()
+			card = feishuCard(chunk, msg.Buttons, id)
+			f.rememberCard(id, feishuCard(chunk, nil, ""))
+		} else {
+			card = feishuCard(chunk, nil, "")
+		}
+		if err := f.client.SendCard(ctx, msg.ChatID, card); err != nil {
+			log.Printf("[feishu] card send failed, sending text: %v", err)
+			if err := f.client.SendMessage(ctx, msg.ChatID, chunk); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func (f *FeishuChannel) handleWebhook(w http.ResponseWriter, r *http.Request) {
@@ -248,12 +297,29 @@ func (f *FeishuChannel) handleWebhook(w http.ResponseWriter, r *http.Request) {
 			Token     string `json:"token"`
 		} `json:"header"`
 		Event struct {
+			// Operator, Action and Context are set on card.action.trigger.
+			Operator struct {
+				OpenID string `json:"open_id"`
+			} `json:"operator"`
+			Action struct {
+				Value struct {
+					Data string `json:"data"`
+					Text string `json:"text"`
+					Card string `json:"card"`
+				} `json:"value"`
+			} `json:"action"`
+			Context struct {
+				OpenChatID    string `json:"open_chat_id"`
+				OpenMessageID string `json:"open_message_id"`
+			} `json:"context"`
+
 			Sender struct {
 				SenderID struct {
 					OpenID string `json:"open_id"`
 				} `json:"sender_id"`
 			} `json:"sender"`
 			Message struct {
+				MessageID   string `json:"message_id"`
 				ChatID      string `json:"chat_id"`
 				MessageType string `json:"message_type"`
 				Content     string `json:"content"`
@@ -279,6 +345,13 @@ func (f *FeishuChannel) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if event.Header.EventType == "card.action.trigger" {
+		senderID, chatID := event.Event.Operator.OpenID, event.Event.Context.OpenChatID
+		value := event.Event.Action.Value
+		f.handleCardAction(w, senderID, chatID, event.Event.Context.OpenMessageID, value.Data, value.Text, value.Card)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 
 	// Only handle message events
@@ -287,8 +360,8 @@ func (f *FeishuChannel) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	senderID := event.Event.Sender.SenderID.OpenID
-	if !f.IsAllowed(senderID) {
-		log.Printf("[feishu] rejected message from %s", senderID)
+	if !f.IsAllowed(senderID, event.Event.Message.ChatID) {
+		f.Reject(event.Event.Message.ChatID, senderID)
 		return
 	}
 
@@ -302,7 +375,8 @@ func (f *FeishuChannel) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[feishu] parse message error: %v", err)
 		return
 	}
-	if content == "" && len(contentBlocks) == 0 {
+	attachments := f.feishuAttachments(context.Background(), event.Event.Message.MessageID, messageType, event.Event.Message.Content)
+	if content == "" && len(contentBlocks) == 0 && len(attachments) == 0 {
 		return
 	}
 
@@ -318,10 +392,100 @@ func (f *FeishuChannel) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		Content:       content,
 		Timestamp:     time.Now(),
 		ContentBlocks: contentBlocks,
+		Attachments:   attachments,
 		Metadata:      metadata,
 	}
 }
 
+// feishuAttachments downloads the voice message or file of an "audio" or
+// "file" message for the gateway to read. Failures are logged.
+func (f *FeishuChannel) feishuAttachments(ctx context.Context, messageID, messageType, rawContent string) []bus.Attachment {
+	if messageType != "audio" && messageType != "file" {
+		return nil
+	}
+	var file struct {
+		FileKey  string `json:"file_key"`
+		FileName string `json:"file_name"`
+	}
+	if err := json.Unmarshal([]byte(rawContent), &file); err != nil || file.FileKey == "" || messageID == "" || f.client == nil {
+		log.Printf("[feishu] %s message without a file to download", messageType)
+		return nil
+	}
+	token, err := f.client.GetTenantAccessToken(ctx)
+	if err != nil {
+		log.Printf("[feishu] get tenant access token: %v", err)
+		return nil
+	}
+	data, mediaType, err := f.fileDownloader(ctx, token, messageID, file.FileKey)
+	if err != nil {
+		log.Printf("[feishu] download %s failed: %v", messageType, err)
+		return nil
+	}
+	if messageType == "audio" {
+		// Voice messages are Opus in an Ogg container, served untyped.
+		mediaType = "audio/ogg"
+	}
+	return []bus.Attachment{{Name: file.FileName, MediaType: mediaType, Data: data}}
+}
+
+// downloadFeishuMessageFile fetches a message resource through
+// /im/v1/messages/:message_id/resources/:file_key.
+func downloadFeishuMessageFile(ctx context.Context, tenantAccessToken, messageID, fileKey string) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, feishuInboundFileTimeout)
+	defer cancel()
+	endpoint := fmt.Sprintf("https://open.feishu.cn/open-apis/im/v1/messages/%s/resources/%s?type=file", url.PathEscape(messageID), url.PathEscape(fileKey))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("create file request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tenantAccessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("request file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("file request failed with status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, feishuInboundFileMaxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("read file response: %w", err)
+	}
+	if len(data) > feishuInboundFileMaxBytes {
+		return nil, "", fmt.Errorf("file exceeds %d bytes", feishuInboundFileMaxBytes)
+	}
+	return data, normalizeFeishuMediaType(resp.Header.Get("Content-Type")), nil
+}
+
+// handleCardAction passes a pressed card button on as the presser's next
+// message, and answers Feishu with a toast naming the choice and, when the
+// card is remembered, the card again without its buttons.
+func (f *FeishuChannel) handleCardAction(w http.ResponseWriter, senderID, chatID, messageID, data, text, card string) {
+	w.Header().Set("Content-Type", "application/json")
+	if data == "" || chatID == "" || !f.IsAllowed(senderID, chatID) {
+		w.Write([]byte("{}"))
+		return
+	}
+	if text == "" {
+		text = data
+	}
+	resp := map[string]any{"toast": map[string]any{"type": "success", "content": "✓ " + text}}
+	if answered := f.takeCard(card); answered != nil {
+		resp["card"] = map[string]any{"type": "raw", "data": answered}
+	}
+	json.NewEncoder(w).Encode(resp)
+
+	f.bus.Inbound <- bus.InboundMessage{
+		Channel:   feishuChannelName,
+		SenderID:  senderID,
+		ChatID:    chatID,
+		Content:   data,
+		Timestamp: time.Now(),
+		Metadata:  map[string]any{"message_type": "card_action", "message_id": messageID},
+	}
+}
+
 func (f *FeishuChannel) parseFeishuInboundMessage(ctx context.Context, messageType, rawContent string) (string, []model.ContentBlock, map[string]any, error) {
 	if messageType == "" {
 		return "", nil, nil, nil
@@ -0,0 +1,164 @@
+package channel
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+)
+
+const (
+	// feishuFoldLines is how long a code block gets before it is folded
+	// into a collapsible panel.
+	feishuFoldLines = 15
+	// feishuCardsKept is how many cards with buttons are remembered, to
+	// take their buttons off once one is pressed.
+	feishuCardsKept = 200
+)
+
+var feishuSummaryRe = regexp.MustCompile(`(?i)<summary>(.*?)</summary>`)
+
+// feishuCard builds a schema 2.0 interactive card from markdown. Code blocks
+// longer than feishuFoldLines and <details> sections become collapsible
+// panels, and buttons go under the text. Each button carries card so a
+// press can be matched to the card it came from.
+func feishuCard(markdown string, buttons []bus.Button, card string) map[string]any {
+	elements := feishuCardElements(markdown)
+	if len(buttons) > 0 {
+		elements = append(elements, feishuButtons(buttons, card))
+	}
+	return map[string]any{
+		"schema": "2.0",
+		"config": map[string]any{"update_multi": true, "width_mode": "fill"},
+		"body":   map[string]any{"elements": elements},
+	}
+}
+
+// feishuCardElements splits markdown into card elements: runs of markdown,
+// and panels for long code blocks and <details> sections.
+func feishuCardElements(md string) []map[string]any {
+	var elements []map[string]any
+	var text []string
+	flush := func() {
+		if s := strings.TrimSpace(strings.Join(text, "\n")); s != "" {
+			elements = append(elements, feishuMarkdown(toFeishuMarkdown(s)))
+		}
+		text = nil
+	}
+
+	lines := strings.Split(md, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), "```") {
+				end++
+			}
+			block := lines[i:min(end+1, len(lines))]
+			if n := end - i - 1; n > feishuFoldLines {
+				flush()
+				title := fmt.Sprintf("Code · %d lines", n)
+				if lang := strings.TrimPrefix(trimmed, "```"); lang != "" {
+					title = fmt.Sprintf("%s · %d lines", lang, n)
+				}
+				elements = append(elements, feishuPanel(title, strings.Join(block, "\n")))
+			} else {
+				text = append(text, block...)
+			}
+			i = end
+		case strings.HasPrefix(strings.ToLower(trimmed), "<details"):
+			flush()
+			title := "Details"
+			var body []string
+			for i++; i < len(lines) && !strings.EqualFold(strings.TrimSpace(lines[i]), "</details>"); i++ {
+				if m := feishuSummaryRe.FindStringSubmatch(lines[i]); m != nil && len(body) == 0 {
+					title = strings.TrimSpace(m[1])
+					continue
+				}
+				body = append(body, lines[i])
+			}
+			elements = append(elements, feishuPanel(title, toFeishuMarkdown(strings.TrimSpace(strings.Join(body, "\n")))))
+		default:
+			text = append(text, line)
+		}
+	}
+	flush()
+	return elements
+}
+
+func feishuMarkdown(content string) map[string]any {
+	return map[string]any{"tag": "markdown", "content": content}
+}
+
+func feishuPanel(title, content string) map[string]any {
+	return map[string]any{
+		"tag":      "collapsible_panel",
+		"expanded": false,
+		"header": map[string]any{
+			"title": map[string]any{"tag": "markdown", "content": "**" + title + "**"},
+		},
+		"elements": []map[string]any{feishuMarkdown(content)},
+	}
+}
+
+// feishuButtons lays buttons out in a row that wraps. The first is
+// highlighted.
+func feishuButtons(buttons []bus.Button, card string) map[string]any {
+	columns := make([]map[string]any, 0, len(buttons))
+	for i, b := range buttons {
+		kind := "default"
+		if i == 0 {
+			kind = "primary"
+		}
+		columns = append(columns, map[string]any{
+			"tag":   "column",
+			"width": "auto",
+			"elements": []map[string]any{{
+				"tag":  "button",
+				"text": map[string]any{"tag": "plain_text", "content": b.Text},
+				"type": kind,
+				"behaviors": []map[string]any{{
+					"type":  "callback",
+					"value": map[string]any{"data": b.Data, "text": b.Text, "card": card},
+				}},
+			}},
+		})
+	}
+	return map[string]any{"tag": "column_set", "flex_mode": "flow", "columns": columns}
+}
+
+func newFeishuCardID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// rememberCard keeps the buttonless version of a card sent with buttons,
+// to put back in place of it when one of them is pressed.
+func (f *FeishuChannel) rememberCard(id string, card map[string]any) {
+	f.cardsMu.Lock()
+	defer f.cardsMu.Unlock()
+	if f.cards == nil {
+		f.cards = make(map[string]map[string]any)
+	}
+	f.cards[id] = card
+	f.cardOrder = append(f.cardOrder, id)
+	if len(f.cardOrder) > feishuCardsKept {
+		delete(f.cards, f.cardOrder[0])
+		f.cardOrder = f.cardOrder[1:]
+	}
+}
+
+// takeCard returns and forgets the card remembered as id, or nil.
+func (f *FeishuChannel) takeCard(id string) map[string]any {
+	f.cardsMu.Lock()
+	defer f.cardsMu.Unlock()
+	card := f.cards[id]
+	delete(f.cards, id)
+	return card
+}
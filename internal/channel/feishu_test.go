@@ -18,6 +18,8 @@ import (
 // mockFeishuClient implements FeishuClient for testing
 type mockFeishuClient struct {
 	sentMessages []struct{ chatID, content string }
+	sentCards    []map[string]any
+	cardErr      error
 	sendErr      error
 	token        string
 	tokenErr     error
@@ -28,6 +30,20 @@ func (m *mockFeishuClient) SendMessage(ctx context.Context, chatID, content stri
 	return m.sendErr
 }
 
+func (m *mockFeishuClient) SendCard(ctx context.Context, chatID string, card map[string]any) error {
+	if m.cardErr != nil {
+		return m.cardErr
+	}
+	m.sentCards = append(m.sentCards, card)
+	return nil
+}
+
+// cardJSON is card as Feishu would receive it.
+func cardJSON(card map[string]any) string {
+	data, _ := json.Marshal(card)
+	return string(data)
+}
+
 func (m *mockFeishuClient) GetTenantAccessToken(ctx context.Context) (string, error) {
 	return m.token, m.tokenErr
 }
@@ -98,17 +114,14 @@ func TestFeishuChannel_Send_Success(t *testing.T) {
 	if err != nil {
 		t.Errorf("Send error: %v", err)
 	}
-	if len(mock.sentMessages) != 1 {
-		t.Fatalf("expected 1 sent message, got %d", len(mock.sentMessages))
-	}
-	if mock.sentMessages[0].chatID != "chat_123" {
-		t.Errorf("chatID = %q, want chat_123", mock.sentMessages[0].chatID)
+	if len(mock.sentCards) != 1 || !strings.Contains(cardJSON(mock.sentCards[0]), `"content":"hello"`) {
+		t.Fatalf("cards = %v", mock.sentCards)
 	}
 }
 
 func TestFeishuChannel_Send_Error(t *testing.T) {
 	b := bus.NewMessageBus(10)
-	mock := &mockFeishuClient{sendErr: fmt.Errorf("send failed")}
+	mock := &mockFeishuClient{cardErr: fmt.Errorf("card refused"), sendErr: fmt.Errorf("send failed")}
 
 	ch, _ := NewFeishuChannelWithFactory(config.FeishuConfig{
 		AppID: "cli_test", AppSecret: "secret",
@@ -147,6 +160,9 @@ func newTestFeishuChannel(t *testing.T, cfg config.FeishuConfig) (*FeishuChannel
 	ch.imageDownloader = func(ctx context.Context, tenantAccessToken, imageKey string) (string, string, error) {
 		return "", "", fmt.Errorf("test image downloader not configured")
 	}
+	ch.fileDownloader = func(ctx context.Context, tenantAccessToken, messageID, fileKey string) ([]byte, string, error) {
+		return nil, "", fmt.Errorf("test file downloader not configured")
+	}
 	return ch, b
 }
 
@@ -330,6 +346,39 @@ func TestFeishuWebhook_RejectedSender(t *testing.T) {
 	}
 }
 
+func TestFeishuWebhook_AudioAndFileMessages(t *testing.T) {
+	ch, b := newTestFeishuChannel(t, config.FeishuConfig{
+		AppID: "cli_test", AppSecret: "secret",
+	})
+	ch.fileDownloader = func(ctx context.Context, tenantAccessToken, messageID, fileKey string) ([]byte, string, error) {
+		if tenantAccessToken != "test-token" || messageID != "om_1" {
+			t.Errorf("download token %q, message %q", tenantAccessToken, messageID)
+		}
+		return []byte("data of " + fileKey), "application/octet-stream", nil
+	}
+
+	post := func(messageType, content string) {
+		data, _ := json.Marshal(map[string]any{
+			"header": map[string]any{"event_type": "im.message.receive_v1"},
+			"event": map[string]any{
+				"sender":  map[string]any{"sender_id": map[string]any{"open_id": "ou_test"}},
+				"message": map[string]any{"message_id": "om_1", "chat_id": "oc_chat", "message_type": messageType, "content": content},
+			},
+		})
+		ch.handleWebhook(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/feishu/webhook", strings.NewReader(string(data))))
+	}
+	post("audio", `{"file_key":"file_v3_voice","duration":2000}`)
+	post("file", `{"file_key":"file_v3_doc","file_name":"notes.md"}`)
+
+	voice, doc := <-b.Inbound, <-b.Inbound
+	if len(voice.Attachments) != 1 || voice.Attachments[0].MediaType != "audio/ogg" || string(voice.Attachments[0].Data) != "data of file_v3_voice" {
+		t.Errorf("voice = %+v", voice)
+	}
+	if len(doc.Attachments) != 1 || doc.Attachments[0].Name != "notes.md" || string(doc.Attachments[0].Data) != "data of file_v3_doc" {
+		t.Errorf("file = %+v", doc)
+	}
+}
+
 func TestFeishuWebhook_ImageMessage(t *testing.T) {
 	ch, b := newTestFeishuChannel(t, config.FeishuConfig{
 		AppID: "cli_test", AppSecret: "secret",
@@ -605,3 +654,68 @@ func TestChannelManager_FeishuEnabled_MissingConfig(t *testing.T) {
 		t.Error("expected error for missing feishu config")
 	}
 }
+
+func TestFeishuCard(t *testing.T) {
+	long := "```go\n" + strings.Repeat("x++\n", feishuFoldLines+1) + "```"
+	md := "# Plan\nRun `go test`:\n```sh\ngo test ./...\n```\n" + long + "\n<details>\n<summary>Why</summary>\nIt **fails** otherwise.\n</details>\nDone."
+	elements := feishuCardElements(md)
+	if len(elements) != 4 {
+		t.Fatalf("elements = %v", elements)
+	}
+	if got := elements[0]["content"]; got != "**Plan**\nRun `go test`:\n```sh\ngo test ./...\n```" {
+		t.Errorf("text = %q", got)
+	}
+	if got := cardJSON(elements[1]); elements[1]["tag"] != "collapsible_panel" || !strings.Contains(got, `**go · 16 lines**`) || !strings.Contains(got, "```go\\nx++") {
+		t.Errorf("code panel = %s", got)
+	}
+	if got := cardJSON(elements[2]); !strings.Contains(got, `**Why**`) || !strings.Contains(got, `"content":"It **fails** otherwise."`) {
+		t.Errorf("details panel = %s", got)
+	}
+	if elements[3]["content"] != "Done." {
+		t.Errorf("tail = %v", elements[3])
+	}
+
+	card := cardJSON(feishuCard("Which flight?", []bus.Button{{Text: "BA 117", Data: "BA 117"}, {Text: "VS 3", Data: "VS 3"}}, "c1"))
+	if !strings.Contains(card, `"schema":"2.0"`) || !strings.Contains(card, `"value":{"card":"c1","data":"VS 3","text":"VS 3"}`) {
+		t.Errorf("card = %s", card)
+	}
+}
+
+func TestFeishuWebhook_CardAction(t *testing.T) {
+	ch, b := newTestFeishuChannel(t, config.FeishuConfig{AppID: "cli_test", AppSecret: "secret"})
+	mock := ch.client.(*mockFeishuClient)
+	if err := ch.Send(bus.OutboundMessage{ChatID: "oc_chat456", Content: "Approve?", Buttons: []bus.Button{{Text: "Approve", Data: "/approve a1"}, {Text: "Deny", Data: "/deny a1"}}}); err != nil {
+		t.Fatal(err)
+	}
+	var card string
+	for id := range ch.cards {
+		card = id
+	}
+	if card == "" || !strings.Contains(cardJSON(mock.sentCards[0]), card) {
+		t.Fatalf("card %q not remembered", card)
+	}
+
+	press := func() *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"schema":"2.0","header":{"event_type":"card.action.trigger"},"event":{"operator":{"open_id":"ou_test123"},"action":{"value":{"data":"/approve a1","text":"Approve","card":%q}},"context":{"open_chat_id":"oc_chat456","open_message_id":"om_1"}}}`, card)
+		w := httptest.NewRecorder()
+		ch.handleWebhook(w, httptest.NewRequest(http.MethodPost, "/feishu/webhook", strings.NewReader(body)))
+		return w
+	}
+	w := press()
+	if got := w.Body.String(); !strings.Contains(got, `"content":"✓ Approve"`) || !strings.Contains(got, `"type":"raw"`) || strings.Contains(got, `"button"`) {
+		t.Errorf("response = %s", got)
+	}
+	select {
+	case msg := <-b.Inbound:
+		if msg.Content != "/approve a1" || msg.ChatID != "oc_chat456" || msg.SenderID != "ou_test123" {
+			t.Errorf("inbound = %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected inbound message")
+	}
+
+	if got := press().Body.String(); strings.Contains(got, `"card"`) {
+		t.Errorf("second press = %s", got)
+	}
+	<-b.Inbound
+}
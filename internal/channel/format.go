@@ -0,0 +1,243 @@
+package channel
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The agent replies in markdown. Each channel renders it in its own
+// dialect and splits long replies at its own size limit.
+
+// splitMessage splits markdown text into chunks no bigger than limit, as
+// measured by size, breaking at line ends where it can. A chunk that ends
+// inside a fenced code block closes the fence and the next chunk reopens
+// it, so every chunk renders on its own.
+func splitMessage(text string, limit int, size func(string) int) []string {
+	if size(text) <= limit {
+		return []string{text}
+	}
+	const fence = "```"
+	var (
+		chunks []string
+		cur    strings.Builder
+		open   string // the fence line of the code block cur ends in, if any
+	)
+	flush := func() {
+		chunk := cur.String()
+		if open != "" {
+			chunk = strings.TrimRight(chunk, "\n") + "\n" + fence
+		}
+		if strings.TrimSpace(chunk) != "" {
+			chunks = append(chunks, strings.TrimRight(chunk, "\n"))
+		}
+		cur.Reset()
+		if open != "" {
+			cur.WriteString(open + "\n")
+		}
+	}
+	// room is what a chunk may hold before the closing fence is added.
+	room := func() int {
+		if open != "" {
+			return limit - size("\n"+fence)
+		}
+		return limit
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if size(cur.String()+line) > room() && cur.Len() > 0 && cur.String() != open+"\n" {
+			flush()
+		}
+		// A single line longer than a chunk is cut wherever it must be.
+		for size(cur.String()+line) > room() {
+			n := fitPrefix(line, room()-size(cur.String()), size)
+			if n == 0 {
+				_, n = utf8.DecodeRuneInString(line)
+			}
+			cur.WriteString(line[:n])
+			line = line[n:]
+			flush()
+		}
+		cur.WriteString(line)
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, fence) {
+			if open == "" {
+				open = trimmed
+			} else {
+				open = ""
+			}
+		}
+	}
+	open = "" // a fence left open by the reply itself stays as written
+	flush()
+	return chunks
+}
+
+// fitPrefix returns the length in bytes of the longest prefix of s, cut at
+// a rune boundary, whose size is at most limit. size must add up over runes.
+func fitPrefix(s string, limit int, size func(string) int) int {
+	total := 0
+	for i, r := range s {
+		total += size(string(r))
+		if total > limit {
+			return i
+		}
+	}
+	return len(s)
+}
+
+func runeCount(s string) int { return utf8.RuneCountInString(s) }
+
+func byteCount(s string) int { return len(s) }
+
+var (
+	mdHeadingRe = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	mdBulletRe  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdLinkRe    = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// telegramSpecial are the characters MarkdownV2 needs escaped in text.
+const telegramSpecial = "_*[]()~`>#+-=|{}.!\\"
+
+func escapeTelegram(s, special string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// toTelegramMarkdownV2 renders markdown as Telegram MarkdownV2: headings
+// become bold, list bullets become "•", and everything else is escaped.
+func toTelegramMarkdownV2(md string) string {
+	var out []string
+	inFence := false
+	for _, line := range strings.Split(md, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			inFence = !inFence
+			out = append(out, "```"+escapeTelegram(strings.TrimPrefix(trimmed, "```"), "`\\"))
+		case inFence:
+			out = append(out, escapeTelegram(line, "`\\"))
+		case mdHeadingRe.MatchString(line):
+			out = append(out, "*"+telegramInline(mdHeadingRe.FindStringSubmatch(line)[1])+"*")
+		case mdBulletRe.MatchString(line):
+			m := mdBulletRe.FindStringSubmatch(line)
+			out = append(out, m[1]+"• "+telegramInline(m[2]))
+		case strings.HasPrefix(trimmed, "> "):
+			out = append(out, ">"+telegramInline(strings.TrimPrefix(trimmed, "> ")))
+		default:
+			out = append(out, telegramInline(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// telegramInline renders the inline markdown of one line.
+func telegramInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		if m := mdLinkRe.FindStringSubmatch(rest); m != nil {
+			b.WriteString("[" + telegramInline(m[1]) + "](" + escapeTelegram(m[2], ")\\") + ")")
+			i += len(m[0])
+			continue
+		}
+		if inner, n, ok := delimited(rest, "`", false); ok {
+			b.WriteString("`" + escapeTelegram(inner, "`\\") + "`")
+			i += n
+			continue
+		}
+		if inner, n, ok := delimited(rest, "**", false); ok {
+			b.WriteString("*" + telegramInline(inner) + "*")
+			i += n
+			continue
+		}
+		if inner, n, ok := delimited(rest, "__", true); ok && !wordBefore(s, i) {
+			b.WriteString("*" + telegramInline(inner) + "*")
+			i += n
+			continue
+		}
+		if inner, n, ok := delimited(rest, "~~", false); ok {
+			b.WriteString("~" + telegramInline(inner) + "~")
+			i += n
+			continue
+		}
+		if inner, n, ok := delimited(rest, "*", false); ok {
+			b.WriteString("_" + telegramInline(inner) + "_")
+			i += n
+			continue
+		}
+		if inner, n, ok := delimited(rest, "_", true); ok && !wordBefore(s, i) {
+			b.WriteString("_" + telegramInline(inner) + "_")
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(rest)
+		b.WriteString(escapeTelegram(string(r), telegramSpecial))
+		i += size
+	}
+	return b.String()
+}
+
+// delimited matches s starting with a span wrapped in delim, such as
+// **bold**. The span may not start or end with a space. With wordEnd, the
+// closing delimiter may not be followed by a letter or digit, so that
+// snake_case names stay as they are.
+func delimited(s, delim string, wordEnd bool) (inner string, n int, ok bool) {
+	if !strings.HasPrefix(s, delim) {
+		return "", 0, false
+	}
+	body := s[len(delim):]
+	for from := 0; ; {
+		end := strings.Index(body[from:], delim)
+		if end < 0 {
+			return "", 0, false
+		}
+		end += from
+		inner = body[:end]
+		after := body[end+len(delim):]
+		if inner == "" || strings.TrimSpace(inner) != inner {
+			return "", 0, false
+		}
+		if wordEnd && after != "" {
+			if r, _ := utf8.DecodeRuneInString(after); unicode.IsLetter(r) || unicode.IsDigit(r) {
+				from = end + len(delim)
+				continue
+			}
+		}
+		return inner, len(delim) + end + len(delim), true
+	}
+}
+
+// wordBefore reports whether s[i] follows a letter or digit.
+func wordBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// toFeishuMarkdown adapts markdown to the subset Feishu card markdown
+// renders: headings become bold lines.
+func toFeishuMarkdown(md string) string {
+	lines := strings.Split(md, "\n")
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			if m := mdHeadingRe.FindStringSubmatch(line); m != nil {
+				lines[i] = "**" + m[1] + "**"
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,121 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestToTelegramMarkdownV2(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"hello", "hello"},
+		{"Done. Cost: $5 (approx.)", `Done\. Cost: $5 \(approx\.\)`},
+		{"**bold** and *italic*", "*bold* and _italic_"},
+		{"use `a_b*c`", "use `a_b*c`"},
+		{"keep snake_case_names", `keep snake\_case\_names`},
+		{"see [the docs](https://example.com/a_b)", "see [the docs](https://example.com/a_b)"},
+		{"## Plan\n- one\n- two", "*Plan*\n• one\n• two"},
+		{"2 * 3 = 6", `2 \* 3 \= 6`},
+		{"```go\nfmt.Println(`x`)\n```", "```go\nfmt.Println(\\`x\\`)\n```"},
+		{"> quoted!", `>quoted\!`},
+	}
+	for _, tt := range tests {
+		if got := toTelegramMarkdownV2(tt.input); got != tt.want {
+			t.Errorf("toTelegramMarkdownV2(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	if got := splitMessage("short", 10, runeCount); len(got) != 1 || got[0] != "short" {
+		t.Errorf("short = %q", got)
+	}
+
+	text := "intro line\n```go\n" + strings.Repeat("x := 1\n", 10) + "```\noutro"
+	chunks := splitMessage(text, 40, runeCount)
+	if len(chunks) < 3 {
+		t.Fatalf("chunks = %q", chunks)
+	}
+	for i, c := range chunks {
+		if runeCount(c) > 40 {
+			t.Errorf("chunk %d has %d runes", i, runeCount(c))
+		}
+		if strings.Count(c, "```")%2 != 0 {
+			t.Errorf("chunk %d leaves a code block open: %q", i, c)
+		}
+	}
+	if !strings.HasPrefix(chunks[1], "```go\n") {
+		t.Errorf("second chunk should reopen the code block: %q", chunks[1])
+	}
+	if !strings.HasSuffix(chunks[len(chunks)-1], "outro") {
+		t.Errorf("last chunk = %q", chunks[len(chunks)-1])
+	}
+
+	// A line longer than the limit is cut at rune boundaries.
+	long := splitMessage(strings.Repeat("你好", 10), 7, byteCount)
+	for _, c := range long {
+		if len(c) > 7 || !strings.HasPrefix(c, "你") && !strings.HasPrefix(c, "好") {
+			t.Errorf("chunk %q", c)
+		}
+	}
+}
+
+func TestTelegramChannel_Send_MarkdownV2(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	ch, _ := NewTelegramChannel(config.TelegramConfig{Token: "fake-token"}, b)
+	mockBot := newMockBot()
+	ch.SetBot(mockBot)
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: "1", Content: "**Done.**\n" + strings.Repeat("line\n", 1000)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mockBot.sentMsgs) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(mockBot.sentMsgs))
+	}
+	first := mockBot.sentMsgs[0].(tgbotapi.MessageConfig)
+	if first.ParseMode != tgbotapi.ModeMarkdownV2 || !strings.HasPrefix(first.Text, `*Done\.*`) {
+		t.Errorf("first message = %s %q", first.ParseMode, first.Text[:20])
+	}
+
+	// A chunk Telegram refuses is resent as plain text.
+	mockBot.sentMsgs = nil
+	mockBot.sendErr = fmt.Errorf("can't parse entities")
+	if err := ch.Send(bus.OutboundMessage{ChatID: "1", Content: "**x**"}); err == nil {
+		t.Error("expected error when plain text fails too")
+	}
+	if len(mockBot.sentMsgs) != 2 || mockBot.sentMsgs[1].(tgbotapi.MessageConfig).Text != "**x**" {
+		t.Errorf("sent = %+v", mockBot.sentMsgs)
+	}
+}
+
+func TestFeishuChannel_Send_Card(t *testing.T) {
+	mock := &mockFeishuClient{token: "t"}
+	ch := &FeishuChannel{client: mock}
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: "oc_1", Content: "plain reply"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(bus.OutboundMessage{ChatID: "oc_1", Content: "# Report\n- **done**"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.sentMessages) != 0 || len(mock.sentCards) != 2 {
+		t.Fatalf("texts = %+v, cards = %d", mock.sentMessages, len(mock.sentCards))
+	}
+	if got := cardJSON(mock.sentCards[1]); !strings.Contains(got, `"content":"**Report**\n- **done**"`) {
+		t.Errorf("card = %s", got)
+	}
+
+	mock.cardErr = fmt.Errorf("card too large")
+	if err := ch.Send(bus.OutboundMessage{ChatID: "oc_1", Content: "**bold**"}); err != nil {
+		t.Fatal(err)
+	}
+	if last := mock.sentMessages[len(mock.sentMessages)-1]; last.content != "**bold**" {
+		t.Errorf("fallback text = %q", last.content)
+	}
+}
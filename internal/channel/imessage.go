@@ -0,0 +1,340 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+
+	_ "modernc.org/sqlite"
+)
+
+const imessageChannelName = "imessage"
+
+const (
+	imessageDefaultPollInterval = 5 * time.Second
+	// imessageGroupStyle is the chat.style of group chats in chat.db.
+	imessageGroupStyle = 43
+)
+
+// IMessage is a message read from the Messages database.
+type IMessage struct {
+	ID     int64  // ROWID, which only grows
+	Handle string // the sender's phone number or email address
+	Text   string
+	// Chat is the chat's GUID when it is a group chat, and empty for
+	// direct ones.
+	Chat string
+}
+
+// IMessageClient interface for Messages access (allows mocking)
+type IMessageClient interface {
+	// LastID returns the ID of the newest message, so polling starts after
+	// what is already there.
+	LastID(ctx context.Context) (int64, error)
+	// Fetch returns the messages others sent after the one with ID after,
+	// oldest first.
+	Fetch(ctx context.Context, after int64) ([]IMessage, error)
+	// Send sends text and files to a handle, or to a group chat by GUID.
+	Send(ctx context.Context, to, text string, files []string) error
+	Close() error
+}
+
+type defaultIMessageClient struct {
+	cfg config.IMessageConfig
+	db  *sql.DB
+}
+
+func (c *defaultIMessageClient) open() (*sql.DB, error) {
+	if c.db != nil {
+		return c.db, nil
+	}
+	path := c.cfg.DBPath
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, "Library", "Messages", "chat.db")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("open Messages database (does myclaw have Full Disk Access?): %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	c.db = db
+	return db, nil
+}
+
+func (c *defaultIMessageClient) LastID(ctx context.Context) (int64, error) {
+	db, err := c.open()
+	if err != nil {
+		return 0, err
+	}
+	var id sql.NullInt64
+	err = db.QueryRowContext(ctx, "SELECT MAX(ROWID) FROM message").Scan(&id)
+	return id.Int64, err
+}
+
+func (c *defaultIMessageClient) Fetch(ctx context.Context, after int64) ([]IMessage, error) {
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.ROWID, h.id, m.text, m.attributedBody, c.guid, c.style
+		FROM message m
+		JOIN handle h ON h.ROWID = m.handle_id
+		LEFT JOIN chat_message_join cmj ON cmj.message_id = m.ROWID
+		LEFT JOIN chat c ON c.ROWID = cmj.chat_id
+		WHERE m.ROWID > ? AND m.is_from_me = 0
+		ORDER BY m.ROWID`, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []IMessage
+	for rows.Next() {
+		var (
+			m     IMessage
+			text  sql.NullString
+			body  []byte
+			guid  sql.NullString
+			style sql.NullInt64
+		)
+		if err := rows.Scan(&m.ID, &m.Handle, &text, &body, &guid, &style); err != nil {
+			return messages, err
+		}
+		m.Text = text.String
+		if m.Text == "" {
+			m.Text = attributedBodyText(body)
+		}
+		if style.Int64 == imessageGroupStyle {
+			m.Chat = guid.String
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// imessageScript sends a message with the Messages app. Its arguments are
+// the recipient, whether that is a group chat GUID, the text and the
+// files to attach.
+const imessageScript = `on run argv
+	set recipient to item 1 of argv
+	set isChat to item 2 of argv is "chat"
+	set msg to item 3 of argv
+	tell application "Messages"
+		if isChat then
+			set target to chat id recipient
+		else
+			set target to participant recipient of (1st account whose service type = iMessage)
+		end if
+		if msg is not "" then send msg to target
+		repeat with i from 4 to count of argv
+			send (POSIX file (item i of argv)) to target
+		end repeat
+	end tell
+end run`
+
+func (c *defaultIMessageClient) Send(ctx context.Context, to, text string, files []string) error {
+	var cmd *exec.Cmd
+	if c.cfg.Shortcut != "" {
+		input, err := json.Marshal(map[string]any{"to": to, "text": text, "files": files})
+		if err != nil {
+			return err
+		}
+		cmd = exec.CommandContext(ctx, "shortcuts", "run", c.cfg.Shortcut)
+		cmd.Stdin = bytes.NewReader(input)
+	} else {
+		kind := "buddy"
+		if isIMessageChat(to) {
+			kind = "chat"
+		}
+		cmd = exec.CommandContext(ctx, "osascript", append([]string{"-e", imessageScript, to, kind, text}, files...)...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", filepath.Base(cmd.Path), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (c *defaultIMessageClient) Close() error {
+	if c.db == nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+// isIMessageChat reports whether to is a group chat GUID, such as
+// "iMessage;+;chat123456", rather than a handle.
+func isIMessageChat(to string) bool {
+	return strings.Contains(to, ";+;")
+}
+
+// attributedBodyText pulls the text out of message.attributedBody, an
+// NSAttributedString in Apple's typedstream format, which is where newer
+// macOS versions keep it. The string follows the NSString class name and a
+// 5-byte header, led by its length: one byte, or 0x81 and two bytes little
+// endian.
+func attributedBodyText(body []byte) string {
+	i := bytes.Index(body, []byte("NSString"))
+	if i < 0 {
+		return ""
+	}
+	rest := body[i+len("NSString"):]
+	if len(rest) < 6 {
+		return ""
+	}
+	rest = rest[5:]
+	n, rest := int(rest[0]), rest[1:]
+	if n == 0x81 {
+		if len(rest) < 2 {
+			return ""
+		}
+		n, rest = int(rest[0])|int(rest[1])<<8, rest[2:]
+	}
+	if n > len(rest) {
+		return ""
+	}
+	return string(rest[:n])
+}
+
+// IMessageClientFactory creates IMessageClient instances
+type IMessageClientFactory func(cfg config.IMessageConfig) IMessageClient
+
+var defaultIMessageClientFactory IMessageClientFactory = func(cfg config.IMessageConfig) IMessageClient {
+	return &defaultIMessageClient{cfg: cfg}
+}
+
+// IMessageChannel answers iMessages on the Mac it runs on. Direct chats
+// are keyed by the sender's handle and group chats by their GUID.
+type IMessageChannel struct {
+	BaseChannel
+	cfg           config.IMessageConfig
+	client        IMessageClient
+	clientFactory IMessageClientFactory
+	cancel        context.CancelFunc
+	lastID        int64
+}
+
+func NewIMessageChannel(cfg config.IMessageConfig, b *bus.MessageBus) (*IMessageChannel, error) {
+	if runtime.GOOS != "darwin" {
+		return nil, errors.New("imessage needs macOS")
+	}
+	return NewIMessageChannelWithFactory(cfg, b, defaultIMessageClientFactory)
+}
+
+func NewIMessageChannelWithFactory(cfg config.IMessageConfig, b *bus.MessageBus, factory IMessageClientFactory) (*IMessageChannel, error) {
+	if len(cfg.AllowFrom) == 0 {
+		return nil, errors.New("imessage allowFrom is required")
+	}
+	return &IMessageChannel{
+		BaseChannel:   NewBaseChannelWithAccess(imessageChannelName, b, Access{AllowFrom: cfg.AllowFrom}),
+		cfg:           cfg,
+		clientFactory: factory,
+	}, nil
+}
+
+func (m *IMessageChannel) Start(ctx context.Context) error {
+	m.client = m.clientFactory(m.cfg)
+	id, err := m.client.LastID(ctx)
+	if err != nil {
+		return fmt.Errorf("read Messages database: %w", err)
+	}
+	m.lastID = id
+	ctx, m.cancel = context.WithCancel(ctx)
+
+	interval := imessageDefaultPollInterval
+	if m.cfg.PollIntervalSeconds > 0 {
+		interval = time.Duration(m.cfg.PollIntervalSeconds) * time.Second
+	}
+	go m.pollLoop(ctx, interval)
+
+	log.Printf("[imessage] polling Messages every %s", interval)
+	return nil
+}
+
+func (m *IMessageChannel) Stop() error {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	if m.client != nil {
+		m.client.Close()
+	}
+	log.Printf("[imessage] stopped")
+	return nil
+}
+
+func (m *IMessageChannel) pollLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.poll(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *IMessageChannel) poll(ctx context.Context) {
+	messages, err := m.client.Fetch(ctx, m.lastID)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("[imessage] fetch error: %v", err)
+	}
+	for _, msg := range messages {
+		m.lastID = max(m.lastID, msg.ID)
+		m.handleMessage(msg)
+	}
+}
+
+func (m *IMessageChannel) handleMessage(msg IMessage) {
+	chatID := msg.Chat
+	if chatID == "" {
+		chatID = msg.Handle
+	}
+	if !m.IsAllowed(msg.Handle) {
+		// No reply: answering strangers would tell them the agent is here.
+		m.Reject("", msg.Handle)
+		return
+	}
+	content := strings.TrimSpace(msg.Text)
+	if content == "" {
+		return
+	}
+	m.bus.Inbound <- bus.InboundMessage{
+		Channel:   imessageChannelName,
+		SenderID:  msg.Handle,
+		ChatID:    chatID,
+		Content:   content,
+		Timestamp: time.Now(),
+		Metadata:  map[string]any{"message_id": msg.ID},
+	}
+}
+
+// Send sends the reply's text, then its media as attachments.
+func (m *IMessageChannel) Send(msg bus.OutboundMessage) error {
+	if m.client == nil {
+		return errors.New("imessage client not initialized")
+	}
+	return m.client.Send(context.Background(), msg.ChatID, msg.Content, msg.Media)
+}
@@ -0,0 +1,100 @@
+package channel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+type imessageSend struct {
+	to, text string
+	files    []string
+}
+
+type mockIMessageClient struct {
+	last  int64
+	inbox []IMessage
+	after []int64
+	sent  []imessageSend
+}
+
+func (m *mockIMessageClient) LastID(ctx context.Context) (int64, error) { return m.last, nil }
+
+func (m *mockIMessageClient) Fetch(ctx context.Context, after int64) ([]IMessage, error) {
+	m.after = append(m.after, after)
+	msgs := m.inbox
+	m.inbox = nil
+	return msgs, nil
+}
+
+func (m *mockIMessageClient) Send(ctx context.Context, to, text string, files []string) error {
+	m.sent = append(m.sent, imessageSend{to, text, files})
+	return nil
+}
+
+func (m *mockIMessageClient) Close() error { return nil }
+
+func TestIMessageChannel(t *testing.T) {
+	if _, err := NewIMessageChannelWithFactory(config.IMessageConfig{}, bus.NewMessageBus(1), nil); err == nil {
+		t.Error("empty allowFrom accepted")
+	}
+
+	b := bus.NewMessageBus(10)
+	client := &mockIMessageClient{last: 100}
+	ch, err := NewIMessageChannelWithFactory(config.IMessageConfig{AllowFrom: []string{"+15551234567"}, PollIntervalSeconds: 3600}, b, func(config.IMessageConfig) IMessageClient { return client })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer ch.Stop()
+
+	client.inbox = []IMessage{
+		{ID: 101, Handle: "+15559999999", Text: "who is this?"},
+		{ID: 102, Handle: "+15551234567", Text: " Remind me to call mum "},
+		{ID: 103, Handle: "+15551234567", Text: "Dinner at 8?", Chat: "iMessage;+;chat42"},
+	}
+	ch.poll(context.Background())
+	if got := <-b.Inbound; got.ChatID != "+15551234567" || got.Content != "Remind me to call mum" {
+		t.Errorf("direct = %+v", got)
+	}
+	if got := <-b.Inbound; got.ChatID != "iMessage;+;chat42" || got.SenderID != "+15551234567" {
+		t.Errorf("group = %+v", got)
+	}
+	if len(b.Inbound) != 0 {
+		t.Error("stranger's message passed on")
+	}
+	ch.poll(context.Background())
+	if client.after[0] != 100 || client.after[1] != 103 {
+		t.Errorf("fetched after %v", client.after)
+	}
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: "iMessage;+;chat42", Content: "Booked.", Media: []string{"/tmp/menu.pdf"}}); err != nil {
+		t.Fatal(err)
+	}
+	if s := client.sent[0]; s.to != "iMessage;+;chat42" || s.text != "Booked." || len(s.files) != 1 || !isIMessageChat(s.to) {
+		t.Errorf("sent = %+v", s)
+	}
+}
+
+func TestAttributedBodyText(t *testing.T) {
+	head := []byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01@\x84\x84\x84\x12NSAttributedString\x00\x84\x84\x08NSObject\x00\x85\x92\x84\x84\x84\x08NSString\x01\x94\x84\x01+")
+	short := append(append([]byte{}, head...), append([]byte{5}, "hello\x86\x84"...)...)
+	if got := attributedBodyText(short); got != "hello" {
+		t.Errorf("short = %q", got)
+	}
+	text := make([]byte, 300)
+	for i := range text {
+		text[i] = 'a'
+	}
+	long := append(append([]byte{}, head...), append([]byte{0x81, 0x2c, 0x01}, text...)...)
+	if got := attributedBodyText(long); len(got) != 300 {
+		t.Errorf("long = %d bytes", len(got))
+	}
+	if attributedBodyText([]byte("nothing here")) != "" || attributedBodyText(append(head[:len(head)-1:len(head)-1], 0x81)) != "" {
+		t.Error("garbage decoded")
+	}
+}
@@ -2,112 +2,482 @@ package channel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"reflect"
 	"sync"
+	"time"
 
 	"github.com/stellarlinkco/myclaw/internal/bus"
 	"github.com/stellarlinkco/myclaw/internal/config"
 )
 
+// DeadLetterFunc receives an outbound message that still failed after every
+// send attempt, together with the last error and the number of attempts.
+type DeadLetterFunc func(msg bus.OutboundMessage, err error, attempts int)
+
+// RouteFunc may take over an outbound message before it is sent locally. It
+// reports true when the message was handled elsewhere.
+type RouteFunc func(msg bus.OutboundMessage) bool
+
 type ChannelManager struct {
+	mu       sync.RWMutex
 	channels map[string]Channel
 	bus      *bus.MessageBus
+
+	// settings holds the config each channel was built from, so Apply can
+	// tell which ones changed. webUI and gateway are set when the manager
+	// runs the web UI.
+	settings   map[string]any
+	webUI      bool
+	gateway    config.GatewayConfig
+	subscribed map[string]bool
+
+	sendAttempts int
+	retryBackoff func(attempt int) time.Duration
+	onDeadLetter DeadLetterFunc
+	route        RouteFunc
+
+	statusMu sync.Mutex
+	status   map[string]*Status
+
+	// outboxes hold each channel's messages waiting to be sent, so that
+	// one channel's slow sends and retries never hold up the bus or the
+	// other channels. A channel is draining while its outbox exists.
+	outboxMu sync.Mutex
+	outboxes map[string][]bus.OutboundMessage
 }
 
-func NewChannelManager(cfg config.ChannelsConfig, b *bus.MessageBus) (*ChannelManager, error) {
-	m := &ChannelManager{
-		channels: make(map[string]Channel),
-		bus:      b,
+// maxOutbox is how many messages a channel's outbox holds. Past that, a
+// channel that cannot keep up has its new messages dead-lettered.
+const maxOutbox = 1000
+
+// errOutboxFull is the error a message dead-lettered by a full outbox has.
+var errOutboxFull = errors.New("outbox full")
+
+// Status is what the manager has seen of a channel, for health checks.
+type Status struct {
+	Running     bool      `json:"running"`
+	StartError  string    `json:"startError,omitempty"`
+	SendError   string    `json:"sendError,omitempty"` // last delivery that failed every attempt; cleared by a success
+	LastSendAt  time.Time `json:"lastSendAt,omitzero"`
+	LastErrorAt time.Time `json:"lastErrorAt,omitzero"`
+}
+
+// Healthy reports whether the channel started and is delivering.
+func (s Status) Healthy() error {
+	switch {
+	case s.StartError != "":
+		return fmt.Errorf("start failed: %s", s.StartError)
+	case s.SendError != "":
+		return fmt.Errorf("send failed: %s", s.SendError)
+	}
+	return nil
+}
+
+// channelNames lists the channels in the order they are created.
+var channelNames = []string{
+	telegramChannelName, feishuChannelName, wecomChannelName, slackChannelName,
+	emailChannelName, whatsappChannelName, imessageChannelName, xmppChannelName,
+	mattermostChannelName, rocketChatChannelName, webUIChannelName,
+}
+
+// channelSettings returns the config of each enabled channel by name. The
+// web UI is only included when webUI is set.
+func channelSettings(cfg config.ChannelsConfig, webUI bool) map[string]any {
+	settings := make(map[string]any)
+	for name, on := range map[string]struct {
+		enabled bool
+		cfg     any
+	}{
+		telegramChannelName:   {cfg.Telegram.Enabled, cfg.Telegram},
+		feishuChannelName:     {cfg.Feishu.Enabled, cfg.Feishu},
+		wecomChannelName:      {cfg.WeCom.Enabled, cfg.WeCom},
+		slackChannelName:      {cfg.Slack.Enabled, cfg.Slack},
+		emailChannelName:      {cfg.Email.Enabled, cfg.Email},
+		whatsappChannelName:   {cfg.WhatsApp.Enabled, cfg.WhatsApp},
+		imessageChannelName:   {cfg.IMessage.Enabled, cfg.IMessage},
+		xmppChannelName:       {cfg.XMPP.Enabled, cfg.XMPP},
+		mattermostChannelName: {cfg.Mattermost.Enabled, cfg.Mattermost},
+		rocketChatChannelName: {cfg.RocketChat.Enabled, cfg.RocketChat},
+		webUIChannelName:      {cfg.WebUI.Enabled && webUI, cfg.WebUI},
+	} {
+		if on.enabled {
+			settings[name] = on.cfg
+		}
 	}
+	return settings
+}
 
-	if cfg.Telegram.Enabled {
+// newChannel creates the channel called name from cfg.
+func newChannel(name string, cfg config.ChannelsConfig, gwCfg config.GatewayConfig, b *bus.MessageBus) (Channel, error) {
+	switch name {
+	case telegramChannelName:
 		ch, err := NewTelegramChannel(cfg.Telegram, b)
 		if err != nil {
 			return nil, fmt.Errorf("init telegram channel: %w", err)
 		}
-		m.channels[ch.Name()] = ch
-		b.SubscribeOutbound(ch.Name(), func(msg bus.OutboundMessage) {
-			if err := ch.Send(msg); err != nil {
-				log.Printf("[channel-mgr] send to %s failed: %v", ch.Name(), err)
-			}
-		})
-	}
-
-	if cfg.Feishu.Enabled {
+		return ch, nil
+	case feishuChannelName:
 		ch, err := NewFeishuChannel(cfg.Feishu, b)
 		if err != nil {
 			return nil, fmt.Errorf("init feishu channel: %w", err)
 		}
-		m.channels[ch.Name()] = ch
-		b.SubscribeOutbound(ch.Name(), func(msg bus.OutboundMessage) {
-			if err := ch.Send(msg); err != nil {
-				log.Printf("[channel-mgr] send to %s failed: %v", ch.Name(), err)
-			}
-		})
-	}
-
-	if cfg.WeCom.Enabled {
+		return ch, nil
+	case wecomChannelName:
 		ch, err := NewWeComChannel(cfg.WeCom, b)
 		if err != nil {
 			return nil, fmt.Errorf("init wecom channel: %w", err)
 		}
-		m.channels[ch.Name()] = ch
-		b.SubscribeOutbound(ch.Name(), func(msg bus.OutboundMessage) {
-			if err := ch.Send(msg); err != nil {
-				log.Printf("[channel-mgr] send to %s failed: %v", ch.Name(), err)
+		return ch, nil
+	case slackChannelName:
+		ch, err := NewSlackChannel(cfg.Slack, b)
+		if err != nil {
+			return nil, fmt.Errorf("init slack channel: %w", err)
+		}
+		return ch, nil
+	case emailChannelName:
+		ch, err := NewEmailChannel(cfg.Email, b)
+		if err != nil {
+			return nil, fmt.Errorf("init email channel: %w", err)
+		}
+		return ch, nil
+	case whatsappChannelName:
+		if cfg.WhatsApp.Mode == config.WhatsAppModeCloud {
+			ch, err := NewWhatsAppCloudChannel(cfg.WhatsApp, b)
+			if err != nil {
+				return nil, fmt.Errorf("init whatsapp cloud channel: %w", err)
 			}
-		})
-	}
-
-	if cfg.WhatsApp.Enabled {
+			return ch, nil
+		}
 		ch, err := NewWhatsApp(cfg.WhatsApp, b)
 		if err != nil {
 			return nil, fmt.Errorf("create whatsapp channel: %w", err)
 		}
-		m.channels[ch.Name()] = ch
-		b.SubscribeOutbound(ch.Name(), func(msg bus.OutboundMessage) {
-			if err := ch.Send(msg); err != nil {
-				log.Printf("[channel-mgr] send to %s failed: %v", ch.Name(), err)
-			}
-		})
+		return ch, nil
+	case imessageChannelName:
+		ch, err := NewIMessageChannel(cfg.IMessage, b)
+		if err != nil {
+			return nil, fmt.Errorf("init imessage channel: %w", err)
+		}
+		return ch, nil
+	case xmppChannelName:
+		ch, err := NewXMPPChannel(cfg.XMPP, b)
+		if err != nil {
+			return nil, fmt.Errorf("init xmpp channel: %w", err)
+		}
+		return ch, nil
+	case mattermostChannelName:
+		ch, err := NewMattermostChannel(cfg.Mattermost, b)
+		if err != nil {
+			return nil, fmt.Errorf("init mattermost channel: %w", err)
+		}
+		return ch, nil
+	case rocketChatChannelName:
+		ch, err := NewRocketChatChannel(cfg.RocketChat, b)
+		if err != nil {
+			return nil, fmt.Errorf("init rocketchat channel: %w", err)
+		}
+		return ch, nil
+	case webUIChannelName:
+		ch, err := NewWebUIChannel(cfg.WebUI, gwCfg, b)
+		if err != nil {
+			return nil, fmt.Errorf("init webui channel: %w", err)
+		}
+		return ch, nil
 	}
+	return nil, fmt.Errorf("unknown channel %q", name)
+}
 
-	return m, nil
+func NewChannelManager(cfg config.ChannelsConfig, b *bus.MessageBus) (*ChannelManager, error) {
+	return newChannelManager(cfg, config.GatewayConfig{}, false, b)
 }
 
 func NewChannelManagerWithGateway(cfg config.ChannelsConfig, gwCfg config.GatewayConfig, b *bus.MessageBus) (*ChannelManager, error) {
-	m, err := NewChannelManager(cfg, b)
-	if err != nil {
-		return nil, err
-	}
+	return newChannelManager(cfg, gwCfg, true, b)
+}
 
-	if cfg.WebUI.Enabled {
-		ch, err := NewWebUIChannel(cfg.WebUI, gwCfg, b)
+func newChannelManager(cfg config.ChannelsConfig, gwCfg config.GatewayConfig, webUI bool, b *bus.MessageBus) (*ChannelManager, error) {
+	m := &ChannelManager{
+		channels: make(map[string]Channel),
+		bus:      b,
+		settings: channelSettings(cfg, webUI),
+		webUI:    webUI,
+		gateway:  gwCfg,
+	}
+	for _, name := range channelNames {
+		if _, ok := m.settings[name]; !ok {
+			continue
+		}
+		ch, err := newChannel(name, cfg, gwCfg, b)
 		if err != nil {
-			return nil, fmt.Errorf("init webui channel: %w", err)
+			return nil, err
+		}
+		m.register(ch)
+	}
+	return m, nil
+}
+
+// Apply brings the channels in line with cfg while they run: it stops the
+// ones that were disabled, starts the ones that were enabled and restarts
+// the ones whose settings changed, leaving the rest alone. A channel whose
+// new settings fail keeps running as it was. Apply returns the names of the
+// channels it changed.
+func (m *ChannelManager) Apply(ctx context.Context, cfg config.ChannelsConfig) ([]string, error) {
+	want := channelSettings(cfg, m.webUI)
+	var changed []string
+	var errs []error
+	for _, name := range channelNames {
+		m.mu.RLock()
+		old, running := m.settings[name]
+		m.mu.RUnlock()
+		settings, enabled := want[name]
+		if running == enabled && reflect.DeepEqual(old, settings) {
+			continue
+		}
+
+		var ch Channel
+		if enabled {
+			var err error
+			if ch, err = newChannel(name, cfg, m.gateway, m.bus); err != nil {
+				errs = append(errs, err)
+				continue
+			}
 		}
-		m.channels[ch.Name()] = ch
-		b.SubscribeOutbound(ch.Name(), func(msg bus.OutboundMessage) {
-			if err := ch.Send(msg); err != nil {
-				log.Printf("[channel-mgr] send to %s failed: %v", ch.Name(), err)
+		if running {
+			if err := m.Stop(name); err != nil {
+				log.Printf("[channel-mgr] error stopping %s: %v", name, err)
 			}
-		})
+			m.unregister(name)
+		}
+		changed = append(changed, name)
+		if !enabled {
+			continue
+		}
+		m.register(ch)
+		m.mu.Lock()
+		m.settings[name] = settings
+		m.mu.Unlock()
+		if err := m.Start(ctx, name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
 	}
+	return changed, errors.Join(errs...)
+}
 
-	return m, nil
+// SetDeadLetterHandler makes outbound sends retry up to maxAttempts times
+// before handing the message to fn instead of dropping it.
+func (m *ChannelManager) SetDeadLetterHandler(maxAttempts int, fn DeadLetterFunc) {
+	m.sendAttempts = maxAttempts
+	m.onDeadLetter = fn
+}
+
+// SetRouter installs fn ahead of local delivery, e.g. to hand messages for a
+// channel served by another gateway instance to that instance.
+func (m *ChannelManager) SetRouter(fn RouteFunc) {
+	m.route = fn
+}
+
+func (m *ChannelManager) register(ch Channel) {
+	name := ch.Name()
+	m.mu.Lock()
+	if m.channels == nil {
+		m.channels = make(map[string]Channel)
+	}
+	m.channels[name] = ch
+	if m.subscribed == nil {
+		m.subscribed = make(map[string]bool)
+	}
+	subscribe := !m.subscribed[name]
+	m.subscribed[name] = true
+	m.mu.Unlock()
+
+	m.statusMu.Lock()
+	if m.status == nil {
+		m.status = make(map[string]*Status)
+	}
+	m.status[name] = &Status{}
+	m.statusMu.Unlock()
+
+	// One subscription per name, so replies go to whichever instance of
+	// the channel is registered when they are sent.
+	if subscribe {
+		m.bus.SubscribeOutbound(name, func(msg bus.OutboundMessage) { m.enqueue(name, msg) })
+	}
+}
+
+// enqueue adds msg to the outbox of the channel called name, and starts
+// draining it unless that is already under way. A full outbox
+// dead-letters msg instead.
+func (m *ChannelManager) enqueue(name string, msg bus.OutboundMessage) {
+	m.outboxMu.Lock()
+	if m.outboxes == nil {
+		m.outboxes = make(map[string][]bus.OutboundMessage)
+	}
+	queue, draining := m.outboxes[name]
+	if len(queue) >= maxOutbox {
+		m.outboxMu.Unlock()
+		log.Printf("[channel-mgr] %s outbox full, not sending message to %s", name, msg.ChatID)
+		if m.onDeadLetter != nil {
+			m.onDeadLetter(msg, errOutboxFull, 0)
+		}
+		return
+	}
+	m.outboxes[name] = append(queue, msg)
+	m.outboxMu.Unlock()
+	if !draining {
+		go m.drain(name)
+	}
+}
+
+// Flush waits until every outbox has been delivered, retries and dead
+// letters included, or ctx is done.
+func (m *ChannelManager) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		m.outboxMu.Lock()
+		pending := len(m.outboxes)
+		m.outboxMu.Unlock()
+		if pending == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain delivers the outbox of the channel called name in order until it
+// is empty.
+func (m *ChannelManager) drain(name string) {
+	for {
+		m.outboxMu.Lock()
+		queue := m.outboxes[name]
+		if len(queue) == 0 {
+			delete(m.outboxes, name)
+			m.outboxMu.Unlock()
+			return
+		}
+		msg := queue[0]
+		m.outboxes[name] = queue[1:]
+		m.outboxMu.Unlock()
+
+		ch, ok := m.Channel(name)
+		if !ok {
+			log.Printf("[channel-mgr] %s is disabled, dropping message to %s", name, msg.ChatID)
+			continue
+		}
+		m.deliver(ch, msg)
+	}
+}
+
+// unregister forgets the channel called name.
+func (m *ChannelManager) unregister(name string) {
+	m.mu.Lock()
+	delete(m.channels, name)
+	delete(m.settings, name)
+	m.mu.Unlock()
+	m.statusMu.Lock()
+	delete(m.status, name)
+	m.statusMu.Unlock()
+}
+
+func (m *ChannelManager) deliver(ch Channel, msg bus.OutboundMessage) {
+	if m.route != nil && m.route(msg) {
+		return
+	}
+
+	attempts := m.sendAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+	backoff := m.retryBackoff
+	if backoff == nil {
+		backoff = func(attempt int) time.Duration {
+			return time.Duration(attempt) * time.Second
+		}
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = ch.Send(msg); err == nil {
+			m.setStatus(ch.Name(), func(s *Status) { s.SendError, s.LastSendAt = "", time.Now() })
+			return
+		}
+		log.Printf("[channel-mgr] send to %s failed (attempt %d/%d): %v", ch.Name(), attempt, attempts, err)
+		if attempt < attempts {
+			time.Sleep(backoff(attempt))
+		}
+	}
+
+	m.setStatus(ch.Name(), func(s *Status) { s.SendError, s.LastErrorAt = err.Error(), time.Now() })
+	if m.onDeadLetter != nil {
+		m.onDeadLetter(msg, err, attempts)
+	}
+}
+
+func (m *ChannelManager) setStatus(name string, update func(*Status)) {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	if s, ok := m.status[name]; ok {
+		update(s)
+	}
+}
+
+// started records the outcome of starting a channel.
+func (m *ChannelManager) started(name string, err error) {
+	m.setStatus(name, func(s *Status) {
+		s.Running, s.StartError = err == nil, ""
+		if err != nil {
+			s.StartError = err.Error()
+		}
+	})
+}
+
+// Status returns what the manager has seen of each registered channel.
+func (m *ChannelManager) Status() map[string]Status {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	out := make(map[string]Status, len(m.status))
+	for name, s := range m.status {
+		out[name] = *s
+	}
+	return out
+}
+
+// Channel returns the registered channel called name.
+func (m *ChannelManager) Channel(name string) (Channel, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ch, ok := m.channels[name]
+	return ch, ok
+}
+
+// snapshot returns the registered channels by name.
+func (m *ChannelManager) snapshot() map[string]Channel {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return maps.Clone(m.channels)
 }
 
 func (m *ChannelManager) StartAll(ctx context.Context) error {
+	channels := m.snapshot()
 	var wg sync.WaitGroup
-	errCh := make(chan error, len(m.channels))
+	errCh := make(chan error, len(channels))
 
-	for name, ch := range m.channels {
+	for name, ch := range channels {
 		wg.Add(1)
 		go func(name string, ch Channel) {
 			defer wg.Done()
 			log.Printf("[channel-mgr] starting %s", name)
-			if err := ch.Start(ctx); err != nil {
+			err := ch.Start(ctx)
+			m.started(name, err)
+			if err != nil {
 				errCh <- fmt.Errorf("%s: %w", name, err)
 			}
 		}(name, ch)
@@ -122,8 +492,31 @@ func (m *ChannelManager) StartAll(ctx context.Context) error {
 	return nil
 }
 
+// Start starts a single registered channel.
+func (m *ChannelManager) Start(ctx context.Context, name string) error {
+	ch, ok := m.Channel(name)
+	if !ok {
+		return fmt.Errorf("unknown channel %q", name)
+	}
+	log.Printf("[channel-mgr] starting %s", name)
+	err := ch.Start(ctx)
+	m.started(name, err)
+	return err
+}
+
+// Stop stops a single registered channel.
+func (m *ChannelManager) Stop(name string) error {
+	ch, ok := m.Channel(name)
+	if !ok {
+		return fmt.Errorf("unknown channel %q", name)
+	}
+	log.Printf("[channel-mgr] stopping %s", name)
+	m.setStatus(name, func(s *Status) { s.Running = false })
+	return ch.Stop()
+}
+
 func (m *ChannelManager) StopAll() error {
-	for name, ch := range m.channels {
+	for name, ch := range m.snapshot() {
 		log.Printf("[channel-mgr] stopping %s", name)
 		if err := ch.Stop(); err != nil {
 			log.Printf("[channel-mgr] error stopping %s: %v", name, err)
@@ -133,6 +526,8 @@ func (m *ChannelManager) StopAll() error {
 }
 
 func (m *ChannelManager) EnabledChannels() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	names := make([]string, 0, len(m.channels))
 	for name := range m.channels {
 		names = append(names, name)
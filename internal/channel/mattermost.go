@@ -0,0 +1,399 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const mattermostChannelName = "mattermost"
+
+const (
+	// mattermostPostMaxRunes is the server's limit on a post's message.
+	mattermostPostMaxRunes = 16383
+	// mattermostFilesPerPost is how many files one post can carry.
+	mattermostFilesPerPost = 5
+
+	mattermostReconnectBaseDelay = time.Second
+	mattermostReconnectMaxDelay  = time.Minute
+)
+
+// mattermostPost is a Mattermost post, as sent and as received.
+type mattermostPost struct {
+	ID        string   `json:"id,omitempty"`
+	ChannelID string   `json:"channel_id"`
+	UserID    string   `json:"user_id,omitempty"`
+	RootID    string   `json:"root_id,omitempty"`
+	Message   string   `json:"message"`
+	Type      string   `json:"type,omitempty"`
+	FileIDs   []string `json:"file_ids,omitempty"`
+}
+
+// MattermostClient interface for the Mattermost API (allows mocking)
+type MattermostClient interface {
+	// Me returns the bot's user ID and username.
+	Me(ctx context.Context) (id, username string, err error)
+	CreatePost(ctx context.Context, post mattermostPost) error
+	// UploadFile uploads a file to a channel and returns its ID, to attach
+	// to a post.
+	UploadFile(ctx context.Context, channelID, path string) (string, error)
+	// Connect opens the WebSocket event stream.
+	Connect(ctx context.Context) (*websocket.Conn, error)
+}
+
+type defaultMattermostClient struct {
+	baseURL string // server URL without the trailing slash
+	token   string
+	http    *http.Client
+}
+
+func (c *defaultMattermostClient) do(ctx context.Context, path, contentType string, body io.Reader, out any) error {
+	method := http.MethodGet
+	if body != nil {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v4"+path, body)
+	if err != nil {
+		return fmt.Errorf("create %s request: %w", path, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("mattermost %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read %s response: %w", path, err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("mattermost %s: %s: %s", path, resp.Status, apiErr.Message)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+func (c *defaultMattermostClient) Me(ctx context.Context) (string, string, error) {
+	var me struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	}
+	if err := c.do(ctx, "/users/me", "", nil, &me); err != nil {
+		return "", "", err
+	}
+	return me.ID, me.Username, nil
+}
+
+func (c *defaultMattermostClient) CreatePost(ctx context.Context, post mattermostPost) error {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, "/posts", "application/json", bytes.NewReader(data), nil)
+}
+
+func (c *defaultMattermostClient) UploadFile(ctx context.Context, channelID, path string) (string, error) {
+	body, contentType, err := multipartFile("files", path, map[string]string{"channel_id": channelID})
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		FileInfos []struct {
+			ID string `json:"id"`
+		} `json:"file_infos"`
+	}
+	if err := c.do(ctx, "/files", contentType, body, &out); err != nil {
+		return "", err
+	}
+	if len(out.FileInfos) == 0 {
+		return "", fmt.Errorf("mattermost kept no file from %s", filepath.Base(path))
+	}
+	return out.FileInfos[0].ID, nil
+}
+
+func (c *defaultMattermostClient) Connect(ctx context.Context) (*websocket.Conn, error) {
+	wsURL := "ws" + strings.TrimPrefix(c.baseURL, "http") + "/api/v4/websocket"
+	conn, _, err := websocket.Dial(ctx, wsURL, &websocket.DialOptions{
+		HTTPHeader: http.Header{"Authorization": {"Bearer " + c.token}},
+	})
+	return conn, err
+}
+
+// multipartFile builds a multipart form with the file at path in field and
+// the given plain fields.
+func multipartFile(field, path string, fields map[string]string) (*bytes.Buffer, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+	part, err := mw.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return nil, "", err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return &body, mw.FormDataContentType(), nil
+}
+
+// MattermostClientFactory creates MattermostClient instances
+type MattermostClientFactory func(cfg config.MattermostConfig) MattermostClient
+
+var defaultMattermostClientFactory MattermostClientFactory = func(cfg config.MattermostConfig) MattermostClient {
+	return &defaultMattermostClient{
+		baseURL: strings.TrimRight(cfg.URL, "/"),
+		token:   cfg.Token,
+		http:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// MattermostChannel answers direct messages, and posts that mention the bot
+// in the channels it is a member of. Each Mattermost channel is one session;
+// replies go to the thread of the post answered, except in direct messages
+// that were not in a thread.
+type MattermostChannel struct {
+	BaseChannel
+	cfg           config.MattermostConfig
+	client        MattermostClient
+	clientFactory MattermostClientFactory
+	cancel        context.CancelFunc
+	botID         string
+	mentionRe     *regexp.Regexp
+
+	mu      sync.Mutex
+	threads map[string]string // chat id -> root post to reply under
+}
+
+func NewMattermostChannel(cfg config.MattermostConfig, b *bus.MessageBus) (*MattermostChannel, error) {
+	return NewMattermostChannelWithFactory(cfg, b, defaultMattermostClientFactory)
+}
+
+func NewMattermostChannelWithFactory(cfg config.MattermostConfig, b *bus.MessageBus, factory MattermostClientFactory) (*MattermostChannel, error) {
+	if cfg.URL == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("mattermost url and token are required")
+	}
+	return &MattermostChannel{
+		BaseChannel:   NewBaseChannelWithAccess(mattermostChannelName, b, Access{AllowFrom: cfg.AllowFrom, DenyFrom: cfg.DenyFrom, DeniedReply: cfg.DeniedReply}),
+		cfg:           cfg,
+		clientFactory: factory,
+		threads:       make(map[string]string),
+	}, nil
+}
+
+func (m *MattermostChannel) Start(ctx context.Context) error {
+	m.client = m.clientFactory(m.cfg)
+	id, username, err := m.client.Me(ctx)
+	if err != nil {
+		return fmt.Errorf("get bot user: %w", err)
+	}
+	m.botID = id
+	m.mentionRe = regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(username) + `\b`)
+	ctx, m.cancel = context.WithCancel(ctx)
+	go m.socketLoop(ctx)
+	log.Printf("[mattermost] connecting as @%s", username)
+	return nil
+}
+
+func (m *MattermostChannel) Stop() error {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	log.Printf("[mattermost] stopped")
+	return nil
+}
+
+// socketLoop keeps the event stream open, reconnecting with backoff.
+func (m *MattermostChannel) socketLoop(ctx context.Context) {
+	for attempt := 0; ; attempt++ {
+		connected, err := m.runSocket(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			attempt = 0
+		}
+		delay := min(mattermostReconnectBaseDelay<<min(attempt, 6), mattermostReconnectMaxDelay)
+		log.Printf("[mattermost] socket error: %v (reconnecting in %s)", err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runSocket reads events until the connection drops. It reports whether
+// the server said hello first.
+func (m *MattermostChannel) runSocket(ctx context.Context) (bool, error) {
+	conn, err := m.client.Connect(ctx)
+	if err != nil {
+		return false, fmt.Errorf("dial socket: %w", err)
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(1 << 20)
+
+	connected := false
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return connected, fmt.Errorf("read socket: %w", err)
+		}
+		var ev struct {
+			Event string `json:"event"`
+			Data  struct {
+				ChannelType string `json:"channel_type"`
+				Post        string `json:"post"`
+				SenderName  string `json:"sender_name"`
+				Mentions    string `json:"mentions"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(data, &ev); err != nil {
+			log.Printf("[mattermost] bad socket frame: %v", err)
+			continue
+		}
+		switch ev.Event {
+		case "hello":
+			connected = true
+			log.Printf("[mattermost] connected")
+		case "posted":
+			var post mattermostPost
+			if err := json.Unmarshal([]byte(ev.Data.Post), &post); err != nil {
+				log.Printf("[mattermost] bad post: %v", err)
+				continue
+			}
+			var mentions []string
+			json.Unmarshal([]byte(ev.Data.Mentions), &mentions)
+			m.handlePost(post, ev.Data.ChannelType, strings.TrimPrefix(ev.Data.SenderName, "@"), mentions)
+		}
+	}
+}
+
+func (m *MattermostChannel) handlePost(post mattermostPost, channelType, sender string, mentions []string) {
+	// Only plain user posts; joins, header changes and our own replies are
+	// skipped.
+	if post.UserID == "" || post.UserID == m.botID || post.Type != "" {
+		return
+	}
+
+	direct := channelType == "D"
+	threadID := post.RootID
+	if !direct {
+		if !slices.Contains(mentions, m.botID) {
+			return
+		}
+		if threadID == "" {
+			threadID = post.ID
+		}
+	}
+
+	chatID := post.ChannelID
+	if !m.IsAllowed(post.UserID, sender, post.ChannelID) {
+		m.Reject(chatID, sender)
+		return
+	}
+
+	content := strings.TrimSpace(m.mentionRe.ReplaceAllString(post.Message, ""))
+	if content == "" {
+		return
+	}
+
+	m.mu.Lock()
+	if threadID != "" {
+		m.threads[chatID] = threadID
+	} else {
+		delete(m.threads, chatID)
+	}
+	m.mu.Unlock()
+
+	m.bus.Inbound <- bus.InboundMessage{
+		Channel:   mattermostChannelName,
+		SenderID:  post.UserID,
+		ChatID:    chatID,
+		Content:   content,
+		Timestamp: time.Now(),
+		Metadata: map[string]any{
+			"post_id":  post.ID,
+			"root_id":  threadID,
+			"username": sender,
+		},
+	}
+}
+
+// Send posts the reply in the thread of the post it answers, splitting long
+// text and attaching media to the last part.
+func (m *MattermostChannel) Send(msg bus.OutboundMessage) error {
+	if m.client == nil {
+		return fmt.Errorf("mattermost client not initialized")
+	}
+	ctx := context.Background()
+	m.mu.Lock()
+	rootID := m.threads[msg.ChatID]
+	m.mu.Unlock()
+
+	var fileIDs []string
+	for _, path := range msg.Media {
+		id, err := m.client.UploadFile(ctx, msg.ChatID, path)
+		if err != nil {
+			return fmt.Errorf("upload %s: %w", filepath.Base(path), err)
+		}
+		fileIDs = append(fileIDs, id)
+	}
+
+	chunks := splitMessage(msg.Content, mattermostPostMaxRunes, runeCount)
+	for i, chunk := range chunks {
+		post := mattermostPost{ChannelID: msg.ChatID, RootID: rootID, Message: chunk}
+		if i == len(chunks)-1 {
+			n := min(len(fileIDs), mattermostFilesPerPost)
+			post.FileIDs, fileIDs = fileIDs[:n], fileIDs[n:]
+		}
+		if post.Message == "" && len(post.FileIDs) == 0 {
+			continue
+		}
+		if err := m.client.CreatePost(ctx, post); err != nil {
+			return err
+		}
+	}
+	for batch := range slices.Chunk(fileIDs, mattermostFilesPerPost) {
+		if err := m.client.CreatePost(ctx, mattermostPost{ChannelID: msg.ChatID, RootID: rootID, FileIDs: batch}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
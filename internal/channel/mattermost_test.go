@@ -0,0 +1,142 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/coder/websocket"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// mattermostEvent builds a posted event as the server sends it, with the
+// post and mentions encoded as strings.
+func mattermostEvent(channelType, sender string, post mattermostPost, mentions ...string) []byte {
+	postJSON, _ := json.Marshal(post)
+	data := map[string]any{"channel_type": channelType, "post": string(postJSON), "sender_name": "@" + sender}
+	if len(mentions) > 0 {
+		m, _ := json.Marshal(mentions)
+		data["mentions"] = string(m)
+	}
+	ev, _ := json.Marshal(map[string]any{"event": "posted", "data": data})
+	return ev
+}
+
+func TestMattermostChannel(t *testing.T) {
+	if _, err := NewMattermostChannel(config.MattermostConfig{URL: "https://chat.example.org"}, bus.NewMessageBus(1)); err == nil {
+		t.Error("missing token accepted")
+	}
+
+	var (
+		mu      sync.Mutex
+		posts   []mattermostPost
+		uploads []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"bad token"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v4/users/me":
+			w.Write([]byte(`{"id":"bot1","username":"claw"}`))
+		case "/api/v4/posts":
+			var p mattermostPost
+			json.NewDecoder(r.Body).Decode(&p)
+			mu.Lock()
+			posts = append(posts, p)
+			mu.Unlock()
+			w.Write([]byte(`{"id":"reply"}`))
+		case "/api/v4/files":
+			_, header, err := r.FormFile("files")
+			if err != nil || r.FormValue("channel_id") != "town" {
+				t.Errorf("upload: %v, channel %q", err, r.FormValue("channel_id"))
+			} else {
+				mu.Lock()
+				uploads = append(uploads, header.Filename)
+				mu.Unlock()
+			}
+			w.Write([]byte(`{"file_infos":[{"id":"f1"}]}`))
+		case "/api/v4/websocket":
+			conn, err := websocket.Accept(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.CloseNow()
+			ctx := r.Context()
+			for _, ev := range [][]byte{
+				[]byte(`{"event":"hello","data":{}}`),
+				mattermostEvent("D", "eve", mattermostPost{ID: "p0", ChannelID: "dm3", UserID: "u3", Message: "hi"}),
+				mattermostEvent("D", "alice", mattermostPost{ID: "p1", ChannelID: "dm1", UserID: "u1", Message: "what's up?"}),
+				mattermostEvent("O", "bob", mattermostPost{ID: "p2", ChannelID: "town", UserID: "u2", Message: "lunch?"}),
+				mattermostEvent("O", "bob", mattermostPost{ID: "p3", ChannelID: "town", UserID: "u2", Message: "bob joined", Type: "system_join_channel"}, "bot1"),
+				mattermostEvent("O", "claw", mattermostPost{ID: "p4", ChannelID: "town", UserID: "bot1", Message: "@claw echo"}, "bot1"),
+				mattermostEvent("O", "bob", mattermostPost{ID: "p5", ChannelID: "town", UserID: "u2", Message: "@claw is the build green?"}, "bot1"),
+			} {
+				conn.Write(ctx, websocket.MessageText, ev)
+			}
+			conn.Read(ctx) // hold the connection until the client goes away
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	b := bus.NewMessageBus(10)
+	ch, err := NewMattermostChannel(config.MattermostConfig{URL: srv.URL + "/", Token: "tok", AllowFrom: []string{"@alice", "town"}}, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer ch.Stop()
+
+	if got := nextInbound(t, b); got.ChatID != "dm1" || got.SenderID != "u1" || got.Content != "what's up?" {
+		t.Errorf("direct = %+v", got)
+	}
+	got := nextInbound(t, b)
+	if got.ChatID != "town" || got.Content != "is the build green?" || got.Metadata["root_id"] != "p5" {
+		t.Errorf("mention = %+v", got)
+	}
+	if len(b.Inbound) != 0 {
+		t.Errorf("%d more messages passed on", len(b.Inbound))
+	}
+
+	file := filepath.Join(t.TempDir(), "report.txt")
+	os.WriteFile(file, []byte("all green"), 0o644)
+	if err := ch.Send(bus.OutboundMessage{ChatID: "town", Content: "Green.", Media: []string{file}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(bus.OutboundMessage{ChatID: "dm1", Content: "Not much."}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(posts) != 2 || posts[0].RootID != "p5" || posts[0].Message != "Green." || len(posts[0].FileIDs) != 1 || posts[1].RootID != "" || posts[1].ChannelID != "dm1" {
+		t.Errorf("posts = %+v", posts)
+	}
+	if len(uploads) != 1 || uploads[0] != "report.txt" {
+		t.Errorf("uploads = %v", uploads)
+	}
+}
+
+func TestDefaultMattermostClient_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"You do not have the appropriate permissions."}`))
+	}))
+	defer srv.Close()
+	client := &defaultMattermostClient{baseURL: srv.URL, token: "tok", http: srv.Client()}
+	err := client.CreatePost(context.Background(), mattermostPost{ChannelID: "town", Message: "hi"})
+	if err == nil || err.Error() != "mattermost /posts: 403 Forbidden: You do not have the appropriate permissions." {
+		t.Errorf("err = %v", err)
+	}
+}
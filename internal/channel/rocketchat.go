@@ -0,0 +1,423 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const rocketChatChannelName = "rocketchat"
+
+const (
+	// rocketChatMessageMaxRunes is the server's default Message_MaxAllowedSize.
+	rocketChatMessageMaxRunes = 5000
+	// rocketChatSeenKept is how many message IDs are remembered. The stream
+	// sends a message again whenever it changes, as when someone reacts to
+	// it or replies in its thread.
+	rocketChatSeenKept = 500
+
+	rocketChatReconnectBaseDelay = time.Second
+	rocketChatReconnectMaxDelay  = time.Minute
+)
+
+// rocketChatMessage is a message on the realtime API's room message stream.
+type rocketChatMessage struct {
+	ID       string `json:"_id"`
+	RoomID   string `json:"rid"`
+	Msg      string `json:"msg"`
+	ThreadID string `json:"tmid"`
+	Type     string `json:"t"` // set on system messages
+	User     struct {
+		ID       string `json:"_id"`
+		Username string `json:"username"`
+	} `json:"u"`
+	Mentions []struct {
+		ID string `json:"_id"`
+	} `json:"mentions"`
+	EditedAt json.RawMessage `json:"editedAt"`
+}
+
+// RocketChatClient interface for the Rocket.Chat API (allows mocking)
+type RocketChatClient interface {
+	// Me returns the bot's username.
+	Me(ctx context.Context) (string, error)
+	SendMessage(ctx context.Context, roomID, text, threadID string) error
+	Upload(ctx context.Context, roomID, path, threadID string) error
+	// Connect opens the realtime API's WebSocket, not yet logged in.
+	Connect(ctx context.Context) (*websocket.Conn, error)
+}
+
+type defaultRocketChatClient struct {
+	baseURL string // server URL without the trailing slash
+	userID  string
+	token   string
+	http    *http.Client
+}
+
+func (c *defaultRocketChatClient) do(ctx context.Context, path, contentType string, body io.Reader, out any) error {
+	method := http.MethodGet
+	if body != nil {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v1"+path, body)
+	if err != nil {
+		return fmt.Errorf("create %s request: %w", path, err)
+	}
+	req.Header.Set("X-User-Id", c.userID)
+	req.Header.Set("X-Auth-Token", c.token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("rocketchat %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read %s response: %w", path, err)
+	}
+	var result struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	json.Unmarshal(data, &result)
+	if resp.StatusCode >= 300 || !result.Success {
+		return fmt.Errorf("rocketchat %s: %s: %s", path, resp.Status, result.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+func (c *defaultRocketChatClient) Me(ctx context.Context) (string, error) {
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err := c.do(ctx, "/me", "", nil, &me); err != nil {
+		return "", err
+	}
+	return me.Username, nil
+}
+
+func (c *defaultRocketChatClient) SendMessage(ctx context.Context, roomID, text, threadID string) error {
+	message := map[string]string{"rid": roomID, "msg": text}
+	if threadID != "" {
+		message["tmid"] = threadID
+	}
+	data, err := json.Marshal(map[string]any{"message": message})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, "/chat.sendMessage", "application/json", bytes.NewReader(data), nil)
+}
+
+func (c *defaultRocketChatClient) Upload(ctx context.Context, roomID, path, threadID string) error {
+	fields := map[string]string{}
+	if threadID != "" {
+		fields["tmid"] = threadID
+	}
+	body, contentType, err := multipartFile("file", path, fields)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, "/rooms.upload/"+roomID, contentType, body, nil)
+}
+
+func (c *defaultRocketChatClient) Connect(ctx context.Context) (*websocket.Conn, error) {
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(c.baseURL, "http")+"/websocket", nil)
+	return conn, err
+}
+
+// RocketChatClientFactory creates RocketChatClient instances
+type RocketChatClientFactory func(cfg config.RocketChatConfig) RocketChatClient
+
+var defaultRocketChatClientFactory RocketChatClientFactory = func(cfg config.RocketChatConfig) RocketChatClient {
+	return &defaultRocketChatClient{
+		baseURL: strings.TrimRight(cfg.URL, "/"),
+		userID:  cfg.UserID,
+		token:   cfg.Token,
+		http:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// RocketChatChannel answers direct messages, and messages that mention the
+// bot in the rooms it is in, over the realtime API. Each room is one
+// session; replies go to the thread of the message answered, except in
+// direct messages that were not in a thread.
+type RocketChatChannel struct {
+	BaseChannel
+	cfg           config.RocketChatConfig
+	client        RocketChatClient
+	clientFactory RocketChatClientFactory
+	cancel        context.CancelFunc
+	mentionRe     *regexp.Regexp
+
+	mu       sync.Mutex
+	threads  map[string]string // chat id -> thread to reply in
+	seen     map[string]bool
+	seenList []string
+}
+
+func NewRocketChatChannel(cfg config.RocketChatConfig, b *bus.MessageBus) (*RocketChatChannel, error) {
+	return NewRocketChatChannelWithFactory(cfg, b, defaultRocketChatClientFactory)
+}
+
+func NewRocketChatChannelWithFactory(cfg config.RocketChatConfig, b *bus.MessageBus, factory RocketChatClientFactory) (*RocketChatChannel, error) {
+	if cfg.URL == "" || cfg.UserID == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("rocketchat url, userId and token are required")
+	}
+	return &RocketChatChannel{
+		BaseChannel:   NewBaseChannelWithAccess(rocketChatChannelName, b, Access{AllowFrom: cfg.AllowFrom, DenyFrom: cfg.DenyFrom, DeniedReply: cfg.DeniedReply}),
+		cfg:           cfg,
+		clientFactory: factory,
+		threads:       make(map[string]string),
+		seen:          make(map[string]bool),
+	}, nil
+}
+
+func (r *RocketChatChannel) Start(ctx context.Context) error {
+	r.client = r.clientFactory(r.cfg)
+	username, err := r.client.Me(ctx)
+	if err != nil {
+		return fmt.Errorf("get bot user: %w", err)
+	}
+	r.mentionRe = regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(username) + `\b`)
+	ctx, r.cancel = context.WithCancel(ctx)
+	go r.socketLoop(ctx)
+	log.Printf("[rocketchat] connecting as @%s", username)
+	return nil
+}
+
+func (r *RocketChatChannel) Stop() error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	log.Printf("[rocketchat] stopped")
+	return nil
+}
+
+// socketLoop keeps the realtime connection open, reconnecting with backoff.
+func (r *RocketChatChannel) socketLoop(ctx context.Context) {
+	for attempt := 0; ; attempt++ {
+		connected, err := r.runSocket(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			attempt = 0
+		}
+		delay := min(rocketChatReconnectBaseDelay<<min(attempt, 6), rocketChatReconnectMaxDelay)
+		log.Printf("[rocketchat] socket error: %v (reconnecting in %s)", err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runSocket speaks DDP: it connects, logs in with the access token as a
+// resume token, subscribes to the messages of all the bot's rooms and
+// handles them until the connection drops. It reports whether it got as
+// far as logging in.
+func (r *RocketChatChannel) runSocket(ctx context.Context) (bool, error) {
+	conn, err := r.client.Connect(ctx)
+	if err != nil {
+		return false, fmt.Errorf("dial socket: %w", err)
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(1 << 20)
+
+	send := func(v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return conn.Write(ctx, websocket.MessageText, data)
+	}
+	if err := send(map[string]any{"msg": "connect", "version": "1", "support": []string{"1"}}); err != nil {
+		return false, err
+	}
+
+	loggedIn := false
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return loggedIn, fmt.Errorf("read socket: %w", err)
+		}
+		var frame struct {
+			Msg        string `json:"msg"`
+			ID         string `json:"id"`
+			Collection string `json:"collection"`
+			Error      *struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+			Fields struct {
+				Args []json.RawMessage `json:"args"`
+			} `json:"fields"`
+		}
+		if err := json.Unmarshal(data, &frame); err != nil {
+			log.Printf("[rocketchat] bad socket frame: %v", err)
+			continue
+		}
+
+		switch frame.Msg {
+		case "ping":
+			err = send(map[string]string{"msg": "pong"})
+		case "connected":
+			err = send(map[string]any{"msg": "method", "method": "login", "id": "login", "params": []any{map[string]string{"resume": r.cfg.Token}}})
+		case "result":
+			if frame.ID != "login" {
+				continue
+			}
+			if frame.Error != nil {
+				return false, fmt.Errorf("login: %s", frame.Error.Reason)
+			}
+			loggedIn = true
+			err = send(map[string]any{"msg": "sub", "id": "messages", "name": "stream-room-messages", "params": []any{"__my_messages__", false}})
+		case "ready":
+			log.Printf("[rocketchat] connected")
+		case "nosub":
+			reason := "refused"
+			if frame.Error != nil {
+				reason = frame.Error.Reason
+			}
+			return loggedIn, fmt.Errorf("subscribe to messages: %s", reason)
+		case "changed":
+			if frame.Collection != "stream-room-messages" || len(frame.Fields.Args) == 0 {
+				continue
+			}
+			var msg rocketChatMessage
+			var room struct {
+				RoomType string `json:"roomType"`
+			}
+			if err := json.Unmarshal(frame.Fields.Args[0], &msg); err != nil {
+				log.Printf("[rocketchat] bad message: %v", err)
+				continue
+			}
+			if len(frame.Fields.Args) > 1 {
+				json.Unmarshal(frame.Fields.Args[1], &room)
+			}
+			r.handleMessage(msg, room.RoomType)
+		}
+		if err != nil {
+			return loggedIn, err
+		}
+	}
+}
+
+// firstSight reports whether the message with id has not been handled
+// before, and remembers it.
+func (r *RocketChatChannel) firstSight(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen[id] {
+		return false
+	}
+	r.seen[id] = true
+	r.seenList = append(r.seenList, id)
+	if len(r.seenList) > rocketChatSeenKept {
+		delete(r.seen, r.seenList[0])
+		r.seenList = r.seenList[1:]
+	}
+	return true
+}
+
+func (r *RocketChatChannel) handleMessage(msg rocketChatMessage, roomType string) {
+	// Only new plain user messages; edits, joins and our own replies are
+	// skipped.
+	if msg.User.ID == "" || msg.User.ID == r.cfg.UserID || msg.Type != "" || len(msg.EditedAt) > 0 {
+		return
+	}
+	if !r.firstSight(msg.ID) {
+		return
+	}
+
+	direct := roomType == "d"
+	threadID := msg.ThreadID
+	if !direct {
+		mentioned := false
+		for _, m := range msg.Mentions {
+			mentioned = mentioned || m.ID == r.cfg.UserID
+		}
+		if !mentioned {
+			return
+		}
+		if threadID == "" {
+			threadID = msg.ID
+		}
+	}
+
+	chatID := msg.RoomID
+	if !r.IsAllowed(msg.User.ID, msg.User.Username, msg.RoomID) {
+		r.Reject(chatID, msg.User.Username)
+		return
+	}
+
+	content := strings.TrimSpace(r.mentionRe.ReplaceAllString(msg.Msg, ""))
+	if content == "" {
+		return
+	}
+
+	r.mu.Lock()
+	if threadID != "" {
+		r.threads[chatID] = threadID
+	} else {
+		delete(r.threads, chatID)
+	}
+	r.mu.Unlock()
+
+	r.bus.Inbound <- bus.InboundMessage{
+		Channel:   rocketChatChannelName,
+		SenderID:  msg.User.ID,
+		ChatID:    chatID,
+		Content:   content,
+		Timestamp: time.Now(),
+		Metadata: map[string]any{
+			"message_id": msg.ID,
+			"thread_id":  threadID,
+			"username":   msg.User.Username,
+		},
+	}
+}
+
+// Send posts the reply in the thread of the message it answers, splitting
+// long text, then uploads its media there.
+func (r *RocketChatChannel) Send(msg bus.OutboundMessage) error {
+	if r.client == nil {
+		return fmt.Errorf("rocketchat client not initialized")
+	}
+	ctx := context.Background()
+	r.mu.Lock()
+	threadID := r.threads[msg.ChatID]
+	r.mu.Unlock()
+
+	if msg.Content != "" {
+		for _, chunk := range splitMessage(msg.Content, rocketChatMessageMaxRunes, runeCount) {
+			if err := r.client.SendMessage(ctx, msg.ChatID, chunk, threadID); err != nil {
+				return err
+			}
+		}
+	}
+	for _, path := range msg.Media {
+		if err := r.client.Upload(ctx, msg.ChatID, path, threadID); err != nil {
+			return fmt.Errorf("upload %s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
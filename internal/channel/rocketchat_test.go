@@ -0,0 +1,154 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/coder/websocket"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// rocketChatChanged builds a room message stream update.
+func rocketChatChanged(roomType, msg string) []byte {
+	return []byte(`{"msg":"changed","collection":"stream-room-messages","id":"id","fields":{"eventName":"__my_messages__","args":[` + msg + `,{"roomType":"` + roomType + `"}]}}`)
+}
+
+func TestRocketChatChannel(t *testing.T) {
+	if _, err := NewRocketChatChannel(config.RocketChatConfig{URL: "https://chat.example.org", Token: "tok"}, bus.NewMessageBus(1)); err == nil {
+		t.Error("missing userId accepted")
+	}
+
+	type sent struct{ room, text, thread string }
+	var (
+		mu      sync.Mutex
+		msgs    []sent
+		uploads []sent
+		frames  []map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/websocket" {
+			conn, err := websocket.Accept(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.CloseNow()
+			ctx := r.Context()
+			read := func() map[string]any {
+				_, data, err := conn.Read(ctx)
+				if err != nil {
+					return nil
+				}
+				var frame map[string]any
+				json.Unmarshal(data, &frame)
+				mu.Lock()
+				frames = append(frames, frame)
+				mu.Unlock()
+				return frame
+			}
+			write := func(s string) { conn.Write(ctx, websocket.MessageText, []byte(s)) }
+
+			read() // connect
+			write(`{"msg":"connected","session":"s1"}`)
+			if login := read(); login["method"] != "login" {
+				t.Errorf("login = %v", login)
+			}
+			write(`{"msg":"result","id":"login","result":{"id":"bot1","token":"tok"}}`)
+			read() // sub
+			write(`{"msg":"ready","subs":["messages"]}`)
+			write(`{"msg":"ping"}`)
+			read() // pong
+			for _, frame := range [][]byte{
+				rocketChatChanged("d", `{"_id":"m1","rid":"dm1","msg":"hello","u":{"_id":"u1","username":"alice"}}`),
+				rocketChatChanged("d", `{"_id":"m1","rid":"dm1","msg":"hello","u":{"_id":"u1","username":"alice"},"reactions":{":+1:":{}}}`),
+				rocketChatChanged("c", `{"_id":"m2","rid":"town","msg":"lunch?","u":{"_id":"u2","username":"bob"}}`),
+				rocketChatChanged("c", `{"_id":"m3","rid":"town","msg":"@claw deploy?","u":{"_id":"u2","username":"bob"},"mentions":[{"_id":"bot1","username":"claw"}],"editedAt":{"$date":1}}`),
+				rocketChatChanged("c", `{"_id":"m4","rid":"town","msg":"bob joined","t":"uj","u":{"_id":"u2","username":"bob"}}`),
+				rocketChatChanged("c", `{"_id":"m5","rid":"town","msg":"@claw is the build green?","u":{"_id":"u2","username":"bob"},"mentions":[{"_id":"bot1","username":"claw"}]}`),
+			} {
+				conn.Write(ctx, websocket.MessageText, frame)
+			}
+			read() // hold the connection until the client goes away
+			return
+		}
+
+		if r.Header.Get("X-User-Id") != "bot1" || r.Header.Get("X-Auth-Token") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"success":false,"error":"You must be logged in to do this."}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/me":
+			w.Write([]byte(`{"_id":"bot1","username":"claw","success":true}`))
+		case "/api/v1/chat.sendMessage":
+			var body struct {
+				Message map[string]string `json:"message"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			msgs = append(msgs, sent{body.Message["rid"], body.Message["msg"], body.Message["tmid"]})
+			mu.Unlock()
+			w.Write([]byte(`{"success":true}`))
+		case "/api/v1/rooms.upload/town":
+			_, header, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("upload: %v", err)
+			} else {
+				mu.Lock()
+				uploads = append(uploads, sent{"town", header.Filename, r.FormValue("tmid")})
+				mu.Unlock()
+			}
+			w.Write([]byte(`{"success":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	b := bus.NewMessageBus(10)
+	ch, err := NewRocketChatChannel(config.RocketChatConfig{URL: srv.URL, UserID: "bot1", Token: "tok", AllowFrom: []string{"alice", "town"}}, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer ch.Stop()
+
+	if got := nextInbound(t, b); got.ChatID != "dm1" || got.SenderID != "u1" || got.Content != "hello" {
+		t.Errorf("direct = %+v", got)
+	}
+	got := nextInbound(t, b)
+	if got.ChatID != "town" || got.Content != "is the build green?" || got.Metadata["thread_id"] != "m5" {
+		t.Errorf("mention = %+v", got)
+	}
+	if len(b.Inbound) != 0 {
+		t.Errorf("%d more messages passed on", len(b.Inbound))
+	}
+
+	file := filepath.Join(t.TempDir(), "chart.png")
+	os.WriteFile(file, []byte("png"), 0o644)
+	if err := ch.Send(bus.OutboundMessage{ChatID: "town", Content: "Green.", Media: []string{file}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(bus.OutboundMessage{ChatID: "dm1", Content: "Hi!"}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(msgs) != 2 || msgs[0] != (sent{"town", "Green.", "m5"}) || msgs[1] != (sent{"dm1", "Hi!", ""}) {
+		t.Errorf("messages = %+v", msgs)
+	}
+	if len(uploads) != 1 || uploads[0] != (sent{"town", "chart.png", "m5"}) {
+		t.Errorf("uploads = %+v", uploads)
+	}
+	if len(frames) < 4 || frames[0]["msg"] != "connect" || frames[2]["name"] != "stream-room-messages" || frames[3]["msg"] != "pong" {
+		t.Errorf("frames = %v", frames)
+	}
+}
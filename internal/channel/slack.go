@@ -0,0 +1,490 @@
+package channel
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const slackChannelName = "slack"
+
+const (
+	slackAPIBase            = "https://slack.com/api/"
+	slackDefaultPort        = 9877
+	slackSignatureMaxSkew   = 5 * time.Minute
+	slackReconnectBaseDelay = time.Second
+	slackReconnectMaxDelay  = time.Minute
+	// slackFileMaxBytes caps a file downloaded from a message; larger ones
+	// are skipped.
+	slackFileMaxBytes = 20 << 20
+	slackFileTimeout  = 30 * time.Second
+)
+
+var slackMentionRe = regexp.MustCompile(`<@[A-Z0-9]+>`)
+
+// SlackClient interface for the Slack Web API (allows mocking)
+type SlackClient interface {
+	PostMessage(ctx context.Context, channel, text, threadTS string) error
+	// OpenConnection returns a Socket Mode WebSocket URL.
+	OpenConnection(ctx context.Context) (string, error)
+	// DownloadFile fetches a file's url_private_download, which needs the
+	// files:read scope.
+	DownloadFile(ctx context.Context, url string) ([]byte, error)
+}
+
+type defaultSlackClient struct {
+	botToken string
+	appToken string
+	baseURL  string
+	http     *http.Client
+}
+
+func (c *defaultSlackClient) call(ctx context.Context, method, token string, payload any, out any) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("marshal %s payload: %w", method, err)
+		}
+		body = strings.NewReader(string(data))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+method, body)
+	if err != nil {
+		return fmt.Errorf("create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read %s response: %w", method, err)
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack %s error: %s", method, result.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+func (c *defaultSlackClient) PostMessage(ctx context.Context, channel, text, threadTS string) error {
+	payload := map[string]string{"channel": channel, "text": text}
+	if threadTS != "" {
+		payload["thread_ts"] = threadTS
+	}
+	return c.call(ctx, "chat.postMessage", c.botToken, payload, nil)
+}
+
+func (c *defaultSlackClient) OpenConnection(ctx context.Context) (string, error) {
+	var out struct {
+		URL string `json:"url"`
+	}
+	if err := c.call(ctx, "apps.connections.open", c.appToken, nil, &out); err != nil {
+		return "", err
+	}
+	return out.URL, nil
+}
+
+func (c *defaultSlackClient) DownloadFile(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create file request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.botToken)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("slack download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("slack download: status %d", resp.StatusCode)
+	}
+	// Without files:read Slack answers with its sign-in page instead.
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return nil, fmt.Errorf("slack download: got a web page; does the app have the files:read scope?")
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, slackFileMaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("slack download: %w", err)
+	}
+	if len(data) > slackFileMaxBytes {
+		return nil, fmt.Errorf("slack download: file exceeds %d bytes", slackFileMaxBytes)
+	}
+	return data, nil
+}
+
+// SlackClientFactory creates SlackClient instances
+type SlackClientFactory func(botToken, appToken string) SlackClient
+
+var defaultSlackClientFactory SlackClientFactory = func(botToken, appToken string) SlackClient {
+	return &defaultSlackClient{
+		botToken: botToken,
+		appToken: appToken,
+		baseURL:  slackAPIBase,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SlackChannel receives messages over Socket Mode (when an app token is
+// configured) or the Events API. A message in a thread has the chat ID
+// "<channel>:<thread_ts>", so each thread is its own session and the reply
+// goes back to it even after a restart. Other direct messages map to one
+// session per DM; a mention outside a thread starts one at the message.
+type SlackChannel struct {
+	BaseChannel
+	cfg           config.SlackConfig
+	client        SlackClient
+	clientFactory SlackClientFactory
+	server        *http.Server
+	cancel        context.CancelFunc
+	now           func() time.Time
+}
+
+func NewSlackChannel(cfg config.SlackConfig, b *bus.MessageBus) (*SlackChannel, error) {
+	return NewSlackChannelWithFactory(cfg, b, defaultSlackClientFactory)
+}
+
+func NewSlackChannelWithFactory(cfg config.SlackConfig, b *bus.MessageBus, factory SlackClientFactory) (*SlackChannel, error) {
+	if cfg.BotToken == "" {
+		return nil, fmt.Errorf("slack botToken is required")
+	}
+	if cfg.AppToken == "" && cfg.SigningSecret == "" {
+		return nil, fmt.Errorf("slack appToken (Socket Mode) or signingSecret (Events API) is required")
+	}
+
+	return &SlackChannel{
+		BaseChannel:   NewBaseChannelWithAccess(slackChannelName, b, Access{AllowFrom: cfg.AllowFrom, DenyFrom: cfg.DenyFrom, DeniedReply: cfg.DeniedReply}),
+		cfg:           cfg,
+		clientFactory: factory,
+		now:           time.Now,
+	}, nil
+}
+
+func (s *SlackChannel) socketMode() bool {
+	return s.cfg.AppToken != ""
+}
+
+func (s *SlackChannel) Start(ctx context.Context) error {
+	s.client = s.clientFactory(s.cfg.BotToken, s.cfg.AppToken)
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	if s.socketMode() {
+		go s.socketLoop(ctx)
+		log.Printf("[slack] connecting in socket mode")
+		return nil
+	}
+
+	port := s.cfg.Port
+	if port == 0 {
+		port = slackDefaultPort
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slack/events", s.handleEvents)
+	s.server = &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+	}
+
+	go func() {
+		log.Printf("[slack] events server listening on :%d", port)
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("[slack] server error: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		s.server.Close()
+	}()
+
+	return nil
+}
+
+func (s *SlackChannel) Stop() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if s.server != nil {
+		s.server.Close()
+	}
+	log.Printf("[slack] stopped")
+	return nil
+}
+
+func (s *SlackChannel) Send(msg bus.OutboundMessage) error {
+	if s.client == nil {
+		return fmt.Errorf("slack client not initialized")
+	}
+	channel, threadTS, _ := strings.Cut(msg.ChatID, ":")
+	return s.client.PostMessage(context.Background(), channel, msg.Content, threadTS)
+}
+
+// socketLoop keeps a Socket Mode connection open, reconnecting with backoff.
+func (s *SlackChannel) socketLoop(ctx context.Context) {
+	for attempt := 0; ; attempt++ {
+		err := s.runSocket(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			// Slack asked us to reconnect; do it right away.
+			attempt = -1
+			continue
+		}
+
+		delay := slackReconnectBaseDelay << min(attempt, 6)
+		if delay > slackReconnectMaxDelay {
+			delay = slackReconnectMaxDelay
+		}
+		log.Printf("[slack] socket error: %v (reconnecting in %s)", err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *SlackChannel) runSocket(ctx context.Context) error {
+	wsURL, err := s.client.OpenConnection(ctx)
+	if err != nil {
+		return err
+	}
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("dial socket: %w", err)
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(1 << 20)
+
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return fmt.Errorf("read socket: %w", err)
+		}
+
+		var env struct {
+			EnvelopeID string          `json:"envelope_id"`
+			Type       string          `json:"type"`
+			Payload    json.RawMessage `json:"payload"`
+		}
+		if err := json.Unmarshal(data, &env); err != nil {
+			log.Printf("[slack] bad socket frame: %v", err)
+			continue
+		}
+
+		if env.EnvelopeID != "" {
+			ack, _ := json.Marshal(map[string]string{"envelope_id": env.EnvelopeID})
+			if err := conn.Write(ctx, websocket.MessageText, ack); err != nil {
+				return fmt.Errorf("ack envelope: %w", err)
+			}
+		}
+
+		switch env.Type {
+		case "hello":
+			log.Printf("[slack] socket mode connected")
+		case "disconnect":
+			conn.Close(websocket.StatusNormalClosure, "")
+			return nil
+		case "events_api":
+			s.handleEventPayload(env.Payload)
+		}
+	}
+}
+
+func (s *SlackChannel) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "read body failed", http.StatusBadRequest)
+		return
+	}
+
+	if !s.verifySignature(r.Header.Get("X-Slack-Request-Timestamp"), r.Header.Get("X-Slack-Signature"), body) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var envelope struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	if envelope.Type == "url_verification" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"challenge": envelope.Challenge})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	// Slack resends events it thinks timed out; we already have them.
+	if r.Header.Get("X-Slack-Retry-Num") != "" {
+		return
+	}
+	if envelope.Type == "event_callback" {
+		s.handleEventPayload(body)
+	}
+}
+
+func (s *SlackChannel) verifySignature(timestamp, signature string, body []byte) bool {
+	if s.cfg.SigningSecret == "" {
+		return false
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := s.now().Sub(time.Unix(ts, 0)); d > slackSignatureMaxSkew || d < -slackSignatureMaxSkew {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.cfg.SigningSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// slackFile is a file shared in a message.
+type slackFile struct {
+	Name               string `json:"name"`
+	Mimetype           string `json:"mimetype"`
+	Size               int64  `json:"size"`
+	URLPrivateDownload string `json:"url_private_download"`
+}
+
+// handleEventPayload handles an event_callback body from either transport.
+func (s *SlackChannel) handleEventPayload(data []byte) {
+	var callback struct {
+		Event struct {
+			Type        string      `json:"type"`
+			Subtype     string      `json:"subtype"`
+			User        string      `json:"user"`
+			BotID       string      `json:"bot_id"`
+			Text        string      `json:"text"`
+			Channel     string      `json:"channel"`
+			ChannelType string      `json:"channel_type"`
+			TS          string      `json:"ts"`
+			ThreadTS    string      `json:"thread_ts"`
+			Files       []slackFile `json:"files"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(data, &callback); err != nil {
+		log.Printf("[slack] parse event error: %v", err)
+		return
+	}
+	ev := callback.Event
+
+	// Only user messages, with or without files; edits, joins and our own
+	// replies are skipped.
+	if ev.BotID != "" || (ev.Subtype != "" && ev.Subtype != "file_share") || ev.User == "" {
+		return
+	}
+
+	threadTS := ev.ThreadTS
+	switch {
+	case ev.Type == "message" && ev.ChannelType == "im":
+	case ev.Type == "app_mention":
+		if threadTS == "" {
+			threadTS = ev.TS
+		}
+	default:
+		return
+	}
+	chatID := ev.Channel
+	if threadTS != "" {
+		chatID += ":" + threadTS
+	}
+
+	if !s.IsAllowed(ev.User, ev.Channel) {
+		s.Reject(chatID, ev.User)
+		return
+	}
+
+	content := strings.TrimSpace(slackMentionRe.ReplaceAllString(ev.Text, ""))
+	if content == "" && len(ev.Files) == 0 {
+		return
+	}
+
+	msg := bus.InboundMessage{
+		Channel:   slackChannelName,
+		SenderID:  ev.User,
+		ChatID:    chatID,
+		Content:   content,
+		Timestamp: time.Now(),
+		Metadata: map[string]any{
+			"slack_channel": ev.Channel,
+			"thread_ts":     threadTS,
+		},
+	}
+	if len(ev.Files) == 0 {
+		s.bus.Inbound <- msg
+		return
+	}
+	// Downloads run apart so the event is acknowledged within Slack's
+	// three seconds.
+	go func() {
+		msg.Attachments = s.downloadFiles(ev.Files)
+		if msg.Content == "" && len(msg.Attachments) == 0 {
+			return
+		}
+		s.bus.Inbound <- msg
+	}()
+}
+
+// downloadFiles fetches the files of a message for the gateway to read,
+// skipping any it cannot.
+func (s *SlackChannel) downloadFiles(files []slackFile) []bus.Attachment {
+	ctx, cancel := context.WithTimeout(context.Background(), slackFileTimeout)
+	defer cancel()
+	var attachments []bus.Attachment
+	for _, f := range files {
+		if f.URLPrivateDownload == "" || f.Size > slackFileMaxBytes {
+			log.Printf("[slack] skipping file %q", f.Name)
+			continue
+		}
+		data, err := s.client.DownloadFile(ctx, f.URLPrivateDownload)
+		if err != nil {
+			log.Printf("[slack] download %q: %v", f.Name, err)
+			continue
+		}
+		attachments = append(attachments, bus.Attachment{Name: f.Name, MediaType: f.Mimetype, Data: data})
+	}
+	return attachments
+}
@@ -0,0 +1,355 @@
+package channel
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+type slackPost struct {
+	channel, text, threadTS string
+}
+
+type mockSlackClient struct {
+	posts   []slackPost
+	postErr error
+	wsURL   string
+	files   map[string][]byte
+}
+
+func (m *mockSlackClient) PostMessage(ctx context.Context, channel, text, threadTS string) error {
+	m.posts = append(m.posts, slackPost{channel, text, threadTS})
+	return m.postErr
+}
+
+func (m *mockSlackClient) OpenConnection(ctx context.Context) (string, error) {
+	if m.wsURL == "" {
+		return "", fmt.Errorf("no socket")
+	}
+	return m.wsURL, nil
+}
+
+func (m *mockSlackClient) DownloadFile(ctx context.Context, url string) ([]byte, error) {
+	data, ok := m.files[url]
+	if !ok {
+		return nil, fmt.Errorf("no file at %s", url)
+	}
+	return data, nil
+}
+
+func newTestSlackChannel(t *testing.T, cfg config.SlackConfig, client *mockSlackClient) (*SlackChannel, *bus.MessageBus) {
+	t.Helper()
+	if cfg.BotToken == "" {
+		cfg.BotToken = "xoxb-test"
+	}
+	if cfg.AppToken == "" && cfg.SigningSecret == "" {
+		cfg.SigningSecret = "secret"
+	}
+	b := bus.NewMessageBus(10)
+	ch, err := NewSlackChannelWithFactory(cfg, b, func(botToken, appToken string) SlackClient {
+		return client
+	})
+	if err != nil {
+		t.Fatalf("NewSlackChannelWithFactory error: %v", err)
+	}
+	ch.client = client
+	return ch, b
+}
+
+func TestNewSlackChannel_Validation(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	if _, err := NewSlackChannel(config.SlackConfig{AppToken: "xapp"}, b); err == nil {
+		t.Error("expected error for missing bot token")
+	}
+	if _, err := NewSlackChannel(config.SlackConfig{BotToken: "xoxb"}, b); err == nil {
+		t.Error("expected error without app token or signing secret")
+	}
+	ch, err := NewSlackChannel(config.SlackConfig{BotToken: "xoxb", AppToken: "xapp"}, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ch.Name() != "slack" || !ch.socketMode() {
+		t.Errorf("name = %q, socketMode = %v", ch.Name(), ch.socketMode())
+	}
+}
+
+func TestSlackChannel_DirectMessage(t *testing.T) {
+	client := &mockSlackClient{}
+	ch, b := newTestSlackChannel(t, config.SlackConfig{}, client)
+
+	ch.handleEventPayload([]byte(`{"event":{"type":"message","channel_type":"im","user":"U1","channel":"D9","text":"hello","ts":"1.1"}}`))
+
+	msg := <-b.Inbound
+	if msg.ChatID != "D9" || msg.SenderID != "U1" || msg.Content != "hello" {
+		t.Errorf("inbound = %+v", msg)
+	}
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: "D9", Content: "hi"}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(client.posts) != 1 || client.posts[0] != (slackPost{"D9", "hi", ""}) {
+		t.Errorf("posts = %+v", client.posts)
+	}
+}
+
+func TestSlackChannel_MentionRepliesInThread(t *testing.T) {
+	client := &mockSlackClient{}
+	ch, b := newTestSlackChannel(t, config.SlackConfig{}, client)
+
+	ch.handleEventPayload([]byte(`{"event":{"type":"app_mention","user":"U1","channel":"C5","text":"<@UBOT> summarize this","ts":"100.1"}}`))
+	ch.handleEventPayload([]byte(`{"event":{"type":"app_mention","user":"U1","channel":"C5","text":"<@UBOT> and this","ts":"200.1","thread_ts":"150.0"}}`))
+	ch.handleEventPayload([]byte(`{"event":{"type":"message","channel_type":"im","user":"U1","channel":"D9","text":"in a thread","ts":"300.1","thread_ts":"250.0"}}`))
+
+	first, second, third := <-b.Inbound, <-b.Inbound, <-b.Inbound
+	if first.ChatID != "C5:100.1" || second.ChatID != "C5:150.0" || third.ChatID != "D9:250.0" {
+		t.Fatalf("chat ids = %q, %q, %q; want one session per thread", first.ChatID, second.ChatID, third.ChatID)
+	}
+	if first.Content != "summarize this" {
+		t.Errorf("mention should be stripped, got %q", first.Content)
+	}
+
+	// Replies find their thread from the chat ID alone, as after a restart.
+	restarted, _ := newTestSlackChannel(t, config.SlackConfig{}, client)
+	restarted.Send(bus.OutboundMessage{ChatID: second.ChatID, Content: "you too"})
+	restarted.Send(bus.OutboundMessage{ChatID: first.ChatID, Content: "done"})
+	restarted.Send(bus.OutboundMessage{ChatID: third.ChatID, Content: "sure"})
+	want := []slackPost{{"C5", "you too", "150.0"}, {"C5", "done", "100.1"}, {"D9", "sure", "250.0"}}
+	if len(client.posts) != len(want) {
+		t.Fatalf("posts = %+v", client.posts)
+	}
+	for i, post := range client.posts {
+		if post != want[i] {
+			t.Errorf("reply %d = %+v, want %+v", i, post, want[i])
+		}
+	}
+}
+
+func TestSlackChannel_Files(t *testing.T) {
+	client := &mockSlackClient{files: map[string][]byte{
+		"https://files.slack.com/voice.m4a": []byte("audio"),
+		"https://files.slack.com/notes.pdf": []byte("%PDF-1.4"),
+	}}
+	ch, b := newTestSlackChannel(t, config.SlackConfig{}, client)
+
+	ch.handleEventPayload([]byte(`{"event":{"type":"message","subtype":"file_share","channel_type":"im","user":"U1","channel":"D9","text":"","ts":"1.1","files":[` +
+		`{"name":"voice.m4a","mimetype":"audio/mp4","size":5,"url_private_download":"https://files.slack.com/voice.m4a"},` +
+		`{"name":"notes.pdf","mimetype":"application/pdf","size":8,"url_private_download":"https://files.slack.com/notes.pdf"},` +
+		`{"name":"huge.mov","mimetype":"video/quicktime","size":999999999,"url_private_download":"https://files.slack.com/huge.mov"},` +
+		`{"name":"gone.txt","mimetype":"text/plain","size":1,"url_private_download":"https://files.slack.com/gone.txt"}]}}`))
+
+	select {
+	case msg := <-b.Inbound:
+		if msg.ChatID != "D9" || len(msg.Attachments) != 2 {
+			t.Fatalf("inbound = %+v", msg)
+		}
+		if a := msg.Attachments[0]; a.Name != "voice.m4a" || a.MediaType != "audio/mp4" || string(a.Data) != "audio" {
+			t.Errorf("first attachment = %+v", a)
+		}
+		if msg.Attachments[1].MediaType != "application/pdf" {
+			t.Errorf("second attachment = %+v", msg.Attachments[1])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected inbound message with files")
+	}
+}
+
+func TestSlackChannel_IgnoresBotsAndDisallowed(t *testing.T) {
+	ch, b := newTestSlackChannel(t, config.SlackConfig{AllowFrom: []string{"U1"}}, &mockSlackClient{})
+
+	ch.handleEventPayload([]byte(`{"event":{"type":"message","channel_type":"im","bot_id":"B1","user":"U1","channel":"D1","text":"echo"}}`))
+	ch.handleEventPayload([]byte(`{"event":{"type":"message","channel_type":"im","subtype":"message_changed","user":"U1","channel":"D1","text":"edit"}}`))
+	ch.handleEventPayload([]byte(`{"event":{"type":"message","channel_type":"im","user":"U2","channel":"D2","text":"stranger"}}`))
+	ch.handleEventPayload([]byte(`{"event":{"type":"message","channel_type":"channel","user":"U1","channel":"C1","text":"chatter"}}`))
+
+	select {
+	case msg := <-b.Inbound:
+		t.Errorf("unexpected inbound: %+v", msg)
+	default:
+	}
+}
+
+func TestSlackChannel_Send_NilClient(t *testing.T) {
+	ch, err := NewSlackChannel(config.SlackConfig{BotToken: "xoxb", SigningSecret: "s"}, bus.NewMessageBus(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(bus.OutboundMessage{ChatID: "D1", Content: "x"}); err == nil {
+		t.Error("expected error before Start")
+	}
+}
+
+func signSlack(secret, ts, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSlackChannel_HandleEvents(t *testing.T) {
+	ch, b := newTestSlackChannel(t, config.SlackConfig{SigningSecret: "shh"}, &mockSlackClient{})
+	now := time.Unix(1700000000, 0)
+	ch.now = func() time.Time { return now }
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	post := func(body, sig string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/slack/events", strings.NewReader(body))
+		req.Header.Set("X-Slack-Request-Timestamp", ts)
+		req.Header.Set("X-Slack-Signature", sig)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		ch.handleEvents(w, req)
+		return w
+	}
+
+	challenge := `{"type":"url_verification","challenge":"abc"}`
+	w := post(challenge, signSlack("shh", ts, challenge), nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"challenge":"abc"`) {
+		t.Errorf("challenge response = %d %s", w.Code, w.Body.String())
+	}
+
+	if w := post(challenge, signSlack("wrong", ts, challenge), nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature status = %d, want 401", w.Code)
+	}
+
+	huge := `{"type":"url_verification","challenge":"` + strings.Repeat("x", 2<<20) + `"}`
+	if w := post(huge, signSlack("shh", ts, huge), nil); w.Code != http.StatusBadRequest {
+		t.Errorf("oversized body status = %d, want 400", w.Code)
+	}
+
+	event := `{"type":"event_callback","event":{"type":"message","channel_type":"im","user":"U1","channel":"D1","text":"ping"}}`
+	post(event, signSlack("shh", ts, event), map[string]string{"X-Slack-Retry-Num": "1"})
+	select {
+	case msg := <-b.Inbound:
+		t.Errorf("retried event should be dropped: %+v", msg)
+	default:
+	}
+
+	if w := post(event, signSlack("shh", ts, event), nil); w.Code != http.StatusOK {
+		t.Fatalf("event status = %d", w.Code)
+	}
+	select {
+	case msg := <-b.Inbound:
+		if msg.Content != "ping" {
+			t.Errorf("inbound = %+v", msg)
+		}
+	default:
+		t.Error("expected inbound message")
+	}
+}
+
+func TestSlackChannel_VerifySignature_Stale(t *testing.T) {
+	ch, _ := newTestSlackChannel(t, config.SlackConfig{SigningSecret: "shh"}, &mockSlackClient{})
+	old := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	if ch.verifySignature(old, signSlack("shh", old, "{}"), []byte("{}")) {
+		t.Error("stale timestamps should be rejected")
+	}
+}
+
+func TestSlackChannel_SocketMode(t *testing.T) {
+	acks := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		ctx := r.Context()
+		conn.Write(ctx, websocket.MessageText, []byte(`{"type":"hello"}`))
+		conn.Write(ctx, websocket.MessageText, []byte(`{"envelope_id":"e1","type":"events_api","payload":{"event":{"type":"message","channel_type":"im","user":"U1","channel":"D1","text":"over socket"}}}`))
+		_, data, err := conn.Read(ctx)
+		if err == nil {
+			acks <- string(data)
+		}
+		conn.Read(ctx) // hold the connection until the client goes away
+	}))
+	defer srv.Close()
+
+	client := &mockSlackClient{wsURL: "ws" + strings.TrimPrefix(srv.URL, "http")}
+	ch, b := newTestSlackChannel(t, config.SlackConfig{AppToken: "xapp"}, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ch.socketLoop(ctx)
+
+	select {
+	case msg := <-b.Inbound:
+		if msg.Content != "over socket" || msg.ChatID != "D1" {
+			t.Errorf("inbound = %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for socket event")
+	}
+	select {
+	case ack := <-acks:
+		var got map[string]string
+		json.Unmarshal([]byte(ack), &got)
+		if got["envelope_id"] != "e1" {
+			t.Errorf("ack = %s", ack)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("envelope was not acknowledged")
+	}
+}
+
+func TestDefaultSlackClient_DownloadFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>sign in</html>"))
+			return
+		}
+		w.Write([]byte("file body"))
+	}))
+	defer srv.Close()
+
+	client := &defaultSlackClient{botToken: "xoxb", http: srv.Client()}
+	data, err := client.DownloadFile(context.Background(), srv.URL+"/f")
+	if err != nil || string(data) != "file body" {
+		t.Errorf("DownloadFile = %q, %v", data, err)
+	}
+	client.botToken = "wrong"
+	if _, err := client.DownloadFile(context.Background(), srv.URL+"/f"); err == nil || !strings.Contains(err.Error(), "files:read") {
+		t.Errorf("expected a files:read hint, got %v", err)
+	}
+}
+
+func TestDefaultSlackClient_PostMessage(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" || r.Header.Get("Authorization") != "Bearer xoxb" {
+			t.Errorf("request = %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		if got["channel"] == "bad" {
+			w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	client := &defaultSlackClient{botToken: "xoxb", baseURL: srv.URL + "/", http: srv.Client()}
+	if err := client.PostMessage(context.Background(), "C1", "hi", "1.0"); err != nil {
+		t.Fatalf("PostMessage error: %v", err)
+	}
+	if got["thread_ts"] != "1.0" || got["text"] != "hi" {
+		t.Errorf("payload = %v", got)
+	}
+	if err := client.PostMessage(context.Background(), "bad", "hi", ""); err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("expected slack error, got %v", err)
+	}
+}
@@ -3,14 +3,19 @@ package channel
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/cexll/agentsdk-go/pkg/model"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -20,26 +25,119 @@ import (
 
 const telegramChannelName = "telegram"
 
+// telegramChunkLimit keeps a reply chunk, with the escapes MarkdownV2 adds,
+// under Telegram's 4096-character message limit. A chunk that still ends up
+// too long is resent as plain text.
+const telegramChunkLimit = 3500
+
+// Buttons share a row when there are at most telegramRowButtons of them,
+// none with a label longer than telegramRowLabel characters.
+const (
+	telegramRowButtons = 3
+	telegramRowLabel   = 16
+)
+
+// A file downloaded from a message may be at most telegramFileMaxBytes, the
+// most the Bot API serves, and must arrive within telegramFileTimeout.
+const (
+	telegramFileMaxBytes = 20 << 20
+	telegramFileTimeout  = 30 * time.Second
+)
+
 // TelegramBot interface for mocking telegram bot API
 type TelegramBot interface {
-	GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel
+	GetUpdatesChan(config tgbotapi.UpdateConfig) <-chan TelegramUpdate
 	StopReceivingUpdates()
 	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
 	GetSelf() tgbotapi.User
 	GetFile(config tgbotapi.FileConfig) (tgbotapi.File, error)
 }
 
+// TelegramUpdate is an update with the forum topic its message is in, which
+// tgbotapi does not decode.
+type TelegramUpdate struct {
+	tgbotapi.Update
+	// ThreadID is the topic of the message, or of the message whose
+	// button was pressed; 0 outside forum topics.
+	ThreadID int
+}
+
+// decodeTelegramUpdates decodes the result of getUpdates.
+func decodeTelegramUpdates(raw json.RawMessage) ([]TelegramUpdate, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	type topic struct {
+		ThreadID int  `json:"message_thread_id"`
+		IsTopic  bool `json:"is_topic_message"`
+	}
+	updates := make([]TelegramUpdate, 0, len(items))
+	for _, item := range items {
+		var u TelegramUpdate
+		if err := json.Unmarshal(item, &u.Update); err != nil {
+			return nil, err
+		}
+		var extra struct {
+			Message       *topic `json:"message"`
+			CallbackQuery *struct {
+				Message *topic `json:"message"`
+			} `json:"callback_query"`
+		}
+		_ = json.Unmarshal(item, &extra)
+		msg := extra.Message
+		if extra.CallbackQuery != nil {
+			msg = extra.CallbackQuery.Message
+		}
+		if msg != nil && msg.IsTopic {
+			u.ThreadID = msg.ThreadID
+		}
+		updates = append(updates, u)
+	}
+	return updates, nil
+}
+
 // tgBotWrapper wraps tgbotapi.BotAPI to implement TelegramBot interface
 type tgBotWrapper struct {
-	bot *tgbotapi.BotAPI
+	bot  *tgbotapi.BotAPI
+	stop chan struct{}
 }
 
-func (w *tgBotWrapper) GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
-	return w.bot.GetUpdatesChan(config)
+// GetUpdatesChan long-polls for updates like tgbotapi's own, decoding the
+// forum topic of each as well.
+func (w *tgBotWrapper) GetUpdatesChan(config tgbotapi.UpdateConfig) <-chan TelegramUpdate {
+	ch := make(chan TelegramUpdate, w.bot.Buffer)
+	go func() {
+		for {
+			select {
+			case <-w.stop:
+				close(ch)
+				return
+			default:
+			}
+			resp, err := w.bot.Request(config)
+			var updates []TelegramUpdate
+			if err == nil {
+				updates, err = decodeTelegramUpdates(resp.Result)
+			}
+			if err != nil {
+				log.Printf("[telegram] get updates failed, retrying in 3s: %v", err)
+				time.Sleep(3 * time.Second)
+				continue
+			}
+			for _, u := range updates {
+				if u.UpdateID >= config.Offset {
+					config.Offset = u.UpdateID + 1
+					ch <- u
+				}
+			}
+		}
+	}()
+	return ch
 }
 
 func (w *tgBotWrapper) StopReceivingUpdates() {
-	w.bot.StopReceivingUpdates()
+	close(w.stop)
 }
 
 func (w *tgBotWrapper) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
@@ -63,12 +161,13 @@ var defaultBotFactory BotFactory = func(token, apiEndpoint string, client *http.
 	if err != nil {
 		return nil, err
 	}
-	return &tgBotWrapper{bot: bot}, nil
+	return &tgBotWrapper{bot: bot, stop: make(chan struct{})}, nil
 }
 
 type TelegramChannel struct {
 	BaseChannel
 	token      string
+	groups     string // which group messages to answer: config.TelegramGroups*
 	bot        TelegramBot
 	proxy      string
 	httpClient *http.Client
@@ -87,8 +186,9 @@ func NewTelegramChannelWithFactory(cfg config.TelegramConfig, b *bus.MessageBus,
 	}
 
 	ch := &TelegramChannel{
-		BaseChannel: NewBaseChannel(telegramChannelName, b, cfg.AllowFrom),
+		BaseChannel: NewBaseChannelWithAccess(telegramChannelName, b, Access{AllowFrom: cfg.AllowFrom, DenyFrom: cfg.DenyFrom, DeniedReply: cfg.DeniedReply}),
 		token:       cfg.Token,
+		groups:      cfg.Groups,
 		proxy:       cfg.Proxy,
 		httpClient:  http.DefaultClient,
 		botFactory:  factory,
@@ -135,10 +235,12 @@ func (t *TelegramChannel) Start(ctx context.Context) error {
 		for {
 			select {
 			case update := <-updates:
-				if update.Message == nil {
-					continue
+				switch {
+				case update.Message != nil:
+					t.handleMessage(update.Message, update.ThreadID)
+				case update.CallbackQuery != nil:
+					t.handleCallback(update.CallbackQuery, update.ThreadID)
 				}
-				t.handleMessage(update.Message)
 			case <-ctx.Done():
 				return
 			}
@@ -149,17 +251,31 @@ func (t *TelegramChannel) Start(ctx context.Context) error {
 	return nil
 }
 
-func (t *TelegramChannel) handleMessage(msg *tgbotapi.Message) {
+// handleMessage passes msg, from the forum topic threadID if it is not 0,
+// on to the bus. Each topic is a chat of its own, "<chat id>:<topic id>".
+// In groups, only messages meant for the bot are passed on, without the
+// mention that said so.
+func (t *TelegramChannel) handleMessage(msg *tgbotapi.Message, threadID int) {
 	senderID := strconv.FormatInt(msg.From.ID, 10)
-
-	if !t.IsAllowed(senderID) {
-		log.Printf("[telegram] rejected message from %s (%s)", senderID, msg.From.UserName)
-		return
-	}
+	chatID := telegramChatID(msg.Chat.ID, threadID)
 
 	content := msg.Text
+	entities := msg.Entities
 	if content == "" && msg.Caption != "" {
 		content = msg.Caption
+		entities = msg.CaptionEntities
+	}
+	if msg.Chat.IsGroup() || msg.Chat.IsSuperGroup() {
+		self := t.bot.GetSelf()
+		if t.groups != config.TelegramGroupsAll && !addressedToBot(msg, content, entities, self, threadID) {
+			return
+		}
+		content = stripMention(content, self.UserName)
+	}
+
+	if !t.IsAllowed(senderID, msg.From.UserName, strconv.FormatInt(msg.Chat.ID, 10)) {
+		t.Reject(chatID, senderID+" ("+msg.From.UserName+")")
+		return
 	}
 
 	contentBlocks := make([]model.ContentBlock, 0, 2)
@@ -182,6 +298,9 @@ func (t *TelegramChannel) handleMessage(msg *tgbotapi.Message) {
 		}
 	}
 
+	// Voice notes and non-image documents are read by the gateway, which
+	// transcribes audio and turns documents into text or blocks.
+	var attachments []bus.Attachment
 	if msg.Document != nil {
 		data, err := t.downloadFileData(msg.Document.FileID)
 		if err != nil {
@@ -191,28 +310,28 @@ func (t *TelegramChannel) handleMessage(msg *tgbotapi.Message) {
 			if mediaType == "" {
 				mediaType = http.DetectContentType(data)
 			}
-			if mediaType == "" {
-				mediaType = "application/octet-stream"
-			}
-			// Use image block type for image MIME types sent as documents
-			blockType := model.ContentBlockDocument
 			if strings.HasPrefix(mediaType, "image/") {
-				blockType = model.ContentBlockImage
+				contentBlocks = append(contentBlocks, model.ContentBlock{
+					Type:      model.ContentBlockImage,
+					MediaType: mediaType,
+					Data:      base64.StdEncoding.EncodeToString(data),
+				})
+			} else {
+				attachments = append(attachments, bus.Attachment{Name: msg.Document.FileName, MediaType: mediaType, Data: data})
 			}
-			contentBlocks = append(contentBlocks, model.ContentBlock{
-				Type:      blockType,
-				MediaType: mediaType,
-				Data:      base64.StdEncoding.EncodeToString(data),
-			})
 		}
 	}
+	if msg.Voice != nil {
+		attachments = t.appendAudio(attachments, msg.Voice.FileID, "", msg.Voice.MimeType)
+	}
+	if msg.Audio != nil {
+		attachments = t.appendAudio(attachments, msg.Audio.FileID, msg.Audio.FileName, msg.Audio.MimeType)
+	}
 
-	if content == "" && len(contentBlocks) == 0 {
+	if content == "" && len(contentBlocks) == 0 && len(attachments) == 0 {
 		return
 	}
 
-	chatID := strconv.FormatInt(msg.Chat.ID, 10)
-
 	t.bus.Inbound <- bus.InboundMessage{
 		Channel:       telegramChannelName,
 		SenderID:      senderID,
@@ -220,6 +339,7 @@ func (t *TelegramChannel) handleMessage(msg *tgbotapi.Message) {
 		Content:       content,
 		Timestamp:     time.Unix(int64(msg.Date), 0),
 		ContentBlocks: contentBlocks,
+		Attachments:   attachments,
 		Metadata: map[string]any{
 			"username":   msg.From.UserName,
 			"first_name": msg.From.FirstName,
@@ -228,6 +348,156 @@ func (t *TelegramChannel) handleMessage(msg *tgbotapi.Message) {
 	}
 }
 
+// telegramChatID is the bus chat ID of the forum topic threadID in chat,
+// or of chat itself when threadID is 0.
+func telegramChatID(chat int64, threadID int) string {
+	id := strconv.FormatInt(chat, 10)
+	if threadID != 0 {
+		id += ":" + strconv.Itoa(threadID)
+	}
+	return id
+}
+
+// parseTelegramChatID splits a bus chat ID into the chat and forum topic.
+func parseTelegramChatID(id string) (int64, int, error) {
+	chat, topic, _ := strings.Cut(id, ":")
+	chatID, err := strconv.ParseInt(chat, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid chat id %q: %w", id, err)
+	}
+	var threadID int
+	if topic != "" {
+		if threadID, err = strconv.Atoi(topic); err != nil {
+			return 0, 0, fmt.Errorf("invalid chat id %q: %w", id, err)
+		}
+	}
+	return chatID, threadID, nil
+}
+
+// addressedToBot reports whether a group message is meant for the bot: it
+// mentions the bot, replies to one of its messages, or is a command not
+// addressed to another bot.
+func addressedToBot(msg *tgbotapi.Message, text string, entities []tgbotapi.MessageEntity, self tgbotapi.User, threadID int) bool {
+	// In a topic, messages that reply to nothing in particular reply to the
+	// message that started it.
+	if r := msg.ReplyToMessage; r != nil && r.From != nil && r.From.ID == self.ID && r.MessageID != threadID {
+		return true
+	}
+	for _, e := range entities {
+		switch e.Type {
+		case "text_mention":
+			if e.User != nil && e.User.ID == self.ID {
+				return true
+			}
+		case "mention":
+			if self.UserName != "" && strings.EqualFold(entityText(text, e), "@"+self.UserName) {
+				return true
+			}
+		case "bot_command":
+			if e.Offset != 0 {
+				continue
+			}
+			_, to, ok := strings.Cut(entityText(text, e), "@")
+			if !ok || strings.EqualFold(to, self.UserName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stripMention removes @username from text, and from commands addressed
+// with it, so the agent sees only what was asked.
+func stripMention(text, username string) string {
+	if username == "" {
+		return text
+	}
+	re := regexp.MustCompile(`(?i)[ \t]*@` + regexp.QuoteMeta(username) + `\b`)
+	return strings.TrimSpace(re.ReplaceAllString(text, ""))
+}
+
+// entityText is the part of text e covers. Telegram counts entity offsets
+// in UTF-16 code units.
+func entityText(text string, e tgbotapi.MessageEntity) string {
+	units := utf16.Encode([]rune(text))
+	if e.Offset < 0 || e.Length < 0 || e.Offset+e.Length > len(units) {
+		return ""
+	}
+	return string(utf16.Decode(units[e.Offset : e.Offset+e.Length]))
+}
+
+// handleCallback turns a press of one of our buttons into a message from the
+// presser, so it passes the same access checks as typed text. The buttons
+// are then taken off the message, so that the question is answered once.
+func (t *TelegramChannel) handleCallback(q *tgbotapi.CallbackQuery, threadID int) {
+	if q.From == nil || q.Message == nil || q.Message.Chat == nil || q.Data == "" {
+		t.answerCallback(q.ID, "")
+		return
+	}
+	senderID := strconv.FormatInt(q.From.ID, 10)
+	chatID := telegramChatID(q.Message.Chat.ID, threadID)
+	if !t.IsAllowed(senderID, q.From.UserName, strconv.FormatInt(q.Message.Chat.ID, 10)) {
+		t.answerCallback(q.ID, "")
+		return
+	}
+	if keyboard := q.Message.ReplyMarkup; keyboard != nil {
+		t.answerCallback(q.ID, "✓ "+buttonText(keyboard, q.Data))
+		edit := tgbotapi.NewEditMessageReplyMarkup(q.Message.Chat.ID, q.Message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+		if _, err := t.bot.Send(edit); err != nil {
+			log.Printf("[telegram] remove buttons failed: %v", err)
+		}
+	} else {
+		t.answerCallback(q.ID, "")
+	}
+	t.bus.Inbound <- bus.InboundMessage{
+		Channel:   telegramChannelName,
+		SenderID:  senderID,
+		ChatID:    chatID,
+		Content:   q.Data,
+		Timestamp: time.Now(),
+		Metadata: map[string]any{
+			"username":   q.From.UserName,
+			"first_name": q.From.FirstName,
+			"message_id": q.Message.MessageID,
+		},
+	}
+}
+
+// answerCallback stops the pressed button's loading spinner, showing text
+// briefly if it is set.
+func (t *TelegramChannel) answerCallback(id, text string) {
+	// Telegram answers with true rather than a message, which Send reports
+	// as an error.
+	_, _ = t.bot.Send(tgbotapi.NewCallback(id, text))
+}
+
+// buttonText is the label of the button in keyboard that sends data, or
+// data itself when there is none.
+func buttonText(keyboard *tgbotapi.InlineKeyboardMarkup, data string) string {
+	for _, row := range keyboard.InlineKeyboard {
+		for _, b := range row {
+			if b.CallbackData != nil && *b.CallbackData == data {
+				return b.Text
+			}
+		}
+	}
+	return data
+}
+
+// appendAudio downloads a voice note or audio file and adds it to atts.
+func (t *TelegramChannel) appendAudio(atts []bus.Attachment, fileID, name, mediaType string) []bus.Attachment {
+	data, err := t.downloadFileData(fileID)
+	if err != nil {
+		log.Printf("[telegram] download audio %s failed: %v", fileID, err)
+		return atts
+	}
+	if mediaType == "" {
+		// Voice notes are Opus in an Ogg container.
+		mediaType = "audio/ogg"
+	}
+	return append(atts, bus.Attachment{Name: name, MediaType: mediaType, Data: data})
+}
+
 func (t *TelegramChannel) downloadFileData(fileID string) ([]byte, error) {
 	if t.bot == nil {
 		return nil, fmt.Errorf("telegram bot not initialized")
@@ -243,7 +513,13 @@ func (t *TelegramChannel) downloadFileData(fileID string) ([]byte, error) {
 		client = http.DefaultClient
 	}
 
-	resp, err := client.Get(file.Link(t.token))
+	ctx, cancel := context.WithTimeout(context.Background(), telegramFileTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, file.Link(t.token), nil)
+	if err != nil {
+		return nil, fmt.Errorf("download telegram file: %w", err)
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("download telegram file: %w", err)
 	}
@@ -253,10 +529,13 @@ func (t *TelegramChannel) downloadFileData(fileID string) ([]byte, error) {
 		return nil, fmt.Errorf("download telegram file: unexpected status %d", resp.StatusCode)
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, telegramFileMaxBytes+1))
 	if err != nil {
 		return nil, fmt.Errorf("read telegram file body: %w", err)
 	}
+	if len(data) > telegramFileMaxBytes {
+		return nil, fmt.Errorf("telegram file exceeds %d bytes", telegramFileMaxBytes)
+	}
 
 	if len(data) == 0 {
 		return nil, fmt.Errorf("telegram file is empty")
@@ -286,113 +565,123 @@ func (t *TelegramChannel) Send(msg bus.OutboundMessage) error {
 		return fmt.Errorf("telegram bot not initialized")
 	}
 
-	chatID, err := strconv.ParseInt(msg.ChatID, 10, 64)
+	chatID, threadID, err := parseTelegramChatID(msg.ChatID)
 	if err != nil {
-		return fmt.Errorf("invalid chat id %q: %w", msg.ChatID, err)
+		return err
 	}
+	to := telegramTarget{chatID: chatID, threadID: threadID}
 
-	content := toTelegramHTML(msg.Content)
-
-	// Telegram has a 4096 char limit per message
-	const maxLen = 4000
-	for len(content) > 0 {
-		chunk := content
-		if len(chunk) > maxLen {
-			// Try to split at last newline before maxLen
-			idx := strings.LastIndex(chunk[:maxLen], "\n")
-			if idx > 0 {
-				chunk = chunk[:idx]
-			} else {
-				chunk = chunk[:maxLen]
-			}
+	if err := t.sendReply(to, msg); err != nil {
+		return err
+	}
+	// The reply got through; retrying would send it again.
+	for _, path := range msg.Media {
+		if err := t.sendFile(to, path); err != nil {
+			log.Printf("[telegram] send %s failed: %v", filepath.Base(path), err)
 		}
-		content = content[len(chunk):]
+	}
+	return nil
+}
 
-		tgMsg := tgbotapi.NewMessage(chatID, chunk)
-		tgMsg.ParseMode = tgbotapi.ModeHTML
-		if _, err := t.bot.Send(tgMsg); err != nil {
-			// Retry without HTML parse mode
-			tgMsg.ParseMode = ""
-			tgMsg.Text = msg.Content
-			if _, err2 := t.bot.Send(tgMsg); err2 != nil {
-				return fmt.Errorf("send telegram message: %w", err2)
-			}
+// telegramTarget is where a message goes: a chat, or a forum topic in one.
+type telegramTarget struct {
+	chatID   int64
+	threadID int
+}
+
+// base addresses a message to the target. tgbotapi cannot set the topic
+// of a message, so one for a topic replies to the message that started
+// the topic, which puts it there.
+func (to telegramTarget) base() tgbotapi.BaseChat {
+	return tgbotapi.BaseChat{ChatID: to.chatID, ReplyToMessageID: to.threadID, AllowSendingWithoutReply: to.threadID != 0}
+}
+
+// sendReply sends the text of msg, its voice note or both.
+func (t *TelegramChannel) sendReply(to telegramTarget, msg bus.OutboundMessage) error {
+	if len(msg.Voice) > 0 && msg.VoiceOnly {
+		err := t.sendVoice(to, msg.Voice, msg.Buttons)
+		if err == nil {
 			return nil
 		}
+		log.Printf("[telegram] send voice note failed, sending text: %v", err)
+	}
+	if err := t.sendText(to, msg.Content, msg.Buttons); err != nil {
+		return err
+	}
+	if len(msg.Voice) > 0 && !msg.VoiceOnly {
+		if err := t.sendVoice(to, msg.Voice, nil); err != nil {
+			log.Printf("[telegram] send voice note failed: %v", err)
+		}
 	}
 	return nil
 }
 
-// toTelegramHTML converts basic markdown to Telegram HTML.
-func toTelegramHTML(s string) string {
-	// Escape HTML entities first
-	s = strings.ReplaceAll(s, "&", "&amp;")
-	s = strings.ReplaceAll(s, "<", "&lt;")
-	s = strings.ReplaceAll(s, ">", "&gt;")
+// sendFile sends the file at path, as a photo when it is an image.
+func (t *TelegramChannel) sendFile(to telegramTarget, path string) error {
+	var c tgbotapi.Chattable
+	if isImageFile(path) {
+		photo := tgbotapi.NewPhoto(to.chatID, tgbotapi.FilePath(path))
+		photo.BaseChat = to.base()
+		c = photo
+	} else {
+		doc := tgbotapi.NewDocument(to.chatID, tgbotapi.FilePath(path))
+		doc.BaseChat = to.base()
+		c = doc
+	}
+	_, err := t.bot.Send(c)
+	return err
+}
 
-	// Code blocks: ```...``` -> <pre>...</pre>
-	for {
-		start := strings.Index(s, "```")
-		if start == -1 {
-			break
-		}
-		end := strings.Index(s[start+3:], "```")
-		if end == -1 {
-			break
+func (t *TelegramChannel) sendText(to telegramTarget, content string, buttons []bus.Button) error {
+	chunks := splitMessage(content, telegramChunkLimit, runeCount)
+	for i, chunk := range chunks {
+		tgMsg := tgbotapi.NewMessage(to.chatID, toTelegramMarkdownV2(chunk))
+		tgMsg.BaseChat = to.base()
+		tgMsg.ParseMode = tgbotapi.ModeMarkdownV2
+		if i == len(chunks)-1 && len(buttons) > 0 {
+			tgMsg.ReplyMarkup = telegramKeyboard(buttons)
 		}
-		end += start + 3
-		code := s[start+3 : end]
-		// Strip optional language tag on first line
-		if nl := strings.Index(code, "\n"); nl >= 0 {
-			firstLine := strings.TrimSpace(code[:nl])
-			if len(firstLine) > 0 && !strings.Contains(firstLine, " ") {
-				code = code[nl+1:]
+		if _, err := t.bot.Send(tgMsg); err != nil {
+			// Telegram rejects the whole message over one bad entity;
+			// the chunk still gets through as plain text.
+			tgMsg.ParseMode = ""
+			tgMsg.Text = chunk
+			if _, err2 := t.bot.Send(tgMsg); err2 != nil {
+				return fmt.Errorf("send telegram message: %w", err2)
 			}
 		}
-		s = s[:start] + "<pre>" + code + "</pre>" + s[end+3:]
 	}
+	return nil
+}
 
-	// Inline code: `...` -> <code>...</code>
-	for {
-		start := strings.Index(s, "`")
-		if start == -1 {
-			break
-		}
-		end := strings.Index(s[start+1:], "`")
-		if end == -1 {
-			break
-		}
-		end += start + 1
-		s = s[:start] + "<code>" + s[start+1:end] + "</code>" + s[end+1:]
+// sendVoice sends audio, OGG/Opus, as a voice note.
+func (t *TelegramChannel) sendVoice(to telegramTarget, audio []byte, buttons []bus.Button) error {
+	note := tgbotapi.NewVoice(to.chatID, tgbotapi.FileBytes{Name: "reply.ogg", Bytes: audio})
+	note.BaseChat = to.base()
+	if len(buttons) > 0 {
+		note.ReplyMarkup = telegramKeyboard(buttons)
 	}
-
-	// Bold: **...** -> <b>...</b>
-	for {
-		start := strings.Index(s, "**")
-		if start == -1 {
-			break
-		}
-		end := strings.Index(s[start+2:], "**")
-		if end == -1 {
-			break
-		}
-		end += start + 2
-		s = s[:start] + "<b>" + s[start+2:end] + "</b>" + s[end+2:]
+	if _, err := t.bot.Send(note); err != nil {
+		return fmt.Errorf("send telegram voice note: %w", err)
 	}
+	return nil
+}
 
-	// Italic: *...* -> <i>...</i> (after bold to avoid conflicts)
-	for {
-		start := strings.Index(s, "*")
-		if start == -1 {
-			break
-		}
-		end := strings.Index(s[start+1:], "*")
-		if end == -1 {
-			break
+// telegramKeyboard lays buttons out under the message: in one row when a
+// few short ones fit, one per row otherwise.
+func telegramKeyboard(buttons []bus.Button) tgbotapi.InlineKeyboardMarkup {
+	oneRow := len(buttons) <= telegramRowButtons
+	for _, b := range buttons {
+		oneRow = oneRow && utf8.RuneCountInString(b.Text) <= telegramRowLabel
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, b := range buttons {
+		button := tgbotapi.NewInlineKeyboardButtonData(b.Text, b.Data)
+		if oneRow && len(rows) == 1 {
+			rows[0] = append(rows[0], button)
+		} else {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
 		}
-		end += start + 1
-		s = s[:start] + "<i>" + s[start+1:end] + "</i>" + s[end+1:]
 	}
-
-	return s
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
@@ -38,6 +38,18 @@ type WebUIChannel struct {
 	server  *http.Server
 	clients sync.Map
 	nextID  atomic.Int64
+	routes  []route // added by Handle, served next to the UI
+}
+
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// Handle serves handler at pattern on the web UI's port, which is the
+// gateway's. It must be called before Start.
+func (w *WebUIChannel) Handle(pattern string, handler http.Handler) {
+	w.routes = append(w.routes, route{pattern, handler})
 }
 
 func NewWebUIChannel(cfg config.WebUIConfig, gwCfg config.GatewayConfig, b *bus.MessageBus) (*WebUIChannel, error) {
@@ -47,7 +59,7 @@ func NewWebUIChannel(cfg config.WebUIConfig, gwCfg config.GatewayConfig, b *bus.
 	}
 
 	ch := &WebUIChannel{
-		BaseChannel: NewBaseChannel(webUIChannelName, b, cfg.AllowFrom),
+		BaseChannel: NewBaseChannelWithAccess(webUIChannelName, b, Access{AllowFrom: cfg.AllowFrom, DenyFrom: cfg.DenyFrom, DeniedReply: cfg.DeniedReply}),
 		port:        port,
 	}
 	return ch, nil
@@ -62,6 +74,9 @@ func (w *WebUIChannel) Start(ctx context.Context) error {
 	mux := http.NewServeMux()
 	mux.Handle("/", http.FileServer(http.FS(staticFS)))
 	mux.HandleFunc("/ws", w.handleWS)
+	for _, r := range w.routes {
+		mux.Handle(r.pattern, r.handler)
+	}
 
 	w.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", w.port),
@@ -114,7 +129,7 @@ func (w *WebUIChannel) handleWS(wr http.ResponseWriter, r *http.Request) {
 		}
 
 		if !w.IsAllowed(clientID) {
-			log.Printf("[webui] rejected message from %s", clientID)
+			w.Reject(clientID, clientID)
 			continue
 		}
 
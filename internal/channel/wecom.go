@@ -19,7 +19,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-	"unicode/utf8"
 
 	"github.com/cexll/agentsdk-go/pkg/model"
 	"github.com/stellarlinkco/myclaw/internal/bus"
@@ -34,8 +33,11 @@ const (
 	wecomDefaultMsgCacheScan  = 1 * time.Minute
 	wecomDefaultReplyCacheTTL = 1 * time.Hour
 	wecomMarkdownMaxBytes     = 20480
+	wecomTruncatedNote        = "\n\n…(truncated)"
 	wecomInboundImageMaxBytes = 10 << 20 // 10MB
 	wecomInboundImageTimeout  = 10 * time.Second
+	wecomInboundFileMaxBytes  = 20 << 20
+	wecomInboundFileTimeout   = 30 * time.Second
 	wecomSendMaxRetries       = 3
 )
 
@@ -90,7 +92,12 @@ func (c *defaultWeComClient) SendMessage(ctx context.Context, responseURL string
 		return fmt.Errorf("wecom response_url is required")
 	}
 
-	content := truncateUTF8ByByteLimit(msg.Content, wecomMarkdownMaxBytes)
+	// response_url takes a single reply, so a long one is cut, at a line
+	// end and with any open code block closed.
+	content := msg.Content
+	if chunks := splitMessage(content, wecomMarkdownMaxBytes-len(wecomTruncatedNote), byteCount); len(chunks) > 1 {
+		content = chunks[0] + wecomTruncatedNote
+	}
 	return c.sendTextWithRetry(ctx, responseURL, content)
 }
 
@@ -187,25 +194,6 @@ func (c *defaultWeComClient) sendTextOnce(ctx context.Context, responseURL, cont
 	return nil
 }
 
-func truncateUTF8ByByteLimit(text string, maxBytes int) string {
-	if maxBytes <= 0 || len([]byte(text)) <= maxBytes {
-		return text
-	}
-	runes := []rune(text)
-	bytesCount := 0
-	for i, r := range runes {
-		runeBytes := utf8.RuneLen(r)
-		if runeBytes < 0 {
-			runeBytes = 1
-		}
-		if bytesCount+runeBytes > maxBytes {
-			return string(runes[:i])
-		}
-		bytesCount += runeBytes
-	}
-	return text
-}
-
 type weComMsgCache struct {
 	mu     sync.Mutex
 	items  map[string]time.Time
@@ -331,15 +319,15 @@ func (c *weComReplyCache) gcLocked(now time.Time) {
 
 type WeComChannel struct {
 	BaseChannel
-	cfg              config.WeComConfig
-	server           *http.Server
-	cancel           context.CancelFunc
-	client           WeComClient
-	clientFactory    WeComClientFactory
-	allowlistEnabled bool
-	msgCache         *weComMsgCache
-	replyCache       *weComReplyCache
-	receiveID        string
+	cfg           config.WeComConfig
+	server        *http.Server
+	cancel        context.CancelFunc
+	client        WeComClient
+	clientFactory WeComClientFactory
+	msgCache      *weComMsgCache
+	replyCache    *weComReplyCache
+	receiveID     string
+	apps          map[int]*weComApp // by agent ID
 }
 
 var defaultWeComClientFactory WeComClientFactory = func(cfg config.WeComConfig) WeComClient {
@@ -351,11 +339,14 @@ func NewWeComChannel(cfg config.WeComConfig, b *bus.MessageBus) (*WeComChannel,
 }
 
 func NewWeComChannelWithFactory(cfg config.WeComConfig, b *bus.MessageBus, factory WeComClientFactory) (*WeComChannel, error) {
-	if strings.TrimSpace(cfg.Token) == "" {
-		return nil, fmt.Errorf("wecom token is required")
-	}
-	if len(strings.TrimSpace(cfg.EncodingAESKey)) != 43 {
-		return nil, fmt.Errorf("wecom encodingAESKey must be 43 chars")
+	// Apps alone need no bot; config.Validate checks their own keys.
+	if len(cfg.Apps) == 0 {
+		if strings.TrimSpace(cfg.Token) == "" {
+			return nil, fmt.Errorf("wecom token is required")
+		}
+		if len(strings.TrimSpace(cfg.EncodingAESKey)) != 43 {
+			return nil, fmt.Errorf("wecom encodingAESKey must be 43 chars")
+		}
 	}
 
 	if factory == nil {
@@ -365,13 +356,13 @@ func NewWeComChannelWithFactory(cfg config.WeComConfig, b *bus.MessageBus, facto
 	receiveID := strings.TrimSpace(cfg.ReceiveID)
 
 	ch := &WeComChannel{
-		BaseChannel:      NewBaseChannel(wecomChannelName, b, cfg.AllowFrom),
-		cfg:              cfg,
-		clientFactory:    factory,
-		allowlistEnabled: len(cfg.AllowFrom) > 0,
-		msgCache:         newWeComMsgCache(wecomDefaultMsgCacheTTL),
-		replyCache:       newWeComReplyCache(wecomDefaultReplyCacheTTL),
-		receiveID:        receiveID,
+		BaseChannel:   NewBaseChannelWithAccess(wecomChannelName, b, Access{AllowFrom: cfg.AllowFrom, DenyFrom: cfg.DenyFrom, DeniedReply: cfg.DeniedReply}),
+		cfg:           cfg,
+		clientFactory: factory,
+		msgCache:      newWeComMsgCache(wecomDefaultMsgCacheTTL),
+		replyCache:    newWeComReplyCache(wecomDefaultReplyCacheTTL),
+		receiveID:     receiveID,
+		apps:          newWeComApps(cfg),
 	}
 
 	return ch, nil
@@ -388,6 +379,9 @@ func (w *WeComChannel) Start(ctx context.Context) error {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/wecom/bot", w.handleCallback)
+	if len(w.apps) > 0 {
+		mux.HandleFunc("/wecom/app", w.handleAppCallback)
+	}
 
 	w.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
@@ -432,6 +426,9 @@ func (w *WeComChannel) Send(msg bus.OutboundMessage) error {
 	if chatID == "" {
 		return fmt.Errorf("wecom chat id is required")
 	}
+	if app, user := w.appChat(chatID); app != nil {
+		return app.send(context.Background(), user, msg)
+	}
 
 	responseURL, ok := w.replyCache.Get(chatID)
 	if !ok {
@@ -471,8 +468,9 @@ type weComText struct {
 }
 
 type weComMixedItem struct {
-	MsgType string    `json:"msgtype"`
-	Text    weComText `json:"text"`
+	MsgType string     `json:"msgtype"`
+	Text    weComText  `json:"text"`
+	Image   weComImage `json:"image"`
 }
 
 type weComMixed struct {
@@ -483,6 +481,11 @@ type weComVoice struct {
 	Content string `json:"content"`
 }
 
+// weComFile is a file sent to the bot; WeCom names only where to fetch it.
+type weComFile struct {
+	URL string `json:"url"`
+}
+
 type weComImage struct {
 	URL      string `json:"url"`
 	PicURL   string `json:"pic_url"`
@@ -512,6 +515,7 @@ type weComInboundMessage struct {
 	Mixed       weComMixed `json:"mixed"`
 	Voice       weComVoice `json:"voice"`
 	Image       weComImage `json:"image"`
+	File        weComFile  `json:"file"`
 }
 
 type weComReplyEnvelope struct {
@@ -652,11 +656,6 @@ func (w *WeComChannel) processDecryptedMessage(plaintext string) {
 		return
 	}
 
-	if !w.allowMessageFrom(senderID) {
-		log.Printf("[wecom] rejected message from %s", senderID)
-		return
-	}
-
 	messageID := strings.TrimSpace(message.MsgID)
 	if messageID != "" && w.msgCache.Seen(messageID) {
 		log.Printf("[wecom] duplicate message dropped: %s", messageID)
@@ -673,9 +672,16 @@ func (w *WeComChannel) processDecryptedMessage(plaintext string) {
 		w.replyCache.Set(chatID, responseURL)
 	}
 
+	// Checked once the response_url is cached, so a denied reply can be sent.
+	if !w.IsAllowed(senderID, strings.TrimSpace(message.ChatID)) {
+		w.Reject(chatID, senderID)
+		return
+	}
+
 	content := extractWeComContent(message)
 	contentBlocks := w.extractWeComContentBlocks(message)
-	if content == "" && len(contentBlocks) == 0 {
+	attachments := extractWeComAttachments(message)
+	if content == "" && len(contentBlocks) == 0 && len(attachments) == 0 {
 		return
 	}
 
@@ -686,6 +692,7 @@ func (w *WeComChannel) processDecryptedMessage(plaintext string) {
 		Content:       content,
 		Timestamp:     time.Now(),
 		ContentBlocks: contentBlocks,
+		Attachments:   attachments,
 		Metadata: map[string]any{
 			"msg_id":         messageID,
 			"aibot_id":       strings.TrimSpace(message.AIBotID),
@@ -717,25 +724,56 @@ func (w *WeComChannel) resolveChatID(message weComInboundMessage, senderID strin
 }
 
 func (w *WeComChannel) extractWeComContentBlocks(message weComInboundMessage) []model.ContentBlock {
-	if !strings.EqualFold(strings.TrimSpace(message.MsgType), "image") {
-		return nil
+	var images []weComImage
+	switch strings.ToLower(strings.TrimSpace(message.MsgType)) {
+	case "image":
+		images = []weComImage{message.Image}
+	case "mixed":
+		for _, item := range message.Mixed.MsgItem {
+			if strings.EqualFold(strings.TrimSpace(item.MsgType), "image") {
+				images = append(images, item.Image)
+			}
+		}
 	}
 
-	block, err := w.buildWeComImageContentBlock(context.Background(), message)
-	if err != nil {
-		log.Printf("[wecom] process image message warning: %v", err)
+	var blocks []model.ContentBlock
+	for _, image := range images {
+		block, err := w.buildWeComImageContentBlock(context.Background(), image)
+		if err != nil {
+			log.Printf("[wecom] process image message warning: %v", err)
+		}
+		if block != nil {
+			blocks = append(blocks, *block)
+		}
 	}
-	if block == nil {
+	return blocks
+}
+
+// extractWeComAttachments fetches the file a file message carries, for the
+// gateway to read.
+func extractWeComAttachments(message weComInboundMessage) []bus.Attachment {
+	if !strings.EqualFold(strings.TrimSpace(message.MsgType), "file") {
 		return nil
 	}
-
-	return []model.ContentBlock{*block}
+	fileURL := strings.TrimSpace(message.File.URL)
+	if fileURL == "" {
+		log.Printf("[wecom] file message missing url")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), wecomInboundFileTimeout)
+	defer cancel()
+	data, mediaType, err := downloadWeComMedia(ctx, fileURL, wecomInboundFileMaxBytes)
+	if err != nil {
+		log.Printf("[wecom] download file warning: %v", err)
+		return nil
+	}
+	return []bus.Attachment{{Name: "file", MediaType: mediaType, Data: data}}
 }
 
-func (w *WeComChannel) buildWeComImageContentBlock(ctx context.Context, message weComInboundMessage) (*model.ContentBlock, error) {
-	imageURL := message.Image.URLValue()
+func (w *WeComChannel) buildWeComImageContentBlock(ctx context.Context, image weComImage) (*model.ContentBlock, error) {
+	imageURL := image.URLValue()
 	if imageURL == "" {
-		mediaID := strings.TrimSpace(message.Image.MediaID)
+		mediaID := strings.TrimSpace(image.MediaID)
 		if mediaID != "" {
 			// TODO: 支持通过企业微信 access_token + media_id 下载图片内容。
 			return nil, fmt.Errorf("wecom image media_id %q requires access_token download", mediaID)
@@ -759,28 +797,38 @@ func (w *WeComChannel) buildWeComImageContentBlock(ctx context.Context, message
 }
 
 func downloadWeComImageAsBase64(ctx context.Context, imageURL string) (string, string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	ctx, cancel := context.WithTimeout(ctx, wecomInboundImageTimeout)
+	defer cancel()
+	body, mediaType, err := downloadWeComMedia(ctx, imageURL, wecomInboundImageMaxBytes)
 	if err != nil {
-		return "", "", fmt.Errorf("create image request: %w", err)
+		return "", "", err
 	}
+	return base64.StdEncoding.EncodeToString(body), mediaType, nil
+}
 
-	httpClient := &http.Client{Timeout: wecomInboundImageTimeout}
-	resp, err := httpClient.Do(req)
+// downloadWeComMedia fetches mediaURL, refusing anything over maxBytes.
+func downloadWeComMedia(ctx context.Context, mediaURL string, maxBytes int64) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
 	if err != nil {
-		return "", "", fmt.Errorf("request image: %w", err)
+		return nil, "", fmt.Errorf("create media request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("request media: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, wecomInboundImageMaxBytes+1))
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
 	if err != nil {
-		return "", "", fmt.Errorf("read image response: %w", err)
+		return nil, "", fmt.Errorf("read media response: %w", err)
 	}
-	if int64(len(body)) > wecomInboundImageMaxBytes {
-		return "", "", fmt.Errorf("image exceeds %d bytes", wecomInboundImageMaxBytes)
+	if int64(len(body)) > maxBytes {
+		return nil, "", fmt.Errorf("media exceeds %d bytes", maxBytes)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", "", fmt.Errorf("image request failed with status %d", resp.StatusCode)
+		return nil, "", fmt.Errorf("media request failed with status %d", resp.StatusCode)
 	}
 
 	mediaType := normalizeWeComMediaType(resp.Header.Get("Content-Type"))
@@ -788,7 +836,7 @@ func downloadWeComImageAsBase64(ctx context.Context, imageURL string) (string, s
 		mediaType = http.DetectContentType(body)
 	}
 
-	return base64.StdEncoding.EncodeToString(body), mediaType, nil
+	return body, mediaType, nil
 }
 
 func normalizeWeComMediaType(value string) string {
@@ -810,6 +858,8 @@ func extractWeComContent(message weComInboundMessage) string {
 		return strings.TrimSpace(message.Voice.Content)
 	case "image":
 		return "[image]"
+	case "file":
+		return "[file]"
 	case "mixed":
 		parts := make([]string, 0, len(message.Mixed.MsgItem))
 		for _, item := range message.Mixed.MsgItem {
@@ -827,23 +877,30 @@ func extractWeComContent(message weComInboundMessage) string {
 	}
 }
 
-func (w *WeComChannel) allowMessageFrom(senderID string) bool {
-	if !w.allowlistEnabled {
-		return true
-	}
-	return w.IsAllowed(senderID)
+func (w *WeComChannel) signature(timestamp, nonce, data string) string {
+	return weComSignature(w.cfg.Token, timestamp, nonce, data)
 }
 
-func (w *WeComChannel) signature(timestamp, nonce, data string) string {
-	parts := []string{w.cfg.Token, timestamp, nonce, data}
+func (w *WeComChannel) decrypt(encrypted string) (string, string, error) {
+	return weComDecrypt(w.cfg.EncodingAESKey, w.receiveID, encrypted)
+}
+
+func (w *WeComChannel) encrypt(plaintext, receiveID string) (string, error) {
+	return weComEncrypt(w.cfg.EncodingAESKey, plaintext, receiveID)
+}
+
+func weComSignature(token, timestamp, nonce, data string) string {
+	parts := []string{token, timestamp, nonce, data}
 	sort.Strings(parts)
 	joined := strings.Join(parts, "")
 	sum := sha1.Sum([]byte(joined))
 	return fmt.Sprintf("%x", sum)
 }
 
-func (w *WeComChannel) decrypt(encrypted string) (string, string, error) {
-	aesKey, err := decodeWeComAESKey(w.cfg.EncodingAESKey)
+// weComDecrypt decrypts a callback payload, checking it was meant for
+// expectedReceiveID when that is set.
+func weComDecrypt(encodingAESKey, expectedReceiveID, encrypted string) (string, string, error) {
+	aesKey, err := decodeWeComAESKey(encodingAESKey)
 	if err != nil {
 		return "", "", fmt.Errorf("decode aes key: %w", err)
 	}
@@ -882,7 +939,7 @@ func (w *WeComChannel) decrypt(encrypted string) (string, string, error) {
 
 	msg := plain[20 : 20+msgLen]
 	receiveID := string(plain[20+msgLen:])
-	expectedReceiveID := strings.TrimSpace(w.receiveID)
+	expectedReceiveID = strings.TrimSpace(expectedReceiveID)
 	if expectedReceiveID != "" && receiveID != expectedReceiveID {
 		return "", "", fmt.Errorf("receive id mismatch")
 	}
@@ -890,8 +947,8 @@ func (w *WeComChannel) decrypt(encrypted string) (string, string, error) {
 	return string(msg), receiveID, nil
 }
 
-func (w *WeComChannel) encrypt(plaintext, receiveID string) (string, error) {
-	aesKey, err := decodeWeComAESKey(w.cfg.EncodingAESKey)
+func weComEncrypt(encodingAESKey, plaintext, receiveID string) (string, error) {
+	aesKey, err := decodeWeComAESKey(encodingAESKey)
 	if err != nil {
 		return "", fmt.Errorf("decode aes key: %w", err)
 	}
@@ -0,0 +1,436 @@
+package channel
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	// wecomAppMessageMaxBytes is the message API's limit on text and
+	// markdown content.
+	wecomAppMessageMaxBytes = 2048
+	// wecomAppUploadMaxBytes is the largest file the media API takes.
+	wecomAppUploadMaxBytes = 20 << 20
+)
+
+// weComAPIBase is the WeCom server API, replaced in tests.
+var weComAPIBase = "https://qyapi.weixin.qq.com/cgi-bin"
+
+// weComApp is a self-built app the channel answers as. Its chats are
+// "<agentId>:<userId>", so replies go back through the app they came in on
+// and each app keeps sessions of its own.
+type weComApp struct {
+	cfg            config.WeComAppConfig
+	corpID         string
+	callbackToken  string
+	encodingAESKey string
+	httpClient     *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExp    time.Time
+}
+
+func newWeComApps(cfg config.WeComConfig) map[int]*weComApp {
+	if len(cfg.Apps) == 0 {
+		return nil
+	}
+	apps := make(map[int]*weComApp, len(cfg.Apps))
+	for _, app := range cfg.Apps {
+		apps[app.AgentID] = &weComApp{
+			cfg:            app,
+			corpID:         strings.TrimSpace(cfg.CorpID),
+			callbackToken:  cmp.Or(app.Token, cfg.Token),
+			encodingAESKey: cmp.Or(app.EncodingAESKey, cfg.EncodingAESKey),
+			httpClient:     &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+	return apps
+}
+
+// weComAppChatID is the chat of userID with the app agentID.
+func weComAppChatID(agentID int, userID string) string {
+	return strconv.Itoa(agentID) + ":" + userID
+}
+
+// appChat returns the app and user a chat ID names, or nil when it is a
+// bot chat.
+func (w *WeComChannel) appChat(chatID string) (*weComApp, string) {
+	agent, user, ok := strings.Cut(chatID, ":")
+	if !ok {
+		return nil, ""
+	}
+	id, err := strconv.Atoi(agent)
+	if err != nil {
+		return nil, ""
+	}
+	return w.apps[id], user
+}
+
+type weComAppEnvelope struct {
+	Encrypt string `xml:"Encrypt"`
+}
+
+type weComAppMessage struct {
+	FromUserName string `xml:"FromUserName"`
+	MsgType      string `xml:"MsgType"`
+	Content      string `xml:"Content"`
+	PicURL       string `xml:"PicUrl"`
+	MediaID      string `xml:"MediaId"`
+	Recognition  string `xml:"Recognition"`
+	MsgID        string `xml:"MsgId"`
+	AgentID      int    `xml:"AgentID"`
+}
+
+// handleAppCallback verifies the callback URL of an app and takes its
+// messages. Callbacks name no app until decrypted, so each app's token is
+// tried against the signature.
+func (w *WeComChannel) handleAppCallback(resp http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	sig, timestamp, nonce := q.Get("msg_signature"), q.Get("timestamp"), q.Get("nonce")
+	if sig == "" || timestamp == "" || nonce == "" {
+		http.Error(resp, "missing signature params", http.StatusBadRequest)
+		return
+	}
+
+	var encrypted string
+	switch req.Method {
+	case http.MethodGet:
+		encrypted = q.Get("echostr")
+	case http.MethodPost:
+		body, err := io.ReadAll(http.MaxBytesReader(resp, req.Body, 1<<20))
+		if err != nil {
+			http.Error(resp, "read body failed", http.StatusBadRequest)
+			return
+		}
+		var envelope weComAppEnvelope
+		if err := xml.Unmarshal(body, &envelope); err != nil {
+			http.Error(resp, "invalid xml", http.StatusBadRequest)
+			return
+		}
+		encrypted = strings.TrimSpace(envelope.Encrypt)
+	default:
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if encrypted == "" {
+		http.Error(resp, "missing encrypted data", http.StatusBadRequest)
+		return
+	}
+
+	var app *weComApp
+	for _, candidate := range w.apps {
+		if weComSignature(candidate.callbackToken, timestamp, nonce, encrypted) == sig {
+			app = candidate
+			break
+		}
+	}
+	if app == nil {
+		http.Error(resp, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	plaintext, _, err := weComDecrypt(app.encodingAESKey, app.corpID, encrypted)
+	if err != nil {
+		http.Error(resp, "decrypt failed", http.StatusBadRequest)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodGet {
+		_, _ = resp.Write([]byte(plaintext))
+		return
+	}
+	go w.processAppMessage(app, plaintext)
+}
+
+// processAppMessage takes a message of app, the app whose token signed it;
+// the AgentID inside is not trusted to name another.
+func (w *WeComChannel) processAppMessage(app *weComApp, plaintext string) {
+	var message weComAppMessage
+	if err := xml.Unmarshal([]byte(plaintext), &message); err != nil {
+		log.Printf("[wecom] unmarshal app message error: %v", err)
+		return
+	}
+	senderID := strings.TrimSpace(message.FromUserName)
+	if senderID == "" {
+		return
+	}
+	if id := strings.TrimSpace(message.MsgID); id != "" && w.msgCache.Seen("app:"+id) {
+		log.Printf("[wecom] duplicate app message dropped: %s", id)
+		return
+	}
+
+	chatID := weComAppChatID(app.cfg.AgentID, senderID)
+	if !w.IsAllowed(senderID, "") {
+		w.Reject(chatID, senderID)
+		return
+	}
+
+	var content string
+	var blocks []model.ContentBlock
+	var attachments []bus.Attachment
+	switch strings.ToLower(message.MsgType) {
+	case "text":
+		content = strings.TrimSpace(message.Content)
+	case "voice":
+		// Recognition is only set when the app has speech recognition
+		// turned on; otherwise the AMR recording is fetched to transcribe.
+		content = strings.TrimSpace(message.Recognition)
+		if content == "" && strings.TrimSpace(message.MediaID) != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), wecomInboundFileTimeout)
+			data, _, err := app.media(ctx, strings.TrimSpace(message.MediaID))
+			cancel()
+			if err != nil {
+				log.Printf("[wecom] app voice download warning: %v", err)
+				return
+			}
+			attachments = []bus.Attachment{{Name: "voice.amr", MediaType: "audio/amr", Data: data}}
+		}
+	case "image":
+		content = "[image]"
+		if pic := strings.TrimSpace(message.PicURL); pic != "" {
+			data, mediaType, err := downloadWeComImageAsBase64(context.Background(), pic)
+			if err != nil {
+				log.Printf("[wecom] app image download warning: %v", err)
+				blocks = []model.ContentBlock{{Type: model.ContentBlockImage, URL: pic}}
+			} else {
+				blocks = []model.ContentBlock{{Type: model.ContentBlockImage, MediaType: mediaType, Data: data}}
+			}
+		}
+	default:
+		// Events such as entering the app carry nothing to answer.
+		return
+	}
+	if content == "" && len(blocks) == 0 && len(attachments) == 0 {
+		return
+	}
+
+	metadata := map[string]any{
+		"msg_id":   strings.TrimSpace(message.MsgID),
+		"msg_type": message.MsgType,
+		"agent_id": app.cfg.AgentID,
+	}
+	if persona := strings.TrimSpace(app.cfg.Persona); persona != "" {
+		metadata["persona"] = persona
+	}
+	w.bus.Inbound <- bus.InboundMessage{
+		Channel:       wecomChannelName,
+		SenderID:      senderID,
+		ChatID:        chatID,
+		Content:       content,
+		Timestamp:     time.Now(),
+		ContentBlocks: blocks,
+		Attachments:   attachments,
+		Metadata:      metadata,
+	}
+}
+
+// send sends msg to user as markdown, split to fit the message API, then
+// its media: images as images and anything else as files.
+func (a *weComApp) send(ctx context.Context, user string, msg bus.OutboundMessage) error {
+	if strings.TrimSpace(msg.Content) != "" {
+		for _, chunk := range splitMessage(msg.Content, wecomAppMessageMaxBytes, byteCount) {
+			if err := a.post(ctx, user, "markdown", map[string]string{"content": chunk}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, path := range msg.Media {
+		kind := "file"
+		if isImageFile(path) {
+			kind = "image"
+		}
+		mediaID, err := a.upload(ctx, kind, path)
+		if err != nil {
+			return fmt.Errorf("upload %s: %w", filepath.Base(path), err)
+		}
+		if err := a.post(ctx, user, kind, map[string]string{"media_id": mediaID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *weComApp) post(ctx context.Context, user, msgType string, body any) error {
+	payload, err := json.Marshal(map[string]any{
+		"touser":  user,
+		"msgtype": msgType,
+		"agentid": a.cfg.AgentID,
+		msgType:   body,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal wecom app message: %w", err)
+	}
+	return a.call(ctx, "/message/send", "application/json", bytes.NewReader(payload), nil)
+}
+
+// upload puts the file at path in the app's temporary media, for three
+// days, and returns its media ID.
+func (a *weComApp) upload(ctx context.Context, kind, path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > wecomAppUploadMaxBytes {
+		return "", fmt.Errorf("%d bytes is over the %d byte limit", info.Size(), wecomAppUploadMaxBytes)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("media", filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return "", err
+	}
+	mw.Close()
+
+	var result struct {
+		MediaID string `json:"media_id"`
+	}
+	if err := a.call(ctx, "/media/upload?type="+kind, mw.FormDataContentType(), &body, &result); err != nil {
+		return "", err
+	}
+	return result.MediaID, nil
+}
+
+// media fetches the temporary media mediaID, such as the recording of a
+// voice message. Errors come back as JSON instead of the file.
+func (a *weComApp) media(ctx context.Context, mediaID string) ([]byte, string, error) {
+	token, err := a.token(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	u := weComAPIBase + "/media/get?access_token=" + url.QueryEscape(token) + "&media_id=" + url.QueryEscape(mediaID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("wecom app media: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, wecomInboundFileMaxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("read wecom app media: %w", err)
+	}
+	if int64(len(data)) > wecomInboundFileMaxBytes {
+		return nil, "", fmt.Errorf("wecom app media exceeds %d bytes", wecomInboundFileMaxBytes)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", &weComHTTPStatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+	mediaType := normalizeWeComMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/json" || mediaType == "text/plain" {
+		var result weComSendResponse
+		if json.Unmarshal(data, &result) == nil && result.ErrCode != 0 {
+			return nil, "", &weComAPIError{Code: result.ErrCode, Msg: result.ErrMsg}
+		}
+	}
+	return data, mediaType, nil
+}
+
+// call posts body to the API endpoint with the app's access token and
+// decodes the answer into out, if set.
+func (a *weComApp) call(ctx context.Context, endpoint, contentType string, body io.Reader, out any) error {
+	token, err := a.token(ctx)
+	if err != nil {
+		return err
+	}
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, weComAPIBase+endpoint+sep+"access_token="+url.QueryEscape(token), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("wecom app request: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &weComHTTPStatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(raw))}
+	}
+	var result weComSendResponse
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return fmt.Errorf("decode wecom app response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		if result.ErrCode == 42001 || result.ErrCode == 40014 {
+			// The token expired early; the next call fetches another.
+			a.mu.Lock()
+			a.accessToken = ""
+			a.mu.Unlock()
+		}
+		return &weComAPIError{Code: result.ErrCode, Msg: result.ErrMsg}
+	}
+	if out != nil {
+		return json.Unmarshal(raw, out)
+	}
+	return nil
+}
+
+// token returns the app's access token, fetching a new one a minute before
+// the old one expires.
+func (a *weComApp) token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.accessToken != "" && time.Now().Before(a.tokenExp) {
+		return a.accessToken, nil
+	}
+	u := weComAPIBase + "/gettoken?corpid=" + url.QueryEscape(a.corpID) + "&corpsecret=" + url.QueryEscape(a.cfg.Secret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("get wecom access token: %w", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode wecom access token: %w", err)
+	}
+	if result.ErrCode != 0 || result.AccessToken == "" {
+		return "", fmt.Errorf("wecom access token for agent %d: %d %s", a.cfg.AgentID, result.ErrCode, result.ErrMsg)
+	}
+	a.accessToken = result.AccessToken
+	a.tokenExp = time.Now().Add(time.Duration(result.ExpiresIn-60) * time.Second)
+	return a.accessToken, nil
+}
@@ -13,6 +13,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"sort"
 	"strings"
 	"testing"
@@ -620,3 +621,188 @@ func TestWeComClient_Send_NoRetryOnPayloadErrcode(t *testing.T) {
 		t.Fatalf("send calls = %d, want 1", sendCalls)
 	}
 }
+
+func TestWeComApp(t *testing.T) {
+	var calls []string
+	var sent []map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		switch r.URL.Path {
+		case "/gettoken":
+			if r.URL.Query().Get("corpsecret") != "hr-secret" {
+				t.Errorf("gettoken query = %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"errcode":0,"access_token":"tok","expires_in":7200}`)
+		case "/media/upload":
+			if r.URL.Query().Get("type") != "image" || r.URL.Query().Get("access_token") != "tok" {
+				t.Errorf("upload query = %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"errcode":0,"media_id":"m1"}`)
+		case "/message/send":
+			var msg map[string]any
+			json.NewDecoder(r.Body).Decode(&msg)
+			sent = append(sent, msg)
+			fmt.Fprint(w, `{"errcode":0}`)
+		}
+	}))
+	defer api.Close()
+	old := weComAPIBase
+	weComAPIBase = api.URL
+	defer func() { weComAPIBase = old }()
+
+	key := "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	ch, b := newTestWeComChannel(t, config.WeComConfig{
+		CorpID: "corp",
+		Apps: []config.WeComAppConfig{
+			{AgentID: 1000002, Secret: "hr-secret", Token: "hr-token", EncodingAESKey: key, Persona: "You are the HR helpdesk."},
+			{AgentID: 1000003, Secret: "it-secret", Token: "it-token", EncodingAESKey: key},
+		},
+	})
+
+	echo := testWeComEncrypt(t, key, "corp", "verified")
+	q := fmt.Sprintf("/wecom/app?msg_signature=%s&timestamp=1&nonce=n&echostr=%s", testWeComSignature("it-token", "1", "n", echo), echo)
+	w := httptest.NewRecorder()
+	ch.handleAppCallback(w, httptest.NewRequest(http.MethodGet, strings.ReplaceAll(q, "+", "%2B"), nil))
+	if w.Code != http.StatusOK || w.Body.String() != "verified" {
+		t.Fatalf("verify = %d %q", w.Code, w.Body.String())
+	}
+
+	plain := `<xml><ToUserName><![CDATA[corp]]></ToUserName><FromUserName><![CDATA[zhangsan]]></FromUserName><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[How many leave days do I have?]]></Content><MsgId>42</MsgId><AgentID>1000002</AgentID></xml>`
+	enc := testWeComEncrypt(t, key, "corp", plain)
+	body := fmt.Sprintf(`<xml><ToUserName><![CDATA[corp]]></ToUserName><AgentID><![CDATA[1000002]]></AgentID><Encrypt><![CDATA[%s]]></Encrypt></xml>`, enc)
+	w = httptest.NewRecorder()
+	ch.handleAppCallback(w, httptest.NewRequest(http.MethodPost, "/wecom/app?msg_signature="+testWeComSignature("hr-token", "1", "n", enc)+"&timestamp=1&nonce=n", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback = %d", w.Code)
+	}
+	select {
+	case msg := <-b.Inbound:
+		if msg.ChatID != "1000002:zhangsan" || msg.SenderID != "zhangsan" || msg.Content != "How many leave days do I have?" || msg.Metadata["persona"] != "You are the HR helpdesk." {
+			t.Errorf("inbound = %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected inbound message")
+	}
+
+	// A message signed by the IT app is the IT app's, whatever AgentID it
+	// names.
+	enc = testWeComEncrypt(t, key, "corp", strings.Replace(plain, "<MsgId>42", "<MsgId>44", 1))
+	forged := fmt.Sprintf(`<xml><Encrypt><![CDATA[%s]]></Encrypt></xml>`, enc)
+	ch.handleAppCallback(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/wecom/app?msg_signature="+testWeComSignature("it-token", "1", "n", enc)+"&timestamp=1&nonce=n", strings.NewReader(forged)))
+	select {
+	case msg := <-b.Inbound:
+		if msg.ChatID != "1000003:zhangsan" || msg.Metadata["persona"] != nil {
+			t.Errorf("inbound = %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected inbound message")
+	}
+
+	w = httptest.NewRecorder()
+	ch.handleAppCallback(w, httptest.NewRequest(http.MethodPost, "/wecom/app?msg_signature=bad&timestamp=1&nonce=n", strings.NewReader(body)))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature = %d", w.Code)
+	}
+
+	img := t.TempDir() + "/chart.png"
+	os.WriteFile(img, []byte("\x89PNG\r\n\x1a\n"), 0o644)
+	if err := ch.Send(bus.OutboundMessage{ChatID: "1000002:zhangsan", Content: "**12** days left.", Media: []string{img}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || sent[0]["msgtype"] != "markdown" || sent[0]["touser"] != "zhangsan" || sent[0]["agentid"] != float64(1000002) || sent[1]["msgtype"] != "image" {
+		t.Fatalf("sent = %v", sent)
+	}
+	if got := sent[1]["image"].(map[string]any)["media_id"]; got != "m1" {
+		t.Errorf("media_id = %v", got)
+	}
+	if strings.Join(calls, ",") != "/gettoken,/message/send,/media/upload,/message/send" {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestWeComCallback_FileAndMixedImages(t *testing.T) {
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			io.WriteString(w, "%PDF-1.4")
+		case "/chart.png":
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, "\x89PNG\r\n\x1a\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer files.Close()
+
+	ch, b := newTestWeComChannel(t, config.WeComConfig{
+		Token:          "verify-token",
+		EncodingAESKey: "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG",
+		ReceiveID:      "recv-id-1",
+	})
+	deliver := func(plaintext string) bus.InboundMessage {
+		t.Helper()
+		encrypt := testWeComEncrypt(t, ch.cfg.EncodingAESKey, ch.receiveID, plaintext)
+		req := httptest.NewRequest(http.MethodPost, "/wecom/bot?msg_signature="+testWeComSignature(ch.cfg.Token, "1", "n", encrypt)+"&timestamp=1&nonce=n", strings.NewReader(testWeComEncryptedRequestBody(t, encrypt)))
+		ch.handleCallback(httptest.NewRecorder(), req)
+		select {
+		case msg := <-b.Inbound:
+			return msg
+		case <-time.After(time.Second):
+			t.Fatal("expected inbound message")
+			return bus.InboundMessage{}
+		}
+	}
+
+	msg := deliver(`{"msgid":"f1","chattype":"single","from":{"userid":"zhangsan"},"msgtype":"file","file":{"url":"` + files.URL + `/report.pdf"}}`)
+	if msg.Content != "[file]" || len(msg.Attachments) != 1 || msg.Attachments[0].MediaType != "application/pdf" || string(msg.Attachments[0].Data) != "%PDF-1.4" {
+		t.Errorf("file message = %+v", msg)
+	}
+
+	msg = deliver(`{"msgid":"m1","chattype":"single","from":{"userid":"zhangsan"},"msgtype":"mixed","mixed":{"msg_item":[{"msgtype":"text","text":{"content":"What does this show?"}},{"msgtype":"image","image":{"url":"` + files.URL + `/chart.png"}}]}}`)
+	if msg.Content != "What does this show?" || len(msg.ContentBlocks) != 1 || msg.ContentBlocks[0].MediaType != "image/png" {
+		t.Errorf("mixed message = %+v", msg)
+	}
+}
+
+func TestWeComApp_VoiceRecording(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gettoken":
+			fmt.Fprint(w, `{"errcode":0,"access_token":"tok","expires_in":7200}`)
+		case "/media/get":
+			if r.URL.Query().Get("access_token") != "tok" {
+				t.Errorf("media query = %s", r.URL.RawQuery)
+			}
+			if r.URL.Query().Get("media_id") != "v1" {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"errcode":40007,"errmsg":"invalid media_id"}`)
+				return
+			}
+			w.Header().Set("Content-Type", "voice/amr")
+			fmt.Fprint(w, "#!AMR\n")
+		}
+	}))
+	defer api.Close()
+	old := weComAPIBase
+	weComAPIBase = api.URL
+	defer func() { weComAPIBase = old }()
+
+	ch, b := newTestWeComChannel(t, config.WeComConfig{
+		CorpID: "corp",
+		Apps:   []config.WeComAppConfig{{AgentID: 1000002, Secret: "s", Token: "t", EncodingAESKey: "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"}},
+	})
+	ch.processAppMessage(ch.apps[1000002], `<xml><FromUserName><![CDATA[zhangsan]]></FromUserName><MsgType><![CDATA[voice]]></MsgType><MediaId><![CDATA[v1]]></MediaId><Format><![CDATA[amr]]></Format><MsgId>43</MsgId><AgentID>1000002</AgentID></xml>`)
+	select {
+	case msg := <-b.Inbound:
+		if len(msg.Attachments) != 1 || msg.Attachments[0].MediaType != "audio/amr" || string(msg.Attachments[0].Data) != "#!AMR\n" {
+			t.Errorf("inbound = %+v", msg)
+		}
+	default:
+		t.Fatal("expected the recording as an attachment")
+	}
+
+	if _, _, err := ch.apps[1000002].media(context.Background(), "gone"); err == nil {
+		t.Error("an errcode answer should be an error, not the file")
+	}
+}
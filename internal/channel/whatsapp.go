@@ -29,7 +29,7 @@ import (
 const whatsappChannelName = "whatsapp"
 
 const (
-	whatsappInboundImageTimeout = 20 * time.Second
+	whatsappInboundMediaTimeout = 20 * time.Second
 	whatsappSendTimeout         = 30 * time.Second
 )
 
@@ -45,7 +45,7 @@ type WhatsAppChannel struct {
 func NewWhatsApp(cfg config.WhatsAppConfig, msgBus *bus.MessageBus) (*WhatsAppChannel, error) {
 	storePath := strings.TrimSpace(cfg.StorePath)
 	if storePath == "" {
-		storePath = filepath.Join(config.ConfigDir(), "whatsapp-store.db")
+		storePath = filepath.Join(config.DataDir(), "whatsapp-store.db")
 	}
 
 	if err := os.MkdirAll(filepath.Dir(storePath), 0755); err != nil {
@@ -67,7 +67,7 @@ func NewWhatsApp(cfg config.WhatsAppConfig, msgBus *bus.MessageBus) (*WhatsAppCh
 	client := whatsmeow.NewClient(deviceStore, waLog.Noop)
 
 	ch := &WhatsAppChannel{
-		BaseChannel:    NewBaseChannel(whatsappChannelName, msgBus, cfg.AllowFrom),
+		BaseChannel:    NewBaseChannelWithAccess(whatsappChannelName, msgBus, Access{AllowFrom: cfg.AllowFrom, DenyFrom: cfg.DenyFrom, DeniedReply: cfg.DeniedReply}),
 		cfg:            cfg,
 		client:         client,
 		storeContainer: container,
@@ -161,13 +161,92 @@ func (w *WhatsAppChannel) Send(msg bus.OutboundMessage) error {
 	ctx, cancel := context.WithTimeout(context.Background(), whatsappSendTimeout)
 	defer cancel()
 
-	_, err = w.client.SendMessage(ctx, chatJID, &waE2E.Message{
+	if err := w.sendReply(ctx, chatJID, content, msg); err != nil {
+		return err
+	}
+	// The reply got through; retrying would send it again.
+	for _, path := range msg.Media {
+		if !isImageFile(path) {
+			continue
+		}
+		if err := w.sendImage(ctx, chatJID, path); err != nil {
+			log.Printf("[whatsapp] send %s failed: %v", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+// sendReply sends content, the voice note of msg or both.
+func (w *WhatsAppChannel) sendReply(ctx context.Context, chatJID types.JID, content string, msg bus.OutboundMessage) error {
+	if len(msg.Voice) > 0 && msg.VoiceOnly {
+		err := w.sendVoice(ctx, chatJID, msg.Voice)
+		if err == nil {
+			return nil
+		}
+		log.Printf("[whatsapp] send voice note failed, sending text: %v", err)
+	}
+	_, err := w.client.SendMessage(ctx, chatJID, &waE2E.Message{
 		Conversation: proto.String(content),
 	})
 	if err != nil {
 		return fmt.Errorf("send whatsapp message: %w", err)
 	}
+	if len(msg.Voice) > 0 && !msg.VoiceOnly {
+		if err := w.sendVoice(ctx, chatJID, msg.Voice); err != nil {
+			log.Printf("[whatsapp] send voice note failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// sendImage uploads the picture at path and sends it.
+func (w *WhatsAppChannel) sendImage(ctx context.Context, chatJID types.JID, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	up, err := w.client.Upload(ctx, data, whatsmeow.MediaImage)
+	if err != nil {
+		return fmt.Errorf("upload whatsapp image: %w", err)
+	}
+	_, err = w.client.SendMessage(ctx, chatJID, &waE2E.Message{
+		ImageMessage: &waE2E.ImageMessage{
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+			Mimetype:      proto.String(http.DetectContentType(data)),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("send whatsapp image: %w", err)
+	}
+	return nil
+}
 
+// sendVoice uploads OGG/Opus audio and sends it as a push-to-talk note.
+func (w *WhatsAppChannel) sendVoice(ctx context.Context, chatJID types.JID, audio []byte) error {
+	up, err := w.client.Upload(ctx, audio, whatsmeow.MediaAudio)
+	if err != nil {
+		return fmt.Errorf("upload whatsapp voice note: %w", err)
+	}
+	_, err = w.client.SendMessage(ctx, chatJID, &waE2E.Message{
+		AudioMessage: &waE2E.AudioMessage{
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+			Mimetype:      proto.String("audio/ogg; codecs=opus"),
+			PTT:           proto.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("send whatsapp voice note: %w", err)
+	}
 	return nil
 }
 
@@ -210,13 +289,17 @@ func (w *WhatsAppChannel) handleMessage(evt *events.Message) {
 
 	rawSender := evt.Info.Sender.String()
 	sender := evt.Info.Sender.ToNonAD().String()
-	if !w.IsAllowed(sender) && !w.IsAllowed(rawSender) {
-		log.Printf("[whatsapp] rejected message from %s", sender)
+	if !w.IsAllowed(sender, rawSender, evt.Info.Chat.String()) {
+		w.Reject(evt.Info.Chat.String(), sender)
 		return
 	}
 
 	content, blocks := w.extractContent(evt)
-	if content == "" && len(blocks) == 0 {
+	attachments := w.extractAttachments(evt)
+	if content == "" {
+		content = strings.TrimSpace(evt.Message.GetDocumentMessage().GetCaption())
+	}
+	if content == "" && len(blocks) == 0 && len(attachments) == 0 {
 		return
 	}
 
@@ -227,6 +310,7 @@ func (w *WhatsAppChannel) handleMessage(evt *events.Message) {
 		Content:       content,
 		Timestamp:     evt.Info.Timestamp,
 		ContentBlocks: blocks,
+		Attachments:   attachments,
 		Metadata: map[string]any{
 			"message_id": evt.Info.ID,
 			"chat_jid":   evt.Info.Chat.String(),
@@ -249,7 +333,7 @@ func (w *WhatsAppChannel) extractContent(evt *events.Message) (string, []model.C
 			content = strings.TrimSpace(image.GetCaption())
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), whatsappInboundImageTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), whatsappInboundMediaTimeout)
 		data, err := w.client.Download(ctx, image)
 		cancel()
 		if err != nil {
@@ -274,6 +358,30 @@ func (w *WhatsAppChannel) extractContent(evt *events.Message) (string, []model.C
 	return content, contentBlocks
 }
 
+// extractAttachments downloads the voice note, audio or document of evt
+// for the gateway to read.
+func (w *WhatsAppChannel) extractAttachments(evt *events.Message) []bus.Attachment {
+	msg := evt.Message
+	var atts []bus.Attachment
+	download := func(media whatsmeow.DownloadableMessage, name, mediaType string) {
+		ctx, cancel := context.WithTimeout(context.Background(), whatsappInboundMediaTimeout)
+		defer cancel()
+		data, err := w.client.Download(ctx, media)
+		if err != nil {
+			log.Printf("[whatsapp] download %s failed: %v", mediaType, err)
+			return
+		}
+		atts = append(atts, bus.Attachment{Name: name, MediaType: mediaType, Data: data})
+	}
+	if audio := msg.GetAudioMessage(); audio != nil {
+		download(audio, "", audio.GetMimetype())
+	}
+	if doc := msg.GetDocumentMessage(); doc != nil {
+		download(doc, doc.GetFileName(), doc.GetMimetype())
+	}
+	return atts
+}
+
 func parseWhatsAppJID(raw string) (types.JID, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
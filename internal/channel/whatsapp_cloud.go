@@ -0,0 +1,616 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	whatsappCloudAPIBase         = "https://graph.facebook.com/v21.0/"
+	whatsappCloudDefaultPort     = 9878
+	whatsappCloudServiceWindow   = 24 * time.Hour
+	whatsappCloudDefaultLanguage = "en_US"
+	// whatsappCloudMediaMaxBytes caps an inbound file; larger ones are
+	// skipped rather than read into memory.
+	whatsappCloudMediaMaxBytes = 20 << 20
+)
+
+// WhatsAppCloudClient interface for the WhatsApp Business Cloud API (allows mocking)
+type WhatsAppCloudClient interface {
+	SendText(ctx context.Context, to, text string) error
+	SendTemplate(ctx context.Context, to, name, language string, params []string) error
+	// SendVoice sends OGG/Opus audio as a voice note.
+	SendVoice(ctx context.Context, to string, audio []byte) error
+	// SendImage sends a PNG or JPEG picture.
+	SendImage(ctx context.Context, to string, image []byte, mediaType string) error
+	// DownloadMedia fetches a file a user sent, by its media ID, with its
+	// MIME type.
+	DownloadMedia(ctx context.Context, id string) ([]byte, string, error)
+}
+
+type defaultWhatsAppCloudClient struct {
+	phoneNumberID string
+	accessToken   string
+	baseURL       string
+	http          *http.Client
+}
+
+func (c *defaultWhatsAppCloudClient) send(ctx context.Context, payload map[string]any) error {
+	payload["messaging_product"] = "whatsapp"
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal whatsapp message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+c.phoneNumberID+"/messages", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create whatsapp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	_, err = c.do(req, "send")
+	return err
+}
+
+// do sends req and returns the response body, or the API's error.
+func (c *defaultWhatsAppCloudClient) do(req *http.Request, op string) ([]byte, error) {
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("whatsapp %s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 == 2 {
+		return body, nil
+	}
+	var result struct {
+		Error struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &result) == nil && result.Error.Message != "" {
+		return nil, fmt.Errorf("whatsapp %s error %d: %s", op, result.Error.Code, result.Error.Message)
+	}
+	return nil, fmt.Errorf("whatsapp %s: status %d", op, resp.StatusCode)
+}
+
+func (c *defaultWhatsAppCloudClient) SendText(ctx context.Context, to, text string) error {
+	return c.send(ctx, map[string]any{
+		"recipient_type": "individual",
+		"to":             to,
+		"type":           "text",
+		"text":           map[string]any{"body": text},
+	})
+}
+
+func (c *defaultWhatsAppCloudClient) SendTemplate(ctx context.Context, to, name, language string, params []string) error {
+	template := map[string]any{
+		"name":     name,
+		"language": map[string]string{"code": language},
+	}
+	if len(params) > 0 {
+		parameters := make([]map[string]string, len(params))
+		for i, p := range params {
+			parameters[i] = map[string]string{"type": "text", "text": p}
+		}
+		template["components"] = []map[string]any{{"type": "body", "parameters": parameters}}
+	}
+	return c.send(ctx, map[string]any{
+		"to":       to,
+		"type":     "template",
+		"template": template,
+	})
+}
+
+// SendVoice uploads audio to the media endpoint and sends it by ID.
+func (c *defaultWhatsAppCloudClient) SendVoice(ctx context.Context, to string, audio []byte) error {
+	id, err := c.upload(ctx, audio, "audio/ogg", "reply.ogg")
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, map[string]any{
+		"recipient_type": "individual",
+		"to":             to,
+		"type":           "audio",
+		"audio":          map[string]any{"id": id},
+	})
+}
+
+// SendImage uploads image to the media endpoint and sends it by ID.
+func (c *defaultWhatsAppCloudClient) SendImage(ctx context.Context, to string, image []byte, mediaType string) error {
+	id, err := c.upload(ctx, image, mediaType, "image")
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, map[string]any{
+		"recipient_type": "individual",
+		"to":             to,
+		"type":           "image",
+		"image":          map[string]any{"id": id},
+	})
+}
+
+// upload stores data with the media endpoint and returns its media ID.
+func (c *defaultWhatsAppCloudClient) upload(ctx context.Context, data []byte, mediaType, name string) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("messaging_product", "whatsapp")
+	form.WriteField("type", mediaType)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	header.Set("Content-Type", mediaType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+c.phoneNumberID+"/media", &body)
+	if err != nil {
+		return "", fmt.Errorf("create whatsapp request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := c.do(req, "upload")
+	if err != nil {
+		return "", err
+	}
+	var media struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &media); err != nil || media.ID == "" {
+		return "", fmt.Errorf("whatsapp upload: no media id in %q", resp)
+	}
+	return media.ID, nil
+}
+
+// DownloadMedia looks up the short-lived URL of a media ID and downloads
+// the file from it, both with the access token.
+func (c *defaultWhatsAppCloudClient) DownloadMedia(ctx context.Context, id string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("create whatsapp request: %w", err)
+	}
+	resp, err := c.do(req, "media")
+	if err != nil {
+		return nil, "", err
+	}
+	var media struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+		FileSize int64  `json:"file_size"`
+	}
+	if err := json.Unmarshal(resp, &media); err != nil || media.URL == "" {
+		return nil, "", fmt.Errorf("whatsapp media: no url in %q", resp)
+	}
+	if media.FileSize > whatsappCloudMediaMaxBytes {
+		return nil, "", fmt.Errorf("whatsapp media: %d bytes exceeds %d", media.FileSize, whatsappCloudMediaMaxBytes)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, media.URL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("create whatsapp request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	fileResp, err := c.http.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("whatsapp download: %w", err)
+	}
+	defer fileResp.Body.Close()
+	if fileResp.StatusCode/100 != 2 {
+		return nil, "", fmt.Errorf("whatsapp download: status %d", fileResp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(fileResp.Body, whatsappCloudMediaMaxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("whatsapp download: %w", err)
+	}
+	if len(data) > whatsappCloudMediaMaxBytes {
+		return nil, "", fmt.Errorf("whatsapp download: file exceeds %d bytes", whatsappCloudMediaMaxBytes)
+	}
+	mediaType := media.MimeType
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	return data, mediaType, nil
+}
+
+// WhatsAppCloudClientFactory creates WhatsAppCloudClient instances
+type WhatsAppCloudClientFactory func(phoneNumberID, accessToken string) WhatsAppCloudClient
+
+var defaultWhatsAppCloudClientFactory WhatsAppCloudClientFactory = func(phoneNumberID, accessToken string) WhatsAppCloudClient {
+	return &defaultWhatsAppCloudClient{
+		phoneNumberID: phoneNumberID,
+		accessToken:   accessToken,
+		baseURL:       whatsappCloudAPIBase,
+		http:          &http.Client{Timeout: whatsappSendTimeout},
+	}
+}
+
+// WhatsAppCloudChannel talks to the WhatsApp Business Cloud API: inbound
+// messages arrive on a webhook, replies go out through the send API. Free-form
+// text is only allowed within 24 hours of the user's last message; outside
+// that window the configured template is used instead.
+type WhatsAppCloudChannel struct {
+	BaseChannel
+	cfg           config.WhatsAppConfig
+	client        WhatsAppCloudClient
+	clientFactory WhatsAppCloudClientFactory
+	server        *http.Server
+	now           func() time.Time
+
+	mu          sync.Mutex
+	lastInbound map[string]time.Time // sender -> last message time
+}
+
+func NewWhatsAppCloudChannel(cfg config.WhatsAppConfig, b *bus.MessageBus) (*WhatsAppCloudChannel, error) {
+	return NewWhatsAppCloudChannelWithFactory(cfg, b, defaultWhatsAppCloudClientFactory)
+}
+
+func NewWhatsAppCloudChannelWithFactory(cfg config.WhatsAppConfig, b *bus.MessageBus, factory WhatsAppCloudClientFactory) (*WhatsAppCloudChannel, error) {
+	if cfg.PhoneNumberID == "" || cfg.AccessToken == "" {
+		return nil, fmt.Errorf("whatsapp phoneNumberId and accessToken are required in cloud mode")
+	}
+	if cfg.AppSecret == "" || cfg.VerifyToken == "" {
+		return nil, fmt.Errorf("whatsapp appSecret and verifyToken are required in cloud mode")
+	}
+
+	return &WhatsAppCloudChannel{
+		BaseChannel:   NewBaseChannelWithAccess(whatsappChannelName, b, Access{AllowFrom: cfg.AllowFrom, DenyFrom: cfg.DenyFrom, DeniedReply: cfg.DeniedReply}),
+		cfg:           cfg,
+		clientFactory: factory,
+		now:           time.Now,
+		lastInbound:   make(map[string]time.Time),
+	}, nil
+}
+
+func (w *WhatsAppCloudChannel) Start(ctx context.Context) error {
+	w.client = w.clientFactory(w.cfg.PhoneNumberID, w.cfg.AccessToken)
+
+	port := w.cfg.Port
+	if port == 0 {
+		port = whatsappCloudDefaultPort
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/whatsapp/webhook", w.handleWebhook)
+	w.server = &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+	}
+
+	go func() {
+		log.Printf("[whatsapp] cloud webhook listening on :%d", port)
+		if err := w.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("[whatsapp] server error: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		w.server.Close()
+	}()
+
+	return nil
+}
+
+func (w *WhatsAppCloudChannel) Stop() error {
+	if w.server != nil {
+		w.server.Close()
+	}
+	log.Printf("[whatsapp] stopped")
+	return nil
+}
+
+// Send replies with text inside the customer service window. Outside it, or
+// when the message carries a "whatsapp_template" metadata entry, a template
+// message is sent with the content as its body parameter. Voice replies go
+// out as voice notes and images after the text, but never with a template.
+func (w *WhatsAppCloudChannel) Send(msg bus.OutboundMessage) error {
+	if w.client == nil {
+		return fmt.Errorf("whatsapp client not initialized")
+	}
+	to := strings.TrimSpace(msg.ChatID)
+	if to == "" {
+		return fmt.Errorf("whatsapp chat id is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), whatsappSendTimeout)
+	defer cancel()
+
+	name, language := w.templateFor(msg)
+	if name == "" {
+		if err := w.sendWithVoice(ctx, to, msg); err != nil {
+			return err
+		}
+		w.sendImages(ctx, to, msg.Media)
+		return nil
+	}
+	var params []string
+	if msg.Content != "" {
+		params = []string{msg.Content}
+	}
+	return w.client.SendTemplate(ctx, to, name, language, params)
+}
+
+func (w *WhatsAppCloudChannel) sendWithVoice(ctx context.Context, to string, msg bus.OutboundMessage) error {
+	if len(msg.Voice) > 0 && msg.VoiceOnly {
+		err := w.client.SendVoice(ctx, to, msg.Voice)
+		if err == nil {
+			return nil
+		}
+		log.Printf("[whatsapp] send voice note failed, sending text: %v", err)
+	}
+	if err := w.client.SendText(ctx, to, msg.Content); err != nil {
+		return err
+	}
+	if len(msg.Voice) > 0 && !msg.VoiceOnly {
+		if err := w.client.SendVoice(ctx, to, msg.Voice); err != nil {
+			log.Printf("[whatsapp] send voice note failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// sendImages sends the pictures among files after a reply went out, so
+// failures are only logged.
+func (w *WhatsAppCloudChannel) sendImages(ctx context.Context, to string, files []string) {
+	for _, path := range files {
+		if !isImageFile(path) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = w.client.SendImage(ctx, to, data, http.DetectContentType(data))
+		}
+		if err != nil {
+			log.Printf("[whatsapp] send %s failed: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+// templateFor picks the template for msg, or "" to send plain text.
+func (w *WhatsAppCloudChannel) templateFor(msg bus.OutboundMessage) (name, language string) {
+	language = w.cfg.Template.Language
+	if lang, ok := msg.Metadata["whatsapp_template_language"].(string); ok && lang != "" {
+		language = lang
+	}
+	if language == "" {
+		language = whatsappCloudDefaultLanguage
+	}
+
+	if name, ok := msg.Metadata["whatsapp_template"].(string); ok && name != "" {
+		return name, language
+	}
+	if w.cfg.Template.Name == "" || w.inServiceWindow(msg.ChatID) {
+		return "", ""
+	}
+	return w.cfg.Template.Name, language
+}
+
+func (w *WhatsAppCloudChannel) inServiceWindow(chatID string) bool {
+	w.mu.Lock()
+	last, ok := w.lastInbound[chatID]
+	w.mu.Unlock()
+	return ok && w.now().Sub(last) < whatsappCloudServiceWindow
+}
+
+func (w *WhatsAppCloudChannel) handleWebhook(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.handleVerify(rw, r)
+	case http.MethodPost:
+		w.handleNotification(rw, r)
+	default:
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleVerify answers Meta's subscription handshake.
+func (w *WhatsAppCloudChannel) handleVerify(rw http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("hub.mode") != "subscribe" || !hmac.Equal([]byte(q.Get("hub.verify_token")), []byte(w.cfg.VerifyToken)) {
+		http.Error(rw, "verification failed", http.StatusForbidden)
+		return
+	}
+	rw.Write([]byte(q.Get("hub.challenge")))
+}
+
+func (w *WhatsAppCloudChannel) handleNotification(rw http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, 1<<20))
+	if err != nil {
+		http.Error(rw, "read body failed", http.StatusBadRequest)
+		return
+	}
+	if !w.verifySignature(r.Header.Get("X-Hub-Signature-256"), body) {
+		http.Error(rw, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	// Meta retries a webhook that is not answered quickly, and media
+	// downloads can take a while, so the payload is handled after the reply.
+	rw.WriteHeader(http.StatusOK)
+	go w.handlePayload(body)
+}
+
+func (w *WhatsAppCloudChannel) verifySignature(signature string, body []byte) bool {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(w.cfg.AppSecret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(sig)))
+}
+
+func (w *WhatsAppCloudChannel) handlePayload(data []byte) {
+	var payload struct {
+		Entry []struct {
+			Changes []struct {
+				Value struct {
+					Contacts []struct {
+						WaID    string `json:"wa_id"`
+						Profile struct {
+							Name string `json:"name"`
+						} `json:"profile"`
+					} `json:"contacts"`
+					Messages []whatsappCloudMessage `json:"messages"`
+				} `json:"value"`
+			} `json:"changes"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		log.Printf("[whatsapp] parse webhook error: %v", err)
+		return
+	}
+
+	for _, entry := range payload.Entry {
+		for _, change := range entry.Changes {
+			names := make(map[string]string)
+			for _, c := range change.Value.Contacts {
+				names[c.WaID] = c.Profile.Name
+			}
+			for _, m := range change.Value.Messages {
+				w.handleMessage(m, names[m.From])
+			}
+		}
+	}
+}
+
+type whatsappCloudMessage struct {
+	From string `json:"from"`
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Text struct {
+		Body string `json:"body"`
+	} `json:"text"`
+	Image    whatsappCloudMedia `json:"image"`
+	Audio    whatsappCloudMedia `json:"audio"`
+	Document whatsappCloudMedia `json:"document"`
+	Button   struct {
+		Text string `json:"text"`
+	} `json:"button"`
+	Interactive struct {
+		ButtonReply struct {
+			Title string `json:"title"`
+		} `json:"button_reply"`
+		ListReply struct {
+			Title string `json:"title"`
+		} `json:"list_reply"`
+	} `json:"interactive"`
+}
+
+// whatsappCloudMedia is the file of an image, audio (voice notes included)
+// or document message, to be downloaded by ID.
+type whatsappCloudMedia struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type"`
+	Caption  string `json:"caption"`
+	Filename string `json:"filename"`
+}
+
+// media returns the file m carries, if any.
+func (m whatsappCloudMessage) media() (whatsappCloudMedia, bool) {
+	var media whatsappCloudMedia
+	switch m.Type {
+	case "image":
+		media = m.Image
+	case "audio":
+		media = m.Audio
+	case "document":
+		media = m.Document
+	}
+	return media, media.ID != ""
+}
+
+func (m whatsappCloudMessage) content() string {
+	switch m.Type {
+	case "text":
+		return m.Text.Body
+	case "image", "document":
+		media, _ := m.media()
+		return media.Caption
+	case "button":
+		return m.Button.Text
+	case "interactive":
+		if m.Interactive.ButtonReply.Title != "" {
+			return m.Interactive.ButtonReply.Title
+		}
+		return m.Interactive.ListReply.Title
+	}
+	return ""
+}
+
+func (w *WhatsAppCloudChannel) handleMessage(m whatsappCloudMessage, name string) {
+	if m.From == "" {
+		return
+	}
+	if !w.IsAllowed(m.From) {
+		w.Reject(m.From, m.From)
+		return
+	}
+
+	// Any message opens the service window, even ones we can't read.
+	w.mu.Lock()
+	w.lastInbound[m.From] = w.now()
+	w.mu.Unlock()
+
+	content := strings.TrimSpace(m.content())
+	attachments := w.downloadMedia(m)
+	if content == "" && len(attachments) == 0 {
+		log.Printf("[whatsapp] ignoring unsupported %s message from %s", m.Type, m.From)
+		return
+	}
+
+	w.bus.Inbound <- bus.InboundMessage{
+		Channel:     whatsappChannelName,
+		SenderID:    m.From,
+		ChatID:      m.From,
+		Content:     content,
+		Timestamp:   time.Now(),
+		Attachments: attachments,
+		Metadata: map[string]any{
+			"message_id": m.ID,
+			"name":       name,
+		},
+	}
+}
+
+// downloadMedia fetches the image, audio or document of m for the gateway
+// to read. A failed download is logged and the message goes on without it.
+func (w *WhatsAppCloudChannel) downloadMedia(m whatsappCloudMessage) []bus.Attachment {
+	media, ok := m.media()
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), whatsappInboundMediaTimeout)
+	defer cancel()
+	data, mediaType, err := w.client.DownloadMedia(ctx, media.ID)
+	if err != nil {
+		log.Printf("[whatsapp] download %s failed: %v", m.Type, err)
+		return nil
+	}
+	if media.MimeType != "" {
+		mediaType = media.MimeType
+	}
+	return []bus.Attachment{{Name: media.Filename, MediaType: mediaType, Data: data}}
+}
@@ -0,0 +1,416 @@
+package channel
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+type whatsappCloudSend struct {
+	to, text, template, language string
+	params                       []string
+	voice, image                 []byte
+}
+
+type mockWhatsAppCloudClient struct {
+	sent     []whatsappCloudSend
+	voiceErr error
+	media    map[string][]byte // media id -> file
+}
+
+func (m *mockWhatsAppCloudClient) SendText(ctx context.Context, to, text string) error {
+	m.sent = append(m.sent, whatsappCloudSend{to: to, text: text})
+	return nil
+}
+
+func (m *mockWhatsAppCloudClient) SendTemplate(ctx context.Context, to, name, language string, params []string) error {
+	m.sent = append(m.sent, whatsappCloudSend{to: to, template: name, language: language, params: params})
+	return nil
+}
+
+func (m *mockWhatsAppCloudClient) SendVoice(ctx context.Context, to string, audio []byte) error {
+	if m.voiceErr != nil {
+		return m.voiceErr
+	}
+	m.sent = append(m.sent, whatsappCloudSend{to: to, voice: audio})
+	return nil
+}
+
+func (m *mockWhatsAppCloudClient) SendImage(ctx context.Context, to string, image []byte, mediaType string) error {
+	m.sent = append(m.sent, whatsappCloudSend{to: to, image: image})
+	return nil
+}
+
+func (m *mockWhatsAppCloudClient) DownloadMedia(ctx context.Context, id string) ([]byte, string, error) {
+	data, ok := m.media[id]
+	if !ok {
+		return nil, "", fmt.Errorf("no media %s", id)
+	}
+	return data, http.DetectContentType(data), nil
+}
+
+func newTestWhatsAppCloudChannel(t *testing.T, cfg config.WhatsAppConfig, client *mockWhatsAppCloudClient) (*WhatsAppCloudChannel, *bus.MessageBus) {
+	t.Helper()
+	cfg.PhoneNumberID = "123"
+	cfg.AccessToken = "token"
+	cfg.AppSecret = "secret"
+	cfg.VerifyToken = "verify"
+	b := bus.NewMessageBus(10)
+	ch, err := NewWhatsAppCloudChannelWithFactory(cfg, b, func(phoneNumberID, accessToken string) WhatsAppCloudClient {
+		return client
+	})
+	if err != nil {
+		t.Fatalf("NewWhatsAppCloudChannelWithFactory error: %v", err)
+	}
+	ch.client = client
+	return ch, b
+}
+
+func whatsappTextPayload(from, text string) string {
+	return fmt.Sprintf(`{"object":"whatsapp_business_account","entry":[{"changes":[{"value":{"contacts":[{"wa_id":%q,"profile":{"name":"Ann"}}],"messages":[{"from":%q,"id":"wamid.1","type":"text","text":{"body":%q}}]}}]}]}`, from, from, text)
+}
+
+func signWhatsApp(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestNewWhatsAppCloudChannel_Validation(t *testing.T) {
+	b := bus.NewMessageBus(10)
+	if _, err := NewWhatsAppCloudChannel(config.WhatsAppConfig{AppSecret: "s", VerifyToken: "v"}, b); err == nil {
+		t.Error("expected error for missing phone number id and token")
+	}
+	if _, err := NewWhatsAppCloudChannel(config.WhatsAppConfig{PhoneNumberID: "1", AccessToken: "t"}, b); err == nil {
+		t.Error("expected error for missing app secret and verify token")
+	}
+	ch, err := NewWhatsAppCloudChannel(config.WhatsAppConfig{PhoneNumberID: "1", AccessToken: "t", AppSecret: "s", VerifyToken: "v"}, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ch.Name() != "whatsapp" {
+		t.Errorf("name = %q", ch.Name())
+	}
+}
+
+func TestWhatsAppCloudChannel_Verify(t *testing.T) {
+	ch, _ := newTestWhatsAppCloudChannel(t, config.WhatsAppConfig{}, &mockWhatsAppCloudClient{})
+
+	w := httptest.NewRecorder()
+	ch.handleWebhook(w, httptest.NewRequest(http.MethodGet, "/whatsapp/webhook?hub.mode=subscribe&hub.verify_token=verify&hub.challenge=42", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "42" {
+		t.Errorf("verify = %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	ch.handleWebhook(w, httptest.NewRequest(http.MethodGet, "/whatsapp/webhook?hub.mode=subscribe&hub.verify_token=nope&hub.challenge=42", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("bad token status = %d, want 403", w.Code)
+	}
+}
+
+func TestWhatsAppCloudChannel_Webhook(t *testing.T) {
+	ch, b := newTestWhatsAppCloudChannel(t, config.WhatsAppConfig{}, &mockWhatsAppCloudClient{})
+	body := whatsappTextPayload("15551234567", "hello")
+
+	post := func(sig string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/whatsapp/webhook", strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", sig)
+		w := httptest.NewRecorder()
+		ch.handleWebhook(w, req)
+		return w
+	}
+
+	if w := post(signWhatsApp("wrong", body)); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature status = %d, want 401", w.Code)
+	}
+	if w := post(""); w.Code != http.StatusUnauthorized {
+		t.Errorf("missing signature status = %d, want 401", w.Code)
+	}
+	if w := post(signWhatsApp("secret", body)); w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	select {
+	case msg := <-b.Inbound:
+		if msg.ChatID != "15551234567" || msg.Content != "hello" || msg.Metadata["name"] != "Ann" {
+			t.Errorf("inbound = %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected inbound message")
+	}
+}
+
+func TestWhatsAppCloudChannel_IgnoresDisallowedAndUnsupported(t *testing.T) {
+	ch, b := newTestWhatsAppCloudChannel(t, config.WhatsAppConfig{AllowFrom: []string{"1"}}, &mockWhatsAppCloudClient{})
+	ch.handlePayload([]byte(whatsappTextPayload("2", "stranger")))
+	ch.handlePayload([]byte(`{"entry":[{"changes":[{"value":{"messages":[{"from":"1","type":"location","location":{"latitude":1}}]}}]}]}`))
+	ch.handlePayload([]byte(`{"entry":[{"changes":[{"value":{"messages":[{"from":"1","type":"image","image":{"id":"gone"}}]}}]}]}`))
+	ch.handlePayload([]byte(`{"entry":[{"changes":[{"value":{"statuses":[{"id":"wamid.1","status":"read"}]}}]}]}`))
+
+	select {
+	case msg := <-b.Inbound:
+		t.Errorf("unexpected inbound: %+v", msg)
+	default:
+	}
+	if !ch.inServiceWindow("1") {
+		t.Error("an unsupported message should still open the service window")
+	}
+}
+
+func TestWhatsAppCloudChannel_InboundMedia(t *testing.T) {
+	client := &mockWhatsAppCloudClient{media: map[string][]byte{
+		"img-1":   []byte("\x89PNG\r\n\x1a\nrest"),
+		"voice-1": []byte("OggS voice"),
+		"doc-1":   []byte("minutes"),
+	}}
+	ch, b := newTestWhatsAppCloudChannel(t, config.WhatsAppConfig{}, client)
+	ch.handlePayload([]byte(`{"entry":[{"changes":[{"value":{"messages":[
+		{"from":"1","type":"image","image":{"id":"img-1","mime_type":"image/png","caption":"what is this?"}},
+		{"from":"1","type":"audio","audio":{"id":"voice-1","mime_type":"audio/ogg; codecs=opus","voice":true}},
+		{"from":"1","type":"document","document":{"id":"doc-1","mime_type":"text/plain","filename":"notes.txt"}}
+	]}}]}]}`))
+
+	image, voice, doc := <-b.Inbound, <-b.Inbound, <-b.Inbound
+	if image.Content != "what is this?" || len(image.Attachments) != 1 || image.Attachments[0].MediaType != "image/png" {
+		t.Errorf("image = %+v", image)
+	}
+	if voice.Content != "" || len(voice.Attachments) != 1 || string(voice.Attachments[0].Data) != "OggS voice" || voice.Attachments[0].MediaType != "audio/ogg; codecs=opus" {
+		t.Errorf("voice = %+v", voice)
+	}
+	if len(doc.Attachments) != 1 || doc.Attachments[0].Name != "notes.txt" || string(doc.Attachments[0].Data) != "minutes" {
+		t.Errorf("document = %+v", doc)
+	}
+}
+
+func TestWhatsAppCloudChannel_WebhookBodyLimit(t *testing.T) {
+	ch, b := newTestWhatsAppCloudChannel(t, config.WhatsAppConfig{}, &mockWhatsAppCloudClient{})
+	body := whatsappTextPayload("1", strings.Repeat("x", 2<<20))
+	req := httptest.NewRequest(http.MethodPost, "/whatsapp/webhook", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", signWhatsApp("secret", body))
+	w := httptest.NewRecorder()
+	ch.handleWebhook(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	select {
+	case msg := <-b.Inbound:
+		t.Errorf("unexpected inbound: %+v", msg)
+	default:
+	}
+}
+
+func TestWhatsAppCloudChannel_InteractiveReply(t *testing.T) {
+	ch, b := newTestWhatsAppCloudChannel(t, config.WhatsAppConfig{}, &mockWhatsAppCloudClient{})
+	ch.handlePayload([]byte(`{"entry":[{"changes":[{"value":{"messages":[{"from":"1","type":"interactive","interactive":{"type":"button_reply","button_reply":{"id":"b1","title":"Yes"}}}]}}]}]}`))
+	if msg := <-b.Inbound; msg.Content != "Yes" {
+		t.Errorf("content = %q", msg.Content)
+	}
+}
+
+func TestWhatsAppCloudChannel_SendUsesTemplateOutsideWindow(t *testing.T) {
+	client := &mockWhatsAppCloudClient{}
+	ch, b := newTestWhatsAppCloudChannel(t, config.WhatsAppConfig{
+		Template: config.WhatsAppTemplateConfig{Name: "notify", Language: "de"},
+	}, client)
+	now := time.Unix(1700000000, 0)
+	ch.now = func() time.Time { return now }
+
+	// Never messaged: template.
+	ch.Send(bus.OutboundMessage{ChatID: "1", Content: "reminder"})
+	// Inside the window: text.
+	ch.handlePayload([]byte(whatsappTextPayload("1", "hi")))
+	<-b.Inbound
+	ch.Send(bus.OutboundMessage{ChatID: "1", Content: "reply"})
+	// Window expired: template again.
+	now = now.Add(25 * time.Hour)
+	ch.Send(bus.OutboundMessage{ChatID: "1", Content: "later"})
+	// Explicit template from metadata.
+	ch.Send(bus.OutboundMessage{ChatID: "1", Metadata: map[string]any{"whatsapp_template": "hello_world", "whatsapp_template_language": "en_US"}})
+
+	want := []whatsappCloudSend{
+		{to: "1", template: "notify", language: "de", params: []string{"reminder"}},
+		{to: "1", text: "reply"},
+		{to: "1", template: "notify", language: "de", params: []string{"later"}},
+		{to: "1", template: "hello_world", language: "en_US"},
+	}
+	if len(client.sent) != len(want) {
+		t.Fatalf("sent = %+v", client.sent)
+	}
+	for i := range want {
+		if fmt.Sprint(client.sent[i]) != fmt.Sprint(want[i]) {
+			t.Errorf("sent[%d] = %+v, want %+v", i, client.sent[i], want[i])
+		}
+	}
+}
+
+func TestWhatsAppCloudChannel_SendTextWithoutTemplate(t *testing.T) {
+	client := &mockWhatsAppCloudClient{}
+	ch, _ := newTestWhatsAppCloudChannel(t, config.WhatsAppConfig{}, client)
+	if err := ch.Send(bus.OutboundMessage{ChatID: "1", Content: "hi"}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(client.sent) != 1 || client.sent[0].text != "hi" {
+		t.Errorf("sent = %+v", client.sent)
+	}
+	if err := ch.Send(bus.OutboundMessage{Content: "x"}); err == nil {
+		t.Error("expected error for empty chat id")
+	}
+}
+
+func TestWhatsAppCloudChannel_SendVoice(t *testing.T) {
+	client := &mockWhatsAppCloudClient{}
+	ch, _ := newTestWhatsAppCloudChannel(t, config.WhatsAppConfig{}, client)
+	if err := ch.Send(bus.OutboundMessage{ChatID: "1", Content: "hi", Voice: []byte("OggS"), VoiceOnly: true}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(client.sent) != 1 || string(client.sent[0].voice) != "OggS" {
+		t.Errorf("voice only sent = %+v", client.sent)
+	}
+
+	client.sent = nil
+	if err := ch.Send(bus.OutboundMessage{ChatID: "1", Content: "hi", Voice: []byte("OggS")}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(client.sent) != 2 || client.sent[0].text != "hi" || client.sent[1].voice == nil {
+		t.Errorf("text and voice sent = %+v", client.sent)
+	}
+
+	// A failed voice note falls back to text.
+	client.sent = nil
+	client.voiceErr = fmt.Errorf("upload failed")
+	if err := ch.Send(bus.OutboundMessage{ChatID: "1", Content: "hi", Voice: []byte("OggS"), VoiceOnly: true}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(client.sent) != 1 || client.sent[0].text != "hi" {
+		t.Errorf("fallback sent = %+v", client.sent)
+	}
+}
+
+func TestWhatsAppCloudChannel_SendImages(t *testing.T) {
+	client := &mockWhatsAppCloudClient{}
+	ch, _ := newTestWhatsAppCloudChannel(t, config.WhatsAppConfig{}, client)
+	dir := t.TempDir()
+	img, doc := filepath.Join(dir, "fox.png"), filepath.Join(dir, "notes.txt")
+	os.WriteFile(img, []byte("\x89PNG\r\n\x1a\n"), 0o644)
+	os.WriteFile(doc, []byte("notes"), 0o644)
+	if err := ch.Send(bus.OutboundMessage{ChatID: "1", Content: "Here you go", Media: []string{img, doc}}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if len(client.sent) != 2 || client.sent[0].text != "Here you go" || client.sent[1].image == nil {
+		t.Errorf("sent = %+v", client.sent)
+	}
+}
+
+func TestDefaultWhatsAppCloudClient(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/123/messages" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("request = %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		if got["to"] == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"Re-engagement message","code":131047}}`))
+			return
+		}
+		w.Write([]byte(`{"messages":[{"id":"wamid.2"}]}`))
+	}))
+	defer srv.Close()
+
+	client := &defaultWhatsAppCloudClient{phoneNumberID: "123", accessToken: "token", baseURL: srv.URL + "/", http: srv.Client()}
+	if err := client.SendText(context.Background(), "1", "hi"); err != nil {
+		t.Fatalf("SendText error: %v", err)
+	}
+	if got["messaging_product"] != "whatsapp" || got["type"] != "text" {
+		t.Errorf("payload = %v", got)
+	}
+
+	if err := client.SendTemplate(context.Background(), "1", "notify", "en_US", []string{"x"}); err != nil {
+		t.Fatalf("SendTemplate error: %v", err)
+	}
+	tmpl, _ := got["template"].(map[string]any)
+	if tmpl["name"] != "notify" || tmpl["components"] == nil {
+		t.Errorf("template payload = %v", got)
+	}
+
+	if err := client.SendText(context.Background(), "bad", "hi"); err == nil || !strings.Contains(err.Error(), "131047") {
+		t.Errorf("expected api error, got %v", err)
+	}
+}
+
+func TestDefaultWhatsAppCloudClient_SendVoice(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/123/media":
+			file, _, err := r.FormFile("file")
+			if err != nil || r.FormValue("messaging_product") != "whatsapp" || r.FormValue("type") != "audio/ogg" {
+				t.Errorf("upload form: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			file.Close()
+			w.Write([]byte(`{"id":"media-1"}`))
+		case "/123/messages":
+			json.NewDecoder(r.Body).Decode(&got)
+			w.Write([]byte(`{"messages":[{"id":"wamid.3"}]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	client := &defaultWhatsAppCloudClient{phoneNumberID: "123", accessToken: "token", baseURL: srv.URL + "/", http: srv.Client()}
+	if err := client.SendVoice(context.Background(), "1", []byte("OggS")); err != nil {
+		t.Fatalf("SendVoice error: %v", err)
+	}
+	audio, _ := got["audio"].(map[string]any)
+	if got["type"] != "audio" || audio["id"] != "media-1" {
+		t.Errorf("payload = %v", got)
+	}
+}
+
+func TestDefaultWhatsAppCloudClient_DownloadMedia(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/media-1":
+			fmt.Fprintf(w, `{"url":%q,"mime_type":"image/jpeg","file_size":4,"id":"media-1"}`, srvURL+"/files/media-1")
+		case "/media-big":
+			fmt.Fprintf(w, `{"url":%q,"mime_type":"video/mp4","file_size":%d}`, srvURL+"/files/big", whatsappCloudMediaMaxBytes+1)
+		case "/files/media-1":
+			w.Write([]byte("JPEG"))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	client := &defaultWhatsAppCloudClient{phoneNumberID: "123", accessToken: "token", baseURL: srv.URL + "/", http: srv.Client()}
+	data, mediaType, err := client.DownloadMedia(context.Background(), "media-1")
+	if err != nil || string(data) != "JPEG" || mediaType != "image/jpeg" {
+		t.Errorf("DownloadMedia = %q, %q, %v", data, mediaType, err)
+	}
+	if _, _, err := client.DownloadMedia(context.Background(), "media-big"); err == nil {
+		t.Error("expected an error for a file over the limit")
+	}
+}
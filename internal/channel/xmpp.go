@@ -0,0 +1,248 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/xmpp"
+)
+
+const xmppChannelName = "xmpp"
+
+const (
+	xmppDefaultNick        = "myclaw"
+	xmppKeepAlive          = time.Minute
+	xmppReconnectBaseDelay = time.Second
+	xmppReconnectMaxDelay  = 5 * time.Minute
+)
+
+// XMPPClient interface for an XMPP session (allows mocking)
+type XMPPClient interface {
+	Join(room, nick string) error
+	Send(to, typ, body string) error
+	// Recv blocks for the next message, and fails once the connection is
+	// lost.
+	Recv() (xmpp.Message, error)
+	Ping() error
+	Close() error
+}
+
+// XMPPClientFactory logs in and returns the session.
+type XMPPClientFactory func(ctx context.Context, cfg config.XMPPConfig) (XMPPClient, error)
+
+var defaultXMPPClientFactory XMPPClientFactory = func(ctx context.Context, cfg config.XMPPConfig) (XMPPClient, error) {
+	return xmpp.Dial(ctx, xmpp.Config{JID: cfg.JID, Password: cfg.Password, Server: cfg.Server})
+}
+
+// XMPPChannel chats as an XMPP user. Direct chats are keyed by the sender's
+// bare JID, and each joined room is one chat keyed by the room's JID, in
+// which the agent answers messages that mention its nickname.
+type XMPPChannel struct {
+	BaseChannel
+	cfg           config.XMPPConfig
+	clientFactory XMPPClientFactory
+	nick          string
+	rooms         map[string]bool
+	cancel        context.CancelFunc
+
+	mu     sync.Mutex
+	client XMPPClient // nil while disconnected
+}
+
+func NewXMPPChannel(cfg config.XMPPConfig, b *bus.MessageBus) (*XMPPChannel, error) {
+	return NewXMPPChannelWithFactory(cfg, b, defaultXMPPClientFactory)
+}
+
+func NewXMPPChannelWithFactory(cfg config.XMPPConfig, b *bus.MessageBus, factory XMPPClientFactory) (*XMPPChannel, error) {
+	if cfg.JID == "" || cfg.Password == "" {
+		return nil, errors.New("xmpp jid and password are required")
+	}
+	nick := cfg.Nick
+	if nick == "" {
+		nick = xmppDefaultNick
+	}
+	rooms := make(map[string]bool, len(cfg.Rooms))
+	for _, room := range cfg.Rooms {
+		rooms[xmpp.Bare(room)] = true
+	}
+	return &XMPPChannel{
+		BaseChannel:   NewBaseChannelWithAccess(xmppChannelName, b, Access{AllowFrom: cfg.AllowFrom, DenyFrom: cfg.DenyFrom, DeniedReply: cfg.DeniedReply}),
+		cfg:           cfg,
+		clientFactory: factory,
+		nick:          nick,
+		rooms:         rooms,
+	}, nil
+}
+
+func (x *XMPPChannel) Start(ctx context.Context) error {
+	ctx, x.cancel = context.WithCancel(ctx)
+	go x.connectLoop(ctx)
+	log.Printf("[xmpp] connecting as %s", x.cfg.JID)
+	return nil
+}
+
+func (x *XMPPChannel) Stop() error {
+	if x.cancel != nil {
+		x.cancel()
+	}
+	x.mu.Lock()
+	if x.client != nil {
+		x.client.Close()
+	}
+	x.mu.Unlock()
+	log.Printf("[xmpp] stopped")
+	return nil
+}
+
+// connectLoop keeps a session open, reconnecting with backoff.
+func (x *XMPPChannel) connectLoop(ctx context.Context) {
+	for attempt := 0; ; attempt++ {
+		connected, err := x.run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			attempt = 0
+		}
+		delay := min(xmppReconnectBaseDelay<<min(attempt, 9), xmppReconnectMaxDelay)
+		log.Printf("[xmpp] %v (reconnecting in %s)", err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run logs in, joins the rooms and handles messages until the connection
+// is lost. It reports whether it got as far as logging in.
+func (x *XMPPChannel) run(ctx context.Context) (bool, error) {
+	client, err := x.clientFactory(ctx, x.cfg)
+	if err != nil {
+		return false, err
+	}
+	x.mu.Lock()
+	x.client = client
+	x.mu.Unlock()
+	defer func() {
+		x.mu.Lock()
+		x.client = nil
+		x.mu.Unlock()
+		client.Close()
+	}()
+
+	for room := range x.rooms {
+		if err := client.Join(room, x.nick); err != nil {
+			return true, fmt.Errorf("join %s: %w", room, err)
+		}
+	}
+	log.Printf("[xmpp] connected as %s", x.cfg.JID)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(xmppKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				client.Ping()
+			case <-done:
+				return
+			case <-ctx.Done():
+				client.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		msg, err := client.Recv()
+		if err != nil {
+			return true, fmt.Errorf("connection lost: %w", err)
+		}
+		x.handleMessage(msg)
+	}
+}
+
+func (x *XMPPChannel) handleMessage(msg xmpp.Message) {
+	var chatID, sender, content string
+	switch msg.Type {
+	case "error":
+		return
+	case "groupchat":
+		room, nick := xmpp.Bare(msg.From), xmpp.Resource(msg.From)
+		// Rooms replay their history on joining, and echo our own messages.
+		if msg.Delayed || !x.rooms[room] || nick == "" || nick == x.nick {
+			return
+		}
+		text, ok := xmppMention(msg.Body, x.nick)
+		if !ok {
+			return
+		}
+		chatID, sender, content = room, msg.From, text
+		if !x.IsAllowed(room, msg.From) {
+			x.Reject(chatID, sender)
+			return
+		}
+	default:
+		sender = xmpp.Bare(msg.From)
+		// Private messages from room occupants come from the room's JID,
+		// which hides who sent them.
+		if x.rooms[sender] {
+			return
+		}
+		chatID, content = sender, strings.TrimSpace(msg.Body)
+		if !x.IsAllowed(sender) {
+			x.Reject(chatID, sender)
+			return
+		}
+	}
+	if content == "" {
+		return
+	}
+
+	x.bus.Inbound <- bus.InboundMessage{
+		Channel:   xmppChannelName,
+		SenderID:  sender,
+		ChatID:    chatID,
+		Content:   content,
+		Timestamp: time.Now(),
+	}
+}
+
+// xmppMention reports whether body mentions nick and returns it without a
+// leading address such as "nick:", "nick," or "@nick".
+func xmppMention(body, nick string) (string, bool) {
+	if !strings.Contains(strings.ToLower(body), strings.ToLower(nick)) {
+		return "", false
+	}
+	text := strings.TrimSpace(body)
+	rest := strings.TrimPrefix(text, "@")
+	if len(rest) >= len(nick) && strings.EqualFold(rest[:len(nick)], nick) {
+		text = strings.TrimLeft(rest[len(nick):], ":, ")
+	}
+	return strings.TrimSpace(text), true
+}
+
+// Send sends the reply's text; media are left out.
+func (x *XMPPChannel) Send(msg bus.OutboundMessage) error {
+	x.mu.Lock()
+	client := x.client
+	x.mu.Unlock()
+	if client == nil {
+		return errors.New("xmpp not connected")
+	}
+	typ := "chat"
+	if x.rooms[msg.ChatID] {
+		typ = "groupchat"
+	}
+	return client.Send(msg.ChatID, typ, msg.Content)
+}
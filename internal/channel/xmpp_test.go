@@ -0,0 +1,136 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/xmpp"
+)
+
+type xmppSend struct {
+	to, typ, body string
+}
+
+type mockXMPPClient struct {
+	inbox chan xmpp.Message
+
+	mu     sync.Mutex
+	joined []string
+	sent   []xmppSend
+}
+
+func (m *mockXMPPClient) Join(room, nick string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.joined = append(m.joined, room+"/"+nick)
+	return nil
+}
+
+func (m *mockXMPPClient) Send(to, typ, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, xmppSend{to, typ, body})
+	return nil
+}
+
+func (m *mockXMPPClient) Recv() (xmpp.Message, error) {
+	msg, ok := <-m.inbox
+	if !ok {
+		return xmpp.Message{}, errors.New("closed")
+	}
+	return msg, nil
+}
+
+func (m *mockXMPPClient) Ping() error  { return nil }
+func (m *mockXMPPClient) Close() error { return nil }
+
+func TestXMPPChannel(t *testing.T) {
+	if _, err := NewXMPPChannelWithFactory(config.XMPPConfig{JID: "bot@example.org"}, bus.NewMessageBus(1), nil); err == nil {
+		t.Error("missing password accepted")
+	}
+
+	b := bus.NewMessageBus(10)
+	client := &mockXMPPClient{inbox: make(chan xmpp.Message, 10)}
+	cfg := config.XMPPConfig{
+		JID:       "bot@example.org",
+		Password:  "secret",
+		Rooms:     []string{"ops@conference.example.org"},
+		Nick:      "claw",
+		AllowFrom: []string{"alice@example.org", "ops@conference.example.org"},
+	}
+	ch, err := NewXMPPChannelWithFactory(cfg, b, func(context.Context, config.XMPPConfig) (XMPPClient, error) { return client, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(bus.OutboundMessage{ChatID: "alice@example.org", Content: "hi"}); err == nil {
+		t.Error("send before connecting succeeded")
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer ch.Stop()
+
+	client.inbox <- xmpp.Message{From: "mallory@evil.example/pc", Type: "chat", Body: "hello"}
+	client.inbox <- xmpp.Message{From: "alice@example.org/phone", Type: "chat", Body: " What's on today? "}
+	client.inbox <- xmpp.Message{From: "ops@conference.example.org/bob", Type: "groupchat", Body: "claw: old question", Delayed: true}
+	client.inbox <- xmpp.Message{From: "ops@conference.example.org/bob", Type: "groupchat", Body: "lunch anyone?"}
+	client.inbox <- xmpp.Message{From: "ops@conference.example.org/claw", Type: "groupchat", Body: "claw here"}
+	client.inbox <- xmpp.Message{From: "ops@conference.example.org/bob", Type: "groupchat", Body: "@Claw, is the build green?"}
+
+	if got := nextInbound(t, b); got.ChatID != "alice@example.org" || got.SenderID != "alice@example.org" || got.Content != "What's on today?" {
+		t.Errorf("direct = %+v", got)
+	}
+	if got := nextInbound(t, b); got.ChatID != "ops@conference.example.org" || got.SenderID != "ops@conference.example.org/bob" || got.Content != "is the build green?" {
+		t.Errorf("room = %+v", got)
+	}
+	if len(b.Inbound) != 0 {
+		t.Errorf("%d more messages passed on", len(b.Inbound))
+	}
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: "ops@conference.example.org", Content: "Green."}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(bus.OutboundMessage{ChatID: "alice@example.org", Content: "Standup at 10."}); err != nil {
+		t.Fatal(err)
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if len(client.joined) != 1 || client.joined[0] != "ops@conference.example.org/claw" {
+		t.Errorf("joined %v", client.joined)
+	}
+	if len(client.sent) != 2 || client.sent[0] != (xmppSend{"ops@conference.example.org", "groupchat", "Green."}) || client.sent[1].typ != "chat" {
+		t.Errorf("sent %+v", client.sent)
+	}
+}
+
+func nextInbound(t *testing.T, b *bus.MessageBus) bus.InboundMessage {
+	t.Helper()
+	select {
+	case msg := <-b.Inbound:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message")
+		return bus.InboundMessage{}
+	}
+}
+
+func TestXMPPMention(t *testing.T) {
+	for body, want := range map[string]string{
+		"claw: deploy?":      "deploy?",
+		"@claw deploy?":      "deploy?",
+		"CLAW, deploy?":      "deploy?",
+		"ask claw to deploy": "ask claw to deploy",
+	} {
+		if got, ok := xmppMention(body, "claw"); !ok || got != want {
+			t.Errorf("xmppMention(%q) = %q, %v", body, got, ok)
+		}
+	}
+	if _, ok := xmppMention("deploy?", "claw"); ok {
+		t.Error("unaddressed message taken as a mention")
+	}
+}
@@ -0,0 +1,107 @@
+// Package choices gives the agent a tool to offer the user a few answers to
+// pick with one tap, shown as buttons under its reply on channels that have
+// them. A pressed button comes back as the user's next message.
+package choices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+const (
+	// MaxOptions is how many answers one reply can offer.
+	MaxOptions = 8
+	// maxOptionBytes is Telegram's limit on a button's data, which carries
+	// the answer back.
+	maxOptionBytes = 64
+)
+
+// Sink is told the options offered during a run, to show with the reply.
+type Sink func(options []string)
+
+type sinkKey struct{}
+
+// WithSink makes sink hear of the options offered during runs with ctx.
+func WithSink(ctx context.Context, sink Sink) context.Context {
+	return context.WithValue(ctx, sinkKey{}, sink)
+}
+
+func sinkOf(ctx context.Context) Sink {
+	sink, _ := ctx.Value(sinkKey{}).(Sink)
+	return sink
+}
+
+// Tools returns offer_choices.
+func Tools() []tool.Tool {
+	return []tool.Tool{&offerTool{}}
+}
+
+type offerTool struct{}
+
+func (t *offerTool) Name() string { return "offer_choices" }
+
+func (t *offerTool) Description() string {
+	return "Offer the user answers they can pick with one tap, shown as buttons under your reply, " +
+		"when you ask something with a few possible answers: yes or no, which of these flights, what time. " +
+		"Still ask the question and list the options in your reply, since some chats show no buttons. " +
+		"The answer picked comes back as the user's next message, word for word."
+}
+
+func (t *offerTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"options": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": fmt.Sprintf("2 to %d short answers, e.g. [\"Yes\", \"No\"] or [\"BA 117 at 08:25\", \"VS 3 at 11:40\"]", MaxOptions),
+			},
+		},
+		Required: []string{"options"},
+	}
+}
+
+func (t *offerTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	options, err := parseOptions(params["options"])
+	if err != nil {
+		return failed(err), nil
+	}
+	sink := sinkOf(ctx)
+	if sink == nil {
+		return &tool.ToolResult{Success: true, Output: "This chat cannot show buttons; list the options in your reply."}, nil
+	}
+	sink(options)
+	return &tool.ToolResult{Success: true, Output: fmt.Sprintf("The %d options are shown as buttons under your reply.", len(options))}, nil
+}
+
+func parseOptions(v any) ([]string, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, errors.New("options must be a list of strings")
+	}
+	var options []string
+	for _, item := range list {
+		s, _ := item.(string)
+		s = strings.TrimSpace(s)
+		if s == "" || slices.Contains(options, s) {
+			continue
+		}
+		if len(s) > maxOptionBytes {
+			return nil, fmt.Errorf("option %q is too long; keep each under %d bytes", s, maxOptionBytes)
+		}
+		options = append(options, s)
+	}
+	if len(options) < 2 || len(options) > MaxOptions {
+		return nil, fmt.Errorf("got %d distinct options; offer 2 to %d", len(options), MaxOptions)
+	}
+	return options, nil
+}
+
+func failed(err error) *tool.ToolResult {
+	return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}
+}
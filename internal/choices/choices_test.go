@@ -0,0 +1,37 @@
+package choices
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestOfferChoices(t *testing.T) {
+	tl := Tools()[0]
+	var got []string
+	ctx := WithSink(context.Background(), func(options []string) { got = options })
+
+	res, err := tl.Execute(ctx, map[string]any{"options": []any{" BA 117 at 08:25 ", "VS 3 at 11:40", "BA 117 at 08:25", ""}})
+	if err != nil || !res.Success {
+		t.Fatalf("Execute = %+v, %v", res, err)
+	}
+	if strings.Join(got, "|") != "BA 117 at 08:25|VS 3 at 11:40" {
+		t.Errorf("offered %q", got)
+	}
+
+	for _, bad := range []any{
+		"Yes or no",
+		[]any{"Yes"},
+		[]any{"Yes", strings.Repeat("x", 65)},
+		[]any{"1", "2", "3", "4", "5", "6", "7", "8", "9"},
+	} {
+		if res, _ := tl.Execute(ctx, map[string]any{"options": bad}); res.Success {
+			t.Errorf("options %v accepted", bad)
+		}
+	}
+
+	res, _ = tl.Execute(context.Background(), map[string]any{"options": []any{"Yes", "No"}})
+	if !res.Success || !strings.Contains(res.Output, "cannot show buttons") {
+		t.Errorf("without a sink = %+v", res)
+	}
+}
@@ -0,0 +1,302 @@
+// Package cluster lets several gateway instances share one assistant. Each
+// instance renews leases in a shared backend: the leader runs cron and
+// heartbeat, every channel is served by exactly one instance, and a chat
+// session stays on the instance that started it while that instance is up.
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultLeaseTTL   = 15 * time.Second
+	DefaultSessionTTL = 30 * time.Minute
+
+	leaderKey         = "leader"
+	channelKeyPrefix  = "channel:"
+	sessionKeyPrefix  = "session:"
+	instanceKeyPrefix = "instance:"
+
+	mailboxInterval = time.Second
+)
+
+// Envelope kinds exchanged between instances.
+const (
+	KindInbound  = "inbound"
+	KindOutbound = "outbound"
+)
+
+// Envelope is a message handed from one instance to another.
+type Envelope struct {
+	Kind    string          `json:"kind"`
+	From    string          `json:"from"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Backend stores leases and the inter-instance mailbox. Every instance of a
+// cluster must point at the same backend.
+type Backend interface {
+	// Acquire takes or renews key for owner. It reports false when another
+	// owner holds an unexpired lease.
+	Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, key, owner string) error
+	// Holder returns the owner of an unexpired lease on key, or "".
+	Holder(ctx context.Context, key string) (string, error)
+	Send(ctx context.Context, to string, env Envelope) error
+	// Receive removes and returns every envelope addressed to "to".
+	Receive(ctx context.Context, to string) ([]Envelope, error)
+	Close() error
+}
+
+// DefaultInstanceID names this instance after the host and process.
+func DefaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "myclaw"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Coordinator keeps this instance's leases alive and delivers envelopes sent
+// to it. Callbacks run on the coordinator goroutine.
+type Coordinator struct {
+	backend    Backend
+	id         string
+	ttl        time.Duration
+	sessionTTL time.Duration
+
+	// OnChannel is called when this instance gains or loses a channel.
+	OnChannel func(name string, owned bool)
+	// OnEnvelope receives envelopes forwarded by other instances.
+	OnEnvelope func(env Envelope)
+
+	mu       sync.RWMutex
+	leader   bool
+	channels map[string]bool // wanted channel -> owned by us
+}
+
+func NewCoordinator(backend Backend, instanceID string, ttl, sessionTTL time.Duration) *Coordinator {
+	if instanceID == "" {
+		instanceID = DefaultInstanceID()
+	}
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	if sessionTTL <= 0 {
+		sessionTTL = DefaultSessionTTL
+	}
+	return &Coordinator{
+		backend:    backend,
+		id:         instanceID,
+		ttl:        ttl,
+		sessionTTL: sessionTTL,
+		channels:   make(map[string]bool),
+	}
+}
+
+func (c *Coordinator) ID() string {
+	return c.id
+}
+
+// WantChannels registers the channels this instance is able to serve. They
+// are claimed on the next renewal.
+func (c *Coordinator) WantChannels(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range names {
+		if _, ok := c.channels[name]; !ok {
+			c.channels[name] = false
+		}
+	}
+}
+
+func (c *Coordinator) IsLeader() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.leader
+}
+
+func (c *Coordinator) OwnsChannel(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.channels[name]
+}
+
+// OwnedChannels lists the channels currently served by this instance.
+func (c *Coordinator) OwnedChannels() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var names []string
+	for name, owned := range c.channels {
+		if owned {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChannelOwner returns the instance serving a channel, or "" if none is.
+func (c *Coordinator) ChannelOwner(ctx context.Context, name string) (string, error) {
+	return c.backend.Holder(ctx, channelKeyPrefix+name)
+}
+
+// ClaimSession pins a session to this instance unless a live peer already
+// has it. It returns the instance that should handle the session.
+func (c *Coordinator) ClaimSession(ctx context.Context, sessionKey string) (string, error) {
+	key := sessionKeyPrefix + sessionKey
+	ok, err := c.backend.Acquire(ctx, key, c.id, c.sessionTTL)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return c.id, nil
+	}
+
+	holder, err := c.backend.Holder(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if holder != "" {
+		alive, err := c.backend.Holder(ctx, instanceKeyPrefix+holder)
+		if err != nil {
+			return "", err
+		}
+		if alive != "" {
+			return holder, nil
+		}
+		// The owner is gone; take the session over.
+		log.Printf("[cluster] taking over session %s from %s", sessionKey, holder)
+		if err := c.backend.Release(ctx, key, holder); err != nil {
+			return "", err
+		}
+	}
+	ok, err = c.backend.Acquire(ctx, key, c.id, c.sessionTTL)
+	if err != nil || ok {
+		return c.id, err
+	}
+	// Another instance took it over first.
+	holder, err = c.backend.Holder(ctx, key)
+	if err == nil && holder == "" {
+		err = fmt.Errorf("session %s changed hands during takeover", sessionKey)
+	}
+	return holder, err
+}
+
+// Forward hands a payload to another instance.
+func (c *Coordinator) Forward(ctx context.Context, to, kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s envelope: %w", kind, err)
+	}
+	return c.backend.Send(ctx, to, Envelope{Kind: kind, From: c.id, Payload: data})
+}
+
+// Run renews leases and drains the mailbox until ctx is done, then releases
+// everything this instance holds.
+func (c *Coordinator) Run(ctx context.Context) {
+	renew := time.NewTicker(c.ttl / 3)
+	defer renew.Stop()
+	mailbox := time.NewTicker(mailboxInterval)
+	defer mailbox.Stop()
+
+	c.renew(ctx)
+	for {
+		select {
+		case <-renew.C:
+			c.renew(ctx)
+		case <-mailbox.C:
+			c.drain(ctx)
+		case <-ctx.Done():
+			c.release()
+			return
+		}
+	}
+}
+
+func (c *Coordinator) renew(ctx context.Context) {
+	if _, err := c.backend.Acquire(ctx, instanceKeyPrefix+c.id, c.id, c.ttl); err != nil {
+		log.Printf("[cluster] heartbeat failed: %v", err)
+	}
+
+	leader, err := c.backend.Acquire(ctx, leaderKey, c.id, c.ttl)
+	if err != nil {
+		// Without the backend we cannot prove we still lead; step down.
+		log.Printf("[cluster] leader renewal failed: %v", err)
+		leader = false
+	}
+	c.mu.Lock()
+	changed := c.leader != leader
+	c.leader = leader
+	names := make([]string, 0, len(c.channels))
+	for name := range c.channels {
+		names = append(names, name)
+	}
+	c.mu.Unlock()
+	if changed {
+		if leader {
+			log.Printf("[cluster] %s is now leader", c.id)
+		} else {
+			log.Printf("[cluster] %s is no longer leader", c.id)
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		owned, err := c.backend.Acquire(ctx, channelKeyPrefix+name, c.id, c.ttl)
+		if err != nil {
+			// Keep serving through a backend hiccup rather than bouncing the
+			// channel; a peer only takes over once our lease has expired.
+			log.Printf("[cluster] claim channel %s failed: %v", name, err)
+			continue
+		}
+		c.mu.Lock()
+		was := c.channels[name]
+		c.channels[name] = owned
+		c.mu.Unlock()
+		if was != owned && c.OnChannel != nil {
+			c.OnChannel(name, owned)
+		}
+	}
+}
+
+func (c *Coordinator) drain(ctx context.Context) {
+	envs, err := c.backend.Receive(ctx, c.id)
+	if err != nil {
+		log.Printf("[cluster] read mailbox failed: %v", err)
+		return
+	}
+	for _, env := range envs {
+		if c.OnEnvelope != nil {
+			c.OnEnvelope(env)
+		}
+	}
+}
+
+func (c *Coordinator) release() {
+	// ctx is already cancelled; give the releases their own short deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c.mu.Lock()
+	keys := []string{leaderKey, instanceKeyPrefix + c.id}
+	for name := range c.channels {
+		keys = append(keys, channelKeyPrefix+name)
+		c.channels[name] = false
+	}
+	c.leader = false
+	c.mu.Unlock()
+
+	for _, key := range keys {
+		if err := c.backend.Release(ctx, key, c.id); err != nil {
+			log.Printf("[cluster] release %s failed: %v", key, err)
+		}
+	}
+}
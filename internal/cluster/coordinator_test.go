@@ -0,0 +1,103 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCoordinator_LeaderAndChannels(t *testing.T) {
+	b := openTestBackend(t)
+	ctx := context.Background()
+
+	a := NewCoordinator(b, "a", time.Minute, time.Hour)
+	a.WantChannels("telegram", "feishu")
+	var gained []string
+	a.OnChannel = func(name string, owned bool) {
+		if owned {
+			gained = append(gained, name)
+		}
+	}
+	a.renew(ctx)
+
+	if !a.IsLeader() {
+		t.Error("first instance should lead")
+	}
+	if len(gained) != 2 || !a.OwnsChannel("telegram") {
+		t.Errorf("gained = %v", gained)
+	}
+
+	standby := NewCoordinator(b, "b", time.Minute, time.Hour)
+	standby.WantChannels("telegram")
+	standby.renew(ctx)
+	if standby.IsLeader() || standby.OwnsChannel("telegram") {
+		t.Error("standby should not lead or own channels while a is alive")
+	}
+	if owner, _ := standby.ChannelOwner(ctx, "telegram"); owner != "a" {
+		t.Errorf("ChannelOwner = %q, want a", owner)
+	}
+
+	// a shuts down cleanly and hands everything back.
+	a.release()
+	standby.renew(ctx)
+	if !standby.IsLeader() || !standby.OwnsChannel("telegram") {
+		t.Error("standby should take over after a leaves")
+	}
+	if got := standby.OwnedChannels(); len(got) != 1 || got[0] != "telegram" {
+		t.Errorf("OwnedChannels = %v", got)
+	}
+}
+
+func TestCoordinator_SessionAffinity(t *testing.T) {
+	b := openTestBackend(t)
+	ctx := context.Background()
+
+	a := NewCoordinator(b, "a", time.Minute, time.Hour)
+	peer := NewCoordinator(b, "b", time.Minute, time.Hour)
+	a.renew(ctx)
+	peer.renew(ctx)
+
+	if owner, err := a.ClaimSession(ctx, "telegram:1"); err != nil || owner != "a" {
+		t.Fatalf("ClaimSession = %q, %v; want a", owner, err)
+	}
+	if owner, _ := peer.ClaimSession(ctx, "telegram:1"); owner != "a" {
+		t.Errorf("peer should see session pinned to a, got %q", owner)
+	}
+
+	// a disappears without releasing its session lease.
+	b.Release(ctx, instanceKeyPrefix+"a", "a")
+	if owner, _ := peer.ClaimSession(ctx, "telegram:1"); owner != "b" {
+		t.Errorf("peer should take over the session of a dead instance, got %q", owner)
+	}
+}
+
+func TestCoordinator_Forward(t *testing.T) {
+	b := openTestBackend(t)
+	ctx := context.Background()
+
+	a := NewCoordinator(b, "a", time.Minute, time.Hour)
+	peer := NewCoordinator(b, "b", time.Minute, time.Hour)
+	var got []Envelope
+	peer.OnEnvelope = func(env Envelope) { got = append(got, env) }
+
+	if err := a.Forward(ctx, "b", KindOutbound, map[string]string{"chat": "1"}); err != nil {
+		t.Fatalf("Forward error: %v", err)
+	}
+	peer.drain(ctx)
+	if len(got) != 1 || got[0].From != "a" || got[0].Kind != KindOutbound {
+		t.Fatalf("got = %+v", got)
+	}
+	if string(got[0].Payload) != `{"chat":"1"}` {
+		t.Errorf("payload = %s", got[0].Payload)
+	}
+}
+
+func TestNewCoordinator_Defaults(t *testing.T) {
+	c := NewCoordinator(nil, "", 0, 0)
+	if c.ID() == "" {
+		t.Error("instance id should default")
+	}
+	if c.ttl != DefaultLeaseTTL || c.sessionTTL != DefaultSessionTTL {
+		t.Errorf("ttl = %s, sessionTTL = %s", c.ttl, c.sessionTTL)
+	}
+}
@@ -0,0 +1,124 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces cluster keys so the Redis database can be
+// shared with other applications.
+const redisKeyPrefix = "myclaw:cluster:"
+
+// acquireScript takes key for ARGV[1] when it is free or already theirs, and
+// sets it to expire after ARGV[2] milliseconds.
+var acquireScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner == false or owner == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes key only while ARGV[1] holds it.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisBackend keeps cluster state in Redis, so instances on different
+// machines can share it over the network. Leases are keys that Redis
+// expires itself; each instance's mailbox is a list.
+type RedisBackend struct {
+	client *redis.Client
+}
+
+// OpenRedis connects to the Redis server at url, such as
+// redis://:password@host:6379/0 or rediss:// for TLS.
+func OpenRedis(url string) (*RedisBackend, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+	return &RedisBackend{client: client}, nil
+}
+
+func (b *RedisBackend) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	ms := max(ttl.Milliseconds(), 1)
+	n, err := acquireScript.Run(ctx, b.client, []string{redisKeyPrefix + "lease:" + key}, owner, ms).Int()
+	if err != nil {
+		return false, fmt.Errorf("acquire %s: %w", key, err)
+	}
+	return n == 1, nil
+}
+
+func (b *RedisBackend) Release(ctx context.Context, key, owner string) error {
+	if err := releaseScript.Run(ctx, b.client, []string{redisKeyPrefix + "lease:" + key}, owner).Err(); err != nil {
+		return fmt.Errorf("release %s: %w", key, err)
+	}
+	return nil
+}
+
+func (b *RedisBackend) Holder(ctx context.Context, key string) (string, error) {
+	owner, err := b.client.Get(ctx, redisKeyPrefix+"lease:"+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("holder of %s: %w", key, err)
+	}
+	return owner, nil
+}
+
+func (b *RedisBackend) Send(ctx context.Context, to string, env Envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("send to %s: %w", to, err)
+	}
+	if err := b.client.RPush(ctx, redisKeyPrefix+"mailbox:"+to, data).Err(); err != nil {
+		return fmt.Errorf("send to %s: %w", to, err)
+	}
+	return nil
+}
+
+func (b *RedisBackend) Receive(ctx context.Context, to string) ([]Envelope, error) {
+	key := redisKeyPrefix + "mailbox:" + to
+	var items *redis.StringSliceCmd
+	// Reading and clearing in one transaction keeps envelopes sent in
+	// between from being lost.
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		items = pipe.LRange(ctx, key, 0, -1)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("receive: %w", err)
+	}
+	var envs []Envelope
+	for _, item := range items.Val() {
+		var env Envelope
+		if err := json.Unmarshal([]byte(item), &env); err != nil {
+			return nil, fmt.Errorf("receive: %w", err)
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+func (b *RedisBackend) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,135 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func openTestRedis(t *testing.T) (*RedisBackend, *miniredis.Miniredis) {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	b, err := OpenRedis("redis://" + srv.Addr())
+	if err != nil {
+		t.Fatalf("OpenRedis error: %v", err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b, srv
+}
+
+func TestRedisBackend_Lease(t *testing.T) {
+	b, srv := openTestRedis(t)
+	ctx := context.Background()
+
+	if ok, err := b.Acquire(ctx, "leader", "a", 10*time.Second); err != nil || !ok {
+		t.Fatalf("first acquire = %v, %v; want true", ok, err)
+	}
+	if ok, _ := b.Acquire(ctx, "leader", "b", 10*time.Second); ok {
+		t.Error("b should not acquire a held lease")
+	}
+	if ok, _ := b.Acquire(ctx, "leader", "a", 10*time.Second); !ok {
+		t.Error("a should be able to renew its lease")
+	}
+	if holder, _ := b.Holder(ctx, "leader"); holder != "a" {
+		t.Errorf("holder = %q, want a", holder)
+	}
+	b.Release(ctx, "leader", "b")
+	if holder, _ := b.Holder(ctx, "leader"); holder != "a" {
+		t.Error("release by a non-owner should be ignored")
+	}
+
+	srv.FastForward(11 * time.Second)
+	if holder, _ := b.Holder(ctx, "leader"); holder != "" {
+		t.Errorf("expired lease holder = %q, want empty", holder)
+	}
+	if ok, _ := b.Acquire(ctx, "leader", "b", 10*time.Second); !ok {
+		t.Error("b should take over an expired lease")
+	}
+	if !srv.Exists(redisKeyPrefix + "lease:leader") {
+		t.Error("leases should live under the cluster prefix")
+	}
+}
+
+func TestRedisBackend_Mailbox(t *testing.T) {
+	b, _ := openTestRedis(t)
+	ctx := context.Background()
+
+	b.Send(ctx, "a", Envelope{Kind: KindInbound, From: "b", Payload: json.RawMessage(`{"n":1}`)})
+	b.Send(ctx, "a", Envelope{Kind: KindOutbound, From: "b", Payload: json.RawMessage(`{"n":2}`)})
+	b.Send(ctx, "c", Envelope{Kind: KindInbound, From: "b", Payload: json.RawMessage(`{}`)})
+
+	envs, err := b.Receive(ctx, "a")
+	if err != nil {
+		t.Fatalf("Receive error: %v", err)
+	}
+	if len(envs) != 2 || envs[0].Kind != KindInbound || envs[1].Kind != KindOutbound || string(envs[1].Payload) != `{"n":2}` {
+		t.Errorf("envs = %+v", envs)
+	}
+	if envs, _ = b.Receive(ctx, "a"); len(envs) != 0 {
+		t.Errorf("mailbox should be drained, got %d", len(envs))
+	}
+	if envs, _ = b.Receive(ctx, "c"); len(envs) != 1 {
+		t.Errorf("other recipients keep their mail, got %d", len(envs))
+	}
+}
+
+func TestRedisBackend_Coordinator(t *testing.T) {
+	b, _ := openTestRedis(t)
+	ctx := context.Background()
+	home := NewCoordinator(b, "home", time.Minute, time.Minute)
+	vps := NewCoordinator(b, "vps", time.Minute, time.Minute)
+	home.WantChannels("telegram")
+	vps.WantChannels("telegram")
+
+	home.renew(ctx)
+	vps.renew(ctx)
+	if !home.IsLeader() || vps.IsLeader() || !home.OwnsChannel("telegram") || vps.OwnsChannel("telegram") {
+		t.Error("the first instance to renew should lead and own the channel")
+	}
+	if owner, _ := vps.ClaimSession(ctx, "telegram:1"); owner != "vps" {
+		t.Errorf("session owner = %q, want vps", owner)
+	}
+	if owner, _ := home.ClaimSession(ctx, "telegram:1"); owner != "vps" {
+		t.Errorf("session owner = %q, want vps while it is alive", owner)
+	}
+
+	home.release()
+	vps.renew(ctx)
+	if !vps.IsLeader() || !vps.OwnsChannel("telegram") {
+		t.Error("vps should take over once home lets go")
+	}
+}
+
+// releaseHook runs after each Release, to let another instance in.
+type releaseHook struct {
+	Backend
+	after func()
+}
+
+func (b releaseHook) Release(ctx context.Context, key, owner string) error {
+	err := b.Backend.Release(ctx, key, owner)
+	b.after()
+	return err
+}
+
+func TestCoordinator_SessionTakeoverRace(t *testing.T) {
+	b, _ := openTestRedis(t)
+	ctx := context.Background()
+	dead := NewCoordinator(b, "dead", time.Minute, time.Hour)
+	fast := NewCoordinator(b, "fast", time.Minute, time.Hour)
+	slow := NewCoordinator(releaseHook{b, func() { fast.ClaimSession(ctx, "telegram:1") }}, "slow", time.Minute, time.Hour)
+	dead.renew(ctx)
+	fast.renew(ctx)
+	slow.renew(ctx)
+	dead.ClaimSession(ctx, "telegram:1")
+	b.Release(ctx, instanceKeyPrefix+"dead", "dead")
+
+	// Both see the owner is gone; fast takes the session while slow is
+	// still between releasing and acquiring it.
+	if owner, err := slow.ClaimSession(ctx, "telegram:1"); err != nil || owner != "fast" {
+		t.Errorf("ClaimSession = %q, %v; want fast", owner, err)
+	}
+}
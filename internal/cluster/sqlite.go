@@ -0,0 +1,153 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS leases (
+	key        TEXT PRIMARY KEY,
+	owner      TEXT NOT NULL,
+	expires_ms INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS mailbox (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	recipient  TEXT NOT NULL,
+	sender     TEXT NOT NULL,
+	kind       TEXT NOT NULL,
+	payload    BLOB NOT NULL,
+	created_ms INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS mailbox_recipient ON mailbox(recipient, id);
+`
+
+// SQLiteBackend keeps cluster state in a single SQLite file. Its locks only
+// hold between processes on one host, so every instance must run on the
+// machine that has the file; use RedisBackend across machines.
+type SQLiteBackend struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// OpenSQLite opens (and creates if needed) the state database at path.
+func OpenSQLite(path string) (*SQLiteBackend, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create cluster state dir: %w", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", filepath.ToSlash(path))
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open cluster state: %w", err)
+	}
+	// One connection keeps lease updates serialized within this process.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("init cluster state: %w", err)
+	}
+	return &SQLiteBackend{db: db, now: time.Now}, nil
+}
+
+func (b *SQLiteBackend) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	now := b.now()
+	res, err := b.db.ExecContext(ctx, `
+INSERT INTO leases (key, owner, expires_ms) VALUES (?, ?, ?)
+ON CONFLICT(key) DO UPDATE SET owner = excluded.owner, expires_ms = excluded.expires_ms
+WHERE leases.owner = excluded.owner OR leases.expires_ms <= ?`,
+		key, owner, now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("acquire %s: %w", key, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("acquire %s: %w", key, err)
+	}
+	return n == 1, nil
+}
+
+func (b *SQLiteBackend) Release(ctx context.Context, key, owner string) error {
+	if _, err := b.db.ExecContext(ctx, `DELETE FROM leases WHERE key = ? AND owner = ?`, key, owner); err != nil {
+		return fmt.Errorf("release %s: %w", key, err)
+	}
+	return nil
+}
+
+func (b *SQLiteBackend) Holder(ctx context.Context, key string) (string, error) {
+	var owner string
+	err := b.db.QueryRowContext(ctx, `SELECT owner FROM leases WHERE key = ? AND expires_ms > ?`,
+		key, b.now().UnixMilli()).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("holder of %s: %w", key, err)
+	}
+	return owner, nil
+}
+
+func (b *SQLiteBackend) Send(ctx context.Context, to string, env Envelope) error {
+	_, err := b.db.ExecContext(ctx, `INSERT INTO mailbox (recipient, sender, kind, payload, created_ms) VALUES (?, ?, ?, ?, ?)`,
+		to, env.From, env.Kind, []byte(env.Payload), b.now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("send to %s: %w", to, err)
+	}
+	return nil
+}
+
+func (b *SQLiteBackend) Receive(ctx context.Context, to string) ([]Envelope, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("receive: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, sender, kind, payload FROM mailbox WHERE recipient = ? ORDER BY id`, to)
+	if err != nil {
+		return nil, fmt.Errorf("receive: %w", err)
+	}
+	var (
+		envs   []Envelope
+		lastID int64
+	)
+	for rows.Next() {
+		var (
+			env     Envelope
+			payload []byte
+		)
+		if err := rows.Scan(&lastID, &env.From, &env.Kind, &payload); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("receive: %w", err)
+		}
+		env.Payload = json.RawMessage(payload)
+		envs = append(envs, env)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("receive: %w", err)
+	}
+	if len(envs) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mailbox WHERE recipient = ? AND id <= ?`, to, lastID); err != nil {
+		return nil, fmt.Errorf("receive: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("receive: %w", err)
+	}
+	return envs, nil
+}
+
+func (b *SQLiteBackend) Close() error {
+	return b.db.Close()
+}
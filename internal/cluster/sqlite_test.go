@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestBackend(t *testing.T) *SQLiteBackend {
+	t.Helper()
+	b, err := OpenSQLite(filepath.Join(t.TempDir(), "cluster", "state.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite error: %v", err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestSQLiteBackend_Lease(t *testing.T) {
+	b := openTestBackend(t)
+	ctx := context.Background()
+
+	ok, err := b.Acquire(ctx, "leader", "a", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("first acquire = %v, %v; want true", ok, err)
+	}
+	if ok, _ := b.Acquire(ctx, "leader", "b", time.Minute); ok {
+		t.Error("b should not acquire a held lease")
+	}
+	if ok, _ := b.Acquire(ctx, "leader", "a", time.Minute); !ok {
+		t.Error("a should be able to renew its lease")
+	}
+	if holder, _ := b.Holder(ctx, "leader"); holder != "a" {
+		t.Errorf("holder = %q, want a", holder)
+	}
+
+	if err := b.Release(ctx, "leader", "b"); err != nil {
+		t.Fatalf("Release error: %v", err)
+	}
+	if holder, _ := b.Holder(ctx, "leader"); holder != "a" {
+		t.Error("release by a non-owner should be ignored")
+	}
+	b.Release(ctx, "leader", "a")
+	if ok, _ := b.Acquire(ctx, "leader", "b", time.Minute); !ok {
+		t.Error("b should acquire a released lease")
+	}
+}
+
+func TestSQLiteBackend_LeaseExpiry(t *testing.T) {
+	b := openTestBackend(t)
+	ctx := context.Background()
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	b.Acquire(ctx, "channel:telegram", "a", 10*time.Second)
+	now = now.Add(11 * time.Second)
+
+	if holder, _ := b.Holder(ctx, "channel:telegram"); holder != "" {
+		t.Errorf("expired lease holder = %q, want empty", holder)
+	}
+	if ok, _ := b.Acquire(ctx, "channel:telegram", "b", 10*time.Second); !ok {
+		t.Error("b should take over an expired lease")
+	}
+}
+
+func TestSQLiteBackend_Mailbox(t *testing.T) {
+	b := openTestBackend(t)
+	ctx := context.Background()
+
+	b.Send(ctx, "a", Envelope{Kind: KindInbound, From: "b", Payload: json.RawMessage(`{"n":1}`)})
+	b.Send(ctx, "a", Envelope{Kind: KindOutbound, From: "b", Payload: json.RawMessage(`{"n":2}`)})
+	b.Send(ctx, "c", Envelope{Kind: KindInbound, From: "b", Payload: json.RawMessage(`{}`)})
+
+	envs, err := b.Receive(ctx, "a")
+	if err != nil {
+		t.Fatalf("Receive error: %v", err)
+	}
+	if len(envs) != 2 {
+		t.Fatalf("len(envs) = %d, want 2", len(envs))
+	}
+	if envs[0].Kind != KindInbound || envs[1].Kind != KindOutbound || envs[0].From != "b" {
+		t.Errorf("envs = %+v", envs)
+	}
+	if string(envs[1].Payload) != `{"n":2}` {
+		t.Errorf("payload = %s", envs[1].Payload)
+	}
+
+	envs, _ = b.Receive(ctx, "a")
+	if len(envs) != 0 {
+		t.Errorf("mailbox should be drained, got %d", len(envs))
+	}
+	envs, _ = b.Receive(ctx, "c")
+	if len(envs) != 1 {
+		t.Errorf("other recipients keep their mail, got %d", len(envs))
+	}
+}
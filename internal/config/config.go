@@ -1,10 +1,18 @@
 package config
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 )
 
 const (
@@ -16,12 +24,29 @@ const (
 	DefaultHost              = "0.0.0.0"
 	DefaultPort              = 18790
 	DefaultBufSize           = 100
+	DefaultSendAttempts      = 3
+	DefaultServerHost        = "127.0.0.1"
+	DefaultServerPort        = 18791
+	DefaultMemoryTopK        = 5
+	DefaultEmbeddingModel    = "text-embedding-3-small"
+	DefaultRollupSchedule    = "0 30 3 * * *"
+	DefaultRollupKeepDays    = 7
+	DefaultSyncSchedule      = "0 */15 * * * *"
+	DefaultSyncBranch        = "main"
+	DefaultBackupSchedule    = "0 0 4 * * *"
+	DefaultBackupKeep        = 7
+	DefaultFeedsSchedule     = "0 0 8 * * *"
+	DefaultFeedsMaxItems     = 10
+	DefaultVoiceReplyChars   = 1000
+	DefaultKBTopK            = 4
+	DefaultKBMinScore        = 0.2
 )
 
 type Config struct {
 	Agent         AgentConfig         `json:"agent"`
 	Channels      ChannelsConfig      `json:"channels"`
 	Provider      ProviderConfig      `json:"provider"`
+	Models        ModelsConfig        `json:"models"`
 	Tools         ToolsConfig         `json:"tools"`
 	Skills        SkillsConfig        `json:"skills"`
 	Hooks         HooksConfig         `json:"hooks"`
@@ -29,6 +54,35 @@ type Config struct {
 	AutoCompact   AutoCompactConfig   `json:"autoCompact"`
 	TokenTracking TokenTrackingConfig `json:"tokenTracking"`
 	Gateway       GatewayConfig       `json:"gateway"`
+	DeadLetter    DeadLetterConfig    `json:"deadLetter"`
+	Cluster       ClusterConfig       `json:"cluster"`
+	Server        ServerConfig        `json:"server"`
+	Memory        MemoryConfig        `json:"memory"`
+	KB            KBConfig            `json:"kb"`
+	Heartbeat     HeartbeatConfig     `json:"heartbeat"`
+	ResponseCache ResponseCacheConfig `json:"responseCache"`
+	Sessions      SessionsConfig      `json:"sessions"`
+	Media         MediaConfig         `json:"media"`
+	Queue         QueueConfig         `json:"queue"`
+	RateLimit     RateLimitConfig     `json:"rateLimit"`
+	Tracing       TracingConfig       `json:"tracing"`
+	Permissions   PermissionsConfig   `json:"permissions"`
+	Redaction     RedactionConfig     `json:"redaction"`
+	Audit         AuditConfig         `json:"audit"`
+	Calendar      CalendarConfig      `json:"calendar"`
+	Mail          MailConfig          `json:"mail"`
+	Feeds         FeedsConfig         `json:"feeds"`
+	GitHub        GitHubConfig        `json:"github"`
+	HomeAssistant HomeAssistantConfig `json:"homeAssistant"`
+	Backup        BackupConfig        `json:"backup"`
+	Webhooks      []WebhookConfig     `json:"webhooks,omitempty"`
+	// Notify names notification sinks, used as "notify:<name>"
+	// delivery targets.
+	Notify map[string]NotifyConfig `json:"notify,omitempty"`
+
+	// Project is the .myclaw directory of the git repository myclaw runs
+	// in, found by LoadConfig; see FindProject. It is never saved.
+	Project string `json:"-"`
 }
 
 type AgentConfig struct {
@@ -39,60 +93,903 @@ type AgentConfig struct {
 	MaxToolIterations int     `json:"maxToolIterations"`
 }
 
+// ModelsConfig holds short names for models.
+type ModelsConfig struct {
+	// Aliases map a short name, such as "fast", to a model name. They are
+	// accepted wherever a model is named: agent.model, the model of a
+	// fallback and the REPL's /model command.
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// Resolve returns the model an alias stands for, or name itself when it is
+// not an alias.
+func (m ModelsConfig) Resolve(name string) string {
+	if target, ok := m.Aliases[strings.TrimSpace(name)]; ok && target != "" {
+		return target
+	}
+	return name
+}
+
 type ProviderConfig struct {
-	Type    string `json:"type,omitempty"` // "anthropic" (default) or "openai"
+	Type    string `json:"type,omitempty"` // "anthropic" (default), "openai" or "gemini"
 	APIKey  string `json:"apiKey"`
 	BaseURL string `json:"baseUrl,omitempty"`
+	// Fallbacks are tried in order when the provider above is rate
+	// limited, failing or timing out.
+	Fallbacks []FallbackProvider `json:"fallbacks,omitempty"`
+	// Timeout bounds each attempt, in seconds, before the next provider is
+	// tried. It only applies when fallbacks are configured; 0 means no limit.
+	Timeout int `json:"timeout,omitempty"`
+	// PromptCache marks the tools, the system prompt and the latest turns
+	// as cacheable with Anthropic, so that later requests read them from
+	// the cache at a tenth of the input price. Other providers ignore it.
+	PromptCache bool `json:"promptCache,omitempty"`
+}
+
+// FallbackProvider is a provider to fail over to. An empty APIKey is taken
+// from the vendor's environment variable and an empty Model from agent.model.
+type FallbackProvider struct {
+	Type    string `json:"type,omitempty"`
+	APIKey  string `json:"apiKey,omitempty"`
+	BaseURL string `json:"baseUrl,omitempty"`
+	Model   string `json:"model,omitempty"`
 }
 
 type ChannelsConfig struct {
-	Telegram TelegramConfig `json:"telegram"`
-	Feishu   FeishuConfig   `json:"feishu"`
-	WeCom    WeComConfig    `json:"wecom"`
-	WhatsApp WhatsAppConfig `json:"whatsapp"`
-	WebUI    WebUIConfig    `json:"webui"`
+	Telegram   TelegramConfig   `json:"telegram"`
+	Feishu     FeishuConfig     `json:"feishu"`
+	WeCom      WeComConfig      `json:"wecom"`
+	WhatsApp   WhatsAppConfig   `json:"whatsapp"`
+	Slack      SlackConfig      `json:"slack"`
+	Email      EmailConfig      `json:"email"`
+	IMessage   IMessageConfig   `json:"imessage,omitzero"`
+	XMPP       XMPPConfig       `json:"xmpp,omitzero"`
+	Mattermost MattermostConfig `json:"mattermost,omitzero"`
+	RocketChat RocketChatConfig `json:"rocketchat,omitzero"`
+	WebUI      WebUIConfig      `json:"webui"`
+}
+
+// SkillScope limits the skills a channel can use. An empty Allow means all
+// loaded skills; Deny removes skills from that set.
+type SkillScope struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// SkillScopes returns the skill scope of each channel that sets one.
+func (c ChannelsConfig) SkillScopes() map[string]SkillScope {
+	scopes := make(map[string]SkillScope)
+	for name, scope := range map[string]SkillScope{
+		"telegram":   c.Telegram.Skills,
+		"feishu":     c.Feishu.Skills,
+		"wecom":      c.WeCom.Skills,
+		"whatsapp":   c.WhatsApp.Skills,
+		"slack":      c.Slack.Skills,
+		"email":      c.Email.Skills,
+		"imessage":   c.IMessage.Skills,
+		"xmpp":       c.XMPP.Skills,
+		"mattermost": c.Mattermost.Skills,
+		"rocketchat": c.RocketChat.Skills,
+		"webui":      c.WebUI.Skills,
+	} {
+		if len(scope.Allow) > 0 || len(scope.Deny) > 0 {
+			scopes[name] = scope
+		}
+	}
+	return scopes
+}
+
+// Admins returns the admin sender IDs of each channel that names any.
+func (c ChannelsConfig) Admins() map[string][]string {
+	admins := make(map[string][]string)
+	for name, ids := range map[string][]string{
+		"telegram":   c.Telegram.Admins,
+		"feishu":     c.Feishu.Admins,
+		"wecom":      c.WeCom.Admins,
+		"whatsapp":   c.WhatsApp.Admins,
+		"slack":      c.Slack.Admins,
+		"email":      c.Email.Admins,
+		"imessage":   c.IMessage.Admins,
+		"xmpp":       c.XMPP.Admins,
+		"mattermost": c.Mattermost.Admins,
+		"rocketchat": c.RocketChat.Admins,
+		"webui":      c.WebUI.Admins,
+	} {
+		if len(ids) > 0 {
+			admins[name] = ids
+		}
+	}
+	return admins
 }
 
 type TelegramConfig struct {
-	Enabled   bool     `json:"enabled"`
-	Token     string   `json:"token"`
-	AllowFrom []string `json:"allowFrom"`
-	Proxy     string   `json:"proxy,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	Token       string     `json:"token"`
+	AllowFrom   []string   `json:"allowFrom"`
+	DenyFrom    []string   `json:"denyFrom,omitempty"`    // user IDs, usernames or chat IDs that are always rejected
+	DeniedReply string     `json:"deniedReply,omitempty"` // sent to rejected senders; empty sends nothing
+	Admins      []string   `json:"admins,omitempty"`      // sender IDs allowed to send control commands
+	Skills      SkillScope `json:"skills,omitempty"`
+	Proxy       string     `json:"proxy,omitempty"`
+	// Groups is which group messages the bot answers: "mention" (default)
+	// those that mention it, reply to it or are commands, "all" every one.
+	Groups string `json:"groups,omitempty"`
+
+	VoiceReplies VoiceReplyConfig `json:"voiceReplies,omitempty"`
+}
+
+const (
+	TelegramGroupsMention = "mention"
+	TelegramGroupsAll     = "all"
+)
+
+// VoiceReplyConfig has a channel send the agent's replies as voice notes,
+// spoken by the openai media.tts service. Mode is "off" (the default),
+// "voice" to send the voice note instead of the text, or "both" to send it
+// after the text. Voice overrides media.tts.voice and Speed (0.25 to 4)
+// the speaking rate. Replies longer than MaxChars (default 1000) when read
+// out go as text only.
+type VoiceReplyConfig struct {
+	Mode     string  `json:"mode,omitempty"`
+	Voice    string  `json:"voice,omitempty"`
+	Speed    float64 `json:"speed,omitempty"`
+	MaxChars int     `json:"maxChars,omitempty"`
+}
+
+// VoiceReplies returns the voice reply settings of each channel that sends
+// them.
+func (c ChannelsConfig) VoiceReplies() map[string]VoiceReplyConfig {
+	replies := make(map[string]VoiceReplyConfig)
+	for name, vr := range map[string]VoiceReplyConfig{
+		"telegram": c.Telegram.VoiceReplies,
+		"whatsapp": c.WhatsApp.VoiceReplies,
+	} {
+		if vr.Mode != "" && vr.Mode != "off" {
+			replies[name] = vr
+		}
+	}
+	return replies
 }
 
 type FeishuConfig struct {
-	Enabled           bool     `json:"enabled"`
-	AppID             string   `json:"appId"`
-	AppSecret         string   `json:"appSecret"`
-	VerificationToken string   `json:"verificationToken"`
-	EncryptKey        string   `json:"encryptKey,omitempty"`
-	Port              int      `json:"port,omitempty"`
-	AllowFrom         []string `json:"allowFrom"`
+	Enabled           bool       `json:"enabled"`
+	AppID             string     `json:"appId"`
+	AppSecret         string     `json:"appSecret"`
+	VerificationToken string     `json:"verificationToken"`
+	EncryptKey        string     `json:"encryptKey,omitempty"`
+	Port              int        `json:"port,omitempty"`
+	AllowFrom         []string   `json:"allowFrom"`
+	DenyFrom          []string   `json:"denyFrom,omitempty"`
+	DeniedReply       string     `json:"deniedReply,omitempty"`
+	Admins            []string   `json:"admins,omitempty"`
+	Skills            SkillScope `json:"skills,omitempty"`
 }
 
 type WeComConfig struct {
-	Enabled        bool     `json:"enabled"`
-	Token          string   `json:"token"`
-	EncodingAESKey string   `json:"encodingAESKey"`
-	ReceiveID      string   `json:"receiveId,omitempty"`
-	Port           int      `json:"port,omitempty"`
-	AllowFrom      []string `json:"allowFrom"`
+	Enabled        bool       `json:"enabled"`
+	Token          string     `json:"token"`
+	EncodingAESKey string     `json:"encodingAESKey"`
+	ReceiveID      string     `json:"receiveId,omitempty"`
+	Port           int        `json:"port,omitempty"`
+	AllowFrom      []string   `json:"allowFrom"`
+	DenyFrom       []string   `json:"denyFrom,omitempty"`
+	DeniedReply    string     `json:"deniedReply,omitempty"`
+	Admins         []string   `json:"admins,omitempty"`
+	Skills         SkillScope `json:"skills,omitempty"`
+	// CorpID and Apps answer as self-built apps besides the bot. Their
+	// messages arrive at /wecom/app and are told apart by agent ID.
+	CorpID string           `json:"corpId,omitempty"`
+	Apps   []WeComAppConfig `json:"apps,omitempty"`
+}
+
+// WeComAppConfig is a self-built WeCom app. Replies go through its message
+// API, so they can be markdown, images and files.
+type WeComAppConfig struct {
+	AgentID int    `json:"agentId"`
+	Secret  string `json:"secret"`
+	// Token and EncodingAESKey check the app's callbacks. Empty, the
+	// channel's are used.
+	Token          string `json:"token,omitempty"`
+	EncodingAESKey string `json:"encodingAESKey,omitempty"`
+	// Persona is added to the system prompt for the app's messages, so each
+	// app can be an assistant of its own.
+	Persona string `json:"persona,omitempty"`
+}
+
+// SlackConfig uses Socket Mode when AppToken is set, otherwise the Events API
+// on Port (signed with SigningSecret).
+type SlackConfig struct {
+	Enabled       bool       `json:"enabled"`
+	BotToken      string     `json:"botToken"`
+	AppToken      string     `json:"appToken,omitempty"`
+	SigningSecret string     `json:"signingSecret,omitempty"`
+	Port          int        `json:"port,omitempty"`
+	AllowFrom     []string   `json:"allowFrom"`
+	DenyFrom      []string   `json:"denyFrom,omitempty"`
+	DeniedReply   string     `json:"deniedReply,omitempty"`
+	Admins        []string   `json:"admins,omitempty"`
+	Skills        SkillScope `json:"skills,omitempty"`
+}
+
+type EmailConfig struct {
+	Enabled             bool       `json:"enabled"`
+	IMAPHost            string     `json:"imapHost"`
+	IMAPPort            int        `json:"imapPort,omitempty"` // default 993 (TLS)
+	SMTPHost            string     `json:"smtpHost"`
+	SMTPPort            int        `json:"smtpPort,omitempty"` // default 587 (STARTTLS); 465 uses TLS
+	Username            string     `json:"username"`
+	Password            string     `json:"password,omitempty"`
+	Address             string     `json:"address,omitempty"` // From address; defaults to username
+	Mailbox             string     `json:"mailbox,omitempty"` // default INBOX
+	PollIntervalSeconds int        `json:"pollIntervalSeconds,omitempty"`
+	AllowFrom           []string   `json:"allowFrom"`
+	DenyFrom            []string   `json:"denyFrom,omitempty"`
+	Admins              []string   `json:"admins,omitempty"`
+	Skills              SkillScope `json:"skills,omitempty"`
+}
+
+// IMessageConfig answers iMessages on macOS. New messages are read from
+// the Messages database, which needs Full Disk Access, and replies are sent
+// through the Messages app, which needs Automation permission.
+type IMessageConfig struct {
+	Enabled bool `json:"enabled"`
+	// AllowFrom lists the phone numbers, in +15551234567 form, and email
+	// addresses answered. It must not be empty: anyone can send an iMessage.
+	AllowFrom []string `json:"allowFrom"`
+	// DBPath is the Messages database; empty, ~/Library/Messages/chat.db.
+	DBPath              string `json:"dbPath,omitempty"`
+	PollIntervalSeconds int    `json:"pollIntervalSeconds,omitempty"` // default 5
+	// Shortcut, when set, names a Shortcuts shortcut that sends replies
+	// instead of AppleScript. Its input is a JSON object with "to", "text"
+	// and "files".
+	Shortcut string     `json:"shortcut,omitempty"`
+	Admins   []string   `json:"admins,omitempty"`
+	Skills   SkillScope `json:"skills,omitempty"`
+}
+
+// XMPPConfig logs in to an XMPP server, such as a self-hosted Prosody or
+// ejabberd, as a user of its own and answers chats and, optionally, group
+// chats.
+type XMPPConfig struct {
+	Enabled  bool   `json:"enabled"`
+	JID      string `json:"jid"` // bot@example.org
+	Password string `json:"password,omitempty"`
+	// Server is the host:port to connect to; empty, it is found from the
+	// JID's domain in DNS.
+	Server string `json:"server,omitempty"`
+	// Rooms are group chats to join, by JID (room@conference.example.org).
+	// The agent answers in them when its nickname is mentioned.
+	Rooms       []string   `json:"rooms,omitempty"`
+	Nick        string     `json:"nick,omitempty"` // nickname in rooms; default myclaw
+	AllowFrom   []string   `json:"allowFrom"`
+	DenyFrom    []string   `json:"denyFrom,omitempty"`
+	DeniedReply string     `json:"deniedReply,omitempty"`
+	Admins      []string   `json:"admins,omitempty"`
+	Skills      SkillScope `json:"skills,omitempty"`
+}
+
+// MattermostConfig answers on a Mattermost server as a bot account, over
+// its WebSocket event stream.
+type MattermostConfig struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url"`   // https://chat.example.org
+	Token   string `json:"token"` // the bot's access token
+	// AllowFrom takes user IDs, usernames and channel IDs.
+	AllowFrom   []string   `json:"allowFrom"`
+	DenyFrom    []string   `json:"denyFrom,omitempty"`
+	DeniedReply string     `json:"deniedReply,omitempty"`
+	Admins      []string   `json:"admins,omitempty"`
+	Skills      SkillScope `json:"skills,omitempty"`
+}
+
+// RocketChatConfig answers on a Rocket.Chat server as a bot user, over its
+// realtime API.
+type RocketChatConfig struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url"` // https://chat.example.org
+	// UserID and Token are the bot user's ID and a personal access token.
+	UserID      string     `json:"userId"`
+	Token       string     `json:"token"`
+	AllowFrom   []string   `json:"allowFrom"` // user IDs, usernames and room IDs
+	DenyFrom    []string   `json:"denyFrom,omitempty"`
+	DeniedReply string     `json:"deniedReply,omitempty"`
+	Admins      []string   `json:"admins,omitempty"`
+	Skills      SkillScope `json:"skills,omitempty"`
 }
 
 type ToolsConfig struct {
-	BraveAPIKey         string `json:"braveApiKey,omitempty"`
-	ExecTimeout         int    `json:"execTimeout"`
-	RestrictToWorkspace bool   `json:"restrictToWorkspace"`
+	BraveAPIKey         string         `json:"braveApiKey,omitempty"`
+	ExecTimeout         int            `json:"execTimeout"`
+	RestrictToWorkspace bool           `json:"restrictToWorkspace"`
+	Search              SearchConfig   `json:"search"`
+	Fetch               FetchConfig    `json:"fetch"`
+	Delegate            DelegateConfig `json:"delegate"`
+}
+
+// DelegateConfig turns on the delegate tool, with which the agent hands a
+// subtask to a sub-agent: a run of its own on Model (default agent.model),
+// with a system prompt of its own and only the Tools listed (default
+// file_read, grep, glob, web_search and web_fetch). Sub-agents may delegate
+// in turn down to MaxDepth levels (default 1: they may not). A sub-agent
+// makes at most MaxIterations model calls (default 10), and one delegated
+// task, with the sub-agents it spawns, is stopped after MaxTokens tokens
+// (default 200000).
+type DelegateConfig struct {
+	Enabled       bool     `json:"enabled,omitempty"`
+	Model         string   `json:"model,omitempty"`
+	SystemPrompt  string   `json:"systemPrompt,omitempty"`
+	Tools         []string `json:"tools,omitempty"`
+	MaxDepth      int      `json:"maxDepth,omitempty"`
+	MaxIterations int      `json:"maxIterations,omitempty"`
+	MaxTokens     int      `json:"maxTokens,omitempty"`
+}
+
+// FetchConfig controls myclaw's web_fetch tool, which reads a page as
+// markdown in place of the SDK's WebFetch. Deny wins over Allow; with Allow
+// set, only those domains (and their subdomains) are fetched. Addresses on
+// the local network are refused unless AllowPrivate is set, since a chat
+// message can carry any link. Pages are cut to MaxTokens (default 4000).
+type FetchConfig struct {
+	Enabled      bool     `json:"enabled"`
+	Allow        []string `json:"allow,omitempty"`
+	Deny         []string `json:"deny,omitempty"`
+	AllowPrivate bool     `json:"allowPrivate,omitempty"`
+	MaxTokens    int      `json:"maxTokens,omitempty"`
+	Timeout      int      `json:"timeout,omitempty"` // seconds, default 20
+}
+
+// SearchConfig selects the backend of myclaw's web_search tool, which takes
+// the place of the SDK's WebSearch once Backend is set. Brave and SerpAPI
+// need APIKey (for Brave, tools.braveApiKey also works and picks Brave when
+// Backend is empty); SearXNG needs the BaseURL of an instance that allows
+// format=json; DuckDuckGo needs neither. BaseURL can also point the others
+// at a proxy. Results are cached for CacheTTL seconds (default 600, -1 for
+// none) and snippets cut to SnippetLength characters (default 300).
+type SearchConfig struct {
+	Backend       string `json:"backend,omitempty"` // "" | "brave" | "serpapi" | "searxng" | "duckduckgo"
+	APIKey        string `json:"apiKey,omitempty"`
+	BaseURL       string `json:"baseUrl,omitempty"`
+	MaxResults    int    `json:"maxResults,omitempty"` // default 5
+	SnippetLength int    `json:"snippetLength,omitempty"`
+	CacheTTL      int    `json:"cacheTTL,omitempty"`
+}
+
+// PermissionsConfig guards the tools the agent runs. A rule is a tool name
+// ("WebFetch"), a tool with a pattern ("Bash(rm:*)", "Write(/etc/**)") or a
+// bare path pattern that applies to every tool. For Bash the pattern is
+// matched against "command:args". Deny rules win over ask rules, and ask
+// rules over allow rules; calls no rule matches get Default (allow). Asked
+// calls prompt on the terminal in the REPL and in the chat in the gateway,
+// where they are denied after AskTimeout seconds (default 300).
+type PermissionsConfig struct {
+	Allow      []string `json:"allow,omitempty"`
+	Ask        []string `json:"ask,omitempty"`
+	Deny       []string `json:"deny,omitempty"`
+	Default    string   `json:"default,omitempty"` // "allow" | "ask" | "deny"
+	AskTimeout int      `json:"askTimeout,omitempty"`
+}
+
+// RedactionConfig masks personal data before it is sent to the model
+// provider. The model sees placeholders such as [EMAIL_1] instead; they are
+// put back in its replies and tool calls before anything local sees them.
+// Emails and Phones are on by default once Enabled. Patterns add regular
+// expressions, masked as [<NAME>_<n>], and Secrets masks literal values as
+// [SECRET_<NAME>]; a secret value may itself be a keyring: reference.
+type RedactionConfig struct {
+	Enabled  bool               `json:"enabled"`
+	Emails   bool               `json:"emails"`
+	Phones   bool               `json:"phones"`
+	Patterns []RedactionPattern `json:"patterns,omitempty"`
+	Secrets  map[string]string  `json:"secrets,omitempty"`
+}
+
+// RedactionPattern is a regular expression to mask, named for its
+// placeholder.
+type RedactionPattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// AuditConfig controls the audit log, <workspace>/.claude/audit.jsonl, of
+// messages, tool calls, file changes and replies. It is on by default.
+type AuditConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+// CalendarConfig connects the calendar tools (list_events, create_event,
+// update_event) to a CalDAV calendar or a Google calendar. Provider is
+// "caldav" or "google"; empty leaves the tools out. Times the agent gives
+// without a zone are in Timezone (an IANA name, default the local zone).
+type CalendarConfig struct {
+	Provider string               `json:"provider,omitempty"`
+	Timezone string               `json:"timezone,omitempty"`
+	CalDAV   CalDAVConfig         `json:"caldav,omitempty"`
+	Google   GoogleCalendarConfig `json:"google,omitempty"`
+}
+
+// CalDAVConfig is a CalDAV calendar collection, such as
+// https://caldav.fastmail.com/dav/calendars/user/me@example.com/Default/.
+type CalDAVConfig struct {
+	URL      string `json:"url,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// GoogleCalendarConfig is the OAuth client (of the "Desktop app" type)
+// `myclaw auth google` signs in with, and the calendar to use (default
+// "primary").
+type GoogleCalendarConfig struct {
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	CalendarID   string `json:"calendarId,omitempty"`
+}
+
+// MailConfig gives the agent the user's own mailbox through the
+// email_search, email_read, email_draft and email_send tools. It is separate
+// from the email channel, which is a mailbox for talking to myclaw. Provider
+// is "imap" or "gmail"; empty leaves the tools out. Send decides what
+// email_send does: "ask" the user first (the default), "allow" without
+// asking, or "deny" so the agent can only draft.
+type MailConfig struct {
+	Provider string         `json:"provider,omitempty"`
+	Send     string         `json:"send,omitempty"`
+	IMAP     MailIMAPConfig `json:"imap,omitempty"`
+	Gmail    GmailConfig    `json:"gmail,omitempty"`
+}
+
+// MailIMAPConfig is a mailbox read over IMAP (TLS) and sent from over SMTP.
+// Drafts (default "Drafts") is where email_draft saves; Sent, when set, gets
+// a copy of each message sent. On Gmail these are "[Gmail]/Drafts" and
+// nothing, as Gmail files sent mail itself.
+type MailIMAPConfig struct {
+	IMAPHost string `json:"imapHost"`
+	IMAPPort int    `json:"imapPort,omitempty"` // default 993 (TLS)
+	SMTPHost string `json:"smtpHost"`
+	SMTPPort int    `json:"smtpPort,omitempty"` // default 587 (STARTTLS); 465 uses TLS
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+	Address  string `json:"address,omitempty"` // From address; defaults to username
+	Mailbox  string `json:"mailbox,omitempty"` // default INBOX
+	Drafts   string `json:"drafts,omitempty"`
+	Sent     string `json:"sent,omitempty"`
+}
+
+// GmailConfig is the OAuth client (of the "Desktop app" type) `myclaw auth
+// gmail` signs in with.
+type GmailConfig struct {
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
 }
 
 type GatewayConfig struct {
 	Host string `json:"host"`
 	Port int    `json:"port"`
+	// DrainTimeout is how long, in seconds, a gateway shutting down waits
+	// for the agent runs in progress to finish (default 30).
+	DrainTimeout int `json:"drainTimeout,omitempty"`
+	// Dashboard is the control panel served at /dashboard/.
+	Dashboard DashboardConfig `json:"dashboard,omitzero"`
+	// Events is the stream of gateway activity served at /events.
+	Events EventsConfig `json:"events,omitzero"`
 }
 
-type SkillsConfig struct {
+// DashboardConfig turns on the gateway's web dashboard: status, recent
+// conversations, token usage, cron jobs, skills and the log tail. Without
+// a token only clients on the same machine may open it; with one, requests
+// must carry it as a bearer token or log in with it once.
+type DashboardConfig struct {
+	Enabled bool   `json:"enabled"`
+	Token   string `json:"token,omitempty"`
+}
+
+// EventsConfig turns on /events, a server-sent event stream of what the
+// gateway does: messages received and sent, tool calls, cron runs and
+// errors. Without a token only clients on the same machine may connect;
+// with one, requests must carry it as a bearer token or a token query
+// parameter.
+type EventsConfig struct {
+	Enabled bool   `json:"enabled"`
+	Token   string `json:"token,omitempty"`
+}
+
+// DefaultDrainTimeout is how long a gateway shutting down waits for runs
+// in progress, in seconds.
+const DefaultDrainTimeout = 30
+
+// ServerConfig is the local HTTP API started by `myclaw serve`. Requests
+// must carry the token as a bearer token.
+type ServerConfig struct {
+	Host  string `json:"host"`
+	Port  int    `json:"port"`
+	Token string `json:"token,omitempty"`
+}
+
+// GitHubConfig gives the agent the GitHub tools: notifications, search,
+// pull request diffs, comments and new issues. Token is a personal access
+// token; when empty, GITHUB_TOKEN, GH_TOKEN and then the GitHub CLI's login
+// (`gh auth token`) are used. APIURL points at GitHub Enterprise's API
+// instead of api.github.com. Write decides what commenting and opening
+// issues do: "ask" the user first (the default), "allow" without asking, or
+// "deny".
+type GitHubConfig struct {
 	Enabled bool   `json:"enabled"`
-	Dir     string `json:"dir,omitempty"` // 默认 workspace/skills
+	Token   string `json:"token,omitempty"`
+	APIURL  string `json:"apiUrl,omitempty"`
+	Write   string `json:"write,omitempty"`
+}
+
+// HomeAssistantConfig connects the home tools (home_states, home_control)
+// to Home Assistant, over its REST API or over MQTT. Provider is "rest" or
+// "mqtt"; empty leaves the tools out. Entities is the allowlist: entity IDs
+// or patterns such as "light.*" the agent may see and control. Nothing else
+// is visible to it, and a service can only be called on an allowed entity.
+type HomeAssistantConfig struct {
+	Provider string                  `json:"provider,omitempty"`
+	URL      string                  `json:"url,omitempty"`   // REST, e.g. http://homeassistant.local:8123
+	Token    string                  `json:"token,omitempty"` // REST, a long-lived access token
+	MQTT     HomeAssistantMQTTConfig `json:"mqtt,omitempty"`
+	Entities []string                `json:"entities,omitempty"`
+}
+
+// HomeAssistantMQTTConfig reaches Home Assistant through an MQTT broker
+// (tcp://host:1883, or ssl://host:8883 for TLS). States are read from the
+// retained messages Home Assistant's mqtt_statestream publishes under
+// StateTopic (default "homeassistant/statestream"); service calls are
+// published as JSON to CommandTopic (default "myclaw/call") for an
+// automation to carry out.
+type HomeAssistantMQTTConfig struct {
+	Broker       string `json:"broker,omitempty"`
+	Username     string `json:"username,omitempty"`
+	Password     string `json:"password,omitempty"`
+	StateTopic   string `json:"stateTopic,omitempty"`
+	CommandTopic string `json:"commandTopic,omitempty"`
+}
+
+// FeedsConfig makes the gateway check RSS and Atom feeds on Schedule (a
+// cron expression with seconds, default 08:00 daily), have the agent
+// summarize the items that are new since the last check, and send the digest
+// to Deliver, as cron targets. MaxItems caps the items a feed contributes to
+// one digest (default 10); older new items are skipped. Which items have been
+// seen is kept in the workspace.
+type FeedsConfig struct {
+	Schedule string       `json:"schedule,omitempty"`
+	Deliver  []string     `json:"deliver,omitempty"`
+	MaxItems int          `json:"maxItems,omitempty"`
+	Feeds    []FeedConfig `json:"feeds,omitempty"`
+}
+
+// FeedConfig is one feed. Name defaults to the feed's own title. With
+// Include, only items whose title or summary contains one of its words are
+// kept; items containing any word of Exclude are dropped. Both ignore case.
+type FeedConfig struct {
+	Name    string   `json:"name,omitempty"`
+	URL     string   `json:"url"`
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// HeartbeatConfig controls the periodic run of HEARTBEAT.md. Interval is in
+// seconds (default 1800). ActiveHours limits runs to a daily local window
+// such as "08:00-22:00"; a window may wrap past midnight. Deliver lists
+// where replies go, as cron targets: "channel:to", "webhook:URL",
+// "file:path" or "notify:name". With Quiet, replies that report nothing
+// (HEARTBEAT_OK, empty or "nothing to report") are not delivered.
+type HeartbeatConfig struct {
+	Interval    int      `json:"interval,omitempty"`
+	ActiveHours string   `json:"activeHours,omitempty"`
+	Deliver     []string `json:"deliver,omitempty"`
+	Quiet       bool     `json:"quiet,omitempty"`
+	// NoCache runs the heartbeat every time, even with responseCache
+	// enabled.
+	NoCache bool `json:"noCache,omitempty"`
+}
+
+// ResponseCacheConfig caches the answers of cron jobs and the heartbeat.
+// A run whose prompt, system prompt and model match one from the last
+// TTLMinutes (default 1500, a day and an hour, so that a daily job finds
+// yesterday's) gets that run's answer without a call to the model. What
+// tools would fetch is not part of the match: jobs that read mail, feeds
+// or calendars should be added with --no-cache.
+type ResponseCacheConfig struct {
+	Enabled    bool `json:"enabled,omitempty"`
+	TTLMinutes int  `json:"ttlMinutes,omitempty"`
+}
+
+// DefaultResponseCacheTTLMinutes is how long a cached job answer is kept.
+const DefaultResponseCacheTTLMinutes = 25 * 60
+
+const (
+	SessionScopeUser = "user"
+	SessionScopeChat = "chat"
+)
+
+// SessionsConfig controls how channel messages map to conversations. With
+// the "user" scope (default) each sender in a group chat gets a session of
+// their own; with "chat" a group shares one. After TTLMinutes without a
+// message, a chat starts a fresh session (0 keeps sessions forever).
+// MaxSessions caps the histories held in memory (default 1000).
+//
+// With MaxRuntimes set, each chat session gets a runtime of its own, built
+// on its first message, and at most MaxRuntimes are live at once. One unused
+// for RuntimeIdleMinutes (default 30) is closed.
+type SessionsConfig struct {
+	Scope              string `json:"scope,omitempty"`
+	TTLMinutes         int    `json:"ttlMinutes,omitempty"`
+	MaxSessions        int    `json:"maxSessions,omitempty"`
+	MaxRuntimes        int    `json:"maxRuntimes,omitempty"`
+	RuntimeIdleMinutes int    `json:"runtimeIdleMinutes,omitempty"`
+}
+
+// DefaultRuntimeIdleMinutes is how long a session runtime is kept unused.
+const DefaultRuntimeIdleMinutes = 30
+
+const (
+	QueueOverflowReject = "reject"
+	QueueOverflowBlock  = "block"
+)
+
+// QueueConfig bounds the agent runs the gateway makes for chat messages.
+// At most Concurrency runs go at once (default 4), and at most
+// ChannelConcurrency[name] of them for one channel. Messages beyond that
+// wait, in order per session, up to MaxPending (default 100). Past that,
+// Overflow "reject" (default) answers with BusyReply, and "block" stops
+// reading new messages until there is room.
+type QueueConfig struct {
+	Concurrency        int            `json:"concurrency,omitempty"`
+	ChannelConcurrency map[string]int `json:"channelConcurrency,omitempty"`
+	MaxPending         int            `json:"maxPending,omitempty"`
+	Overflow           string         `json:"overflow,omitempty"`
+	BusyReply          string         `json:"busyReply,omitempty"`
+}
+
+// RateLimit caps what one chat user may ask of the agent. Zero means no
+// limit.
+type RateLimit struct {
+	MessagesPerMinute int `json:"messagesPerMinute,omitempty"`
+	TokensPerDay      int `json:"tokensPerDay,omitempty"`
+}
+
+// RateLimitConfig applies per-user limits in the gateway. Channels override
+// the limits for users of one channel; -1 lifts a limit. Channel admins are never limited.
+// SlowDownReply and BudgetReply replace the default replies to users over
+// their per-minute and daily limits.
+type RateLimitConfig struct {
+	RateLimit
+	Channels      map[string]RateLimit `json:"channels,omitempty"`
+	SlowDownReply string               `json:"slowDownReply,omitempty"`
+	BudgetReply   string               `json:"budgetReply,omitempty"`
+}
+
+// For returns the limits for users of channel.
+func (c RateLimitConfig) For(channel string) RateLimit {
+	limit := c.RateLimit
+	if override, ok := c.Channels[channel]; ok {
+		if override.MessagesPerMinute != 0 {
+			limit.MessagesPerMinute = override.MessagesPerMinute
+		}
+		if override.TokensPerDay != 0 {
+			limit.TokensPerDay = override.TokensPerDay
+		}
+	}
+	return limit
+}
+
+// MediaConfig controls how the gateway reads files sent in chats. Voice
+// notes are transcribed with STT; without a provider the agent is told a
+// voice note could not be read. MaxDocumentChars caps the text taken from
+// each document (default 20000), and from each input of myclaw agent -m.
+// myclaw agent --voice transcribes with STT too, records with Record and
+// speaks replies with TTS.
+type MediaConfig struct {
+	STT              STTConfig   `json:"stt"`
+	TTS              TTSConfig   `json:"tts"`
+	Images           ImageConfig `json:"images"`
+	MaxDocumentChars int         `json:"maxDocumentChars,omitempty"`
+	// Record is the command that records the microphone to the WAV file
+	// given as its {file} argument (appended when missing) until it is
+	// interrupted. The default is sox's rec, which also stops at a pause,
+	// or else arecord.
+	Record []string `json:"record,omitempty"`
+}
+
+// STTConfig selects a speech-to-text service. The "openai" provider works
+// with any OpenAI-compatible /audio/transcriptions endpoint, such as Groq's,
+// through BaseURL. Model defaults to whisper-1. The "whispercpp" provider
+// runs whisper.cpp locally: Command (default whisper-cli) with the ggml
+// model file at Model; audio other than WAV is converted with ffmpeg.
+type STTConfig struct {
+	Provider string `json:"provider,omitempty"` // "" (off) | "openai" | "whispercpp"
+	APIKey   string `json:"apiKey,omitempty"`
+	BaseURL  string `json:"baseUrl,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Language string `json:"language,omitempty"` // ISO-639-1 hint, e.g. "en"
+	Command  string `json:"command,omitempty"`
+}
+
+// TTSConfig selects how myclaw agent --voice speaks replies. The "openai"
+// provider calls an OpenAI-compatible /audio/speech endpoint (Model default
+// tts-1, Voice default alloy) and plays the audio with Play, the player
+// command, or the first of afplay, paplay, aplay, play and ffplay found.
+// The "command" provider pipes the text to Command, such as ["say"] on
+// macOS or ["espeak-ng", "--stdin"].
+type TTSConfig struct {
+	Provider string   `json:"provider,omitempty"` // "" (off) | "openai" | "command"
+	APIKey   string   `json:"apiKey,omitempty"`
+	BaseURL  string   `json:"baseUrl,omitempty"`
+	Model    string   `json:"model,omitempty"`
+	Voice    string   `json:"voice,omitempty"`
+	Command  []string `json:"command,omitempty"`
+	Play     []string `json:"play,omitempty"`
+}
+
+// ImageConfig selects the service behind the image_generate tool. The
+// "openai" provider calls an OpenAI-compatible /images/generations endpoint
+// (Model default gpt-image-1); "stability" calls Stability AI's Stable Image
+// API with Model core (default), ultra or sd3, and its key defaults to
+// STABILITY_API_KEY. Images are saved in workspace/images.
+type ImageConfig struct {
+	Provider string `json:"provider,omitempty"` // "" (off) | "openai" | "stability"
+	APIKey   string `json:"apiKey,omitempty"`
+	BaseURL  string `json:"baseUrl,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// DeadLetterConfig controls what happens to outbound messages that keep
+// failing to send. Admin alerts are skipped when AdminChannel is empty.
+type DeadLetterConfig struct {
+	MaxAttempts  int    `json:"maxAttempts,omitempty"`
+	AdminChannel string `json:"adminChannel,omitempty"`
+	AdminChatID  string `json:"adminChatId,omitempty"`
+}
+
+// ClusterConfig lets several gateways share one assistant through common
+// state. With RedisURL the state lives in Redis and instances may run on
+// different machines. Otherwise it is a SQLite file at StatePath (default
+// ~/.myclaw/data/cluster/state.db), which only instances on the same host
+// can share safely.
+type ClusterConfig struct {
+	Enabled           bool   `json:"enabled"`
+	InstanceID        string `json:"instanceId,omitempty"`
+	StatePath         string `json:"statePath,omitempty"`
+	RedisURL          string `json:"redisUrl,omitempty"`
+	LeaseTTLSeconds   int    `json:"leaseTtlSeconds,omitempty"`
+	SessionTTLMinutes int    `json:"sessionTtlMinutes,omitempty"`
+}
+
+// MemoryConfig controls long-term memory. With Semantic set, MEMORY.md and
+// the journal are indexed into workspace/memory/vectors.db and only the
+// chunks relevant to each message are added to the prompt, instead of the
+// whole files going into the system prompt.
+type MemoryConfig struct {
+	Semantic    bool            `json:"semantic"`
+	TopK        int             `json:"topK,omitempty"`
+	Embedding   EmbeddingConfig `json:"embedding"`
+	Rollup      RollupConfig    `json:"rollup"`
+	AutoExtract bool            `json:"autoExtract"` // ask the model for durable facts after each turn
+	Sync        SyncConfig      `json:"sync"`
+}
+
+// KBConfig tunes recall from the knowledge base that "myclaw kb add"
+// builds in workspace/kb. Up to TopK passages (default 4; -1 turns recall
+// off) scoring at least MinScore (default 0.2) are added to each message.
+// Passages are embedded with memory.embedding.
+type KBConfig struct {
+	TopK     int     `json:"topK,omitempty"`
+	MinScore float64 `json:"minScore,omitempty"`
+}
+
+// SyncConfig keeps workspace/memory in a git repository. The gateway commits
+// changes on Schedule (default every 15 minutes) and, with Remote set, pulls
+// and pushes Branch so several machines share one memory.
+type SyncConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Remote   string `json:"remote,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// BackupConfig has the gateway snapshot the config file, the workspace,
+// the skills and the cron jobs to a tarball on Schedule (default daily at
+// 04:00), keeping the newest Keep (default 7; 0 keeps them all). Backups go to S3 when
+// S3.Bucket is set, to WebDAV when WebDAV.URL is, and otherwise to Dir
+// (default ~/.myclaw/backups). "myclaw backup" works with the same target
+// whether or not Enabled is on.
+type BackupConfig struct {
+	Enabled  bool               `json:"enabled"`
+	Schedule string             `json:"schedule,omitempty"`
+	Keep     int                `json:"keep"`
+	Dir      string             `json:"dir,omitempty"`
+	S3       S3BackupConfig     `json:"s3,omitempty"`
+	WebDAV   WebDAVBackupConfig `json:"webdav,omitempty"`
+}
+
+// WebhookConfig has the gateway POST each of its events (see /events) of
+// a type in Events, or of any type when Events is empty, to URL as JSON.
+// With a Secret, requests carry X-Myclaw-Signature: sha256=<hex HMAC-SHA256
+// of the body keyed with Secret>.
+type WebhookConfig struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events,omitempty"`
+}
+
+// Push notification services a NotifyConfig can use.
+const (
+	NotifyNtfy     = "ntfy"
+	NotifyBark     = "bark"
+	NotifyPushover = "pushover"
+	NotifyDesktop  = "desktop"
+)
+
+// NotifyConfig is a one-way notification sink: somewhere the output
+// of cron jobs, the heartbeat and feeds can be delivered to as
+// "notify:<name>", and, for a type in Events, gateway events such as
+// task.finished are pushed.
+//
+// For ntfy, URL is the topic's URL (https://ntfy.sh/my-topic) and Token an
+// optional access token. For Bark, URL is the push URL with the device key
+// (https://api.day.app/<key>). For Pushover, Token is the application's
+// token and User the user or group key. Desktop shows the notification on
+// the machine the gateway runs on and takes no settings.
+type NotifyConfig struct {
+	Type   string   `json:"type"` // ntfy, bark, pushover or desktop
+	URL    string   `json:"url,omitempty"`
+	Token  string   `json:"token,omitempty"`
+	User   string   `json:"user,omitempty"`
+	Events []string `json:"events,omitempty"`
+}
+
+// S3BackupConfig is a bucket on S3 or an S3-compatible store. Endpoint is
+// the store's URL for other than AWS, such as https://minio.example.com,
+// and defaults to AWS in Region (default us-east-1). The keys default to
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+type S3BackupConfig struct {
+	Bucket          string `json:"bucket,omitempty"`
+	Region          string `json:"region,omitempty"`
+	Endpoint        string `json:"endpoint,omitempty"`
+	Prefix          string `json:"prefix,omitempty"`
+	AccessKeyID     string `json:"accessKeyId,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+}
+
+// WebDAVBackupConfig is a WebDAV folder, such as one on Nextcloud, that
+// backups are written to.
+type WebDAVBackupConfig struct {
+	URL      string `json:"url,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// RollupConfig makes the gateway fold journal files older than KeepDays into
+// MEMORY.md on Schedule (a cron expression with seconds, default 03:30
+// daily). Rolled-up files move to memory/archive/.
+type RollupConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Schedule string `json:"schedule,omitempty"`
+	KeepDays int    `json:"keepDays,omitempty"`
+}
+
+// EmbeddingConfig selects how memory is embedded. Provider "openai" calls an
+// OpenAI-compatible /embeddings endpoint; key and base URL default to the
+// main provider's when that is OpenAI. "local" is an offline hashing
+// embedder (keyword-level matching only). Empty means "openai" when the main
+// provider is OpenAI, "local" otherwise, since Anthropic has no embeddings API.
+type EmbeddingConfig struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	APIKey   string `json:"apiKey,omitempty"`
+	BaseURL  string `json:"baseUrl,omitempty"`
+}
+
+type SkillsConfig struct {
+	Enabled  bool     `json:"enabled"`
+	Dir      string   `json:"dir,omitempty"`      // 默认 workspace/skills
+	Disabled []string `json:"disabled,omitempty"` // skill names that stay installed but are not loaded
+	// Command skills: upper bound on run time in seconds (default 60) and
+	// sandbox mode: "env" (default), "none" or "bwrap".
+	CommandTimeout int    `json:"commandTimeout,omitempty"`
+	Sandbox        string `json:"sandbox,omitempty"`
+	Registry       string `json:"registry,omitempty"` // https URL of a skill registry index
 }
 
 type HooksConfig struct {
@@ -108,36 +1005,172 @@ type HookEntry struct {
 }
 
 type MCPConfig struct {
-	Servers []string `json:"servers,omitempty"`
+	Servers []MCPServer `json:"servers,omitempty"`
+}
+
+// MCPServer is an MCP server whose tools the agent gets. Spec is a command
+// run over stdio ("stdio://npx -y some-server") or the URL of an SSE
+// endpoint; an https+stream:// URL is streamable HTTP. In the config a
+// server is its spec alone, or {"name", "spec"} when it has a name for
+// "myclaw mcp" to refer to it by.
+type MCPServer struct {
+	Name string `json:"name,omitempty"`
+	Spec string `json:"spec"`
+}
+
+// Label is the server's name, or its spec when it has none.
+func (s MCPServer) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Spec
+}
+
+func (s *MCPServer) UnmarshalJSON(data []byte) error {
+	var spec string
+	if err := json.Unmarshal(data, &spec); err == nil {
+		*s = MCPServer{Spec: spec}
+		return nil
+	}
+	type plain MCPServer
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+func (s MCPServer) MarshalJSON() ([]byte, error) {
+	if s.Name == "" {
+		return json.Marshal(s.Spec)
+	}
+	type plain MCPServer
+	return json.Marshal(plain(s))
+}
+
+// mcpName is what an MCP server may be named, to be typed on the command line.
+var mcpName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Validate checks that s has a spec and a name that can be typed.
+func (s MCPServer) Validate() error {
+	if strings.TrimSpace(s.Spec) == "" {
+		return errors.New("no spec")
+	}
+	if s.Name != "" && !mcpName.MatchString(s.Name) {
+		return fmt.Errorf("name %q: want letters, digits, - and _", s.Name)
+	}
+	return nil
+}
+
+// Specs returns the spec of every server, as the runtime takes them.
+func (c MCPConfig) Specs() []string {
+	specs := make([]string, 0, len(c.Servers))
+	for _, s := range c.Servers {
+		specs = append(specs, s.Spec)
+	}
+	return specs
+}
+
+// Server returns the server named ref, or the unnamed one whose spec it is.
+func (c MCPConfig) Server(ref string) (MCPServer, bool) {
+	for _, s := range c.Servers {
+		if s.Name == ref || s.Name == "" && s.Spec == ref {
+			return s, true
+		}
+	}
+	return MCPServer{}, false
 }
 
+const (
+	WhatsAppModeWeb   = "web"
+	WhatsAppModeCloud = "cloud"
+)
+
 type WhatsAppConfig struct {
-	Enabled   bool     `json:"enabled"`
-	JID       string   `json:"jid,omitempty"`
-	StorePath string   `json:"storePath,omitempty"`
-	AllowFrom []string `json:"allowFrom,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	Mode        string     `json:"mode,omitempty"` // "web" (QR login, default) | "cloud" (Business Cloud API)
+	JID         string     `json:"jid,omitempty"`
+	StorePath   string     `json:"storePath,omitempty"`
+	AllowFrom   []string   `json:"allowFrom,omitempty"`
+	DenyFrom    []string   `json:"denyFrom,omitempty"`
+	DeniedReply string     `json:"deniedReply,omitempty"`
+	Admins      []string   `json:"admins,omitempty"`
+	Skills      SkillScope `json:"skills,omitempty"`
+
+	VoiceReplies VoiceReplyConfig `json:"voiceReplies,omitempty"`
+
+	// Cloud API mode
+	PhoneNumberID string                 `json:"phoneNumberId,omitempty"`
+	AccessToken   string                 `json:"accessToken,omitempty"`
+	AppSecret     string                 `json:"appSecret,omitempty"`
+	VerifyToken   string                 `json:"verifyToken,omitempty"`
+	Port          int                    `json:"port,omitempty"`
+	Template      WhatsAppTemplateConfig `json:"template,omitempty"`
+}
+
+// WhatsAppTemplateConfig names an approved message template used for
+// proactive messages outside the 24-hour customer service window. The
+// message text is passed as the template's first body parameter.
+type WhatsAppTemplateConfig struct {
+	Name     string `json:"name,omitempty"`
+	Language string `json:"language,omitempty"`
 }
 
 type WebUIConfig struct {
-	Enabled   bool     `json:"enabled"`
-	AllowFrom []string `json:"allowFrom,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	AllowFrom   []string   `json:"allowFrom,omitempty"`
+	DenyFrom    []string   `json:"denyFrom,omitempty"`
+	DeniedReply string     `json:"deniedReply,omitempty"`
+	Admins      []string   `json:"admins,omitempty"`
+	Skills      SkillScope `json:"skills,omitempty"`
 }
 
+// AutoCompactConfig controls compaction of long conversations. The runtime
+// compacts on its own past Threshold of the context window; /compact and
+// `myclaw sessions compact` do it on demand, asking the model with Prompt.
 type AutoCompactConfig struct {
 	Enabled       bool    `json:"enabled"`
 	Threshold     float64 `json:"threshold,omitempty"`
 	PreserveCount int     `json:"preserveCount,omitempty"`
+	// Prompt replaces the default summarization request of manual
+	// compaction. Automatic compaction uses the runtime's own.
+	Prompt string `json:"prompt,omitempty"`
 }
 
+// TokenTrackingConfig controls token accounting. Every gateway run is
+// recorded in the usage ledger with its estimated cost; Prices adds to or
+// overrides the built-in price table. When a day's estimated cost reaches
+// DailyBudgetUSD, an alert goes to AlertChatID on AlertChannel.
 type TokenTrackingConfig struct {
-	Enabled bool `json:"enabled"`
+	Enabled        bool             `json:"enabled"`
+	Prices         map[string]Price `json:"prices,omitempty"`
+	DailyBudgetUSD float64          `json:"dailyBudgetUsd,omitempty"`
+	AlertChannel   string           `json:"alertChannel,omitempty"`
+	AlertChatID    string           `json:"alertChatId,omitempty"`
+}
+
+// Price is what a model costs, in USD per million tokens. Models are
+// matched by prefix, so "claude-sonnet-4-5" covers its dated versions.
+type Price struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cacheRead,omitempty"`
+	CacheWrite float64 `json:"cacheWrite,omitempty"`
+}
+
+// TracingConfig exports OpenTelemetry traces of agent runs over OTLP/HTTP.
+// Endpoint is a host:port or URL; when empty, the standard
+// OTEL_EXPORTER_OTLP_* variables apply, then localhost:4318. SampleRate is
+// the share of runs traced, 1 when unset.
+type TracingConfig struct {
+	Enabled     bool              `json:"enabled"`
+	Endpoint    string            `json:"endpoint,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Insecure    bool              `json:"insecure,omitempty"`
+	ServiceName string            `json:"serviceName,omitempty"`
+	SampleRate  float64           `json:"sampleRate,omitempty"`
 }
 
 func DefaultConfig() *Config {
-	home, _ := os.UserHomeDir()
 	return &Config{
 		Agent: AgentConfig{
-			Workspace:         filepath.Join(home, ".myclaw", "workspace"),
+			Workspace:         filepath.Join(DataDir(), "workspace"),
 			Model:             DefaultModel,
 			MaxTokens:         DefaultMaxTokens,
 			Temperature:       DefaultTemperature,
@@ -148,6 +1181,7 @@ func DefaultConfig() *Config {
 		Tools: ToolsConfig{
 			ExecTimeout:         DefaultExecTimeout,
 			RestrictToWorkspace: true,
+			Fetch:               FetchConfig{Enabled: true},
 		},
 		Skills: SkillsConfig{
 			Enabled: true,
@@ -161,6 +1195,43 @@ func DefaultConfig() *Config {
 			Host: DefaultHost,
 			Port: DefaultPort,
 		},
+		DeadLetter: DeadLetterConfig{
+			MaxAttempts: DefaultSendAttempts,
+		},
+		Server: ServerConfig{
+			Host: DefaultServerHost,
+			Port: DefaultServerPort,
+		},
+		Memory: MemoryConfig{
+			TopK: DefaultMemoryTopK,
+			Rollup: RollupConfig{
+				Schedule: DefaultRollupSchedule,
+				KeepDays: DefaultRollupKeepDays,
+			},
+			Sync: SyncConfig{
+				Branch:   DefaultSyncBranch,
+				Schedule: DefaultSyncSchedule,
+			},
+		},
+		KB: KBConfig{
+			TopK:     DefaultKBTopK,
+			MinScore: DefaultKBMinScore,
+		},
+		Feeds: FeedsConfig{
+			Schedule: DefaultFeedsSchedule,
+			MaxItems: DefaultFeedsMaxItems,
+		},
+		Backup: BackupConfig{
+			Schedule: DefaultBackupSchedule,
+			Keep:     DefaultBackupKeep,
+		},
+		Redaction: RedactionConfig{
+			Emails: true,
+			Phones: true,
+		},
+		Audit: AuditConfig{
+			Enabled: true,
+		},
 	}
 }
 
@@ -169,28 +1240,112 @@ func ConfigDir() string {
 	return filepath.Join(home, ".myclaw")
 }
 
+// profile is the profile chosen with SetProfile.
+var profile string
+
+var validProfile = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// SetProfile selects the named profile for the rest of the process. An
+// empty name leaves the choice to MYCLAW_PROFILE.
+func SetProfile(name string) error {
+	if name != "" && !validProfile.MatchString(name) {
+		return fmt.Errorf("profile %q: use letters, digits, '-' and '_'", name)
+	}
+	profile = name
+	return nil
+}
+
+// Profile returns the active profile: the one set with SetProfile, then
+// MYCLAW_PROFILE. It is empty for the default config.
+func Profile() string {
+	if profile != "" {
+		return profile
+	}
+	return os.Getenv("MYCLAW_PROFILE")
+}
+
+// ConfigPath returns the active profile's config file:
+// ~/.myclaw/profiles/<name>.json, or ~/.myclaw/config.json without one.
+// A .yaml, .yml or .toml file is used instead when there is no .json one.
 func ConfigPath() string {
-	return filepath.Join(ConfigDir(), "config.json")
+	return findConfig(configBase())
+}
+
+// ConfigPaths returns every file ConfigPath chooses between, one for each
+// format, so that a watcher notices whichever of them is written.
+func ConfigPaths() []string {
+	paths := make([]string, len(configExts))
+	for i, ext := range configExts {
+		paths[i] = configBase() + ext
+	}
+	return paths
+}
+
+// configBase is the config file path without its extension.
+func configBase() string {
+	if name := Profile(); name != "" {
+		return filepath.Join(ConfigDir(), "profiles", name)
+	}
+	return filepath.Join(ConfigDir(), "config")
+}
+
+// DataDir returns where the active profile keeps its workspace, cron jobs
+// and other state: ~/.myclaw/profiles/<name>, or ~/.myclaw without one.
+func DataDir() string {
+	if name := Profile(); name != "" {
+		return filepath.Join(ConfigDir(), "profiles", name)
+	}
+	return ConfigDir()
+}
+
+// googleAPIKey returns GOOGLE_API_KEY, or GEMINI_API_KEY when that is unset.
+func googleAPIKey() string {
+	if key := os.Getenv("GOOGLE_API_KEY"); key != "" {
+		return key
+	}
+	return os.Getenv("GEMINI_API_KEY")
+}
+
+// vendorAPIKey returns the environment API key for a provider type.
+func vendorAPIKey(providerType string) string {
+	switch providerType {
+	case "openai":
+		return os.Getenv("OPENAI_API_KEY")
+	case "gemini":
+		return googleAPIKey()
+	default:
+		if key := os.Getenv("ANTHROPIC_API_KEY"); key != "" {
+			return key
+		}
+		return os.Getenv("ANTHROPIC_AUTH_TOKEN")
+	}
 }
 
 func LoadConfig() (*Config, error) {
-	cfg := DefaultConfig()
+	name, _ := Workspace()
+	return LoadWorkspaceConfig(name)
+}
 
-	data, err := os.ReadFile(ConfigPath())
+// LoadWorkspaceConfig is LoadConfig with the given workspace, by name or by
+// path, in place of the active one.
+func LoadWorkspaceConfig(workspace string) (*Config, error) {
+	cfg, err := loadConfigFile()
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return nil, fmt.Errorf("read config: %w", err)
-		}
-	} else {
-		if err := json.Unmarshal(data, cfg); err != nil {
-			return nil, fmt.Errorf("parse config: %w", err)
-		}
+		return nil, err
+	}
+	if err := applyEnv(cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
 	}
 
-	// Environment variable overrides
+	// Shorter environment variables kept from before the MYCLAW_<KEY>
+	// scheme, which they win over.
 	if key := os.Getenv("MYCLAW_API_KEY"); key != "" {
 		cfg.Provider.APIKey = key
 	}
+	// With provider.type gemini, Google's key wins over other vendors' keys.
+	if cfg.Provider.Type == "gemini" && cfg.Provider.APIKey == "" {
+		cfg.Provider.APIKey = googleAPIKey()
+	}
 	if key := os.Getenv("ANTHROPIC_API_KEY"); key != "" && cfg.Provider.APIKey == "" {
 		cfg.Provider.APIKey = key
 	}
@@ -203,6 +1358,35 @@ func LoadConfig() (*Config, error) {
 			cfg.Provider.Type = "openai"
 		}
 	}
+	if key := googleAPIKey(); key != "" && cfg.Provider.APIKey == "" {
+		cfg.Provider.APIKey = key
+		if cfg.Provider.Type == "" {
+			cfg.Provider.Type = "gemini"
+		}
+	}
+	for i := range cfg.Provider.Fallbacks {
+		if fb := &cfg.Provider.Fallbacks[i]; fb.APIKey == "" {
+			fb.APIKey = vendorAPIKey(fb.Type)
+		}
+	}
+	if cfg.Media.STT.Provider == "openai" && cfg.Media.STT.APIKey == "" {
+		cfg.Media.STT.APIKey = vendorAPIKey(cfg.Media.STT.Provider)
+	}
+	if cfg.Media.TTS.Provider == "openai" && cfg.Media.TTS.APIKey == "" {
+		cfg.Media.TTS.APIKey = vendorAPIKey(cfg.Media.TTS.Provider)
+	}
+	if img := &cfg.Media.Images; img.APIKey == "" {
+		switch img.Provider {
+		case "openai":
+			img.APIKey = vendorAPIKey(img.Provider)
+		case "stability":
+			img.APIKey = os.Getenv("STABILITY_API_KEY")
+		}
+	}
+	if s3 := &cfg.Backup.S3; s3.Bucket != "" && s3.AccessKeyID == "" && s3.SecretAccessKey == "" {
+		s3.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		s3.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
 	if url := os.Getenv("MYCLAW_BASE_URL"); url != "" {
 		cfg.Provider.BaseURL = url
 	}
@@ -228,16 +1412,465 @@ func LoadConfig() (*Config, error) {
 		cfg.Channels.WeCom.ReceiveID = receiveID
 	}
 
+	if token := os.Getenv("MYCLAW_SLACK_BOT_TOKEN"); token != "" {
+		cfg.Channels.Slack.BotToken = token
+	}
+	if token := os.Getenv("MYCLAW_SLACK_APP_TOKEN"); token != "" {
+		cfg.Channels.Slack.AppToken = token
+	}
+	if secret := os.Getenv("MYCLAW_SLACK_SIGNING_SECRET"); secret != "" {
+		cfg.Channels.Slack.SigningSecret = secret
+	}
+
+	if token := os.Getenv("MYCLAW_SERVER_TOKEN"); token != "" {
+		cfg.Server.Token = token
+	}
+
+	if user := os.Getenv("MYCLAW_EMAIL_USERNAME"); user != "" {
+		cfg.Channels.Email.Username = user
+	}
+	if pass := os.Getenv("MYCLAW_EMAIL_PASSWORD"); pass != "" {
+		cfg.Channels.Email.Password = pass
+	}
+
+	if pass := os.Getenv("MYCLAW_XMPP_PASSWORD"); pass != "" {
+		cfg.Channels.XMPP.Password = pass
+	}
+
+	if token := os.Getenv("MYCLAW_MATTERMOST_TOKEN"); token != "" {
+		cfg.Channels.Mattermost.Token = token
+	}
+	if token := os.Getenv("MYCLAW_ROCKETCHAT_TOKEN"); token != "" {
+		cfg.Channels.RocketChat.Token = token
+	}
+
+	if token := os.Getenv("MYCLAW_WHATSAPP_ACCESS_TOKEN"); token != "" {
+		cfg.Channels.WhatsApp.AccessToken = token
+	}
+	if secret := os.Getenv("MYCLAW_WHATSAPP_APP_SECRET"); secret != "" {
+		cfg.Channels.WhatsApp.AppSecret = secret
+	}
+	if token := os.Getenv("MYCLAW_WHATSAPP_VERIFY_TOKEN"); token != "" {
+		cfg.Channels.WhatsApp.VerifyToken = token
+	}
+
+	if key := os.Getenv("MYCLAW_EMBEDDING_API_KEY"); key != "" {
+		cfg.Memory.Embedding.APIKey = key
+	}
+	for _, name := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(name); token != "" && cfg.GitHub.Token == "" {
+			cfg.GitHub.Token = token
+		}
+	}
+	if search := &cfg.Tools.Search; search.Backend == "" && cfg.Tools.BraveAPIKey != "" {
+		search.Backend = "brave"
+	}
+	if search := &cfg.Tools.Search; search.APIKey == "" {
+		switch search.Backend {
+		case "brave":
+			search.APIKey = cfg.Tools.BraveAPIKey
+			if search.APIKey == "" {
+				search.APIKey = os.Getenv("BRAVE_API_KEY")
+			}
+		case "serpapi":
+			search.APIKey = os.Getenv("SERPAPI_API_KEY")
+		}
+	}
+
 	if cfg.Agent.Workspace == "" {
 		cfg.Agent.Workspace = DefaultConfig().Agent.Workspace
 	}
+	if err := applyWorkspace(cfg, workspace); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	applyProject(cfg)
+
+	// Last, so values the environment replaced are never looked up.
+	if err := resolveSecrets(cfg, keyring); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate reports settings that would keep the agent from working, all of
+// them at once.
+func (c *Config) Validate() error {
+	var errs []error
+	switch c.Provider.Type {
+	case "", "anthropic", "openai", "gemini":
+	default:
+		errs = append(errs, fmt.Errorf("provider.type %q: want anthropic, openai or gemini", c.Provider.Type))
+	}
+	if c.Provider.APIKey == "" {
+		errs = append(errs, errors.New("provider.apiKey is not set"))
+	}
+	if c.Agent.Workspace == "" {
+		errs = append(errs, errors.New("agent.workspace is not set"))
+	}
+	if c.Gateway.Port < 0 || c.Gateway.Port > 65535 {
+		errs = append(errs, fmt.Errorf("gateway.port %d is out of range", c.Gateway.Port))
+	}
+	if c.Gateway.DrainTimeout < 0 {
+		errs = append(errs, fmt.Errorf("gateway.drainTimeout %d is negative", c.Gateway.DrainTimeout))
+	}
+	switch c.Queue.Overflow {
+	case "", QueueOverflowReject, QueueOverflowBlock:
+	default:
+		errs = append(errs, fmt.Errorf("queue.overflow %q: want reject or block", c.Queue.Overflow))
+	}
+	if c.Sessions.MaxRuntimes < 0 {
+		errs = append(errs, fmt.Errorf("sessions.maxRuntimes %d: want 0 (one shared runtime) or more", c.Sessions.MaxRuntimes))
+	}
+	if c.Cluster.RedisURL != "" && c.Cluster.StatePath != "" {
+		errs = append(errs, errors.New("cluster: set redisUrl or statePath, not both"))
+	}
+	if c.Sessions.RuntimeIdleMinutes < 0 {
+		errs = append(errs, fmt.Errorf("sessions.runtimeIdleMinutes %d is negative", c.Sessions.RuntimeIdleMinutes))
+	}
+	if c.ResponseCache.TTLMinutes < 0 {
+		errs = append(errs, fmt.Errorf("responseCache.ttlMinutes %d is negative", c.ResponseCache.TTLMinutes))
+	}
+	switch c.Permissions.Default {
+	case "", "allow", "ask", "deny":
+	default:
+		errs = append(errs, fmt.Errorf("permissions.default %q: want allow, ask or deny", c.Permissions.Default))
+	}
+	for i, p := range c.Redaction.Patterns {
+		if p.Name == "" {
+			errs = append(errs, fmt.Errorf("redaction.patterns[%d] has no name", i))
+		}
+		if _, err := regexp.Compile(p.Pattern); err != nil || p.Pattern == "" {
+			errs = append(errs, fmt.Errorf("redaction.patterns[%d] %q: not a valid regular expression", i, p.Pattern))
+		}
+	}
+	switch search := c.Tools.Search; search.Backend {
+	case "", "duckduckgo":
+	case "brave", "serpapi":
+		if search.APIKey == "" {
+			errs = append(errs, fmt.Errorf("tools.search.apiKey is not set for %s", search.Backend))
+		}
+	case "searxng":
+		if search.BaseURL == "" {
+			errs = append(errs, errors.New("tools.search.baseUrl is not set for searxng"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tools.search.backend %q: want brave, serpapi, searxng or duckduckgo", search.Backend))
+	}
+	switch cal := c.Calendar; cal.Provider {
+	case "":
+	case "caldav":
+		if cal.CalDAV.URL == "" {
+			errs = append(errs, errors.New("calendar.caldav.url is not set"))
+		}
+	case "google":
+		if cal.Google.ClientID == "" || cal.Google.ClientSecret == "" {
+			errs = append(errs, errors.New("calendar.google.clientId and clientSecret are not set"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("calendar.provider %q: want caldav or google", cal.Provider))
+	}
+	if tz := c.Calendar.Timezone; tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			errs = append(errs, fmt.Errorf("calendar.timezone %q: %w", tz, err))
+		}
+	}
+	switch mail := c.Mail; mail.Provider {
+	case "":
+	case "imap":
+		if mail.IMAP.IMAPHost == "" || mail.IMAP.SMTPHost == "" || mail.IMAP.Username == "" {
+			errs = append(errs, errors.New("mail.imap.imapHost, smtpHost and username are not set"))
+		}
+	case "gmail":
+		if mail.Gmail.ClientID == "" || mail.Gmail.ClientSecret == "" {
+			errs = append(errs, errors.New("mail.gmail.clientId and clientSecret are not set"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mail.provider %q: want imap or gmail", mail.Provider))
+	}
+	switch c.Mail.Send {
+	case "", "ask", "allow", "deny":
+	default:
+		errs = append(errs, fmt.Errorf("mail.send %q: want ask, allow or deny", c.Mail.Send))
+	}
+	for i, feed := range c.Feeds.Feeds {
+		if !strings.HasPrefix(feed.URL, "http://") && !strings.HasPrefix(feed.URL, "https://") {
+			errs = append(errs, fmt.Errorf("feeds.feeds[%d].url %q: want an http or https URL", i, feed.URL))
+		}
+	}
+	for i, hook := range c.Webhooks {
+		if !strings.HasPrefix(hook.URL, "http://") && !strings.HasPrefix(hook.URL, "https://") {
+			errs = append(errs, fmt.Errorf("webhooks[%d].url %q: want an http or https URL", i, hook.URL))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Notify)) {
+		n := c.Notify[name]
+		switch n.Type {
+		case NotifyNtfy, NotifyBark:
+			if !strings.HasPrefix(n.URL, "http://") && !strings.HasPrefix(n.URL, "https://") {
+				errs = append(errs, fmt.Errorf("notify.%s.url %q: want an http or https URL", name, n.URL))
+			}
+		case NotifyPushover:
+			if n.Token == "" || n.User == "" {
+				errs = append(errs, fmt.Errorf("notify.%s: pushover needs token and user", name))
+			}
+		case NotifyDesktop:
+		default:
+			errs = append(errs, fmt.Errorf("notify.%s.type %q: want ntfy, bark, pushover or desktop", name, n.Type))
+		}
+	}
+	for _, deliver := range []struct {
+		field string
+		specs []string
+	}{{"heartbeat.deliver", c.Heartbeat.Deliver}, {"feeds.deliver", c.Feeds.Deliver}} {
+		for _, spec := range deliver.specs {
+			if name, ok := strings.CutPrefix(strings.TrimSpace(spec), "notify:"); ok {
+				if _, found := c.Notify[name]; !found {
+					errs = append(errs, fmt.Errorf("%s %q: no notify sink named %q", deliver.field, spec, name))
+				}
+			}
+		}
+	}
+	switch c.Channels.Telegram.Groups {
+	case "", TelegramGroupsMention, TelegramGroupsAll:
+	default:
+		errs = append(errs, fmt.Errorf("channels.telegram.groups %q: want mention or all", c.Channels.Telegram.Groups))
+	}
+	if c.Channels.Email.Enabled && len(c.Channels.Email.AllowFrom) == 0 {
+		// From headers are easy to forge, so nobody is let in by default.
+		errs = append(errs, errors.New("channels.email.allowFrom must list who may message the agent"))
+	}
+	if c.Channels.IMessage.Enabled && len(c.Channels.IMessage.AllowFrom) == 0 {
+		errs = append(errs, errors.New("channels.imessage.allowFrom must list who may message the agent"))
+	}
+	if x := c.Channels.XMPP; x.Enabled {
+		if local, domain, ok := strings.Cut(x.JID, "@"); !ok || local == "" || domain == "" {
+			errs = append(errs, fmt.Errorf("channels.xmpp.jid %q: want user@domain", x.JID))
+		}
+		if x.Password == "" {
+			errs = append(errs, errors.New("channels.xmpp.password is required"))
+		}
+	}
+	if mm := c.Channels.Mattermost; mm.Enabled {
+		if !strings.HasPrefix(mm.URL, "http://") && !strings.HasPrefix(mm.URL, "https://") {
+			errs = append(errs, fmt.Errorf("channels.mattermost.url %q: want an http or https URL", mm.URL))
+		}
+		if mm.Token == "" {
+			errs = append(errs, errors.New("channels.mattermost.token is required"))
+		}
+	}
+	if rc := c.Channels.RocketChat; rc.Enabled {
+		if !strings.HasPrefix(rc.URL, "http://") && !strings.HasPrefix(rc.URL, "https://") {
+			errs = append(errs, fmt.Errorf("channels.rocketchat.url %q: want an http or https URL", rc.URL))
+		}
+		if rc.UserID == "" || rc.Token == "" {
+			errs = append(errs, errors.New("channels.rocketchat.userId and token are required"))
+		}
+	}
+	if wc := c.Channels.WeCom; len(wc.Apps) > 0 {
+		if wc.CorpID == "" {
+			errs = append(errs, errors.New("channels.wecom.corpId is needed for apps"))
+		}
+		seen := make(map[int]bool)
+		for i, app := range wc.Apps {
+			switch {
+			case app.AgentID <= 0:
+				errs = append(errs, fmt.Errorf("channels.wecom.apps[%d].agentId is not set", i))
+			case seen[app.AgentID]:
+				errs = append(errs, fmt.Errorf("channels.wecom.apps[%d]: agentId %d is used twice", i, app.AgentID))
+			}
+			seen[app.AgentID] = true
+			if app.Secret == "" {
+				errs = append(errs, fmt.Errorf("channels.wecom.apps[%d].secret is not set", i))
+			}
+			if app.Token == "" && wc.Token == "" {
+				errs = append(errs, fmt.Errorf("channels.wecom.apps[%d].token is not set", i))
+			}
+			if key := cmp.Or(app.EncodingAESKey, wc.EncodingAESKey); len(key) != 43 {
+				errs = append(errs, fmt.Errorf("channels.wecom.apps[%d].encodingAESKey must be 43 characters", i))
+			}
+		}
+	}
+	switch c.GitHub.Write {
+	case "", "ask", "allow", "deny":
+	default:
+		errs = append(errs, fmt.Errorf("github.write %q: want ask, allow or deny", c.GitHub.Write))
+	}
+	switch stt := c.Media.STT; stt.Provider {
+	case "", "openai":
+	case "whispercpp":
+		if stt.Model == "" {
+			errs = append(errs, errors.New("media.stt.model is not set; whispercpp needs the path of a ggml model file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("media.stt.provider %q: want openai or whispercpp", stt.Provider))
+	}
+	switch tts := c.Media.TTS; tts.Provider {
+	case "", "openai":
+	case "command":
+		if len(tts.Command) == 0 {
+			errs = append(errs, errors.New("media.tts.command is not set"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("media.tts.provider %q: want openai or command", tts.Provider))
+	}
+	switch c.Media.Images.Provider {
+	case "", "openai", "stability":
+	default:
+		errs = append(errs, fmt.Errorf("media.images.provider %q: want openai or stability", c.Media.Images.Provider))
+	}
+	names := make(map[string]bool)
+	for i, server := range c.MCP.Servers {
+		if err := server.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("mcp.servers[%d]: %w", i, err))
+		}
+		if server.Name != "" && names[server.Name] {
+			errs = append(errs, fmt.Errorf("mcp.servers[%d]: name %q is used twice", i, server.Name))
+		}
+		names[server.Name] = true
+	}
+	if c.KB.MinScore < 0 || c.KB.MinScore >= 1 {
+		errs = append(errs, fmt.Errorf("kb.minScore %g: want 0 up to 1", c.KB.MinScore))
+	}
+	for _, ch := range []struct {
+		name string
+		vr   VoiceReplyConfig
+	}{{"telegram", c.Channels.Telegram.VoiceReplies}, {"whatsapp", c.Channels.WhatsApp.VoiceReplies}} {
+		name, vr := ch.name, ch.vr
+		switch vr.Mode {
+		case "", "off":
+			continue
+		case "voice", "both":
+		default:
+			errs = append(errs, fmt.Errorf("channels.%s.voiceReplies.mode %q: want off, voice or both", name, vr.Mode))
+		}
+		if vr.Speed != 0 && (vr.Speed < 0.25 || vr.Speed > 4) {
+			errs = append(errs, fmt.Errorf("channels.%s.voiceReplies.speed %v: want 0.25 to 4", name, vr.Speed))
+		}
+		if c.Media.TTS.Provider != "openai" {
+			errs = append(errs, fmt.Errorf("channels.%s.voiceReplies needs media.tts.provider openai", name))
+		}
+	}
+	switch ha := c.HomeAssistant; ha.Provider {
+	case "":
+	case "rest":
+		if !strings.HasPrefix(ha.URL, "http://") && !strings.HasPrefix(ha.URL, "https://") {
+			errs = append(errs, fmt.Errorf("homeAssistant.url %q: want an http or https URL", ha.URL))
+		}
+		if ha.Token == "" {
+			errs = append(errs, errors.New("homeAssistant.token is not set"))
+		}
+	case "mqtt":
+		if ha.MQTT.Broker == "" {
+			errs = append(errs, errors.New("homeAssistant.mqtt.broker is not set"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("homeAssistant.provider %q: want rest or mqtt", ha.Provider))
+	}
+	if ha := c.HomeAssistant; ha.Provider != "" && len(ha.Entities) == 0 {
+		errs = append(errs, errors.New("homeAssistant.entities is not set; list the entities the agent may use, e.g. light.*"))
+	}
+	for _, pattern := range c.HomeAssistant.Entities {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.Contains(pattern, ".") {
+			errs = append(errs, fmt.Errorf("homeAssistant.entities %q: want an entity ID or a pattern such as light.*", pattern))
+		}
+	}
+	if len(c.Feeds.Feeds) > 0 && len(c.Feeds.Deliver) == 0 {
+		errs = append(errs, errors.New("feeds.deliver is not set; the digest needs somewhere to go"))
+	}
+	if fetch := c.Tools.Fetch; fetch.MaxTokens < 0 || fetch.Timeout < 0 {
+		errs = append(errs, errors.New("tools.fetch.maxTokens and tools.fetch.timeout must not be negative"))
+	}
+	for _, d := range append(append([]string(nil), c.Tools.Fetch.Allow...), c.Tools.Fetch.Deny...) {
+		if strings.Contains(d, "/") || strings.TrimSpace(d) == "" {
+			errs = append(errs, fmt.Errorf("tools.fetch domain %q: want a domain such as example.com", d))
+		}
+	}
+	if c.Backup.Keep < 0 {
+		errs = append(errs, fmt.Errorf("backup.keep %d is negative", c.Backup.Keep))
+	}
+	if c.Backup.S3.Bucket != "" && c.Backup.WebDAV.URL != "" {
+		errs = append(errs, errors.New("backup: set either s3.bucket or webdav.url, not both"))
+	}
+	if d := c.Tools.Delegate; d.MaxDepth < 0 || d.MaxIterations < 0 || d.MaxTokens < 0 {
+		errs = append(errs, errors.New("tools.delegate.maxDepth, maxIterations and maxTokens must not be negative"))
+	}
+	if r := c.Tracing.SampleRate; r < 0 || r > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sampleRate %v: want 0 to 1", r))
+	}
+	return errors.Join(errs...)
+}
+
+// loadConfigFile reads the config file over the defaults, without
+// environment overrides.
+func loadConfigFile() (*Config, error) {
+	if name := Profile(); name != "" && !validProfile.MatchString(name) {
+		return nil, fmt.Errorf("profile %q: use letters, digits, '-' and '_'", name)
+	}
+	cfg := DefaultConfig()
 
+	path := ConfigPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read config: %w", err)
+		}
+		return cfg, nil
+	}
+	if data, err = toJSON(path, data); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
 	return cfg, nil
 }
 
+// UpdateConfig applies update to the config file and writes back only the
+// settings it changed, leaving comments, key order and everything else in
+// the file as it was. Unlike LoadConfig followed by SaveConfig, it never
+// writes secrets that came from environment variables into the file.
+func UpdateConfig(update func(cfg *Config) error) error {
+	cfg, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+	before, err := configNode(cfg)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	if err := update(cfg); err != nil {
+		return err
+	}
+	after, err := configNode(cfg)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	changes := diffConfig(before, after, nil)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	path := ConfigPath()
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read config: %w", err)
+	}
+	if data, err = editConfig(path, data, changes); err != nil {
+		return fmt.Errorf("update config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// SaveConfig writes all of cfg to ConfigPath, in that file's format,
+// replacing the file. Use UpdateConfig to change settings in a file the user
+// may have edited.
 func SaveConfig(cfg *Config) error {
-	dir := ConfigDir()
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	path := ConfigPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return fmt.Errorf("create config dir: %w", err)
 	}
 
@@ -245,6 +1878,9 @@ func SaveConfig(cfg *Config) error {
 	if err != nil {
 		return fmt.Errorf("marshal config: %w", err)
 	}
+	if data, err = fromJSON(path, data); err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
 
-	return os.WriteFile(ConfigPath(), data, 0644)
+	return os.WriteFile(path, data, 0644)
 }
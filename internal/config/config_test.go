@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -168,6 +169,67 @@ func TestLoadConfig_EnvPriority(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_GoogleAPIKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("ANTHROPIC_AUTH_TOKEN", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("GOOGLE_API_KEY", "google-key")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	if cfg.Provider.APIKey != "google-key" || cfg.Provider.Type != "gemini" {
+		t.Errorf("provider = %+v, want gemini with google-key", cfg.Provider)
+	}
+
+	// With provider.type gemini, the Google key beats other vendors' keys.
+	t.Setenv("ANTHROPIC_API_KEY", "anthropic-key")
+	if err := SaveConfig(&Config{Provider: ProviderConfig{Type: "gemini"}}); err != nil {
+		t.Fatalf("SaveConfig error: %v", err)
+	}
+	if cfg, _ = LoadConfig(); cfg.Provider.APIKey != "google-key" {
+		t.Errorf("apiKey = %q, want google-key", cfg.Provider.APIKey)
+	}
+	if err := SaveConfig(&Config{}); err != nil {
+		t.Fatalf("SaveConfig error: %v", err)
+	}
+	if cfg, _ = LoadConfig(); cfg.Provider.APIKey != "anthropic-key" {
+		t.Errorf("apiKey = %q, want anthropic-key", cfg.Provider.APIKey)
+	}
+}
+
+func TestLoadConfig_FallbackAPIKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "anthropic-key")
+	t.Setenv("OPENAI_API_KEY", "openai-key")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("GOOGLE_API_KEY", "google-key")
+
+	err := SaveConfig(&Config{Provider: ProviderConfig{Fallbacks: []FallbackProvider{
+		{Type: "openai"},
+		{Type: "gemini"},
+		{Type: "openai", APIKey: "local", BaseURL: "http://localhost:11434/v1"},
+	}}, Media: MediaConfig{STT: STTConfig{Provider: "openai"}}})
+	if err != nil {
+		t.Fatalf("SaveConfig error: %v", err)
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	fb := cfg.Provider.Fallbacks
+	if len(fb) != 3 || fb[0].APIKey != "openai-key" || fb[1].APIKey != "google-key" || fb[2].APIKey != "local" {
+		t.Errorf("fallbacks = %+v", fb)
+	}
+	if cfg.Media.STT.APIKey != "openai-key" {
+		t.Errorf("stt key = %q, want openai-key", cfg.Media.STT.APIKey)
+	}
+}
+
 func TestLoadConfig_BaseURLEnv(t *testing.T) {
 	tmpDir := t.TempDir()
 	origHome := os.Getenv("HOME")
@@ -214,6 +276,75 @@ func TestSaveConfig(t *testing.T) {
 	}
 }
 
+func TestProfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("MYCLAW_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("ANTHROPIC_AUTH_TOKEN", "")
+	t.Setenv("MYCLAW_PROFILE", "")
+	t.Cleanup(func() { SetProfile("") })
+
+	if got, want := ConfigPath(), filepath.Join(tmpDir, ".myclaw", "config.json"); got != want {
+		t.Errorf("ConfigPath() = %q, want %q", got, want)
+	}
+	if got, want := DataDir(), filepath.Join(tmpDir, ".myclaw"); got != want {
+		t.Errorf("DataDir() = %q, want %q", got, want)
+	}
+
+	t.Setenv("MYCLAW_PROFILE", "personal")
+	if got := Profile(); got != "personal" {
+		t.Errorf("Profile() = %q, want personal from MYCLAW_PROFILE", got)
+	}
+	if err := SetProfile("work"); err != nil {
+		t.Fatalf("SetProfile: %v", err)
+	}
+	if got := Profile(); got != "work" {
+		t.Errorf("Profile() = %q, want work over MYCLAW_PROFILE", got)
+	}
+
+	profileDir := filepath.Join(tmpDir, ".myclaw", "profiles")
+	if got, want := ConfigPath(), filepath.Join(profileDir, "work.json"); got != want {
+		t.Errorf("ConfigPath() = %q, want %q", got, want)
+	}
+	if got, want := DefaultConfig().Agent.Workspace, filepath.Join(profileDir, "work", "workspace"); got != want {
+		t.Errorf("workspace = %q, want %q", got, want)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Agent.Model = "work-model"
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if loaded.Agent.Model != "work-model" {
+		t.Errorf("model = %q, want work-model", loaded.Agent.Model)
+	}
+
+	SetProfile("")
+	loaded, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig personal: %v", err)
+	}
+	if loaded.Agent.Model != DefaultModel {
+		t.Errorf("personal model = %q, want the default", loaded.Agent.Model)
+	}
+}
+
+func TestProfile_InvalidName(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := SetProfile("../work"); err == nil {
+		t.Error("SetProfile(../work) = nil, want error")
+	}
+	t.Setenv("MYCLAW_PROFILE", "a/b")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig with MYCLAW_PROFILE=a/b = nil, want error")
+	}
+}
+
 func TestLoadConfig_InvalidJSON(t *testing.T) {
 	tmpDir := t.TempDir()
 	origHome := os.Getenv("HOME")
@@ -318,3 +449,134 @@ func TestLoadConfig_WeComEnvOverrides(t *testing.T) {
 		t.Errorf("wecom receiveId = %q, want wecom-receive-id", cfg.Channels.WeCom.ReceiveID)
 	}
 }
+
+func TestUpdateConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("MYCLAW_API_KEY", "env-key")
+
+	if err := UpdateConfig(func(cfg *Config) error {
+		cfg.Skills.Disabled = []string{"noisy"}
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateConfig error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".myclaw", "config.json"))
+	if err != nil {
+		t.Fatalf("read saved config: %v", err)
+	}
+	if strings.Contains(string(data), "env-key") {
+		t.Error("environment secret written to config file")
+	}
+	cfg, _ := LoadConfig()
+	if len(cfg.Skills.Disabled) != 1 || cfg.Skills.Disabled[0] != "noisy" || cfg.Provider.APIKey != "env-key" {
+		t.Errorf("loaded config = %+v", cfg)
+	}
+
+	if err := UpdateConfig(func(cfg *Config) error { return os.ErrInvalid }); err == nil {
+		t.Error("expected update error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Provider.APIKey = "sk-test"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+
+	cfg.Provider.Type = "mistral"
+	cfg.Provider.APIKey = ""
+	cfg.Queue.Overflow = "drop"
+	cfg.Sessions = SessionsConfig{MaxRuntimes: -1, RuntimeIdleMinutes: -5}
+	cfg.ResponseCache.TTLMinutes = -1
+	cfg.Gateway = GatewayConfig{Port: 70000, DrainTimeout: -1}
+	cfg.Cluster = ClusterConfig{Enabled: true, StatePath: "/shared/state.db", RedisURL: "redis://db:6379"}
+	cfg.Permissions.Default = "sometimes"
+	cfg.Redaction.Patterns = []RedactionPattern{{Name: "ssn", Pattern: "[0-9"}}
+	cfg.Tools.Search.Backend = "serpapi"
+	cfg.Tools.Fetch.Deny = []string{"https://example.com/"}
+	cfg.Calendar = CalendarConfig{Provider: "caldav", Timezone: "Mars/Olympus"}
+	cfg.Mail = MailConfig{Provider: "gmail", Send: "sometimes"}
+	cfg.Feeds.Feeds = []FeedConfig{{URL: "example.com/feed.xml"}}
+	cfg.GitHub.Write = "sometimes"
+	cfg.Webhooks = []WebhookConfig{{URL: "https://n8n.example.com/hook"}, {URL: "n8n.example.com/hook"}}
+	cfg.Notify = map[string]NotifyConfig{"phone": {Type: "ntfy", URL: "ntfy.sh/claw"}, "ipad": {Type: "pushover", Token: "t"}, "pager": {Type: "gotify"}}
+	cfg.Heartbeat.Deliver = []string{"notify:phone", "notify:watch"}
+	cfg.Media.STT = STTConfig{Provider: "whispercpp"}
+	cfg.Media.TTS = TTSConfig{Provider: "command"}
+	cfg.Media.Images.Provider = "midjourney"
+	cfg.KB.MinScore = 1.5
+	cfg.MCP.Servers = []MCPServer{{Name: "files", Spec: "stdio://a"}, {Name: "files", Spec: "stdio://b"}, {Name: "my files", Spec: "stdio://c"}, {}}
+	cfg.Channels.Telegram.VoiceReplies = VoiceReplyConfig{Mode: "always", Speed: 9}
+	cfg.Channels.Telegram.Groups = "some"
+	cfg.Channels.IMessage.Enabled = true
+	cfg.Channels.Email.Enabled = true
+	cfg.Channels.XMPP = XMPPConfig{Enabled: true, JID: "example.org"}
+	cfg.Channels.Mattermost = MattermostConfig{Enabled: true, URL: "chat.example.org", Token: "t"}
+	cfg.Channels.RocketChat = RocketChatConfig{Enabled: true, URL: "https://chat.example.org", Token: "t"}
+	cfg.Channels.WeCom.Apps = []WeComAppConfig{{AgentID: 1000002, Secret: "s", Token: "t"}, {AgentID: 1000002}}
+	cfg.HomeAssistant = HomeAssistantConfig{Provider: "rest", URL: "homeassistant.local:8123", Entities: []string{"kitchen"}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"provider.type", "provider.apiKey", "queue.overflow", "sessions.maxRuntimes", "sessions.runtimeIdleMinutes", "responseCache.ttlMinutes", "gateway.port", "gateway.drainTimeout", "cluster: set redisUrl or statePath", "permissions.default", "redaction.patterns[0]", "tools.search.apiKey", "tools.fetch domain", "calendar.caldav.url", "calendar.timezone", "mail.gmail.clientId", "mail.send", "feeds.feeds[0].url", "feeds.deliver", "webhooks[1].url", "notify.phone.url", "notify.ipad: pushover needs token and user", "notify.pager.type", "heartbeat.deliver \"notify:watch\"", "channels.telegram.groups", "channels.email.allowFrom", "channels.imessage.allowFrom", "channels.xmpp.jid", "channels.xmpp.password", "channels.mattermost.url", "channels.rocketchat.userId", "channels.wecom.corpId", "channels.wecom.apps[0].encodingAESKey", "channels.wecom.apps[1]: agentId 1000002 is used twice", "channels.wecom.apps[1].secret", "channels.wecom.apps[1].token", "github.write", "homeAssistant.url", "homeAssistant.token", "homeAssistant.entities \"kitchen\"", "media.stt.model", "media.tts.command", "media.images.provider", "kb.minScore", "mcp.servers[1]: name \"files\" is used twice", "mcp.servers[2]: name", "mcp.servers[3]: no spec", "channels.telegram.voiceReplies.mode", "channels.telegram.voiceReplies.speed", "channels.telegram.voiceReplies needs media.tts.provider openai"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %s in %v", want, err)
+		}
+	}
+}
+
+func TestLoadConfig_SearchKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("BRAVE_API_KEY", "")
+	t.Setenv("SERPAPI_API_KEY", "serp-env")
+	cfgDir := filepath.Join(tmpDir, ".myclaw")
+	os.MkdirAll(cfgDir, 0755)
+
+	os.WriteFile(filepath.Join(cfgDir, "config.json"), []byte(`{"tools":{"braveApiKey":"brave-file"}}`), 0644)
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if s := cfg.Tools.Search; s.Backend != "brave" || s.APIKey != "brave-file" {
+		t.Errorf("tools.braveApiKey should select brave, got %+v", s)
+	}
+
+	os.WriteFile(filepath.Join(cfgDir, "config.json"), []byte(`{"tools":{"search":{"backend":"serpapi"}}}`), 0644)
+	if cfg, err = LoadConfig(); err != nil || cfg.Tools.Search.APIKey != "serp-env" {
+		t.Errorf("SERPAPI_API_KEY should fill tools.search.apiKey, got %+v, %v", cfg.Tools.Search, err)
+	}
+}
+
+func TestMCPServer_JSON(t *testing.T) {
+	var cfg MCPConfig
+	data := `{"servers":["stdio://npx -y server-memory",{"name":"docs","spec":"https+stream://mcp.example.com/mcp"}]}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := []MCPServer{{Spec: "stdio://npx -y server-memory"}, {Name: "docs", Spec: "https+stream://mcp.example.com/mcp"}}
+	if len(cfg.Servers) != 2 || cfg.Servers[0] != want[0] || cfg.Servers[1] != want[1] {
+		t.Fatalf("servers = %+v", cfg.Servers)
+	}
+	out, err := json.Marshal(cfg)
+	if err != nil || string(out) != data {
+		t.Errorf("marshal = %s, %v", out, err)
+	}
+
+	if got := cfg.Specs(); len(got) != 2 || got[1] != want[1].Spec {
+		t.Errorf("Specs = %q", got)
+	}
+	if s, ok := cfg.Server("docs"); !ok || s != want[1] {
+		t.Errorf("Server(docs) = %+v, %v", s, ok)
+	}
+	if s, ok := cfg.Server(want[0].Spec); !ok || s.Label() != want[0].Spec {
+		t.Errorf("Server(spec) = %+v, %v", s, ok)
+	}
+	if _, ok := cfg.Server("missing"); ok {
+		t.Error("found a missing server")
+	}
+}
@@ -0,0 +1,564 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// configChange is one setting UpdateConfig writes: value at path, or the
+// removal of path when value is nil.
+type configChange struct {
+	path  []string
+	value *yaml.Node
+}
+
+// configNode returns cfg in its JSON form as a mapping node, which keeps
+// the order of the fields.
+func configNode(cfg *Config) (*yaml.Node, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc.Content[0], nil
+}
+
+// diffConfig returns the changes that turn the mapping before into after.
+// Mappings are compared key by key; anything else, lists included, is
+// replaced whole. A null is a removal, since the file has no use for it.
+func diffConfig(before, after *yaml.Node, path []string) []configChange {
+	var changes []configChange
+	for i := 0; i+1 < len(after.Content); i += 2 {
+		key, b := after.Content[i].Value, after.Content[i+1]
+		sub := append(path[:len(path):len(path)], key)
+		a := mappingValue(before, key)
+		switch {
+		case a != nil && a.Kind == yaml.MappingNode && b.Kind == yaml.MappingNode:
+			changes = append(changes, diffConfig(a, b, sub)...)
+		case b.Tag == "!!null":
+			if a != nil && a.Tag != "!!null" {
+				changes = append(changes, configChange{path: sub})
+			}
+		case a == nil || !sameNode(a, b):
+			changes = append(changes, configChange{path: sub, value: b})
+		}
+	}
+	for i := 0; i+1 < len(before.Content); i += 2 {
+		key := before.Content[i].Value
+		if mappingValue(after, key) == nil && before.Content[i+1].Tag != "!!null" {
+			changes = append(changes, configChange{path: append(path[:len(path):len(path)], key)})
+		}
+	}
+	return changes
+}
+
+// mappingIndex returns the index of key's node in the mapping n, or -1.
+func mappingIndex(n *yaml.Node, key string) int {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if i := mappingIndex(n, key); i >= 0 {
+		return n.Content[i+1]
+	}
+	return nil
+}
+
+func sameNode(a, b *yaml.Node) bool {
+	if a.Kind != b.Kind || a.Tag != b.Tag || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !sameNode(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// editConfig applies changes to data, the config file at path, touching
+// nothing else: comments, key order and settings left at their defaults
+// stay as they were.
+func editConfig(path string, data []byte, changes []configChange) ([]byte, error) {
+	var out []byte
+	var err error
+	if strings.ToLower(filepath.Ext(path)) == ".toml" {
+		out, err = editTOML(data, changes)
+	} else {
+		out, err = editYAML(data, changes, isJSON(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Never write a file LoadConfig cannot read back.
+	if _, err := toJSON(path, out); err != nil {
+		return nil, fmt.Errorf("edited config does not parse: %w", err)
+	}
+	return out, nil
+}
+
+// editYAML edits a YAML or, since JSON is YAML, a JSON file through a
+// yaml.Node, which keeps the key order and the comments.
+func editYAML(data []byte, changes []configChange, asJSON bool) ([]byte, error) {
+	var doc yaml.Node
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file is not a mapping")
+	}
+	for _, c := range changes {
+		value := c.value
+		if value != nil && !asJSON {
+			value = copyNode(value)
+			plainStyle(value)
+		}
+		setNode(root, c.path, value)
+	}
+
+	var buf bytes.Buffer
+	if asJSON {
+		var compact bytes.Buffer
+		if err := writeJSONNode(&compact, root); err != nil {
+			return nil, err
+		}
+		if err := json.Indent(&buf, compact.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		if len(data) == 0 || bytes.HasSuffix(data, []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	}
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// setNode sets path below the mapping m to value, or removes it when value
+// is nil. A replaced value keeps the comments of the old one.
+func setNode(m *yaml.Node, path []string, value *yaml.Node) {
+	for i, key := range path {
+		idx := mappingIndex(m, key)
+		if i == len(path)-1 {
+			switch {
+			case value == nil && idx >= 0:
+				m.Content = slices.Delete(m.Content, idx, idx+2)
+			case value == nil:
+			case idx >= 0:
+				old := m.Content[idx+1]
+				value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+				m.Content[idx+1] = value
+			default:
+				m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+			}
+			return
+		}
+		if idx < 0 {
+			if value == nil {
+				return
+			}
+			child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+			m = child
+			continue
+		}
+		m = m.Content[idx+1]
+		if m.Kind != yaml.MappingNode {
+			*m = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+	}
+}
+
+func copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = copyNode(child)
+	}
+	return &c
+}
+
+// writeJSONNode writes the node, read from JSON, back as compact JSON in
+// the same key order.
+func writeJSONNode(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONString(buf, n.Content[i].Value); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeJSONNode(buf, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONNode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case "!!int", "!!float", "!!bool", "!!null":
+			buf.WriteString(n.Value)
+		default:
+			return writeJSONString(buf, n.Value)
+		}
+	default:
+		return fmt.Errorf("line %d: cannot write %s as JSON", n.Line, n.Tag)
+	}
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1) // Encode ends with a newline
+	return nil
+}
+
+// tomlSection is the root table of a TOML file or a [table] or [[table]]
+// there, up to the next header.
+type tomlSection struct {
+	path  []string
+	array bool
+	start int // start of the header line
+	end   int // start of the next header line
+	body  int // end of the header or the last key/value line, where keys go
+	keys  bool
+}
+
+// tomlEntry is a key = value line.
+type tomlEntry struct {
+	path    []string // from the root, through the section
+	section *tomlSection
+	start   int
+	end     int // end of the value, before any comment
+	keyEnd  int
+	inline  *yaml.Node // the value, when it is an inline table
+}
+
+// editTOML applies changes as edits to the text of a TOML file. Values are
+// replaced where they are; new keys go at the end of their table, and new
+// tables at the end of the file.
+func editTOML(data []byte, changes []configChange) ([]byte, error) {
+	root := &tomlSection{}
+	sections := []*tomlSection{root}
+	var entries []*tomlEntry
+	cur := root
+	var p unstable.Parser
+	p.Reset(data)
+	for p.NextExpression() {
+		e := p.Expression()
+		keys, start, end := tomlKeyParts(e)
+		switch e.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			line := lineStart(data, start)
+			cur.end = line
+			cur = &tomlSection{path: keys, array: e.Kind == unstable.ArrayTable, start: line, body: lineEnd(data, end)}
+			sections = append(sections, cur)
+		case unstable.KeyValue:
+			en := &tomlEntry{
+				path:    append(slices.Clone(cur.path), keys...),
+				section: cur,
+				start:   int(e.Raw.Offset),
+				end:     int(e.Raw.Offset + e.Raw.Length),
+				keyEnd:  end,
+			}
+			if v := e.Value(); v.Kind == unstable.InlineTable {
+				en.inline = tomlNode(v)
+			}
+			entries = append(entries, en)
+			cur.body = lineEnd(data, int(e.Raw.Offset+e.Raw.Length))
+			cur.keys = true
+		}
+	}
+	if err := p.Error(); err != nil {
+		return nil, err
+	}
+	cur.end = len(data)
+	if !root.keys {
+		// Top-level keys go above the first table, below any leading comments.
+		root.body = root.end
+	}
+
+	e := &tomlEditor{data: data}
+	rewritten := map[*tomlEntry]*yaml.Node{}
+	newTables := map[string]*strings.Builder{}
+	var newTableOrder []string
+	for _, c := range changes {
+		// A change inside an inline table rewrites the whole value.
+		if i := slices.IndexFunc(entries, func(en *tomlEntry) bool {
+			return en.inline != nil && len(en.path) < len(c.path) && hasPrefix(c.path, en.path)
+		}); i >= 0 {
+			en := entries[i]
+			if rewritten[en] == nil {
+				rewritten[en] = copyNode(en.inline)
+			}
+			setNode(rewritten[en], c.path[len(en.path):], c.value)
+			continue
+		}
+
+		inline := c.value != nil && c.value.Kind != yaml.MappingNode && !isTableArray(c.value)
+		if i := slices.IndexFunc(entries, func(en *tomlEntry) bool { return slices.Equal(en.path, c.path) }); i >= 0 && inline {
+			en := entries[i]
+			e.replace(en.start, en.end, string(data[en.start:en.keyEnd])+" = "+tomlValue(c.value))
+			continue
+		}
+
+		// Anything else of the old value goes, then the new one is added.
+		for _, en := range entries {
+			if hasPrefix(en.path, c.path) && !hasPrefix(en.section.path, c.path) {
+				e.remove(lineStart(data, en.start), lineEnd(data, en.end))
+			}
+		}
+		for _, s := range sections[1:] {
+			if hasPrefix(s.path, c.path) {
+				e.remove(trimBlank(data, s.start), trimComments(data, s.start, s.end))
+			}
+		}
+		if c.value == nil {
+			continue
+		}
+		if !inline {
+			var sb strings.Builder
+			if c.value.Kind == yaml.MappingNode {
+				writeTOMLTable(&sb, c.value, c.path, false)
+			} else {
+				for _, item := range c.value.Content {
+					fmt.Fprintf(&sb, "\n[[%s]]\n", tomlPath(c.path))
+					writeTOMLTable(&sb, item, c.path, true)
+				}
+			}
+			e.insert(len(data), sb.String())
+			continue
+		}
+
+		parent := c.path[:len(c.path)-1]
+		if s := tomlSectionFor(sections, entries, parent); s != nil {
+			e.insert(s.body, tomlPath(c.path[len(s.path):])+" = "+tomlValue(c.value)+"\n")
+			continue
+		}
+		name := tomlPath(parent)
+		if newTables[name] == nil {
+			newTables[name] = &strings.Builder{}
+			newTableOrder = append(newTableOrder, name)
+		}
+		fmt.Fprintf(newTables[name], "%s = %s\n", tomlKey(c.path[len(c.path)-1]), tomlValue(c.value))
+	}
+	for en, value := range rewritten {
+		e.replace(en.start, en.end, string(data[en.start:en.keyEnd])+" = "+tomlValue(value))
+	}
+	for _, name := range newTableOrder {
+		e.insert(len(data), "\n["+name+"]\n"+newTables[name].String())
+	}
+	return e.apply(), nil
+}
+
+// tomlSectionFor returns the section a new key of the table parent goes
+// in: the table's own, or one that already sets some of it with dotted
+// keys. It returns nil when the table needs a header of its own.
+func tomlSectionFor(sections []*tomlSection, entries []*tomlEntry, parent []string) *tomlSection {
+	if len(parent) == 0 {
+		return sections[0]
+	}
+	for _, s := range sections[1:] {
+		if !s.array && slices.Equal(s.path, parent) {
+			return s
+		}
+	}
+	for _, en := range entries {
+		if !en.section.array && len(en.section.path) < len(parent) && hasPrefix(en.path, parent) {
+			return en.section
+		}
+	}
+	return nil
+}
+
+// tomlNode returns an inline table or other value as a node, keeping the
+// order of the keys and the text of numbers.
+func tomlNode(n *unstable.Node) *yaml.Node {
+	switch n.Kind {
+	case unstable.InlineTable:
+		m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		it := n.Children()
+		for it.Next() {
+			kv := it.Node()
+			keys, _, _ := tomlKeyParts(kv)
+			setNode(m, keys, tomlNode(kv.Value()))
+		}
+		return m
+	case unstable.Array:
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		it := n.Children()
+		for it.Next() {
+			seq.Content = append(seq.Content, tomlNode(it.Node()))
+		}
+		return seq
+	case unstable.Integer:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: string(n.Data)}
+	case unstable.Float:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: string(n.Data)}
+	case unstable.Bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: string(n.Data)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(n.Data)}
+}
+
+// tomlKeyParts returns the key of a table header or key/value and the
+// byte range it spans.
+func tomlKeyParts(n *unstable.Node) (keys []string, start, end int) {
+	it := n.Key()
+	for it.Next() {
+		k := it.Node()
+		if len(keys) == 0 {
+			start = int(k.Raw.Offset)
+		}
+		keys = append(keys, string(k.Data))
+		end = int(k.Raw.Offset + k.Raw.Length)
+	}
+	return keys, start, end
+}
+
+func hasPrefix(path, prefix []string) bool {
+	return len(path) >= len(prefix) && slices.Equal(path[:len(prefix)], prefix)
+}
+
+func lineStart(data []byte, i int) int {
+	return bytes.LastIndexByte(data[:i], '\n') + 1
+}
+
+// lineEnd returns the start of the line after the one i is on.
+func lineEnd(data []byte, i int) int {
+	if j := bytes.IndexByte(data[i:], '\n'); j >= 0 {
+		return i + j + 1
+	}
+	return len(data)
+}
+
+// trimComments moves end back over the blank and comment lines before it,
+// which belong to what follows.
+func trimComments(data []byte, start, end int) int {
+	for end > start {
+		line := lineStart(data, max(end-1, start))
+		if line <= start {
+			break
+		}
+		text := strings.TrimSpace(string(data[line:end]))
+		if text != "" && !strings.HasPrefix(text, "#") {
+			break
+		}
+		end = line
+	}
+	return end
+}
+
+// trimBlank moves start back over the blank lines before it.
+func trimBlank(data []byte, start int) int {
+	for start > 0 {
+		line := lineStart(data, start-1)
+		if strings.TrimSpace(string(data[line:start])) != "" {
+			break
+		}
+		start = line
+	}
+	return start
+}
+
+// tomlEditor collects edits to a file's text and applies them together.
+type tomlEditor struct {
+	data  []byte
+	edits []tomlEdit
+}
+
+type tomlEdit struct {
+	start, end int
+	text       string
+}
+
+func (e *tomlEditor) replace(start, end int, text string) {
+	e.edits = append(e.edits, tomlEdit{start, end, text})
+}
+
+func (e *tomlEditor) remove(start, end int) {
+	for i, ed := range e.edits {
+		// Removals overlap when a table and its keys both go.
+		if ed.text == "" && start < ed.end && ed.start < end {
+			e.edits[i] = tomlEdit{start: min(start, ed.start), end: max(end, ed.end)}
+			return
+		}
+	}
+	e.edits = append(e.edits, tomlEdit{start: start, end: end})
+}
+
+func (e *tomlEditor) insert(at int, text string) {
+	if at == len(e.data) && at > 0 && e.data[at-1] != '\n' {
+		text = "\n" + text
+	}
+	e.edits = append(e.edits, tomlEdit{start: at, end: at, text: text})
+}
+
+func (e *tomlEditor) apply() []byte {
+	// From the end back, so offsets stay valid; inserts at the same place
+	// keep the order they were made in.
+	edits := slices.Clone(e.edits)
+	order := make([]int, len(edits))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if edits[order[a]].start != edits[order[b]].start {
+			return edits[order[a]].start > edits[order[b]].start
+		}
+		return order[a] > order[b]
+	})
+	out := slices.Clone(e.data)
+	for _, i := range order {
+		ed := edits[i]
+		out = slices.Concat(out[:ed.start], []byte(ed.text), out[ed.end:])
+	}
+	return out
+}
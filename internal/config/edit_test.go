@@ -0,0 +1,200 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateConfig_KeepsFile(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"config.json", `{
+  "agent": {
+    "model": "gpt-4o",
+    "maxTokens": 2048
+  },
+  "channels": {
+    "telegram": {"enabled": true, "allowFrom": ["123"]}
+  },
+  "hooks": {"preToolUse": [{"command": "old"}]}
+}
+`, `{
+  "agent": {
+    "model": "gpt-4.1",
+    "maxTokens": 2048
+  },
+  "channels": {
+    "telegram": {
+      "enabled": true
+    },
+    "slack": {
+      "enabled": true
+    }
+  },
+  "hooks": {
+    "preToolUse": [
+      {
+        "command": "a"
+      },
+      {
+        "command": "b",
+        "pattern": "Bash"
+      }
+    ]
+  },
+  "models": {
+    "aliases": {
+      "fast.model": "x"
+    }
+  }
+}
+`},
+		{"config.yaml", `# my config
+agent:
+  model: gpt-4o # the usual
+  maxTokens: 2048
+channels:
+  telegram:
+    enabled: true
+    allowFrom: ["123"]
+hooks:
+  preToolUse:
+    - command: old
+`, `# my config
+agent:
+  model: gpt-4.1 # the usual
+  maxTokens: 2048
+channels:
+  telegram:
+    enabled: true
+  slack:
+    enabled: true
+hooks:
+  preToolUse:
+    - command: a
+    - command: b
+      pattern: Bash
+models:
+  aliases:
+    fast.model: x
+`},
+		{"config.toml", `# my config
+[agent]
+model = "gpt-4o" # the usual
+maxTokens = 2048
+
+# chat apps
+[channels.telegram]
+enabled = true
+allowFrom = ["123"]
+
+[[hooks.preToolUse]]
+command = "old"
+
+# the end
+`, `# my config
+[agent]
+model = "gpt-4.1" # the usual
+maxTokens = 2048
+
+# chat apps
+[channels.telegram]
+enabled = true
+
+# the end
+
+[models.aliases]
+"fast.model" = "x"
+
+[[hooks.preToolUse]]
+command = "a"
+
+[[hooks.preToolUse]]
+command = "b"
+pattern = "Bash"
+
+[channels.slack]
+enabled = true
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeHomeConfig(t, tt.name, tt.content)
+			if err := UpdateConfig(func(cfg *Config) error {
+				cfg.Agent.Model = "gpt-4.1"
+				cfg.Channels.Telegram.AllowFrom = nil
+				cfg.Channels.Slack.Enabled = true
+				cfg.Models.Aliases = map[string]string{"fast.model": "x"}
+				cfg.Hooks.PreToolUse = []HookEntry{{Command: "a"}, {Command: "b", Pattern: "Bash"}}
+				return nil
+			}); err != nil {
+				t.Fatalf("UpdateConfig: %v", err)
+			}
+			if data, _ := os.ReadFile(path); string(data) != tt.want {
+				t.Errorf("file =\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateConfig_TOMLKeys(t *testing.T) {
+	path := writeHomeConfig(t, "config.toml", `# top
+agent = { model = "gpt-4o", maxTokens = 2_048 }
+
+[channels]
+telegram.enabled = true
+`)
+	if err := UpdateConfig(func(cfg *Config) error {
+		cfg.Agent.Model = "gpt-4.1"
+		cfg.Channels.Telegram.AllowFrom = []string{"123"}
+		cfg.Provider.Type = "openai"
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	want := `# top
+agent = {model = "gpt-4.1", maxTokens = 2_048}
+
+[channels]
+telegram.enabled = true
+telegram.allowFrom = ["123"]
+
+[provider]
+type = "openai"
+`
+	if data, _ := os.ReadFile(path); string(data) != want {
+		t.Errorf("file =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestUpdateConfig_Unchanged(t *testing.T) {
+	content := `{"agent": {"model": "gpt-4o"}}`
+	path := writeHomeConfig(t, "config.json", content)
+	if err := UpdateConfig(func(cfg *Config) error {
+		cfg.Agent.Model = "gpt-4o"
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("unchanged config was rewritten:\n%s", data)
+	}
+}
+
+// writeHomeConfig writes the named config file under a temporary HOME and
+// returns its path.
+func writeHomeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MYCLAW_API_KEY", "")
+	dir := filepath.Join(home, ".myclaw")
+	os.MkdirAll(dir, 0755)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
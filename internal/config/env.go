@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix starts the name of every environment variable that overrides
+// a config value.
+const EnvPrefix = "MYCLAW_"
+
+// EnvName returns the environment variable that overrides key: the
+// prefix, then each part of the key in upper snake case, so agent.maxTokens
+// is MYCLAW_AGENT_MAX_TOKENS.
+func EnvName(key string) string {
+	parts := splitKey(key)
+	for i, p := range parts {
+		parts[i] = snakeUpper(p)
+	}
+	return EnvPrefix + strings.Join(parts, "_")
+}
+
+// snakeUpper converts a camelCase name to UPPER_SNAKE_CASE, keeping runs
+// of capitals together: encodingAESKey is ENCODING_AES_KEY.
+func snakeUpper(s string) string {
+	r := []rune(s)
+	var sb strings.Builder
+	for i, c := range r {
+		if i > 0 && unicode.IsUpper(c) {
+			prev := r[i-1]
+			nextLower := i+1 < len(r) && unicode.IsLower(r[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToUpper(c))
+	}
+	return sb.String()
+}
+
+// applyEnv sets each value whose EnvName is in the environment. Every
+// field that is not a section of the config can be set; values are parsed
+// as Set parses them, so lists and maps are JSON or, for lists of strings,
+// comma-separated.
+func applyEnv(cfg *Config) error {
+	var errs []error
+	walkEnv(reflect.ValueOf(cfg).Elem(), nil, &errs)
+	return errors.Join(errs...)
+}
+
+func walkEnv(v reflect.Value, path []string, errs *[]error) {
+	t := v.Type()
+	for i := range t.NumField() {
+		name := jsonName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		key := append(path[:len(path):len(path)], name)
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct {
+			walkEnv(f, key, errs)
+			continue
+		}
+		env := EnvName(strings.Join(key, "."))
+		value, ok := os.LookupEnv(env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := parseValue(f, env, value); err != nil {
+			*errs = append(*errs, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"agent.model":                     "MYCLAW_AGENT_MODEL",
+		"agent.maxTokens":                 "MYCLAW_AGENT_MAX_TOKENS",
+		"channels.telegram.token":         "MYCLAW_CHANNELS_TELEGRAM_TOKEN",
+		"channels.wecom.encodingAESKey":   "MYCLAW_CHANNELS_WECOM_ENCODING_AES_KEY",
+		"provider.baseUrl":                "MYCLAW_PROVIDER_BASE_URL",
+		"tokenTracking.alertChatId":       "MYCLAW_TOKEN_TRACKING_ALERT_CHAT_ID",
+		"channels.whatsapp.phoneNumberId": "MYCLAW_CHANNELS_WHATSAPP_PHONE_NUMBER_ID",
+	}
+	for key, want := range tests {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%s) = %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestLoadConfig_KeyEnvOverrides(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "")
+	t.Setenv("MYCLAW_TELEGRAM_TOKEN", "")
+	t.Setenv("MYCLAW_AGENT_MODEL", "env-model")
+	t.Setenv("MYCLAW_AGENT_MAX_TOKENS", "1024")
+	t.Setenv("MYCLAW_CHANNELS_TELEGRAM_ENABLED", "true")
+	t.Setenv("MYCLAW_CHANNELS_TELEGRAM_TOKEN", "tg-token")
+	t.Setenv("MYCLAW_CHANNELS_TELEGRAM_ALLOW_FROM", "1,2")
+	t.Setenv("MYCLAW_MODELS_ALIASES", `{"fast": "haiku"}`)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Agent.Model != "env-model" || cfg.Agent.MaxTokens != 1024 {
+		t.Errorf("agent = %+v", cfg.Agent)
+	}
+	tg := cfg.Channels.Telegram
+	if !tg.Enabled || tg.Token != "tg-token" || !reflect.DeepEqual(tg.AllowFrom, []string{"1", "2"}) {
+		t.Errorf("telegram = %+v", tg)
+	}
+	if cfg.Models.Aliases["fast"] != "haiku" {
+		t.Errorf("aliases = %v", cfg.Models.Aliases)
+	}
+
+	// The older, shorter names win.
+	t.Setenv("MYCLAW_TELEGRAM_TOKEN", "short")
+	if cfg, _ = LoadConfig(); cfg.Channels.Telegram.Token != "short" {
+		t.Errorf("token = %q, want short", cfg.Channels.Telegram.Token)
+	}
+
+	t.Setenv("MYCLAW_AGENT_MAX_TOKENS", "lots")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "MYCLAW_AGENT_MAX_TOKENS") {
+		t.Errorf("LoadConfig with a bad value = %v", err)
+	}
+}
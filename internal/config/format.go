@@ -0,0 +1,239 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configExts are the config file formats, in the order ConfigPath looks
+// for them.
+var configExts = []string{".json", ".yaml", ".yml", ".toml"}
+
+// findConfig returns the config file named base with the first extension
+// that exists, or base.json when there is none.
+func findConfig(base string) string {
+	for _, ext := range configExts {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return base + ".json"
+}
+
+func isJSON(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext != ".yaml" && ext != ".yml" && ext != ".toml"
+}
+
+// toJSON converts a config file in the format its extension names to
+// JSON, so that every format decodes through the same json tags.
+func toJSON(path string, data []byte) ([]byte, error) {
+	var v any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		v = stringKeys(v)
+	case ".toml":
+		// Dates and times come back as types that encode to JSON strings.
+		var t map[string]any
+		if err := toml.Unmarshal(data, &t); err != nil {
+			var decode *toml.DecodeError
+			if errors.As(err, &decode) {
+				line, _ := decode.Position()
+				return nil, fmt.Errorf("line %d: %s", line, strings.TrimPrefix(decode.Error(), "toml: "))
+			}
+			return nil, err
+		}
+		v = t
+	default:
+		return data, nil
+	}
+	if v == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(v)
+}
+
+// stringKeys converts the map[any]any YAML uses for maps with non-string
+// keys, which JSON cannot encode.
+func stringKeys(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = stringKeys(e)
+		}
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = stringKeys(e)
+		}
+		return m
+	case []any:
+		for i, e := range v {
+			v[i] = stringKeys(e)
+		}
+	}
+	return v
+}
+
+// fromJSON converts JSON config data to the format path's extension
+// names, keeping the order of the keys.
+func fromJSON(path string, data []byte) ([]byte, error) {
+	if isJSON(path) {
+		return data, nil
+	}
+	// JSON is YAML, and a yaml.Node keeps the keys in order.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	root := doc.Content[0]
+	if strings.ToLower(filepath.Ext(path)) == ".toml" {
+		var sb strings.Builder
+		writeTOMLTable(&sb, root, nil, false)
+		return []byte(strings.TrimPrefix(sb.String(), "\n")), nil
+	}
+	plainStyle(root)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// plainStyle drops the flow style and quotes n has from being read as
+// JSON, so it is written as block YAML. The encoder still quotes strings
+// that would otherwise read as another type.
+func plainStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		plainStyle(c)
+	}
+}
+
+// writeTOMLTable writes the mapping n as the TOML table at path: a header
+// and its values, when it has any, then its tables and arrays of tables.
+// An item of an array of tables has its [[header]] written by the caller.
+func writeTOMLTable(sb *strings.Builder, n *yaml.Node, path []string, item bool) {
+	var values strings.Builder
+	var tables []int
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		v := n.Content[i+1]
+		switch {
+		case v.Tag == "!!null":
+		case v.Kind == yaml.MappingNode, isTableArray(v):
+			tables = append(tables, i)
+		default:
+			fmt.Fprintf(&values, "%s = %s\n", tomlKey(n.Content[i].Value), tomlValue(v))
+		}
+	}
+	if values.Len() > 0 {
+		if len(path) > 0 && !item {
+			fmt.Fprintf(sb, "\n[%s]\n", tomlPath(path))
+		}
+		sb.WriteString(values.String())
+	}
+	for _, i := range tables {
+		sub := append(path[:len(path):len(path)], n.Content[i].Value)
+		v := n.Content[i+1]
+		if v.Kind == yaml.MappingNode {
+			writeTOMLTable(sb, v, sub, false)
+			continue
+		}
+		for _, elem := range v.Content {
+			fmt.Fprintf(sb, "\n[[%s]]\n", tomlPath(sub))
+			writeTOMLTable(sb, elem, sub, true)
+		}
+	}
+}
+
+func isTableArray(n *yaml.Node) bool {
+	if n.Kind != yaml.SequenceNode || len(n.Content) == 0 {
+		return false
+	}
+	for _, item := range n.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+func tomlValue(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		items := make([]string, len(n.Content))
+		for i, item := range n.Content {
+			items[i] = tomlValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case yaml.MappingNode:
+		items := make([]string, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			items = append(items, tomlKey(n.Content[i].Value)+" = "+tomlValue(n.Content[i+1]))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	switch n.Tag {
+	case "!!int", "!!float", "!!bool":
+		return n.Value
+	}
+	return tomlString(n.Value)
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(k string) string {
+	if bareKey.MatchString(k) {
+		return k
+	}
+	return tomlString(k)
+}
+
+func tomlPath(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = tomlKey(k)
+	}
+	return strings.Join(quoted, ".")
+}
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_YAMLAndTOML(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+provider:
+  type: openai
+agent:
+  model: gpt-4o
+  maxTokens: 2048
+channels:
+  telegram:
+    enabled: true
+    allowFrom: ["123"]
+`,
+		"config.toml": `
+[provider]
+type = "openai"
+
+[agent]
+model = "gpt-4o"
+maxTokens = 2048
+
+[channels.telegram]
+enabled = true
+allowFrom = ["123"]
+`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("MYCLAW_API_KEY", "")
+			dir := filepath.Join(home, ".myclaw")
+			os.MkdirAll(dir, 0755)
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := ConfigPath(); filepath.Base(got) != name {
+				t.Fatalf("ConfigPath() = %s, want %s", got, name)
+			}
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.Provider.Type != "openai" || cfg.Agent.Model != "gpt-4o" || cfg.Agent.MaxTokens != 2048 {
+				t.Errorf("cfg = %+v %+v", cfg.Provider, cfg.Agent)
+			}
+			if !cfg.Channels.Telegram.Enabled || len(cfg.Channels.Telegram.AllowFrom) != 1 {
+				t.Errorf("telegram = %+v", cfg.Channels.Telegram)
+			}
+			if cfg.Agent.Temperature != DefaultTemperature {
+				t.Errorf("temperature = %v, want the default", cfg.Agent.Temperature)
+			}
+
+			// Saving keeps the format and the values.
+			if err := UpdateConfig(func(cfg *Config) error {
+				cfg.Agent.Model = "gpt-4.1"
+				cfg.Models.Aliases = map[string]string{"fast.model": "x"}
+				cfg.Hooks.PreToolUse = []HookEntry{{Command: "a"}, {Command: "b", Pattern: "Bash"}}
+				return nil
+			}); err != nil {
+				t.Fatalf("UpdateConfig: %v", err)
+			}
+			data, _ := os.ReadFile(filepath.Join(dir, name))
+			if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+				t.Errorf("%s was rewritten as JSON:\n%s", name, data)
+			}
+			cfg, err = LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig after save: %v\n%s", err, data)
+			}
+			if cfg.Agent.Model != "gpt-4.1" || cfg.Agent.MaxTokens != 2048 || cfg.Models.Aliases["fast.model"] != "x" {
+				t.Errorf("after save: agent %+v, aliases %v\n%s", cfg.Agent, cfg.Models.Aliases, data)
+			}
+			if hooks := cfg.Hooks.PreToolUse; len(hooks) != 2 || hooks[1].Pattern != "Bash" {
+				t.Errorf("hooks after save = %+v\n%s", hooks, data)
+			}
+			if warnings, err := CheckFile(ConfigPath()); err != nil || len(warnings) != 0 {
+				t.Errorf("CheckFile after save = %v, %v", warnings, err)
+			}
+		})
+	}
+}
+
+func TestCheckFile_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("agent:\n  modle: x\n  maxTokens: lots\n"), 0644)
+	_, err := CheckFile(path)
+	if err == nil || !strings.Contains(err.Error(), "agent.maxTokens: got string, want integer") {
+		t.Errorf("CheckFile = %v", err)
+	}
+
+	os.WriteFile(path, []byte("agent:\n  modle: x\n"), 0644)
+	warnings, err := CheckFile(path)
+	if err != nil || len(warnings) != 1 || !strings.Contains(warnings[0], "did you mean agent.model?") {
+		t.Errorf("CheckFile = %v, %v", warnings, err)
+	}
+
+	os.WriteFile(path, []byte("agent: [\n"), 0644)
+	if _, err := CheckFile(path); err == nil || !strings.Contains(err.Error(), "line") {
+		t.Errorf("CheckFile syntax error = %v", err)
+	}
+}
@@ -0,0 +1,342 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Get returns the value at key, a dotted path of JSON field names such as
+// "agent.model" or "channels.telegram.allowFrom". Map entries and list
+// items are addressed by their key or index: "models.aliases.fast".
+func Get(cfg *Config, key string) (any, error) {
+	v := reflect.ValueOf(cfg).Elem()
+	parts := splitKey(key)
+	for i, part := range parts {
+		path := strings.Join(parts[:i+1], ".")
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := fieldByTag(v, part)
+			if !ok {
+				return nil, unknownKeyError(v.Type(), parts[:i], part)
+			}
+			v = f
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(part).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return nil, fmt.Errorf("%s is not set", path)
+			}
+		case reflect.Slice:
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 || n >= v.Len() {
+				return nil, fmt.Errorf("%s: no item %s in a list of %d", path, part, v.Len())
+			}
+			v = v.Index(n)
+		default:
+			return nil, fmt.Errorf("%s: %s is a %s, not an object", path, strings.Join(parts[:i], "."), typeName(v.Type()))
+		}
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil, fmt.Errorf("%s is not set", path)
+			}
+			v = v.Elem()
+		}
+	}
+	return v.Interface(), nil
+}
+
+// Set parses value for the field at key and stores it in cfg. Strings are
+// taken as they are, lists of strings may be comma-separated, and anything
+// else is read as JSON: "8192", "true", `{"fast": "claude-haiku-4-5"}`.
+func Set(cfg *Config, key, value string) error {
+	parts := splitKey(key)
+	if len(parts) == 0 {
+		return errors.New("empty key")
+	}
+	return setValue(reflect.ValueOf(cfg).Elem(), parts, 0, value)
+}
+
+func setValue(v reflect.Value, parts []string, i int, value string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if i == len(parts) {
+		return parseValue(v, strings.Join(parts, "."), value)
+	}
+	part, path := parts[i], strings.Join(parts[:i+1], ".")
+	switch v.Kind() {
+	case reflect.Struct:
+		f, ok := fieldByTag(v, part)
+		if !ok {
+			return unknownKeyError(v.Type(), parts[:i], part)
+		}
+		return setValue(f, parts, i+1, value)
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		k := reflect.ValueOf(part).Convert(v.Type().Key())
+		// Map entries are not addressable, so change a copy and put it back.
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if old := v.MapIndex(k); old.IsValid() {
+			elem.Set(old)
+		}
+		if err := setValue(elem, parts, i+1, value); err != nil {
+			return err
+		}
+		v.SetMapIndex(k, elem)
+		return nil
+	case reflect.Slice:
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n >= v.Len() {
+			return fmt.Errorf("%s: no item %s in a list of %d", path, part, v.Len())
+		}
+		return setValue(v.Index(n), parts, i+1, value)
+	default:
+		return fmt.Errorf("%s: %s is a %s, not an object", path, strings.Join(parts[:i], "."), typeName(v.Type()))
+	}
+}
+
+func parseValue(v reflect.Value, key, value string) error {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "["):
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(items)
+		return nil
+	}
+	ptr := reflect.New(v.Type())
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(ptr.Interface()); err != nil {
+		return fmt.Errorf("%s: %q is not a valid %s", key, value, typeName(v.Type()))
+	}
+	v.Set(ptr.Elem())
+	return nil
+}
+
+// CheckFile reads the config file at path, in any format LoadConfig
+// reads, and reports what is wrong with it. Keys the config does not
+// know, usually typos, come back as warnings; a file that does not parse
+// is an error naming the line or field at fault.
+func CheckFile(path string) (warnings []string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isJSON(path) {
+		if data, err = toJSON(path, data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, jsonError(path, data, err)
+	}
+	if err := json.Unmarshal(data, DefaultConfig()); err != nil {
+		return nil, jsonError(path, data, err)
+	}
+	unknownKeys(reflect.TypeFor[Config](), raw, nil, &warnings)
+	return warnings, nil
+}
+
+// jsonError turns a decoding error into one that names the line and
+// column, or the field and the type it wants.
+func jsonError(path string, data []byte, err error) error {
+	if !isJSON(path) {
+		// Offsets are into the converted JSON, not the file.
+		var typ *json.UnmarshalTypeError
+		if errors.As(err, &typ) && typ.Field != "" {
+			return fmt.Errorf("%s: %s: got %s, want %s", path, typ.Field, typ.Value, typeName(typ.Type))
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		line, col := position(data, syntax.Offset)
+		return fmt.Errorf("%s:%d:%d: %v", path, line, col, syntax)
+	}
+	var typ *json.UnmarshalTypeError
+	if errors.As(err, &typ) {
+		line, col := position(data, typ.Offset)
+		field := typ.Field
+		if field == "" {
+			field = "config"
+		}
+		return fmt.Errorf("%s:%d:%d: %s: got %s, want %s", path, line, col, field, typ.Value, typeName(typ.Type))
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// position converts a byte offset in data to a 1-based line and column.
+func position(data []byte, offset int64) (line, col int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// unknownKeys walks the decoded JSON in raw alongside t and appends a
+// warning for each object key t has no field for.
+func unknownKeys(t reflect.Type, raw any, path []string, warnings *[]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return
+		}
+		for _, k := range slices.Sorted(maps.Keys(obj)) {
+			f, ok := fieldTypeByTag(t, k)
+			if !ok {
+				*warnings = append(*warnings, unknownKeyError(t, path, k).Error())
+				continue
+			}
+			unknownKeys(f, obj[k], append(path, k), warnings)
+		}
+	case reflect.Map:
+		if obj, ok := raw.(map[string]any); ok {
+			for k, v := range obj {
+				unknownKeys(t.Elem(), v, append(path, k), warnings)
+			}
+		}
+	case reflect.Slice:
+		if list, ok := raw.([]any); ok {
+			for i, v := range list {
+				unknownKeys(t.Elem(), v, append(path, strconv.Itoa(i)), warnings)
+			}
+		}
+	}
+}
+
+func unknownKeyError(t reflect.Type, parent []string, key string) error {
+	name := strings.Join(append(slices.Clone(parent), key), ".")
+	if hint := closestField(t, key); hint != "" {
+		return fmt.Errorf("unknown key %s (did you mean %s?)", name, strings.Join(append(slices.Clone(parent), hint), "."))
+	}
+	return fmt.Errorf("unknown key %s", name)
+}
+
+// closestField returns the field of t whose JSON name is within two edits
+// of key, ignoring case, or "".
+func closestField(t reflect.Type, key string) string {
+	best, bestDist := "", 3
+	for i := range t.NumField() {
+		name := jsonName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		if d := editDistance(strings.ToLower(name), strings.ToLower(key)); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// fieldByTag finds the field of v named name in config.json. Like
+// encoding/json, it ignores case.
+func fieldByTag(v reflect.Value, name string) (reflect.Value, bool) {
+	for i := range v.NumField() {
+		if strings.EqualFold(jsonName(v.Type().Field(i)), name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func fieldTypeByTag(t reflect.Type, name string) (reflect.Type, bool) {
+	for i := range t.NumField() {
+		if strings.EqualFold(jsonName(t.Field(i)), name) {
+			return t.Field(i).Type, true
+		}
+	}
+	return nil, false
+}
+
+// jsonName returns the name f has in config.json, or "" when it has none.
+func jsonName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+func splitKey(key string) []string {
+	var parts []string
+	for _, p := range strings.Split(key, ".") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list of " + typeName(t.Elem())
+	case reflect.Map:
+		return "map of " + typeName(t.Elem())
+	case reflect.Pointer:
+		return typeName(t.Elem())
+	}
+	return "object"
+}
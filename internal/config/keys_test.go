@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetSet(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := DefaultConfig()
+
+	sets := []struct{ key, value string }{
+		{"agent.model", "claude-opus-4-1"},
+		{"agent.maxTokens", "4096"},
+		{"agent.temperature", "0.2"},
+		{"channels.telegram.enabled", "true"},
+		{"channels.telegram.allowFrom", "123, 456"},
+		{"models.aliases.fast", "claude-haiku-4-5"},
+		{"Agent.MaxToolIterations", "5"},
+	}
+	for _, s := range sets {
+		if err := Set(cfg, s.key, s.value); err != nil {
+			t.Fatalf("Set(%s, %s): %v", s.key, s.value, err)
+		}
+	}
+
+	if cfg.Agent.Model != "claude-opus-4-1" || cfg.Agent.MaxTokens != 4096 || cfg.Agent.Temperature != 0.2 {
+		t.Errorf("agent = %+v", cfg.Agent)
+	}
+	if cfg.Agent.MaxToolIterations != 5 {
+		t.Errorf("maxToolIterations = %d, want 5 set with different case", cfg.Agent.MaxToolIterations)
+	}
+	if !cfg.Channels.Telegram.Enabled || !reflect.DeepEqual(cfg.Channels.Telegram.AllowFrom, []string{"123", "456"}) {
+		t.Errorf("telegram = %+v", cfg.Channels.Telegram)
+	}
+
+	got, err := Get(cfg, "models.aliases.fast")
+	if err != nil || got != "claude-haiku-4-5" {
+		t.Errorf("Get(models.aliases.fast) = %v, %v", got, err)
+	}
+	got, err = Get(cfg, "agent.maxTokens")
+	if err != nil || got != 4096 {
+		t.Errorf("Get(agent.maxTokens) = %v, %v", got, err)
+	}
+	if _, err := Get(cfg, "agent"); err != nil {
+		t.Errorf("Get(agent): %v", err)
+	}
+}
+
+func TestGetSet_Errors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := DefaultConfig()
+
+	tests := []struct {
+		key, value, want string
+	}{
+		{"agent.modle", "x", "did you mean agent.model?"},
+		{"agent.maxTokens", "many", `"many" is not a valid integer`},
+		{"agent.model.name", "x", "agent.model is a string, not an object"},
+		{"nothing", "x", "unknown key nothing"},
+	}
+	for _, tt := range tests {
+		err := Set(cfg, tt.key, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Set(%s, %s) = %v, want error containing %q", tt.key, tt.value, err, tt.want)
+		}
+	}
+	if _, err := Get(cfg, "models.aliases.none"); err == nil {
+		t.Error("Get(models.aliases.none) = nil error")
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	path := filepath.Join(dir, "config.json")
+
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"agent": {"modle": "x", "model": "y"}, "chanels": {}, "models": {"aliases": {"fast": "z"}}}`)
+	warnings, err := CheckFile(path)
+	if err != nil {
+		t.Fatalf("CheckFile: %v", err)
+	}
+	want := []string{
+		"unknown key agent.modle (did you mean agent.model?)",
+		"unknown key chanels (did you mean channels?)",
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+
+	write("{\n  \"agent\": {\n    \"maxTokens\": \"lots\"\n  }\n}")
+	_, err = CheckFile(path)
+	if err == nil || !strings.Contains(err.Error(), "agent.maxTokens: got string, want integer") || !strings.Contains(err.Error(), ":3:") {
+		t.Errorf("CheckFile type error = %v", err)
+	}
+
+	write("{\n  \"agent\": {,\n}")
+	_, err = CheckFile(path)
+	if err == nil || !strings.Contains(err.Error(), "config.json:2:") {
+		t.Errorf("CheckFile syntax error = %v", err)
+	}
+}
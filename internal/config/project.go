@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ProjectDirName is the directory a git repository keeps project-local
+// instructions, skills and memory in.
+const ProjectDirName = ".myclaw"
+
+// FindProject looks for a .myclaw directory in dir and its parents, up to
+// the root of the git repository dir is in, and returns its path. Outside a
+// git repository, or when the repository has none, it returns "".
+// ~/.myclaw itself is never a project.
+func FindProject(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	global := filepath.Clean(ConfigDir())
+	var found string
+	for {
+		candidate := filepath.Join(dir, ProjectDirName)
+		if found == "" && candidate != global {
+			if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+				found = candidate
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return found
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// applyProject records the project the working directory is in, unless
+// MYCLAW_NO_PROJECT is set.
+func applyProject(cfg *Config) {
+	if os.Getenv("MYCLAW_NO_PROJECT") != "" {
+		return
+	}
+	if wd, err := os.Getwd(); err == nil {
+		cfg.Project = FindProject(wd)
+	}
+}
+
+// MemoryWorkspace is the directory whose memory/ holds memory: the
+// project's, when it has a memory directory, or else the workspace.
+func (c *Config) MemoryWorkspace() string {
+	if c.Project != "" {
+		if info, err := os.Stat(filepath.Join(c.Project, "memory")); err == nil && info.IsDir() {
+			return c.Project
+		}
+	}
+	return c.Agent.Workspace
+}
+
+// ProjectSkillsDir is the project's skills directory, or "" outside a
+// project.
+func (c *Config) ProjectSkillsDir() string {
+	if c.Project == "" {
+		return ""
+	}
+	return filepath.Join(c.Project, "skills")
+}
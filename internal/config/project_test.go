@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProject(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MYCLAW_PROFILE", "")
+
+	repo := filepath.Join(home, "src", "repo")
+	deep := filepath.Join(repo, "pkg", "deep")
+	for _, dir := range []string{filepath.Join(repo, ".git"), deep, filepath.Join(home, ".myclaw")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ~/.myclaw is the global config dir, not a project, and the walk
+	// stops at the repository root anyway.
+	if got := FindProject(deep); got != "" {
+		t.Errorf("FindProject without .myclaw = %q, want none", got)
+	}
+
+	project := filepath.Join(repo, ProjectDirName)
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProject(deep); got != project {
+		t.Errorf("FindProject(%s) = %q, want %q", deep, got, project)
+	}
+
+	nested := filepath.Join(repo, "pkg", ProjectDirName)
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProject(deep); got != nested {
+		t.Errorf("FindProject should prefer the nearest .myclaw, got %q", got)
+	}
+
+	outside := filepath.Join(home, "notes")
+	if err := os.MkdirAll(filepath.Join(outside, ProjectDirName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProject(outside); got != "" {
+		t.Errorf("FindProject outside a git repository = %q, want none", got)
+	}
+}
+
+func TestLoadConfig_Project(t *testing.T) {
+	// The working directory comes back with symlinks resolved.
+	home, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("MYCLAW_PROFILE", "")
+	t.Setenv("MYCLAW_WORKSPACE", "")
+	t.Setenv("MYCLAW_NO_PROJECT", "")
+
+	repo := filepath.Join(home, "repo")
+	project := filepath.Join(repo, ProjectDirName)
+	for _, dir := range []string{filepath.Join(repo, ".git"), project} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(repo)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Project != project {
+		t.Errorf("Project = %q, want %q", cfg.Project, project)
+	}
+	if got := cfg.ProjectSkillsDir(); got != filepath.Join(project, "skills") {
+		t.Errorf("ProjectSkillsDir() = %q", got)
+	}
+	if got := cfg.MemoryWorkspace(); got != cfg.Agent.Workspace {
+		t.Errorf("MemoryWorkspace() without project memory = %q, want the workspace", got)
+	}
+	if err := os.MkdirAll(filepath.Join(project, "memory"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.MemoryWorkspace(); got != project {
+		t.Errorf("MemoryWorkspace() = %q, want %q", got, project)
+	}
+
+	t.Setenv("MYCLAW_NO_PROJECT", "1")
+	if cfg, err := LoadConfig(); err != nil || cfg.Project != "" {
+		t.Errorf("with MYCLAW_NO_PROJECT, Project = %v, %v, want none", cfg, err)
+	}
+}
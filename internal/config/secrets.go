@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/stellarlinkco/myclaw/internal/secrets"
+)
+
+// keyring is where keyring: values are looked up. Tests replace it.
+var keyring = secrets.Keyring
+
+// resolveSecrets replaces each "keyring:<name>" string in cfg, at any
+// depth, with the secret of that name.
+func resolveSecrets(cfg *Config, store func() secrets.Store) error {
+	r := secretResolver{store: store}
+	return r.resolve(reflect.ValueOf(cfg).Elem())
+}
+
+type secretResolver struct {
+	store  func() secrets.Store
+	opened secrets.Store
+}
+
+func (r *secretResolver) resolve(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		name, ok := secrets.Name(v.String())
+		if !ok {
+			return nil
+		}
+		if r.opened == nil {
+			r.opened = r.store()
+		}
+		secret, err := r.opened.Get(name)
+		if errors.Is(err, secrets.ErrNotFound) {
+			return fmt.Errorf("%s: %w (run: myclaw secret set %s)", secrets.Ref(name), err, name)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", secrets.Ref(name), err)
+		}
+		v.SetString(secret)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return r.resolve(v.Elem())
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if err := r.resolve(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if err := r.resolve(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Map values can't be set in place, so resolve a copy of each.
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			if err := r.resolve(value); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/secrets"
+)
+
+// mapStore is a keyring held in memory.
+type mapStore map[string]string
+
+func (m mapStore) Get(name string) (string, error) {
+	v, ok := m[name]
+	if !ok {
+		return "", secrets.ErrNotFound
+	}
+	return v, nil
+}
+
+func (m mapStore) Set(name, value string) error { m[name] = value; return nil }
+func (m mapStore) Delete(name string) error     { delete(m, name); return nil }
+
+func useKeyring(t *testing.T, store mapStore) {
+	t.Helper()
+	orig := keyring
+	keyring = func() secrets.Store { return store }
+	t.Cleanup(func() { keyring = orig })
+}
+
+func TestLoadConfig_KeyringSecrets(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("ANTHROPIC_AUTH_TOKEN", "")
+	t.Setenv("MYCLAW_TELEGRAM_TOKEN", "from-env")
+	useKeyring(t, mapStore{"provider.apiKey": "sk-from-keyring", "honeycomb": "hc-key"})
+
+	testCfg := map[string]any{
+		"provider": map[string]any{"apiKey": "keyring:provider.apiKey"},
+		"channels": map[string]any{"telegram": map[string]any{"token": "keyring:missing"}},
+		"tracing":  map[string]any{"headers": map[string]any{"x-honeycomb-team": "keyring:honeycomb"}},
+	}
+	data, _ := json.Marshal(testCfg)
+	os.MkdirAll(ConfigDir(), 0755)
+	os.WriteFile(ConfigPath(), data, 0644)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	if cfg.Provider.APIKey != "sk-from-keyring" {
+		t.Errorf("apiKey = %q", cfg.Provider.APIKey)
+	}
+	// The environment replaced the missing secret before it was looked up.
+	if cfg.Channels.Telegram.Token != "from-env" {
+		t.Errorf("telegram token = %q", cfg.Channels.Telegram.Token)
+	}
+	if got := cfg.Tracing.Headers["x-honeycomb-team"]; got != "hc-key" {
+		t.Errorf("tracing header = %q", got)
+	}
+
+	// UpdateConfig keeps the reference rather than writing the secret.
+	if err := UpdateConfig(func(cfg *Config) error { return nil }); err != nil {
+		t.Fatalf("UpdateConfig error: %v", err)
+	}
+	data, _ = os.ReadFile(ConfigPath())
+	if strings.Contains(string(data), "sk-from-keyring") || !strings.Contains(string(data), "keyring:provider.apiKey") {
+		t.Errorf("saved config = %s", data)
+	}
+}
+
+func TestLoadConfig_KeyringSecretMissing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLAW_API_KEY", "")
+	useKeyring(t, mapStore{})
+
+	os.MkdirAll(ConfigDir(), 0755)
+	os.WriteFile(filepath.Join(ConfigDir(), "config.json"), []byte(`{"provider":{"apiKey":"keyring:provider.apiKey"}}`), 0644)
+
+	_, err := LoadConfig()
+	if !errors.Is(err, secrets.ErrNotFound) {
+		t.Fatalf("LoadConfig error = %v, want ErrNotFound", err)
+	}
+	if !strings.Contains(err.Error(), "myclaw secret set provider.apiKey") {
+		t.Errorf("error %q does not say how to fix it", err)
+	}
+}
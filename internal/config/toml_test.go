@@ -0,0 +1,92 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTOMLToJSON(t *testing.T) {
+	doc := `# myclaw
+title = "a \"quoted\" \u00e9"
+path = 'C:\dir'
+count = 1_000
+hex = 0x1f
+ratio = 0.5
+on = true
+when = 1979-05-27T07:32:00Z
+day = 1979-05-27
+list = [
+  "a",  # first
+  "b",
+]
+inline = { x = 1, y.z = "w" }
+body = """
+one \
+  two"""
+
+[agent]
+model = "claude"
+
+[channels.telegram]
+allowFrom = ["1", "2"]
+
+[[hooks.preToolUse]]
+matcher = "Bash"
+
+[[hooks.preToolUse]]
+matcher = "Write"
+`
+	data, err := toJSON("config.toml", []byte(doc))
+	if err != nil {
+		t.Fatalf("toJSON: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	want := map[string]any{
+		"title":  `a "quoted" é`,
+		"path":   `C:\dir`,
+		"count":  float64(1000),
+		"hex":    float64(31),
+		"ratio":  0.5,
+		"on":     true,
+		"when":   "1979-05-27T07:32:00Z",
+		"day":    "1979-05-27",
+		"list":   []any{"a", "b"},
+		"inline": map[string]any{"x": float64(1), "y": map[string]any{"z": "w"}},
+		"body":   "one two",
+		"agent":  map[string]any{"model": "claude"},
+		"channels": map[string]any{
+			"telegram": map[string]any{"allowFrom": []any{"1", "2"}},
+		},
+		"hooks": map[string]any{
+			"preToolUse": []any{
+				map[string]any{"matcher": "Bash"},
+				map[string]any{"matcher": "Write"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toJSON =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestTOMLToJSON_Errors(t *testing.T) {
+	tests := []struct{ doc, want string }{
+		{"a = 1\na = 2", "key a is already defined"},
+		{"[a]\n[a]", "table a already exists"},
+		{"a = 1\nb = \"open", "basic string not terminated"},
+		{"a = 1\n\nb = 1 2", "line 3:"},
+		{"a = 1\nb = nope", "line 2:"},
+		{"a = 1\n[a.b]", "expected a to be a table"},
+	}
+	for _, tt := range tests {
+		_, err := toJSON("config.toml", []byte(tt.doc))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("toJSON(%q) = %v, want error containing %q", tt.doc, err, tt.want)
+		}
+	}
+}
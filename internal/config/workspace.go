@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultWorkspace names the workspace agent.workspace points at. Other
+// workspaces live side by side under WorkspacesDir, each with its own
+// memory, skills and sessions.
+const DefaultWorkspace = "default"
+
+// workspace is the workspace chosen with SetWorkspace.
+var workspace string
+
+// SetWorkspace selects a workspace, by name or by path, for the rest of the
+// process. An empty value leaves the choice to MYCLAW_WORKSPACE and then to
+// the workspace saved with SwitchWorkspace.
+func SetWorkspace(nameOrPath string) error {
+	if nameOrPath != "" && !isWorkspacePath(nameOrPath) && !validProfile.MatchString(nameOrPath) {
+		return fmt.Errorf("workspace %q: use letters, digits, '-' and '_', or a path", nameOrPath)
+	}
+	workspace = nameOrPath
+	return nil
+}
+
+// ValidateWorkspaceName reports whether name can name a workspace under
+// WorkspacesDir.
+func ValidateWorkspaceName(name string) error {
+	if !validProfile.MatchString(name) {
+		return fmt.Errorf("workspace name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Workspace returns the active workspace and what chose it: "flag",
+// "env", "switch", or "config" for the default.
+func Workspace() (name, source string) {
+	if workspace != "" {
+		return workspace, "flag"
+	}
+	if name := os.Getenv("MYCLAW_WORKSPACE"); name != "" {
+		return name, "env"
+	}
+	if data, err := os.ReadFile(activeWorkspaceFile()); err == nil {
+		if name := strings.TrimSpace(string(data)); name != "" {
+			return name, "switch"
+		}
+	}
+	return DefaultWorkspace, "config"
+}
+
+// WorkspacesDir is where the active profile keeps its named workspaces.
+func WorkspacesDir() string {
+	return filepath.Join(DataDir(), "workspaces")
+}
+
+// WorkspacePath returns the directory of a named workspace, or the path
+// given instead of a name. The default workspace has no fixed path; it is
+// agent.workspace.
+func WorkspacePath(nameOrPath string) string {
+	if isWorkspacePath(nameOrPath) {
+		return expandHome(nameOrPath)
+	}
+	return filepath.Join(WorkspacesDir(), nameOrPath)
+}
+
+// ListWorkspaces returns the names of the named workspaces, sorted.
+func ListWorkspaces() ([]string, error) {
+	entries, err := os.ReadDir(WorkspacesDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read workspaces: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && validProfile.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SwitchWorkspace makes name the workspace later commands use when neither
+// --workspace nor MYCLAW_WORKSPACE says otherwise. Switching to the
+// default forgets the choice.
+func SwitchWorkspace(name string) error {
+	if name == DefaultWorkspace {
+		if err := os.Remove(activeWorkspaceFile()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("switch workspace: %w", err)
+		}
+		return nil
+	}
+	if err := checkWorkspace(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(DataDir(), 0755); err != nil {
+		return fmt.Errorf("switch workspace: %w", err)
+	}
+	if err := os.WriteFile(activeWorkspaceFile(), []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("switch workspace: %w", err)
+	}
+	return nil
+}
+
+// applyWorkspace points cfg at a workspace. A named workspace must
+// exist, so a typo does not start an empty one.
+func applyWorkspace(cfg *Config, nameOrPath string) error {
+	if nameOrPath == "" || nameOrPath == DefaultWorkspace {
+		return nil
+	}
+	if err := checkWorkspace(nameOrPath); err != nil {
+		return err
+	}
+	cfg.Agent.Workspace = WorkspacePath(nameOrPath)
+	return nil
+}
+
+func checkWorkspace(nameOrPath string) error {
+	if isWorkspacePath(nameOrPath) {
+		return nil
+	}
+	if err := ValidateWorkspaceName(nameOrPath); err != nil {
+		return err
+	}
+	if info, err := os.Stat(WorkspacePath(nameOrPath)); err != nil || !info.IsDir() {
+		return fmt.Errorf("workspace %q does not exist; create it with 'myclaw workspace create %s' or pick another with 'myclaw workspace switch'", nameOrPath, nameOrPath)
+	}
+	return nil
+}
+
+func activeWorkspaceFile() string {
+	return filepath.Join(DataDir(), "active-workspace")
+}
+
+// isWorkspacePath reports whether a --workspace value is a path rather
+// than a name.
+func isWorkspacePath(s string) bool {
+	return strings.ContainsAny(s, `/\`) || s == "." || s == ".." || strings.HasPrefix(s, "~")
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("MYCLAW_PROFILE", "")
+	t.Setenv("MYCLAW_WORKSPACE", "")
+	t.Cleanup(func() { SetWorkspace("") })
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if want := filepath.Join(tmpDir, ".myclaw", "workspace"); cfg.Agent.Workspace != want {
+		t.Errorf("default workspace = %q, want %q", cfg.Agent.Workspace, want)
+	}
+	if name, source := Workspace(); name != DefaultWorkspace || source != "config" {
+		t.Errorf("Workspace() = %q, %q, want default, config", name, source)
+	}
+
+	if err := SwitchWorkspace("work"); err == nil {
+		t.Error("SwitchWorkspace to a missing workspace = nil, want error")
+	}
+	work := WorkspacePath("work")
+	if want := filepath.Join(tmpDir, ".myclaw", "workspaces", "work"); work != want {
+		t.Errorf("WorkspacePath(work) = %q, want %q", work, want)
+	}
+	for _, name := range []string{"work", "blog"} {
+		if err := os.MkdirAll(WorkspacePath(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if names, err := ListWorkspaces(); err != nil || !reflect.DeepEqual(names, []string{"blog", "work"}) {
+		t.Errorf("ListWorkspaces() = %v, %v, want [blog work]", names, err)
+	}
+
+	if err := SwitchWorkspace("work"); err != nil {
+		t.Fatalf("SwitchWorkspace: %v", err)
+	}
+	if name, source := Workspace(); name != "work" || source != "switch" {
+		t.Errorf("Workspace() = %q, %q, want work, switch", name, source)
+	}
+	if cfg, err := LoadConfig(); err != nil || cfg.Agent.Workspace != work {
+		t.Errorf("LoadConfig() workspace = %v, %v, want %q", cfg, err, work)
+	}
+
+	t.Setenv("MYCLAW_WORKSPACE", "blog")
+	if name, source := Workspace(); name != "blog" || source != "env" {
+		t.Errorf("Workspace() = %q, %q, want blog, env", name, source)
+	}
+	if err := SetWorkspace(DefaultWorkspace); err != nil {
+		t.Fatalf("SetWorkspace: %v", err)
+	}
+	if cfg, err := LoadConfig(); err != nil || cfg.Agent.Workspace != filepath.Join(tmpDir, ".myclaw", "workspace") {
+		t.Errorf("--workspace default should win over the environment, got %v, %v", cfg, err)
+	}
+
+	if err := SetWorkspace("missing"); err != nil {
+		t.Fatalf("SetWorkspace: %v", err)
+	}
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "myclaw workspace create missing") {
+		t.Errorf("LoadConfig with a missing workspace = %v, want a hint to create it", err)
+	}
+
+	dir := t.TempDir()
+	if err := SetWorkspace(dir); err != nil {
+		t.Fatalf("SetWorkspace(path): %v", err)
+	}
+	if cfg, err := LoadConfig(); err != nil || cfg.Agent.Workspace != dir {
+		t.Errorf("LoadConfig() with a path = %v, %v, want %q", cfg, err, dir)
+	}
+
+	SetWorkspace("")
+	t.Setenv("MYCLAW_WORKSPACE", "")
+	if err := SwitchWorkspace(DefaultWorkspace); err != nil {
+		t.Fatalf("SwitchWorkspace(default): %v", err)
+	}
+	if name, _ := Workspace(); name != DefaultWorkspace {
+		t.Errorf("after switching back, Workspace() = %q, want default", name)
+	}
+}
+
+func TestWorkspace_PerProfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("MYCLAW_WORKSPACE", "")
+	t.Cleanup(func() { SetProfile("") })
+
+	if err := SetProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := WorkspacesDir(), filepath.Join(tmpDir, ".myclaw", "profiles", "work", "workspaces"); got != want {
+		t.Errorf("WorkspacesDir() = %q, want %q", got, want)
+	}
+}
+
+func TestSetWorkspace_InvalidName(t *testing.T) {
+	t.Cleanup(func() { SetWorkspace("") })
+	if err := SetWorkspace("a b"); err == nil {
+		t.Error("SetWorkspace(a b) = nil, want error")
+	}
+	if err := ValidateWorkspaceName("../x"); err == nil {
+		t.Error("ValidateWorkspaceName(../x) = nil, want error")
+	}
+}
@@ -0,0 +1,88 @@
+package cron
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ResponseCache keeps the output of background runs by a hash of what went
+// into them, so that a job asking the same of the same model, with the same
+// context, within the TTL is given its last answer instead of a new run.
+type ResponseCache struct {
+	path string
+	ttl  time.Duration
+	now  func() time.Time
+	mu   sync.Mutex
+}
+
+type cachedResponse struct {
+	Output string    `json:"output"`
+	Time   time.Time `json:"time"`
+}
+
+// CachePath is where a workspace keeps cached job responses.
+func CachePath(workspace string) string {
+	return filepath.Join(workspace, "cron", "responses.json")
+}
+
+func NewResponseCache(path string, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{path: path, ttl: ttl, now: time.Now}
+}
+
+// CacheKey hashes the inputs of a run: the model, the system prompt and
+// the prompt, say.
+func CacheKey(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the output cached under key, if it is younger than the TTL.
+func (c *ResponseCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.load()[key]
+	if !ok || c.now().Sub(entry.Time) >= c.ttl {
+		return "", false
+	}
+	return entry.Output, true
+}
+
+// Put caches output under key, dropping entries past the TTL.
+func (c *ResponseCache) Put(key, output string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := c.load()
+	now := c.now()
+	for k, entry := range entries {
+		if now.Sub(entry.Time) >= c.ttl {
+			delete(entries, k)
+		}
+	}
+	entries[key] = cachedResponse{Output: output, Time: now}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+// load reads the cache file; a missing or unreadable one is empty.
+func (c *ResponseCache) load() map[string]cachedResponse {
+	entries := make(map[string]cachedResponse)
+	if data, err := os.ReadFile(c.path); err == nil {
+		_ = json.Unmarshal(data, &entries)
+	}
+	return entries
+}
@@ -0,0 +1,44 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	path := CachePath(t.TempDir())
+	now := time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC)
+	c := NewResponseCache(path, 25*time.Hour)
+	c.now = func() time.Time { return now }
+
+	key := CacheKey("claude-sonnet", "system", "summarize the week")
+	if key == CacheKey("claude-sonnet", "system", "summarize the weekend") || key == CacheKey("claude-sonnetsystem", "", "summarize the week") {
+		t.Fatal("different inputs share a key")
+	}
+	if _, ok := c.Get(key); ok {
+		t.Fatal("empty cache has an entry")
+	}
+	if err := c.Put(key, "A quiet week."); err != nil {
+		t.Fatal(err)
+	}
+
+	// A day later, from a new cache on the same file, as after a restart.
+	now = now.Add(24 * time.Hour)
+	c = NewResponseCache(path, 25*time.Hour)
+	c.now = func() time.Time { return now }
+	if got, ok := c.Get(key); !ok || got != "A quiet week." {
+		t.Errorf("Get = %q, %v; want the cached answer", got, ok)
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok := c.Get(key); ok {
+		t.Error("entry past the TTL is returned")
+	}
+	other := CacheKey("claude-sonnet", "system", "water the plants")
+	if err := c.Put(other, "Done."); err != nil {
+		t.Fatal(err)
+	}
+	if entries := c.load(); len(entries) != 1 {
+		t.Errorf("entries = %v, want the expired one pruned", entries)
+	}
+}
@@ -154,10 +154,10 @@ func TestService_ExecuteJob_WithHandler(t *testing.T) {
 
 	var executed bool
 	var receivedJob CronJob
-	s.OnJob = func(job CronJob) (string, error) {
+	s.OnJob = func(job CronJob) (Result, error) {
 		executed = true
 		receivedJob = job
-		return "success", nil
+		return Result{Output: "success"}, nil
 	}
 
 	job, _ := s.AddJob("exec-test", Schedule{Kind: "every", EveryMs: 1000}, Payload{Message: "test msg"})
@@ -196,8 +196,8 @@ func TestService_ExecuteJob_HandlerError(t *testing.T) {
 	tmpDir := t.TempDir()
 	s := NewService(filepath.Join(tmpDir, "jobs.json"))
 
-	s.OnJob = func(job CronJob) (string, error) {
-		return "", fmt.Errorf("handler error")
+	s.OnJob = func(job CronJob) (Result, error) {
+		return Result{}, fmt.Errorf("handler error")
 	}
 
 	job, _ := s.AddJob("error-test", Schedule{Kind: "every", EveryMs: 1000}, Payload{Message: "x"})
@@ -216,8 +216,8 @@ func TestService_ExecuteJob_DeleteAfterRun(t *testing.T) {
 	tmpDir := t.TempDir()
 	s := NewService(filepath.Join(tmpDir, "jobs.json"))
 
-	s.OnJob = func(job CronJob) (string, error) {
-		return "done", nil
+	s.OnJob = func(job CronJob) (Result, error) {
+		return Result{Output: "done"}, nil
 	}
 
 	// Add job with DeleteAfterRun set
@@ -239,9 +239,9 @@ func TestService_TickLoop_EverySchedule(t *testing.T) {
 	s := NewService(filepath.Join(tmpDir, "jobs.json"))
 
 	executeCount := 0
-	s.OnJob = func(job CronJob) (string, error) {
+	s.OnJob = func(job CronJob) (Result, error) {
 		executeCount++
-		return "tick", nil
+		return Result{Output: "tick"}, nil
 	}
 
 	// Add job with 100ms interval, with LastRunAtMs in the past
@@ -263,14 +263,40 @@ func TestService_TickLoop_EverySchedule(t *testing.T) {
 	}
 }
 
+func TestService_TickLoop_ShouldRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := NewService(filepath.Join(tmpDir, "jobs.json"))
+
+	executeCount := 0
+	s.OnJob = func(job CronJob) (Result, error) {
+		executeCount++
+		return Result{Output: "tick"}, nil
+	}
+	s.ShouldRun = func() bool { return false }
+
+	job := NewCronJob("standby", Schedule{Kind: "every", EveryMs: 100}, Payload{Message: "tick"})
+	job.State.LastRunAtMs = time.Now().UnixMilli() - 200
+	s.jobs = append(s.jobs, job)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	time.Sleep(1500 * time.Millisecond)
+	cancel()
+	s.Stop()
+
+	if executeCount != 0 {
+		t.Errorf("executeCount = %d, want 0 while ShouldRun is false", executeCount)
+	}
+}
+
 func TestService_TickLoop_AtSchedule(t *testing.T) {
 	tmpDir := t.TempDir()
 	s := NewService(filepath.Join(tmpDir, "jobs.json"))
 
 	executed := false
-	s.OnJob = func(job CronJob) (string, error) {
+	s.OnJob = func(job CronJob) (Result, error) {
 		executed = true
-		return "at-job", nil
+		return Result{Output: "at-job"}, nil
 	}
 
 	// Add "at" job scheduled for now
@@ -358,8 +384,8 @@ func TestService_RegisterCronJob_Success(t *testing.T) {
 	os.WriteFile(storePath, data, 0644)
 
 	s := NewService(storePath)
-	s.OnJob = func(job CronJob) (string, error) {
-		return "done", nil
+	s.OnJob = func(job CronJob) (Result, error) {
+		return Result{Output: "done"}, nil
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -407,6 +433,44 @@ func TestService_RemoveJob_WithCron(t *testing.T) {
 	s.Stop()
 }
 
+func TestService_Reload(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "jobs.json")
+	s := NewService(storePath)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	s.AddJob("hourly", Schedule{Kind: "cron", Expr: "0 0 * * * *"}, Payload{Message: "x"})
+
+	// Another process adds a job and disables the first.
+	other := NewService(storePath)
+	if err := other.Load(); err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	other.AddJob("daily", Schedule{Kind: "cron", Expr: "0 0 9 * * *"}, Payload{Message: "y"})
+	other.EnableJob(other.ListJobs()[0].ID, false)
+
+	if err := s.Reload(); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	if jobs := s.ListJobs(); len(jobs) != 2 {
+		t.Fatalf("expected 2 jobs after reload, got %d", len(jobs))
+	}
+	if len(s.entryMap) != 1 {
+		t.Errorf("expected 1 entry in entryMap, got %d", len(s.entryMap))
+	}
+
+	// A broken store leaves the jobs alone.
+	os.WriteFile(storePath, []byte("{"), 0644)
+	if err := s.Reload(); err == nil {
+		t.Error("expected error for a broken store")
+	}
+	if jobs := s.ListJobs(); len(jobs) != 2 {
+		t.Errorf("expected 2 jobs after a failed reload, got %d", len(jobs))
+	}
+}
+
 func TestTruncate(t *testing.T) {
 	tests := []struct {
 		input string
@@ -0,0 +1,173 @@
+package cron
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultTemplate formats a job's output for channels and webhooks.
+const DefaultTemplate = "{{result}}"
+
+// DefaultFileTemplate formats a job's output appended to a file.
+const DefaultFileTemplate = "## {{title}} ({{timestamp}})\n\n{{result}}\n\n"
+
+// webhookClient posts job output to webhook targets.
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+// Target is a place a job's output is sent. Exactly one of Channel,
+// Webhook, File and Notify is set.
+type Target struct {
+	Channel string `json:"channel,omitempty"` // e.g. "telegram" or "feishu"
+	To      string `json:"to,omitempty"`      // chat or user ID on Channel
+	Webhook string `json:"webhook,omitempty"` // URL the output is POSTed to
+	File    string `json:"file,omitempty"`    // workspace-relative file the output is appended to
+	Notify  string `json:"notify,omitempty"`  // name of a push notification sink in the config
+	// Template formats the output. {{title}}, {{timestamp}}, {{result}}
+	// and {{job}} are replaced.
+	Template string `json:"template,omitempty"`
+}
+
+// ParseTarget reads a target given as "channel:to", "webhook:URL",
+// "file:path" or "notify:name".
+func ParseTarget(spec string) (Target, error) {
+	kind, rest, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || rest == "" {
+		return Target{}, fmt.Errorf("invalid target %q: want channel:to, webhook:URL, file:path or notify:name", spec)
+	}
+	var t Target
+	switch kind {
+	case "webhook":
+		t.Webhook = rest
+	case "file":
+		t.File = rest
+	case "notify":
+		t.Notify = rest
+	default:
+		t.Channel, t.To = kind, rest
+	}
+	return t, t.Validate()
+}
+
+// Validate checks that t names exactly one usable destination.
+func (t Target) Validate() error {
+	set := 0
+	for _, s := range []string{t.Channel, t.Webhook, t.File, t.Notify} {
+		if s != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("target must have exactly one of channel, webhook, file and notify")
+	}
+	switch {
+	case t.Channel != "" && t.To == "":
+		return fmt.Errorf("target %s: missing recipient", t.Channel)
+	case t.Webhook != "":
+		u, err := url.Parse(t.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("target webhook %q: must be an http(s) URL", t.Webhook)
+		}
+	case t.File != "":
+		if filepath.IsAbs(t.File) || !filepath.IsLocal(t.File) {
+			return fmt.Errorf("target file %q: must be a path inside the workspace", t.File)
+		}
+	}
+	return nil
+}
+
+// String renders t as ParseTarget reads it.
+func (t Target) String() string {
+	switch {
+	case t.Webhook != "":
+		return "webhook:" + t.Webhook
+	case t.File != "":
+		return "file:" + t.File
+	case t.Notify != "":
+		return "notify:" + t.Notify
+	}
+	return t.Channel + ":" + t.To
+}
+
+// Render formats the output of job, finished at at, for t.
+func (t Target) Render(job CronJob, result string, at time.Time) string {
+	tmpl := t.Template
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+		if t.File != "" {
+			tmpl = DefaultFileTemplate
+		}
+	}
+	return strings.NewReplacer(
+		"{{title}}", job.Name,
+		"{{job}}", job.ID,
+		"{{timestamp}}", at.Format("2006-01-02 15:04"),
+		"{{result}}", strings.TrimSpace(result),
+	).Replace(tmpl)
+}
+
+// Targets returns where the output of job goes: its targets, plus the
+// channel of the older deliver/channel/to fields when those are set.
+func (j CronJob) Targets() []Target {
+	targets := j.Payload.Targets
+	if j.Payload.Deliver && j.Payload.Channel != "" {
+		targets = append([]Target{{Channel: j.Payload.Channel, To: j.Payload.To}}, targets...)
+	}
+	return targets
+}
+
+// AppendFile appends text to t.File under workspace.
+func (t Target) AppendFile(workspace, text string) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	path := filepath.Join(workspace, t.File)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// PostWebhook sends the rendered text to t.Webhook as JSON, with the job's
+// name, ID, timestamp and raw result alongside.
+func (t Target) PostWebhook(ctx context.Context, job CronJob, result, text string, at time.Time) error {
+	body, err := json.Marshal(map[string]any{
+		"title":     job.Name,
+		"job":       job.ID,
+		"timestamp": at.Format(time.RFC3339),
+		"result":    result,
+		"text":      text,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", t.Webhook, resp.Status)
+	}
+	return nil
+}
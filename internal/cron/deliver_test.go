@@ -0,0 +1,103 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTarget(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]Target{
+		"telegram:12345":                  {Channel: "telegram", To: "12345"},
+		"feishu:ou_abc":                   {Channel: "feishu", To: "ou_abc"},
+		"webhook:https://example.com/h?x": {Webhook: "https://example.com/h?x"},
+		"file:reports/inbox.md":           {File: "reports/inbox.md"},
+		"notify:phone":                    {Notify: "phone"},
+	}
+	for spec, want := range cases {
+		got, err := ParseTarget(spec)
+		if err != nil || got != want {
+			t.Errorf("ParseTarget(%q) = %+v, %v", spec, got, err)
+		}
+		if got.String() != spec {
+			t.Errorf("String() = %q, want %q", got.String(), spec)
+		}
+	}
+	for _, spec := range []string{"telegram", "telegram:", "webhook:ftp://x", "file:/etc/passwd", "file:../up.md", "notify:"} {
+		if _, err := ParseTarget(spec); err == nil {
+			t.Errorf("ParseTarget(%q): expected error", spec)
+		}
+	}
+}
+
+func TestTarget_Render(t *testing.T) {
+	t.Parallel()
+
+	job := CronJob{ID: "j1", Name: "Inbox"}
+	at := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	if got := (Target{Channel: "telegram"}).Render(job, " 3 new mails \n", at); got != "3 new mails" {
+		t.Errorf("default = %q", got)
+	}
+	if got := (Target{File: "x.md"}).Render(job, "3 new mails", at); got != "## Inbox (2026-10-16 09:00)\n\n3 new mails\n\n" {
+		t.Errorf("file default = %q", got)
+	}
+	tmpl := Target{Channel: "slack", Template: "*{{title}}* {{timestamp}} [{{job}}]\n{{result}}"}
+	if got := tmpl.Render(job, "{{title}}", at); got != "*Inbox* 2026-10-16 09:00 [j1]\n{{title}}" {
+		t.Errorf("custom = %q", got)
+	}
+}
+
+func TestCronJob_Targets(t *testing.T) {
+	t.Parallel()
+
+	job := CronJob{Payload: Payload{Deliver: true, Channel: "telegram", To: "1", Targets: []Target{{File: "a.md"}}}}
+	targets := job.Targets()
+	if len(targets) != 2 || targets[0].Channel != "telegram" || targets[1].File != "a.md" {
+		t.Errorf("targets = %+v", targets)
+	}
+	job.Payload.Deliver = false
+	if targets := job.Targets(); len(targets) != 1 {
+		t.Errorf("targets = %+v", targets)
+	}
+}
+
+func TestTarget_Deliver(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	target := Target{File: "reports/inbox.md"}
+	target.AppendFile(workspace, "one\n")
+	if err := target.AppendFile(workspace, "two\n"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "reports", "inbox.md")); string(data) != "one\ntwo\n" {
+		t.Errorf("file = %q", data)
+	}
+
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		if got["job"] == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	hook := Target{Webhook: srv.URL}
+	at := time.Now()
+	if err := hook.PostWebhook(context.Background(), CronJob{ID: "j1", Name: "Inbox"}, "raw", "text", at); err != nil {
+		t.Fatal(err)
+	}
+	if got["title"] != "Inbox" || got["result"] != "raw" || got["text"] != "text" || got["timestamp"] != at.Format(time.RFC3339) {
+		t.Errorf("webhook body = %v", got)
+	}
+	if err := hook.PostWebhook(context.Background(), CronJob{ID: "fail"}, "", "", at); err == nil {
+		t.Error("expected error for a 500 response")
+	}
+}
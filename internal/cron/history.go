@@ -0,0 +1,164 @@
+package cron
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// maxHistoryBytes is the size at which the history file is trimmed.
+	maxHistoryBytes = 1 << 20
+	// keepRunsPerJob is how many runs of each job survive a trim.
+	keepRunsPerJob = 100
+	// snippetLen caps the output kept per run.
+	snippetLen = 500
+)
+
+// Result is what a job run produced.
+type Result struct {
+	Output       string
+	InputTokens  int
+	OutputTokens int
+	// Cached is set when Output is a cached answer, not a new run.
+	Cached bool
+}
+
+// Run is one execution of a job.
+type Run struct {
+	JobID        string    `json:"jobId"`
+	JobName      string    `json:"jobName"`
+	StartedAt    time.Time `json:"startedAt"`
+	DurationMs   int64     `json:"durationMs"`
+	Status       string    `json:"status"` // "ok" | "error"
+	InputTokens  int       `json:"inputTokens,omitempty"`
+	OutputTokens int       `json:"outputTokens,omitempty"`
+	Output       string    `json:"output,omitempty"`
+	Cached       bool      `json:"cached,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// History is an append-only log of job runs, one JSON object per line.
+type History struct {
+	path string
+	mu   sync.Mutex
+}
+
+// HistoryPath is where a workspace keeps its job run history.
+func HistoryPath(workspace string) string {
+	return filepath.Join(workspace, "cron", "history.jsonl")
+}
+
+func NewHistory(path string) *History {
+	return &History{path: path}
+}
+
+// Append records run, trimming the log once it outgrows maxHistoryBytes.
+func (h *History) Append(run Run) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(h.path); err == nil && info.Size() > maxHistoryBytes {
+		return h.trim()
+	}
+	return nil
+}
+
+// Runs returns the runs of job id, newest first. An empty id returns the
+// runs of every job. limit <= 0 means no limit.
+func (h *History) Runs(id string, limit int) ([]Run, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	all, err := h.read()
+	if err != nil {
+		return nil, err
+	}
+	var runs []Run
+	for i := len(all) - 1; i >= 0; i-- {
+		if id != "" && all[i].JobID != id {
+			continue
+		}
+		runs = append(runs, all[i])
+		if limit > 0 && len(runs) == limit {
+			break
+		}
+	}
+	return runs, nil
+}
+
+func (h *History) read() ([]Run, error) {
+	f, err := os.Open(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxHistoryBytes)
+	for scanner.Scan() {
+		var run Run
+		// Skip lines cut short by a crash mid-write.
+		if json.Unmarshal(scanner.Bytes(), &run) == nil {
+			runs = append(runs, run)
+		}
+	}
+	return runs, scanner.Err()
+}
+
+// trim rewrites the log with the newest keepRunsPerJob runs of each job.
+func (h *History) trim() error {
+	all, err := h.read()
+	if err != nil {
+		return err
+	}
+	count := make(map[string]int)
+	keep := make([]bool, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		if count[all[i].JobID] < keepRunsPerJob {
+			count[all[i].JobID]++
+			keep[i] = true
+		}
+	}
+	var buf []byte
+	for i, run := range all {
+		if !keep[i] {
+			continue
+		}
+		data, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, data...), '\n')
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
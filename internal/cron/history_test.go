@@ -0,0 +1,87 @@
+package cron
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_ExecuteJob_RecordsHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := NewService(filepath.Join(tmpDir, "jobs.json"))
+	s.History = NewHistory(HistoryPath(tmpDir))
+	fail := false
+	s.OnJob = func(job CronJob) (Result, error) {
+		if fail {
+			return Result{}, fmt.Errorf("inbox unreachable")
+		}
+		return Result{Output: strings.Repeat("é", 400), InputTokens: 120, OutputTokens: 30}, nil
+	}
+
+	job, _ := s.AddJob("nightly", Schedule{Kind: "every", EveryMs: 1000}, Payload{Message: "x"})
+	s.executeJob(*job)
+	fail = true
+	s.executeJob(*job)
+
+	runs, err := s.History.Runs(job.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].Status != "error" || runs[0].Error != "inbox unreachable" {
+		t.Fatalf("runs = %+v", runs)
+	}
+	if ok := runs[1]; ok.Status != "ok" || ok.InputTokens != 120 || ok.OutputTokens != 30 || ok.JobName != "nightly" ||
+		len(ok.Output) > snippetLen+3 || !strings.HasSuffix(ok.Output, "é...") {
+		t.Errorf("ok run = %+v", ok)
+	}
+	state := s.ListJobs()[0].State
+	if state.LastStatus != "error" || state.LastRunAtMs != runs[0].StartedAt.UnixMilli() {
+		t.Errorf("state = %+v", state)
+	}
+}
+
+func TestHistory_RunsAndTrim(t *testing.T) {
+	h := NewHistory(filepath.Join(t.TempDir(), "cron", "history.jsonl"))
+	if runs, err := h.Runs("", 0); err != nil || runs != nil {
+		t.Fatalf("empty history = %v, %v", runs, err)
+	}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		h.Append(Run{JobID: "a", StartedAt: start.Add(time.Duration(i) * time.Minute), Status: "ok"})
+		h.Append(Run{JobID: "b", StartedAt: start.Add(time.Duration(i) * time.Minute), Status: "ok"})
+	}
+	runs, _ := h.Runs("a", 2)
+	if len(runs) != 2 || runs[0].JobID != "a" || !runs[0].StartedAt.After(runs[1].StartedAt) {
+		t.Errorf("runs = %+v", runs)
+	}
+	if all, _ := h.Runs("", 0); len(all) != 10 {
+		t.Errorf("all runs = %d, want 10", len(all))
+	}
+
+	// A torn last line is skipped.
+	f, _ := os.OpenFile(h.path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"jobId":"a","sta`)
+	f.Close()
+	if all, err := h.Runs("", 0); err != nil || len(all) != 10 {
+		t.Errorf("after torn line: %d runs, %v", len(all), err)
+	}
+
+	big := strings.Repeat("x", snippetLen)
+	appended := keepRunsPerJob + maxHistoryBytes/snippetLen
+	for i := 0; i < appended; i++ {
+		h.Append(Run{JobID: "a", Status: "ok", Output: big})
+	}
+	info, _ := os.Stat(h.path)
+	if info.Size() > maxHistoryBytes {
+		t.Errorf("history is %d bytes, want it trimmed", info.Size())
+	}
+	if runs, _ := h.Runs("a", 0); len(runs) >= appended {
+		t.Errorf("kept %d runs of a", len(runs))
+	}
+	if runs, _ := h.Runs("b", 0); len(runs) != 5 {
+		t.Errorf("kept %d runs of b, want 5", len(runs))
+	}
+}
@@ -0,0 +1,310 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	rcron "github.com/robfig/cron/v3"
+)
+
+// exprParser accepts the expressions the scheduler runs: six fields, with
+// seconds first.
+var exprParser = rcron.NewParser(rcron.Second | rcron.Minute | rcron.Hour | rcron.Dom | rcron.Month | rcron.Dow | rcron.Descriptor)
+
+const (
+	timePattern    = `(?P<time>noon|midnight|\d{1,2}(?::\d{2})?\s*(?:am|pm|a\.m\.|p\.m\.)?)`
+	dayNamePattern = `(?:mon|tue|tues|wed|thu|thur|thurs|fri|sat|sun)[a-z]*`
+	unitPattern    = `(?P<unit>minute|min|hour|hr|day)s?`
+)
+
+// scheduleRule recognises one form of schedule, before or after the prompt.
+type scheduleRule struct {
+	pattern           string
+	build             func(m map[string]string, now time.Time) (Schedule, error)
+	leading, trailing *regexp.Regexp
+}
+
+// scheduleRules are tried in order, so longer forms come first.
+var scheduleRules = []*scheduleRule{
+	{
+		pattern: `(?:(?:every|each|on)\s+)?(?P<days>daily|day|weekdays?|weekends?|` + dayNamePattern + `(?:\s*(?:,|and|&)\s*` + dayNamePattern + `)*)\s+at\s+` + timePattern,
+		build:   buildWeekly,
+	},
+	{
+		pattern: `every\s+(?P<part>morning|afternoon|evening|night)`,
+		build: func(m map[string]string, _ time.Time) (Schedule, error) {
+			hour := map[string]int{"morning": 8, "afternoon": 14, "evening": 18, "night": 21}[m["part"]]
+			return Schedule{Kind: "cron", Expr: fmt.Sprintf("0 0 %d * * *", hour)}, nil
+		},
+	},
+	{
+		pattern: `(?:every\s+(?:(?P<n>\d+)\s+)?` + unitPattern + `|(?P<hourly>hourly))`,
+		build: func(m map[string]string, _ time.Time) (Schedule, error) {
+			if m["hourly"] != "" {
+				return Schedule{Kind: "every", EveryMs: time.Hour.Milliseconds()}, nil
+			}
+			d, err := unitDuration(m["n"], m["unit"])
+			if err != nil {
+				return Schedule{}, err
+			}
+			return Schedule{Kind: "every", EveryMs: d.Milliseconds()}, nil
+		},
+	},
+	{
+		pattern: `in\s+(?P<n>\d+)\s+` + unitPattern,
+		build: func(m map[string]string, now time.Time) (Schedule, error) {
+			d, err := unitDuration(m["n"], m["unit"])
+			if err != nil {
+				return Schedule{}, err
+			}
+			return Schedule{Kind: "at", AtMs: now.Add(d).UnixMilli()}, nil
+		},
+	},
+	{
+		pattern: `(?:(?P<when>today|tomorrow)\s+)?at\s+` + timePattern + `(?:\s+(?P<when2>today|tomorrow))?`,
+		build: func(m map[string]string, now time.Time) (Schedule, error) {
+			hour, minute, err := parseClock(m["time"])
+			if err != nil {
+				return Schedule{}, err
+			}
+			at := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+			when := m["when"] + m["when2"]
+			if when == "tomorrow" || (when == "" && !at.After(now)) {
+				at = at.AddDate(0, 0, 1)
+			}
+			if !at.After(now) {
+				return Schedule{}, fmt.Errorf("%s today has already passed", m["time"])
+			}
+			return Schedule{Kind: "at", AtMs: at.UnixMilli()}, nil
+		},
+	},
+}
+
+func init() {
+	for _, rule := range scheduleRules {
+		rule.leading = regexp.MustCompile(`(?i)^(?:` + rule.pattern + `)[\s,:;-]+(?P<prompt>.+)$`)
+		rule.trailing = regexp.MustCompile(`(?i)^(?P<prompt>.+?)[\s,;]+(?:` + rule.pattern + `)[.!]?$`)
+	}
+}
+
+// ErrUnrecognized is returned by ParseNatural when text has no schedule it
+// understands.
+var ErrUnrecognized = errors.New("no recognizable schedule")
+
+// ParseNatural splits a request such as "every weekday at 9am summarize my
+// inbox" into a schedule and the prompt to run. The schedule may come first
+// or last. Times are in now's location.
+func ParseNatural(text string, now time.Time) (Schedule, string, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, rule := range scheduleRules {
+		for _, re := range []*regexp.Regexp{rule.leading, rule.trailing} {
+			match := re.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			groups := make(map[string]string)
+			for i, name := range re.SubexpNames() {
+				if name != "" && match[i] != "" {
+					groups[name] = strings.ToLower(match[i])
+				}
+			}
+			prompt := strings.TrimSpace(match[re.SubexpIndex("prompt")])
+			sched, err := rule.build(groups, now)
+			if err != nil {
+				return Schedule{}, "", err
+			}
+			return sched, prompt, nil
+		}
+	}
+	return Schedule{}, "", ErrUnrecognized
+}
+
+func buildWeekly(m map[string]string, _ time.Time) (Schedule, error) {
+	hour, minute, err := parseClock(m["time"])
+	if err != nil {
+		return Schedule{}, err
+	}
+	var dow string
+	switch days := m["days"]; {
+	case days == "daily" || days == "day":
+		dow = "*"
+	case strings.HasPrefix(days, "weekday"):
+		dow = "1-5"
+	case strings.HasPrefix(days, "weekend"):
+		dow = "0,6"
+	default:
+		var nums []string
+		for _, name := range dayNameRe.FindAllString(days, -1) {
+			nums = append(nums, strconv.Itoa(weekdayNumber(name)))
+		}
+		dow = strings.Join(nums, ",")
+	}
+	return Schedule{Kind: "cron", Expr: fmt.Sprintf("0 %d %d * * %s", minute, hour, dow)}, nil
+}
+
+var dayNameRe = regexp.MustCompile(dayNamePattern)
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func weekdayNumber(name string) int {
+	for i, prefix := range weekdayNames {
+		if strings.HasPrefix(name, prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseClock reads "9am", "9:30 pm", "17:45", "noon" or "midnight".
+func parseClock(s string) (hour, minute int, err error) {
+	s = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), ".", "")
+	switch s {
+	case "noon":
+		return 12, 0, nil
+	case "midnight":
+		return 0, 0, nil
+	}
+	suffix := ""
+	if strings.HasSuffix(s, "am") || strings.HasSuffix(s, "pm") {
+		suffix = s[len(s)-2:]
+		s = strings.TrimSpace(s[:len(s)-2])
+	}
+	h, mm, _ := strings.Cut(s, ":")
+	hour, err = strconv.Atoi(h)
+	if err == nil && mm != "" {
+		minute, err = strconv.Atoi(mm)
+	}
+	if err != nil || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid time %q", s+suffix)
+	}
+	switch suffix {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, fmt.Errorf("invalid time %q", s+suffix)
+		}
+		hour %= 12
+		if suffix == "pm" {
+			hour += 12
+		}
+	default:
+		if hour > 23 {
+			return 0, 0, fmt.Errorf("invalid time %q", s)
+		}
+	}
+	return hour, minute, nil
+}
+
+func unitDuration(n, unit string) (time.Duration, error) {
+	count := 1
+	if n != "" {
+		var err error
+		if count, err = strconv.Atoi(n); err != nil || count <= 0 {
+			return 0, fmt.Errorf("invalid interval %q", n)
+		}
+	}
+	switch unit {
+	case "minute", "min":
+		return time.Duration(count) * time.Minute, nil
+	case "hour", "hr":
+		return time.Duration(count) * time.Hour, nil
+	default:
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+}
+
+// Validate checks that s can be scheduled at now.
+func (s Schedule) Validate(now time.Time) error {
+	switch s.Kind {
+	case "cron":
+		if _, err := exprParser.Parse(s.Expr); err != nil {
+			return fmt.Errorf("invalid cron expression %q: %w", s.Expr, err)
+		}
+	case "every":
+		if s.EveryMs < time.Second.Milliseconds() {
+			return fmt.Errorf("interval must be at least a second")
+		}
+	case "at":
+		if s.AtMs <= now.UnixMilli() {
+			return fmt.Errorf("%s is in the past", time.UnixMilli(s.AtMs).Format(time.RFC3339))
+		}
+	default:
+		return fmt.Errorf("unknown schedule kind %q", s.Kind)
+	}
+	return nil
+}
+
+// Next returns when s next fires after after. A one-shot schedule whose
+// time has passed reports false.
+func (s Schedule) Next(after time.Time) (time.Time, bool) {
+	switch s.Kind {
+	case "cron":
+		sched, err := exprParser.Parse(s.Expr)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return sched.Next(after), true
+	case "every":
+		if s.EveryMs <= 0 {
+			return time.Time{}, false
+		}
+		return after.Add(time.Duration(s.EveryMs) * time.Millisecond), true
+	case "at":
+		at := time.UnixMilli(s.AtMs)
+		return at, at.After(after)
+	}
+	return time.Time{}, false
+}
+
+var simpleExpr = regexp.MustCompile(`^0 (\d+) (\d+) \* \* (\S+)$`)
+
+// Describe renders s for people, as in "weekdays at 09:00".
+func (s Schedule) Describe() string {
+	switch s.Kind {
+	case "cron":
+		m := simpleExpr.FindStringSubmatch(s.Expr)
+		if m == nil {
+			return "cron " + s.Expr
+		}
+		minute, _ := strconv.Atoi(m[1])
+		hour, _ := strconv.Atoi(m[2])
+		return fmt.Sprintf("%s at %02d:%02d", describeDays(m[3]), hour, minute)
+	case "every":
+		d := time.Duration(s.EveryMs) * time.Millisecond
+		switch {
+		case d%(24*time.Hour) == 0:
+			return fmt.Sprintf("every %dd", d/(24*time.Hour))
+		case d%time.Hour == 0:
+			return fmt.Sprintf("every %dh", d/time.Hour)
+		case d%time.Minute == 0:
+			return fmt.Sprintf("every %dm", d/time.Minute)
+		}
+		return "every " + d.String()
+	case "at":
+		return "once at " + time.UnixMilli(s.AtMs).Format("Mon 2006-01-02 15:04")
+	}
+	return s.Kind
+}
+
+func describeDays(dow string) string {
+	switch dow {
+	case "*":
+		return "every day"
+	case "1-5":
+		return "weekdays"
+	case "0,6":
+		return "weekends"
+	}
+	var names []string
+	for _, part := range strings.Split(dow, ",") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 6 {
+			return "days " + dow
+		}
+		names = append(names, time.Weekday(n).String()+"s")
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,113 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseNatural(t *testing.T) {
+	t.Parallel()
+
+	// Friday 2026-10-16 10:00.
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.Local)
+	cases := []struct {
+		text, prompt string
+		want         Schedule
+		describe     string
+	}{
+		{"every weekday at 9am summarize my inbox", "summarize my inbox",
+			Schedule{Kind: "cron", Expr: "0 0 9 * * 1-5"}, "weekdays at 09:00"},
+		{"Daily at 18:30: plan tomorrow", "plan tomorrow",
+			Schedule{Kind: "cron", Expr: "0 30 18 * * *"}, "every day at 18:30"},
+		{"water the plants every Monday and Thursday at 7 pm", "water the plants",
+			Schedule{Kind: "cron", Expr: "0 0 19 * * 1,4"}, "Mondays, Thursdays at 19:00"},
+		{"on weekends at noon suggest a hike", "suggest a hike",
+			Schedule{Kind: "cron", Expr: "0 0 12 * * 0,6"}, "weekends at 12:00"},
+		{"every morning read me the news", "read me the news",
+			Schedule{Kind: "cron", Expr: "0 0 8 * * *"}, "every day at 08:00"},
+		{"remind me to stretch every 2 hours", "remind me to stretch",
+			Schedule{Kind: "every", EveryMs: 2 * time.Hour.Milliseconds()}, "every 2h"},
+		{"hourly check the build", "check the build",
+			Schedule{Kind: "every", EveryMs: time.Hour.Milliseconds()}, "every 1h"},
+		{"in 20 minutes take the pizza out", "take the pizza out",
+			Schedule{Kind: "at", AtMs: now.Add(20 * time.Minute).UnixMilli()}, "once at Fri 2026-10-16 10:20"},
+		{"call mom at 5pm", "call mom",
+			Schedule{Kind: "at", AtMs: now.Add(7 * time.Hour).UnixMilli()}, "once at Fri 2026-10-16 17:00"},
+		{"tomorrow at 8:30 check the train times", "check the train times",
+			Schedule{Kind: "at", AtMs: now.Add(22*time.Hour + 30*time.Minute).UnixMilli()}, "once at Sat 2026-10-17 08:30"},
+		{"at 9am back up the notes", "back up the notes",
+			Schedule{Kind: "at", AtMs: now.Add(23 * time.Hour).UnixMilli()}, "once at Sat 2026-10-17 09:00"},
+	}
+	for _, c := range cases {
+		got, prompt, err := ParseNatural(c.text, now)
+		if err != nil {
+			t.Errorf("%q: %v", c.text, err)
+			continue
+		}
+		if got != c.want || prompt != c.prompt {
+			t.Errorf("%q = %+v %q, want %+v %q", c.text, got, prompt, c.want, c.prompt)
+		}
+		if got.Describe() != c.describe {
+			t.Errorf("%q describes as %q, want %q", c.text, got.Describe(), c.describe)
+		}
+		if err := got.Validate(now); err != nil {
+			t.Errorf("%q: validate: %v", c.text, err)
+		}
+	}
+
+	for _, text := range []string{"summarize my inbox", "first thing after lunch tidy up"} {
+		if _, _, err := ParseNatural(text, now); !errors.Is(err, ErrUnrecognized) {
+			t.Errorf("%q: err = %v, want ErrUnrecognized", text, err)
+		}
+	}
+	if _, _, err := ParseNatural("every day at 13pm do it", now); err == nil {
+		t.Error("expected error for 13pm")
+	}
+	if _, _, err := ParseNatural("today at 9am do it", now); err == nil {
+		t.Error("expected error for a time that has passed")
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	bad := []Schedule{
+		{Kind: "cron", Expr: "0 9 * * 1-5"},
+		{Kind: "every", EveryMs: 10},
+		{Kind: "at", AtMs: now.Add(-time.Minute).UnixMilli()},
+		{Kind: "weekly"},
+	}
+	for _, s := range bad {
+		if err := s.Validate(now); err == nil {
+			t.Errorf("%+v: expected error", s)
+		}
+	}
+	if err := (Schedule{Kind: "cron", Expr: "@daily"}).Validate(now); err != nil {
+		t.Errorf("@daily: %v", err)
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		s    Schedule
+		want time.Time
+		ok   bool
+	}{
+		{Schedule{Kind: "cron", Expr: "0 0 9 * * *"}, time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), true},
+		{Schedule{Kind: "every", EveryMs: time.Hour.Milliseconds()}, now.Add(time.Hour), true},
+		{Schedule{Kind: "at", AtMs: now.Add(time.Minute).UnixMilli()}, now.Add(time.Minute), true},
+		{Schedule{Kind: "at", AtMs: now.UnixMilli()}, time.Time{}, false},
+		{Schedule{Kind: "cron", Expr: "bad"}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.s.Next(now)
+		if ok != tt.ok || (ok && !got.Equal(tt.want)) {
+			t.Errorf("%+v: Next = %v, %v; want %v, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
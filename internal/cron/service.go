@@ -7,8 +7,10 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	rcron "github.com/robfig/cron/v3"
 )
@@ -17,7 +19,12 @@ type Service struct {
 	storePath string
 	mu        sync.Mutex
 	jobs      []CronJob
-	OnJob     func(job CronJob) (string, error)
+	OnJob     func(job CronJob) (Result, error)
+	// History, when set, records every run.
+	History *History
+	// ShouldRun, when set, is consulted before jobs fire; jobs wait while it
+	// reports false (e.g. on a standby gateway).
+	ShouldRun func() bool
 	cron      *rcron.Cron
 	entryMap  map[string]rcron.EntryID // job ID -> cron entry ID
 }
@@ -61,6 +68,9 @@ func (s *Service) Start(ctx context.Context) error {
 func (s *Service) registerJob(job *CronJob) {
 	jobCopy := *job
 	id, err := s.cron.AddFunc(job.Schedule.Expr, func() {
+		if !s.shouldRun() {
+			return
+		}
 		s.executeJob(jobCopy)
 	})
 	if err != nil {
@@ -78,14 +88,37 @@ func (s *Service) executeJob(job CronJob) {
 		return
 	}
 
+	started := time.Now()
 	result, err := s.OnJob(job)
+	run := Run{
+		JobID:        job.ID,
+		JobName:      job.Name,
+		StartedAt:    started,
+		DurationMs:   time.Since(started).Milliseconds(),
+		Status:       "ok",
+		InputTokens:  result.InputTokens,
+		OutputTokens: result.OutputTokens,
+		Output:       truncate(result.Output, snippetLen),
+		Cached:       result.Cached,
+	}
+	if err != nil {
+		run.Status = "error"
+		run.Error = err.Error()
+	}
+	if s.History != nil {
+		if herr := s.History.Append(run); herr != nil {
+			log.Printf("[cron] record run of %s: %v", job.Name, herr)
+		}
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for i := range s.jobs {
 		if s.jobs[i].ID == job.ID {
-			s.jobs[i].State.LastRunAtMs = time.Now().UnixMilli()
+			s.jobs[i].State.LastRunAtMs = started.UnixMilli()
+			s.jobs[i].State.LastDurationMs = run.DurationMs
+			s.jobs[i].State.LastResult = run.Output
 			if err != nil {
 				s.jobs[i].State.LastStatus = "error"
 				s.jobs[i].State.LastError = err.Error()
@@ -93,7 +126,7 @@ func (s *Service) executeJob(job CronJob) {
 			} else {
 				s.jobs[i].State.LastStatus = "ok"
 				s.jobs[i].State.LastError = ""
-				log.Printf("[cron] job %s result: %s", job.Name, truncate(result, 100))
+				log.Printf("[cron] job %s result: %s", job.Name, truncate(result.Output, 100))
 			}
 
 			if s.jobs[i].DeleteAfterRun {
@@ -113,6 +146,9 @@ func (s *Service) tickLoop(ctx context.Context) {
 	for {
 		select {
 		case <-ticker.C:
+			if !s.shouldRun() {
+				continue
+			}
 			now := time.Now().UnixMilli()
 			s.mu.Lock()
 			for i := range s.jobs {
@@ -148,6 +184,10 @@ func (s *Service) tickLoop(ctx context.Context) {
 	}
 }
 
+func (s *Service) shouldRun() bool {
+	return s.ShouldRun == nil || s.ShouldRun()
+}
+
 func (s *Service) Stop() {
 	if s.cron != nil {
 		s.cron.Stop()
@@ -214,6 +254,57 @@ func (s *Service) EnableJob(id string, enabled bool) (*CronJob, error) {
 	return nil, fmt.Errorf("job %s not found", id)
 }
 
+// Load reads the job store without starting the scheduler, so commands can
+// edit jobs in place.
+func (s *Service) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Reload re-reads the job store while the scheduler runs, so jobs added,
+// removed or changed by another process take effect without a restart.
+// The jobs in memory stay as they are when the store cannot be read.
+func (s *Service) Reload() error {
+	// Read under the lock, so the store can't be one this service is
+	// about to overwrite.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.storePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var jobs []CronJob
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &jobs); err != nil {
+			return err
+		}
+	}
+	if reflect.DeepEqual(jobs, s.jobs) {
+		return nil
+	}
+	s.jobs = jobs
+	if s.cron == nil {
+		return nil
+	}
+	for id, entryID := range s.entryMap {
+		s.cron.Remove(entryID)
+		delete(s.entryMap, id)
+	}
+	for i := range s.jobs {
+		if s.jobs[i].Enabled && s.jobs[i].Schedule.Kind == "cron" {
+			s.registerJob(&s.jobs[i])
+		}
+	}
+	log.Printf("[cron] reloaded %d jobs", len(s.jobs))
+	return nil
+}
+
+// StorePath returns the file the jobs are kept in.
+func (s *Service) StorePath() string {
+	return s.storePath
+}
+
 func (s *Service) load() error {
 	data, err := os.ReadFile(s.storePath)
 	if err != nil {
@@ -241,5 +332,8 @@ func truncate(s string, n int) string {
 	if len(s) <= n {
 		return s
 	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
 	return s[:n] + "..."
 }
@@ -17,13 +17,19 @@ type Payload struct {
 	Deliver bool   `json:"deliver"`
 	Channel string `json:"channel"`
 	To      string `json:"to"`
+	// Targets are further places the output is sent.
+	Targets []Target `json:"targets,omitempty"`
+	// NoCache runs the job every time, even with responseCache enabled.
+	NoCache bool `json:"noCache,omitempty"`
 }
 
 type JobState struct {
-	NextRunAtMs int64  `json:"nextRunAtMs"`
-	LastRunAtMs int64  `json:"lastRunAtMs"`
-	LastStatus  string `json:"lastStatus"` // "ok" | "error"
-	LastError   string `json:"lastError"`
+	NextRunAtMs    int64  `json:"nextRunAtMs"`
+	LastRunAtMs    int64  `json:"lastRunAtMs"`
+	LastStatus     string `json:"lastStatus"` // "ok" | "error"
+	LastError      string `json:"lastError"`
+	LastDurationMs int64  `json:"lastDurationMs,omitempty"`
+	LastResult     string `json:"lastResult,omitempty"` // start of the output
 }
 
 type CronJob struct {
@@ -0,0 +1,215 @@
+package deadletter
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// DefaultStorePath is where the gateway and CLI keep dead-lettered messages.
+func DefaultStorePath() string {
+	return filepath.Join(config.DataDir(), "data", "deadletter", "messages.json")
+}
+
+// Entry is an outbound message that could not be delivered. Channel,
+// ChatID and Content summarize it for listings; Outbound is the message
+// itself, with its media, voice, buttons and metadata.
+type Entry struct {
+	ID             string               `json:"id"`
+	Channel        string               `json:"channel"`
+	ChatID         string               `json:"chatId"`
+	Content        string               `json:"content"`
+	ReplyTo        string               `json:"replyTo,omitempty"`
+	Outbound       *bus.OutboundMessage `json:"message,omitempty"`
+	Reason         string               `json:"reason"`
+	Attempts       int                  `json:"attempts"`
+	FailedAtMs     int64                `json:"failedAtMs"`
+	RetryRequested bool                 `json:"retryRequested,omitempty"`
+}
+
+// Message returns the outbound message for redelivery. Entries stored
+// before the whole message was kept are rebuilt from their summary.
+func (e Entry) Message() bus.OutboundMessage {
+	if e.Outbound != nil {
+		return *e.Outbound
+	}
+	return bus.OutboundMessage{
+		Channel: e.Channel,
+		ChatID:  e.ChatID,
+		Content: e.Content,
+		ReplyTo: e.ReplyTo,
+	}
+}
+
+// Store persists dead-lettered messages as a JSON file. The file is re-read on
+// every operation so the CLI and a running gateway can share it.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+func (s *Store) Add(msg bus.OutboundMessage, reason string, attempts int) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	entry := Entry{
+		ID:         fmt.Sprintf("%x", b),
+		Channel:    msg.Channel,
+		ChatID:     msg.ChatID,
+		Content:    msg.Content,
+		ReplyTo:    msg.ReplyTo,
+		Outbound:   &msg,
+		Reason:     reason,
+		Attempts:   attempts,
+		FailedAtMs: time.Now().UnixMilli(),
+	}
+	entries = append(entries, entry)
+	if err := s.save(entries); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+func (s *Store) List() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// MarkRetry flags entries for redelivery by the gateway. With no ids every
+// entry is flagged. It returns the number of entries flagged.
+func (s *Store) MarkRetry(ids ...string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	match := idSet(ids)
+	n := 0
+	for i := range entries {
+		if match(entries[i].ID) {
+			entries[i].RetryRequested = true
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save(entries)
+}
+
+// TakeRetries removes and returns all entries flagged for redelivery.
+func (s *Store) TakeRetries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var taken, kept []Entry
+	for _, entry := range entries {
+		if entry.RetryRequested {
+			taken = append(taken, entry)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	if len(taken) == 0 {
+		return nil, nil
+	}
+	return taken, s.save(kept)
+}
+
+// Purge deletes entries. With no ids every entry is deleted. It returns the
+// number of entries removed.
+func (s *Store) Purge(ids ...string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	match := idSet(ids)
+	kept := entries[:0]
+	for _, entry := range entries {
+		if !match(entry.ID) {
+			kept = append(kept, entry)
+		}
+	}
+	n := len(entries) - len(kept)
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save(kept)
+}
+
+func idSet(ids []string) func(string) bool {
+	if len(ids) == 0 {
+		return func(string) bool { return true }
+	}
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return func(id string) bool { return set[id] }
+}
+
+func (s *Store) load() ([]Entry, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read dead letters: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse dead letters: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *Store) save(entries []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	if entries == nil {
+		entries = []Entry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Entries hold replies meant for one person; only the owner may read
+	// them.
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(s.path, 0600)
+}
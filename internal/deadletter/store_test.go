@@ -0,0 +1,173 @@
+package deadletter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+)
+
+func TestStore_AddAndList(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "dl", "messages.json"))
+
+	entry, err := s.Add(bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "hello"}, "bot blocked", 3)
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if entry.ID == "" {
+		t.Error("entry ID should not be empty")
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("len(entries) = %d, want 1", len(entries))
+	}
+	got := entries[0]
+	if got.Channel != "telegram" || got.ChatID != "42" || got.Content != "hello" {
+		t.Errorf("entry = %+v", got)
+	}
+	if got.Reason != "bot blocked" || got.Attempts != 3 {
+		t.Errorf("reason/attempts = %q/%d", got.Reason, got.Attempts)
+	}
+	if got.FailedAtMs == 0 {
+		t.Error("FailedAtMs should be set")
+	}
+}
+
+func TestStore_KeepsWholeMessage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.json")
+	s := NewStore(path)
+	msg := bus.OutboundMessage{
+		Channel:  "wecom",
+		ChatID:   "zhangsan",
+		Content:  "报告已生成",
+		Media:    []string{"/tmp/report.pdf"},
+		Metadata: map[string]any{"wecom_agent_id": "1000002"},
+		Buttons:  []bus.Button{{Text: "OK", Data: "ok"}},
+		Voice:    []byte("OggS"),
+	}
+	if _, err := s.Add(msg, "timeout", 3); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("List = %v, %v", entries, err)
+	}
+	got := entries[0].Message()
+	if got.Media[0] != "/tmp/report.pdf" || got.Metadata["wecom_agent_id"] != "1000002" || got.Buttons[0].Data != "ok" || string(got.Voice) != "OggS" {
+		t.Errorf("message = %+v", got)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, %v", info.Mode(), err)
+	}
+
+	// Entries written before the whole message was kept still retry.
+	os.WriteFile(path, []byte(`[{"id":"a1","channel":"telegram","chatId":"42","content":"hi","reason":"x","attempts":1,"failedAtMs":1}]`), 0600)
+	entries, _ = s.List()
+	if got := entries[0].Message(); got.Channel != "telegram" || got.ChatID != "42" || got.Content != "hi" {
+		t.Errorf("old entry = %+v", got)
+	}
+}
+
+func TestStore_ListMissingFile(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "missing.json"))
+	entries, err := s.List()
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("len(entries) = %d, want 0", len(entries))
+	}
+}
+
+func TestStore_ListCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.json")
+	os.WriteFile(path, []byte("{not json"), 0644)
+
+	if _, err := NewStore(path).List(); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestStore_MarkRetryAndTake(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "messages.json"))
+	first, _ := s.Add(bus.OutboundMessage{Channel: "feishu", ChatID: "a", Content: "one"}, "x", 1)
+	s.Add(bus.OutboundMessage{Channel: "feishu", ChatID: "b", Content: "two"}, "y", 1)
+
+	n, err := s.MarkRetry(first.ID)
+	if err != nil {
+		t.Fatalf("MarkRetry error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("marked = %d, want 1", n)
+	}
+
+	taken, err := s.TakeRetries()
+	if err != nil {
+		t.Fatalf("TakeRetries error: %v", err)
+	}
+	if len(taken) != 1 || taken[0].ID != first.ID {
+		t.Fatalf("taken = %+v", taken)
+	}
+	msg := taken[0].Message()
+	if msg.Channel != "feishu" || msg.ChatID != "a" || msg.Content != "one" {
+		t.Errorf("message = %+v", msg)
+	}
+
+	remaining, _ := s.List()
+	if len(remaining) != 1 || remaining[0].ChatID != "b" {
+		t.Errorf("remaining = %+v", remaining)
+	}
+
+	taken, _ = s.TakeRetries()
+	if len(taken) != 0 {
+		t.Errorf("second take = %d entries, want 0", len(taken))
+	}
+}
+
+func TestStore_MarkRetryAll(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "messages.json"))
+	s.Add(bus.OutboundMessage{Channel: "a"}, "x", 1)
+	s.Add(bus.OutboundMessage{Channel: "b"}, "y", 1)
+
+	n, _ := s.MarkRetry()
+	if n != 2 {
+		t.Errorf("marked = %d, want 2", n)
+	}
+	n, _ = s.MarkRetry("nonexistent")
+	if n != 0 {
+		t.Errorf("marked nonexistent = %d, want 0", n)
+	}
+}
+
+func TestStore_Purge(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "messages.json"))
+	first, _ := s.Add(bus.OutboundMessage{Channel: "a"}, "x", 1)
+	s.Add(bus.OutboundMessage{Channel: "b"}, "y", 1)
+	s.Add(bus.OutboundMessage{Channel: "c"}, "z", 1)
+
+	n, err := s.Purge(first.ID)
+	if err != nil {
+		t.Fatalf("Purge error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("purged = %d, want 1", n)
+	}
+	entries, _ := s.List()
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) = %d, want 2", len(entries))
+	}
+
+	n, _ = s.Purge()
+	if n != 2 {
+		t.Errorf("purged all = %d, want 2", n)
+	}
+	entries, _ = s.List()
+	if len(entries) != 0 {
+		t.Errorf("len(entries) = %d, want 0", len(entries))
+	}
+}
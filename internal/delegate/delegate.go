@@ -0,0 +1,413 @@
+// Package delegate provides the delegate tool, with which the agent hands a
+// subtask to a sub-agent: a run of its own with another system prompt, a
+// cheaper model and fewer tools, whose answer comes back as the tool's
+// result.
+package delegate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/audit"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+	"github.com/stellarlinkco/myclaw/internal/provider"
+	"github.com/stellarlinkco/myclaw/internal/redact"
+	"github.com/stellarlinkco/myclaw/internal/tracing"
+	"github.com/stellarlinkco/myclaw/internal/usage"
+)
+
+// ToolName is the name the agent calls the delegate tool by.
+const ToolName = "delegate"
+
+const (
+	DefaultMaxDepth      = 1
+	DefaultMaxIterations = 10
+	DefaultMaxTokens     = 200000
+)
+
+// DefaultTools are the tools a sub-agent has when tools.delegate.tools is
+// not set: enough to look things up, nothing that changes anything.
+var DefaultTools = []string{"file_read", "grep", "glob", "web_search", "web_fetch"}
+
+// DefaultInstructions is the sub-agent's system prompt when neither the
+// config nor the call gives one.
+const DefaultInstructions = "You are a sub-agent working on one task for another agent. " +
+	"Do the task with the tools you have and answer with the result alone, " +
+	"complete and self-contained: the other agent sees nothing else of your work."
+
+// Spec is a task handed to a sub-agent.
+type Spec struct {
+	Task         string
+	Instructions string   // the sub-agent's system prompt
+	Tools        []string // the tools it may use
+}
+
+// Runner runs spec on a sub-agent and returns its answer and the tokens
+// the run used.
+type Runner func(ctx context.Context, spec Spec) (string, model.Usage, error)
+
+// Tools returns the delegate tool when cfg turns it on. tools are the
+// custom tools of the agent's runtime, which sub-agents may be given by
+// name alongside the built-in ones, and disallowed the tools it keeps from
+// the agent, which sub-agents do not get either.
+func Tools(cfg *config.Config, tools []tool.Tool, disallowed []string) []tool.Tool {
+	if !cfg.Tools.Delegate.Enabled {
+		return nil
+	}
+	r := &runner{
+		cfg:        cfg,
+		tools:      tools,
+		disallowed: disallowed,
+		ledger:     usage.NewLedger(usage.Path(cfg.Agent.Workspace), cfg.TokenTracking.Prices),
+	}
+	return []tool.Tool{NewTool(cfg.Tools.Delegate, r.run)}
+}
+
+// Tool is the delegate tool.
+type Tool struct {
+	cfg config.DelegateConfig
+	run Runner
+}
+
+// NewTool returns a delegate tool that hands tasks to run.
+func NewTool(cfg config.DelegateConfig, run Runner) *Tool {
+	return &Tool{cfg: cfg, run: run}
+}
+
+func (t *Tool) Name() string { return ToolName }
+
+func (t *Tool) Description() string {
+	return "Hand a self-contained subtask to a sub-agent and get its answer back. " +
+		"Use it for research or lookups that would take many steps, to keep them out of this conversation. " +
+		"The sub-agent sees only the task you write, so include everything it needs to know. " +
+		"It can use: " + strings.Join(t.allowed(), ", ") + "."
+}
+
+func (t *Tool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"task": map[string]any{"type": "string", "description": "The subtask, with all the context the sub-agent needs"},
+			"instructions": map[string]any{
+				"type":        "string",
+				"description": "How the sub-agent should work and answer, if not the default",
+			},
+			"tools": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "The tools to give it, from those it can use; all of them if left out",
+			},
+		},
+		Required: []string{"task"},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	task, _ := params["task"].(string)
+	if strings.TrimSpace(task) == "" {
+		return failed(errors.New("task is required")), nil
+	}
+	if depth := Depth(ctx); depth >= t.maxDepth() {
+		return failed(fmt.Errorf("delegation depth limit (%d) reached", t.maxDepth())), nil
+	}
+	spec := Spec{Task: task, Instructions: t.cfg.SystemPrompt, Tools: t.allowed()}
+	if s, _ := params["instructions"].(string); strings.TrimSpace(s) != "" {
+		spec.Instructions = s
+	}
+	if spec.Instructions == "" {
+		spec.Instructions = DefaultInstructions
+	}
+	if raw, ok := params["tools"].([]any); ok && len(raw) > 0 {
+		allowed := make(map[string]bool, len(spec.Tools))
+		for _, name := range spec.Tools {
+			allowed[name] = true
+		}
+		spec.Tools = nil
+		for _, v := range raw {
+			name, _ := v.(string)
+			if !allowed[name] {
+				return failed(fmt.Errorf("tool %q is not one sub-agents can use; they can use %s", name, strings.Join(t.allowed(), ", "))), nil
+			}
+			spec.Tools = append(spec.Tools, name)
+		}
+	}
+	// Nested delegations share the budget of the task they are part of.
+	if budgetFrom(ctx) == nil {
+		ctx = withBudget(ctx, NewBudget(t.maxTokens()))
+	}
+	out, u, err := t.run(ctx, spec)
+	if err != nil {
+		return failed(fmt.Errorf("sub-agent: %w", err)), nil
+	}
+	if strings.TrimSpace(out) == "" {
+		out = "The sub-agent gave no answer."
+	}
+	return &tool.ToolResult{
+		Success: true,
+		Output:  out,
+		Data:    map[string]any{"inputTokens": u.InputTokens, "outputTokens": u.OutputTokens},
+	}, nil
+}
+
+func (t *Tool) allowed() []string {
+	if len(t.cfg.Tools) > 0 {
+		return t.cfg.Tools
+	}
+	return DefaultTools
+}
+
+func (t *Tool) maxDepth() int {
+	if t.cfg.MaxDepth > 0 {
+		return t.cfg.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+func (t *Tool) maxTokens() int {
+	if t.cfg.MaxTokens > 0 {
+		return t.cfg.MaxTokens
+	}
+	return DefaultMaxTokens
+}
+
+func (t *Tool) maxIterations() int {
+	if t.cfg.MaxIterations > 0 {
+		return t.cfg.MaxIterations
+	}
+	return DefaultMaxIterations
+}
+
+func failed(err error) *tool.ToolResult {
+	return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}
+}
+
+type depthKey struct{}
+
+// Depth returns how many delegations deep ctx is: 0 for the main agent.
+func Depth(ctx context.Context) int {
+	d, _ := ctx.Value(depthKey{}).(int)
+	return d
+}
+
+func withDepth(ctx context.Context, depth int) context.Context {
+	return context.WithValue(ctx, depthKey{}, depth)
+}
+
+// runner runs sub-agents on runtimes built for the one task.
+type runner struct {
+	cfg        *config.Config
+	tools      []tool.Tool
+	disallowed []string
+	ledger     *usage.Ledger
+}
+
+func (r *runner) run(ctx context.Context, spec Spec) (string, model.Usage, error) {
+	d := r.cfg.Tools.Delegate
+	t := NewTool(d, r.run)
+	depth := Depth(ctx)
+
+	sub := *r.cfg
+	if d.Model != "" {
+		sub.Agent.Model = d.Model
+	}
+	modelName := sub.Models.Resolve(sub.Agent.Model)
+	redactor, err := redact.New(sub.Redaction)
+	if err != nil {
+		return "", model.Usage{}, err
+	}
+	policy, err := permission.NewPolicy(sub.Permissions)
+	if err != nil {
+		return "", model.Usage{}, err
+	}
+	var used model.Usage
+	modelFactory := tracing.ModelFactory(redact.ModelFactory(provider.New(&sub), redactor), modelName)
+	modelFactory = Budgeted(modelFactory, budgetFrom(ctx), &used)
+
+	custom := make(map[string]tool.Tool, len(r.tools))
+	for _, ct := range r.tools {
+		custom[ct.Name()] = ct
+	}
+	builtins := []string{} // none but those named
+	var tools []tool.Tool
+	for _, name := range spec.Tools {
+		if ct, ok := custom[name]; ok {
+			tools = append(tools, ct)
+		} else {
+			builtins = append(builtins, name)
+		}
+	}
+	if depth+1 < t.maxDepth() {
+		tools = append(tools, t)
+	}
+
+	middlewares := []middleware.Middleware{tracing.Middleware()}
+	if auditLog := audit.Open(&sub); auditLog != nil {
+		middlewares = append(middlewares, audit.Middleware(auditLog))
+	}
+	rt, err := api.New(context.Background(), api.Options{
+		ProjectRoot:         sub.Agent.Workspace,
+		ModelFactory:        modelFactory,
+		Middleware:          middlewares,
+		HookMiddleware:      permission.HookMiddleware(policy),
+		SystemPrompt:        spec.Instructions,
+		MaxIterations:       t.maxIterations(),
+		EnabledBuiltinTools: builtins,
+		CustomTools:         tools,
+		DisallowedTools:     r.disallowed,
+	})
+	if err != nil {
+		return "", model.Usage{}, fmt.Errorf("create runtime: %w", err)
+	}
+	defer rt.Close()
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	resp, err := rt.Run(withDepth(ctx, depth+1), api.Request{
+		Prompt:    spec.Task,
+		SessionID: "delegate-" + hex.EncodeToString(b),
+	})
+	r.record(modelName, used)
+	if err != nil {
+		return "", used, err
+	}
+	var out string
+	if resp != nil && resp.Result != nil {
+		out = resp.Result.Output
+	}
+	return out, used, nil
+}
+
+// record writes a sub-agent's run to the usage ledger.
+func (r *runner) record(modelName string, u model.Usage) {
+	if u.InputTokens == 0 && u.OutputTokens == 0 {
+		return
+	}
+	_, _, err := r.ledger.Record(usage.Entry{
+		Time:             time.Now(),
+		Session:          ToolName,
+		Model:            modelName,
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheReadTokens:  u.CacheReadTokens,
+		CacheWriteTokens: u.CacheCreationTokens,
+	})
+	if err != nil {
+		log.Printf("[usage] record failed: %v", err)
+	}
+}
+
+// ErrBudgetSpent is returned by a budgeted model once the delegated task it
+// works on has used its tokens.
+var ErrBudgetSpent = errors.New("token budget for the delegated task spent")
+
+// Budget is the tokens a delegated task, with the sub-agents it spawns, may
+// use.
+type Budget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// NewBudget returns a budget of limit tokens.
+func NewBudget(limit int) *Budget {
+	return &Budget{limit: int64(limit)}
+}
+
+// Used returns the tokens spent so far.
+func (b *Budget) Used() int {
+	return int(b.used.Load())
+}
+
+func (b *Budget) spend(u model.Usage) {
+	b.used.Add(int64(u.InputTokens + u.OutputTokens))
+}
+
+func (b *Budget) check() error {
+	if b.used.Load() >= b.limit {
+		return fmt.Errorf("%w after %d tokens", ErrBudgetSpent, b.Used())
+	}
+	return nil
+}
+
+type budgetKey struct{}
+
+func budgetFrom(ctx context.Context) *Budget {
+	b, _ := ctx.Value(budgetKey{}).(*Budget)
+	return b
+}
+
+func withBudget(ctx context.Context, b *Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+// Budgeted wraps the models f builds so they spend from b, refusing calls
+// once it is spent, and add what they use to used. With a nil b the models
+// only count.
+func Budgeted(f api.ModelFactory, b *Budget, used *model.Usage) api.ModelFactory {
+	return api.ModelFactoryFunc(func(ctx context.Context) (model.Model, error) {
+		m, err := f.Model(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &budgetedModel{Model: m, b: b, used: used}, nil
+	})
+}
+
+// budgetedModel is called by one run at a time, so used needs no lock.
+type budgetedModel struct {
+	model.Model
+	b    *Budget
+	used *model.Usage
+}
+
+func (m *budgetedModel) check() error {
+	if m.b == nil {
+		return nil
+	}
+	return m.b.check()
+}
+
+func (m *budgetedModel) spend(u model.Usage) {
+	if m.b != nil {
+		m.b.spend(u)
+	}
+	m.used.InputTokens += u.InputTokens
+	m.used.OutputTokens += u.OutputTokens
+	m.used.TotalTokens += u.TotalTokens
+	m.used.CacheReadTokens += u.CacheReadTokens
+	m.used.CacheCreationTokens += u.CacheCreationTokens
+}
+
+func (m *budgetedModel) Complete(ctx context.Context, req model.Request) (*model.Response, error) {
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	resp, err := m.Model.Complete(ctx, req)
+	if resp != nil {
+		m.spend(resp.Usage)
+	}
+	return resp, err
+}
+
+func (m *budgetedModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	return m.Model.CompleteStream(ctx, req, func(res model.StreamResult) error {
+		if res.Response != nil {
+			m.spend(res.Response.Usage)
+		}
+		return cb(res)
+	})
+}
@@ -0,0 +1,129 @@
+package delegate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestTool_Execute(t *testing.T) {
+	t.Parallel()
+
+	var got Spec
+	var depth int
+	var budget *Budget
+	tl := NewTool(config.DelegateConfig{Enabled: true, Tools: []string{"file_read", "web_search"}}, func(ctx context.Context, spec Spec) (string, model.Usage, error) {
+		got, depth, budget = spec, Depth(ctx), budgetFrom(ctx)
+		return "42", model.Usage{InputTokens: 10, OutputTokens: 2}, nil
+	})
+
+	res, err := tl.Execute(context.Background(), map[string]any{"task": "What is the answer?"})
+	if err != nil || !res.Success || res.Output != "42" {
+		t.Fatalf("Execute = %+v, %v", res, err)
+	}
+	want := Spec{Task: "What is the answer?", Instructions: DefaultInstructions, Tools: []string{"file_read", "web_search"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("spec = %+v, want %+v", got, want)
+	}
+	if depth != 0 || budget == nil || budget.limit != DefaultMaxTokens {
+		t.Errorf("depth = %d, budget = %+v", depth, budget)
+	}
+
+	res, _ = tl.Execute(context.Background(), map[string]any{
+		"task":         "Look it up",
+		"instructions": "Answer in one word.",
+		"tools":        []any{"web_search"},
+	})
+	if !res.Success || got.Instructions != "Answer in one word." || !reflect.DeepEqual(got.Tools, []string{"web_search"}) {
+		t.Errorf("Execute = %+v, spec = %+v", res, got)
+	}
+
+	for _, params := range []map[string]any{
+		{"task": " "},
+		{"task": "Delete it", "tools": []any{"bash"}},
+	} {
+		if res, _ := tl.Execute(context.Background(), params); res.Success {
+			t.Errorf("Execute(%v) succeeded", params)
+		}
+	}
+
+	// A sub-agent one level down may not delegate with the default depth.
+	if res, _ := tl.Execute(withDepth(context.Background(), 1), map[string]any{"task": "Again"}); res.Success || !strings.Contains(res.Output, "depth limit (1)") {
+		t.Errorf("nested Execute = %+v", res)
+	}
+}
+
+func TestTool_Execute_Error(t *testing.T) {
+	t.Parallel()
+
+	tl := NewTool(config.DelegateConfig{}, func(ctx context.Context, spec Spec) (string, model.Usage, error) {
+		return "", model.Usage{}, ErrBudgetSpent
+	})
+	res, err := tl.Execute(context.Background(), map[string]any{"task": "Do it"})
+	if err != nil || res.Success || !errors.Is(res.Error, ErrBudgetSpent) {
+		t.Errorf("Execute = %+v, %v", res, err)
+	}
+}
+
+func TestTool_Execute_SharesBudget(t *testing.T) {
+	t.Parallel()
+
+	b := NewBudget(100)
+	var got *Budget
+	tl := NewTool(config.DelegateConfig{MaxDepth: 3}, func(ctx context.Context, spec Spec) (string, model.Usage, error) {
+		got = budgetFrom(ctx)
+		return "ok", model.Usage{}, nil
+	})
+	ctx := withBudget(withDepth(context.Background(), 1), b)
+	if res, _ := tl.Execute(ctx, map[string]any{"task": "Do it"}); !res.Success || got != b {
+		t.Errorf("Execute = %+v, budget %p, want %p", res, got, b)
+	}
+}
+
+type stubModel struct {
+	usage model.Usage
+	calls int
+}
+
+func (m *stubModel) Complete(ctx context.Context, req model.Request) (*model.Response, error) {
+	m.calls++
+	return &model.Response{Usage: m.usage}, nil
+}
+
+func (m *stubModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	m.calls++
+	return cb(model.StreamResult{Final: true, Response: &model.Response{Usage: m.usage}})
+}
+
+func TestBudgeted(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubModel{usage: model.Usage{InputTokens: 40, OutputTokens: 10}}
+	b := NewBudget(100)
+	var used model.Usage
+	m, err := Budgeted(api.ModelFactoryFunc(func(context.Context) (model.Model, error) { return stub, nil }), b, &used).Model(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Complete(context.Background(), model.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CompleteStream(context.Background(), model.Request{}, func(model.StreamResult) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if b.Used() != 100 || used.InputTokens != 80 || used.OutputTokens != 20 {
+		t.Errorf("budget used %d, usage %+v", b.Used(), used)
+	}
+	if _, err := m.Complete(context.Background(), model.Request{}); !errors.Is(err, ErrBudgetSpent) {
+		t.Errorf("Complete over budget: err = %v", err)
+	}
+	if stub.calls != 2 {
+		t.Errorf("model called %d times, want 2", stub.calls)
+	}
+}
@@ -0,0 +1,239 @@
+package feeds
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// maxFeedBytes caps the size of a feed download.
+const maxFeedBytes = 10 << 20
+
+// Feed is an RSS or Atom feed.
+type Feed struct {
+	Title string
+	Items []Item
+}
+
+// Item is one entry of a feed. ID is the entry's GUID or Atom ID, else its
+// link or title, so that it stays the same from one fetch to the next.
+type Item struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link,omitempty"`
+	Published time.Time `json:"published,omitempty"`
+	Summary   string    `json:"summary,omitempty"`
+}
+
+// xmlFeed holds the parts of RSS 2.0, RSS 1.0 (RDF) and Atom documents
+// myclaw reads. Elements match by local name, whatever their namespace.
+type xmlFeed struct {
+	XMLName xml.Name
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"` // RSS 1.0 keeps items outside the channel
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Links       []string `xml:"link"` // may include an empty atom:link
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"date"` // dc:date
+	Description string   `xml:"description"`
+	Encoded     string   `xml:"encoded"` // content:encoded
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     atomText   `xml:"title"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   atomText   `xml:"summary"`
+	Content   atomText   `xml:"content"`
+	Links     []atomLink `xml:"link"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// atomText is an Atom text construct: text, escaped HTML, or inline XHTML.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t atomText) html() string {
+	if t.Type == "xhtml" {
+		return t.Inner
+	}
+	return t.Text
+}
+
+// Parse reads an RSS 2.0, RSS 1.0 or Atom document.
+func Parse(data []byte) (*Feed, error) {
+	var doc xmlFeed
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse feed: %w", err)
+	}
+	feed := &Feed{}
+	switch doc.XMLName.Local {
+	case "rss", "RDF":
+		feed.Title = strings.TrimSpace(doc.Channel.Title)
+		for _, it := range append(doc.Channel.Items, doc.Items...) {
+			body := it.Description
+			if body == "" {
+				body = it.Encoded
+			}
+			date := it.PubDate
+			if date == "" {
+				date = it.Date
+			}
+			var link string
+			for _, l := range it.Links {
+				if link = strings.TrimSpace(l); link != "" {
+					break
+				}
+			}
+			feed.Items = append(feed.Items, newItem(it.GUID, it.Title, link, date, body))
+		}
+	case "feed":
+		feed.Title = strings.TrimSpace(doc.Title)
+		for _, e := range doc.Entries {
+			body := e.Summary.html()
+			if strings.TrimSpace(body) == "" {
+				body = e.Content.html()
+			}
+			date := e.Published
+			if date == "" {
+				date = e.Updated
+			}
+			feed.Items = append(feed.Items, newItem(e.ID, plainText(e.Title.html()), atomHref(e.Links), date, body))
+		}
+	default:
+		return nil, fmt.Errorf("parse feed: <%s> is not an RSS or Atom document", doc.XMLName.Local)
+	}
+	return feed, nil
+}
+
+func newItem(id, title, link, date, body string) Item {
+	it := Item{
+		Title:     strings.Join(strings.Fields(title), " "),
+		Link:      strings.TrimSpace(link),
+		Published: parseDate(date),
+		Summary:   plainText(body),
+	}
+	for _, candidate := range []string{id, it.Link, it.Title} {
+		if it.ID = strings.TrimSpace(candidate); it.ID != "" {
+			break
+		}
+	}
+	return it
+}
+
+// atomHref picks an entry's web page: the alternate link, which is also the
+// one without a rel.
+func atomHref(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// plainText strips the markup from an HTML fragment and collapses its
+// whitespace.
+func plainText(fragment string) string {
+	if !strings.ContainsAny(fragment, "<&") {
+		return strings.Join(strings.Fields(fragment), " ")
+	}
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return strings.Join(strings.Fields(fragment), " ")
+	}
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style"):
+			return
+		case n.Type == html.ElementNode:
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Fetch downloads and parses the feed at url.
+func Fetch(ctx context.Context, client *http.Client, url string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "myclaw (feed reader)")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.5")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	}
+	feed, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	}
+	return feed, nil
+}
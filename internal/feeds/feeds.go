@@ -0,0 +1,253 @@
+// Package feeds watches RSS and Atom feeds for new items and turns them into
+// a digest prompt for the agent.
+package feeds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	// seenRetention is how long an item that has left its feed is
+	// remembered as seen.
+	seenRetention = 90 * 24 * time.Hour
+	// summaryChars caps each item's summary in the digest prompt.
+	summaryChars = 600
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Path returns where the seen items are kept for workspace.
+func Path(workspace string) string {
+	return filepath.Join(workspace, ".claude", "feeds.json")
+}
+
+// Store remembers which items of each feed have been seen, by feed URL and
+// item ID. The file is re-read on every operation so the CLI and a running
+// gateway can share it.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// seenItems maps feed URL to item ID to when the item was last in the feed,
+// in Unix milliseconds.
+type seenItems map[string]map[string]int64
+
+// Seen reports which of the item IDs of the feed at url have been seen.
+func (s *Store) Seen(url string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(all[url]))
+	for id := range all[url] {
+		seen[id] = true
+	}
+	return seen, nil
+}
+
+// MarkSeen records ids as seen in the feed at url at now, and forgets items
+// that have not been in the feed for a long time.
+func (s *Store) MarkSeen(url string, ids []string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+	feed := all[url]
+	if feed == nil {
+		feed = make(map[string]int64)
+		all[url] = feed
+	}
+	for _, id := range ids {
+		feed[id] = now.UnixMilli()
+	}
+	cutoff := now.Add(-seenRetention).UnixMilli()
+	for id, at := range feed {
+		if at < cutoff {
+			delete(feed, id)
+		}
+	}
+	return s.save(all)
+}
+
+func (s *Store) load() (seenItems, error) {
+	all := seenItems{}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	return all, nil
+}
+
+func (s *Store) save(all seenItems) error {
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Update is what one check found in one feed.
+type Update struct {
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	Items []Item `json:"items"` // new items that pass the filters, newest first
+
+	current []string // the IDs of every item in the feed
+}
+
+// Watcher checks the configured feeds for items it has not seen.
+type Watcher struct {
+	feeds    []config.FeedConfig
+	maxItems int
+	store    *Store
+	client   *http.Client
+}
+
+// NewWatcher returns a watcher for cfg that keeps its state in store.
+func NewWatcher(cfg config.FeedsConfig, store *Store) *Watcher {
+	maxItems := cfg.MaxItems
+	if maxItems <= 0 {
+		maxItems = config.DefaultFeedsMaxItems
+	}
+	return &Watcher{feeds: cfg.Feeds, maxItems: maxItems, store: store, client: httpClient}
+}
+
+// Check fetches every feed and returns the new items of each. A feed that
+// cannot be fetched is left out and its error joined into the one
+// returned. Nothing is marked seen until Commit.
+func (w *Watcher) Check(ctx context.Context) ([]Update, error) {
+	var updates []Update
+	var errs []error
+	for _, fc := range w.feeds {
+		u, err := w.check(ctx, fc)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		updates = append(updates, u)
+	}
+	return updates, errors.Join(errs...)
+}
+
+func (w *Watcher) check(ctx context.Context, fc config.FeedConfig) (Update, error) {
+	feed, err := Fetch(ctx, w.client, fc.URL)
+	if err != nil {
+		return Update{}, err
+	}
+	seen, err := w.store.Seen(fc.URL)
+	if err != nil {
+		return Update{}, err
+	}
+	u := Update{Name: fc.Name, URL: fc.URL}
+	if u.Name == "" {
+		u.Name = feed.Title
+	}
+	if u.Name == "" {
+		u.Name = fc.URL
+	}
+	for _, it := range feed.Items {
+		u.current = append(u.current, it.ID)
+		if !seen[it.ID] && Match(it, fc.Include, fc.Exclude) {
+			u.Items = append(u.Items, it)
+		}
+	}
+	sort.SliceStable(u.Items, func(i, j int) bool { return u.Items[i].Published.After(u.Items[j].Published) })
+	if len(u.Items) > w.maxItems {
+		u.Items = u.Items[:w.maxItems]
+	}
+	return u, nil
+}
+
+// Commit marks everything in updates seen, including items the filters or
+// MaxItems left out, so they do not come up in the next digest.
+func (w *Watcher) Commit(updates []Update, now time.Time) error {
+	var errs []error
+	for _, u := range updates {
+		if err := w.store.MarkSeen(u.URL, u.current, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Match reports whether it passes a feed's filters: it contains one of
+// include, if any, and none of exclude, ignoring case.
+func Match(it Item, include, exclude []string) bool {
+	text := strings.ToLower(it.Title + " " + it.Summary)
+	has := func(words []string) bool {
+		for _, w := range words {
+			if w = strings.ToLower(strings.TrimSpace(w)); w != "" && strings.Contains(text, w) {
+				return true
+			}
+		}
+		return false
+	}
+	return (len(include) == 0 || has(include)) && !has(exclude)
+}
+
+// Digest returns the prompt that has the agent summarize updates, or ""
+// when there are no new items.
+func Digest(updates []Update) string {
+	var b strings.Builder
+	for _, u := range updates {
+		if len(u.Items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n", u.Name)
+		for _, it := range u.Items {
+			fmt.Fprintf(&b, "\n- %s\n", it.Title)
+			if it.Link != "" {
+				fmt.Fprintf(&b, "  Link: %s\n", it.Link)
+			}
+			if !it.Published.IsZero() {
+				fmt.Fprintf(&b, "  Published: %s\n", it.Published.Local().Format("Mon 2 Jan 15:04"))
+			}
+			if summary := it.Summary; summary != "" {
+				if r := []rune(summary); len(r) > summaryChars {
+					summary = string(r[:summaryChars]) + "…"
+				}
+				fmt.Fprintf(&b, "  %s\n", summary)
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "Write a digest of these new items from the feeds I follow. Group them by feed, and give each " +
+		"item its title as a link and a sentence or two on what it is about, going only by what is here. " +
+		"Keep it short enough to read on a phone.\n" + b.String()
+}
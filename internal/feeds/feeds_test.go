@@ -0,0 +1,192 @@
+package feeds
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+  <title>Go Blog</title>
+  <atom:link href="https://go.dev/feed.xml" rel="self"/>
+  <item>
+    <title>Go 1.30 is released</title>
+    <link>https://go.dev/blog/go1.30</link>
+    <guid>tag:go.dev,2026:go1.30</guid>
+    <pubDate>Tue, 11 Aug 2026 17:00:00 +0000</pubDate>
+    <description><![CDATA[<p>Today the Go team is <b>happy</b> to release Go 1.30.</p>]]></description>
+  </item>
+  <item>
+    <title>Hiring: Go developer relations</title>
+    <link>https://go.dev/blog/hiring</link>
+    <pubDate>Wed, 5 Aug 2026 09:00:00 GMT</pubDate>
+    <description>We are hiring.</description>
+  </item>
+  <item>
+    <title>Range over func</title>
+    <link>https://go.dev/blog/range-functions</link>
+    <pubDate>Mon, 3 Aug 2026 09:00:00 +0000</pubDate>
+    <content:encoded>&lt;p&gt;Iterators &amp;amp; you.&lt;/p&gt;</content:encoded>
+  </item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example Journal</title>
+  <entry>
+    <id>urn:uuid:1225c695</id>
+    <title type="html">Atom &amp;lt;3</title>
+    <link rel="alternate" href="https://example.org/2026/atom"/>
+    <link rel="edit" href="https://example.org/edit/1"/>
+    <updated>2026-10-15T18:30:02Z</updated>
+    <summary type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">Some <em>inline</em> text.</div></summary>
+  </entry>
+</feed>`
+
+const rdfFeed = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel><title>Old School</title></channel>
+  <item rdf:about="https://example.net/1">
+    <title>First post</title>
+    <link>https://example.net/1</link>
+    <dc:date>2026-10-01T08:00:00Z</dc:date>
+  </item>
+</rdf:RDF>`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	feed, err := Parse([]byte(rssFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Go Blog" || len(feed.Items) != 3 {
+		t.Fatalf("rss = %+v", feed)
+	}
+	first := feed.Items[0]
+	if first.ID != "tag:go.dev,2026:go1.30" || first.Link != "https://go.dev/blog/go1.30" ||
+		first.Summary != "Today the Go team is happy to release Go 1.30." ||
+		!first.Published.Equal(time.Date(2026, 8, 11, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("rss item = %+v", first)
+	}
+	if it := feed.Items[1]; it.ID != "https://go.dev/blog/hiring" || it.Published.IsZero() {
+		t.Errorf("item without guid = %+v", it)
+	}
+	if it := feed.Items[2]; it.Summary != "Iterators & you." {
+		t.Errorf("content:encoded summary = %q", it.Summary)
+	}
+
+	feed, err = Parse([]byte(atomFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Example Journal" || len(feed.Items) != 1 {
+		t.Fatalf("atom = %+v", feed)
+	}
+	if it := feed.Items[0]; it.ID != "urn:uuid:1225c695" || it.Title != "Atom <3" ||
+		it.Link != "https://example.org/2026/atom" || it.Summary != "Some inline text." || it.Published.IsZero() {
+		t.Errorf("atom entry = %+v", it)
+	}
+
+	feed, err = Parse([]byte(rdfFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Old School" || len(feed.Items) != 1 || feed.Items[0].Published.IsZero() {
+		t.Errorf("rdf = %+v", feed)
+	}
+
+	if _, err := Parse([]byte(`<html><body>Not a feed</body></html>`)); err == nil {
+		t.Error("expected error for HTML")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	it := Item{Title: "Go 1.30 is released", Summary: "Faster builds."}
+	tests := []struct {
+		include, exclude []string
+		want             bool
+	}{
+		{nil, nil, true},
+		{[]string{"rust", "GO 1.30"}, nil, true},
+		{[]string{"rust"}, nil, false},
+		{nil, []string{"builds"}, false},
+		{[]string{"go"}, []string{"hiring"}, true},
+	}
+	for _, tt := range tests {
+		if got := Match(it, tt.include, tt.exclude); got != tt.want {
+			t.Errorf("Match(%v, %v) = %v", tt.include, tt.exclude, got)
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/go.xml":
+			w.Write([]byte(rssFeed))
+		case "/atom.xml":
+			w.Write([]byte(atomFeed))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.FeedsConfig{
+		MaxItems: 1,
+		Feeds: []config.FeedConfig{
+			{URL: srv.URL + "/go.xml", Exclude: []string{"hiring"}},
+			{Name: "Journal", URL: srv.URL + "/atom.xml"},
+			{URL: srv.URL + "/gone.xml"},
+		},
+	}
+	w := NewWatcher(cfg, NewStore(filepath.Join(t.TempDir(), "feeds.json")))
+	ctx := context.Background()
+
+	updates, err := w.Check(ctx)
+	if err == nil || !strings.Contains(err.Error(), "gone.xml") {
+		t.Errorf("check error = %v", err)
+	}
+	if len(updates) != 2 || updates[0].Name != "Go Blog" || updates[1].Name != "Journal" {
+		t.Fatalf("updates = %+v", updates)
+	}
+	if items := updates[0].Items; len(items) != 1 || items[0].Title != "Go 1.30 is released" {
+		t.Errorf("go items = %+v", items)
+	}
+
+	digest := Digest(updates)
+	for _, want := range []string{"## Go Blog", "- Go 1.30 is released", "Link: https://go.dev/blog/go1.30", "## Journal", "Some inline text."} {
+		if !strings.Contains(digest, want) {
+			t.Errorf("digest lacks %q:\n%s", want, digest)
+		}
+	}
+	if strings.Contains(digest, "Hiring") || strings.Contains(digest, "Range over func") {
+		t.Errorf("digest has filtered or capped items:\n%s", digest)
+	}
+
+	// Until committed, the same items come up again.
+	if again, _ := w.Check(ctx); Digest(again) != digest {
+		t.Error("uncommitted check changed")
+	}
+	if err := w.Commit(updates, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	updates, _ = w.Check(ctx)
+	if digest := Digest(updates); digest != "" {
+		t.Errorf("digest after commit =\n%s", digest)
+	}
+}
@@ -0,0 +1,281 @@
+// Package fetch is the web_fetch tool: it reads a web page and gives the
+// agent its main text as markdown, cut to a token budget, within the
+// domain policy of tools.fetch.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"golang.org/x/net/html"
+)
+
+const (
+	// ToolName is the name the agent calls the fetcher by.
+	ToolName = "web_fetch"
+	// SDKToolName is the SDK's own fetch tool, which web_fetch replaces.
+	SDKToolName = "WebFetch"
+
+	defaultMaxTokens = 4000
+	defaultTimeout   = 20 * time.Second
+	maxBodyBytes     = 5 << 20
+	maxRedirects     = 5
+	userAgent        = "Mozilla/5.0 (compatible; myclaw; +https://github.com/stellarlinkco/myclaw)"
+)
+
+// ErrPrivateAddress is returned for hosts on the local network when
+// tools.fetch.allowPrivate is off.
+var ErrPrivateAddress = errors.New("address is on a private network")
+
+// Page is a fetched page.
+type Page struct {
+	URL       string `json:"url"`
+	Title     string `json:"title,omitempty"`
+	Markdown  string `json:"markdown"`
+	Tokens    int    `json:"tokens"`    // estimated, as returned
+	Truncated bool   `json:"truncated"` // cut to the token budget
+}
+
+// Fetcher reads pages within a domain policy.
+type Fetcher struct {
+	client    *http.Client
+	allow     []string
+	deny      []string
+	maxTokens int
+}
+
+// New returns a Fetcher for cfg.
+func New(cfg config.FetchConfig) *Fetcher {
+	f := &Fetcher{allow: cfg.Allow, deny: cfg.Deny, maxTokens: cfg.MaxTokens}
+	if f.maxTokens <= 0 {
+		f.maxTokens = defaultMaxTokens
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !cfg.AllowPrivate {
+		// Checked on the address actually dialed, so a name that resolves
+		// to a local address is caught too.
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return fmt.Errorf("%s: %w", host, ErrPrivateAddress)
+			}
+			return nil
+		}
+	}
+	f.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     60 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return f.check(req.URL)
+		},
+	}
+	return f
+}
+
+// Fetch reads rawURL and returns its main content as markdown, cut to
+// maxTokens (the configured budget when 0 or larger).
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string, maxTokens int) (*Page, error) {
+	u, err := parseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.check(u); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.5")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", u, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+
+	page := &Page{URL: resp.Request.URL.String()}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		doc, err := html.Parse(strings.NewReader(string(body)))
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", u, err)
+		}
+		page.Title, page.Markdown = Markdown(doc, resp.Request.URL)
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		page.Markdown = strings.TrimSpace(string(body))
+	default:
+		return nil, fmt.Errorf("fetch %s: cannot read %s content", u, mediaType)
+	}
+
+	if maxTokens <= 0 || maxTokens > f.maxTokens {
+		maxTokens = f.maxTokens
+	}
+	page.Markdown, page.Truncated = Truncate(page.Markdown, maxTokens)
+	page.Tokens = estimateTokens(page.Markdown)
+	return page, nil
+}
+
+// check applies the domain policy to u.
+func (f *Fetcher) check(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("fetch %s: only http and https URLs can be fetched", u)
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchDomain(host, f.deny) {
+		return fmt.Errorf("fetch %s: %s is denied by tools.fetch.deny", u, host)
+	}
+	if len(f.allow) > 0 && !matchDomain(host, f.allow) {
+		return fmt.Errorf("fetch %s: %s is not in tools.fetch.allow", u, host)
+	}
+	return nil
+}
+
+// Truncate cuts md to about maxTokens, at a paragraph break when one is
+// near, and reports whether it cut anything.
+func Truncate(md string, maxTokens int) (string, bool) {
+	limit := maxTokens * 4
+	if len(md) <= limit {
+		return md, false
+	}
+	for limit > 0 && !utf8.RuneStart(md[limit]) {
+		limit--
+	}
+	cut := md[:limit]
+	if i := strings.LastIndex(cut, "\n\n"); i > limit/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut), true
+}
+
+// estimateTokens estimates text at about four bytes a token, as the
+// runtime's history does.
+func estimateTokens(text string) int {
+	return len(text) / 4
+}
+
+func parseURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("url is required")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %q", raw)
+	}
+	u.Fragment = ""
+	return u, nil
+}
+
+// matchDomain reports whether host is one of domains or a subdomain of one.
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "*.")
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// Tool is the web_fetch tool.
+type Tool struct {
+	fetcher *Fetcher
+}
+
+// Tools returns the tools to add to a runtime and the SDK tools to leave
+// out for cfg: web_fetch in place of WebFetch unless it is turned off.
+func Tools(cfg config.FetchConfig) (add []tool.Tool, disallow []string) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return []tool.Tool{&Tool{fetcher: New(cfg)}}, []string{SDKToolName}
+}
+
+func (t *Tool) Name() string { return ToolName }
+
+func (t *Tool) Description() string {
+	return "Read a web page, such as a link the user sent. Returns the page's main text as markdown " +
+		"without navigation or ads, cut to a token budget."
+}
+
+func (t *Tool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"url": map[string]any{
+				"type":        "string",
+				"description": "The http or https URL to read",
+			},
+			"max_tokens": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Return at most about this many tokens (default and limit %d)", t.fetcher.maxTokens),
+			},
+		},
+		Required: []string{"url"},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	rawURL, _ := params["url"].(string)
+	maxTokens := 0
+	if n, ok := params["max_tokens"].(float64); ok {
+		maxTokens = int(n)
+	}
+	page, err := t.fetcher.Fetch(ctx, rawURL, maxTokens)
+	if err != nil {
+		return nil, err
+	}
+	var sb strings.Builder
+	if page.Title != "" {
+		fmt.Fprintf(&sb, "# %s\n\n", page.Title)
+	}
+	fmt.Fprintf(&sb, "Source: %s\n\n%s\n", page.URL, page.Markdown)
+	if page.Truncated {
+		fmt.Fprintf(&sb, "\n[Truncated to about %d tokens.]\n", page.Tokens)
+	}
+	return &tool.ToolResult{Success: true, Output: sb.String(), Data: page}, nil
+}
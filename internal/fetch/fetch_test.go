@@ -0,0 +1,154 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<html><head><title>Hello</title></head><body><nav>Menu</nav><main><p>Hello, <i>world</i>.</p></main></body></html>`)
+		case "/long":
+			w.Header().Set("Content-Type", "text/plain")
+			for i := 0; i < 100; i++ {
+				fmt.Fprintf(w, "Paragraph %d has some words in it.\n\n", i)
+			}
+		case "/moved":
+			http.Redirect(w, r, "/article", http.StatusFound)
+		case "/away":
+			http.Redirect(w, r, "http://denied.example.com/", http.StatusFound)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetch(t *testing.T) {
+	srv := newServer(t)
+	f := New(config.FetchConfig{Enabled: true, AllowPrivate: true, MaxTokens: 100})
+	ctx := context.Background()
+
+	page, err := f.Fetch(ctx, srv.URL+"/moved#top", 0)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if page.Title != "Hello" || page.Markdown != "Hello, _world_." || page.URL != srv.URL+"/article" || page.Truncated {
+		t.Errorf("page = %+v", page)
+	}
+
+	page, err = f.Fetch(ctx, srv.URL+"/long", 0)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if !page.Truncated || len(page.Markdown) > 400 || !strings.HasSuffix(page.Markdown, "in it.") {
+		t.Errorf("long page not cut at a paragraph: truncated=%v len=%d %q", page.Truncated, len(page.Markdown), page.Markdown)
+	}
+	if short, _ := f.Fetch(ctx, srv.URL+"/long", 20); len(short.Markdown) > 80 {
+		t.Errorf("max_tokens 20 returned %d bytes", len(short.Markdown))
+	}
+	if long, _ := f.Fetch(ctx, srv.URL+"/long", 100000); len(long.Markdown) > 400 {
+		t.Errorf("max_tokens above the config returned %d bytes", len(long.Markdown))
+	}
+
+	for path, want := range map[string]string{
+		"/missing": "404",
+		"/image":   "image/png",
+	} {
+		if _, err := f.Fetch(ctx, srv.URL+path, 0); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Fetch(%s) error = %v, want %q", path, err, want)
+		}
+	}
+}
+
+func TestFetch_Policy(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+
+	f := New(config.FetchConfig{Enabled: true})
+	if _, err := f.Fetch(ctx, srv.URL+"/article", 0); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("loopback fetch error = %v, want ErrPrivateAddress", err)
+	}
+	if _, err := f.Fetch(ctx, "file:///etc/passwd", 0); err == nil {
+		t.Error("file URL fetched")
+	}
+
+	f = New(config.FetchConfig{Enabled: true, AllowPrivate: true, Deny: []string{"denied.example.com"}})
+	if _, err := f.Fetch(ctx, srv.URL+"/away", 0); err == nil || !strings.Contains(err.Error(), "tools.fetch.deny") {
+		t.Errorf("redirect to a denied host error = %v", err)
+	}
+
+	f = New(config.FetchConfig{Enabled: true, AllowPrivate: true, Allow: []string{"example.com"}})
+	if _, err := f.Fetch(ctx, srv.URL+"/article", 0); err == nil || !strings.Contains(err.Error(), "tools.fetch.allow") {
+		t.Errorf("fetch outside the allow list error = %v", err)
+	}
+	for host, allowed := range map[string]bool{
+		"example.com":         true,
+		"docs.example.com":    true,
+		"notexample.com":      false,
+		"example.com.evil.io": false,
+	} {
+		u, _ := parseURL(host + "/x")
+		if err := f.check(u); (err == nil) != allowed {
+			t.Errorf("check(%s) = %v", host, err)
+		}
+	}
+
+	f = New(config.FetchConfig{Allow: []string{"example.com"}, Deny: []string{"*.example.com"}})
+	u, _ := parseURL("docs.example.com")
+	if err := f.check(u); err == nil {
+		t.Error("deny should win over allow")
+	}
+}
+
+func TestTools(t *testing.T) {
+	if add, disallow := Tools(config.FetchConfig{}); add != nil || disallow != nil {
+		t.Errorf("Tools(off) = %v, %v", add, disallow)
+	}
+	add, disallow := Tools(config.FetchConfig{Enabled: true})
+	if len(add) != 1 || add[0].Name() != ToolName || len(disallow) != 1 || disallow[0] != SDKToolName {
+		t.Fatalf("Tools(on) = %v, %v", add, disallow)
+	}
+	if got := add[0].(*Tool).fetcher.maxTokens; got != defaultMaxTokens {
+		t.Errorf("maxTokens = %d, want %d", got, defaultMaxTokens)
+	}
+}
+
+func TestTool_Execute(t *testing.T) {
+	srv := newServer(t)
+	tl := &Tool{fetcher: New(config.FetchConfig{Enabled: true, AllowPrivate: true})}
+	res, err := tl.Execute(context.Background(), map[string]any{"url": srv.URL + "/article"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if want := "# Hello\n\nSource: " + srv.URL + "/article\n\nHello, _world_.\n"; res.Output != want {
+		t.Errorf("output = %q, want %q", res.Output, want)
+	}
+	if _, err := tl.Execute(context.Background(), map[string]any{"url": ""}); err == nil {
+		t.Error("Execute without a url = nil error")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got, cut := Truncate("short", 10); got != "short" || cut {
+		t.Errorf("Truncate(short) = %q, %v", got, cut)
+	}
+	if got, cut := Truncate("aéééé", 1); got != "aé" || !cut {
+		t.Errorf("Truncate = %q, %v, want a whole rune", got, cut)
+	}
+}
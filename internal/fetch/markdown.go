@@ -0,0 +1,403 @@
+package fetch
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// skipped are elements that never hold the text of a page.
+var skipped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"nav": true, "aside": true, "footer": true, "form": true, "button": true,
+	"iframe": true, "svg": true, "canvas": true, "select": true, "input": true,
+	"textarea": true, "dialog": true, "head": true,
+}
+
+// skippedRoles are ARIA roles of page furniture.
+var skippedRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true,
+	"complementary": true, "search": true, "dialog": true, "alert": true,
+}
+
+// boilerplateWords mark elements by class or id as page furniture.
+var boilerplateWords = map[string]bool{
+	"cookie": true, "cookies": true, "consent": true, "sidebar": true,
+	"advert": true, "advertisement": true, "ad": true, "ads": true,
+	"promo": true, "newsletter": true, "share": true, "social": true,
+	"related": true, "comments": true, "breadcrumb": true, "breadcrumbs": true,
+	"menu": true, "popup": true, "modal": true, "banner": true, "subscribe": true,
+}
+
+var (
+	wordSplit  = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// Markdown returns the title of an HTML document and its main content as
+// markdown: the <article> or <main> element when there is one, without
+// navigation, scripts and similar boilerplate. Links are made absolute
+// against base.
+func Markdown(doc *html.Node, base *url.URL) (title, md string) {
+	title = pageTitle(doc)
+	root := find(doc, func(n *html.Node) bool { return n.Data == "article" })
+	if root == nil {
+		root = find(doc, func(n *html.Node) bool { return n.Data == "main" || attr(n, "role") == "main" })
+	}
+	whole := root == nil
+	if whole {
+		if root = find(doc, func(n *html.Node) bool { return n.Data == "body" }); root == nil {
+			root = doc
+		}
+	}
+	c := &converter{base: base, skipHeader: whole}
+	c.children(root)
+	return title, c.String()
+}
+
+type converter struct {
+	buf        bytes.Buffer
+	base       *url.URL
+	skipHeader bool  // a page-wide <header> is the site's, not the article's
+	lists      []int // per open list: 0 for bullets, else the next number
+	pre        bool
+}
+
+func (c *converter) String() string {
+	lines := strings.Split(c.buf.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func (c *converter) sub() *converter {
+	return &converter{base: c.base, skipHeader: c.skipHeader}
+}
+
+func (c *converter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.node(child)
+	}
+}
+
+func (c *converter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.text(n.Data)
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		c.children(n)
+		return
+	default:
+		return
+	}
+	if c.isBoilerplate(n) {
+		return
+	}
+
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		c.block()
+		c.buf.WriteString(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		c.children(n)
+		c.block()
+	case "p", "div", "section", "article", "main", "header", "figure", "figcaption", "dl", "dd", "dt", "address", "details", "summary":
+		c.block()
+		c.children(n)
+		c.block()
+	case "br":
+		c.buf.WriteString("\n")
+	case "hr":
+		c.block()
+		c.buf.WriteString("---")
+		c.block()
+	case "ul", "ol":
+		next := 0
+		if n.Data == "ol" {
+			next = 1
+		}
+		if len(c.lists) == 0 {
+			c.block()
+		}
+		c.lists = append(c.lists, next)
+		c.children(n)
+		c.lists = c.lists[:len(c.lists)-1]
+		if len(c.lists) == 0 {
+			c.block()
+		}
+	case "li":
+		c.line()
+		depth := max(len(c.lists), 1)
+		c.buf.WriteString(strings.Repeat("  ", depth-1))
+		if len(c.lists) > 0 && c.lists[len(c.lists)-1] > 0 {
+			c.buf.WriteString(strconv.Itoa(c.lists[len(c.lists)-1]) + ". ")
+			c.lists[len(c.lists)-1]++
+		} else {
+			c.buf.WriteString("- ")
+		}
+		c.children(n)
+		c.line()
+	case "pre":
+		c.block()
+		c.buf.WriteString("```\n")
+		c.pre = true
+		c.children(n)
+		c.pre = false
+		c.line()
+		c.buf.WriteString("```")
+		c.block()
+	case "code", "kbd", "samp":
+		if c.pre {
+			c.children(n)
+			return
+		}
+		c.wrap(n, "`")
+	case "strong", "b":
+		c.wrap(n, "**")
+	case "em", "i":
+		c.wrap(n, "_")
+	case "a":
+		c.link(n)
+	case "img":
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			c.text("[image: " + alt + "]")
+		}
+	case "blockquote":
+		inner := c.sub()
+		inner.children(n)
+		if text := inner.String(); text != "" {
+			c.block()
+			c.buf.WriteString("> " + strings.ReplaceAll(text, "\n", "\n> "))
+			c.block()
+		}
+	case "table":
+		c.table(n)
+	default:
+		c.children(n)
+	}
+}
+
+func (c *converter) isBoilerplate(n *html.Node) bool {
+	if skipped[n.Data] || (n.Data == "header" && c.skipHeader) {
+		return true
+	}
+	if skippedRoles[attr(n, "role")] || attr(n, "aria-hidden") == "true" || hasAttr(n, "hidden") {
+		return true
+	}
+	for _, w := range wordSplit.Split(strings.ToLower(attr(n, "class")+" "+attr(n, "id")), -1) {
+		if boilerplateWords[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// text writes inline text with runs of white space collapsed, except in
+// preformatted blocks.
+func (c *converter) text(s string) {
+	if c.pre {
+		c.buf.WriteString(s)
+		return
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" && !c.atLineStart() && !c.endsWith(" ") {
+			c.buf.WriteString(" ")
+		}
+		return
+	}
+	if isSpace(s[0]) && !c.atLineStart() && !c.endsWith(" ") {
+		c.buf.WriteString(" ")
+	}
+	c.buf.WriteString(strings.Join(fields, " "))
+	if isSpace(s[len(s)-1]) {
+		c.buf.WriteString(" ")
+	}
+}
+
+// wrap writes the children of n between marks, or nothing when they are
+// empty.
+func (c *converter) wrap(n *html.Node, mark string) {
+	inner := c.sub()
+	inner.children(n)
+	c.inline(inner.buf.String(), func(text string) string { return mark + text + mark })
+}
+
+func (c *converter) link(n *html.Node) {
+	inner := c.sub()
+	inner.children(n)
+	href := c.resolve(attr(n, "href"))
+	c.inline(inner.buf.String(), func(text string) string {
+		if href == "" || href == text {
+			return text
+		}
+		return "[" + text + "](" + href + ")"
+	})
+}
+
+// inline writes the rendered children raw as decorate turns them, keeping
+// the white space around them.
+func (c *converter) inline(raw string, decorate func(string) string) {
+	text := strings.Join(strings.Fields(raw), " ")
+	if text == "" {
+		c.text(raw)
+		return
+	}
+	if isSpace(raw[0]) {
+		c.text(" ")
+	}
+	c.buf.WriteString(decorate(text))
+	if isSpace(raw[len(raw)-1]) {
+		c.buf.WriteString(" ")
+	}
+}
+
+func (c *converter) table(n *html.Node) {
+	var rows [][]string
+	header := false
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var row []string
+			for cell := n.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+					continue
+				}
+				if cell.Data == "th" && len(rows) == 0 {
+					header = true
+				}
+				inner := c.sub()
+				inner.children(cell)
+				row = append(row, strings.ReplaceAll(strings.Join(strings.Fields(inner.String()), " "), "|", `\|`))
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if !header {
+		rows = append([][]string{make([]string, width)}, rows...)
+	}
+	c.block()
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		c.buf.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			c.buf.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	c.block()
+}
+
+func (c *converter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if c.base != nil {
+		u = c.base.ResolveReference(u)
+	}
+	return u.String()
+}
+
+// block ends the current block with a blank line.
+func (c *converter) block() {
+	if c.buf.Len() == 0 || c.endsWith("\n\n") {
+		return
+	}
+	if c.endsWith("\n") {
+		c.buf.WriteString("\n")
+		return
+	}
+	c.buf.WriteString("\n\n")
+}
+
+// line ends the current line.
+func (c *converter) line() {
+	if c.buf.Len() > 0 && !c.endsWith("\n") {
+		c.buf.WriteString("\n")
+	}
+}
+
+func (c *converter) atLineStart() bool {
+	return c.buf.Len() == 0 || c.endsWith("\n") || c.endsWith("- ") || c.endsWith("# ")
+}
+
+func (c *converter) endsWith(s string) bool {
+	return bytes.HasSuffix(c.buf.Bytes(), []byte(s))
+}
+
+func pageTitle(doc *html.Node) string {
+	if n := find(doc, func(n *html.Node) bool {
+		return n.Data == "meta" && attr(n, "property") == "og:title" && attr(n, "content") != ""
+	}); n != nil {
+		return strings.TrimSpace(attr(n, "content"))
+	}
+	if n := find(doc, func(n *html.Node) bool { return n.Data == "title" }); n != nil {
+		var sb strings.Builder
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			sb.WriteString(child.Data)
+		}
+		return strings.Join(strings.Fields(sb.String()), " ")
+	}
+	return ""
+}
+
+// find returns the first element under n, depth first, that match accepts.
+func find(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := find(child, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r' || b == '\f'
+}
@@ -0,0 +1,64 @@
+package fetch
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestMarkdown(t *testing.T) {
+	page := `<html><head><title>  Release
+notes </title><script>var x = 1;</script></head>
+<body>
+<header><a href="/">Home</a> <a href="/blog">Blog</a></header>
+<nav><ul><li>Docs</li></ul></nav>
+<article>
+<h1>Go 1.30</h1>
+<p>This release adds <b>iterators</b> for <a href="/pkg/maps">maps</a>, and <code>min</code>.</p>
+<div class="cookie-banner">We use cookies.</div>
+<ul><li>Faster builds</li><li>Smaller binaries<ol><li>linker</li><li>runtime</li></ol></li></ul>
+<pre><code>go install golang.org/dl/go1.30@latest
+go1.30 download</code></pre>
+<blockquote><p>Upgrade today.</p></blockquote>
+<table><tr><th>OS</th><th>Arch</th></tr><tr><td>linux</td><td>amd64</td></tr></table>
+</article>
+<footer>Copyright</footer>
+</body></html>`
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://go.dev/doc/go1.30")
+	title, md := Markdown(doc, base)
+	if title != "Release notes" {
+		t.Errorf("title = %q", title)
+	}
+	want := "# Go 1.30\n\n" +
+		"This release adds **iterators** for [maps](https://go.dev/pkg/maps), and `min`.\n\n" +
+		"- Faster builds\n- Smaller binaries\n  1. linker\n  2. runtime\n\n" +
+		"```\ngo install golang.org/dl/go1.30@latest\ngo1.30 download\n```\n\n" +
+		"> Upgrade today.\n\n" +
+		"| OS | Arch |\n| --- | --- |\n| linux | amd64 |"
+	if md != want {
+		t.Errorf("markdown =\n%s\n\nwant\n%s", md, want)
+	}
+}
+
+func TestMarkdown_WholePage(t *testing.T) {
+	page := `<html><head><meta property="og:title" content="Notes"></head><body>
+<header>Site name</header>
+<div id="sidebar">Popular posts</div>
+<p>First   paragraph.</p><p>Second<br>line.</p>
+<div role="navigation">Next page</div>
+</body></html>`
+	doc, _ := html.Parse(strings.NewReader(page))
+	title, md := Markdown(doc, nil)
+	if title != "Notes" {
+		t.Errorf("title = %q", title)
+	}
+	if want := "First paragraph.\n\nSecond\nline."; md != want {
+		t.Errorf("markdown = %q, want %q", md, want)
+	}
+}
@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/skills"
+)
+
+// pausedReply answers chat messages while the gateway is paused.
+const pausedReply = "I'm paused right now. Please try again later."
+
+const adminHelp = `Commands:
+/status - uptime, model and channels
+/skills - skills loaded for this channel
+/usage - turns and tokens since start
+/pause - stop answering chat messages
+/resume - answer chat messages again
+/restart - rebuild the agent runtime and reload skills`
+
+// isAdmin reports whether senderID is listed in the admins of channel.
+func (g *Gateway) isAdmin(channel, senderID string) bool {
+	return senderID != "" && slices.Contains(g.config().Channels.Admins()[channel], senderID)
+}
+
+// handleAdmin answers a control command from a channel admin without
+// asking the model. It reports false for anything else, including
+// commands from other senders, which go to the agent as usual.
+func (g *Gateway) handleAdmin(msg bus.InboundMessage) (string, bool) {
+	fields := strings.Fields(msg.Content)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") || !g.isAdmin(msg.Channel, msg.SenderID) {
+		return "", false
+	}
+	// Telegram group commands look like /status@my_bot.
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+
+	var reply string
+	switch command {
+	case "/help":
+		reply = adminHelp
+	case "/status":
+		reply = g.adminStatus()
+	case "/skills":
+		reply = g.adminSkills(msg.Channel)
+	case "/usage":
+		reply = fmt.Sprintf("Since %s: %d turns, %d input tokens, %d output tokens.",
+			g.started.Format("2006-01-02 15:04"), g.usage.turns.Load(), g.usage.inputTokens.Load(), g.usage.outputTokens.Load())
+		if cached := g.usage.cacheReadTokens.Load(); cached > 0 {
+			reply += fmt.Sprintf(" %d input tokens read from the prompt cache.", cached)
+		}
+	case "/pause":
+		g.paused.Store(true)
+		reply = "Paused. Chat messages get no reply until /resume; cron jobs and the heartbeat keep running."
+	case "/resume":
+		g.paused.Store(false)
+		reply = "Resumed."
+	case "/restart":
+		if err := g.reloadSkills(); err != nil {
+			reply = fmt.Sprintf("Restart failed, still on the previous runtime: %v", err)
+		} else {
+			reply = "Restarted the agent runtime."
+		}
+	default:
+		return "", false
+	}
+	log.Printf("[gateway] admin command %s from %s/%s", command, msg.Channel, msg.SenderID)
+	return reply, true
+}
+
+func (g *Gateway) adminStatus() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Up %s (since %s)\n", time.Since(g.started).Round(time.Second), g.started.Format("2006-01-02 15:04"))
+	cfg := g.config()
+	fmt.Fprintf(&b, "Model: %s (%s)\n", cfg.Models.Resolve(cfg.Agent.Model), cfg.Provider.Type)
+	if g.channels != nil {
+		channels := g.channels.EnabledChannels()
+		slices.Sort(channels)
+		fmt.Fprintf(&b, "Channels: %s\n", strings.Join(channels, ", "))
+	}
+	if g.sessions != nil {
+		fmt.Fprintf(&b, "Sessions: %d\n", g.sessions.active())
+	}
+	if g.pool != nil {
+		fmt.Fprintf(&b, "Session runtimes: %d live (up to %d)\n", g.pool.live(), g.pool.max)
+	}
+	if g.queue != nil {
+		waiting, running := g.queue.depth()
+		fmt.Fprintf(&b, "Queue: %d waiting, %d running (up to %d at once)\n", waiting, running, g.queue.limit())
+	}
+	if g.cron != nil {
+		fmt.Fprintf(&b, "Cron jobs: %d\n", len(g.cron.ListJobs()))
+	}
+	state := "answering"
+	if g.paused.Load() {
+		state = "paused"
+	}
+	fmt.Fprintf(&b, "State: %s", state)
+	return b.String()
+}
+
+func (g *Gateway) adminSkills(channel string) string {
+	g.runtimeMu.RLock()
+	regs := g.skillRegs
+	g.runtimeMu.RUnlock()
+	if scope, ok := g.config().Channels.SkillScopes()[channel]; ok {
+		regs = skills.Scope(regs, scope.Allow, scope.Deny)
+	}
+	if len(regs) == 0 {
+		return "No skills loaded."
+	}
+	names := make([]string, 0, len(regs))
+	for _, reg := range regs {
+		names = append(names, reg.Definition.Name)
+	}
+	slices.Sort(names)
+	return fmt.Sprintf("Skills (%d): %s", len(names), strings.Join(names, ", "))
+}
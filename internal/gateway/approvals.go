@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+)
+
+const defaultApprovalTimeout = 5 * time.Minute
+
+// approvals holds the tool calls waiting for a chat user to approve them.
+// The zero value is ready to use.
+type approvals struct {
+	mu      sync.Mutex
+	pending map[string]*approval
+}
+
+type approval struct {
+	channel, chatID, senderID string
+	answer                    chan bool
+}
+
+func (a *approvals) add(pending *approval) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	id := fmt.Sprintf("%x", b)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pending == nil {
+		a.pending = make(map[string]*approval)
+	}
+	a.pending[id] = pending
+	return id
+}
+
+func (a *approvals) remove(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.pending, id)
+}
+
+// approvalAsker asks in the chat msg came from whether a tool call may run.
+// The sender of msg and the channel's admins can answer.
+func (g *Gateway) approvalAsker(msg bus.InboundMessage) permission.AskFunc {
+	return func(ctx context.Context, req permission.Request) (bool, error) {
+		pending := &approval{channel: msg.Channel, chatID: msg.ChatID, senderID: msg.SenderID, answer: make(chan bool, 1)}
+		id := g.approvals.add(pending)
+		defer g.approvals.remove(id)
+
+		log.Printf("[gateway] asking %s/%s to approve %s (%s)", msg.Channel, msg.SenderID, req, id)
+		g.bus.Outbound <- bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: fmt.Sprintf("🔐 May I run %s?\nReply /approve %s or /deny %s.", req, id, id),
+			Buttons: []bus.Button{
+				{Text: "Approve", Data: "/approve " + id},
+				{Text: "Deny", Data: "/deny " + id},
+			},
+		}
+
+		timeout := time.Duration(g.config().Permissions.AskTimeout) * time.Second
+		if timeout <= 0 {
+			timeout = defaultApprovalTimeout
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case ok := <-pending.answer:
+			return ok, nil
+		case <-timer.C:
+			g.bus.Outbound <- bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: fmt.Sprintf("No answer to %s, so I didn't run it.", id)}
+			return false, errors.New("no answer in time")
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// handleApproval answers /approve and /deny for a pending tool call. It
+// reports false for any other message.
+func (g *Gateway) handleApproval(msg bus.InboundMessage) (string, bool) {
+	fields := strings.Fields(msg.Content)
+	if len(fields) != 2 {
+		return "", false
+	}
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	if command != "/approve" && command != "/deny" {
+		return "", false
+	}
+	id := fields[1]
+
+	g.approvals.mu.Lock()
+	pending, ok := g.approvals.pending[id]
+	ok = ok && pending.channel == msg.Channel && pending.chatID == msg.ChatID
+	allowed := ok && (msg.SenderID == pending.senderID || g.isAdmin(msg.Channel, msg.SenderID))
+	if allowed {
+		delete(g.approvals.pending, id)
+	}
+	g.approvals.mu.Unlock()
+	switch {
+	case !ok:
+		return fmt.Sprintf("Nothing is waiting for approval as %s.", id), true
+	case !allowed:
+		return "Only the person who asked, or an admin, can answer this.", true
+	}
+
+	approved := command == "/approve"
+	pending.answer <- approved
+	log.Printf("[gateway] %s/%s answered %s: %s", msg.Channel, msg.SenderID, id, command)
+	if approved {
+		return "Approved.", true
+	}
+	return "Denied.", true
+}
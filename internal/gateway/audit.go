@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"log"
+
+	"github.com/stellarlinkco/myclaw/internal/audit"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+)
+
+// auditInbound logs a message as it arrives, before it is answered or
+// turned away.
+func (g *Gateway) auditInbound(msg bus.InboundMessage) {
+	g.recordAudit(audit.Entry{
+		Kind:    audit.Inbound,
+		Source:  audit.Source{Channel: msg.Channel, ChatID: msg.ChatID, Sender: msg.SenderID},
+		Content: msg.Content,
+	})
+}
+
+// auditOutbound logs every message the gateway sends: replies, command
+// answers, reminders and alerts.
+func (g *Gateway) auditOutbound(msg bus.OutboundMessage) {
+	g.recordAudit(audit.Entry{
+		Kind:    audit.Outbound,
+		Source:  audit.Source{Channel: msg.Channel, ChatID: msg.ChatID},
+		Content: msg.Content,
+	})
+}
+
+func (g *Gateway) recordAudit(e audit.Entry) {
+	if err := g.audit.Append(e); err != nil {
+		log.Printf("[audit] record failed: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"time"
+
+	rcron "github.com/robfig/cron/v3"
+	"github.com/stellarlinkco/myclaw/internal/backup"
+)
+
+// backupLoop backs the assistant up on the configured schedule and drops
+// backups beyond backup.keep. In cluster mode only the leader backs up.
+func (g *Gateway) backupLoop(ctx context.Context) {
+	bc := g.config().Backup
+	parser := rcron.NewParser(rcron.Second | rcron.Minute | rcron.Hour | rcron.Dom | rcron.Month | rcron.Dow | rcron.Descriptor)
+	schedule, err := parser.Parse(bc.Schedule)
+	if err != nil {
+		log.Printf("[gateway] backups disabled, bad schedule %q: %v", bc.Schedule, err)
+		return
+	}
+	log.Printf("[gateway] backups scheduled (%s)", bc.Schedule)
+
+	for {
+		timer := time.NewTimer(time.Until(schedule.Next(time.Now())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if !g.shouldRunJobs() {
+			continue
+		}
+		g.backup(ctx)
+	}
+}
+
+func (g *Gateway) backup(ctx context.Context) {
+	cfg := g.config()
+	target, err := backup.NewTarget(cfg.Backup)
+	if err != nil {
+		log.Printf("[gateway] backup failed: %v", err)
+		return
+	}
+	info, dropped, err := backup.Create(ctx, cfg, target, cfg.Backup.Keep, time.Now())
+	if err != nil {
+		log.Printf("[gateway] backup failed: %v", err)
+		return
+	}
+	log.Printf("[gateway] backed up to %s: %s (%d bytes, %d old dropped)", target, info.Name, info.Size, len(dropped))
+}
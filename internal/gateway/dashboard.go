@@ -0,0 +1,361 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/channel"
+	"github.com/stellarlinkco/myclaw/internal/cron"
+	"github.com/stellarlinkco/myclaw/internal/session"
+	"github.com/stellarlinkco/myclaw/internal/usage"
+)
+
+//go:embed static/dashboard.html
+var dashboardPage []byte
+
+const (
+	// dashboardCookie holds a hash of the dashboard token once logged in.
+	dashboardCookie = "myclaw_dashboard"
+	// dashboardLogLines is how much of the log the dashboard keeps.
+	dashboardLogLines = 500
+	// dashboardSessions is how many conversations are listed per channel.
+	dashboardSessions = 10
+	// dashboardUsageDays is the default and longest span of the usage chart.
+	dashboardUsageDays, dashboardMaxUsageDays = 14, 90
+)
+
+// logTail keeps the last lines written to the log.
+type logTail struct {
+	mu    sync.Mutex
+	max   int
+	lines []string
+}
+
+func newLogTail(max int) *logTail {
+	return &logTail{max: max}
+}
+
+func (t *logTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		t.lines = append(t.lines, line)
+	}
+	if over := len(t.lines) - t.max; over > 0 {
+		t.lines = slices.Delete(t.lines, 0, over)
+	}
+	return len(p), nil
+}
+
+// Lines returns the lines kept, oldest first.
+func (t *logTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.lines)
+}
+
+// captureLogs copies the log to g.logs for the dashboard until the returned
+// func is called.
+func (g *Gateway) captureLogs() func() {
+	g.logs = newLogTail(dashboardLogLines)
+	prev := log.Writer()
+	log.SetOutput(io.MultiWriter(prev, g.logs))
+	return func() { log.SetOutput(prev) }
+}
+
+// mountDashboard serves the dashboard page and the JSON it polls under
+// /dashboard/ on mux.
+func (g *Gateway) mountDashboard(mux *http.ServeMux) {
+	mux.Handle("GET /dashboard", http.RedirectHandler("/dashboard/", http.StatusMovedPermanently))
+	mux.HandleFunc("POST /dashboard/login", g.dashboardLogin)
+	mux.Handle("GET /dashboard/{$}", g.dashboardAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(dashboardPage)
+	})))
+	api := map[string]func(*http.Request) any{
+		"status":   func(*http.Request) any { return g.dashboardStatus() },
+		"sessions": func(*http.Request) any { return g.dashboardConversations() },
+		"usage":    g.dashboardUsage,
+		"cron":     func(*http.Request) any { return g.dashboardCron() },
+		"skills":   func(*http.Request) any { return g.dashboardSkills() },
+		"logs":     func(*http.Request) any { return g.dashboardLogs() },
+	}
+	for name, fn := range api {
+		mux.Handle("GET /dashboard/api/"+name, g.dashboardAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			_ = json.NewEncoder(w).Encode(fn(r))
+		})))
+	}
+}
+
+// dashboardAuth lets a request through when it carries the dashboard token,
+// as a bearer token or the login cookie, or, with no token configured, when
+// it comes from this machine.
+func (g *Gateway) dashboardAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := g.config().Gateway.Dashboard.Token
+		if token == "" {
+			if !isLoopback(r.RemoteAddr) {
+				http.Error(w, "The dashboard only answers this machine. Set gateway.dashboard.token to open it from elsewhere.", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && equalStrings(bearer, token) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if c, err := r.Cookie(dashboardCookie); err == nil && equalStrings(c.Value, dashboardSession(token)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/dashboard/api/") {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, dashboardLoginPage)
+	})
+}
+
+const dashboardLoginPage = `<!DOCTYPE html>
+<html lang="en"><head><meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>myclaw dashboard</title></head>
+<body style="font-family: sans-serif; max-width: 320px; margin: 15vh auto;">
+<h1 style="font-size: 1.2em;">myclaw dashboard</h1>
+<form method="post" action="/dashboard/login">
+<input type="password" name="token" placeholder="gateway.dashboard.token" autofocus style="width: 100%; padding: 8px; box-sizing: border-box;">
+<button type="submit" style="margin-top: 8px; padding: 8px 16px;">Log in</button>
+</form></body></html>`
+
+// dashboardLogin sets the login cookie for a correct token.
+func (g *Gateway) dashboardLogin(w http.ResponseWriter, r *http.Request) {
+	token := g.config().Gateway.Dashboard.Token
+	if token == "" || !equalStrings(r.PostFormValue("token"), token) {
+		if token != "" {
+			log.Printf("[gateway] dashboard login with a wrong token from %s", r.RemoteAddr)
+		}
+		http.Redirect(w, r, "/dashboard/", http.StatusSeeOther)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     dashboardCookie,
+		Value:    dashboardSession(token),
+		Path:     "/dashboard/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/dashboard/", http.StatusSeeOther)
+}
+
+// dashboardSession is the cookie value for token, so that the cookie does
+// not hold the token itself.
+func dashboardSession(token string) string {
+	sum := sha256.Sum256([]byte("myclaw dashboard\x00" + token))
+	return hex.EncodeToString(sum[:])
+}
+
+func equalStrings(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+type dashboardQueue struct {
+	Waiting int `json:"waiting"`
+	Running int `json:"running"`
+	Limit   int `json:"limit"`
+}
+
+type dashboardTotals struct {
+	Turns           int64 `json:"turns"`
+	InputTokens     int64 `json:"inputTokens"`
+	OutputTokens    int64 `json:"outputTokens"`
+	CacheReadTokens int64 `json:"cacheReadTokens"`
+}
+
+// dashboardStatusReport is what /dashboard/api/status answers.
+type dashboardStatusReport struct {
+	Started  time.Time                 `json:"started"`
+	Uptime   string                    `json:"uptime"`
+	Model    string                    `json:"model"`
+	Provider string                    `json:"provider"`
+	State    string                    `json:"state"` // answering, paused or draining
+	Leader   *bool                     `json:"leader,omitempty"`
+	Channels map[string]channel.Status `json:"channels"`
+	Sessions int                       `json:"sessions"`
+	Inflight int64                     `json:"inflight"`
+	Queue    *dashboardQueue           `json:"queue,omitempty"`
+	Usage    dashboardTotals           `json:"usage"` // since the gateway started
+}
+
+func (g *Gateway) dashboardStatus() dashboardStatusReport {
+	cfg := g.config()
+	report := dashboardStatusReport{
+		Started:  g.started,
+		Uptime:   time.Since(g.started).Round(time.Second).String(),
+		Model:    cfg.Models.Resolve(cfg.Agent.Model),
+		Provider: cfg.Provider.Type,
+		State:    "answering",
+		Channels: map[string]channel.Status{},
+		Inflight: g.inflight.Load(),
+		Usage: dashboardTotals{
+			Turns:           g.usage.turns.Load(),
+			InputTokens:     g.usage.inputTokens.Load(),
+			OutputTokens:    g.usage.outputTokens.Load(),
+			CacheReadTokens: g.usage.cacheReadTokens.Load(),
+		},
+	}
+	switch {
+	case g.draining.Load():
+		report.State = "draining"
+	case g.paused.Load():
+		report.State = "paused"
+	}
+	if g.coord != nil {
+		leader := g.coord.IsLeader()
+		report.Leader = &leader
+	}
+	if g.channels != nil {
+		report.Channels = g.channels.Status()
+	}
+	if g.sessions != nil {
+		report.Sessions = g.sessions.active()
+	}
+	if g.queue != nil {
+		waiting, running := g.queue.depth()
+		report.Queue = &dashboardQueue{Waiting: waiting, Running: running, Limit: g.queue.limit()}
+	}
+	return report
+}
+
+// dashboardConversation lists the latest conversations of one channel.
+type dashboardConversation struct {
+	Channel  string            `json:"channel"`
+	Sessions []session.Summary `json:"sessions"`
+}
+
+// dashboardConversations groups the saved sessions by the channel their ID
+// starts with, newest first, keeping the latest few of each.
+func (g *Gateway) dashboardConversations() []dashboardConversation {
+	list, err := session.NewStore(g.config().Agent.Workspace).List()
+	if err != nil {
+		log.Printf("[gateway] dashboard: %v", err)
+	}
+	byChannel := map[string][]session.Summary{}
+	for _, s := range list {
+		name, _, ok := strings.Cut(s.ID, ":")
+		if !ok {
+			name = "other"
+		}
+		if len(byChannel[name]) < dashboardSessions {
+			byChannel[name] = append(byChannel[name], s)
+		}
+	}
+	out := []dashboardConversation{}
+	for name, sessions := range byChannel {
+		out = append(out, dashboardConversation{Channel: name, Sessions: sessions})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Channel < out[j].Channel })
+	return out
+}
+
+// dashboardUsageReport is what /dashboard/api/usage answers: the ledger's
+// totals for each of the last days, including days without runs.
+type dashboardUsageReport struct {
+	Days  []usage.Totals `json:"days"`
+	Total usage.Totals   `json:"total"`
+}
+
+func (g *Gateway) dashboardUsage(r *http.Request) any {
+	days := dashboardUsageDays
+	if n, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && n > 0 {
+		days = min(n, dashboardMaxUsageDays)
+	}
+	now := time.Now()
+	y, m, d := now.Date()
+	since := time.Date(y, m, d-days+1, 0, 0, 0, 0, time.Local)
+	entries, err := usage.Read(usage.Path(g.config().Agent.Workspace), since)
+	if err != nil {
+		log.Printf("[gateway] dashboard: %v", err)
+	}
+	groups, total := usage.Summarize(entries, "day")
+	byDay := make(map[string]usage.Totals, len(groups))
+	for _, t := range groups {
+		byDay[t.Key] = t
+	}
+	report := dashboardUsageReport{Days: make([]usage.Totals, 0, days), Total: total}
+	for day := since; !day.After(now); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		t, ok := byDay[key]
+		if !ok {
+			t = usage.Totals{Key: key}
+		}
+		report.Days = append(report.Days, t)
+	}
+	return report
+}
+
+// dashboardCron lists the cron jobs, next to run first.
+func (g *Gateway) dashboardCron() []cron.CronJob {
+	jobs := []cron.CronJob{}
+	if g.cron != nil {
+		jobs = append(jobs, g.cron.ListJobs()...)
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		a, b := jobs[i].State.NextRunAtMs, jobs[j].State.NextRunAtMs
+		if (a == 0) != (b == 0) {
+			return b == 0
+		}
+		return a < b
+	})
+	return jobs
+}
+
+type dashboardSkill struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+func (g *Gateway) dashboardSkills() []dashboardSkill {
+	g.runtimeMu.RLock()
+	regs := g.skillRegs
+	g.runtimeMu.RUnlock()
+	out := make([]dashboardSkill, 0, len(regs))
+	for _, reg := range regs {
+		out = append(out, dashboardSkill{Name: reg.Definition.Name, Description: reg.Definition.Description})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (g *Gateway) dashboardLogs() []string {
+	if g.logs == nil {
+		return []string{}
+	}
+	return g.logs.Lines()
+}
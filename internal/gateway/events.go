@@ -0,0 +1,259 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/stellarlinkco/myclaw/internal/audit"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/cron"
+)
+
+// Event types sent on /events and to webhooks.
+const (
+	EventMessageReceived = "message.received"
+	EventMessageSent     = "message.sent"
+	EventToolCall        = "tool.call"
+	EventCronFired       = "cron.fired"
+	EventReminderDue     = "reminder.due"
+	EventTaskFinished    = "task.finished"
+	EventMemoryWritten   = "memory.written"
+	EventBudgetExceeded  = "budget.exceeded"
+	EventError           = "error"
+)
+
+var eventTypes = []string{EventMessageReceived, EventMessageSent, EventToolCall, EventCronFired, EventReminderDue, EventTaskFinished, EventMemoryWritten, EventBudgetExceeded, EventError}
+
+const (
+	// eventBuffer is how many events a slow client may fall behind by
+	// before further ones are dropped for it.
+	eventBuffer = 256
+	// eventTextChars is how much of a message an event carries.
+	eventTextChars = 500
+	// eventHeartbeat keeps idle streams from being closed by proxies.
+	eventHeartbeat = 15 * time.Second
+)
+
+// Event is one thing the gateway did, as sent on /events.
+type Event struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Channel string    `json:"channel,omitempty"`
+	ChatID  string    `json:"chatId,omitempty"`
+	Sender  string    `json:"sender,omitempty"`
+	Session string    `json:"session,omitempty"`
+	Tool    string    `json:"tool,omitempty"`
+	Job     string    `json:"job,omitempty"`
+	Task    string    `json:"task,omitempty"`
+	Status  string    `json:"status,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	Text    string    `json:"text,omitempty"`
+	Error   string    `json:"error,omitempty"`
+
+	SpentUSD  float64 `json:"spentUsd,omitempty"`
+	BudgetUSD float64 `json:"budgetUsd,omitempty"`
+}
+
+// eventHub fans events out to the clients of /events. Publishing never
+// blocks: a client that falls behind misses events.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan Event]struct{})}
+}
+
+func (h *eventHub) subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBuffer)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs, ch)
+		h.mu.Unlock()
+	}
+}
+
+func (h *eventHub) publish(ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// emit publishes ev when /events or webhooks are on.
+func (g *Gateway) emit(ev Event) {
+	if g.events == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	ev.Text = truncate(ev.Text, eventTextChars)
+	g.events.publish(ev)
+}
+
+func (g *Gateway) emitInbound(msg bus.InboundMessage) {
+	g.emit(Event{Type: EventMessageReceived, Channel: msg.Channel, ChatID: msg.ChatID, Sender: msg.SenderID, Text: msg.Content})
+}
+
+func (g *Gateway) emitOutbound(msg bus.OutboundMessage) {
+	g.emit(Event{Type: EventMessageSent, Channel: msg.Channel, ChatID: msg.ChatID, Text: msg.Content})
+}
+
+// eventsMiddleware emits an event for each tool call the agent makes, and
+// another when the call wrote to the memory directory.
+func (g *Gateway) eventsMiddleware() middleware.Middleware {
+	return middleware.Funcs{
+		Identifier: "events",
+		OnAfterTool: func(ctx context.Context, st *middleware.State) error {
+			call, ok := st.ToolCall.(agent.ToolCall)
+			if !ok {
+				return nil
+			}
+			src := audit.SourceOf(ctx)
+			ev := Event{Type: EventToolCall, Channel: src.Channel, ChatID: src.ChatID, Sender: src.Sender, Session: src.Session, Tool: call.Name}
+			if res, ok := st.ToolResult.(agent.ToolResult); ok {
+				if failed, _ := res.Metadata["is_error"].(bool); failed {
+					ev.Error, _ = res.Metadata["error"].(string)
+					if ev.Error == "" {
+						ev.Error = "failed"
+					}
+				}
+			}
+			g.emit(ev)
+			if ev.Error == "" {
+				if path := g.memoryPath(call); path != "" {
+					g.emit(Event{Type: EventMemoryWritten, Channel: src.Channel, ChatID: src.ChatID, Sender: src.Sender, Session: src.Session, Tool: call.Name, Path: path})
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// memoryPath returns the file in the memory directory that call wrote, if
+// any.
+func (g *Gateway) memoryPath(call agent.ToolCall) string {
+	if g.mem == nil {
+		return ""
+	}
+	switch call.Name {
+	case "Write", "Edit", "MultiEdit":
+	default:
+		return ""
+	}
+	path, _ := call.Input["file_path"].(string)
+	if path == "" {
+		path, _ = call.Input["path"].(string)
+	}
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(g.config().Agent.Workspace, path)
+	}
+	rel, err := filepath.Rel(filepath.Dir(g.mem.LongTermPath()), filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return path
+}
+
+// mountEvents serves the event stream at /events. A "types" query
+// parameter, a comma-separated list, narrows it to those event types.
+func (g *Gateway) mountEvents(mux *http.ServeMux) {
+	mux.Handle("GET /events", g.eventsAuth(http.HandlerFunc(g.serveEvents)))
+}
+
+// eventsAuth lets a request through when it carries the events token, as
+// a bearer token or a token query parameter (browsers' EventSource cannot
+// set headers), or, with no token configured, when it comes from this
+// machine.
+func (g *Gateway) eventsAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := g.config().Gateway.Events.Token
+		if token == "" {
+			if !isLoopback(r.RemoteAddr) {
+				http.Error(w, "The event stream only answers this machine. Set gateway.events.token to open it from elsewhere.", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			given = r.URL.Query().Get("token")
+		}
+		if given == "" || !equalStrings(given, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (g *Gateway) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || g.events == nil {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	var types map[string]bool
+	if q := r.URL.Query().Get("types"); q != "" {
+		types = make(map[string]bool)
+		for _, t := range strings.Split(q, ",") {
+			types[strings.TrimSpace(t)] = true
+		}
+	}
+	events, cancel := g.events.subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev := <-events:
+			if types != nil && !types[ev.Type] {
+				continue
+			}
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// cronJobName is how events name job: by its name, or its ID without one.
+func cronJobName(job cron.CronJob) string {
+	if job.Name != "" {
+		return job.Name
+	}
+	return job.ID
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	rcron "github.com/robfig/cron/v3"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/cron"
+	"github.com/stellarlinkco/myclaw/internal/feeds"
+)
+
+const feedsSessionID = "feed-digest"
+
+// feedDigest is the feed watcher with its schedule and where digests go.
+type feedDigest struct {
+	watcher  *feeds.Watcher
+	spec     string
+	schedule rcron.Schedule
+	targets  []cron.Target
+}
+
+// setupFeeds prepares the feed digest from cfg.Feeds; without feeds it does
+// nothing.
+func (g *Gateway) setupFeeds() error {
+	fc := g.config().Feeds
+	if len(fc.Feeds) == 0 {
+		return nil
+	}
+	spec := fc.Schedule
+	if spec == "" {
+		spec = config.DefaultFeedsSchedule
+	}
+	parser := rcron.NewParser(rcron.Second | rcron.Minute | rcron.Hour | rcron.Dom | rcron.Month | rcron.Dow | rcron.Descriptor)
+	schedule, err := parser.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("feeds: bad schedule %q: %w", spec, err)
+	}
+	fd := &feedDigest{spec: spec, schedule: schedule}
+	for _, s := range fc.Deliver {
+		target, err := cron.ParseTarget(s)
+		if err != nil {
+			return fmt.Errorf("feeds: %w", err)
+		}
+		fd.targets = append(fd.targets, target)
+	}
+	fd.watcher = feeds.NewWatcher(fc, feeds.NewStore(feeds.Path(g.config().Agent.Workspace)))
+	g.feeds = fd
+	return nil
+}
+
+// feedsLoop sends a digest of new feed items on the configured schedule. In
+// cluster mode only the leader checks the feeds.
+func (g *Gateway) feedsLoop(ctx context.Context) {
+	log.Printf("[gateway] feed digest scheduled (%s, %d feeds)", g.feeds.spec, len(g.config().Feeds.Feeds))
+	for {
+		timer := time.NewTimer(time.Until(g.feeds.schedule.Next(time.Now())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if g.coord != nil && !g.coord.IsLeader() {
+			continue
+		}
+		g.sendFeedDigest(ctx)
+	}
+}
+
+// sendFeedDigest has the agent summarize the new feed items and delivers
+// the result. Items are marked seen only once the digest is written, so a
+// failed run tries them again next time.
+func (g *Gateway) sendFeedDigest(ctx context.Context) {
+	updates, err := g.feeds.watcher.Check(ctx)
+	if err != nil {
+		log.Printf("[gateway] feeds: %v", err)
+	}
+	if prompt := feeds.Digest(updates); prompt != "" {
+		digest, err := g.summarizer(feedsSessionID)(ctx, prompt)
+		if err != nil {
+			log.Printf("[gateway] feed digest error: %v", err)
+			return
+		}
+		g.deliver(cron.CronJob{ID: "feeds", Name: "Feed digest"}, g.feeds.targets, digest)
+	}
+	if err := g.feeds.watcher.Commit(updates, time.Now()); err != nil {
+		log.Printf("[gateway] feeds: save seen items: %v", err)
+	}
+}
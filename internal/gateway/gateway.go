@@ -2,23 +2,53 @@ package gateway
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
 	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/audit"
 	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/calendar"
 	"github.com/stellarlinkco/myclaw/internal/channel"
+	"github.com/stellarlinkco/myclaw/internal/choices"
+	"github.com/stellarlinkco/myclaw/internal/cluster"
 	"github.com/stellarlinkco/myclaw/internal/config"
 	"github.com/stellarlinkco/myclaw/internal/cron"
+	"github.com/stellarlinkco/myclaw/internal/deadletter"
+	"github.com/stellarlinkco/myclaw/internal/delegate"
+	"github.com/stellarlinkco/myclaw/internal/fetch"
+	"github.com/stellarlinkco/myclaw/internal/github"
 	"github.com/stellarlinkco/myclaw/internal/heartbeat"
+	"github.com/stellarlinkco/myclaw/internal/homeassistant"
+	"github.com/stellarlinkco/myclaw/internal/imagegen"
+	"github.com/stellarlinkco/myclaw/internal/kb"
+	"github.com/stellarlinkco/myclaw/internal/mailbox"
+	"github.com/stellarlinkco/myclaw/internal/media"
 	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+	"github.com/stellarlinkco/myclaw/internal/prompt"
+	"github.com/stellarlinkco/myclaw/internal/provider"
+	"github.com/stellarlinkco/myclaw/internal/ratelimit"
+	"github.com/stellarlinkco/myclaw/internal/redact"
+	"github.com/stellarlinkco/myclaw/internal/reminders"
+	"github.com/stellarlinkco/myclaw/internal/search"
 	"github.com/stellarlinkco/myclaw/internal/skills"
+	"github.com/stellarlinkco/myclaw/internal/tasks"
+	"github.com/stellarlinkco/myclaw/internal/todo"
+	"github.com/stellarlinkco/myclaw/internal/tracing"
+	"github.com/stellarlinkco/myclaw/internal/usage"
 )
 
 // Runtime interface for agent runtime (allows mocking in tests)
@@ -51,41 +81,90 @@ type Options struct {
 
 // DefaultRuntimeFactory creates the default agentsdk-go runtime
 func DefaultRuntimeFactory(cfg *config.Config, sysPrompt string) (Runtime, error) {
-	return newRuntime(cfg, sysPrompt, nil)
+	return newRuntime(cfg, sysPrompt, nil, nil)
 }
 
-func newRuntime(cfg *config.Config, sysPrompt string, skillRegs []api.SkillRegistration) (Runtime, error) {
-	var provider api.ModelFactory
-	switch cfg.Provider.Type {
-	case "openai":
-		provider = &model.OpenAIProvider{
-			APIKey:    cfg.Provider.APIKey,
-			BaseURL:   cfg.Provider.BaseURL,
-			ModelName: cfg.Agent.Model,
-			MaxTokens: cfg.Agent.MaxTokens,
-		}
-	default: // "anthropic" or empty
-		provider = &model.AnthropicProvider{
-			APIKey:    cfg.Provider.APIKey,
-			BaseURL:   cfg.Provider.BaseURL,
-			ModelName: cfg.Agent.Model,
-			MaxTokens: cfg.Agent.MaxTokens,
-		}
+// newRuntime builds a runtime with skillRegs, and with tools added to the
+// tools those skills contribute. extra middleware runs after the built-in
+// ones.
+func newRuntime(cfg *config.Config, sysPrompt string, skillRegs []api.SkillRegistration, tools []tool.Tool, extra ...middleware.Middleware) (Runtime, error) {
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := prompt.Load(cfg)
+	if err != nil {
+		log.Printf("[gateway] %v; using AGENTS.md and SOUL.md as written", err)
 	}
+	modelFactory := tracing.ModelFactory(redact.ModelFactory(provider.New(cfg), redactor), cfg.Models.Resolve(cfg.Agent.Model))
+	modelFactory = prompt.ModelFactory(modelFactory, tmpl)
+	skillRegs = tracing.Skills(skillRegs)
+	policy, err := permission.NewPolicy(cfg.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	searchTools, disallowed, err := search.Tools(cfg.Tools.Search)
+	if err != nil {
+		return nil, err
+	}
+	fetchTools, fetchDisallowed := fetch.Tools(cfg.Tools.Fetch)
+	tools = append(append(append(skills.Tools(skillRegs, skills.CommandOptionsFromConfig(cfg.Skills)), searchTools...), fetchTools...), tools...)
+	if calendarTools, err := calendar.Tools(cfg.Calendar); err != nil {
+		log.Printf("[calendar] calendar tools unavailable: %v", err)
+	} else {
+		tools = append(tools, calendarTools...)
+	}
+	if mailTools, err := mailbox.Tools(cfg.Mail); err != nil {
+		log.Printf("[mail] email tools unavailable: %v", err)
+	} else {
+		tools = append(tools, mailTools...)
+	}
+	if githubTools, err := github.Tools(cfg.GitHub); err != nil {
+		log.Printf("[github] GitHub tools unavailable: %v", err)
+	} else {
+		tools = append(tools, githubTools...)
+	}
+	if homeTools, err := homeassistant.Tools(cfg.HomeAssistant); err != nil {
+		log.Printf("[homeassistant] home tools unavailable: %v", err)
+	} else {
+		tools = append(tools, homeTools...)
+	}
+	if imageTools, err := imagegen.Tools(cfg.Media.Images, cfg.Agent.Workspace); err != nil {
+		log.Printf("[imagegen] image generation unavailable: %v", err)
+	} else {
+		tools = append(tools, imageTools...)
+	}
+	tools = append(tools, todo.Tools(todo.NewStore(todo.Path(cfg.Agent.Workspace)))...)
+	disallowed = append(disallowed, fetchDisallowed...)
+	tools = append(tools, delegate.Tools(cfg, tools, disallowed)...)
+
+	middlewares := []middleware.Middleware{tracing.Middleware()}
+	if auditLog := audit.Open(cfg); auditLog != nil {
+		middlewares = append(middlewares, audit.Middleware(auditLog))
+	}
+	middlewares = append(middlewares, extra...)
 
 	rt, err := api.New(context.Background(), api.Options{
-		ProjectRoot:   cfg.Agent.Workspace,
-		ModelFactory:  provider,
-		SystemPrompt:  sysPrompt,
-		MaxIterations: cfg.Agent.MaxToolIterations,
-		MCPServers:    cfg.MCP.Servers,
-		TokenTracking: cfg.TokenTracking.Enabled,
+		ProjectRoot:    cfg.Agent.Workspace,
+		ModelFactory:   modelFactory,
+		Middleware:     middlewares,
+		HookMiddleware: permission.HookMiddleware(policy),
+		SystemPrompt:   sysPrompt,
+		MaxIterations:  cfg.Agent.MaxToolIterations,
+		MCPServers:     cfg.MCP.Specs(),
+		TokenTracking:  cfg.TokenTracking.Enabled,
+		MaxSessions:    cfg.Sessions.MaxSessions,
+		// AGENTS.md, SOUL.md and memory come first in the system prompt and
+		// skills are tools, so all of them fall in the cached prefix.
+		DefaultEnableCache: cfg.Provider.PromptCache,
 		AutoCompact: api.CompactConfig{
 			Enabled:       cfg.AutoCompact.Enabled,
 			Threshold:     cfg.AutoCompact.Threshold,
 			PreserveCount: cfg.AutoCompact.PreserveCount,
 		},
-		Skills: skillRegs,
+		Skills:          skillRegs,
+		CustomTools:     tools,
+		DisallowedTools: disallowed,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create runtime: %w", err)
@@ -93,16 +172,81 @@ func newRuntime(cfg *config.Config, sysPrompt string, skillRegs []api.SkillRegis
 	return &runtimeAdapter{rt: rt}, nil
 }
 
+const (
+	deadLetterRetryInterval = 30 * time.Second
+	reminderCheckInterval   = 10 * time.Second
+	deadLetterAlertKey      = "deadletter_alert"
+	drainPollInterval       = 50 * time.Millisecond
+)
+
 type Gateway struct {
-	cfg        *config.Config
-	bus        *bus.MessageBus
-	runtime    Runtime
-	channels   *channel.ChannelManager
-	cron       *cron.Service
-	hb         *heartbeat.Service
-	mem        *memory.MemoryStore
-	skillRegs  []api.SkillRegistration
-	signalChan chan os.Signal // for testing
+	cfg         *config.Config // as started; see config
+	live        atomic.Pointer[config.Config]
+	reloadMu    sync.Mutex // one reload at a time
+	bus         *bus.MessageBus
+	runtime     Runtime
+	channels    *channel.ChannelManager
+	cron        *cron.Service
+	hb          *heartbeat.Service
+	responses   *cron.ResponseCache // nil unless responseCache.enabled is on
+	mem         *memory.MemoryStore
+	vector      *memory.VectorStore // nil unless memory.semantic is on
+	extractWG   sync.WaitGroup      // pending memory extractions
+	kbMu        sync.Mutex
+	kb          *kb.Store // opened by knowledge once there is a knowledge base
+	kbFailed    bool      // opening it failed; not tried again
+	deadLetters *deadletter.Store
+	coord       *cluster.Coordinator // nil unless cluster mode is enabled
+	clusterDB   cluster.Backend
+	skillRegs   []api.SkillRegistration
+	signalChan  chan os.Signal // for testing
+
+	started time.Time
+	paused  atomic.Bool // set by /pause: chat messages get no agent reply
+	usage   usageCounter
+
+	// On shutdown, draining turns new chat messages and jobs away while
+	// the inflight ones, counted from when they are accepted, finish.
+	draining atomic.Bool
+	inflight atomic.Int64
+
+	sessions  *sessionTable // nil in tests that build a Gateway by hand
+	media     *media.Processor
+	queue     *workQueue         // nil in tests that build a Gateway by hand
+	journal   *inboxJournal      // nil in tests that build a Gateway by hand
+	limits    *ratelimit.Limiter // nil in tests that build a Gateway by hand
+	reminders *reminders.Store   // nil in tests that build a Gateway by hand
+	tasks     *tasks.Store       // nil in tests that build a Gateway by hand
+	ledger    *usage.Ledger      // nil in tests that build a Gateway by hand
+	audit     *audit.Log         // nil when audit.enabled is off
+	feeds     *feedDigest        // nil unless feeds are configured
+	logs      *logTail           // nil unless the dashboard is on
+	events    *eventHub          // nil in tests that build a Gateway by hand
+	approvals approvals          // tool calls waiting for a chat user's answer
+
+	stopTracing func(context.Context) error // flushes traces; nil in tests that build a Gateway by hand
+
+	// The runtime is rebuilt when skills change. Runs hold runtimeMu only to
+	// pick up the current runtime and count themselves in runtimeRuns, so
+	// that a replaced runtime is closed once its last run finishes.
+	runtimeMu       sync.RWMutex
+	runtimeRuns     *sync.WaitGroup
+	channelRuntimes map[string]Runtime // channels whose skill scope narrows skillRegs
+	buildRuntime    func(skillRegs []api.SkillRegistration) (Runtime, error)
+	pool            *runtimePool // session runtimes; nil unless sessions.maxRuntimes is set
+
+	// Webhooks and notify sinks are served by one subscriber each, started
+	// when the config first has any; see startEventSinks.
+	webhooksStarted, notifyStarted sync.Once
+}
+
+// config returns the config in effect, which a reload may have replaced
+// since the gateway started.
+func (g *Gateway) config() *config.Config {
+	if cfg := g.live.Load(); cfg != nil {
+		return cfg
+	}
+	return g.cfg
 }
 
 // New creates a Gateway with default options
@@ -112,73 +256,134 @@ func New(cfg *config.Config) (*Gateway, error) {
 
 // NewWithOptions creates a Gateway with custom options for testing
 func NewWithOptions(cfg *config.Config, opts Options) (*Gateway, error) {
-	g := &Gateway{cfg: cfg}
+	g := &Gateway{cfg: cfg, started: time.Now()}
 
 	// Message bus
 	g.bus = bus.NewMessageBus(config.DefaultBufSize)
 
 	// Memory
-	g.mem = memory.NewMemoryStore(cfg.Agent.Workspace)
-
-	// Build system prompt
-	sysPrompt := g.buildSystemPrompt()
-
-	if cfg.Skills.Enabled {
-		skillDir := cfg.Skills.Dir
-		if skillDir == "" {
-			skillDir = filepath.Join(cfg.Agent.Workspace, "skills")
-		}
-		skillRegs, err := skills.LoadSkills(skillDir)
+	g.mem = memory.NewMemoryStore(cfg.MemoryWorkspace())
+	if cfg.Memory.Semantic {
+		vector, err := memory.OpenConfigured(cfg)
 		if err != nil {
-			log.Printf("[gateway] skills load warning: %v", err)
+			log.Printf("[gateway] semantic memory unavailable, using MEMORY.md: %v", err)
 		}
-		g.skillRegs = skillRegs
+		g.vector = vector
 	}
 
+	sessions, err := newSessionTable(sessionsPath(cfg.Agent.Workspace), cfg.Sessions)
+	if err != nil {
+		return nil, err
+	}
+	g.sessions = sessions
+
+	journal, err := openInboxJournal(journalPath(cfg.Agent.Workspace))
+	if err != nil {
+		return nil, err
+	}
+	g.journal = journal
+
+	stt, err := media.NewTranscriber(cfg.Media.STT)
+	if err != nil {
+		return nil, err
+	}
+	g.media = &media.Processor{
+		STT:              stt,
+		PDF:              cfg.Provider.Type != provider.TypeOpenAI && cfg.Provider.Type != provider.TypeGemini,
+		MaxDocumentChars: cfg.Media.MaxDocumentChars,
+	}
+
+	g.queue = newWorkQueue(cfg.Queue)
+	g.limits = ratelimit.New(ratelimit.Path(cfg.Agent.Workspace), cfg.RateLimit)
+	g.reminders = reminders.NewStore(reminders.Path(cfg.Agent.Workspace))
+	g.tasks = tasks.NewStore(tasks.Path(cfg.Agent.Workspace))
+	g.ledger = usage.NewLedger(usage.Path(cfg.Agent.Workspace), cfg.TokenTracking.Prices)
+	if g.audit = audit.Open(cfg); g.audit != nil {
+		g.bus.TapOutbound(g.auditOutbound)
+	}
+	// Events are published even with nobody listening, so webhooks and
+	// notify sinks added by a reload get them.
+	g.events = newEventHub()
+	g.bus.TapOutbound(g.emitOutbound)
+
+	g.skillRegs = g.loadSkills()
+
 	// Create runtime using factory (allows injection for testing)
 	factory := opts.RuntimeFactory
-	var (
-		rt  Runtime
-		err error
-	)
-	if factory == nil {
-		rt, err = newRuntime(cfg, sysPrompt, g.skillRegs)
-	} else {
-		rt, err = factory(cfg, sysPrompt)
+	g.buildRuntime = func(skillRegs []api.SkillRegistration) (Runtime, error) {
+		if factory == nil {
+			var extra []middleware.Middleware
+			if g.events != nil {
+				extra = append(extra, g.eventsMiddleware())
+			}
+			tools := append(append(reminders.Tools(g.reminders), tasks.Tools(g.tasks)...), choices.Tools()...)
+			return newRuntime(g.config(), g.buildSystemPrompt(), skillRegs, tools, extra...)
+		}
+		return factory(g.config(), g.buildSystemPrompt())
+	}
+	rt, channelRuntimes, err := g.buildRuntimes(g.skillRegs)
+	if err != nil {
+		return nil, err
+	}
+	g.runtime, g.channelRuntimes = rt, channelRuntimes
+	g.runtimeRuns = &sync.WaitGroup{}
+	if cfg.Sessions.MaxRuntimes > 0 {
+		idle := cfg.Sessions.RuntimeIdleMinutes
+		if idle <= 0 {
+			idle = config.DefaultRuntimeIdleMinutes
+		}
+		g.pool = newRuntimePool(cfg.Sessions.MaxRuntimes, time.Duration(idle)*time.Minute, g.buildSessionRuntime)
 	}
+
+	stopTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
+		closeRuntimes(rt, channelRuntimes)
 		return nil, err
 	}
-	g.runtime = rt
+	g.stopTracing = stopTracing
 
 	// Signal channel for testing
 	g.signalChan = opts.SignalChan
 
-	// runAgent helper for cron/heartbeat
+	if rc := cfg.ResponseCache; rc.Enabled {
+		ttl := rc.TTLMinutes
+		if ttl == 0 {
+			ttl = config.DefaultResponseCacheTTLMinutes
+		}
+		g.responses = cron.NewResponseCache(cron.CachePath(cfg.Agent.Workspace), time.Duration(ttl)*time.Minute)
+	}
+
+	// runAgent helper for heartbeat
 	runAgent := func(prompt string) (string, error) {
-		return g.runAgent(context.Background(), prompt, "system", nil)
+		defer g.track()()
+		result, err := g.runJob(context.Background(), prompt, g.config().Heartbeat.NoCache)
+		return result.Output, err
 	}
 
 	// Cron
-	cronStorePath := filepath.Join(config.ConfigDir(), "data", "cron", "jobs.json")
+	cronStorePath := filepath.Join(config.DataDir(), "data", "cron", "jobs.json")
 	g.cron = cron.NewService(cronStorePath)
-	g.cron.OnJob = func(job cron.CronJob) (string, error) {
-		result, err := runAgent(job.Payload.Message)
+	g.cron.History = cron.NewHistory(cron.HistoryPath(cfg.Agent.Workspace))
+	g.cron.ShouldRun = g.shouldRunJobs
+	g.cron.OnJob = func(job cron.CronJob) (cron.Result, error) {
+		defer g.track()()
+		g.emit(Event{Type: EventCronFired, Job: cronJobName(job), Text: job.Payload.Message})
+		result, err := g.runJob(context.Background(), job.Payload.Message, job.Payload.NoCache)
 		if err != nil {
-			return "", err
-		}
-		if job.Payload.Deliver && job.Payload.Channel != "" {
-			g.bus.Outbound <- bus.OutboundMessage{
-				Channel: job.Payload.Channel,
-				ChatID:  job.Payload.To,
-				Content: result,
-			}
+			g.emit(Event{Type: EventError, Job: cronJobName(job), Error: err.Error()})
+			return cron.Result{}, err
 		}
+		g.deliver(job, job.Targets(), result.Output)
 		return result, nil
 	}
 
 	// Heartbeat
-	g.hb = heartbeat.New(cfg.Agent.Workspace, runAgent, 0)
+	if err := g.setupHeartbeat(runAgent); err != nil {
+		return nil, err
+	}
+	if err := g.setupFeeds(); err != nil {
+		return nil, err
+	}
 
 	// Channels (with gateway config for WebUI port)
 	chMgr, err := channel.NewChannelManagerWithGateway(cfg.Channels, cfg.Gateway, g.bus)
@@ -187,30 +392,230 @@ func NewWithOptions(cfg *config.Config, opts Options) (*Gateway, error) {
 	}
 	g.channels = chMgr
 
+	// Dead letters: failed sends are retried, then persisted for `myclaw deadletter`
+	g.deadLetters = deadletter.NewStore(deadletter.DefaultStorePath())
+	chMgr.SetDeadLetterHandler(cfg.DeadLetter.MaxAttempts, g.handleDeadLetter)
+
+	if cfg.Cluster.Enabled {
+		if err := g.setupCluster(); err != nil {
+			return nil, err
+		}
+	}
+
 	return g, nil
 }
 
-func (g *Gateway) buildSystemPrompt() string {
-	var sb strings.Builder
+// setupHeartbeat creates the heartbeat service from cfg.Heartbeat.
+func (g *Gateway) setupHeartbeat(runAgent func(string) (string, error)) error {
+	hc := g.config().Heartbeat
+	g.hb = heartbeat.New(g.config().Agent.Workspace, runAgent, time.Duration(hc.Interval)*time.Second)
+	g.hb.Quiet = hc.Quiet
+	g.hb.ShouldRun = g.shouldRunJobs
+	todos := todo.NewStore(todo.Path(g.config().Agent.Workspace))
+	g.hb.Extra = func() string {
+		prompt, err := todos.NagPrompt(time.Now())
+		if err != nil {
+			log.Printf("[heartbeat] load todos: %v", err)
+		}
+		return prompt
+	}
+	if hc.ActiveHours != "" {
+		active, err := heartbeat.ParseActiveHours(hc.ActiveHours)
+		if err != nil {
+			return fmt.Errorf("heartbeat: %w", err)
+		}
+		g.hb.Active = active
+	}
+	var targets []cron.Target
+	for _, spec := range hc.Deliver {
+		target, err := cron.ParseTarget(spec)
+		if err != nil {
+			return fmt.Errorf("heartbeat: %w", err)
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) > 0 {
+		job := cron.CronJob{ID: "heartbeat", Name: "Heartbeat"}
+		g.hb.OnResult = func(result string) { g.deliver(job, targets, result) }
+	}
+	return nil
+}
+
+// deliver sends the output of a cron job, or of the heartbeat, to targets.
+// Channel messages go through the bus, so failed sends end up as dead
+// letters; webhook, file and notify failures are logged.
+func (g *Gateway) deliver(job cron.CronJob, targets []cron.Target, output string) {
+	now := time.Now()
+	for _, target := range targets {
+		text := target.Render(job, output, now)
+		var err error
+		switch {
+		case target.Channel != "":
+			g.bus.Outbound <- bus.OutboundMessage{
+				Channel: target.Channel,
+				ChatID:  target.To,
+				Content: text,
+			}
+		case target.Webhook != "":
+			err = target.PostWebhook(context.Background(), job, output, text, now)
+		case target.File != "":
+			err = target.AppendFile(g.config().Agent.Workspace, text)
+		case target.Notify != "":
+			err = g.pushNotification(context.Background(), target.Notify, job.Name, text)
+		}
+		if err != nil {
+			log.Printf("[cron] deliver %s to %s: %v", job.Name, target, err)
+		}
+	}
+}
 
-	if data, err := os.ReadFile(filepath.Join(g.cfg.Agent.Workspace, "AGENTS.md")); err == nil {
-		sb.Write(data)
-		sb.WriteString("\n\n")
+// setupCluster joins the shared state so that only the leader runs cron and
+// heartbeat, and each channel runs on exactly one instance.
+func (g *Gateway) setupCluster() error {
+	cc := g.config().Cluster
+	var (
+		backend cluster.Backend
+		state   string
+		err     error
+	)
+	if cc.RedisURL != "" {
+		backend, err = cluster.OpenRedis(cc.RedisURL)
+		state = "redis"
+	} else {
+		state = cc.StatePath
+		if state == "" {
+			state = filepath.Join(config.DataDir(), "data", "cluster", "state.db")
+		}
+		backend, err = cluster.OpenSQLite(state)
+	}
+	if err != nil {
+		return fmt.Errorf("open cluster state: %w", err)
 	}
+	g.clusterDB = backend
+
+	g.coord = cluster.NewCoordinator(backend, cc.InstanceID,
+		time.Duration(cc.LeaseTTLSeconds)*time.Second,
+		time.Duration(cc.SessionTTLMinutes)*time.Minute)
+	g.coord.WantChannels(g.channels.EnabledChannels()...)
+	g.coord.OnEnvelope = g.handleEnvelope
+	g.channels.SetRouter(g.routeOutbound)
+	log.Printf("[gateway] cluster mode as %s (state: %s)", g.coord.ID(), state)
+	return nil
+}
+
+func (g *Gateway) buildSystemPrompt() string {
+	var sb strings.Builder
 
-	if data, err := os.ReadFile(filepath.Join(g.cfg.Agent.Workspace, "SOUL.md")); err == nil {
-		sb.Write(data)
-		sb.WriteString("\n\n")
+	for _, path := range prompt.Paths(g.config()) {
+		if data, err := os.ReadFile(path); err == nil {
+			sb.Write(data)
+			sb.WriteString("\n\n")
+		}
 	}
 
-	if memCtx := g.mem.GetMemoryContext(); memCtx != "" {
-		sb.WriteString(memCtx)
+	// With semantic memory, relevant chunks are added per message instead.
+	if g.vector == nil {
+		if memCtx := g.mem.GetMemoryContext(); memCtx != "" {
+			sb.WriteString(memCtx)
+		}
 	}
 
 	return sb.String()
 }
 
 func (g *Gateway) runAgent(ctx context.Context, prompt, sessionID string, contentBlocks []model.ContentBlock) (string, error) {
+	return g.runChannelAgent(ctx, "", prompt, sessionID, contentBlocks)
+}
+
+// runChannelAgent runs the agent for a message from channel, with the
+// skills that channel is scoped to.
+func (g *Gateway) runChannelAgent(ctx context.Context, channel, prompt, sessionID string, contentBlocks []model.ContentBlock) (string, error) {
+	resp, err := g.respond(ctx, channel, prompt, sessionID, contentBlocks)
+	if err != nil {
+		return "", err
+	}
+	if resp == nil || resp.Result == nil {
+		return "", nil
+	}
+	return resp.Result.Output, nil
+}
+
+// knowledge returns the knowledge base to recall from, or nil when there
+// is none yet or recall is off. It is opened on first use, so documents
+// added with "myclaw kb add" while the gateway runs are picked up.
+func (g *Gateway) knowledge() *kb.Store {
+	cfg := g.config()
+	if cfg == nil || cfg.KB.TopK < 0 { // nil in tests that build a Gateway by hand
+		return nil
+	}
+	g.kbMu.Lock()
+	defer g.kbMu.Unlock()
+	if g.kb != nil || g.kbFailed || !kb.Exists(cfg.Agent.Workspace) {
+		return g.kb
+	}
+	store, err := kb.OpenConfigured(cfg)
+	if err != nil {
+		log.Printf("[gateway] knowledge base unavailable: %v", err)
+		g.kbFailed = true
+		return nil
+	}
+	g.kb = store
+	return store
+}
+
+// runJob runs the prompt of a cron job or of the heartbeat in the system
+// session. With the response cache on and noCache unset, a run with the
+// same inputs as one within the TTL gets that run's answer instead.
+func (g *Gateway) runJob(ctx context.Context, prompt string, noCache bool) (cron.Result, error) {
+	prompt = g.withRecall(ctx, prompt)
+	var key string
+	if g.responses != nil && !noCache {
+		cfg := g.config()
+		key = cron.CacheKey(cfg.Models.Resolve(cfg.Agent.Model), g.buildSystemPrompt(), prompt)
+		if output, ok := g.responses.Get(key); ok {
+			return cron.Result{Output: output, Cached: true}, nil
+		}
+	}
+	resp, err := g.request(ctx, "", prompt, "system", nil)
+	if err != nil {
+		return cron.Result{}, err
+	}
+	var result cron.Result
+	if resp != nil && resp.Result != nil {
+		result = cron.Result{
+			Output:       resp.Result.Output,
+			InputTokens:  resp.Result.Usage.InputTokens,
+			OutputTokens: resp.Result.Usage.OutputTokens,
+		}
+	}
+	if key != "" && strings.TrimSpace(result.Output) != "" {
+		if err := g.responses.Put(key, result.Output); err != nil {
+			log.Printf("[gateway] cache job response: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// respond is runChannelAgent returning the whole response, usage included.
+func (g *Gateway) respond(ctx context.Context, channel, prompt, sessionID string, contentBlocks []model.ContentBlock) (*api.Response, error) {
+	return g.request(ctx, channel, g.withRecall(ctx, prompt), sessionID, contentBlocks)
+}
+
+// withRecall puts what the knowledge base and semantic memory recall for
+// prompt before it.
+func (g *Gateway) withRecall(ctx context.Context, prompt string) string {
+	var knowledge string
+	if store := g.knowledge(); store != nil {
+		knowledge = store.Prefix(ctx, prompt, g.config().KB.TopK, g.config().KB.MinScore)
+	}
+	if g.vector != nil {
+		prompt = g.vector.WithRecall(ctx, prompt, g.config().Memory.TopK)
+	}
+	return knowledge + prompt
+}
+
+// request runs prompt, recall already added, as it stands.
+func (g *Gateway) request(ctx context.Context, channel, prompt, sessionID string, contentBlocks []model.ContentBlock) (*api.Response, error) {
 	// Workaround: agentsdk-go drops Prompt when ContentBlocks exist (anthropic.go:420-431).
 	// Merge text prompt into ContentBlocks so both text and media reach the API.
 	blocks := contentBlocks
@@ -221,18 +626,15 @@ func (g *Gateway) runAgent(ctx context.Context, prompt, sessionID string, conten
 		prompt = "" // clear to avoid duplication if SDK is fixed later
 	}
 
-	resp, err := g.runtime.Run(ctx, api.Request{
+	req := api.Request{
 		Prompt:        prompt,
 		ContentBlocks: blocks,
 		SessionID:     sessionID,
-	})
-	if err != nil {
-		return "", err
 	}
-	if resp == nil || resp.Result == nil {
-		return "", nil
+	if channel != "" {
+		req.Channels = []string{channel}
 	}
-	return resp.Result.Output, nil
+	return g.run(ctx, req)
 }
 
 func (g *Gateway) Run(ctx context.Context) error {
@@ -241,10 +643,32 @@ func (g *Gateway) Run(ctx context.Context) error {
 
 	go g.bus.DispatchOutbound(ctx)
 
-	if err := g.channels.StartAll(ctx); err != nil {
-		return fmt.Errorf("start channels: %w", err)
+	if g.config().Gateway.Dashboard.Enabled {
+		defer g.captureLogs()()
+	}
+	// Before the channels start, so the web UI can mount the endpoints.
+	if err := g.serveHealth(ctx); err != nil {
+		log.Printf("[gateway] %v", err)
+	}
+
+	var coordDone chan struct{}
+	if g.coord != nil {
+		// Channels start as this instance claims them and stop if a peer
+		// takes them over.
+		g.coord.OnChannel = func(name string, owned bool) {
+			g.onChannelOwnership(ctx, name, owned)
+		}
+		coordDone = make(chan struct{})
+		go func() {
+			defer close(coordDone)
+			g.coord.Run(ctx)
+		}()
+	} else {
+		if err := g.channels.StartAll(ctx); err != nil {
+			return fmt.Errorf("start channels: %w", err)
+		}
+		log.Printf("[gateway] channels started: %v", g.channels.EnabledChannels())
 	}
-	log.Printf("[gateway] channels started: %v", g.channels.EnabledChannels())
 
 	if err := g.cron.Start(ctx); err != nil {
 		log.Printf("[gateway] cron start warning: %v", err)
@@ -257,51 +681,572 @@ func (g *Gateway) Run(ctx context.Context) error {
 	}()
 
 	go g.processLoop(ctx)
+	go g.deadLetterLoop(ctx)
+	if g.reminders != nil {
+		go g.reminderLoop(ctx)
+	}
+	if g.tasks != nil {
+		go g.taskLoop(ctx)
+	}
+	if g.feeds != nil {
+		go g.feedsLoop(ctx)
+	}
+	if g.pool != nil {
+		go g.runtimePoolLoop(ctx)
+	}
+	if g.config().Memory.Rollup.Enabled {
+		go g.memoryRollupLoop(ctx)
+	}
+	if g.config().Memory.Sync.Enabled {
+		go g.memorySyncLoop(ctx)
+	}
+	if g.config().Backup.Enabled {
+		go g.backupLoop(ctx)
+	}
+	g.startEventSinks(ctx)
+	if g.config().Skills.Enabled && g.buildRuntime != nil {
+		go g.watchSkills(ctx)
+	}
+	go g.watchReload(ctx)
 
-	log.Printf("[gateway] running on %s:%d", g.cfg.Gateway.Host, g.cfg.Gateway.Port)
+	log.Printf("[gateway] running on %s:%d", g.config().Gateway.Host, g.config().Gateway.Port)
 
 	// Use injected signal channel for testing, or create default
 	sigCh := g.signalChan
 	if sigCh == nil {
 		sigCh = make(chan os.Signal, 1)
-		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	}
+	// SIGHUP reloads the config; anything else shuts down.
+	for sig := <-sigCh; sig == syscall.SIGHUP; sig = <-sigCh {
+		log.Printf("[gateway] SIGHUP, reloading config")
+		_ = g.reload(ctx)
 	}
-	<-sigCh
 
 	log.Printf("[gateway] shutting down...")
+	g.drain(g.drainTimeout())
+	// Runs still going past the drain timeout are cancelled. In cluster
+	// mode, our leases are handed back before the state store closes.
+	cancel()
+	if coordDone != nil {
+		<-coordDone
+	}
 	return g.Shutdown()
 }
 
+// shouldRunJobs reports whether cron jobs and the heartbeat may start: not
+// while draining, nor, in cluster mode, on an instance other than the
+// leader.
+func (g *Gateway) shouldRunJobs() bool {
+	return !g.draining.Load() && (g.coord == nil || g.coord.IsLeader())
+}
+
+// track counts a chat message or job as inflight until the returned func
+// is called.
+func (g *Gateway) track() func() {
+	g.inflight.Add(1)
+	return func() { g.inflight.Add(-1) }
+}
+
+// drainTimeout is how long drain waits.
+func (g *Gateway) drainTimeout() time.Duration {
+	timeout := config.DefaultDrainTimeout
+	if cfg := g.config(); cfg != nil && cfg.Gateway.DrainTimeout > 0 {
+		timeout = cfg.Gateway.DrainTimeout
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// drain stops taking new chat messages and jobs, then waits up to timeout
+// for the inflight ones to finish and for their replies to be sent, or
+// dead-lettered, by the channels. It reports whether they were in time.
+func (g *Gateway) drain(timeout time.Duration) bool {
+	g.draining.Store(true)
+	deadline := time.Now().Add(timeout)
+	for g.inflight.Load() > 0 || len(g.bus.Outbound) > 0 {
+		if time.Now().After(deadline) {
+			log.Printf("[gateway] %d runs still in progress after %s, cancelling them", g.inflight.Load(), timeout)
+			return false
+		}
+		time.Sleep(drainPollInterval)
+	}
+	if g.channels == nil {
+		return true
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	if err := g.channels.Flush(ctx); err != nil {
+		log.Printf("[gateway] replies still queued after %s, stopping the channels anyway", timeout)
+		return false
+	}
+	return true
+}
+
 func (g *Gateway) processLoop(ctx context.Context) {
+	g.replayJournal(ctx)
 	for {
 		select {
 		case msg := <-g.bus.Inbound:
-			log.Printf("[gateway] inbound from %s/%s: %s", msg.Channel, msg.SenderID, truncate(msg.Content, 80))
+			g.accept(ctx, msg)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// accept journals msg, so that it is replayed should the gateway stop
+// before answering it, and dispatches it. A message the journal has
+// already taken is dropped.
+func (g *Gateway) accept(ctx context.Context, msg bus.InboundMessage) {
+	ack := func() {}
+	if g.journal != nil {
+		id, ok, err := g.journal.add(msg)
+		switch {
+		case err != nil:
+			log.Printf("[gateway] journal message from %s/%s: %v", msg.Channel, msg.SenderID, err)
+		case !ok:
+			log.Printf("[gateway] dropping a repeat of a message from %s/%s", msg.Channel, msg.SenderID)
+			return
+		default:
+			ack = g.journalAck(id)
+		}
+	}
+	g.dispatch(ctx, msg, ack)
+}
+
+// replayJournal dispatches the messages a previous run of the gateway took
+// but did not answer.
+func (g *Gateway) replayJournal(ctx context.Context) {
+	if g.journal == nil {
+		return
+	}
+	recs := g.journal.replay()
+	if len(recs) > 0 {
+		log.Printf("[gateway] replaying %d messages left unanswered", len(recs))
+	}
+	for _, rec := range recs {
+		g.dispatch(ctx, *rec.Msg, g.journalAck(rec.ID))
+	}
+}
 
-			result, err := g.runAgent(ctx, msg.Content, msg.SessionKey(), msg.ContentBlocks)
-			if err != nil {
-				log.Printf("[gateway] agent error: %v", err)
-				result = "Sorry, I encountered an error processing your message."
+func (g *Gateway) journalAck(id string) func() {
+	return func() {
+		if err := g.journal.ack(id); err != nil {
+			log.Printf("[gateway] ack message %s: %v", id, err)
+		}
+	}
+}
+
+// dispatch answers msg, or hands it to the agent, and calls ack once it is
+// done with. A message cut short by shutdown is not acked.
+func (g *Gateway) dispatch(ctx context.Context, msg bus.InboundMessage, ack func()) {
+	log.Printf("[gateway] inbound from %s/%s: %s", msg.Channel, msg.SenderID, truncate(msg.Content, 80))
+	g.auditInbound(msg)
+	g.emitInbound(msg)
+
+	if reply, ok := g.handleApproval(msg); ok {
+		g.bus.Outbound <- bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: reply}
+		ack()
+		return
+	}
+	if reply, ok := g.handleAdmin(msg); ok {
+		g.bus.Outbound <- bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: reply}
+		ack()
+		return
+	}
+	if g.draining.Load() {
+		// Not acked, so the journal replays it after the restart.
+		log.Printf("[gateway] shutting down, keeping the message from %s/%s for the restart", msg.Channel, msg.SenderID)
+		return
+	}
+	if g.paused.Load() {
+		log.Printf("[gateway] paused, not answering %s/%s", msg.Channel, msg.SenderID)
+		g.bus.Outbound <- bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: pausedReply}
+		ack()
+		return
+	}
+
+	if g.forwardToSessionOwner(ctx, msg) {
+		ack()
+		return
+	}
+	if reply, ok := g.allow(msg); !ok {
+		log.Printf("[gateway] rate limited %s/%s", msg.Channel, msg.SenderID)
+		g.bus.Outbound <- bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: reply}
+		ack()
+		return
+	}
+
+	sessionID := g.sessionID(msg)
+	done := g.track()
+	run := func() {
+		defer done()
+		g.handleMessage(ctx, msg, sessionID)
+		if ctx.Err() == nil {
+			ack()
+		}
+	}
+	if g.queue == nil {
+		run()
+		return
+	}
+	if !g.queue.submit(ctx, msg.Channel, sessionID, run) {
+		done()
+		log.Printf("[gateway] queue full, turning away %s/%s", msg.Channel, msg.SenderID)
+		reply := g.config().Queue.BusyReply
+		if reply == "" {
+			reply = defaultBusyReply
+		}
+		g.bus.Outbound <- bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: reply}
+		ack()
+	}
+}
+
+// handleMessage runs the agent on a chat message and sends the reply.
+func (g *Gateway) handleMessage(ctx context.Context, msg bus.InboundMessage, sessionID string) {
+	// Reminders the agent schedules and tasks it starts go back to this chat.
+	ctx = reminders.WithOrigin(ctx, msg.Channel, msg.ChatID)
+	ctx = tasks.WithOrigin(ctx, msg.Channel, msg.ChatID)
+	// Tool calls that need approval are asked about in this chat.
+	ctx = permission.WithAsker(ctx, g.approvalAsker(msg))
+	ctx = audit.WithSource(ctx, audit.Source{Channel: msg.Channel, ChatID: msg.ChatID, Sender: msg.SenderID, Session: sessionID})
+	// AGENTS.md and SOUL.md can tell channels and people apart.
+	persona, _ := msg.Metadata["persona"].(string)
+	ctx = prompt.WithVars(ctx, prompt.Vars{Channel: msg.Channel, UserName: senderName(msg), UserID: sender(msg), Persona: persona})
+	// Images the agent draws, and answers it offers as buttons, go to the
+	// chat with the reply.
+	var (
+		replyMu sync.Mutex
+		images  []string
+		buttons []bus.Button
+	)
+	ctx = imagegen.WithSink(ctx, func(path string) {
+		replyMu.Lock()
+		images = append(images, path)
+		replyMu.Unlock()
+	})
+	ctx = choices.WithSink(ctx, func(options []string) {
+		replyMu.Lock()
+		buttons = nil
+		for _, o := range options {
+			buttons = append(buttons, bus.Button{Text: o, Data: o})
+		}
+		replyMu.Unlock()
+	})
+	content, blocks := g.withAttachments(ctx, msg)
+	resp, err := g.respond(ctx, msg.Channel, content, sessionID, blocks)
+	var result string
+	if err != nil && ctx.Err() != nil {
+		// Cut short by shutdown; the journal replays the message.
+		log.Printf("[gateway] run for %s/%s cancelled: %v", msg.Channel, msg.SenderID, err)
+		return
+	}
+	if err != nil {
+		log.Printf("[gateway] agent error: %v", err)
+		g.emit(Event{Type: EventError, Channel: msg.Channel, ChatID: msg.ChatID, Sender: msg.SenderID, Session: sessionID, Error: err.Error()})
+		result = "Sorry, I encountered an error processing your message."
+	} else if resp != nil && resp.Result != nil {
+		result = resp.Result.Output
+		if g.limits != nil {
+			g.limits.Record(msg.Channel, sender(msg), resp.Result.Usage.InputTokens, resp.Result.Usage.OutputTokens)
+		}
+	}
+
+	if result != "" {
+		out := bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: result,
+		}
+		replyMu.Lock()
+		out.Media = images
+		if err == nil {
+			out.Buttons = buttons
+		}
+		replyMu.Unlock()
+		if err == nil {
+			g.addVoice(ctx, &out)
+		}
+		g.bus.Outbound <- out
+		if err == nil && g.config().Memory.AutoExtract {
+			g.extractMemory(content, result)
+		}
+	}
+}
+
+// allow checks msg against the sender's rate limits. Admins are never
+// limited.
+func (g *Gateway) allow(msg bus.InboundMessage) (string, bool) {
+	if g.limits == nil {
+		return "", true
+	}
+	if g.isAdmin(msg.Channel, msg.SenderID) {
+		g.limits.Count(msg.Channel, sender(msg))
+		return "", true
+	}
+	return g.limits.Allow(msg.Channel, sender(msg))
+}
+
+// sender identifies who sent msg, falling back to the chat for channels
+// that don't say.
+func sender(msg bus.InboundMessage) string {
+	if msg.SenderID != "" {
+		return msg.SenderID
+	}
+	return msg.ChatID
+}
+
+// senderName is the sender's name as their channel gives it, or their id.
+func senderName(msg bus.InboundMessage) string {
+	for _, key := range []string{"first_name", "username", "push_name", "name", "from_name"} {
+		if name, _ := msg.Metadata[key].(string); strings.TrimSpace(name) != "" {
+			return strings.TrimSpace(name)
+		}
+	}
+	return sender(msg)
+}
+
+// withAttachments returns the text and blocks of msg with its attachments
+// read in: transcripts and document text are added to the text.
+func (g *Gateway) withAttachments(ctx context.Context, msg bus.InboundMessage) (string, []model.ContentBlock) {
+	if len(msg.Attachments) == 0 {
+		return msg.Content, msg.ContentBlocks
+	}
+	p := g.media
+	if p == nil {
+		p = &media.Processor{}
+	}
+	notes, blocks := p.Process(ctx, msg.Attachments)
+	content := strings.TrimSpace(strings.Join(append([]string{msg.Content}, notes...), "\n\n"))
+	return content, append(msg.ContentBlocks, blocks...)
+}
+
+// sessionID returns the session msg runs in.
+func (g *Gateway) sessionID(msg bus.InboundMessage) string {
+	if g.sessions == nil {
+		return msg.SessionKey()
+	}
+	return g.sessions.resolve(msg)
+}
+
+func (g *Gateway) onChannelOwnership(ctx context.Context, name string, owned bool) {
+	if owned {
+		log.Printf("[gateway] claimed channel %s", name)
+		if err := g.channels.Start(ctx, name); err != nil {
+			log.Printf("[gateway] start %s failed: %v", name, err)
+		}
+		return
+	}
+	log.Printf("[gateway] channel %s is now served by another instance", name)
+	if err := g.channels.Stop(name); err != nil {
+		log.Printf("[gateway] stop %s failed: %v", name, err)
+	}
+}
+
+// forwardToSessionOwner hands msg to the peer instance its session is pinned
+// to. It reports false when the message should be handled here.
+func (g *Gateway) forwardToSessionOwner(ctx context.Context, msg bus.InboundMessage) bool {
+	if g.coord == nil {
+		return false
+	}
+	owner, err := g.coord.ClaimSession(ctx, msg.SessionKey())
+	if err != nil {
+		log.Printf("[gateway] session affinity check failed, handling locally: %v", err)
+		return false
+	}
+	if owner == g.coord.ID() {
+		return false
+	}
+	if err := g.coord.Forward(ctx, owner, cluster.KindInbound, msg); err != nil {
+		log.Printf("[gateway] forward session %s to %s failed, handling locally: %v", msg.SessionKey(), owner, err)
+		return false
+	}
+	log.Printf("[gateway] forwarded session %s to %s", msg.SessionKey(), owner)
+	return true
+}
+
+// routeOutbound sends replies for channels served by a peer to that peer.
+func (g *Gateway) routeOutbound(msg bus.OutboundMessage) bool {
+	if g.coord.OwnsChannel(msg.Channel) {
+		return false
+	}
+	ctx := context.Background()
+	owner, err := g.coord.ChannelOwner(ctx, msg.Channel)
+	if err != nil || owner == "" || owner == g.coord.ID() {
+		return false
+	}
+	if err := g.coord.Forward(ctx, owner, cluster.KindOutbound, msg); err != nil {
+		log.Printf("[gateway] forward %s reply to %s failed, sending locally: %v", msg.Channel, owner, err)
+		return false
+	}
+	return true
+}
+
+// handleEnvelope feeds messages forwarded by peers into the local bus.
+func (g *Gateway) handleEnvelope(env cluster.Envelope) {
+	switch env.Kind {
+	case cluster.KindInbound:
+		var msg bus.InboundMessage
+		if err := json.Unmarshal(env.Payload, &msg); err != nil {
+			log.Printf("[gateway] bad inbound envelope from %s: %v", env.From, err)
+			return
+		}
+		// Don't stall lease renewal on a busy inbound queue.
+		go func() { g.bus.Inbound <- msg }()
+	case cluster.KindOutbound:
+		var msg bus.OutboundMessage
+		if err := json.Unmarshal(env.Payload, &msg); err != nil {
+			log.Printf("[gateway] bad outbound envelope from %s: %v", env.From, err)
+			return
+		}
+		go func() { g.bus.Outbound <- msg }()
+	default:
+		log.Printf("[gateway] unknown envelope kind %q from %s", env.Kind, env.From)
+	}
+}
+
+func (g *Gateway) handleDeadLetter(msg bus.OutboundMessage, sendErr error, attempts int) {
+	if _, isAlert := msg.Metadata[deadLetterAlertKey]; isAlert {
+		log.Printf("[gateway] dead-letter alert to %s/%s failed: %v", msg.Channel, msg.ChatID, sendErr)
+		return
+	}
+
+	reason := "unknown error"
+	if sendErr != nil {
+		reason = sendErr.Error()
+	}
+	entry, err := g.deadLetters.Add(msg, reason, attempts)
+	if err != nil {
+		log.Printf("[gateway] store dead letter failed: %v", err)
+		return
+	}
+	log.Printf("[gateway] dead-lettered message %s for %s/%s after %d attempts: %s", entry.ID, msg.Channel, msg.ChatID, attempts, reason)
+
+	admin := g.config().DeadLetter
+	if admin.AdminChannel == "" {
+		return
+	}
+	alert := bus.OutboundMessage{
+		Channel: admin.AdminChannel,
+		ChatID:  admin.AdminChatID,
+		Content: fmt.Sprintf("Delivery to %s/%s failed after %d attempts: %s\nDead letter id: %s (run 'myclaw deadletter retry %s')",
+			msg.Channel, msg.ChatID, attempts, reason, entry.ID, entry.ID),
+		Metadata: map[string]any{deadLetterAlertKey: entry.ID},
+	}
+	// Called from the outbound dispatcher, so never block on a full queue.
+	select {
+	case g.bus.Outbound <- alert:
+	default:
+		log.Printf("[gateway] outbound queue full, dead-letter alert for %s not sent", entry.ID)
+	}
+}
+
+// deadLetterLoop requeues dead letters flagged by `myclaw deadletter retry`.
+func (g *Gateway) deadLetterLoop(ctx context.Context) {
+	ticker := time.NewTicker(deadLetterRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		g.requeueDeadLetters(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (g *Gateway) requeueDeadLetters(ctx context.Context) {
+	entries, err := g.deadLetters.TakeRetries()
+	if err != nil {
+		log.Printf("[gateway] load dead letters failed: %v", err)
+		return
+	}
+	for i, entry := range entries {
+		log.Printf("[gateway] retrying dead letter %s to %s/%s", entry.ID, entry.Channel, entry.ChatID)
+		select {
+		case g.bus.Outbound <- entry.Message():
+		case <-ctx.Done():
+			// Shutting down: put back whatever was not requeued.
+			for _, rest := range entries[i:] {
+				_, _ = g.deadLetters.Add(rest.Message(), rest.Reason, rest.Attempts)
 			}
+			return
+		}
+	}
+}
+
+// reminderLoop sends reminders the agent scheduled when they fall due. In
+// cluster mode only the leader sends them.
+func (g *Gateway) reminderLoop(ctx context.Context) {
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
 
-			if result != "" {
-				g.bus.Outbound <- bus.OutboundMessage{
-					Channel: msg.Channel,
-					ChatID:  msg.ChatID,
-					Content: result,
-				}
+	for {
+		select {
+		case <-ticker.C:
+			if g.coord != nil && !g.coord.IsLeader() {
+				continue
 			}
+			g.sendDueReminders(time.Now())
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+func (g *Gateway) sendDueReminders(now time.Time) {
+	due, err := g.reminders.Due(now)
+	if err != nil {
+		log.Printf("[gateway] load reminders failed: %v", err)
+		return
+	}
+	for _, r := range due {
+		log.Printf("[gateway] sending reminder %s to %s/%s", r.ID, r.Channel, r.ChatID)
+		g.emit(Event{Type: EventReminderDue, Channel: r.Channel, ChatID: r.ChatID, Text: r.Message})
+		g.bus.Outbound <- bus.OutboundMessage{
+			Channel: r.Channel,
+			ChatID:  r.ChatID,
+			Content: "⏰ " + r.Message,
+		}
+	}
+}
+
 func (g *Gateway) Shutdown() error {
 	g.cron.Stop()
 	_ = g.channels.StopAll()
-	if g.runtime != nil {
-		g.runtime.Close()
+	g.extractWG.Wait()
+	g.runtimeMu.Lock()
+	rt, channelRuntimes, runs := g.runtime, g.channelRuntimes, g.runtimeRuns
+	g.runtimeMu.Unlock()
+	if runs != nil {
+		runs.Wait()
+	}
+	closeRuntimes(rt, channelRuntimes)
+	if g.pool != nil {
+		g.pool.close()
+	}
+	if g.journal != nil {
+		_ = g.journal.close()
+	}
+	if g.clusterDB != nil {
+		_ = g.clusterDB.Close()
+	}
+	if g.vector != nil {
+		_ = g.vector.Close()
+	}
+	g.kbMu.Lock()
+	if g.kb != nil {
+		_ = g.kb.Close()
+		g.kb = nil
+	}
+	g.kbMu.Unlock()
+	if g.stopTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := g.stopTracing(ctx); err != nil {
+			log.Printf("[gateway] flush traces: %v", err)
+		}
+		cancel()
 	}
 	log.Printf("[gateway] shutdown complete")
 	return nil
@@ -1,21 +1,42 @@
 package gateway
 
 import (
+	"bufio"
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/cexll/agentsdk-go/pkg/agent"
 	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
 	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/audit"
 	"github.com/stellarlinkco/myclaw/internal/bus"
 	"github.com/stellarlinkco/myclaw/internal/channel"
+	"github.com/stellarlinkco/myclaw/internal/cluster"
 	"github.com/stellarlinkco/myclaw/internal/config"
 	"github.com/stellarlinkco/myclaw/internal/cron"
+	"github.com/stellarlinkco/myclaw/internal/deadletter"
 	"github.com/stellarlinkco/myclaw/internal/heartbeat"
+	"github.com/stellarlinkco/myclaw/internal/imagegen"
 	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+	"github.com/stellarlinkco/myclaw/internal/ratelimit"
+	"github.com/stellarlinkco/myclaw/internal/reminders"
+	"github.com/stellarlinkco/myclaw/internal/tasks"
+	"github.com/stellarlinkco/myclaw/internal/usage"
 )
 
 // mockRuntime implements Runtime interface for testing
@@ -24,6 +45,8 @@ type mockRuntime struct {
 	err      error
 	closed   bool
 	reqCh    chan api.Request
+	block    chan struct{} // when set, Run waits for it to close
+	onRun    func(ctx context.Context)
 }
 
 func (m *mockRuntime) Run(ctx context.Context, req api.Request) (*api.Response, error) {
@@ -33,6 +56,12 @@ func (m *mockRuntime) Run(ctx context.Context, req api.Request) (*api.Response,
 		default:
 		}
 	}
+	if m.block != nil {
+		<-m.block
+	}
+	if m.onRun != nil {
+		m.onRun(ctx)
+	}
 	return m.response, m.err
 }
 
@@ -695,6 +724,94 @@ func TestGateway_Run_WithSignalChan(t *testing.T) {
 	}
 }
 
+func TestGateway_Run_DrainsInflight(t *testing.T) {
+	cfg := &config.Config{
+		Agent:   config.AgentConfig{Workspace: t.TempDir()},
+		Gateway: config.GatewayConfig{Host: "localhost", Port: 8080},
+	}
+	mockRt := &mockRuntime{
+		response: &api.Response{Result: &api.Result{Output: "finished"}},
+		reqCh:    make(chan api.Request, 1),
+		block:    make(chan struct{}),
+	}
+	sigCh := make(chan os.Signal, 1)
+	g, err := NewWithOptions(cfg, Options{RuntimeFactory: mockRuntimeFactory(mockRt), SignalChan: sigCh})
+	if err != nil {
+		t.Fatalf("NewWithOptions error: %v", err)
+	}
+	replies := make(chan string, 4)
+	g.bus.SubscribeOutbound("telegram", func(msg bus.OutboundMessage) { replies <- msg.Content })
+
+	done := make(chan error, 1)
+	go func() { done <- g.Run(context.Background()) }()
+	g.bus.Inbound <- bus.InboundMessage{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "long task"}
+	select {
+	case <-mockRt.reqCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("message never reached the runtime")
+	}
+
+	sigCh <- syscall.SIGTERM
+	for !g.draining.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	g.bus.Inbound <- bus.InboundMessage{Channel: "telegram", SenderID: "2", ChatID: "2", Content: "hello?"}
+	select {
+	case <-done:
+		t.Fatal("Run returned with a run in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(mockRt.block)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not exit once the run finished")
+	}
+	select {
+	case got := <-replies:
+		if got != "finished" {
+			t.Errorf("reply = %q, want the finished run's", got)
+		}
+	default:
+		t.Error("the finished run's reply was not sent before shutdown")
+	}
+	if !mockRt.closed {
+		t.Error("runtime should be closed after shutdown")
+	}
+
+	// The message that came in while draining waits for the next start.
+	journal, err := openInboxJournal(journalPath(cfg.Agent.Workspace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.close()
+	if recs := journal.replay(); len(recs) != 1 || recs[0].Msg.Content != "hello?" {
+		t.Errorf("left for replay: %+v", recs)
+	}
+}
+
+func TestGateway_Drain(t *testing.T) {
+	g := &Gateway{bus: bus.NewMessageBus(10)}
+	done := g.track()
+	if g.drain(20 * time.Millisecond) {
+		t.Error("drain reported done with a run in progress")
+	}
+	if !g.draining.Load() || g.shouldRunJobs() {
+		t.Error("jobs should not start while draining")
+	}
+	done()
+	if !g.drain(time.Second) {
+		t.Error("drain timed out with nothing in progress")
+	}
+	if g.drainTimeout() != config.DefaultDrainTimeout*time.Second {
+		t.Errorf("drainTimeout = %s", g.drainTimeout())
+	}
+}
+
 func TestGateway_Run_ChannelStartError(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -783,8 +900,51 @@ func TestGateway_CronOnJob(t *testing.T) {
 	if err != nil {
 		t.Errorf("OnJob error: %v", err)
 	}
-	if result != "cron result" {
-		t.Errorf("result = %q, want 'cron result'", result)
+	if result.Output != "cron result" {
+		t.Errorf("result = %q, want 'cron result'", result.Output)
+	}
+}
+
+func TestGateway_CronOnJob_ResponseCache(t *testing.T) {
+	cfg := &config.Config{
+		Agent:         config.AgentConfig{Workspace: t.TempDir(), Model: "claude-sonnet"},
+		ResponseCache: config.ResponseCacheConfig{Enabled: true},
+	}
+	runs := 0
+	mockRt := &mockRuntime{
+		response: &api.Response{Result: &api.Result{Output: "A quiet week.", Usage: model.Usage{InputTokens: 800, OutputTokens: 40}}},
+		onRun:    func(context.Context) { runs++ },
+	}
+	g, err := NewWithOptions(cfg, Options{RuntimeFactory: mockRuntimeFactory(mockRt)})
+	if err != nil {
+		t.Fatalf("NewWithOptions error: %v", err)
+	}
+	defer g.Shutdown()
+
+	job := cron.CronJob{ID: "weekly", Payload: cron.Payload{Message: "summarize the week"}}
+	first, err := g.cron.OnJob(job)
+	if err != nil || first.Cached || first.InputTokens != 800 {
+		t.Fatalf("first run = %+v, %v", first, err)
+	}
+	second, err := g.cron.OnJob(job)
+	if err != nil || !second.Cached || second.Output != "A quiet week." || second.InputTokens != 0 {
+		t.Errorf("second run = %+v, %v; want the cached answer", second, err)
+	}
+	if runs != 1 {
+		t.Errorf("runs = %d, want 1", runs)
+	}
+
+	job.Payload.NoCache = true
+	if result, err := g.cron.OnJob(job); err != nil || result.Cached {
+		t.Errorf("no-cache run = %+v, %v", result, err)
+	}
+	g.config().Agent.Model = "claude-opus"
+	job.Payload.NoCache = false
+	if result, err := g.cron.OnJob(job); err != nil || result.Cached {
+		t.Errorf("run with another model = %+v, %v", result, err)
+	}
+	if runs != 3 {
+		t.Errorf("runs = %d, want 3", runs)
 	}
 }
 
@@ -847,13 +1007,90 @@ func TestGateway_CronOnJob_WithDelivery(t *testing.T) {
 	if err != nil {
 		t.Errorf("OnJob error: %v", err)
 	}
-	if result != "delivered result" {
-		t.Errorf("result = %q, want 'delivered result'", result)
+	if result.Output != "delivered result" {
+		t.Errorf("result = %q, want 'delivered result'", result.Output)
 	}
 
 	<-done
 }
 
+func TestGateway_CronOnJob_Targets(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: tmpDir}}
+	mockRt := &mockRuntime{response: &api.Response{Result: &api.Result{Output: "3 new mails"}}}
+	g, err := NewWithOptions(cfg, Options{RuntimeFactory: mockRuntimeFactory(mockRt)})
+	if err != nil {
+		t.Fatalf("NewWithOptions error: %v", err)
+	}
+	defer g.Shutdown()
+
+	job := cron.CronJob{
+		ID:   "inbox",
+		Name: "Inbox",
+		Payload: cron.Payload{
+			Message: "summarize my inbox",
+			Targets: []cron.Target{
+				{Channel: "feishu", To: "ou_1", Template: "{{title}}: {{result}}"},
+				{File: "reports/inbox.md", Template: "- {{result}}\n"},
+			},
+		},
+	}
+	if _, err := g.cron.OnJob(job); err != nil {
+		t.Fatalf("OnJob error: %v", err)
+	}
+	select {
+	case msg := <-g.bus.Outbound:
+		if msg.Channel != "feishu" || msg.ChatID != "ou_1" || msg.Content != "Inbox: 3 new mails" {
+			t.Errorf("outbound = %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for outbound message")
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "reports", "inbox.md")); string(data) != "- 3 new mails\n" {
+		t.Errorf("file = %q", data)
+	}
+}
+
+func TestGateway_HeartbeatPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agent: config.AgentConfig{Workspace: tmpDir},
+		Heartbeat: config.HeartbeatConfig{
+			Interval:    600,
+			ActiveHours: "00:00-24:00",
+			Deliver:     []string{"telegram:42"},
+			Quiet:       true,
+		},
+	}
+	mockRt := &mockRuntime{response: &api.Response{Result: &api.Result{Output: "Dentist at 3pm."}}}
+	g, err := NewWithOptions(cfg, Options{RuntimeFactory: mockRuntimeFactory(mockRt)})
+	if err != nil {
+		t.Fatalf("NewWithOptions error: %v", err)
+	}
+	defer g.Shutdown()
+	if !g.hb.Quiet || g.hb.Active == nil || g.hb.OnResult == nil {
+		t.Fatalf("heartbeat = %+v", g.hb)
+	}
+	g.hb.OnResult("Dentist at 3pm.")
+	select {
+	case msg := <-g.bus.Outbound:
+		if msg.Channel != "telegram" || msg.ChatID != "42" || msg.Content != "Dentist at 3pm." {
+			t.Errorf("outbound = %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for outbound message")
+	}
+
+	cfg.Heartbeat = config.HeartbeatConfig{ActiveHours: "9am-5pm"}
+	if _, err := NewWithOptions(cfg, Options{RuntimeFactory: mockRuntimeFactory(mockRt)}); err == nil {
+		t.Error("expected error for invalid active hours")
+	}
+	cfg.Heartbeat = config.HeartbeatConfig{Deliver: []string{"telegram"}}
+	if _, err := NewWithOptions(cfg, Options{RuntimeFactory: mockRuntimeFactory(mockRt)}); err == nil {
+		t.Error("expected error for invalid target")
+	}
+}
+
 func TestGateway_CronOnJob_Error(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -890,6 +1127,92 @@ func TestGateway_CronOnJob_Error(t *testing.T) {
 	}
 }
 
+func TestGateway_HandleDeadLetter(t *testing.T) {
+	tmpDir := t.TempDir()
+	g := &Gateway{
+		cfg: &config.Config{
+			DeadLetter: config.DeadLetterConfig{AdminChannel: "telegram", AdminChatID: "admin"},
+		},
+		bus:         bus.NewMessageBus(10),
+		deadLetters: deadletter.NewStore(filepath.Join(tmpDir, "messages.json")),
+	}
+
+	g.handleDeadLetter(bus.OutboundMessage{Channel: "feishu", ChatID: "c1", Content: "expensive answer"}, fmt.Errorf("user blocked bot"), 3)
+
+	entries, err := g.deadLetters.List()
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("len(entries) = %d, want 1", len(entries))
+	}
+	if entries[0].Content != "expensive answer" || entries[0].Reason != "user blocked bot" {
+		t.Errorf("entry = %+v", entries[0])
+	}
+
+	select {
+	case alert := <-g.bus.Outbound:
+		if alert.Channel != "telegram" || alert.ChatID != "admin" {
+			t.Errorf("alert target = %s/%s, want telegram/admin", alert.Channel, alert.ChatID)
+		}
+		if !contains(alert.Content, entries[0].ID) {
+			t.Errorf("alert should mention dead letter id, got %q", alert.Content)
+		}
+		// A failing alert must not be dead-lettered again
+		g.handleDeadLetter(alert, fmt.Errorf("admin unreachable"), 3)
+	default:
+		t.Fatal("expected admin alert")
+	}
+
+	entries, _ = g.deadLetters.List()
+	if len(entries) != 1 {
+		t.Errorf("len(entries) = %d, want 1 after failed alert", len(entries))
+	}
+}
+
+func TestGateway_HandleDeadLetter_NoAdmin(t *testing.T) {
+	g := &Gateway{
+		cfg:         &config.Config{},
+		bus:         bus.NewMessageBus(10),
+		deadLetters: deadletter.NewStore(filepath.Join(t.TempDir(), "messages.json")),
+	}
+
+	g.handleDeadLetter(bus.OutboundMessage{Channel: "telegram", ChatID: "1"}, fmt.Errorf("boom"), 1)
+
+	select {
+	case msg := <-g.bus.Outbound:
+		t.Errorf("unexpected outbound message: %+v", msg)
+	default:
+	}
+}
+
+func TestGateway_RequeueDeadLetters(t *testing.T) {
+	g := &Gateway{
+		cfg:         &config.Config{},
+		bus:         bus.NewMessageBus(10),
+		deadLetters: deadletter.NewStore(filepath.Join(t.TempDir(), "messages.json")),
+	}
+	entry, _ := g.deadLetters.Add(bus.OutboundMessage{Channel: "telegram", ChatID: "7", Content: "retry me"}, "x", 3)
+	g.deadLetters.Add(bus.OutboundMessage{Channel: "telegram", ChatID: "8", Content: "stay"}, "x", 3)
+	g.deadLetters.MarkRetry(entry.ID)
+
+	g.requeueDeadLetters(context.Background())
+
+	select {
+	case msg := <-g.bus.Outbound:
+		if msg.Content != "retry me" || msg.ChatID != "7" {
+			t.Errorf("requeued = %+v", msg)
+		}
+	default:
+		t.Fatal("expected requeued message")
+	}
+
+	entries, _ := g.deadLetters.List()
+	if len(entries) != 1 || entries[0].ChatID != "8" {
+		t.Errorf("remaining = %+v", entries)
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
 }
@@ -902,3 +1225,1628 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func newClusterTestGateway(t *testing.T) (*Gateway, *cluster.SQLiteBackend) {
+	t.Helper()
+	backend, err := cluster.OpenSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite error: %v", err)
+	}
+	t.Cleanup(func() { backend.Close() })
+	return &Gateway{
+		cfg:   &config.Config{},
+		bus:   bus.NewMessageBus(10),
+		coord: cluster.NewCoordinator(backend, "local", time.Minute, time.Hour),
+	}, backend
+}
+
+// startPeer runs another instance against the same backend until the test ends.
+func startPeer(t *testing.T, backend cluster.Backend, channels ...string) *cluster.Coordinator {
+	t.Helper()
+	peer := cluster.NewCoordinator(backend, "peer", time.Minute, time.Hour)
+	peer.WantChannels(channels...)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		peer.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	deadline := time.Now().Add(2 * time.Second)
+	for !peer.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatal("peer did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return peer
+}
+
+func TestNewWithOptions_Cluster(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agent: config.AgentConfig{Workspace: tmpDir},
+		Cluster: config.ClusterConfig{
+			Enabled:    true,
+			InstanceID: "home",
+			StatePath:  filepath.Join(tmpDir, "cluster", "state.db"),
+		},
+	}
+
+	g, err := NewWithOptions(cfg, Options{RuntimeFactory: mockRuntimeFactory(&mockRuntime{})})
+	if err != nil {
+		t.Fatalf("NewWithOptions error: %v", err)
+	}
+	defer g.Shutdown()
+
+	if g.coord == nil || g.coord.ID() != "home" {
+		t.Fatal("coordinator should be configured")
+	}
+	if g.cron.ShouldRun == nil || g.cron.ShouldRun() {
+		t.Error("cron should wait until this instance leads")
+	}
+	if g.hb.ShouldRun == nil {
+		t.Error("heartbeat should be gated on leadership")
+	}
+}
+
+func TestGateway_ForwardToSessionOwner(t *testing.T) {
+	g, backend := newClusterTestGateway(t)
+	peer := startPeer(t, backend)
+	ctx := context.Background()
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "hi"}
+	if owner, _ := peer.ClaimSession(ctx, msg.SessionKey()); owner != "peer" {
+		t.Fatalf("peer claim = %q", owner)
+	}
+
+	if !g.forwardToSessionOwner(ctx, msg) {
+		t.Fatal("message for a peer's session should be forwarded")
+	}
+	envs, err := backend.Receive(ctx, "peer")
+	if err != nil || len(envs) != 1 || envs[0].Kind != cluster.KindInbound {
+		t.Fatalf("peer mailbox = %+v, %v", envs, err)
+	}
+
+	other := bus.InboundMessage{Channel: "telegram", ChatID: "2"}
+	if g.forwardToSessionOwner(ctx, other) {
+		t.Error("a new session should be handled locally")
+	}
+}
+
+func TestGateway_RouteOutbound(t *testing.T) {
+	g, backend := newClusterTestGateway(t)
+	startPeer(t, backend, "telegram")
+
+	if !g.routeOutbound(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "reply"}) {
+		t.Fatal("reply for a peer's channel should be routed to the peer")
+	}
+	envs, _ := backend.Receive(context.Background(), "peer")
+	if len(envs) != 1 || envs[0].Kind != cluster.KindOutbound {
+		t.Fatalf("peer mailbox = %+v", envs)
+	}
+
+	if g.routeOutbound(bus.OutboundMessage{Channel: "feishu", ChatID: "1"}) {
+		t.Error("unowned channel should be sent locally")
+	}
+}
+
+func TestGateway_HandleEnvelope(t *testing.T) {
+	g, _ := newClusterTestGateway(t)
+
+	g.handleEnvelope(cluster.Envelope{Kind: cluster.KindInbound, From: "peer", Payload: []byte(`{"Channel":"telegram","ChatID":"1","Content":"hi"}`)})
+	select {
+	case msg := <-g.bus.Inbound:
+		if msg.Content != "hi" || msg.ChatID != "1" {
+			t.Errorf("inbound = %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected forwarded inbound message")
+	}
+
+	g.handleEnvelope(cluster.Envelope{Kind: cluster.KindOutbound, From: "peer", Payload: []byte(`{"Channel":"telegram","ChatID":"1","Content":"reply"}`)})
+	select {
+	case msg := <-g.bus.Outbound:
+		if msg.Content != "reply" {
+			t.Errorf("outbound = %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected forwarded outbound message")
+	}
+}
+
+func TestGateway_SemanticMemory(t *testing.T) {
+	tmpDir := t.TempDir()
+	mem := memory.NewMemoryStore(tmpDir)
+	mem.WriteLongTerm("# Pets\nThe dog is called Biscuit.")
+
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: tmpDir}}
+	cfg.Memory = config.MemoryConfig{Semantic: true, TopK: 3}
+	vector, err := memory.OpenConfigured(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vector.Close()
+
+	reqCh := make(chan api.Request, 1)
+	g := &Gateway{
+		cfg:     cfg,
+		mem:     mem,
+		vector:  vector,
+		runtime: &mockRuntime{response: &api.Response{Result: &api.Result{Output: "ok"}}, reqCh: reqCh},
+	}
+	if prompt := g.buildSystemPrompt(); contains(prompt, "Biscuit") {
+		t.Errorf("system prompt should not include MEMORY.md: %q", prompt)
+	}
+
+	if _, err := g.runAgent(context.Background(), "what is my dog called?", "s", nil); err != nil {
+		t.Fatal(err)
+	}
+	req := <-reqCh
+	if !contains(req.Prompt, "Biscuit") || !contains(req.Prompt, "</memory>\n\nwhat is my dog called?") {
+		t.Errorf("prompt = %q", req.Prompt)
+	}
+}
+
+func TestGateway_RollupMemory(t *testing.T) {
+	tmpDir := t.TempDir()
+	mem := memory.NewMemoryStore(tmpDir)
+	mem.WriteLongTerm("# Facts")
+	old := time.Now().AddDate(0, 0, -10).Format("2006-01-02")
+	os.WriteFile(filepath.Join(tmpDir, "memory", old+".md"), []byte("Adopted a cat named Miso."), 0644)
+
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: tmpDir}}
+	cfg.Memory.Rollup = config.RollupConfig{Enabled: true, Schedule: config.DefaultRollupSchedule, KeepDays: 7}
+	reqCh := make(chan api.Request, 1)
+	g := &Gateway{
+		cfg:     cfg,
+		mem:     mem,
+		runtime: &mockRuntime{response: &api.Response{Result: &api.Result{Output: "- Has a cat named Miso"}}, reqCh: reqCh},
+	}
+
+	g.rollupMemory(context.Background())
+
+	req := <-reqCh
+	if req.SessionID != rollupSessionID || !contains(req.Prompt, "Adopted a cat") {
+		t.Errorf("request = %+v", req)
+	}
+	if lt, _ := mem.ReadLongTerm(); !contains(lt, "- Has a cat named Miso") {
+		t.Errorf("long-term = %q", lt)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "memory", "archive", old+".md")); err != nil {
+		t.Errorf("journal not archived: %v", err)
+	}
+}
+
+func TestGateway_ExtractMemory(t *testing.T) {
+	tmpDir := t.TempDir()
+	mem := memory.NewMemoryStore(tmpDir)
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: tmpDir}}
+	cfg.Memory.AutoExtract = true
+	reqCh := make(chan api.Request, 1)
+	g := &Gateway{
+		cfg:     cfg,
+		mem:     mem,
+		runtime: &mockRuntime{response: &api.Response{Result: &api.Result{Output: "- Birthday is June 2"}}, reqCh: reqCh},
+	}
+
+	g.extractMemory("my birthday is June 2nd", "I'll remember that!")
+	g.extractWG.Wait()
+
+	req := <-reqCh
+	if req.SessionID != extractSessionID || !contains(req.Prompt, "my birthday is June 2nd") {
+		t.Errorf("request = %+v", req)
+	}
+	if lt, _ := mem.ReadLongTerm(); !contains(lt, memory.ExtractHeading+"\n- Birthday is June 2") {
+		t.Errorf("long-term = %q", lt)
+	}
+}
+
+// skillsRuntime records the skills it was built with and signals Close.
+type skillsRuntime struct {
+	skills []string
+	closed chan struct{}
+}
+
+func (r *skillsRuntime) Run(ctx context.Context, req api.Request) (*api.Response, error) {
+	return &api.Response{Result: &api.Result{Output: strings.Join(r.skills, ",")}}, nil
+}
+
+func (r *skillsRuntime) Close() { close(r.closed) }
+
+func TestGateway_ReloadSkills(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: tmpDir}}
+	cfg.Skills.Enabled = true
+	writeSkill := func(name string) {
+		dir := filepath.Join(tmpDir, "skills", name)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("---\nname: "+name+"\n---\nbody"), 0644)
+	}
+	writeSkill("writer")
+
+	g := &Gateway{cfg: cfg, mem: memory.NewMemoryStore(tmpDir)}
+	g.buildRuntime = func(regs []api.SkillRegistration) (Runtime, error) {
+		rt := &skillsRuntime{closed: make(chan struct{})}
+		for _, reg := range regs {
+			rt.skills = append(rt.skills, reg.Definition.Name)
+		}
+		return rt, nil
+	}
+	first, _ := g.buildRuntime(g.loadSkills())
+	g.runtime, g.runtimeRuns = first, &sync.WaitGroup{}
+
+	if out, _ := g.runAgent(context.Background(), "hi", "s", nil); out != "writer" {
+		t.Fatalf("initial skills = %q", out)
+	}
+
+	writeSkill("translator")
+	cfg.Skills.Disabled = []string{"writer"}
+	g.reloadSkills()
+	if out, _ := g.runAgent(context.Background(), "hi", "s", nil); out != "translator" {
+		t.Errorf("skills after reload = %q", out)
+	}
+	select {
+	case <-first.(*skillsRuntime).closed:
+	case <-time.After(time.Second):
+		t.Error("old runtime not closed after reload")
+	}
+
+	g.buildRuntime = func([]api.SkillRegistration) (Runtime, error) { return nil, fmt.Errorf("bad skill") }
+	g.reloadSkills()
+	if out, _ := g.runAgent(context.Background(), "hi", "s", nil); out != "translator" {
+		t.Errorf("failed reload replaced runtime: %q", out)
+	}
+}
+
+func TestGateway_ReloadConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MYCLAW_API_KEY", "sk-test")
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = filepath.Join(home, "workspace")
+	if err := config.SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	// Each runtime answers with the model it was built for.
+	factory := func(cfg *config.Config, sysPrompt string) (Runtime, error) {
+		return &mockRuntime{response: &api.Response{Result: &api.Result{Output: cfg.Agent.Model}}}, nil
+	}
+	g, err := NewWithOptions(cfg, Options{RuntimeFactory: factory})
+	if err != nil {
+		t.Fatalf("NewWithOptions: %v", err)
+	}
+	defer g.Shutdown()
+	ctx := context.Background()
+	port := cfg.Gateway.Port
+
+	if err := config.UpdateConfig(func(cfg *config.Config) error {
+		cfg.Agent.Model = "claude-haiku-4-5"
+		cfg.Gateway.Port = port + 1
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if err := g.reload(ctx); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if out, _ := g.runAgent(ctx, "hi", "s", nil); out != "claude-haiku-4-5" {
+		t.Errorf("model after reload = %q", out)
+	}
+	if got := g.config().Gateway.Port; got != port {
+		t.Errorf("gateway.port = %d, want %d until a restart", got, port)
+	}
+
+	// A config that doesn't load leaves everything as it was.
+	os.WriteFile(config.ConfigPath(), []byte("{"), 0644)
+	if err := g.reload(ctx); err == nil {
+		t.Error("expected error for a broken config")
+	}
+	if out, _ := g.runAgent(ctx, "hi", "s", nil); out != "claude-haiku-4-5" {
+		t.Errorf("model after failed reload = %q", out)
+	}
+}
+
+func TestGateway_ReloadAddsWebhook(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MYCLAW_API_KEY", "sk-test")
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = filepath.Join(home, "workspace")
+	if err := config.SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	g, err := NewWithOptions(cfg, Options{RuntimeFactory: func(*config.Config, string) (Runtime, error) { return &mockRuntime{}, nil }})
+	if err != nil {
+		t.Fatalf("NewWithOptions: %v", err)
+	}
+	defer g.Shutdown()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g.startEventSinks(ctx)
+
+	got := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get("X-Myclaw-Event")
+	}))
+	defer srv.Close()
+	if err := config.UpdateConfig(func(cfg *config.Config) error {
+		cfg.Webhooks = []config.WebhookConfig{{URL: srv.URL}}
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if err := g.reload(ctx); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(g.config().Webhooks) != 1 {
+		t.Fatalf("webhooks after reload = %v", g.config().Webhooks)
+	}
+	g.emit(Event{Type: EventTaskFinished, Task: "1", Status: "done"})
+	select {
+	case typ := <-got:
+		if typ != EventTaskFinished {
+			t.Errorf("event = %q", typ)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook added by a reload got no event")
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 10)
+	go watchFiles(ctx, []string{file, filepath.Join(t.TempDir(), "missing", "jobs.json")}, 10*time.Millisecond, func() { changed <- struct{}{} })
+	time.Sleep(50 * time.Millisecond)
+
+	os.WriteFile(filepath.Join(dir, "other.txt"), []byte("x"), 0644)
+	os.WriteFile(file, []byte("agent: {}"), 0644)
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reload after the config was written")
+	}
+	select {
+	case <-changed:
+		t.Error("reloaded more than once")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestGateway_ChannelSkillScope(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: tmpDir}}
+	cfg.Skills.Enabled = true
+	for _, name := range []string{"soc-report", "writer"} {
+		dir := filepath.Join(tmpDir, "skills", name)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("---\nname: "+name+"\n---\nbody"), 0644)
+	}
+	cfg.Channels.Telegram.Skills.Allow = []string{"soc-report", "writer"}
+	cfg.Channels.WeCom.Skills.Deny = []string{"SOC-Report"}
+	cfg.Channels.Slack.Skills.Allow = []string{"writer"}
+
+	built := 0
+	g := &Gateway{cfg: cfg, mem: memory.NewMemoryStore(tmpDir)}
+	g.buildRuntime = func(regs []api.SkillRegistration) (Runtime, error) {
+		built++
+		rt := &skillsRuntime{closed: make(chan struct{})}
+		for _, reg := range regs {
+			rt.skills = append(rt.skills, reg.Definition.Name)
+		}
+		return rt, nil
+	}
+	rt, channelRuntimes, err := g.buildRuntimes(g.loadSkills())
+	if err != nil {
+		t.Fatalf("buildRuntimes: %v", err)
+	}
+	g.runtime, g.channelRuntimes, g.runtimeRuns = rt, channelRuntimes, &sync.WaitGroup{}
+	// Telegram allows everything, and wecom and slack are left with the
+	// same skill, so only two runtimes are built.
+	if built != 2 {
+		t.Errorf("built %d runtimes, want 2", built)
+	}
+
+	for channel, want := range map[string]string{
+		"":         "soc-report,writer",
+		"telegram": "soc-report,writer",
+		"wecom":    "writer",
+		"slack":    "writer",
+		"feishu":   "soc-report,writer",
+	} {
+		if out, _ := g.runChannelAgent(context.Background(), channel, "hi", "s", nil); out != want {
+			t.Errorf("channel %q skills = %q, want %q", channel, out, want)
+		}
+	}
+
+	closeRuntimes(g.runtime, g.channelRuntimes)
+	for _, r := range []Runtime{g.runtime, g.channelRuntimes["wecom"]} {
+		select {
+		case <-r.(*skillsRuntime).closed:
+		default:
+			t.Error("runtime not closed")
+		}
+	}
+}
+
+func TestGateway_AdminCommands(t *testing.T) {
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: t.TempDir(), Model: "claude-test"}}
+	cfg.Channels.Telegram.Admins = []string{"42"}
+	msgBus := bus.NewMessageBus(10)
+	mockRt := &mockRuntime{
+		response: &api.Response{Result: &api.Result{Output: "from the model", Usage: model.Usage{InputTokens: 120, OutputTokens: 8, CacheReadTokens: 900}}},
+		reqCh:    make(chan api.Request, 1),
+	}
+	g := &Gateway{cfg: cfg, bus: msgBus, runtime: mockRt, started: time.Now()}
+	g.buildRuntime = func([]api.SkillRegistration) (Runtime, error) { return mockRt, nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.processLoop(ctx)
+
+	send := func(sender, text string) string {
+		t.Helper()
+		msgBus.Inbound <- bus.InboundMessage{Channel: "telegram", SenderID: sender, ChatID: "c1", Content: text}
+		select {
+		case out := <-msgBus.Outbound:
+			return out.Content
+		case <-time.After(time.Second):
+			t.Fatalf("no reply to %q", text)
+			return ""
+		}
+	}
+
+	// Commands from other senders go to the model.
+	if got := send("7", "/status"); got != "from the model" {
+		t.Errorf("non-admin /status = %q", got)
+	}
+	<-mockRt.reqCh
+	if got := send("42", "/status@my_bot"); !strings.Contains(got, "Model: claude-test") || !strings.Contains(got, "State: answering") {
+		t.Errorf("/status = %q", got)
+	}
+	if got := send("42", "/usage"); !strings.Contains(got, "1 turns, 120 input tokens, 8 output tokens. 900 input tokens read from the prompt cache.") {
+		t.Errorf("/usage = %q", got)
+	}
+	if got := send("42", "/skills"); got != "No skills loaded." {
+		t.Errorf("/skills = %q", got)
+	}
+
+	if got := send("42", "/pause"); !strings.HasPrefix(got, "Paused.") {
+		t.Errorf("/pause = %q", got)
+	}
+	if got := send("7", "hello"); got != pausedReply {
+		t.Errorf("reply while paused = %q", got)
+	}
+	if got := send("42", "/resume"); got != "Resumed." {
+		t.Errorf("/resume = %q", got)
+	}
+	if got := send("42", "/restart"); got != "Restarted the agent runtime." {
+		t.Errorf("/restart = %q", got)
+	}
+	select {
+	case req := <-mockRt.reqCh:
+		t.Errorf("admin commands reached the model: %+v", req)
+	default:
+	}
+}
+
+func TestSessionTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	table, err := newSessionTable(path, config.SessionsConfig{TTLMinutes: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.now = func() time.Time { return now }
+
+	dm := bus.InboundMessage{Channel: "telegram", SenderID: "1", ChatID: "1"}
+	alice := bus.InboundMessage{Channel: "telegram", SenderID: "1", ChatID: "-100"}
+	bob := bus.InboundMessage{Channel: "telegram", SenderID: "2", ChatID: "-100"}
+	if got := table.resolve(dm); got != "telegram:1" {
+		t.Errorf("direct message session = %q", got)
+	}
+	if a, b := table.resolve(alice), table.resolve(bob); a != "telegram:-100:1" || b != "telegram:-100:2" {
+		t.Errorf("group sessions = %q, %q", a, b)
+	}
+
+	// An idle session is replaced, and the table survives a restart.
+	now = now.Add(time.Hour)
+	if got := table.resolve(dm); got != "telegram:1@20261016-100000" {
+		t.Errorf("session after TTL = %q", got)
+	}
+	reloaded, err := newSessionTable(path, config.SessionsConfig{TTLMinutes: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded.now = func() time.Time { return now.Add(time.Minute) }
+	if got := reloaded.resolve(dm); got != "telegram:1@20261016-100000" {
+		t.Errorf("session after reload = %q", got)
+	}
+	if n := reloaded.active(); n != 1 {
+		t.Errorf("active = %d, want 1", n)
+	}
+
+	chat, _ := newSessionTable(filepath.Join(t.TempDir(), "s.json"), config.SessionsConfig{Scope: "chat"})
+	if got := chat.resolve(bob); got != "telegram:-100" {
+		t.Errorf("chat scope session = %q", got)
+	}
+	if _, err := newSessionTable(path, config.SessionsConfig{Scope: "team"}); err == nil {
+		t.Error("expected error for an unknown scope")
+	}
+}
+
+func TestWorkQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := newWorkQueue(config.QueueConfig{Concurrency: 2, MaxPending: 4, ChannelConcurrency: map[string]int{"slack": 1}})
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	started := make(chan string, 10)
+	job := func(name string) func() {
+		return func() {
+			started <- name
+			<-release
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+		}
+	}
+
+	// Two slack sessions share a run slot; a's messages keep their order.
+	for _, name := range []string{"a1", "a2"} {
+		if !q.submit(ctx, "slack", "a", job(name)) {
+			t.Fatalf("%s rejected", name)
+		}
+	}
+	q.submit(ctx, "slack", "b", job("b1"))
+	q.submit(ctx, "telegram", "c", job("c1"))
+	if q.submit(ctx, "telegram", "d", job("d1")) {
+		t.Error("fifth message should be rejected with MaxPending 4")
+	}
+
+	got := map[string]bool{<-started: true, <-started: true}
+	if !got["c1"] || got["a2"] {
+		t.Errorf("first runs = %v", got)
+	}
+	select {
+	case name := <-started:
+		t.Errorf("%s started past the limits", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if waiting, running := q.depth(); waiting != 2 || running != 2 {
+		t.Errorf("depth = %d waiting, %d running", waiting, running)
+	}
+
+	close(release)
+	for range 2 {
+		<-started
+	}
+	deadline := time.After(time.Second)
+	for {
+		if waiting, running := q.depth(); waiting == 0 && running == 0 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("queue did not drain")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Index(strings.Join(order, ","), "a1") > strings.Index(strings.Join(order, ","), "a2") {
+		t.Errorf("order = %v", order)
+	}
+}
+
+func TestGateway_QueueFull(t *testing.T) {
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: t.TempDir()}}
+	cfg.Queue = config.QueueConfig{Concurrency: 1, MaxPending: 1, BusyReply: "busy"}
+	msgBus := bus.NewMessageBus(10)
+	block := make(chan struct{})
+	mockRt := &mockRuntime{
+		response: &api.Response{Result: &api.Result{Output: "done"}},
+		block:    block,
+	}
+	g := &Gateway{cfg: cfg, bus: msgBus, runtime: mockRt, queue: newWorkQueue(cfg.Queue)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.processLoop(ctx)
+
+	msgBus.Inbound <- bus.InboundMessage{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "first"}
+	msgBus.Inbound <- bus.InboundMessage{Channel: "telegram", SenderID: "2", ChatID: "2", Content: "second"}
+	select {
+	case out := <-msgBus.Outbound:
+		if out.ChatID != "2" || out.Content != "busy" {
+			t.Errorf("reply = %+v", out)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no busy reply")
+	}
+	close(block)
+	if out := <-msgBus.Outbound; out.ChatID != "1" || out.Content != "done" {
+		t.Errorf("reply = %+v", out)
+	}
+}
+
+func TestGateway_RateLimit(t *testing.T) {
+	ws := t.TempDir()
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: ws}}
+	cfg.Channels.Telegram.Admins = []string{"42"}
+	cfg.RateLimit = config.RateLimitConfig{RateLimit: config.RateLimit{MessagesPerMinute: 1}, SlowDownReply: "slow down"}
+	msgBus := bus.NewMessageBus(10)
+	mockRt := &mockRuntime{response: &api.Response{Result: &api.Result{Output: "ok", Usage: model.Usage{InputTokens: 10, OutputTokens: 5}}}}
+	g := &Gateway{cfg: cfg, bus: msgBus, runtime: mockRt, limits: ratelimit.New(ratelimit.Path(ws), cfg.RateLimit)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.processLoop(ctx)
+
+	send := func(sender string) string {
+		t.Helper()
+		msgBus.Inbound <- bus.InboundMessage{Channel: "telegram", SenderID: sender, ChatID: "c1", Content: "hi"}
+		select {
+		case out := <-msgBus.Outbound:
+			return out.Content
+		case <-time.After(time.Second):
+			t.Fatal("no reply")
+			return ""
+		}
+	}
+	if got := send("7"); got != "ok" {
+		t.Errorf("first = %q", got)
+	}
+	if got := send("7"); got != "slow down" {
+		t.Errorf("second = %q", got)
+	}
+	if got := send("42") + send("42"); got != "okok" {
+		t.Errorf("admin = %q", got)
+	}
+
+	usage, err := ratelimit.Load(ratelimit.Path(ws))
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := usage.Day(time.Now().Format("2006-01-02"))
+	if len(day) != 2 || day[0].User != "telegram:42" || day[0].Messages != 2 || day[0].Tokens() != 30 || day[1].Limited != 1 {
+		t.Errorf("usage = %+v", day)
+	}
+}
+
+func TestGateway_SendDueReminders(t *testing.T) {
+	ws := t.TempDir()
+	msgBus := bus.NewMessageBus(10)
+	g := &Gateway{cfg: &config.Config{}, bus: msgBus, reminders: reminders.NewStore(reminders.Path(ws))}
+	now := time.Now()
+	if _, err := g.reminders.Add("telegram", "42", "call mom", cron.Schedule{Kind: "at", AtMs: now.Add(time.Minute).UnixMilli()}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	g.sendDueReminders(now)
+	select {
+	case out := <-msgBus.Outbound:
+		t.Errorf("sent early: %+v", out)
+	default:
+	}
+	g.sendDueReminders(now.Add(2 * time.Minute))
+	select {
+	case out := <-msgBus.Outbound:
+		if out.Channel != "telegram" || out.ChatID != "42" || out.Content != "⏰ call mom" {
+			t.Errorf("reminder = %+v", out)
+		}
+	default:
+		t.Error("reminder not sent")
+	}
+}
+
+func TestGateway_RunTask(t *testing.T) {
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: t.TempDir()}}
+	mockRt := &mockRuntime{
+		response: &api.Response{Result: &api.Result{Output: "Panels pay for themselves in 8 years."}},
+		reqCh:    make(chan api.Request, 1),
+	}
+	g, err := NewWithOptions(cfg, Options{RuntimeFactory: mockRuntimeFactory(mockRt)})
+	if err != nil {
+		t.Fatalf("NewWithOptions error: %v", err)
+	}
+	defer g.Shutdown()
+
+	now := time.Now()
+	added, _ := g.tasks.Add("Solar research", "Research solar panels", "telegram", "42", now)
+	task, ok, err := g.tasks.Next(now)
+	if err != nil || !ok || task.ID != added.ID {
+		t.Fatalf("Next = %+v, %v, %v", task, ok, err)
+	}
+	g.runTask(context.Background(), task)
+	if req := <-mockRt.reqCh; req.SessionID != "task-"+task.ID || !strings.Contains(req.Prompt, "Research solar panels") {
+		t.Errorf("request = %+v", req)
+	}
+	select {
+	case out := <-g.bus.Outbound:
+		if out.Channel != "telegram" || out.ChatID != "42" || !strings.Contains(out.Content, "Panels pay for themselves") {
+			t.Errorf("report = %+v", out)
+		}
+	default:
+		t.Error("no report sent")
+	}
+	if done, _, _ := g.tasks.Get(task.ID); done.Status != tasks.Done {
+		t.Errorf("status = %s, want done", done.Status)
+	}
+
+	// A task canceled while it runs is stopped without a report.
+	mockRt.err = context.Canceled
+	_, _ = g.tasks.Add("Trip plan", "Plan a trip", "telegram", "42", now)
+	task, _, _ = g.tasks.Next(now)
+	_, _, _ = g.tasks.Cancel(task.ID, "", "", now)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g.runTask(ctx, task)
+	select {
+	case out := <-g.bus.Outbound:
+		t.Errorf("canceled task reported: %+v", out)
+	default:
+	}
+	if got, _, _ := g.tasks.Get(task.ID); got.Status != tasks.Canceled {
+		t.Errorf("status = %s, want canceled", got.Status)
+	}
+}
+
+func TestGateway_RecordUsage_BudgetAlert(t *testing.T) {
+	msgBus := bus.NewMessageBus(10)
+	cfg := &config.Config{}
+	cfg.Agent.Model = "claude-sonnet-4-5-20250929"
+	cfg.TokenTracking = config.TokenTrackingConfig{DailyBudgetUSD: 5, AlertChannel: "telegram", AlertChatID: "42"}
+	g := &Gateway{cfg: cfg, bus: msgBus, ledger: usage.NewLedger(usage.Path(t.TempDir()), nil)}
+	req := api.Request{SessionID: "telegram:7", Channels: []string{"telegram"}}
+
+	// $3 per run: the second run crosses the budget, the third is already past it.
+	for range 3 {
+		g.recordUsage(req, model.Usage{InputTokens: 1_000_000})
+	}
+	if got := g.usage.turns.Load(); got != 3 {
+		t.Errorf("turns = %d, want 3", got)
+	}
+	select {
+	case out := <-msgBus.Outbound:
+		if out.Channel != "telegram" || out.ChatID != "42" || !strings.Contains(out.Content, "$6.00") {
+			t.Errorf("alert = %+v", out)
+		}
+	default:
+		t.Fatal("budget alert not sent")
+	}
+	select {
+	case out := <-msgBus.Outbound:
+		t.Errorf("alerted twice: %+v", out)
+	default:
+	}
+}
+
+func TestGateway_HealthEndpoints(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MYCLAW_API_KEY", "sk-test")
+	var providerDown atomic.Bool
+	providerAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if providerDown.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"data": [{"id": "claude-sonnet-4-5"}]}`)
+	}))
+	defer providerAPI.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Provider.APIKey = "sk-test"
+	cfg.Provider.BaseURL = providerAPI.URL
+	msgBus := bus.NewMessageBus(10)
+	chMgr, _ := channel.NewChannelManager(config.ChannelsConfig{}, msgBus)
+	g := &Gateway{cfg: cfg, bus: msgBus, channels: chMgr, started: time.Now()}
+	srv := httptest.NewServer(g.healthMux())
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, body := get("/healthz"); code != http.StatusOK || !strings.Contains(body, `"ok":true`) {
+		t.Errorf("healthz = %d %s", code, body)
+	}
+	if code, body := get("/readyz"); code != http.StatusOK || !strings.Contains(body, `"name":"provider","ok":true`) {
+		t.Errorf("readyz = %d %s", code, body)
+	}
+
+	// A provider failure shows once the cached check expires; a fresh mux
+	// has no cached result.
+	providerDown.Store(true)
+	srv.Config.Handler = g.healthMux()
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "401") {
+		t.Errorf("readyz with provider down = %d %s", code, body)
+	}
+}
+
+func TestGateway_Dashboard(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: workspace, Model: "claude-sonnet-4-5"}}
+	cfg.Gateway.Dashboard = config.DashboardConfig{Enabled: true, Token: "s3cret"}
+	history := filepath.Join(workspace, ".claude", "history")
+	os.MkdirAll(history, 0755)
+	os.WriteFile(filepath.Join(history, "telegram-42.json"), []byte(`{"version":1,"session_id":"telegram:42","updated_at":"2026-10-16T09:00:00Z","messages":[{"role":"user","content":"Book a table"}]}`), 0644)
+	ledger := usage.NewLedger(usage.Path(workspace), nil)
+	ledger.Record(usage.Entry{Time: time.Now(), Model: "m", InputTokens: 100, OutputTokens: 20})
+
+	g := &Gateway{cfg: cfg, started: time.Now(), logs: newLogTail(2)}
+	g.logs.Write([]byte("one\n"))
+	g.logs.Write([]byte("two\nthree\n"))
+	mux := http.NewServeMux()
+	g.mountDashboard(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	get := func(path string, header http.Header) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, _ := get("/dashboard/api/status", nil); code != http.StatusUnauthorized {
+		t.Errorf("status without the token = %d", code)
+	}
+	if code, body := get("/dashboard/", nil); code != http.StatusUnauthorized || !strings.Contains(body, `action="/dashboard/login"`) {
+		t.Errorf("page without the token = %d %s", code, body)
+	}
+	login := func(token string) *http.Cookie {
+		resp, err := client.PostForm(srv.URL+"/dashboard/login", map[string][]string{"token": {token}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		for _, c := range resp.Cookies() {
+			if c.Name == dashboardCookie {
+				return c
+			}
+		}
+		return nil
+	}
+	if c := login("wrong"); c != nil {
+		t.Error("login with a wrong token set a cookie")
+	}
+	cookie := login("s3cret")
+	if cookie == nil || strings.Contains(cookie.Value, "s3cret") {
+		t.Fatalf("login cookie = %v", cookie)
+	}
+	if code, body := get("/dashboard/", http.Header{"Cookie": {cookie.String()}}); code != http.StatusOK || !strings.Contains(body, "<title>myclaw dashboard</title>") {
+		t.Errorf("page after login = %d %.80s", code, body)
+	}
+
+	bearer := http.Header{"Authorization": {"Bearer s3cret"}}
+	if code, body := get("/dashboard/api/status", bearer); code != http.StatusOK || !strings.Contains(body, `"state":"answering"`) || !strings.Contains(body, `"model":"claude-sonnet-4-5"`) {
+		t.Errorf("status = %d %s", code, body)
+	}
+	if _, body := get("/dashboard/api/sessions", bearer); !strings.Contains(body, `"channel":"telegram"`) || !strings.Contains(body, `"preview":"Book a table"`) {
+		t.Errorf("sessions = %s", body)
+	}
+	var report dashboardUsageReport
+	_, body := get("/dashboard/api/usage?days=7", bearer)
+	if err := json.Unmarshal([]byte(body), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Days) != 7 || report.Days[6].InputTokens != 100 || report.Days[0].Runs != 0 || report.Total.OutputTokens != 20 {
+		t.Errorf("usage = %s", body)
+	}
+	if _, body := get("/dashboard/api/logs", bearer); strings.TrimSpace(body) != `["two","three"]` {
+		t.Errorf("logs = %s", body)
+	}
+	if _, body := get("/dashboard/api/cron", bearer); strings.TrimSpace(body) != `[]` {
+		t.Errorf("cron = %s", body)
+	}
+
+	// Without a token, only this machine gets in.
+	cfg.Gateway.Dashboard.Token = ""
+	if code, _ := get("/dashboard/api/skills", nil); code != http.StatusOK {
+		t.Errorf("skills from loopback = %d", code)
+	}
+	if isLoopback("192.168.1.20:5000") || !isLoopback("[::1]:5000") {
+		t.Error("isLoopback")
+	}
+}
+
+func TestGateway_Events(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Gateway.Events = config.EventsConfig{Enabled: true, Token: "s3cret"}
+	g := &Gateway{cfg: cfg, events: newEventHub()}
+	mux := http.NewServeMux()
+	g.mountEvents(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, path := range []string{"/events", "/events?token=wrong"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s = %d", path, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/events?token=s3cret&types=message.received,tool.call")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	r := bufio.NewReader(resp.Body)
+	if line, _ := r.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("first line = %q", line)
+	}
+
+	g.emitOutbound(bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "filtered out"})
+	g.emitInbound(bus.InboundMessage{Channel: "telegram", ChatID: "42", SenderID: "7", Content: "Turn on the lights"})
+	ctx := audit.WithSource(context.Background(), audit.Source{Channel: "telegram", ChatID: "42", Session: "telegram:42"})
+	st := &middleware.State{
+		ToolCall:   agent.ToolCall{Name: "ha_call_service"},
+		ToolResult: agent.ToolResult{Metadata: map[string]any{"is_error": true, "error": "unreachable"}},
+	}
+	if err := g.eventsMiddleware().AfterTool(ctx, st); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func() (string, Event) {
+		t.Helper()
+		var name string
+		var ev Event
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				name = v
+			}
+			if v, ok := strings.CutPrefix(line, "data: "); ok {
+				if err := json.Unmarshal([]byte(v), &ev); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if line == "" && name != "" {
+				return name, ev
+			}
+		}
+	}
+	if name, ev := read(); name != EventMessageReceived || ev.Sender != "7" || ev.Text != "Turn on the lights" || ev.Time.IsZero() {
+		t.Errorf("first event = %s %+v", name, ev)
+	}
+	if name, ev := read(); name != EventToolCall || ev.Tool != "ha_call_service" || ev.Session != "telegram:42" || ev.Error != "unreachable" {
+		t.Errorf("second event = %s %+v", name, ev)
+	}
+}
+
+func TestGateway_Webhooks(t *testing.T) {
+	type delivery struct {
+		event, signature string
+		body             []byte
+	}
+	got := make(chan delivery, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- delivery{r.Header.Get("X-Myclaw-Event"), r.Header.Get("X-Myclaw-Signature"), body}
+	}))
+	defer srv.Close()
+
+	workspace := t.TempDir()
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: workspace}}
+	cfg.Webhooks = []config.WebhookConfig{{URL: srv.URL, Secret: "k3y", Events: []string{EventTaskFinished, EventMemoryWritten}}}
+	g := &Gateway{cfg: cfg, events: newEventHub(), mem: memory.NewMemoryStore(workspace)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g.startWebhooks(ctx)
+
+	g.emit(Event{Type: EventMessageSent, Text: "not wanted"})
+	st := &middleware.State{ToolCall: agent.ToolCall{Name: "Write", Input: map[string]any{"file_path": "memory/MEMORY.md"}}}
+	if err := g.eventsMiddleware().AfterTool(context.Background(), st); err != nil {
+		t.Fatal(err)
+	}
+	g.emit(Event{Type: EventTaskFinished, Task: "3f2a1b0c", Status: "done", Text: "Found three options."})
+
+	byType := make(map[string]delivery)
+	for len(byType) < 2 {
+		select {
+		case d := <-got:
+			byType[d.event] = d
+		case <-time.After(5 * time.Second):
+			t.Fatalf("deliveries = %v", byType)
+		}
+	}
+	task := byType[EventTaskFinished]
+	if task.signature != webhookSignature("k3y", task.body) || !strings.Contains(string(task.body), `"task":"3f2a1b0c","status":"done"`) {
+		t.Errorf("task.finished = %s %s", task.signature, task.body)
+	}
+	if mem := byType[EventMemoryWritten]; !strings.Contains(string(mem.body), `"tool":"Write"`) || !strings.Contains(string(mem.body), "MEMORY.md") {
+		t.Errorf("memory.written = %s", mem.body)
+	}
+	select {
+	case d := <-got:
+		t.Errorf("unexpected delivery %s %s", d.event, d.body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestGateway_Notify(t *testing.T) {
+	type push struct{ topic, title, message string }
+	got := make(chan push, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		topic, _ := body["topic"].(string)
+		title, _ := body["title"].(string)
+		message, _ := body["message"].(string)
+		got <- push{topic, title, message}
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: t.TempDir()}}
+	cfg.Notify = map[string]config.NotifyConfig{
+		"phone": {Type: config.NotifyNtfy, URL: srv.URL + "/claw", Events: []string{EventTaskFinished}},
+		"quiet": {Type: config.NotifyNtfy, URL: srv.URL + "/quiet"},
+	}
+	g := &Gateway{cfg: cfg, events: newEventHub()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g.startNotify(ctx)
+
+	g.emit(Event{Type: EventMessageSent, Text: "not wanted"})
+	g.emit(Event{Type: EventTaskFinished, Task: "3f2a1b0c", Status: "done", Text: "Found three options."})
+	next := func() push {
+		t.Helper()
+		select {
+		case p := <-got:
+			return p
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification")
+			return push{}
+		}
+	}
+	if p := next(); p != (push{"claw", "Task 3f2a1b0c done", "Found three options."}) {
+		t.Errorf("task.finished = %+v", p)
+	}
+
+	targets := []cron.Target{{Notify: "quiet"}, {Notify: "missing"}}
+	g.deliver(cron.CronJob{ID: "heartbeat", Name: "Heartbeat"}, targets, " Water the plants. ")
+	if p := next(); p != (push{"quiet", "Heartbeat", "Water the plants."}) {
+		t.Errorf("deliver = %+v", p)
+	}
+	select {
+	case p := <-got:
+		t.Errorf("unexpected notification %+v", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestGateway_Approvals(t *testing.T) {
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: t.TempDir()}}
+	cfg.Channels.Telegram.Admins = []string{"42"}
+	cfg.Permissions.AskTimeout = 5
+	msgBus := bus.NewMessageBus(10)
+	g := &Gateway{cfg: cfg, bus: msgBus}
+
+	origin := bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "c1"}
+	ask := g.approvalAsker(origin)
+	req := permission.Request{Tool: "Bash", Target: "rm:-rf build"}
+
+	answer := make(chan bool, 1)
+	go func() {
+		ok, _ := ask(context.Background(), req)
+		answer <- ok
+	}()
+
+	var prompt bus.OutboundMessage
+	select {
+	case prompt = <-msgBus.Outbound:
+	case <-time.After(time.Second):
+		t.Fatal("no approval prompt")
+	}
+	if !strings.Contains(prompt.Content, "Bash: rm -rf build") || len(prompt.Buttons) != 2 {
+		t.Fatalf("prompt = %+v", prompt)
+	}
+	approve := prompt.Buttons[0].Data
+	id := strings.TrimPrefix(approve, "/approve ")
+
+	reply := func(msg bus.InboundMessage) string {
+		t.Helper()
+		got, ok := g.handleApproval(msg)
+		if !ok {
+			t.Fatalf("handleApproval(%q) not handled", msg.Content)
+		}
+		return got
+	}
+	if _, ok := g.handleApproval(bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "c1", Content: "/approve"}); ok {
+		t.Error("/approve without id should not be handled")
+	}
+	if got := reply(bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "c2", Content: approve}); !strings.HasPrefix(got, "Nothing is waiting") {
+		t.Errorf("other chat = %q", got)
+	}
+	if got := reply(bus.InboundMessage{Channel: "telegram", SenderID: "8", ChatID: "c1", Content: approve}); !strings.HasPrefix(got, "Only the person who asked") {
+		t.Errorf("other sender = %q", got)
+	}
+	if got := reply(bus.InboundMessage{Channel: "telegram", SenderID: "42", ChatID: "c1", Content: "/approve@my_bot " + id}); got != "Approved." {
+		t.Errorf("admin approve = %q", got)
+	}
+	select {
+	case ok := <-answer:
+		if !ok {
+			t.Error("asker returned false after approval")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("asker did not return")
+	}
+	if got := reply(bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "c1", Content: "/deny " + id}); !strings.HasPrefix(got, "Nothing is waiting") {
+		t.Errorf("answered twice = %q", got)
+	}
+
+	// The requester can deny too.
+	go func() {
+		ok, _ := ask(context.Background(), req)
+		answer <- ok
+	}()
+	prompt = <-msgBus.Outbound
+	if got := reply(bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "c1", Content: prompt.Buttons[1].Data}); got != "Denied." {
+		t.Errorf("deny = %q", got)
+	}
+	if ok := <-answer; ok {
+		t.Error("asker returned true after deny")
+	}
+}
+
+func TestGateway_Audit(t *testing.T) {
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: t.TempDir()}}
+	msgBus := bus.NewMessageBus(10)
+	mockRt := &mockRuntime{response: &api.Response{Result: &api.Result{Output: "hi back"}}}
+	g := &Gateway{cfg: cfg, bus: msgBus, runtime: mockRt, started: time.Now()}
+	g.audit = audit.NewLog(audit.Path(cfg.Agent.Workspace))
+	msgBus.TapOutbound(g.auditOutbound)
+	delivered := make(chan struct{}, 1)
+	msgBus.SubscribeOutbound("telegram", func(bus.OutboundMessage) { delivered <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.processLoop(ctx)
+	go msgBus.DispatchOutbound(ctx)
+
+	msgBus.Inbound <- bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "c1", Content: "hello"}
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("no reply")
+	}
+
+	entries, err := audit.Read(audit.Path(cfg.Agent.Workspace), time.Time{})
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("audit = %+v, %v", entries, err)
+	}
+	if in := entries[0]; in.Kind != audit.Inbound || in.Sender != "7" || in.Content != "hello" {
+		t.Errorf("inbound entry = %+v", in)
+	}
+	if out := entries[1]; out.Kind != audit.Outbound || out.ChatID != "c1" || out.Content != "hi back" {
+		t.Errorf("outbound entry = %+v", out)
+	}
+}
+
+func TestSenderName(t *testing.T) {
+	tests := []struct {
+		msg  bus.InboundMessage
+		want string
+	}{
+		{bus.InboundMessage{SenderID: "42", Metadata: map[string]any{"first_name": "Ana", "username": "ana_b"}}, "Ana"},
+		{bus.InboundMessage{SenderID: "42", Metadata: map[string]any{"username": "ana_b"}}, "ana_b"},
+		{bus.InboundMessage{SenderID: "42", Metadata: map[string]any{"push_name": " "}}, "42"},
+		{bus.InboundMessage{ChatID: "c1"}, "c1"},
+	}
+	for _, tt := range tests {
+		if got := senderName(tt.msg); got != tt.want {
+			t.Errorf("senderName(%+v) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestGateway_SendFeedDigest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><title>Go Blog</title>
+<item><title>Go 1.30 is released</title><link>https://go.dev/blog/go1.30</link></item>
+</channel></rss>`))
+	}))
+	defer srv.Close()
+
+	msgBus := bus.NewMessageBus(10)
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: t.TempDir()}}
+	cfg.Feeds = config.FeedsConfig{Schedule: "every day", Deliver: []string{"telegram:42"}, Feeds: []config.FeedConfig{{URL: srv.URL}}}
+	reqCh := make(chan api.Request, 2)
+	g := &Gateway{
+		cfg:     cfg,
+		bus:     msgBus,
+		runtime: &mockRuntime{response: &api.Response{Result: &api.Result{Output: "Go 1.30 is out."}}, reqCh: reqCh},
+	}
+	if err := g.setupFeeds(); err == nil {
+		t.Error("setupFeeds accepted schedule \"every day\"")
+	}
+	cfg.Feeds.Schedule = ""
+	if err := g.setupFeeds(); err != nil {
+		t.Fatal(err)
+	}
+
+	g.sendFeedDigest(context.Background())
+	req := <-reqCh
+	if req.SessionID != feedsSessionID || !contains(req.Prompt, "- Go 1.30 is released") {
+		t.Errorf("request = %+v", req)
+	}
+	select {
+	case out := <-msgBus.Outbound:
+		if out.Channel != "telegram" || out.ChatID != "42" || !contains(out.Content, "Go 1.30 is out.") {
+			t.Errorf("digest = %+v", out)
+		}
+	default:
+		t.Fatal("digest not delivered")
+	}
+
+	// Nothing new: no run, no message.
+	g.sendFeedDigest(context.Background())
+	select {
+	case req := <-reqCh:
+		t.Errorf("ran again: %+v", req)
+	case out := <-msgBus.Outbound:
+		t.Errorf("delivered again: %+v", out)
+	default:
+	}
+}
+
+func TestGateway_AddVoice(t *testing.T) {
+	var got struct {
+		Input          string  `json:"input"`
+		Voice          string  `json:"voice"`
+		Speed          float64 `json:"speed"`
+		ResponseFormat string  `json:"response_format"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte("OggS"))
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Media.TTS = config.TTSConfig{Provider: "openai", APIKey: "sk-test", BaseURL: srv.URL}
+	cfg.Channels.Telegram.VoiceReplies = config.VoiceReplyConfig{Mode: "voice", Voice: "nova", Speed: 1.2, MaxChars: 20}
+	g := &Gateway{cfg: cfg}
+
+	msg := bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "It is **sunny**."}
+	g.addVoice(context.Background(), &msg)
+	if string(msg.Voice) != "OggS" || !msg.VoiceOnly {
+		t.Errorf("msg = %+v", msg)
+	}
+	if got.Input != "It is sunny." || got.Voice != "nova" || got.Speed != 1.2 || got.ResponseFormat != "opus" {
+		t.Errorf("request = %+v", got)
+	}
+
+	// Too long to listen to: text only.
+	msg = bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: strings.Repeat("word ", 10)}
+	g.addVoice(context.Background(), &msg)
+	if msg.Voice != nil {
+		t.Errorf("long reply got a voice note")
+	}
+
+	// Channels without voice replies are left alone.
+	msg = bus.OutboundMessage{Channel: "whatsapp", ChatID: "1", Content: "hi"}
+	g.addVoice(context.Background(), &msg)
+	if msg.Voice != nil {
+		t.Errorf("whatsapp reply got a voice note")
+	}
+}
+
+func TestGateway_HandleMessage_Images(t *testing.T) {
+	ctx := context.Background()
+	gen := &fakeImageGenerator{}
+	draw := imagegen.NewTool(gen, filepath.Join(t.TempDir(), "images"))
+	mockRt := &mockRuntime{
+		response: &api.Response{Result: &api.Result{Output: "Here is your fox."}},
+		onRun: func(ctx context.Context) {
+			if res, err := draw.Execute(ctx, map[string]any{"prompt": "a fox"}); err != nil || !res.Success {
+				t.Errorf("image_generate = %+v, %v", res, err)
+			}
+		},
+	}
+	g := &Gateway{cfg: &config.Config{Agent: config.AgentConfig{Workspace: t.TempDir()}}, bus: bus.NewMessageBus(10), runtime: mockRt}
+
+	g.handleMessage(ctx, bus.InboundMessage{Channel: "telegram", SenderID: "u1", ChatID: "c1", Content: "draw a fox"}, "telegram:c1")
+	out := <-g.bus.Outbound
+	if out.Content != "Here is your fox." || len(out.Media) != 1 || !strings.HasSuffix(out.Media[0], "-a-fox.png") {
+		t.Errorf("out = %+v", out)
+	}
+}
+
+type fakeImageGenerator struct{}
+
+func (fakeImageGenerator) Name() string { return "fake" }
+
+func (fakeImageGenerator) Generate(context.Context, string, string) (*imagegen.Image, error) {
+	return &imagegen.Image{Data: []byte("\x89PNG"), MediaType: "image/png"}, nil
+}
+
+func TestInboxJournal(t *testing.T) {
+	path := journalPath(t.TempDir())
+	now := time.Now() // opening compacts by the clock
+	j, err := openInboxJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.now = func() time.Time { return now }
+
+	first := bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "hi", Metadata: map[string]any{"message_id": 7}}
+	id, ok, err := j.add(first)
+	if err != nil || !ok {
+		t.Fatalf("add = %v, %v", ok, err)
+	}
+	if _, ok, _ := j.add(first); ok {
+		t.Error("a redelivered message was taken twice")
+	}
+	if err := j.ack(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := j.add(first); ok {
+		t.Error("a redelivered message was taken again after its ack")
+	}
+	// Without a message ID there is nothing to tell repeats by.
+	webui := bus.InboundMessage{Channel: "webui", ChatID: "1", Content: "hi"}
+	for range 2 {
+		if _, ok, _ := j.add(webui); !ok {
+			t.Error("a webui message was dropped")
+		}
+	}
+	j.close()
+
+	// A crash cut the last line short.
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"id":"x","msg":{"Chan`)
+	f.Close()
+
+	j, err = openInboxJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.now = func() time.Time { return now }
+	recs := j.replay()
+	if len(recs) != 2 || recs[0].Msg.Content != "hi" || recs[0].Msg.Channel != "webui" {
+		t.Fatalf("replay = %+v", recs)
+	}
+	if _, ok, _ := j.add(first); ok {
+		t.Error("the key of an acked message was forgotten on reopening")
+	}
+	for _, rec := range recs {
+		j.ack(rec.ID)
+	}
+	j.close()
+
+	// A day on, keys and unanswered messages are forgotten.
+	j, _ = openInboxJournal(path)
+	defer j.close()
+	j.add(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "still there?", Metadata: map[string]any{"message_id": 8}})
+	now = now.Add(journalKeep + time.Minute)
+	j.now = func() time.Time { return now }
+	if err := j.compact(); err != nil {
+		t.Fatal(err)
+	}
+	if len(j.replay()) != 0 || len(j.seen) != 0 {
+		t.Errorf("after a day: pending %v, seen %v", j.replay(), j.seen)
+	}
+	if _, ok, _ := j.add(first); !ok {
+		t.Error("a day-old key still drops messages")
+	}
+}
+
+func TestGateway_ReplaysJournal(t *testing.T) {
+	workspace := t.TempDir()
+	j, err := openInboxJournal(journalPath(workspace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.add(bus.InboundMessage{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "where were we?", Metadata: map[string]any{"message_id": 3}})
+	j.close()
+
+	mockRt := &mockRuntime{
+		response: &api.Response{Result: &api.Result{Output: "Right here."}},
+		reqCh:    make(chan api.Request, 1),
+	}
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: workspace}}
+	g, err := NewWithOptions(cfg, Options{RuntimeFactory: mockRuntimeFactory(mockRt)})
+	if err != nil {
+		t.Fatalf("NewWithOptions error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go g.processLoop(ctx)
+	select {
+	case req := <-mockRt.reqCh:
+		if !strings.Contains(req.Prompt, "where were we?") {
+			t.Errorf("prompt = %q", req.Prompt)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the journaled message was not replayed")
+	}
+	if out := <-g.bus.Outbound; out.Content != "Right here." {
+		t.Errorf("reply = %q", out.Content)
+	}
+	// The channel delivers it again after reconnecting.
+	g.bus.Inbound <- bus.InboundMessage{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "where were we?", Metadata: map[string]any{"message_id": 3}}
+	select {
+	case <-mockRt.reqCh:
+		t.Error("a redelivered message was answered again")
+	case <-time.After(100 * time.Millisecond):
+	}
+	cancel()
+	g.Shutdown()
+	j, _ = openInboxJournal(journalPath(workspace))
+	defer j.close()
+	if recs := j.replay(); len(recs) != 0 {
+		t.Errorf("left for replay: %+v", recs)
+	}
+}
+
+func TestRuntimePool(t *testing.T) {
+	var built []*mockRuntime
+	fail := false
+	pool := newRuntimePool(2, time.Minute, func(channel string) (Runtime, error) {
+		if fail {
+			return nil, fmt.Errorf("bad config")
+		}
+		rt := &mockRuntime{response: &api.Response{Result: &api.Result{Output: channel}}}
+		built = append(built, rt)
+		return rt, nil
+	})
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+	use := func(channel, session string) (Runtime, bool) {
+		t.Helper()
+		rt, release, ok, err := pool.acquire(channel, session)
+		if err != nil {
+			t.Fatalf("acquire %s/%s: %v", channel, session, err)
+		}
+		if ok {
+			release()
+		}
+		now = now.Add(time.Second)
+		return rt, ok
+	}
+
+	a, _ := use("telegram", "a")
+	if again, _ := use("telegram", "a"); again != a || len(built) != 1 {
+		t.Errorf("session a got a second runtime (%d built)", len(built))
+	}
+	if other, _ := use("slack", "a"); other == a {
+		t.Error("a session on another channel shares a runtime")
+	}
+
+	// Full: a new session evicts the least recently used runtime.
+	use("telegram", "b")
+	if !built[0].closed || built[1].closed || pool.live() != 2 {
+		t.Errorf("after eviction closed = %v, %v, live %d", built[0].closed, built[1].closed, pool.live())
+	}
+
+	// Full of busy runtimes: the run goes to the shared runtime.
+	_, releaseB, _, _ := pool.acquire("telegram", "b")
+	_, releaseC, _, _ := pool.acquire("slack", "a")
+	if _, ok := use("telegram", "c"); ok {
+		t.Error("got a runtime from a pool of busy ones")
+	}
+
+	// A reset closes idle runtimes at once and busy ones when they finish.
+	pool.reset()
+	if built[2].closed || pool.live() != 0 {
+		t.Errorf("busy runtime closed by reset (live %d)", pool.live())
+	}
+	releaseB()
+	releaseC()
+	if !built[1].closed || !built[2].closed {
+		t.Error("busy runtimes not closed after their runs")
+	}
+
+	// Idle runtimes are swept.
+	use("telegram", "a")
+	now = now.Add(30 * time.Second)
+	use("telegram", "b")
+	now = now.Add(40 * time.Second)
+	if n := pool.sweep(); n != 1 || !built[3].closed || built[4].closed {
+		t.Errorf("sweep closed %d", n)
+	}
+
+	// A failed build is not kept.
+	fail = true
+	if _, _, _, err := pool.acquire("telegram", "d"); err == nil {
+		t.Error("expected the build error")
+	}
+	fail = false
+	if _, ok := use("telegram", "d"); !ok || pool.live() != 2 {
+		t.Errorf("session d after a failed build: ok %v, live %d", ok, pool.live())
+	}
+
+	pool.close()
+	if _, ok := use("telegram", "e"); ok || !built[4].closed {
+		t.Error("closed pool handed out a runtime")
+	}
+}
+
+func TestGateway_SessionRuntimes(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{Agent: config.AgentConfig{Workspace: tmpDir}}
+	cfg.Sessions.MaxRuntimes = 4
+	shared := &mockRuntime{response: &api.Response{Result: &api.Result{Output: "shared"}}}
+	g := &Gateway{cfg: cfg, runtime: shared, runtimeRuns: &sync.WaitGroup{}}
+	built := 0
+	g.buildRuntime = func(regs []api.SkillRegistration) (Runtime, error) {
+		built++
+		return &mockRuntime{response: &api.Response{Result: &api.Result{Output: fmt.Sprintf("session %d", built)}}}, nil
+	}
+	g.pool = newRuntimePool(4, time.Minute, g.buildSessionRuntime)
+
+	output := func(req api.Request) string {
+		resp, err := g.run(context.Background(), req)
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		return resp.Result.Output
+	}
+	chat := api.Request{Prompt: "hi", SessionID: "telegram:1", Channels: []string{"telegram"}}
+	if got := output(chat); got != "session 1" {
+		t.Errorf("chat run = %q", got)
+	}
+	if got := output(chat); got != "session 1" || built != 1 {
+		t.Errorf("second chat run = %q (%d built)", got, built)
+	}
+	if got := output(api.Request{Prompt: "hi", SessionID: "system"}); got != "shared" {
+		t.Errorf("system run = %q", got)
+	}
+
+	if err := g.swapRuntimes(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := output(chat); got != "session 3" {
+		t.Errorf("chat run after a swap = %q", got)
+	}
+	if !strings.Contains(g.adminStatus(), "Session runtimes: 1 live (up to 4)") {
+		t.Errorf("status = %q", g.adminStatus())
+	}
+}
@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/channel"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/health"
+)
+
+// providerCheckTTL is how long a provider check is reused, so that probes
+// every few seconds do not each call the provider.
+const providerCheckTTL = 30 * time.Second
+
+// healthMux serves /healthz, which answers while the gateway runs, and
+// /readyz, which checks the config, the provider and the channels.
+func (g *Gateway) healthMux() *http.ServeMux {
+	providerProbe := health.Provider(g.config(), providerCheckTTL)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"ok":     true,
+			"uptime": time.Since(g.started).Round(time.Second).String(),
+		})
+	})
+	mux.Handle("GET /readyz", health.Handler(func() []health.Probe {
+		return append([]health.Probe{health.Config(), providerProbe}, g.channelProbes()...)
+	}))
+	return mux
+}
+
+// channelProbes checks each channel this instance runs. A channel that
+// another cluster member owns is not running here, and is not a failure.
+func (g *Gateway) channelProbes() []health.Probe {
+	status := g.channels.Status()
+	names := make([]string, 0, len(status))
+	for name := range status {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	probes := make([]health.Probe, 0, len(names))
+	for _, name := range names {
+		s := status[name]
+		probes = append(probes, health.Probe{Name: "channel:" + name, Run: func(context.Context) error { return s.Healthy() }})
+	}
+	return probes
+}
+
+// serveHealth serves the health endpoints, and the dashboard and event
+// stream when they are on, on the gateway's address. When the web UI is on, it already listens
+// there and serves them instead.
+func (g *Gateway) serveHealth(ctx context.Context) error {
+	mux := g.healthMux()
+	paths := []string{"/healthz", "/readyz"}
+	if g.config().Gateway.Dashboard.Enabled {
+		g.mountDashboard(mux)
+		paths = append(paths, "/dashboard", "/dashboard/")
+	}
+	if g.config().Gateway.Events.Enabled && g.events != nil {
+		g.mountEvents(mux)
+		paths = append(paths, "/events")
+	}
+	if ch, ok := g.channels.Channel("webui"); ok {
+		if web, ok := ch.(*channel.WebUIChannel); ok {
+			for _, path := range paths {
+				web.Handle(path, mux)
+			}
+			return nil
+		}
+	}
+
+	port := g.config().Gateway.Port
+	if port == 0 {
+		port = config.DefaultPort
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(g.config().Gateway.Host, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("health endpoints: %w", err)
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[gateway] health server error: %v", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,288 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+)
+
+const (
+	// journalKeep is how long the key of a message is remembered, to drop
+	// a channel's redelivery of it, and the oldest message replayed.
+	journalKeep = 24 * time.Hour
+	// journalCompactSize is how large the journal grows before the records
+	// of finished messages are dropped from it.
+	journalCompactSize = 1 << 20
+)
+
+// inboxJournal is a write-ahead log of chat messages. A message is written
+// and synced before it is handled and acked once it has been answered, so
+// that messages a crash interrupted are replayed when the gateway starts
+// again. Delivery is at least once: a message answered just before a crash
+// may be answered twice. Messages a channel delivers twice, such as after
+// a reconnect, are recognised by their channel's message ID and dropped.
+type inboxJournal struct {
+	path string
+	now  func() time.Time
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	pending map[string]journalRecord // by ID
+	order   []string                 // pending IDs, oldest first
+	seen    map[string]time.Time     // message keys, when they arrived
+}
+
+// journalRecord is a line of the journal: a message, or the ack of one.
+type journalRecord struct {
+	ID   string              `json:"id,omitempty"`
+	Ack  string              `json:"ack,omitempty"`
+	Key  string              `json:"key,omitempty"`
+	AtMs int64               `json:"atMs"`
+	Msg  *bus.InboundMessage `json:"msg,omitempty"`
+}
+
+// journalPath is where a workspace keeps its inbound message journal.
+func journalPath(workspace string) string {
+	return filepath.Join(workspace, ".claude", "inbox.jsonl")
+}
+
+// openInboxJournal reads the journal at path, keeping the messages not
+// acked for replay, and compacts it.
+func openInboxJournal(path string) (*inboxJournal, error) {
+	j := &inboxJournal{
+		path:    path,
+		now:     time.Now,
+		pending: make(map[string]journalRecord),
+		seen:    make(map[string]time.Time),
+	}
+	f, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read inbox journal: %w", err)
+	}
+	if f != nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+		for scanner.Scan() {
+			var rec journalRecord
+			// A crash can leave the last line cut short.
+			if json.Unmarshal(scanner.Bytes(), &rec) != nil {
+				continue
+			}
+			j.apply(rec)
+		}
+		err := scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read inbox journal: %w", err)
+		}
+	}
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// apply replays rec onto the journal's state.
+func (j *inboxJournal) apply(rec journalRecord) {
+	if rec.Key != "" {
+		j.seen[rec.Key] = time.UnixMilli(rec.AtMs)
+	}
+	switch {
+	case rec.Msg != nil && rec.ID != "":
+		if _, ok := j.pending[rec.ID]; !ok {
+			j.order = append(j.order, rec.ID)
+		}
+		j.pending[rec.ID] = rec
+	case rec.Ack != "":
+		delete(j.pending, rec.Ack)
+	}
+}
+
+// add journals msg and returns the ID to ack it by. ok is false when msg
+// is a message already taken, which should be dropped.
+func (j *inboxJournal) add(msg bus.InboundMessage) (id string, ok bool, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	key := journalKey(msg)
+	now := j.now()
+	if at, seen := j.seen[key]; key != "" && seen && now.Sub(at) < journalKeep {
+		return "", false, nil
+	}
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	rec := journalRecord{ID: hex.EncodeToString(b), Key: key, AtMs: now.UnixMilli(), Msg: &msg}
+	if err := j.write(rec, true); err != nil {
+		return "", false, err
+	}
+	j.apply(rec)
+	return rec.ID, true, nil
+}
+
+// ack records that the message with id has been handled.
+func (j *inboxJournal) ack(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	rec, ok := j.pending[id]
+	if !ok {
+		return nil
+	}
+	if err := j.write(journalRecord{Ack: id, Key: rec.Key, AtMs: rec.AtMs}, false); err != nil {
+		return err
+	}
+	delete(j.pending, id)
+	if j.size > journalCompactSize {
+		return j.compact()
+	}
+	return nil
+}
+
+// replay returns the messages not acked, oldest first, with their IDs.
+func (j *inboxJournal) replay() []journalRecord {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var recs []journalRecord
+	for _, id := range j.order {
+		if rec, ok := j.pending[id]; ok {
+			recs = append(recs, rec)
+		}
+	}
+	return recs
+}
+
+func (j *inboxJournal) write(rec journalRecord, sync bool) error {
+	if j.f == nil {
+		return os.ErrClosed
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	n, err := j.f.Write(append(data, '\n'))
+	j.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write inbox journal: %w", err)
+	}
+	if sync {
+		return j.f.Sync()
+	}
+	return nil
+}
+
+// compact rewrites the journal with the pending messages and the keys of
+// recent ones, dropping what is older than journalKeep, and reopens it for
+// appending. The caller holds j.mu, or has the journal to itself.
+func (j *inboxJournal) compact() error {
+	cutoff := j.now().Add(-journalKeep)
+	var recs []journalRecord
+	var order []string
+	for _, id := range j.order {
+		rec, ok := j.pending[id]
+		if !ok {
+			continue
+		}
+		if time.UnixMilli(rec.AtMs).Before(cutoff) {
+			log.Printf("[gateway] not replaying a message from %s/%s received %s", rec.Msg.Channel, rec.Msg.SenderID, time.UnixMilli(rec.AtMs).Format(time.RFC3339))
+			delete(j.pending, id)
+			continue
+		}
+		recs = append(recs, rec)
+		order = append(order, id)
+	}
+	j.order = order
+	// Pending messages carry their key; the others' are kept on their own.
+	pendingKeys := make(map[string]bool, len(recs))
+	for _, rec := range recs {
+		pendingKeys[rec.Key] = true
+	}
+	for key, at := range j.seen {
+		switch {
+		case at.Before(cutoff):
+			delete(j.seen, key)
+		case !pendingKeys[key]:
+			recs = append(recs, journalRecord{Key: key, AtMs: at.UnixMilli()})
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, rec := range recs {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if j.f != nil {
+		j.f.Close()
+		j.f = nil
+	}
+	if err := writeSynced(j.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("write inbox journal: %w", err)
+	}
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open inbox journal: %w", err)
+	}
+	j.f, j.size = f, int64(buf.Len())
+	return nil
+}
+
+// writeSynced replaces the file at path with data, which is on disk when it
+// returns.
+func writeSynced(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (j *inboxJournal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
+
+// journalKey identifies msg by the ID its channel gave it, or is empty for
+// channels that give none. The content is part of it, since Telegram
+// button presses carry the ID of the message the buttons are on.
+func journalKey(msg bus.InboundMessage) string {
+	for _, name := range []string{"message_id", "msg_id"} {
+		if id, ok := msg.Metadata[name]; ok && id != nil && fmt.Sprint(id) != "" {
+			sum := sha256.Sum256([]byte(msg.Content))
+			return fmt.Sprintf("%s:%s:%v:%x", msg.Channel, msg.ChatID, id, sum[:8])
+		}
+	}
+	return ""
+}
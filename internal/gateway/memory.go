@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	rcron "github.com/robfig/cron/v3"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+const (
+	rollupSessionID  = "memory-rollup"
+	extractSessionID = "memory-extract"
+)
+
+// memoryRollupLoop folds old journal files into MEMORY.md on the configured
+// schedule. In cluster mode only the leader rolls up.
+func (g *Gateway) memoryRollupLoop(ctx context.Context) {
+	rc := g.config().Memory.Rollup
+	parser := rcron.NewParser(rcron.Second | rcron.Minute | rcron.Hour | rcron.Dom | rcron.Month | rcron.Dow | rcron.Descriptor)
+	schedule, err := parser.Parse(rc.Schedule)
+	if err != nil {
+		log.Printf("[gateway] memory rollup disabled, bad schedule %q: %v", rc.Schedule, err)
+		return
+	}
+	log.Printf("[gateway] memory rollup scheduled (%s, keep %d days)", rc.Schedule, rc.KeepDays)
+
+	for {
+		timer := time.NewTimer(time.Until(schedule.Next(time.Now())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if g.coord != nil && !g.coord.IsLeader() {
+			continue
+		}
+		g.rollupMemory(ctx)
+	}
+}
+
+// memorySyncLoop commits the memory directory and syncs it with the remote
+// on the configured schedule. In cluster mode only the leader syncs.
+func (g *Gateway) memorySyncLoop(ctx context.Context) {
+	sc := g.config().Memory.Sync
+	parser := rcron.NewParser(rcron.Second | rcron.Minute | rcron.Hour | rcron.Dom | rcron.Month | rcron.Dow | rcron.Descriptor)
+	schedule, err := parser.Parse(sc.Schedule)
+	if err != nil {
+		log.Printf("[gateway] memory sync disabled, bad schedule %q: %v", sc.Schedule, err)
+		return
+	}
+	log.Printf("[gateway] memory sync scheduled (%s)", sc.Schedule)
+
+	gs := memory.NewGitSync(g.config().Agent.Workspace, sc)
+	for {
+		timer := time.NewTimer(time.Until(schedule.Next(time.Now())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if g.coord != nil && !g.coord.IsLeader() {
+			continue
+		}
+		result, err := gs.Sync(ctx)
+		if err != nil {
+			log.Printf("[gateway] %v", err)
+			continue
+		}
+		if result.Committed || result.Pulled {
+			log.Printf("[gateway] memory synced (committed=%v pulled=%v pushed=%v)", result.Committed, result.Pulled, result.Pushed)
+		}
+	}
+}
+
+// summarizer runs memory housekeeping prompts in their own session, which is
+// deleted afterwards so it never shows up among conversations.
+func (g *Gateway) summarizer(sessionID string) memory.Summarizer {
+	return func(ctx context.Context, prompt string) (string, error) {
+		defer session.NewStore(g.config().Agent.Workspace).Delete(sessionID)
+		resp, err := g.run(ctx, api.Request{Prompt: prompt, SessionID: sessionID})
+		if err != nil || resp == nil || resp.Result == nil {
+			return "", err
+		}
+		return resp.Result.Output, nil
+	}
+}
+
+func (g *Gateway) rollupMemory(ctx context.Context) {
+	result, err := g.mem.Rollup(ctx, g.config().Memory.Rollup.KeepDays, time.Now(), g.summarizer(rollupSessionID))
+	if err != nil {
+		log.Printf("[gateway] memory rollup error: %v", err)
+		return
+	}
+	if len(result.Days) > 0 {
+		log.Printf("[gateway] memory rollup: archived %d journal files, added %d bytes to MEMORY.md", len(result.Days), len(result.Added))
+		if result.Added != "" {
+			g.emit(Event{Type: EventMemoryWritten, Session: rollupSessionID, Path: g.mem.LongTermPath(), Text: result.Added})
+		}
+	}
+}
+
+// extractMemory looks for durable facts in one exchange in the background;
+// Shutdown waits for it to finish.
+func (g *Gateway) extractMemory(user, reply string) {
+	g.extractWG.Add(1)
+	go func() {
+		defer g.extractWG.Done()
+		facts, err := g.mem.Extract(context.Background(), user, reply, g.summarizer(extractSessionID))
+		if err != nil {
+			log.Printf("[gateway] memory extraction error: %v", err)
+			return
+		}
+		if len(facts) > 0 {
+			log.Printf("[gateway] memory: noted %d new facts", len(facts))
+			g.emit(Event{Type: EventMemoryWritten, Session: extractSessionID, Path: g.mem.LongTermPath(), Text: strings.Join(facts, "\n")})
+		}
+	}()
+}
@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/notify"
+)
+
+// pushNotification sends title and text to the notify sink called name.
+func (g *Gateway) pushNotification(ctx context.Context, name, title, text string) error {
+	sink, ok := g.config().Notify[name]
+	if !ok {
+		return fmt.Errorf("no notify sink named %q", name)
+	}
+	return notify.Send(ctx, sink, title, text)
+}
+
+// notifyEvents reports whether any notify sink subscribes to events.
+func notifyEvents(cfg *config.Config) bool {
+	for _, sink := range cfg.Notify {
+		if len(sink.Events) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// startNotify pushes the gateway's events to the notify sinks that list
+// them until ctx is done. Unlike webhooks, a sink without events gets
+// none: it only receives what is delivered to it as "notify:<name>".
+func (g *Gateway) startNotify(ctx context.Context) {
+	for _, name := range slices.Sorted(maps.Keys(g.config().Notify)) {
+		for _, typ := range g.config().Notify[name].Events {
+			if !slices.Contains(eventTypes, typ) {
+				log.Printf("[gateway] notify.%s: unknown event type %q", name, typ)
+			}
+		}
+	}
+	events, cancel := g.events.subscribe()
+	go func() {
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-events:
+				sinks := g.config().Notify
+				for _, name := range slices.Sorted(maps.Keys(sinks)) {
+					if !slices.Contains(sinks[name].Events, ev.Type) {
+						continue
+					}
+					title, text := eventNotification(ev)
+					if err := notify.Send(ctx, sinks[name], title, text); err != nil {
+						log.Printf("[gateway] notify %s: %v", name, err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// eventNotification words ev as a notification title and text.
+func eventNotification(ev Event) (title, text string) {
+	text = ev.Text
+	switch ev.Type {
+	case EventTaskFinished:
+		title = fmt.Sprintf("Task %s %s", ev.Task, ev.Status)
+		if text == "" {
+			text = ev.Error
+		}
+	case EventCronFired:
+		title = "Cron: " + ev.Job
+	case EventReminderDue:
+		title = "Reminder"
+	case EventBudgetExceeded:
+		title = "Daily budget exceeded"
+		text = fmt.Sprintf("$%.2f spent of $%.2f.", ev.SpentUSD, ev.BudgetUSD)
+	case EventError:
+		title = "Error"
+		if ev.Job != "" {
+			title = "Cron job " + ev.Job + " failed"
+		} else if ev.Task != "" {
+			title = "Task " + ev.Task + " failed"
+		}
+		text = ev.Error
+	case EventMessageReceived:
+		title = fmt.Sprintf("Message from %s on %s", ev.Sender, ev.Channel)
+	case EventMessageSent:
+		title = "Reply on " + ev.Channel
+	case EventToolCall:
+		title = "Tool call: " + ev.Tool
+	case EventMemoryWritten:
+		title = "Memory updated"
+		if text == "" {
+			text = ev.Path
+		}
+	default:
+		title = ev.Type
+	}
+	return title, text
+}
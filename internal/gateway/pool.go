@@ -0,0 +1,225 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/skills"
+)
+
+// runtimeSweepInterval is how often idle session runtimes are looked for.
+const runtimeSweepInterval = time.Minute
+
+// runtimePool gives each chat session a runtime of its own, built on the
+// session's first message. At most max are live at once: a new session
+// evicts the least recently used idle one, and when all are busy its run
+// goes to the shared runtime instead. Runtimes idle for longer than idle
+// are closed by sweep; their sessions reload history from disk when they
+// speak again.
+type runtimePool struct {
+	build func(channel string) (Runtime, error)
+	max   int
+	idle  time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[poolKey]*pooledRuntime
+	closed  bool
+}
+
+type poolKey struct {
+	channel string
+	session string
+}
+
+type pooledRuntime struct {
+	ready chan struct{} // closed once rt or err is set
+	rt    Runtime
+	err   error
+
+	// Guarded by runtimePool.mu.
+	runs     int
+	lastUsed time.Time
+	evicted  bool // out of the pool; closed when its last run ends
+}
+
+func newRuntimePool(max int, idle time.Duration, build func(channel string) (Runtime, error)) *runtimePool {
+	return &runtimePool{
+		build:   build,
+		max:     max,
+		idle:    idle,
+		now:     time.Now,
+		entries: make(map[poolKey]*pooledRuntime),
+	}
+}
+
+// acquire returns the runtime of a session, building it if need be, and a
+// release func to call when the run ends. ok is false when the pool is
+// closed or full of busy runtimes; the caller then uses the shared one.
+func (p *runtimePool) acquire(channel, session string) (rt Runtime, release func(), ok bool, err error) {
+	key := poolKey{channel, session}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, nil, false, nil
+	}
+	var evicted Runtime
+	e := p.entries[key]
+	building := e == nil
+	if building {
+		if len(p.entries) >= p.max {
+			var found bool
+			if evicted, found = p.evictLRU(); !found {
+				p.mu.Unlock()
+				return nil, nil, false, nil
+			}
+		}
+		e = &pooledRuntime{ready: make(chan struct{})}
+		p.entries[key] = e
+	}
+	e.runs++
+	p.mu.Unlock()
+	closeRuntime(evicted)
+
+	if building {
+		e.rt, e.err = p.build(channel)
+		close(e.ready)
+	} else {
+		<-e.ready
+	}
+	release = func() { p.release(key, e) }
+	if e.err != nil {
+		release()
+		return nil, nil, false, e.err
+	}
+	return e.rt, release, true, nil
+}
+
+func (p *runtimePool) release(key poolKey, e *pooledRuntime) {
+	p.mu.Lock()
+	e.runs--
+	e.lastUsed = p.now()
+	var done Runtime
+	switch {
+	case e.err != nil && !e.evicted:
+		// Built again on the session's next message.
+		p.remove(key, e)
+	case e.evicted && e.runs == 0:
+		done = e.rt
+	}
+	p.mu.Unlock()
+	closeRuntime(done)
+}
+
+// evictLRU evicts the least recently used idle runtime, returning it to be
+// closed, and reports whether there was one. The caller holds p.mu.
+func (p *runtimePool) evictLRU() (Runtime, bool) {
+	var oldest poolKey
+	var found *pooledRuntime
+	for key, e := range p.entries {
+		if e.runs == 0 && (found == nil || e.lastUsed.Before(found.lastUsed)) {
+			oldest, found = key, e
+		}
+	}
+	if found == nil {
+		return nil, false
+	}
+	return p.remove(oldest, found), true
+}
+
+// remove takes e out of the pool and returns its runtime for the caller to
+// close once it lets go of p.mu, or nil while a run still uses it; the
+// last run's release closes it then.
+func (p *runtimePool) remove(key poolKey, e *pooledRuntime) Runtime {
+	if p.entries[key] == e {
+		delete(p.entries, key)
+	}
+	e.evicted = true
+	if e.runs > 0 {
+		return nil
+	}
+	return e.rt
+}
+
+// sweep closes the runtimes unused since before idle ago and returns how
+// many it closed.
+func (p *runtimePool) sweep() int {
+	p.mu.Lock()
+	cutoff := p.now().Add(-p.idle)
+	var idle []Runtime
+	for key, e := range p.entries {
+		if e.runs == 0 && e.lastUsed.Before(cutoff) {
+			idle = append(idle, p.remove(key, e))
+		}
+	}
+	p.mu.Unlock()
+	for _, rt := range idle {
+		closeRuntime(rt)
+	}
+	return len(idle)
+}
+
+// reset empties the pool, so that sessions get runtimes built with the
+// current skills and config. Runs in progress finish on their runtime.
+func (p *runtimePool) reset() {
+	p.mu.Lock()
+	var idle []Runtime
+	for key, e := range p.entries {
+		idle = append(idle, p.remove(key, e))
+	}
+	p.mu.Unlock()
+	for _, rt := range idle {
+		closeRuntime(rt)
+	}
+}
+
+func closeRuntime(rt Runtime) {
+	if rt != nil {
+		rt.Close()
+	}
+}
+
+// close empties the pool for good; later runs use the shared runtime.
+func (p *runtimePool) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.reset()
+}
+
+// live returns how many runtimes the pool holds.
+func (p *runtimePool) live() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+// buildSessionRuntime builds a runtime for a session on channel, with the
+// skills the channel's scope leaves it.
+func (g *Gateway) buildSessionRuntime(channel string) (Runtime, error) {
+	g.runtimeMu.RLock()
+	skillRegs := g.skillRegs
+	g.runtimeMu.RUnlock()
+	if scope, ok := g.config().Channels.SkillScopes()[channel]; ok {
+		skillRegs = skills.Scope(skillRegs, scope.Allow, scope.Deny)
+	}
+	return g.buildRuntime(skillRegs)
+}
+
+// runtimePoolLoop closes idle session runtimes until ctx is done.
+func (g *Gateway) runtimePoolLoop(ctx context.Context) {
+	ticker := time.NewTicker(runtimeSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := g.pool.sweep(); n > 0 {
+				log.Printf("[gateway] closed %d idle session runtimes, %d live", n, g.pool.live())
+			}
+		}
+	}
+}
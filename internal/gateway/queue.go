@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	defaultQueueConcurrency = 4
+	defaultQueueMaxPending  = 100
+)
+
+// defaultBusyReply answers a message turned away because the queue is full.
+const defaultBusyReply = "I'm handling a lot of messages right now. Please try again in a minute."
+
+// workQueue runs chat messages with bounded concurrency. Messages of one
+// session run one at a time, in the order they arrived; different sessions
+// run side by side up to the global and per-channel limits.
+type workQueue struct {
+	global   chan struct{}            // one token per run in progress
+	channels map[string]chan struct{} // the same, for channels with their own limit
+	slots    chan struct{}            // one token per message queued or running
+	block    bool                     // wait for a slot instead of rejecting
+
+	mu      sync.Mutex
+	lanes   map[string][]func() // per session; the head is the job running
+	running atomic.Int64
+}
+
+func newWorkQueue(cfg config.QueueConfig) *workQueue {
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultQueueConcurrency
+	}
+	maxPending := cfg.MaxPending
+	if maxPending <= 0 {
+		maxPending = defaultQueueMaxPending
+	}
+	q := &workQueue{
+		global:   make(chan struct{}, concurrency),
+		channels: map[string]chan struct{}{},
+		slots:    make(chan struct{}, maxPending),
+		block:    cfg.Overflow == config.QueueOverflowBlock,
+		lanes:    map[string][]func(){},
+	}
+	for name, limit := range cfg.ChannelConcurrency {
+		if limit > 0 {
+			q.channels[name] = make(chan struct{}, limit)
+		}
+	}
+	return q
+}
+
+// submit queues job for session on channel. It reports false when the queue
+// is full and job was not queued; in block mode it waits for room instead,
+// and reports false only if ctx ends first.
+func (q *workQueue) submit(ctx context.Context, channel, session string, job func()) bool {
+	if q.block {
+		select {
+		case q.slots <- struct{}{}:
+		case <-ctx.Done():
+			return false
+		}
+	} else {
+		select {
+		case q.slots <- struct{}{}:
+		default:
+			return false
+		}
+	}
+
+	q.mu.Lock()
+	lane := q.lanes[session]
+	q.lanes[session] = append(lane, job)
+	q.mu.Unlock()
+	if len(lane) == 0 {
+		go q.drain(ctx, channel, session)
+	}
+	return true
+}
+
+// drain runs the jobs of session until its lane is empty. When ctx ends,
+// jobs still waiting are dropped.
+func (q *workQueue) drain(ctx context.Context, channel, session string) {
+	for {
+		q.mu.Lock()
+		lane := q.lanes[session]
+		if len(lane) == 0 {
+			delete(q.lanes, session)
+			q.mu.Unlock()
+			return
+		}
+		job := lane[0]
+		q.mu.Unlock()
+
+		if !q.acquire(ctx, channel) {
+			q.mu.Lock()
+			for range q.lanes[session] {
+				<-q.slots
+			}
+			delete(q.lanes, session)
+			q.mu.Unlock()
+			return
+		}
+		q.running.Add(1)
+		job()
+		q.running.Add(-1)
+		q.release(channel)
+
+		q.mu.Lock()
+		q.lanes[session] = q.lanes[session][1:]
+		<-q.slots
+		q.mu.Unlock()
+	}
+}
+
+// acquire takes a run token for channel, then a global one.
+func (q *workQueue) acquire(ctx context.Context, channel string) bool {
+	if sem := q.channels[channel]; sem != nil {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	select {
+	case q.global <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		if sem := q.channels[channel]; sem != nil {
+			<-sem
+		}
+		return false
+	}
+}
+
+func (q *workQueue) release(channel string) {
+	<-q.global
+	if sem := q.channels[channel]; sem != nil {
+		<-sem
+	}
+}
+
+// depth returns how many messages wait and how many run.
+func (q *workQueue) depth() (waiting, running int) {
+	running = int(q.running.Load())
+	return len(q.slots) - running, running
+}
+
+// limit returns the global concurrency limit.
+func (q *workQueue) limit() int {
+	return cap(q.global)
+}
@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// reloadDebounce groups the writes of one save into one reload.
+const reloadDebounce = 500 * time.Millisecond
+
+// runtimeSections are the config sections a reload applies by building a
+// new runtime. Channels are applied by the channel manager and
+// liveSections as they are; the other sections are read once at startup
+// and take a restart.
+var runtimeSections = []string{
+	"agent", "provider", "models", "tools", "skills", "hooks", "mcp",
+	"autoCompact", "tokenTracking", "permissions", "redaction",
+}
+
+// liveSections are the config sections read each time they are used, so a
+// reload applies them by storing the new config.
+var liveSections = []string{"webhooks", "notify"}
+
+// reload re-reads the config file and the cron jobs and applies what
+// changed while the gateway runs. Runs in progress finish on the runtime
+// they started on. A config that fails to load or validate is ignored and
+// the gateway carries on with the one it has.
+func (g *Gateway) reload(ctx context.Context) error {
+	g.reloadMu.Lock()
+	defer g.reloadMu.Unlock()
+
+	var errs []error
+	if err := g.reloadConfig(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := g.cron.Reload(); err != nil {
+		log.Printf("[gateway] cron reload failed, keeping previous jobs: %v", err)
+		errs = append(errs, fmt.Errorf("reload cron jobs: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (g *Gateway) reloadConfig(ctx context.Context) error {
+	old := g.config()
+	cfg, err := config.LoadConfig()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		log.Printf("[gateway] config reload failed, keeping previous config: %v", err)
+		return fmt.Errorf("reload config: %w", err)
+	}
+
+	var applied, restart []string
+	// The workspace holds sessions, memory and the stores opened at
+	// startup, so it cannot move under a running gateway.
+	if cfg.Agent.Workspace != old.Agent.Workspace {
+		restart = append(restart, "agent.workspace")
+		cfg.Agent.Workspace = old.Agent.Workspace
+	}
+	// The web UI serves the health endpoints, mounted once at startup.
+	if !reflect.DeepEqual(cfg.Channels.WebUI, old.Channels.WebUI) {
+		restart = append(restart, "channels.webui")
+		cfg.Channels.WebUI = old.Channels.WebUI
+	}
+	rebuild, channels := false, false
+	nv, ov := reflect.ValueOf(cfg).Elem(), reflect.ValueOf(old).Elem()
+	for i := range nv.NumField() {
+		if reflect.DeepEqual(nv.Field(i).Interface(), ov.Field(i).Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(nv.Type().Field(i).Tag.Get("json"), ",")
+		switch {
+		case name == "channels" && g.coord == nil:
+			// Skill scopes live in the channel config too.
+			channels, rebuild = true, true
+		case slices.Contains(runtimeSections, name):
+			rebuild = true
+		case slices.Contains(liveSections, name):
+		default:
+			// Including channels in cluster mode, where instances share
+			// out the channels at startup.
+			restart = append(restart, name)
+			nv.Field(i).Set(ov.Field(i))
+			continue
+		}
+		applied = append(applied, name)
+	}
+	if len(restart) > 0 {
+		log.Printf("[gateway] config changes to %s take a restart", strings.Join(restart, ", "))
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	g.live.Store(cfg)
+	if rebuild {
+		if err := g.swapRuntimes(g.loadSkills()); err != nil {
+			g.live.Store(old)
+			log.Printf("[gateway] config reload failed, keeping previous config: %v", err)
+			return fmt.Errorf("reload config: %w", err)
+		}
+	}
+	g.startEventSinks(ctx)
+	if channels {
+		changed, err := g.channels.Apply(ctx, cfg.Channels)
+		if len(changed) > 0 {
+			log.Printf("[gateway] channels restarted: %v", changed)
+		}
+		if err != nil {
+			log.Printf("[gateway] channel reload: %v", err)
+			return fmt.Errorf("reload channels: %w", err)
+		}
+	}
+	log.Printf("[gateway] config reloaded: %s", strings.Join(applied, ", "))
+	return nil
+}
+
+// watchReload reloads whenever the config file or the cron job store is
+// written, so edits and `myclaw cron` changes apply without a restart.
+func (g *Gateway) watchReload(ctx context.Context) {
+	files := append(config.ConfigPaths(), g.cron.StorePath())
+	if err := watchFiles(ctx, files, reloadDebounce, func() { _ = g.reload(ctx) }); err != nil {
+		log.Printf("[gateway] config hot-reload disabled: %v", err)
+	}
+}
+
+// watchFiles calls onChange whenever one of files is written, created,
+// removed or renamed. It watches their directories, since editors often
+// save by replacing the file, and skips directories that don't exist.
+// Events within debounce of each other trigger a single call. It blocks
+// until ctx is done.
+func watchFiles(ctx context.Context, files []string, debounce time.Duration, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	watched := make(map[string]bool)
+	for _, f := range files {
+		dir := filepath.Dir(f)
+		if watched[dir] {
+			continue
+		}
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("watch %s: %w", dir, err)
+		}
+		watched[dir] = true
+	}
+	if len(watched) == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("[gateway] watcher error: %v", err)
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Op&^fsnotify.Chmod == 0 || !slices.Contains(files, filepath.Clean(ev.Name)) {
+				continue
+			}
+			timer.Reset(debounce)
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// sessionTable maps each chat (or chat member) to the session its messages
+// run in, and starts a new session once the old one has been idle for ttl.
+// The table is saved so that sessions outlive a restart. Expired entries
+// are kept: forgetting one would send its key back to its first session.
+type sessionTable struct {
+	path  string
+	scope string
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*sessionEntry
+}
+
+type sessionEntry struct {
+	ID       string    `json:"id"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// sessionsPath is where a workspace keeps its session table.
+func sessionsPath(workspace string) string {
+	return filepath.Join(workspace, ".claude", "sessions.json")
+}
+
+func newSessionTable(path string, cfg config.SessionsConfig) (*sessionTable, error) {
+	scope := cfg.Scope
+	switch scope {
+	case "":
+		scope = config.SessionScopeUser
+	case config.SessionScopeUser, config.SessionScopeChat:
+	default:
+		return nil, fmt.Errorf("sessions.scope %q: want %q or %q", cfg.Scope, config.SessionScopeUser, config.SessionScopeChat)
+	}
+	t := &sessionTable{
+		path:    path,
+		scope:   scope,
+		ttl:     time.Duration(cfg.TTLMinutes) * time.Minute,
+		now:     time.Now,
+		entries: make(map[string]*sessionEntry),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &t.entries); err != nil {
+			log.Printf("[gateway] ignoring unreadable %s: %v", path, err)
+			t.entries = make(map[string]*sessionEntry)
+		}
+	}
+	return t, nil
+}
+
+// key identifies whose conversation msg belongs to. Senders in a group chat
+// are told apart unless the scope is "chat".
+func (t *sessionTable) key(msg bus.InboundMessage) string {
+	key := msg.SessionKey()
+	if t.scope == config.SessionScopeUser && msg.SenderID != "" && msg.SenderID != msg.ChatID {
+		key += ":" + msg.SenderID
+	}
+	return key
+}
+
+// resolve returns the session ID for msg. The first session of a key uses
+// the key itself; one started after the TTL lapsed gets its start time
+// appended.
+func (t *sessionTable) resolve(msg bus.InboundMessage) string {
+	key := t.key(msg)
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[key]
+	switch {
+	case !ok:
+		e = &sessionEntry{ID: key}
+		t.entries[key] = e
+	case t.ttl > 0 && now.Sub(e.LastSeen) > t.ttl:
+		e.ID = key + "@" + now.Format("20060102-150405")
+		log.Printf("[gateway] session %s idle for over %s, starting %s", key, t.ttl, e.ID)
+	}
+	e.LastSeen = now
+	if err := t.save(); err != nil {
+		log.Printf("[gateway] save sessions: %v", err)
+	}
+	return e.ID
+}
+
+// active counts the sessions that have not expired.
+func (t *sessionTable) active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	n := 0
+	for _, e := range t.entries {
+		if t.ttl <= 0 || now.Sub(e.LastSeen) <= t.ttl {
+			n++
+		}
+	}
+	return n
+}
+
+func (t *sessionTable) save() error {
+	data, err := json.MarshalIndent(t.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
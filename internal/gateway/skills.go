@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/stellarlinkco/myclaw/internal/skills"
+	"github.com/stellarlinkco/myclaw/internal/tracing"
+)
+
+func (g *Gateway) skillsDir() string {
+	cfg := g.config()
+	if cfg.Skills.Dir != "" {
+		return cfg.Skills.Dir
+	}
+	return filepath.Join(cfg.Agent.Workspace, "skills")
+}
+
+// loadSkills returns the enabled skills, or nil when skills are off.
+func (g *Gateway) loadSkills() []api.SkillRegistration {
+	cfg := g.config()
+	if !cfg.Skills.Enabled {
+		return nil
+	}
+	skillRegs, err := skills.LoadSkillDirs(g.skillsDir(), cfg.ProjectSkillsDir())
+	if err != nil {
+		log.Printf("[gateway] skills load warning: %v", err)
+	}
+	return skills.FilterDisabled(skillRegs, cfg.Skills.Disabled)
+}
+
+// run sends req to the runtime of its session when sessions have their own,
+// and otherwise to the current runtime, or to the runtime of its channel
+// when that channel's skills are scoped.
+func (g *Gateway) run(ctx context.Context, req api.Request) (*api.Response, error) {
+	channel := ""
+	if len(req.Channels) > 0 {
+		channel = req.Channels[0]
+	}
+	rt, done, err := g.acquireRuntime(channel, req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	ctx, span := tracing.StartRun(ctx, channel, req)
+	resp, err := rt.Run(ctx, req)
+	tracing.EndRun(span, resp, err)
+	if err == nil && resp != nil && resp.Result != nil {
+		g.recordUsage(req, resp.Result.Usage)
+	}
+	return resp, err
+}
+
+// acquireRuntime picks the runtime for a run and returns it with a func to
+// call when the run ends. Chat runs take their session's runtime from the
+// pool; the rest, and chat runs the pool has no room for, share one.
+func (g *Gateway) acquireRuntime(channel, sessionID string) (Runtime, func(), error) {
+	if g.pool != nil && channel != "" && sessionID != "" {
+		rt, release, ok, err := g.pool.acquire(channel, sessionID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("build runtime for %s: %w", sessionID, err)
+		}
+		if ok {
+			return rt, release, nil
+		}
+	}
+	g.runtimeMu.RLock()
+	rt, runs := g.runtime, g.runtimeRuns
+	if scoped, ok := g.channelRuntimes[channel]; ok {
+		rt = scoped
+	}
+	if runs != nil {
+		runs.Add(1)
+	}
+	g.runtimeMu.RUnlock()
+	if runs == nil {
+		return rt, func() {}, nil
+	}
+	return rt, runs.Done, nil
+}
+
+// buildRuntimes builds the runtime with all of skillRegs, plus one for
+// each channel whose skill scope leaves it a different set. Channels left
+// with the same skills share a runtime.
+func (g *Gateway) buildRuntimes(skillRegs []api.SkillRegistration) (Runtime, map[string]Runtime, error) {
+	rt, err := g.buildRuntime(skillRegs)
+	if err != nil {
+		return nil, nil, err
+	}
+	bySkills := map[string]Runtime{skillSetKey(skillRegs): rt}
+	var channelRuntimes map[string]Runtime
+	for channel, scope := range g.config().Channels.SkillScopes() {
+		scoped := skills.Scope(skillRegs, scope.Allow, scope.Deny)
+		key := skillSetKey(scoped)
+		if bySkills[key] == nil {
+			built, err := g.buildRuntime(scoped)
+			if err != nil {
+				for _, r := range bySkills {
+					r.Close()
+				}
+				return nil, nil, fmt.Errorf("build runtime for %s: %w", channel, err)
+			}
+			bySkills[key] = built
+		}
+		if bySkills[key] != rt {
+			if channelRuntimes == nil {
+				channelRuntimes = make(map[string]Runtime)
+			}
+			channelRuntimes[channel] = bySkills[key]
+		}
+	}
+	return rt, channelRuntimes, nil
+}
+
+func skillSetKey(skillRegs []api.SkillRegistration) string {
+	names := make([]string, 0, len(skillRegs))
+	for _, reg := range skillRegs {
+		names = append(names, reg.Definition.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "\x00")
+}
+
+// closeRuntimes closes rt and the distinct runtimes in channelRuntimes.
+func closeRuntimes(rt Runtime, channelRuntimes map[string]Runtime) {
+	closed := []Runtime{rt}
+	if rt != nil {
+		rt.Close()
+	}
+	for _, r := range channelRuntimes {
+		if slices.Contains(closed, r) {
+			continue
+		}
+		closed = append(closed, r)
+		r.Close()
+	}
+}
+
+// watchSkills reloads skills whenever SKILL.md files change.
+func (g *Gateway) watchSkills(ctx context.Context) {
+	dir := g.skillsDir()
+	log.Printf("[gateway] watching %s for skill changes", dir)
+	if err := skills.Watch(ctx, dir, skills.DefaultWatchDebounce, func() { g.reloadSkills() }); err != nil {
+		log.Printf("[gateway] skills hot-reload disabled: %v", err)
+	}
+}
+
+// reloadSkills builds a runtime with the current skills and swaps it in.
+// If the new runtime cannot be built the old one stays and the error is
+// returned.
+func (g *Gateway) reloadSkills() error {
+	skillRegs := g.loadSkills()
+	if err := g.swapRuntimes(skillRegs); err != nil {
+		log.Printf("[gateway] skills reload failed, keeping previous skills: %v", err)
+		return err
+	}
+	names := make([]string, 0, len(skillRegs))
+	for _, reg := range skillRegs {
+		names = append(names, reg.Definition.Name)
+	}
+	log.Printf("[gateway] skills reloaded: %v", names)
+	return nil
+}
+
+// swapRuntimes builds runtimes with skillRegs and the current config and
+// swaps them in. Runs already in progress finish on the old runtimes,
+// which are closed afterwards.
+func (g *Gateway) swapRuntimes(skillRegs []api.SkillRegistration) error {
+	rt, channelRuntimes, err := g.buildRuntimes(skillRegs)
+	if err != nil {
+		return err
+	}
+
+	g.runtimeMu.Lock()
+	old, oldChannels, oldRuns := g.runtime, g.channelRuntimes, g.runtimeRuns
+	g.runtime, g.channelRuntimes, g.runtimeRuns = rt, channelRuntimes, &sync.WaitGroup{}
+	g.skillRegs = skillRegs
+	g.runtimeMu.Unlock()
+	if g.pool != nil {
+		g.pool.reset()
+	}
+
+	go func() {
+		if oldRuns != nil {
+			oldRuns.Wait()
+		}
+		closeRuntimes(old, oldChannels)
+	}()
+	return nil
+}
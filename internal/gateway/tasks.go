@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/audit"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/prompt"
+	"github.com/stellarlinkco/myclaw/internal/reminders"
+	"github.com/stellarlinkco/myclaw/internal/tasks"
+)
+
+// taskCheckInterval is how often the task queue, and whether the task
+// running was canceled, is looked at.
+const taskCheckInterval = 5 * time.Second
+
+// taskLoop runs background tasks one at a time, oldest first, until ctx is
+// done. Tasks a stopped gateway left running start over. In cluster mode
+// only the leader runs them, and none start while draining; a task still
+// running at shutdown is left to start over with the next gateway.
+func (g *Gateway) taskLoop(ctx context.Context) {
+	if n, err := g.tasks.Requeue(); err != nil {
+		log.Printf("[gateway] load background tasks failed: %v", err)
+	} else if n > 0 {
+		log.Printf("[gateway] restarting %d interrupted background tasks", n)
+	}
+	ticker := time.NewTicker(taskCheckInterval)
+	defer ticker.Stop()
+
+	var running string // ID of the task running, if any
+	var stop context.CancelFunc
+	done := make(chan struct{})
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			running, stop = "", nil
+		case <-ticker.C:
+			if running != "" {
+				if t, ok, err := g.tasks.Get(running); err == nil && (!ok || t.Status == tasks.Canceled) {
+					log.Printf("[gateway] background task %s canceled", running)
+					stop()
+				}
+				continue
+			}
+			if !g.shouldRunJobs() {
+				continue
+			}
+			t, ok, err := g.tasks.Next(time.Now())
+			if err != nil {
+				log.Printf("[gateway] load background tasks failed: %v", err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			runCtx, cancel := context.WithCancel(ctx)
+			running, stop = t.ID, cancel
+			go func() {
+				defer cancel()
+				g.runTask(runCtx, t)
+				select {
+				case done <- struct{}{}:
+				case <-ctx.Done():
+				}
+			}()
+		}
+	}
+}
+
+// runTask runs t in a session of its own and sends the chat that started
+// it the result.
+func (g *Gateway) runTask(ctx context.Context, t tasks.Task) {
+	log.Printf("[gateway] running background task %s for %s/%s", t.ID, t.Channel, t.ChatID)
+	sessionID := "task-" + t.ID
+	ctx = reminders.WithOrigin(ctx, t.Channel, t.ChatID)
+	ctx = tasks.WithOrigin(ctx, t.Channel, t.ChatID)
+	ctx = audit.WithSource(ctx, audit.Source{Channel: t.Channel, ChatID: t.ChatID, Session: sessionID})
+	ctx = prompt.WithVars(ctx, prompt.Vars{Channel: t.Channel})
+	resp, err := g.respond(ctx, t.Channel, t.Prompt, sessionID, nil)
+	if err != nil && ctx.Err() != nil {
+		// Canceled, or the gateway is stopping: Finish would find the
+		// task canceled, or it is to start over.
+		return
+	}
+	var output string
+	if resp != nil && resp.Result != nil {
+		output = resp.Result.Output
+	}
+	finished, ok, ferr := g.tasks.Finish(t.ID, output, err, time.Now())
+	if ferr != nil {
+		log.Printf("[gateway] save background task %s failed: %v", t.ID, ferr)
+		return
+	}
+	if !ok {
+		return
+	}
+	log.Printf("[gateway] background task %s %s", t.ID, finished.Status)
+	g.emit(Event{Type: EventTaskFinished, Channel: t.Channel, ChatID: t.ChatID, Session: sessionID, Task: t.ID, Status: string(finished.Status), Text: finished.Result, Error: finished.Error})
+	if finished.Status == tasks.Failed {
+		g.emit(Event{Type: EventError, Channel: t.Channel, ChatID: t.ChatID, Session: sessionID, Task: t.ID, Error: finished.Error})
+	}
+	if t.Channel == "" {
+		return
+	}
+	g.bus.Outbound <- bus.OutboundMessage{Channel: t.Channel, ChatID: t.ChatID, Content: tasks.Report(finished)}
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/usage"
+)
+
+// usageCounter totals the agent turns served since the gateway started.
+type usageCounter struct {
+	turns, inputTokens, outputTokens, cacheReadTokens atomic.Int64
+}
+
+func (u *usageCounter) add(tokens model.Usage) {
+	u.turns.Add(1)
+	u.inputTokens.Add(int64(tokens.InputTokens))
+	u.outputTokens.Add(int64(tokens.OutputTokens))
+	u.cacheReadTokens.Add(int64(tokens.CacheReadTokens))
+}
+
+// recordUsage writes a run to the usage ledger, and alerts the configured
+// chat when the run takes the day's estimated cost past the daily budget.
+func (g *Gateway) recordUsage(req api.Request, u model.Usage) {
+	g.usage.add(u)
+	if g.ledger == nil {
+		return
+	}
+	cfg := g.config()
+	entry := usage.Entry{
+		Time:             time.Now(),
+		Session:          req.SessionID,
+		Model:            cfg.Models.Resolve(cfg.Agent.Model),
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheReadTokens:  u.CacheReadTokens,
+		CacheWriteTokens: u.CacheCreationTokens,
+	}
+	if len(req.Channels) > 0 {
+		entry.Channel = req.Channels[0]
+	}
+	before, after, err := g.ledger.Record(entry)
+	if err != nil {
+		log.Printf("[gateway] record usage failed: %v", err)
+		return
+	}
+
+	tt := cfg.TokenTracking
+	if tt.DailyBudgetUSD <= 0 || before >= tt.DailyBudgetUSD || after < tt.DailyBudgetUSD {
+		return
+	}
+	log.Printf("[gateway] daily budget of $%.2f reached ($%.2f)", tt.DailyBudgetUSD, after)
+	g.emit(Event{Type: EventBudgetExceeded, Channel: entry.Channel, Session: entry.Session, SpentUSD: after, BudgetUSD: tt.DailyBudgetUSD})
+	if tt.AlertChannel == "" {
+		return
+	}
+	alert := bus.OutboundMessage{
+		Channel: tt.AlertChannel,
+		ChatID:  tt.AlertChatID,
+		Content: fmt.Sprintf("Today's estimated model spend is $%.2f, past the daily budget of $%.2f. Run 'myclaw usage --since 1d --by session' to see where it went.", after, tt.DailyBudgetUSD),
+	}
+	select {
+	case g.bus.Outbound <- alert:
+	default:
+		log.Printf("[gateway] outbound queue full, budget alert not sent")
+	}
+}
@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/voice"
+)
+
+// voiceReplyTimeout bounds the wait for a reply to be spoken; the text is
+// sent alone when it runs out.
+const voiceReplyTimeout = time.Minute
+
+// addVoice speaks the reply in msg into a voice note when its channel sends
+// voice replies. A reply too long to listen to, or one that fails to be
+// spoken, goes as text only.
+func (g *Gateway) addVoice(ctx context.Context, msg *bus.OutboundMessage) {
+	cfg := g.config()
+	vr, ok := cfg.Channels.VoiceReplies()[msg.Channel]
+	if !ok {
+		return
+	}
+	limit := vr.MaxChars
+	if limit <= 0 {
+		limit = config.DefaultVoiceReplyChars
+	}
+	text := voice.Speakable(msg.Content)
+	if text == "" || utf8.RuneCountInString(text) > limit {
+		return
+	}
+	synth, err := voice.NewSynthesizer(cfg.Media.TTS)
+	if err != nil || synth == nil {
+		log.Printf("[gateway] voice replies on %s need media.tts: %v", msg.Channel, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, voiceReplyTimeout)
+	defer cancel()
+	audio, err := synth.Synthesize(ctx, text, voice.SpeechOptions{Format: voice.FormatOpus, Voice: vr.Voice, Speed: vr.Speed})
+	if err != nil {
+		log.Printf("[gateway] voice reply for %s/%s failed, sending text: %v", msg.Channel, msg.ChatID, err)
+		return
+	}
+	msg.Voice = audio
+	msg.VoiceOnly = vr.Mode == "voice"
+}
@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	// webhookAttempts is how many times an event is posted before it is
+	// given up on.
+	webhookAttempts = 3
+	// webhookRetryDelay is the wait before the second attempt, doubled
+	// before each one after.
+	webhookRetryDelay = 2 * time.Second
+	// webhookConcurrency caps the posts in flight at once.
+	webhookConcurrency = 8
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// startEventSinks starts the subscribers that post events to webhooks and
+// push them to notify sinks, each once the config has any to serve. It runs
+// at startup and after each reload, so a reload can add the first.
+func (g *Gateway) startEventSinks(ctx context.Context) {
+	if g.events == nil {
+		return
+	}
+	if len(g.config().Webhooks) > 0 {
+		g.webhooksStarted.Do(func() { g.startWebhooks(ctx) })
+	}
+	if notifyEvents(g.config()) {
+		g.notifyStarted.Do(func() { g.startNotify(ctx) })
+	}
+}
+
+// startWebhooks posts the gateway's events to the configured webhooks
+// until ctx is done. The list is read for each event, so once this runs a
+// reload that edits it applies at once.
+func (g *Gateway) startWebhooks(ctx context.Context) {
+	for i, hook := range g.config().Webhooks {
+		for _, typ := range hook.Events {
+			if !slices.Contains(eventTypes, typ) {
+				log.Printf("[gateway] webhooks[%d]: unknown event type %q", i, typ)
+			}
+		}
+	}
+	events, cancel := g.events.subscribe()
+	go func() {
+		defer cancel()
+		sem := make(chan struct{}, webhookConcurrency)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-events:
+				for _, hook := range g.config().Webhooks {
+					if len(hook.Events) > 0 && !slices.Contains(hook.Events, ev.Type) {
+						continue
+					}
+					select {
+					case sem <- struct{}{}:
+					case <-ctx.Done():
+						return
+					}
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						defer func() { <-sem }()
+						if err := postWebhook(ctx, hook, ev); err != nil {
+							log.Printf("[gateway] webhook %s: %v", hook.URL, err)
+						}
+					}()
+				}
+			}
+		}
+	}()
+}
+
+// postWebhook posts ev to hook, trying again after network errors, 429s
+// and 5xx answers.
+func postWebhook(ctx context.Context, hook config.WebhookConfig, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := sendWebhook(ctx, hook, ev.Type, body)
+		if err == nil || !retry || attempt == webhookAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func sendWebhook(ctx context.Context, hook config.WebhookConfig, typ string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "myclaw")
+	req.Header.Set("X-Myclaw-Event", typ)
+	if hook.Secret != "" {
+		req.Header.Set("X-Myclaw-Signature", webhookSignature(hook.Secret, body))
+	}
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, fmt.Errorf("%s event: %s", typ, resp.Status)
+	}
+	return false, nil
+}
+
+// webhookSignature is the X-Myclaw-Signature of body: its HMAC-SHA256
+// keyed with secret, as "sha256=" and hex.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,415 @@
+// Package github gives the agent tools for the user's GitHub account:
+// notifications, issue and pull request search, pull request diffs,
+// comments and new issues.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	defaultAPIURL    = "https://api.github.com"
+	maxResponseBytes = 10 << 20
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// ErrNoToken is returned when no token is configured and the GitHub CLI is
+// not signed in either.
+var ErrNoToken = errors.New("no GitHub token; set github.token or GITHUB_TOKEN, or sign in with `gh auth login`")
+
+// Client calls the GitHub REST API as one user.
+type Client struct {
+	token   string
+	baseURL string
+	http    *http.Client
+}
+
+// New returns a client for cfg, taking the token from the GitHub CLI when
+// cfg has none.
+func New(ctx context.Context, cfg config.GitHubConfig) (*Client, error) {
+	baseURL := strings.TrimRight(cfg.APIURL, "/")
+	if baseURL == "" {
+		baseURL = defaultAPIURL
+	}
+	token := cfg.Token
+	if token == "" {
+		token = ghToken(ctx, baseURL)
+	}
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	return &Client{token: token, baseURL: baseURL, http: httpClient}, nil
+}
+
+// ghToken asks the GitHub CLI for its token for the host of apiURL, or
+// returns "" when gh is not installed or not signed in.
+func ghToken(ctx context.Context, apiURL string) string {
+	args := []string{"auth", "token"}
+	if u, err := url.Parse(apiURL); err == nil && u.Host != "api.github.com" {
+		args = append(args, "--hostname", u.Host)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "gh", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Ref names an issue or pull request.
+type Ref struct {
+	Owner, Repo string
+	Number      int
+}
+
+func (r Ref) String() string { return fmt.Sprintf("%s/%s#%d", r.Owner, r.Repo, r.Number) }
+
+var (
+	shortRef = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)#(\d+)$`)
+	urlRef   = regexp.MustCompile(`^https?://[^/]+/([\w.-]+)/([\w.-]+)/(?:pull|issues)/(\d+)`)
+	repoName = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)$`)
+)
+
+// ParseRef reads "owner/repo#123" or the web URL of an issue or pull
+// request.
+func ParseRef(s string) (Ref, error) {
+	s = strings.TrimSpace(s)
+	m := shortRef.FindStringSubmatch(s)
+	if m == nil {
+		m = urlRef.FindStringSubmatch(s)
+	}
+	if m == nil {
+		return Ref{}, fmt.Errorf("%q: want owner/repo#123 or an issue or pull request URL", s)
+	}
+	n, _ := strconv.Atoi(m[3])
+	return Ref{Owner: m[1], Repo: m[2], Number: n}, nil
+}
+
+// ParseRepo reads "owner/repo" or a repository URL.
+func ParseRepo(s string) (owner, repo string, err error) {
+	s = strings.TrimSpace(s)
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		s = strings.Trim(u.Path, "/")
+	}
+	m := repoName.FindStringSubmatch(strings.TrimSuffix(s, ".git"))
+	if m == nil {
+		return "", "", fmt.Errorf("%q: want owner/repo", s)
+	}
+	return m[1], m[2], nil
+}
+
+// Notification is an unread thread in the user's GitHub inbox.
+type Notification struct {
+	ID      string    `json:"id"`
+	Repo    string    `json:"repo"`
+	Type    string    `json:"type"` // PullRequest, Issue, Release, ...
+	Title   string    `json:"title"`
+	Reason  string    `json:"reason"` // review_requested, mention, ...
+	URL     string    `json:"url,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// Notifications lists unread notifications, newest first; with
+// participating, only threads the user takes part in.
+func (c *Client) Notifications(ctx context.Context, participating bool, limit int) ([]Notification, error) {
+	v := url.Values{"per_page": {strconv.Itoa(limit)}}
+	if participating {
+		v.Set("participating", "true")
+	}
+	var raw []struct {
+		ID         string    `json:"id"`
+		Reason     string    `json:"reason"`
+		UpdatedAt  time.Time `json:"updated_at"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Subject struct {
+			Title string `json:"title"`
+			Type  string `json:"type"`
+			URL   string `json:"url"`
+		} `json:"subject"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/notifications?"+v.Encode(), nil, &raw); err != nil {
+		return nil, err
+	}
+	out := make([]Notification, 0, len(raw))
+	for _, n := range raw {
+		out = append(out, Notification{
+			ID:      n.ID,
+			Repo:    n.Repository.FullName,
+			Type:    n.Subject.Type,
+			Title:   n.Subject.Title,
+			Reason:  n.Reason,
+			URL:     webURL(n.Subject.URL),
+			Updated: n.UpdatedAt,
+		})
+	}
+	return out, nil
+}
+
+// webURL turns the API URL of an issue or pull request into its page, or
+// returns "" for other subjects.
+func webURL(apiURL string) string {
+	u, err := url.Parse(apiURL)
+	if err != nil || !strings.Contains(u.Path, "/issues/") && !strings.Contains(u.Path, "/pulls/") {
+		return ""
+	}
+	path := strings.TrimPrefix(strings.TrimPrefix(u.Path, "/api/v3"), "/repos")
+	path = strings.Replace(path, "/pulls/", "/pull/", 1)
+	return "https://" + strings.TrimPrefix(u.Host, "api.") + path
+}
+
+// Issue is an issue or pull request as search and issue listings give it.
+type Issue struct {
+	Repo     string    `json:"repo"`
+	Number   int       `json:"number"`
+	Title    string    `json:"title"`
+	State    string    `json:"state"`
+	Author   string    `json:"author"`
+	URL      string    `json:"url"`
+	Pull     bool      `json:"pull"`
+	Comments int       `json:"comments"`
+	Updated  time.Time `json:"updated"`
+}
+
+type apiIssue struct {
+	Number        int       `json:"number"`
+	Title         string    `json:"title"`
+	State         string    `json:"state"`
+	HTMLURL       string    `json:"html_url"`
+	RepositoryURL string    `json:"repository_url"`
+	Comments      int       `json:"comments"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	User          struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+func (a apiIssue) issue() Issue {
+	repo := a.RepositoryURL
+	if i := strings.Index(repo, "/repos/"); i >= 0 {
+		repo = repo[i+len("/repos/"):]
+	}
+	return Issue{
+		Repo:     repo,
+		Number:   a.Number,
+		Title:    a.Title,
+		State:    a.State,
+		Author:   a.User.Login,
+		URL:      a.HTMLURL,
+		Pull:     a.PullRequest != nil,
+		Comments: a.Comments,
+		Updated:  a.UpdatedAt,
+	}
+}
+
+// Search finds issues and pull requests with GitHub's search syntax, such
+// as "is:open is:pr review-requested:@me", most recently updated first.
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]Issue, error) {
+	v := url.Values{"q": {query}, "sort": {"updated"}, "order": {"desc"}, "per_page": {strconv.Itoa(limit)}}
+	var res struct {
+		Items []apiIssue `json:"items"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/search/issues?"+v.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+	out := make([]Issue, 0, len(res.Items))
+	for _, it := range res.Items {
+		out = append(out, it.issue())
+	}
+	return out, nil
+}
+
+// PullRequest is a pull request with its changed files.
+type PullRequest struct {
+	Ref       string     `json:"ref"`
+	Title     string     `json:"title"`
+	Author    string     `json:"author"`
+	State     string     `json:"state"`
+	Draft     bool       `json:"draft,omitempty"`
+	Merged    bool       `json:"merged,omitempty"`
+	Base      string     `json:"base"`
+	Head      string     `json:"head"`
+	Body      string     `json:"body,omitempty"`
+	URL       string     `json:"url"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+	Files     []PullFile `json:"files"`
+}
+
+// PullFile is one file a pull request changes. Patch is its diff, empty for
+// binary or very large files.
+type PullFile struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Patch     string `json:"patch,omitempty"`
+}
+
+// maxPullFiles caps the files fetched for one pull request.
+const maxPullFiles = 100
+
+// PullRequest fetches the pull request ref and its changed files.
+func (c *Client) PullRequest(ctx context.Context, ref Ref) (*PullRequest, error) {
+	var raw struct {
+		Title     string `json:"title"`
+		State     string `json:"state"`
+		Draft     bool   `json:"draft"`
+		Merged    bool   `json:"merged"`
+		Body      string `json:"body"`
+		HTMLURL   string `json:"html_url"`
+		Additions int    `json:"additions"`
+		Deletions int    `json:"deletions"`
+		User      struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		Head struct {
+			Label string `json:"label"`
+		} `json:"head"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d", url.PathEscape(ref.Owner), url.PathEscape(ref.Repo), ref.Number)
+	if err := c.call(ctx, http.MethodGet, path, nil, &raw); err != nil {
+		return nil, err
+	}
+	pr := &PullRequest{
+		Ref:       ref.String(),
+		Title:     raw.Title,
+		Author:    raw.User.Login,
+		State:     raw.State,
+		Draft:     raw.Draft,
+		Merged:    raw.Merged,
+		Base:      raw.Base.Ref,
+		Head:      raw.Head.Label,
+		Body:      raw.Body,
+		URL:       raw.HTMLURL,
+		Additions: raw.Additions,
+		Deletions: raw.Deletions,
+	}
+	var files []struct {
+		Filename  string `json:"filename"`
+		Status    string `json:"status"`
+		Additions int    `json:"additions"`
+		Deletions int    `json:"deletions"`
+		Patch     string `json:"patch"`
+	}
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("%s/files?per_page=%d", path, maxPullFiles), nil, &files); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		pr.Files = append(pr.Files, PullFile{Name: f.Filename, Status: f.Status, Additions: f.Additions, Deletions: f.Deletions, Patch: f.Patch})
+	}
+	return pr, nil
+}
+
+// Comment adds a comment to the issue or pull request ref and returns its
+// URL.
+func (c *Client) Comment(ctx context.Context, ref Ref, body string) (string, error) {
+	var out struct {
+		HTMLURL string `json:"html_url"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", url.PathEscape(ref.Owner), url.PathEscape(ref.Repo), ref.Number)
+	if err := c.call(ctx, http.MethodPost, path, map[string]string{"body": body}, &out); err != nil {
+		return "", err
+	}
+	return out.HTMLURL, nil
+}
+
+// NewIssue is an issue to open.
+type NewIssue struct {
+	Title  string   `json:"title"`
+	Body   string   `json:"body,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// CreateIssue opens an issue in owner/repo.
+func (c *Client) CreateIssue(ctx context.Context, owner, repo string, in NewIssue) (Issue, error) {
+	var out apiIssue
+	path := fmt.Sprintf("/repos/%s/%s/issues", url.PathEscape(owner), url.PathEscape(repo))
+	if err := c.call(ctx, http.MethodPost, path, in, &out); err != nil {
+		return Issue{}, err
+	}
+	issue := out.issue()
+	issue.Repo = owner + "/" + repo
+	return issue, nil
+}
+
+func (c *Client) call(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("github: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("github: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			msg = apiErr.Message
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized:
+			return fmt.Errorf("github: the token was rejected (%s); check github.token or run `gh auth login`", msg)
+		case resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+			return fmt.Errorf("github: rate limit reached; it resets at %s", rateLimitReset(resp.Header))
+		case resp.StatusCode == http.StatusNotFound:
+			return fmt.Errorf("github: %s not found, or the token cannot see it", path)
+		}
+		return fmt.Errorf("github: %s: %s", resp.Status, msg)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("github: decode response: %w", err)
+	}
+	return nil
+}
+
+func rateLimitReset(h http.Header) string {
+	secs, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return "the top of the hour"
+	}
+	return time.Unix(secs, 0).Local().Format("15:04")
+}
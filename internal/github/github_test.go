@@ -0,0 +1,185 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+)
+
+func TestParseRef(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"golang/go#123":                                 "golang/go#123",
+		"https://github.com/golang/go/pull/456":         "golang/go#456",
+		"https://github.com/golang/go/issues/7#issue-1": "golang/go#7",
+		"https://ghe.example.com/team/app/pull/9/files": "team/app#9",
+	} {
+		ref, err := ParseRef(in)
+		if err != nil || ref.String() != want {
+			t.Errorf("ParseRef(%q) = %v, %v; want %s", in, ref, err, want)
+		}
+	}
+	for _, in := range []string{"", "golang/go", "#12", "https://github.com/golang/go"} {
+		if _, err := ParseRef(in); err == nil {
+			t.Errorf("ParseRef(%q): expected error", in)
+		}
+	}
+
+	if owner, repo, err := ParseRepo("https://github.com/golang/go.git"); err != nil || owner != "golang" || repo != "go" {
+		t.Errorf("ParseRepo(url) = %s, %s, %v", owner, repo, err)
+	}
+	if _, _, err := ParseRepo("golang"); err == nil {
+		t.Error("ParseRepo accepted a bare owner")
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // no gh
+	if _, err := New(context.Background(), config.GitHubConfig{}); err != ErrNoToken {
+		t.Errorf("error = %v, want ErrNoToken", err)
+	}
+	c, err := New(context.Background(), config.GitHubConfig{Token: "t", APIURL: "https://ghe.example.com/api/v3/"})
+	if err != nil || c.baseURL != "https://ghe.example.com/api/v3" {
+		t.Errorf("client = %+v, %v", c, err)
+	}
+	if tools, err := Tools(config.GitHubConfig{Token: "t"}); tools != nil || err != nil {
+		t.Errorf("tools when disabled = %v, %v", tools, err)
+	}
+}
+
+// fakeGitHub serves the API calls the tools make and records what was
+// posted.
+func fakeGitHub(t *testing.T) (*Client, *[]string) {
+	t.Helper()
+	var posted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /notifications":
+			if r.URL.Query().Get("participating") != "true" {
+				t.Errorf("notifications query = %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`[{"id":"1","reason":"review_requested","updated_at":"2026-10-16T08:00:00Z",
+				"repository":{"full_name":"golang/go"},
+				"subject":{"title":"cmd/go: faster builds","type":"PullRequest","url":"https://api.github.com/repos/golang/go/pulls/456"}}]`))
+		case "GET /search/issues":
+			if q := r.URL.Query().Get("q"); q != "is:open is:pr review-requested:@me" {
+				t.Errorf("search q = %q", q)
+			}
+			w.Write([]byte(`{"total_count":1,"items":[{"number":456,"title":"cmd/go: faster builds","state":"open",
+				"html_url":"https://github.com/golang/go/pull/456","repository_url":"https://api.github.com/repos/golang/go",
+				"updated_at":"2026-10-15T10:00:00Z","user":{"login":"gopher"},"pull_request":{}}]}`))
+		case "GET /repos/golang/go/pulls/456":
+			w.Write([]byte(`{"title":"cmd/go: faster builds","state":"open","body":"Caches more.","html_url":"https://github.com/golang/go/pull/456",
+				"additions":10,"deletions":2,"user":{"login":"gopher"},"base":{"ref":"master"},"head":{"label":"gopher:cache"}}`))
+		case "GET /repos/golang/go/pulls/456/files":
+			w.Write([]byte(`[{"filename":"src/cmd/go/build.go","status":"modified","additions":10,"deletions":2,"patch":"@@ -1,2 +1,10 @@\n+cache()"},
+				{"filename":"logo.png","status":"added","additions":0,"deletions":0}]`))
+		case "POST /repos/golang/go/issues/456/comments":
+			var in map[string]string
+			json.NewDecoder(r.Body).Decode(&in)
+			posted = append(posted, "comment:"+in["body"])
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"html_url":"https://github.com/golang/go/pull/456#issuecomment-1"}`))
+		case "POST /repos/golang/go/issues":
+			var in NewIssue
+			json.NewDecoder(r.Body).Decode(&in)
+			posted = append(posted, "issue:"+in.Title+":"+strings.Join(in.Labels, ","))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number":789,"title":"` + in.Title + `","state":"open","html_url":"https://github.com/golang/go/issues/789"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return &Client{token: "secret", baseURL: srv.URL, http: srv.Client()}, &posted
+}
+
+func TestTools(t *testing.T) {
+	t.Parallel()
+
+	c, posted := fakeGitHub(t)
+	all := NewTools(c, "")
+	byName := make(map[string]int)
+	for i, tl := range all {
+		byName[tl.Name()] = i
+	}
+	run := func(ctx context.Context, name string, params map[string]any) (string, bool) {
+		t.Helper()
+		res, err := all[byName[name]].Execute(ctx, params)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res.Output, res.Success
+	}
+	ctx := context.Background()
+
+	out, ok := run(ctx, "github_notifications", map[string]any{"participating": true})
+	if !ok || !strings.Contains(out, "golang/go PullRequest: cmd/go: faster builds (review requested") ||
+		!strings.Contains(out, "https://github.com/golang/go/pull/456") {
+		t.Errorf("notifications = %q", out)
+	}
+
+	out, ok = run(ctx, "github_search", map[string]any{"query": "is:open is:pr review-requested:@me"})
+	if !ok || !strings.Contains(out, "- golang/go#456 PR: cmd/go: faster builds (open by gopher") {
+		t.Errorf("search = %q", out)
+	}
+
+	out, ok = run(ctx, "github_pr", map[string]any{"ref": "https://github.com/golang/go/pull/456"})
+	for _, want := range []string{"golang/go#456: cmd/go: faster builds", "master ← gopher:cache, +10 −2 in 2 files", "Caches more.", "- logo.png (added", "--- src/cmd/go/build.go\n@@ -1,2 +1,10 @@\n+cache()"} {
+		if !ok || !strings.Contains(out, want) {
+			t.Errorf("pr lacks %q:\n%s", want, out)
+		}
+	}
+	if out, ok := run(ctx, "github_pr", map[string]any{"ref": "golang/go#1"}); ok || !strings.Contains(out, "not found") {
+		t.Errorf("missing pr = %q", out)
+	}
+
+	// Writes ask first; without anyone to ask they are refused.
+	comment := map[string]any{"ref": "golang/go#456", "body": "LGTM"}
+	if out, ok := run(ctx, "github_comment", comment); ok || !strings.Contains(out, "approval") {
+		t.Errorf("unconfirmed comment = %q", out)
+	}
+	var asked []string
+	yes := permission.WithAsker(ctx, func(_ context.Context, req permission.Request) (bool, error) {
+		asked = append(asked, req.Target)
+		return true, nil
+	})
+	if out, ok := run(yes, "github_comment", comment); !ok || !strings.Contains(out, "issuecomment-1") {
+		t.Errorf("comment = %q", out)
+	}
+	out, ok = run(yes, "github_create_issue", map[string]any{"repo": "golang/go", "title": "Flaky test", "body": "It flakes.", "labels": []any{"Testing"}})
+	if !ok || !strings.Contains(out, "Opened golang/go#789") {
+		t.Errorf("create issue = %q", out)
+	}
+	if len(asked) != 2 || asked[0] != "comment on golang/go#456: LGTM" || !strings.Contains(asked[1], `"Flaky test" — It flakes.`) {
+		t.Errorf("asked = %q", asked)
+	}
+	if strings.Join(*posted, "|") != "comment:LGTM|issue:Flaky test:Testing" {
+		t.Errorf("posted = %q", *posted)
+	}
+
+	denied := NewTools(c, WriteDeny)
+	res, _ := denied[byName["github_comment"]].Execute(yes, comment)
+	if res.Success || !strings.Contains(res.Output, "github.write is deny") {
+		t.Errorf("denied comment = %+v", res)
+	}
+
+	bad := NewTools(&Client{token: "wrong", baseURL: c.baseURL, http: c.http}, WriteAllow)
+	res, _ = bad[byName["github_search"]].Execute(ctx, map[string]any{"query": "x"})
+	if res.Success || !strings.Contains(res.Output, "token was rejected (Bad credentials)") {
+		t.Errorf("bad token = %+v", res)
+	}
+}
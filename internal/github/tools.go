@@ -0,0 +1,347 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/fetch"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+)
+
+// Write policies for github_comment and github_create_issue; see
+// config.GitHubConfig.
+const (
+	WriteAsk   = "ask"
+	WriteAllow = "allow"
+	WriteDeny  = "deny"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 50
+	// diffMaxTokens caps the diff github_pr returns.
+	diffMaxTokens = 12000
+)
+
+// Tools returns the GitHub tools for cfg, or nil when they are not
+// enabled.
+func Tools(cfg config.GitHubConfig) ([]tool.Tool, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	c, err := New(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewTools(c, cfg.Write), nil
+}
+
+// NewTools returns the tools the agent works with GitHub through. write is
+// the policy for commenting and opening issues: ask (the default), allow or
+// deny.
+func NewTools(c *Client, write string) []tool.Tool {
+	if write == "" {
+		write = WriteAsk
+	}
+	return []tool.Tool{
+		&notificationsTool{c: c},
+		&searchTool{c: c},
+		&pullTool{c: c},
+		&commentTool{c: c, policy: write},
+		&createIssueTool{c: c, policy: write},
+	}
+}
+
+type notificationsTool struct{ c *Client }
+
+func (t *notificationsTool) Name() string { return "github_notifications" }
+
+func (t *notificationsTool) Description() string {
+	return "List the user's unread GitHub notifications, newest first: review requests, mentions, " +
+		"and activity on issues and pull requests they follow."
+}
+
+func (t *notificationsTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"participating": map[string]any{"type": "boolean", "description": "Only threads the user takes part in or is asked to review"},
+			"limit":         map[string]any{"type": "integer", "description": "How many at most; default 20, at most 50"},
+		},
+		Required: []string{},
+	}
+}
+
+func (t *notificationsTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	participating, _ := params["participating"].(bool)
+	list, err := t.c.Notifications(ctx, participating, limitParam(params))
+	if err != nil {
+		return failed(err), nil
+	}
+	if len(list) == 0 {
+		return &tool.ToolResult{Success: true, Output: "No unread notifications.\n", Data: list}, nil
+	}
+	var b strings.Builder
+	for _, n := range list {
+		fmt.Fprintf(&b, "- %s %s: %s (%s, %s)", n.Repo, n.Type, n.Title, strings.ReplaceAll(n.Reason, "_", " "), n.Updated.Local().Format("Mon 2 Jan 15:04"))
+		if n.URL != "" {
+			b.WriteString(" " + n.URL)
+		}
+		b.WriteString("\n")
+	}
+	return &tool.ToolResult{Success: true, Output: b.String(), Data: list}, nil
+}
+
+type searchTool struct{ c *Client }
+
+func (t *searchTool) Name() string { return "github_search" }
+
+func (t *searchTool) Description() string {
+	return "Search GitHub issues and pull requests with GitHub's search syntax, most recently updated first. " +
+		"For example \"is:open is:pr review-requested:@me\" for reviews waiting on the user, " +
+		"\"is:open is:issue assignee:@me\" for their issues, or \"repo:owner/name is:pr is:open\"."
+}
+
+func (t *searchTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"query": map[string]any{"type": "string", "description": "The search, in GitHub's syntax"},
+			"limit": map[string]any{"type": "integer", "description": "How many at most; default 20, at most 50"},
+		},
+		Required: []string{"query"},
+	}
+}
+
+func (t *searchTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	query := stringParam(params, "query")
+	if query == "" {
+		return failed(errors.New("query is required")), nil
+	}
+	issues, err := t.c.Search(ctx, query, limitParam(params))
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: FormatIssues(issues), Data: issues}, nil
+}
+
+// FormatIssues lists issues and pull requests one to a line.
+func FormatIssues(issues []Issue) string {
+	if len(issues) == 0 {
+		return "Nothing found.\n"
+	}
+	var b strings.Builder
+	for _, it := range issues {
+		kind := "issue"
+		if it.Pull {
+			kind = "PR"
+		}
+		fmt.Fprintf(&b, "- %s#%d %s: %s (%s by %s, updated %s) %s\n",
+			it.Repo, it.Number, kind, it.Title, it.State, it.Author, it.Updated.Local().Format("Mon 2 Jan"), it.URL)
+	}
+	return b.String()
+}
+
+type pullTool struct{ c *Client }
+
+func (t *pullTool) Name() string { return "github_pr" }
+
+func (t *pullTool) Description() string {
+	return "Read a GitHub pull request: its description, the files it changes and the diff, " +
+		"to summarize or review it."
+}
+
+func (t *pullTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"ref": map[string]any{"type": "string", "description": "owner/repo#123 or the pull request URL"},
+		},
+		Required: []string{"ref"},
+	}
+}
+
+func (t *pullTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	ref, err := ParseRef(stringParam(params, "ref"))
+	if err != nil {
+		return failed(err), nil
+	}
+	pr, err := t.c.PullRequest(ctx, ref)
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: FormatPull(pr, diffMaxTokens), Data: pr}, nil
+}
+
+// FormatPull shows pr with its diff, cut to about maxTokens.
+func FormatPull(pr *PullRequest, maxTokens int) string {
+	var b strings.Builder
+	state := pr.State
+	switch {
+	case pr.Merged:
+		state = "merged"
+	case pr.Draft:
+		state += ", draft"
+	}
+	fmt.Fprintf(&b, "%s: %s\nBy %s, %s, %s ← %s, +%d −%d in %d files\n%s\n",
+		pr.Ref, pr.Title, pr.Author, state, pr.Base, pr.Head, pr.Additions, pr.Deletions, len(pr.Files), pr.URL)
+	if body := strings.TrimSpace(pr.Body); body != "" {
+		b.WriteString("\n" + body + "\n")
+	}
+	b.WriteString("\nFiles:\n")
+	for _, f := range pr.Files {
+		fmt.Fprintf(&b, "- %s (%s, +%d −%d)\n", f.Name, f.Status, f.Additions, f.Deletions)
+	}
+	var diff strings.Builder
+	for _, f := range pr.Files {
+		if f.Patch == "" {
+			continue
+		}
+		fmt.Fprintf(&diff, "\n--- %s\n%s\n", f.Name, f.Patch)
+	}
+	if diff.Len() > 0 {
+		text, truncated := fetch.Truncate(diff.String(), maxTokens)
+		b.WriteString("\nDiff:\n" + text)
+		if truncated {
+			fmt.Fprintf(&b, "\n[Diff truncated to about %d tokens.]\n", maxTokens)
+		}
+	}
+	return b.String()
+}
+
+type commentTool struct {
+	c      *Client
+	policy string
+}
+
+func (t *commentTool) Name() string { return "github_comment" }
+
+func (t *commentTool) Description() string {
+	return "Comment on a GitHub issue or pull request as the user. Only when the user asked for it; " +
+		"the user may be asked to confirm first."
+}
+
+func (t *commentTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"ref":  map[string]any{"type": "string", "description": "owner/repo#123 or the issue or pull request URL"},
+			"body": map[string]any{"type": "string", "description": "The comment, in GitHub markdown"},
+		},
+		Required: []string{"ref", "body"},
+	}
+}
+
+func (t *commentTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	ref, err := ParseRef(stringParam(params, "ref"))
+	if err != nil {
+		return failed(err), nil
+	}
+	body := stringParam(params, "body")
+	if body == "" {
+		return failed(errors.New("body is required")), nil
+	}
+	if err := confirm(ctx, t.policy, t.Name(), fmt.Sprintf("comment on %s: %s", ref, preview(body)), params); err != nil {
+		return failed(err), nil
+	}
+	link, err := t.c.Comment(ctx, ref, body)
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: "Commented: " + link, Data: map[string]string{"url": link}}, nil
+}
+
+type createIssueTool struct {
+	c      *Client
+	policy string
+}
+
+func (t *createIssueTool) Name() string { return "github_create_issue" }
+
+func (t *createIssueTool) Description() string {
+	return "Open an issue in a GitHub repository as the user. Only when the user asked for it; " +
+		"the user may be asked to confirm first."
+}
+
+func (t *createIssueTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"repo":   map[string]any{"type": "string", "description": "owner/repo"},
+			"title":  map[string]any{"type": "string"},
+			"body":   map[string]any{"type": "string", "description": "The description, in GitHub markdown"},
+			"labels": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		Required: []string{"repo", "title"},
+	}
+}
+
+func (t *createIssueTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	owner, repo, err := ParseRepo(stringParam(params, "repo"))
+	if err != nil {
+		return failed(err), nil
+	}
+	in := NewIssue{Title: stringParam(params, "title"), Body: stringParam(params, "body")}
+	if in.Title == "" {
+		return failed(errors.New("title is required")), nil
+	}
+	if labels, ok := params["labels"].([]any); ok {
+		for _, l := range labels {
+			if s, ok := l.(string); ok && strings.TrimSpace(s) != "" {
+				in.Labels = append(in.Labels, strings.TrimSpace(s))
+			}
+		}
+	}
+	target := fmt.Sprintf("open an issue in %s/%s: %q — %s", owner, repo, in.Title, preview(in.Body))
+	if err := confirm(ctx, t.policy, t.Name(), target, params); err != nil {
+		return failed(err), nil
+	}
+	issue, err := t.c.CreateIssue(ctx, owner, repo, in)
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: fmt.Sprintf("Opened %s#%d: %s", issue.Repo, issue.Number, issue.URL), Data: issue}, nil
+}
+
+// confirm applies the write policy to a write described by target.
+func confirm(ctx context.Context, policy, name, target string, params map[string]any) error {
+	switch policy {
+	case WriteDeny:
+		return errors.New("writing to GitHub is turned off (github.write is deny); give the user the text to post instead")
+	case WriteAllow:
+		return nil
+	}
+	return permission.Confirm(ctx, permission.Request{Tool: name, Target: target, Params: params})
+}
+
+func preview(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > 200 {
+		s = string(r[:200]) + "…"
+	}
+	return s
+}
+
+func limitParam(params map[string]any) int {
+	n, _ := params["limit"].(float64)
+	switch {
+	case n <= 0:
+		return defaultLimit
+	case n > maxLimit:
+		return maxLimit
+	}
+	return int(n)
+}
+
+func stringParam(params map[string]any, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+func failed(err error) *tool.ToolResult {
+	return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}
+}
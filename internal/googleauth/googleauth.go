@@ -0,0 +1,105 @@
+// Package googleauth keeps the Google sign-ins myclaw makes with `myclaw
+// auth google` and `myclaw auth gmail`, and the HTTP clients that use them.
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrNoToken is returned when nobody has signed in yet.
+var ErrNoToken = errors.New("not signed in")
+
+// Config returns the OAuth client for a Google "Desktop app" client ID.
+func Config(clientID, clientSecret string, scopes ...string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://accounts.google.com/o/oauth2/auth",
+			TokenURL: "https://oauth2.googleapis.com/token",
+		},
+	}
+}
+
+// LoadToken reads the token saved at path.
+func LoadToken(path string) (*oauth2.Token, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tok oauth2.Token
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return &tok, nil
+}
+
+// SaveToken writes tok to path, readable by the owner only.
+func SaveToken(path string, tok *oauth2.Token) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tok, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Client returns an HTTP client that signs its requests with the token
+// saved at path, refreshing it through base as needed.
+func Client(conf *oauth2.Config, path string, base *http.Client) (*http.Client, error) {
+	tok, err := LoadToken(path)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, base)
+	src := &savingTokenSource{src: conf.TokenSource(ctx, tok), path: path, last: tok.AccessToken}
+	return oauth2.NewClient(ctx, src), nil
+}
+
+// Revoked reports whether err came from Google refusing to refresh the
+// sign-in, and the reason it gave.
+func Revoked(err error) (string, bool) {
+	var re *oauth2.RetrieveError
+	if !errors.As(err, &re) {
+		return "", false
+	}
+	return re.ErrorCode, true
+}
+
+// savingTokenSource saves the token whenever Google hands out a new one,
+// so a refreshed access token outlives the process.
+type savingTokenSource struct {
+	src  oauth2.TokenSource
+	path string
+	mu   sync.Mutex
+	last string
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tok.AccessToken != s.last {
+		s.last = tok.AccessToken
+		_ = SaveToken(s.path, tok)
+	}
+	return tok, nil
+}
@@ -0,0 +1,126 @@
+// Package health checks whether myclaw can serve: that its config is valid,
+// its model provider answers and its channels are connected. The gateway
+// serves the checks over HTTP; `myclaw status --probe` runs them too.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/mcp"
+	"github.com/stellarlinkco/myclaw/internal/provider"
+)
+
+// probeTimeout bounds each check.
+const probeTimeout = 5 * time.Second
+
+// Probe is one named check. Run returns nil when the check passes.
+type Probe struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Check is the outcome of a Probe.
+type Check struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Report is the outcome of a set of probes. OK is true when every check
+// passed.
+type Report struct {
+	OK     bool    `json:"ok"`
+	Checks []Check `json:"checks"`
+}
+
+// Run runs probes concurrently and reports them in order.
+func Run(ctx context.Context, probes ...Probe) Report {
+	checks := make([]Check, len(probes))
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+			defer cancel()
+			checks[i] = Check{Name: p.Name, OK: true}
+			if err := p.Run(ctx); err != nil {
+				checks[i] = Check{Name: p.Name, Error: err.Error()}
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{OK: true, Checks: checks}
+	for _, c := range checks {
+		report.OK = report.OK && c.OK
+	}
+	return report
+}
+
+// Config checks that the config file still loads and is valid, so an edit
+// that broke it shows before the next restart.
+func Config() Probe {
+	return Probe{Name: "config", Run: func(context.Context) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return err
+		}
+		return cfg.Validate()
+	}}
+}
+
+// Provider checks that the configured model provider answers. A result is
+// reused for ttl so that frequent probes do not each call the provider.
+func Provider(cfg *config.Config, ttl time.Duration) Probe {
+	c := &cached{ttl: ttl, run: func(ctx context.Context) error {
+		_, err := provider.ListModels(ctx, cfg)
+		return err
+	}}
+	return Probe{Name: "provider", Run: c.Run}
+}
+
+// MCP checks that an MCP server connects and completes the handshake.
+func MCP(server config.MCPServer) Probe {
+	return Probe{Name: "mcp:" + server.Label(), Run: func(ctx context.Context) error {
+		_, err := mcp.Inspect(ctx, server.Spec)
+		return err
+	}}
+}
+
+type cached struct {
+	ttl time.Duration
+	run func(ctx context.Context) error
+
+	mu  sync.Mutex
+	at  time.Time
+	err error
+}
+
+func (c *cached) Run(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.at.IsZero() && time.Since(c.at) < c.ttl {
+		return c.err
+	}
+	c.err, c.at = c.run(ctx), time.Now()
+	return c.err
+}
+
+// Handler serves the report of probes as JSON, with status 503 when a
+// check failed.
+func Handler(probes func() []Probe) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Run(r.Context(), probes()...)
+		w.Header().Set("Content-Type", "application/json")
+		if !report.OK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	ok := Probe{Name: "ok", Run: func(context.Context) error { return nil }}
+	bad := Probe{Name: "bad", Run: func(context.Context) error { return errors.New("down") }}
+	slow := Probe{Name: "slow", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	if report := Run(context.Background(), ok); !report.OK || len(report.Checks) != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report := Run(ctx, ok, bad, slow)
+	if report.OK {
+		t.Error("report should fail")
+	}
+	want := []Check{{Name: "ok", OK: true}, {Name: "bad", Error: "down"}, {Name: "slow", Error: "context deadline exceeded"}}
+	for i, c := range report.Checks {
+		if c != want[i] {
+			t.Errorf("check %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+}
+
+func TestCached(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	c := &cached{ttl: time.Hour, run: func(context.Context) error {
+		calls++
+		return errors.New("unreachable")
+	}}
+	for range 3 {
+		if err := c.Run(context.Background()); err == nil {
+			t.Error("expected the cached error")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	c.at = time.Now().Add(-2 * time.Hour)
+	_ = c.Run(context.Background())
+	if calls != 2 {
+		t.Errorf("calls after ttl = %d, want 2", calls)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	failing := false
+	h := Handler(func() []Probe {
+		return []Probe{{Name: "channel:telegram", Run: func(context.Context) error {
+			if failing {
+				return errors.New("send failed")
+			}
+			return nil
+		}}}
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d", rec.Code)
+	}
+
+	failing = true
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || report.OK || report.Checks[0].Error != "send failed" {
+		t.Errorf("status = %d, report = %+v", rec.Code, report)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MYCLAW_API_KEY", "sk-test")
+	dir := filepath.Join(home, ".myclaw")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Config().Run(context.Background()); err != nil {
+		t.Errorf("default config: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"provider": {"type": "mistral"}}`), 0644)
+	if err := Config().Run(context.Background()); err == nil {
+		t.Error("expected an invalid provider type")
+	}
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"provider": `), 0644)
+	if err := Config().Run(context.Background()); err == nil {
+		t.Error("expected a parse error")
+	}
+}
@@ -76,6 +76,24 @@ func TestTick_WithContent(t *testing.T) {
 	}
 }
 
+func TestTick_ShouldRunFalse(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "HEARTBEAT.md"), []byte("check inbox"), 0644)
+
+	var called atomic.Int32
+	s := New(tmpDir, func(prompt string) (string, error) {
+		called.Add(1)
+		return "ok", nil
+	}, time.Second)
+	s.ShouldRun = func() bool { return false }
+
+	s.tick()
+
+	if called.Load() != 0 {
+		t.Error("handler should not be called on a standby instance")
+	}
+}
+
 func TestStart_ContextCancel(t *testing.T) {
 	tmpDir := t.TempDir()
 	s := New(tmpDir, nil, 100*time.Millisecond)
@@ -188,3 +206,106 @@ func TestTruncate(t *testing.T) {
 		}
 	}
 }
+
+func TestParseActiveHours(t *testing.T) {
+	day, err := ParseActiveHours("08:00-22:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	night, err := ParseActiveHours("22:30 - 06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(h, m int) time.Time { return time.Date(2026, 10, 16, h, m, 0, 0, time.Local) }
+	cases := []struct {
+		t          time.Time
+		day, night bool
+	}{
+		{at(7, 59), false, false},
+		{at(8, 0), true, false},
+		{at(21, 59), true, false},
+		{at(22, 0), false, false},
+		{at(23, 0), false, true},
+		{at(5, 59), false, true},
+	}
+	for _, c := range cases {
+		if got := day.Contains(c.t); got != c.day {
+			t.Errorf("day window at %s = %v", c.t.Format("15:04"), got)
+		}
+		if got := night.Contains(c.t); got != c.night {
+			t.Errorf("night window at %s = %v", c.t.Format("15:04"), got)
+		}
+	}
+	for _, spec := range []string{"8-22", "08:00", "25:00-26:00", "09:00-09:00"} {
+		if _, err := ParseActiveHours(spec); err == nil {
+			t.Errorf("ParseActiveHours(%q): expected error", spec)
+		}
+	}
+}
+
+func TestTick_ActiveHours(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "HEARTBEAT.md"), []byte("Check tasks"), 0644)
+	var called atomic.Int32
+	s := New(tmpDir, func(prompt string) (string, error) {
+		called.Add(1)
+		return "ok", nil
+	}, time.Second)
+	s.Active, _ = ParseActiveHours("08:00-22:00")
+	s.now = func() time.Time { return time.Date(2026, 10, 16, 23, 0, 0, 0, time.Local) }
+	s.tick()
+	if called.Load() != 0 {
+		t.Error("handler should not be called outside active hours")
+	}
+	s.now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local) }
+	s.tick()
+	if called.Load() != 1 {
+		t.Error("handler should be called inside active hours")
+	}
+}
+
+func TestTick_QuietDelivery(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "HEARTBEAT.md"), []byte("Check tasks"), 0644)
+	reply := "HEARTBEAT_OK"
+	s := New(tmpDir, func(prompt string) (string, error) { return reply, nil }, time.Second)
+	var delivered []string
+	s.OnResult = func(result string) { delivered = append(delivered, result) }
+
+	s.tick()
+	if len(delivered) != 1 {
+		t.Fatalf("without Quiet every reply is delivered, got %v", delivered)
+	}
+	s.Quiet = true
+	for _, reply = range []string{"HEARTBEAT_OK", "  ", "Nothing to report."} {
+		s.tick()
+	}
+	reply = "Dentist at 3pm today."
+	s.tick()
+	if len(delivered) != 2 || delivered[1] != "Dentist at 3pm today." {
+		t.Errorf("delivered = %q", delivered)
+	}
+}
+
+func TestTick_Extra(t *testing.T) {
+	tmpDir := t.TempDir()
+	var prompts []string
+	s := New(tmpDir, func(prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return "ok", nil
+	}, time.Second)
+	extra := "Overdue: pay rent"
+	s.Extra = func() string { return extra }
+
+	// Extra alone runs a tick without HEARTBEAT.md.
+	s.tick()
+	os.WriteFile(filepath.Join(tmpDir, "HEARTBEAT.md"), []byte("Check tasks"), 0644)
+	s.tick()
+	extra = ""
+	s.tick()
+
+	want := []string{"Overdue: pay rent", "Check tasks\n\nOverdue: pay rent", "Check tasks"}
+	if fmt.Sprint(prompts) != fmt.Sprint(want) {
+		t.Errorf("prompts = %q, want %q", prompts, want)
+	}
+}
@@ -2,6 +2,7 @@ package heartbeat
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -13,6 +14,80 @@ type Service struct {
 	workspace   string
 	onHeartbeat func(prompt string) (string, error)
 	interval    time.Duration
+
+	// ShouldRun, when set, skips ticks while it reports false.
+	ShouldRun func() bool
+	// Active, when set, skips ticks outside its daily window.
+	Active *ActiveHours
+	// OnResult, when set, receives each reply, such as for delivery.
+	OnResult func(result string)
+	// Quiet withholds replies that report nothing from OnResult.
+	Quiet bool
+	// Extra, when set, returns text to add to the prompt, such as overdue
+	// todos. A tick runs when either HEARTBEAT.md or Extra has something.
+	Extra func() string
+
+	now func() time.Time
+}
+
+// ActiveHours is a daily window in local time, in minutes since midnight.
+// From > To wraps past midnight.
+type ActiveHours struct {
+	From, To int
+}
+
+// ParseActiveHours reads a window such as "08:00-22:00" or "22:00-06:00".
+func ParseActiveHours(spec string) (*ActiveHours, error) {
+	from, to, ok := strings.Cut(strings.ReplaceAll(spec, " ", ""), "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid active hours %q: want HH:MM-HH:MM", spec)
+	}
+	var a ActiveHours
+	var err error
+	if a.From, err = parseClock(from); err == nil {
+		a.To, err = parseClock(to)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid active hours %q: %w", spec, err)
+	}
+	if a.From == a.To {
+		return nil, fmt.Errorf("invalid active hours %q: empty window", spec)
+	}
+	return &a, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if s == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, fmt.Errorf("bad time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether t falls inside the window.
+func (a ActiveHours) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if a.From < a.To {
+		return m >= a.From && m < a.To
+	}
+	return m >= a.From || m < a.To
+}
+
+// IsNoop reports whether a heartbeat reply has nothing to report.
+func IsNoop(result string) bool {
+	result = strings.TrimSpace(result)
+	if result == "" || strings.Contains(result, "HEARTBEAT_OK") {
+		return true
+	}
+	normalized := strings.TrimRight(strings.ToLower(result), ".! ")
+	switch normalized {
+	case "nothing to report", "nothing to do", "no updates", "nothing new", "none", "n/a":
+		return true
+	}
+	return false
 }
 
 func New(workspace string, onHB func(string) (string, error), interval time.Duration) *Service {
@@ -23,6 +98,7 @@ func New(workspace string, onHB func(string) (string, error), interval time.Dura
 		workspace:   workspace,
 		onHeartbeat: onHB,
 		interval:    interval,
+		now:         time.Now,
 	}
 }
 
@@ -44,16 +120,26 @@ func (s *Service) Start(ctx context.Context) error {
 }
 
 func (s *Service) tick() {
+	if s.ShouldRun != nil && !s.ShouldRun() {
+		return
+	}
+	if s.Active != nil && !s.Active.Contains(s.now()) {
+		return
+	}
+
 	hbPath := filepath.Join(s.workspace, "HEARTBEAT.md")
 	data, err := os.ReadFile(hbPath)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			log.Printf("[heartbeat] read error: %v", err)
-		}
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("[heartbeat] read error: %v", err)
 		return
 	}
 
 	content := strings.TrimSpace(string(data))
+	if s.Extra != nil {
+		if extra := strings.TrimSpace(s.Extra()); extra != "" {
+			content = strings.TrimSpace(content + "\n\n" + extra)
+		}
+	}
 	if content == "" {
 		return
 	}
@@ -71,11 +157,15 @@ func (s *Service) tick() {
 		return
 	}
 
-	if strings.Contains(result, "HEARTBEAT_OK") {
+	noop := IsNoop(result)
+	if noop {
 		log.Printf("[heartbeat] nothing to do")
 	} else {
 		log.Printf("[heartbeat] result: %s", truncate(result, 200))
 	}
+	if s.OnResult != nil && !(s.Quiet && noop) {
+		s.OnResult(result)
+	}
 }
 
 func truncate(s string, n int) string {
@@ -0,0 +1,181 @@
+// Package homeassistant gives the agent tools to read the states of a Home
+// Assistant instance and call its services, over the REST API or over MQTT,
+// limited to an allowlist of entities.
+package homeassistant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	defaultStateTopic   = "homeassistant/statestream"
+	defaultCommandTopic = "myclaw/call"
+	maxResponseBytes    = 10 << 20
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// State is the state of one entity.
+type State struct {
+	EntityID    string         `json:"entity_id"`
+	State       string         `json:"state"`
+	Attributes  map[string]any `json:"attributes,omitempty"`
+	LastChanged time.Time      `json:"last_changed,omitempty"`
+}
+
+// Domain returns the part of the entity ID before the dot, such as light.
+func (s State) Domain() string {
+	domain, _, _ := strings.Cut(s.EntityID, ".")
+	return domain
+}
+
+// Name returns the entity's friendly name, or its ID when it has none.
+func (s State) Name() string {
+	if name, ok := s.Attributes["friendly_name"].(string); ok && name != "" {
+		return name
+	}
+	return s.EntityID
+}
+
+// Home is a Home Assistant instance.
+type Home interface {
+	// States returns the state of every entity.
+	States(ctx context.Context) ([]State, error)
+	// Call calls domain.service with data and returns the states it
+	// changed, when the transport reports them.
+	Call(ctx context.Context, domain, service string, data map[string]any) ([]State, error)
+}
+
+// New returns the instance cfg selects, or nil when no provider is set.
+func New(cfg config.HomeAssistantConfig) (Home, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "rest":
+		if cfg.URL == "" || cfg.Token == "" {
+			return nil, errors.New("homeassistant: rest needs url and token")
+		}
+		return &rest{url: strings.TrimRight(cfg.URL, "/"), token: cfg.Token, http: httpClient}, nil
+	case "mqtt":
+		if cfg.MQTT.Broker == "" {
+			return nil, errors.New("homeassistant: mqtt needs broker")
+		}
+		m := &mqttHome{cfg: cfg.MQTT}
+		if m.cfg.StateTopic == "" {
+			m.cfg.StateTopic = defaultStateTopic
+		}
+		if m.cfg.CommandTopic == "" {
+			m.cfg.CommandTopic = defaultCommandTopic
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("homeassistant: unknown provider %q", cfg.Provider)
+	}
+}
+
+// Allowlist is the entities the agent may see and control, as entity IDs
+// and patterns such as light.*.
+type Allowlist []string
+
+// Allows reports whether entityID is on the list.
+func (a Allowlist) Allows(entityID string) bool {
+	for _, pattern := range a {
+		if ok, _ := path.Match(pattern, entityID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the states on the list, sorted by entity ID.
+func (a Allowlist) Filter(states []State) []State {
+	var out []State
+	for _, s := range states {
+		if a.Allows(s.EntityID) {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].EntityID < out[j].EntityID })
+	return out
+}
+
+// rest talks to the Home Assistant REST API with a long-lived access token.
+type rest struct {
+	url   string
+	token string
+	http  *http.Client
+}
+
+func (r *rest) States(ctx context.Context) ([]State, error) {
+	var states []State
+	if err := r.call(ctx, http.MethodGet, "/api/states", nil, &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (r *rest) Call(ctx context.Context, domain, service string, data map[string]any) ([]State, error) {
+	var changed []State
+	if err := r.call(ctx, http.MethodPost, "/api/services/"+domain+"/"+service, data, &changed); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+func (r *rest) call(ctx context.Context, method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.url+endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("home assistant: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("home assistant: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return errors.New("home assistant rejected the token; check homeAssistant.token")
+	case resp.StatusCode >= 300:
+		msg := strings.TrimSpace(string(data))
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			msg = apiErr.Message
+		}
+		if len(msg) > 200 {
+			msg = msg[:200]
+		}
+		return fmt.Errorf("home assistant: %s %s: %s: %s", method, endpoint, resp.Status, msg)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("home assistant: %s: %w", endpoint, err)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package homeassistant
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const statesJSON = `[
+	{"entity_id":"light.living_room","state":"on","attributes":{"friendly_name":"Living Room","brightness":200}},
+	{"entity_id":"light.porch","state":"off","attributes":{"friendly_name":"Porch"}},
+	{"entity_id":"sensor.kitchen_temperature","state":"21.5","attributes":{"friendly_name":"Kitchen Temperature","unit_of_measurement":"°C"}},
+	{"entity_id":"lock.front_door","state":"locked","attributes":{"friendly_name":"Front Door"}}
+]`
+
+// fakeREST serves the Home Assistant REST API and records service calls.
+func fakeREST(t *testing.T) (Home, *[]string) {
+	t.Helper()
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/states":
+			w.Write([]byte(statesJSON))
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/services/"):
+			var data map[string]any
+			json.NewDecoder(r.Body).Decode(&data)
+			raw, _ := json.Marshal(data)
+			calls = append(calls, strings.TrimPrefix(r.URL.Path, "/api/services/")+" "+string(raw))
+			w.Write([]byte(`[{"entity_id":"light.living_room","state":"off","attributes":{"friendly_name":"Living Room"}}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	home, err := New(config.HomeAssistantConfig{Provider: "rest", URL: srv.URL + "/", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return home, &calls
+}
+
+func TestTools_REST(t *testing.T) {
+	t.Parallel()
+
+	home, calls := fakeREST(t)
+	tools := NewTools(home, Allowlist{"light.*", "sensor.kitchen_*"})
+	states, control := tools[0], tools[1]
+	ctx := context.Background()
+
+	res, _ := states.Execute(ctx, map[string]any{})
+	if !res.Success || strings.Contains(res.Output, "lock.front_door") ||
+		!strings.Contains(res.Output, "- light.living_room (Living Room): on\n") ||
+		!strings.Contains(res.Output, "- sensor.kitchen_temperature (Kitchen Temperature): 21.5 °C\n") {
+		t.Errorf("all states = %q", res.Output)
+	}
+	res, _ = states.Execute(ctx, map[string]any{"entity": "living room"})
+	if !strings.Contains(res.Output, "brightness: 200") || strings.Contains(res.Output, "porch") {
+		t.Errorf("living room = %q", res.Output)
+	}
+	res, _ = states.Execute(ctx, map[string]any{"entity": "front door"})
+	if !res.Success || res.Output != "No matching entities.\n" {
+		t.Errorf("front door = %q", res.Output)
+	}
+
+	res, _ = control.Execute(ctx, map[string]any{"entity": "light.living_room", "service": "turn_off", "data": map[string]any{"transition": 2, "area_id": "house"}})
+	if !res.Success || res.Output != "Called light.turn_off on light.living_room. Living Room is now off." {
+		t.Errorf("turn off = %+v", res)
+	}
+	if len(*calls) != 1 || (*calls)[0] != `light/turn_off {"entity_id":"light.living_room","transition":2}` {
+		t.Errorf("calls = %q", *calls)
+	}
+
+	for _, params := range []map[string]any{
+		{"entity": "lock.front_door", "service": "lock.unlock"},
+		{"entity": "light.porch", "service": "script.turn_on"},
+		{"entity": "light.porch", "service": "homeassistant.restart"},
+		{"entity": "light.porch", "service": "homeassistant.reload_all"},
+		{"entity": "light.porch", "service": "light.turn on"},
+		{"entity": "porch", "service": "turn_on"},
+	} {
+		if res, _ := control.Execute(ctx, params); res.Success {
+			t.Errorf("%v allowed: %q", params, res.Output)
+		}
+	}
+	if len(*calls) != 1 {
+		t.Errorf("refused calls reached Home Assistant: %q", *calls)
+	}
+	if res, _ := control.Execute(ctx, map[string]any{"entity": "light.porch", "service": "homeassistant.toggle"}); !res.Success {
+		t.Errorf("homeassistant.toggle = %q", res.Output)
+	}
+
+	bad, _ := New(config.HomeAssistantConfig{Provider: "rest", URL: home.(*rest).url, Token: "wrong"})
+	if _, err := bad.States(ctx); err == nil || !strings.Contains(err.Error(), "rejected the token") {
+		t.Errorf("bad token error = %v", err)
+	}
+}
+
+// fakeBroker is an MQTT broker that answers one client at a time: it
+// sends the retained messages to subscribers and records what is
+// published.
+func fakeBroker(t *testing.T, retained map[string]string) (string, chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	published := make(chan string, 10)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn := &mqttConn{c: c, r: bufio.NewReader(c)}
+			for {
+				kind, body, err := conn.read()
+				if err != nil {
+					break
+				}
+				switch kind & 0xf0 {
+				case mqttConnect:
+					if !strings.Contains(string(body), "user") || !strings.Contains(string(body), "pass") {
+						conn.send(mqttConnAck, []byte{0, 4})
+						continue
+					}
+					conn.send(mqttConnAck, []byte{0, 0})
+				case mqttSubscribe & 0xf0:
+					conn.send(mqttSubAck, []byte{0, 1, 0})
+					for topic, payload := range retained {
+						conn.send(mqttPublish|1, append(appendString(nil, topic), payload...))
+					}
+				case mqttPublish:
+					n := int(body[0])<<8 | int(body[1])
+					published <- string(body[2:2+n]) + " " + string(body[2+n:])
+				}
+			}
+			c.Close()
+		}
+	}()
+	return "tcp://" + ln.Addr().String(), published
+}
+
+func TestTools_MQTT(t *testing.T) {
+	t.Parallel()
+
+	broker, published := fakeBroker(t, map[string]string{
+		"ha/light/living_room/state":                        "on",
+		"ha/light/living_room/friendly_name":                `"Living Room"`,
+		"ha/light/living_room/brightness":                   "200",
+		"ha/light/living_room/last_changed":                 `"2026-10-16T07:00:00+00:00"`,
+		"ha/sensor/kitchen_temperature/state":               "21.5",
+		"ha/sensor/kitchen_temperature/unit_of_measurement": `"°C"`,
+		"ha/sensor/gone/friendly_name":                      `"Removed"`,
+	})
+	home, err := New(config.HomeAssistantConfig{Provider: "mqtt", MQTT: config.HomeAssistantMQTTConfig{
+		Broker: broker, Username: "user", Password: "pass", StateTopic: "ha",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools := NewTools(home, Allowlist{"light.*", "sensor.*"})
+	ctx := context.Background()
+
+	res, _ := tools[0].Execute(ctx, map[string]any{})
+	if res.Output != "- light.living_room (Living Room): on\n- sensor.kitchen_temperature (sensor.kitchen_temperature): 21.5 °C\n" {
+		t.Errorf("states = %q", res.Output)
+	}
+	res, _ = tools[0].Execute(ctx, map[string]any{"entity": "light.living_room"})
+	if !strings.Contains(res.Output, "brightness: 200") || !strings.Contains(res.Output, "since ") {
+		t.Errorf("living room = %q", res.Output)
+	}
+
+	res, _ = tools[1].Execute(ctx, map[string]any{"entity": "light.living_room", "service": "light.turn_off"})
+	if !res.Success || res.Output != "Sent light.turn_off for light.living_room." {
+		t.Errorf("turn off = %+v", res)
+	}
+	if got := <-published; got != `myclaw/call {"domain":"light","service":"turn_off","data":{"entity_id":"light.living_room"}}` {
+		t.Errorf("published = %s", got)
+	}
+
+	wrong, _ := New(config.HomeAssistantConfig{Provider: "mqtt", MQTT: config.HomeAssistantMQTTConfig{Broker: broker}})
+	if _, err := wrong.States(ctx); err == nil || !strings.Contains(err.Error(), "username or password") {
+		t.Errorf("refused connect error = %v", err)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	t.Parallel()
+
+	allow := Allowlist{"light.*", "switch.kettle"}
+	for id, want := range map[string]bool{
+		"light.porch":     true,
+		"switch.kettle":   true,
+		"switch.heater":   false,
+		"lock.front_door": false,
+	} {
+		if got := allow.Allows(id); got != want {
+			t.Errorf("Allows(%s) = %v", id, got)
+		}
+	}
+	if home, err := New(config.HomeAssistantConfig{}); home != nil || err != nil {
+		t.Errorf("New without provider = %v, %v", home, err)
+	}
+}
@@ -0,0 +1,322 @@
+package homeassistant
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	mqttDialTimeout = 10 * time.Second
+	// mqttSettle is how long reading states waits after the last retained
+	// message before taking the set as complete.
+	mqttSettle = 500 * time.Millisecond
+	// mqttReadLimit caps how long reading states takes in all.
+	mqttReadLimit = 10 * time.Second
+)
+
+// MQTT control packet types, shifted into the first byte of the header.
+const (
+	mqttConnect    = 0x10
+	mqttConnAck    = 0x20
+	mqttPublish    = 0x30
+	mqttSubscribe  = 0x82 // with the reserved flags the spec requires
+	mqttSubAck     = 0x90
+	mqttDisconnect = 0xe0
+)
+
+// mqttHome reads states from mqtt_statestream's retained messages and sends
+// service calls to a topic an automation listens on. Each operation opens
+// its own connection, which keeps it free of reconnect logic.
+type mqttHome struct {
+	cfg config.HomeAssistantMQTTConfig
+}
+
+// mqttCall is the payload of a service call on the command topic.
+type mqttCall struct {
+	Domain  string         `json:"domain"`
+	Service string         `json:"service"`
+	Data    map[string]any `json:"data"`
+}
+
+func (m *mqttHome) States(ctx context.Context) ([]State, error) {
+	conn, err := dialMQTT(ctx, m.cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+	base := strings.TrimRight(m.cfg.StateTopic, "/") + "/"
+	if err := conn.subscribe(base + "#"); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*State)
+	deadline := time.Now().Add(mqttReadLimit)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	for {
+		settle := time.Now().Add(mqttSettle)
+		if settle.After(deadline) {
+			settle = deadline
+		}
+		_ = conn.c.SetReadDeadline(settle)
+		topic, payload, err := conn.next()
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(strings.TrimPrefix(topic, base), "/")
+		if len(parts) != 3 {
+			continue
+		}
+		id := parts[0] + "." + parts[1]
+		s := byID[id]
+		if s == nil {
+			s = &State{EntityID: id, Attributes: make(map[string]any)}
+			byID[id] = s
+		}
+		setStateField(s, parts[2], payload)
+	}
+
+	var states []State
+	for _, s := range byID {
+		// Attributes of an entity whose state was never published are
+		// left over from a removed entity.
+		if s.State != "" {
+			states = append(states, *s)
+		}
+	}
+	return states, nil
+}
+
+// setStateField applies one statestream message: the state as plain text,
+// timestamps in ISO 8601 and attributes as JSON.
+func setStateField(s *State, key string, payload []byte) {
+	text := string(payload)
+	switch key {
+	case "state":
+		s.State = text
+	case "last_changed":
+		s.LastChanged, _ = time.Parse(time.RFC3339Nano, strings.Trim(text, `"`))
+	case "last_updated":
+	default:
+		var v any
+		if json.Unmarshal(payload, &v) != nil {
+			v = text
+		}
+		s.Attributes[key] = v
+	}
+}
+
+func (m *mqttHome) Call(ctx context.Context, domain, service string, data map[string]any) ([]State, error) {
+	payload, err := json.Marshal(mqttCall{Domain: domain, Service: service, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialMQTT(ctx, m.cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+	if err := conn.publish(m.cfg.CommandTopic, payload); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// mqttConn is a minimal MQTT 3.1.1 client: QoS 0 publish and subscribe,
+// enough for short request-like sessions.
+type mqttConn struct {
+	c net.Conn
+	r *bufio.Reader
+}
+
+func dialMQTT(ctx context.Context, cfg config.HomeAssistantMQTTConfig) (*mqttConn, error) {
+	u, err := url.Parse(cfg.Broker)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("mqtt broker %q: want tcp://host:1883 or ssl://host:8883", cfg.Broker)
+	}
+	secure := false
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		secure, port = true, "8883"
+	default:
+		return nil, fmt.Errorf("mqtt broker %q: unknown scheme %q", cfg.Broker, u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: mqttDialTimeout}
+	var c net.Conn
+	if secure {
+		c, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname()}}).DialContext(ctx, "tcp", addr)
+	} else {
+		c, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mqtt: %w", err)
+	}
+	conn := &mqttConn{c: c, r: bufio.NewReader(c)}
+	deadline := time.Now().Add(mqttDialTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.SetDeadline(deadline)
+	if err := conn.connect(cfg.Username, cfg.Password); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (m *mqttConn) connect(username, password string) error {
+	id := make([]byte, 6)
+	_, _ = rand.Read(id)
+	body := appendString(nil, "MQTT")
+	flags := byte(0x02) // clean session
+	if username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+	body = append(body, 4, flags, 0, 60) // protocol level 4, keep alive 60s
+	body = appendString(body, "myclaw-"+hex.EncodeToString(id))
+	if username != "" {
+		body = appendString(body, username)
+		if password != "" {
+			body = appendString(body, password)
+		}
+	}
+	if err := m.send(mqttConnect, body); err != nil {
+		return err
+	}
+	kind, ack, err := m.read()
+	if err != nil {
+		return fmt.Errorf("mqtt connect: %w", err)
+	}
+	if kind&0xf0 != mqttConnAck || len(ack) < 2 {
+		return errors.New("mqtt connect: the broker did not acknowledge")
+	}
+	switch ack[1] {
+	case 0:
+		return nil
+	case 4, 5:
+		return errors.New("mqtt connect: the broker refused the username or password")
+	default:
+		return fmt.Errorf("mqtt connect: the broker refused the connection (code %d)", ack[1])
+	}
+}
+
+func (m *mqttConn) subscribe(filter string) error {
+	body := []byte{0, 1} // packet identifier
+	body = appendString(body, filter)
+	body = append(body, 0) // QoS 0
+	return m.send(mqttSubscribe, body)
+}
+
+func (m *mqttConn) publish(topic string, payload []byte) error {
+	return m.send(mqttPublish, append(appendString(nil, topic), payload...))
+}
+
+// next returns the next message published to the connection, skipping
+// other packets.
+func (m *mqttConn) next() (topic string, payload []byte, err error) {
+	for {
+		kind, body, err := m.read()
+		if err != nil {
+			return "", nil, err
+		}
+		if kind&0xf0 != mqttPublish {
+			continue
+		}
+		if len(body) < 2 {
+			return "", nil, errors.New("mqtt: short publish packet")
+		}
+		n := int(binary.BigEndian.Uint16(body))
+		if len(body) < 2+n {
+			return "", nil, errors.New("mqtt: short publish packet")
+		}
+		rest := body[2+n:]
+		if qos := kind >> 1 & 3; qos > 0 && len(rest) >= 2 {
+			rest = rest[2:] // packet identifier
+		}
+		return string(body[2 : 2+n]), rest, nil
+	}
+}
+
+func (m *mqttConn) close() {
+	_ = m.c.SetWriteDeadline(time.Now().Add(time.Second))
+	_ = m.send(mqttDisconnect, nil)
+	m.c.Close()
+}
+
+func (m *mqttConn) send(kind byte, body []byte) error {
+	pkt := []byte{kind}
+	for n := len(body); ; {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		pkt = append(pkt, b)
+		if n == 0 {
+			break
+		}
+	}
+	if _, err := m.c.Write(append(pkt, body...)); err != nil {
+		return fmt.Errorf("mqtt: %w", err)
+	}
+	return nil
+}
+
+func (m *mqttConn) read() (kind byte, body []byte, err error) {
+	if kind, err = m.r.ReadByte(); err != nil {
+		return 0, nil, err
+	}
+	n, shift := 0, 0
+	for i := 0; ; i++ {
+		b, err := m.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		if shift += 7; i == 3 {
+			return 0, nil, errors.New("mqtt: malformed packet length")
+		}
+	}
+	body = make([]byte, n)
+	if _, err := io.ReadFull(m.r, body); err != nil {
+		return 0, nil, err
+	}
+	return kind, body, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,226 @@
+package homeassistant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// maxListed caps the entities home_states lists at once.
+const maxListed = 100
+
+var serviceName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// genericServices are the services of the homeassistant domain the agent
+// may call. They act on their target alone; the rest, such as restart,
+// stop and reload_all, act on Home Assistant itself.
+var genericServices = map[string]bool{"turn_on": true, "turn_off": true, "toggle": true, "update_entity": true}
+
+// Tools returns the home tools for cfg, or nil when no Home Assistant is
+// configured.
+func Tools(cfg config.HomeAssistantConfig) ([]tool.Tool, error) {
+	home, err := New(cfg)
+	if err != nil || home == nil {
+		return nil, err
+	}
+	return NewTools(home, cfg.Entities), nil
+}
+
+// NewTools returns the tools the agent reads and controls home with,
+// limited to the entities on allow.
+func NewTools(home Home, allow Allowlist) []tool.Tool {
+	return []tool.Tool{&statesTool{home: home, allow: allow}, &controlTool{home: home, allow: allow}}
+}
+
+type statesTool struct {
+	home  Home
+	allow Allowlist
+}
+
+func (t *statesTool) Name() string { return "home_states" }
+
+func (t *statesTool) Description() string {
+	return "Read the state of smart home devices and sensors in Home Assistant: lights, switches, " +
+		"thermostats, temperatures and so on. Give an entity ID (light.living_room), a pattern (sensor.*) " +
+		"or words from a name (\"living room\") to narrow it down; one match shows all its attributes."
+}
+
+func (t *statesTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"entity": map[string]any{"type": "string", "description": "Entity ID, pattern or words from a name; empty lists everything"},
+		},
+		Required: []string{},
+	}
+}
+
+func (t *statesTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	states, err := t.home.States(ctx)
+	if err != nil {
+		return failed(err), nil
+	}
+	states = Match(t.allow.Filter(states), stringParam(params, "entity"))
+	if len(states) == 0 {
+		return &tool.ToolResult{Success: true, Output: "No matching entities.\n", Data: states}, nil
+	}
+	return &tool.ToolResult{Success: true, Output: Format(states), Data: states}, nil
+}
+
+// Match returns the states whose entity ID is query or matches it as a
+// pattern, or else whose name or ID contains every word of query.
+func Match(states []State, query string) []State {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return states
+	}
+	var out []State
+	for _, s := range states {
+		if ok, _ := path.Match(query, s.EntityID); ok {
+			out = append(out, s)
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+	words := strings.Fields(strings.ToLower(query))
+	for _, s := range states {
+		text := strings.ToLower(s.Name() + " " + strings.ReplaceAll(s.EntityID, "_", " "))
+		all := true
+		for _, w := range words {
+			all = all && strings.Contains(text, w)
+		}
+		if all {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Format lists states one to a line, or a single state with its
+// attributes.
+func Format(states []State) string {
+	var b strings.Builder
+	for i, s := range states {
+		if i == maxListed {
+			fmt.Fprintf(&b, "[%d more; narrow it down with entity.]\n", len(states)-maxListed)
+			break
+		}
+		fmt.Fprintf(&b, "- %s (%s): %s", s.EntityID, s.Name(), s.State)
+		if unit, ok := s.Attributes["unit_of_measurement"].(string); ok && unit != "" {
+			b.WriteString(" " + unit)
+		}
+		b.WriteString("\n")
+	}
+	if len(states) == 1 {
+		s := states[0]
+		if !s.LastChanged.IsZero() {
+			fmt.Fprintf(&b, "  since %s\n", s.LastChanged.Local().Format("Mon 2 Jan 15:04"))
+		}
+		keys := make([]string, 0, len(s.Attributes))
+		for k := range s.Attributes {
+			if k != "friendly_name" && k != "unit_of_measurement" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "  %s: %v\n", k, s.Attributes[k])
+		}
+	}
+	return b.String()
+}
+
+type controlTool struct {
+	home  Home
+	allow Allowlist
+}
+
+func (t *controlTool) Name() string { return "home_control" }
+
+func (t *controlTool) Description() string {
+	return "Control a smart home device through a Home Assistant service, such as light.turn_off, " +
+		"light.turn_on with data {\"brightness_pct\": 40}, switch.toggle or climate.set_temperature with " +
+		"{\"temperature\": 21}. Look the entity up with home_states first when unsure of its ID."
+}
+
+func (t *controlTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"entity":  map[string]any{"type": "string", "description": "The entity ID, e.g. light.living_room"},
+			"service": map[string]any{"type": "string", "description": "domain.service, e.g. light.turn_off; a bare service name uses the entity's domain"},
+			"data":    map[string]any{"type": "object", "description": "Extra service data, e.g. {\"brightness_pct\": 40}"},
+		},
+		Required: []string{"entity", "service"},
+	}
+}
+
+func (t *controlTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	entity := stringParam(params, "entity")
+	if !strings.Contains(entity, ".") {
+		return failed(errors.New("entity must be an entity ID such as light.living_room")), nil
+	}
+	if !t.allow.Allows(entity) {
+		return failed(fmt.Errorf("%s is not on the allowlist (homeAssistant.entities); tell the user", entity)), nil
+	}
+	entityDomain, _, _ := strings.Cut(entity, ".")
+	domain, service, ok := strings.Cut(stringParam(params, "service"), ".")
+	if !ok {
+		domain, service = entityDomain, domain
+	}
+	if !serviceName.MatchString(domain) || !serviceName.MatchString(service) {
+		return failed(errors.New("service must look like light.turn_off")), nil
+	}
+	// A service of another domain could act on anything, whatever the
+	// entity, and so could most of the homeassistant domain.
+	if domain == "homeassistant" {
+		if !genericServices[service] {
+			return failed(fmt.Errorf("homeassistant.%s cannot be called; use a %s service", service, entityDomain)), nil
+		}
+	} else if domain != entityDomain {
+		return failed(fmt.Errorf("%s.%s cannot be called on %s; use a %s service", domain, service, entity, entityDomain)), nil
+	}
+	data := make(map[string]any)
+	if extra, ok := params["data"].(map[string]any); ok {
+		for k, v := range extra {
+			data[k] = v
+		}
+	}
+	// The target is the entity alone.
+	for _, k := range []string{"area_id", "device_id", "floor_id", "label_id", "target"} {
+		delete(data, k)
+	}
+	data["entity_id"] = entity
+
+	changed, err := t.home.Call(ctx, domain, service, data)
+	if err != nil {
+		return failed(err), nil
+	}
+	out := fmt.Sprintf("Called %s.%s on %s.", domain, service, entity)
+	if len(changed) == 0 {
+		out = fmt.Sprintf("Sent %s.%s for %s.", domain, service, entity)
+	}
+	for _, s := range changed {
+		if s.EntityID == entity {
+			out += fmt.Sprintf(" %s is now %s.", s.Name(), s.State)
+		}
+	}
+	return &tool.ToolResult{Success: true, Output: out, Data: changed}, nil
+}
+
+func stringParam(params map[string]any, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+func failed(err error) *tool.ToolResult {
+	return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}
+}
@@ -0,0 +1,236 @@
+// Package imagegen makes pictures from text prompts with an image service
+// and gives the agent the image_generate tool.
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	openAIAPIURL    = "https://api.openai.com/v1"
+	stabilityAPIURL = "https://api.stability.ai"
+
+	// DefaultOpenAIModel and DefaultStabilityModel are used when no model
+	// is set.
+	DefaultOpenAIModel    = "gpt-image-1"
+	DefaultStabilityModel = "core"
+
+	maxImageBytes = 50 << 20
+)
+
+// Image generation takes a while, especially at high quality.
+var httpClient = &http.Client{Timeout: 3 * time.Minute}
+
+// Shapes an image can be asked for in.
+const (
+	Square    = "square"
+	Landscape = "landscape"
+	Portrait  = "portrait"
+)
+
+// Image is a generated picture.
+type Image struct {
+	Data      []byte
+	MediaType string
+	// RevisedPrompt is the prompt the service actually drew, when it
+	// rewrote the one it was given.
+	RevisedPrompt string
+}
+
+// Generator draws images with one service.
+type Generator interface {
+	Name() string
+	Generate(ctx context.Context, prompt, shape string) (*Image, error)
+}
+
+// New returns the generator cfg selects, or nil when Provider is empty.
+func New(cfg config.ImageConfig) (Generator, error) {
+	base := strings.TrimRight(cfg.BaseURL, "/")
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "openai":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("media.images: openai needs an API key")
+		}
+		g := &openAI{baseURL: base, apiKey: cfg.APIKey, model: cfg.Model}
+		if g.baseURL == "" {
+			g.baseURL = openAIAPIURL
+		}
+		if g.model == "" {
+			g.model = DefaultOpenAIModel
+		}
+		return g, nil
+	case "stability":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("media.images: stability needs an API key")
+		}
+		g := &stability{baseURL: base, apiKey: cfg.APIKey, model: cfg.Model}
+		if g.baseURL == "" {
+			g.baseURL = stabilityAPIURL
+		}
+		if g.model == "" {
+			g.model = DefaultStabilityModel
+		}
+		return g, nil
+	default:
+		return nil, fmt.Errorf("media.images: unknown provider %q", cfg.Provider)
+	}
+}
+
+// openAI calls an OpenAI-compatible /images/generations endpoint.
+type openAI struct {
+	baseURL, apiKey, model string
+}
+
+func (g *openAI) Name() string { return "openai" }
+
+// size is the nearest size to shape the model takes. DALL·E 2 only draws
+// squares.
+func (g *openAI) size(shape string) string {
+	switch {
+	case strings.HasPrefix(g.model, "dall-e-2") || shape == Square:
+		return "1024x1024"
+	case strings.HasPrefix(g.model, "dall-e-3"):
+		if shape == Portrait {
+			return "1024x1792"
+		}
+		return "1792x1024"
+	case shape == Portrait:
+		return "1024x1536"
+	default:
+		return "1536x1024"
+	}
+}
+
+func (g *openAI) Generate(ctx context.Context, prompt, shape string) (*Image, error) {
+	req := map[string]any{"model": g.model, "prompt": prompt, "n": 1, "size": g.size(shape)}
+	// DALL·E answers with a URL unless asked otherwise; the GPT image
+	// models always return base64 and reject the parameter.
+	if strings.HasPrefix(g.model, "dall-e") {
+		req["response_format"] = "b64_json"
+	}
+	body, _ := json.Marshal(req)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+"/images/generations", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+g.apiKey)
+	data, _, err := do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
+	}
+	var out struct {
+		Data []struct {
+			B64JSON       string `json:"b64_json"`
+			URL           string `json:"url"`
+			RevisedPrompt string `json:"revised_prompt"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("openai: decode response: %w", err)
+	}
+	if len(out.Data) == 0 {
+		return nil, fmt.Errorf("openai: no image in the response")
+	}
+	img := &Image{MediaType: "image/png", RevisedPrompt: out.Data[0].RevisedPrompt}
+	switch d := out.Data[0]; {
+	case d.B64JSON != "":
+		if img.Data, err = base64.StdEncoding.DecodeString(d.B64JSON); err != nil {
+			return nil, fmt.Errorf("openai: decode image: %w", err)
+		}
+	case d.URL != "":
+		getReq, err := http.NewRequestWithContext(ctx, http.MethodGet, d.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if img.Data, img.MediaType, err = do(getReq); err != nil {
+			return nil, fmt.Errorf("openai: download image: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("openai: no image in the response")
+	}
+	return img, nil
+}
+
+// stability calls Stability AI's Stable Image generate endpoints.
+type stability struct {
+	baseURL, apiKey, model string
+}
+
+func (g *stability) Name() string { return "stability" }
+
+func (g *stability) Generate(ctx context.Context, prompt, shape string) (*Image, error) {
+	ratio := "1:1"
+	switch shape {
+	case Landscape:
+		ratio = "16:9"
+	case Portrait:
+		ratio = "9:16"
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("prompt", prompt)
+	form.WriteField("aspect_ratio", ratio)
+	form.WriteField("output_format", "png")
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+"/v2beta/stable-image/generate/"+g.model, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	req.Header.Set("Accept", "image/*")
+	data, mediaType, err := do(req)
+	if err != nil {
+		return nil, fmt.Errorf("stability: %w", err)
+	}
+	return &Image{Data: data, MediaType: mediaType}, nil
+}
+
+// do sends req and returns the response body and its media type, or the
+// service's error message.
+func do(req *http.Request) ([]byte, string, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes))
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var out struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &out) == nil {
+			if out.Error.Message != "" {
+				return nil, "", fmt.Errorf("%s: %s", resp.Status, out.Error.Message)
+			}
+			if len(out.Errors) > 0 {
+				return nil, "", fmt.Errorf("%s: %s", resp.Status, strings.Join(out.Errors, "; "))
+			}
+		}
+		return nil, "", fmt.Errorf("%s", resp.Status)
+	}
+	mediaType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	return data, strings.TrimSpace(mediaType), nil
+}
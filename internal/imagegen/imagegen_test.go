@@ -0,0 +1,162 @@
+package imagegen
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+var png = []byte("\x89PNG\r\n\x1a\nimage")
+
+func TestOpenAI(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/images/generations" && r.Header.Get("Authorization") == "Bearer sk-test":
+			json.NewDecoder(r.Body).Decode(&got)
+			if got["prompt"] == "by url" {
+				json.NewEncoder(w).Encode(map[string]any{"data": []map[string]string{{"url": "http://" + r.Host + "/files/1.png"}}})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"data": []map[string]string{{"b64_json": base64.StdEncoding.EncodeToString(png), "revised_prompt": "A red fox, watercolour"}}})
+		case r.URL.Path == "/files/1.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"Incorrect API key"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	gen, err := New(config.ImageConfig{Provider: "openai", APIKey: "sk-test", BaseURL: srv.URL + "/v1/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := gen.Generate(context.Background(), "a fox", Landscape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(img.Data) != string(png) || img.RevisedPrompt != "A red fox, watercolour" {
+		t.Errorf("image = %+v", img)
+	}
+	if got["model"] != DefaultOpenAIModel || got["size"] != "1536x1024" || got["response_format"] != nil {
+		t.Errorf("request = %v", got)
+	}
+
+	gen, _ = New(config.ImageConfig{Provider: "openai", APIKey: "sk-test", BaseURL: srv.URL + "/v1", Model: "dall-e-3"})
+	img, err = gen.Generate(context.Background(), "by url", Portrait)
+	if err != nil || string(img.Data) != string(png) {
+		t.Fatalf("by url = %+v, %v", img, err)
+	}
+	if got["size"] != "1024x1792" || got["response_format"] != "b64_json" {
+		t.Errorf("dall-e-3 request = %v", got)
+	}
+
+	gen, _ = New(config.ImageConfig{Provider: "openai", APIKey: "wrong", BaseURL: srv.URL + "/v1"})
+	if _, err := gen.Generate(context.Background(), "a fox", Square); err == nil || !strings.Contains(err.Error(), "Incorrect API key") {
+		t.Errorf("bad key error = %v", err)
+	}
+}
+
+func TestStability(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2beta/stable-image/generate/core" || r.Header.Get("Accept") != "image/*" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.FormValue("prompt") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"name":"bad_request","errors":["prompt: cannot be empty"]}`))
+			return
+		}
+		if r.FormValue("aspect_ratio") != "9:16" || r.FormValue("output_format") != "png" {
+			t.Errorf("form = %v", r.Form)
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	}))
+	defer srv.Close()
+
+	gen, err := New(config.ImageConfig{Provider: "stability", APIKey: "sk-st", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := gen.Generate(context.Background(), "a lighthouse", Portrait)
+	if err != nil || string(img.Data) != string(png) || img.MediaType != "image/png" {
+		t.Errorf("Generate = %+v, %v", img, err)
+	}
+	if _, err := gen.Generate(context.Background(), "", Portrait); err == nil || !strings.Contains(err.Error(), "cannot be empty") {
+		t.Errorf("bad request error = %v", err)
+	}
+
+	for _, cfg := range []config.ImageConfig{{Provider: "openai"}, {Provider: "stability"}, {Provider: "midjourney", APIKey: "k"}} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+	if gen, err := New(config.ImageConfig{}); gen != nil || err != nil {
+		t.Errorf("no provider = %v, %v", gen, err)
+	}
+}
+
+type fakeGenerator struct{ prompts []string }
+
+func (f *fakeGenerator) Name() string { return "fake" }
+
+func (f *fakeGenerator) Generate(_ context.Context, prompt, shape string) (*Image, error) {
+	f.prompts = append(f.prompts, prompt+"/"+shape)
+	return &Image{Data: png, MediaType: "image/png"}, nil
+}
+
+func TestGenerateTool(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "images")
+	gen := &fakeGenerator{}
+	tl := NewTool(gen, dir).(*generateTool)
+	tl.now = func() time.Time { return time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC) }
+
+	var sent []string
+	ctx := WithSink(context.Background(), func(path string) { sent = append(sent, path) })
+	params := map[string]any{"prompt": "A diagram of the TCP handshake!", "shape": "landscape"}
+	res, err := tl.Execute(ctx, params)
+	if err != nil || !res.Success {
+		t.Fatalf("Execute = %+v, %v", res, err)
+	}
+	want := filepath.Join(dir, "20260301-093000-a-diagram-of-the-tcp.png")
+	if len(sent) != 1 || sent[0] != want || !strings.Contains(res.Output, want) {
+		t.Errorf("sent = %q, output = %q", sent, res.Output)
+	}
+	if data, _ := os.ReadFile(want); string(data) != string(png) {
+		t.Errorf("saved %q", data)
+	}
+	if gen.prompts[0] != "A diagram of the TCP handshake!/landscape" {
+		t.Errorf("prompts = %q", gen.prompts)
+	}
+
+	// The same second and prompt does not overwrite the first image.
+	res, _ = tl.Execute(context.Background(), params)
+	if !strings.Contains(res.Output, "-tcp-2.png") || strings.Contains(res.Output, "with your reply") {
+		t.Errorf("second output = %q", res.Output)
+	}
+
+	for _, p := range []map[string]any{{}, {"prompt": "x", "shape": "round"}} {
+		if res, _ := tl.Execute(ctx, p); res.Success {
+			t.Errorf("%v: expected failure", p)
+		}
+	}
+}
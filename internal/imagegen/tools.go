@@ -0,0 +1,166 @@
+package imagegen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// Dir is where generated images are saved.
+func Dir(workspace string) string {
+	return filepath.Join(workspace, "images")
+}
+
+// Sink is told the file of each image generated during a run.
+type Sink func(path string)
+
+type sinkKey struct{}
+
+// WithSink makes sink hear of the images generated during runs with ctx:
+// the gateway sends them to the chat, the CLI says where they are.
+func WithSink(ctx context.Context, sink Sink) context.Context {
+	return context.WithValue(ctx, sinkKey{}, sink)
+}
+
+func sinkOf(ctx context.Context) Sink {
+	sink, _ := ctx.Value(sinkKey{}).(Sink)
+	return sink
+}
+
+// Tools returns image_generate for cfg, or nil when no provider is set.
+func Tools(cfg config.ImageConfig, workspace string) ([]tool.Tool, error) {
+	gen, err := New(cfg)
+	if err != nil || gen == nil {
+		return nil, err
+	}
+	return []tool.Tool{NewTool(gen, Dir(workspace))}, nil
+}
+
+// NewTool returns the image_generate tool, saving images in dir.
+func NewTool(gen Generator, dir string) tool.Tool {
+	return &generateTool{gen: gen, dir: dir, now: time.Now}
+}
+
+type generateTool struct {
+	gen Generator
+	dir string
+	now func() time.Time
+}
+
+func (t *generateTool) Name() string { return "image_generate" }
+
+func (t *generateTool) Description() string {
+	return "Draw an image from a description (" + t.gen.Name() + "): pictures, illustrations, logos, simple diagrams. " +
+		"Describe the subject, style and any text in detail. The image is saved to a file and shown to the user with your reply."
+}
+
+func (t *generateTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"prompt": map[string]any{"type": "string", "description": "What to draw, in detail"},
+			"shape": map[string]any{
+				"type":        "string",
+				"enum":        []string{Square, Landscape, Portrait},
+				"description": "Shape of the image (default square)",
+			},
+		},
+		Required: []string{"prompt"},
+	}
+}
+
+func (t *generateTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	prompt := stringParam(params, "prompt")
+	if prompt == "" {
+		return failed(errors.New("prompt is required")), nil
+	}
+	shape := stringParam(params, "shape")
+	switch shape {
+	case "":
+		shape = Square
+	case Square, Landscape, Portrait:
+	default:
+		return failed(fmt.Errorf("shape %q: want square, landscape or portrait", shape)), nil
+	}
+	img, err := t.gen.Generate(ctx, prompt, shape)
+	if err != nil {
+		return failed(err), nil
+	}
+	path, err := t.save(prompt, img)
+	if err != nil {
+		return failed(err), nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Image saved to %s.", path)
+	if sink := sinkOf(ctx); sink != nil {
+		sink(path)
+		sb.WriteString(" The user gets it with your reply.")
+	}
+	if img.RevisedPrompt != "" {
+		fmt.Fprintf(&sb, "\nDrawn from: %s", img.RevisedPrompt)
+	}
+	return &tool.ToolResult{Success: true, Output: sb.String(), Data: map[string]string{"path": path}}, nil
+}
+
+// save writes img to a file named after the time and the first words of
+// prompt.
+func (t *generateTool) save(prompt string, img *Image) (string, error) {
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return "", fmt.Errorf("save image: %w", err)
+	}
+	ext := ".png"
+	switch img.MediaType {
+	case "image/jpeg":
+		ext = ".jpg"
+	case "image/webp":
+		ext = ".webp"
+	}
+	name := t.now().Format("20060102-150405")
+	if s := slug(prompt); s != "" {
+		name += "-" + s
+	}
+	path := filepath.Join(t.dir, name+ext)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(t.dir, fmt.Sprintf("%s-%d%s", name, i, ext))
+	}
+	if err := os.WriteFile(path, img.Data, 0o644); err != nil {
+		return "", fmt.Errorf("save image: %w", err)
+	}
+	return path, nil
+}
+
+// slug is the first few words of s, lower case and joined by dashes.
+func slug(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 5 {
+		words = words[:5]
+	}
+	out := strings.Join(words, "-")
+	if r := []rune(out); len(r) > 40 {
+		out = strings.TrimRight(string(r[:40]), "-")
+	}
+	return out
+}
+
+func stringParam(params map[string]any, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+func failed(err error) *tool.ToolResult {
+	return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}
+}
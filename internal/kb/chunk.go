@@ -0,0 +1,111 @@
+package kb
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxChunkChars is about the size of a passage recalled into a prompt:
+	// long enough to carry an argument, short enough that several fit.
+	maxChunkChars = 1500
+	// minChunkChars keeps a short paragraph with the next one rather than
+	// indexing a line on its own.
+	minChunkChars = 200
+)
+
+type chunk struct {
+	heading, content string
+}
+
+// split cuts text, markdown or plain, into passages. A heading starts a new
+// passage and names the ones under it; sections longer than maxChunkChars
+// are split at paragraph breaks, and paragraphs longer than that at
+// sentence or word boundaries.
+func split(text string) []chunk {
+	var chunks []chunk
+	var heading string
+	var body strings.Builder
+
+	emit := func() {
+		content := strings.TrimSpace(body.String())
+		body.Reset()
+		if content != "" {
+			chunks = append(chunks, chunk{heading: heading, content: content})
+		}
+	}
+	add := func(para string) {
+		if body.Len() > 0 && body.Len()+len(para) > maxChunkChars {
+			emit()
+		}
+		if body.Len() > 0 {
+			body.WriteString("\n\n")
+		}
+		body.WriteString(para)
+		if body.Len() >= maxChunkChars-minChunkChars {
+			emit()
+		}
+	}
+
+	// Form feeds separate the pages of pdftotext output.
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\f", "\n\n")
+	var para []string
+	flush := func() {
+		p := strings.TrimSpace(strings.Join(para, "\n"))
+		para = para[:0]
+		for _, piece := range cut(p, maxChunkChars) {
+			add(piece)
+		}
+	}
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		switch {
+		case !inFence && isHeading(trimmed):
+			flush()
+			emit()
+			heading = strings.TrimSpace(strings.Trim(trimmed, "#"))
+		case !inFence && trimmed == "":
+			flush()
+		default:
+			para = append(para, line)
+		}
+	}
+	flush()
+	emit()
+	return chunks
+}
+
+// isHeading reports whether line is a markdown ATX heading.
+func isHeading(line string) bool {
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	return level >= 1 && level <= 6 && (len(line) == level || line[level] == ' ')
+}
+
+// cut splits s into pieces of at most max bytes, preferring to end them at
+// a sentence, then at a space.
+func cut(s string, max int) []string {
+	var pieces []string
+	for len(s) > max {
+		end := strings.LastIndexAny(s[:max], ".!?\n")
+		if end < max/2 {
+			end = strings.LastIndexFunc(s[:max], unicode.IsSpace)
+		}
+		if end < max/2 {
+			// One long word: cut it, but not inside a UTF-8 sequence.
+			end = max - 1
+			for end > 0 && s[end+1]&0xC0 == 0x80 {
+				end--
+			}
+		}
+		pieces = append(pieces, strings.TrimSpace(s[:end+1]))
+		s = strings.TrimSpace(s[end+1:])
+	}
+	if s != "" {
+		pieces = append(pieces, s)
+	}
+	return pieces
+}
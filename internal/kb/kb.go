@@ -0,0 +1,609 @@
+// Package kb is the personal knowledge base: documents and web pages the
+// user adds are split into passages, embedded and kept in a local index,
+// and the passages relevant to each message are recalled into its prompt.
+package kb
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/fetch"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	embedBatchSize = 64
+	// maxPageTokens is how much of a web page is indexed; the fetch
+	// tool's budget is for a prompt, not an index.
+	maxPageTokens = 200_000
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS documents (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	source  TEXT NOT NULL UNIQUE,
+	title   TEXT NOT NULL,
+	kind    TEXT NOT NULL,
+	hash    TEXT NOT NULL,
+	added   INTEGER NOT NULL,
+	indexed INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS chunks (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	doc_id   INTEGER NOT NULL,
+	heading  TEXT NOT NULL,
+	content  TEXT NOT NULL,
+	embedder TEXT NOT NULL,
+	vector   BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS chunks_doc ON chunks(doc_id);
+`
+
+// ErrNotFound is returned for a document that is not in the knowledge base.
+var ErrNotFound = errors.New("no such document in the knowledge base")
+
+// Document is an indexed source.
+type Document struct {
+	ID      int64     `json:"id"`
+	Source  string    `json:"source"` // absolute path or URL
+	Title   string    `json:"title"`
+	Kind    string    `json:"kind"`
+	Chunks  int       `json:"chunks"`
+	Added   time.Time `json:"added"`
+	Indexed time.Time `json:"indexed"`
+}
+
+// Indexed is a document Add or Reindex went over.
+type Indexed struct {
+	Document
+	// Unchanged is set when the source had not changed since it was
+	// last indexed, so nothing was embedded.
+	Unchanged bool `json:"unchanged,omitempty"`
+}
+
+// Result is a passage matching a query.
+type Result struct {
+	DocumentID int64   `json:"documentId"`
+	Source     string  `json:"source"`
+	Title      string  `json:"title"`
+	Heading    string  `json:"heading,omitempty"`
+	Content    string  `json:"content"`
+	Score      float64 `json:"score"`
+}
+
+// Store is the knowledge base index, an SQLite file.
+type Store struct {
+	db       *sql.DB
+	embedder memory.Embedder
+	fetcher  *fetch.Fetcher // nil: web pages cannot be added
+	now      func() time.Time
+}
+
+// Path is where the index of workspace lives.
+func Path(workspace string) string {
+	return filepath.Join(workspace, "kb", "index.db")
+}
+
+// Open opens (and creates if needed) the index at path. Passages are
+// embedded with embedder and web pages read with fetcher.
+func Open(path string, embedder memory.Embedder, fetcher *fetch.Fetcher) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("open knowledge base: %w", err)
+	}
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", filepath.ToSlash(path))
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open knowledge base: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("init knowledge base: %w", err)
+	}
+	return &Store{db: db, embedder: embedder, fetcher: fetcher, now: time.Now}, nil
+}
+
+// OpenConfigured opens the knowledge base of cfg's workspace with the
+// memory.embedding embedder, reading web pages within tools.fetch.
+func OpenConfigured(cfg *config.Config) (*Store, error) {
+	embedder, err := memory.NewEmbedder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	fc := cfg.Tools.Fetch
+	fc.MaxTokens = maxPageTokens
+	return Open(Path(cfg.Agent.Workspace), embedder, fetch.New(fc))
+}
+
+// Exists reports whether workspace has a knowledge base, so that callers
+// recalling from it need not create an empty one.
+func Exists(workspace string) bool {
+	_, err := os.Stat(Path(workspace))
+	return err == nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add indexes source: a file, every file of a known kind under a
+// directory, or a web page. Sources already in the index are read again
+// and re-embedded when they changed. With a directory, files that fail are
+// reported in the error while the others are still indexed.
+func (s *Store) Add(ctx context.Context, source string) ([]Indexed, error) {
+	if isURL(source) {
+		doc, err := loadURL(ctx, s.fetcher, source)
+		if err != nil {
+			return nil, fmt.Errorf("add %s: %w", source, err)
+		}
+		ix, err := s.index(ctx, doc, false)
+		if err != nil {
+			return nil, err
+		}
+		return []Indexed{*ix}, nil
+	}
+
+	path, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("add %s: %w", source, err)
+	}
+	if !info.IsDir() {
+		ix, err := s.addFile(ctx, path, false)
+		if err != nil {
+			return nil, err
+		}
+		return []Indexed{*ix}, nil
+	}
+
+	var added []Indexed
+	var errs []error
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if p != path && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || kindOf(p) == "" {
+			return nil
+		}
+		ix, err := s.addFile(ctx, p, false)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			errs = append(errs, err)
+			return nil
+		}
+		added = append(added, *ix)
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(added) == 0 && len(errs) == 0 {
+		return nil, fmt.Errorf("add %s: no PDF, markdown, HTML or text files in it", source)
+	}
+	return added, errors.Join(errs...)
+}
+
+func (s *Store) addFile(ctx context.Context, path string, force bool) (*Indexed, error) {
+	doc, err := loadFile(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("add %s: %w", path, err)
+	}
+	return s.index(ctx, doc, force)
+}
+
+// index splits and embeds doc and replaces its passages in the index,
+// unless it is unchanged and force is not set.
+func (s *Store) index(ctx context.Context, doc *document, force bool) (*Indexed, error) {
+	if strings.TrimSpace(doc.text) == "" {
+		hint := ""
+		if doc.kind == KindPDF {
+			hint = " (a scanned PDF needs OCR first)"
+		}
+		return nil, fmt.Errorf("add %s: no text found%s", doc.source, hint)
+	}
+	sum := sha256.Sum256([]byte(doc.text))
+	hash := hex.EncodeToString(sum[:])
+	name := s.embedder.Name()
+
+	if !force {
+		existing, err := s.find(ctx, doc.source)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if existing != nil && existing.hash == hash {
+			var stale bool
+			err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM chunks WHERE doc_id = ? AND embedder != ?)`, existing.ID, name).Scan(&stale)
+			if err != nil {
+				return nil, fmt.Errorf("read knowledge base: %w", err)
+			}
+			if !stale {
+				return &Indexed{Document: existing.Document, Unchanged: true}, nil
+			}
+		}
+	}
+
+	chunks := split(doc.text)
+	vectors := make([][]float32, 0, len(chunks))
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		batch := chunks[start:min(start+embedBatchSize, len(chunks))]
+		texts := make([]string, len(batch))
+		for i, c := range batch {
+			texts[i] = embedText(doc.title, c.heading, c.content)
+		}
+		vs, err := s.embedder.Embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("add %s: %w", doc.source, err)
+		}
+		vectors = append(vectors, vs...)
+	}
+
+	now := s.now().UTC()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("write knowledge base: %w", err)
+	}
+	defer tx.Rollback()
+	var id int64
+	added := now
+	var addedUnix int64
+	switch err := tx.QueryRowContext(ctx, `SELECT id, added FROM documents WHERE source = ?`, doc.source).Scan(&id, &addedUnix); {
+	case errors.Is(err, sql.ErrNoRows):
+		res, err := tx.ExecContext(ctx, `INSERT INTO documents (source, title, kind, hash, added, indexed) VALUES (?, ?, ?, ?, ?, ?)`,
+			doc.source, doc.title, doc.kind, hash, now.Unix(), now.Unix())
+		if err != nil {
+			return nil, fmt.Errorf("write knowledge base: %w", err)
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("write knowledge base: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("write knowledge base: %w", err)
+	default:
+		added = time.Unix(addedUnix, 0).UTC()
+		_, err := tx.ExecContext(ctx, `UPDATE documents SET title = ?, kind = ?, hash = ?, indexed = ? WHERE id = ?`,
+			doc.title, doc.kind, hash, now.Unix(), id)
+		if err != nil {
+			return nil, fmt.Errorf("write knowledge base: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM chunks WHERE doc_id = ?`, id); err != nil {
+			return nil, fmt.Errorf("write knowledge base: %w", err)
+		}
+	}
+	for i, c := range chunks {
+		_, err := tx.ExecContext(ctx, `INSERT INTO chunks (doc_id, heading, content, embedder, vector) VALUES (?, ?, ?, ?, ?)`,
+			id, c.heading, c.content, name, encodeVector(vectors[i]))
+		if err != nil {
+			return nil, fmt.Errorf("write knowledge base: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("write knowledge base: %w", err)
+	}
+	return &Indexed{Document: Document{
+		ID: id, Source: doc.source, Title: doc.title, Kind: doc.kind,
+		Chunks: len(chunks), Added: added, Indexed: now,
+	}}, nil
+}
+
+// embedText is what a passage is embedded as: with the document and
+// section it is from, which often carry what it is about.
+func embedText(title, heading, content string) string {
+	var sb strings.Builder
+	sb.WriteString(title)
+	if heading != "" {
+		sb.WriteString(" > " + heading)
+	}
+	sb.WriteString("\n" + content)
+	return sb.String()
+}
+
+const documentColumns = `d.id, d.source, d.title, d.kind, d.hash, d.added, d.indexed, (SELECT COUNT(*) FROM chunks c WHERE c.doc_id = d.id)`
+
+type storedDocument struct {
+	Document
+	hash string
+}
+
+func scanDocument(row interface{ Scan(...any) error }) (*storedDocument, error) {
+	var d storedDocument
+	var added, indexed int64
+	if err := row.Scan(&d.ID, &d.Source, &d.Title, &d.Kind, &d.hash, &added, &indexed, &d.Chunks); err != nil {
+		return nil, err
+	}
+	d.Added, d.Indexed = time.Unix(added, 0).UTC(), time.Unix(indexed, 0).UTC()
+	return &d, nil
+}
+
+// find returns the document ref names: its id, its URL or a path to it.
+func (s *Store) find(ctx context.Context, ref string) (*storedDocument, error) {
+	query := `SELECT ` + documentColumns + ` FROM documents d WHERE d.source = ?`
+	arg := any(ref)
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		query, arg = `SELECT `+documentColumns+` FROM documents d WHERE d.id = ?`, id
+	} else if !isURL(ref) {
+		if abs, err := filepath.Abs(ref); err == nil {
+			arg = abs
+		}
+	}
+	d, err := scanDocument(s.db.QueryRowContext(ctx, query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", ref, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read knowledge base: %w", err)
+	}
+	return d, nil
+}
+
+// List returns the indexed documents, most recently added first.
+func (s *Store) List(ctx context.Context) ([]Document, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+documentColumns+` FROM documents d ORDER BY d.added DESC, d.id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("read knowledge base: %w", err)
+	}
+	defer rows.Close()
+	var docs []Document
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("read knowledge base: %w", err)
+		}
+		docs = append(docs, d.Document)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read knowledge base: %w", err)
+	}
+	return docs, nil
+}
+
+// Remove drops the document ref names (see Reindex) and its passages.
+func (s *Store) Remove(ctx context.Context, ref string) (*Document, error) {
+	d, err := s.find(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("write knowledge base: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chunks WHERE doc_id = ?`, d.ID); err != nil {
+		return nil, fmt.Errorf("write knowledge base: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM documents WHERE id = ?`, d.ID); err != nil {
+		return nil, fmt.Errorf("write knowledge base: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("write knowledge base: %w", err)
+	}
+	return &d.Document, nil
+}
+
+// Reindex reads the documents refs name again, by id, URL or path, and
+// re-embeds them whether or not they changed; with no refs it reindexes
+// them all. A document that fails is reported in the error while the
+// others are still reindexed.
+func (s *Store) Reindex(ctx context.Context, refs ...string) ([]Indexed, error) {
+	var sources []string
+	if len(refs) == 0 {
+		docs, err := s.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range docs {
+			sources = append(sources, d.Source)
+		}
+	}
+	for _, ref := range refs {
+		d, err := s.find(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, d.Source)
+	}
+
+	var done []Indexed
+	var errs []error
+	for _, source := range sources {
+		var ix *Indexed
+		var err error
+		if isURL(source) {
+			var doc *document
+			if doc, err = loadURL(ctx, s.fetcher, source); err == nil {
+				ix, err = s.index(ctx, doc, true)
+			} else {
+				err = fmt.Errorf("reindex %s: %w", source, err)
+			}
+		} else {
+			ix, err = s.addFile(ctx, source, true)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return done, ctx.Err()
+			}
+			errs = append(errs, err)
+			continue
+		}
+		done = append(done, *ix)
+	}
+	return done, errors.Join(errs...)
+}
+
+// Search returns the limit passages closest to query (all when limit is
+// 0), leaving out those scoring under minScore. Passages embedded with
+// another embedder than the store's are embedded again first.
+func (s *Store) Search(ctx context.Context, query string, limit int, minScore float64) ([]Result, error) {
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	q := vectors[0]
+
+	rows, err := s.db.QueryContext(ctx, `SELECT d.id, d.source, d.title, c.heading, c.content, c.vector
+		FROM chunks c JOIN documents d ON d.id = c.doc_id WHERE c.embedder = ?`, s.embedder.Name())
+	if err != nil {
+		return nil, fmt.Errorf("search knowledge base: %w", err)
+	}
+	defer rows.Close()
+	var results []Result
+	for rows.Next() {
+		var r Result
+		var blob []byte
+		if err := rows.Scan(&r.DocumentID, &r.Source, &r.Title, &r.Heading, &r.Content, &blob); err != nil {
+			return nil, fmt.Errorf("search knowledge base: %w", err)
+		}
+		if r.Score = cosine(q, decodeVector(blob)); r.Score > 0 && r.Score >= minScore {
+			results = append(results, r)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search knowledge base: %w", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// refresh re-embeds the passages stored with another embedder, after
+// memory.embedding changed. Their text is in the index, so the sources are
+// not read again.
+func (s *Store) refresh(ctx context.Context) error {
+	name := s.embedder.Name()
+	rows, err := s.db.QueryContext(ctx, `SELECT c.id, d.title, c.heading, c.content
+		FROM chunks c JOIN documents d ON d.id = c.doc_id WHERE c.embedder != ?`, name)
+	if err != nil {
+		return fmt.Errorf("search knowledge base: %w", err)
+	}
+	var ids []int64
+	var texts []string
+	for rows.Next() {
+		var id int64
+		var title, heading, content string
+		if err := rows.Scan(&id, &title, &heading, &content); err != nil {
+			rows.Close()
+			return fmt.Errorf("search knowledge base: %w", err)
+		}
+		ids = append(ids, id)
+		texts = append(texts, embedText(title, heading, content))
+	}
+	rows.Close()
+
+	for start := 0; start < len(ids); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(ids))
+		vectors, err := s.embedder.Embed(ctx, texts[start:end])
+		if err != nil {
+			return err
+		}
+		for i, id := range ids[start:end] {
+			if _, err := s.db.ExecContext(ctx, `UPDATE chunks SET embedder = ?, vector = ? WHERE id = ?`, name, encodeVector(vectors[i]), id); err != nil {
+				return fmt.Errorf("search knowledge base: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Recall returns the passages relevant to prompt, formatted for the model,
+// or "" when nothing matches. An empty knowledge base is not searched, so
+// the prompt is not sent to the embedder for nothing.
+func (s *Store) Recall(ctx context.Context, prompt string, limit int, minScore float64) (string, error) {
+	if strings.TrimSpace(prompt) == "" {
+		return "", nil
+	}
+	var indexed bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM chunks)`).Scan(&indexed); err != nil || !indexed {
+		return "", err
+	}
+	results, err := s.Search(ctx, prompt, limit, minScore)
+	if err != nil || len(results) == 0 {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("<knowledge>\nPassages from the user's knowledge base that may be relevant:\n\n")
+	for _, r := range results {
+		label := r.Title
+		if r.Heading != "" && r.Heading != r.Title {
+			label += " > " + r.Heading
+		}
+		fmt.Fprintf(&sb, "[%s] (%s)\n%s\n\n", label, r.Source, r.Content)
+	}
+	sb.WriteString("</knowledge>\n\n")
+	return sb.String(), nil
+}
+
+// Prefix is Recall for a prompt about to be sent: errors are logged and
+// give "", so a failing embedder never blocks a conversation.
+func (s *Store) Prefix(ctx context.Context, prompt string, limit int, minScore float64) string {
+	recalled, err := s.Recall(ctx, prompt, limit, minScore)
+	if err != nil {
+		log.Printf("[kb] recall error: %v", err)
+		return ""
+	}
+	return recalled
+}
+
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,187 @@
+package kb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/fetch"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+)
+
+func TestSplit(t *testing.T) {
+	text := "Intro paragraph.\n\n# Setup\nInstall the tool.\n\nThen run it.\n\n```\n# not a heading\n```\n## Usage\nCall it.\n"
+	chunks := split(text)
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	if chunks[0].heading != "" || chunks[0].content != "Intro paragraph." {
+		t.Errorf("first = %+v", chunks[0])
+	}
+	if chunks[1].heading != "Setup" || !strings.Contains(chunks[1].content, "Then run it.") || !strings.Contains(chunks[1].content, "# not a heading") {
+		t.Errorf("second = %+v", chunks[1])
+	}
+	if chunks[2].heading != "Usage" || chunks[2].content != "Call it." {
+		t.Errorf("third = %+v", chunks[2])
+	}
+
+	// A long section is cut into passages no longer than maxChunkChars,
+	// long paragraphs at sentence ends.
+	sentence := "The quick brown fox jumps over the lazy dog. "
+	long := strings.Repeat(sentence, 100) + "\f" + strings.Repeat("word ", 50)
+	chunks = split(long)
+	if len(chunks) < 3 {
+		t.Fatalf("long text in %d chunks", len(chunks))
+	}
+	for _, c := range chunks {
+		if len(c.content) > maxChunkChars {
+			t.Errorf("chunk of %d chars", len(c.content))
+		}
+	}
+	if !strings.HasSuffix(chunks[0].content, "dog.") {
+		t.Errorf("first chunk ends %q", chunks[0].content[len(chunks[0].content)-20:])
+	}
+
+	if got := cut(strings.Repeat("é", 1000), 101); len(got) < 2 || !strings.HasPrefix(got[1], "é") {
+		t.Errorf("cut split a rune: %q", got)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	ctx := context.Background()
+
+	doc, err := loadFile(ctx, write("notes.md", "Some front matter\n# Trip planning\nBook the ferry.\n"))
+	if err != nil || doc.kind != KindMarkdown || doc.title != "Trip planning" {
+		t.Errorf("markdown = %+v, %v", doc, err)
+	}
+
+	page := `<html><head><title>Warranty</title></head><body><nav>Home</nav><main><h1>Terms</h1><p>Two years on parts.</p></main></body></html>`
+	doc, err = loadFile(ctx, write("warranty.html", page))
+	if err != nil || doc.title != "Warranty" || !strings.Contains(doc.text, "Two years on parts.") || strings.Contains(doc.text, "Home") {
+		t.Errorf("html = %+v, %v", doc, err)
+	}
+
+	orig := pdfToText
+	defer func() { pdfToText = orig }()
+	pdfToText = func(_ context.Context, path string) (string, error) { return "Page one.\fPage two.", nil }
+	doc, err = loadFile(ctx, write("Manual.PDF", "%PDF-1.4"))
+	if err != nil || doc.kind != KindPDF || doc.title != "Manual" || doc.text != "Page one.\fPage two." {
+		t.Errorf("pdf = %+v, %v", doc, err)
+	}
+
+	if _, err := loadFile(ctx, write("photo.jpg", "x")); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("jpg error = %v", err)
+	}
+}
+
+func TestLoadURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Release notes</title></head><body><article><p>Version 2 adds sync.</p></article></body></html>`))
+	}))
+	defer srv.Close()
+
+	doc, err := loadURL(context.Background(), fetch.New(config.FetchConfig{AllowPrivate: true}), srv.URL+"/notes")
+	if err != nil || doc.kind != KindURL || doc.title != "Release notes" || !strings.Contains(doc.text, "Version 2 adds sync.") {
+		t.Errorf("url = %+v, %v", doc, err)
+	}
+	if _, err := loadURL(context.Background(), nil, srv.URL); err == nil {
+		t.Error("expected an error without a fetcher")
+	}
+}
+
+// countingEmbedder records how many texts it was asked to embed.
+type countingEmbedder struct {
+	*memory.HashEmbedder
+	embedded int
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	c.embedded += len(texts)
+	return c.HashEmbedder.Embed(ctx, texts)
+}
+
+func TestStore_SQLite(t *testing.T) {
+	ctx := context.Background()
+	docs := t.TempDir()
+	os.WriteFile(filepath.Join(docs, "boiler.md"), []byte("# Boiler\n\n## Service\nThe boiler is serviced every October by Hearth & Co.\n\n## Pressure\nKeep the pressure between 1 and 1.5 bar.\n"), 0o644)
+	os.MkdirAll(filepath.Join(docs, "cars"), 0o755)
+	os.WriteFile(filepath.Join(docs, "cars", "insurance.txt"), []byte("Car insurance renews in March with Acme Mutual."), 0o644)
+	os.WriteFile(filepath.Join(docs, "cars", "photo.jpg"), []byte("x"), 0o644)
+	os.MkdirAll(filepath.Join(docs, ".git"), 0o755)
+	os.WriteFile(filepath.Join(docs, ".git", "HEAD.txt"), []byte("ref: main"), 0o644)
+
+	emb := &countingEmbedder{HashEmbedder: memory.NewHashEmbedder(0)}
+	store, err := Open(filepath.Join(t.TempDir(), "kb", "index.db"), emb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if recalled, err := store.Recall(ctx, "boiler pressure", 4, 0); err != nil || recalled != "" || emb.embedded != 0 {
+		t.Errorf("empty Recall = %q, %v (embedded %d)", recalled, err, emb.embedded)
+	}
+
+	added, err := store.Add(ctx, docs)
+	if err != nil || len(added) != 2 {
+		t.Fatalf("Add = %+v, %v", added, err)
+	}
+	list, err := store.List(ctx)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %+v, %v", list, err)
+	}
+
+	results, err := store.Search(ctx, "what pressure should the boiler be at", 1, 0)
+	if err != nil || len(results) != 1 || results[0].Heading != "Pressure" || results[0].Title != "Boiler" {
+		t.Fatalf("Search = %+v, %v", results, err)
+	}
+	recalled, err := store.Recall(ctx, "when does the car insurance renew", 1, 0.1)
+	if err != nil || !strings.Contains(recalled, "<knowledge>") || !strings.Contains(recalled, "Acme Mutual") {
+		t.Errorf("Recall = %q, %v", recalled, err)
+	}
+
+	// Adding again embeds nothing; a changed file is re-embedded.
+	emb.embedded = 0
+	again, err := store.Add(ctx, filepath.Join(docs, "boiler.md"))
+	if err != nil || !again[0].Unchanged || emb.embedded != 0 {
+		t.Errorf("re-add = %+v, %v (embedded %d)", again, err, emb.embedded)
+	}
+	os.WriteFile(filepath.Join(docs, "boiler.md"), []byte("# Boiler\nReplaced in 2025.\n"), 0o644)
+	again, err = store.Add(ctx, filepath.Join(docs, "boiler.md"))
+	if err != nil || again[0].Unchanged || again[0].Chunks != 1 || again[0].ID != added[0].ID && again[0].ID != added[1].ID {
+		t.Errorf("changed re-add = %+v, %v", again, err)
+	}
+
+	reindexed, err := store.Reindex(ctx)
+	if err != nil || len(reindexed) != 2 || reindexed[0].Unchanged {
+		t.Errorf("Reindex = %+v, %v", reindexed, err)
+	}
+
+	removed, err := store.Remove(ctx, filepath.Join(docs, "cars", "insurance.txt"))
+	if err != nil || removed.Title != "insurance" {
+		t.Fatalf("Remove = %+v, %v", removed, err)
+	}
+	if _, err := store.Remove(ctx, "999"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Remove unknown = %v", err)
+	}
+	if list, _ := store.List(ctx); len(list) != 1 || list[0].Title != "Boiler" {
+		t.Errorf("after remove = %+v", list)
+	}
+	if results, _ := store.Search(ctx, "car insurance Acme", 0, 0); len(results) != 0 {
+		t.Errorf("removed passages found: %+v", results)
+	}
+}
@@ -0,0 +1,136 @@
+package kb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/stellarlinkco/myclaw/internal/fetch"
+	"golang.org/x/net/html"
+)
+
+// Kinds of document the knowledge base reads.
+const (
+	KindPDF      = "pdf"
+	KindMarkdown = "markdown"
+	KindHTML     = "html"
+	KindText     = "text"
+	KindURL      = "url"
+)
+
+// maxFileBytes bounds a text, markdown or HTML file.
+const maxFileBytes = 20 << 20
+
+// document is a source read for indexing.
+type document struct {
+	source, title, kind, text string
+}
+
+// kindOf returns the kind of the file at path by its extension, or "" when
+// it is not one the knowledge base reads.
+func kindOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		return KindPDF
+	case ".md", ".markdown":
+		return KindMarkdown
+	case ".html", ".htm":
+		return KindHTML
+	case ".txt", ".text", ".rst", ".org":
+		return KindText
+	}
+	return ""
+}
+
+// isURL reports whether source is a web page rather than a path.
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// loadURL reads a web page as markdown.
+func loadURL(ctx context.Context, f *fetch.Fetcher, rawURL string) (*document, error) {
+	if f == nil {
+		return nil, errors.New("web pages cannot be added here")
+	}
+	page, err := f.Fetch(ctx, rawURL, 0)
+	if err != nil {
+		return nil, err
+	}
+	title := page.Title
+	if title == "" {
+		title = page.URL
+	}
+	return &document{source: rawURL, title: title, kind: KindURL, text: page.Markdown}, nil
+}
+
+// loadFile reads the file at path, an absolute path of a kind kindOf knows.
+func loadFile(ctx context.Context, path string) (*document, error) {
+	doc := &document{source: path, kind: kindOf(path), title: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	if doc.kind == "" {
+		return nil, fmt.Errorf("%s: unsupported file type; want PDF, markdown, HTML or text", path)
+	}
+	if doc.kind == KindPDF {
+		text, err := pdfToText(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		doc.text = text
+		return doc, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxFileBytes {
+		return nil, fmt.Errorf("%s: over %d MB", path, maxFileBytes>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch doc.kind {
+	case KindHTML:
+		node, err := html.Parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		title, md := fetch.Markdown(node, nil)
+		if title != "" {
+			doc.title = title
+		}
+		doc.text = md
+	case KindMarkdown:
+		doc.text = string(data)
+		for _, line := range strings.SplitN(doc.text, "\n", 20) {
+			if t := strings.TrimSpace(line); strings.HasPrefix(t, "# ") {
+				doc.title = strings.TrimSpace(t[2:])
+				break
+			}
+		}
+	default:
+		doc.text = string(data)
+	}
+	return doc, nil
+}
+
+// pdfToText extracts the text of a PDF with pdftotext from poppler.
+var pdfToText = func(ctx context.Context, path string) (string, error) {
+	bin, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return "", errors.New("reading PDFs needs pdftotext; install poppler-utils (poppler on macOS)")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, "-enc", "UTF-8", path, "-")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: pdftotext: %v %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,135 @@
+// Package keys decodes key presses from a terminal in raw mode.
+package keys
+
+import (
+	"unicode/utf8"
+)
+
+// Type is a kind of key. Typed characters are Rune.
+type Type int
+
+const (
+	Rune Type = iota
+	Enter
+	Backspace
+	Delete
+	Tab
+	Esc
+	Up
+	Down
+	Left
+	Right
+	Home
+	End
+	PgUp
+	PgDown
+	CtrlC
+	CtrlD
+	CtrlN
+	CtrlO
+	CtrlS
+	CtrlU
+	CtrlL
+	CtrlK
+	CtrlR
+	CtrlW
+	CtrlG
+)
+
+// Key is one key press.
+type Key struct {
+	Type Type
+	Rune rune // for Rune
+}
+
+var controlKeys = map[byte]Type{
+	0x01: Home, // Ctrl-A
+	0x02: Left, // Ctrl-B
+	0x03: CtrlC,
+	0x04: CtrlD,
+	0x05: End,   // Ctrl-E
+	0x06: Right, // Ctrl-F
+	0x07: CtrlG,
+	0x08: Backspace,
+	0x09: Tab,
+	0x0a: Enter,
+	0x0b: CtrlK,
+	0x0c: CtrlL,
+	0x0d: Enter,
+	0x0e: CtrlN,
+	0x0f: CtrlO,
+	0x10: Up, // Ctrl-P
+	0x12: CtrlR,
+	0x13: CtrlS,
+	0x15: CtrlU,
+	0x17: CtrlW,
+	0x7f: Backspace,
+}
+
+// escapeKeys are the CSI and SS3 sequences of xterm-like terminals, after
+// the leading ESC.
+var escapeKeys = map[string]Type{
+	"[A": Up, "[B": Down, "[C": Right, "[D": Left,
+	"OA": Up, "OB": Down, "OC": Right, "OD": Left,
+	"[H": Home, "[F": End, "OH": Home, "OF": End,
+	"[1~": Home, "[7~": Home, "[4~": End, "[8~": End,
+	"[3~": Delete, "[5~": PgUp, "[6~": PgDown,
+	"[Z": Tab,
+}
+
+// Parse decodes what one read from a raw terminal returned. An ESC
+// that starts no known sequence is the Esc key; other unknown sequences
+// are dropped.
+func Parse(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		c := b[0]
+		if c == 0x1b {
+			n, key, ok := parseEscape(b[1:])
+			if ok {
+				keys = append(keys, Key{Type: key})
+			} else if n == 0 {
+				keys = append(keys, Key{Type: Esc})
+			}
+			b = b[1+n:]
+			continue
+		}
+		if t, ok := controlKeys[c]; ok {
+			// A pasted CRLF is one Enter.
+			if c == '\r' && len(b) > 1 && b[1] == '\n' {
+				b = b[1:]
+			}
+			keys = append(keys, Key{Type: t})
+			b = b[1:]
+			continue
+		}
+		if c < 0x20 {
+			b = b[1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(b)
+		keys = append(keys, Key{Type: Rune, Rune: r})
+		b = b[size:]
+	}
+	return keys
+}
+
+// parseEscape reads the sequence after an ESC. It returns how many bytes
+// the sequence used, and the key when it is one it knows.
+func parseEscape(b []byte) (int, Type, bool) {
+	if len(b) == 0 || (b[0] != '[' && b[0] != 'O') {
+		return 0, 0, false
+	}
+	// A CSI sequence ends with a byte in 0x40-0x7e; SS3 is one byte long.
+	end := 1
+	if b[0] == '[' {
+		for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+			end++
+		}
+	}
+	if end >= len(b) {
+		return len(b), 0, false
+	}
+	key, ok := escapeKeys[string(b[:end+1])]
+	return end + 1, key, ok
+}
@@ -0,0 +1,28 @@
+package keys
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Key
+	}{
+		{"hi", []Key{{Type: Rune, Rune: 'h'}, {Type: Rune, Rune: 'i'}}},
+		{"你", []Key{{Type: Rune, Rune: '你'}}},
+		{"\r\n", []Key{{Type: Enter}}},
+		{"\x7f\x03", []Key{{Type: Backspace}, {Type: CtrlC}}},
+		{"\x1b[A\x1bOB", []Key{{Type: Up}, {Type: Down}}},
+		{"\x1b[5~\x1b[3~", []Key{{Type: PgUp}, {Type: Delete}}},
+		{"\x1b", []Key{{Type: Esc}}},
+		{"\x1b[1;5Cx", []Key{{Type: Rune, Rune: 'x'}}}, // unknown sequence dropped
+		{"\x01\x05", []Key{{Type: Home}, {Type: End}}},
+	}
+	for _, tt := range tests {
+		if got := Parse([]byte(tt.in)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package mailbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/googleauth"
+	"golang.org/x/oauth2"
+)
+
+const (
+	gmailAPIURL = "https://gmail.googleapis.com/gmail/v1/users/me"
+	// GmailScope lets myclaw read, draft and send mail, not delete it.
+	GmailScope = "https://www.googleapis.com/auth/gmail.modify"
+
+	maxResponseBytes = 30 << 20
+)
+
+// ErrNotAuthorized is returned when Gmail is configured but nobody has
+// signed in with `myclaw auth gmail` yet.
+var ErrNotAuthorized = errors.New("not signed in to Gmail; run `myclaw auth gmail`")
+
+// GmailTokenPath is where the Gmail sign-in is kept.
+func GmailTokenPath() string {
+	return filepath.Join(config.DataDir(), "data", "mail", "gmail-token.json")
+}
+
+// GmailOAuth returns the OAuth client for cfg.
+func GmailOAuth(cfg config.GmailConfig) *oauth2.Config {
+	return googleauth.Config(cfg.ClientID, cfg.ClientSecret, GmailScope)
+}
+
+// gmail is a mailbox in Gmail. IDs are Gmail's message and draft IDs.
+type gmail struct {
+	client  *http.Client
+	baseURL string
+}
+
+func newGmail(cfg config.GmailConfig, tokenPath string) (*gmail, error) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil, errors.New("mail: gmail needs clientId and clientSecret")
+	}
+	client, err := googleauth.Client(GmailOAuth(cfg), tokenPath, httpClient)
+	if errors.Is(err, googleauth.ErrNoToken) {
+		err = ErrNotAuthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mail: %w", err)
+	}
+	return &gmail{client: client, baseURL: gmailAPIURL}, nil
+}
+
+func (g *gmail) Name() string { return "gmail" }
+
+// gmailMessage is a message as the Gmail API has it, in the metadata or
+// raw format.
+type gmailMessage struct {
+	ID           string   `json:"id,omitempty"`
+	ThreadID     string   `json:"threadId,omitempty"`
+	LabelIDs     []string `json:"labelIds,omitempty"`
+	InternalDate string   `json:"internalDate,omitempty"`
+	Raw          string   `json:"raw,omitempty"`
+	Payload      *struct {
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+	} `json:"payload,omitempty"`
+}
+
+type gmailDraft struct {
+	ID      string       `json:"id,omitempty"`
+	Message gmailMessage `json:"message"`
+}
+
+func (gm gmailMessage) unread() bool {
+	for _, label := range gm.LabelIDs {
+		if label == "UNREAD" {
+			return true
+		}
+	}
+	return false
+}
+
+func (gm gmailMessage) raw() ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(gm.Raw, "="))
+}
+
+func encodeRaw(raw []byte) string {
+	return base64.URLEncoding.EncodeToString(raw)
+}
+
+// gmailQuery turns q into Gmail's search syntax.
+func gmailQuery(q Query) string {
+	parts := []string{q.Text}
+	if q.From != "" {
+		parts = append(parts, "from:"+q.From)
+	}
+	if q.Unread {
+		parts = append(parts, "is:unread")
+	}
+	if !q.Since.IsZero() {
+		parts = append(parts, "after:"+q.Since.Format("2006/01/02"))
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+var gmailMetadataHeaders = []string{"From", "Reply-To", "To", "Cc", "Subject", "Date", "Message-ID", "References"}
+
+func (g *gmail) Search(ctx context.Context, q Query) ([]Message, error) {
+	v := url.Values{"maxResults": {strconv.Itoa(q.limit())}}
+	if query := gmailQuery(q); query != "" {
+		v.Set("q", query)
+	}
+	var list struct {
+		Messages []gmailMessage `json:"messages"`
+	}
+	if err := g.call(ctx, http.MethodGet, g.baseURL+"/messages?"+v.Encode(), nil, &list); err != nil {
+		return nil, err
+	}
+	meta := url.Values{"format": {"metadata"}, "metadataHeaders": gmailMetadataHeaders}
+	messages := make([]Message, 0, len(list.Messages))
+	for _, item := range list.Messages {
+		var gm gmailMessage
+		if err := g.call(ctx, http.MethodGet, g.baseURL+"/messages/"+url.PathEscape(item.ID)+"?"+meta.Encode(), nil, &gm); err != nil {
+			return nil, err
+		}
+		h := mail.Header{}
+		if gm.Payload != nil {
+			for _, header := range gm.Payload.Headers {
+				key := http.CanonicalHeaderKey(header.Name)
+				h[key] = append(h[key], header.Value)
+			}
+		}
+		msg := headerMessage(h)
+		msg.ID, msg.ThreadID, msg.Unread = gm.ID, gm.ThreadID, gm.unread()
+		if msg.Date.IsZero() {
+			if ms, err := strconv.ParseInt(gm.InternalDate, 10, 64); err == nil {
+				msg.Date = time.UnixMilli(ms)
+			}
+		}
+		messages = append(messages, msg)
+	}
+	sortMessages(messages)
+	return messages, nil
+}
+
+func (g *gmail) Read(ctx context.Context, id string) (Message, error) {
+	var gm gmailMessage
+	if err := g.call(ctx, http.MethodGet, g.baseURL+"/messages/"+url.PathEscape(id)+"?format=raw", nil, &gm); err != nil {
+		return Message{}, err
+	}
+	raw, err := gm.raw()
+	if err != nil {
+		return Message{}, fmt.Errorf("gmail: message %s: %w", id, err)
+	}
+	msg, err := parseMessage(raw)
+	msg.ID, msg.ThreadID, msg.Unread = gm.ID, gm.ThreadID, gm.unread()
+	return msg, err
+}
+
+func (g *gmail) SaveDraft(ctx context.Context, d Draft) (string, error) {
+	raw, err := buildMessage("", d)
+	if err != nil {
+		return "", err
+	}
+	in := gmailDraft{Message: gmailMessage{Raw: encodeRaw(raw), ThreadID: d.ThreadID}}
+	var out gmailDraft
+	if err := g.call(ctx, http.MethodPost, g.baseURL+"/drafts", in, &out); err != nil {
+		return "", err
+	}
+	return out.ID, nil
+}
+
+func (g *gmail) Draft(ctx context.Context, id string) (Draft, error) {
+	var out gmailDraft
+	if err := g.call(ctx, http.MethodGet, g.baseURL+"/drafts/"+url.PathEscape(id)+"?format=raw", nil, &out); err != nil {
+		return Draft{}, err
+	}
+	raw, err := out.Message.raw()
+	if err != nil {
+		return Draft{}, fmt.Errorf("gmail: draft %s: %w", id, err)
+	}
+	d, err := parseDraft(raw)
+	d.ID, d.ThreadID = out.ID, out.Message.ThreadID
+	return d, err
+}
+
+func (g *gmail) Send(ctx context.Context, d Draft) error {
+	raw, err := buildMessage("", d)
+	if err != nil {
+		return err
+	}
+	in := gmailMessage{Raw: encodeRaw(raw), ThreadID: d.ThreadID}
+	return g.call(ctx, http.MethodPost, g.baseURL+"/messages/send", in, &gmailMessage{})
+}
+
+func (g *gmail) SendDraft(ctx context.Context, id string) error {
+	return g.call(ctx, http.MethodPost, g.baseURL+"/drafts/send", gmailDraft{ID: id}, &gmailMessage{})
+}
+
+func (g *gmail) call(ctx context.Context, method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		if reason, ok := googleauth.Revoked(err); ok {
+			return fmt.Errorf("gmail: sign-in expired or revoked (%s); run `myclaw auth gmail`", reason)
+		}
+		return fmt.Errorf("gmail: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("gmail: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("gmail: %w", ErrNotFound)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			msg = apiErr.Error.Message
+		}
+		return fmt.Errorf("gmail: %s: %s", resp.Status, msg)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("gmail: decode response: %w", err)
+	}
+	return nil
+}
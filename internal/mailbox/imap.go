@@ -0,0 +1,303 @@
+package mailbox
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultIMAPPort = 993
+
+// maxIMAPLiteral bounds the size of one literal a server may send, so a
+// broken or hostile server cannot make the client allocate at will.
+const maxIMAPLiteral = 50 << 20
+
+var (
+	imapUIDRe   = regexp.MustCompile(`\bUID (\d+)`)
+	imapFlagsRe = regexp.MustCompile(`\bFLAGS \(([^)]*)\)`)
+)
+
+// IMAPConn is a minimal IMAP4rev1 client: just enough to log in, search a
+// mailbox, download messages, flag them and append new ones. It is kept
+// over go-imap so that literal sizes stay capped at maxIMAPLiteral.
+type IMAPConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapResponse is one untagged response line; literals ({n} blocks) are
+// collected separately in the order they appeared.
+type imapResponse struct {
+	text     string
+	literals [][]byte
+}
+
+// DialIMAP connects to host over TLS; port 0 means 993.
+func DialIMAP(ctx context.Context, host string, port int) (*IMAPConn, error) {
+	if port == 0 {
+		port = defaultIMAPPort
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 30 * time.Second}}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("dial imap: %w", err)
+	}
+	c, err := newIMAPConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func newIMAPConn(conn net.Conn) (*IMAPConn, error) {
+	c := &IMAPConn{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readLine()
+	if err != nil {
+		return nil, fmt.Errorf("read greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		return nil, fmt.Errorf("unexpected greeting: %s", greeting)
+	}
+	return c, nil
+}
+
+func (c *IMAPConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *IMAPConn) setDeadline(ctx context.Context) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+	} else {
+		c.conn.SetDeadline(time.Now().Add(time.Minute))
+	}
+}
+
+// send writes one command line and returns its tag. A command holding CR,
+// LF or NUL is refused: arguments come from quoted strings, and a line
+// break in one would end the command and start another.
+func (c *IMAPConn) send(ctx context.Context, cmd string) (string, error) {
+	if strings.ContainsAny(cmd, "\r\n\x00") {
+		verb, _, _ := strings.Cut(cmd, " ")
+		return "", fmt.Errorf("imap %s: argument contains a line break or NUL", verb)
+	}
+	c.setDeadline(ctx)
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, cmd); err != nil {
+		return "", err
+	}
+	return tag, nil
+}
+
+// command sends one command and reads until its tagged completion.
+func (c *IMAPConn) command(ctx context.Context, format string, args ...any) ([]imapResponse, error) {
+	cmd := fmt.Sprintf(format, args...)
+	tag, err := c.send(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return c.read(tag, cmd)
+}
+
+// read collects untagged responses until the completion for tag.
+func (c *IMAPConn) read(tag, cmd string) ([]imapResponse, error) {
+	verb, _, _ := strings.Cut(cmd, " ")
+	var responses []imapResponse
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		resp := imapResponse{text: line}
+		for {
+			n, ok := literalSize(line)
+			if !ok {
+				break
+			}
+			if n > maxIMAPLiteral {
+				return nil, fmt.Errorf("imap %s: literal of %d bytes is over the %d byte limit", verb, n, maxIMAPLiteral)
+			}
+			lit := make([]byte, n)
+			if _, err := io.ReadFull(c.r, lit); err != nil {
+				return nil, err
+			}
+			resp.literals = append(resp.literals, lit)
+			if line, err = c.readLine(); err != nil {
+				return nil, err
+			}
+			resp.text += " " + line
+		}
+
+		if rest, ok := strings.CutPrefix(resp.text, tag+" "); ok {
+			status, _, _ := strings.Cut(rest, " ")
+			if status != "OK" {
+				return nil, fmt.Errorf("imap %s: %s", verb, rest)
+			}
+			return responses, nil
+		}
+		responses = append(responses, resp)
+	}
+}
+
+// literalSize reports whether line ends with a literal marker like {123}.
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[open+1:len(line)-1], "+"))
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// IMAPQuote quotes s as an IMAP string. s must not hold CR, LF or NUL,
+// which a quoted string cannot carry; commands with them are refused.
+func IMAPQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func (c *IMAPConn) Login(ctx context.Context, user, pass string) error {
+	_, err := c.command(ctx, "LOGIN %s %s", IMAPQuote(user), IMAPQuote(pass))
+	return err
+}
+
+func (c *IMAPConn) Select(ctx context.Context, mailbox string) error {
+	_, err := c.command(ctx, "SELECT %s", IMAPQuote(mailbox))
+	return err
+}
+
+// Search returns the UIDs of the messages in the selected mailbox that
+// match criteria, such as "UNSEEN" or `FROM "bob"`, oldest first.
+func (c *IMAPConn) Search(ctx context.Context, criteria string) ([]uint32, error) {
+	responses, err := c.command(ctx, "UID SEARCH %s", criteria)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, resp := range responses {
+		rest, ok := strings.CutPrefix(resp.text, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(rest) {
+			if uid, err := strconv.ParseUint(field, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// Fetch downloads a full message without setting \Seen.
+func (c *IMAPConn) Fetch(ctx context.Context, uid uint32) ([]byte, error) {
+	responses, err := c.command(ctx, "UID FETCH %d (BODY.PEEK[])", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range responses {
+		if strings.Contains(resp.text, "FETCH") && len(resp.literals) > 0 {
+			return resp.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap fetch %d: no body returned", uid)
+}
+
+// IMAPHeader is the header of a message and its flags.
+type IMAPHeader struct {
+	UID    uint32
+	Flags  []string
+	Header []byte
+}
+
+// FetchHeaders downloads the headers and flags of the messages uids,
+// without setting \Seen.
+func (c *IMAPConn) FetchHeaders(ctx context.Context, uids []uint32) ([]IMAPHeader, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	set := make([]string, len(uids))
+	for i, uid := range uids {
+		set[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+	responses, err := c.command(ctx, "UID FETCH %s (UID FLAGS BODY.PEEK[HEADER])", strings.Join(set, ","))
+	if err != nil {
+		return nil, err
+	}
+	var headers []IMAPHeader
+	for _, resp := range responses {
+		m := imapUIDRe.FindStringSubmatch(resp.text)
+		if !strings.Contains(resp.text, "FETCH") || m == nil || len(resp.literals) == 0 {
+			continue
+		}
+		uid, _ := strconv.ParseUint(m[1], 10, 32)
+		h := IMAPHeader{UID: uint32(uid), Header: resp.literals[0]}
+		if f := imapFlagsRe.FindStringSubmatch(resp.text); f != nil {
+			h.Flags = strings.Fields(f[1])
+		}
+		headers = append(headers, h)
+	}
+	return headers, nil
+}
+
+func (c *IMAPConn) MarkSeen(ctx context.Context, uid uint32) error {
+	_, err := c.command(ctx, `UID STORE %d +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+// Delete flags a message \Deleted and expunges it.
+func (c *IMAPConn) Delete(ctx context.Context, uid uint32) error {
+	if _, err := c.command(ctx, `UID STORE %d +FLAGS.SILENT (\Deleted)`, uid); err != nil {
+		return err
+	}
+	_, err := c.command(ctx, "EXPUNGE")
+	return err
+}
+
+// Append adds msg to mailbox with flags, such as `\Draft`.
+func (c *IMAPConn) Append(ctx context.Context, mailbox, flags string, msg []byte) error {
+	cmd := fmt.Sprintf("APPEND %s (%s) {%d}", IMAPQuote(mailbox), flags, len(msg))
+	tag, err := c.send(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+") {
+		return fmt.Errorf("imap APPEND: %s", strings.TrimPrefix(line, tag+" "))
+	}
+	if _, err := c.conn.Write(msg); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(c.conn, "\r\n"); err != nil {
+		return err
+	}
+	_, err = c.read(tag, cmd)
+	return err
+}
+
+func (c *IMAPConn) Logout(ctx context.Context) {
+	c.command(ctx, "LOGOUT")
+	c.conn.Close()
+}
@@ -0,0 +1,152 @@
+package mailbox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeIMAPServer answers a scripted session over one connection.
+func fakeIMAPServer(t *testing.T, conn net.Conn, messages map[uint32]string) <-chan []string {
+	done := make(chan []string, 1)
+	go func() {
+		defer conn.Close()
+		var commands []string
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "* OK IMAP ready\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				done <- commands
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			tag, cmd, _ := strings.Cut(line, " ")
+			commands = append(commands, cmd)
+			switch {
+			case strings.HasPrefix(cmd, "LOGIN"):
+				if cmd != `LOGIN "bot@example.com" "p\"w"` {
+					fmt.Fprintf(conn, "%s NO bad credentials\r\n", tag)
+					continue
+				}
+			case strings.HasPrefix(cmd, "SELECT"):
+				fmt.Fprint(conn, "* 2 EXISTS\r\n")
+			case cmd == "UID SEARCH UNSEEN":
+				fmt.Fprint(conn, "* SEARCH 7 9\r\n")
+			case strings.HasPrefix(cmd, "UID FETCH"):
+				var uid uint32
+				fmt.Sscanf(cmd, "UID FETCH %d", &uid)
+				msg := messages[uid]
+				fmt.Fprintf(conn, "* 1 FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", uid, len(msg), msg)
+			case cmd == "LOGOUT":
+				fmt.Fprintf(conn, "* BYE\r\n%s OK bye\r\n", tag)
+				done <- commands
+				return
+			}
+			fmt.Fprintf(conn, "%s OK done\r\n", tag)
+		}
+	}()
+	return done
+}
+
+func TestIMAPConn_FetchUnseen(t *testing.T) {
+	client, server := net.Pipe()
+	messages := map[uint32]string{
+		7: "From: a@x\r\nSubject: one\r\n\r\nfirst {3}\r\n",
+		9: "From: b@x\r\nSubject: two\r\n\r\nsecond\r\n",
+	}
+	done := fakeIMAPServer(t, server, messages)
+
+	ctx := context.Background()
+	conn, err := newIMAPConn(client)
+	if err != nil {
+		t.Fatalf("newIMAPConn error: %v", err)
+	}
+	if err := conn.Login(ctx, "bot@example.com", `p"w`); err != nil {
+		t.Fatalf("login error: %v", err)
+	}
+	if err := conn.Select(ctx, "INBOX"); err != nil {
+		t.Fatalf("select error: %v", err)
+	}
+	uids, err := conn.Search(ctx, "UNSEEN")
+	if err != nil || len(uids) != 2 || uids[0] != 7 || uids[1] != 9 {
+		t.Fatalf("uids = %v, err = %v", uids, err)
+	}
+	for _, uid := range uids {
+		raw, err := conn.Fetch(ctx, uid)
+		if err != nil {
+			t.Fatalf("fetch %d error: %v", uid, err)
+		}
+		if string(raw) != messages[uid] {
+			t.Errorf("fetch %d = %q", uid, raw)
+		}
+		if err := conn.MarkSeen(ctx, uid); err != nil {
+			t.Fatalf("markSeen error: %v", err)
+		}
+	}
+	conn.Logout(ctx)
+
+	commands := <-done
+	if !contains(commands, `UID STORE 9 +FLAGS.SILENT (\Seen)`) || !contains(commands, "UID FETCH 7 (BODY.PEEK[])") {
+		t.Errorf("commands = %q", commands)
+	}
+}
+
+func TestIMAPConn_CommandError(t *testing.T) {
+	client, server := net.Pipe()
+	fakeIMAPServer(t, server, nil)
+	conn, err := newIMAPConn(client)
+	if err != nil {
+		t.Fatalf("newIMAPConn error: %v", err)
+	}
+	defer client.Close()
+	if err := conn.Login(context.Background(), "bot@example.com", "wrong"); err == nil || !strings.Contains(err.Error(), "bad credentials") {
+		t.Errorf("expected login failure, got %v", err)
+	}
+}
+
+func TestIMAPConn_Limits(t *testing.T) {
+	client, server := net.Pipe()
+	done := fakeIMAPServer(t, server, nil)
+	conn, err := newIMAPConn(client)
+	if err != nil {
+		t.Fatalf("newIMAPConn error: %v", err)
+	}
+	ctx := context.Background()
+	criteria := imapCriteria(Query{Text: "x\"\r\na2 DELETE \"INBOX"})
+	if _, err := conn.Search(ctx, criteria); err == nil || !strings.Contains(err.Error(), "line break") {
+		t.Errorf("search with a line break: %v", err)
+	}
+	conn.Logout(ctx)
+	if commands := <-done; len(commands) != 1 || commands[0] != "LOGOUT" {
+		t.Errorf("commands sent = %q", commands)
+	}
+
+	client, server = net.Pipe()
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+		fmt.Fprint(server, "* OK IMAP ready\r\n")
+		r.ReadString('\n')
+		fmt.Fprintf(server, "* 1 FETCH (UID 7 BODY[] {%d}\r\n", maxIMAPLiteral+1)
+	}()
+	if conn, err = newIMAPConn(client); err != nil {
+		t.Fatalf("newIMAPConn error: %v", err)
+	}
+	defer client.Close()
+	if _, err := conn.Fetch(ctx, 7); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("fetch of an oversized literal: %v", err)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
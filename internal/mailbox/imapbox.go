@@ -0,0 +1,252 @@
+package mailbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	defaultIMAPMailbox = "INBOX"
+	defaultDrafts      = "Drafts"
+)
+
+// imapMailbox is a mailbox read over IMAP and sent from over SMTP. Message
+// IDs are UIDs in the configured mailbox, draft IDs UIDs in the drafts
+// mailbox.
+type imapMailbox struct {
+	cfg  config.MailIMAPConfig
+	dial func(ctx context.Context) (*IMAPConn, error)
+	send func(ctx context.Context, from string, to []string, msg []byte) error
+}
+
+func newIMAPMailbox(cfg config.MailIMAPConfig) *imapMailbox {
+	if cfg.Address == "" {
+		cfg.Address = cfg.Username
+	}
+	if cfg.Mailbox == "" {
+		cfg.Mailbox = defaultIMAPMailbox
+	}
+	if cfg.Drafts == "" {
+		cfg.Drafts = defaultDrafts
+	}
+	smtp := SMTPServer{Host: cfg.SMTPHost, Port: cfg.SMTPPort, Username: cfg.Username, Password: cfg.Password}
+	return &imapMailbox{
+		cfg: cfg,
+		dial: func(ctx context.Context) (*IMAPConn, error) {
+			return DialIMAP(ctx, cfg.IMAPHost, cfg.IMAPPort)
+		},
+		send: smtp.Send,
+	}
+}
+
+func (m *imapMailbox) Name() string { return "imap" }
+
+// open logs in and selects box. The caller logs out.
+func (m *imapMailbox) open(ctx context.Context, box string) (*IMAPConn, error) {
+	c, err := m.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Login(ctx, m.cfg.Username, m.cfg.Password); err != nil {
+		c.Logout(ctx)
+		return nil, err
+	}
+	if err := c.Select(ctx, box); err != nil {
+		c.Logout(ctx)
+		return nil, err
+	}
+	return c, nil
+}
+
+// imapCriteria turns q into UID SEARCH criteria.
+func imapCriteria(q Query) string {
+	var parts []string
+	if q.Text != "" {
+		parts = append(parts, "TEXT "+IMAPQuote(q.Text))
+	}
+	if q.From != "" {
+		parts = append(parts, "FROM "+IMAPQuote(q.From))
+	}
+	if q.Unread {
+		parts = append(parts, "UNSEEN")
+	}
+	if !q.Since.IsZero() {
+		parts = append(parts, "SINCE "+q.Since.Format("2-Jan-2006"))
+	}
+	if len(parts) == 0 {
+		return "ALL"
+	}
+	criteria := strings.Join(parts, " ")
+	if strings.IndexFunc(criteria, func(r rune) bool { return r > unicode.MaxASCII }) >= 0 {
+		criteria = "CHARSET UTF-8 " + criteria
+	}
+	return criteria
+}
+
+func (m *imapMailbox) Search(ctx context.Context, q Query) ([]Message, error) {
+	c, err := m.open(ctx, m.cfg.Mailbox)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Logout(ctx)
+
+	uids, err := c.Search(ctx, imapCriteria(q))
+	if err != nil {
+		return nil, err
+	}
+	if n := q.limit(); len(uids) > n {
+		uids = uids[len(uids)-n:]
+	}
+	headers, err := c.FetchHeaders(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]Message, 0, len(headers))
+	for _, h := range headers {
+		msg, err := parseHeader(h.Header)
+		if err != nil {
+			continue
+		}
+		msg.ID = strconv.FormatUint(uint64(h.UID), 10)
+		msg.Unread = !slices.Contains(h.Flags, `\Seen`)
+		messages = append(messages, msg)
+	}
+	sortMessages(messages)
+	return messages, nil
+}
+
+func parseUID(id string) (uint32, error) {
+	uid, err := strconv.ParseUint(strings.TrimSpace(id), 10, 32)
+	if err != nil || uid == 0 {
+		return 0, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	return uint32(uid), nil
+}
+
+// fetch downloads message id from box.
+func (m *imapMailbox) fetch(ctx context.Context, box, id string) ([]byte, error) {
+	uid, err := parseUID(id)
+	if err != nil {
+		return nil, err
+	}
+	c, err := m.open(ctx, box)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Logout(ctx)
+	raw, err := c.Fetch(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return raw, nil
+}
+
+func (m *imapMailbox) Read(ctx context.Context, id string) (Message, error) {
+	raw, err := m.fetch(ctx, m.cfg.Mailbox, id)
+	if err != nil {
+		return Message{}, err
+	}
+	msg, err := parseMessage(raw)
+	msg.ID = id
+	return msg, err
+}
+
+func (m *imapMailbox) SaveDraft(ctx context.Context, d Draft) (string, error) {
+	raw, err := buildMessage(m.cfg.Address, d)
+	if err != nil {
+		return "", err
+	}
+	c, err := m.open(ctx, m.cfg.Drafts)
+	if err != nil {
+		return "", err
+	}
+	defer c.Logout(ctx)
+	if err := c.Append(ctx, m.cfg.Drafts, `\Draft \Seen`, raw); err != nil {
+		return "", fmt.Errorf("save draft in %s: %w", m.cfg.Drafts, err)
+	}
+
+	// Find the UID of the new draft by its Message-ID.
+	saved, err := parseHeader(raw)
+	if err != nil {
+		return "", err
+	}
+	uids, err := c.Search(ctx, "HEADER Message-ID "+IMAPQuote(saved.MessageID))
+	if err != nil {
+		return "", err
+	}
+	if len(uids) == 0 {
+		return "", fmt.Errorf("saved the draft in %s but cannot find it again", m.cfg.Drafts)
+	}
+	return strconv.FormatUint(uint64(uids[len(uids)-1]), 10), nil
+}
+
+func (m *imapMailbox) Draft(ctx context.Context, id string) (Draft, error) {
+	raw, err := m.fetch(ctx, m.cfg.Drafts, id)
+	if err != nil {
+		return Draft{}, err
+	}
+	d, err := parseDraft(raw)
+	d.ID = id
+	return d, err
+}
+
+func (m *imapMailbox) Send(ctx context.Context, d Draft) error {
+	raw, err := buildMessage(m.cfg.Address, d)
+	if err != nil {
+		return err
+	}
+	to, err := envelope(d)
+	if err != nil {
+		return err
+	}
+	if err := m.send(ctx, m.cfg.Address, to, raw); err != nil {
+		return err
+	}
+	if m.cfg.Sent != "" {
+		// The mail is out; failing to file a copy must not look like a
+		// failed send, or the agent sends it again.
+		if err := m.appendTo(ctx, m.cfg.Sent, `\Seen`, raw); err != nil {
+			log.Printf("[mail] sent, but could not save a copy in %s: %v", m.cfg.Sent, err)
+		}
+	}
+	return nil
+}
+
+func (m *imapMailbox) appendTo(ctx context.Context, box, flags string, raw []byte) error {
+	c, err := m.open(ctx, box)
+	if err != nil {
+		return err
+	}
+	defer c.Logout(ctx)
+	return c.Append(ctx, box, flags, raw)
+}
+
+func (m *imapMailbox) SendDraft(ctx context.Context, id string) error {
+	d, err := m.Draft(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := m.Send(ctx, d); err != nil {
+		return err
+	}
+	uid, _ := parseUID(id)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	c, err := m.open(ctx, m.cfg.Drafts)
+	if err == nil {
+		defer c.Logout(ctx)
+		err = c.Delete(ctx, uid)
+	}
+	if err != nil {
+		log.Printf("[mail] sent draft %s, but could not remove it from %s: %v", id, m.cfg.Drafts, err)
+	}
+	return nil
+}
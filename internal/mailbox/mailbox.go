@@ -0,0 +1,131 @@
+// Package mailbox reads, drafts and sends the user's own email, over IMAP
+// and SMTP or through the Gmail API, and gives the agent tools for it. The
+// IMAP client and SMTP sender are shared with the email channel.
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// ErrNotFound is returned for a message or draft ID the mailbox does not
+// have.
+var ErrNotFound = errors.New("no such message")
+
+// Message is one email. Search leaves Body and Attachments empty.
+type Message struct {
+	ID          string    `json:"id"`
+	ThreadID    string    `json:"threadId,omitempty"`
+	MessageID   string    `json:"messageId,omitempty"`
+	References  string    `json:"references,omitempty"`
+	From        string    `json:"from"`
+	ReplyTo     string    `json:"replyTo,omitempty"`
+	To          []string  `json:"to,omitempty"`
+	Cc          []string  `json:"cc,omitempty"`
+	Subject     string    `json:"subject"`
+	Date        time.Time `json:"date"`
+	Unread      bool      `json:"unread,omitempty"`
+	Body        string    `json:"body,omitempty"`
+	Attachments []string  `json:"attachments,omitempty"`
+}
+
+// Draft is a message to send. InReplyTo, References and ThreadID place a
+// reply in its conversation.
+type Draft struct {
+	ID         string   `json:"id,omitempty"`
+	To         []string `json:"to"`
+	Cc         []string `json:"cc,omitempty"`
+	Subject    string   `json:"subject"`
+	Body       string   `json:"body"`
+	InReplyTo  string   `json:"inReplyTo,omitempty"`
+	References string   `json:"references,omitempty"`
+	ThreadID   string   `json:"threadId,omitempty"`
+}
+
+// Query selects messages. Text matches anywhere in a message; on Gmail it
+// may use Gmail's search operators. Limit defaults to 10.
+type Query struct {
+	Text   string
+	From   string
+	Unread bool
+	Since  time.Time
+	Limit  int
+}
+
+func (q Query) limit() int {
+	switch {
+	case q.Limit <= 0:
+		return defaultLimit
+	case q.Limit > maxLimit:
+		return maxLimit
+	}
+	return q.Limit
+}
+
+// Mailbox is the user's mailbox. Reading never marks a message read.
+type Mailbox interface {
+	Name() string
+	// Search returns the messages matching q, newest first.
+	Search(ctx context.Context, q Query) ([]Message, error)
+	Read(ctx context.Context, id string) (Message, error)
+	// SaveDraft saves d where the user's mail client shows drafts and
+	// returns its ID.
+	SaveDraft(ctx context.Context, d Draft) (string, error)
+	Draft(ctx context.Context, id string) (Draft, error)
+	Send(ctx context.Context, d Draft) error
+	// SendDraft sends a saved draft and removes it from the drafts.
+	SendDraft(ctx context.Context, id string) error
+}
+
+// New returns the mailbox cfg selects, or nil when no provider is set.
+func New(cfg config.MailConfig) (Mailbox, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "imap":
+		if cfg.IMAP.IMAPHost == "" || cfg.IMAP.SMTPHost == "" || cfg.IMAP.Username == "" {
+			return nil, errors.New("mail: imap needs imapHost, smtpHost and username")
+		}
+		return newIMAPMailbox(cfg.IMAP), nil
+	case "gmail":
+		return newGmail(cfg.Gmail, GmailTokenPath())
+	}
+	return nil, fmt.Errorf("mail: unknown provider %q", cfg.Provider)
+}
+
+// Reply returns a draft answering m, to whoever m asks replies to go to.
+func Reply(m Message) Draft {
+	to := m.ReplyTo
+	if to == "" {
+		to = m.From
+	}
+	subject := m.Subject
+	if lower := strings.ToLower(subject); !strings.HasPrefix(lower, "re:") && !strings.HasPrefix(lower, "aw:") {
+		subject = "Re: " + subject
+	}
+	return Draft{
+		To:         []string{to},
+		Subject:    subject,
+		InReplyTo:  m.MessageID,
+		References: strings.TrimSpace(m.References + " " + m.MessageID),
+		ThreadID:   m.ThreadID,
+	}
+}
+
+func sortMessages(messages []Message) {
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Date.After(messages[j].Date) })
+}
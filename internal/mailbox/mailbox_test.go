@@ -0,0 +1,512 @@
+package mailbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/googleauth"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+	"golang.org/x/oauth2"
+)
+
+const (
+	plainMessage = "From: Alice <alice@example.com>\r\n" +
+		"To: me@example.com\r\n" +
+		"Subject: =?utf-8?q?Lunch_=E2=98=95?=\r\n" +
+		"Date: Fri, 16 Oct 2026 09:30:00 +0000\r\n" +
+		"Message-ID: <m1@example.com>\r\n" +
+		"\r\n" +
+		"Lunch at noon?\r\n"
+	multipartMessage = "From: bob@example.com\r\n" +
+		"Subject: Report\r\n" +
+		"Date: Fri, 16 Oct 2026 10:00:00 +0000\r\n" +
+		"Message-ID: <m2@example.com>\r\n" +
+		"References: <m0@example.com>\r\n" +
+		"Content-Type: multipart/mixed; boundary=b1\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/html; charset=iso-8859-1\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"<p>Caf=E9 <b>report</b> attached.</p>\r\n" +
+		"--b1\r\n" +
+		"Content-Type: application/pdf; name=report.pdf\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"JVBERi0xLjQK\r\n" +
+		"--b1--\r\n"
+)
+
+func TestParseMessage(t *testing.T) {
+	m, err := parseMessage([]byte(plainMessage))
+	if err != nil {
+		t.Fatalf("parseMessage error: %v", err)
+	}
+	if m.From != "Alice <alice@example.com>" || m.Subject != "Lunch ☕" || m.Body != "Lunch at noon?" || m.MessageID != "<m1@example.com>" {
+		t.Errorf("message = %+v", m)
+	}
+	if want := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC); !m.Date.Equal(want) {
+		t.Errorf("date = %v", m.Date)
+	}
+
+	m, err = parseMessage([]byte(multipartMessage))
+	if err != nil {
+		t.Fatalf("parseMessage error: %v", err)
+	}
+	if m.Body != "Café **report** attached." {
+		t.Errorf("body = %q", m.Body)
+	}
+	if len(m.Attachments) != 1 || m.Attachments[0] != "report.pdf (application/pdf, 9 bytes)" {
+		t.Errorf("attachments = %q", m.Attachments)
+	}
+
+	reply := Reply(m)
+	if reply.To[0] != "bob@example.com" || reply.Subject != "Re: Report" || reply.InReplyTo != "<m2@example.com>" || reply.References != "<m0@example.com> <m2@example.com>" {
+		t.Errorf("reply = %+v", reply)
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	d := Draft{
+		To:        []string{"Zoë <zoe@example.com>, bob@example.com"},
+		Cc:        []string{"carol@example.com"},
+		Subject:   "Grüße",
+		Body:      "Hi,\nsee you.",
+		InReplyTo: "<m1@example.com>",
+	}
+	raw, err := buildMessage("me@example.com", d)
+	if err != nil {
+		t.Fatalf("buildMessage error: %v", err)
+	}
+	back, err := parseDraft(raw)
+	if err != nil {
+		t.Fatalf("parseDraft error: %v", err)
+	}
+	if strings.Join(back.To, ";") != "Zoë <zoe@example.com>;bob@example.com" || back.Subject != "Grüße" || back.Body != "Hi,\nsee you." || back.InReplyTo != "<m1@example.com>" {
+		t.Errorf("round trip = %+v", back)
+	}
+	to, _ := envelope(d)
+	if strings.Join(to, ",") != "zoe@example.com,bob@example.com,carol@example.com" {
+		t.Errorf("envelope = %q", to)
+	}
+	if _, err := buildMessage("me@example.com", Draft{Body: "x"}); err == nil {
+		t.Error("expected an error without recipients")
+	}
+	if _, err := buildMessage("me@example.com", Draft{To: []string{"not an address"}, Body: "x"}); err == nil {
+		t.Error("expected an error for a bad address")
+	}
+}
+
+// fakeIMAP is an IMAP server over in-memory mailboxes, enough for
+// imapMailbox.
+type fakeIMAP struct {
+	mu    sync.Mutex
+	boxes map[string][]*fakeMessage
+	next  uint32
+}
+
+type fakeMessage struct {
+	uid   uint32
+	flags []string
+	raw   string
+}
+
+func newFakeIMAP() *fakeIMAP {
+	return &fakeIMAP{boxes: map[string][]*fakeMessage{"INBOX": nil, "Drafts": nil, "Sent": nil}}
+}
+
+func (f *fakeIMAP) add(box, raw string, flags ...string) uint32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next++
+	f.boxes[box] = append(f.boxes[box], &fakeMessage{uid: f.next, flags: flags, raw: raw})
+	return f.next
+}
+
+func (f *fakeIMAP) dial(context.Context) (*IMAPConn, error) {
+	client, server := net.Pipe()
+	go f.serve(server)
+	return newIMAPConn(client)
+}
+
+func (f *fakeIMAP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK ready\r\n")
+	selected := ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, cmd, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		f.mu.Lock()
+		switch {
+		case strings.HasPrefix(cmd, "SELECT "):
+			selected = strings.Trim(strings.TrimPrefix(cmd, "SELECT "), `"`)
+		case strings.HasPrefix(cmd, "UID SEARCH "):
+			criteria := strings.TrimPrefix(cmd, "UID SEARCH ")
+			var uids []string
+			for _, m := range f.boxes[selected] {
+				if f.matches(m, criteria) {
+					uids = append(uids, strconv.Itoa(int(m.uid)))
+				}
+			}
+			fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+		case strings.HasPrefix(cmd, "UID FETCH "):
+			set, items, _ := strings.Cut(strings.TrimPrefix(cmd, "UID FETCH "), " ")
+			for _, field := range strings.Split(set, ",") {
+				uid, _ := strconv.Atoi(field)
+				for _, m := range f.boxes[selected] {
+					if int(m.uid) != uid {
+						continue
+					}
+					if strings.Contains(items, "HEADER") {
+						header, _, _ := strings.Cut(m.raw, "\r\n\r\n")
+						header += "\r\n\r\n"
+						fmt.Fprintf(conn, "* 1 FETCH (UID %d FLAGS (%s) BODY[HEADER] {%d}\r\n%s)\r\n", m.uid, strings.Join(m.flags, " "), len(header), header)
+					} else {
+						fmt.Fprintf(conn, "* 1 FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", m.uid, len(m.raw), m.raw)
+					}
+				}
+			}
+		case strings.HasPrefix(cmd, "APPEND "):
+			var box, flags string
+			var n int
+			rest := strings.TrimPrefix(cmd, "APPEND ")
+			box, rest, _ = strings.Cut(strings.TrimPrefix(rest, `"`), `" (`)
+			flags, rest, _ = strings.Cut(rest, ") {")
+			n, _ = strconv.Atoi(strings.TrimSuffix(rest, "}"))
+			fmt.Fprint(conn, "+ go ahead\r\n")
+			lit := make([]byte, n+2)
+			if _, err := io.ReadFull(r, lit); err != nil {
+				f.mu.Unlock()
+				return
+			}
+			f.next++
+			f.boxes[box] = append(f.boxes[box], &fakeMessage{uid: f.next, flags: strings.Fields(flags), raw: string(lit[:n])})
+		case strings.HasPrefix(cmd, "UID STORE "):
+			var uid int
+			fmt.Sscanf(cmd, "UID STORE %d", &uid)
+			for _, m := range f.boxes[selected] {
+				if int(m.uid) == uid && strings.Contains(cmd, `\Deleted`) {
+					m.flags = append(m.flags, `\Deleted`)
+				}
+			}
+		case cmd == "EXPUNGE":
+			var kept []*fakeMessage
+			for _, m := range f.boxes[selected] {
+				if !strings.Contains(strings.Join(m.flags, " "), `\Deleted`) {
+					kept = append(kept, m)
+				}
+			}
+			f.boxes[selected] = kept
+		case cmd == "LOGOUT":
+			f.mu.Unlock()
+			fmt.Fprintf(conn, "* BYE\r\n%s OK bye\r\n", tag)
+			return
+		}
+		f.mu.Unlock()
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+func (f *fakeIMAP) matches(m *fakeMessage, criteria string) bool {
+	switch {
+	case criteria == "ALL":
+		return true
+	case criteria == "UNSEEN":
+		return !strings.Contains(strings.Join(m.flags, " "), `\Seen`)
+	case strings.HasPrefix(criteria, "HEADER Message-ID "):
+		return strings.Contains(m.raw, "Message-ID: "+strings.Trim(strings.TrimPrefix(criteria, "HEADER Message-ID "), `"`))
+	case strings.HasPrefix(criteria, "TEXT "):
+		return strings.Contains(m.raw, strings.Trim(strings.TrimPrefix(criteria, "TEXT "), `"`))
+	}
+	return false
+}
+
+func TestIMAPMailbox(t *testing.T) {
+	server := newFakeIMAP()
+	first := server.add("INBOX", plainMessage, `\Seen`)
+	server.add("INBOX", multipartMessage)
+
+	type sent struct {
+		from string
+		to   []string
+		msg  string
+	}
+	var outbox []sent
+	mb := newIMAPMailbox(config.MailIMAPConfig{IMAPHost: "imap.example.com", SMTPHost: "smtp.example.com", Username: "me@example.com", Sent: "Sent"})
+	mb.dial = server.dial
+	mb.send = func(_ context.Context, from string, to []string, msg []byte) error {
+		outbox = append(outbox, sent{from, to, string(msg)})
+		return nil
+	}
+	ctx := context.Background()
+
+	messages, err := mb.Search(ctx, Query{})
+	if err != nil || len(messages) != 2 {
+		t.Fatalf("Search = %+v, %v", messages, err)
+	}
+	if messages[0].Subject != "Report" || !messages[0].Unread || messages[1].ID != strconv.Itoa(int(first)) || messages[1].Unread {
+		t.Errorf("messages = %+v", messages)
+	}
+	if messages, _ := mb.Search(ctx, Query{Unread: true, Limit: 5}); len(messages) != 1 {
+		t.Errorf("unread = %+v", messages)
+	}
+	if got := imapCriteria(Query{Text: "café", From: "bob", Since: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}); got != `CHARSET UTF-8 TEXT "café" FROM "bob" SINCE 1-Oct-2026` {
+		t.Errorf("criteria = %s", got)
+	}
+
+	m, err := mb.Read(ctx, messages[0].ID)
+	if err != nil || m.Body != "Café **report** attached." {
+		t.Fatalf("Read = %+v, %v", m, err)
+	}
+	if _, err := mb.Read(ctx, "999"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Read missing = %v", err)
+	}
+
+	id, err := mb.SaveDraft(ctx, Draft{To: []string{"bob@example.com"}, Subject: "Re: Report", Body: "Thanks!"})
+	if err != nil || id == "" {
+		t.Fatalf("SaveDraft = %q, %v", id, err)
+	}
+	d, err := mb.Draft(ctx, id)
+	if err != nil || d.Body != "Thanks!" || d.To[0] != "bob@example.com" {
+		t.Fatalf("Draft = %+v, %v", d, err)
+	}
+	if err := mb.SendDraft(ctx, id); err != nil {
+		t.Fatalf("SendDraft error: %v", err)
+	}
+	if len(outbox) != 1 || outbox[0].from != "me@example.com" || outbox[0].to[0] != "bob@example.com" || !strings.Contains(outbox[0].msg, "Thanks!") {
+		t.Errorf("outbox = %+v", outbox)
+	}
+	server.mu.Lock()
+	drafts, sentBox := len(server.boxes["Drafts"]), len(server.boxes["Sent"])
+	server.mu.Unlock()
+	if drafts != 0 || sentBox != 1 {
+		t.Errorf("drafts = %d, sent = %d; want the draft moved to Sent", drafts, sentBox)
+	}
+}
+
+func TestGmail(t *testing.T) {
+	raw := base64.URLEncoding.EncodeToString([]byte(plainMessage))
+	var sentRaw, sentDraft string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			http.Error(w, "no token", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/messages":
+			if q := r.URL.Query().Get("q"); q != "lunch from:alice is:unread" {
+				t.Errorf("q = %q", q)
+			}
+			fmt.Fprint(w, `{"messages":[{"id":"g1","threadId":"t1"}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/messages/g1" && r.URL.Query().Get("format") == "metadata":
+			fmt.Fprint(w, `{"id":"g1","threadId":"t1","labelIds":["INBOX","UNREAD"],"payload":{"headers":[
+				{"name":"From","value":"Alice <alice@example.com>"},{"name":"Subject","value":"Lunch"},
+				{"name":"Date","value":"Fri, 16 Oct 2026 09:30:00 +0000"},{"name":"Message-ID","value":"<m1@example.com>"}]}}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/messages/g1":
+			fmt.Fprintf(w, `{"id":"g1","threadId":"t1","raw":%q}`, raw)
+		case r.Method == http.MethodGet:
+			http.NotFound(w, r)
+		case r.URL.Path == "/messages/send":
+			var in gmailMessage
+			json.NewDecoder(r.Body).Decode(&in)
+			if in.ThreadID != "t1" {
+				t.Errorf("threadId = %q", in.ThreadID)
+			}
+			data, _ := in.raw()
+			sentRaw = string(data)
+			fmt.Fprint(w, `{"id":"g2"}`)
+		case r.URL.Path == "/drafts/send":
+			var in gmailDraft
+			json.NewDecoder(r.Body).Decode(&in)
+			sentDraft = in.ID
+			fmt.Fprint(w, `{"id":"g3"}`)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	tokenPath := filepath.Join(t.TempDir(), "gmail-token.json")
+	gcfg := config.GmailConfig{ClientID: "id", ClientSecret: "secret"}
+	if _, err := newGmail(gcfg, tokenPath); !errors.Is(err, ErrNotAuthorized) {
+		t.Fatalf("newGmail without a token error = %v", err)
+	}
+	if err := googleauth.SaveToken(tokenPath, &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	g, err := newGmail(gcfg, tokenPath)
+	if err != nil {
+		t.Fatalf("newGmail error: %v", err)
+	}
+	g.baseURL = srv.URL
+	ctx := context.Background()
+
+	messages, err := g.Search(ctx, Query{Text: "lunch", From: "alice", Unread: true})
+	if err != nil || len(messages) != 1 || messages[0].ID != "g1" || !messages[0].Unread || messages[0].From != "Alice <alice@example.com>" {
+		t.Fatalf("Search = %+v, %v", messages, err)
+	}
+	m, err := g.Read(ctx, "g1")
+	if err != nil || m.Body != "Lunch at noon?" || m.ThreadID != "t1" {
+		t.Fatalf("Read = %+v, %v", m, err)
+	}
+	if _, err := g.Read(ctx, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Read missing = %v", err)
+	}
+	reply := Reply(m)
+	reply.Body = "Sure."
+	if err := g.Send(ctx, reply); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if !strings.Contains(sentRaw, "In-Reply-To: <m1@example.com>") || strings.Contains(sentRaw, "From:") {
+		t.Errorf("sent = %q", sentRaw)
+	}
+	if err := g.SendDraft(ctx, "d1"); err != nil || sentDraft != "d1" {
+		t.Errorf("SendDraft = %v, sent %q", err, sentDraft)
+	}
+}
+
+// memMailbox keeps messages and drafts in memory.
+type memMailbox struct {
+	messages map[string]Message
+	drafts   map[string]Draft
+	sent     []Draft
+}
+
+func (m *memMailbox) Name() string { return "mem" }
+
+func (m *memMailbox) Search(_ context.Context, q Query) ([]Message, error) {
+	var out []Message
+	for _, msg := range m.messages {
+		if !q.Unread || msg.Unread {
+			out = append(out, msg)
+		}
+	}
+	sortMessages(out)
+	return out, nil
+}
+
+func (m *memMailbox) Read(_ context.Context, id string) (Message, error) {
+	msg, ok := m.messages[id]
+	if !ok {
+		return msg, ErrNotFound
+	}
+	return msg, nil
+}
+
+func (m *memMailbox) SaveDraft(_ context.Context, d Draft) (string, error) {
+	d.ID = "d" + strconv.Itoa(len(m.drafts)+1)
+	m.drafts[d.ID] = d
+	return d.ID, nil
+}
+
+func (m *memMailbox) Draft(_ context.Context, id string) (Draft, error) {
+	d, ok := m.drafts[id]
+	if !ok {
+		return d, ErrNotFound
+	}
+	return d, nil
+}
+
+func (m *memMailbox) Send(_ context.Context, d Draft) error {
+	m.sent = append(m.sent, d)
+	return nil
+}
+
+func (m *memMailbox) SendDraft(ctx context.Context, id string) error {
+	d, err := m.Draft(ctx, id)
+	if err != nil {
+		return err
+	}
+	delete(m.drafts, id)
+	return m.Send(ctx, d)
+}
+
+func TestTools(t *testing.T) {
+	mb := &memMailbox{
+		messages: map[string]Message{
+			"1": {ID: "1", From: "Alice <alice@example.com>", Subject: "Lunch", MessageID: "<m1@example.com>", Unread: true, Date: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), Body: "Noon?"},
+			"2": {ID: "2", From: "bob@example.com", Subject: "Old", Date: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		drafts: map[string]Draft{},
+	}
+	byName := func(policy, name string) func(ctx context.Context, params map[string]any) (string, bool) {
+		for _, tl := range NewTools(mb, policy) {
+			if tl.Name() == name {
+				return func(ctx context.Context, params map[string]any) (string, bool) {
+					res, err := tl.Execute(ctx, params)
+					if err != nil {
+						t.Fatalf("%s error: %v", name, err)
+					}
+					return res.Output, res.Success
+				}
+			}
+		}
+		t.Fatalf("no tool %s", name)
+		return nil
+	}
+	ctx := context.Background()
+
+	out, ok := byName("", "email_search")(ctx, map[string]any{"unread": true})
+	if !ok || !strings.Contains(out, "[id: 1]") || strings.Contains(out, "[id: 2]") || !strings.Contains(out, "(unread)") {
+		t.Errorf("email_search = %q", out)
+	}
+	if out, ok := byName("", "email_search")(ctx, map[string]any{"since": "last week"}); ok {
+		t.Errorf("email_search with a bad since = %q", out)
+	}
+	if out, ok := byName("", "email_read")(ctx, map[string]any{"id": "1"}); !ok || !strings.Contains(out, "Subject: Lunch") || !strings.Contains(out, "Noon?") {
+		t.Errorf("email_read = %q", out)
+	}
+
+	out, ok = byName("", "email_draft")(ctx, map[string]any{"reply_to": "1", "body": "Yes!"})
+	if !ok || !strings.Contains(out, "Saved draft d1") || !strings.Contains(out, "Subject: Re: Lunch") {
+		t.Fatalf("email_draft = %q", out)
+	}
+	if d := mb.drafts["d1"]; d.To[0] != "Alice <alice@example.com>" || d.InReplyTo != "<m1@example.com>" {
+		t.Errorf("draft = %+v", d)
+	}
+	if out, ok := byName("", "email_draft")(ctx, map[string]any{"body": "hi"}); ok {
+		t.Errorf("email_draft without to = %q", out)
+	}
+
+	// By default sending asks, and nobody to ask means no.
+	if out, ok := byName("", "email_send")(ctx, map[string]any{"draft_id": "d1"}); ok || !strings.Contains(out, "permission denied") || len(mb.sent) != 0 {
+		t.Errorf("email_send unasked = %q, sent %d", out, len(mb.sent))
+	}
+	var asked permission.Request
+	yes := permission.WithAsker(ctx, func(_ context.Context, req permission.Request) (bool, error) {
+		asked = req
+		return true, nil
+	})
+	if out, ok := byName("", "email_send")(yes, map[string]any{"draft_id": "d1"}); !ok || len(mb.sent) != 1 || len(mb.drafts) != 0 {
+		t.Errorf("email_send approved = %q, sent %d", out, len(mb.sent))
+	}
+	if asked.Tool != "email_send" || asked.Target != `to Alice <alice@example.com>: "Re: Lunch" — Yes!` {
+		t.Errorf("asked %+v", asked)
+	}
+
+	if out, ok := byName(SendAllow, "email_send")(ctx, map[string]any{"to": []any{"carol@example.com"}, "subject": "Hi", "body": "Hello"}); !ok || len(mb.sent) != 2 {
+		t.Errorf("email_send allowed = %q", out)
+	}
+	if out, ok := byName(SendDeny, "email_send")(yes, map[string]any{"to": "carol@example.com", "body": "Hello"}); ok || !strings.Contains(out, "email_draft") || len(mb.sent) != 2 {
+		t.Errorf("email_send denied = %q", out)
+	}
+}
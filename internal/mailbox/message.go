@@ -0,0 +1,326 @@
+package mailbox
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/fetch"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// maxPartSize caps how much of one MIME part is read.
+const maxPartSize = 5 << 20
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// NewMessageID returns a fresh Message-ID in the domain of from.
+func NewMessageID(from string) string {
+	domain := "myclaw"
+	if _, d, ok := strings.Cut(from, "@"); ok {
+		domain = strings.TrimRight(d, ">")
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
+
+// parseMessage reads a whole RFC 5322 message, body and all.
+func parseMessage(raw []byte) (Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return Message{}, fmt.Errorf("parse message: %w", err)
+	}
+	m := headerMessage(msg.Header)
+	m.Body, m.Attachments = readBody(msg.Header, msg.Body)
+	return m, nil
+}
+
+// parseHeader reads a message header without the body.
+func parseHeader(raw []byte) (Message, error) {
+	msg, err := mail.ReadMessage(io.MultiReader(bytes.NewReader(raw), strings.NewReader("\r\n")))
+	if err != nil {
+		return Message{}, fmt.Errorf("parse header: %w", err)
+	}
+	return headerMessage(msg.Header), nil
+}
+
+func headerMessage(h mail.Header) Message {
+	m := Message{
+		MessageID:  strings.TrimSpace(h.Get("Message-Id")),
+		References: strings.TrimSpace(h.Get("References")),
+		From:       displayAddress(h.Get("From")),
+		ReplyTo:    displayAddress(h.Get("Reply-To")),
+		To:         displayAddresses(h.Get("To")),
+		Cc:         displayAddresses(h.Get("Cc")),
+		Subject:    decodeHeader(h.Get("Subject")),
+	}
+	if date, err := h.Date(); err == nil {
+		m.Date = date
+	}
+	return m
+}
+
+func decodeHeader(s string) string {
+	if decoded, err := wordDecoder.DecodeHeader(s); err == nil {
+		return decoded
+	}
+	return s
+}
+
+var addressParser = &mail.AddressParser{WordDecoder: wordDecoder}
+
+// displayAddress shows an address as "Name <addr>", decoded.
+func displayAddress(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	addr, err := addressParser.Parse(s)
+	if err != nil {
+		return decodeHeader(strings.TrimSpace(s))
+	}
+	return formatAddress(addr)
+}
+
+func displayAddresses(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	list, err := addressParser.ParseList(s)
+	if err != nil {
+		return []string{decodeHeader(strings.TrimSpace(s))}
+	}
+	out := make([]string, len(list))
+	for i, addr := range list {
+		out[i] = formatAddress(addr)
+	}
+	return out
+}
+
+func formatAddress(addr *mail.Address) string {
+	if addr.Name == "" {
+		return addr.Address
+	}
+	return addr.Name + " <" + addr.Address + ">"
+}
+
+// parseAddresses reads addresses as the agent or a header gives them,
+// "bob@example.com" or "Bob <bob@example.com>", several to an entry if
+// separated by commas.
+func parseAddresses(list []string) ([]*mail.Address, error) {
+	var out []*mail.Address
+	for _, s := range list {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		addrs, err := addressParser.ParseList(s)
+		if err != nil {
+			return nil, fmt.Errorf("address %q: %w", s, err)
+		}
+		out = append(out, addrs...)
+	}
+	return out, nil
+}
+
+// readBody returns the text of a message, HTML turned into markdown, and
+// a line about each attachment.
+func readBody(h mail.Header, body io.Reader) (string, []string) {
+	var plain, markup strings.Builder
+	var attachments []string
+
+	var walk func(h mail.Header, body io.Reader)
+	walk = func(h mail.Header, body io.Reader) {
+		mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+		if err != nil {
+			mediaType = "text/plain"
+		}
+		if strings.HasPrefix(mediaType, "multipart/") {
+			mr := multipart.NewReader(body, params["boundary"])
+			for {
+				part, err := mr.NextRawPart()
+				if err != nil {
+					return
+				}
+				walk(mail.Header(part.Header), part)
+			}
+		}
+
+		disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+		filename := dparams["filename"]
+		if filename == "" {
+			filename = params["name"]
+		}
+		filename = decodeHeader(filename)
+		isAttachment := disposition == "attachment" || filename != ""
+
+		r := io.LimitReader(decodeTransfer(h.Get("Content-Transfer-Encoding"), body), maxPartSize)
+		if isAttachment || !strings.HasPrefix(mediaType, "text/") {
+			n, _ := io.Copy(io.Discard, r)
+			if filename == "" {
+				filename = "unnamed"
+			}
+			attachments = append(attachments, fmt.Sprintf("%s (%s, %s)", filename, mediaType, formatSize(n)))
+			return
+		}
+		if cs := params["charset"]; cs != "" && !strings.EqualFold(cs, "utf-8") && !strings.EqualFold(cs, "us-ascii") {
+			if cr, err := charset.NewReaderLabel(cs, r); err == nil {
+				r = cr
+			}
+		}
+		data, _ := io.ReadAll(r)
+		switch mediaType {
+		case "text/html":
+			markup.Write(data)
+		default:
+			plain.Write(data)
+		}
+	}
+	walk(h, body)
+
+	text := strings.ReplaceAll(plain.String(), "\r\n", "\n")
+	if strings.TrimSpace(text) == "" && markup.Len() > 0 {
+		if doc, err := html.Parse(strings.NewReader(markup.String())); err == nil {
+			_, text = fetch.Markdown(doc, nil)
+		}
+	}
+	return strings.TrimSpace(text), attachments
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineSkipper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// newlineSkipper drops CR/LF so base64 bodies wrapped at 76 columns decode.
+type newlineSkipper struct{ r io.Reader }
+
+func (n *newlineSkipper) Read(p []byte) (int, error) {
+	for {
+		c, err := n.r.Read(p)
+		j := 0
+		for _, b := range p[:c] {
+			if b != '\r' && b != '\n' {
+				p[j] = b
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// buildMessage writes d as a plain-text RFC 5322 message from from. An
+// empty from leaves the From header to the server, as Gmail fills it in.
+func buildMessage(from string, d Draft) ([]byte, error) {
+	to, err := parseAddresses(d.To)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := parseAddresses(d.Cc)
+	if err != nil {
+		return nil, err
+	}
+	if len(to)+len(cc) == 0 {
+		return nil, fmt.Errorf("no recipients")
+	}
+
+	var buf bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+	list := func(addrs []*mail.Address) string {
+		s := make([]string, len(addrs))
+		for i, addr := range addrs {
+			s[i] = addr.String()
+		}
+		return strings.Join(s, ", ")
+	}
+
+	if from != "" {
+		header("From", from)
+	}
+	if len(to) > 0 {
+		header("To", list(to))
+	}
+	if len(cc) > 0 {
+		header("Cc", list(cc))
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", d.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", NewMessageID(from))
+	if d.InReplyTo != "" {
+		header("In-Reply-To", d.InReplyTo)
+	}
+	if d.References != "" {
+		header("References", d.References)
+	}
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	body := strings.ReplaceAll(strings.ReplaceAll(d.Body, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := io.WriteString(qp, body); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseDraft reads back a message buildMessage wrote.
+func parseDraft(raw []byte) (Draft, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return Draft{}, fmt.Errorf("parse draft: %w", err)
+	}
+	m := headerMessage(msg.Header)
+	body, _ := readBody(msg.Header, msg.Body)
+	return Draft{
+		To:         m.To,
+		Cc:         m.Cc,
+		Subject:    m.Subject,
+		Body:       body,
+		InReplyTo:  strings.TrimSpace(msg.Header.Get("In-Reply-To")),
+		References: m.References,
+	}, nil
+}
+
+// envelope returns the bare addresses d is delivered to.
+func envelope(d Draft) ([]string, error) {
+	addrs, err := parseAddresses(append(append([]string(nil), d.To...), d.Cc...))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(addrs))
+	for i, addr := range addrs {
+		out[i] = addr.Address
+	}
+	return out, nil
+}
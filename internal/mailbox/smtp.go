@@ -0,0 +1,70 @@
+package mailbox
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+const defaultSMTPPort = 587
+
+// SMTPServer is where mail is sent from. Port 0 means 587 (STARTTLS); 465
+// speaks TLS from the start.
+type SMTPServer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// Send delivers msg, a whole RFC 5322 message, to the to addresses.
+func (s SMTPServer) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	port := s.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(port))
+	auth := smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	if port != 465 {
+		return smtp.SendMail(addr, auth, from, to, msg)
+	}
+
+	// Port 465 speaks TLS from the first byte, which smtp.SendMail can't do.
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 30 * time.Second}, Config: &tls.Config{ServerName: s.Host}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("dial smtp: %w", err)
+	}
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("smtp auth: %w", err)
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,326 @@
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/fetch"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+)
+
+// Send policies for email_send; see config.MailConfig.
+const (
+	SendAsk   = "ask"
+	SendAllow = "allow"
+	SendDeny  = "deny"
+)
+
+// readMaxTokens caps the body email_read returns.
+const readMaxTokens = 6000
+
+// Tools returns the email tools for cfg, or nil when no mailbox is
+// configured.
+func Tools(cfg config.MailConfig) ([]tool.Tool, error) {
+	mb, err := New(cfg)
+	if err != nil || mb == nil {
+		return nil, err
+	}
+	return NewTools(mb, cfg.Send), nil
+}
+
+// NewTools returns the tools the agent reads and writes mb with. send is
+// the policy for email_send: ask (the default), allow or deny.
+func NewTools(mb Mailbox, send string) []tool.Tool {
+	if send == "" {
+		send = SendAsk
+	}
+	return []tool.Tool{
+		&searchTool{mb: mb},
+		&readTool{mb: mb},
+		&draftTool{mb: mb},
+		&sendTool{mb: mb, policy: send},
+	}
+}
+
+type searchTool struct{ mb Mailbox }
+
+func (t *searchTool) Name() string { return "email_search" }
+
+func (t *searchTool) Description() string {
+	return "Search the user's mailbox, newest first, e.g. to triage unread mail or find a message to answer. " +
+		"Gives each message's id for email_read. Without parameters, lists the latest messages."
+}
+
+func (t *searchTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"query":  map[string]any{"type": "string", "description": "Words to find anywhere in a message"},
+			"from":   map[string]any{"type": "string", "description": "Only messages from this address or name"},
+			"unread": map[string]any{"type": "boolean", "description": "Only unread messages"},
+			"since":  map[string]any{"type": "string", "description": "Only messages on or after this date, e.g. 2026-10-01"},
+			"limit":  map[string]any{"type": "integer", "description": "How many messages at most; default 10, at most 50"},
+		},
+		Required: []string{},
+	}
+}
+
+func (t *searchTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	q := Query{Text: stringParam(params, "query"), From: stringParam(params, "from")}
+	q.Unread, _ = params["unread"].(bool)
+	if n, ok := params["limit"].(float64); ok {
+		q.Limit = int(n)
+	}
+	if since := stringParam(params, "since"); since != "" {
+		d, err := time.ParseInLocation("2006-01-02", since, time.Local)
+		if err != nil {
+			return failed(fmt.Errorf("since %q: want a date such as 2026-10-01", since)), nil
+		}
+		q.Since = d
+	}
+	messages, err := t.mb.Search(ctx, q)
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: FormatList(messages), Data: messages}, nil
+}
+
+// FormatList lists messages one to a line with their IDs.
+func FormatList(messages []Message) string {
+	if len(messages) == 0 {
+		return "No messages.\n"
+	}
+	var b strings.Builder
+	for _, m := range messages {
+		subject := m.Subject
+		if subject == "" {
+			subject = "(no subject)"
+		}
+		fmt.Fprintf(&b, "- [id: %s] %s — %s — %s", m.ID, m.Date.Local().Format("Mon 2 Jan 15:04"), m.From, subject)
+		if m.Unread {
+			b.WriteString(" (unread)")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+type readTool struct{ mb Mailbox }
+
+func (t *readTool) Name() string { return "email_read" }
+
+func (t *readTool) Description() string {
+	return "Read one message from the user's mailbox by the id email_search shows. Does not mark it read."
+}
+
+func (t *readTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"id": map[string]any{"type": "string", "description": "The message id"},
+		},
+		Required: []string{"id"},
+	}
+}
+
+func (t *readTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	id := stringParam(params, "id")
+	if id == "" {
+		return failed(errors.New("id is required")), nil
+	}
+	m, err := t.mb.Read(ctx, id)
+	if err != nil {
+		return failed(err), nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\n", m.From)
+	if len(m.To) > 0 {
+		fmt.Fprintf(&b, "To: %s\n", strings.Join(m.To, ", "))
+	}
+	if len(m.Cc) > 0 {
+		fmt.Fprintf(&b, "Cc: %s\n", strings.Join(m.Cc, ", "))
+	}
+	fmt.Fprintf(&b, "Date: %s\nSubject: %s\n", m.Date.Local().Format("Mon 2 Jan 2006 15:04"), m.Subject)
+	for _, a := range m.Attachments {
+		fmt.Fprintf(&b, "Attachment: %s\n", a)
+	}
+	body, truncated := fetch.Truncate(m.Body, readMaxTokens)
+	b.WriteString("\n" + body + "\n")
+	if truncated {
+		fmt.Fprintf(&b, "\n[Truncated to about %d tokens.]\n", readMaxTokens)
+	}
+	return &tool.ToolResult{Success: true, Output: b.String(), Data: m}, nil
+}
+
+// composeSchema is the message the draft and send tools take.
+func composeSchema() map[string]any {
+	return map[string]any{
+		"to":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Recipients, e.g. \"bob@example.com\" or \"Bob <bob@example.com>\"; for a reply, default the sender"},
+		"cc":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"subject":  map[string]any{"type": "string", "description": "For a reply, default \"Re: \" and the original subject"},
+		"body":     map[string]any{"type": "string", "description": "The text of the message, plain text"},
+		"reply_to": map[string]any{"type": "string", "description": "The id of the message this answers, to keep the conversation together"},
+	}
+}
+
+// compose builds a draft from the compose parameters.
+func compose(ctx context.Context, mb Mailbox, params map[string]any) (Draft, error) {
+	var d Draft
+	if id := stringParam(params, "reply_to"); id != "" {
+		orig, err := mb.Read(ctx, id)
+		if err != nil {
+			return d, fmt.Errorf("reply_to: %w", err)
+		}
+		d = Reply(orig)
+	}
+	if to := listParam(params, "to"); len(to) > 0 {
+		d.To = to
+	}
+	d.Cc = listParam(params, "cc")
+	if s := stringParam(params, "subject"); s != "" {
+		d.Subject = s
+	}
+	d.Body, _ = params["body"].(string)
+	switch {
+	case len(d.To)+len(d.Cc) == 0:
+		return d, errors.New("to is required")
+	case strings.TrimSpace(d.Body) == "":
+		return d, errors.New("body is required")
+	}
+	if _, err := envelope(d); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+// FormatDraft shows d as the user would see it before sending.
+func FormatDraft(d Draft) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s\n", strings.Join(d.To, ", "))
+	if len(d.Cc) > 0 {
+		fmt.Fprintf(&b, "Cc: %s\n", strings.Join(d.Cc, ", "))
+	}
+	fmt.Fprintf(&b, "Subject: %s\n\n%s\n", d.Subject, strings.TrimSpace(d.Body))
+	return b.String()
+}
+
+type draftTool struct{ mb Mailbox }
+
+func (t *draftTool) Name() string { return "email_draft" }
+
+func (t *draftTool) Description() string {
+	return "Save an email as a draft in the user's mailbox for them to review, edit and send from their mail app. " +
+		"Use it rather than email_send unless the user asked to send. Returns the draft id email_send can send."
+}
+
+func (t *draftTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{Type: "object", Properties: composeSchema(), Required: []string{"body"}}
+}
+
+func (t *draftTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	d, err := compose(ctx, t.mb, params)
+	if err != nil {
+		return failed(err), nil
+	}
+	if d.ID, err = t.mb.SaveDraft(ctx, d); err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: fmt.Sprintf("Saved draft %s:\n%s", d.ID, FormatDraft(d)), Data: d}, nil
+}
+
+type sendTool struct {
+	mb     Mailbox
+	policy string
+}
+
+func (t *sendTool) Name() string { return "email_send" }
+
+func (t *sendTool) Description() string {
+	return "Send an email from the user's mailbox: a draft by draft_id, or a new message. " +
+		"Only when the user asked to send; the user may be asked to confirm first."
+}
+
+func (t *sendTool) Schema() *tool.JSONSchema {
+	props := composeSchema()
+	props["draft_id"] = map[string]any{"type": "string", "description": "Send this draft from email_draft instead of a new message"}
+	return &tool.JSONSchema{Type: "object", Properties: props, Required: []string{}}
+}
+
+func (t *sendTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	if t.policy == SendDeny {
+		return failed(errors.New("sending email is turned off (mail.send is deny); save a draft with email_draft for the user to send")), nil
+	}
+	var d Draft
+	var err error
+	if id := stringParam(params, "draft_id"); id != "" {
+		d, err = t.mb.Draft(ctx, id)
+	} else {
+		d, err = compose(ctx, t.mb, params)
+	}
+	if err != nil {
+		return failed(err), nil
+	}
+
+	if t.policy != SendAllow {
+		req := permission.Request{Tool: t.Name(), Target: confirmTarget(d), Params: params}
+		if err := permission.Confirm(ctx, req); err != nil {
+			return failed(err), nil
+		}
+	}
+
+	if d.ID != "" {
+		err = t.mb.SendDraft(ctx, d.ID)
+	} else {
+		err = t.mb.Send(ctx, d)
+	}
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: "Sent:\n" + FormatDraft(d), Data: d}, nil
+}
+
+// confirmTarget describes d for the confirmation prompt, e.g.
+// `to bob@example.com: "Lunch" — Sounds good, see you…`.
+func confirmTarget(d Draft) string {
+	body := strings.Join(strings.Fields(d.Body), " ")
+	if r := []rune(body); len(r) > 200 {
+		body = string(r[:200]) + "…"
+	}
+	to := append(append([]string(nil), d.To...), d.Cc...)
+	return fmt.Sprintf("to %s: %q — %s", strings.Join(to, ", "), d.Subject, body)
+}
+
+func stringParam(params map[string]any, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+// listParam reads a list of strings, or one string of comma-separated
+// entries as some models send.
+func listParam(params map[string]any, name string) []string {
+	var out []string
+	switch v := params[name].(type) {
+	case string:
+		if strings.TrimSpace(v) != "" {
+			out = append(out, v)
+		}
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+	case []string:
+		out = v
+	}
+	return out
+}
+
+func failed(err error) *tool.ToolResult {
+	return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}
+}
@@ -0,0 +1,181 @@
+package markdown
+
+import (
+	"strings"
+)
+
+// language is what the highlighter knows of a language: enough to color
+// keywords, strings, numbers and comments, not to parse it.
+type language struct {
+	keywords     []string
+	lineComments []string
+	blockComment bool // /* ... */
+	backticks    bool // `...` strings
+	foldCase     bool // keywords match in any case
+}
+
+var (
+	cLike = []string{"//"}
+	hash  = []string{"#"}
+)
+
+var languages = map[string]*language{
+	"go": {keywords: words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false iota"),
+		lineComments: cLike, blockComment: true, backticks: true},
+	"python": {keywords: words("and as assert async await break class continue def del elif else except finally for from global if import in is lambda None nonlocal not or pass raise return True False try while with yield self"),
+		lineComments: hash},
+	"javascript": {keywords: words("async await break case catch class const continue default delete do else export extends false finally for from function if import in instanceof let new null of return static super switch this throw true try typeof undefined var void while yield interface type enum implements"),
+		lineComments: cLike, blockComment: true, backticks: true},
+	"rust": {keywords: words("as async await break const continue crate else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while"),
+		lineComments: cLike, blockComment: true},
+	"c": {keywords: words("auto break case char class const continue default delete do double else enum extern false float for goto if int long namespace new nullptr private protected public return short signed sizeof static struct switch template this throw true try typedef union unsigned using virtual void volatile while"),
+		lineComments: cLike, blockComment: true},
+	"java": {keywords: words("abstract boolean break byte case catch char class const continue default do double else enum extends final finally float for if implements import instanceof int interface long new null package private protected public return short static super switch this throw throws true false try void while var val fun when object"),
+		lineComments: cLike, blockComment: true},
+	"shell": {keywords: words("if then else elif fi for while until do done case esac in function return export local echo exit set unset source"),
+		lineComments: hash},
+	"sql": {keywords: words("select from where and or not insert into values update set delete create table index drop alter join left right inner outer on group by order having limit offset as null is in like distinct union all primary key foreign references default"),
+		lineComments: []string{"--"}, blockComment: true, foldCase: true},
+	"ruby": {keywords: words("begin class def do else elsif end ensure false for if in module next nil not or and redo rescue retry return self super then true unless until when while yield require"),
+		lineComments: hash},
+	"yaml": {keywords: words("true false null yes no on off"), lineComments: hash},
+	"toml": {keywords: words("true false"), lineComments: hash},
+	"json": {keywords: words("true false null")},
+}
+
+// aliases maps the names used after a code fence to a language.
+var aliases = map[string]string{
+	"golang": "go", "py": "python", "python3": "python",
+	"js": "javascript", "jsx": "javascript", "ts": "javascript", "tsx": "javascript", "typescript": "javascript",
+	"rs": "rust", "cpp": "c", "c++": "c", "h": "c", "cs": "java", "csharp": "java", "kotlin": "java", "kt": "java",
+	"sh": "shell", "bash": "shell", "zsh": "shell", "console": "shell",
+	"rb": "ruby", "yml": "yaml",
+}
+
+func words(s string) []string { return strings.Fields(s) }
+
+func lookup(name string) *language {
+	name = strings.ToLower(name)
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	return languages[name]
+}
+
+// Token colors.
+var (
+	keywordColor = ansi{"35", "39"}
+	stringColor  = ansi{"32", "39"}
+	numberColor  = ansi{"33", "39"}
+	commentColor = ansi{"90", "39"}
+)
+
+// highlight colors lines of code in lang. Lines of an unknown language are
+// returned as they are. Each line is styled on its own, so a block comment
+// spanning lines is colored line by line.
+func highlight(lines []string, lang string) []string {
+	l := lookup(lang)
+	if l == nil {
+		return lines
+	}
+	r := renderer{opts: Options{Color: true}}
+	out := make([]string, len(lines))
+	inComment := false
+	for n, line := range lines {
+		var b strings.Builder
+		i := 0
+		if inComment {
+			end := strings.Index(line, "*/")
+			if end < 0 {
+				out[n] = r.style(commentColor, line)
+				continue
+			}
+			b.WriteString(r.style(commentColor, line[:end+2]))
+			i = end + 2
+			inComment = false
+		}
+		for i < len(line) {
+			c := line[i]
+			rest := line[i:]
+			switch {
+			case hasAnyPrefix(rest, l.lineComments):
+				b.WriteString(r.style(commentColor, rest))
+				i = len(line)
+			case l.blockComment && strings.HasPrefix(rest, "/*"):
+				end := strings.Index(rest[2:], "*/")
+				if end < 0 {
+					b.WriteString(r.style(commentColor, rest))
+					inComment = true
+					i = len(line)
+				} else {
+					b.WriteString(r.style(commentColor, rest[:end+4]))
+					i += end + 4
+				}
+			case c == '"' || c == '\'' || (c == '`' && l.backticks):
+				end := stringEnd(rest)
+				b.WriteString(r.style(stringColor, rest[:end]))
+				i += end
+			case isDigit(c) && (i == 0 || !isWordByte(line[i-1])):
+				end := 1
+				for end < len(rest) && (isWordByte(rest[end]) || rest[end] == '.') {
+					end++
+				}
+				b.WriteString(r.style(numberColor, rest[:end]))
+				i += end
+			case isWordByte(c):
+				end := 1
+				for end < len(rest) && isWordByte(rest[end]) {
+					end++
+				}
+				word := rest[:end]
+				if l.isKeyword(word) {
+					word = r.style(keywordColor, word)
+				}
+				b.WriteString(word)
+				i += end
+			default:
+				b.WriteByte(c)
+				i++
+			}
+		}
+		out[n] = b.String()
+	}
+	return out
+}
+
+func (l *language) isKeyword(word string) bool {
+	for _, k := range l.keywords {
+		if k == word || l.foldCase && strings.EqualFold(k, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringEnd returns the length of the string literal s starts with, up to
+// the end of the line when it is not closed there.
+func stringEnd(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
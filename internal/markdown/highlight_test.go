@@ -0,0 +1,32 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	got := strings.Join(highlight([]string{
+		`func f() string { return "hi" } // done`,
+		"x := 42 /* start",
+		"end */ y",
+	}, "golang"), "\n")
+	for _, want := range []string{
+		"\x1b[35mfunc\x1b[39m",
+		"\x1b[35mreturn\x1b[39m",
+		"\x1b[32m\"hi\"\x1b[39m",
+		"\x1b[90m// done\x1b[39m",
+		"\x1b[33m42\x1b[39m",
+		"\x1b[90mend */\x1b[39m y",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("highlight missing %q:\n%q", want, got)
+		}
+	}
+	if got := highlight([]string{"SELECT 1"}, "sql")[0]; !strings.Contains(got, "\x1b[35mSELECT") {
+		t.Errorf("sql = %q", got)
+	}
+	if got := highlight([]string{"plain text"}, "brainfuck")[0]; got != "plain text" {
+		t.Errorf("unknown language = %q", got)
+	}
+}
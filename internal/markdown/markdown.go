@@ -0,0 +1,235 @@
+// Package markdown renders the agent's markdown replies for a terminal:
+// headings, lists, quotes, tables and code blocks with syntax highlighting.
+// It knows the markdown models write, not all of CommonMark; what it does
+// not recognize is printed as written.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Options configures Render.
+type Options struct {
+	// Width is the terminal width, for rules. Zero means 80.
+	Width int
+	// Color enables ANSI styling. Without it only the layout changes:
+	// markers are dropped, bullets and tables are drawn.
+	Color bool
+}
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletRe  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedRe = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	taskRe    = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	ruleRe    = regexp.MustCompile(`^\s*(-(\s*-){2,}|\*(\s*\*){2,}|_(\s*_){2,})\s*$`)
+	fenceRe   = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+#.-]*)")
+	delimRe   = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// Render renders md for a terminal.
+func Render(md string, opts Options) string {
+	if opts.Width <= 0 {
+		opts.Width = 80
+	}
+	r := renderer{opts: opts}
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case fenceRe.MatchString(line):
+			m := fenceRe.FindStringSubmatch(line)
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), m[1]) {
+				end++
+			}
+			out = append(out, r.code(lines[i+1:min(end, len(lines))], m[2])...)
+			i = end
+		case i+1 < len(lines) && strings.Contains(line, "|") && delimRe.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			end := i + 2
+			for end < len(lines) && strings.Contains(lines[end], "|") && strings.TrimSpace(lines[end]) != "" {
+				end++
+			}
+			out = append(out, r.table(line, lines[i+1], lines[i+2:end])...)
+			i = end - 1
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			out = append(out, r.heading(len(m[1]), m[2])...)
+		case ruleRe.MatchString(line):
+			out = append(out, r.style(dim, strings.Repeat("─", opts.Width)))
+		case bulletRe.MatchString(line):
+			m := bulletRe.FindStringSubmatch(line)
+			out = append(out, m[1]+r.item("•", m[2]))
+		case orderedRe.MatchString(line):
+			m := orderedRe.FindStringSubmatch(line)
+			out = append(out, m[1]+r.item(m[2], m[3]))
+		case strings.HasPrefix(strings.TrimSpace(line), ">"):
+			text := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(line), ">"), " ")
+			out = append(out, r.style(dim, "│ ")+r.style(italic, r.inline(text)))
+		default:
+			out = append(out, r.inline(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+type renderer struct {
+	opts Options
+}
+
+func (r renderer) heading(level int, text string) []string {
+	text = r.inline(text)
+	if level > 2 {
+		return []string{r.style(bold, text)}
+	}
+	// The top two levels are underlined, so they stand out without color.
+	under := "─"
+	if level == 1 {
+		under = "═"
+	}
+	return []string{r.style(bold, text), r.style(dim, strings.Repeat(under, min(Width(text), r.opts.Width)))}
+}
+
+func (r renderer) item(marker, text string) string {
+	if m := taskRe.FindStringSubmatch(text); m != nil {
+		marker, text = "☐", m[2]
+		if m[1] != " " {
+			marker = "☑"
+		}
+	}
+	return r.style(dim, marker) + " " + r.inline(text)
+}
+
+// code indents a code block and highlights it when lang is known.
+func (r renderer) code(lines []string, lang string) []string {
+	if r.opts.Color {
+		lines = highlight(lines, lang)
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = "  " + line
+	}
+	return out
+}
+
+// ANSI styles, each with the code that turns it off again so they nest.
+type ansi struct{ on, off string }
+
+var (
+	bold      = ansi{"1", "22"}
+	dim       = ansi{"2", "22"}
+	italic    = ansi{"3", "23"}
+	underline = ansi{"4", "24"}
+	strike    = ansi{"9", "29"}
+	codeColor = ansi{"36", "39"}
+)
+
+func (r renderer) style(a ansi, s string) string {
+	if !r.opts.Color || s == "" {
+		return s
+	}
+	return "\x1b[" + a.on + "m" + s + "\x1b[" + a.off + "m"
+}
+
+// inline renders emphasis, code spans and links in one line of text.
+func (r renderer) inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_~[]()#>|-+.!", s[i+1]) >= 0:
+			b.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				code := s[i+1 : i+1+end]
+				if r.opts.Color {
+					b.WriteString(r.style(codeColor, code))
+				} else {
+					b.WriteString("`" + code + "`")
+				}
+				i += end + 2
+				continue
+			}
+		case c == '[':
+			if m := linkRe.FindStringSubmatch(s[i:]); m != nil {
+				text, url := r.inline(m[1]), m[2]
+				if r.opts.Color {
+					b.WriteString(r.style(underline, text))
+				} else {
+					b.WriteString(text)
+				}
+				if m[1] != url {
+					b.WriteString(r.style(dim, " ("+url+")"))
+				}
+				i += len(m[0])
+				continue
+			}
+		}
+		if delim, a, ok := emphasis(s, i); ok {
+			if end := closing(s, i+len(delim), delim); end >= 0 {
+				b.WriteString(r.style(a, r.inline(s[i+len(delim):end])))
+				i = end + len(delim)
+				continue
+			}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String()
+}
+
+var linkRe = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+
+// emphasis reports whether an emphasis delimiter opens at s[i].
+func emphasis(s string, i int) (string, ansi, bool) {
+	for _, d := range []struct {
+		delim string
+		style ansi
+	}{{"**", bold}, {"__", bold}, {"~~", strike}, {"*", italic}, {"_", italic}} {
+		if !strings.HasPrefix(s[i:], d.delim) {
+			continue
+		}
+		after := i + len(d.delim)
+		// An opener is followed by text, and an underscore one does not
+		// sit inside a word, as in snake_case.
+		if after >= len(s) || s[after] == ' ' {
+			return "", ansi{}, false
+		}
+		if d.delim[0] == '_' && i > 0 && isWordByte(s[i-1]) {
+			return "", ansi{}, false
+		}
+		return d.delim, d.style, true
+	}
+	return "", ansi{}, false
+}
+
+// closing finds the delimiter closing emphasis whose text starts at from.
+func closing(s string, from int, delim string) int {
+	for j := from + 1; j+len(delim) <= len(s); j++ {
+		if !strings.HasPrefix(s[j:], delim) || s[j-1] == ' ' {
+			continue
+		}
+		end := j + len(delim)
+		if delim[0] == '_' && end < len(s) && isWordByte(s[end]) {
+			continue
+		}
+		// "**" neither opens nor closes a single "*".
+		if len(delim) == 1 && s[j-1] == delim[0] {
+			continue
+		}
+		if len(delim) == 1 && end < len(s) && s[end] == delim[0] {
+			j++
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
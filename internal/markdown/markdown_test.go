@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender_Plain(t *testing.T) {
+	md := strings.Join([]string{
+		"# Title",
+		"Some **bold**, *italic* and `code` with a [link](https://example.com).",
+		"- one",
+		"  - nested",
+		"1. first",
+		"- [x] done",
+		"> quoted",
+		"---",
+		"```go",
+		"func main() {}",
+		"```",
+		"snake_case_name stays",
+	}, "\n")
+	got := Render(md, Options{Width: 20})
+	want := strings.Join([]string{
+		"Title",
+		"═════",
+		"Some bold, italic and `code` with a link (https://example.com).",
+		"• one",
+		"  • nested",
+		"1. first",
+		"☑ done",
+		"│ quoted",
+		strings.Repeat("─", 20),
+		"  func main() {}",
+		"snake_case_name stays",
+	}, "\n")
+	if got != want {
+		t.Errorf("Render =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRender_Color(t *testing.T) {
+	got := Render("## Hi\n**a *b* c** `x`", Options{Color: true})
+	for _, want := range []string{
+		"\x1b[1mHi\x1b[22m",
+		"\x1b[1ma \x1b[3mb\x1b[23m c\x1b[22m",
+		"\x1b[36mx\x1b[39m",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Render missing %q:\n%q", want, got)
+		}
+	}
+}
+
+func TestInline_Unmatched(t *testing.T) {
+	r := renderer{}
+	for _, s := range []string{"2 * 3 * 4", "a_b_c", "**open", "price: $5_"} {
+		if got := r.inline(s); got != s {
+			t.Errorf("inline(%q) = %q", s, got)
+		}
+	}
+	if got := r.inline(`\*not italic\*`); got != "*not italic*" {
+		t.Errorf("escaped = %q", got)
+	}
+}
+
+func TestWidth(t *testing.T) {
+	if got := Width("\x1b[1m你好\x1b[22m ok"); got != 7 {
+		t.Errorf("Width = %d", got)
+	}
+}
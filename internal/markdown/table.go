@@ -0,0 +1,117 @@
+package markdown
+
+import (
+	"strings"
+)
+
+type align int
+
+const (
+	alignLeft align = iota
+	alignCenter
+	alignRight
+)
+
+// table draws a pipe table with box-drawing lines, each column as wide as
+// its widest cell.
+func (r renderer) table(header, delim string, rows []string) []string {
+	var aligns []align
+	for _, cell := range splitRow(delim) {
+		cell = strings.TrimSpace(cell)
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, alignCenter)
+		case strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, alignRight)
+		default:
+			aligns = append(aligns, alignLeft)
+		}
+	}
+	cells := [][]string{r.cells(header)}
+	for _, row := range rows {
+		cells = append(cells, r.cells(row))
+	}
+	widths := make([]int, len(aligns))
+	for _, row := range cells {
+		for i, cell := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], Width(cell))
+			}
+		}
+	}
+
+	rule := func(left, mid, right string) string {
+		parts := make([]string, len(widths))
+		for i, w := range widths {
+			parts[i] = strings.Repeat("─", w+2)
+		}
+		return r.style(dim, left+strings.Join(parts, mid)+right)
+	}
+	line := func(row []string, header bool) string {
+		var b strings.Builder
+		bar := r.style(dim, "│")
+		b.WriteString(bar)
+		for i, w := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			if header {
+				cell = r.style(bold, cell)
+			}
+			b.WriteString(" " + alignCell(cell, w, aligns[i]) + " " + bar)
+		}
+		return b.String()
+	}
+
+	out := []string{rule("┌", "┬", "┐"), line(cells[0], true), rule("├", "┼", "┤")}
+	for _, row := range cells[1:] {
+		out = append(out, line(row, false))
+	}
+	return append(out, rule("└", "┴", "┘"))
+}
+
+func (r renderer) cells(row string) []string {
+	cells := splitRow(row)
+	for i, cell := range cells {
+		cells[i] = r.inline(strings.TrimSpace(cell))
+	}
+	return cells
+}
+
+// splitRow splits a table row at the pipes not escaped or in code spans,
+// dropping the outer pipes.
+func splitRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+	var cells []string
+	start, inCode := 0, false
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '\\':
+			i++
+		case '`':
+			inCode = !inCode
+		case '|':
+			if !inCode {
+				cells = append(cells, row[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(cells, row[start:])
+}
+
+func alignCell(s string, width int, a align) string {
+	gap := max(width-Width(s), 0)
+	switch a {
+	case alignRight:
+		return strings.Repeat(" ", gap) + s
+	case alignCenter:
+		return strings.Repeat(" ", gap/2) + s + strings.Repeat(" ", gap-gap/2)
+	}
+	return s + strings.Repeat(" ", gap)
+}
@@ -0,0 +1,29 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender_Table(t *testing.T) {
+	md := "| Name | Qty |\n|:-----|----:|\n| tea | 2 |\n| `a|b` | 10 |"
+	got := Render(md, Options{})
+	want := strings.Join([]string{
+		"┌───────┬─────┐",
+		"│ Name  │ Qty │",
+		"├───────┼─────┤",
+		"│ tea   │   2 │",
+		"│ `a|b` │  10 │",
+		"└───────┴─────┘",
+	}, "\n")
+	if got != want {
+		t.Errorf("table =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRender_NotATable(t *testing.T) {
+	md := "a | b\nno delimiter"
+	if got := Render(md, Options{}); got != md {
+		t.Errorf("Render = %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+package markdown
+
+import (
+	"unicode"
+)
+
+// RuneWidth returns how many terminal cells r takes: two for wide East
+// Asian characters and emoji, none for combining marks and controls.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || r == 0x200b:
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
+
+// Width returns how many terminal cells s takes, not counting ANSI escape
+// sequences.
+func Width(s string) int {
+	w := 0
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case inEscape:
+			// A CSI sequence ends with a letter.
+			inEscape = !(r >= '@' && r <= '~' && r != '[')
+		case r == 0x1b:
+			inEscape = true
+		default:
+			w += RuneWidth(r)
+		}
+	}
+	return w
+}
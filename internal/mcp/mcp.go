@@ -0,0 +1,148 @@
+// Package mcp checks the MCP servers in mcp.servers: that a spec names a
+// command or URL the runtime can reach, and what a server says about
+// itself and its tools when connected to.
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	sdkmcp "github.com/cexll/agentsdk-go/pkg/mcp"
+)
+
+// Spec prefixes the runtime reads.
+const (
+	stdioPrefix  = "stdio://"
+	streamScheme = "+stream"
+)
+
+// Transports a URL server can be added with.
+const (
+	TransportHTTP = "http" // streamable HTTP
+	TransportSSE  = "sse"
+)
+
+// Info is what a server told us when connected to.
+type Info struct {
+	Server   string `json:"server,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Tools    []Tool `json:"tools"`
+}
+
+// Tool is a tool a server exposes.
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Spec returns the spec of a server given on the command line: a URL,
+// reached with transport (TransportHTTP when empty), or a command and its
+// arguments, run over stdio.
+func Spec(args []string, transport string) (string, error) {
+	if len(args) == 0 {
+		return "", errors.New("give the command to run or the URL of the server")
+	}
+	if len(args) == 1 && isURL(args[0]) {
+		u := args[0]
+		switch transport {
+		case "", TransportHTTP:
+			scheme, rest, _ := strings.Cut(u, "://")
+			return strings.ToLower(scheme) + streamScheme + "://" + rest, nil
+		case TransportSSE:
+			return u, nil
+		default:
+			return "", fmt.Errorf("transport %q: want http or sse", transport)
+		}
+	}
+	if transport != "" {
+		return "", errors.New("a transport is only for URLs; commands run over stdio")
+	}
+	for _, arg := range args {
+		if strings.ContainsAny(arg, " \t\n") {
+			return "", fmt.Errorf("argument %q: arguments with spaces cannot be passed to an MCP server", arg)
+		}
+	}
+	return stdioPrefix + strings.Join(args, " "), nil
+}
+
+func isURL(s string) bool {
+	lower := strings.ToLower(s)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// Validate checks spec without connecting: a command must be on the PATH,
+// a URL well formed.
+func Validate(spec string) error {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return errors.New("empty server spec")
+	}
+	scheme, rest, ok := strings.Cut(spec, "://")
+	if !ok || strings.ContainsAny(scheme, " \t") {
+		// A bare command, as the runtime reads it.
+		scheme, rest = "stdio", spec
+	}
+	base, _, _ := strings.Cut(strings.ToLower(scheme), "+")
+	switch base {
+	case "stdio":
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return errors.New("no command to run")
+		}
+		if _, err := exec.LookPath(fields[0]); err != nil {
+			return fmt.Errorf("command %q not found", fields[0])
+		}
+		return nil
+	case "http", "https", "sse":
+		_, err := sdkmcp.BuildSessionTransport(context.Background(), spec)
+		return err
+	default:
+		return fmt.Errorf("unsupported scheme %q; want stdio, http or https", scheme)
+	}
+}
+
+// Inspect connects to the server of spec, completes the MCP handshake and
+// lists its tools. A stdio server is started for it and stopped after.
+func Inspect(ctx context.Context, spec string) (*Info, error) {
+	if err := Validate(spec); err != nil {
+		return nil, err
+	}
+	transport, err := sdkmcp.BuildSessionTransport(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	return inspect(ctx, transport)
+}
+
+func inspect(ctx context.Context, transport sdkmcp.Transport) (*Info, error) {
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "myclaw", Version: "1"}, nil)
+	session, err := client.Connect(ctx, transport, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("connect: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	defer session.Close()
+
+	info := &Info{Tools: []Tool{}}
+	if init := session.InitializeResult(); init != nil {
+		info.Protocol = init.ProtocolVersion
+		if init.ServerInfo != nil {
+			info.Server, info.Version = init.ServerInfo.Name, init.ServerInfo.Version
+		}
+	}
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("list tools: %w", err)
+		}
+		if tool != nil {
+			info.Tools = append(info.Tools, Tool{Name: tool.Name, Description: tool.Description})
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sdkmcp "github.com/cexll/agentsdk-go/pkg/mcp"
+)
+
+func TestSpec(t *testing.T) {
+	tests := []struct {
+		args      []string
+		transport string
+		want      string
+		wantErr   string
+	}{
+		{args: []string{"https://mcp.example.com/mcp"}, want: "https+stream://mcp.example.com/mcp"},
+		{args: []string{"HTTP://localhost:8080/mcp"}, transport: "http", want: "http+stream://localhost:8080/mcp"},
+		{args: []string{"https://mcp.example.com/sse"}, transport: "sse", want: "https://mcp.example.com/sse"},
+		{args: []string{"npx", "-y", "@modelcontextprotocol/server-filesystem", "/tmp"}, want: "stdio://npx -y @modelcontextprotocol/server-filesystem /tmp"},
+		{args: []string{"https://mcp.example.com"}, transport: "ws", wantErr: "want http or sse"},
+		{args: []string{"npx", "server"}, transport: "sse", wantErr: "only for URLs"},
+		{args: []string{"node", "/my docs/server.js"}, wantErr: "spaces"},
+		{wantErr: "command to run"},
+	}
+	for _, tt := range tests {
+		got, err := Spec(tt.args, tt.transport)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Spec(%q, %q) error = %v, want %q", tt.args, tt.transport, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Spec(%q, %q) = %q, %v; want %q", tt.args, tt.transport, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, spec := range []string{"stdio://go version", "go version", "https+stream://mcp.example.com/mcp", "https://mcp.example.com/sse"} {
+		if err := Validate(spec); err != nil {
+			t.Errorf("Validate(%q) = %v", spec, err)
+		}
+	}
+	for spec, want := range map[string]string{
+		"":                               "empty",
+		"stdio://myclaw-no-such-command": "not found",
+		"ftp://example.com":              "unsupported scheme",
+	} {
+		if err := Validate(spec); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate(%q) = %v, want %q", spec, err, want)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	server := sdkmcp.NewServer(&sdkmcp.Implementation{Name: "notes", Version: "1.2.0"}, nil)
+	server.AddTool(&sdkmcp.Tool{
+		Name:        "search_notes",
+		Description: "Search the notes.\nMatches titles and bodies.",
+		InputSchema: map[string]any{"type": "object"},
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clientTransport, serverTransport := sdkmcp.NewInMemoryTransports()
+	session, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	info, err := inspect(ctx, clientTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Server != "notes" || info.Version != "1.2.0" || info.Protocol == "" {
+		t.Errorf("info = %+v", info)
+	}
+	if len(info.Tools) != 1 || info.Tools[0].Name != "search_notes" || !strings.HasPrefix(info.Tools[0].Description, "Search the notes.") {
+		t.Errorf("tools = %+v", info.Tools)
+	}
+
+	if _, err := Inspect(ctx, "stdio://myclaw-no-such-command"); err == nil {
+		t.Error("expected an error for a missing command")
+	}
+}
@@ -0,0 +1,133 @@
+package media
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // decoders for image.Decode
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+const (
+	// MaxImageSide is the longest side an image is sent with; larger ones
+	// are scaled down, as the providers would do anyway.
+	MaxImageSide = 1568
+	// MaxImageBytes keeps an image under the 5 MB providers take once it
+	// is base64-encoded.
+	MaxImageBytes = 5 << 20 * 3 / 4
+
+	// minImageSide is as small as an image is scaled to fit MaxImageBytes.
+	minImageSide = 256
+)
+
+// ImageBlock returns data, a PNG, JPEG, GIF or WebP image, as a block for
+// the model, scaled down and re-encoded when it is over MaxImageSide or
+// MaxImageBytes. scaled reports whether it was.
+func ImageBlock(data []byte) (block model.ContentBlock, scaled bool, err error) {
+	mediaType := http.DetectContentType(data)
+	switch mediaType {
+	case "image/png", "image/jpeg", "image/gif":
+	case "image/webp":
+		// The standard library cannot decode WebP, so it goes as it is.
+		if len(data) > MaxImageBytes {
+			return block, false, fmt.Errorf("WebP image is over %d MB; convert it to PNG or JPEG", MaxImageBytes>>20)
+		}
+		return imageBlock(mediaType, data), false, nil
+	default:
+		return block, false, fmt.Errorf("not a PNG, JPEG, GIF or WebP image (%s)", mediaType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return block, false, fmt.Errorf("read image: %w", err)
+	}
+	if max(cfg.Width, cfg.Height) <= MaxImageSide && len(data) <= MaxImageBytes {
+		return imageBlock(mediaType, data), false, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return block, false, fmt.Errorf("read image: %w", err)
+	}
+	side := min(max(cfg.Width, cfg.Height), MaxImageSide)
+	for {
+		out, outType, err := encodeImage(fit(img, side), mediaType)
+		if err != nil {
+			return block, false, err
+		}
+		if len(out) <= MaxImageBytes {
+			return imageBlock(outType, out), true, nil
+		}
+		if side <= minImageSide {
+			return block, false, fmt.Errorf("image is still over %d MB at %d pixels", MaxImageBytes>>20, side)
+		}
+		side = max(side*3/4, minImageSide)
+	}
+}
+
+func imageBlock(mediaType string, data []byte) model.ContentBlock {
+	return model.ContentBlock{
+		Type:      model.ContentBlockImage,
+		MediaType: mediaType,
+		Data:      base64.StdEncoding.EncodeToString(data),
+	}
+}
+
+// encodeImage keeps photos JPEG. PNGs and GIFs, which may be screenshots
+// or have transparency, stay PNG unless that is too big, in which case
+// they become JPEGs on white.
+func encodeImage(img *image.RGBA, mediaType string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if mediaType != "image/jpeg" {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("encode image: %w", err)
+		}
+		if buf.Len() <= MaxImageBytes {
+			return buf.Bytes(), "image/png", nil
+		}
+		buf.Reset()
+		opaque := image.NewRGBA(img.Bounds())
+		draw.Draw(opaque, opaque.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(opaque, opaque.Bounds(), img, img.Bounds().Min, draw.Over)
+		img = opaque
+	}
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", fmt.Errorf("encode image: %w", err)
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// fit scales src down so that its longer side is side pixels, averaging
+// the source pixels that fall in each new one.
+func fit(src image.Image, side int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	w, h := side, side
+	if sw >= sh {
+		h = max(1, sh*side/sw)
+	} else {
+		w = max(1, sw*side/sh)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(b.Min.X+sx, b.Min.Y+sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(bl / n >> 8), uint8(a / n >> 8)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,176 @@
+// Package media turns files received in chats into something the agent can
+// read: voice notes become transcripts, documents become text or document
+// blocks, and images become image blocks.
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+)
+
+// DefaultMaxDocumentChars caps the text taken from one document.
+const DefaultMaxDocumentChars = 20000
+
+// textExtensions are read as text whatever media type the sender claimed.
+var textExtensions = map[string]bool{
+	".txt": true, ".md": true, ".csv": true, ".tsv": true, ".json": true,
+	".yaml": true, ".yml": true, ".xml": true, ".html": true, ".log": true,
+	".ini": true, ".toml": true, ".go": true, ".py": true, ".js": true, ".ts": true,
+	".sh": true, ".sql": true,
+}
+
+// Processor reads attachments for the agent.
+type Processor struct {
+	STT Transcriber // nil when speech to text is off
+	// PDF is set when the model reads PDF document blocks. Otherwise a
+	// PDF is described instead of passed on.
+	PDF              bool
+	MaxDocumentChars int
+}
+
+// Process reads atts. notes are text to add to the message: transcripts,
+// document text, or a line saying why a file could not be read. blocks go
+// to the model alongside the message.
+func (p *Processor) Process(ctx context.Context, atts []bus.Attachment) (notes []string, blocks []model.ContentBlock) {
+	for _, att := range atts {
+		mediaType := mediaTypeOf(att)
+		name := fileName(att)
+		switch {
+		case strings.HasPrefix(mediaType, "audio/"):
+			notes = append(notes, p.transcribe(ctx, att))
+		case strings.HasPrefix(mediaType, "image/"):
+			blocks = append(blocks, model.ContentBlock{
+				Type:      model.ContentBlockImage,
+				MediaType: mediaType,
+				Data:      base64.StdEncoding.EncodeToString(att.Data),
+			})
+		case mediaType == "application/pdf":
+			if !p.PDF {
+				notes = append(notes, fmt.Sprintf("[Document %s (PDF) received; this model cannot read PDFs]", name))
+				continue
+			}
+			blocks = append(blocks, model.ContentBlock{
+				Type:      model.ContentBlockDocument,
+				MediaType: mediaType,
+				Data:      base64.StdEncoding.EncodeToString(att.Data),
+			})
+		case isText(att, mediaType):
+			text, _ := Clip(strings.TrimSpace(string(att.Data)), p.MaxDocumentChars)
+			notes = append(notes, Document(name, text))
+		default:
+			notes = append(notes, fmt.Sprintf("[Attachment %s (%s) not readable]", name, mediaType))
+		}
+	}
+	return notes, blocks
+}
+
+func (p *Processor) transcribe(ctx context.Context, att bus.Attachment) string {
+	if p.STT == nil {
+		return "[Voice message received; speech-to-text is not configured]"
+	}
+	text, err := p.STT.Transcribe(ctx, att)
+	if err != nil {
+		log.Printf("[media] transcribe %s failed: %v", fileName(att), err)
+		return "[Voice message received, but it could not be transcribed]"
+	}
+	if text == "" {
+		return "[Voice message received; no speech was recognized]"
+	}
+	return "[Voice message transcript]\n" + text
+}
+
+// Clip cuts text to limit runes, or DefaultMaxDocumentChars when limit is
+// not positive, noting the cut. It reports whether it cut.
+func Clip(text string, limit int) (string, bool) {
+	if limit <= 0 {
+		limit = DefaultMaxDocumentChars
+	}
+	if utf8.RuneCountInString(text) <= limit {
+		return text, false
+	}
+	runes := []rune(text)
+	return string(runes[:limit]) + fmt.Sprintf("\n[... cut at %d characters]", limit), true
+}
+
+// Document presents the text of the file name to the model, fenced so
+// the model sees where it ends. The fence is longer than any run of
+// backticks in text.
+func Document(name, text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fmt.Sprintf("Document %s:\n%s\n%s\n%s", name, fence, text, fence)
+}
+
+// LooksBinary reports whether data is not text: not UTF-8, or holding NUL
+// bytes, which text files never do.
+func LooksBinary(data []byte) bool {
+	return !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0
+}
+
+// mediaTypeOf returns the media type of att without parameters, detecting
+// it from the content when the sender gave none.
+func mediaTypeOf(att bus.Attachment) string {
+	mediaType := att.MediaType
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(filepath.Ext(att.Name)); byExt != "" {
+			mediaType = byExt
+		} else {
+			mediaType = http.DetectContentType(att.Data)
+		}
+	}
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = parsed
+	}
+	return strings.ToLower(mediaType)
+}
+
+func isText(att bus.Attachment, mediaType string) bool {
+	if LooksBinary(att.Data) {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		mediaType == "application/xml",
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"),
+		strings.Contains(mediaType, "yaml"):
+		return true
+	}
+	return textExtensions[strings.ToLower(filepath.Ext(att.Name))]
+}
+
+// fileName names att for the model and for upload, inventing a name from
+// its media type when the sender gave none.
+func fileName(att bus.Attachment) string {
+	if att.Name != "" {
+		return att.Name
+	}
+	mediaType := mediaTypeOf(att)
+	ext := ""
+	switch mediaType {
+	case "audio/ogg":
+		ext = ".ogg"
+	case "audio/mpeg":
+		ext = ".mp3"
+	default:
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	kind, _, _ := strings.Cut(mediaType, "/")
+	return kind + ext
+}
@@ -0,0 +1,193 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+type fakeTranscriber struct {
+	text string
+	err  error
+}
+
+func (f fakeTranscriber) Transcribe(context.Context, bus.Attachment) (string, error) {
+	return f.text, f.err
+}
+
+func TestProcess(t *testing.T) {
+	t.Parallel()
+
+	p := &Processor{STT: fakeTranscriber{text: "buy milk"}, PDF: true, MaxDocumentChars: 10}
+	notes, blocks := p.Process(context.Background(), []bus.Attachment{
+		{MediaType: "audio/ogg; codecs=opus", Data: []byte("OggS")},
+		{Name: "notes.md", MediaType: "application/octet-stream", Data: []byte("# Plan\nship it on Friday")},
+		{Name: "report.pdf", MediaType: "application/pdf", Data: []byte("%PDF-1.4")},
+		{Name: "photo", Data: []byte("\x89PNG\r\n\x1a\n")},
+		{Name: "archive.zip", MediaType: "application/zip", Data: []byte("PK\x03\x04")},
+	})
+	want := []string{
+		"[Voice message transcript]\nbuy milk",
+		"Document notes.md:\n```\n# Plan\nshi\n[... cut at 10 characters]\n```",
+		"[Attachment archive.zip (application/zip) not readable]",
+	}
+	if strings.Join(notes, "|") != strings.Join(want, "|") {
+		t.Errorf("notes = %q, want %q", notes, want)
+	}
+	if len(blocks) != 2 || blocks[0].Type != model.ContentBlockDocument || blocks[1].Type != model.ContentBlockImage || blocks[1].MediaType != "image/png" {
+		t.Errorf("blocks = %+v", blocks)
+	}
+
+	p = &Processor{STT: fakeTranscriber{err: fmt.Errorf("offline")}}
+	notes, blocks = p.Process(context.Background(), []bus.Attachment{
+		{MediaType: "audio/mpeg", Data: []byte("ID3")},
+		{Name: "report.pdf", MediaType: "application/pdf", Data: []byte("%PDF-1.4")},
+	})
+	if len(blocks) != 0 || !strings.Contains(notes[0], "could not be transcribed") || !strings.Contains(notes[1], "cannot read PDFs") {
+		t.Errorf("notes = %q, blocks = %+v", notes, blocks)
+	}
+	if notes, _ := (&Processor{}).Process(context.Background(), []bus.Attachment{{MediaType: "audio/ogg"}}); !strings.Contains(notes[0], "not configured") {
+		t.Errorf("without STT: %q", notes)
+	}
+}
+
+func TestOpenAITranscriber(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(file)
+		if header.Filename != "audio.ogg" || string(data) != "OggS" || r.FormValue("model") != "whisper-large-v3" || r.FormValue("language") != "en" {
+			t.Errorf("upload %s %q model %q language %q", header.Filename, data, r.FormValue("model"), r.FormValue("language"))
+		}
+		w.Write([]byte(`{"text": " buy milk "}`))
+	}))
+	defer srv.Close()
+
+	stt, err := NewTranscriber(config.STTConfig{Provider: "openai", APIKey: "sk-test", BaseURL: srv.URL + "/v1", Model: "whisper-large-v3", Language: "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := stt.Transcribe(context.Background(), bus.Attachment{MediaType: "audio/ogg", Data: []byte("OggS")})
+	if err != nil || text != "buy milk" {
+		t.Errorf("Transcribe = %q, %v", text, err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"message": "unsupported format"}}`))
+	}))
+	defer failing.Close()
+	stt, _ = NewTranscriber(config.STTConfig{Provider: "openai", APIKey: "k", BaseURL: failing.URL})
+	if _, err := stt.Transcribe(context.Background(), bus.Attachment{MediaType: "audio/ogg"}); err == nil || !strings.Contains(err.Error(), "unsupported format") {
+		t.Errorf("err = %v", err)
+	}
+
+	if stt, err := NewTranscriber(config.STTConfig{}); stt != nil || err != nil {
+		t.Errorf("no provider = %v, %v", stt, err)
+	}
+	for _, cfg := range []config.STTConfig{{Provider: "openai"}, {Provider: "vosk", APIKey: "k"}} {
+		if _, err := NewTranscriber(cfg); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+}
+
+func TestWhisperCPP(t *testing.T) {
+	// A stand-in for whisper-cli that prints its arguments as segments.
+	bin := filepath.Join(t.TempDir(), "whisper-cli")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho \" $1 $2\"\necho \" $5 $6 $7 $8\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stt, err := NewTranscriber(config.STTConfig{Provider: "whispercpp", Command: bin, Model: "ggml-base.en.bin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wav := append([]byte("RIFF\x00\x00\x00\x00WAVEfmt "), make([]byte, 32)...)
+	text, err := stt.Transcribe(context.Background(), bus.Attachment{MediaType: "audio/wav", Data: wav})
+	if err != nil || text != "-m ggml-base.en.bin -l auto -nt -np" {
+		t.Errorf("Transcribe = %q, %v", text, err)
+	}
+	if _, err := NewTranscriber(config.STTConfig{Provider: "whispercpp"}); err == nil {
+		t.Error("whispercpp without a model: expected error")
+	}
+}
+
+func TestDocument(t *testing.T) {
+	if got := Document("a.txt", "hi"); got != "Document a.txt:\n```\nhi\n```" {
+		t.Errorf("Document = %q", got)
+	}
+	// A fence inside the text gets a longer one around it.
+	if got := Document("README.md", "```go\nx\n```"); got != "Document README.md:\n````\n```go\nx\n```\n````" {
+		t.Errorf("Document = %q", got)
+	}
+	if !LooksBinary([]byte("a\x00b")) || !LooksBinary([]byte{0xff, 0xfe}) || LooksBinary([]byte("héllo")) {
+		t.Error("LooksBinary misjudged")
+	}
+}
+
+func TestImageBlock(t *testing.T) {
+	t.Parallel()
+
+	encode := func(w, h int) []byte {
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.Set(x, y, color.NRGBA{uint8(x), uint8(y), 200, 255})
+			}
+		}
+		var buf bytes.Buffer
+		png.Encode(&buf, img)
+		return buf.Bytes()
+	}
+	size := func(block model.ContentBlock) image.Point {
+		data, _ := base64.StdEncoding.DecodeString(block.Data)
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return image.Pt(cfg.Width, cfg.Height)
+	}
+
+	small := encode(40, 30)
+	block, scaled, err := ImageBlock(small)
+	if err != nil || scaled || block.Type != model.ContentBlockImage || block.MediaType != "image/png" {
+		t.Fatalf("small = %+v, %v, %v", block.MediaType, scaled, err)
+	}
+	if block.Data != base64.StdEncoding.EncodeToString(small) {
+		t.Error("small image was changed")
+	}
+
+	block, scaled, err = ImageBlock(encode(2000, 1000))
+	if err != nil || !scaled {
+		t.Fatalf("large = %v, %v", scaled, err)
+	}
+	if got := size(block); got != image.Pt(MaxImageSide, MaxImageSide/2) {
+		t.Errorf("scaled to %v", got)
+	}
+
+	if _, _, err := ImageBlock([]byte("%PDF-1.7")); err == nil {
+		t.Error("expected error for a PDF")
+	}
+}
@@ -0,0 +1,181 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	// DefaultSTTModel is the transcription model used when none is set.
+	DefaultSTTModel = "whisper-1"
+	// DefaultWhisperCommand is the whisper.cpp program run when none is
+	// set.
+	DefaultWhisperCommand = "whisper-cli"
+
+	openAIAPIURL = "https://api.openai.com/v1"
+)
+
+// sttClient sends audio to the speech-to-text service. Voice notes are
+// short, but uploads on slow links take a while.
+var sttClient = &http.Client{Timeout: 2 * time.Minute}
+
+// Transcriber turns speech into text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio bus.Attachment) (string, error)
+}
+
+// NewTranscriber returns the transcriber cfg selects, or nil when speech to
+// text is off.
+func NewTranscriber(cfg config.STTConfig) (Transcriber, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "openai":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("media.stt: openai needs an API key")
+		}
+		t := &openAITranscriber{baseURL: cfg.BaseURL, apiKey: cfg.APIKey, model: cfg.Model, language: cfg.Language}
+		if t.baseURL == "" {
+			t.baseURL = openAIAPIURL
+		}
+		if t.model == "" {
+			t.model = DefaultSTTModel
+		}
+		return t, nil
+	case "whispercpp":
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("media.stt: whispercpp needs the model file")
+		}
+		t := &whisperCPP{command: cfg.Command, model: cfg.Model, language: cfg.Language}
+		if t.command == "" {
+			t.command = DefaultWhisperCommand
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("media.stt: unknown provider %q", cfg.Provider)
+	}
+}
+
+// openAITranscriber calls an OpenAI-compatible /audio/transcriptions
+// endpoint.
+type openAITranscriber struct {
+	baseURL, apiKey, model, language string
+}
+
+func (t *openAITranscriber) Transcribe(ctx context.Context, audio bus.Attachment) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("model", t.model)
+	if t.language != "" {
+		form.WriteField("language", t.language)
+	}
+	part, err := form.CreateFormFile("file", fileName(audio))
+	if err != nil {
+		return "", err
+	}
+	part.Write(audio.Data)
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(t.baseURL, "/")+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	resp, err := sttClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+
+	var out struct {
+		Text  string `json:"text"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.Unmarshal(data, &out)
+	if resp.StatusCode != http.StatusOK {
+		if out.Error.Message != "" {
+			return "", fmt.Errorf("transcribe: %s: %s", resp.Status, out.Error.Message)
+		}
+		return "", fmt.Errorf("transcribe: %s", resp.Status)
+	}
+	return strings.TrimSpace(out.Text), nil
+}
+
+// whisperCPP runs whisper.cpp's command line program on each recording.
+type whisperCPP struct {
+	command, model, language string
+}
+
+func (t *whisperCPP) Transcribe(ctx context.Context, audio bus.Attachment) (string, error) {
+	dir, err := os.MkdirTemp("", "myclaw-stt-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	// whisper.cpp reads 16 kHz WAV; anything else goes through ffmpeg.
+	wav := filepath.Join(dir, "audio.wav")
+	if isWAV(audio.Data) {
+		if err := os.WriteFile(wav, audio.Data, 0o600); err != nil {
+			return "", err
+		}
+	} else {
+		in := filepath.Join(dir, "in"+filepath.Ext(fileName(audio)))
+		if err := os.WriteFile(in, audio.Data, 0o600); err != nil {
+			return "", err
+		}
+		convert := exec.CommandContext(ctx, "ffmpeg", "-nostdin", "-loglevel", "error", "-i", in, "-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le", wav)
+		if out, err := convert.CombinedOutput(); err != nil {
+			if errors.Is(err, exec.ErrNotFound) {
+				return "", errors.New("transcribe: whisper.cpp needs WAV audio; install ffmpeg to convert other formats")
+			}
+			return "", fmt.Errorf("transcribe: convert with ffmpeg: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	language := t.language
+	if language == "" {
+		language = "auto"
+	}
+	cmd := exec.CommandContext(ctx, t.command, "-m", t.model, "-f", wav, "-l", language, "-nt", "-np")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if i := strings.LastIndexByte(msg, '\n'); i >= 0 {
+			msg = msg[i+1:]
+		}
+		return "", fmt.Errorf("transcribe: %s: %v %s", t.command, err, msg)
+	}
+	// Each segment is a line of its own.
+	return strings.Join(strings.Fields(string(out)), " "), nil
+}
+
+// isWAV reports whether data starts with a RIFF WAVE header.
+func isWAV(data []byte) bool {
+	return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WAVE"
+}
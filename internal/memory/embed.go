@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// Embedder turns text into vectors. Name identifies the model; stored
+// vectors from a different embedder are re-computed.
+type Embedder interface {
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// NewEmbedder returns the embedder selected by cfg.Memory.Embedding.
+func NewEmbedder(cfg *config.Config) (Embedder, error) {
+	ec := cfg.Memory.Embedding
+	provider := ec.Provider
+	if provider == "" {
+		provider = "local"
+		if cfg.Provider.Type == "openai" {
+			provider = "openai"
+		}
+	}
+
+	switch provider {
+	case "local":
+		return NewHashEmbedder(0), nil
+	case "openai":
+		if ec.APIKey == "" && cfg.Provider.Type == "openai" {
+			ec.APIKey = cfg.Provider.APIKey
+			if ec.BaseURL == "" {
+				ec.BaseURL = cfg.Provider.BaseURL
+			}
+		}
+		if ec.APIKey == "" {
+			return nil, fmt.Errorf("embedding API key not set (memory.embedding.apiKey or MYCLAW_EMBEDDING_API_KEY)")
+		}
+		return &OpenAIEmbedder{APIKey: ec.APIKey, BaseURL: ec.BaseURL, Model: ec.Model}, nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q (want openai or local)", provider)
+	}
+}
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint.
+type OpenAIEmbedder struct {
+	APIKey  string
+	BaseURL string // default https://api.openai.com/v1
+	Model   string // default config.DefaultEmbeddingModel
+	Client  *http.Client
+}
+
+func (e *OpenAIEmbedder) model() string {
+	if e.Model == "" {
+		return config.DefaultEmbeddingModel
+	}
+	return e.Model
+}
+
+func (e *OpenAIEmbedder) Name() string { return "openai:" + e.model() }
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	base := strings.TrimRight(e.BaseURL, "/")
+	if base == "" {
+		base = "https://api.openai.com/v1"
+	}
+	body, _ := json.Marshal(map[string]any{"model": e.model(), "input": texts})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.APIKey)
+
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("decode embeddings: %w", err)
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index >= 0 && d.Index < len(vectors) {
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("embeddings: missing vector for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+const defaultHashDims = 512
+
+// HashEmbedder is an offline embedder that hashes words and word pairs into
+// a fixed number of buckets. It matches shared vocabulary rather than
+// meaning, but needs no API.
+type HashEmbedder struct {
+	dims int
+}
+
+// NewHashEmbedder creates a hashing embedder; dims <= 0 uses 512.
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = defaultHashDims
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+func (e *HashEmbedder) Name() string { return fmt.Sprintf("local:hash-%d", e.dims) }
+
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, e.dims)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for j, w := range words {
+			e.add(v, w, 1)
+			if j > 0 {
+				e.add(v, words[j-1]+" "+w, 0.5)
+			}
+		}
+		normalize(v)
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func (e *HashEmbedder) add(v []float32, token string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(token))
+	sum := h.Sum64()
+	// The top bit picks the sign so unrelated tokens tend to cancel out.
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	v[sum%uint64(len(v))] += weight
+}
+
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	n := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= n
+	}
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
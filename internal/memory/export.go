@@ -0,0 +1,235 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotVersion is the version of the export format.
+const SnapshotVersion = 1
+
+// Snapshot is a portable copy of MEMORY.md and the journal, including
+// archived journal files.
+type Snapshot struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	LongTerm   string         `json:"longTerm"`
+	Journal    []JournalEntry `json:"journal,omitempty"`
+}
+
+// JournalEntry is one day of journal.
+type JournalEntry struct {
+	Date     string `json:"date"`
+	Archived bool   `json:"archived,omitempty"`
+	Content  string `json:"content"`
+}
+
+// ImportResult describes what Import changed.
+type ImportResult struct {
+	LongTerm bool `json:"longTerm"` // MEMORY.md was changed
+	Journal  int  `json:"journal"`  // journal files written
+}
+
+const (
+	markdownHeader     = "<!-- myclaw memory export v1 -->"
+	markdownFilePrefix = "<!-- file: "
+	markdownFileSuffix = " -->"
+)
+
+// Export reads MEMORY.md and every journal file into a Snapshot.
+func (m *MemoryStore) Export() (*Snapshot, error) {
+	longTerm, err := m.ReadLongTerm()
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{Version: SnapshotVersion, ExportedAt: time.Now().UTC(), LongTerm: longTerm}
+	for _, archived := range []bool{true, false} {
+		dir := m.journalDir(archived)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			date, ok := strings.CutSuffix(e.Name(), ".md")
+			if !ok || e.IsDir() || !isDate(date) {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			s.Journal = append(s.Journal, JournalEntry{Date: date, Archived: archived, Content: string(data)})
+		}
+	}
+	sort.SliceStable(s.Journal, func(i, j int) bool { return s.Journal[i].Date < s.Journal[j].Date })
+	return s, nil
+}
+
+// Import writes a snapshot into the store. Unless replace is set, it merges:
+// lines that are not already in MEMORY.md or the day's journal file are
+// appended to it, so importing the same snapshot twice changes nothing.
+// With replace, MEMORY.md and the journal files in the snapshot are
+// overwritten; other journal files are left alone.
+func (m *MemoryStore) Import(s *Snapshot, replace bool) (ImportResult, error) {
+	var result ImportResult
+	if s.Version > SnapshotVersion {
+		return result, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	changed, err := importFile(m.LongTermPath(), s.LongTerm, replace)
+	if err != nil {
+		return result, err
+	}
+	result.LongTerm = changed
+	for _, entry := range s.Journal {
+		if !isDate(entry.Date) {
+			return result, fmt.Errorf("invalid journal date %q", entry.Date)
+		}
+		changed, err := importFile(filepath.Join(m.journalDir(entry.Archived), entry.Date+".md"), entry.Content, replace)
+		if err != nil {
+			return result, err
+		}
+		if changed {
+			result.Journal++
+		}
+	}
+	return result, nil
+}
+
+func (m *MemoryStore) journalDir(archived bool) string {
+	if archived {
+		return filepath.Join(m.memoryDir(), "archive")
+	}
+	return m.memoryDir()
+}
+
+// importFile writes content to path, or merges it in, and reports whether
+// the file changed.
+func importFile(path, content string, replace bool) (bool, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	merged := content
+	if !replace {
+		merged = mergeLines(string(existing), content)
+	}
+	if merged == string(existing) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, []byte(merged), 0644)
+}
+
+// mergeLines appends the non-blank lines of incoming that existing lacks.
+func mergeLines(existing, incoming string) string {
+	if strings.TrimSpace(existing) == "" {
+		return incoming
+	}
+	have := make(map[string]bool)
+	for _, line := range strings.Split(existing, "\n") {
+		have[strings.TrimSpace(line)] = true
+	}
+	var missing []string
+	for _, line := range strings.Split(incoming, "\n") {
+		if t := strings.TrimSpace(line); t != "" && !have[t] {
+			have[t] = true
+			missing = append(missing, line)
+		}
+	}
+	if len(missing) == 0 {
+		return existing
+	}
+	if !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+	return existing + strings.Join(missing, "\n") + "\n"
+}
+
+// MarshalMarkdown renders the snapshot as one markdown document. Each file
+// starts with an HTML comment naming it, so the document reads naturally and
+// can still be imported.
+func (s *Snapshot) MarshalMarkdown() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(markdownHeader + "\n")
+	writeFile := func(name, content string) {
+		buf.WriteString("\n" + markdownFilePrefix + name + markdownFileSuffix + "\n")
+		buf.WriteString(content)
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			buf.WriteString("\n")
+		}
+	}
+	writeFile("MEMORY.md", s.LongTerm)
+	for _, e := range s.Journal {
+		name := e.Date + ".md"
+		if e.Archived {
+			name = "archive/" + name
+		}
+		writeFile(name, e.Content)
+	}
+	return buf.Bytes()
+}
+
+// ParseSnapshot reads a snapshot written by json.Marshal or MarshalMarkdown.
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var s Snapshot
+		if err := json.Unmarshal(trimmed, &s); err != nil {
+			return nil, fmt.Errorf("parse snapshot: %w", err)
+		}
+		return &s, nil
+	}
+	if !bytes.HasPrefix(data, []byte(markdownHeader)) {
+		return nil, fmt.Errorf("parse snapshot: not a myclaw memory export")
+	}
+
+	s := &Snapshot{Version: SnapshotVersion}
+	var name string
+	var content strings.Builder
+	flush := func() error {
+		text := content.String()
+		content.Reset()
+		date := strings.TrimSuffix(strings.TrimPrefix(name, "archive/"), ".md")
+		switch {
+		case name == "":
+		case name == "MEMORY.md":
+			s.LongTerm = text
+		case isDate(date):
+			s.Journal = append(s.Journal, JournalEntry{Date: date, Archived: strings.HasPrefix(name, "archive/"), Content: text})
+		default:
+			return fmt.Errorf("parse snapshot: unexpected file %q", name)
+		}
+		return nil
+	}
+	for _, line := range strings.SplitAfter(string(data), "\n")[1:] {
+		t := strings.TrimSpace(line)
+		if !strings.HasPrefix(t, markdownFilePrefix) || !strings.HasSuffix(t, markdownFileSuffix) {
+			content.WriteString(line)
+			continue
+		}
+		// Drop the blank line that separates files.
+		if c := content.String(); strings.HasSuffix(c, "\n") {
+			content.Reset()
+			content.WriteString(strings.TrimSuffix(c, "\n"))
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		name = strings.TrimSuffix(strings.TrimPrefix(t, markdownFilePrefix), markdownFileSuffix)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ExtractHeading is the MEMORY.md section that extracted facts go under.
+const ExtractHeading = "## Noted automatically"
+
+const extractPrompt = `Read this exchange between the user and the assistant. List any durable facts
+worth remembering for future conversations: the user's preferences, people
+and relationships, important dates, and details of ongoing projects. Skip
+small talk, one-off requests and anything already in the known facts. Reply
+with one markdown bullet point per fact, or the single word ` + rollupNothing + `.
+
+# Known facts
+%s
+# User
+%s
+
+# Assistant
+%s`
+
+// extractMu serializes extractions so concurrent conversations neither share
+// the extraction session nor interleave their writes to MEMORY.md.
+var extractMu sync.Mutex
+
+// Extract asks summarize for durable facts from one exchange and appends the
+// ones not already in MEMORY.md. It returns the facts it added.
+func (m *MemoryStore) Extract(ctx context.Context, user, reply string, summarize Summarizer) ([]string, error) {
+	if strings.TrimSpace(user) == "" {
+		return nil, nil
+	}
+	extractMu.Lock()
+	defer extractMu.Unlock()
+
+	longTerm, err := m.ReadLongTerm()
+	if err != nil {
+		return nil, err
+	}
+	answer, err := summarize(ctx, fmt.Sprintf(extractPrompt, longTerm, user, reply))
+	if err != nil {
+		return nil, fmt.Errorf("extract facts: %w", err)
+	}
+
+	var known []string
+	for _, line := range strings.Split(longTerm, "\n") {
+		if n := normalizeFact(line); n != "" {
+			known = append(known, n)
+		}
+	}
+	var added []string
+	for _, line := range strings.Split(answer, "\n") {
+		fact := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•"))
+		n := normalizeFact(fact)
+		if n == "" || strings.EqualFold(strings.Trim(fact, ". "), rollupNothing) || isKnownFact(n, known) {
+			continue
+		}
+		known = append(known, n)
+		added = append(added, fact)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	var sb strings.Builder
+	if lastHeading(longTerm) != ExtractHeading {
+		if strings.TrimSpace(longTerm) != "" {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(ExtractHeading + "\n")
+	}
+	for i, fact := range added {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("- " + fact)
+	}
+	if err := m.AppendLongTerm(sb.String()); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// normalizeFact lowercases s and reduces it to words, so that facts differing
+// only in case, punctuation or bullet style compare equal.
+func normalizeFact(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "#") {
+		return ""
+	}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+func isKnownFact(fact string, known []string) bool {
+	for _, k := range known {
+		if k == fact || strings.Contains(k, fact) {
+			return true
+		}
+	}
+	return false
+}
+
+func lastHeading(content string) string {
+	lines := strings.Split(content, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
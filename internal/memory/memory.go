@@ -27,8 +27,12 @@ func (m *MemoryStore) ensureDir() error {
 
 // Long-term memory
 
+func (m *MemoryStore) LongTermPath() string {
+	return filepath.Join(m.memoryDir(), "MEMORY.md")
+}
+
 func (m *MemoryStore) ReadLongTerm() (string, error) {
-	data, err := os.ReadFile(filepath.Join(m.memoryDir(), "MEMORY.md"))
+	data, err := os.ReadFile(m.LongTermPath())
 	if err != nil {
 		if os.IsNotExist(err) {
 			return "", nil
@@ -42,7 +46,46 @@ func (m *MemoryStore) WriteLongTerm(content string) error {
 	if err := m.ensureDir(); err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(m.memoryDir(), "MEMORY.md"), []byte(content), 0644)
+	return os.WriteFile(m.LongTermPath(), []byte(content), 0644)
+}
+
+// AppendLongTerm adds content to the end of MEMORY.md on a line of its own.
+func (m *MemoryStore) AppendLongTerm(content string) error {
+	existing, err := m.ReadLongTerm()
+	if err != nil {
+		return err
+	}
+	if existing != "" && !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+	return m.WriteLongTerm(existing + content + "\n")
+}
+
+// Clear empties MEMORY.md. With all set it also deletes the journal and the
+// semantic index.
+func (m *MemoryStore) Clear(all bool) error {
+	if err := m.WriteLongTerm(""); err != nil {
+		return err
+	}
+	if !all {
+		return nil
+	}
+	entries, err := os.ReadDir(m.memoryDir())
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if name == "MEMORY.md" || e.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(name, ".md") || strings.HasPrefix(name, "vectors.db") {
+			if err := os.Remove(filepath.Join(m.memoryDir(), name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // Daily journal
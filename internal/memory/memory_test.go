@@ -1,6 +1,9 @@
 package memory
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -234,3 +237,223 @@ func TestTodayFile(t *testing.T) {
 		t.Errorf("todayFile = %q, want %q", ms.todayFile(), expected)
 	}
 }
+
+func TestAppendLongTerm(t *testing.T) {
+	ms := NewMemoryStore(t.TempDir())
+	ms.WriteLongTerm("# Facts\n- likes tea")
+	if err := ms.AppendLongTerm("- lives in Lisbon"); err != nil {
+		t.Fatalf("AppendLongTerm error: %v", err)
+	}
+	content, _ := ms.ReadLongTerm()
+	if content != "# Facts\n- likes tea\n- lives in Lisbon\n" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestClear(t *testing.T) {
+	tmpDir := t.TempDir()
+	ms := NewMemoryStore(tmpDir)
+	ms.WriteLongTerm("facts")
+	ms.AppendToday("journal entry")
+	os.WriteFile(filepath.Join(tmpDir, "memory", "vectors.db"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "memory", "notes.txt"), []byte("keep"), 0644)
+
+	if err := ms.Clear(false); err != nil {
+		t.Fatal(err)
+	}
+	if lt, _ := ms.ReadLongTerm(); lt != "" {
+		t.Errorf("long-term = %q", lt)
+	}
+	if today, _ := ms.ReadToday(); today == "" {
+		t.Error("journal should survive Clear(false)")
+	}
+
+	if err := ms.Clear(true); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(tmpDir, "memory"))
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, ",") != "MEMORY.md,notes.txt" {
+		t.Errorf("remaining files = %v", names)
+	}
+}
+
+func TestRollup(t *testing.T) {
+	tmpDir := t.TempDir()
+	ms := NewMemoryStore(tmpDir)
+	ms.WriteLongTerm("# Facts\n- likes tea")
+	dir := filepath.Join(tmpDir, "memory")
+	for date, content := range map[string]string{
+		"2026-03-01": "Started a pottery class.",
+		"2026-03-05": "",
+		"2026-03-09": "Call with the landlord.",
+		"2026-03-10": "Today's notes.",
+	} {
+		os.WriteFile(filepath.Join(dir, date+".md"), []byte(content), 0644)
+	}
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+
+	var prompt string
+	result, err := ms.Rollup(context.Background(), 2, now, func(ctx context.Context, p string) (string, error) {
+		prompt = p
+		return "- Takes a pottery class", nil
+	})
+	if err != nil {
+		t.Fatalf("Rollup error: %v", err)
+	}
+	if strings.Join(result.Days, ",") != "2026-03-01,2026-03-05" {
+		t.Errorf("days = %v", result.Days)
+	}
+	if !strings.Contains(prompt, "pottery") || !strings.Contains(prompt, "likes tea") || strings.Contains(prompt, "landlord") {
+		t.Errorf("prompt = %q", prompt)
+	}
+	lt, _ := ms.ReadLongTerm()
+	if !strings.HasSuffix(lt, "## From the journal (2026-03-01 to 2026-03-05)\n- Takes a pottery class\n") {
+		t.Errorf("long-term = %q", lt)
+	}
+	for _, date := range []string{"2026-03-01", "2026-03-05"} {
+		if _, err := os.Stat(filepath.Join(dir, "archive", date+".md")); err != nil {
+			t.Errorf("%s not archived: %v", date, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2026-03-09.md")); err != nil {
+		t.Errorf("recent journal should stay: %v", err)
+	}
+
+	// Nothing left to roll up; nothing worth keeping leaves MEMORY.md alone.
+	result, _ = ms.Rollup(context.Background(), 2, now, nil)
+	if len(result.Days) != 0 {
+		t.Errorf("second run days = %v", result.Days)
+	}
+	result, err = ms.Rollup(context.Background(), 1, now, func(ctx context.Context, p string) (string, error) {
+		return "NOTHING.", nil
+	})
+	if err != nil || len(result.Days) != 1 || result.Added != "" {
+		t.Errorf("result = %+v, %v", result, err)
+	}
+	if after, _ := ms.ReadLongTerm(); after != lt {
+		t.Errorf("long-term changed: %q", after)
+	}
+
+	_, err = ms.Rollup(context.Background(), 0, now.AddDate(0, 0, 1), func(ctx context.Context, p string) (string, error) {
+		return "", fmt.Errorf("offline")
+	})
+	if err == nil {
+		t.Error("expected summarize error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2026-03-10.md")); err != nil {
+		t.Errorf("journal should not be archived when summarizing fails: %v", err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	ms := NewMemoryStore(t.TempDir())
+	ms.WriteLongTerm("# About\n- Lives in Lisbon.")
+
+	var prompt string
+	facts, err := ms.Extract(context.Background(), "remind me, my sister Anna visits on May 3", "Noted!", func(ctx context.Context, p string) (string, error) {
+		prompt = p
+		return "- Sister is called Anna\n* anna visits on May 3\n- lives in lisbon\n- Sister is called Anna.", nil
+	})
+	if err != nil {
+		t.Fatalf("Extract error: %v", err)
+	}
+	if len(facts) != 2 {
+		t.Errorf("facts = %q", facts)
+	}
+	if !strings.Contains(prompt, "Lives in Lisbon") || !strings.Contains(prompt, "my sister Anna") {
+		t.Errorf("prompt = %q", prompt)
+	}
+	lt, _ := ms.ReadLongTerm()
+	want := "# About\n- Lives in Lisbon.\n\n" + ExtractHeading + "\n- Sister is called Anna\n- anna visits on May 3\n"
+	if lt != want {
+		t.Errorf("long-term = %q, want %q", lt, want)
+	}
+
+	// Later facts go under the same heading; known facts and NOTHING add nothing.
+	ms.Extract(context.Background(), "I switched to tea", "", func(ctx context.Context, p string) (string, error) {
+		return "- Drinks tea, not coffee", nil
+	})
+	if lt, _ = ms.ReadLongTerm(); strings.Count(lt, ExtractHeading) != 1 || !strings.HasSuffix(lt, "- anna visits on May 3\n- Drinks tea, not coffee\n") {
+		t.Errorf("long-term = %q", lt)
+	}
+	for _, answer := range []string{"NOTHING", "- drinks tea"} {
+		facts, err = ms.Extract(context.Background(), "hi", "hello", func(ctx context.Context, p string) (string, error) {
+			return answer, nil
+		})
+		if err != nil || facts != nil {
+			t.Errorf("answer %q: facts = %q, %v", answer, facts, err)
+		}
+	}
+
+	if facts, _ := ms.Extract(context.Background(), " ", "", nil); facts != nil {
+		t.Errorf("empty message facts = %q", facts)
+	}
+	if _, err := ms.Extract(context.Background(), "hi", "", func(ctx context.Context, p string) (string, error) {
+		return "", fmt.Errorf("offline")
+	}); err == nil {
+		t.Error("expected summarize error")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	src := NewMemoryStore(t.TempDir())
+	src.WriteLongTerm("# Facts\n- Likes jazz\n")
+	os.WriteFile(filepath.Join(src.memoryDir(), "2026-03-02.md"), []byte("Called the plumber.\n"), 0644)
+	os.MkdirAll(filepath.Join(src.memoryDir(), "archive"), 0755)
+	os.WriteFile(filepath.Join(src.memoryDir(), "archive", "2026-01-05.md"), []byte("New year plans."), 0644)
+	os.WriteFile(filepath.Join(src.memoryDir(), "notes.md"), []byte("not a journal file"), 0644)
+
+	snap, err := src.Export()
+	if err != nil {
+		t.Fatalf("Export error: %v", err)
+	}
+	if len(snap.Journal) != 2 || snap.Journal[0].Date != "2026-01-05" || !snap.Journal[0].Archived || snap.Journal[1].Archived {
+		t.Fatalf("journal = %+v", snap.Journal)
+	}
+
+	// Both formats round-trip.
+	data, _ := json.Marshal(snap)
+	for name, data := range map[string][]byte{"json": data, "md": snap.MarshalMarkdown()} {
+		got, err := ParseSnapshot(data)
+		if err != nil {
+			t.Fatalf("%s: ParseSnapshot error: %v", name, err)
+		}
+		if got.LongTerm != snap.LongTerm || len(got.Journal) != 2 || got.Journal[1] != snap.Journal[1] {
+			t.Errorf("%s: parsed = %+v", name, got)
+		}
+	}
+	if _, err := ParseSnapshot([]byte("# just some notes")); err == nil {
+		t.Error("expected error for unknown format")
+	}
+
+	// Merging keeps local lines and adds missing ones, once.
+	dst := NewMemoryStore(t.TempDir())
+	dst.WriteLongTerm("# Facts\n- Has two kids\n")
+	result, err := dst.Import(snap, false)
+	if err != nil || !result.LongTerm || result.Journal != 2 {
+		t.Fatalf("Import = %+v, %v", result, err)
+	}
+	if lt, _ := dst.ReadLongTerm(); lt != "# Facts\n- Has two kids\n- Likes jazz\n" {
+		t.Errorf("merged long-term = %q", lt)
+	}
+	if _, err := os.Stat(filepath.Join(dst.memoryDir(), "archive", "2026-01-05.md")); err != nil {
+		t.Errorf("archived journal not imported: %v", err)
+	}
+	if result, _ := dst.Import(snap, false); result.LongTerm || result.Journal != 0 {
+		t.Errorf("second import changed %+v", result)
+	}
+
+	result, _ = dst.Import(snap, true)
+	if lt, _ := dst.ReadLongTerm(); !result.LongTerm || lt != snap.LongTerm {
+		t.Errorf("replaced long-term = %q", lt)
+	}
+
+	snap.Journal = []JournalEntry{{Date: "../../etc/passwd"}}
+	if _, err := dst.Import(snap, false); err == nil {
+		t.Error("expected error for bad journal date")
+	}
+}
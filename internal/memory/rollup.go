@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Summarizer answers a prompt, usually by running the agent.
+type Summarizer func(ctx context.Context, prompt string) (string, error)
+
+// RollupResult describes one roll-up run.
+type RollupResult struct {
+	Days  []string // dates of the journal files rolled up
+	Added string   // text appended to MEMORY.md; empty if nothing was worth keeping
+}
+
+const rollupNothing = "NOTHING"
+
+const rollupPrompt = `Below are daily journal entries that are about to be archived, followed by the
+current long-term memory. List the facts from the journal that are worth
+remembering long-term (preferences, people, decisions, ongoing projects,
+commitments) and are not already in long-term memory. Reply with markdown
+bullet points only, or with the single word ` + rollupNothing + ` if there is nothing new.
+
+# Journal
+%s
+# Long-term memory
+%s`
+
+// Rollup summarizes journal files older than keepDays into MEMORY.md and
+// moves them to memory/archive/. Today's file is never rolled up.
+func (m *MemoryStore) Rollup(ctx context.Context, keepDays int, now time.Time, summarize Summarizer) (RollupResult, error) {
+	var result RollupResult
+	if keepDays < 1 {
+		keepDays = 1
+	}
+	cutoff := now.AddDate(0, 0, -keepDays+1).Format("2006-01-02")
+
+	entries, err := os.ReadDir(m.memoryDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, err
+	}
+	var journal strings.Builder
+	for _, e := range entries {
+		date, ok := strings.CutSuffix(e.Name(), ".md")
+		if !ok || e.IsDir() || !isDate(date) || date >= cutoff {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.memoryDir(), e.Name()))
+		if err != nil {
+			return result, err
+		}
+		result.Days = append(result.Days, date)
+		if content := strings.TrimSpace(string(data)); content != "" {
+			fmt.Fprintf(&journal, "## %s\n%s\n\n", date, content)
+		}
+	}
+	if len(result.Days) == 0 {
+		return result, nil
+	}
+	sort.Strings(result.Days)
+
+	if journal.Len() > 0 {
+		longTerm, err := m.ReadLongTerm()
+		if err != nil {
+			return result, err
+		}
+		summary, err := summarize(ctx, fmt.Sprintf(rollupPrompt, journal.String(), longTerm))
+		if err != nil {
+			return result, fmt.Errorf("summarize journal: %w", err)
+		}
+		summary = strings.TrimSpace(summary)
+		if summary != "" && !strings.EqualFold(strings.Trim(summary, ". "), rollupNothing) {
+			heading := fmt.Sprintf("## From the journal (%s to %s)", result.Days[0], result.Days[len(result.Days)-1])
+			if err := m.AppendLongTerm("\n" + heading + "\n" + summary); err != nil {
+				return result, err
+			}
+			result.Added = summary
+		}
+	}
+
+	archive := filepath.Join(m.memoryDir(), "archive")
+	if err := os.MkdirAll(archive, 0755); err != nil {
+		return result, err
+	}
+	for _, date := range result.Days {
+		if err := os.Rename(filepath.Join(m.memoryDir(), date+".md"), filepath.Join(archive, date+".md")); err != nil {
+			return result, fmt.Errorf("archive %s: %w", date, err)
+		}
+	}
+	return result, nil
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// syncIgnore keeps the search index, which is rebuilt from the markdown files
+// on each machine, out of the repository.
+const syncIgnore = "vectors.db*\n*.bak\n"
+
+// GitSync keeps the memory directory in a git repository.
+type GitSync struct {
+	Dir    string
+	Remote string // pushed to and pulled from when set
+	Branch string
+
+	env []string // committer identity when git has none configured
+}
+
+// SyncResult describes one sync.
+type SyncResult struct {
+	Committed bool `json:"committed"` // local changes were committed
+	Pulled    bool `json:"pulled"`    // remote changes were merged
+	Pushed    bool `json:"pushed"`
+}
+
+// NewGitSync returns a GitSync for the memory directory of workspace.
+func NewGitSync(workspace string, cfg config.SyncConfig) *GitSync {
+	branch := cfg.Branch
+	if branch == "" {
+		branch = config.DefaultSyncBranch
+	}
+	return &GitSync{Dir: filepath.Join(workspace, "memory"), Remote: cfg.Remote, Branch: branch}
+}
+
+// Init creates the repository if needed and points origin at Remote.
+func (s *GitSync) Init(ctx context.Context) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, ".git")); os.IsNotExist(err) {
+		if _, err := s.git(ctx, "init", "-q", "-b", s.Branch); err != nil {
+			return err
+		}
+	}
+	if email, err := s.git(ctx, "config", "user.email"); err != nil || email == "" {
+		s.env = []string{
+			"GIT_AUTHOR_NAME=myclaw", "GIT_AUTHOR_EMAIL=myclaw@localhost",
+			"GIT_COMMITTER_NAME=myclaw", "GIT_COMMITTER_EMAIL=myclaw@localhost",
+		}
+	}
+	ignore := filepath.Join(s.Dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := os.WriteFile(ignore, []byte(syncIgnore), 0644); err != nil {
+			return err
+		}
+	}
+	if s.Remote == "" {
+		return nil
+	}
+	if current, err := s.git(ctx, "remote", "get-url", "origin"); err != nil {
+		_, err = s.git(ctx, "remote", "add", "origin", s.Remote)
+		return err
+	} else if current != s.Remote {
+		_, err = s.git(ctx, "remote", "set-url", "origin", s.Remote)
+		return err
+	}
+	return nil
+}
+
+// Sync commits local changes, then rebases them onto the remote branch and
+// pushes. If the rebase conflicts it is aborted, leaving the local commit in
+// place, and an error is returned so the conflict can be resolved by hand.
+func (s *GitSync) Sync(ctx context.Context) (SyncResult, error) {
+	var result SyncResult
+	if err := s.Init(ctx); err != nil {
+		return result, fmt.Errorf("memory sync: %w", err)
+	}
+	var remoteHead string
+	if s.Remote != "" {
+		heads, err := s.git(ctx, "ls-remote", "--heads", "origin", s.Branch)
+		if err != nil {
+			return result, fmt.Errorf("memory sync: %w", err)
+		}
+		remoteHead, _, _ = strings.Cut(heads, "\t")
+	}
+	if _, err := s.git(ctx, "rev-parse", "--verify", "-q", "HEAD"); err != nil && remoteHead != "" {
+		if err := s.adoptRemote(ctx); err != nil {
+			return result, fmt.Errorf("memory sync: %w", err)
+		}
+		result.Pulled = true
+	}
+
+	if _, err := s.git(ctx, "add", "-A"); err != nil {
+		return result, fmt.Errorf("memory sync: %w", err)
+	}
+	status, err := s.git(ctx, "status", "--porcelain")
+	if err != nil {
+		return result, fmt.Errorf("memory sync: %w", err)
+	}
+	if status != "" {
+		msg := "Update memory " + time.Now().Format("2006-01-02 15:04")
+		if _, err := s.git(ctx, "commit", "-q", "-m", msg); err != nil {
+			return result, fmt.Errorf("memory sync: %w", err)
+		}
+		result.Committed = true
+	}
+	if s.Remote == "" {
+		return result, nil
+	}
+
+	if remoteHead != "" {
+		before, _ := s.git(ctx, "rev-parse", "HEAD")
+		if _, err := s.git(ctx, "pull", "-q", "--rebase", "origin", s.Branch); err != nil {
+			s.git(ctx, "rebase", "--abort")
+			return result, fmt.Errorf("memory sync: could not merge remote changes, resolve them in %s: %w", s.Dir, err)
+		}
+		after, _ := s.git(ctx, "rev-parse", "HEAD")
+		result.Pulled = result.Pulled || before != after
+	}
+	if head, _ := s.git(ctx, "rev-parse", "HEAD"); head != remoteHead {
+		if _, err := s.git(ctx, "push", "-q", "origin", "HEAD:"+s.Branch); err != nil {
+			return result, fmt.Errorf("memory sync: %w", err)
+		}
+		result.Pushed = true
+	}
+	return result, nil
+}
+
+// adoptRemote starts a new repository from the remote history, so that the
+// files here become changes on top of it rather than an unrelated history.
+// Files missing here are checked out; files on both sides are merged line by
+// line like an import.
+func (s *GitSync) adoptRemote(ctx context.Context) error {
+	if _, err := s.git(ctx, "fetch", "-q", "origin", s.Branch); err != nil {
+		return err
+	}
+	if _, err := s.git(ctx, "reset", "-q", "FETCH_HEAD"); err != nil {
+		return err
+	}
+	deleted, err := s.git(ctx, "ls-files", "--deleted")
+	if err != nil {
+		return err
+	}
+	if deleted != "" {
+		if _, err := s.git(ctx, append([]string{"checkout", "--"}, strings.Split(deleted, "\n")...)...); err != nil {
+			return err
+		}
+	}
+	modified, err := s.git(ctx, "ls-files", "--modified")
+	if err != nil || modified == "" {
+		return err
+	}
+	for _, name := range strings.Split(modified, "\n") {
+		remote, err := s.git(ctx, "show", "HEAD:"+name)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(s.Dir, name)
+		local, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(mergeLines(remote+"\n", string(local))), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *GitSync) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = s.Dir
+	cmd.Env = append(os.Environ(), s.env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestGitSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "memory.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	ctx := context.Background()
+	cfg := config.SyncConfig{Enabled: true, Remote: remote}
+
+	wsA := t.TempDir()
+	a := NewMemoryStore(wsA)
+	a.WriteLongTerm("- Likes jazz\n")
+	os.WriteFile(filepath.Join(wsA, "memory", "vectors.db"), []byte("index"), 0644)
+	result, err := NewGitSync(wsA, cfg).Sync(ctx)
+	if err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if !result.Committed || !result.Pushed || result.Pulled {
+		t.Errorf("first sync = %+v", result)
+	}
+
+	// A second machine with memory of its own adopts the remote history.
+	wsB := t.TempDir()
+	b := NewMemoryStore(wsB)
+	b.AppendToday("Met Sam for lunch.")
+	result, err = NewGitSync(wsB, cfg).Sync(ctx)
+	if err != nil {
+		t.Fatalf("second machine sync: %v", err)
+	}
+	if !result.Committed || !result.Pulled || !result.Pushed {
+		t.Errorf("second machine sync = %+v", result)
+	}
+	if lt, _ := b.ReadLongTerm(); lt != "- Likes jazz\n" {
+		t.Errorf("second machine long-term = %q", lt)
+	}
+	if _, err := os.Stat(filepath.Join(wsB, "memory", "vectors.db")); !os.IsNotExist(err) {
+		t.Errorf("search index should not be synced: %v", err)
+	}
+
+	// Changes on both sides to different files are merged.
+	a.AppendLongTerm("- Has a dog")
+	b.AppendToday("Booked flights.")
+	if _, err := NewGitSync(wsB, cfg).Sync(ctx); err != nil {
+		t.Fatalf("sync B: %v", err)
+	}
+	result, err = NewGitSync(wsA, cfg).Sync(ctx)
+	if err != nil || !result.Committed || !result.Pulled || !result.Pushed {
+		t.Fatalf("sync A = %+v, %v", result, err)
+	}
+	if today, _ := a.ReadToday(); today == "" {
+		t.Error("journal from B not pulled into A")
+	}
+
+	// Conflicting edits fail without leaving a rebase in progress.
+	a.AppendLongTerm("- Plays chess")
+	b.AppendLongTerm("- Plays go")
+	if _, err := NewGitSync(wsA, cfg).Sync(ctx); err != nil {
+		t.Fatalf("sync A: %v", err)
+	}
+	if result, err := NewGitSync(wsB, cfg).Sync(ctx); err == nil {
+		t.Errorf("conflicting sync = %+v, want error", result)
+	}
+	if _, err := os.Stat(filepath.Join(wsB, "memory", ".git", "rebase-merge")); !os.IsNotExist(err) {
+		t.Error("rebase left in progress")
+	}
+
+	// Nothing to do when nothing changed.
+	if result, err := NewGitSync(wsA, cfg).Sync(ctx); err != nil || result != (SyncResult{}) {
+		t.Errorf("idle sync = %+v, %v", result, err)
+	}
+}
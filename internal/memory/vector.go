@@ -0,0 +1,321 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	maxChunkChars  = 1200
+	embedBatchSize = 64
+)
+
+const vectorSchema = `
+CREATE TABLE IF NOT EXISTS chunks (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	source   TEXT NOT NULL,
+	heading  TEXT NOT NULL,
+	content  TEXT NOT NULL,
+	hash     TEXT NOT NULL,
+	embedder TEXT NOT NULL,
+	vector   BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS chunks_hash ON chunks(embedder, hash);
+`
+
+// VectorStore indexes MEMORY.md and the daily journal in SQLite so memory
+// can be searched by meaning. The index lives in memory/vectors.db and is
+// brought up to date before every search, so files can be edited freely.
+type VectorStore struct {
+	mem      *MemoryStore
+	db       *sql.DB
+	embedder Embedder
+}
+
+// SearchResult is one memory chunk matching a query.
+type SearchResult struct {
+	Source  string  `json:"source"`
+	Heading string  `json:"heading,omitempty"`
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+}
+
+type memoryChunk struct {
+	source, heading, content, hash string
+}
+
+// OpenVectorStore opens (and creates if needed) the vector index for workspace.
+func OpenVectorStore(workspace string, embedder Embedder) (*VectorStore, error) {
+	mem := NewMemoryStore(workspace)
+	if err := mem.ensureDir(); err != nil {
+		return nil, err
+	}
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", filepath.ToSlash(filepath.Join(mem.memoryDir(), "vectors.db")))
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open memory index: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(vectorSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("init memory index: %w", err)
+	}
+	return &VectorStore{mem: mem, db: db, embedder: embedder}, nil
+}
+
+// OpenConfigured opens the vector store with the embedder chosen by cfg.
+func OpenConfigured(cfg *config.Config) (*VectorStore, error) {
+	embedder, err := NewEmbedder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return OpenVectorStore(cfg.MemoryWorkspace(), embedder)
+}
+
+func (v *VectorStore) Close() error {
+	return v.db.Close()
+}
+
+// Sync re-indexes changed memory files. Unchanged chunks keep their vectors,
+// so only new or edited text is sent to the embedder. It returns the number
+// of chunks embedded.
+func (v *VectorStore) Sync(ctx context.Context) (int, error) {
+	chunks, err := v.mem.chunks()
+	if err != nil {
+		return 0, err
+	}
+	name := v.embedder.Name()
+
+	if _, err := v.db.ExecContext(ctx, `DELETE FROM chunks WHERE embedder != ?`, name); err != nil {
+		return 0, fmt.Errorf("sync memory index: %w", err)
+	}
+	rows, err := v.db.QueryContext(ctx, `SELECT id, hash FROM chunks`)
+	if err != nil {
+		return 0, fmt.Errorf("sync memory index: %w", err)
+	}
+	indexed := make(map[string]int64)
+	var stale []int64
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("sync memory index: %w", err)
+		}
+		if _, dup := indexed[hash]; dup {
+			stale = append(stale, id)
+			continue
+		}
+		indexed[hash] = id
+	}
+	rows.Close()
+
+	var missing []memoryChunk
+	current := make(map[string]bool, len(chunks))
+	for _, c := range chunks {
+		current[c.hash] = true
+		if _, ok := indexed[c.hash]; !ok {
+			missing = append(missing, c)
+		}
+	}
+	for hash, id := range indexed {
+		if !current[hash] {
+			stale = append(stale, id)
+		}
+	}
+	for _, id := range stale {
+		if _, err := v.db.ExecContext(ctx, `DELETE FROM chunks WHERE id = ?`, id); err != nil {
+			return 0, fmt.Errorf("sync memory index: %w", err)
+		}
+	}
+
+	for start := 0; start < len(missing); start += embedBatchSize {
+		batch := missing[start:min(start+embedBatchSize, len(missing))]
+		texts := make([]string, len(batch))
+		for i, c := range batch {
+			texts[i] = c.heading + "\n" + c.content
+		}
+		vectors, err := v.embedder.Embed(ctx, texts)
+		if err != nil {
+			return start, err
+		}
+		for i, c := range batch {
+			_, err := v.db.ExecContext(ctx, `INSERT INTO chunks (source, heading, content, hash, embedder, vector) VALUES (?, ?, ?, ?, ?, ?)`,
+				c.source, c.heading, c.content, c.hash, name, encodeVector(vectors[i]))
+			if err != nil {
+				return start, fmt.Errorf("sync memory index: %w", err)
+			}
+		}
+	}
+	return len(missing), nil
+}
+
+// Search syncs the index and returns the limit chunks closest to query.
+// Chunks with no similarity at all are left out.
+func (v *VectorStore) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	if _, err := v.Sync(ctx); err != nil {
+		return nil, err
+	}
+	vectors, err := v.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	q := vectors[0]
+
+	rows, err := v.db.QueryContext(ctx, `SELECT source, heading, content, vector FROM chunks WHERE embedder = ?`, v.embedder.Name())
+	if err != nil {
+		return nil, fmt.Errorf("search memory: %w", err)
+	}
+	defer rows.Close()
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var blob []byte
+		if err := rows.Scan(&r.Source, &r.Heading, &r.Content, &blob); err != nil {
+			return nil, fmt.Errorf("search memory: %w", err)
+		}
+		if r.Score = cosine(q, decodeVector(blob)); r.Score > 0 {
+			results = append(results, r)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search memory: %w", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// Recall returns the memory relevant to prompt, formatted for the model, or
+// "" when nothing matches.
+func (v *VectorStore) Recall(ctx context.Context, prompt string, limit int) (string, error) {
+	if strings.TrimSpace(prompt) == "" {
+		return "", nil
+	}
+	results, err := v.Search(ctx, prompt, limit)
+	if err != nil || len(results) == 0 {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("<memory>\nNotes from long-term memory that may be relevant:\n\n")
+	for _, r := range results {
+		label := r.Source
+		if r.Heading != "" {
+			label += " > " + r.Heading
+		}
+		fmt.Fprintf(&sb, "[%s]\n%s\n\n", label, r.Content)
+	}
+	sb.WriteString("</memory>\n\n")
+	return sb.String(), nil
+}
+
+// WithRecall prefixes prompt with the memory relevant to it. Errors are
+// logged and the prompt is returned unchanged, so a failing embedder never
+// blocks a conversation.
+func (v *VectorStore) WithRecall(ctx context.Context, prompt string, limit int) string {
+	recalled, err := v.Recall(ctx, prompt, limit)
+	if err != nil {
+		log.Printf("[memory] recall error: %v", err)
+		return prompt
+	}
+	return recalled + prompt
+}
+
+// chunks splits MEMORY.md and the journal files into sections.
+func (m *MemoryStore) chunks() ([]memoryChunk, error) {
+	entries, err := os.ReadDir(m.memoryDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var chunks []memoryChunk
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.memoryDir(), e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunkMarkdown(e.Name(), string(data))...)
+	}
+	return chunks, nil
+}
+
+// chunkMarkdown starts a new chunk at every heading and splits long
+// sections at paragraph breaks.
+func chunkMarkdown(source, text string) []memoryChunk {
+	var chunks []memoryChunk
+	var heading string
+	var body []string
+	seen := make(map[string]bool)
+
+	emit := func() {
+		content := strings.TrimSpace(strings.Join(body, "\n"))
+		body = body[:0]
+		if content == "" {
+			return
+		}
+		sum := sha256.Sum256([]byte(source + "\x00" + heading + "\x00" + content))
+		hash := hex.EncodeToString(sum[:])
+		if seen[hash] {
+			return
+		}
+		seen[hash] = true
+		chunks = append(chunks, memoryChunk{source: source, heading: heading, content: content, hash: hash})
+	}
+
+	size := 0
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			emit()
+			size = 0
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			continue
+		}
+		if trimmed == "" && size >= maxChunkChars {
+			emit()
+			size = 0
+			continue
+		}
+		body = append(body, line)
+		size += len(line) + 1
+	}
+	emit()
+	return chunks
+}
+
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// countingEmbedder records how many texts it was asked to embed.
+type countingEmbedder struct {
+	*HashEmbedder
+	embedded int
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	c.embedded += len(texts)
+	return c.HashEmbedder.Embed(ctx, texts)
+}
+
+func TestChunkMarkdown(t *testing.T) {
+	text := "intro line\n\n# Family\nSister is Anna.\n\n## Work\nWorks at Acme.\n# Family\nSister is Anna.\n"
+	chunks := chunkMarkdown("MEMORY.md", text)
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	if chunks[0].heading != "" || chunks[0].content != "intro line" {
+		t.Errorf("first chunk = %+v", chunks[0])
+	}
+	if chunks[1].heading != "Family" || chunks[2].heading != "Work" || chunks[2].content != "Works at Acme." {
+		t.Errorf("chunks = %+v", chunks)
+	}
+
+	long := strings.Repeat("word ", maxChunkChars/5+10) + "\n\nnext paragraph"
+	if got := chunkMarkdown("x.md", long); len(got) != 2 {
+		t.Errorf("long section split into %d chunks", len(got))
+	}
+}
+
+func TestVectorStore_SearchAndSync(t *testing.T) {
+	ws := t.TempDir()
+	mem := NewMemoryStore(ws)
+	mem.WriteLongTerm("# Pets\nThe user has a dog called Biscuit.\n\n# Work\nThe user writes Go at a logistics company.\n\n# Food\nAllergic to peanuts.")
+	mem.AppendToday("Booked dentist appointment for Friday.")
+
+	emb := &countingEmbedder{HashEmbedder: NewHashEmbedder(0)}
+	vs, err := OpenVectorStore(ws, emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vs.Close()
+
+	results, err := vs.Search(context.Background(), "what is my dog called", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].Heading != "Pets" || results[0].Source != "MEMORY.md" {
+		t.Fatalf("results = %+v", results)
+	}
+	if len(results) > 2 {
+		t.Errorf("limit ignored: %d results", len(results))
+	}
+	if emb.embedded != 5 { // 4 chunks + query
+		t.Errorf("embedded %d texts, want 5", emb.embedded)
+	}
+
+	// Unchanged files are not embedded again; edits are picked up.
+	emb.embedded = 0
+	if n, err := vs.Sync(context.Background()); err != nil || n != 0 {
+		t.Errorf("Sync = %d, %v; want nothing to do", n, err)
+	}
+	mem.WriteLongTerm("# Pets\nThe user has a cat called Miso.")
+	if n, err := vs.Sync(context.Background()); err != nil || n != 1 {
+		t.Errorf("Sync after edit = %d, %v", n, err)
+	}
+	results, _ = vs.Search(context.Background(), "cat", 5)
+	for _, r := range results {
+		if strings.Contains(r.Content, "Biscuit") || strings.Contains(r.Content, "peanuts") {
+			t.Errorf("stale chunk still indexed: %+v", r)
+		}
+	}
+	if len(results) == 0 || !strings.Contains(results[0].Content, "Miso") {
+		t.Errorf("results after edit = %+v", results)
+	}
+}
+
+func TestVectorStore_EmbedderChangeReindexes(t *testing.T) {
+	ws := t.TempDir()
+	NewMemoryStore(ws).WriteLongTerm("Likes hiking.")
+
+	vs, err := OpenVectorStore(ws, NewHashEmbedder(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs.Sync(context.Background())
+	vs.Close()
+
+	emb := &countingEmbedder{HashEmbedder: NewHashEmbedder(128)}
+	vs, err = OpenVectorStore(ws, emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vs.Close()
+	if n, err := vs.Sync(context.Background()); err != nil || n != 1 {
+		t.Errorf("Sync with new embedder = %d, %v", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "memory", "vectors.db")); err != nil {
+		t.Errorf("index file missing: %v", err)
+	}
+}
+
+func TestVectorStore_Recall(t *testing.T) {
+	ws := t.TempDir()
+	NewMemoryStore(ws).WriteLongTerm("# Travel\nPrefers window seats on flights.")
+	vs, err := OpenVectorStore(ws, NewHashEmbedder(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vs.Close()
+
+	got := vs.WithRecall(context.Background(), "book me flights to Paris", 3)
+	if !strings.HasPrefix(got, "<memory>") || !strings.Contains(got, "[MEMORY.md > Travel]") || !strings.HasSuffix(got, "book me flights to Paris") {
+		t.Errorf("WithRecall = %q", got)
+	}
+	if got := vs.WithRecall(context.Background(), "zzz", 3); got != "zzz" {
+		t.Errorf("unrelated prompt = %q", got)
+	}
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "embed-small" {
+			http.Error(w, "bad model", http.StatusBadRequest)
+			return
+		}
+		// Return out of order to check that index is honoured.
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer srv.Close()
+
+	e := &OpenAIEmbedder{APIKey: "key", BaseURL: srv.URL + "/v1", Model: "embed-small"}
+	vectors, err := e.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v", vectors)
+	}
+	if e.Name() != "openai:embed-small" {
+		t.Errorf("Name = %q", e.Name())
+	}
+
+	e.APIKey = "wrong"
+	if _, err := e.Embed(context.Background(), []string{"a"}); err == nil {
+		t.Error("expected error for rejected request")
+	}
+}
+
+func TestNewEmbedder(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if e, err := NewEmbedder(cfg); err != nil || !strings.HasPrefix(e.Name(), "local:") {
+		t.Errorf("anthropic default = %v, %v", e, err)
+	}
+
+	cfg.Provider = config.ProviderConfig{Type: "openai", APIKey: "sk", BaseURL: "http://proxy/v1"}
+	e, err := NewEmbedder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oe, ok := e.(*OpenAIEmbedder); !ok || oe.APIKey != "sk" || oe.BaseURL != "http://proxy/v1" {
+		t.Errorf("openai default = %+v", e)
+	}
+
+	cfg = config.DefaultConfig()
+	cfg.Memory.Embedding.Provider = "openai"
+	if _, err := NewEmbedder(cfg); err == nil {
+		t.Error("expected error without embedding key")
+	}
+	cfg.Memory.Embedding.Provider = "voyage"
+	if _, err := NewEmbedder(cfg); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// desktopAppID is the app a Windows toast is shown as. Windows only shows
+// toasts from registered apps, so myclaw borrows PowerShell's.
+const desktopAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// desktopMaxMessage keeps a notification bubble readable; the full text is
+// in the chat, file or log it was also delivered to.
+const desktopMaxMessage = 500
+
+// macScript shows a notification with the title and text given as
+// arguments, so neither needs quoting for AppleScript.
+const macScript = `on run argv
+	display notification (item 2 of argv) with title (item 1 of argv)
+end run`
+
+// windowsScript shows a toast with a title line and a text line.
+const windowsScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$lines = $template.GetElementsByTagName('text')
+$lines.Item(0).AppendChild($template.CreateTextNode(%s)) > $null
+$lines.Item(1).AppendChild($template.CreateTextNode(%s)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier(%s).Show([Windows.UI.Notifications.ToastNotification]::new($template))`
+
+// sendDesktop shows a notification on the desktop of the machine the
+// gateway runs on.
+func sendDesktop(ctx context.Context, title, message string) error {
+	name, args, err := desktopCommand(runtime.GOOS, title, truncateRunes(message, desktopMaxMessage))
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) && name == "notify-send" {
+			return errors.New("desktop: notify-send not found; install libnotify (libnotify-bin on Debian and Ubuntu)")
+		}
+		return fmt.Errorf("desktop: %s: %w: %s", filepath.Base(name), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// desktopCommand returns the program, and its arguments, that shows a
+// notification on goos: osascript on macOS, a PowerShell toast on Windows
+// and notify-send elsewhere.
+func desktopCommand(goos, title, message string) (string, []string, error) {
+	switch goos {
+	case "darwin":
+		return "osascript", []string{"-e", macScript, title, message}, nil
+	case "windows":
+		script := fmt.Sprintf(windowsScript, powerShellString(title), powerShellString(message), powerShellString(desktopAppID))
+		return "powershell.exe", []string{"-NoProfile", "-NonInteractive", "-Command", script}, nil
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return "notify-send", []string{"--app-name=myclaw", "--", title, message}, nil
+	}
+	return "", nil, fmt.Errorf("desktop notifications are not supported on %s", goos)
+}
+
+// powerShellString quotes s as a single-quoted PowerShell string, in which
+// nothing but the quote itself is special.
+func powerShellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
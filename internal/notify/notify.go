@@ -0,0 +1,189 @@
+// Package notify pushes one-way notifications to a phone through ntfy,
+// Bark or Pushover, or shows them on the local desktop.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	// pushoverURL is the Pushover message API, used when the sink sets no
+	// URL of its own.
+	pushoverURL = "https://api.pushover.net/1/messages.json"
+	// barkGroup groups myclaw's notifications together on the device.
+	barkGroup = "myclaw"
+
+	// Longest messages and titles the services take. ntfy turns longer
+	// messages into attachments; Pushover rejects them.
+	ntfyMaxMessageBytes = 4096
+	pushoverMaxMessage  = 1024
+	pushoverMaxTitle    = 250
+	maxResponseBytes    = 64 << 10
+	ellipsis            = "…"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Send pushes a notification with title and message to the sink.
+func Send(ctx context.Context, sink config.NotifyConfig, title, message string) error {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		message = title
+	}
+	switch sink.Type {
+	case config.NotifyNtfy:
+		return sendNtfy(ctx, sink, title, message)
+	case config.NotifyBark:
+		return sendBark(ctx, sink, title, message)
+	case config.NotifyPushover:
+		return sendPushover(ctx, sink, title, message)
+	case config.NotifyDesktop:
+		return sendDesktop(ctx, title, message)
+	}
+	return fmt.Errorf("unknown notify type %q", sink.Type)
+}
+
+// sendNtfy publishes to the topic named by the last element of sink.URL,
+// on the server the rest of it points to.
+func sendNtfy(ctx context.Context, sink config.NotifyConfig, title, message string) error {
+	u, err := url.Parse(sink.URL)
+	if err != nil {
+		return fmt.Errorf("ntfy url: %w", err)
+	}
+	topic := path.Base(u.Path)
+	if topic == "/" || topic == "." {
+		return fmt.Errorf("ntfy url %q: no topic", sink.URL)
+	}
+	u.Path = path.Dir(u.Path)
+	body, err := json.Marshal(map[string]any{
+		"topic":    topic,
+		"title":    title,
+		"message":  truncateBytes(message, ntfyMaxMessageBytes),
+		"markdown": true,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sink.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+sink.Token)
+	}
+	_, err = do(req, "ntfy")
+	return err
+}
+
+// sendBark pushes to the device whose key ends sink.URL.
+func sendBark(ctx context.Context, sink config.NotifyConfig, title, message string) error {
+	body, err := json.Marshal(map[string]string{"title": title, "body": message, "group": barkGroup})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(sink.URL, "/"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	data, err := do(req, "bark")
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &resp) == nil && resp.Code != 0 && resp.Code != http.StatusOK {
+		return fmt.Errorf("bark: %d %s", resp.Code, resp.Message)
+	}
+	return nil
+}
+
+// sendPushover sends a message to sink.User with the application token
+// sink.Token.
+func sendPushover(ctx context.Context, sink config.NotifyConfig, title, message string) error {
+	endpoint := sink.URL
+	if endpoint == "" {
+		endpoint = pushoverURL
+	}
+	form := url.Values{
+		"token":   {sink.Token},
+		"user":    {sink.User},
+		"title":   {truncateRunes(title, pushoverMaxTitle)},
+		"message": {truncateRunes(message, pushoverMaxMessage)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	data, err := do(req, "pushover")
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Status int      `json:"status"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("pushover: %w", err)
+	}
+	if resp.Status != 1 {
+		return fmt.Errorf("pushover: %s", strings.Join(resp.Errors, "; "))
+	}
+	return nil
+}
+
+// do sends req and returns the body of a 2xx answer. Other answers become
+// errors carrying the start of their body.
+func do(req *http.Request, service string) ([]byte, error) {
+	req.Header.Set("User-Agent", "myclaw")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", service, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", service, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s: %s", service, resp.Status, truncateRunes(strings.TrimSpace(string(data)), 200))
+	}
+	return data, nil
+}
+
+// truncateBytes cuts s to at most n bytes, on a rune boundary, marking the
+// cut.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + ellipsis
+}
+
+// truncateRunes cuts s to at most n runes, marking the cut.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + ellipsis
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestSend_Ntfy(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" || r.Header.Get("Authorization") != "Bearer tk_1" {
+			t.Errorf("request %s, auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"id":"x"}`))
+	}))
+	defer srv.Close()
+
+	long := strings.Repeat("é", 3000)
+	if err := Send(context.Background(), config.NotifyConfig{Type: "ntfy", URL: srv.URL + "/claw", Token: "tk_1"}, "Inbox", long); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := got["message"].(string)
+	if got["topic"] != "claw" || got["title"] != "Inbox" || got["markdown"] != true || len(msg) > ntfyMaxMessageBytes || !utf8.ValidString(msg) {
+		t.Errorf("body = %v (message %d bytes)", got["topic"], len(msg))
+	}
+}
+
+func TestSend_Bark(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/devicekey" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		if got["body"] == "fail" {
+			w.Write([]byte(`{"code":400,"message":"failed to get device token"}`))
+			return
+		}
+		w.Write([]byte(`{"code":200,"message":"success"}`))
+	}))
+	defer srv.Close()
+
+	sink := config.NotifyConfig{Type: "bark", URL: srv.URL + "/devicekey/"}
+	if err := Send(context.Background(), sink, "Heartbeat", " All quiet. "); err != nil {
+		t.Fatal(err)
+	}
+	if got["title"] != "Heartbeat" || got["body"] != "All quiet." || got["group"] != "myclaw" {
+		t.Errorf("body = %v", got)
+	}
+	if err := Send(context.Background(), sink, "Heartbeat", "fail"); err == nil || !strings.Contains(err.Error(), "device token") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSend_Pushover(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("token") != "app" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"token":"invalid","errors":["application token is invalid"],"status":0}`))
+			return
+		}
+		if r.Form.Get("user") != "u1" || r.Form.Get("title") != "Task done" || utf8.RuneCountInString(r.Form.Get("message")) != pushoverMaxMessage {
+			t.Errorf("form = %v", r.Form)
+		}
+		w.Write([]byte(`{"status":1,"request":"r1"}`))
+	}))
+	defer srv.Close()
+
+	sink := config.NotifyConfig{Type: "pushover", URL: srv.URL, Token: "app", User: "u1"}
+	if err := Send(context.Background(), sink, "Task done", strings.Repeat("x", 2000)); err != nil {
+		t.Fatal(err)
+	}
+	sink.Token = "bad"
+	if err := Send(context.Background(), sink, "Task done", "x"); err == nil || !strings.Contains(err.Error(), "application token is invalid") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSend_UnknownType(t *testing.T) {
+	if err := Send(context.Background(), config.NotifyConfig{Type: "pager"}, "t", "m"); err == nil {
+		t.Error("unknown type accepted")
+	}
+}
+
+func TestDesktopCommand(t *testing.T) {
+	name, args, err := desktopCommand("linux", "-Reminder", "Stretch")
+	if err != nil || name != "notify-send" || strings.Join(args, "|") != "--app-name=myclaw|--|-Reminder|Stretch" {
+		t.Errorf("linux = %s %q, %v", name, args, err)
+	}
+	name, args, err = desktopCommand("darwin", "Reminder", `Call "mom"`)
+	if err != nil || name != "osascript" || len(args) != 4 || args[2] != "Reminder" || args[3] != `Call "mom"` {
+		t.Errorf("darwin = %s %q, %v", name, args, err)
+	}
+	name, args, err = desktopCommand("windows", "Reminder", "It's 6pm")
+	if err != nil || name != "powershell.exe" || !strings.Contains(args[len(args)-1], `CreateTextNode('It''s 6pm')`) {
+		t.Errorf("windows = %s %q, %v", name, args, err)
+	}
+	if _, _, err := desktopCommand("plan9", "Reminder", "x"); err == nil {
+		t.Error("plan9 accepted")
+	}
+}
@@ -0,0 +1,163 @@
+// Package permission guards the tools the agent runs. A policy of allow, ask
+// and deny rules decides each call; calls that need asking go to whoever
+// started the run, on the terminal or in the chat.
+package permission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sdkconfig "github.com/cexll/agentsdk-go/pkg/config"
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	coremw "github.com/cexll/agentsdk-go/pkg/core/middleware"
+	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// Decisions a policy can make about a tool call.
+const (
+	Allow = "allow"
+	Ask   = "ask"
+	Deny  = "deny"
+)
+
+// ErrDenied is returned, wrapped, for tool calls that may not run.
+var ErrDenied = errors.New("permission denied")
+
+// Request describes a tool call waiting for approval.
+type Request struct {
+	Tool   string
+	Target string // the command or path the rule matched, e.g. "rm:-rf build"
+	Rule   string
+	Params map[string]any
+}
+
+// String describes the call for a prompt, e.g. "Bash: rm -rf build".
+func (r Request) String() string {
+	target := r.Target
+	if name, args, ok := strings.Cut(target, ":"); ok && strings.EqualFold(r.Tool, "bash") {
+		target = strings.TrimSpace(name + " " + args)
+	}
+	if target == "" {
+		return r.Tool
+	}
+	return r.Tool + ": " + target
+}
+
+// AskFunc asks the user whether a tool call may run. An error counts as a
+// no.
+type AskFunc func(ctx context.Context, req Request) (bool, error)
+
+type askerKey struct{}
+
+// WithAsker makes ask answer the calls that need approval during runs with
+// ctx. Without one, such calls are denied.
+func WithAsker(ctx context.Context, ask AskFunc) context.Context {
+	return context.WithValue(ctx, askerKey{}, ask)
+}
+
+func askerOf(ctx context.Context) AskFunc {
+	ask, _ := ctx.Value(askerKey{}).(AskFunc)
+	return ask
+}
+
+// Policy decides tool calls from config rules.
+type Policy struct {
+	matcher *security.PermissionMatcher
+	def     string
+}
+
+// NewPolicy compiles the rules of cfg. It returns nil when cfg has no rules
+// and allows everything by default, so that tools run unguarded as before.
+func NewPolicy(cfg config.PermissionsConfig) (*Policy, error) {
+	def := cfg.Default
+	switch def {
+	case "":
+		def = Allow
+	case Allow, Ask, Deny:
+	default:
+		return nil, fmt.Errorf("permissions.default %q: want allow, ask or deny", cfg.Default)
+	}
+	if len(cfg.Allow)+len(cfg.Ask)+len(cfg.Deny) == 0 && def == Allow {
+		return nil, nil
+	}
+	matcher, err := security.NewPermissionMatcher(&sdkconfig.PermissionsConfig{
+		Allow: cfg.Allow,
+		Ask:   cfg.Ask,
+		Deny:  cfg.Deny,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("permissions: %w", err)
+	}
+	return &Policy{matcher: matcher, def: def}, nil
+}
+
+// Decide returns allow, ask or deny for a call of tool with params, and the
+// request to ask with.
+func (p *Policy) Decide(tool string, params map[string]any) (string, Request) {
+	d := p.matcher.Match(tool, params)
+	req := Request{Tool: tool, Target: d.Target, Rule: d.Rule, Params: params}
+	switch d.Action {
+	case security.PermissionAllow:
+		return Allow, req
+	case security.PermissionAsk:
+		return Ask, req
+	case security.PermissionDeny:
+		return Deny, req
+	}
+	req.Rule = "default"
+	return p.def, req
+}
+
+// check returns nil when the call may run, asking through ctx if needed.
+func (p *Policy) check(ctx context.Context, tool string, params map[string]any) error {
+	decision, req := p.Decide(tool, params)
+	switch decision {
+	case Allow:
+		return nil
+	case Deny:
+		return fmt.Errorf("%w: %s (rule %s)", ErrDenied, req, req.Rule)
+	}
+	return Confirm(ctx, req)
+}
+
+// Confirm asks whoever started the run with ctx whether req may go ahead,
+// whatever the policy says; tools that must never act unasked call it. It
+// returns nil on a yes and an error wrapping ErrDenied otherwise.
+func Confirm(ctx context.Context, req Request) error {
+	ask := askerOf(ctx)
+	if ask == nil {
+		return fmt.Errorf("%w: %s needs approval and no one can be asked here", ErrDenied, req)
+	}
+	ok, err := ask(ctx, req)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrDenied, req, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: the user declined %s", ErrDenied, req)
+	}
+	return nil
+}
+
+// HookMiddleware returns runtime hook middleware that enforces p before
+// each tool call. A denied call is not run; the model gets the reason as
+// the tool's result. With a nil policy it returns nil.
+func HookMiddleware(p *Policy) []coremw.Middleware {
+	if p == nil {
+		return nil
+	}
+	return []coremw.Middleware{func(next coremw.Handler) coremw.Handler {
+		return func(ctx context.Context, evt coreevents.Event) error {
+			if evt.Type == coreevents.PreToolUse {
+				if call, ok := evt.Payload.(coreevents.ToolUsePayload); ok {
+					if err := p.check(ctx, call.Name, call.Params); err != nil {
+						return err
+					}
+				}
+			}
+			return next(ctx, evt)
+		}
+	}}
+}
@@ -0,0 +1,151 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestNewPolicy(t *testing.T) {
+	p, err := NewPolicy(config.PermissionsConfig{})
+	if err != nil || p != nil {
+		t.Fatalf("empty config = %v, %v; want nil policy", p, err)
+	}
+	if HookMiddleware(nil) != nil {
+		t.Error("HookMiddleware(nil) should be nil")
+	}
+	if _, err := NewPolicy(config.PermissionsConfig{Default: "maybe"}); err == nil {
+		t.Error("expected error for bad default")
+	}
+	p, err = NewPolicy(config.PermissionsConfig{Default: Ask})
+	if err != nil || p == nil {
+		t.Fatalf("default ask = %v, %v; want a policy", p, err)
+	}
+}
+
+func TestPolicy_Decide(t *testing.T) {
+	p, err := NewPolicy(config.PermissionsConfig{
+		Allow:   []string{"Read"},
+		Ask:     []string{"Bash(git:push*)"},
+		Deny:    []string{"Bash(rm:*)"},
+		Default: Deny,
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy error: %v", err)
+	}
+
+	tests := []struct {
+		tool   string
+		params map[string]any
+		want   string
+	}{
+		{"Read", map[string]any{"file_path": "notes.md"}, Allow},
+		{"Bash", map[string]any{"command": "git push origin main"}, Ask},
+		{"Bash", map[string]any{"command": "rm -rf build"}, Deny},
+		{"Write", map[string]any{"file_path": "notes.md"}, Deny},
+	}
+	for _, tt := range tests {
+		if got, req := p.Decide(tt.tool, tt.params); got != tt.want {
+			t.Errorf("Decide(%s, %v) = %s (rule %q), want %s", tt.tool, tt.params, got, req.Rule, tt.want)
+		}
+	}
+
+	if _, req := p.Decide("Write", nil); req.Rule != "default" {
+		t.Errorf("unmatched rule = %q, want default", req.Rule)
+	}
+}
+
+func TestRequest_String(t *testing.T) {
+	if got := (Request{Tool: "Bash", Target: "rm:-rf build"}).String(); got != "Bash: rm -rf build" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Request{Tool: "Write", Target: "/tmp/a"}).String(); got != "Write: /tmp/a" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Request{Tool: "WebSearch"}).String(); got != "WebSearch" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestHookMiddleware(t *testing.T) {
+	p, err := NewPolicy(config.PermissionsConfig{
+		Ask:  []string{"Write"},
+		Deny: []string{"Bash(rm:*)"},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy error: %v", err)
+	}
+	mw := HookMiddleware(p)
+	if len(mw) != 1 {
+		t.Fatalf("got %d middlewares", len(mw))
+	}
+	ran := 0
+	handler := mw[0](func(context.Context, coreevents.Event) error {
+		ran++
+		return nil
+	})
+	call := func(ctx context.Context, tool string, params map[string]any) error {
+		return handler(ctx, coreevents.Event{
+			Type:    coreevents.PreToolUse,
+			Payload: coreevents.ToolUsePayload{Name: tool, Params: params},
+		})
+	}
+
+	if err := call(context.Background(), "Read", map[string]any{"file_path": "a"}); err != nil {
+		t.Errorf("allowed call: %v", err)
+	}
+	if err := call(context.Background(), "Bash", map[string]any{"command": "rm -rf /"}); !errors.Is(err, ErrDenied) {
+		t.Errorf("denied call: %v", err)
+	}
+	if err := call(context.Background(), "Write", map[string]any{"file_path": "a"}); !errors.Is(err, ErrDenied) || !strings.Contains(err.Error(), "no one can be asked") {
+		t.Errorf("ask without asker: %v", err)
+	}
+
+	var asked Request
+	answer := true
+	ctx := WithAsker(context.Background(), func(_ context.Context, req Request) (bool, error) {
+		asked = req
+		return answer, nil
+	})
+	if err := call(ctx, "Write", map[string]any{"file_path": "a"}); err != nil {
+		t.Errorf("approved call: %v", err)
+	}
+	if asked.Tool != "Write" || asked.Rule == "" {
+		t.Errorf("asked = %+v", asked)
+	}
+	answer = false
+	if err := call(ctx, "Write", map[string]any{"file_path": "a"}); !errors.Is(err, ErrDenied) || !strings.Contains(err.Error(), "declined") {
+		t.Errorf("declined call: %v", err)
+	}
+	if ran != 2 {
+		t.Errorf("next ran %d times, want 2", ran)
+	}
+
+	// Other events pass straight through.
+	if err := handler(context.Background(), coreevents.Event{Type: coreevents.PostToolUse}); err != nil || ran != 3 {
+		t.Errorf("other event: err=%v ran=%d", err, ran)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	req := Request{Tool: "email_send", Target: "to bob@example.com"}
+	if err := Confirm(context.Background(), req); !errors.Is(err, ErrDenied) {
+		t.Fatalf("Confirm without asker = %v, want ErrDenied", err)
+	}
+	var asked Request
+	yes := WithAsker(context.Background(), func(_ context.Context, r Request) (bool, error) {
+		asked = r
+		return true, nil
+	})
+	if err := Confirm(yes, req); err != nil || asked.Target != req.Target {
+		t.Fatalf("Confirm = %v, asked %+v", err, asked)
+	}
+	no := WithAsker(context.Background(), func(context.Context, Request) (bool, error) { return false, nil })
+	if err := Confirm(no, req); !errors.Is(err, ErrDenied) || !strings.Contains(err.Error(), "declined") {
+		t.Fatalf("Confirm declined = %v", err)
+	}
+}
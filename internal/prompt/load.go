@@ -0,0 +1,68 @@
+package prompt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// Files are the instruction files read as templates, in prompt order.
+// Memory follows them in the prompt but is never a template: notes the
+// agent wrote may well contain "{{".
+var Files = []string{"AGENTS.md", "SOUL.md"}
+
+// Paths returns where the instruction files are read from, in prompt
+// order: the workspace's, then those of the project myclaw runs in.
+func Paths(cfg *config.Config) []string {
+	var paths []string
+	for _, dir := range []string{cfg.Agent.Workspace, cfg.Project} {
+		if dir == "" {
+			continue
+		}
+		for _, name := range Files {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return paths
+}
+
+// Load parses the instruction files as one template, joined as the system
+// prompt joins them. It returns nil when they use no template actions.
+func Load(cfg *config.Config) (*Template, error) {
+	var sb strings.Builder
+	for _, path := range Paths(cfg) {
+		if data, err := os.ReadFile(path); err == nil {
+			sb.Write(data)
+			sb.WriteString("\n\n")
+		}
+	}
+	return Parse(sb.String(), BaseVars(cfg))
+}
+
+// BaseVars are the variables that hold for every request made with cfg.
+func BaseVars(cfg *config.Config) Vars {
+	host, _ := os.Hostname()
+	return Vars{
+		Hostname:  host,
+		OS:        runtime.GOOS,
+		Profile:   config.Profile(),
+		Model:     cfg.Models.Resolve(cfg.Agent.Model),
+		Workspace: cfg.Agent.Workspace,
+	}
+}
+
+// Check reports a template in the instruction files that fails to parse or
+// to render, such as one using a variable that does not exist.
+func Check(cfg *config.Config) error {
+	tmpl, err := Load(cfg)
+	if err != nil || tmpl == nil {
+		return err
+	}
+	_, err = tmpl.Render(context.Background(), time.Now())
+	return err
+}
@@ -0,0 +1,157 @@
+// Package prompt renders the agent's instruction files as Go templates, so
+// AGENTS.md and SOUL.md can use the date, the host and who the agent is
+// talking to, and keep blocks for one channel or profile:
+//
+//	Today is {{.Weekday}}, {{.Date}}.
+//	{{if eq .Channel "telegram"}}Keep replies short; they are read on a phone.{{end}}
+//
+// A runtime is built with one system prompt, so the template is rendered
+// for each model request by a model wrapper, with the variables of the
+// message being answered.
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+// Vars are the variables a template can use.
+type Vars struct {
+	Date     string // 2006-01-02, when the request is made
+	Time     string // 15:04
+	Weekday  string // Monday
+	Hostname string
+	OS       string // linux, darwin, windows
+	Profile  string // the config profile, or empty
+	Model    string
+	// Workspace is the agent's workspace directory.
+	Workspace string
+	// Channel is where the message came from: cli, telegram, slack, ...;
+	// empty for cron jobs and heartbeats.
+	Channel string
+	// UserName and UserID identify the sender, as far as the channel says.
+	UserName string
+	UserID   string
+	// Persona is what the channel asks the agent to be for this message,
+	// such as a WeCom app's persona. It is added to the end of the system
+	// prompt whether or not the template uses it.
+	Persona string
+}
+
+// merge returns v with the non-empty fields of o laid over it.
+func (v Vars) merge(o Vars) Vars {
+	for _, f := range []struct{ dst, src *string }{
+		{&v.Hostname, &o.Hostname}, {&v.OS, &o.OS}, {&v.Profile, &o.Profile}, {&v.Model, &o.Model},
+		{&v.Workspace, &o.Workspace}, {&v.Channel, &o.Channel}, {&v.UserName, &o.UserName}, {&v.UserID, &o.UserID},
+		{&v.Persona, &o.Persona},
+	} {
+		if *f.src != "" {
+			*f.dst = *f.src
+		}
+	}
+	return v
+}
+
+type varsKey struct{}
+
+// WithVars returns ctx carrying the variables of one message, such as its
+// channel and sender. Non-empty fields override the template's own.
+func WithVars(ctx context.Context, v Vars) context.Context {
+	return context.WithValue(ctx, varsKey{}, v)
+}
+
+func varsFrom(ctx context.Context) Vars {
+	v, _ := ctx.Value(varsKey{}).(Vars)
+	return v
+}
+
+// Template is a system prompt that uses template actions.
+type Template struct {
+	source string // trimmed, as the runtime may trim it
+	tmpl   *template.Template
+	base   Vars
+}
+
+// Parse parses source with base as the variables every render starts
+// from. It returns nil, and no error, when source has no template actions,
+// so prompts that don't use them are sent exactly as written.
+func Parse(source string, base Vars) (*Template, error) {
+	if !strings.Contains(source, "{{") {
+		return nil, nil
+	}
+	tmpl, err := template.New("system prompt").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("system prompt template: %w", err)
+	}
+	return &Template{source: strings.TrimSpace(source), tmpl: tmpl, base: base}, nil
+}
+
+// Render executes the template at now with the variables in ctx.
+func (t *Template) Render(ctx context.Context, now time.Time) (string, error) {
+	v := t.base.merge(varsFrom(ctx))
+	v.Date, v.Time, v.Weekday = now.Format("2006-01-02"), now.Format("15:04"), now.Weekday().String()
+	var sb strings.Builder
+	if err := t.tmpl.Execute(&sb, v); err != nil {
+		return "", fmt.Errorf("system prompt template: %w", err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// apply replaces the template in system, where the runtime put it among
+// its own additions, with its rendering. On error system is left as is.
+func (t *Template) apply(ctx context.Context, system string) string {
+	i := strings.Index(system, t.source)
+	if i < 0 {
+		return system
+	}
+	rendered, err := t.Render(ctx, time.Now())
+	if err != nil {
+		log.Printf("[prompt] %v; sending it unrendered", err)
+		return system
+	}
+	return system[:i] + rendered + system[i+len(t.source):]
+}
+
+// ModelFactory wraps the models f builds so each request's system prompt
+// has t, if any, rendered for it and the message's persona added.
+func ModelFactory(f api.ModelFactory, t *Template) api.ModelFactory {
+	return api.ModelFactoryFunc(func(ctx context.Context) (model.Model, error) {
+		m, err := f.Model(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &templatedModel{Model: m, t: t}, nil
+	})
+}
+
+type templatedModel struct {
+	model.Model
+	t *Template
+}
+
+func (m *templatedModel) system(ctx context.Context, system string) string {
+	if m.t != nil {
+		system = m.t.apply(ctx, system)
+	}
+	if persona := strings.TrimSpace(varsFrom(ctx).Persona); persona != "" {
+		system = strings.TrimSpace(system + "\n\n" + persona)
+	}
+	return system
+}
+
+func (m *templatedModel) Complete(ctx context.Context, req model.Request) (*model.Response, error) {
+	req.System = m.system(ctx, req.System)
+	return m.Model.Complete(ctx, req)
+}
+
+func (m *templatedModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	req.System = m.system(ctx, req.System)
+	return m.Model.CompleteStream(ctx, req, cb)
+}
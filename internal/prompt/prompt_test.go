@@ -0,0 +1,139 @@
+package prompt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestParse_Plain(t *testing.T) {
+	tmpl, err := Parse("# Agent\n\nBe helpful.", Vars{})
+	if err != nil || tmpl != nil {
+		t.Errorf("a prompt without actions should not be a template: %v, %v", tmpl, err)
+	}
+	if _, err := Parse("Hello {{.Channel", Vars{}); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestRender(t *testing.T) {
+	source := `Today is {{.Weekday}}, {{.Date}} on {{.Hostname}}.
+{{if eq .Channel "telegram"}}Keep it short, {{.UserName}}.{{else}}Channel: {{.Channel}}.{{end}}`
+	tmpl, err := Parse(source, Vars{Hostname: "box", Channel: "cli"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+
+	got, err := tmpl.Render(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Today is Monday, 2026-03-02 on box.\nChannel: cli." {
+		t.Errorf("Render = %q", got)
+	}
+
+	ctx := WithVars(context.Background(), Vars{Channel: "telegram", UserName: "Ana"})
+	if got, _ = tmpl.Render(ctx, now); !strings.HasSuffix(got, "Keep it short, Ana.") || !strings.Contains(got, "on box") {
+		t.Errorf("Render with message vars = %q", got)
+	}
+
+	bad, _ := Parse("{{.Nope}}", Vars{})
+	if _, err := bad.Render(context.Background(), now); err == nil {
+		t.Error("expected an error for an unknown variable")
+	}
+}
+
+type stubModel struct {
+	got model.Request
+}
+
+func (m *stubModel) Complete(_ context.Context, req model.Request) (*model.Response, error) {
+	m.got = req
+	return &model.Response{}, nil
+}
+
+func (m *stubModel) CompleteStream(_ context.Context, req model.Request, cb model.StreamHandler) error {
+	m.got = req
+	return cb(model.StreamResult{Final: true, Response: &model.Response{}})
+}
+
+func TestModelFactory(t *testing.T) {
+	tmpl, err := Parse("Hi {{.UserName}}.\n\n", Vars{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &stubModel{}
+	factory := ModelFactory(api.ModelFactoryFunc(func(context.Context) (model.Model, error) { return stub, nil }), tmpl)
+	m, err := factory.Model(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithVars(context.Background(), Vars{UserName: "Ana"})
+	// The runtime adds its own sections, which are left alone.
+	system := "Hi {{.UserName}}.\n\n## Memory\n\nUse {{ and }} freely."
+	if _, err := m.Complete(ctx, model.Request{System: system}); err != nil {
+		t.Fatal(err)
+	}
+	if stub.got.System != "Hi Ana.\n\n## Memory\n\nUse {{ and }} freely." {
+		t.Errorf("System = %q", stub.got.System)
+	}
+	if err := m.CompleteStream(ctx, model.Request{System: system}, func(model.StreamResult) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stub.got.System, "Hi Ana.") {
+		t.Errorf("streamed System = %q", stub.got.System)
+	}
+
+	plain, err := ModelFactory(api.ModelFactoryFunc(func(context.Context) (model.Model, error) { return stub, nil }), nil).Model(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plain.Complete(context.Background(), model.Request{System: "Be brief."}); err != nil || stub.got.System != "Be brief." {
+		t.Errorf("System without a template = %q", stub.got.System)
+	}
+	ctx = WithVars(context.Background(), Vars{Persona: "You are the HR helpdesk."})
+	if _, err := plain.Complete(ctx, model.Request{System: "Be brief."}); err != nil || stub.got.System != "Be brief.\n\nYou are the HR helpdesk." {
+		t.Errorf("System with a persona = %q", stub.got.System)
+	}
+}
+
+func TestLoadAndCheck(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = t.TempDir()
+	if tmpl, err := Load(cfg); err != nil || tmpl != nil {
+		t.Errorf("no files: %v, %v", tmpl, err)
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(cfg.Agent.Workspace, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("AGENTS.md", "# Agent on {{.OS}}")
+	write("SOUL.md", "Be kind.")
+	tmpl, err := Load(cfg)
+	if err != nil || tmpl == nil {
+		t.Fatalf("Load = %v, %v", tmpl, err)
+	}
+	if tmpl.source != "# Agent on {{.OS}}\n\nBe kind." {
+		t.Errorf("source = %q", tmpl.source)
+	}
+	if err := Check(cfg); err != nil {
+		t.Errorf("Check = %v", err)
+	}
+
+	write("SOUL.md", "Be {{.Mood}}.")
+	if err := Check(cfg); err == nil || !strings.Contains(err.Error(), "Mood") {
+		t.Errorf("Check should report the unknown variable, got %v", err)
+	}
+}
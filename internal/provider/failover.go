@@ -0,0 +1,231 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/openai/openai-go"
+)
+
+const (
+	// breakerThreshold is how many consecutive failures open a provider's
+	// circuit.
+	breakerThreshold = 3
+	// breakerCooldown is how long an open circuit skips the provider.
+	breakerCooldown = time.Minute
+	// fallbackRetries limits the SDK's own retries per provider in a chain,
+	// so that failing over is not delayed by a long backoff.
+	fallbackRetries = 2
+)
+
+// errAttemptTimeout marks an attempt cut short by provider.timeout.
+var errAttemptTimeout = errors.New("provider attempt timed out")
+
+// link is one provider in a failover chain.
+type link struct {
+	name    string
+	factory api.ModelFactory
+	primary bool
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+}
+
+// available reports whether the circuit lets a request through. Once the
+// cooldown has passed a single request is let through to probe the
+// provider; its result closes or reopens the circuit.
+func (l *link) available(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.failures < breakerThreshold {
+		return true
+	}
+	if now.Sub(l.openedAt) < breakerCooldown {
+		return false
+	}
+	l.openedAt = now
+	return true
+}
+
+func (l *link) record(err error, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		l.failures = 0
+		return
+	}
+	l.failures++
+	if l.failures == breakerThreshold {
+		log.Printf("[provider] %s failed %d times in a row, skipping it for %s", l.name, l.failures, breakerCooldown)
+	}
+	if l.failures >= breakerThreshold {
+		l.openedAt = now
+	}
+}
+
+// Failover is a model factory that tries each provider of a chain in turn.
+type Failover struct {
+	links   []*link
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewFailover returns a factory that fails over from the first provider to
+// the next on rate limits, server errors and timeouts. timeout bounds each
+// attempt; 0 means no limit. Each factory is named for the log.
+func NewFailover(names []string, factories []api.ModelFactory, timeout time.Duration) *Failover {
+	f := &Failover{timeout: timeout, now: time.Now}
+	for i, factory := range factories {
+		f.links = append(f.links, &link{name: names[i], factory: factory, primary: i == 0})
+	}
+	return f
+}
+
+// Model implements api.ModelFactory. Providers are resolved per request,
+// so one that cannot be built is skipped like one that fails.
+func (f *Failover) Model(context.Context) (model.Model, error) {
+	return &failoverModel{f: f}, nil
+}
+
+type failoverModel struct {
+	f *Failover
+}
+
+func (m *failoverModel) Complete(ctx context.Context, req model.Request) (*model.Response, error) {
+	var resp *model.Response
+	err := m.f.try(ctx, req, func(ctx context.Context, mdl model.Model, req model.Request) (bool, error) {
+		var err error
+		resp, err = mdl.Complete(ctx, req)
+		return false, err
+	})
+	return resp, err
+}
+
+// CompleteStream fails over only while nothing has been streamed; once the
+// first chunk has reached cb, errors are returned as they are.
+func (m *failoverModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	return m.f.try(ctx, req, func(ctx context.Context, mdl model.Model, req model.Request) (bool, error) {
+		started := false
+		err := mdl.CompleteStream(ctx, req, func(res model.StreamResult) error {
+			if !started {
+				started = true
+				stopAttemptTimer(ctx)
+			}
+			return cb(res)
+		})
+		return started, err
+	})
+}
+
+// try runs call against each available provider until one succeeds, the
+// error is not worth failing over for, or call reports that output has
+// already been delivered.
+func (f *Failover) try(ctx context.Context, req model.Request, call func(context.Context, model.Model, model.Request) (bool, error)) error {
+	var errs []error
+	for _, l := range f.links {
+		if !l.available(f.now()) {
+			continue
+		}
+		mdl, err := l.factory.Model(ctx)
+		if err != nil {
+			l.record(err, f.now())
+			log.Printf("[provider] %s unavailable: %v", l.name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", l.name, err))
+			continue
+		}
+		linkReq := req
+		if !l.primary {
+			// Model names and token limits in the request are the
+			// primary's; fallbacks use their own.
+			linkReq.Model = ""
+			linkReq.MaxTokens = 0
+		}
+		attemptCtx, cancel := f.attemptContext(ctx)
+		delivered, err := call(attemptCtx, mdl, linkReq)
+		timedOut := errors.Is(context.Cause(attemptCtx), errAttemptTimeout)
+		cancel()
+		if err == nil {
+			l.record(nil, f.now())
+			log.Printf("[provider] turn served by %s", l.name)
+			return nil
+		}
+		if timedOut && ctx.Err() == nil {
+			err = fmt.Errorf("%w after %s", errAttemptTimeout, f.timeout)
+		}
+		if delivered || ctx.Err() != nil || !Retryable(err) {
+			return err
+		}
+		l.record(err, f.now())
+		log.Printf("[provider] %s failed, trying next provider: %v", l.name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", l.name, err))
+	}
+	if len(errs) == 0 {
+		return errors.New("all providers are temporarily disabled after repeated failures")
+	}
+	return fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+type attemptTimerKey struct{}
+
+// attemptContext bounds an attempt by f.timeout. The timer can be stopped
+// with stopAttemptTimer, so a stream that has started is not cut off.
+func (f *Failover) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if f.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(f.timeout, func() { cancel(errAttemptTimeout) })
+	ctx = context.WithValue(ctx, attemptTimerKey{}, timer)
+	return ctx, func() {
+		timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+func stopAttemptTimer(ctx context.Context) {
+	if timer, ok := ctx.Value(attemptTimerKey{}).(*time.Timer); ok {
+		timer.Stop()
+	}
+}
+
+// Retryable reports whether err is worth trying another provider for:
+// rate limits, server errors, timeouts and network failures.
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, errAttemptTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return retryableStatus(anthropicErr.StatusCode)
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return retryableStatus(openaiErr.StatusCode)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// Some failures only surface as text, such as an overloaded event in
+	// the middle of an Anthropic stream.
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "overloaded") || strings.Contains(msg, "rate limit")
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+}
@@ -0,0 +1,208 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/openai/openai-go"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+type stubModel struct {
+	err    error
+	chunks int
+	calls  int
+	req    model.Request
+	wait   bool
+}
+
+func (s *stubModel) Model(context.Context) (model.Model, error) { return s, nil }
+
+func (s *stubModel) Complete(ctx context.Context, req model.Request) (*model.Response, error) {
+	s.calls++
+	s.req = req
+	if s.wait {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &model.Response{Message: model.Message{Role: "assistant", Content: "ok"}}, nil
+}
+
+func (s *stubModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	s.calls++
+	for i := 0; i < s.chunks; i++ {
+		if err := cb(model.StreamResult{Delta: "x"}); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func statusErr(code int) error {
+	return fmt.Errorf("wrapped: %w", &openai.Error{StatusCode: code})
+}
+
+func newTestFailover(timeout time.Duration, stubs ...*stubModel) *Failover {
+	var names []string
+	var factories []api.ModelFactory
+	for i, s := range stubs {
+		names = append(names, fmt.Sprintf("p%d", i))
+		factories = append(factories, s)
+	}
+	return NewFailover(names, factories, timeout)
+}
+
+func TestFailover_Complete(t *testing.T) {
+	t.Parallel()
+
+	primary := &stubModel{err: statusErr(429)}
+	fallback := &stubModel{}
+	f := newTestFailover(0, primary, fallback)
+	mdl, _ := f.Model(context.Background())
+	resp, err := mdl.Complete(context.Background(), model.Request{Model: "claude-x", MaxTokens: 100})
+	if err != nil || resp.Message.Content != "ok" {
+		t.Fatalf("complete = %+v, %v", resp, err)
+	}
+	if primary.req.Model != "claude-x" || fallback.req.Model != "" || fallback.req.MaxTokens != 0 {
+		t.Errorf("requests: primary %+v, fallback %+v", primary.req, fallback.req)
+	}
+
+	// Client errors are returned without failing over.
+	primary.err = statusErr(400)
+	fallback.calls = 0
+	if _, err := mdl.Complete(context.Background(), model.Request{}); err == nil || fallback.calls != 0 {
+		t.Errorf("400: err %v, fallback calls %d", err, fallback.calls)
+	}
+
+	primary.err = statusErr(503)
+	fallback.err = statusErr(500)
+	if _, err := mdl.Complete(context.Background(), model.Request{}); err == nil {
+		t.Error("expected error when every provider fails")
+	}
+}
+
+func TestFailover_CircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	primary := &stubModel{err: statusErr(502)}
+	fallback := &stubModel{}
+	f := newTestFailover(0, primary, fallback)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+	mdl, _ := f.Model(context.Background())
+
+	for i := 0; i < breakerThreshold+2; i++ {
+		if _, err := mdl.Complete(context.Background(), model.Request{}); err != nil {
+			t.Fatalf("complete %d: %v", i, err)
+		}
+	}
+	if primary.calls != breakerThreshold {
+		t.Errorf("primary calls = %d, want %d while the circuit is open", primary.calls, breakerThreshold)
+	}
+
+	// After the cooldown one request probes the primary and closes the circuit.
+	now = now.Add(breakerCooldown)
+	primary.err = nil
+	if _, err := mdl.Complete(context.Background(), model.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls != breakerThreshold+1 || !f.links[0].available(now) {
+		t.Errorf("primary calls = %d after cooldown", primary.calls)
+	}
+}
+
+func TestFailover_Stream(t *testing.T) {
+	t.Parallel()
+
+	// Nothing streamed yet: fail over.
+	primary := &stubModel{err: statusErr(529)}
+	fallback := &stubModel{chunks: 2}
+	mdl, _ := newTestFailover(0, primary, fallback).Model(context.Background())
+	var got int
+	err := mdl.CompleteStream(context.Background(), model.Request{}, func(model.StreamResult) error { got++; return nil })
+	if err != nil || got != 2 {
+		t.Fatalf("stream: %d chunks, %v", got, err)
+	}
+
+	// Part of the answer was delivered: the error stands.
+	primary = &stubModel{chunks: 1, err: statusErr(500)}
+	fallback = &stubModel{chunks: 2}
+	mdl, _ = newTestFailover(0, primary, fallback).Model(context.Background())
+	err = mdl.CompleteStream(context.Background(), model.Request{}, func(model.StreamResult) error { return nil })
+	if err == nil || fallback.calls != 0 {
+		t.Errorf("mid-stream failure: err %v, fallback calls %d", err, fallback.calls)
+	}
+}
+
+func TestFailover_Timeout(t *testing.T) {
+	t.Parallel()
+
+	primary := &stubModel{wait: true}
+	fallback := &stubModel{}
+	mdl, _ := newTestFailover(20*time.Millisecond, primary, fallback).Model(context.Background())
+	if _, err := mdl.Complete(context.Background(), model.Request{}); err != nil || fallback.calls != 1 {
+		t.Fatalf("timeout: err %v, fallback calls %d", err, fallback.calls)
+	}
+
+	// A cancelled caller is not failed over.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fallback.calls = 0
+	if _, err := mdl.Complete(ctx, model.Request{}); !errors.Is(err, context.Canceled) || fallback.calls != 0 {
+		t.Errorf("cancelled: err %v, fallback calls %d", err, fallback.calls)
+	}
+}
+
+func TestNew_Fallbacks(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agent: config.AgentConfig{Model: config.DefaultModel, MaxTokens: 100000},
+		Provider: config.ProviderConfig{
+			APIKey: "key",
+			Fallbacks: []config.FallbackProvider{
+				{Type: TypeOpenAI, APIKey: "okey", Model: "gpt-4o"},
+				{Type: TypeOpenAI, APIKey: "local", BaseURL: "http://localhost:11434/v1", Model: "llama3"},
+			},
+		},
+	}
+	f, ok := New(cfg).(*Failover)
+	if !ok || len(f.links) != 3 {
+		t.Fatalf("New = %T", New(cfg))
+	}
+	if f.links[2].name != "openai (http://localhost:11434/v1)" {
+		t.Errorf("fallback name = %q", f.links[2].name)
+	}
+	p := f.links[1].factory.(*model.OpenAIProvider)
+	if p.ModelName != "gpt-4o" || p.APIKey != "okey" || p.MaxRetries != fallbackRetries {
+		t.Errorf("fallback = %+v", p)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	t.Parallel()
+
+	cases := map[error]bool{
+		statusErr(429):              true,
+		statusErr(500):              true,
+		statusErr(401):              false,
+		statusErr(400):              false,
+		context.Canceled:            false,
+		context.DeadlineExceeded:    true,
+		errors.New("Overloaded"):    true,
+		errors.New("invalid input"): false,
+	}
+	for err, want := range cases {
+		if got := Retryable(err); got != want {
+			t.Errorf("Retryable(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
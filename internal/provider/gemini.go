@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// GeminiBaseURL is Gemini's OpenAI-compatible endpoint.
+	GeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta/openai/"
+	// GeminiAPIURL is the native Gemini API, used to list models.
+	GeminiAPIURL = "https://generativelanguage.googleapis.com/v1beta"
+	// DefaultGeminiModel is used when agent.model names another vendor's model.
+	DefaultGeminiModel = "gemini-2.5-flash"
+)
+
+// geminiOutputLimits are the maximum output tokens per model family,
+// longest prefix first.
+var geminiOutputLimits = []struct {
+	prefix string
+	limit  int
+}{
+	{"gemini-2.5", 65536},
+	{"gemini-2.0", 8192},
+	{"gemini-1.5", 8192},
+}
+
+// GeminiModel returns the Gemini model to use for agent.model. The default
+// config names a Claude model, which Gemini does not serve, so names from
+// other vendors map to DefaultGeminiModel. A "models/" prefix, as the
+// Gemini API lists them, is dropped.
+func GeminiModel(name string) string {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "models/")
+	lower := strings.ToLower(name)
+	if name == "" || strings.HasPrefix(lower, "claude") || strings.HasPrefix(lower, "gpt-") ||
+		strings.HasPrefix(lower, "o1") || strings.HasPrefix(lower, "o3") {
+		return DefaultGeminiModel
+	}
+	return name
+}
+
+// GeminiMaxTokens caps maxTokens at the output limit of model, so that a
+// value sized for another provider does not get the request rejected.
+// Unknown models are passed through unchanged.
+func GeminiMaxTokens(model string, maxTokens int) int {
+	for _, l := range geminiOutputLimits {
+		if strings.HasPrefix(model, l.prefix) && maxTokens > l.limit {
+			return l.limit
+		}
+	}
+	return maxTokens
+}
+
+// ListGeminiModels returns the Gemini models that can generate content.
+// baseURL overrides GeminiAPIURL.
+func ListGeminiModels(ctx context.Context, apiKey, baseURL string) ([]ModelInfo, error) {
+	if baseURL == "" {
+		baseURL = GeminiAPIURL
+	}
+	var models []ModelInfo
+	pageToken := ""
+	for {
+		q := url.Values{"key": {apiKey}, "pageSize": {"1000"}}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		var page struct {
+			Models []struct {
+				Name                       string   `json:"name"`
+				DisplayName                string   `json:"displayName"`
+				InputTokenLimit            int      `json:"inputTokenLimit"`
+				OutputTokenLimit           int      `json:"outputTokenLimit"`
+				SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := getJSON(ctx, strings.TrimSuffix(baseURL, "/")+"/models?"+q.Encode(), nil, &page); err != nil {
+			return nil, fmt.Errorf("list gemini models: %w", err)
+		}
+		for _, m := range page.Models {
+			if !slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+				continue
+			}
+			models = append(models, ModelInfo{
+				ID:           strings.TrimPrefix(m.Name, "models/"),
+				Name:         m.DisplayName,
+				InputTokens:  m.InputTokenLimit,
+				OutputTokens: m.OutputTokenLimit,
+			})
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
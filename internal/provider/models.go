@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// AnthropicAPIURL is the Anthropic API, as the SDK's base URL.
+	AnthropicAPIURL = "https://api.anthropic.com"
+	// OpenAIAPIURL is the OpenAI API, including its version path.
+	OpenAIAPIURL = "https://api.openai.com/v1"
+)
+
+// httpClient talks to the providers' model listing endpoints.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// ModelInfo describes a model a provider offers.
+type ModelInfo struct {
+	ID           string `json:"id"`
+	Name         string `json:"name,omitempty"`
+	InputTokens  int    `json:"inputTokens,omitempty"`
+	OutputTokens int    `json:"outputTokens,omitempty"`
+}
+
+// ListAnthropicModels returns the models of the Anthropic API, or of an
+// Anthropic-compatible endpoint at baseURL.
+func ListAnthropicModels(ctx context.Context, apiKey, baseURL string) ([]ModelInfo, error) {
+	if baseURL == "" {
+		baseURL = AnthropicAPIURL
+	}
+	var models []ModelInfo
+	afterID := ""
+	for {
+		q := url.Values{"limit": {"1000"}}
+		if afterID != "" {
+			q.Set("after_id", afterID)
+		}
+		var page struct {
+			Data []struct {
+				ID          string `json:"id"`
+				DisplayName string `json:"display_name"`
+			} `json:"data"`
+			HasMore bool   `json:"has_more"`
+			LastID  string `json:"last_id"`
+		}
+		header := http.Header{
+			"X-Api-Key":         {apiKey},
+			"Authorization":     {"Bearer " + apiKey},
+			"Anthropic-Version": {"2023-06-01"},
+		}
+		if err := getJSON(ctx, strings.TrimSuffix(baseURL, "/")+"/v1/models?"+q.Encode(), header, &page); err != nil {
+			return nil, fmt.Errorf("list anthropic models: %w", err)
+		}
+		for _, m := range page.Data {
+			models = append(models, ModelInfo{ID: m.ID, Name: m.DisplayName})
+		}
+		if !page.HasMore || page.LastID == "" {
+			break
+		}
+		afterID = page.LastID
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+// ListOpenAIModels returns the models of the OpenAI API, or of an
+// OpenAI-compatible endpoint at baseURL.
+func ListOpenAIModels(ctx context.Context, apiKey, baseURL string) ([]ModelInfo, error) {
+	if baseURL == "" {
+		baseURL = OpenAIAPIURL
+	}
+	var page struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	header := http.Header{"Authorization": {"Bearer " + apiKey}}
+	if err := getJSON(ctx, strings.TrimSuffix(baseURL, "/")+"/models", header, &page); err != nil {
+		return nil, fmt.Errorf("list openai models: %w", err)
+	}
+	models := make([]ModelInfo, 0, len(page.Data))
+	for _, m := range page.Data {
+		models = append(models, ModelInfo{ID: m.ID})
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+// getJSON decodes the response to a GET into out. Error responses are
+// reported with the message of their {"error":{"message":...}} body when
+// there is one.
+func getJSON(ctx context.Context, rawURL string, header http.Header, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if header != nil {
+		req.Header = header
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error.Message != "" {
+			return fmt.Errorf("%s", body.Error.Message)
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,105 @@
+// Package provider builds the model provider selected by provider.type.
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	TypeAnthropic = "anthropic"
+	TypeOpenAI    = "openai"
+	TypeGemini    = "gemini"
+)
+
+// New returns the model factory for cfg.Provider, using cfg.Agent.Model
+// and cfg.Agent.MaxTokens. Model aliases are resolved. With provider.fallbacks configured it returns a
+// Failover over the provider and its fallbacks.
+func New(cfg *config.Config) api.ModelFactory {
+	p := cfg.Provider
+	modelName := cfg.Models.Resolve(cfg.Agent.Model)
+	if len(p.Fallbacks) == 0 {
+		return build(p.Type, p.APIKey, p.BaseURL, modelName, cfg.Agent.MaxTokens, 0)
+	}
+	names := []string{Display(p.Type)}
+	factories := []api.ModelFactory{build(p.Type, p.APIKey, p.BaseURL, modelName, cfg.Agent.MaxTokens, fallbackRetries)}
+	for _, fb := range p.Fallbacks {
+		name := modelName
+		if fb.Model != "" {
+			name = cfg.Models.Resolve(fb.Model)
+		}
+		names = append(names, fallbackName(fb))
+		factories = append(factories, build(fb.Type, fb.APIKey, fb.BaseURL, name, cfg.Agent.MaxTokens, fallbackRetries))
+	}
+	return NewFailover(names, factories, time.Duration(p.Timeout)*time.Second)
+}
+
+func build(providerType, apiKey, baseURL, modelName string, maxTokens, retries int) api.ModelFactory {
+	switch providerType {
+	case TypeOpenAI:
+		return &model.OpenAIProvider{
+			APIKey:     apiKey,
+			BaseURL:    baseURL,
+			ModelName:  modelName,
+			MaxTokens:  maxTokens,
+			MaxRetries: retries,
+		}
+	case TypeGemini:
+		// Gemini is reached through its OpenAI-compatible endpoint.
+		if baseURL == "" {
+			baseURL = GeminiBaseURL
+		}
+		name := GeminiModel(modelName)
+		return &model.OpenAIProvider{
+			APIKey:     apiKey,
+			BaseURL:    baseURL,
+			ModelName:  name,
+			MaxTokens:  GeminiMaxTokens(name, maxTokens),
+			MaxRetries: retries,
+		}
+	default: // "anthropic" or empty
+		return &model.AnthropicProvider{
+			APIKey:     apiKey,
+			BaseURL:    baseURL,
+			ModelName:  modelName,
+			MaxTokens:  maxTokens,
+			MaxRetries: retries,
+		}
+	}
+}
+
+// fallbackName names a fallback for the log, with its base URL when set so
+// that a local OpenAI-compatible server can be told apart from OpenAI.
+func fallbackName(fb config.FallbackProvider) string {
+	name := Display(fb.Type)
+	if fb.BaseURL != "" {
+		name += " (" + fb.BaseURL + ")"
+	}
+	return name
+}
+
+// Display names the provider type for status output.
+func Display(t string) string {
+	if t == "" {
+		return TypeAnthropic + " (default)"
+	}
+	return t
+}
+
+// ListModels returns the models the configured provider offers.
+func ListModels(ctx context.Context, cfg *config.Config) ([]ModelInfo, error) {
+	switch cfg.Provider.Type {
+	case TypeGemini:
+		// provider.baseUrl is the OpenAI-compatible endpoint, which does
+		// not report token limits, so the native API is asked instead.
+		return ListGeminiModels(ctx, cfg.Provider.APIKey, "")
+	case TypeOpenAI:
+		return ListOpenAIModels(ctx, cfg.Provider.APIKey, cfg.Provider.BaseURL)
+	default:
+		return ListAnthropicModels(ctx, cfg.Provider.APIKey, cfg.Provider.BaseURL)
+	}
+}
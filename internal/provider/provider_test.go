@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agent:    config.AgentConfig{Model: config.DefaultModel, MaxTokens: 100000},
+		Provider: config.ProviderConfig{APIKey: "key"},
+	}
+	if _, ok := New(cfg).(*model.AnthropicProvider); !ok {
+		t.Errorf("default provider = %T, want anthropic", New(cfg))
+	}
+
+	cfg.Provider.Type = TypeOpenAI
+	if p, ok := New(cfg).(*model.OpenAIProvider); !ok || p.BaseURL != "" {
+		t.Errorf("openai provider = %+v", New(cfg))
+	}
+
+	cfg.Provider.Type = TypeGemini
+	p, ok := New(cfg).(*model.OpenAIProvider)
+	if !ok {
+		t.Fatalf("gemini provider = %T", New(cfg))
+	}
+	if p.BaseURL != GeminiBaseURL || p.ModelName != DefaultGeminiModel || p.MaxTokens != 65536 || p.APIKey != "key" {
+		t.Errorf("gemini provider = %+v", p)
+	}
+
+	cfg.Agent.Model = "models/gemini-2.0-flash"
+	cfg.Provider.BaseURL = "https://proxy.example.com/v1/"
+	p = New(cfg).(*model.OpenAIProvider)
+	if p.ModelName != "gemini-2.0-flash" || p.MaxTokens != 8192 || p.BaseURL != "https://proxy.example.com/v1/" {
+		t.Errorf("gemini provider = %+v", p)
+	}
+}
+
+func TestGeminiMaxTokens(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		model    string
+		in, want int
+	}{
+		{"gemini-2.5-pro", 8192, 8192},
+		{"gemini-2.5-pro", 200000, 65536},
+		{"gemini-1.5-flash", 10000, 8192},
+		{"gemini-exp-1206", 200000, 200000},
+	}
+	for _, c := range cases {
+		if got := GeminiMaxTokens(c.model, c.in); got != c.want {
+			t.Errorf("GeminiMaxTokens(%q, %d) = %d, want %d", c.model, c.in, got, c.want)
+		}
+	}
+}
+
+func TestListGeminiModels(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" || r.URL.Query().Get("key") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"message":"API key not valid"}}`))
+			return
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			w.Write([]byte(`{"models":[
+				{"name":"models/gemini-2.5-pro","displayName":"Gemini 2.5 Pro","outputTokenLimit":65536,"supportedGenerationMethods":["generateContent"]},
+				{"name":"models/text-embedding-004","supportedGenerationMethods":["embedContent"]}
+			],"nextPageToken":"p2"}`))
+			return
+		}
+		w.Write([]byte(`{"models":[{"name":"models/gemini-2.0-flash","supportedGenerationMethods":["generateContent","countTokens"]}]}`))
+	}))
+	defer srv.Close()
+
+	models, err := ListGeminiModels(context.Background(), "key", srv.URL)
+	if err != nil {
+		t.Fatalf("ListGeminiModels: %v", err)
+	}
+	if len(models) != 2 || models[0].ID != "gemini-2.0-flash" || models[1].ID != "gemini-2.5-pro" || models[1].OutputTokens != 65536 {
+		t.Errorf("models = %+v", models)
+	}
+
+	if _, err := ListGeminiModels(context.Background(), "bad", srv.URL); err == nil || err.Error() != "list gemini models: API key not valid" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestNew_ModelAlias(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agent:    config.AgentConfig{Model: "fast", MaxTokens: 1000},
+		Provider: config.ProviderConfig{APIKey: "key"},
+		Models:   config.ModelsConfig{Aliases: map[string]string{"fast": "claude-haiku-4-5"}},
+	}
+	if p := New(cfg).(*model.AnthropicProvider); p.ModelName != "claude-haiku-4-5" {
+		t.Errorf("model = %q, want alias resolved", p.ModelName)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			if r.Header.Get("X-Api-Key") != "key" || r.Header.Get("Anthropic-Version") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+				return
+			}
+			if r.URL.Query().Get("after_id") == "" {
+				w.Write([]byte(`{"data":[{"id":"claude-sonnet-4-5","display_name":"Claude Sonnet 4.5"}],"has_more":true,"last_id":"claude-sonnet-4-5"}`))
+				return
+			}
+			w.Write([]byte(`{"data":[{"id":"claude-haiku-4-5","display_name":"Claude Haiku 4.5"}],"has_more":false}`))
+		case "/openai/models":
+			if r.Header.Get("Authorization") != "Bearer key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{Provider: config.ProviderConfig{APIKey: "key", BaseURL: srv.URL}}
+	models, err := ListModels(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("anthropic: %v", err)
+	}
+	if len(models) != 2 || models[0].ID != "claude-haiku-4-5" || models[1].Name != "Claude Sonnet 4.5" {
+		t.Errorf("anthropic models = %+v", models)
+	}
+
+	cfg.Provider = config.ProviderConfig{Type: TypeOpenAI, APIKey: "key", BaseURL: srv.URL + "/openai"}
+	models, err = ListModels(context.Background(), cfg)
+	if err != nil || len(models) != 2 || models[0].ID != "gpt-4o" {
+		t.Errorf("openai models = %+v, %v", models, err)
+	}
+
+	if _, err := ListAnthropicModels(context.Background(), "bad", srv.URL); err == nil || err.Error() != "list anthropic models: invalid x-api-key" {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := ListOpenAIModels(context.Background(), "bad", srv.URL+"/openai"); err == nil || err.Error() != "list openai models: 401 Unauthorized" {
+		t.Errorf("err = %v", err)
+	}
+}
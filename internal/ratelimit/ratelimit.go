@@ -0,0 +1,204 @@
+// Package ratelimit keeps chat users within their message and token
+// budgets, and records what each user consumed per day.
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	// keepDays is how long daily usage is kept.
+	keepDays = 30
+
+	dayLayout = "2006-01-02"
+
+	defaultSlowDownReply = "You're sending messages faster than I can keep up. Please wait %s and try again."
+	defaultBudgetReply   = "You've used today's token budget. It resets at midnight."
+)
+
+// Path returns where the gateway keeps usage for workspace.
+func Path(workspace string) string {
+	return filepath.Join(workspace, ".claude", "usage.json")
+}
+
+// UserUsage is what one user consumed in one day.
+type UserUsage struct {
+	User         string `json:"user"` // channel:senderID
+	Messages     int    `json:"messages"`
+	InputTokens  int    `json:"inputTokens"`
+	OutputTokens int    `json:"outputTokens"`
+	Limited      int    `json:"limited,omitempty"` // messages turned away
+}
+
+// Tokens is the input and output tokens together.
+func (u UserUsage) Tokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Usage maps days ("2006-01-02", local time) to the usage of each user.
+type Usage map[string]map[string]*UserUsage
+
+// Day returns the usage of day, heaviest users first.
+func (u Usage) Day(day string) []UserUsage {
+	users := make([]UserUsage, 0, len(u[day]))
+	for _, usage := range u[day] {
+		users = append(users, *usage)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Tokens() != users[j].Tokens() {
+			return users[i].Tokens() > users[j].Tokens()
+		}
+		return users[i].User < users[j].User
+	})
+	return users
+}
+
+// Load reads the usage saved at path. A missing file is no usage.
+func Load(path string) (Usage, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Usage{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	usage := Usage{}
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return usage, nil
+}
+
+// Limiter decides whether a user may send another message, and records
+// what the messages it let through cost.
+type Limiter struct {
+	cfg  config.RateLimitConfig
+	path string
+	now  func() time.Time
+
+	mu     sync.Mutex
+	usage  Usage
+	recent map[string][]time.Time // when each user's messages of the last minute came
+}
+
+// New returns a limiter that keeps usage at path.
+func New(path string, cfg config.RateLimitConfig) *Limiter {
+	usage, err := Load(path)
+	if err != nil {
+		log.Printf("[ratelimit] %v; starting from zero", err)
+		usage = Usage{}
+	}
+	return &Limiter{cfg: cfg, path: path, now: time.Now, usage: usage, recent: map[string][]time.Time{}}
+}
+
+// Allow counts a message from user on channel. When the user is over a
+// limit, it reports false with the reply to send instead.
+func (l *Limiter) Allow(channel, user string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	limit := l.cfg.For(channel)
+	usage := l.today(channel, user, now)
+	key := usage.User
+	defer l.save()
+
+	if limit.TokensPerDay > 0 && usage.Tokens() >= limit.TokensPerDay {
+		usage.Limited++
+		if l.cfg.BudgetReply != "" {
+			return l.cfg.BudgetReply, false
+		}
+		return defaultBudgetReply, false
+	}
+	if limit.MessagesPerMinute > 0 {
+		recent := l.recent[key]
+		for len(recent) > 0 && now.Sub(recent[0]) >= time.Minute {
+			recent = recent[1:]
+		}
+		l.recent[key] = recent
+		if len(recent) >= limit.MessagesPerMinute {
+			usage.Limited++
+			wait := recent[0].Add(time.Minute).Sub(now).Round(time.Second)
+			if wait < time.Second {
+				wait = time.Second
+			}
+			if l.cfg.SlowDownReply != "" {
+				return l.cfg.SlowDownReply, false
+			}
+			return fmt.Sprintf(defaultSlowDownReply, wait), false
+		}
+		l.recent[key] = append(recent, now)
+	}
+	usage.Messages++
+	return "", true
+}
+
+// Count counts a message from user on channel without checking limits.
+func (l *Limiter) Count(channel, user string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.today(channel, user, l.now()).Messages++
+	l.save()
+}
+
+// Record adds the tokens a message from user on channel cost.
+func (l *Limiter) Record(channel, user string, input, output int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	usage := l.today(channel, user, l.now())
+	usage.InputTokens += input
+	usage.OutputTokens += output
+	l.save()
+}
+
+func (l *Limiter) today(channel, user string, now time.Time) *UserUsage {
+	day := now.Format(dayLayout)
+	key := channel + ":" + user
+	users := l.usage[day]
+	if users == nil {
+		users = map[string]*UserUsage{}
+		l.usage[day] = users
+	}
+	if users[key] == nil {
+		users[key] = &UserUsage{User: key}
+	}
+	return users[key]
+}
+
+// save writes the usage of the last keepDays days.
+func (l *Limiter) save() {
+	oldest := l.now().AddDate(0, 0, -keepDays).Format(dayLayout)
+	for day := range l.usage {
+		if day < oldest {
+			delete(l.usage, day)
+		}
+	}
+	data, err := json.MarshalIndent(l.usage, "", "  ")
+	if err != nil {
+		log.Printf("[ratelimit] marshal usage: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		log.Printf("[ratelimit] save usage: %v", err)
+		return
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Printf("[ratelimit] save usage: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		log.Printf("[ratelimit] save usage: %v", err)
+	}
+}
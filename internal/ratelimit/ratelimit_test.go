@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestLimiter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	cfg := config.RateLimitConfig{
+		RateLimit: config.RateLimit{MessagesPerMinute: 2, TokensPerDay: 1000},
+		Channels:  map[string]config.RateLimit{"webui": {MessagesPerMinute: -1}},
+	}
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	l := New(path, cfg)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if reply, ok := l.Allow("telegram", "42"); !ok {
+			t.Fatalf("message %d limited: %q", i, reply)
+		}
+		now = now.Add(10 * time.Second)
+	}
+	reply, ok := l.Allow("telegram", "42")
+	if ok || !strings.Contains(reply, "wait 40s") {
+		t.Errorf("third message = %q, %v", reply, ok)
+	}
+	if _, ok := l.Allow("telegram", "7"); !ok {
+		t.Error("another user was limited")
+	}
+	for i := 0; i < 5; i++ {
+		if _, ok := l.Allow("webui", "42"); !ok {
+			t.Error("webui lifts the per-minute limit")
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := l.Allow("telegram", "42"); !ok {
+		t.Error("limit did not reset after a minute")
+	}
+	l.Record("telegram", "42", 900, 150)
+	if reply, ok := l.Allow("telegram", "42"); ok || reply != defaultBudgetReply {
+		t.Errorf("over budget = %q, %v", reply, ok)
+	}
+
+	usage, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := usage.Day("2026-10-16")
+	if len(day) != 3 || day[0].User != "telegram:42" {
+		t.Fatalf("usage = %+v", day)
+	}
+	if got := day[0]; got.Messages != 3 || got.Tokens() != 1050 || got.Limited != 2 {
+		t.Errorf("telegram:42 = %+v", got)
+	}
+
+	// The daily budget resets the next day.
+	now = now.Add(24 * time.Hour)
+	if _, ok := l.Allow("telegram", "42"); !ok {
+		t.Error("budget did not reset the next day")
+	}
+}
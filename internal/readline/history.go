@@ -0,0 +1,102 @@
+package readline
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxHistory is how many entries the history keeps.
+const MaxHistory = 1000
+
+// LoadHistory reads the history saved in path and makes AddHistory append
+// to it. A missing file is an empty history.
+func (r *Reader) LoadHistory(path string) error {
+	r.historyFile = path
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			r.history = append(r.history, decodeEntry(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(r.history) > MaxHistory {
+		r.history = r.history[len(r.history)-MaxHistory:]
+		return r.rewriteHistory()
+	}
+	return nil
+}
+
+// AddHistory adds entry to the history, and to the history file when
+// LoadHistory set one. Blank entries and repeats of the last are skipped.
+func (r *Reader) AddHistory(entry string) error {
+	if strings.TrimSpace(entry) == "" || (len(r.history) > 0 && r.history[len(r.history)-1] == entry) {
+		return nil
+	}
+	r.history = append(r.history, entry)
+	if len(r.history) > MaxHistory {
+		r.history = r.history[1:]
+	}
+	if r.historyFile == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.historyFile), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.historyFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(encodeEntry(entry) + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rewriteHistory replaces the history file with the entries in memory.
+func (r *Reader) rewriteHistory() error {
+	var b strings.Builder
+	for _, entry := range r.history {
+		b.WriteString(encodeEntry(entry) + "\n")
+	}
+	tmp := r.historyFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.historyFile)
+}
+
+// encodeEntry puts an entry on one line, escaping line breaks and the
+// backslashes that escape them.
+func encodeEntry(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func decodeEntry(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,49 @@
+package readline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistory_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ws", ".repl_history")
+	r := New(strings.NewReader(""), &strings.Builder{})
+	if err := r.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory missing file: %v", err)
+	}
+	for _, entry := range []string{"one", "one", " ", "two\nlines", `back\slash`} {
+		if err := r.AddHistory(entry); err != nil {
+			t.Fatalf("AddHistory: %v", err)
+		}
+	}
+
+	loaded := New(strings.NewReader(""), &strings.Builder{})
+	if err := loaded.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	want := []string{"one", "two\nlines", `back\slash`}
+	if !reflect.DeepEqual(loaded.history, want) {
+		t.Errorf("history = %q, want %q", loaded.history, want)
+	}
+}
+
+func TestHistory_Trimmed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	var b strings.Builder
+	for i := 0; i < MaxHistory+10; i++ {
+		b.WriteString("entry\n")
+	}
+	os.WriteFile(path, []byte(b.String()), 0600)
+
+	r := New(strings.NewReader(""), &strings.Builder{})
+	if err := r.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if len(r.history) != MaxHistory || strings.Count(string(data), "\n") != MaxHistory {
+		t.Errorf("kept %d entries, file has %d", len(r.history), strings.Count(string(data), "\n"))
+	}
+}
@@ -0,0 +1,353 @@
+// Package readline reads lines from a terminal with editing, history and
+// reverse search, in the spirit of GNU readline. When input is not a
+// terminal it reads plain lines, so scripts can pipe into the same code.
+package readline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/stellarlinkco/myclaw/internal/keys"
+	"github.com/stellarlinkco/myclaw/internal/markdown"
+	"golang.org/x/term"
+)
+
+// ErrInterrupt is returned when the user presses Ctrl-C at the prompt.
+var ErrInterrupt = errors.New("interrupted")
+
+// Reader reads lines from in, echoing prompts to out.
+type Reader struct {
+	in      io.Reader
+	out     io.Writer
+	fd      int           // the terminal, or -1
+	plain   *bufio.Reader // reads when in is not a terminal; see readPlain
+	pending []keys.Key    // keys read past the end of the last line
+
+	history     []string
+	historyFile string
+}
+
+// New returns a Reader that edits lines when in and out are both a
+// terminal, and reads them as they come otherwise.
+func New(in io.Reader, out io.Writer) *Reader {
+	r := &Reader{in: in, out: out, fd: -1}
+	fin, ok1 := in.(*os.File)
+	fout, ok2 := out.(*os.File)
+	if ok1 && ok2 && term.IsTerminal(int(fin.Fd())) && term.IsTerminal(int(fout.Fd())) {
+		r.fd = int(fin.Fd())
+	}
+	return r
+}
+
+// Interactive reports whether lines are read from a terminal.
+func (r *Reader) Interactive() bool {
+	return r.fd >= 0
+}
+
+// ReadLine shows prompt and reads one line. It returns io.EOF at the end
+// of input, and ErrInterrupt when the line is abandoned with Ctrl-C.
+func (r *Reader) ReadLine(prompt string) (string, error) {
+	if r.fd < 0 {
+		return r.readPlain(prompt)
+	}
+	state, err := term.MakeRaw(r.fd)
+	if err != nil {
+		return r.readPlain(prompt)
+	}
+	defer term.Restore(r.fd, state)
+
+	e := &editor{r: r, prompt: prompt, hist: len(r.history)}
+	e.render()
+	for {
+		k, err := r.nextKey()
+		if err != nil {
+			fmt.Fprint(r.out, "\r\n")
+			return "", err
+		}
+		if line, done, err := e.handle(k); done {
+			fmt.Fprint(r.out, "\r\n")
+			return line, err
+		}
+	}
+}
+
+// ReadInput reads an entry that may go on over several lines: a line that
+// ends with a backslash continues on the next, and text opened with """
+// runs until the closing """. Continuation lines are prompted with more.
+func (r *Reader) ReadInput(prompt, more string) (string, error) {
+	const quotes = `"""`
+	line, err := r.ReadLine(prompt)
+	if err != nil {
+		return "", err
+	}
+	if strings.Count(line, quotes)%2 == 1 {
+		text := line
+		for strings.Count(text, quotes)%2 == 1 {
+			next, err := r.ReadLine(more)
+			if err != nil {
+				return "", err
+			}
+			text += "\n" + next
+		}
+		open, end := strings.Index(text, quotes), strings.LastIndex(text, quotes)
+		inner := strings.TrimPrefix(text[open+len(quotes):end], "\n")
+		inner = strings.TrimSuffix(inner, "\n")
+		return text[:open] + inner + text[end+len(quotes):], nil
+	}
+	var lines []string
+	for strings.HasSuffix(line, `\`) {
+		lines = append(lines, strings.TrimSuffix(line, `\`))
+		if line, err = r.ReadLine(more); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(append(lines, line), "\n"), nil
+}
+
+// readPlain reads a line without editing. It is also the fallback when a
+// terminal cannot be put in raw mode, so the buffered reader is made on
+// first use.
+func (r *Reader) readPlain(prompt string) (string, error) {
+	if r.plain == nil {
+		r.plain = bufio.NewReader(r.in)
+	}
+	fmt.Fprint(r.out, prompt)
+	line, err := r.plain.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (r *Reader) nextKey() (keys.Key, error) {
+	buf := make([]byte, 256)
+	for len(r.pending) == 0 {
+		n, err := r.in.Read(buf)
+		r.pending = append(r.pending, keys.Parse(buf[:n])...)
+		if len(r.pending) == 0 && err != nil {
+			return keys.Key{}, err
+		}
+	}
+	k := r.pending[0]
+	r.pending = r.pending[1:]
+	return k, nil
+}
+
+func (r *Reader) width() int {
+	if w, _, err := term.GetSize(r.fd); err == nil && w > 0 {
+		return w
+	}
+	return 80
+}
+
+// editor is the state of the line being edited.
+type editor struct {
+	r      *Reader
+	prompt string
+	buf    []rune
+	pos    int
+	row    int // the row of the cursor, counted from the prompt's
+
+	hist  int    // index into history; len(history) is the new line
+	saved []rune // the new line, while browsing history
+
+	search *search
+}
+
+// search is a reverse incremental search through history.
+type search struct {
+	query  []rune
+	match  int // index into history, or -1
+	failed bool
+	orig   []rune
+}
+
+// handle applies k. It reports done when the line is finished, with the
+// line or the error ending it.
+func (e *editor) handle(k keys.Key) (string, bool, error) {
+	if e.search != nil && !e.searchKey(k) {
+		e.render()
+		return "", false, nil
+	}
+	switch k.Type {
+	case keys.Enter:
+		return string(e.buf), true, nil
+	case keys.CtrlC:
+		fmt.Fprint(e.r.out, "^C")
+		return "", true, ErrInterrupt
+	case keys.CtrlD:
+		if len(e.buf) == 0 {
+			return "", true, io.EOF
+		}
+		e.delete(e.pos, e.pos+1)
+	case keys.Rune:
+		e.buf = append(e.buf[:e.pos], append([]rune{k.Rune}, e.buf[e.pos:]...)...)
+		e.pos++
+	case keys.Backspace:
+		e.delete(e.pos-1, e.pos)
+	case keys.Delete:
+		e.delete(e.pos, e.pos+1)
+	case keys.Left:
+		e.pos = max(e.pos-1, 0)
+	case keys.Right:
+		e.pos = min(e.pos+1, len(e.buf))
+	case keys.Home:
+		e.pos = 0
+	case keys.End:
+		e.pos = len(e.buf)
+	case keys.CtrlK:
+		e.delete(e.pos, len(e.buf))
+	case keys.CtrlU:
+		e.delete(0, e.pos)
+	case keys.CtrlW:
+		start := e.pos
+		for start > 0 && e.buf[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && e.buf[start-1] != ' ' {
+			start--
+		}
+		e.delete(start, e.pos)
+	case keys.Up:
+		e.browse(e.hist - 1)
+	case keys.Down, keys.CtrlN:
+		e.browse(e.hist + 1)
+	case keys.CtrlR:
+		e.search = &search{match: -1, orig: e.buf}
+	case keys.CtrlL:
+		fmt.Fprint(e.r.out, "\x1b[H\x1b[2J")
+		e.row = 0
+	}
+	e.render()
+	return "", false, nil
+}
+
+// delete removes buf[from:to], clamped to the line.
+func (e *editor) delete(from, to int) {
+	from, to = max(from, 0), min(to, len(e.buf))
+	if from >= to {
+		return
+	}
+	e.buf = append(e.buf[:from:from], e.buf[to:]...)
+	e.pos = from
+}
+
+// browse shows history entry i, where len(history) is the new line.
+func (e *editor) browse(i int) {
+	history := e.r.history
+	if i < 0 || i > len(history) || i == e.hist {
+		return
+	}
+	if e.hist == len(history) {
+		e.saved = e.buf
+	}
+	e.hist = i
+	if i == len(history) {
+		e.buf = e.saved
+	} else {
+		e.buf = []rune(history[i])
+	}
+	e.pos = len(e.buf)
+}
+
+// searchKey applies k to the search. It reports whether the search is
+// over and k, unless it was used up, should be handled as usual.
+func (e *editor) searchKey(k keys.Key) bool {
+	s := e.search
+	switch k.Type {
+	case keys.Rune:
+		s.query = append(s.query, k.Rune)
+		from := s.match
+		if from < 0 {
+			from = len(e.r.history) - 1
+		}
+		e.find(from)
+		return false
+	case keys.Backspace:
+		if len(s.query) > 0 {
+			s.query = s.query[:len(s.query)-1]
+		}
+		e.find(len(e.r.history) - 1)
+		return false
+	case keys.CtrlR:
+		if s.match > 0 {
+			e.find(s.match - 1)
+		}
+		return false
+	case keys.CtrlG, keys.Esc, keys.CtrlC:
+		e.buf, e.pos = s.orig, len(s.orig)
+		e.search = nil
+		return false
+	}
+	// Any other key takes the match and goes on editing it.
+	if s.match >= 0 {
+		e.buf = []rune(e.r.history[s.match])
+		e.pos, e.hist = len(e.buf), s.match
+	}
+	e.search = nil
+	return true
+}
+
+// find looks for the query in history, going back from entry from. When
+// nothing matches, the last match stays.
+func (e *editor) find(from int) {
+	s := e.search
+	for i := min(from, len(e.r.history)-1); i >= 0; i-- {
+		if strings.Contains(e.r.history[i], string(s.query)) {
+			s.match, s.failed = i, false
+			return
+		}
+	}
+	s.failed = len(s.query) > 0
+}
+
+// render redraws the line, which may have wrapped over several rows, and
+// puts the cursor where it belongs.
+func (e *editor) render() {
+	line := e.prompt + display(e.buf)
+	before := e.prompt + display(e.buf[:e.pos])
+	if s := e.search; s != nil {
+		label, match := "reverse-i-search", ""
+		if s.failed {
+			label = "failed " + label
+		}
+		if s.match >= 0 {
+			match = display([]rune(e.r.history[s.match]))
+		}
+		line = fmt.Sprintf("(%s)`%s': %s", label, string(s.query), match)
+		before = line
+	}
+
+	cols := e.r.width()
+	var b strings.Builder
+	if e.row > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", e.row)
+	}
+	b.WriteString("\r" + line + "\x1b[J")
+	total := markdown.Width(line)
+	// A line that fills the last row exactly leaves the cursor past the
+	// edge; move it to the next row so the sums below hold.
+	if total > 0 && total%cols == 0 {
+		b.WriteString("\r\n")
+	}
+	cur := markdown.Width(before)
+	if up := total/cols - cur/cols; up > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", up)
+	}
+	b.WriteString("\r")
+	if col := cur % cols; col > 0 {
+		fmt.Fprintf(&b, "\x1b[%dC", col)
+	}
+	e.row = cur / cols
+	io.WriteString(e.r.out, b.String())
+}
+
+// display shows the line breaks of a recalled multi-line entry as ↵, so
+// it edits as one line.
+func display(buf []rune) string {
+	return strings.ReplaceAll(string(buf), "\n", "↵")
+}
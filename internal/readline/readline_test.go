@@ -0,0 +1,146 @@
+package readline
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stellarlinkco/myclaw/internal/keys"
+)
+
+func TestReadInput_Plain(t *testing.T) {
+	in := strings.NewReader("one\ntwo \\\nthree\n\"\"\"\nline a\n  line b\n\"\"\"\nsay \"\"\"hi\nthere\"\"\" now\nlast")
+	var out bytes.Buffer
+	r := New(in, &out)
+	if r.Interactive() {
+		t.Fatal("a reader is not a terminal")
+	}
+
+	for _, want := range []string{"one", "two \nthree", "line a\n  line b", "say hi\nthere now", "last"} {
+		got, err := r.ReadInput("> ", "... ")
+		if err != nil || got != want {
+			t.Errorf("ReadInput = %q, %v; want %q", got, err, want)
+		}
+	}
+	if _, err := r.ReadInput("> ", "... "); err != io.EOF {
+		t.Errorf("at end: %v", err)
+	}
+	if !strings.Contains(out.String(), "> ... > ") {
+		t.Errorf("prompts = %q", out.String())
+	}
+}
+
+func TestReadLine_RawModeFails(t *testing.T) {
+	// A descriptor that is not a terminal cannot be made raw, as happens
+	// when a terminal goes away; the line is then read plainly.
+	f, err := os.CreateTemp(t.TempDir(), "in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := &Reader{in: strings.NewReader("hello\n"), out: io.Discard, fd: int(f.Fd())}
+	if line, err := r.ReadLine("> "); err != nil || line != "hello" {
+		t.Errorf("ReadLine = %q, %v", line, err)
+	}
+}
+
+// testEditor returns an editor over history, drawing into a buffer.
+func testEditor(history ...string) *editor {
+	r := &Reader{out: &bytes.Buffer{}, fd: -1, history: history}
+	return &editor{r: r, prompt: "> ", hist: len(history)}
+}
+
+func press(e *editor, ks ...any) (string, bool, error) {
+	var (
+		line string
+		done bool
+		err  error
+	)
+	for _, k := range ks {
+		switch k := k.(type) {
+		case string:
+			for _, c := range k {
+				line, done, err = e.handle(keys.Key{Type: keys.Rune, Rune: c})
+			}
+		case keys.Type:
+			line, done, err = e.handle(keys.Key{Type: k})
+		}
+	}
+	return line, done, err
+}
+
+func TestEditor_Editing(t *testing.T) {
+	e := testEditor()
+	line, done, err := press(e, "hello world", keys.CtrlW, "there", keys.Home, keys.Delete, "H", keys.End, keys.Left, keys.Left, keys.CtrlK, keys.Enter)
+	if !done || err != nil || line != "Hello the" {
+		t.Errorf("line = %q, %v, %v", line, done, err)
+	}
+
+	e = testEditor()
+	if _, done, err := press(e, "abc", keys.CtrlC); !done || !errors.Is(err, ErrInterrupt) {
+		t.Errorf("Ctrl-C = %v, %v", done, err)
+	}
+	e = testEditor()
+	if _, done, err := press(e, keys.CtrlD); !done || err != io.EOF {
+		t.Errorf("Ctrl-D = %v, %v", done, err)
+	}
+}
+
+func TestEditor_History(t *testing.T) {
+	e := testEditor("first", "second")
+	press(e, "draft", keys.Up)
+	if string(e.buf) != "second" {
+		t.Errorf("Up = %q", string(e.buf))
+	}
+	press(e, keys.Up, keys.Up)
+	if string(e.buf) != "first" {
+		t.Errorf("Up Up = %q", string(e.buf))
+	}
+	press(e, keys.Down, keys.Down)
+	if string(e.buf) != "draft" {
+		t.Errorf("back down = %q", string(e.buf))
+	}
+}
+
+func TestEditor_Search(t *testing.T) {
+	e := testEditor("git status", "go test ./...", "git push")
+	press(e, keys.CtrlR, "git")
+	if e.search == nil || e.search.match != 2 {
+		t.Fatalf("search = %+v", e.search)
+	}
+	press(e, keys.CtrlR)
+	if e.search.match != 0 {
+		t.Errorf("next match = %d", e.search.match)
+	}
+	if !strings.Contains(e.r.out.(*bytes.Buffer).String(), "(reverse-i-search)`git': git status") {
+		t.Errorf("display = %q", e.r.out.(*bytes.Buffer).String())
+	}
+	press(e, "x")
+	if !e.search.failed || e.search.match != 0 {
+		t.Errorf("failed search = %+v", e.search)
+	}
+
+	// Esc gives the line back as it was.
+	press(e, keys.Esc)
+	if e.search != nil || len(e.buf) != 0 {
+		t.Errorf("after Esc: %+v %q", e.search, string(e.buf))
+	}
+
+	line, done, _ := press(e, keys.CtrlR, "test", keys.Enter)
+	if !done || line != "go test ./..." {
+		t.Errorf("accepted %q, %v", line, done)
+	}
+}
+
+func TestEditor_RenderWraps(t *testing.T) {
+	e := testEditor()
+	press(e, strings.Repeat("x", 100), keys.Home)
+	// 102 cells on an 80 column terminal: the cursor went back up a row.
+	out := e.r.out.(*bytes.Buffer).String()
+	if !strings.HasSuffix(out, "\x1b[1A\r\x1b[2C") || e.row != 0 {
+		t.Errorf("row %d, output ends %q", e.row, out[max(len(out)-20, 0):])
+	}
+}
@@ -0,0 +1,309 @@
+// Package redact masks personal data and secrets in what is sent to the
+// model provider. Each value becomes a placeholder such as [EMAIL_1], the
+// same one every time it appears, and the placeholders in the model's
+// replies and tool calls are turned back into the values locally.
+package redact
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// International numbers with separators, like +1 415-555-2671 or
+	// (020) 7946 0958, and mainland China mobiles written without them.
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{2,4}\)[\s.-]?|\b\d{2,4}[\s.-])\d{3,4}[\s.-]?\d{3,4}\b|\b1[3-9]\d{9}\b`)
+
+	// tokenPattern matches placeholders, and anything else in their
+	// format, which is left alone when masking.
+	tokenPattern = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*\]`)
+	nameCleaner  = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+type rule struct {
+	label string
+	re    *regexp.Regexp
+}
+
+type secret struct {
+	value, token string
+}
+
+// Redactor masks and unmasks text. Its placeholders live as long as it
+// does, so a value keeps its placeholder across turns and sessions.
+type Redactor struct {
+	rules   []rule
+	secrets []secret
+
+	mu      sync.Mutex
+	byToken map[string]string
+	byValue map[string]string
+	counts  map[string]int
+}
+
+// New builds a redactor from cfg. It returns nil when redaction is off.
+func New(cfg config.RedactionConfig) (*Redactor, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	r := &Redactor{
+		byToken: make(map[string]string),
+		byValue: make(map[string]string),
+		counts:  make(map[string]int),
+	}
+	for name, value := range cfg.Secrets {
+		if value == "" {
+			continue
+		}
+		token := "[SECRET_" + label(name) + "]"
+		r.secrets = append(r.secrets, secret{value: value, token: token})
+		r.byToken[token] = value
+	}
+	// Longest first, so a secret that contains another is masked whole.
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i].value) > len(r.secrets[j].value) })
+
+	if cfg.Emails {
+		r.rules = append(r.rules, rule{label: "EMAIL", re: emailPattern})
+	}
+	if cfg.Phones {
+		r.rules = append(r.rules, rule{label: "PHONE", re: phonePattern})
+	}
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %s: %w", p.Name, err)
+		}
+		r.rules = append(r.rules, rule{label: label(p.Name), re: re})
+	}
+	return r, nil
+}
+
+// label turns a configured name into placeholder form: "home wifi" becomes
+// "HOME_WIFI".
+func label(name string) string {
+	name = strings.Trim(nameCleaner.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+	if name == "" {
+		return "REDACTED"
+	}
+	return name
+}
+
+// Redact masks text.
+func (r *Redactor) Redact(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+	for _, s := range r.secrets {
+		text = strings.ReplaceAll(text, s.value, s.token)
+	}
+	for _, rule := range r.rules {
+		text = outsideTokens(text, func(s string) string {
+			return rule.re.ReplaceAllStringFunc(s, func(value string) string {
+				return r.token(rule.label, value)
+			})
+		})
+	}
+	return text
+}
+
+// Restore puts the values back for the placeholders in text.
+func (r *Redactor) Restore(text string) string {
+	if r == nil || !strings.Contains(text, "[") {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return tokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		if value, ok := r.byToken[token]; ok {
+			return value
+		}
+		return token
+	})
+}
+
+func (r *Redactor) token(label, value string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if token, ok := r.byValue[value]; ok {
+		return token
+	}
+	r.counts[label]++
+	token := fmt.Sprintf("[%s_%d]", label, r.counts[label])
+	r.byValue[value] = token
+	r.byToken[token] = value
+	return token
+}
+
+// outsideTokens applies f to the parts of text between placeholders, so a
+// pattern never masks part of a placeholder made by an earlier one.
+func outsideTokens(text string, f func(string) string) string {
+	locs := tokenPattern.FindAllStringIndex(text, -1)
+	if len(locs) == 0 {
+		return f(text)
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		b.WriteString(f(text[last:loc[0]]))
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(f(text[last:]))
+	return b.String()
+}
+
+// mapStrings returns v with f applied to every string in it, copying maps
+// and slices rather than changing them.
+func mapStrings(v any, f func(string) string) any {
+	switch v := v.(type) {
+	case string:
+		return f(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = mapStrings(e, f)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = mapStrings(e, f)
+		}
+		return out
+	}
+	return v
+}
+
+func mapArgs(args map[string]any, f func(string) string) map[string]any {
+	if args == nil {
+		return nil
+	}
+	return mapStrings(args, f).(map[string]any)
+}
+
+// request returns a masked copy of req. The runtime's history is not
+// changed, so it keeps the real values.
+func (r *Redactor) request(req model.Request) model.Request {
+	req.System = r.Redact(req.System)
+	messages := make([]model.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		msg.Content = r.Redact(msg.Content)
+		msg.ReasoningContent = r.Redact(msg.ReasoningContent)
+		if msg.ContentBlocks != nil {
+			blocks := make([]model.ContentBlock, len(msg.ContentBlocks))
+			for j, block := range msg.ContentBlocks {
+				block.Text = r.Redact(block.Text)
+				blocks[j] = block
+			}
+			msg.ContentBlocks = blocks
+		}
+		if msg.ToolCalls != nil {
+			calls := make([]model.ToolCall, len(msg.ToolCalls))
+			for j, call := range msg.ToolCalls {
+				call.Arguments = mapArgs(call.Arguments, r.Redact)
+				call.Result = r.Redact(call.Result)
+				calls[j] = call
+			}
+			msg.ToolCalls = calls
+		}
+		messages[i] = msg
+	}
+	req.Messages = messages
+	return req
+}
+
+func (r *Redactor) restoreCall(call *model.ToolCall) {
+	call.Arguments = mapArgs(call.Arguments, r.Restore)
+}
+
+func (r *Redactor) restoreResponse(resp *model.Response) {
+	resp.Message.Content = r.Restore(resp.Message.Content)
+	resp.Message.ReasoningContent = r.Restore(resp.Message.ReasoningContent)
+	for i := range resp.Message.ContentBlocks {
+		resp.Message.ContentBlocks[i].Text = r.Restore(resp.Message.ContentBlocks[i].Text)
+	}
+	for i := range resp.Message.ToolCalls {
+		r.restoreCall(&resp.Message.ToolCalls[i])
+	}
+}
+
+// ModelFactory wraps the models f builds so r masks what they are sent and
+// unmasks what they answer. With a nil r it returns f.
+func ModelFactory(f api.ModelFactory, r *Redactor) api.ModelFactory {
+	if r == nil {
+		return f
+	}
+	return api.ModelFactoryFunc(func(ctx context.Context) (model.Model, error) {
+		m, err := f.Model(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &redactedModel{Model: m, r: r}, nil
+	})
+}
+
+type redactedModel struct {
+	model.Model
+	r *Redactor
+}
+
+func (m *redactedModel) Complete(ctx context.Context, req model.Request) (*model.Response, error) {
+	resp, err := m.Model.Complete(ctx, m.r.request(req))
+	if resp != nil {
+		m.r.restoreResponse(resp)
+	}
+	return resp, err
+}
+
+func (m *redactedModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	var pending string
+	return m.Model.CompleteStream(ctx, m.r.request(req), func(res model.StreamResult) error {
+		if res.Delta != "" || res.Final {
+			text := pending + res.Delta
+			pending = ""
+			// A placeholder may be split across deltas; hold back a
+			// trailing "[..." until the rest of it arrives.
+			if i := strings.LastIndexByte(text, '['); i >= 0 && !res.Final && isTokenPrefix(text[i:]) {
+				text, pending = text[:i], text[i:]
+			}
+			if text == "" && res.ToolCall == nil && res.Response == nil && !res.Final {
+				return nil
+			}
+			res.Delta = m.r.Restore(text)
+		}
+		if res.ToolCall != nil {
+			call := *res.ToolCall
+			m.r.restoreCall(&call)
+			res.ToolCall = &call
+		}
+		if res.Response != nil {
+			m.r.restoreResponse(res.Response)
+		}
+		return cb(res)
+	})
+}
+
+// maxTokenLen bounds how much of a stream is held back for a placeholder.
+const maxTokenLen = 64
+
+func isTokenPrefix(s string) bool {
+	if len(s) > maxTokenLen {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package redact
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func newRedactor(t *testing.T, cfg config.RedactionConfig) *Redactor {
+	t.Helper()
+	cfg.Enabled = true
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	return r
+}
+
+func TestNew_Disabled(t *testing.T) {
+	r, err := New(config.RedactionConfig{Emails: true})
+	if err != nil || r != nil {
+		t.Fatalf("New = %v, %v; want nil", r, err)
+	}
+	if got := r.Redact("a@b.co"); got != "a@b.co" {
+		t.Errorf("nil Redact = %q", got)
+	}
+	if _, err := New(config.RedactionConfig{Enabled: true, Patterns: []config.RedactionPattern{{Name: "x", Pattern: "("}}}); err == nil {
+		t.Error("expected error for bad pattern")
+	}
+}
+
+func TestRedactor_RoundTrip(t *testing.T) {
+	r := newRedactor(t, config.RedactionConfig{
+		Emails:   true,
+		Phones:   true,
+		Patterns: []config.RedactionPattern{{Name: "order id", Pattern: `ORD-\d+`}},
+		Secrets:  map[string]string{"wifi": "hunter2"},
+	})
+
+	in := "Mail ann@example.com or call +1 415-555-2671 about ORD-991; wifi is hunter2. Again: ann@example.com, 13812345678."
+	masked := r.Redact(in)
+	for _, leaked := range []string{"ann@example.com", "415-555-2671", "ORD-991", "hunter2", "13812345678"} {
+		if strings.Contains(masked, leaked) {
+			t.Errorf("masked text still has %q: %s", leaked, masked)
+		}
+	}
+	want := "Mail [EMAIL_1] or call [PHONE_1] about [ORDER_ID_1]; wifi is [SECRET_WIFI]. Again: [EMAIL_1], [PHONE_2]."
+	if masked != want {
+		t.Errorf("Redact =\n%s\nwant\n%s", masked, want)
+	}
+	if got := r.Restore(masked); got != in {
+		t.Errorf("Restore = %q", got)
+	}
+	if got := r.Restore("[UNKNOWN_9] stays"); got != "[UNKNOWN_9] stays" {
+		t.Errorf("Restore unknown = %q", got)
+	}
+}
+
+func TestRedactor_LeavesDatesAndPlaceholders(t *testing.T) {
+	if got := newRedactor(t, config.RedactionConfig{Phones: true}).Redact("due 2026-10-16"); got != "due 2026-10-16" {
+		t.Errorf("date masked as a phone: %q", got)
+	}
+	r := newRedactor(t, config.RedactionConfig{
+		Phones:   true,
+		Patterns: []config.RedactionPattern{{Name: "num", Pattern: `\d+`}},
+	})
+	// The \d+ pattern must not mask the digit inside [PHONE_1].
+	if got := r.Redact("call 020 7946 0958 then 42"); got != "call [PHONE_1] then [NUM_1]" {
+		t.Errorf("Redact = %q", got)
+	}
+}
+
+type stubModel struct {
+	got    model.Request
+	reply  model.Response
+	deltas []string
+}
+
+func (m *stubModel) Complete(_ context.Context, req model.Request) (*model.Response, error) {
+	m.got = req
+	resp := m.reply
+	return &resp, nil
+}
+
+func (m *stubModel) CompleteStream(_ context.Context, req model.Request, cb model.StreamHandler) error {
+	m.got = req
+	for _, d := range m.deltas {
+		if err := cb(model.StreamResult{Delta: d}); err != nil {
+			return err
+		}
+	}
+	resp := m.reply
+	return cb(model.StreamResult{Final: true, Response: &resp})
+}
+
+func TestModelFactory(t *testing.T) {
+	r := newRedactor(t, config.RedactionConfig{Emails: true})
+	stub := &stubModel{}
+	factory := ModelFactory(api.ModelFactoryFunc(func(context.Context) (model.Model, error) { return stub, nil }), r)
+	m, err := factory.Model(context.Background())
+	if err != nil {
+		t.Fatalf("Model error: %v", err)
+	}
+
+	history := []model.Message{
+		{Role: "user", Content: "email bob@example.com"},
+		{Role: "assistant", ToolCalls: []model.ToolCall{{Name: "Read", Arguments: map[string]any{"to": "bob@example.com"}, Result: "sent to bob@example.com"}}},
+	}
+	stub.reply = model.Response{Message: model.Message{
+		Content:   "Done, [EMAIL_1] has it.",
+		ToolCalls: []model.ToolCall{{Name: "Send", Arguments: map[string]any{"to": []any{"[EMAIL_1]"}}}},
+	}}
+	resp, err := m.Complete(context.Background(), model.Request{System: "owner: bob@example.com", Messages: history})
+	if err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+	if strings.Contains(stub.got.System+stub.got.Messages[0].Content+stub.got.Messages[1].ToolCalls[0].Result, "bob@") {
+		t.Errorf("provider saw the address: %+v", stub.got)
+	}
+	if stub.got.Messages[1].ToolCalls[0].Arguments["to"] != "[EMAIL_1]" {
+		t.Errorf("tool arguments not masked: %+v", stub.got.Messages[1].ToolCalls[0].Arguments)
+	}
+	if history[0].Content != "email bob@example.com" || history[1].ToolCalls[0].Arguments["to"] != "bob@example.com" {
+		t.Errorf("history was changed: %+v", history)
+	}
+	if resp.Message.Content != "Done, bob@example.com has it." {
+		t.Errorf("reply = %q", resp.Message.Content)
+	}
+	if to := resp.Message.ToolCalls[0].Arguments["to"].([]any)[0]; to != "bob@example.com" {
+		t.Errorf("tool call argument = %v", to)
+	}
+
+	// A placeholder split across deltas still comes out whole.
+	stub.deltas = []string{"Sent to [EMA", "IL_1", "] and [x"}
+	var streamed strings.Builder
+	err = m.CompleteStream(context.Background(), model.Request{Messages: history}, func(res model.StreamResult) error {
+		streamed.WriteString(res.Delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("CompleteStream error: %v", err)
+	}
+	if got := streamed.String(); got != "Sent to bob@example.com and [x" {
+		t.Errorf("streamed %q", got)
+	}
+}
@@ -0,0 +1,180 @@
+// Package reminders keeps messages the agent scheduled for later, such as
+// "remind me to call mom at 6pm", and hands them back when they are due.
+package reminders
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/cron"
+)
+
+// Path returns where reminders are kept for workspace.
+func Path(workspace string) string {
+	return filepath.Join(workspace, ".claude", "reminders.json")
+}
+
+// Reminder is a message to send to a chat once or on a schedule.
+type Reminder struct {
+	ID          string        `json:"id"`
+	Channel     string        `json:"channel"`
+	ChatID      string        `json:"chatId"`
+	Message     string        `json:"message"`
+	Schedule    cron.Schedule `json:"schedule"`
+	NextAtMs    int64         `json:"nextAtMs"`
+	CreatedAtMs int64         `json:"createdAtMs"`
+}
+
+// Next returns when r is next due.
+func (r Reminder) Next() time.Time {
+	return time.UnixMilli(r.NextAtMs)
+}
+
+// Store persists reminders as a JSON file. The file is re-read on every
+// operation so the CLI and a running gateway can share it.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Add schedules message for the chat, first due at the schedule's next time
+// after now.
+func (s *Store) Add(channel, chatID, message string, schedule cron.Schedule, now time.Time) (Reminder, error) {
+	if err := schedule.Validate(now); err != nil {
+		return Reminder{}, err
+	}
+	next, ok := schedule.Next(now)
+	if !ok {
+		return Reminder{}, fmt.Errorf("schedule %s never fires", schedule.Describe())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return Reminder{}, err
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	r := Reminder{
+		ID:          fmt.Sprintf("%x", b),
+		Channel:     channel,
+		ChatID:      chatID,
+		Message:     message,
+		Schedule:    schedule,
+		NextAtMs:    next.UnixMilli(),
+		CreatedAtMs: now.UnixMilli(),
+	}
+	if err := s.save(append(list, r)); err != nil {
+		return Reminder{}, err
+	}
+	return r, nil
+}
+
+// List returns the reminders of a chat, soonest first. An empty channel
+// lists every reminder.
+func (s *Store) List(channel, chatID string) ([]Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var out []Reminder
+	for _, r := range list {
+		if channel == "" || (r.Channel == channel && r.ChatID == chatID) {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].NextAtMs < out[j].NextAtMs })
+	return out, nil
+}
+
+// Remove deletes reminder id. With a channel, only a reminder of that chat
+// is removed. It reports whether one was.
+func (s *Store) Remove(id, channel, chatID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	for i, r := range list {
+		if r.ID == id && (channel == "" || (r.Channel == channel && r.ChatID == chatID)) {
+			return true, s.save(append(list[:i], list[i+1:]...))
+		}
+	}
+	return false, nil
+}
+
+// Due returns the reminders due at now. Recurring ones move on to their
+// next time and one-shot ones are removed, so each is returned once. A
+// reminder missed while the gateway was down is due as soon as it is back.
+func (s *Store) Due(now time.Time) ([]Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var due []Reminder
+	kept := list[:0]
+	for _, r := range list {
+		if r.NextAtMs > now.UnixMilli() {
+			kept = append(kept, r)
+			continue
+		}
+		due = append(due, r)
+		if next, ok := r.Schedule.Next(now); ok && r.Schedule.Kind != "at" {
+			r.NextAtMs = next.UnixMilli()
+			kept = append(kept, r)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	return due, s.save(kept)
+}
+
+func (s *Store) load() ([]Reminder, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Reminder
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	return list, nil
+}
+
+func (s *Store) save(list []Reminder) error {
+	if list == nil {
+		list = []Reminder{}
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,112 @@
+package reminders
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/cron"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "reminders.json")
+	store := NewStore(path)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	once, err := store.Add("telegram", "42", "call mom", cron.Schedule{Kind: "at", AtMs: now.Add(time.Hour).UnixMilli()}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	daily, err := store.Add("telegram", "42", "stretch", cron.Schedule{Kind: "cron", Expr: "0 30 9 * * *"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Add("slack", "C1", "standup", cron.Schedule{Kind: "every", EveryMs: 10}, now); err == nil {
+		t.Error("expected error for an invalid schedule")
+	}
+	if _, err := store.Add("slack", "C1", "late", cron.Schedule{Kind: "at", AtMs: now.Add(-time.Minute).UnixMilli()}, now); err == nil {
+		t.Error("expected error for a time in the past")
+	}
+
+	list, _ := store.List("telegram", "42")
+	if len(list) != 2 || list[0].ID != daily.ID {
+		t.Errorf("list = %+v", list)
+	}
+	if list, _ := store.List("slack", "C1"); len(list) != 0 {
+		t.Errorf("slack list = %+v", list)
+	}
+
+	// A new store on the same file sees the reminders, as after a restart.
+	store = NewStore(path)
+	if due, _ := store.Due(now.Add(10 * time.Minute)); len(due) != 0 {
+		t.Errorf("nothing is due yet: %+v", due)
+	}
+	due, err := store.Due(now.Add(2 * time.Hour))
+	if err != nil || len(due) != 2 {
+		t.Fatalf("due = %+v, %v", due, err)
+	}
+	list, _ = store.List("", "")
+	if len(list) != 1 || list[0].ID != daily.ID || !list[0].Next().Equal(time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("after due = %+v", list)
+	}
+
+	if removed, _ := store.Remove(daily.ID, "slack", "C1"); removed {
+		t.Error("removed a reminder of another chat")
+	}
+	if removed, _ := store.Remove(daily.ID, "telegram", "42"); !removed {
+		t.Error("reminder not removed")
+	}
+	if removed, _ := store.Remove(once.ID, "", ""); removed {
+		t.Error("one-shot reminder should be gone after it was due")
+	}
+}
+
+func TestScheduleTool(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(filepath.Join(t.TempDir(), "reminders.json"))
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	tools := Tools(store)
+	schedule := tools[2].(*scheduleTool)
+	schedule.now = func() time.Time { return now }
+	ctx := WithOrigin(context.Background(), "telegram", "42")
+
+	res, _ := schedule.Execute(ctx, map[string]any{"message": "Time to call mom!", "when": "in 20 minutes"})
+	if !res.Success || !strings.Contains(res.Output, "next at Fri 2026-10-16 09:20") {
+		t.Errorf("when: %+v", res)
+	}
+	res, _ = schedule.Execute(ctx, map[string]any{"message": "Standup", "cron": "0 0 10 * * 1-5"})
+	if !res.Success {
+		t.Errorf("cron: %+v", res)
+	}
+	for _, params := range []map[string]any{
+		{"message": "x"},
+		{"message": "x", "when": "soonish"},
+		{"message": "x", "at": "6pm"},
+		{"message": "x", "when": "in 5 minutes", "cron": "@daily"},
+		{"when": "in 5 minutes"},
+	} {
+		if res, _ := schedule.Execute(ctx, params); res.Success {
+			t.Errorf("%v: expected failure", params)
+		}
+	}
+	if res, _ := schedule.Execute(context.Background(), map[string]any{"message": "x", "when": "in 5 minutes"}); res.Success {
+		t.Error("scheduling outside a chat should fail")
+	}
+
+	res, _ = tools[1].Execute(ctx, nil)
+	if !strings.Contains(res.Output, "Time to call mom!") || !strings.Contains(res.Output, "Standup") {
+		t.Errorf("list: %q", res.Output)
+	}
+	list, _ := store.List("telegram", "42")
+	if res, _ := tools[0].Execute(WithOrigin(context.Background(), "slack", "C1"), map[string]any{"id": list[0].ID}); res.Success {
+		t.Error("cancelled a reminder from another chat")
+	}
+	if res, _ := tools[0].Execute(ctx, map[string]any{"id": list[0].ID}); !res.Success {
+		t.Errorf("cancel: %+v", res)
+	}
+}
@@ -0,0 +1,205 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/cron"
+)
+
+type originKey struct{}
+
+type origin struct{ channel, chatID string }
+
+// WithOrigin marks ctx as a run for a message from chatID on channel, so
+// that reminders scheduled during the run go back to that chat.
+func WithOrigin(ctx context.Context, channel, chatID string) context.Context {
+	return context.WithValue(ctx, originKey{}, origin{channel, chatID})
+}
+
+func originOf(ctx context.Context) (origin, error) {
+	o, ok := ctx.Value(originKey{}).(origin)
+	if !ok || o.channel == "" {
+		return origin{}, errors.New("reminders can only be used from a chat")
+	}
+	return o, nil
+}
+
+// Tools returns the tools the agent manages reminders with.
+func Tools(store *Store) []tool.Tool {
+	return []tool.Tool{
+		&cancelTool{store: store},
+		&listTool{store: store},
+		&scheduleTool{store: store, now: time.Now},
+	}
+}
+
+type scheduleTool struct {
+	store *Store
+	now   func() time.Time
+}
+
+func (t *scheduleTool) Name() string { return "schedule_message" }
+
+func (t *scheduleTool) Description() string {
+	return "Schedule a message to send to this chat later, once or on a schedule. " +
+		"Use it for reminders (\"remind me to call mom at 6pm\") and recurring nudges. " +
+		"Give exactly one of when, at or cron."
+}
+
+func (t *scheduleTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"message": map[string]any{
+				"type":        "string",
+				"description": "The text to send, written to the user, e.g. \"Time to call mom!\"",
+			},
+			"when": map[string]any{
+				"type":        "string",
+				"description": "When, in words: \"in 20 minutes\", \"tomorrow at 8am\", \"every weekday at 9am\", \"every 2 hours\"",
+			},
+			"at": map[string]any{
+				"type":        "string",
+				"description": "A one-time send time in RFC 3339, e.g. 2026-10-16T18:00:00+08:00",
+			},
+			"cron": map[string]any{
+				"type":        "string",
+				"description": "A recurring schedule as a cron expression with seconds: \"0 0 9 * * 1-5\"",
+			},
+		},
+		Required: []string{"message"},
+	}
+}
+
+func (t *scheduleTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	o, err := originOf(ctx)
+	if err != nil {
+		return failed(err), nil
+	}
+	message := strings.TrimSpace(stringParam(params, "message"))
+	if message == "" {
+		return failed(errors.New("message is required")), nil
+	}
+	now := t.now()
+	schedule, err := parseSchedule(params, now)
+	if err != nil {
+		return failed(err), nil
+	}
+	r, err := t.store.Add(o.channel, o.chatID, message, schedule, now)
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{
+		Success: true,
+		Output:  fmt.Sprintf("Scheduled reminder %s (%s), next at %s.", r.ID, schedule.Describe(), r.Next().Format("Mon 2006-01-02 15:04 MST")),
+	}, nil
+}
+
+// parseSchedule reads the one schedule parameter that was given.
+func parseSchedule(params map[string]any, now time.Time) (cron.Schedule, error) {
+	when, at, expr := stringParam(params, "when"), stringParam(params, "at"), stringParam(params, "cron")
+	given := 0
+	for _, v := range []string{when, at, expr} {
+		if v != "" {
+			given++
+		}
+	}
+	if given != 1 {
+		return cron.Schedule{}, errors.New("give exactly one of when, at or cron")
+	}
+	switch {
+	case at != "":
+		ts, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return cron.Schedule{}, fmt.Errorf("at must be RFC 3339: %w", err)
+		}
+		return cron.Schedule{Kind: "at", AtMs: ts.UnixMilli()}, nil
+	case expr != "":
+		return cron.Schedule{Kind: "cron", Expr: expr}, nil
+	}
+	// ParseNatural splits a schedule from a prompt, so give it one.
+	schedule, _, err := cron.ParseNatural(when+": remind", now)
+	if err != nil {
+		return cron.Schedule{}, fmt.Errorf("could not understand when %q: %w; use at or cron instead", when, err)
+	}
+	return schedule, nil
+}
+
+type listTool struct{ store *Store }
+
+func (t *listTool) Name() string { return "list_reminders" }
+
+func (t *listTool) Description() string {
+	return "List the reminders scheduled for this chat."
+}
+
+func (t *listTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{Type: "object", Properties: map[string]any{}, Required: []string{}}
+}
+
+func (t *listTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	o, err := originOf(ctx)
+	if err != nil {
+		return failed(err), nil
+	}
+	list, err := t.store.List(o.channel, o.chatID)
+	if err != nil {
+		return failed(err), nil
+	}
+	if len(list) == 0 {
+		return &tool.ToolResult{Success: true, Output: "No reminders scheduled."}, nil
+	}
+	var b strings.Builder
+	for _, r := range list {
+		fmt.Fprintf(&b, "- %s: %q, %s, next at %s\n", r.ID, r.Message, r.Schedule.Describe(), r.Next().Format("Mon 2006-01-02 15:04"))
+	}
+	return &tool.ToolResult{Success: true, Output: b.String()}, nil
+}
+
+type cancelTool struct{ store *Store }
+
+func (t *cancelTool) Name() string { return "cancel_reminder" }
+
+func (t *cancelTool) Description() string {
+	return "Cancel a reminder of this chat by the id list_reminders shows."
+}
+
+func (t *cancelTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"id": map[string]any{"type": "string", "description": "The reminder id"},
+		},
+		Required: []string{"id"},
+	}
+}
+
+func (t *cancelTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	o, err := originOf(ctx)
+	if err != nil {
+		return failed(err), nil
+	}
+	id := stringParam(params, "id")
+	removed, err := t.store.Remove(id, o.channel, o.chatID)
+	if err != nil {
+		return failed(err), nil
+	}
+	if !removed {
+		return failed(fmt.Errorf("no reminder %q in this chat", id)), nil
+	}
+	return &tool.ToolResult{Success: true, Output: "Cancelled reminder " + id + "."}, nil
+}
+
+func stringParam(params map[string]any, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+func failed(err error) *tool.ToolResult {
+	return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}
+}
@@ -0,0 +1,316 @@
+// Package search is myclaw's web search: a few search backends behind one
+// interface, and the web_search tool the agent calls them through.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"golang.org/x/net/html"
+)
+
+const (
+	braveAPIURL      = "https://api.search.brave.com/res/v1"
+	serpAPIURL       = "https://serpapi.com"
+	duckDuckGoURL    = "https://html.duckduckgo.com"
+	maxResponseBytes = 2 << 20
+	userAgent        = "myclaw (+https://github.com/stellarlinkco/myclaw)"
+)
+
+// httpClient sends the search requests; the tool call has its own deadline
+// on top.
+var httpClient = &http.Client{Timeout: 20 * time.Second}
+
+// Result is one search hit.
+type Result struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// Backend runs a query against one search service.
+type Backend interface {
+	Name() string
+	Search(ctx context.Context, query string, count int) ([]Result, error)
+}
+
+// NewBackend returns the backend cfg selects, or nil when Backend is empty.
+func NewBackend(cfg config.SearchConfig) (Backend, error) {
+	base := strings.TrimRight(cfg.BaseURL, "/")
+	pick := func(def string) string {
+		if base != "" {
+			return base
+		}
+		return def
+	}
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "brave":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("tools.search: brave needs an API key")
+		}
+		return &brave{baseURL: pick(braveAPIURL), apiKey: cfg.APIKey}, nil
+	case "serpapi":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("tools.search: serpapi needs an API key")
+		}
+		return &serpAPI{baseURL: pick(serpAPIURL), apiKey: cfg.APIKey}, nil
+	case "searxng":
+		if base == "" {
+			return nil, fmt.Errorf("tools.search: searxng needs baseUrl")
+		}
+		return &searxng{baseURL: base}, nil
+	case "duckduckgo":
+		return &duckDuckGo{baseURL: pick(duckDuckGoURL)}, nil
+	default:
+		return nil, fmt.Errorf("tools.search: unknown backend %q", cfg.Backend)
+	}
+}
+
+// brave calls the Brave Search API.
+type brave struct {
+	baseURL, apiKey string
+}
+
+func (b *brave) Name() string { return "brave" }
+
+func (b *brave) Search(ctx context.Context, query string, count int) ([]Result, error) {
+	q := url.Values{"q": {query}, "count": {strconv.Itoa(count)}}
+	var out struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	header := http.Header{"X-Subscription-Token": {b.apiKey}}
+	if err := getJSON(ctx, b.baseURL+"/web/search?"+q.Encode(), header, &out); err != nil {
+		return nil, fmt.Errorf("brave: %w", err)
+	}
+	results := make([]Result, 0, len(out.Web.Results))
+	for _, r := range out.Web.Results {
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: stripTags(r.Description)})
+	}
+	return results, nil
+}
+
+// serpAPI calls SerpAPI's Google engine.
+type serpAPI struct {
+	baseURL, apiKey string
+}
+
+func (s *serpAPI) Name() string { return "serpapi" }
+
+func (s *serpAPI) Search(ctx context.Context, query string, count int) ([]Result, error) {
+	q := url.Values{"engine": {"google"}, "q": {query}, "num": {strconv.Itoa(count)}, "api_key": {s.apiKey}}
+	var out struct {
+		Error          string `json:"error"`
+		OrganicResults []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"organic_results"`
+	}
+	if err := getJSON(ctx, s.baseURL+"/search.json?"+q.Encode(), nil, &out); err != nil {
+		return nil, fmt.Errorf("serpapi: %w", err)
+	}
+	if out.Error != "" && len(out.OrganicResults) == 0 && !strings.Contains(out.Error, "hasn't returned any results") {
+		return nil, fmt.Errorf("serpapi: %s", out.Error)
+	}
+	results := make([]Result, 0, len(out.OrganicResults))
+	for _, r := range out.OrganicResults {
+		results = append(results, Result{Title: r.Title, URL: r.Link, Snippet: r.Snippet})
+	}
+	return results, nil
+}
+
+// searxng calls a SearXNG instance's JSON API.
+type searxng struct {
+	baseURL string
+}
+
+func (s *searxng) Name() string { return "searxng" }
+
+func (s *searxng) Search(ctx context.Context, query string, count int) ([]Result, error) {
+	q := url.Values{"q": {query}, "format": {"json"}}
+	var out struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := getJSON(ctx, s.baseURL+"/search?"+q.Encode(), nil, &out); err != nil {
+		return nil, fmt.Errorf("searxng: %w", err)
+	}
+	results := make([]Result, 0, len(out.Results))
+	for _, r := range out.Results {
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+// duckDuckGo reads DuckDuckGo's HTML results page, which needs no key.
+type duckDuckGo struct {
+	baseURL string
+}
+
+func (d *duckDuckGo) Name() string { return "duckduckgo" }
+
+func (d *duckDuckGo) Search(ctx context.Context, query string, count int) ([]Result, error) {
+	form := url.Values{"q": {query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.baseURL+"/html/", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := do(req)
+	if err != nil {
+		return nil, fmt.Errorf("duckduckgo: %w", err)
+	}
+	doc, err := html.Parse(strings.NewReader(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("duckduckgo: %w", err)
+	}
+	return parseDuckDuckGo(doc), nil
+}
+
+// parseDuckDuckGo collects the result links and their snippets. A snippet
+// belongs to the link before it.
+func parseDuckDuckGo(doc *html.Node) []Result {
+	var results []Result
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			switch {
+			case hasClass(n, "result__a"):
+				if href := resultURL(attr(n, "href")); href != "" {
+					results = append(results, Result{Title: text(n), URL: href})
+				}
+				return
+			case hasClass(n, "result__snippet") && len(results) > 0:
+				results[len(results)-1].Snippet = text(n)
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return results
+}
+
+// resultURL unwraps DuckDuckGo's redirect links and drops its ads.
+func resultURL(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if strings.HasSuffix(u.Host, "duckduckgo.com") {
+		return ""
+	}
+	if u.Scheme == "" {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
+func getJSON(ctx context.Context, endpoint string, header http.Header, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Accept", "application/json")
+	body, err := do(req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func do(req *http.Request) ([]byte, error) {
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 200 {
+			msg = msg[:200]
+		}
+		return nil, fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	return body, nil
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func text(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// stripTags removes the <strong> highlighting Brave puts in descriptions.
+func stripTags(s string) string {
+	if !strings.Contains(s, "<") {
+		return s
+	}
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+	return text(doc)
+}
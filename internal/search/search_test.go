@@ -0,0 +1,210 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestNewBackend(t *testing.T) {
+	tests := []struct {
+		cfg     config.SearchConfig
+		want    string
+		wantErr bool
+	}{
+		{cfg: config.SearchConfig{}},
+		{cfg: config.SearchConfig{Backend: "brave", APIKey: "k"}, want: "brave"},
+		{cfg: config.SearchConfig{Backend: "brave"}, wantErr: true},
+		{cfg: config.SearchConfig{Backend: "serpapi", APIKey: "k"}, want: "serpapi"},
+		{cfg: config.SearchConfig{Backend: "searxng"}, wantErr: true},
+		{cfg: config.SearchConfig{Backend: "searxng", BaseURL: "http://localhost:8888"}, want: "searxng"},
+		{cfg: config.SearchConfig{Backend: "duckduckgo"}, want: "duckduckgo"},
+		{cfg: config.SearchConfig{Backend: "bing"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		b, err := NewBackend(tt.cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewBackend(%+v) error = %v, wantErr %v", tt.cfg, err, tt.wantErr)
+			continue
+		}
+		if got := ""; b != nil {
+			if got = b.Name(); got != tt.want {
+				t.Errorf("NewBackend(%+v) = %s, want %s", tt.cfg, got, tt.want)
+			}
+		} else if tt.want != "" {
+			t.Errorf("NewBackend(%+v) = nil, want %s", tt.cfg, tt.want)
+		}
+	}
+}
+
+func TestBackends(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/web/search":
+			if r.Header.Get("X-Subscription-Token") != "brave-key" || r.URL.Query().Get("q") != "go generics" {
+				http.Error(w, "bad request", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"web":{"results":[{"title":"Generics","url":"https://go.dev/doc/tutorial/generics","description":"Learn <strong>generics</strong>."}]}}`)
+		case "/search.json":
+			if r.URL.Query().Get("api_key") != "serp-key" {
+				fmt.Fprint(w, `{"error":"Invalid API key."}`)
+				return
+			}
+			fmt.Fprint(w, `{"organic_results":[{"title":"Generics","link":"https://go.dev/doc/tutorial/generics","snippet":"Learn generics."}]}`)
+		case "/search":
+			if r.URL.Query().Get("format") != "json" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"results":[{"title":"Generics","url":"https://go.dev/doc/tutorial/generics","content":"Learn generics."}]}`)
+		case "/html/":
+			r.ParseForm()
+			if r.Method != http.MethodPost || r.PostForm.Get("q") != "go generics" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `<html><body>
+<div class="result"><a class="result__a" href="//duckduckgo.com/y.js?ad_provider=x">Ad</a></div>
+<div class="result"><h2><a class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2Ftutorial%2Fgenerics&rut=x">Tutorial: <b>Generics</b></a></h2>
+<a class="result__snippet" href="#">Learn   <b>generics</b>.</a></div>
+</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	want := Result{Title: "Generics", URL: "https://go.dev/doc/tutorial/generics", Snippet: "Learn generics."}
+	for _, cfg := range []config.SearchConfig{
+		{Backend: "brave", APIKey: "brave-key", BaseURL: srv.URL},
+		{Backend: "serpapi", APIKey: "serp-key", BaseURL: srv.URL},
+		{Backend: "searxng", BaseURL: srv.URL + "/"},
+		{Backend: "duckduckgo", BaseURL: srv.URL},
+	} {
+		b, err := NewBackend(cfg)
+		if err != nil {
+			t.Fatalf("NewBackend(%s): %v", cfg.Backend, err)
+		}
+		results, err := b.Search(context.Background(), "go generics", 5)
+		if err != nil {
+			t.Errorf("%s: %v", cfg.Backend, err)
+			continue
+		}
+		if cfg.Backend == "duckduckgo" {
+			want.Title = "Tutorial: Generics"
+		}
+		if len(results) != 1 || results[0] != want {
+			t.Errorf("%s results = %+v, want [%+v]", cfg.Backend, results, want)
+		}
+	}
+
+	b, _ := NewBackend(config.SearchConfig{Backend: "serpapi", APIKey: "wrong", BaseURL: srv.URL})
+	if _, err := b.Search(context.Background(), "go generics", 5); err == nil || !strings.Contains(err.Error(), "Invalid API key") {
+		t.Errorf("serpapi error = %v, want the API's message", err)
+	}
+	b, _ = NewBackend(config.SearchConfig{Backend: "brave", APIKey: "wrong", BaseURL: srv.URL})
+	if _, err := b.Search(context.Background(), "go generics", 5); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("brave error = %v, want the HTTP status", err)
+	}
+}
+
+type fakeBackend struct {
+	calls   atomic.Int32
+	results []Result
+}
+
+func (f *fakeBackend) Name() string { return "fake" }
+
+func (f *fakeBackend) Search(ctx context.Context, query string, count int) ([]Result, error) {
+	f.calls.Add(1)
+	return f.results, nil
+}
+
+func TestTool_Execute(t *testing.T) {
+	cache = &resultCache{entries: map[cacheKey]cacheEntry{}}
+	backend := &fakeBackend{results: []Result{
+		{Title: "Go", URL: "https://go.dev/", Snippet: strings.Repeat("word ", 40)},
+		{Title: "Spam", URL: "https://spam.example.com/go", Snippet: "buy now"},
+		{Title: "Blog", URL: "https://www.go.dev/blog", Snippet: "news"},
+	}}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tl := &Tool{backend: backend, maxResults: 5, snippetLen: 50, ttl: time.Minute, now: func() time.Time { return now }}
+
+	res, err := tl.Execute(context.Background(), map[string]any{
+		"query":           "golang",
+		"blocked_domains": []any{"example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	results := res.Data.([]Result)
+	if len(results) != 2 || results[0].Title != "Go" || results[1].Title != "Blog" {
+		t.Fatalf("results = %+v, want spam blocked", results)
+	}
+	if n := len([]rune(results[0].Snippet)); n > 51 || !strings.HasSuffix(results[0].Snippet, "…") {
+		t.Errorf("snippet %q should be cut to 50 characters", results[0].Snippet)
+	}
+	if !strings.Contains(res.Output, "1. Go\n   https://go.dev/") {
+		t.Errorf("output = %q", res.Output)
+	}
+
+	res, err = tl.Execute(context.Background(), map[string]any{"query": "GoLang ", "allowed_domains": []any{"go.dev"}, "max_results": float64(1)})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if results := res.Data.([]Result); len(results) != 1 || results[0].Title != "Go" {
+		t.Errorf("results = %+v, want only the first go.dev result", results)
+	}
+	// max_results is part of the cache key, so that was a second search.
+	if got := backend.calls.Load(); got != 2 {
+		t.Errorf("backend calls = %d, want 2", got)
+	}
+	tl.Execute(context.Background(), map[string]any{"query": "golang"})
+	if got := backend.calls.Load(); got != 2 {
+		t.Errorf("backend calls = %d, want the repeat served from cache", got)
+	}
+	now = now.Add(2 * time.Minute)
+	tl.Execute(context.Background(), map[string]any{"query": "golang"})
+	if got := backend.calls.Load(); got != 3 {
+		t.Errorf("backend calls = %d, want a fresh search after the TTL", got)
+	}
+
+	if _, err := tl.Execute(context.Background(), map[string]any{"query": " "}); err == nil {
+		t.Error("Execute with an empty query = nil error")
+	}
+}
+
+func TestTools(t *testing.T) {
+	add, disallow, err := Tools(config.SearchConfig{})
+	if err != nil || add != nil || disallow != nil {
+		t.Errorf("Tools(off) = %v, %v, %v, want nothing", add, disallow, err)
+	}
+	add, disallow, err = Tools(config.SearchConfig{Backend: "duckduckgo"})
+	if err != nil || len(add) != 1 || add[0].Name() != ToolName || len(disallow) != 1 || disallow[0] != SDKToolName {
+		t.Errorf("Tools(duckduckgo) = %v, %v, %v", add, disallow, err)
+	}
+	tl := add[0].(*Tool)
+	if tl.maxResults != defaultMaxResults || tl.snippetLen != defaultSnippetLength || tl.ttl != defaultCacheTTL {
+		t.Errorf("defaults = %+v", tl)
+	}
+	if tl, _ := NewTool(config.SearchConfig{Backend: "duckduckgo", CacheTTL: -1}); tl.ttl > 0 {
+		t.Errorf("cacheTTL -1 should turn the cache off, got %v", tl.ttl)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate(short) = %q", got)
+	}
+	if got := truncate("héllo wörld, again", 12); got != "héllo wörld…" {
+		t.Errorf("truncate = %q", got)
+	}
+}
@@ -0,0 +1,268 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	// ToolName is the name the agent calls web search by.
+	ToolName = "web_search"
+	// SDKToolName is the SDK's own search tool, which web_search replaces.
+	SDKToolName = "WebSearch"
+
+	defaultMaxResults    = 5
+	maxMaxResults        = 20
+	defaultSnippetLength = 300
+	defaultCacheTTL      = 10 * time.Minute
+	maxCacheEntries      = 256
+)
+
+// Tool is the web_search tool.
+type Tool struct {
+	backend    Backend
+	maxResults int
+	snippetLen int
+	ttl        time.Duration
+	now        func() time.Time
+}
+
+// NewTool returns the web_search tool for cfg, or nil when cfg names no
+// backend and the SDK's WebSearch should stay.
+func NewTool(cfg config.SearchConfig) (*Tool, error) {
+	backend, err := NewBackend(cfg)
+	if err != nil || backend == nil {
+		return nil, err
+	}
+	t := &Tool{
+		backend:    backend,
+		maxResults: cfg.MaxResults,
+		snippetLen: cfg.SnippetLength,
+		ttl:        time.Duration(cfg.CacheTTL) * time.Second,
+		now:        time.Now,
+	}
+	if t.maxResults <= 0 {
+		t.maxResults = defaultMaxResults
+	}
+	if t.snippetLen <= 0 {
+		t.snippetLen = defaultSnippetLength
+	}
+	if cfg.CacheTTL == 0 {
+		t.ttl = defaultCacheTTL
+	}
+	return t, nil
+}
+
+// Tools returns the tools to add to a runtime and the SDK tools to leave
+// out for cfg: web_search in place of WebSearch once a backend is set.
+func Tools(cfg config.SearchConfig) (add []tool.Tool, disallow []string, err error) {
+	t, err := NewTool(cfg)
+	if err != nil || t == nil {
+		return nil, nil, err
+	}
+	return []tool.Tool{t}, []string{SDKToolName}, nil
+}
+
+func (t *Tool) Name() string { return ToolName }
+
+func (t *Tool) Description() string {
+	return "Search the web (" + t.backend.Name() + ") for current information. " +
+		"Returns titles, URLs and short snippets; fetch a page for its full text."
+}
+
+func (t *Tool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "The search query",
+			},
+			"max_results": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("How many results to return (default %d, at most %d)", t.maxResults, maxMaxResults),
+			},
+			"allowed_domains": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Only include results from these domains",
+			},
+			"blocked_domains": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Never include results from these domains",
+			},
+		},
+		Required: []string{"query"},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	query, _ := params["query"].(string)
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	count := t.maxResults
+	if n, ok := params["max_results"].(float64); ok && n > 0 {
+		count = min(int(n), maxMaxResults)
+	}
+	allowed, blocked := stringList(params["allowed_domains"]), stringList(params["blocked_domains"])
+
+	results, err := t.search(ctx, query, count)
+	if err != nil {
+		return nil, err
+	}
+	results = filterDomains(results, allowed, blocked)
+	if len(results) > count {
+		results = results[:count]
+	}
+	for i := range results {
+		results[i].Snippet = truncate(results[i].Snippet, t.snippetLen)
+	}
+	return &tool.ToolResult{Success: true, Output: format(query, results), Data: results}, nil
+}
+
+// search asks the backend, or the cache when the same query was asked
+// recently. Filters are applied afterwards, so they share the entry.
+func (t *Tool) search(ctx context.Context, query string, count int) ([]Result, error) {
+	key := cacheKey{backend: t.backend.Name(), query: strings.ToLower(query), count: count}
+	if t.ttl > 0 {
+		if results, ok := cache.get(key, t.now().Add(-t.ttl)); ok {
+			return results, nil
+		}
+	}
+	results, err := t.backend.Search(ctx, query, count)
+	if err != nil {
+		return nil, err
+	}
+	if t.ttl > 0 {
+		cache.put(key, results, t.now())
+	}
+	return append([]Result(nil), results...), nil
+}
+
+func format(query string, results []Result) string {
+	if len(results) == 0 {
+		return fmt.Sprintf("No results for %q.", query)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Results for %q:\n", query)
+	for i, r := range results {
+		fmt.Fprintf(&sb, "\n%d. %s\n   %s\n", i+1, r.Title, r.URL)
+		if r.Snippet != "" {
+			fmt.Fprintf(&sb, "   %s\n", r.Snippet)
+		}
+	}
+	return sb.String()
+}
+
+// truncate cuts s to at most n characters, at a word boundary when one is
+// close.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	cut := string(r[:n])
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)*3/4 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+func filterDomains(results []Result, allowed, blocked []string) []Result {
+	if len(allowed) == 0 && len(blocked) == 0 {
+		return results
+	}
+	out := results[:0]
+	for _, r := range results {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if len(allowed) > 0 && !matchDomain(host, allowed) {
+			continue
+		}
+		if matchDomain(host, blocked) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// matchDomain reports whether host is one of domains or a subdomain of one.
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "www.")
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d) || host == "www."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func stringList(v any) []string {
+	items, _ := v.([]any)
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// cache holds recent results for every runtime in the process, so a
+// gateway reload does not throw them away.
+var cache = &resultCache{entries: map[cacheKey]cacheEntry{}}
+
+type cacheKey struct {
+	backend, query string
+	count          int
+}
+
+type cacheEntry struct {
+	results []Result
+	at      time.Time
+}
+
+type resultCache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+}
+
+// get returns the entry for key if it was stored after since.
+func (c *resultCache) get(key cacheKey, since time.Time) ([]Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !e.at.After(since) {
+		return nil, false
+	}
+	return append([]Result(nil), e.results...), true
+}
+
+func (c *resultCache) put(key cacheKey, results []Result, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCacheEntries {
+		var oldest cacheKey
+		for k, e := range c.entries {
+			if oldest == (cacheKey{}) || e.at.Before(c.entries[oldest].at) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = cacheEntry{results: append([]Result(nil), results...), at: now}
+}
@@ -0,0 +1,140 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runner runs a command with stdin and returns its stdout.
+type runner func(stdin, name string, args ...string) (string, error)
+
+func runCommand(stdin, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return stdout.String(), &commandError{name: name, code: exitErr.ExitCode(), stderr: strings.TrimSpace(stderr.String())}
+		}
+		return "", fmt.Errorf("run %s: %w", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// commandError is a keyring tool that exited with an error.
+type commandError struct {
+	name   string
+	code   int
+	stderr string
+}
+
+func (e *commandError) Error() string {
+	if e.stderr == "" {
+		return fmt.Sprintf("%s exited with status %d", e.name, e.code)
+	}
+	return fmt.Sprintf("%s: %s", e.name, e.stderr)
+}
+
+func exitCode(err error) int {
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.code
+	}
+	return 0
+}
+
+// keychain stores secrets in the macOS keychain with the security tool.
+type keychain struct {
+	run runner
+}
+
+// security exits with 44 for items the keychain doesn't have.
+const keychainNotFound = 44
+
+func (k keychain) Get(name string) (string, error) {
+	if err := CheckName(name); err != nil {
+		return "", err
+	}
+	out, err := k.run("", "security", "find-generic-password", "-s", Service, "-a", name, "-w")
+	if exitCode(err) == keychainNotFound {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+func (k keychain) Set(name, value string) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	// The value goes in hex on stdin, so it shows up neither in the
+	// process list nor needs quoting.
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", Service, name, hex.EncodeToString([]byte(value)))
+	_, err := k.run(command, "security", "-i")
+	return err
+}
+
+func (k keychain) Delete(name string) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	_, err := k.run("", "security", "delete-generic-password", "-s", Service, "-a", name)
+	if exitCode(err) == keychainNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// secretService stores secrets through the Secret Service API (GNOME
+// Keyring, KWallet) with libsecret's secret-tool.
+type secretService struct {
+	run runner
+}
+
+func (s secretService) Get(name string) (string, error) {
+	if err := CheckName(name); err != nil {
+		return "", err
+	}
+	out, err := s.run("", "secret-tool", "lookup", "service", Service, "account", name)
+	var cmdErr *commandError
+	// secret-tool exits 1 without a word when nothing matches.
+	if errors.As(err, &cmdErr) && cmdErr.code == 1 && cmdErr.stderr == "" {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", missingTool(err, "secret-tool", "libsecret-tools")
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+func (s secretService) Set(name, value string) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	_, err := s.run(value, "secret-tool", "store", "--label", Service+" "+name, "service", Service, "account", name)
+	return missingTool(err, "secret-tool", "libsecret-tools")
+}
+
+func (s secretService) Delete(name string) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	_, err := s.run("", "secret-tool", "clear", "service", Service, "account", name)
+	return missingTool(err, "secret-tool", "libsecret-tools")
+}
+
+// missingTool explains how to get tool when it isn't installed.
+func missingTool(err error, tool, pkg string) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("%w (install %s, or use environment variables instead of the keyring)", err, pkg)
+	}
+	return err
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package secrets
+
+import "runtime"
+
+func systemKeyring() Store {
+	if runtime.GOOS == "darwin" {
+		return keychain{run: runCommand}
+	}
+	return secretService{run: runCommand}
+}
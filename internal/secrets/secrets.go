@@ -0,0 +1,57 @@
+// Package secrets keeps credentials in the operating system's keyring, so
+// that config.json can name them instead of holding them: the macOS
+// keychain, the Secret Service (libsecret) on Linux and the Windows
+// credential manager.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Service is the keyring service myclaw's secrets are stored under.
+const Service = "myclaw"
+
+// Prefix marks a config value that names a secret, as in
+// "keyring:provider.apiKey".
+const Prefix = "keyring:"
+
+// ErrNotFound is returned for a secret the keyring doesn't have.
+var ErrNotFound = errors.New("secret not found in keyring")
+
+// Store reads and writes secrets by name.
+type Store interface {
+	Get(name string) (string, error)
+	Set(name, value string) error
+	Delete(name string) error
+}
+
+// Keyring returns the system keyring.
+func Keyring() Store {
+	return systemKeyring()
+}
+
+// Ref returns the config value that refers to the secret name.
+func Ref(name string) string {
+	return Prefix + name
+}
+
+// Name returns the secret a config value refers to, if it refers to one.
+func Name(value string) (string, bool) {
+	name, ok := strings.CutPrefix(value, Prefix)
+	name = strings.TrimSpace(name)
+	return name, ok && name != ""
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// CheckName rejects names that aren't made of letters, digits, dots,
+// dashes and underscores, such as "provider.apiKey".
+func CheckName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("secret name %q: use letters, digits, '.', '-' and '_'", name)
+	}
+	return nil
+}
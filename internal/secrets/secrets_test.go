@@ -0,0 +1,105 @@
+package secrets
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+type call struct {
+	stdin string
+	args  string
+}
+
+// fakeRunner records commands and answers them from out and err.
+type fakeRunner struct {
+	calls []call
+	out   string
+	err   error
+}
+
+func (f *fakeRunner) run(stdin, name string, args ...string) (string, error) {
+	f.calls = append(f.calls, call{stdin: stdin, args: name + " " + strings.Join(args, " ")})
+	return f.out, f.err
+}
+
+func TestName(t *testing.T) {
+	if name, ok := Name(Ref("provider.apiKey")); !ok || name != "provider.apiKey" {
+		t.Errorf("Name(Ref) = %q, %v", name, ok)
+	}
+	for _, value := range []string{"sk-plain", "keyring:", "keyring:  "} {
+		if _, ok := Name(value); ok {
+			t.Errorf("Name(%q) should not be a reference", value)
+		}
+	}
+	if err := CheckName("channels.telegram.token"); err != nil {
+		t.Errorf("CheckName: %v", err)
+	}
+	if err := CheckName("a b; rm"); err == nil {
+		t.Error("expected error for name with spaces")
+	}
+}
+
+func TestKeychain(t *testing.T) {
+	f := &fakeRunner{out: "sk-test\n"}
+	k := keychain{run: f.run}
+
+	if got, err := k.Get("provider.apiKey"); err != nil || got != "sk-test" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+	if want := "security find-generic-password -s myclaw -a provider.apiKey -w"; f.calls[0].args != want {
+		t.Errorf("get ran %q", f.calls[0].args)
+	}
+
+	if err := k.Set("provider.apiKey", "sk-test"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	set := f.calls[1]
+	if set.args != "security -i" || set.stdin != "add-generic-password -U -s myclaw -a provider.apiKey -X 736b2d74657374\n" {
+		t.Errorf("set ran %+v", set)
+	}
+	if strings.Contains(set.args, "sk-test") {
+		t.Error("secret leaked into the command line")
+	}
+
+	f.err = &commandError{name: "security", code: keychainNotFound}
+	if _, err := k.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get missing = %v", err)
+	}
+	if err := k.Delete("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete missing = %v", err)
+	}
+}
+
+func TestSecretService(t *testing.T) {
+	f := &fakeRunner{out: "sk-test"}
+	s := secretService{run: f.run}
+
+	if got, err := s.Get("provider.apiKey"); err != nil || got != "sk-test" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+	if err := s.Set("provider.apiKey", "sk-test"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	set := f.calls[1]
+	if set.stdin != "sk-test" || set.args != "secret-tool store --label myclaw provider.apiKey service myclaw account provider.apiKey" {
+		t.Errorf("set ran %+v", set)
+	}
+
+	f.err = &commandError{name: "secret-tool", code: 1}
+	if _, err := s.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get missing = %v", err)
+	}
+	f.err = &commandError{name: "secret-tool", code: 1, stderr: "Cannot autolaunch D-Bus"}
+	if _, err := s.Get("provider.apiKey"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get without D-Bus = %v", err)
+	}
+	f.err = exec.ErrNotFound
+	if err := s.Delete("provider.apiKey"); err == nil || !strings.Contains(err.Error(), "libsecret-tools") {
+		t.Errorf("Delete without secret-tool = %v", err)
+	}
+	if _, err := s.Get("bad name"); err == nil {
+		t.Error("expected error for bad name")
+	}
+}
@@ -0,0 +1,5 @@
+// Package agentpb is the code generated from agent.proto, the gRPC service
+// served by `myclaw serve --grpc`.
+package agentpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative agent.proto
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/stellarlinkco/myclaw/internal/server/agentpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultGRPCSessionID is used when a gRPC request does not name a session.
+const DefaultGRPCSessionID = "grpc"
+
+// grpcAgent serves the Agent service of agentpb/agent.proto.
+type grpcAgent struct {
+	agentpb.UnimplementedAgentServer
+	s *Server
+}
+
+// GRPCServer returns a gRPC server with the Agent service registered. Calls
+// authenticate with the same bearer token as the HTTP API, sent as
+// "authorization" metadata.
+func (s *Server) GRPCServer() *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.MaxRecvMsgSize(maxBodyBytes),
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := s.grpcAuthorized(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.grpcAuthorized(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	agentpb.RegisterAgentServer(gs, &grpcAgent{s: s})
+	return gs
+}
+
+// ServeGRPC accepts gRPC connections on ln until ctx is cancelled, then
+// lets calls in flight finish for up to the shutdown timeout.
+func (s *Server) ServeGRPC(ctx context.Context, ln net.Listener) error {
+	gs := s.GRPCServer()
+	errCh := make(chan error, 1)
+	go func() { errCh <- gs.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("[server] shutting down gRPC")
+	stopped := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		// Open Chat streams would otherwise hold shutdown forever.
+		gs.Stop()
+	}
+	return <-errCh
+}
+
+func (s *Server) grpcAuthorized(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if s.validAuthorization(v) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing token")
+}
+
+// runSession checks req and returns the session it runs in.
+func runSession(req *agentpb.RunRequest) (string, error) {
+	if strings.TrimSpace(req.GetPrompt()) == "" {
+		return "", status.Error(codes.InvalidArgument, "prompt is required")
+	}
+	if id := req.GetSessionId(); id != "" {
+		return id, nil
+	}
+	return DefaultGRPCSessionID, nil
+}
+
+func (a *grpcAgent) Run(ctx context.Context, req *agentpb.RunRequest) (*agentpb.RunResponse, error) {
+	sessionID, err := runSession(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.s.run(ctx, api.Request{Prompt: req.GetPrompt(), SessionID: sessionID})
+	if err != nil {
+		log.Printf("[server] grpc run error (session %s): %v", sessionID, err)
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	out := &agentpb.RunResponse{Output: resultOutput(resp), SessionId: sessionID}
+	if resp != nil && resp.Result != nil {
+		out.StopReason = resp.Result.StopReason
+		out.Usage = &agentpb.Usage{
+			InputTokens:  int64(resp.Result.Usage.InputTokens),
+			OutputTokens: int64(resp.Result.Usage.OutputTokens),
+		}
+	}
+	return out, nil
+}
+
+// Chat answers each request on the stream in turn. A failed run is reported
+// as an ERROR event and the stream stays open for the next request.
+func (a *grpcAgent) Chat(stream agentpb.Agent_ChatServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sessionID, err := runSession(req)
+		if err != nil {
+			return err
+		}
+		if err := a.chatTurn(stream, req.GetPrompt(), sessionID); err != nil {
+			return err
+		}
+	}
+}
+
+func (a *grpcAgent) chatTurn(stream agentpb.Agent_ChatServer, prompt, sessionID string) error {
+	defer a.s.lockSession(sessionID)()
+
+	send := func(ev *agentpb.RunEvent) error {
+		ev.SessionId = sessionID
+		return stream.Send(ev)
+	}
+
+	events, err := a.s.startStream(stream.Context(), api.Request{Prompt: prompt, SessionID: sessionID})
+	if err != nil {
+		log.Printf("[server] grpc chat error (session %s): %v", sessionID, err)
+		return send(&agentpb.RunEvent{Type: agentpb.RunEvent_ERROR, Text: err.Error()})
+	}
+
+	result := &agentpb.RunResponse{SessionId: sessionID, Usage: &agentpb.Usage{}}
+	var output, pending strings.Builder
+	var failed string
+	// flush sends the text gathered so far; text is batched while more
+	// events are already queued.
+	flush := func() error {
+		if pending.Len() == 0 {
+			return nil
+		}
+		text := pending.String()
+		pending.Reset()
+		return send(&agentpb.RunEvent{Type: agentpb.RunEvent_TEXT, Text: text})
+	}
+
+	for {
+		var ev api.StreamEvent
+		var ok bool
+		select {
+		case ev, ok = <-events:
+		default:
+			if err := flush(); err != nil {
+				return err
+			}
+			ev, ok = <-events
+		}
+		if !ok {
+			break
+		}
+
+		var out *agentpb.RunEvent
+		switch ev.Type {
+		case api.EventContentBlockDelta:
+			if ev.Delta != nil && ev.Delta.Type == "text_delta" {
+				pending.WriteString(ev.Delta.Text)
+				output.WriteString(ev.Delta.Text)
+			}
+		case api.EventMessageDelta:
+			if ev.Delta != nil && ev.Delta.StopReason != "" {
+				result.StopReason = ev.Delta.StopReason
+			}
+			if ev.Usage != nil {
+				result.Usage.InputTokens += int64(ev.Usage.InputTokens)
+				result.Usage.OutputTokens += int64(ev.Usage.OutputTokens)
+			}
+		case api.EventToolExecutionStart:
+			out = &agentpb.RunEvent{Type: agentpb.RunEvent_TOOL_START, ToolName: ev.Name}
+		case api.EventToolExecutionOutput:
+			out = &agentpb.RunEvent{Type: agentpb.RunEvent_TOOL_OUTPUT, ToolName: ev.Name, Text: fmt.Sprint(ev.Output)}
+		case api.EventToolExecutionResult:
+			out = &agentpb.RunEvent{Type: agentpb.RunEvent_TOOL_RESULT, ToolName: ev.Name, Text: fmt.Sprint(ev.Output), IsError: ev.IsError != nil && *ev.IsError}
+		case api.EventError:
+			failed = fmt.Sprint(ev.Output)
+		}
+		if out != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+			if err := send(out); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if failed != "" {
+		log.Printf("[server] grpc chat error (session %s): %s", sessionID, failed)
+		return send(&agentpb.RunEvent{Type: agentpb.RunEvent_ERROR, Text: failed})
+	}
+	result.Output = output.String()
+	return send(&agentpb.RunEvent{Type: agentpb.RunEvent_DONE, Result: result})
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/stellarlinkco/myclaw/internal/server/agentpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// startGRPC serves s on a local port and returns a client for it.
+func startGRPC(t *testing.T, s *Server) agentpb.AgentClient {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.ServeGRPC(ctx, ln) }()
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("ServeGRPC error: %v", err)
+		}
+	})
+	return agentpb.NewAgentClient(conn)
+}
+
+func withToken(t *testing.T, token string) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestGRPC_Run(t *testing.T) {
+	rt := &mockRuntime{output: "pong"}
+	c := startGRPC(t, newTestServer(t, rt))
+
+	resp, err := c.Run(withToken(t, "secret"), &agentpb.RunRequest{Prompt: "ping"})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if resp.GetOutput() != "pong" || resp.GetSessionId() != DefaultGRPCSessionID {
+		t.Errorf("response = %v", resp)
+	}
+	if len(rt.requests) != 1 || rt.requests[0].SessionID != DefaultGRPCSessionID {
+		t.Errorf("requests = %+v", rt.requests)
+	}
+}
+
+func TestGRPC_Errors(t *testing.T) {
+	c := startGRPC(t, newTestServer(t, &mockRuntime{}))
+
+	if _, err := c.Run(withToken(t, "wrong"), &agentpb.RunRequest{Prompt: "ping"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("bad token error = %v", err)
+	}
+	_, err := c.Run(withToken(t, "secret"), &agentpb.RunRequest{})
+	if st, _ := status.FromError(err); st.Code() != codes.InvalidArgument || st.Message() != "prompt is required" {
+		t.Errorf("empty prompt error = %v", err)
+	}
+
+	stream, err := c.Chat(withToken(t, "wrong"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("bad token stream error = %v", err)
+	}
+}
+
+func TestGRPC_ChatStream(t *testing.T) {
+	rt := &streamRuntime{events: []api.StreamEvent{
+		textDelta("Hel"),
+		textDelta("lo"),
+		{Type: api.EventToolExecutionStart, Name: "bash"},
+		{Type: api.EventToolExecutionResult, Name: "bash", Output: "ok"},
+		textDelta("!"),
+		{Type: api.EventMessageDelta, Delta: &api.Delta{StopReason: "end_turn"}, Usage: &api.Usage{InputTokens: 5, OutputTokens: 2}},
+	}}
+	s := newTestServer(t, &rt.mockRuntime)
+	s.runtime = rt
+	c := startGRPC(t, s)
+
+	stream, err := c.Chat(withToken(t, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text string
+	var types []agentpb.RunEvent_Type
+	var done []*agentpb.RunResponse
+	// The second request is sent only after the first has been answered,
+	// so the stream is exercised in both directions.
+	for _, prompt := range []string{"one", "two"} {
+		if err := stream.Send(&agentpb.RunRequest{Prompt: prompt, SessionId: "chat"}); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			ev, err := stream.Recv()
+			if err != nil {
+				t.Fatalf("Recv error: %v", err)
+			}
+			if ev.GetSessionId() != "chat" {
+				t.Errorf("event session = %q", ev.GetSessionId())
+			}
+			if ev.GetType() != agentpb.RunEvent_TEXT {
+				types = append(types, ev.GetType())
+			}
+			switch ev.GetType() {
+			case agentpb.RunEvent_TEXT:
+				text += ev.GetText()
+			case agentpb.RunEvent_TOOL_RESULT:
+				if ev.GetToolName() != "bash" || ev.GetText() != "ok" {
+					t.Errorf("tool result = %v", ev)
+				}
+			case agentpb.RunEvent_DONE:
+				done = append(done, ev.GetResult())
+			}
+			if ev.GetType() == agentpb.RunEvent_DONE || ev.GetType() == agentpb.RunEvent_ERROR {
+				break
+			}
+		}
+	}
+	stream.CloseSend()
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("stream end = %v, want EOF", err)
+	}
+
+	want := []agentpb.RunEvent_Type{
+		agentpb.RunEvent_TOOL_START, agentpb.RunEvent_TOOL_RESULT, agentpb.RunEvent_DONE,
+		agentpb.RunEvent_TOOL_START, agentpb.RunEvent_TOOL_RESULT, agentpb.RunEvent_DONE,
+	}
+	if len(types) != len(want) {
+		t.Fatalf("event types = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("event types = %v, want %v", types, want)
+		}
+	}
+	if text != "Hello!Hello!" {
+		t.Errorf("text = %q", text)
+	}
+	if r := done[0]; r.GetOutput() != "Hello!" || r.GetStopReason() != "end_turn" || r.GetUsage().GetInputTokens() != 5 || r.GetUsage().GetOutputTokens() != 2 {
+		t.Errorf("result = %v", r)
+	}
+}
+
+func TestGRPC_ChatError(t *testing.T) {
+	rt := &streamRuntime{events: []api.StreamEvent{textDelta("par"), {Type: api.EventError, Output: "boom"}}}
+	s := newTestServer(t, &rt.mockRuntime)
+	s.runtime = rt
+	c := startGRPC(t, s)
+
+	stream, err := c.Chat(withToken(t, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&agentpb.RunRequest{Prompt: "hi"})
+	stream.CloseSend()
+	var last *agentpb.RunEvent
+	for {
+		ev, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv error: %v", err)
+		}
+		last = ev
+	}
+	if last.GetType() != agentpb.RunEvent_ERROR || last.GetText() != "boom" {
+		t.Errorf("last event = %v", last)
+	}
+}
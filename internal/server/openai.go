@@ -0,0 +1,335 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+// SessionHeader names a myclaw session for OpenAI-style requests. With it
+// (or the request's "user" field) myclaw keeps the history itself and only
+// the last user message is sent to the agent. Without it every request is
+// self-contained: the whole message list becomes the prompt and the
+// temporary session is deleted afterwards.
+const SessionHeader = "X-Myclaw-Session"
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	User     string        `json:"user,omitempty"`
+}
+
+type chatContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+}
+
+// text returns the message's text and any inline (data URL) images.
+func (m chatMessage) text() (string, []model.ContentBlock) {
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s, nil
+	}
+	var parts []chatContentPart
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return "", nil
+	}
+	var texts []string
+	var blocks []model.ContentBlock
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			texts = append(texts, p.Text)
+		case "image_url":
+			// Only inline images; the agent cannot fetch remote URLs here.
+			meta, data, ok := strings.Cut(strings.TrimPrefix(p.ImageURL.URL, "data:"), ",")
+			mediaType, isBase64 := strings.CutSuffix(meta, ";base64")
+			if ok && isBase64 && strings.HasPrefix(p.ImageURL.URL, "data:") {
+				blocks = append(blocks, model.ContentBlock{Type: model.ContentBlockImage, MediaType: mediaType, Data: data})
+			}
+		}
+	}
+	return strings.Join(texts, "\n"), blocks
+}
+
+// buildChatRequest turns an OpenAI message list into an agent request.
+// ephemeral reports whether the session should be removed afterwards.
+func buildChatRequest(r *http.Request, req chatCompletionRequest) (api.Request, bool, error) {
+	last := -1
+	for i, m := range req.Messages {
+		if m.Role == "user" {
+			last = i
+		}
+	}
+	if last < 0 {
+		return api.Request{}, false, fmt.Errorf("messages must include a user message")
+	}
+	prompt, blocks := req.Messages[last].text()
+
+	sessionID := r.Header.Get(SessionHeader)
+	if sessionID == "" && req.User != "" {
+		sessionID = req.User
+	}
+	if sessionID != "" {
+		return withBlocks(api.Request{Prompt: prompt, SessionID: "openai:" + sessionID}, blocks), false, nil
+	}
+
+	// Stateless: replay the client's conversation as a transcript.
+	if len(req.Messages) > 1 {
+		var sb strings.Builder
+		for i, m := range req.Messages {
+			text, _ := m.text()
+			switch {
+			case i == last:
+				continue
+			case m.Role == "system" || m.Role == "developer":
+				fmt.Fprintf(&sb, "Instructions: %s\n\n", text)
+			case text != "":
+				fmt.Fprintf(&sb, "%s: %s\n\n", roleLabel(m.Role), text)
+			}
+		}
+		if sb.Len() > 0 {
+			prompt = "Conversation so far:\n\n" + sb.String() + "User: " + prompt
+		}
+	}
+	return withBlocks(api.Request{Prompt: prompt, SessionID: "openai-" + randomID()}, blocks), true, nil
+}
+
+// withBlocks folds the prompt into the content blocks; the SDK ignores
+// Prompt when ContentBlocks are set.
+func withBlocks(req api.Request, blocks []model.ContentBlock) api.Request {
+	if len(blocks) == 0 {
+		return req
+	}
+	if strings.TrimSpace(req.Prompt) != "" {
+		blocks = append([]model.ContentBlock{{Type: model.ContentBlockText, Text: req.Prompt}}, blocks...)
+	}
+	req.Prompt = ""
+	req.ContentBlocks = blocks
+	return req
+}
+
+func roleLabel(role string) string {
+	switch role {
+	case "assistant":
+		return "Assistant"
+	case "tool":
+		return "Tool"
+	default:
+		return "User"
+	}
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data": []map[string]any{{
+			"id":       s.cfg.Agent.Model,
+			"object":   "model",
+			"created":  s.started.Unix(),
+			"owned_by": "myclaw",
+		}},
+	})
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	agentReq, ephemeral, err := buildChatRequest(r, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if ephemeral {
+		defer s.sessions.Delete(agentReq.SessionID)
+	}
+
+	completion := chatCompletion{
+		ID:      "chatcmpl-" + randomID(),
+		Created: time.Now().Unix(),
+		Model:   s.cfg.Agent.Model,
+	}
+	if req.Stream {
+		s.streamChat(w, r.Context(), agentReq, completion)
+		return
+	}
+
+	resp, err := s.run(r.Context(), agentReq)
+	if err != nil {
+		log.Printf("[server] chat completion error: %v", err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	var usage model.Usage
+	if resp != nil && resp.Result != nil {
+		usage = resp.Result.Usage
+	}
+	writeJSON(w, http.StatusOK, completion.response(resultOutput(resp), usage))
+}
+
+type chatCompletion struct {
+	ID      string
+	Created int64
+	Model   string
+}
+
+func (c chatCompletion) response(content string, usage model.Usage) map[string]any {
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.InputTokens + usage.OutputTokens
+	}
+	return map[string]any{
+		"id":      c.ID,
+		"object":  "chat.completion",
+		"created": c.Created,
+		"model":   c.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": content},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]int{
+			"prompt_tokens":     usage.InputTokens,
+			"completion_tokens": usage.OutputTokens,
+			"total_tokens":      total,
+		},
+	}
+}
+
+func (c chatCompletion) chunk(delta map[string]string, finish string) map[string]any {
+	var finishReason any
+	if finish != "" {
+		finishReason = finish
+	}
+	return map[string]any{
+		"id":      c.ID,
+		"object":  "chat.completion.chunk",
+		"created": c.Created,
+		"model":   c.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"delta":         delta,
+			"finish_reason": finishReason,
+		}},
+	}
+}
+
+// streamChat answers with server-sent events in the OpenAI chunk format.
+func (s *Server) streamChat(w http.ResponseWriter, ctx context.Context, req api.Request, c chatCompletion) {
+	defer s.lockSession(req.SessionID)()
+
+	events, err := s.startStream(ctx, req)
+	if err != nil {
+		log.Printf("[server] chat stream error: %v", err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(v any) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	send(c.chunk(map[string]string{"role": "assistant"}, ""))
+	for {
+		text, errMsg, open := nextText(events)
+		if text != "" {
+			send(c.chunk(map[string]string{"content": text}, ""))
+		}
+		if errMsg != "" {
+			send(map[string]any{"error": map[string]string{"message": errMsg}})
+		}
+		if !open {
+			break
+		}
+	}
+	send(c.chunk(map[string]string{}, "stop"))
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// startStream streams from the runtime, or fakes a one-event stream when the
+// runtime cannot stream.
+func (s *Server) startStream(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error) {
+	if st, ok := s.runtime.(StreamRuntime); ok {
+		return st.RunStream(ctx, req)
+	}
+	resp, err := s.runtime.Run(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan api.StreamEvent, 1)
+	ch <- api.StreamEvent{Type: api.EventContentBlockDelta, Delta: &api.Delta{Type: "text_delta", Text: resultOutput(resp)}}
+	close(ch)
+	return ch, nil
+}
+
+// nextText blocks for the next event, then drains whatever else is already
+// queued so the client gets text in batches rather than one rune per chunk.
+func nextText(events <-chan api.StreamEvent) (text, errMsg string, open bool) {
+	var sb strings.Builder
+	handle := func(ev api.StreamEvent) {
+		switch {
+		case ev.Type == api.EventContentBlockDelta && ev.Delta != nil && ev.Delta.Type == "text_delta":
+			sb.WriteString(ev.Delta.Text)
+		case ev.Type == api.EventError:
+			errMsg = fmt.Sprint(ev.Output)
+		}
+	}
+
+	ev, ok := <-events
+	if !ok {
+		return "", "", false
+	}
+	handle(ev)
+	for errMsg == "" {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return sb.String(), errMsg, false
+			}
+			handle(ev)
+		default:
+			return sb.String(), errMsg, true
+		}
+	}
+	return sb.String(), errMsg, true
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+type streamRuntime struct {
+	mockRuntime
+	events []api.StreamEvent
+}
+
+func (s *streamRuntime) RunStream(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+	ch := make(chan api.StreamEvent, len(s.events))
+	for _, ev := range s.events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
+func textDelta(s string) api.StreamEvent {
+	return api.StreamEvent{Type: api.EventContentBlockDelta, Delta: &api.Delta{Type: "text_delta", Text: s}}
+}
+
+// readSSE returns the data payloads of an event stream.
+func readSSE(t *testing.T, body string) []string {
+	t.Helper()
+	var data []string
+	sc := bufio.NewScanner(strings.NewReader(body))
+	for sc.Scan() {
+		if line, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+			data = append(data, line)
+		}
+	}
+	return data
+}
+
+func TestChatCompletions_Stateless(t *testing.T) {
+	rt := &mockRuntime{output: "Paris."}
+	s := newTestServer(t, rt)
+
+	body := `{"model":"gpt-4o","messages":[
+		{"role":"system","content":"Be brief."},
+		{"role":"user","content":"Capital of Italy?"},
+		{"role":"assistant","content":"Rome."},
+		{"role":"user","content":[{"type":"text","text":"And France?"}]}]}`
+	w := do(s, http.MethodPost, "/v1/chat/completions", "secret", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Object  string `json:"object"`
+		Choices []struct {
+			Message struct {
+				Role, Content string
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Object != "chat.completion" || resp.Choices[0].Message.Content != "Paris." || resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("response = %s", w.Body.String())
+	}
+
+	req := rt.requests[0]
+	for _, want := range []string{"Instructions: Be brief.", "User: Capital of Italy?", "Assistant: Rome.", "User: And France?"} {
+		if !strings.Contains(req.Prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, req.Prompt)
+		}
+	}
+	if !strings.HasPrefix(req.SessionID, "openai-") {
+		t.Errorf("session = %q, want a temporary session", req.SessionID)
+	}
+}
+
+// persistingRuntime writes a history file the way the SDK does.
+type persistingRuntime struct {
+	mockRuntime
+	dir string
+}
+
+func (p *persistingRuntime) Run(ctx context.Context, req api.Request) (*api.Response, error) {
+	os.WriteFile(filepath.Join(p.dir, req.SessionID+".json"), []byte(`{"session_id":"`+req.SessionID+`","messages":[]}`), 0644)
+	return p.mockRuntime.Run(ctx, req)
+}
+
+func TestChatCompletions_StatelessSessionRemoved(t *testing.T) {
+	s := newTestServer(t, &mockRuntime{})
+	histDir := filepath.Join(s.cfg.Agent.Workspace, ".claude", "history")
+	os.MkdirAll(histDir, 0755)
+	s.runtime = &persistingRuntime{mockRuntime: mockRuntime{output: "ok"}, dir: histDir}
+
+	do(s, http.MethodPost, "/v1/chat/completions", "secret", `{"messages":[{"role":"user","content":"hi"}]}`)
+	if entries, _ := os.ReadDir(histDir); len(entries) != 0 {
+		t.Errorf("temporary session not removed: %v", entries)
+	}
+	if len(s.locks) != 0 {
+		t.Errorf("session locks left behind: %v", s.locks)
+	}
+
+	do(s, http.MethodPost, "/v1/chat/completions", "secret", `{"user":"kept","messages":[{"role":"user","content":"hi"}]}`)
+	if entries, _ := os.ReadDir(histDir); len(entries) != 1 {
+		t.Errorf("named session should be kept: %v", entries)
+	}
+}
+
+func TestChatCompletions_NamedSession(t *testing.T) {
+	rt := &mockRuntime{output: "ok"}
+	s := newTestServer(t, rt)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(
+		`{"messages":[{"role":"user","content":"first"},{"role":"assistant","content":"x"},{"role":"user","content":"second"}]}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set(SessionHeader, "editor")
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	do(s, http.MethodPost, "/v1/chat/completions", "secret", `{"user":"alice","messages":[{"role":"user","content":"hey"}]}`)
+
+	if got := rt.requests[0]; got.SessionID != "openai:editor" || got.Prompt != "second" {
+		t.Errorf("header session request = %+v", got)
+	}
+	if got := rt.requests[1].SessionID; got != "openai:alice" {
+		t.Errorf("user session = %q", got)
+	}
+}
+
+func TestChatCompletions_Image(t *testing.T) {
+	rt := &mockRuntime{output: "a cat"}
+	s := newTestServer(t, rt)
+	do(s, http.MethodPost, "/v1/chat/completions", "secret", `{"messages":[{"role":"user","content":[
+		{"type":"text","text":"What is this?"},
+		{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}},
+		{"type":"image_url","image_url":{"url":"https://example.com/remote.png"}}]}]}`)
+
+	req := rt.requests[0]
+	if req.Prompt != "" || len(req.ContentBlocks) != 2 {
+		t.Fatalf("request = %+v", req)
+	}
+	if req.ContentBlocks[0].Text != "What is this?" || req.ContentBlocks[1].Type != model.ContentBlockImage || req.ContentBlocks[1].MediaType != "image/png" {
+		t.Errorf("blocks = %+v", req.ContentBlocks)
+	}
+}
+
+func TestChatCompletions_Errors(t *testing.T) {
+	s := newTestServer(t, &mockRuntime{})
+	if w := do(s, http.MethodPost, "/v1/chat/completions", "secret", `{"messages":[{"role":"system","content":"x"}]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("no user message status = %d", w.Code)
+	}
+	if w := do(s, http.MethodPost, "/v1/chat/completions", "", `{}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d", w.Code)
+	}
+}
+
+func TestChatCompletions_Stream(t *testing.T) {
+	rt := &streamRuntime{events: []api.StreamEvent{
+		{Type: api.EventMessageStart},
+		textDelta("Hel"),
+		textDelta("lo"),
+		{Type: api.EventToolExecutionStart, Name: "bash"},
+		textDelta("!"),
+		{Type: api.EventMessageStop},
+	}}
+	s := newTestServer(t, &rt.mockRuntime)
+	s.runtime = rt
+
+	w := do(s, http.MethodPost, "/v1/chat/completions", "secret", `{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("content type = %q", ct)
+	}
+	data := readSSE(t, w.Body.String())
+	if len(data) < 3 || data[len(data)-1] != "[DONE]" {
+		t.Fatalf("stream = %q", data)
+	}
+
+	var content strings.Builder
+	var role, finish string
+	for _, d := range data[:len(data)-1] {
+		var chunk struct {
+			Object  string `json:"object"`
+			Choices []struct {
+				Delta struct {
+					Role, Content string
+				} `json:"delta"`
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(d), &chunk); err != nil {
+			t.Fatalf("bad chunk %q: %v", d, err)
+		}
+		if chunk.Object != "chat.completion.chunk" {
+			t.Errorf("object = %q", chunk.Object)
+		}
+		c := chunk.Choices[0]
+		if c.Delta.Role != "" {
+			role = c.Delta.Role
+		}
+		content.WriteString(c.Delta.Content)
+		if c.FinishReason != nil {
+			finish = *c.FinishReason
+		}
+	}
+	if role != "assistant" || content.String() != "Hello!" || finish != "stop" {
+		t.Errorf("role = %q, content = %q, finish = %q", role, content.String(), finish)
+	}
+}
+
+func TestChatCompletions_StreamFallbackAndError(t *testing.T) {
+	s := newTestServer(t, &mockRuntime{output: "whole answer"})
+	w := do(s, http.MethodPost, "/v1/chat/completions", "secret", `{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if !strings.Contains(w.Body.String(), `"content":"whole answer"`) {
+		t.Errorf("fallback stream = %s", w.Body.String())
+	}
+
+	rt := &streamRuntime{events: []api.StreamEvent{textDelta("par"), {Type: api.EventError, Output: "boom"}}}
+	s.runtime = rt
+	w = do(s, http.MethodPost, "/v1/chat/completions", "secret", `{"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if !strings.Contains(w.Body.String(), `"error":{"message":"boom"}`) || !strings.HasSuffix(w.Body.String(), "data: [DONE]\n\n") {
+		t.Errorf("error stream = %s", w.Body.String())
+	}
+}
+
+func TestModels(t *testing.T) {
+	s := newTestServer(t, &mockRuntime{})
+	w := do(s, http.MethodGet, "/v1/models", "secret", "")
+	if !strings.Contains(w.Body.String(), s.cfg.Agent.Model) {
+		t.Errorf("models = %s", w.Body.String())
+	}
+}
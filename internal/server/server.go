@@ -0,0 +1,261 @@
+// Package server implements the local HTTP API started by `myclaw serve`.
+//
+// Every request must carry `Authorization: Bearer <token>`. Endpoints:
+//
+//	POST /v1/messages       run the agent: {"message": "...", "session_id": "..."}
+//	GET  /v1/sessions       list saved sessions
+//	GET  /v1/sessions/{id}  one session's messages and tool calls
+//	GET  /v1/status         model, provider and workspace information
+//
+// It also speaks enough of the OpenAI API (see openai.go) for existing
+// clients to use myclaw as a backend, and can serve the gRPC service in
+// agentpb/agent.proto (see grpc.go) and a chat page for browsers (see ui.go).
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"github.com/stellarlinkco/myclaw/internal/media"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+const (
+	// DefaultSessionID is used when a request does not name a session.
+	DefaultSessionID = "api"
+
+	shutdownTimeout = 10 * time.Second
+	maxBodyBytes    = 4 << 20
+)
+
+// Runtime interface for agent runtime (allows mocking in tests)
+type Runtime interface {
+	Run(ctx context.Context, req api.Request) (*api.Response, error)
+	Close()
+}
+
+// StreamRuntime is implemented by runtimes that can stream events. Without
+// it, streaming requests get the whole answer in a single chunk.
+type StreamRuntime interface {
+	RunStream(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error)
+}
+
+// Server serves the HTTP API for one runtime.
+type Server struct {
+	cfg      *config.Config
+	runtime  Runtime
+	token    string
+	sessions *session.Store
+	started  time.Time
+	mux      *http.ServeMux
+
+	ui    bool             // serve the chat page; see EnableUI
+	media *media.Processor // reads chat page uploads; nil until EnableUI
+
+	mu    sync.Mutex
+	locks map[string]*runLock // session id -> run lock, while in use
+}
+
+// runLock is the lock of one session and the number of runs holding or
+// waiting for it; it leaves Server.locks when that drops to zero.
+type runLock struct {
+	sync.Mutex
+	refs int
+}
+
+// New creates a server. token must not be empty.
+func New(cfg *config.Config, rt Runtime, token string) (*Server, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, fmt.Errorf("server token is required")
+	}
+	s := &Server{
+		cfg:      cfg,
+		runtime:  rt,
+		token:    token,
+		sessions: session.NewStore(cfg.Agent.Workspace),
+		started:  time.Now(),
+		mux:      http.NewServeMux(),
+		locks:    make(map[string]*runLock),
+	}
+	s.mux.HandleFunc("POST /v1/messages", s.handleMessages)
+	s.mux.HandleFunc("GET /v1/sessions", s.handleSessions)
+	s.mux.HandleFunc("GET /v1/sessions/{id}", s.handleSession)
+	s.mux.HandleFunc("GET /v1/status", s.handleStatus)
+	s.mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	s.mux.HandleFunc("GET /v1/models", s.handleModels)
+	return s, nil
+}
+
+// Handler returns the authenticated API handler.
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.ui && r.Method == http.MethodGet && r.URL.Path == "/" {
+			s.servePage(w, r)
+			return
+		}
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="myclaw"`)
+			writeError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+		s.mux.ServeHTTP(w, r)
+	})
+}
+
+// Serve accepts connections on ln until ctx is cancelled, then lets
+// in-flight requests finish before returning.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	return serveHTTP(ctx, ln, &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	})
+}
+
+func serveHTTP(ctx context.Context, ln net.Listener, srv *http.Server) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("[server] shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	return s.validAuthorization(r.Header.Get("Authorization"))
+}
+
+// validAuthorization reports whether an Authorization value carries the
+// server token.
+func (s *Server) validAuthorization(value string) bool {
+	token, ok := strings.CutPrefix(value, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// lockSession serializes runs within one session; different sessions run
+// concurrently. It blocks until the session is free and returns the
+// function that frees it again.
+func (s *Server) lockSession(id string) (unlock func()) {
+	s.mu.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &runLock{}
+		s.locks[id] = l
+	}
+	l.refs++
+	s.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if l.refs--; l.refs == 0 {
+			delete(s.locks, id)
+		}
+	}
+}
+
+type messageRequest struct {
+	Message   string `json:"message"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+type messageResponse struct {
+	SessionID string `json:"session_id"`
+	Output    string `json:"output"`
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	var req messageRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		writeError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+	if req.SessionID == "" {
+		req.SessionID = DefaultSessionID
+	}
+
+	resp, err := s.run(r.Context(), api.Request{Prompt: req.Message, SessionID: req.SessionID})
+	if err != nil {
+		log.Printf("[server] run error (session %s): %v", req.SessionID, err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{SessionID: req.SessionID, Output: resultOutput(resp)})
+}
+
+func (s *Server) run(ctx context.Context, req api.Request) (*api.Response, error) {
+	defer s.lockSession(req.SessionID)()
+	return s.runtime.Run(ctx, req)
+}
+
+func resultOutput(resp *api.Response) string {
+	if resp == nil || resp.Result == nil {
+		return ""
+	}
+	return resp.Result.Output
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	summaries, err := s.sessions.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if summaries == nil {
+		summaries = []session.Summary{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"sessions": summaries})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	provider := s.cfg.Provider.Type
+	if provider == "" {
+		provider = "anthropic"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":            true,
+		"model":         s.cfg.Agent.Model,
+		"provider":      provider,
+		"workspace":     s.cfg.Agent.Workspace,
+		"uptimeSeconds": int(time.Since(s.started).Seconds()),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]any{"error": map[string]string{"message": msg}})
+}
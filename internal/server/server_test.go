@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+type mockRuntime struct {
+	mu       sync.Mutex
+	requests []api.Request
+	output   string
+	err      error
+	block    chan struct{}
+}
+
+func (m *mockRuntime) Run(ctx context.Context, req api.Request) (*api.Response, error) {
+	if m.block != nil {
+		<-m.block
+	}
+	m.mu.Lock()
+	m.requests = append(m.requests, req)
+	m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &api.Response{Result: &api.Result{Output: m.output}}, nil
+}
+
+func (m *mockRuntime) Close() {}
+
+func newTestServer(t *testing.T, rt *mockRuntime) *Server {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Agent.Workspace = t.TempDir()
+	s, err := New(cfg, rt, "secret")
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	return s
+}
+
+func do(s *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestNew_RequiresToken(t *testing.T) {
+	if _, err := New(config.DefaultConfig(), &mockRuntime{}, " "); err == nil {
+		t.Error("expected error for empty token")
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	s := newTestServer(t, &mockRuntime{})
+	for _, token := range []string{"", "wrong"} {
+		if w := do(s, http.MethodGet, "/v1/status", token, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, want 401", token, w.Code)
+		}
+	}
+	if w := do(s, http.MethodGet, "/v1/status", "secret", ""); w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
+
+func TestServer_Messages(t *testing.T) {
+	rt := &mockRuntime{output: "hi there"}
+	s := newTestServer(t, rt)
+
+	w := do(s, http.MethodPost, "/v1/messages", "secret", `{"message":"hello","session_id":"script"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp messageResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Output != "hi there" || resp.SessionID != "script" {
+		t.Errorf("response = %+v", resp)
+	}
+	if rt.requests[0].Prompt != "hello" || rt.requests[0].SessionID != "script" {
+		t.Errorf("request = %+v", rt.requests[0])
+	}
+
+	do(s, http.MethodPost, "/v1/messages", "secret", `{"message":"again"}`)
+	if rt.requests[1].SessionID != DefaultSessionID {
+		t.Errorf("default session = %q", rt.requests[1].SessionID)
+	}
+}
+
+func TestServer_MessagesErrors(t *testing.T) {
+	s := newTestServer(t, &mockRuntime{err: errors.New("model down")})
+	if w := do(s, http.MethodPost, "/v1/messages", "secret", `not json`); w.Code != http.StatusBadRequest {
+		t.Errorf("bad json status = %d", w.Code)
+	}
+	if w := do(s, http.MethodPost, "/v1/messages", "secret", `{"message":"  "}`); w.Code != http.StatusBadRequest {
+		t.Errorf("empty message status = %d", w.Code)
+	}
+	w := do(s, http.MethodPost, "/v1/messages", "secret", `{"message":"x"}`)
+	if w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "model down") {
+		t.Errorf("runtime error response = %d %s", w.Code, w.Body.String())
+	}
+	if w := do(s, http.MethodGet, "/v1/messages", "secret", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /v1/messages status = %d", w.Code)
+	}
+}
+
+func TestServer_SessionsAndStatus(t *testing.T) {
+	s := newTestServer(t, &mockRuntime{})
+	histDir := filepath.Join(s.cfg.Agent.Workspace, ".claude", "history")
+	os.MkdirAll(histDir, 0755)
+	os.WriteFile(filepath.Join(histDir, "api.json"), []byte(`{"version":1,"session_id":"api","updated_at":"2024-01-01T00:00:00Z","messages":[{"Role":"user","Content":"hello"}]}`), 0644)
+
+	w := do(s, http.MethodGet, "/v1/sessions", "secret", "")
+	var sessions struct {
+		Sessions []struct {
+			ID string `json:"id"`
+		} `json:"sessions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &sessions)
+	if len(sessions.Sessions) != 1 || sessions.Sessions[0].ID != "api" {
+		t.Errorf("sessions = %s", w.Body.String())
+	}
+
+	w = do(s, http.MethodGet, "/v1/status", "secret", "")
+	var status map[string]any
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if status["model"] != config.DefaultModel || status["provider"] != "anthropic" {
+		t.Errorf("status = %v", status)
+	}
+}
+
+func TestServer_ServeGracefulShutdown(t *testing.T) {
+	rt := &mockRuntime{output: "done", block: make(chan struct{})}
+	s := newTestServer(t, rt)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx, ln) }()
+
+	result := make(chan int, 1)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+ln.Addr().String()+"/v1/messages", strings.NewReader(`{"message":"slow"}`))
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			result <- 0
+			return
+		}
+		resp.Body.Close()
+		result <- resp.StatusCode
+	}()
+
+	// Wait for the request to reach the runtime, then shut down mid-flight.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		n := len(s.locks)
+		s.mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	close(rt.block)
+
+	if code := <-result; code != http.StatusOK {
+		t.Errorf("in-flight request status = %d, want 200", code)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve error: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/stellarlinkco/myclaw/internal/bus"
+	"github.com/stellarlinkco/myclaw/internal/media"
+	"github.com/stellarlinkco/myclaw/internal/provider"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+//go:embed static/chat.html
+var chatPage []byte
+
+const (
+	// UISessionPrefix starts the IDs of sessions held in the chat UI, so
+	// that it neither lists nor continues conversations from elsewhere.
+	UISessionPrefix = "web:"
+
+	maxUploadBytes = 20 << 20
+)
+
+// EnableUI serves the chat page at / and the endpoints it uses. The page
+// itself needs no token; it asks for one and sends it with each request.
+func (s *Server) EnableUI() error {
+	stt, err := media.NewTranscriber(s.cfg.Media.STT)
+	if err != nil {
+		return err
+	}
+	s.media = &media.Processor{
+		STT:              stt,
+		PDF:              s.cfg.Provider.Type != provider.TypeOpenAI && s.cfg.Provider.Type != provider.TypeGemini,
+		MaxDocumentChars: s.cfg.Media.MaxDocumentChars,
+	}
+	s.ui = true
+	s.mux.HandleFunc("POST /ui/chat", s.handleUIChat)
+	return nil
+}
+
+func (s *Server) servePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	_, _ = w.Write(chatPage)
+}
+
+// handleSession answers GET /v1/sessions/{id} with the session's messages
+// and tool calls.
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := s.sessions.Get(r.PathValue("id"))
+	if errors.Is(err, session.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no such session")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	turns := sess.Turns()
+	if turns == nil {
+		turns = []session.Turn{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": sess.ID, "updatedAt": sess.UpdatedAt, "turns": turns})
+}
+
+// uiEvent is one server-sent event of a chat UI answer.
+type uiEvent struct {
+	Text      string `json:"text,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Done      bool   `json:"done,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// handleUIChat runs a message from the chat page, sent as a multipart form
+// with "message", "session_id" and any number of "file" parts, and streams
+// the answer as server-sent events.
+func (s *Server) handleUIChat(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	if err := r.ParseMultipartForm(maxUploadBytes); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("read form: %v", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	var atts []bus.Attachment
+	for _, fh := range r.MultipartForm.File["file"] {
+		f, err := fh.Open()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		atts = append(atts, bus.Attachment{Name: fh.Filename, MediaType: fh.Header.Get("Content-Type"), Data: data})
+	}
+	message := strings.TrimSpace(r.FormValue("message"))
+	if message == "" && len(atts) == 0 {
+		writeError(w, http.StatusBadRequest, "message or file is required")
+		return
+	}
+	sessionID := strings.TrimSpace(r.FormValue("session_id"))
+	if !strings.HasPrefix(sessionID, UISessionPrefix) {
+		sessionID = UISessionPrefix + sessionID
+	}
+	if sessionID == UISessionPrefix {
+		sessionID += "default"
+	}
+
+	notes, blocks := s.media.Process(r.Context(), atts)
+	prompt := strings.TrimSpace(strings.Join(append([]string{message}, notes...), "\n\n"))
+	req := withBlocks(api.Request{Prompt: prompt, SessionID: sessionID}, blocks)
+
+	defer s.lockSession(sessionID)()
+	events, err := s.startStream(r.Context(), req)
+	if err != nil {
+		log.Printf("[server] chat ui error (session %s): %v", sessionID, err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	send := func(ev uiEvent) {
+		data, _ := json.Marshal(ev)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	for {
+		text, errMsg, open := nextText(events)
+		if text != "" {
+			send(uiEvent{Text: text})
+		}
+		if errMsg != "" {
+			send(uiEvent{Error: errMsg})
+		}
+		if !open {
+			break
+		}
+	}
+	send(uiEvent{Done: true, SessionID: sessionID})
+}
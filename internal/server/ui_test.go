@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func TestServer_UIPage(t *testing.T) {
+	s := newTestServer(t, &mockRuntime{})
+	if w := do(s, http.MethodGet, "/", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("page without --ui = %d", w.Code)
+	}
+	if err := s.EnableUI(); err != nil {
+		t.Fatal(err)
+	}
+	w := do(s, http.MethodGet, "/", "", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/ui/chat") {
+		t.Errorf("page = %d", w.Code)
+	}
+	if w := do(s, http.MethodGet, "/v1/sessions", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("API without a token = %d", w.Code)
+	}
+}
+
+func TestServer_UIChat(t *testing.T) {
+	rt := &mockRuntime{output: "Looks like a receipt."}
+	s := newTestServer(t, rt)
+	if err := s.EnableUI(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("message", "What is this?")
+	mw.WriteField("session_id", "kitchen")
+	part, _ := mw.CreateFormFile("file", "notes.txt")
+	part.Write([]byte("milk, eggs"))
+	part, _ = mw.CreateFormFile("file", "photo.png")
+	part.Write([]byte("\x89PNG\r\n\x1a\n"))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/ui/chat", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+
+	out := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(out, `data: {"text":"Looks like a receipt."}`) || !strings.Contains(out, `"done":true,"session_id":"web:kitchen"`) {
+		t.Fatalf("chat = %d %s", w.Code, out)
+	}
+	got := rt.requests[0]
+	if got.SessionID != "web:kitchen" || len(got.ContentBlocks) != 2 {
+		t.Fatalf("request = %+v", got)
+	}
+	if text := got.ContentBlocks[0].Text; !strings.HasPrefix(text, "What is this?") || !strings.Contains(text, "Document notes.txt:") {
+		t.Errorf("prompt = %q", text)
+	}
+	if got.ContentBlocks[1].Type != model.ContentBlockImage {
+		t.Errorf("image block = %+v", got.ContentBlocks[1])
+	}
+
+	if w := do(s, http.MethodPost, "/ui/chat", "secret", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("empty form = %d", w.Code)
+	}
+}
+
+func TestServer_Session(t *testing.T) {
+	s := newTestServer(t, &mockRuntime{})
+	histDir := filepath.Join(s.cfg.Agent.Workspace, ".claude", "history")
+	os.MkdirAll(histDir, 0755)
+	os.WriteFile(filepath.Join(histDir, "web-kitchen.json"), []byte(`{"version":1,"session_id":"web:kitchen","updated_at":"2024-01-01T00:00:00Z","messages":[{"Role":"user","Content":"hello"},{"Role":"assistant","Content":"hi"}]}`), 0644)
+
+	w := do(s, http.MethodGet, "/v1/sessions/web:kitchen", "secret", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"turns":[{"role":"user","content":"hello"},{"role":"assistant","content":"hi"}]`) {
+		t.Errorf("session = %d %s", w.Code, w.Body.String())
+	}
+	if w := do(s, http.MethodGet, "/v1/sessions/nope", "secret", ""); w.Code != http.StatusNotFound {
+		t.Errorf("missing session = %d", w.Code)
+	}
+}
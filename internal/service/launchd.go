@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// labelPrefix makes launchd labels reverse-DNS names, as launchd expects.
+const labelPrefix = "com.stellarlinkco."
+
+// launchd installs per-user agents, which start at login.
+type launchd struct {
+	home string
+	run  runner
+}
+
+func (l launchd) Path(name string) string {
+	return filepath.Join(l.home, "Library", "LaunchAgents", labelPrefix+name+".plist")
+}
+
+func (l launchd) Install(u Unit) error {
+	path := l.Path(u.Name)
+	// Unload a previous install first, or load fails on the running job.
+	if exists(path) {
+		_, _ = l.run("launchctl", "unload", path)
+	}
+	if err := writeUnit(path, []byte(LaunchdPlist(u)), u.LogDir); err != nil {
+		return err
+	}
+	_, err := l.run("launchctl", "load", "-w", path)
+	return err
+}
+
+func (l launchd) Uninstall(name string) error {
+	path := l.Path(name)
+	if !exists(path) {
+		return fmt.Errorf("%s is not installed", name)
+	}
+	_, _ = l.run("launchctl", "unload", "-w", path)
+	return os.Remove(path)
+}
+
+func (l launchd) Status(name string) (Status, error) {
+	st := Status{Path: l.Path(name), Installed: exists(l.Path(name))}
+	if !st.Installed {
+		return st, nil
+	}
+	out, err := l.run("launchctl", "list", labelPrefix+name)
+	if err != nil {
+		st.Detail = "not loaded"
+		return st, nil
+	}
+	// A loaded job has a "PID" entry only while it runs.
+	st.Running = strings.Contains(out, `"PID" =`)
+	st.Detail = "loaded"
+	if st.Running {
+		st.Detail = "running"
+	}
+	return st, nil
+}
+
+// LaunchdPlist returns the launch agent for u. launchd restarts the
+// gateway when it exits with an error, at most every five seconds.
+func LaunchdPlist(u Unit) string {
+	stdout, stderr := u.LogFiles()
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	plistKey(&b, "Label", labelPrefix+u.Name)
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, a := range append([]string{u.Executable}, u.Args...) {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", xmlEscape(a))
+	}
+	b.WriteString("\t</array>\n")
+	if len(u.Env) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, env := range u.Env {
+			k, v, _ := strings.Cut(env, "=")
+			fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", xmlEscape(k), xmlEscape(v))
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	b.WriteString("\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	b.WriteString("\t<key>ThrottleInterval</key>\n\t<integer>5</integer>\n")
+	plistKey(&b, "StandardOutPath", stdout)
+	plistKey(&b, "StandardErrorPath", stderr)
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+func plistKey(b *strings.Builder, key, value string) {
+	fmt.Fprintf(b, "\t<key>%s</key>\n\t<string>%s</string>\n", key, xmlEscape(value))
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+func xmlEscape(s string) string {
+	return xmlEscaper.Replace(s)
+}
@@ -0,0 +1,123 @@
+// Package service runs the gateway in the background under the system's
+// service manager: a systemd user unit on Linux or a launchd agent on
+// macOS, started at login and restarted when it fails.
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Unit is the gateway service to install.
+type Unit struct {
+	Name       string   // myclaw, or myclaw-<profile>
+	Executable string   // absolute path of the myclaw binary
+	Args       []string // such as ["gateway"]
+	LogDir     string   // where gateway.log and gateway.err.log go
+	Env        []string // KEY=value pairs set for the service, such as PATH
+}
+
+// Status is what the service manager knows about a unit.
+type Status struct {
+	Installed bool   `json:"installed"`
+	Running   bool   `json:"running"`
+	Path      string `json:"path"`
+	Detail    string `json:"detail,omitempty"` // the state the manager reports
+}
+
+// Manager installs, removes and reports on units.
+type Manager interface {
+	// Path returns the file the unit called name is written to.
+	Path(name string) string
+	// Install writes the unit, replacing one already there, and starts it.
+	Install(u Unit) error
+	// Uninstall stops the unit and removes its file.
+	Uninstall(name string) error
+	Status(name string) (Status, error)
+}
+
+// ErrUnsupported is returned on systems without a supported manager.
+var ErrUnsupported = errors.New("services are supported on Linux (systemd) and macOS (launchd)")
+
+// System returns the service manager of the running system.
+func System() (Manager, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	switch runtime.GOOS {
+	case "linux":
+		return systemd{home: home, run: runCommand}, nil
+	case "darwin":
+		return launchd{home: home, run: runCommand}, nil
+	}
+	return nil, ErrUnsupported
+}
+
+// Name returns the unit name for profile: myclaw, or myclaw-<profile>, so
+// each profile's gateway runs as its own service.
+func Name(profile string) string {
+	if profile == "" {
+		return "myclaw"
+	}
+	return "myclaw-" + profile
+}
+
+// LogFiles returns the files the service's output and errors go to.
+func (u Unit) LogFiles() (stdout, stderr string) {
+	return filepath.Join(u.LogDir, "gateway.log"), filepath.Join(u.LogDir, "gateway.err.log")
+}
+
+// runner runs a command and returns its combined output.
+type runner func(name string, args ...string) (string, error)
+
+func runCommand(name string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return out.String(), &commandError{name: name, code: exitErr.ExitCode(), output: strings.TrimSpace(out.String())}
+		}
+		return "", fmt.Errorf("run %s: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// commandError is a service tool that exited with an error.
+type commandError struct {
+	name   string
+	code   int
+	output string
+}
+
+func (e *commandError) Error() string {
+	if e.output == "" {
+		return fmt.Sprintf("%s exited with status %d", e.name, e.code)
+	}
+	return fmt.Sprintf("%s: %s", e.name, e.output)
+}
+
+// writeUnit writes data to path, creating its directory and the log
+// directory.
+func writeUnit(path string, data []byte, logDir string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		return fmt.Errorf("create log dir: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
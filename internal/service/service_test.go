@@ -0,0 +1,141 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRunner records commands and answers them from outputs.
+type fakeRunner struct {
+	calls   []string
+	outputs map[string]string
+}
+
+func (f *fakeRunner) run(name string, args ...string) (string, error) {
+	call := strings.Join(append([]string{name}, args...), " ")
+	f.calls = append(f.calls, call)
+	return f.outputs[call], nil
+}
+
+func testUnit(t *testing.T) Unit {
+	return Unit{
+		Name:       "myclaw-work",
+		Executable: "/opt/my claw/myclaw",
+		Args:       []string{"--profile", "work", "gateway"},
+		LogDir:     filepath.Join(t.TempDir(), "logs"),
+		Env:        []string{"PATH=/usr/bin:/bin"},
+	}
+}
+
+func TestName(t *testing.T) {
+	if got := Name(""); got != "myclaw" {
+		t.Errorf("Name(\"\") = %q", got)
+	}
+	if got := Name("work"); got != "myclaw-work" {
+		t.Errorf("Name(work) = %q", got)
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	u := testUnit(t)
+	unit := SystemdUnit(u)
+	for _, want := range []string{
+		`ExecStart="/opt/my claw/myclaw" --profile work gateway`,
+		"Environment=PATH=/usr/bin:/bin",
+		"Restart=on-failure",
+		"StandardOutput=append:" + filepath.Join(u.LogDir, "gateway.log"),
+		"WantedBy=default.target",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit missing %q:\n%s", want, unit)
+		}
+	}
+	if got := systemdCommand([]string{"echo", "$HOME", "50%"}); got != `echo $$HOME 50%%` {
+		t.Errorf("systemdCommand = %s", got)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	u := testUnit(t)
+	u.Args = append(u.Args, "a&b")
+	plist := LaunchdPlist(u)
+	for _, want := range []string{
+		"<string>com.stellarlinkco.myclaw-work</string>",
+		"<string>/opt/my claw/myclaw</string>",
+		"<string>a&amp;b</string>",
+		"<key>PATH</key>\n\t\t<string>/usr/bin:/bin</string>",
+		"<key>SuccessfulExit</key>\n\t\t<false/>",
+		"<string>" + filepath.Join(u.LogDir, "gateway.err.log") + "</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist missing %q:\n%s", want, plist)
+		}
+	}
+}
+
+func TestSystemd_InstallStatusUninstall(t *testing.T) {
+	f := &fakeRunner{outputs: map[string]string{"systemctl --user is-active myclaw-work.service": "active\n"}}
+	s := systemd{home: t.TempDir(), run: f.run}
+	u := testUnit(t)
+
+	if st, _ := s.Status(u.Name); st.Installed {
+		t.Errorf("installed before install: %+v", st)
+	}
+	if err := s.Install(u); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	data, err := os.ReadFile(s.Path(u.Name))
+	if err != nil || !strings.Contains(string(data), "ExecStart=") {
+		t.Fatalf("unit file: %v\n%s", err, data)
+	}
+	if _, err := os.Stat(u.LogDir); err != nil {
+		t.Errorf("log dir not created: %v", err)
+	}
+	if got := strings.Join(f.calls, "; "); got != "systemctl --user daemon-reload; systemctl --user enable myclaw-work.service; systemctl --user restart myclaw-work.service" {
+		t.Errorf("calls = %s", got)
+	}
+
+	if st, _ := s.Status(u.Name); !st.Installed || !st.Running {
+		t.Errorf("status = %+v", st)
+	}
+
+	if err := s.Uninstall(u.Name); err != nil {
+		t.Fatalf("Uninstall: %v", err)
+	}
+	if _, err := os.Stat(s.Path(u.Name)); !os.IsNotExist(err) {
+		t.Error("unit file not removed")
+	}
+	if err := s.Uninstall(u.Name); err == nil {
+		t.Error("expected error uninstalling twice")
+	}
+}
+
+func TestLaunchd_InstallStatus(t *testing.T) {
+	f := &fakeRunner{outputs: map[string]string{
+		"launchctl list com.stellarlinkco.myclaw-work": "{\n\t\"PID\" = 4242;\n\t\"Label\" = \"com.stellarlinkco.myclaw-work\";\n};\n",
+	}}
+	l := launchd{home: t.TempDir(), run: f.run}
+	u := testUnit(t)
+
+	if err := l.Install(u); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	path := l.Path(u.Name)
+	if got := strings.Join(f.calls, "; "); got != "launchctl load -w "+path {
+		t.Errorf("calls = %s", got)
+	}
+	// A reinstall unloads the running agent first.
+	f.calls = nil
+	if err := l.Install(u); err != nil {
+		t.Fatalf("reinstall: %v", err)
+	}
+	if len(f.calls) != 2 || f.calls[0] != "launchctl unload "+path {
+		t.Errorf("reinstall calls = %v", f.calls)
+	}
+
+	if st, _ := l.Status(u.Name); !st.Running || st.Detail != "running" {
+		t.Errorf("status = %+v", st)
+	}
+}
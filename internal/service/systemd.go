@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// systemd installs user units, which need no root and run while the user
+// is logged in, or always with `loginctl enable-linger`.
+type systemd struct {
+	home string
+	run  runner
+}
+
+func (s systemd) Path(name string) string {
+	return filepath.Join(s.home, ".config", "systemd", "user", name+".service")
+}
+
+func (s systemd) Install(u Unit) error {
+	if err := writeUnit(s.Path(u.Name), []byte(SystemdUnit(u)), u.LogDir); err != nil {
+		return err
+	}
+	if _, err := s.run("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	// restart rather than start, so a reinstall runs the new unit.
+	if _, err := s.run("systemctl", "--user", "enable", u.Name+".service"); err != nil {
+		return err
+	}
+	_, err := s.run("systemctl", "--user", "restart", u.Name+".service")
+	return err
+}
+
+func (s systemd) Uninstall(name string) error {
+	path := s.Path(name)
+	if !exists(path) {
+		return fmt.Errorf("%s is not installed", name)
+	}
+	// Fails when the unit is already stopped and disabled, which is fine.
+	_, _ = s.run("systemctl", "--user", "disable", "--now", name+".service")
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	_, err := s.run("systemctl", "--user", "daemon-reload")
+	return err
+}
+
+func (s systemd) Status(name string) (Status, error) {
+	st := Status{Path: s.Path(name), Installed: exists(s.Path(name))}
+	if !st.Installed {
+		return st, nil
+	}
+	// is-active exits non-zero for anything but active, with the state
+	// still on stdout.
+	out, _ := s.run("systemctl", "--user", "is-active", name+".service")
+	st.Detail = strings.TrimSpace(out)
+	st.Running = st.Detail == "active"
+	return st, nil
+}
+
+// SystemdUnit returns the unit file for u. The gateway restarts five
+// seconds after it fails, but not after a clean stop.
+func SystemdUnit(u Unit) string {
+	stdout, stderr := u.LogFiles()
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\n")
+	fmt.Fprintf(&b, "Description=myclaw gateway (%s)\n", u.Name)
+	fmt.Fprintf(&b, "After=network-online.target\n")
+	fmt.Fprintf(&b, "Wants=network-online.target\n\n")
+	fmt.Fprintf(&b, "[Service]\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", systemdCommand(append([]string{u.Executable}, u.Args...)))
+	for _, env := range u.Env {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(env))
+	}
+	fmt.Fprintf(&b, "Restart=on-failure\n")
+	fmt.Fprintf(&b, "RestartSec=5\n")
+	fmt.Fprintf(&b, "StandardOutput=append:%s\n", strings.ReplaceAll(stdout, "%", "%%"))
+	fmt.Fprintf(&b, "StandardError=append:%s\n\n", strings.ReplaceAll(stderr, "%", "%%"))
+	fmt.Fprintf(&b, "[Install]\n")
+	fmt.Fprintf(&b, "WantedBy=default.target\n")
+	return b.String()
+}
+
+// systemdCommand quotes args for ExecStart, where $ would expand a
+// variable.
+func systemdCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = systemdQuote(strings.ReplaceAll(a, "$", "$$"))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// systemdQuote escapes the % of specifiers in s and double-quotes it when
+// it has spaces or quotes.
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,117 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+// SummaryPrefix starts the message that stands in for a compacted part of
+// a conversation.
+const SummaryPrefix = "Summary of our earlier conversation:\n"
+
+// Compaction splits a session into the older messages a summary replaces
+// and the recent ones kept as they are.
+type Compaction struct {
+	Dropped []message.Message
+	Kept    []message.Message
+}
+
+// PlanCompaction keeps the last keep messages and drops the rest. The kept
+// part never starts with a tool result, which would lose the call it
+// answers, so it may keep a few more.
+func (s *Session) PlanCompaction(keep int) Compaction {
+	cut := max(len(s.Messages)-max(keep, 0), 0)
+	for cut > 0 && cut < len(s.Messages) && s.Messages[cut].Role == "tool" {
+		cut--
+	}
+	return Compaction{Dropped: s.Messages[:cut], Kept: s.Messages[cut:]}
+}
+
+// Transcript is the dropped part as plain text, for the model to summarize.
+func (c Compaction) Transcript() string {
+	dropped := &Session{Messages: c.Dropped}
+	var sb strings.Builder
+	for _, turn := range dropped.Turns() {
+		if turn.Content != "" {
+			fmt.Fprintf(&sb, "%s: %s\n\n", roleTitle(turn.Role), turn.Content)
+		}
+		for _, call := range turn.Calls {
+			fmt.Fprintf(&sb, "(%s called tool %s)\n\n", roleTitle(turn.Role), call.Name)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// Preview lists the change as a diff: "-" for each dropped message, "+" for
+// the summary that replaces them and a blank margin for the kept ones.
+func (c Compaction) Preview() string {
+	var sb strings.Builder
+	line := func(mark string, msg message.Message) {
+		text := preview(msg.Content, 72)
+		for _, call := range msg.ToolCalls {
+			if text != "" {
+				text += " "
+			}
+			text += "(tool " + call.Name + ")"
+		}
+		fmt.Fprintf(&sb, "%s [%s] %s\n", mark, msg.Role, text)
+	}
+	for _, msg := range c.Dropped {
+		line("-", msg)
+	}
+	fmt.Fprintf(&sb, "+ [system] summary of the %d messages above\n", len(c.Dropped))
+	for _, msg := range c.Kept {
+		line(" ", msg)
+	}
+	return sb.String()
+}
+
+// Apply returns the compacted messages: summary, as a system message like
+// the runtime's own compaction writes, followed by the kept ones.
+func (c Compaction) Apply(summary string) []message.Message {
+	msgs := []message.Message{{Role: "system", Content: SummaryPrefix + strings.TrimSpace(summary)}}
+	return append(msgs, message.CloneMessages(c.Kept)...)
+}
+
+// Save writes sess in the runtime's history format, replacing what was
+// saved for its id. The previous file, if any, is kept with a .bak suffix.
+// Like Delete, a running gateway keeps its in-memory copy until it
+// restarts.
+func (s *Store) Save(sess *Session) error {
+	if strings.TrimSpace(sess.ID) == "" {
+		return fmt.Errorf("session id is required")
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("create history dir: %w", err)
+	}
+	data, err := json.Marshal(persisted{
+		Version:   1,
+		SessionID: sess.ID,
+		UpdatedAt: time.Now().UTC(),
+		Messages:  sess.Messages,
+	})
+	if err != nil {
+		return fmt.Errorf("encode session: %w", err)
+	}
+
+	path := s.path(sess.ID)
+	if old, err := os.ReadFile(path); err == nil {
+		if err := os.WriteFile(path+".bak", old, 0600); err != nil {
+			return fmt.Errorf("back up session: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write session: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write session: %w", err)
+	}
+	return nil
+}
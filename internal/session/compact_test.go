@@ -0,0 +1,84 @@
+package session
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+func compactSession() *Session {
+	return &Session{
+		ID: "cli",
+		Messages: []message.Message{
+			{Role: "user", Content: "list files"},
+			{Role: "assistant", ToolCalls: []message.ToolCall{{ID: "t1", Name: "Bash"}}},
+			{Role: "tool", ToolCalls: []message.ToolCall{{ID: "t1", Name: "Bash", Result: "a.txt"}}},
+			{Role: "assistant", Content: "One file."},
+			{Role: "user", Content: "thanks"},
+		},
+	}
+}
+
+func TestPlanCompaction(t *testing.T) {
+	sess := compactSession()
+	plan := sess.PlanCompaction(2)
+	if len(plan.Dropped) != 3 || len(plan.Kept) != 2 {
+		t.Errorf("keep 2: dropped %d, kept %d", len(plan.Dropped), len(plan.Kept))
+	}
+
+	// Keeping 3 would start with the tool result; the call goes along.
+	plan = sess.PlanCompaction(3)
+	if len(plan.Kept) != 4 || plan.Kept[0].Role != "assistant" {
+		t.Errorf("keep 3: kept %+v", plan.Kept)
+	}
+
+	if plan := sess.PlanCompaction(10); len(plan.Dropped) != 0 {
+		t.Errorf("keeping more than there is should drop nothing: %+v", plan.Dropped)
+	}
+}
+
+func TestCompaction_PreviewAndApply(t *testing.T) {
+	plan := compactSession().PlanCompaction(2)
+	preview := plan.Preview()
+	for _, want := range []string{"- [user] list files", "- [assistant] (tool Bash)", "+ [system] summary of the 3 messages above", "  [user] thanks"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview missing %q:\n%s", want, preview)
+		}
+	}
+	if got := plan.Transcript(); !strings.Contains(got, "User: list files") || !strings.Contains(got, "called tool Bash") {
+		t.Errorf("transcript = %q", got)
+	}
+
+	msgs := plan.Apply(" they listed files ")
+	if len(msgs) != 3 || msgs[0].Role != "system" || msgs[0].Content != SummaryPrefix+"they listed files" {
+		t.Errorf("applied = %+v", msgs)
+	}
+}
+
+func TestStore_Save(t *testing.T) {
+	store := NewStore(t.TempDir())
+	sess := compactSession()
+	if err := store.Save(sess); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	sess.Messages = sess.Messages[3:]
+	if err := store.Save(sess); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	got, err := store.Get("cli")
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if len(got.Messages) != 2 || got.UpdatedAt.IsZero() {
+		t.Errorf("saved = %+v", got)
+	}
+	if _, err := os.Stat(store.path("cli") + ".bak"); err != nil {
+		t.Errorf("previous history should be backed up: %v", err)
+	}
+	if list, _ := store.List(); len(list) != 1 {
+		t.Errorf("the backup should not be listed as a session: %+v", list)
+	}
+}
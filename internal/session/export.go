@@ -0,0 +1,243 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// Markdown renders the session as a readable transcript. Tool calls are
+// listed under the assistant turn that made them.
+func (s *Session) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Session %s\n\n", s.ID)
+	if !s.UpdatedAt.IsZero() {
+		fmt.Fprintf(&sb, "_Last updated %s_\n\n", s.UpdatedAt.Local().Format(time.RFC3339))
+	}
+
+	for _, msg := range s.Messages {
+		content := strings.TrimSpace(msg.Content)
+		switch msg.Role {
+		case "user":
+			if content == "" {
+				continue
+			}
+			fmt.Fprintf(&sb, "## User\n\n%s\n\n", content)
+		case "assistant":
+			if content == "" && len(msg.ToolCalls) == 0 {
+				continue
+			}
+			sb.WriteString("## Assistant\n\n")
+			if content != "" {
+				sb.WriteString(content)
+				sb.WriteString("\n\n")
+			}
+			for _, call := range msg.ToolCalls {
+				fmt.Fprintf(&sb, "- tool `%s`\n", call.Name)
+			}
+			if len(msg.ToolCalls) > 0 {
+				sb.WriteString("\n")
+			}
+		case "tool":
+			// Tool output is usually long and noisy; the call list above is
+			// enough for a transcript.
+		default:
+			if content == "" {
+				continue
+			}
+			fmt.Fprintf(&sb, "## %s\n\n%s\n\n", msg.Role, content)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+// Turn is one message of a transcript, with the tool calls it made and
+// what they returned.
+type Turn struct {
+	Role    string `json:"role"`
+	Content string `json:"content,omitempty"`
+	Calls   []Call `json:"toolCalls,omitempty"`
+}
+
+// Call is a tool call in a transcript.
+type Call struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Result    string         `json:"result,omitempty"`
+}
+
+// Turns pairs each tool call with the result the runtime recorded for it,
+// and drops the tool messages that carried them and empty messages.
+func (s *Session) Turns() []Turn {
+	results := map[string]string{}
+	for _, msg := range s.Messages {
+		if msg.Role != "tool" {
+			continue
+		}
+		for _, call := range msg.ToolCalls {
+			if call.ID != "" {
+				results[call.ID] = call.Result
+			}
+		}
+	}
+
+	var turns []Turn
+	for _, msg := range s.Messages {
+		if msg.Role == "tool" {
+			continue
+		}
+		turn := Turn{Role: msg.Role, Content: strings.TrimSpace(msg.Content)}
+		for _, call := range msg.ToolCalls {
+			result := call.Result
+			if r, ok := results[call.ID]; ok && call.ID != "" {
+				result = r
+			}
+			turn.Calls = append(turn.Calls, Call{Name: call.Name, Arguments: call.Arguments, Result: result})
+		}
+		if turn.Content == "" && len(turn.Calls) == 0 {
+			continue
+		}
+		turns = append(turns, turn)
+	}
+	return turns
+}
+
+// Transcript renders the session as markdown for archiving: unlike
+// Markdown, each tool call shows its arguments and result. The runtime
+// keeps no time per message, so the transcript is stamped with when the
+// session was last updated and when it was exported.
+func (s *Session) Transcript(exportedAt time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Session %s\n\n", s.ID)
+	if !s.UpdatedAt.IsZero() {
+		fmt.Fprintf(&sb, "- Last updated: %s\n", s.UpdatedAt.Local().Format(time.RFC3339))
+	}
+	fmt.Fprintf(&sb, "- Exported: %s\n\n", exportedAt.Local().Format(time.RFC3339))
+
+	for _, turn := range s.Turns() {
+		fmt.Fprintf(&sb, "## %s\n\n", roleTitle(turn.Role))
+		if turn.Content != "" {
+			sb.WriteString(turn.Content)
+			sb.WriteString("\n\n")
+		}
+		for _, call := range turn.Calls {
+			fmt.Fprintf(&sb, "**Tool `%s`**\n\n", call.Name)
+			if args := formatArguments(call.Arguments); args != "" {
+				sb.WriteString(fence("json", args))
+			}
+			if result := strings.TrimSpace(call.Result); result != "" {
+				sb.WriteString("Result:\n\n")
+				sb.WriteString(fence("", result))
+			}
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+// HTML renders the transcript as a standalone page with the same content
+// as Transcript.
+func (s *Session) HTML(exportedAt time.Time) (string, error) {
+	type htmlCall struct {
+		Name, Arguments, Result string
+	}
+	type htmlTurn struct {
+		Role, Title, Content string
+		Calls                []htmlCall
+	}
+	data := struct {
+		ID, UpdatedAt, ExportedAt string
+		Turns                     []htmlTurn
+	}{ID: s.ID, ExportedAt: exportedAt.Local().Format(time.RFC3339)}
+	if !s.UpdatedAt.IsZero() {
+		data.UpdatedAt = s.UpdatedAt.Local().Format(time.RFC3339)
+	}
+	for _, turn := range s.Turns() {
+		t := htmlTurn{Role: turn.Role, Title: roleTitle(turn.Role), Content: turn.Content}
+		for _, call := range turn.Calls {
+			t.Calls = append(t.Calls, htmlCall{
+				Name:      call.Name,
+				Arguments: formatArguments(call.Arguments),
+				Result:    strings.TrimSpace(call.Result),
+			})
+		}
+		data.Turns = append(data.Turns, t)
+	}
+
+	var sb strings.Builder
+	if err := htmlTranscript.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("render html: %w", err)
+	}
+	return sb.String(), nil
+}
+
+var htmlTranscript = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Session {{.ID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 50rem; margin: 2rem auto; padding: 0 1rem; color: #1f2328; line-height: 1.5; }
+header p { color: #59636e; margin: 0; }
+section { border-left: 4px solid #d1d9e0; margin: 1.5rem 0; padding: 0 1rem; }
+section.user { border-color: #0969da; }
+section.assistant { border-color: #1a7f37; }
+h2 { font-size: 1rem; margin: 0 0 .5rem; }
+.content { white-space: pre-wrap; }
+details { margin: .5rem 0; }
+summary { cursor: pointer; font-family: ui-monospace, Menlo, monospace; }
+pre { background: #f6f8fa; padding: .75rem; overflow-x: auto; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<header>
+<h1>Session {{.ID}}</h1>
+{{if .UpdatedAt}}<p>Last updated {{.UpdatedAt}}</p>
+{{end}}<p>Exported {{.ExportedAt}}</p>
+</header>
+{{range .Turns}}<section class="{{.Role}}">
+<h2>{{.Title}}</h2>
+{{if .Content}}<div class="content">{{.Content}}</div>
+{{end}}{{range .Calls}}<details>
+<summary>Tool {{.Name}}</summary>
+{{if .Arguments}}<pre>{{.Arguments}}</pre>
+{{end}}{{if .Result}}<p>Result:</p>
+<pre>{{.Result}}</pre>
+{{end}}</details>
+{{end}}</section>
+{{end}}</body>
+</html>
+`))
+
+func roleTitle(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	}
+	return role
+}
+
+func formatArguments(args map[string]any) string {
+	if len(args) == 0 {
+		return ""
+	}
+	data, err := json.MarshalIndent(args, "", "  ")
+	if err != nil {
+		return fmt.Sprint(args)
+	}
+	return string(data)
+}
+
+// fence wraps text in a code fence longer than any backtick run inside it.
+func fence(lang, text string) string {
+	ticks := "```"
+	for strings.Contains(text, ticks) {
+		ticks += "`"
+	}
+	return ticks + lang + "\n" + text + "\n" + ticks + "\n\n"
+}
@@ -0,0 +1,72 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+func exportSession() *Session {
+	return &Session{
+		ID:        "cli",
+		UpdatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Messages: []message.Message{
+			{Role: "user", Content: "list files"},
+			{Role: "assistant", ToolCalls: []message.ToolCall{{ID: "t1", Name: "Bash", Arguments: map[string]any{"command": "ls"}}}},
+			{Role: "tool", ToolCalls: []message.ToolCall{{ID: "t1", Name: "Bash", Result: "a.txt\n<b>.txt"}}},
+			{Role: "assistant", Content: "There are two files."},
+		},
+	}
+}
+
+func TestSession_Turns(t *testing.T) {
+	turns := exportSession().Turns()
+	if len(turns) != 3 {
+		t.Fatalf("turns = %+v", turns)
+	}
+	call := turns[1].Calls[0]
+	if call.Name != "Bash" || call.Arguments["command"] != "ls" || call.Result != "a.txt\n<b>.txt" {
+		t.Errorf("call = %+v, want its result paired in", call)
+	}
+}
+
+func TestSession_Transcript(t *testing.T) {
+	md := exportSession().Transcript(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	for _, want := range []string{
+		"# Session cli",
+		"- Last updated: ",
+		"- Exported: ",
+		"## User\n\nlist files",
+		"**Tool `Bash`**",
+		`"command": "ls"`,
+		"Result:\n\n```\na.txt\n<b>.txt\n```",
+		"There are two files.",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("transcript missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestSession_HTML(t *testing.T) {
+	page, err := exportSession().HTML(time.Now())
+	if err != nil {
+		t.Fatalf("HTML error: %v", err)
+	}
+	for _, want := range []string{"<title>Session cli</title>", "<summary>Tool Bash</summary>", "&lt;b&gt;.txt", "There are two files."} {
+		if !strings.Contains(page, want) {
+			t.Errorf("html missing %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "<b>.txt") {
+		t.Error("tool output should be escaped")
+	}
+}
+
+func TestFence(t *testing.T) {
+	if got := fence("", "a ``` b"); !strings.HasPrefix(got, "````\n") {
+		t.Errorf("fence = %q, want a longer fence than the text's", got)
+	}
+}
@@ -0,0 +1,202 @@
+// Package session reads and manages conversation history saved by the agent
+// runtime. The CLI and the gateway run the runtime against the same
+// workspace, so both write their sessions to the same history directory.
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+// HistoryDir is where the runtime persists history for a workspace.
+func HistoryDir(workspace string) string {
+	return filepath.Join(workspace, ".claude", "history")
+}
+
+// ErrNotFound is returned for a session that has no saved history.
+var ErrNotFound = errors.New("not found")
+
+// Session is a saved conversation.
+type Session struct {
+	ID        string            `json:"id"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+	Messages  []message.Message `json:"messages"`
+}
+
+// Summary describes a session without its messages.
+type Summary struct {
+	ID        string    `json:"id"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Messages  int       `json:"messages"`
+	Preview   string    `json:"preview,omitempty"`
+}
+
+// persisted mirrors the runtime's on-disk history format.
+type persisted struct {
+	Version   int               `json:"version"`
+	SessionID string            `json:"session_id,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at,omitempty"`
+	Messages  []message.Message `json:"messages,omitempty"`
+}
+
+type Store struct {
+	dir string
+}
+
+func NewStore(workspace string) *Store {
+	return &Store{dir: HistoryDir(workspace)}
+}
+
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// List returns every saved session, most recently updated first.
+func (s *Store) List() ([]Summary, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read history dir: %w", err)
+	}
+
+	var summaries []Summary
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		sess, err := s.load(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			// One unreadable file should not hide the rest.
+			continue
+		}
+		summaries = append(summaries, summarize(sess))
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt)
+	})
+	return summaries, nil
+}
+
+// Get loads a session by its id (e.g. "telegram:123") or file name.
+func (s *Store) Get(id string) (*Session, error) {
+	path, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.load(path)
+}
+
+// Delete removes a saved session. A running gateway keeps its in-memory
+// copy until it restarts.
+func (s *Store) Delete(id string) error {
+	path, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) find(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", fmt.Errorf("session id is required")
+	}
+	path := s.path(id)
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("session %q %w", id, ErrNotFound)
+		}
+		return "", fmt.Errorf("stat session: %w", err)
+	}
+	return path, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, FileName(id))
+}
+
+func (s *Store) load(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read session: %w", err)
+	}
+	var p persisted
+	if err := json.Unmarshal(data, &p); err != nil {
+		// Older runtimes wrote a bare message array.
+		var msgs []message.Message
+		if err2 := json.Unmarshal(data, &msgs); err2 != nil {
+			return nil, fmt.Errorf("parse session: %w", err)
+		}
+		p.Messages = msgs
+	}
+
+	sess := &Session{
+		ID:        p.SessionID,
+		UpdatedAt: p.UpdatedAt,
+		Messages:  p.Messages,
+	}
+	if sess.ID == "" {
+		sess.ID = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	if sess.UpdatedAt.IsZero() {
+		if info, err := os.Stat(path); err == nil {
+			sess.UpdatedAt = info.ModTime()
+		}
+	}
+	return sess, nil
+}
+
+// FileName maps a session id to its history file name the same way the
+// runtime does.
+func FileName(id string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(id) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('-')
+		}
+	}
+	name := strings.Trim(b.String(), "-")
+	if name == "" {
+		name = "default"
+	}
+	return name + ".json"
+}
+
+func summarize(sess *Session) Summary {
+	sum := Summary{
+		ID:        sess.ID,
+		UpdatedAt: sess.UpdatedAt,
+		Messages:  len(sess.Messages),
+	}
+	for _, msg := range sess.Messages {
+		if msg.Role == "user" && strings.TrimSpace(msg.Content) != "" {
+			sum.Preview = preview(msg.Content, 60)
+			break
+		}
+	}
+	return sum
+}
+
+func preview(s string, n int) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(s), "\n", 2)[0])
+	if runes := []rune(line); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return line
+}
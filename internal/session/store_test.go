@@ -0,0 +1,125 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+func writeHistory(t *testing.T, workspace, id string, updated time.Time, body string) {
+	t.Helper()
+	dir := HistoryDir(workspace)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `{"version":1,"session_id":"` + id + `","updated_at":"` + updated.UTC().Format(time.RFC3339) + `","messages":` + body + `}`
+	if err := os.WriteFile(filepath.Join(dir, FileName(id)), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := map[string]string{
+		"telegram:12345": "telegram-12345.json",
+		"cli-repl":       "cli-repl.json",
+		"  ":             "default.json",
+		"a/b c":          "a-b-c.json",
+	}
+	for id, want := range tests {
+		if got := FileName(id); got != want {
+			t.Errorf("FileName(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestStore_ListAndGet(t *testing.T) {
+	ws := t.TempDir()
+	now := time.Now()
+	writeHistory(t, ws, "telegram:1", now.Add(-time.Hour), `[{"Role":"user","Content":"old question"}]`)
+	writeHistory(t, ws, "cli-repl", now, `[{"Role":"user","Content":"what's the weather?\nin Paris"},{"Role":"assistant","Content":"Sunny."}]`)
+	os.WriteFile(filepath.Join(HistoryDir(ws), "broken.json"), []byte("{nope"), 0644)
+
+	store := NewStore(ws)
+	summaries, err := store.List()
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("len(summaries) = %d, want 2", len(summaries))
+	}
+	if summaries[0].ID != "cli-repl" || summaries[0].Messages != 2 {
+		t.Errorf("newest = %+v", summaries[0])
+	}
+	if summaries[0].Preview != "what's the weather?" {
+		t.Errorf("preview = %q", summaries[0].Preview)
+	}
+
+	sess, err := store.Get("telegram:1")
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if sess.ID != "telegram:1" || len(sess.Messages) != 1 {
+		t.Errorf("session = %+v", sess)
+	}
+
+	if _, err := store.Get("missing"); err == nil {
+		t.Error("expected not found error")
+	}
+}
+
+func TestStore_ListNoDir(t *testing.T) {
+	summaries, err := NewStore(t.TempDir()).List()
+	if err != nil || len(summaries) != 0 {
+		t.Errorf("List = %v, %v", summaries, err)
+	}
+}
+
+func TestStore_Delete(t *testing.T) {
+	ws := t.TempDir()
+	writeHistory(t, ws, "feishu:abc", time.Now(), `[]`)
+	store := NewStore(ws)
+
+	if err := store.Delete("feishu:abc"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir(), "feishu-abc.json")); !os.IsNotExist(err) {
+		t.Error("history file should be removed")
+	}
+	if err := store.Delete("feishu:abc"); err == nil {
+		t.Error("deleting twice should fail")
+	}
+}
+
+func TestSession_Markdown(t *testing.T) {
+	sess := &Session{
+		ID: "cli",
+		Messages: []message.Message{
+			{Role: "user", Content: "list files"},
+			{Role: "assistant", ToolCalls: []message.ToolCall{{Name: "Bash"}}},
+			{Role: "tool", Content: "a.txt\nb.txt"},
+			{Role: "assistant", Content: "There are two files."},
+		},
+	}
+	md := sess.Markdown()
+	for _, want := range []string{"# Session cli", "## User\n\nlist files", "- tool `Bash`", "There are two files."} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "a.txt") {
+		t.Error("tool output should be left out of the transcript")
+	}
+}
+
+func TestPreview(t *testing.T) {
+	if got := preview("  天气怎么样？明天会下雨吗\nsecond line", 5); got != "天气怎么样..." {
+		t.Errorf("preview = %q", got)
+	}
+	if got := preview("short", 5); got != "short" {
+		t.Errorf("preview = %q", got)
+	}
+}
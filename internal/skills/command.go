@@ -0,0 +1,310 @@
+package skills
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+// Sandbox modes for command skills.
+const (
+	SandboxEnv   = "env"   // run in the skill dir with a minimal environment
+	SandboxNone  = "none"  // run in the skill dir with the full environment
+	SandboxBwrap = "bwrap" // run under bubblewrap: read-only root, no network
+)
+
+// DefaultCommandTimeout applies when neither the config nor the skill sets one.
+const DefaultCommandTimeout = 60 * time.Second
+
+// maxCommandOutput caps what a command skill returns to the model.
+const maxCommandOutput = 64 * 1024
+
+// Definition metadata keys for command skills.
+const (
+	typeMetadataKey    = "type"
+	runMetadataKey     = "run"
+	dirMetadataKey     = "dir"
+	timeoutMetadataKey = "timeout"
+)
+
+// CommandOptions controls how command skills run.
+type CommandOptions struct {
+	Timeout time.Duration // upper bound; a skill may ask for less
+	Sandbox string        // SandboxEnv (default), SandboxNone or SandboxBwrap
+}
+
+// Command describes the executable behind a command skill.
+type Command struct {
+	Run     string        `json:"run"`
+	Dir     string        `json:"dir"`
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// CommandOf returns the command behind a command skill, or nil for a
+// prompt skill.
+func CommandOf(def runtimeskills.Definition) *Command {
+	if def.Metadata[typeMetadataKey] != "command" {
+		return nil
+	}
+	c := &Command{Run: def.Metadata[runMetadataKey], Dir: def.Metadata[dirMetadataKey]}
+	if secs, err := strconv.Atoi(def.Metadata[timeoutMetadataKey]); err == nil && secs > 0 {
+		c.Timeout = time.Duration(secs) * time.Second
+	}
+	return c
+}
+
+// checkCommand validates the run line of a command skill in dir. A program
+// given as a path must stay inside the skill directory; a bare name is
+// looked up on PATH when the command runs.
+func checkCommand(dir, run string) error {
+	fields := strings.Fields(run)
+	if len(fields) == 0 {
+		return errors.New("command skill needs run")
+	}
+	program := fields[0]
+	if !strings.ContainsRune(program, '/') && !strings.ContainsRune(program, filepath.Separator) {
+		return nil
+	}
+	if filepath.IsAbs(program) {
+		return fmt.Errorf("run %q: use a path relative to the skill directory", program)
+	}
+	resolved := filepath.Join(dir, program)
+	rel, err := filepath.Rel(dir, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("run %q: path leaves the skill directory", program)
+	}
+	return nil
+}
+
+// commandNote tells the model how to run a command skill.
+func commandNote(skill string, params []Parameter) string {
+	note := "This skill runs a command. Call the " + ParameterToolPrefix + skill + " tool to run it"
+	if len(params) == 0 {
+		return note + ", passing any command-line arguments in args."
+	}
+	return note + " with these parameters, taking values from the conversation:\n" + parameterList(params)
+}
+
+// CommandOptionsFromConfig returns the command options cfg asks for.
+func CommandOptionsFromConfig(cfg config.SkillsConfig) CommandOptions {
+	return CommandOptions{
+		Timeout: time.Duration(cfg.CommandTimeout) * time.Second,
+		Sandbox: strings.ToLower(strings.TrimSpace(cfg.Sandbox)),
+	}
+}
+
+// commandSchema is the tool schema of a command skill: its declared
+// parameters, or free-form arguments when it declares none.
+func commandSchema(params []Parameter) *tool.JSONSchema {
+	if len(params) > 0 {
+		return ParameterSchema(params)
+	}
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"args": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Command-line arguments",
+			},
+		},
+		Required: []string{},
+	}
+}
+
+// Tools returns the tools skills contribute to the runtime: one per
+// command skill, which runs its executable, and one per prompt skill with
+// parameters, which fills them into the skill's instructions.
+func Tools(registrations []api.SkillRegistration, opts CommandOptions) []tool.Tool {
+	var tools []tool.Tool
+	for _, reg := range registrations {
+		params := Parameters(reg.Definition)
+		if cmd := CommandOf(reg.Definition); cmd != nil {
+			tools = append(tools, &commandTool{def: reg.Definition, params: params, cmd: *cmd, opts: opts})
+			continue
+		}
+		if len(params) == 0 || reg.Handler == nil {
+			continue
+		}
+		tools = append(tools, &parameterTool{def: reg.Definition, params: params, handler: reg.Handler})
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name() < tools[j].Name() })
+	return tools
+}
+
+type commandTool struct {
+	def    runtimeskills.Definition
+	params []Parameter
+	cmd    Command
+	opts   CommandOptions
+}
+
+func (t *commandTool) Name() string { return ParameterToolPrefix + t.def.Name }
+
+func (t *commandTool) Description() string {
+	desc := "Run the " + t.def.Name + " skill's command and return its output."
+	if t.def.Description != "" {
+		desc += " " + t.def.Description
+	}
+	return desc
+}
+
+func (t *commandTool) Schema() *tool.JSONSchema { return commandSchema(t.params) }
+
+func (t *commandTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	if params == nil {
+		params = map[string]any{}
+	}
+	out, err := RunCommand(ctx, t.cmd, t.params, params, t.opts)
+	if err != nil {
+		return &tool.ToolResult{Success: false, Output: strings.TrimSpace(out + "\n" + err.Error()), Error: err}, nil
+	}
+	return &tool.ToolResult{Success: true, Output: out}, nil
+}
+
+// RunCommand runs a command skill. Declared parameters are validated and
+// passed both as PARAM_<NAME> environment variables and as a JSON object on
+// stdin; a skill without parameters gets args appended to its command line.
+// It returns stdout, with stderr appended when the command fails.
+func RunCommand(ctx context.Context, c Command, params []Parameter, args map[string]any, opts CommandOptions) (string, error) {
+	fields := strings.Fields(c.Run)
+	if err := checkCommand(c.Dir, c.Run); err != nil {
+		return "", err
+	}
+
+	env := []string{}
+	stdin := []byte("{}")
+	if len(params) > 0 {
+		values, err := ResolveArguments(params, args)
+		if err != nil {
+			return "", err
+		}
+		for name, v := range values {
+			env = append(env, "PARAM_"+strings.ToUpper(name)+"="+formatValue(v))
+		}
+		sort.Strings(env)
+		if stdin, err = json.Marshal(values); err != nil {
+			return "", err
+		}
+	} else if raw, ok := args["args"]; ok {
+		if err := (tool.DefaultValidator{}).Validate(args, commandSchema(nil)); err != nil {
+			return "", err
+		}
+		for _, a := range raw.([]any) {
+			fields = append(fields, a.(string))
+		}
+	}
+	if strings.ContainsRune(fields[0], '/') {
+		fields[0] = filepath.Join(c.Dir, fields[0])
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	if c.Timeout > 0 && c.Timeout < timeout {
+		timeout = c.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch opts.Sandbox {
+	case SandboxNone:
+		cmd = exec.CommandContext(ctx, fields[0], fields[1:]...)
+		cmd.Env = append(os.Environ(), env...)
+	case "", SandboxEnv:
+		cmd = exec.CommandContext(ctx, fields[0], fields[1:]...)
+		cmd.Env = append(minimalEnv(), env...)
+	case SandboxBwrap:
+		bwrap, err := exec.LookPath("bwrap")
+		if err != nil {
+			return "", errors.New("sandbox bwrap: bubblewrap is not installed")
+		}
+		bargs := []string{
+			"--ro-bind", "/", "/",
+			"--dev", "/dev",
+			"--proc", "/proc",
+			"--tmpfs", "/tmp",
+			"--bind", c.Dir, c.Dir,
+			"--unshare-all",
+			"--die-with-parent",
+			"--chdir", c.Dir,
+			"--",
+		}
+		cmd = exec.CommandContext(ctx, bwrap, append(bargs, fields...)...)
+		cmd.Env = append(minimalEnv(), env...)
+	default:
+		return "", fmt.Errorf("unknown sandbox %q", opts.Sandbox)
+	}
+	cmd.Dir = c.Dir
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.WaitDelay = time.Second
+	stdout := &cappedBuffer{limit: maxCommandOutput}
+	stderr := &cappedBuffer{limit: maxCommandOutput}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	err := cmd.Run()
+	out := stdout.String()
+	if ctx.Err() == context.DeadlineExceeded {
+		return out, fmt.Errorf("command timed out after %s", timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			out = strings.TrimSpace(out + "\n" + msg)
+		}
+		return out, fmt.Errorf("command failed: %w", err)
+	}
+	return out, nil
+}
+
+// minimalEnv is the environment a sandboxed command starts from.
+func minimalEnv() []string {
+	var env []string
+	for _, key := range []string{"PATH", "HOME", "LANG", "TMPDIR"} {
+		if v, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+v)
+		}
+	}
+	return env
+}
+
+// cappedBuffer keeps the first limit bytes written to it and notes when
+// more was discarded.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "\n[output truncated]"
+	}
+	return b.buf.String()
+}
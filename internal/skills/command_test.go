@@ -0,0 +1,116 @@
+package skills
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
+)
+
+func writeCommandSkill(t *testing.T, root, frontmatter, script string) {
+	t.Helper()
+	writeTestSkillFile(t, root, "fetch", "---\nname: fetch\ndescription: fetch things\ntype: command\n"+frontmatter+"---\nFetches things.\n")
+	path := filepath.Join(root, "fetch", "scripts", "fetch.sh")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommandSkill_Args(t *testing.T) {
+	root := t.TempDir()
+	writeCommandSkill(t, root, "run: ./scripts/fetch.sh --verbose\n", "echo \"args: $*\"\necho \"secret: $MYCLAW_TEST_SECRET\"\npwd\n")
+	t.Setenv("MYCLAW_TEST_SECRET", "s3cret")
+
+	registrations, err := LoadSkills(root)
+	if err != nil {
+		t.Fatalf("load skills: %v", err)
+	}
+	cmd := CommandOf(registrations[0].Definition)
+	if cmd == nil || cmd.Run != "./scripts/fetch.sh --verbose" {
+		t.Fatalf("command = %+v", cmd)
+	}
+	res, err := registrations[0].Handler.Execute(context.Background(), runtimeskills.ActivationContext{Prompt: "anything"})
+	if err != nil || !strings.Contains(res.Output.(string), "Call the skill_fetch tool") {
+		t.Fatalf("handler output = %v, %v", res.Output, err)
+	}
+
+	tools := Tools(registrations, CommandOptions{})
+	if len(tools) != 1 || tools[0].Name() != "skill_fetch" {
+		t.Fatalf("tools = %v", tools)
+	}
+	result, err := tools[0].Execute(context.Background(), map[string]any{"args": []any{"a", "b c"}})
+	if err != nil || !result.Success {
+		t.Fatalf("execute: %+v, %v", result, err)
+	}
+	for _, want := range []string{"args: --verbose a b c", "secret: \n", filepath.Join(root, "fetch")} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("output missing %q:\n%s", want, result.Output)
+		}
+	}
+
+	// Without the sandbox the full environment is passed on.
+	result, _ = Tools(registrations, CommandOptions{Sandbox: SandboxNone})[0].Execute(context.Background(), nil)
+	if !strings.Contains(result.Output, "secret: s3cret") {
+		t.Errorf("sandbox none output = %q", result.Output)
+	}
+}
+
+func TestCommandSkill_Parameters(t *testing.T) {
+	root := t.TempDir()
+	writeCommandSkill(t, root, "run: ./scripts/fetch.sh\nparameters:\n  - name: url\n  - name: retries\n    type: integer\n    default: 2\n",
+		"echo \"$PARAM_URL $PARAM_RETRIES\"\ncat\n")
+	registrations, err := LoadSkills(root)
+	if err != nil {
+		t.Fatalf("load skills: %v", err)
+	}
+	tool := Tools(registrations, CommandOptions{})[0]
+	result, _ := tool.Execute(context.Background(), map[string]any{"url": "https://example.com"})
+	if !result.Success || !strings.Contains(result.Output, "https://example.com 2") || !strings.Contains(result.Output, `"url":"https://example.com"`) {
+		t.Errorf("output = %+v", result)
+	}
+	if result, _ := tool.Execute(context.Background(), map[string]any{}); result.Success {
+		t.Errorf("expected missing url to fail, got %+v", result)
+	}
+}
+
+func TestCommandSkill_FailureAndTimeout(t *testing.T) {
+	root := t.TempDir()
+	writeCommandSkill(t, root, "run: ./scripts/fetch.sh\n", "echo partial\necho broken >&2\nexit 3\n")
+	registrations, _ := LoadSkills(root)
+	result, _ := Tools(registrations, CommandOptions{})[0].Execute(context.Background(), nil)
+	if result.Success || !strings.Contains(result.Output, "partial") || !strings.Contains(result.Output, "broken") {
+		t.Errorf("failed command result = %+v", result)
+	}
+
+	root = t.TempDir()
+	writeCommandSkill(t, root, "run: ./scripts/fetch.sh\n", "sleep 5\n")
+	registrations, _ = LoadSkills(root)
+	start := time.Now()
+	result, _ = Tools(registrations, CommandOptions{Timeout: 200 * time.Millisecond})[0].Execute(context.Background(), nil)
+	if result.Success || !strings.Contains(result.Output, "timed out") || time.Since(start) > 3*time.Second {
+		t.Errorf("timeout result = %+v after %s", result, time.Since(start))
+	}
+}
+
+func TestCommandSkill_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"no run":       "type: command\n",
+		"escapes dir":  "type: command\nrun: ../other/run.sh\n",
+		"absolute":     "type: command\nrun: /bin/sh\n",
+		"unknown type": "type: daemon\n",
+	}
+	for name, frontmatter := range cases {
+		root := t.TempDir()
+		writeTestSkillFile(t, root, "broken", "---\nname: broken\n"+frontmatter+"---\nbody\n")
+		if _, err := LoadSkills(root); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
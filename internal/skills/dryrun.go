@@ -0,0 +1,121 @@
+package skills
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
+)
+
+// DryRunResult reports how the runtime would treat one skill for a prompt.
+type DryRunResult struct {
+	Skill         string          `json:"skill"`
+	InvalidName   string          `json:"invalidName,omitempty"` // why the runtime would refuse to register it
+	Matchers      []MatcherResult `json:"matchers"`
+	Activated     bool            `json:"activated"`
+	Score         float64         `json:"score,omitempty"`
+	Reason        string          `json:"reason,omitempty"`
+	AlsoActivated []string        `json:"alsoActivated"` // other skills the prompt activates
+	Output        string          `json:"output"`
+	HandlerError  string          `json:"handlerError,omitempty"`
+	Prompt        string          `json:"prompt"` // the prompt as the model would receive it
+}
+
+// MatcherResult is the outcome of one matcher.
+type MatcherResult struct {
+	Matcher string  `json:"matcher"`
+	Matched bool    `json:"matched"`
+	Score   float64 `json:"score,omitempty"`
+	Reason  string  `json:"reason,omitempty"`
+}
+
+// DryRun evaluates the named skill against prompt the way the runtime does:
+// it registers all of registrations, matches the prompt, executes the
+// skill's handler and prepends what the activated skills return to the
+// prompt. Nothing is sent to a model.
+func DryRun(ctx context.Context, registrations []api.SkillRegistration, name, prompt string) (*DryRunResult, error) {
+	var target *api.SkillRegistration
+	for i := range registrations {
+		if strings.EqualFold(registrations[i].Definition.Name, name) {
+			target = &registrations[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("skill not found: %s", name)
+	}
+	result := &DryRunResult{Skill: target.Definition.Name, Matchers: []MatcherResult{}, AlsoActivated: []string{}}
+	if err := target.Definition.Validate(); err != nil {
+		result.InvalidName = err.Error()
+	}
+
+	activation := runtimeskills.ActivationContext{Prompt: prompt}
+	for _, m := range target.Definition.Matchers {
+		if m == nil {
+			continue
+		}
+		res := m.Match(activation)
+		result.Matchers = append(result.Matchers, MatcherResult{
+			Matcher: describeMatcher(m),
+			Matched: res.Matched,
+			Score:   res.Score,
+			Reason:  res.Reason,
+		})
+	}
+
+	registry := runtimeskills.NewRegistry()
+	for _, reg := range registrations {
+		_ = registry.Register(reg.Definition, reg.Handler)
+	}
+	var prefix []string
+	for _, match := range registry.Match(activation) {
+		def := match.Skill.Definition()
+		if strings.EqualFold(def.Name, target.Definition.Name) {
+			result.Activated, result.Score, result.Reason = true, match.Score, match.Reason
+		} else {
+			result.AlsoActivated = append(result.AlsoActivated, def.Name)
+		}
+		out, err := match.Skill.Execute(ctx, activation)
+		if err != nil {
+			continue
+		}
+		if text, ok := out.Output.(string); ok && strings.TrimSpace(text) != "" {
+			prefix = append(prefix, strings.TrimSpace(text))
+		}
+	}
+	sort.Strings(result.AlsoActivated)
+
+	out, err := target.Handler.Execute(ctx, activation)
+	if err != nil {
+		result.HandlerError = err.Error()
+	} else if text, ok := out.Output.(string); ok {
+		result.Output = text
+	} else if out.Output != nil {
+		result.Output = fmt.Sprint(out.Output)
+	}
+
+	result.Prompt = strings.TrimSpace(prompt)
+	if len(prefix) > 0 {
+		result.Prompt = strings.TrimSpace(strings.Join(prefix, "\n") + "\n\n" + result.Prompt)
+	}
+	return result, nil
+}
+
+func describeMatcher(m runtimeskills.Matcher) string {
+	switch km := m.(type) {
+	case runtimeskills.KeywordMatcher:
+		var parts []string
+		if len(km.All) > 0 {
+			parts = append(parts, "all of ["+strings.Join(km.All, ", ")+"]")
+		}
+		if len(km.Any) > 0 {
+			parts = append(parts, "any of ["+strings.Join(km.Any, ", ")+"]")
+		}
+		return "keywords: " + strings.Join(parts, ", ")
+	default:
+		return fmt.Sprintf("%T", m)
+	}
+}
@@ -0,0 +1,66 @@
+package skills
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"writer":     "---\nname: writer\nkeywords: [write, draft]\n---\nWriting rules.\n",
+		"translator": "---\nname: translator\nkeywords: [translate]\n---\nTranslation rules.\n",
+		"always":     "---\nname: always\n---\nHouse style.\n",
+		"Bad_Name":   "---\nname: Bad_Name\nkeywords: [zzz]\n---\nbody\n",
+	}
+	for dir, content := range files {
+		os.MkdirAll(filepath.Join(root, dir), 0o755)
+		os.WriteFile(filepath.Join(root, dir, skillFileName), []byte(content), 0o644)
+	}
+	registrations, err := LoadSkills(root)
+	if err != nil {
+		t.Fatalf("load skills: %v", err)
+	}
+
+	result, err := DryRun(context.Background(), registrations, "Writer", "please draft a letter")
+	if err != nil {
+		t.Fatalf("DryRun error: %v", err)
+	}
+	if !result.Activated || result.Skill != "writer" || !strings.Contains(result.Reason, "hit=draft") {
+		t.Errorf("result = %+v", result)
+	}
+	if len(result.Matchers) != 1 || !result.Matchers[0].Matched || !strings.Contains(result.Matchers[0].Matcher, "any of [draft, write]") {
+		t.Errorf("matchers = %+v", result.Matchers)
+	}
+	if strings.Join(result.AlsoActivated, ",") != "always" {
+		t.Errorf("also activated = %v", result.AlsoActivated)
+	}
+	if result.Output != "Writing rules." {
+		t.Errorf("output = %q", result.Output)
+	}
+	if !strings.Contains(result.Prompt, "Writing rules.") || !strings.Contains(result.Prompt, "House style.") || !strings.HasSuffix(result.Prompt, "\n\nplease draft a letter") {
+		t.Errorf("prompt = %q", result.Prompt)
+	}
+
+	result, _ = DryRun(context.Background(), registrations, "translator", "please draft a letter")
+	if result.Activated || result.Matchers[0].Matched || strings.Contains(result.Prompt, "Translation rules.") {
+		t.Errorf("translator should not activate: %+v", result)
+	}
+	if result.Output != "Translation rules." {
+		t.Errorf("handler should still run: %q", result.Output)
+	}
+
+	result, _ = DryRun(context.Background(), registrations, "Bad_Name", "zzz")
+	if result.InvalidName == "" || result.Activated {
+		t.Errorf("invalid name result = %+v", result)
+	}
+
+	if _, err := DryRun(context.Background(), registrations, "missing", "hi"); err == nil {
+		t.Error("expected error for unknown skill")
+	}
+}
@@ -0,0 +1,421 @@
+package skills
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// sourceFileName records where an installed skill came from, for updates.
+const sourceFileName = ".myclaw-source.json"
+
+// maxArchiveSize caps downloaded and extracted skill archives.
+const maxArchiveSize = 50 << 20
+
+const (
+	SourceGit     = "git"
+	SourceArchive = "archive"
+	SourcePath    = "path"
+)
+
+// InstallOptions adjusts Install.
+type InstallOptions struct {
+	Name     string // directory name; defaults to the skill's frontmatter name
+	Force    bool   // replace an installed skill with the same name
+	Registry string // registry index URL, for sources that name a registry skill
+}
+
+// Installed describes an installed skill.
+type Installed struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Dir         string    `json:"dir"`
+	Source      string    `json:"source"`
+	Kind        string    `json:"kind"`
+	Ref         string    `json:"ref,omitempty"`      // git branch or tag
+	Registry    string    `json:"registry,omitempty"` // index URL of a registry skill
+	Version     string    `json:"version,omitempty"`  // installed registry version
+	InstalledAt time.Time `json:"installedAt"`
+}
+
+var validSkillDirName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// httpClient downloads skill archives.
+var httpClient = &http.Client{Timeout: 2 * time.Minute}
+
+// SourceKind reports how source would be installed: a git repository
+// (optionally with #ref), a .tar.gz/.tgz/.tar archive (local or http), a
+// local directory, or a registry skill name (optionally name@version) that
+// is not also a path.
+func SourceKind(source string) string {
+	base, _, _ := strings.Cut(source, "#")
+	lower := strings.ToLower(base)
+	switch {
+	case isRegistryName(source):
+		return SourceRegistry
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"), strings.HasSuffix(lower, ".tar"):
+		return SourceArchive
+	case strings.HasSuffix(lower, ".git"), strings.HasPrefix(lower, "git@"), strings.HasPrefix(lower, "git://"),
+		strings.HasPrefix(lower, "ssh://"), strings.HasPrefix(lower, "file://"), strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"):
+		return SourceGit
+	default:
+		return SourcePath
+	}
+}
+
+// Install copies the skill at source into skillDir after checking that its
+// SKILL.md parses. The source must contain SKILL.md at its root or inside a
+// single top-level directory, as in release archives.
+func Install(skillDir, source string, opts InstallOptions) (*Installed, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, errors.New("skill source is required")
+	}
+	if err := os.MkdirAll(skillDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create skills dir: %w", err)
+	}
+	// Stage inside skillDir so the final rename stays on one filesystem.
+	// LoadSkills ignores dot directories.
+	staging, err := os.MkdirTemp(skillDir, ".install-")
+	if err != nil {
+		return nil, fmt.Errorf("create staging dir: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	inst := &Installed{Source: source, Kind: SourceKind(source), InstalledAt: time.Now().UTC()}
+	if !strings.Contains(source, "://") && inst.Kind != SourceGit && inst.Kind != SourceRegistry {
+		// Record local paths absolutely so updates work from any directory.
+		if abs, err := filepath.Abs(source); err == nil {
+			inst.Source = abs
+		}
+	}
+	fetched := filepath.Join(staging, "src")
+	switch inst.Kind {
+	case SourceGit:
+		url, ref, _ := strings.Cut(source, "#")
+		inst.Ref = ref
+		err = cloneGit(url, ref, fetched)
+	case SourceArchive:
+		err = fetchArchive(inst.Source, fetched)
+	case SourceRegistry:
+		inst.Registry = opts.Registry
+		inst.Version, err = fetchRegistry(opts.Registry, source, fetched)
+	default:
+		err = copyDir(inst.Source, fetched)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := findSkillRoot(fetched)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	reg, skip, err := parseSkillFile(filepath.Join(root, skillFileName))
+	if err != nil || skip {
+		if err == nil {
+			err = errInvalidSkillYAML
+		}
+		return nil, fmt.Errorf("%s: invalid %s: %w", source, skillFileName, err)
+	}
+	inst.Name = reg.Definition.Name
+	inst.Description = reg.Definition.Description
+
+	dirName := opts.Name
+	if dirName == "" {
+		dirName = inst.Name
+	}
+	if !validSkillDirName.MatchString(dirName) {
+		return nil, fmt.Errorf("skill name %q cannot be used as a directory name; pass a name", dirName)
+	}
+	inst.Dir = filepath.Join(skillDir, dirName)
+
+	if err := checkCollision(skillDir, dirName, inst.Name, opts.Force); err != nil {
+		return nil, err
+	}
+	_ = os.RemoveAll(filepath.Join(root, ".git"))
+	data, _ := json.MarshalIndent(inst, "", "  ")
+	if err := os.WriteFile(filepath.Join(root, sourceFileName), data, 0o644); err != nil {
+		return nil, fmt.Errorf("record skill source: %w", err)
+	}
+
+	// Swap the new copy in, keeping the old one until the rename succeeds.
+	old := filepath.Join(staging, "old")
+	if _, err := os.Stat(inst.Dir); err == nil {
+		if err := os.Rename(inst.Dir, old); err != nil {
+			return nil, fmt.Errorf("replace skill: %w", err)
+		}
+	}
+	if err := os.Rename(root, inst.Dir); err != nil {
+		_ = os.Rename(old, inst.Dir)
+		return nil, fmt.Errorf("install skill: %w", err)
+	}
+	return inst, nil
+}
+
+// checkCollision refuses to overwrite an existing directory or to install a
+// second skill with the same name, unless force is set and the clash is with
+// the skill being replaced.
+func checkCollision(skillDir, dirName, name string, force bool) error {
+	target := filepath.Join(skillDir, dirName)
+	if _, err := os.Stat(target); err == nil && !force {
+		return fmt.Errorf("skill directory %s already exists (use --force to replace it)", target)
+	}
+	entries, err := os.ReadDir(skillDir)
+	if err != nil {
+		return fmt.Errorf("read skills dir %q: %w", skillDir, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == dirName || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		reg, skip, err := parseSkillFile(filepath.Join(skillDir, entry.Name(), skillFileName))
+		if err != nil || skip {
+			continue
+		}
+		if reg.Definition.Name == name {
+			return fmt.Errorf("a skill named %q is already installed in %s", name, filepath.Join(skillDir, entry.Name()))
+		}
+	}
+	return nil
+}
+
+// Update reinstalls the named skill from the source recorded at install time.
+func Update(skillDir, name string) (*Installed, error) {
+	dir, err := FindSkillDir(skillDir, name)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := ReadInstalled(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Registry skills update from the registry they came from; a pinned
+	// name@version stays pinned.
+	return Install(skillDir, prev.Source, InstallOptions{Name: filepath.Base(dir), Force: true, Registry: prev.Registry})
+}
+
+// Remove deletes the named skill from skillDir and returns its directory.
+func Remove(skillDir, name string) (string, error) {
+	dir, err := FindSkillDir(skillDir, name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("remove skill: %w", err)
+	}
+	return dir, nil
+}
+
+// ReadInstalled returns the install record of the skill in dir. Skills that
+// were copied in by hand have none.
+func ReadInstalled(dir string) (*Installed, error) {
+	data, err := os.ReadFile(filepath.Join(dir, sourceFileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("skill in %s was not installed with skills install; nothing to update from", dir)
+		}
+		return nil, err
+	}
+	var inst Installed
+	if err := json.Unmarshal(data, &inst); err != nil {
+		return nil, fmt.Errorf("read %s: %w", sourceFileName, err)
+	}
+	return &inst, nil
+}
+
+// FindSkillDir returns the directory of the skill called name, matching
+// either the directory name or the frontmatter name.
+func FindSkillDir(skillDir, name string) (string, error) {
+	if validSkillDirName.MatchString(name) {
+		dir := filepath.Join(skillDir, name)
+		if _, err := os.Stat(filepath.Join(dir, skillFileName)); err == nil {
+			return dir, nil
+		}
+	}
+	entries, err := os.ReadDir(skillDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("read skills dir %q: %w", skillDir, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(skillDir, entry.Name())
+		reg, skip, err := parseSkillFile(filepath.Join(dir, skillFileName))
+		if err == nil && !skip && reg.Definition.Name == name {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("skill not found: %s", name)
+}
+
+func findSkillRoot(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, skillFileName)); err == nil {
+		return dir, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var subdirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != ".git" {
+			subdirs = append(subdirs, entry.Name())
+		}
+	}
+	if len(subdirs) == 1 {
+		if _, err := os.Stat(filepath.Join(dir, subdirs[0], skillFileName)); err == nil {
+			return filepath.Join(dir, subdirs[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no %s found", skillFileName)
+}
+
+func cloneGit(url, ref, dest string) error {
+	args := []string{"clone", "-q", "--depth", "1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	cmd := exec.Command("git", append(args, url, dest)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("git clone %s: %s", url, msg)
+		}
+		return fmt.Errorf("git clone %s: %w", url, err)
+	}
+	return nil
+}
+
+func fetchArchive(source, dest string) error {
+	var r io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := httpClient.Get(source)
+		if err != nil {
+			return fmt.Errorf("download %s: %w", source, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("download %s: %s", source, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if err := extractTar(io.LimitReader(r, maxArchiveSize), dest, !strings.HasSuffix(strings.ToLower(source), ".tar")); err != nil {
+		return fmt.Errorf("extract %s: %w", source, err)
+	}
+	return nil
+}
+
+// extractTar unpacks regular files and directories, rejecting entries that
+// would land outside dest. Links and other special files are skipped.
+func extractTar(r io.Reader, dest string, gzipped bool) error {
+	if gzipped {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("unsafe path %q in archive", hdr.Name)
+		}
+		target := filepath.Join(dest, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			total += hdr.Size
+			if total > maxArchiveSize {
+				return errors.New("archive too large")
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, fs.FileMode(hdr.Mode)&0o755|0o644); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func copyDir(src, dest string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory, git repository or archive", src)
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dest, rel)
+		switch {
+		case d.IsDir() && d.Name() == ".git":
+			return filepath.SkipDir
+		case d.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case !d.Type().IsRegular():
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return writeFile(target, f, info.Mode().Perm())
+	})
+}
+
+func writeFile(path string, r io.Reader, mode fs.FileMode) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
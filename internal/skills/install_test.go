@@ -0,0 +1,208 @@
+package skills
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSourceSkill(t *testing.T, dir, name, description string) {
+	t.Helper()
+	content := "---\nname: " + name + "\ndescription: " + description + "\n---\nUse me.\n"
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, skillFileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("write skill: %v", err)
+	}
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestSourceKind(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"https://github.com/me/skill.git":  SourceGit,
+		"https://github.com/me/skill#v1":   SourceGit,
+		"git@github.com:me/skill.git":      SourceGit,
+		"https://example.com/skill.tar.gz": SourceArchive,
+		"./skill.tgz":                      SourceArchive,
+		"/tmp/skills/writer":               SourcePath,
+		"../writer":                        SourcePath,
+	}
+	for source, want := range cases {
+		if got := SourceKind(source); got != want {
+			t.Errorf("SourceKind(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestInstall_LocalPathUpdateRemove(t *testing.T) {
+	t.Parallel()
+
+	src := filepath.Join(t.TempDir(), "writer-src")
+	writeSourceSkill(t, src, "writer", "v1")
+	skillDir := filepath.Join(t.TempDir(), "skills")
+
+	inst, err := Install(skillDir, src, InstallOptions{})
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if inst.Name != "writer" || inst.Kind != SourcePath || inst.Dir != filepath.Join(skillDir, "writer") {
+		t.Fatalf("installed = %+v", inst)
+	}
+	regs, err := LoadSkills(skillDir)
+	if err != nil || len(regs) != 1 || regs[0].Definition.Description != "v1" {
+		t.Fatalf("load after install = %+v, %v", regs, err)
+	}
+
+	// Installing again collides; the same name under another directory too.
+	if _, err := Install(skillDir, src, InstallOptions{}); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("second install error = %v", err)
+	}
+	if _, err := Install(skillDir, src, InstallOptions{Name: "writer2"}); err == nil || !strings.Contains(err.Error(), "already installed") {
+		t.Errorf("duplicate name error = %v", err)
+	}
+
+	writeSourceSkill(t, src, "writer", "v2")
+	if inst, err = Update(skillDir, "writer"); err != nil || inst.Description != "v2" {
+		t.Fatalf("update = %+v, %v", inst, err)
+	}
+	entries, _ := os.ReadDir(skillDir)
+	if len(entries) != 1 {
+		t.Errorf("staging left behind: %v", entries)
+	}
+
+	if dir, err := Remove(skillDir, "writer"); err != nil || dir != filepath.Join(skillDir, "writer") {
+		t.Fatalf("remove = %q, %v", dir, err)
+	}
+	if _, err := Remove(skillDir, "writer"); err == nil {
+		t.Error("expected error removing missing skill")
+	}
+}
+
+func TestInstall_Archive(t *testing.T) {
+	t.Parallel()
+
+	data := tarGz(t, map[string]string{
+		"translator-1.0/SKILL.md":  "---\nname: translator\ndescription: translate text\n---\nTranslate.\n",
+		"translator-1.0/README.md": "docs",
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/translator.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	skillDir := t.TempDir()
+	inst, err := Install(skillDir, srv.URL+"/translator.tar.gz", InstallOptions{})
+	if err != nil {
+		t.Fatalf("install archive: %v", err)
+	}
+	if inst.Kind != SourceArchive || inst.Name != "translator" {
+		t.Errorf("installed = %+v", inst)
+	}
+	if _, err := os.Stat(filepath.Join(skillDir, "translator", "README.md")); err != nil {
+		t.Errorf("archive contents not installed: %v", err)
+	}
+	if _, err := Install(skillDir, srv.URL+"/missing.tar.gz", InstallOptions{}); err == nil {
+		t.Error("expected error for missing archive")
+	}
+
+	evil := filepath.Join(t.TempDir(), "evil.tgz")
+	os.WriteFile(evil, tarGz(t, map[string]string{"../../escape/SKILL.md": "---\nname: evil\n---\n"}), 0o644)
+	if _, err := Install(skillDir, evil, InstallOptions{}); err == nil || !strings.Contains(err.Error(), "unsafe path") {
+		t.Errorf("unsafe archive error = %v", err)
+	}
+}
+
+func TestInstall_Git(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	writeSourceSkill(t, repo, "reviewer", "code review")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "-A"},
+		{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "-m", "init"},
+		{"tag", "v1"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	skillDir := t.TempDir()
+	inst, err := Install(skillDir, "file://"+repo+"#v1", InstallOptions{Name: "review"})
+	if err != nil {
+		t.Fatalf("install git: %v", err)
+	}
+	if inst.Kind != SourceGit || inst.Ref != "v1" || inst.Dir != filepath.Join(skillDir, "review") {
+		t.Errorf("installed = %+v", inst)
+	}
+	if _, err := os.Stat(filepath.Join(inst.Dir, ".git")); !os.IsNotExist(err) {
+		t.Error(".git should not be installed")
+	}
+	if _, err := Update(skillDir, "reviewer"); err != nil {
+		t.Errorf("update by skill name: %v", err)
+	}
+}
+
+func TestInstall_Invalid(t *testing.T) {
+	t.Parallel()
+
+	skillDir := t.TempDir()
+	empty := t.TempDir()
+	if _, err := Install(skillDir, empty, InstallOptions{}); err == nil || !strings.Contains(err.Error(), "no SKILL.md") {
+		t.Errorf("missing SKILL.md error = %v", err)
+	}
+
+	bad := t.TempDir()
+	os.WriteFile(filepath.Join(bad, skillFileName), []byte("---\nname: [oops\n---\n"), 0o644)
+	if _, err := Install(skillDir, bad, InstallOptions{}); err == nil {
+		t.Error("expected error for invalid frontmatter")
+	}
+
+	unnamed := t.TempDir()
+	writeSourceSkill(t, unnamed, "My Skill", "spaces")
+	if _, err := Install(skillDir, unnamed, InstallOptions{}); err == nil {
+		t.Error("expected error for name unusable as directory")
+	}
+	if _, err := Install(skillDir, unnamed, InstallOptions{Name: "my-skill"}); err != nil {
+		t.Errorf("install with explicit name: %v", err)
+	}
+
+	manual := filepath.Join(skillDir, "manual")
+	writeSourceSkill(t, manual, "manual", "copied by hand")
+	if _, err := Update(skillDir, "manual"); err == nil {
+		t.Error("expected error updating a skill without install record")
+	}
+}
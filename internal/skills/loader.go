@@ -2,14 +2,20 @@ package skills
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cexll/agentsdk-go/pkg/api"
 	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
@@ -21,9 +27,13 @@ const skillFileName = "SKILL.md"
 var errInvalidSkillYAML = errors.New("invalid skill YAML frontmatter")
 
 type skillFrontmatter struct {
-	Name        string   `yaml:"name"`
-	Description string   `yaml:"description"`
-	Keywords    []string `yaml:"keywords"`
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Keywords    []string    `yaml:"keywords"`
+	Parameters  []Parameter `yaml:"parameters"`
+	Type        string      `yaml:"type"`    // "prompt" (default) or "command"
+	Run         string      `yaml:"run"`     // command skills: executable and arguments
+	Timeout     int         `yaml:"timeout"` // command skills: seconds
 }
 
 func LoadSkills(skillDir string) ([]api.SkillRegistration, error) {
@@ -52,15 +62,21 @@ func LoadSkills(skillDir string) ([]api.SkillRegistration, error) {
 		return entries[i].Name() < entries[j].Name()
 	})
 
-	registrations := make([]api.SkillRegistration, 0, len(entries))
-	seen := make(map[string]string, len(entries))
+	var paths []string
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		// Dot directories hold installs in progress.
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
+		paths = append(paths, filepath.Join(skillDir, entry.Name(), skillFileName))
+	}
+	loaded := loadSkillFiles(paths)
+	pruneSkillCache(skillDir, paths)
 
-		skillPath := filepath.Join(skillDir, entry.Name(), skillFileName)
-		reg, skip, parseErr := parseSkillFile(skillPath)
+	registrations := make([]api.SkillRegistration, 0, len(paths))
+	seen := make(map[string]string, len(paths))
+	for i, skillPath := range paths {
+		reg, skip, parseErr := loaded[i].reg, loaded[i].skip, loaded[i].err
 		if parseErr != nil {
 			return nil, parseErr
 		}
@@ -78,6 +94,159 @@ func LoadSkills(skillDir string) ([]api.SkillRegistration, error) {
 	return registrations, nil
 }
 
+type loadedSkill struct {
+	reg  api.SkillRegistration
+	skip bool
+	err  error
+}
+
+// loadSkillFiles loads the skill files at paths, several at once, and
+// returns what each gave in the same order.
+func loadSkillFiles(paths []string) []loadedSkill {
+	loaded := make([]loadedSkill, len(paths))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				l := &loaded[i]
+				l.reg, l.skip, l.err = loadSkillFile(paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return loaded
+}
+
+// skillCache holds parsed skill files by path, so that loading skills
+// again, as the CLI does before building a runtime or the gateway on every
+// skill change, only parses the files that changed since.
+var skillCache = struct {
+	sync.Mutex
+	entries map[string]cachedSkill
+}{entries: make(map[string]cachedSkill)}
+
+type cachedSkill struct {
+	modTime time.Time
+	size    int64
+	reg     api.SkillRegistration
+	skip    bool
+}
+
+// pruneSkillCache drops the cached skills of skillDir that are not at paths,
+// such as those of removed skill folders.
+func pruneSkillCache(skillDir string, paths []string) {
+	skillCache.Lock()
+	defer skillCache.Unlock()
+	for path := range skillCache.entries {
+		if filepath.Dir(filepath.Dir(path)) == filepath.Clean(skillDir) && !slices.Contains(paths, path) {
+			delete(skillCache.entries, path)
+		}
+	}
+}
+
+// loadSkillFile is parseSkillFile, cached until the file's modification
+// time or size changes. Errors are not cached.
+func loadSkillFile(path string) (api.SkillRegistration, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		skillCache.Lock()
+		delete(skillCache.entries, path)
+		skillCache.Unlock()
+		return parseSkillFile(path)
+	}
+	skillCache.Lock()
+	cached, ok := skillCache.entries[path]
+	skillCache.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.reg, cached.skip, nil
+	}
+
+	reg, skip, err := parseSkillFile(path)
+	if err != nil {
+		return reg, skip, err
+	}
+	skillCache.Lock()
+	skillCache.entries[path] = cachedSkill{modTime: info.ModTime(), size: info.Size(), reg: reg, skip: skip}
+	skillCache.Unlock()
+	return reg, skip, nil
+}
+
+// LoadSkillDirs loads the skills of each directory in turn. A skill in a
+// later directory replaces one of the same name from an earlier one, so a
+// project can override a workspace skill.
+func LoadSkillDirs(dirs ...string) ([]api.SkillRegistration, error) {
+	var registrations []api.SkillRegistration
+	for _, dir := range dirs {
+		loaded, err := LoadSkills(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, reg := range loaded {
+			registrations = slices.DeleteFunc(registrations, func(r api.SkillRegistration) bool {
+				return r.Definition.Name == reg.Definition.Name
+			})
+			registrations = append(registrations, reg)
+		}
+	}
+	return registrations, nil
+}
+
+// IsDisabled reports whether name is in the disabled list, ignoring case.
+func IsDisabled(name string, disabled []string) bool {
+	return hasName(disabled, name)
+}
+
+func hasName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(strings.TrimSpace(n), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterDisabled returns the registrations whose names are not in disabled.
+func FilterDisabled(registrations []api.SkillRegistration, disabled []string) []api.SkillRegistration {
+	if len(disabled) == 0 {
+		return registrations
+	}
+	out := make([]api.SkillRegistration, 0, len(registrations))
+	for _, reg := range registrations {
+		if IsDisabled(reg.Definition.Name, disabled) {
+			continue
+		}
+		out = append(out, reg)
+	}
+	return out
+}
+
+// Scope returns the registrations a channel may use: those named in allow
+// (all of them when allow is empty) minus those named in deny. Names match
+// ignoring case.
+func Scope(registrations []api.SkillRegistration, allow, deny []string) []api.SkillRegistration {
+	if len(allow) == 0 && len(deny) == 0 {
+		return registrations
+	}
+	out := make([]api.SkillRegistration, 0, len(registrations))
+	for _, reg := range registrations {
+		if len(allow) > 0 && !hasName(allow, reg.Definition.Name) {
+			continue
+		}
+		if hasName(deny, reg.Definition.Name) {
+			continue
+		}
+		out = append(out, reg)
+	}
+	return out
+}
+
 func parseSkillFile(path string) (api.SkillRegistration, bool, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -99,11 +268,44 @@ func parseSkillFile(path string) (api.SkillRegistration, bool, error) {
 		return api.SkillRegistration{}, false, fmt.Errorf("parse skill %q: missing name", path)
 	}
 
+	params, err := normalizeParameters(meta.Parameters)
+	if err != nil {
+		return api.SkillRegistration{}, false, fmt.Errorf("parse skill %q: %w", path, err)
+	}
+
 	body = strings.TrimSpace(body)
 	def := runtimeskills.Definition{
 		Name:        strings.TrimSpace(meta.Name),
 		Description: strings.TrimSpace(meta.Description),
 	}
+	def.Metadata = map[string]string{}
+	if len(params) > 0 {
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return api.SkillRegistration{}, false, fmt.Errorf("parse skill %q: encode parameters: %w", path, err)
+		}
+		def.Metadata[parametersMetadataKey] = string(encoded)
+	}
+	switch kind := strings.ToLower(strings.TrimSpace(meta.Type)); kind {
+	case "", "prompt":
+	case "command":
+		dir := filepath.Dir(path)
+		if err := checkCommand(dir, meta.Run); err != nil {
+			return api.SkillRegistration{}, false, fmt.Errorf("parse skill %q: %w", path, err)
+		}
+		def.Metadata[typeMetadataKey] = kind
+		def.Metadata[runMetadataKey] = strings.TrimSpace(meta.Run)
+		def.Metadata[dirMetadataKey] = dir
+		if meta.Timeout > 0 {
+			def.Metadata[timeoutMetadataKey] = strconv.Itoa(meta.Timeout)
+		}
+	default:
+		return api.SkillRegistration{}, false, fmt.Errorf("parse skill %q: unknown type %q", path, meta.Type)
+	}
+	if len(def.Metadata) == 0 {
+		def.Metadata = nil
+	}
+	isCommand := def.Metadata[typeMetadataKey] == "command"
 
 	keywords := sanitizeKeywords(meta.Keywords)
 	if len(keywords) > 0 {
@@ -112,12 +314,28 @@ func parseSkillFile(path string) (api.SkillRegistration, bool, error) {
 		}
 	}
 
-	handler := runtimeskills.HandlerFunc(func(context.Context, runtimeskills.ActivationContext) (runtimeskills.Result, error) {
+	handler := runtimeskills.HandlerFunc(func(_ context.Context, ac runtimeskills.ActivationContext) (runtimeskills.Result, error) {
+		output := body
+		if isCommand {
+			output = strings.TrimSpace(body + "\n\n" + commandNote(def.Name, params))
+		} else if len(params) > 0 {
+			// Values come from the skill's parameter tool; a keyword
+			// activation only has the defaults.
+			if args, ok := ac.Metadata[ArgumentsKey].(map[string]any); ok {
+				values, err := ResolveArguments(params, args)
+				if err != nil {
+					return runtimeskills.Result{}, fmt.Errorf("skill %s: %w", def.Name, err)
+				}
+				output = renderBody(body, values)
+			} else {
+				output = renderBody(body, defaultValues(params)) + "\n\n" + parameterNote(def.Name, params)
+			}
+		}
 		return runtimeskills.Result{
 			Skill:  def.Name,
-			Output: body,
+			Output: output,
 			Metadata: map[string]any{
-				"system_prompt": body,
+				"system_prompt": output,
 				"source_path":   path,
 			},
 		}, nil
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
 )
@@ -153,6 +154,79 @@ func TestLoadSkills_MultipleSkills(t *testing.T) {
 	}
 }
 
+func TestLoadSkills_Cache(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for _, name := range []string{"alpha", "beta", "gamma", "delta", "epsilon"} {
+		writeTestSkillFile(t, root, name, "---\nname: "+name+"\ndescription: first\n---\nbody\n")
+	}
+	path := filepath.Join(root, "beta", skillFileName)
+	description := func() string {
+		t.Helper()
+		registrations, err := LoadSkills(root)
+		if err != nil || len(registrations) != 5 {
+			t.Fatalf("load skills = %d, %v", len(registrations), err)
+		}
+		return registrations[1].Definition.Description
+	}
+	if got := description(); got != "first" {
+		t.Fatalf("description = %q", got)
+	}
+
+	// Rewritten with the same size and modification time: still cached.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("---\nname: beta\ndescription: other\n---\nbody\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+	if got := description(); got != "first" {
+		t.Errorf("unchanged file parsed again: %q", got)
+	}
+
+	later := info.ModTime().Add(time.Second)
+	os.Chtimes(path, later, later)
+	if got := description(); got != "other" {
+		t.Errorf("changed file not parsed again: %q", got)
+	}
+
+	os.RemoveAll(filepath.Dir(path))
+	if registrations, err := LoadSkills(root); err != nil || len(registrations) != 4 {
+		t.Errorf("after removal = %d, %v", len(registrations), err)
+	}
+	skillCache.Lock()
+	_, cached := skillCache.entries[path]
+	skillCache.Unlock()
+	if cached {
+		t.Error("removed skill still cached")
+	}
+}
+
+func TestLoadSkillDirs_LaterDirWins(t *testing.T) {
+	t.Parallel()
+
+	workspace, project := t.TempDir(), t.TempDir()
+	writeTestSkillFile(t, workspace, "alpha", "---\nname: alpha\ndescription: workspace alpha\n---\nalpha body\n")
+	writeTestSkillFile(t, workspace, "beta", "---\nname: beta\ndescription: beta helper\n---\nbeta body\n")
+	writeTestSkillFile(t, project, "alpha", "---\nname: alpha\ndescription: project alpha\n---\nalpha body\n")
+	writeTestSkillFile(t, project, "gamma", "---\nname: gamma\ndescription: gamma helper\n---\ngamma body\n")
+
+	registrations, err := LoadSkillDirs(workspace, project, filepath.Join(project, "missing"), "")
+	if err != nil {
+		t.Fatalf("load skills: %v", err)
+	}
+	got := map[string]string{}
+	for _, reg := range registrations {
+		got[reg.Definition.Name] = reg.Definition.Description
+	}
+	if len(registrations) != 3 || got["alpha"] != "project alpha" || got["beta"] == "" || got["gamma"] == "" {
+		t.Fatalf("registrations = %v", got)
+	}
+}
+
 func TestLoadSkills_KeywordMatching(t *testing.T) {
 	t.Parallel()
 
@@ -268,3 +342,27 @@ func writeTestSkillFile(t *testing.T, root, dirName, content string) string {
 	}
 	return skillPath
 }
+
+func TestFilterDisabled(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for _, name := range []string{"writer", "noisy"} {
+		writeSourceSkill(t, filepath.Join(root, name), name, name+" skill")
+	}
+	registrations, err := LoadSkills(root)
+	if err != nil {
+		t.Fatalf("load skills: %v", err)
+	}
+
+	enabled := FilterDisabled(registrations, []string{" Noisy "})
+	if len(enabled) != 1 || enabled[0].Definition.Name != "writer" {
+		t.Fatalf("enabled = %+v", enabled)
+	}
+	if !IsDisabled("noisy", []string{"NOISY"}) || IsDisabled("writer", []string{"noisy"}) {
+		t.Error("IsDisabled mismatch")
+	}
+	if got := FilterDisabled(registrations, nil); len(got) != 2 {
+		t.Errorf("nil disabled list filtered to %d", len(got))
+	}
+}
@@ -0,0 +1,241 @@
+package skills
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// parametersMetadataKey holds a skill's parameters, JSON-encoded, in its
+// definition metadata.
+const parametersMetadataKey = "parameters"
+
+// ArgumentsKey is the activation metadata key under which a skill's
+// handler looks for parameter values.
+const ArgumentsKey = "skill_arguments"
+
+// ParameterToolPrefix prefixes the tool registered for each command skill
+// and each skill that declares parameters.
+const ParameterToolPrefix = "skill_"
+
+var parameterTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"array":   true,
+}
+
+var parameterNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Parameter is a typed value a skill declares in its frontmatter. The
+// skill body refers to it as {{name}}. A parameter without a default is
+// required.
+type Parameter struct {
+	Name        string `yaml:"name" json:"name"`
+	Type        string `yaml:"type" json:"type"`
+	Default     any    `yaml:"default" json:"default,omitempty"`
+	Description string `yaml:"description" json:"description,omitempty"`
+}
+
+// Required reports whether the parameter has no default.
+func (p Parameter) Required() bool {
+	return p.Default == nil
+}
+
+// normalizeParameters trims and checks declared parameters: names must be
+// identifiers and unique, types must be known, and defaults must match
+// their type.
+func normalizeParameters(params []Parameter) ([]Parameter, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	out := make([]Parameter, 0, len(params))
+	seen := make(map[string]bool, len(params))
+	for _, p := range params {
+		p.Name = strings.TrimSpace(p.Name)
+		p.Type = strings.ToLower(strings.TrimSpace(p.Type))
+		p.Description = strings.TrimSpace(p.Description)
+		if !parameterNamePattern.MatchString(p.Name) {
+			return nil, fmt.Errorf("invalid parameter name %q", p.Name)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("duplicate parameter %q", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Type == "" {
+			p.Type = "string"
+		}
+		if !parameterTypes[p.Type] {
+			return nil, fmt.Errorf("parameter %q: unsupported type %q", p.Name, p.Type)
+		}
+		if p.Default != nil {
+			if err := (tool.DefaultValidator{}).Validate(map[string]any{p.Name: p.Default}, ParameterSchema([]Parameter{p})); err != nil {
+				return nil, fmt.Errorf("parameter %q: default: %w", p.Name, err)
+			}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// ParameterSchema returns the JSON schema for params.
+func ParameterSchema(params []Parameter) *tool.JSONSchema {
+	schema := &tool.JSONSchema{Type: "object", Properties: map[string]any{}, Required: []string{}}
+	for _, p := range params {
+		prop := map[string]any{"type": p.Type}
+		if p.Description != "" {
+			prop["description"] = p.Description
+		}
+		if p.Default != nil {
+			prop["default"] = p.Default
+		}
+		if p.Type == "array" {
+			prop["items"] = map[string]any{"type": "string"}
+		}
+		schema.Properties[p.Name] = prop
+		if p.Required() {
+			schema.Required = append(schema.Required, p.Name)
+		}
+	}
+	return schema
+}
+
+// Parameters returns the parameters a skill declares.
+func Parameters(def runtimeskills.Definition) []Parameter {
+	raw := def.Metadata[parametersMetadataKey]
+	if raw == "" {
+		return nil
+	}
+	var params []Parameter
+	if err := json.Unmarshal([]byte(raw), &params); err != nil {
+		return nil
+	}
+	return params
+}
+
+// ResolveArguments validates args against params and fills in defaults.
+// Unknown arguments are rejected so that typos do not pass silently.
+func ResolveArguments(params []Parameter, args map[string]any) (map[string]any, error) {
+	known := make(map[string]bool, len(params))
+	for _, p := range params {
+		known[p.Name] = true
+	}
+	for name := range args {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	if err := (tool.DefaultValidator{}).Validate(args, ParameterSchema(params)); err != nil {
+		return nil, err
+	}
+	values := make(map[string]any, len(params))
+	for _, p := range params {
+		if v, ok := args[p.Name]; ok {
+			values[p.Name] = v
+		} else if p.Default != nil {
+			values[p.Name] = p.Default
+		}
+	}
+	return values, nil
+}
+
+func defaultValues(params []Parameter) map[string]any {
+	values := make(map[string]any, len(params))
+	for _, p := range params {
+		if p.Default != nil {
+			values[p.Name] = p.Default
+		}
+	}
+	return values
+}
+
+// renderBody replaces {{name}} placeholders with values. Placeholders
+// without a value are left as they are.
+func renderBody(body string, values map[string]any) string {
+	if len(values) == 0 {
+		return body
+	}
+	pairs := make([]string, 0, len(values)*2)
+	for name, v := range values {
+		pairs = append(pairs, "{{"+name+"}}", formatValue(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(body)
+}
+
+func formatValue(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case []any:
+		parts := make([]string, len(value))
+		for i, item := range value {
+			parts[i] = formatValue(item)
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// parameterNote tells the model how to fill a skill's parameters.
+func parameterNote(skill string, params []Parameter) string {
+	return "This skill takes parameters. Call the " + ParameterToolPrefix + skill +
+		" tool with values from the conversation to get these instructions filled in:\n" + parameterList(params)
+}
+
+// parameterList describes params, one per line.
+func parameterList(params []Parameter) string {
+	var b strings.Builder
+	for _, p := range params {
+		fmt.Fprintf(&b, "- %s (%s", p.Name, p.Type)
+		if p.Required() {
+			b.WriteString(", required")
+		} else {
+			fmt.Fprintf(&b, ", default %s", formatValue(p.Default))
+		}
+		b.WriteString(")")
+		if p.Description != "" {
+			b.WriteString(": " + p.Description)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+type parameterTool struct {
+	def     runtimeskills.Definition
+	params  []Parameter
+	handler runtimeskills.Handler
+}
+
+func (t *parameterTool) Name() string { return ParameterToolPrefix + t.def.Name }
+
+func (t *parameterTool) Description() string {
+	desc := "Get the instructions of the " + t.def.Name + " skill with its parameters filled in."
+	if t.def.Description != "" {
+		desc += " " + t.def.Description
+	}
+	return desc
+}
+
+func (t *parameterTool) Schema() *tool.JSONSchema { return ParameterSchema(t.params) }
+
+func (t *parameterTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	if params == nil {
+		params = map[string]any{}
+	}
+	res, err := t.handler.Execute(ctx, runtimeskills.ActivationContext{
+		Metadata: map[string]any{ArgumentsKey: params},
+	})
+	if err != nil {
+		return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}, nil
+	}
+	text, _ := res.Output.(string)
+	return &tool.ToolResult{Success: true, Output: text}, nil
+}
@@ -0,0 +1,102 @@
+package skills
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
+)
+
+const paramSkill = `---
+name: translator
+description: translate text
+keywords: [translate]
+parameters:
+  - name: language
+    type: string
+    description: Target language
+  - name: formal
+    type: boolean
+    default: false
+  - name: max_words
+    type: integer
+    default: 200
+---
+Translate into {{language}}. Formal: {{formal}}. At most {{max_words}} words.
+`
+
+func TestLoadSkills_Parameters(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeTestSkillFile(t, root, "translator", paramSkill)
+	registrations, err := LoadSkills(root)
+	if err != nil {
+		t.Fatalf("load skills: %v", err)
+	}
+	params := Parameters(registrations[0].Definition)
+	if len(params) != 3 || params[0].Name != "language" || !params[0].Required() || params[1].Required() {
+		t.Fatalf("parameters = %+v", params)
+	}
+	schema := ParameterSchema(params)
+	if len(schema.Required) != 1 || schema.Required[0] != "language" || len(schema.Properties) != 3 {
+		t.Fatalf("schema = %+v", schema)
+	}
+
+	// A keyword activation fills defaults and explains how to supply the rest.
+	res, err := registrations[0].Handler.Execute(context.Background(), runtimeskills.ActivationContext{Prompt: "translate this"})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	out := res.Output.(string)
+	if !strings.Contains(out, "Translate into {{language}}. Formal: false. At most 200 words.") {
+		t.Errorf("keyword output = %q", out)
+	}
+	if !strings.Contains(out, "skill_translator") || !strings.Contains(out, "language (string, required): Target language") {
+		t.Errorf("expected parameter note, got %q", out)
+	}
+
+	tools := Tools(registrations, CommandOptions{})
+	if len(tools) != 1 || tools[0].Name() != "skill_translator" {
+		t.Fatalf("tools = %v", tools)
+	}
+	result, err := tools[0].Execute(context.Background(), map[string]any{"language": "French", "max_words": float64(50)})
+	if err != nil || !result.Success {
+		t.Fatalf("tool execute: %+v, %v", result, err)
+	}
+	if result.Output != "Translate into French. Formal: false. At most 50 words." {
+		t.Errorf("tool output = %q", result.Output)
+	}
+
+	for _, args := range []map[string]any{
+		{},
+		{"language": 3},
+		{"language": "French", "tone": "dry"},
+	} {
+		result, err := tools[0].Execute(context.Background(), args)
+		if err != nil || result.Success {
+			t.Errorf("args %v: expected failed result, got %+v, %v", args, result, err)
+		}
+	}
+}
+
+func TestLoadSkills_InvalidParameters(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"bad type":      "  - name: n\n    type: date\n",
+		"bad default":   "  - name: n\n    type: integer\n    default: many\n",
+		"bad name":      "  - name: not valid\n",
+		"duplicate":     "  - name: n\n  - name: n\n",
+		"missing name":  "  - type: string\n",
+		"array default": "  - name: n\n    type: array\n    default: [1, 2]\n",
+	}
+	for name, params := range cases {
+		root := t.TempDir()
+		writeTestSkillFile(t, root, "broken", "---\nname: broken\nparameters:\n"+params+"---\nbody\n")
+		if _, err := LoadSkills(root); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
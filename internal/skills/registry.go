@@ -0,0 +1,207 @@
+package skills
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceRegistry marks skills installed by name from a registry index.
+const SourceRegistry = "registry"
+
+// maxIndexSize caps a downloaded registry index.
+const maxIndexSize = 10 << 20
+
+var registryNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*(@[A-Za-z0-9._+-]+)?$`)
+
+// Index is a skill registry: a JSON document served over HTTPS that lists
+// skills and the archives of their versions.
+type Index struct {
+	Skills []IndexEntry `json:"skills"`
+}
+
+// IndexEntry is one skill in a registry.
+type IndexEntry struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Versions    []IndexVersion `json:"versions"`
+}
+
+// IndexVersion is a published version of a skill. URL points to a
+// .tar.gz/.tgz/.tar archive whose SHA-256 must equal SHA256.
+type IndexVersion struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
+}
+
+// Latest returns the highest version of the skill.
+func (e IndexEntry) Latest() *IndexVersion {
+	var latest *IndexVersion
+	for i := range e.Versions {
+		if latest == nil || compareVersions(e.Versions[i].Version, latest.Version) > 0 {
+			latest = &e.Versions[i]
+		}
+	}
+	return latest
+}
+
+// isRegistryName reports whether source names a registry skill, optionally
+// pinned as name@version, rather than something on disk.
+func isRegistryName(source string) bool {
+	if !registryNamePattern.MatchString(source) {
+		return false
+	}
+	_, err := os.Stat(source)
+	return err != nil
+}
+
+// FetchIndex downloads and decodes the registry index at url.
+func FetchIndex(url string) (*Index, error) {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return nil, errors.New("no skill registry configured; set skills.registry")
+	}
+	if !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("skill registry %s: must be an https URL", url)
+	}
+	data, err := download(url, maxIndexSize)
+	if err != nil {
+		return nil, err
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("decode registry index %s: %w", url, err)
+	}
+	return &idx, nil
+}
+
+// Lookup finds a skill in the index. ref is name or name@version; without
+// a version the latest is returned.
+func (idx *Index) Lookup(ref string) (*IndexEntry, *IndexVersion, error) {
+	name, version, _ := strings.Cut(ref, "@")
+	for i := range idx.Skills {
+		entry := &idx.Skills[i]
+		if entry.Name != name {
+			continue
+		}
+		if version == "" {
+			if latest := entry.Latest(); latest != nil {
+				return entry, latest, nil
+			}
+			return nil, nil, fmt.Errorf("registry skill %s has no versions", name)
+		}
+		for j := range entry.Versions {
+			if entry.Versions[j].Version == strings.TrimPrefix(version, "v") || entry.Versions[j].Version == version {
+				return entry, &entry.Versions[j], nil
+			}
+		}
+		return nil, nil, fmt.Errorf("registry skill %s has no version %s", name, version)
+	}
+	return nil, nil, fmt.Errorf("skill %s not found in registry", name)
+}
+
+// fetchRegistry resolves ref in the registry at url, downloads the
+// archive, checks its SHA-256 and unpacks it into dest. It returns the
+// version installed.
+func fetchRegistry(url, ref, dest string) (string, error) {
+	idx, err := FetchIndex(url)
+	if err != nil {
+		return "", err
+	}
+	_, version, err := idx.Lookup(ref)
+	if err != nil {
+		return "", err
+	}
+	if version.SHA256 == "" {
+		return "", fmt.Errorf("registry skill %s@%s has no sha256", strings.Split(ref, "@")[0], version.Version)
+	}
+	data, err := download(version.URL, maxArchiveSize)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, version.SHA256) {
+		return "", fmt.Errorf("checksum mismatch for %s: got sha256 %s, registry lists %s", version.URL, got, version.SHA256)
+	}
+	gzipped := !strings.HasSuffix(strings.ToLower(version.URL), ".tar")
+	if err := extractTar(bytes.NewReader(data), dest, gzipped); err != nil {
+		return "", fmt.Errorf("extract %s: %w", version.URL, err)
+	}
+	return version.Version, nil
+}
+
+func download(url string, limit int64) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("download %s: larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// compareVersions orders dotted versions numerically, so 1.10.0 sorts
+// after 1.9.0. A leading v is ignored and non-numeric parts compare as
+// strings.
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		x, y := "0", "0"
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		nx, errX := strconv.Atoi(x)
+		ny, errY := strconv.Atoi(y)
+		switch {
+		case errX == nil && errY == nil:
+			if nx != ny {
+				if nx < ny {
+					return -1
+				}
+				return 1
+			}
+		case x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
+
+// Search returns the index entries whose name or description contains
+// query, ignoring case, sorted by name.
+func (idx *Index) Search(query string) []IndexEntry {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var out []IndexEntry
+	for _, entry := range idx.Skills {
+		if query == "" || strings.Contains(strings.ToLower(entry.Name), query) ||
+			strings.Contains(strings.ToLower(entry.Description), query) {
+			out = append(out, entry)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
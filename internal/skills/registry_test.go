@@ -0,0 +1,134 @@
+package skills
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveRegistry serves an index over HTTPS with writer 1.2.0 and 1.10.0,
+// and points the package HTTP client at it for the rest of the test.
+func serveRegistry(t *testing.T, tamper bool) string {
+	t.Helper()
+	archives := map[string][]byte{
+		"/writer-1.2.0.tar.gz":  tarGz(t, map[string]string{"writer/SKILL.md": "---\nname: writer\ndescription: v1.2\n---\nUse me.\n"}),
+		"/writer-1.10.0.tar.gz": tarGz(t, map[string]string{"writer/SKILL.md": "---\nname: writer\ndescription: v1.10\n---\nUse me.\n"}),
+	}
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.json" {
+			var versions []IndexVersion
+			for _, v := range []string{"1.2.0", "1.10.0"} {
+				path := "/writer-" + v + ".tar.gz"
+				sum := sha256.Sum256(archives[path])
+				if tamper {
+					sum[0] ^= 0xff
+				}
+				versions = append(versions, IndexVersion{Version: v, URL: srv.URL + path, SHA256: hex.EncodeToString(sum[:])})
+			}
+			json.NewEncoder(w).Encode(Index{Skills: []IndexEntry{
+				{Name: "writer", Description: "writing helper", Versions: versions},
+				{Name: "translator", Description: "translate text"},
+			}})
+			return
+		}
+		if data, ok := archives[r.URL.Path]; ok {
+			w.Write(data)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	prev := httpClient
+	httpClient = srv.Client()
+	t.Cleanup(func() { httpClient = prev })
+	return srv.URL + "/index.json"
+}
+
+func TestRegistryInstall(t *testing.T) {
+	registry := serveRegistry(t, false)
+	skillDir := filepath.Join(t.TempDir(), "skills")
+
+	if got := SourceKind("writer@1.2.0"); got != SourceRegistry {
+		t.Fatalf("SourceKind(writer@1.2.0) = %q", got)
+	}
+
+	idx, err := FetchIndex(registry)
+	if err != nil {
+		t.Fatalf("fetch index: %v", err)
+	}
+	if found := idx.Search("TRANSLATE"); len(found) != 1 || found[0].Name != "translator" {
+		t.Errorf("search = %+v", found)
+	}
+
+	// Pinned install stays pinned across updates.
+	inst, err := Install(skillDir, "writer@1.2.0", InstallOptions{Registry: registry})
+	if err != nil {
+		t.Fatalf("install pinned: %v", err)
+	}
+	if inst.Kind != SourceRegistry || inst.Version != "1.2.0" || inst.Description != "v1.2" || inst.Registry != registry {
+		t.Fatalf("installed = %+v", inst)
+	}
+	if inst, err = Update(skillDir, "writer"); err != nil || inst.Version != "1.2.0" {
+		t.Fatalf("update pinned = %+v, %v", inst, err)
+	}
+
+	// Unpinned picks the highest version, comparing numerically.
+	inst, err = Install(skillDir, "writer", InstallOptions{Registry: registry, Force: true})
+	if err != nil || inst.Version != "1.10.0" {
+		t.Fatalf("install latest = %+v, %v", inst, err)
+	}
+
+	for source, want := range map[string]string{
+		"writer@9.9.9": "no version 9.9.9",
+		"missing":      "not found in registry",
+		"translator":   "has no versions",
+	} {
+		if _, err := Install(skillDir, source, InstallOptions{Registry: registry, Force: true}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("install %s: err = %v, want %q", source, err, want)
+		}
+	}
+	if _, err := Install(skillDir, "writer", InstallOptions{}); err == nil || !strings.Contains(err.Error(), "skills.registry") {
+		t.Errorf("install without registry: err = %v", err)
+	}
+	if _, err := FetchIndex("http://example.com/index.json"); err == nil {
+		t.Error("expected plain http registry to be refused")
+	}
+}
+
+func TestRegistryInstall_ChecksumMismatch(t *testing.T) {
+	registry := serveRegistry(t, true)
+	skillDir := filepath.Join(t.TempDir(), "skills")
+	_, err := Install(skillDir, "writer", InstallOptions{Registry: registry})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("err = %v, want checksum mismatch", err)
+	}
+	if regs, _ := LoadSkills(skillDir); len(regs) != 0 {
+		t.Errorf("nothing should be installed, got %d skills", len(regs))
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.0", 1},
+		{"1.0", "1.0.0", 0},
+		{"v2.0.0", "1.99", 1},
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+	}
+	for _, c := range cases {
+		if got := compareVersions(c.a, c.b); got != c.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
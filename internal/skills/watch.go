@@ -0,0 +1,92 @@
+package skills
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchDebounce groups the burst of events an editor or git checkout
+// produces into one reload.
+const DefaultWatchDebounce = 500 * time.Millisecond
+
+// Watch calls onChange whenever a SKILL.md under skillDir is written,
+// created, removed or renamed, or a skill directory comes or goes. Events
+// within debounce of each other trigger a single call. It blocks until ctx
+// is done.
+func Watch(ctx context.Context, skillDir string, debounce time.Duration, onChange func()) error {
+	if err := os.MkdirAll(skillDir, 0o755); err != nil {
+		return fmt.Errorf("create skills dir: %w", err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create skills watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// fsnotify is not recursive: watch the skills dir and each skill dir.
+	if err := watcher.Add(skillDir); err != nil {
+		return fmt.Errorf("watch %s: %w", skillDir, err)
+	}
+	entries, _ := os.ReadDir(skillDir)
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			_ = watcher.Add(filepath.Join(skillDir, entry.Name()))
+		}
+	}
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("[skills] watcher error: %v", err)
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !relevantEvent(skillDir, ev) {
+				continue
+			}
+			if ev.Has(fsnotify.Create) && filepath.Dir(ev.Name) == filepath.Clean(skillDir) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					_ = watcher.Add(ev.Name)
+				}
+			}
+			timer.Reset(debounce)
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
+
+// relevantEvent reports whether ev can change the loaded skills: a change
+// to a SKILL.md, or a skill directory appearing or disappearing. Dot
+// directories, used for installs in progress, are ignored.
+func relevantEvent(skillDir string, ev fsnotify.Event) bool {
+	rel, err := filepath.Rel(skillDir, ev.Name)
+	if err != nil || strings.HasPrefix(rel, ".") {
+		return false
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	switch len(parts) {
+	case 1:
+		return ev.Has(fsnotify.Create) || ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename)
+	case 2:
+		return parts[1] == skillFileName && ev.Op&^fsnotify.Chmod != 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,58 @@
+package skills
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeSourceSkill(t, filepath.Join(root, "writer"), "writer", "v1")
+
+	changes := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, root, 20*time.Millisecond, func() { changes <- struct{}{} })
+	}()
+	time.Sleep(100 * time.Millisecond) // let the watcher start
+
+	expect := func(what string, want bool) {
+		t.Helper()
+		select {
+		case <-changes:
+			if !want {
+				t.Errorf("%s: unexpected reload", what)
+			}
+		case <-time.After(500 * time.Millisecond):
+			if want {
+				t.Errorf("%s: no reload", what)
+			}
+		}
+	}
+
+	writeSourceSkill(t, filepath.Join(root, "writer"), "writer", "v2")
+	expect("edit SKILL.md", true)
+
+	os.WriteFile(filepath.Join(root, "writer", "notes.txt"), []byte("x"), 0o644)
+	os.MkdirAll(filepath.Join(root, ".install-123"), 0o755)
+	expect("unrelated files", false)
+
+	writeSourceSkill(t, filepath.Join(root, "translator"), "translator", "new")
+	expect("new skill", true)
+	writeSourceSkill(t, filepath.Join(root, "translator"), "translator", "edited")
+	expect("edit in new skill dir", true)
+
+	os.RemoveAll(filepath.Join(root, "writer"))
+	expect("remove skill", true)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch returned %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+// Package tasks keeps background tasks: long requests, such as "research X
+// and write a report", that the agent hands off to run on their own while
+// the chat goes on, and whose result is sent to the chat when they finish.
+package tasks
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// keepFinished is how long finished tasks are kept for their results.
+const keepFinished = 30 * 24 * time.Hour
+
+// Path returns where background tasks are kept for workspace.
+func Path(workspace string) string {
+	return filepath.Join(workspace, ".claude", "tasks.json")
+}
+
+// Status is where a task is in its life.
+type Status string
+
+const (
+	Queued   Status = "queued"
+	Running  Status = "running"
+	Done     Status = "done"
+	Failed   Status = "failed"
+	Canceled Status = "canceled"
+)
+
+// Task is a request the agent runs in the background for a chat.
+type Task struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Prompt       string `json:"prompt"`
+	Channel      string `json:"channel,omitempty"`
+	ChatID       string `json:"chatId,omitempty"`
+	Status       Status `json:"status"`
+	Result       string `json:"result,omitempty"`
+	Error        string `json:"error,omitempty"`
+	CreatedAtMs  int64  `json:"createdAtMs"`
+	StartedAtMs  int64  `json:"startedAtMs,omitempty"`
+	FinishedAtMs int64  `json:"finishedAtMs,omitempty"`
+}
+
+// Finished reports whether t is done, failed or canceled.
+func (t Task) Finished() bool {
+	return t.Status == Done || t.Status == Failed || t.Status == Canceled
+}
+
+// String describes t on one line, such as
+// "3f2a1b0c running  Research solar panels (started 10:02)".
+func (t Task) String() string {
+	var when string
+	switch {
+	case t.FinishedAtMs > 0:
+		when = "finished " + stamp(t.FinishedAtMs)
+	case t.StartedAtMs > 0:
+		when = "started " + stamp(t.StartedAtMs)
+	default:
+		when = "queued " + stamp(t.CreatedAtMs)
+	}
+	return fmt.Sprintf("%s %-8s %s (%s)", t.ID, t.Status, t.Title, when)
+}
+
+func stamp(ms int64) string {
+	at := time.UnixMilli(ms)
+	if now := time.Now(); at.Year() == now.Year() && at.YearDay() == now.YearDay() {
+		return at.Format("15:04")
+	}
+	return at.Format("Mon 2006-01-02 15:04")
+}
+
+// Store persists tasks as a JSON file. The file is re-read on every
+// operation so the CLI and a running gateway can share it.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Add queues prompt as a task for the chat. Finished tasks older than
+// keepFinished are dropped on the way.
+func (s *Store) Add(title, prompt, channel, chatID string, now time.Time) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return Task{}, err
+	}
+	cutoff := now.Add(-keepFinished).UnixMilli()
+	kept := list[:0]
+	for _, t := range list {
+		if !t.Finished() || t.FinishedAtMs >= cutoff {
+			kept = append(kept, t)
+		}
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	t := Task{
+		ID:          fmt.Sprintf("%x", b),
+		Title:       title,
+		Prompt:      prompt,
+		Channel:     channel,
+		ChatID:      chatID,
+		Status:      Queued,
+		CreatedAtMs: now.UnixMilli(),
+	}
+	if err := s.save(append(kept, t)); err != nil {
+		return Task{}, err
+	}
+	return t, nil
+}
+
+// Get returns task id and whether there is one.
+func (s *Store) Get(id string) (Task, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return Task{}, false, err
+	}
+	for _, t := range list {
+		if t.ID == id {
+			return t, true, nil
+		}
+	}
+	return Task{}, false, nil
+}
+
+// List returns the tasks of a chat, newest first. An empty channel lists
+// every task.
+func (s *Store) List(channel, chatID string) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var out []Task
+	for _, t := range list {
+		if channel == "" || (t.Channel == channel && t.ChatID == chatID) {
+			out = append(out, t)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAtMs > out[j].CreatedAtMs })
+	return out, nil
+}
+
+// Cancel marks task id canceled unless it has finished. With a channel,
+// only a task of that chat is canceled. It returns the task as it is and
+// whether there is one; a running task is stopped by the gateway, which
+// looks for canceled tasks while it runs them.
+func (s *Store) Cancel(id, channel, chatID string, now time.Time) (Task, bool, error) {
+	return s.update(id, func(t *Task) bool {
+		if channel != "" && (t.Channel != channel || t.ChatID != chatID) {
+			return false
+		}
+		if !t.Finished() {
+			t.Status = Canceled
+			t.FinishedAtMs = now.UnixMilli()
+		}
+		return true
+	})
+}
+
+// Next starts the oldest queued task and returns it, or reports false when
+// none is queued.
+func (s *Store) Next(now time.Time) (Task, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return Task{}, false, err
+	}
+	next := -1
+	for i, t := range list {
+		if t.Status == Queued && (next < 0 || t.CreatedAtMs < list[next].CreatedAtMs) {
+			next = i
+		}
+	}
+	if next < 0 {
+		return Task{}, false, nil
+	}
+	list[next].Status = Running
+	list[next].StartedAtMs = now.UnixMilli()
+	return list[next], true, s.save(list)
+}
+
+// Finish records how running task id ended: done with result, or failed
+// with runErr. It reports false, leaving the task as it is, when the task
+// is no longer running, such as when it was canceled meanwhile.
+func (s *Store) Finish(id, result string, runErr error, now time.Time) (Task, bool, error) {
+	return s.update(id, func(t *Task) bool {
+		if t.Status != Running {
+			return false
+		}
+		t.Status, t.Result = Done, result
+		if runErr != nil {
+			t.Status, t.Error = Failed, runErr.Error()
+		}
+		t.FinishedAtMs = now.UnixMilli()
+		return true
+	})
+}
+
+// Requeue puts the tasks left running, by a gateway that stopped while it
+// ran them, back in the queue, and returns how many there were.
+func (s *Store) Requeue() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for i := range list {
+		if list[i].Status == Running {
+			list[i].Status = Queued
+			list[i].StartedAtMs = 0
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save(list)
+}
+
+// update applies fn to task id and saves the list, unless fn turns the task
+// down by returning false. It returns the task and whether fn took it.
+func (s *Store) update(id string, fn func(*Task) bool) (Task, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return Task{}, false, err
+	}
+	for i := range list {
+		if list[i].ID != id {
+			continue
+		}
+		if !fn(&list[i]) {
+			return Task{}, false, nil
+		}
+		return list[i], true, s.save(list)
+	}
+	return Task{}, false, nil
+}
+
+func (s *Store) load() ([]Task, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Task
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	return list, nil
+}
+
+func (s *Store) save(list []Task) error {
+	if list == nil {
+		list = []Task{}
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,125 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(filepath.Join(t.TempDir(), "tasks.json"))
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+
+	a, err := store.Add("Solar research", "Research solar panels", "telegram", "1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := store.Add("Trip plan", "Plan a trip", "slack", "2", now.Add(time.Minute))
+	if a.Status != Queued || a.ID == "" || a.ID == b.ID {
+		t.Fatalf("added %+v, %+v", a, b)
+	}
+
+	next, ok, err := store.Next(now)
+	if err != nil || !ok || next.ID != a.ID || next.Status != Running {
+		t.Fatalf("Next = %+v, %v, %v", next, ok, err)
+	}
+	// A restart puts it back in the queue.
+	if n, err := store.Requeue(); err != nil || n != 1 {
+		t.Fatalf("Requeue = %d, %v", n, err)
+	}
+	if next, _, _ = store.Next(now); next.ID != a.ID {
+		t.Fatalf("Next after requeue = %+v", next)
+	}
+	done, ok, err := store.Finish(a.ID, "Panels are cheap.", nil, now.Add(time.Hour))
+	if err != nil || !ok || done.Status != Done || done.Result != "Panels are cheap." {
+		t.Fatalf("Finish = %+v, %v, %v", done, ok, err)
+	}
+	if _, ok, _ := store.Finish(a.ID, "again", nil, now); ok {
+		t.Error("Finish took a finished task")
+	}
+
+	// Only the chat that started a task can cancel it through the tools.
+	if _, ok, _ := store.Cancel(b.ID, "telegram", "1", now); ok {
+		t.Error("canceled another chat's task")
+	}
+	canceled, ok, err := store.Cancel(b.ID, "", "", now)
+	if err != nil || !ok || canceled.Status != Canceled {
+		t.Fatalf("Cancel = %+v, %v, %v", canceled, ok, err)
+	}
+	if _, ok, _ := store.Next(now); ok {
+		t.Error("Next started a canceled task")
+	}
+
+	list, err := store.List("telegram", "1")
+	if err != nil || len(list) != 1 || list[0].ID != a.ID {
+		t.Errorf("List = %+v, %v", list, err)
+	}
+	if list, _ = store.List("", ""); len(list) != 2 || list[0].ID != b.ID {
+		t.Errorf("List all = %+v", list)
+	}
+
+	// Finished tasks are dropped a month on.
+	if _, err := store.Add("Later", "Later", "telegram", "1", now.AddDate(0, 2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ = store.List("", ""); len(list) != 1 {
+		t.Errorf("List after a month = %+v", list)
+	}
+}
+
+func TestStore_FinishFailed(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(filepath.Join(t.TempDir(), "tasks.json"))
+	now := time.Now()
+	task, _ := store.Add("Report", "Write a report", "telegram", "1", now)
+	_, _, _ = store.Next(now)
+	failed, ok, err := store.Finish(task.ID, "", errors.New("model unavailable"), now)
+	if err != nil || !ok || failed.Status != Failed || failed.Error != "model unavailable" {
+		t.Fatalf("Finish = %+v, %v, %v", failed, ok, err)
+	}
+	if r := Report(failed); !strings.Contains(r, "failed: Report") || !strings.Contains(r, "model unavailable") {
+		t.Errorf("Report = %q", r)
+	}
+}
+
+func TestTools(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(filepath.Join(t.TempDir(), "tasks.json"))
+	tools := Tools(store)
+	start, list, cancel := tools[0], tools[1], tools[2]
+
+	if res, _ := start.Execute(context.Background(), map[string]any{"prompt": "Research X"}); res.Success {
+		t.Error("started a task outside a chat")
+	}
+	ctx := WithOrigin(context.Background(), "telegram", "1")
+	res, err := start.Execute(ctx, map[string]any{"title": "X research", "prompt": "Research X and write a report"})
+	if err != nil || !res.Success {
+		t.Fatalf("start = %+v, %v", res, err)
+	}
+	id := res.Data.(Task).ID
+
+	res, _ = list.Execute(ctx, map[string]any{})
+	if !res.Success || !strings.Contains(res.Output, id) || !strings.Contains(res.Output, "queued") {
+		t.Errorf("list = %+v", res)
+	}
+	other := WithOrigin(context.Background(), "slack", "2")
+	if res, _ = list.Execute(other, map[string]any{}); res.Output != "No background tasks." {
+		t.Errorf("list in another chat = %+v", res)
+	}
+	if res, _ = cancel.Execute(other, map[string]any{"id": id}); res.Success {
+		t.Error("canceled from another chat")
+	}
+	if res, _ = cancel.Execute(ctx, map[string]any{"id": id}); !res.Success {
+		t.Errorf("cancel = %+v", res)
+	}
+	if res, _ = list.Execute(ctx, map[string]any{"id": id}); !strings.Contains(res.Output, "was canceled") {
+		t.Errorf("list id = %+v", res)
+	}
+}
@@ -0,0 +1,190 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+type originKey struct{}
+
+type origin struct{ channel, chatID string }
+
+// WithOrigin marks ctx as a run for a message from chatID on channel, so
+// that tasks started during the run report back to that chat.
+func WithOrigin(ctx context.Context, channel, chatID string) context.Context {
+	return context.WithValue(ctx, originKey{}, origin{channel, chatID})
+}
+
+func originOf(ctx context.Context) (origin, error) {
+	o, ok := ctx.Value(originKey{}).(origin)
+	if !ok || o.channel == "" {
+		return origin{}, errors.New("background tasks can only be used from a chat")
+	}
+	return o, nil
+}
+
+// Tools returns the tools the agent manages background tasks with.
+func Tools(store *Store) []tool.Tool {
+	return []tool.Tool{
+		&startTool{store: store, now: time.Now},
+		&listTool{store: store},
+		&cancelTool{store: store, now: time.Now},
+	}
+}
+
+type startTool struct {
+	store *Store
+	now   func() time.Time
+}
+
+func (t *startTool) Name() string { return "start_background_task" }
+
+func (t *startTool) Description() string {
+	return "Run a long request, such as \"research X and write a report\", as a background task. " +
+		"It runs on its own, without this conversation, so the prompt must say everything the task needs; " +
+		"the result is sent to this chat when it is done. Tell the user the task id."
+}
+
+func (t *startTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"title":  map[string]any{"type": "string", "description": "A few words naming the task, e.g. \"Solar panel research\""},
+			"prompt": map[string]any{"type": "string", "description": "The full request, with all the context it needs"},
+		},
+		Required: []string{"title", "prompt"},
+	}
+}
+
+func (t *startTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	o, err := originOf(ctx)
+	if err != nil {
+		return failed(err), nil
+	}
+	title, prompt := stringParam(params, "title"), stringParam(params, "prompt")
+	if prompt == "" {
+		return failed(errors.New("prompt is required")), nil
+	}
+	if title == "" {
+		title = prompt
+	}
+	task, err := t.store.Add(title, prompt, o.channel, o.chatID, t.now())
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: fmt.Sprintf("Started background task %s: %s", task.ID, task.Title), Data: task}, nil
+}
+
+type listTool struct{ store *Store }
+
+func (t *listTool) Name() string { return "list_background_tasks" }
+
+func (t *listTool) Description() string {
+	return "List the background tasks of this chat with their status, newest first, and the result of one by id."
+}
+
+func (t *listTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"id": map[string]any{"type": "string", "description": "A task id, to get its result"},
+		},
+		Required: []string{},
+	}
+}
+
+func (t *listTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	o, err := originOf(ctx)
+	if err != nil {
+		return failed(err), nil
+	}
+	list, err := t.store.List(o.channel, o.chatID)
+	if err != nil {
+		return failed(err), nil
+	}
+	if id := stringParam(params, "id"); id != "" {
+		for _, task := range list {
+			if task.ID == id {
+				return &tool.ToolResult{Success: true, Output: Report(task), Data: task}, nil
+			}
+		}
+		return failed(fmt.Errorf("no background task %q in this chat", id)), nil
+	}
+	if len(list) == 0 {
+		return &tool.ToolResult{Success: true, Output: "No background tasks."}, nil
+	}
+	var b strings.Builder
+	for _, task := range list {
+		b.WriteString("- " + task.String() + "\n")
+	}
+	return &tool.ToolResult{Success: true, Output: b.String()}, nil
+}
+
+type cancelTool struct {
+	store *Store
+	now   func() time.Time
+}
+
+func (t *cancelTool) Name() string { return "cancel_background_task" }
+
+func (t *cancelTool) Description() string {
+	return "Cancel a queued or running background task of this chat by its id."
+}
+
+func (t *cancelTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"id": map[string]any{"type": "string", "description": "The task id"},
+		},
+		Required: []string{"id"},
+	}
+}
+
+func (t *cancelTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	o, err := originOf(ctx)
+	if err != nil {
+		return failed(err), nil
+	}
+	id := stringParam(params, "id")
+	task, ok, err := t.store.Cancel(id, o.channel, o.chatID, t.now())
+	if err != nil {
+		return failed(err), nil
+	}
+	if !ok {
+		return failed(fmt.Errorf("no background task %q in this chat", id)), nil
+	}
+	if task.Status != Canceled {
+		return failed(fmt.Errorf("background task %s is already %s", id, task.Status)), nil
+	}
+	return &tool.ToolResult{Success: true, Output: "Canceled background task " + id + "."}, nil
+}
+
+// Report describes task with its result or error, as sent to the chat when
+// it finishes.
+func Report(task Task) string {
+	switch task.Status {
+	case Done:
+		return fmt.Sprintf("✅ Background task %s finished: %s\n\n%s", task.ID, task.Title, task.Result)
+	case Failed:
+		return fmt.Sprintf("❌ Background task %s failed: %s\n\n%s", task.ID, task.Title, task.Error)
+	case Canceled:
+		return fmt.Sprintf("Background task %s was canceled: %s", task.ID, task.Title)
+	default:
+		return fmt.Sprintf("Background task %s is %s: %s", task.ID, task.Status, task.Title)
+	}
+}
+
+func stringParam(params map[string]any, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+func failed(err error) *tool.ToolResult {
+	return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}
+}
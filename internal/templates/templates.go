@@ -0,0 +1,215 @@
+// Package templates provides workspace starters: prompt files, skills and
+// example automations that `myclaw init` copies into a workspace.
+//
+// A template is a directory holding any of AGENTS.md, SOUL.md, HEARTBEAT.md,
+// skills/<name>/SKILL.md, an optional template.json ({"description": ...})
+// and an optional automations.json with example cron jobs.
+package templates
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/stellarlinkco/myclaw/internal/cron"
+)
+
+//go:embed all:builtin
+var builtinFS embed.FS
+
+const (
+	metaFile        = "template.json"
+	automationsFile = "automations.json"
+	skillsDir       = "skills"
+)
+
+// Info describes a template.
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Automation is an example cron job shipped with a template.
+type Automation struct {
+	Name     string        `json:"name"`
+	Schedule cron.Schedule `json:"schedule"`
+	Message  string        `json:"message"`
+}
+
+// Template is a loaded template ready to apply.
+type Template struct {
+	Info
+	files fs.FS
+}
+
+// Builtin lists the embedded templates.
+func Builtin() []Info {
+	entries, _ := fs.ReadDir(builtinFS, "builtin")
+	var infos []Info
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		sub, err := fs.Sub(builtinFS, path.Join("builtin", entry.Name()))
+		if err != nil {
+			continue
+		}
+		infos = append(infos, readInfo(entry.Name(), sub))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Load returns the embedded template called name.
+func Load(name string) (*Template, error) {
+	dir := path.Join("builtin", name)
+	if _, err := fs.Stat(builtinFS, dir); err != nil || name == "" || strings.ContainsAny(name, "/\\.") {
+		return nil, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(builtinNames(), ", "))
+	}
+	sub, err := fs.Sub(builtinFS, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{Info: readInfo(name, sub), files: sub}, nil
+}
+
+// IsGitURL reports whether ref looks like a repository rather than a
+// built-in template name.
+func IsGitURL(ref string) bool {
+	return strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://") ||
+		strings.HasPrefix(ref, "git@") || strings.HasPrefix(ref, "ssh://") ||
+		strings.HasSuffix(ref, ".git")
+}
+
+// Clone fetches a template from a git repository into dir using the git
+// binary. A "#subdir" suffix selects a template inside the repository.
+func Clone(url, dir string) (*Template, error) {
+	repo, sub, _ := strings.Cut(url, "#")
+	cmd := exec.Command("git", "clone", "--depth", "1", repo, dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("git clone %s: %w: %s", repo, err, strings.TrimSpace(string(out)))
+	}
+
+	root := dir
+	if sub != "" {
+		root = filepath.Join(dir, filepath.FromSlash(sub))
+		if rel, err := filepath.Rel(dir, root); err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("invalid template path %q", sub)
+		}
+	}
+	info, err := os.Stat(root)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("template directory %q not found in %s", sub, repo)
+	}
+
+	name := path.Base(strings.TrimSuffix(repo, ".git"))
+	if sub != "" {
+		name = path.Base(sub)
+	}
+	files := os.DirFS(root)
+	return &Template{Info: readInfo(name, files), files: files}, nil
+}
+
+// Automations returns the template's example cron jobs.
+func (t *Template) Automations() ([]Automation, error) {
+	data, err := fs.ReadFile(t.files, automationsFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read automations: %w", err)
+	}
+	var automations []Automation
+	if err := json.Unmarshal(data, &automations); err != nil {
+		return nil, fmt.Errorf("parse automations: %w", err)
+	}
+	return automations, nil
+}
+
+// Apply copies the template into workspace, placing skills/ under skillsRoot.
+// Existing files are kept unless overwrite is set. Files that belong to the
+// template's repository rather than the workspace, such as .github/ and the
+// top-level README and LICENSE, are left out. Executable files, such as a
+// skill's scripts, stay executable. It returns the files it wrote.
+func (t *Template) Apply(workspace, skillsRoot string, overwrite bool) ([]string, error) {
+	var written []string
+	err := fs.WalkDir(t.files, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == ".git" || p == ".github" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if p == metaFile || p == automationsFile || repoFile(p) {
+			return nil
+		}
+
+		dest := filepath.Join(workspace, filepath.FromSlash(p))
+		if rest, ok := strings.CutPrefix(p, skillsDir+"/"); ok {
+			dest = filepath.Join(skillsRoot, filepath.FromSlash(rest))
+		}
+		if !overwrite {
+			if _, err := os.Stat(dest); err == nil {
+				return nil
+			}
+		}
+
+		data, err := fs.ReadFile(t.files, p)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", p, err)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("read %s: %w", p, err)
+		}
+		mode := 0644 | info.Mode()&0o111
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("create %s: %w", filepath.Dir(dest), err)
+		}
+		if err := os.WriteFile(dest, data, mode); err != nil {
+			return fmt.Errorf("write %s: %w", dest, err)
+		}
+		// WriteFile keeps the mode of a file it overwrites.
+		if err := os.Chmod(dest, mode); err != nil {
+			return fmt.Errorf("write %s: %w", dest, err)
+		}
+		written = append(written, dest)
+		return nil
+	})
+	return written, err
+}
+
+// repoFile reports whether p is a top-level README or LICENSE, which
+// describe a template's repository rather than the workspace.
+func repoFile(p string) bool {
+	base := strings.ToUpper(strings.TrimSuffix(p, path.Ext(p)))
+	return !strings.Contains(p, "/") && (base == "README" || base == "LICENSE")
+}
+
+func readInfo(name string, files fs.FS) Info {
+	info := Info{Name: name}
+	if data, err := fs.ReadFile(files, metaFile); err == nil {
+		_ = json.Unmarshal(data, &info)
+		info.Name = name
+	}
+	return info
+}
+
+func builtinNames() []string {
+	var names []string
+	for _, info := range Builtin() {
+		names = append(names, info.Name)
+	}
+	return names
+}
@@ -0,0 +1,199 @@
+package templates
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltin(t *testing.T) {
+	infos := Builtin()
+	want := []string{"dev-helper", "family-bot", "personal-assistant"}
+	if len(infos) != len(want) {
+		t.Fatalf("Builtin() = %+v, want %v", infos, want)
+	}
+	for i, info := range infos {
+		if info.Name != want[i] {
+			t.Errorf("infos[%d].Name = %q, want %q", i, info.Name, want[i])
+		}
+		if info.Description == "" {
+			t.Errorf("%s has no description", info.Name)
+		}
+	}
+}
+
+func TestLoad_Unknown(t *testing.T) {
+	for _, name := range []string{"nope", "../builtin", ""} {
+		if _, err := Load(name); err == nil {
+			t.Errorf("Load(%q) should fail", name)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	tmpl, err := Load("personal-assistant")
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	ws := t.TempDir()
+	skills := filepath.Join(t.TempDir(), "skills")
+
+	written, err := tmpl.Apply(ws, skills, false)
+	if err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if len(written) == 0 {
+		t.Fatal("Apply wrote nothing")
+	}
+	for _, path := range []string{
+		filepath.Join(ws, "AGENTS.md"),
+		filepath.Join(ws, "SOUL.md"),
+		filepath.Join(skills, "daily-briefing", "SKILL.md"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s: %v", path, err)
+		}
+	}
+	for _, name := range []string{metaFile, automationsFile} {
+		if _, err := os.Stat(filepath.Join(ws, name)); err == nil {
+			t.Errorf("%s should not be copied", name)
+		}
+	}
+
+	// Existing files are kept unless overwrite is set.
+	agents := filepath.Join(ws, "AGENTS.md")
+	os.WriteFile(agents, []byte("mine"), 0644)
+	if _, err := tmpl.Apply(ws, skills, false); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if data, _ := os.ReadFile(agents); string(data) != "mine" {
+		t.Errorf("AGENTS.md overwritten without overwrite: %q", data)
+	}
+	if _, err := tmpl.Apply(ws, skills, true); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if data, _ := os.ReadFile(agents); string(data) == "mine" {
+		t.Error("AGENTS.md not replaced with overwrite")
+	}
+}
+
+func TestApply_RepositoryFiles(t *testing.T) {
+	src := t.TempDir()
+	for name, mode := range map[string]os.FileMode{
+		"AGENTS.md":                   0644,
+		"README.md":                   0644,
+		"LICENSE":                     0644,
+		".github/workflows/ci.yml":    0644,
+		"skills/deploy/SKILL.md":      0644,
+		"skills/deploy/README.md":     0644,
+		"skills/deploy/scripts/go.sh": 0755,
+	} {
+		file := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if err := os.WriteFile(file, []byte(name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ws := t.TempDir()
+	skills := filepath.Join(ws, "skills")
+	script := filepath.Join(skills, "deploy", "scripts", "go.sh")
+	os.MkdirAll(filepath.Dir(script), 0755)
+	os.WriteFile(script, []byte("old"), 0644)
+
+	tmpl := &Template{Info: Info{Name: "repo"}, files: os.DirFS(src)}
+	written, err := tmpl.Apply(ws, skills, true)
+	if err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if len(written) != 4 {
+		t.Errorf("written = %v", written)
+	}
+	for _, name := range []string{"README.md", "LICENSE", ".github"} {
+		if _, err := os.Stat(filepath.Join(ws, name)); err == nil {
+			t.Errorf("%s copied into the workspace", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(skills, "deploy", "README.md")); err != nil {
+		t.Errorf("a skill's README was skipped: %v", err)
+	}
+	if info, err := os.Stat(script); err != nil || info.Mode()&0o100 == 0 {
+		t.Errorf("script mode = %v, %v", info.Mode(), err)
+	}
+}
+
+func TestAutomations(t *testing.T) {
+	for _, info := range Builtin() {
+		tmpl, err := Load(info.Name)
+		if err != nil {
+			t.Fatalf("Load(%s) error: %v", info.Name, err)
+		}
+		autos, err := tmpl.Automations()
+		if err != nil {
+			t.Fatalf("%s automations: %v", info.Name, err)
+		}
+		if len(autos) == 0 {
+			t.Errorf("%s has no example automations", info.Name)
+		}
+		for _, a := range autos {
+			if a.Name == "" || a.Message == "" || a.Schedule.Kind != "cron" || a.Schedule.Expr == "" {
+				t.Errorf("%s: incomplete automation %+v", info.Name, a)
+			}
+		}
+	}
+}
+
+func TestIsGitURL(t *testing.T) {
+	cases := map[string]bool{
+		"dev-helper":                        false,
+		"https://github.com/acme/templates": true,
+		"git@github.com:acme/templates.git": true,
+		"/srv/templates.git":                true,
+	}
+	for ref, want := range cases {
+		if got := IsGitURL(ref); got != want {
+			t.Errorf("IsGitURL(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "templates.git")
+	sub := filepath.Join(repo, "team")
+	os.MkdirAll(filepath.Join(sub, "skills", "triage"), 0755)
+	os.WriteFile(filepath.Join(sub, "AGENTS.md"), []byte("# Team"), 0644)
+	os.WriteFile(filepath.Join(sub, "skills", "triage", "SKILL.md"), []byte("triage"), 0644)
+	os.WriteFile(filepath.Join(sub, "template.json"), []byte(`{"description":"team bot"}`), 0644)
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "init")
+
+	tmpl, err := Clone(repo+"#team", filepath.Join(t.TempDir(), "clone"))
+	if err != nil {
+		t.Fatalf("Clone error: %v", err)
+	}
+	if tmpl.Name != "team" || tmpl.Description != "team bot" {
+		t.Errorf("info = %+v", tmpl.Info)
+	}
+	ws := t.TempDir()
+	if _, err := tmpl.Apply(ws, filepath.Join(ws, "skills"), false); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "skills", "triage", "SKILL.md")); string(data) != "triage" {
+		t.Errorf("skill = %q", data)
+	}
+
+	if _, err := Clone(repo+"#../etc", filepath.Join(t.TempDir(), "clone")); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("expected invalid path error, got %v", err)
+	}
+}
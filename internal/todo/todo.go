@@ -0,0 +1,293 @@
+// Package todo keeps the user's todo list in the workspace, gives the agent
+// tools for it, and picks out overdue items for the heartbeat to nag about.
+package todo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/cron"
+)
+
+// nagEvery is how often the heartbeat brings up the same overdue item.
+const nagEvery = 24 * time.Hour
+
+// Path returns where the todo list is kept for workspace.
+func Path(workspace string) string {
+	return filepath.Join(workspace, ".claude", "todo.json")
+}
+
+// Item is one thing to do. An item due on a day rather than at a time
+// (DueDay) is overdue once that day is over.
+type Item struct {
+	ID          int    `json:"id"`
+	Text        string `json:"text"`
+	DueAtMs     int64  `json:"dueAtMs,omitempty"`
+	DueDay      bool   `json:"dueDay,omitempty"`
+	CreatedAtMs int64  `json:"createdAtMs"`
+	DoneAtMs    int64  `json:"doneAtMs,omitempty"`
+	NaggedAtMs  int64  `json:"naggedAtMs,omitempty"`
+}
+
+// Done reports whether the item is checked off.
+func (it Item) Done() bool { return it.DoneAtMs != 0 }
+
+// Overdue reports whether the item is open and past due at now.
+func (it Item) Overdue(now time.Time) bool {
+	if it.Done() || it.DueAtMs == 0 {
+		return false
+	}
+	deadline := time.UnixMilli(it.DueAtMs)
+	if it.DueDay {
+		deadline = deadline.AddDate(0, 0, 1)
+	}
+	return !now.Before(deadline)
+}
+
+// DescribeDue renders the due time, as in "Fri 16 Oct" or "Fri 16 Oct
+// 17:00", or "" without one.
+func (it Item) DescribeDue() string {
+	if it.DueAtMs == 0 {
+		return ""
+	}
+	due := time.UnixMilli(it.DueAtMs)
+	if it.DueDay {
+		return due.Format("Mon 2 Jan")
+	}
+	return due.Format("Mon 2 Jan 15:04")
+}
+
+// String renders the item for a list, as in "3. Pay rent (due Fri 16 Oct)".
+func (it Item) String() string {
+	s := fmt.Sprintf("%d. %s", it.ID, it.Text)
+	if due := it.DescribeDue(); due != "" && !it.Done() {
+		s += " (due " + due + ")"
+	}
+	if it.Done() {
+		s += " (done)"
+	}
+	return s
+}
+
+// ParseDue reads a due time: "today", "tomorrow", a weekday or a date
+// (2026-10-20) for a day, or "in 2 hours", "at 5pm", "tomorrow at 9am" or
+// "2026-10-20 17:00" for a time. Times are in now's location.
+func ParseDue(s string, now time.Time) (at time.Time, day bool, err error) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s {
+	case "today":
+		return today, true, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true, nil
+	}
+	for d := 1; d <= 7; d++ {
+		day := today.AddDate(0, 0, d)
+		if name := strings.ToLower(day.Weekday().String()); s == name || s == name[:3] {
+			return day, true, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, true, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02t15:04"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, false, nil
+		}
+	}
+	if sched, rest, err := cron.ParseNatural("todo "+s, now); err == nil && sched.Kind == "at" && rest == "todo" {
+		return time.UnixMilli(sched.AtMs), false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("cannot read due %q; try \"tomorrow\", \"friday\", \"2026-10-20\", \"in 2 hours\" or \"tomorrow at 9am\"", s)
+}
+
+// Store persists the todo list as a JSON file. The file is re-read on
+// every operation so the CLI and a running gateway can share it.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Add puts text on the list, due at due if it is not zero.
+func (s *Store) Add(text string, due time.Time, dueDay bool, now time.Time) (Item, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Item{}, fmt.Errorf("todo text is empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return Item{}, err
+	}
+	it := Item{ID: 1, Text: text, CreatedAtMs: now.UnixMilli()}
+	for _, other := range list {
+		it.ID = max(it.ID, other.ID+1)
+	}
+	if !due.IsZero() {
+		it.DueAtMs, it.DueDay = due.UnixMilli(), dueDay
+	}
+	if err := s.save(append(list, it)); err != nil {
+		return Item{}, err
+	}
+	return it, nil
+}
+
+// List returns the open items, those due soonest first and undated ones
+// last; with all, done items follow.
+func (s *Store) List(all bool) ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var out []Item
+	for _, it := range list {
+		if all || !it.Done() {
+			out = append(out, it)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		switch {
+		case a.Done() != b.Done():
+			return !a.Done()
+		case (a.DueAtMs == 0) != (b.DueAtMs == 0):
+			return a.DueAtMs != 0
+		case a.DueAtMs != b.DueAtMs:
+			return a.DueAtMs < b.DueAtMs
+		}
+		return a.ID < b.ID
+	})
+	return out, nil
+}
+
+// Complete checks off item id. It reports false when there is no such
+// item.
+func (s *Store) Complete(id int, now time.Time) (Item, bool, error) {
+	return s.update(id, func(it *Item) { it.DoneAtMs = now.UnixMilli() })
+}
+
+// Remove deletes item id. It reports whether there was one.
+func (s *Store) Remove(id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	for i, it := range list {
+		if it.ID == id {
+			return true, s.save(append(list[:i], list[i+1:]...))
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) update(id int, change func(*Item)) (Item, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return Item{}, false, err
+	}
+	for i := range list {
+		if list[i].ID == id {
+			change(&list[i])
+			return list[i], true, s.save(list)
+		}
+	}
+	return Item{}, false, nil
+}
+
+// Nag returns the overdue items that were not brought up in the last day
+// and marks them brought up, so each overdue item comes up once a day.
+func (s *Store) Nag(now time.Time) ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var due []Item
+	for i, it := range list {
+		if it.Overdue(now) && now.Sub(time.UnixMilli(it.NaggedAtMs)) >= nagEvery {
+			list[i].NaggedAtMs = now.UnixMilli()
+			due = append(due, list[i])
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	return due, s.save(list)
+}
+
+// NagPrompt returns what the heartbeat adds to its prompt about the items
+// Nag picks at now, or "" when there are none.
+func (s *Store) NagPrompt(now time.Time) (string, error) {
+	items, err := s.Nag(now)
+	if err != nil || len(items) == 0 {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("These todos are overdue; remind the user about them briefly and offer to reschedule or check them off:\n")
+	for _, it := range items {
+		b.WriteString("- " + it.String() + "\n")
+	}
+	return b.String(), nil
+}
+
+// ParseID reads an item ID as given on the command line or by the agent,
+// "3" or "#3".
+func ParseID(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("bad todo id %q", s)
+	}
+	return id, nil
+}
+
+func (s *Store) load() ([]Item, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Item
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	return list, nil
+}
+
+func (s *Store) save(list []Item) error {
+	if list == nil {
+		list = []Item{}
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
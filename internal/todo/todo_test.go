@@ -0,0 +1,159 @@
+package todo
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local) // a Friday
+	tests := []struct {
+		in   string
+		want time.Time
+		day  bool
+	}{
+		{"today", time.Date(2026, 10, 16, 0, 0, 0, 0, time.Local), true},
+		{"Tomorrow", time.Date(2026, 10, 17, 0, 0, 0, 0, time.Local), true},
+		{"monday", time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local), true},
+		{"fri", time.Date(2026, 10, 23, 0, 0, 0, 0, time.Local), true},
+		{"2026-10-20", time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local), true},
+		{"2026-10-20 17:00", time.Date(2026, 10, 20, 17, 0, 0, 0, time.Local), false},
+		{"in 2 hours", now.Add(2 * time.Hour), false},
+		{"tomorrow at 9am", time.Date(2026, 10, 17, 9, 0, 0, 0, time.Local), false},
+	}
+	for _, tt := range tests {
+		got, day, err := ParseDue(tt.in, now)
+		if err != nil || !got.Equal(tt.want) || day != tt.day {
+			t.Errorf("ParseDue(%q) = %v, %v, %v; want %v, %v", tt.in, got, day, err, tt.want, tt.day)
+		}
+	}
+	for _, in := range []string{"", "someday", "every day at 9am"} {
+		if _, _, err := ParseDue(in, now); err == nil {
+			t.Errorf("ParseDue(%q): expected error", in)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "todo.json")
+	store := NewStore(path)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+
+	rent, err := store.Add("Pay rent", now.AddDate(0, 0, -1), true, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	milk, _ := store.Add("Buy milk", time.Time{}, false, now)
+	call, _ := store.Add("Call the bank", now.Add(time.Hour), false, now)
+	if _, err := store.Add("  ", time.Time{}, false, now); err == nil {
+		t.Error("expected error for empty text")
+	}
+	if rent.ID != 1 || milk.ID != 2 || call.ID != 3 {
+		t.Errorf("ids = %d, %d, %d", rent.ID, milk.ID, call.ID)
+	}
+
+	list, _ := store.List(false)
+	if got := Format(list); got != "1. Pay rent (due Thu 15 Oct)\n3. Call the bank (due Fri 16 Oct 10:00)\n2. Buy milk\n" {
+		t.Errorf("list =\n%s", got)
+	}
+
+	// A new store on the same file sees the list, as from the CLI.
+	store = NewStore(path)
+	if _, ok, err := store.Complete(milk.ID, now); !ok || err != nil {
+		t.Fatalf("complete = %v, %v", ok, err)
+	}
+	if _, ok, _ := store.Complete(99, now); ok {
+		t.Error("completed a missing item")
+	}
+	if list, _ := store.List(false); len(list) != 2 {
+		t.Errorf("open = %+v", list)
+	}
+	if list, _ := store.List(true); len(list) != 3 || list[2].ID != milk.ID || !list[2].Done() {
+		t.Errorf("all = %+v", list)
+	}
+
+	if removed, _ := store.Remove(call.ID); !removed {
+		t.Error("remove failed")
+	}
+	if removed, _ := store.Remove(call.ID); removed {
+		t.Error("removed twice")
+	}
+	if next, _ := store.Add("Water plants", time.Time{}, false, now); next.ID != 3 {
+		t.Errorf("next id = %d, want 3", next.ID)
+	}
+}
+
+func TestNag(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(filepath.Join(t.TempDir(), "todo.json"))
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	store.Add("Pay rent", now.AddDate(0, 0, -1), true, now)
+	store.Add("Call the bank", now.Add(time.Hour), false, now)
+	store.Add("File taxes", now, true, now) // due today: not overdue until tomorrow
+	store.Add("Buy milk", time.Time{}, false, now)
+
+	prompt, err := store.NagPrompt(now)
+	if err != nil || !strings.Contains(prompt, "Pay rent") || strings.Count(prompt, "\n- ") != 1 {
+		t.Fatalf("prompt = %q, %v", prompt, err)
+	}
+	if prompt, _ := store.NagPrompt(now.Add(time.Minute)); prompt != "" {
+		t.Errorf("nagged again within a day: %q", prompt)
+	}
+
+	later := now.Add(2 * time.Hour)
+	items, _ := store.Nag(later)
+	if len(items) != 1 || items[0].Text != "Call the bank" {
+		t.Errorf("later = %+v", items)
+	}
+
+	nextDay := now.Add(25 * time.Hour)
+	items, _ = store.Nag(nextDay)
+	if len(items) != 2 || items[0].Text != "Pay rent" || items[1].Text != "File taxes" {
+		t.Errorf("next day = %+v", items)
+	}
+}
+
+func TestTools(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(filepath.Join(t.TempDir(), "todo.json"))
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	tools := Tools(store)
+	add, list, done := tools[0].(*addTool), tools[1], tools[2].(*doneTool)
+	add.now = func() time.Time { return now }
+	done.now = add.now
+	ctx := context.Background()
+
+	res, _ := add.Execute(ctx, map[string]any{"text": "Renew passport", "due": "friday"})
+	if !res.Success || res.Output != "Added 1. Renew passport (due Fri 23 Oct)" {
+		t.Errorf("add = %+v", res)
+	}
+	if res, _ := add.Execute(ctx, map[string]any{"text": "x", "due": "someday"}); res.Success {
+		t.Error("expected failure for a bad due")
+	}
+
+	res, _ = list.Execute(ctx, map[string]any{})
+	if res.Output != "1. Renew passport (due Fri 23 Oct)\n" {
+		t.Errorf("list = %q", res.Output)
+	}
+
+	if res, _ := done.Execute(ctx, map[string]any{"id": float64(2)}); res.Success {
+		t.Error("checked off a missing item")
+	}
+	res, _ = done.Execute(ctx, map[string]any{"id": "#1"})
+	if !res.Success || res.Output != "Checked off 1. Renew passport" {
+		t.Errorf("done = %+v", res)
+	}
+	res, _ = list.Execute(ctx, map[string]any{})
+	if res.Output != "The todo list is empty.\n" {
+		t.Errorf("list after done = %q", res.Output)
+	}
+}
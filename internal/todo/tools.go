@@ -0,0 +1,161 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// Tools returns the tools the agent keeps the todo list with.
+func Tools(store *Store) []tool.Tool {
+	return []tool.Tool{
+		&addTool{store: store, now: time.Now},
+		&listTool{store: store},
+		&doneTool{store: store, now: time.Now},
+	}
+}
+
+type addTool struct {
+	store *Store
+	now   func() time.Time
+}
+
+func (t *addTool) Name() string { return "todo_add" }
+
+func (t *addTool) Description() string {
+	return "Add an item to the user's todo list, optionally with a due date. " +
+		"Use it for things the user has to do (\"I need to renew my passport by Friday\"); " +
+		"for a message at a set time, schedule a reminder instead."
+}
+
+func (t *addTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"text": map[string]any{"type": "string", "description": "What to do, e.g. \"Renew passport\""},
+			"due": map[string]any{
+				"type":        "string",
+				"description": "When it is due: \"tomorrow\", \"friday\", \"2026-10-20\", \"in 2 hours\" or \"tomorrow at 9am\"",
+			},
+		},
+		Required: []string{"text"},
+	}
+}
+
+func (t *addTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	text := stringParam(params, "text")
+	if text == "" {
+		return failed(errors.New("text is required")), nil
+	}
+	now := t.now()
+	var due time.Time
+	var day bool
+	if s := stringParam(params, "due"); s != "" {
+		var err error
+		if due, day, err = ParseDue(s, now); err != nil {
+			return failed(err), nil
+		}
+	}
+	it, err := t.store.Add(text, due, day, now)
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: "Added " + it.String(), Data: it}, nil
+}
+
+type listTool struct{ store *Store }
+
+func (t *listTool) Name() string { return "todo_list" }
+
+func (t *listTool) Description() string {
+	return "List the user's open todos with their ids, soonest due first."
+}
+
+func (t *listTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"all": map[string]any{"type": "boolean", "description": "Include items already done"},
+		},
+		Required: []string{},
+	}
+}
+
+func (t *listTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	all, _ := params["all"].(bool)
+	items, err := t.store.List(all)
+	if err != nil {
+		return failed(err), nil
+	}
+	return &tool.ToolResult{Success: true, Output: Format(items), Data: items}, nil
+}
+
+// Format lists items one to a line.
+func Format(items []Item) string {
+	if len(items) == 0 {
+		return "The todo list is empty.\n"
+	}
+	var b strings.Builder
+	for _, it := range items {
+		b.WriteString(it.String() + "\n")
+	}
+	return b.String()
+}
+
+type doneTool struct {
+	store *Store
+	now   func() time.Time
+}
+
+func (t *doneTool) Name() string { return "todo_done" }
+
+func (t *doneTool) Description() string {
+	return "Check off an item on the user's todo list by the id todo_list shows."
+}
+
+func (t *doneTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"id": map[string]any{"type": "integer", "description": "The item id"},
+		},
+		Required: []string{"id"},
+	}
+}
+
+func (t *doneTool) Execute(ctx context.Context, params map[string]any) (*tool.ToolResult, error) {
+	var id int
+	var err error
+	switch v := params["id"].(type) {
+	case float64:
+		id = int(v)
+	case string:
+		id, err = ParseID(v)
+	default:
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		return failed(err), nil
+	}
+	it, ok, err := t.store.Complete(id, t.now())
+	if err != nil {
+		return failed(err), nil
+	}
+	if !ok {
+		return failed(fmt.Errorf("no todo %d", id)), nil
+	}
+	return &tool.ToolResult{Success: true, Output: fmt.Sprintf("Checked off %d. %s", it.ID, it.Text), Data: it}, nil
+}
+
+func stringParam(params map[string]any, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+func failed(err error) *tool.ToolResult {
+	return &tool.ToolResult{Success: false, Output: err.Error(), Error: err}
+}
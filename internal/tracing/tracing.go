@@ -0,0 +1,225 @@
+// Package tracing exports OpenTelemetry traces of agent runs: each run is a
+// trace, with child spans for model calls, tool invocations and skill
+// activations.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentation    = "github.com/stellarlinkco/myclaw"
+	defaultServiceName = "myclaw"
+	toolSpanKey        = "tracing.tool_span"
+)
+
+// tracer is looked up on each span so that Setup, which replaces the
+// global provider, takes effect for wrappers built before it.
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Setup exports traces over OTLP/HTTP when cfg is enabled. The returned
+// function flushes pending spans and stops the exporter. When tracing is
+// off, spans are not recorded and the function does nothing.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	switch {
+	case strings.Contains(cfg.Endpoint, "://"):
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	case cfg.Endpoint != "":
+		opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create trace exporter: %w", err)
+	}
+
+	name := cfg.ServiceName
+	if name == "" {
+		name = defaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", name)))
+	if err != nil {
+		return nil, fmt.Errorf("trace resource: %w", err)
+	}
+	rate := cfg.SampleRate
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(rate))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// StartRun starts the root span of an agent run on channel.
+func StartRun(ctx context.Context, channel string, req api.Request) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "agent.run", trace.WithAttributes(
+		attribute.String("myclaw.channel", channel),
+		attribute.String("myclaw.session_id", req.SessionID),
+	))
+}
+
+// EndRun ends a span from StartRun with the outcome of the run.
+func EndRun(span trace.Span, resp *api.Response, err error) {
+	if resp != nil && resp.Result != nil {
+		setUsage(span, resp.Result.Usage)
+		span.SetAttributes(attribute.String("myclaw.stop_reason", resp.Result.StopReason))
+		if len(resp.Result.ToolCalls) > 0 {
+			span.SetAttributes(attribute.Int("myclaw.tool_calls", len(resp.Result.ToolCalls)))
+		}
+	}
+	end(span, err)
+}
+
+func setUsage(span trace.Span, u model.Usage) {
+	span.SetAttributes(
+		attribute.Int("gen_ai.usage.input_tokens", u.InputTokens),
+		attribute.Int("gen_ai.usage.output_tokens", u.OutputTokens),
+	)
+	if u.CacheReadTokens > 0 {
+		span.SetAttributes(attribute.Int("gen_ai.usage.cache_read_tokens", u.CacheReadTokens))
+	}
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ModelFactory wraps the models f builds so each call is a span. name is
+// the configured model, for when a request does not carry one.
+func ModelFactory(f api.ModelFactory, name string) api.ModelFactory {
+	return api.ModelFactoryFunc(func(ctx context.Context) (model.Model, error) {
+		m, err := f.Model(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &tracedModel{Model: m, name: name}, nil
+	})
+}
+
+type tracedModel struct {
+	model.Model
+	name string
+}
+
+func (m *tracedModel) start(ctx context.Context, req model.Request) (context.Context, trace.Span) {
+	name := req.Model
+	if name == "" {
+		name = m.name
+	}
+	return tracer().Start(ctx, "model.complete", trace.WithAttributes(
+		attribute.String("gen_ai.request.model", name),
+		attribute.Int("gen_ai.request.messages", len(req.Messages)),
+	))
+}
+
+func (m *tracedModel) Complete(ctx context.Context, req model.Request) (*model.Response, error) {
+	ctx, span := m.start(ctx, req)
+	resp, err := m.Model.Complete(ctx, req)
+	if resp != nil {
+		setUsage(span, resp.Usage)
+		span.SetAttributes(attribute.String("gen_ai.response.finish_reason", resp.StopReason))
+	}
+	end(span, err)
+	return resp, err
+}
+
+func (m *tracedModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	ctx, span := m.start(ctx, req)
+	err := m.Model.CompleteStream(ctx, req, func(r model.StreamResult) error {
+		if r.Final && r.Response != nil {
+			setUsage(span, r.Response.Usage)
+			span.SetAttributes(attribute.String("gen_ai.response.finish_reason", r.Response.StopReason))
+		}
+		return cb(r)
+	})
+	end(span, err)
+	return err
+}
+
+// Middleware returns runtime middleware that makes each tool invocation a
+// span.
+func Middleware() middleware.Middleware {
+	return middleware.Funcs{
+		Identifier: "tracing",
+		OnBeforeTool: func(ctx context.Context, st *middleware.State) error {
+			call, _ := st.ToolCall.(agent.ToolCall)
+			_, span := tracer().Start(ctx, "tool "+call.Name, trace.WithAttributes(
+				attribute.String("tool.name", call.Name),
+				attribute.String("tool.call_id", call.ID),
+			))
+			st.Values[toolSpanKey] = span
+			return nil
+		},
+		OnAfterTool: func(ctx context.Context, st *middleware.State) error {
+			span, ok := st.Values[toolSpanKey].(trace.Span)
+			if !ok {
+				return nil
+			}
+			delete(st.Values, toolSpanKey)
+			var err error
+			if res, ok := st.ToolResult.(agent.ToolResult); ok {
+				if failed, _ := res.Metadata["is_error"].(bool); failed {
+					msg, _ := res.Metadata["error"].(string)
+					err = fmt.Errorf("%s", msg)
+				}
+			}
+			end(span, err)
+			return nil
+		},
+	}
+}
+
+// Skills wraps the handlers of registrations so each activation is a span.
+func Skills(registrations []api.SkillRegistration) []api.SkillRegistration {
+	out := make([]api.SkillRegistration, len(registrations))
+	for i, reg := range registrations {
+		out[i] = reg
+		if reg.Handler == nil {
+			continue
+		}
+		name, handler := reg.Definition.Name, reg.Handler
+		out[i].Handler = runtimeskills.HandlerFunc(func(ctx context.Context, ac runtimeskills.ActivationContext) (runtimeskills.Result, error) {
+			ctx, span := tracer().Start(ctx, "skill "+name, trace.WithAttributes(attribute.String("skill.name", name)))
+			res, err := handler.Execute(ctx, ac)
+			end(span, err)
+			return res, err
+		})
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	runtimeskills "github.com/cexll/agentsdk-go/pkg/runtime/skills"
+	"github.com/stellarlinkco/myclaw/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type stubModel struct{ err error }
+
+func (m stubModel) Complete(context.Context, model.Request) (*model.Response, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &model.Response{Usage: model.Usage{InputTokens: 120, OutputTokens: 30}, StopReason: "tool_use"}, nil
+}
+
+func (m stubModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	resp, err := m.Complete(ctx, req)
+	if err != nil {
+		return err
+	}
+	return cb(model.StreamResult{Final: true, Response: resp})
+}
+
+func attr(s sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// TestSpans runs one traced agent turn by hand: a model call, a tool call
+// that fails and a skill activation, all under the run's span.
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	ctx, run := StartRun(context.Background(), "telegram", api.Request{SessionID: "telegram:42"})
+
+	factory := ModelFactory(api.ModelFactoryFunc(func(context.Context) (model.Model, error) { return stubModel{}, nil }), "claude-sonnet-4-5")
+	mdl, err := factory.Model(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mdl.Complete(ctx, model.Request{}); err != nil {
+		t.Fatal(err)
+	}
+
+	mw := Middleware()
+	st := &middleware.State{Values: map[string]any{}, ToolCall: agent.ToolCall{ID: "call_1", Name: "web_fetch"}}
+	_ = mw.BeforeTool(ctx, st)
+	st.ToolResult = agent.ToolResult{Name: "web_fetch", Metadata: map[string]any{"is_error": true, "error": "timeout"}}
+	_ = mw.AfterTool(ctx, st)
+
+	regs := Skills([]api.SkillRegistration{{
+		Definition: runtimeskills.Definition{Name: "weather"},
+		Handler: runtimeskills.HandlerFunc(func(context.Context, runtimeskills.ActivationContext) (runtimeskills.Result, error) {
+			return runtimeskills.Result{Output: "sunny"}, nil
+		}),
+	}})
+	if res, err := regs[0].Handler.Execute(ctx, runtimeskills.ActivationContext{}); err != nil || res.Output != "sunny" {
+		t.Fatalf("skill = %+v, %v", res, err)
+	}
+
+	EndRun(run, &api.Response{Result: &api.Result{StopReason: "end_turn", Usage: model.Usage{InputTokens: 200, OutputTokens: 50}}}, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans, want 4", len(spans))
+	}
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans {
+		byName[s.Name()] = s
+	}
+	root := byName["agent.run"]
+	if root == nil || attr(root, "myclaw.channel").AsString() != "telegram" || attr(root, "gen_ai.usage.input_tokens").AsInt64() != 200 {
+		t.Fatalf("run span = %+v", root)
+	}
+	for _, name := range []string{"model.complete", "tool web_fetch", "skill weather"} {
+		s := byName[name]
+		if s == nil {
+			t.Fatalf("no %q span", name)
+		}
+		if s.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("%q is not a child of the run", name)
+		}
+	}
+	if m := byName["model.complete"]; attr(m, "gen_ai.request.model").AsString() != "claude-sonnet-4-5" || attr(m, "gen_ai.usage.output_tokens").AsInt64() != 30 {
+		t.Errorf("model span attributes = %v", m.Attributes())
+	}
+	if tool := byName["tool web_fetch"]; tool.Status().Code != codes.Error || tool.Status().Description != "timeout" {
+		t.Errorf("tool span status = %+v", tool.Status())
+	}
+}
+
+func TestModelError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	mdl, _ := ModelFactory(api.ModelFactoryFunc(func(context.Context) (model.Model, error) {
+		return stubModel{err: errors.New("overloaded")}, nil
+	}), "gpt-4o").Model(context.Background())
+	if err := mdl.CompleteStream(context.Background(), model.Request{}, func(model.StreamResult) error { return nil }); err == nil {
+		t.Fatal("expected error")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error {
+		t.Errorf("spans = %v", spans)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	stop, err := Setup(context.Background(), config.TracingConfig{})
+	if err != nil || stop(context.Background()) != nil {
+		t.Fatalf("disabled: %v", err)
+	}
+
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	stop, err = Setup(context.Background(), config.TracingConfig{Enabled: true, Endpoint: "http://127.0.0.1:1/v1/traces", SampleRate: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); !ok {
+		t.Error("tracer provider not installed")
+	}
+	// Nothing was traced, so shutting down sends nothing.
+	if err := stop(context.Background()); err != nil {
+		t.Errorf("stop: %v", err)
+	}
+}
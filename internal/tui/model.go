@@ -0,0 +1,712 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+type screen int
+
+const (
+	chatScreen screen = iota
+	sessionsScreen
+	memoryScreen
+)
+
+type entryKind int
+
+const (
+	userEntry entryKind = iota
+	assistantEntry
+	toolEntry
+	infoEntry
+	errorEntry
+)
+
+// entry is one block of the conversation pane.
+type entry struct {
+	kind   entryKind
+	text   string
+	toolID string // for tool entries, to mark them done
+	done   bool
+	failed bool
+}
+
+// Messages the model handles besides key presses and the components' own.
+type (
+	// startMsg reports that a run has started streaming.
+	startMsg struct {
+		run    int
+		ctx    context.Context
+		events <-chan api.StreamEvent
+	}
+	eventMsg struct {
+		run int
+		ev  api.StreamEvent
+	}
+	doneMsg struct {
+		run int
+		err error
+	}
+	askMsg struct {
+		req    permission.Request
+		answer chan bool
+	}
+)
+
+const chatHelp = "Enter send · PgUp/PgDn scroll · Ctrl-S sessions · Ctrl-O memory · Ctrl-N new · Ctrl-C stop/quit"
+
+// model is the state of the TUI, a tea.Model. Runs stream their events
+// back through commands; tool approvals, asked from the agent's goroutine,
+// arrive on asks.
+type model struct {
+	opts   Options
+	ctx    context.Context
+	asks   chan askMsg
+	styles styles
+
+	width, height int
+	screen        screen
+	status        string
+
+	// chat
+	sessionID string
+	entries   []entry
+	convo     viewport.Model
+	input     textinput.Model
+	spinner   spinner.Model
+	run       int // counts runs, so events of a stopped one are ignored
+	running   bool
+	cancel    context.CancelFunc
+	next      tea.Cmd // waits for the next event of the run
+	asking    *askMsg
+
+	// sessions
+	sessions list.Model
+
+	// memory
+	memory viewport.Model
+	daily  bool // showing today's notes rather than MEMORY.md
+}
+
+func newModel(ctx context.Context, opts Options) *model {
+	m := &model{
+		opts:      opts,
+		ctx:       ctx,
+		asks:      make(chan askMsg),
+		styles:    newStyles(opts.Color),
+		sessionID: opts.SessionID,
+		convo:     viewport.New(0, 0),
+		input:     textinput.New(),
+		spinner:   spinner.New(spinner.WithSpinner(spinner.Dot)),
+		memory:    viewport.New(0, 0),
+	}
+	m.input.Prompt = "> "
+	m.input.Placeholder = "Ask anything, or /help"
+	m.input.PlaceholderStyle = m.styles.dim
+	m.input.Focus()
+	m.spinner.Style = m.styles.spinner
+
+	m.sessions = list.New(nil, sessionDelegate{m.styles}, 0, 0)
+	m.sessions.SetShowTitle(false)
+	m.sessions.SetShowStatusBar(false)
+	m.sessions.SetShowHelp(false)
+	m.sessions.SetFilteringEnabled(false)
+	m.sessions.DisableQuitKeybindings()
+
+	if m.sessionID == "" {
+		m.newSession()
+	}
+	m.resize(80, 24)
+	return m
+}
+
+func (m *model) newSession() {
+	m.sessionID = fmt.Sprintf("cli-tui-%d", time.Now().UnixNano())
+	m.entries = nil
+	m.refresh()
+}
+
+func (m *model) Init() tea.Cmd {
+	return tea.Batch(textinput.Blink, m.waitAsk())
+}
+
+// waitAsk waits for the next approval asked for by a run.
+func (m *model) waitAsk() tea.Cmd {
+	asks, ctx := m.asks, m.ctx
+	return func() tea.Msg {
+		select {
+		case a := <-asks:
+			return a
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.resize(msg.Width, msg.Height)
+		return m, nil
+	case tea.KeyMsg:
+		m.status = ""
+		if m.asking != nil {
+			m.answer(msg)
+			return m, nil
+		}
+		switch m.screen {
+		case sessionsScreen:
+			return m, m.sessionsKey(msg)
+		case memoryScreen:
+			return m, m.memoryKey(msg)
+		}
+		return m, m.chatKey(msg)
+	case startMsg:
+		if msg.run != m.run {
+			return m, drain(msg.events)
+		}
+		m.next = waitEvent(msg.ctx, msg.run, msg.events)
+		return m, m.next
+	case eventMsg:
+		if msg.run != m.run {
+			return m, nil
+		}
+		m.event(msg.ev)
+		return m, m.next
+	case doneMsg:
+		if msg.run == m.run {
+			m.finish(msg.err)
+		}
+		return m, nil
+	case askMsg:
+		m.asking = &msg
+		m.screen = chatScreen
+		return m, m.waitAsk()
+	case spinner.TickMsg:
+		// The spinner only turns while an answer is coming.
+		if !m.running {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		m.refresh()
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	switch m.screen {
+	case sessionsScreen:
+		m.sessions, cmd = m.sessions.Update(msg)
+	case memoryScreen:
+		m.memory, cmd = m.memory.Update(msg)
+	default:
+		m.input, cmd = m.input.Update(msg)
+	}
+	return m, cmd
+}
+
+func (m *model) chatKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c":
+		if m.running {
+			m.cancel()
+			return nil
+		}
+		return tea.Quit
+	case "ctrl+d":
+		if m.input.Value() == "" && !m.running {
+			return tea.Quit
+		}
+	case "enter":
+		return m.submit()
+	case "up":
+		m.convo.ScrollUp(1)
+		return nil
+	case "down":
+		m.convo.ScrollDown(1)
+		return nil
+	case "pgup":
+		m.convo.PageUp()
+		return nil
+	case "pgdown":
+		m.convo.PageDown()
+		return nil
+	case "ctrl+n":
+		return m.command("/new")
+	case "ctrl+s":
+		return m.command("/sessions")
+	case "ctrl+o":
+		return m.command("/memory")
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return cmd
+}
+
+// submit sends the input to the model, or runs it when it is a command.
+func (m *model) submit() tea.Cmd {
+	text := strings.TrimSpace(m.input.Value())
+	if text == "" {
+		return nil
+	}
+	if strings.HasPrefix(text, "/") {
+		m.input.Reset()
+		return m.command(text)
+	}
+	if m.running {
+		m.status = "Still answering: wait, or stop it with Ctrl-C."
+		return nil
+	}
+	m.input.Reset()
+	m.entries = append(m.entries, entry{kind: userEntry, text: text})
+	m.refresh()
+	m.convo.GotoBottom()
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	ctx = permission.WithAsker(ctx, m.ask)
+	m.run++
+	m.running, m.cancel = true, cancel
+	run, req, stream := m.run, api.Request{Prompt: text, SessionID: m.sessionID}, m.opts.Stream
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		events, err := stream(ctx, req)
+		if err != nil {
+			return doneMsg{run: run, err: err}
+		}
+		return startMsg{run: run, ctx: ctx, events: events}
+	})
+}
+
+// waitEvent returns the next event of the run, or its end.
+func waitEvent(ctx context.Context, run int, events <-chan api.StreamEvent) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-events
+		if !ok {
+			return doneMsg{run: run, err: ctx.Err()}
+		}
+		return eventMsg{run: run, ev: ev}
+	}
+}
+
+// drain reads what is left of a run nobody is watching, so it can finish.
+func drain(events <-chan api.StreamEvent) tea.Cmd {
+	return func() tea.Msg {
+		for range events {
+		}
+		return nil
+	}
+}
+
+// command runs a slash command typed in the input.
+func (m *model) command(text string) tea.Cmd {
+	switch strings.Fields(text)[0] {
+	case "/quit", "/exit":
+		if m.running {
+			m.cancel()
+		}
+		return tea.Quit
+	case "/new", "/clear":
+		if m.busy() {
+			return nil
+		}
+		m.newSession()
+		m.status = "Started session " + m.sessionID
+	case "/sessions":
+		if m.busy() {
+			return nil
+		}
+		return m.openSessions()
+	case "/memory":
+		m.openMemory(false)
+	case "/help":
+		m.entries = append(m.entries, entry{kind: infoEntry, text: "Commands: /new, /sessions, /memory, /quit.\n" + chatHelp})
+		m.refresh()
+		m.convo.GotoBottom()
+	default:
+		m.status = "Unknown command " + text + " (try /help)"
+	}
+	return nil
+}
+
+// busy reports, and says, whether a run is in progress.
+func (m *model) busy() bool {
+	if m.running {
+		m.status = "Wait for the answer, or stop it with Ctrl-C."
+	}
+	return m.running
+}
+
+// ask shows the approval prompt for req and waits for the answer. Runs
+// call it from their own goroutine.
+func (m *model) ask(ctx context.Context, req permission.Request) (bool, error) {
+	answer := make(chan bool, 1)
+	select {
+	case m.asks <- askMsg{req: req, answer: answer}:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	select {
+	case ok := <-answer:
+		return ok, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (m *model) answer(msg tea.KeyMsg) {
+	var yes bool
+	switch msg.String() {
+	case "y", "Y":
+		yes = true
+	case "n", "N", "enter", "esc", "ctrl+c":
+	default:
+		return
+	}
+	m.asking.answer <- yes
+	verdict := "Denied"
+	if yes {
+		verdict = "Allowed"
+	}
+	m.entries = append(m.entries, entry{kind: infoEntry, text: verdict + " " + m.asking.req.String()})
+	m.asking = nil
+	m.refresh()
+}
+
+// event adds a streamed event to the conversation.
+func (m *model) event(ev api.StreamEvent) {
+	switch {
+	case ev.Type == api.EventContentBlockDelta && ev.Delta != nil && ev.Delta.Type == "text_delta":
+		if n := len(m.entries); n > 0 && m.entries[n-1].kind == assistantEntry {
+			m.entries[n-1].text += ev.Delta.Text
+		} else {
+			m.entries = append(m.entries, entry{kind: assistantEntry, text: ev.Delta.Text})
+		}
+	case ev.Type == api.EventToolExecutionStart:
+		m.entries = append(m.entries, entry{kind: toolEntry, text: ev.Name, toolID: ev.ToolUseID})
+	case ev.Type == api.EventToolExecutionResult:
+		for i := len(m.entries) - 1; i >= 0; i-- {
+			if e := &m.entries[i]; e.kind == toolEntry && e.toolID == ev.ToolUseID && !e.done {
+				e.done = true
+				e.failed = ev.IsError != nil && *ev.IsError
+				break
+			}
+		}
+	case ev.Type == api.EventError:
+		m.entries = append(m.entries, entry{kind: errorEntry, text: fmt.Sprint(ev.Output)})
+	default:
+		return
+	}
+	m.refresh()
+}
+
+func (m *model) finish(err error) {
+	m.running, m.next = false, nil
+	m.cancel()
+	switch {
+	case errors.Is(err, context.Canceled):
+		m.entries = append(m.entries, entry{kind: infoEntry, text: "Stopped."})
+	case err != nil:
+		m.entries = append(m.entries, entry{kind: errorEntry, text: err.Error()})
+	}
+	// Tools cut off by a stop never report back.
+	for i := range m.entries {
+		m.entries[i].done = m.entries[i].done || m.entries[i].kind == toolEntry
+	}
+	m.refresh()
+}
+
+// sessionItem is a saved session in the switcher.
+type sessionItem struct{ session.Summary }
+
+func (s sessionItem) FilterValue() string { return s.ID }
+
+// sessionDelegate draws a session as one line, the selected one in
+// reverse video.
+type sessionDelegate struct{ styles styles }
+
+func (d sessionDelegate) Height() int                         { return 1 }
+func (d sessionDelegate) Spacing() int                        { return 0 }
+func (d sessionDelegate) Update(tea.Msg, *list.Model) tea.Cmd { return nil }
+
+func (d sessionDelegate) Render(w io.Writer, l list.Model, index int, item list.Item) {
+	s := item.(sessionItem)
+	line := fmt.Sprintf(" %s  %-24s %4d msgs  %s", s.UpdatedAt.Local().Format("2006-01-02 15:04"), ansi.Truncate(s.ID, 24, ""), s.Messages, s.Preview)
+	line = ansi.Truncate(line, l.Width(), "")
+	if index == l.Index() {
+		line = d.styles.bar.Width(l.Width()).Render(line)
+	}
+	io.WriteString(w, line)
+}
+
+func (m *model) openSessions() tea.Cmd {
+	if m.opts.Sessions == nil {
+		m.status = "No session history."
+		return nil
+	}
+	saved, err := m.opts.Sessions.List()
+	if err != nil {
+		m.status = "List sessions: " + err.Error()
+		return nil
+	}
+	items := make([]list.Item, len(saved))
+	selected := 0
+	for i, s := range saved {
+		items[i] = sessionItem{s}
+		if s.ID == m.sessionID {
+			selected = i
+		}
+	}
+	cmd := m.sessions.SetItems(items)
+	m.sessions.Select(selected)
+	m.screen = sessionsScreen
+	return cmd
+}
+
+func (m *model) sessionsKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "ctrl+c", "ctrl+s":
+		m.screen = chatScreen
+		return nil
+	case "enter":
+		if item, ok := m.sessions.SelectedItem().(sessionItem); ok {
+			m.loadSession(item.ID)
+			m.screen = chatScreen
+		}
+		return nil
+	}
+	var cmd tea.Cmd
+	m.sessions, cmd = m.sessions.Update(msg)
+	return cmd
+}
+
+// loadSession continues the saved session id, showing its conversation.
+func (m *model) loadSession(id string) {
+	sess, err := m.opts.Sessions.Get(id)
+	if err != nil {
+		m.status = "Open session: " + err.Error()
+		return
+	}
+	m.sessionID, m.entries = sess.ID, nil
+	for _, msg := range sess.Messages {
+		content := strings.TrimSpace(msg.Content)
+		switch msg.Role {
+		case "user":
+			if content != "" {
+				m.entries = append(m.entries, entry{kind: userEntry, text: content})
+			}
+		case "assistant":
+			if content != "" {
+				m.entries = append(m.entries, entry{kind: assistantEntry, text: content})
+			}
+			for _, call := range msg.ToolCalls {
+				m.entries = append(m.entries, entry{kind: toolEntry, text: call.Name, done: true})
+			}
+		}
+	}
+	m.refresh()
+	m.convo.GotoBottom()
+	m.status = "Continuing session " + sess.ID
+}
+
+func (m *model) openMemory(daily bool) {
+	if m.opts.Memory == nil {
+		m.status = "No memory store."
+		return
+	}
+	read := m.opts.Memory.ReadLongTerm
+	if daily {
+		read = m.opts.Memory.ReadToday
+	}
+	content, err := read()
+	if err != nil {
+		m.status = "Read memory: " + err.Error()
+		return
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		content = m.styles.dim.Render("Nothing here yet.")
+	} else {
+		content = ansi.Wrap(content, m.width, "")
+	}
+	m.memory.SetContent(content)
+	m.memory.GotoTop()
+	m.daily = daily
+	m.screen = memoryScreen
+}
+
+func (m *model) memoryKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "ctrl+c", "ctrl+o":
+		m.screen = chatScreen
+		return nil
+	case "tab":
+		m.openMemory(!m.daily)
+		return nil
+	}
+	var cmd tea.Cmd
+	m.memory, cmd = m.memory.Update(msg)
+	return cmd
+}
+
+// resize lays the screens out for a terminal of width by height cells.
+// Every screen has a title bar and a footer line; the chat also has the
+// input line.
+func (m *model) resize(width, height int) {
+	m.width, m.height = max(width, 20), max(height, 5)
+	m.convo.Width, m.convo.Height = m.width, m.height-3
+	m.memory.Width, m.memory.Height = m.width, m.height-2
+	m.sessions.SetSize(m.width, m.height-2)
+	m.input.Width = m.width - lipgloss.Width(m.input.Prompt) - 1
+	m.refresh()
+}
+
+// refresh redraws the conversation into its viewport, which keeps
+// following the end unless it has been scrolled up.
+func (m *model) refresh() {
+	follow := m.convo.AtBottom()
+	m.convo.SetContent(m.conversation())
+	if follow {
+		m.convo.GotoBottom()
+	}
+}
+
+// conversation renders the entries as wrapped, styled lines.
+func (m *model) conversation() string {
+	var lines []string
+	add := func(style lipgloss.Style, text string, width int, first, rest string) {
+		for i, l := range strings.Split(ansi.Wrap(text, width, ""), "\n") {
+			prefix := rest
+			if i == 0 {
+				prefix = first
+			}
+			lines = append(lines, style.Render(prefix+l))
+		}
+	}
+	for i, e := range m.entries {
+		if i > 0 && (e.kind == userEntry || m.entries[i-1].kind == userEntry) {
+			lines = append(lines, "")
+		}
+		switch e.kind {
+		case userEntry:
+			add(m.styles.user, e.text, m.width-2, "› ", "  ")
+		case assistantEntry:
+			add(lipgloss.NewStyle(), e.text, m.width, "", "")
+		case toolEntry:
+			mark := m.spinner.View()
+			switch {
+			case e.failed:
+				mark = "✗"
+			case e.done:
+				mark = "✓"
+			}
+			lines = append(lines, m.styles.dim.Render(ansi.Truncate("  "+mark+" tool "+e.text, m.width, "")))
+		case infoEntry:
+			add(m.styles.dim, e.text, m.width, "", "")
+		case errorEntry:
+			add(m.styles.err, "Error: "+e.text, m.width, "", "")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *model) View() string {
+	switch m.screen {
+	case sessionsScreen:
+		return lipgloss.JoinVertical(lipgloss.Left,
+			m.bar(" Sessions", "Enter open · Esc back "),
+			m.sessionsView(),
+			m.footer(m.status))
+	case memoryScreen:
+		title, other := " Memory · MEMORY.md", "Tab today's notes"
+		if m.daily {
+			title, other = " Memory · today's notes", "Tab MEMORY.md"
+		}
+		return lipgloss.JoinVertical(lipgloss.Left,
+			m.bar(title, other+" · Esc back "),
+			m.memory.View(),
+			m.footer(m.status))
+	}
+
+	title := " myclaw"
+	if m.opts.Model != "" {
+		title += " · " + m.opts.Model
+	}
+	title += " · " + m.sessionID
+	right := ""
+	if m.running {
+		right = m.spinner.View() + " answering "
+	}
+	input := m.input.View()
+	if m.asking != nil {
+		input = m.styles.ask.Render(ansi.Truncate(fmt.Sprintf("Allow %s? [y/N]", m.asking.req), m.width, ""))
+	}
+	footer := chatHelp
+	switch {
+	case m.status != "":
+		footer = m.status
+	case !m.convo.AtBottom():
+		footer = fmt.Sprintf("%d more lines below · PgDn to scroll down", m.convo.TotalLineCount()-m.convo.YOffset-m.convo.Height)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left,
+		m.bar(title, right),
+		m.convo.View(),
+		input,
+		m.footer(footer))
+}
+
+func (m *model) sessionsView() string {
+	if len(m.sessions.Items()) == 0 {
+		return lipgloss.NewStyle().Height(m.height - 2).Render(m.styles.dim.Render("No saved sessions."))
+	}
+	return m.sessions.View()
+}
+
+// bar draws a full-width title bar with left and right text.
+func (m *model) bar(left, right string) string {
+	right = ansi.Truncate(right, m.width, "")
+	gap := m.width - lipgloss.Width(right)
+	left = ansi.Truncate(left, gap, "")
+	return m.styles.bar.Render(left + strings.Repeat(" ", gap-lipgloss.Width(left)) + right)
+}
+
+func (m *model) footer(text string) string {
+	return m.styles.dim.Render(ansi.Truncate(text, m.width, ""))
+}
+
+// styles are the lipgloss styles of the screens. Without color only
+// reverse video, bold and faint text are used.
+type styles struct {
+	bar, user, dim, err, ask, spinner lipgloss.Style
+}
+
+func newStyles(color bool) styles {
+	s := styles{
+		bar:  lipgloss.NewStyle().Reverse(true),
+		user: lipgloss.NewStyle().Bold(true),
+		dim:  lipgloss.NewStyle().Faint(true),
+		err:  lipgloss.NewStyle().Bold(true),
+		ask:  lipgloss.NewStyle().Bold(true),
+	}
+	if color {
+		s.user = s.user.Foreground(lipgloss.Color("6"))
+		s.err = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+		s.ask = s.ask.Foreground(lipgloss.Color("3"))
+		s.spinner = lipgloss.NewStyle().Foreground(lipgloss.Color("5"))
+	}
+	return s
+}
@@ -0,0 +1,282 @@
+package tui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/charmbracelet/bubbles/cursor"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/permission"
+	"github.com/stellarlinkco/myclaw/internal/session"
+)
+
+// driver runs a model the way tea.Program does: commands run in their own
+// goroutines and the messages they return go back to Update.
+type driver struct {
+	t    *testing.T
+	m    *model
+	msgs chan tea.Msg
+}
+
+// testModel returns a driver for a model on an 80x12 screen whose runs
+// answer with events.
+func testModel(t *testing.T, opts Options, events ...api.StreamEvent) *driver {
+	t.Helper()
+	if opts.Stream == nil {
+		opts.Stream = func(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error) {
+			ch := make(chan api.StreamEvent, len(events))
+			for _, ev := range events {
+				ch <- ev
+			}
+			close(ch)
+			return ch, nil
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	d := &driver{t: t, m: newModel(ctx, opts), msgs: make(chan tea.Msg, 64)}
+	d.m.Update(tea.WindowSizeMsg{Width: 80, Height: 12})
+	d.m.input.Cursor.SetMode(cursor.CursorStatic)
+	d.exec(d.m.Init())
+	return d
+}
+
+func (d *driver) exec(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	go func() {
+		if msg := cmd(); msg != nil {
+			d.msgs <- msg
+		}
+	}()
+}
+
+// send updates the model with msg and reports whether it quit.
+func (d *driver) send(msg tea.Msg) bool {
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		for _, cmd := range batch {
+			d.exec(cmd)
+		}
+		return false
+	}
+	_, cmd := d.m.Update(msg)
+	if cmd == nil {
+		return false
+	}
+	// Commands of keys return at once, with the cursor not blinking, so
+	// they are run here, where quitting can be seen.
+	if _, ok := msg.(tea.KeyMsg); ok {
+		switch msg := cmd().(type) {
+		case tea.QuitMsg:
+			return true
+		case nil:
+		default:
+			d.msgs <- msg
+		}
+		return false
+	}
+	d.exec(cmd)
+	return false
+}
+
+func (d *driver) key(k tea.KeyType) bool {
+	return d.send(tea.KeyMsg{Type: k})
+}
+
+func (d *driver) typeText(s string) {
+	for _, r := range s {
+		d.send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+// wait handles messages until cond holds.
+func (d *driver) wait(cond func() bool) {
+	d.t.Helper()
+	for !cond() {
+		select {
+		case msg := <-d.msgs:
+			d.send(msg)
+		case <-time.After(5 * time.Second):
+			d.t.Fatal("timed out")
+		}
+	}
+}
+
+func (d *driver) view() string {
+	return d.m.View()
+}
+
+func TestModel_Stream(t *testing.T) {
+	failed := true
+	d := testModel(t, Options{Model: "claude"},
+		api.StreamEvent{Type: api.EventContentBlockDelta, Delta: &api.Delta{Type: "text_delta", Text: "Let me "}},
+		api.StreamEvent{Type: api.EventContentBlockDelta, Delta: &api.Delta{Type: "text_delta", Text: "look."}},
+		api.StreamEvent{Type: api.EventToolExecutionStart, ToolUseID: "t1", Name: "bash"},
+		api.StreamEvent{Type: api.EventToolExecutionResult, ToolUseID: "t1", IsError: &failed},
+		api.StreamEvent{Type: api.EventContentBlockDelta, Delta: &api.Delta{Type: "text_delta", Text: "Done."}},
+	)
+	d.typeText("list files")
+	d.key(tea.KeyEnter)
+	if !d.m.running || d.m.input.Value() != "" {
+		t.Fatalf("not running after Enter: running=%v input=%q", d.m.running, d.m.input.Value())
+	}
+	d.wait(func() bool { return !d.m.running })
+
+	view := d.view()
+	for _, want := range []string{"myclaw · claude", "› list files", "Let me look.", "✗ tool bash", "Done."} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+	if lines := strings.Split(view, "\n"); len(lines) != 12 {
+		t.Errorf("view has %d lines, want 12:\n%s", len(lines), view)
+	}
+}
+
+func TestModel_Cancel(t *testing.T) {
+	d := testModel(t, Options{Stream: func(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error) {
+		ch := make(chan api.StreamEvent)
+		go func() {
+			<-ctx.Done()
+			close(ch)
+		}()
+		return ch, nil
+	}})
+	d.typeText("hi")
+	d.key(tea.KeyEnter)
+	if d.key(tea.KeyCtrlC) {
+		t.Fatal("Ctrl-C during a run quit")
+	}
+	d.wait(func() bool { return !d.m.running })
+	if !strings.Contains(d.view(), "Stopped.") {
+		t.Errorf("view:\n%s", d.view())
+	}
+	if !d.key(tea.KeyCtrlC) {
+		t.Error("Ctrl-C when idle did not quit")
+	}
+}
+
+func TestModel_Ask(t *testing.T) {
+	var d *driver
+	d = testModel(t, Options{Stream: func(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error) {
+		ch := make(chan api.StreamEvent, 1)
+		go func() {
+			defer close(ch)
+			ok, _ := d.m.ask(ctx, permission.Request{Tool: "Bash", Target: "rm:-rf build"})
+			if ok {
+				ch <- api.StreamEvent{Type: api.EventContentBlockDelta, Delta: &api.Delta{Type: "text_delta", Text: "removed"}}
+			}
+		}()
+		return ch, nil
+	}})
+	d.typeText("clean up")
+	d.key(tea.KeyEnter)
+	d.wait(func() bool { return d.m.asking != nil })
+	if !strings.Contains(d.view(), "Allow ") {
+		t.Fatalf("no approval prompt:\n%s", d.view())
+	}
+	d.typeText("y")
+	d.wait(func() bool { return !d.m.running })
+	if view := d.view(); !strings.Contains(view, "removed") || !strings.Contains(view, "Allowed") {
+		t.Errorf("view:\n%s", view)
+	}
+}
+
+func TestModel_Scroll(t *testing.T) {
+	d := testModel(t, Options{})
+	for i := 0; i < 30; i++ {
+		d.m.entries = append(d.m.entries, entry{kind: assistantEntry, text: "line " + string(rune('a'+i%26))})
+	}
+	d.m.refresh()
+	if !strings.Contains(d.view(), "line d") { // the 30th line
+		t.Fatalf("not at the bottom:\n%s", d.view())
+	}
+	d.key(tea.KeyPgUp)
+	view := d.view()
+	if strings.Contains(view, "line d\n") || !strings.Contains(view, "more lines below") {
+		t.Errorf("did not scroll up:\n%s", view)
+	}
+	// New text does not pull the view back down while scrolled up.
+	d.m.event(api.StreamEvent{Type: api.EventError, Output: "late"})
+	if strings.Contains(d.view(), "late") {
+		t.Errorf("scrolled to the end:\n%s", d.view())
+	}
+	for i := 0; i < 10; i++ {
+		d.key(tea.KeyPgUp)
+	}
+	if !strings.Contains(d.view(), "line a") {
+		t.Errorf("did not reach the top:\n%s", d.view())
+	}
+}
+
+func TestModel_SessionsAndMemory(t *testing.T) {
+	ws := t.TempDir()
+	dir := session.HistoryDir(ws)
+	os.MkdirAll(dir, 0755)
+	history := `{"version":1,"session_id":"old","messages":[{"role":"user","content":"what is up"},{"role":"assistant","content":"not much"}]}`
+	if err := os.WriteFile(filepath.Join(dir, session.FileName("old")), []byte(history), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mem := memory.NewMemoryStore(ws)
+	if err := mem.WriteLongTerm("User likes tea."); err != nil {
+		t.Fatal(err)
+	}
+	d := testModel(t, Options{Sessions: session.NewStore(ws), Memory: mem})
+
+	d.key(tea.KeyCtrlS)
+	if d.m.screen != sessionsScreen || !strings.Contains(d.view(), "what is up") {
+		t.Fatalf("sessions view:\n%s", d.view())
+	}
+	d.key(tea.KeyEnter)
+	if d.m.screen != chatScreen || d.m.sessionID != "old" || !strings.Contains(d.view(), "not much") {
+		t.Errorf("session not opened: %s\n%s", d.m.sessionID, d.view())
+	}
+
+	d.typeText("/memory")
+	d.key(tea.KeyEnter)
+	if d.m.screen != memoryScreen || !strings.Contains(d.view(), "User likes tea.") {
+		t.Fatalf("memory view:\n%s", d.view())
+	}
+	d.key(tea.KeyTab)
+	if !d.m.daily || !strings.Contains(d.view(), "Nothing here yet.") {
+		t.Errorf("today's notes:\n%s", d.view())
+	}
+	d.key(tea.KeyEsc)
+	if d.m.screen != chatScreen {
+		t.Error("Esc did not go back")
+	}
+}
+
+func TestModel_Input(t *testing.T) {
+	d := testModel(t, Options{})
+	d.typeText("helo")
+	d.key(tea.KeyLeft)
+	d.typeText("l")
+	d.key(tea.KeyHome)
+	d.key(tea.KeyDelete)
+	if d.m.input.Value() != "ello" || d.m.input.Position() != 0 {
+		t.Errorf("input = %q, cursor %d", d.m.input.Value(), d.m.input.Position())
+	}
+	if d.key(tea.KeyCtrlD) {
+		t.Error("Ctrl-D with input quit")
+	}
+	d.key(tea.KeyCtrlU)
+	d.key(tea.KeyEnd)
+	d.key(tea.KeyCtrlU)
+	if !d.key(tea.KeyCtrlD) {
+		t.Errorf("Ctrl-D on empty input did not quit, input %q", d.m.input.Value())
+	}
+
+	d.typeText("/nope")
+	d.key(tea.KeyEnter)
+	if !strings.Contains(d.view(), "Unknown command /nope") {
+		t.Errorf("view:\n%s", d.view())
+	}
+}
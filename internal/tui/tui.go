@@ -0,0 +1,80 @@
+// Package tui is a full-screen terminal interface to the agent: a
+// scrollable conversation with streamed answers and tool activity, a
+// session switcher and a memory viewer. It is a bubbletea program built
+// from bubbles components and styled with lipgloss.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/stellarlinkco/myclaw/internal/memory"
+	"github.com/stellarlinkco/myclaw/internal/session"
+	"golang.org/x/term"
+)
+
+// ErrNotTerminal is returned by Run when input or output is not a terminal.
+var ErrNotTerminal = errors.New("tui needs an interactive terminal")
+
+// Options configures Run.
+type Options struct {
+	// Stream runs a prompt, streaming its events. Required.
+	Stream func(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error)
+	// Sessions and Memory back the session switcher and memory viewer;
+	// either may be nil.
+	Sessions *session.Store
+	Memory   *memory.MemoryStore
+	// Model is shown in the header.
+	Model string
+	// SessionID is the session to start in; empty starts a new one.
+	SessionID string
+	// Color enables styling beyond reverse video and bold.
+	Color bool
+	// In and Out default to os.Stdin and os.Stdout.
+	In, Out *os.File
+}
+
+// Run shows the TUI until the user quits or ctx is done.
+func Run(ctx context.Context, opts Options) error {
+	if opts.Stream == nil {
+		return errors.New("tui: Stream is required")
+	}
+	in, out := opts.In, opts.Out
+	if in == nil {
+		in = os.Stdin
+	}
+	if out == nil {
+		out = os.Stdout
+	}
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return ErrNotTerminal
+	}
+	if !opts.Color {
+		// The styles then use no colors, but the cursor and selection
+		// still need reverse video to show.
+		lipgloss.SetColorProfile(termenv.ANSI)
+	}
+
+	// Cancelling ctx stops a run in progress and any approval waiting.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	p := tea.NewProgram(newModel(ctx, opts),
+		tea.WithContext(ctx),
+		tea.WithInput(in),
+		tea.WithOutput(out),
+		tea.WithAltScreen(),
+	)
+	if _, err := p.Run(); err != nil {
+		if ctx.Err() != nil && errors.Is(err, tea.ErrProgramKilled) {
+			return ctx.Err()
+		}
+		return fmt.Errorf("tui: %w", err)
+	}
+	return nil
+}
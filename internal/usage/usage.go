@@ -0,0 +1,235 @@
+// Package usage keeps a ledger of the tokens each agent run used and what
+// it cost, for reports by day, channel, model and session.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const dayLayout = "2006-01-02"
+
+// Path returns where the ledger is kept for workspace.
+func Path(workspace string) string {
+	return filepath.Join(workspace, ".claude", "usage.jsonl")
+}
+
+// DefaultPrices are list prices in USD per million tokens. They are
+// estimates; tokenTracking.prices overrides them.
+var DefaultPrices = map[string]config.Price{
+	"claude-opus-4":     {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheRead: 0.1, CacheWrite: 1.25},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+	"gpt-4o":            {Input: 2.5, Output: 10, CacheRead: 1.25},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.6, CacheRead: 0.075},
+	"gpt-4.1":           {Input: 2, Output: 8, CacheRead: 0.5},
+	"gpt-4.1-mini":      {Input: 0.4, Output: 1.6, CacheRead: 0.1},
+	"gemini-2.5-pro":    {Input: 1.25, Output: 10, CacheRead: 0.31},
+	"gemini-2.5-flash":  {Input: 0.3, Output: 2.5, CacheRead: 0.075},
+}
+
+// PriceFor returns the price of model: the entry of prices, then of
+// DefaultPrices, with the longest matching prefix.
+func PriceFor(model string, prices map[string]config.Price) (config.Price, bool) {
+	for _, table := range []map[string]config.Price{prices, DefaultPrices} {
+		best := ""
+		for prefix := range table {
+			if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+				best = prefix
+			}
+		}
+		if best != "" {
+			return table[best], true
+		}
+	}
+	return config.Price{}, false
+}
+
+// Entry is one agent run in the ledger.
+type Entry struct {
+	Time             time.Time `json:"time"`
+	Session          string    `json:"session,omitempty"`
+	Channel          string    `json:"channel,omitempty"`
+	Model            string    `json:"model"`
+	InputTokens      int       `json:"inputTokens"`
+	OutputTokens     int       `json:"outputTokens"`
+	CacheReadTokens  int       `json:"cacheReadTokens,omitempty"`
+	CacheWriteTokens int       `json:"cacheWriteTokens,omitempty"`
+	CostUSD          float64   `json:"costUsd"`
+	// SavedUSD is what prompt caching saved: cache reads at the input
+	// price less what they cost, less the premium paid for cache writes.
+	SavedUSD float64 `json:"savedUsd,omitempty"`
+	Priced   bool    `json:"priced"` // false when the model has no known price
+}
+
+// Ledger appends entries to a JSON Lines file.
+type Ledger struct {
+	path   string
+	prices map[string]config.Price
+
+	mu      sync.Mutex
+	day     string  // the day dayCost covers
+	dayCost float64 // estimated cost so far on day; -1 until read
+}
+
+func NewLedger(path string, prices map[string]config.Price) *Ledger {
+	return &Ledger{path: path, prices: prices, dayCost: -1}
+}
+
+// Record prices e and appends it. It returns the estimated cost of e's day
+// before and after e.
+func (l *Ledger) Record(e Entry) (before, after float64, err error) {
+	if price, ok := PriceFor(e.Model, l.prices); ok {
+		e.Priced = true
+		e.CostUSD = (float64(e.InputTokens)*price.Input +
+			float64(e.OutputTokens)*price.Output +
+			float64(e.CacheReadTokens)*price.CacheRead +
+			float64(e.CacheWriteTokens)*price.CacheWrite) / 1e6
+		if price.CacheRead > 0 {
+			e.SavedUSD = (float64(e.CacheReadTokens)*(price.Input-price.CacheRead) -
+				float64(e.CacheWriteTokens)*max(price.CacheWrite-price.Input, 0)) / 1e6
+		}
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	day := e.Time.Local().Format(dayLayout)
+	if l.day != day || l.dayCost < 0 {
+		l.day, l.dayCost = day, 0
+		start, _ := time.ParseInLocation(dayLayout, day, time.Local)
+		entries, err := Read(l.path, start)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, prev := range entries {
+			if prev.Time.Local().Format(dayLayout) == day {
+				l.dayCost += prev.CostUSD
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return 0, 0, err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return 0, 0, err
+	}
+	before = l.dayCost
+	l.dayCost += e.CostUSD
+	return before, l.dayCost, nil
+}
+
+// Read returns the entries at path from since on, oldest first. A missing
+// ledger has no entries; unreadable lines are skipped.
+func Read(path string, since time.Time) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Totals adds up the entries of one group.
+type Totals struct {
+	Key              string  `json:"key"`
+	Runs             int     `json:"runs"`
+	InputTokens      int     `json:"inputTokens"`
+	OutputTokens     int     `json:"outputTokens"`
+	CacheReadTokens  int     `json:"cacheReadTokens,omitempty"`
+	CacheWriteTokens int     `json:"cacheWriteTokens,omitempty"`
+	CostUSD          float64 `json:"costUsd"`
+	SavedUSD         float64 `json:"savedUsd,omitempty"`
+	UnpricedRuns     int     `json:"unpricedRuns,omitempty"`
+}
+
+func (t *Totals) add(e Entry) {
+	t.Runs++
+	t.InputTokens += e.InputTokens
+	t.OutputTokens += e.OutputTokens
+	t.CacheReadTokens += e.CacheReadTokens
+	t.CacheWriteTokens += e.CacheWriteTokens
+	t.CostUSD += e.CostUSD
+	t.SavedUSD += e.SavedUSD
+	if !e.Priced {
+		t.UnpricedRuns++
+	}
+}
+
+// Groupings for Summarize.
+var Groupings = map[string]func(Entry) string{
+	"day":     func(e Entry) string { return e.Time.Local().Format(dayLayout) },
+	"channel": func(e Entry) string { return orDash(e.Channel) },
+	"model":   func(e Entry) string { return orDash(e.Model) },
+	"session": func(e Entry) string { return orDash(e.Session) },
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Summarize groups entries by key and totals each group, along with all
+// of them. Days come in order; other groups come costliest first.
+func Summarize(entries []Entry, by string) (groups []Totals, total Totals) {
+	key := Groupings[by]
+	index := map[string]int{}
+	total.Key = "total"
+	for _, e := range entries {
+		k := key(e)
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, Totals{Key: k})
+		}
+		groups[i].add(e)
+		total.add(e)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if by == "day" || groups[i].CostUSD == groups[j].CostUSD {
+			return groups[i].Key < groups[j].Key
+		}
+		return groups[i].CostUSD > groups[j].CostUSD
+	})
+	return groups, total
+}
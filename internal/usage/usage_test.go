@@ -0,0 +1,115 @@
+package usage
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestPriceFor(t *testing.T) {
+	t.Parallel()
+
+	prices := map[string]config.Price{"claude-sonnet-4-5": {Input: 2, Output: 10}}
+	tests := []struct {
+		model string
+		input float64
+		ok    bool
+	}{
+		{"claude-sonnet-4-5-20250929", 2, true}, // config wins over the defaults
+		{"claude-sonnet-4-20250514", 3, true},
+		{"gpt-4o-mini-2024-07-18", 0.15, true}, // longest prefix, not gpt-4o
+		{"gpt-4o-2024-08-06", 2.5, true},
+		{"local-llama", 0, false},
+	}
+	for _, tt := range tests {
+		price, ok := PriceFor(tt.model, prices)
+		if ok != tt.ok || price.Input != tt.input {
+			t.Errorf("PriceFor(%q) = %+v, %v; want input %v, %v", tt.model, price, ok, tt.input, tt.ok)
+		}
+	}
+}
+
+func TestLedger(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".claude", "usage.jsonl")
+	yesterday := time.Date(2026, 10, 15, 23, 0, 0, 0, time.Local)
+	today := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+
+	ledger := NewLedger(path, nil)
+	record := func(e Entry) (float64, float64) {
+		t.Helper()
+		before, after, err := ledger.Record(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return before, after
+	}
+	record(Entry{Time: yesterday, Channel: "telegram", Model: "claude-opus-4-1", OutputTokens: 1_000_000})
+	before, after := record(Entry{Time: today, Session: "telegram:42", Channel: "telegram", Model: "claude-sonnet-4-5", InputTokens: 1_000_000, CacheReadTokens: 1_000_000})
+	if before != 0 || !near(after, 3.3) {
+		t.Errorf("first run today: before %v, after %v", before, after)
+	}
+
+	// A new ledger on the same file picks up the day's spend, as after a restart.
+	ledger = NewLedger(path, nil)
+	before, after = record(Entry{Time: today.Add(time.Hour), Session: "cli", Channel: "cli", Model: "local-llama", InputTokens: 10})
+	if !near(before, 3.3) || !near(after, 3.3) {
+		t.Errorf("after restart: before %v, after %v", before, after)
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("not json\n")
+	f.Close()
+
+	all, err := Read(path, time.Time{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Read = %d entries, %v", len(all), err)
+	}
+	if all[2].Priced || !all[1].Priced {
+		t.Errorf("priced = %v, %v", all[1].Priced, all[2].Priced)
+	}
+	// 1M tokens read from the cache at $0.3/M instead of $3/M.
+	if !near(all[1].SavedUSD, 2.7) || all[0].SavedUSD != 0 {
+		t.Errorf("saved = %v, %v", all[0].SavedUSD, all[1].SavedUSD)
+	}
+	if since, _ := Read(path, today); len(since) != 2 {
+		t.Errorf("Read since today = %d entries", len(since))
+	}
+	if missing, err := Read(filepath.Join(t.TempDir(), "none.jsonl"), time.Time{}); missing != nil || err != nil {
+		t.Errorf("missing ledger = %v, %v", missing, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	entries := []Entry{
+		{Time: day.AddDate(0, 0, -1), Channel: "cli", InputTokens: 10, CostUSD: 0.5, Priced: true},
+		{Time: day, Channel: "telegram", InputTokens: 20, CostUSD: 1, Priced: true},
+		{Time: day, Channel: "telegram", InputTokens: 30, OutputTokens: 5},
+		{Time: day, InputTokens: 1, CostUSD: 0.1, Priced: true},
+	}
+
+	groups, total := Summarize(entries, "channel")
+	if len(groups) != 3 || groups[0].Key != "telegram" || groups[0].Runs != 2 || groups[0].UnpricedRuns != 1 || groups[2].Key != "-" {
+		t.Errorf("by channel = %+v", groups)
+	}
+	if total.Runs != 4 || total.InputTokens != 61 || !near(total.CostUSD, 1.6) || total.UnpricedRuns != 1 {
+		t.Errorf("total = %+v", total)
+	}
+
+	groups, _ = Summarize(entries, "day")
+	if len(groups) != 2 || groups[0].Key != "2026-10-15" || groups[1].Runs != 3 {
+		t.Errorf("by day = %+v", groups)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
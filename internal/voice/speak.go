@@ -0,0 +1,216 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+const (
+	// DefaultTTSModel and DefaultTTSVoice are used when none is set.
+	DefaultTTSModel = "tts-1"
+	DefaultTTSVoice = "alloy"
+
+	openAIAPIURL = "https://api.openai.com/v1"
+	// maxSpeechChars caps what one request speaks; the endpoint takes
+	// 4096 characters.
+	maxSpeechChars = 4000
+)
+
+var ttsClient = &http.Client{Timeout: 2 * time.Minute}
+
+// defaultPlayers are tried in order when no player is set.
+var defaultPlayers = [][]string{
+	{"afplay"},
+	{"paplay"},
+	{"aplay", "-q"},
+	{"play", "-q"},
+	{"ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet"},
+}
+
+// Speaker reads text aloud.
+type Speaker interface {
+	Speak(ctx context.Context, text string) error
+}
+
+// NewSpeaker returns the speaker cfg selects, or nil when speech is off.
+func NewSpeaker(cfg config.TTSConfig) (Speaker, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "openai":
+		synth, err := NewSynthesizer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		s := &openAISpeaker{synth: synth, play: cfg.Play}
+		if len(s.play) == 0 {
+			for _, p := range defaultPlayers {
+				if _, err := exec.LookPath(p[0]); err == nil {
+					s.play = p
+					break
+				}
+			}
+		}
+		if len(s.play) == 0 {
+			return nil, errors.New("media.tts: no audio player found; install sox (play) or ffmpeg (ffplay), or set media.tts.play")
+		}
+		return s, nil
+	case "command":
+		if len(cfg.Command) == 0 {
+			return nil, errors.New("media.tts: command needs the command to run")
+		}
+		return &commandSpeaker{command: cfg.Command}, nil
+	default:
+		return nil, fmt.Errorf("media.tts: unknown provider %q", cfg.Provider)
+	}
+}
+
+// commandSpeaker pipes the text to a program that speaks it.
+type commandSpeaker struct {
+	command []string
+}
+
+func (s *commandSpeaker) Speak(ctx context.Context, text string) error {
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("speak: %s: %v %s", s.command[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// openAISpeaker plays what a Synthesizer says.
+type openAISpeaker struct {
+	synth *Synthesizer
+	play  []string
+}
+
+func (s *openAISpeaker) Speak(ctx context.Context, text string) error {
+	audio, err := s.synth.Synthesize(ctx, text, SpeechOptions{Format: FormatWAV})
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "myclaw-voice-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "reply.wav")
+	if err := os.WriteFile(file, audio, 0o600); err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, s.play[0], withFile(s.play[1:], file)...)
+	if out, err := cmd.CombinedOutput(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("speak: %s: %v %s", s.play[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Audio formats a Synthesizer returns.
+const (
+	FormatWAV  = "wav"
+	FormatOpus = "opus" // Opus in an OGG container, as voice notes are
+)
+
+// SpeechOptions adjust one synthesis. Empty fields take the defaults of
+// the Synthesizer.
+type SpeechOptions struct {
+	Format string
+	Voice  string
+	Speed  float64 // 0.25 to 4
+}
+
+// Synthesizer turns text into audio with an OpenAI-compatible /audio/speech
+// endpoint.
+type Synthesizer struct {
+	baseURL, apiKey, model, voice string
+}
+
+// NewSynthesizer returns the synthesizer of cfg, or nil when speech is
+// off. Only the openai provider makes audio; the command provider speaks
+// on its own.
+func NewSynthesizer(cfg config.TTSConfig) (*Synthesizer, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "openai":
+	default:
+		return nil, fmt.Errorf("media.tts: provider %q cannot make audio files; use openai", cfg.Provider)
+	}
+	if cfg.APIKey == "" {
+		return nil, errors.New("media.tts: openai needs an API key")
+	}
+	s := &Synthesizer{baseURL: cfg.BaseURL, apiKey: cfg.APIKey, model: cfg.Model, voice: cfg.Voice}
+	if s.baseURL == "" {
+		s.baseURL = openAIAPIURL
+	}
+	if s.model == "" {
+		s.model = DefaultTTSModel
+	}
+	if s.voice == "" {
+		s.voice = DefaultTTSVoice
+	}
+	return s, nil
+}
+
+// Synthesize returns text spoken, in opts.Format (default WAV).
+func (s *Synthesizer) Synthesize(ctx context.Context, text string, opts SpeechOptions) ([]byte, error) {
+	if r := []rune(text); len(r) > maxSpeechChars {
+		text = string(r[:maxSpeechChars])
+	}
+	req := map[string]any{
+		"model":           s.model,
+		"voice":           s.voice,
+		"input":           text,
+		"response_format": FormatWAV,
+	}
+	if opts.Format != "" {
+		req["response_format"] = opts.Format
+	}
+	if opts.Voice != "" {
+		req["voice"] = opts.Voice
+	}
+	if opts.Speed != 0 {
+		req["speed"] = opts.Speed
+	}
+	body, _ := json.Marshal(req)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(s.baseURL, "/")+"/audio/speech", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+s.apiKey)
+	resp, err := ttsClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("speak: %w", err)
+	}
+	defer resp.Body.Close()
+	audio, err := io.ReadAll(io.LimitReader(resp.Body, 50<<20))
+	if err != nil {
+		return nil, fmt.Errorf("speak: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var out struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(audio, &out) == nil && out.Error.Message != "" {
+			return nil, fmt.Errorf("speak: %s: %s", resp.Status, out.Error.Message)
+		}
+		return nil, fmt.Errorf("speak: %s", resp.Status)
+	}
+	return audio, nil
+}
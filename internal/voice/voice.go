@@ -0,0 +1,144 @@
+// Package voice records speech from the microphone and speaks replies
+// aloud, through programs found on the system, for myclaw agent --voice.
+package voice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/markdown"
+)
+
+// fileArg stands for the audio file in a command.
+const fileArg = "{file}"
+
+// stopGrace is how long a recorder has to finish its file after being
+// interrupted.
+const stopGrace = 3 * time.Second
+
+// defaultRecorders are tried in order when no record command is set. sox's
+// rec waits for speech and stops after two seconds of silence.
+var defaultRecorders = [][]string{
+	{"rec", "-q", "-c", "1", "-r", "16000", "-b", "16", fileArg, "silence", "1", "0.1", "3%", "1", "2.0", "3%"},
+	{"arecord", "-q", "-f", "S16_LE", "-c", "1", "-r", "16000", fileArg},
+}
+
+// ErrNoRecorder is returned when no record command is set and none of the
+// known recorders is installed.
+var ErrNoRecorder = errors.New("no audio recorder found; install sox (rec) or alsa-utils (arecord), or set media.record")
+
+// Recorder records the microphone with an external program.
+type Recorder struct {
+	command []string
+}
+
+// NewRecorder returns a recorder running command, or the first known
+// recorder installed when command is empty.
+func NewRecorder(command []string) (*Recorder, error) {
+	if len(command) > 0 {
+		return &Recorder{command: command}, nil
+	}
+	for _, c := range defaultRecorders {
+		if _, err := exec.LookPath(c[0]); err == nil {
+			return &Recorder{command: c}, nil
+		}
+	}
+	return nil, ErrNoRecorder
+}
+
+// Record records until stop is closed or the program ends by itself, and
+// returns the WAV audio it wrote.
+func (r *Recorder) Record(ctx context.Context, stop <-chan struct{}) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "myclaw-voice-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "speech.wav")
+
+	cmd := exec.CommandContext(ctx, r.command[0], withFile(r.command[1:], file)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var waitErr error
+	select {
+	case waitErr = <-done:
+	case <-stop:
+		// An interrupt lets the recorder finish the WAV header; Windows
+		// cannot send one, so it gets killed there.
+		if runtime.GOOS == "windows" || cmd.Process.Signal(os.Interrupt) != nil {
+			cmd.Process.Kill()
+		}
+		select {
+		case <-done:
+		case <-time.After(stopGrace):
+			cmd.Process.Kill()
+			<-done
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	audio, err := os.ReadFile(file)
+	if err != nil || len(audio) == 0 {
+		if waitErr != nil {
+			return nil, fmt.Errorf("record: %s: %v %s", r.command[0], waitErr, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("record: %s wrote no audio", r.command[0])
+	}
+	return audio, nil
+}
+
+// withFile puts file in place of {file} in args, or after them when there
+// is none.
+func withFile(args []string, file string) []string {
+	out := make([]string, 0, len(args)+1)
+	found := false
+	for _, a := range args {
+		if strings.Contains(a, fileArg) {
+			a, found = strings.ReplaceAll(a, fileArg, file), true
+		}
+		out = append(out, a)
+	}
+	if !found {
+		out = append(out, file)
+	}
+	return out
+}
+
+var (
+	codeBlockRe = regexp.MustCompile("(?s)```.*?(```|$)")
+	linkURLRe   = regexp.MustCompile(` \(https?://[^)\s]+\)`)
+	bareURLRe   = regexp.MustCompile(`https?://\S+`)
+)
+
+// Speakable turns a markdown reply into text worth reading aloud: markup,
+// code blocks and links are left out.
+func Speakable(md string) string {
+	md = codeBlockRe.ReplaceAllString(md, "\n(code omitted)\n")
+	text := markdown.Render(md, markdown.Options{})
+	text = linkURLRe.ReplaceAllString(text, "")
+	text = bareURLRe.ReplaceAllString(text, "(link)")
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		// Rules and heading underlines are drawn with box characters.
+		line = strings.Trim(line, " •│─═☐☑")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,126 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stellarlinkco/myclaw/internal/config"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	// Ends by itself, as rec does at a pause.
+	r, err := NewRecorder([]string{"sh", "-c", `printf RIFFdata > "$0"`, fileArg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := r.Record(context.Background(), nil)
+	if err != nil || string(audio) != "RIFFdata" {
+		t.Errorf("Record = %q, %v", audio, err)
+	}
+
+	// Runs until stopped, writing the file when interrupted.
+	r, _ = NewRecorder([]string{"sh", "-c", `trap 'printf stopped > "$0"; exit 0' INT; while :; do sleep 0.05; done`})
+	stop := make(chan struct{})
+	time.AfterFunc(200*time.Millisecond, func() { close(stop) })
+	audio, err = r.Record(context.Background(), stop)
+	if err != nil || string(audio) != "stopped" {
+		t.Errorf("stopped Record = %q, %v", audio, err)
+	}
+
+	r, _ = NewRecorder([]string{"sh", "-c", "echo no microphone >&2; exit 1"})
+	if _, err := r.Record(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "no microphone") {
+		t.Errorf("failing recorder error = %v", err)
+	}
+}
+
+func TestSpeakers(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	said := filepath.Join(dir, "said.txt")
+	s, err := NewSpeaker(config.TTSConfig{Provider: "command", Command: []string{"sh", "-c", `cat > "$0"`, said}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Speak(context.Background(), "Hello there"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(said); string(data) != "Hello there" {
+		t.Errorf("command speaker got %q", data)
+	}
+
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"bad key"}}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte("RIFFspeech"))
+	}))
+	defer srv.Close()
+	played := filepath.Join(dir, "played.wav")
+	s, err = NewSpeaker(config.TTSConfig{Provider: "openai", APIKey: "sk-test", BaseURL: srv.URL + "/v1", Voice: "nova",
+		Play: []string{"sh", "-c", `cp "$1" "$0"`, played}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Speak(context.Background(), "Good morning"); err != nil {
+		t.Fatal(err)
+	}
+	if got["input"] != "Good morning" || got["voice"] != "nova" || got["model"] != DefaultTTSModel || got["response_format"] != "wav" {
+		t.Errorf("request = %v", got)
+	}
+	if data, _ := os.ReadFile(played); string(data) != "RIFFspeech" {
+		t.Errorf("played %q", data)
+	}
+
+	s, _ = NewSpeaker(config.TTSConfig{Provider: "openai", APIKey: "wrong", BaseURL: srv.URL + "/v1", Play: []string{"true"}})
+	if err := s.Speak(context.Background(), "hi"); err == nil || !strings.Contains(err.Error(), "bad key") {
+		t.Errorf("bad key error = %v", err)
+	}
+
+	synth, err := NewSynthesizer(config.TTSConfig{Provider: "openai", APIKey: "sk-test", BaseURL: srv.URL + "/v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := synth.Synthesize(context.Background(), "Hi", SpeechOptions{Format: FormatOpus, Voice: "echo", Speed: 1.25})
+	if err != nil || string(audio) != "RIFFspeech" {
+		t.Errorf("Synthesize = %q, %v", audio, err)
+	}
+	if got["response_format"] != "opus" || got["voice"] != "echo" || got["speed"] != 1.25 {
+		t.Errorf("request = %v", got)
+	}
+	if _, err := NewSynthesizer(config.TTSConfig{Provider: "command", Command: []string{"say"}}); err == nil {
+		t.Error("NewSynthesizer accepted the command provider")
+	}
+
+	if s, err := NewSpeaker(config.TTSConfig{}); s != nil || err != nil {
+		t.Errorf("no provider = %v, %v", s, err)
+	}
+	for _, cfg := range []config.TTSConfig{{Provider: "openai"}, {Provider: "command"}, {Provider: "polly"}} {
+		if _, err := NewSpeaker(cfg); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+}
+
+func TestSpeakable(t *testing.T) {
+	t.Parallel()
+
+	md := "# Weather\n\nIt is **sunny**, see [the forecast](https://example.com/f).\n\n```sh\ncurl wttr.in\n```\n- Take https://example.com/x\n---"
+	want := "Weather\nIt is sunny, see the forecast.\n(code omitted)\nTake (link)"
+	if got := Speakable(md); got != want {
+		t.Errorf("Speakable =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,355 @@
+// Package xmpp is a minimal XMPP client: just enough to log in over TLS
+// with SASL, join group chats (MUC) and send and receive messages, for
+// servers such as Prosody and ejabberd.
+package xmpp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	nsStream  = "http://etherx.jabber.org/streams"
+	nsTLS     = "urn:ietf:params:xml:ns:xmpp-tls"
+	nsSASL    = "urn:ietf:params:xml:ns:xmpp-sasl"
+	nsBind    = "urn:ietf:params:xml:ns:xmpp-bind"
+	nsSession = "urn:ietf:params:xml:ns:xmpp-session"
+	nsMUC     = "http://jabber.org/protocol/muc"
+
+	defaultPort = 5222
+)
+
+// Config says how to log in.
+type Config struct {
+	JID      string // user@example.org
+	Password string
+	// Server is the host:port to connect to. Empty, it is looked up in DNS
+	// for the JID's domain, falling back to the domain on port 5222.
+	Server   string
+	Resource string
+}
+
+// Message is a chat or group chat message.
+type Message struct {
+	From string
+	To   string
+	Type string // chat, groupchat, normal, error
+	Body string
+	// Delayed is set on messages sent before they were delivered: offline
+	// messages and the history a room replays on joining.
+	Delayed bool
+}
+
+// Client is a logged-in XMPP session. Recv must be called in one goroutine;
+// the other methods are safe to call from any.
+type Client struct {
+	conn   net.Conn
+	dec    *xml.Decoder
+	domain string
+	jid    string // full JID the server bound
+
+	mu sync.Mutex // serializes writes
+}
+
+// Dial connects, starts TLS, logs in and binds a resource, then sends
+// initial presence so messages are delivered.
+func Dial(ctx context.Context, cfg Config) (*Client, error) {
+	local, domain, ok := strings.Cut(Bare(cfg.JID), "@")
+	if !ok || local == "" || domain == "" {
+		return nil, fmt.Errorf("xmpp: bad JID %q", cfg.JID)
+	}
+	addr := cfg.Server
+	if addr == "" {
+		addr = lookupServer(ctx, domain)
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("xmpp: dial %s: %w", addr, err)
+	}
+	c := &Client{conn: conn, domain: domain}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(time.Minute))
+	}
+	if err := c.login(local, cfg.Password, cfg.Resource, &tls.Config{ServerName: domain}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// lookupServer finds the client port of domain from its SRV records.
+func lookupServer(ctx context.Context, domain string) string {
+	_, addrs, err := net.DefaultResolver.LookupSRV(ctx, "xmpp-client", "tcp", domain)
+	if err == nil && len(addrs) > 0 && addrs[0].Target != "." {
+		return net.JoinHostPort(strings.TrimSuffix(addrs[0].Target, "."), strconv.Itoa(int(addrs[0].Port)))
+	}
+	return net.JoinHostPort(domain, strconv.Itoa(defaultPort))
+}
+
+type features struct {
+	StartTLS   *struct{} `xml:"urn:ietf:params:xml:ns:xmpp-tls starttls"`
+	Mechanisms *struct {
+		List []string `xml:"mechanism"`
+	} `xml:"urn:ietf:params:xml:ns:xmpp-sasl mechanisms"`
+	Bind    *struct{} `xml:"urn:ietf:params:xml:ns:xmpp-bind bind"`
+	Session *struct {
+		Optional *struct{} `xml:"optional"`
+	} `xml:"urn:ietf:params:xml:ns:xmpp-session session"`
+}
+
+func (c *Client) login(user, password, resource string, tlsConfig *tls.Config) error {
+	f, err := c.openStream()
+	if err != nil {
+		return err
+	}
+	if _, ok := c.conn.(*tls.Conn); !ok {
+		if f.StartTLS == nil {
+			return errors.New("xmpp: server does not offer STARTTLS")
+		}
+		if err := c.write("<starttls xmlns='%s'/>", nsTLS); err != nil {
+			return err
+		}
+		if el, err := c.next(); err != nil {
+			return err
+		} else if el.Name.Local != "proceed" {
+			return fmt.Errorf("xmpp: STARTTLS refused (%s)", el.Name.Local)
+		}
+		tlsConn := tls.Client(c.conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("xmpp: TLS handshake: %w", err)
+		}
+		c.conn = tlsConn
+		if f, err = c.openStream(); err != nil {
+			return err
+		}
+	}
+
+	if f.Mechanisms == nil {
+		return errors.New("xmpp: server offers no SASL mechanisms")
+	}
+	if err := c.authenticate(f.Mechanisms.List, user, password); err != nil {
+		return err
+	}
+	if f, err = c.openStream(); err != nil {
+		return err
+	}
+	if f.Bind == nil {
+		return errors.New("xmpp: server offers no resource binding")
+	}
+	if resource == "" {
+		resource = "myclaw"
+	}
+	if err := c.write("<iq type='set' id='bind'><bind xmlns='%s'><resource>%s</resource></bind></iq>", nsBind, escape(resource)); err != nil {
+		return err
+	}
+	var bound struct {
+		Type string `xml:"type,attr"`
+		JID  string `xml:"bind>jid"`
+	}
+	if err := c.decodeNext(&bound); err != nil {
+		return err
+	}
+	if bound.Type != "result" || bound.JID == "" {
+		return errors.New("xmpp: resource binding failed")
+	}
+	c.jid = bound.JID
+
+	// Old servers need a session; RFC 6121 made it optional.
+	if f.Session != nil && f.Session.Optional == nil {
+		if err := c.write("<iq type='set' id='session'><session xmlns='%s'/></iq>", nsSession); err != nil {
+			return err
+		}
+		if err := c.decodeNext(&struct{}{}); err != nil {
+			return err
+		}
+	}
+	return c.write("<presence/>")
+}
+
+// openStream opens a new stream, as needed at the start and after TLS and
+// SASL, and reads the features the server offers on it.
+func (c *Client) openStream() (*features, error) {
+	c.dec = xml.NewDecoder(c.conn)
+	if err := c.write("<?xml version='1.0'?><stream:stream to='%s' xmlns='jabber:client' xmlns:stream='%s' version='1.0'>", escape(c.domain), nsStream); err != nil {
+		return nil, err
+	}
+	for {
+		tok, err := c.dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("xmpp: open stream: %w", err)
+		}
+		if el, ok := tok.(xml.StartElement); ok && el.Name.Space == nsStream && el.Name.Local == "stream" {
+			break
+		}
+	}
+	el, err := c.next()
+	if err != nil {
+		return nil, err
+	}
+	if el.Name.Local != "features" {
+		return nil, fmt.Errorf("xmpp: expected stream features, got %s", el.Name.Local)
+	}
+	f := &features{}
+	if err := c.dec.DecodeElement(f, &el); err != nil {
+		return nil, fmt.Errorf("xmpp: read features: %w", err)
+	}
+	return f, nil
+}
+
+// next returns the next element on the stream, failing on a stream error
+// or the end of the stream.
+func (c *Client) next() (xml.StartElement, error) {
+	for {
+		tok, err := c.dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space == nsStream && t.Name.Local == "error" {
+				var streamErr struct {
+					Inner []byte `xml:",innerxml"`
+				}
+				c.dec.DecodeElement(&streamErr, &t)
+				return t, fmt.Errorf("xmpp: stream error: %s", streamErrorText(streamErr.Inner))
+			}
+			return t, nil
+		case xml.EndElement:
+			if t.Name.Space == nsStream && t.Name.Local == "stream" {
+				return xml.StartElement{}, io.EOF
+			}
+		}
+	}
+}
+
+func (c *Client) decodeNext(v any) error {
+	el, err := c.next()
+	if err != nil {
+		return err
+	}
+	return c.dec.DecodeElement(v, &el)
+}
+
+// streamErrorText names the condition of a stream error from its XML.
+func streamErrorText(inner []byte) string {
+	dec := xml.NewDecoder(bytes.NewReader(inner))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "unknown"
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			return el.Name.Local
+		}
+	}
+}
+
+func (c *Client) write(format string, args ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := fmt.Fprintf(c.conn, format, args...)
+	return err
+}
+
+// JID returns the full JID the session is bound to.
+func (c *Client) JID() string { return c.jid }
+
+// Join enters the group chat room as nick, without the room's history.
+func (c *Client) Join(room, nick string) error {
+	return c.write("<presence to='%s/%s'><x xmlns='%s'><history maxstanzas='0'/></x></presence>", escape(room), escape(nick), nsMUC)
+}
+
+// Send sends body to the JID to. Group chat messages go to the room's
+// bare JID with typ "groupchat"; others use "chat".
+func (c *Client) Send(to, typ, body string) error {
+	return c.write("<message to='%s' type='%s'><body>%s</body></message>", escape(to), escape(typ), escape(body))
+}
+
+// Ping keeps the connection from being dropped as idle.
+func (c *Client) Ping() error {
+	return c.write(" ")
+}
+
+type stanza struct {
+	XMLName xml.Name
+	ID      string    `xml:"id,attr"`
+	From    string    `xml:"from,attr"`
+	To      string    `xml:"to,attr"`
+	Type    string    `xml:"type,attr"`
+	Body    string    `xml:"body"`
+	Delay   *struct{} `xml:"urn:xmpp:delay delay"`
+	Ping    *struct{} `xml:"urn:xmpp:ping ping"`
+}
+
+// Recv returns the next message. It answers server pings and other
+// requests on the way, and returns io.EOF when the server ends the stream.
+func (c *Client) Recv() (Message, error) {
+	for {
+		var s stanza
+		if err := c.decodeNext(&s); err != nil {
+			return Message{}, err
+		}
+		switch s.XMLName.Local {
+		case "message":
+			if s.Body == "" {
+				continue // chat states, receipts and the like
+			}
+			return Message{From: s.From, To: s.To, Type: s.Type, Body: s.Body, Delayed: s.Delay != nil}, nil
+		case "iq":
+			if err := c.answerIQ(s); err != nil {
+				return Message{}, err
+			}
+		}
+	}
+}
+
+// answerIQ answers pings, and tells requests it does not handle so.
+func (c *Client) answerIQ(s stanza) error {
+	if s.Type != "get" && s.Type != "set" {
+		return nil
+	}
+	if s.Ping != nil {
+		return c.write("<iq type='result' id='%s' to='%s'/>", escape(s.ID), escape(s.From))
+	}
+	return c.write("<iq type='error' id='%s' to='%s'><error type='cancel'><service-unavailable xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></iq>", escape(s.ID), escape(s.From))
+}
+
+// Close ends the stream and the connection.
+func (c *Client) Close() error {
+	c.write("<presence type='unavailable'/></stream:stream>")
+	return c.conn.Close()
+}
+
+// Bare strips the resource from jid: user@example.org/phone becomes
+// user@example.org, and room@muc.example.org/nick the room.
+func Bare(jid string) string {
+	bare, _, _ := strings.Cut(jid, "/")
+	return bare
+}
+
+// Resource returns the part of jid after the slash: the device of a user,
+// or the nickname of a room occupant.
+func Resource(jid string) string {
+	_, res, _ := strings.Cut(jid, "/")
+	return res
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return strings.ReplaceAll(b.String(), "'", "&#39;")
+}
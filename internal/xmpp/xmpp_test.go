@@ -0,0 +1,188 @@
+package xmpp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xdg-go/scram"
+)
+
+func TestSCRAM(t *testing.T) {
+	// The SCRAM-SHA-256 example from RFC 7677.
+	conv := func() *scram.ClientConversation {
+		client, err := scram.SHA256.NewClient("user", "pencil", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := client.WithNonceGenerator(func() string { return "rOprNGfwEbeRWgbNEkqO" }).NewConversation()
+		if first, _ := c.Step(""); first != "n,,n=user,r=rOprNGfwEbeRWgbNEkqO" {
+			t.Errorf("client first = %q", first)
+		}
+		return c
+	}
+	const serverFirst = "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"
+	s := conv()
+	final, err := scramFinal(s, serverFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final != "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=" {
+		t.Errorf("client final = %q", final)
+	}
+	if _, err := s.Step("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="); err != nil || !s.Valid() {
+		t.Errorf("server not verified: %v", err)
+	}
+	s = conv()
+	scramFinal(s, serverFirst)
+	if _, err := s.Step("v=AAAA"); err == nil {
+		t.Error("forged server signature accepted")
+	}
+	if _, err := scramFinal(conv(), "r=someoneelse,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"); err == nil {
+		t.Error("foreign nonce accepted")
+	}
+	if _, err := scramFinal(conv(), "r=rOprNGfwEbeRWgbNEkqO%hvYD,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=2000000000"); err == nil {
+		t.Error("huge iteration count accepted")
+	}
+}
+
+// fakeServer plays the server side of a login over conn, then sends a ping
+// and a couple of messages and hands back what the client sends after.
+func fakeServer(t *testing.T, conn net.Conn, cert tls.Certificate) <-chan []string {
+	done := make(chan []string, 1)
+	go func() {
+		defer conn.Close()
+		var got []string
+		defer func() { done <- got }()
+
+		dec := xml.NewDecoder(conn)
+		// next reads the client's next element as text.
+		next := func() string {
+			for {
+				tok, err := dec.Token()
+				if err != nil {
+					return ""
+				}
+				el, ok := tok.(xml.StartElement)
+				if !ok {
+					continue
+				}
+				if el.Name.Local == "stream" {
+					return "stream:"
+				}
+				var v struct {
+					Inner string `xml:",innerxml"`
+				}
+				dec.DecodeElement(&v, &el)
+				return el.Name.Local + ":" + v.Inner
+			}
+		}
+		// open answers the client's stream header with one offering features.
+		open := func(features string) {
+			if header := next(); header != "stream:" {
+				t.Errorf("expected stream header, got %q", header)
+			}
+			fmt.Fprintf(conn, "<stream:stream xmlns='jabber:client' xmlns:stream='%s' from='example.com' version='1.0'><stream:features>%s</stream:features>", nsStream, features)
+		}
+
+		open("<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'><required/></starttls>")
+		if got := next(); got != "starttls:" {
+			t.Errorf("expected starttls, got %q", got)
+			return
+		}
+		fmt.Fprintf(conn, "<proceed xmlns='%s'/>", nsTLS)
+		tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+		conn, dec = tlsConn, xml.NewDecoder(tlsConn)
+
+		open("<mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>PLAIN</mechanism></mechanisms>")
+		if auth := next(); auth != "auth:"+base64.StdEncoding.EncodeToString([]byte("\x00bot\x00s3cret")) {
+			fmt.Fprintf(conn, "<failure xmlns='%s'><not-authorized/></failure>", nsSASL)
+			return
+		}
+		fmt.Fprintf(conn, "<success xmlns='%s'/>", nsSASL)
+
+		open("<bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'/>")
+		next()
+		fmt.Fprintf(conn, "<iq type='result' id='bind'><bind xmlns='%s'><jid>bot@example.com/myclaw</jid></bind></iq>", nsBind)
+		next() // initial presence
+
+		fmt.Fprint(conn, "<iq type='get' id='p1' from='example.com'><ping xmlns='urn:xmpp:ping'/></iq>")
+		got = append(got, next())
+		fmt.Fprint(conn, "<message from='alice@example.com/phone' type='chat'><composing xmlns='http://jabber.org/protocol/chatstates'/></message>")
+		fmt.Fprint(conn, "<message from='room@muc.example.com/alice' type='groupchat'><body>old</body><delay xmlns='urn:xmpp:delay' stamp='2024-01-01T00:00:00Z'/></message>")
+		fmt.Fprint(conn, "<message from='alice@example.com/phone' type='chat'><body>hi &amp; bye</body></message>")
+		for {
+			el := next()
+			if el == "" {
+				return
+			}
+			got = append(got, el)
+		}
+	}()
+	return done
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	client, server := net.Pipe()
+	done := fakeServer(t, server, srv.TLS.Certificates[0])
+	c := &Client{conn: client, domain: "example.com"}
+	if err := c.login("bot", "s3cret", "", &tls.Config{ServerName: "example.com", RootCAs: roots}); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if c.JID() != "bot@example.com/myclaw" {
+		t.Errorf("jid = %q", c.JID())
+	}
+
+	msg, err := c.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.From != "room@muc.example.com/alice" || !msg.Delayed {
+		t.Errorf("first = %+v", msg)
+	}
+	msg, err = c.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Body != "hi & bye" || msg.Type != "chat" || msg.Delayed || Bare(msg.From) != "alice@example.com" || Resource(msg.From) != "phone" {
+		t.Errorf("second = %+v", msg)
+	}
+	if err := c.Join("room@muc.example.com", "claw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send("alice@example.com", "chat", "it's <done>"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	got := <-done
+	if len(got) != 4 || got[0] != "iq:" || got[1] != "presence:<x xmlns='http://jabber.org/protocol/muc'><history maxstanzas='0'/></x>" || got[2] != "message:<body>it&#39;s &lt;done&gt;</body>" {
+		t.Errorf("client sent %q", got)
+	}
+}
+
+func TestClient_LoginFailure(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	client, server := net.Pipe()
+	defer client.Close()
+	fakeServer(t, server, srv.TLS.Certificates[0])
+	c := &Client{conn: client, domain: "example.com"}
+	err := c.login("bot", "wrong", "", &tls.Config{ServerName: "example.com", RootCAs: roots})
+	if err == nil || err.Error() != "xmpp: login failed: not-authorized" {
+		t.Errorf("err = %v", err)
+	}
+}